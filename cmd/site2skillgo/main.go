@@ -1,35 +1,75 @@
 // Package main is the entry point for the site2skillgo tool.
 // site2skillgo converts website documentation into Claude/Codex AI skill packages
-// through a multi-step pipeline: fetch, convert, normalize, generate, validate, and package.
+// through a multi-step pipeline: fetch, convert, normalize, chunk, generate, validate, and package.
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
-	"os/user"
+	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/f4ah6o/site2skill-go/internal/access"
+	"github.com/f4ah6o/site2skill-go/internal/api"
+	"github.com/f4ah6o/site2skill-go/internal/config"
+	"github.com/f4ah6o/site2skill-go/internal/confluence"
 	"github.com/f4ah6o/site2skill-go/internal/converter"
+	"github.com/f4ah6o/site2skill-go/internal/embeddings"
+	"github.com/f4ah6o/site2skill-go/internal/events"
 	"github.com/f4ah6o/site2skill-go/internal/fetcher"
-	"github.com/f4ah6o/site2skill-go/internal/normalizer"
+	"github.com/f4ah6o/site2skill-go/internal/golden"
+	"github.com/f4ah6o/site2skill-go/internal/links"
+	"github.com/f4ah6o/site2skill-go/internal/logging"
+	"github.com/f4ah6o/site2skill-go/internal/mcp"
+	"github.com/f4ah6o/site2skill-go/internal/metrics"
+	"github.com/f4ah6o/site2skill-go/internal/mirror"
+	"github.com/f4ah6o/site2skill-go/internal/notion"
+	"github.com/f4ah6o/site2skill-go/internal/openai"
 	"github.com/f4ah6o/site2skill-go/internal/packager"
+	"github.com/f4ah6o/site2skill-go/internal/pathglob"
+	"github.com/f4ah6o/site2skill-go/internal/progress"
+	"github.com/f4ah6o/site2skill-go/internal/registry"
 	"github.com/f4ah6o/site2skill-go/internal/search"
+	"github.com/f4ah6o/site2skill-go/internal/selftest"
 	"github.com/f4ah6o/site2skill-go/internal/skillgen"
+	"github.com/f4ah6o/site2skill-go/internal/summarize"
+	"github.com/f4ah6o/site2skill-go/internal/translate"
 	"github.com/f4ah6o/site2skill-go/internal/validator"
+	"github.com/f4ah6o/site2skill-go/internal/warc"
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+	"github.com/f4ah6o/site2skill-go/internal/watch"
+	"github.com/f4ah6o/site2skill-go/pkg/site2skill"
 )
 
 const (
 	// FormatClaude specifies output format for Claude AI skill packages.
-	FormatClaude = "claude"
+	FormatClaude = site2skill.FormatClaude
 	// FormatCodex specifies output format for OpenAI Codex skill packages.
-	FormatCodex = "codex"
+	FormatCodex = site2skill.FormatCodex
 	// FormatBoth specifies output format for both Claude and Codex skill packages.
-	FormatBoth = "both"
+	FormatBoth = site2skill.FormatBoth
 )
 
 // CodexConfig represents the structure of the Codex configuration file (config.toml).
@@ -43,21 +83,6 @@ type CodexConfig struct {
 	} `toml:"features"`
 }
 
-// getCodexHome returns the Codex home directory.
-// It checks the CODEX_HOME environment variable first, then falls back to ~/.codex
-func getCodexHome() (string, error) {
-	if codexHome := os.Getenv("CODEX_HOME"); codexHome != "" {
-		return codexHome, nil
-	}
-
-	usr, err := user.Current()
-	if err != nil {
-		return "", fmt.Errorf("failed to get current user: %w", err)
-	}
-
-	return filepath.Join(usr.HomeDir, ".codex"), nil
-}
-
 // checkCodexSkillsConfig checks if the Codex skills feature is enabled in the config.toml file.
 // It first locates the Codex home directory, then reads and parses the configuration file.
 //
@@ -69,7 +94,7 @@ func getCodexHome() (string, error) {
 // If the config file does not exist, returns (false, false, nil).
 // If the file exists but cannot be parsed, returns (false, true, error).
 func checkCodexSkillsConfig() (bool, bool, error) {
-	codexHome, err := getCodexHome()
+	codexHome, err := site2skill.CodexHome()
 	if err != nil {
 		return false, false, err
 	}
@@ -99,8 +124,60 @@ func main() {
 	switch subcommand {
 	case "generate":
 		runGenerate(os.Args[2:])
+	case "update":
+		runUpdate(os.Args[2:])
+	case "reconvert":
+		runReconvert(os.Args[2:])
+	case "import":
+		runImport(os.Args[2:])
+	case "build":
+		runBuild(os.Args[2:])
+	case "cron":
+		runCron(os.Args[2:])
+	case "dev":
+		runDev(os.Args[2:])
 	case "search":
 		runSearch(os.Args[2:])
+	case "related":
+		runRelated(os.Args[2:])
+	case "index":
+		runIndex(os.Args[2:])
+	case "hash":
+		runHash(os.Args[2:])
+	case "diff":
+		runDiff(os.Args[2:])
+	case "check":
+		runCheck(os.Args[2:])
+	case "validate":
+		runValidate(os.Args[2:])
+	case "export":
+		runExport(os.Args[2:])
+	case "inspect":
+		runInspect(os.Args[2:])
+	case "mcp":
+		runMCP(os.Args[2:])
+	case "serve":
+		runServe(os.Args[2:])
+	case "keygen":
+		runKeygen(os.Args[2:])
+	case "package":
+		runPackage(os.Args[2:])
+	case "verify":
+		runVerify(os.Args[2:])
+	case "push":
+		runPush(os.Args[2:])
+	case "pull":
+		runPull(os.Args[2:])
+	case "config":
+		runConfig(os.Args[2:])
+	case "selftest":
+		runSelftest(os.Args[2:])
+	case "golden":
+		runGolden(os.Args[2:])
+	case "init":
+		runInit(os.Args[2:])
+	case "completion":
+		runCompletion(os.Args[2:])
 	case "-h", "--help", "help":
 		printUsage()
 	default:
@@ -117,6 +194,40 @@ func main() {
 	}
 }
 
+// commands lists the subcommands with their summaries, for the help text and
+// the shell completion scripts.
+var commands = []struct{ name, summary string }{
+	{"generate", "Generate a skill package from a documentation website"},
+	{"update", "Crawl a skill's site again and update only the pages that changed"},
+	{"reconvert", "Rebuild a skill from the pages of an earlier crawl, without network access"},
+	{"import", "Build a skill from a WARC archive or a wget or HTTrack mirror of a site"},
+	{"build", "Build several profiles of a config file concurrently"},
+	{"cron", "Update the skills of config file profiles on a schedule, publishing those that changed"},
+	{"dev", "Serve a local site copy and rebuild its skill whenever it changes"},
+	{"search", "Search through skill documentation files"},
+	{"related", "List the documents most similar to a document of a skill"},
+	{"index", "Rebuild, watch, or embed the search index of a skill"},
+	{"hash", "Print the content hashes of a skill's documents and of the whole skill"},
+	{"diff", "Report the documents added, modified, and removed between two versions of a skill"},
+	{"check", "Report the broken links and heading anchors of a skill"},
+	{"validate", "Check a skill's structure, documents, and size before publishing it"},
+	{"export", "Write a skill's documents into one Markdown or JSON Lines file"},
+	{"inspect", "Show how one page is extracted and converted"},
+	{"mcp", "Serve a skill's search and documents to agents over MCP"},
+	{"serve", "Serve a skill's search, documents, and manifest as a JSON API"},
+	{"keygen", "Create a key pair for signing skill packages"},
+	{"package", "Package a skill directory as a .skill file with its integrity manifest"},
+	{"verify", "Check a skill package against its integrity manifest and signature"},
+	{"push", "Upload a skill package to an OCI registry or S3 bucket"},
+	{"pull", "Download a skill package from an OCI registry or S3 bucket"},
+	{"config", "Check a site2skill.yaml config file"},
+	{"selftest", "Build a bundled miniature docs site and check the output"},
+	{"golden", "Record a site as a replayable fixture, or check the skills of fixtures against their snapshots"},
+	{"init", "Probe a documentation site and write a starter config file profile"},
+	{"completion", "Print a bash, zsh, or fish completion script"},
+	{"help", "Show this help message"},
+}
+
 // printUsage displays the main usage information and help text for the site2skillgo command.
 // It is called when the user runs the program without arguments, with the help flag,
 // or provides an invalid subcommand.
@@ -128,25 +239,133 @@ and packages them as Agent Skills (ZIP format) for use with Claude or Codex.
 
 Usage:
   site2skillgo generate <URL> <SKILL_NAME> [options]
+  site2skillgo update <SKILL_DIR> [options]
+  site2skillgo reconvert <SKILL_NAME> [options]
+  site2skillgo import <WARC_FILE|MIRROR_DIR> <SKILL_NAME> [options]
+  site2skillgo build [PROFILE...] [--all-profiles] [options]
+  site2skillgo cron [PROFILE...] [--all-profiles] [options]
+  site2skillgo dev <SOURCE_DIR> <SKILL_NAME> [options] [-- GENERATE_OPTIONS]
   site2skillgo search <QUERY> [options]
+  site2skillgo related <DOC_PATH> [options]
+  site2skillgo index optimize [SKILL_DIR]
+  site2skillgo index watch [SKILL_DIR]
+  site2skillgo hash [SKILL_DIR] [--json | --quiet]
+  site2skillgo diff <OLD_SKILL_DIR> <NEW_SKILL_DIR> [--patch] [--json]
+  site2skillgo check [SKILL_DIR] [--json]
+  site2skillgo validate [SKILL_DIR] [options]
+  site2skillgo export [SKILL_DIR] [--format markdown|jsonl] [--output FILE]
+  site2skillgo inspect <URL> [options]
+  site2skillgo mcp [SKILL_DIR] [--sse ADDR] [--watch]
+  site2skillgo serve [SKILL_DIR] [--addr ADDR] [--watch]
+  site2skillgo keygen <NAME>
+  site2skillgo package <SKILL_DIR> [options]
+  site2skillgo verify <SKILL_FILE> [--key <PUBLIC_KEY>]
+  site2skillgo push <SKILL_FILE> <REF> [options]
+  site2skillgo pull <REF> [options]
+  site2skillgo config validate [FILE]
+  site2skillgo selftest [options]
+  site2skillgo golden record [options] <URL> <SKILL_NAME> <FIXTURE_DIR>
+  site2skillgo golden verify [--update] [--json] <FIXTURE_DIR>...
+  site2skillgo init <URL> [options]
+  site2skillgo completion bash|zsh|fish
   site2skillgo help
 
-Commands:
-  generate    Generate a skill package from a documentation website
-  search      Search through skill documentation files
-  help        Show this help message
-
+`)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-11s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintf(os.Stderr, `
 Generate Options:
   --format string          Output format: claude, codex, or both (default "claude")
   --global                 Install to global skills directory
+  --seed string            Another start URL, possibly on another host, crawled into the same skill (repeatable)
   --temp-dir string        Temporary directory for processing (default "build")
   --skip-fetch             Skip the download step (use existing files)
   --clean                  Clean up temporary directory after completion
   --locale-priority string Locale priority order (default "en,ja")
   --no-locale-priority     Disable locale priority mode
   --locale-param string    Query parameter name for locale (e.g., "hl")
+  --locale-file string     YAML/JSON file with extra locale codes and aliases
+  --each-locale            Build one documentation tree per locale of the priority (SKILL/en/docs, SKILL/ja/docs)
   --include string         Include only URLs containing this string (repeatable)
   --exclude string         Exclude URLs containing this string (repeatable)
+  --feed                   Treat the URL as an RSS or Atom feed and turn its entries into dated documents
+  --feed-articles          With --feed, download the article page of every entry
+  --repo                   Treat the URL as a GitHub or GitLab repository and read its README, docs/, and wiki
+  --confluence             Treat the URL as a Confluence space and read its pages through the REST API
+  --notion                 Treat the URL as a Notion page and read its page tree through the Notion API
+  --include-pdf            Download linked PDF documents and convert their text into Markdown
+  --sitemaps               Also crawl the pages listed in the sitemaps of robots.txt
+  --ignore-canonical       Save pages under their own URL, ignoring <link rel="canonical">
+  --skip-external-canonical Skip pages whose canonical URL is outside the crawl scope
+  --max-redirects int      Redirects followed per request before the page fails (default 10)
+  --max-page-size string   Skip pages and PDF documents larger than this size (e.g., 10MB)
+  --max-total-size string  Stop the crawl once it has downloaded this much (e.g., 1GB)
+  --content-types string   Media types of the responses saved (default "text/html,text/plain,text/markdown")
+  --link-attrs string      Attributes of any element crawled as links (default "data-href,data-url,data-link")
+  --compress-crawl         Store the crawled HTML pages gzip-compressed (page.html.gz)
+  --strip-params string    Query parameters removed from URLs besides utm_* and click IDs (e.g., "ref,session_*")
+  --trailing-slash string  Trailing slash policy of URLs: keep, strip, or add (default "keep")
+  --robots string          robots.txt policy: strict, default, or off (default "default")
+  --consent-cookie string  Send this cookie with every request to get past consent walls (NAME=VALUE, repeatable)
+  --no-meta-refresh        Save pages redirecting with a meta refresh instead of crawling their target
+  --keep-interstitials     Save pages that look like consent walls or JavaScript notices (flagged in the report)
+  --keep-noindex           Save pages whose robots meta tag or X-Robots-Tag says noindex
+  --resolve string         Connect to ADDRESS for HOST:PORT, like curl (HOST:PORT:ADDRESS, repeatable)
+  --host-header string     Crawl the URL's server as this host (Host header and output URLs)
+  --sandbox                Only connect to the hosts of the crawl and --allow-host, with pinned DNS addresses
+  --allow-host string      Host the sandbox also allows, e.g., cdn.example.com or "*.example.com" (repeatable)
+  --max-runtime duration   Stop the build with an error once it has run this long (e.g., 30m)
+  --recheck-after string   Take the pages fetched less than this long ago from the HTTP cache without re-checking them (e.g., 7d)
+  --proxy string           Send requests through this HTTP, HTTPS, or SOCKS5 proxy (e.g., "socks5://127.0.0.1:1080")
+  --ca-cert string         Trust the certificate authorities of this PEM file besides the system's
+  --insecure               Skip the verification of TLS certificates (self-signed staging servers)
+  --politeness string      YAML file of per-host delays, time windows, and User-Agent suffixes
+  --max-conns-per-host int Connections opened to a host at most, and kept open for reuse (default 8)
+  --no-http2               Disable HTTP/2, for servers whose HTTP/2 support is broken
+  --no-adaptive-throttle   Keep the delay of hosts answering 429, 503, or slowly, without retrying
+  --device string          Client the crawler presents itself as: desktop or mobile (default "desktop")
+  --user-agent string      User-Agent template with {version}, {contact}, and {run_id} (default "site2skillgo/{version} (+{contact})")
+  --contact string         URL or email address where site operators can reach you, in the User-Agent
+  --run-id string          Identifier of the run in logs, the crawl report, and {run_id} (default: generated)
+  --rewrite-url string     Rewrite output URLs, e.g., staging.docs.internal=docs.example.com (FROM=TO, repeatable or comma-separated)
+  --access-rule string     Tag pages with an access level, e.g., "/internal/**=internal" (PATTERN=LEVEL, repeatable)
+  --llms-txt string        Also write llms.txt and llms-full.txt of the site into this directory
+  --plugin string          Run a plugin extracting, converting, or exporting pages (command, repeatable)
+  --publish string         Publish the packaged skills to s3://BUCKET, gs://BUCKET, or git+URL#BRANCH (repeatable)
+  --export string          Also write the documents as mdx, asciidoc, or markdown into a directory, e.g., mdx=site/content (FORMAT=DIR, repeatable)
+  --version-priority string Crawl only the first of these versions that exists (e.g., "latest,v2")
+  --timestamp string       Time recorded in the output for reproducible builds, RFC 3339 or Unix seconds (default $SOURCE_DATE_EPOCH)
+  --update                 Update the existing skill instead of regenerating it, like the update command
+  --only string            Rebuild only the sections of the existing skill whose URL path matches, e.g., "/guides/**" (repeatable)
+  --cache-dir string       HTTP cache directory shared across runs (default "<temp-dir>/http-cache")
+  --no-cache               Disable the on-disk HTTP and conversion caches
+  --report string          Path of the JSON crawl report (default "<temp-dir>/crawl-report.json")
+  --warc string            Also record the crawl's requests and responses into this WARC archive (.warc or .warc.gz)
+  --content-selector string CSS selector for the main content (default: Readability extraction)
+  --strip-selector string  CSS selector of elements to remove before conversion (repeatable)
+  --platform string        Extraction profile: auto, none, or a platform such as docusaurus or sphinx (default "auto")
+  --converter string       Convert matching pages with a command, HTML in and Markdown out, e.g., "/reference/**=pandoc -f html -t gfm" (MATCH=COMMAND, repeatable)
+  --table-fallback string  Rendering of tables that can't be GFM tables: html or list (default "html")
+  --table-csv-rows int     Also save tables with more rows than this as CSV files (default 50, 0 = off)
+  --admonitions string     Rendering of note/warning boxes: github, blockquote, or none (default "github")
+  --dedupe-snippets        Replace repeated long code samples with links to shared files in snippets/
+  --keep-duplicates        Keep pages whose content duplicates another page (default: keep one, list the others as aliases)
+  --stitch-pages           Convert each paginated article or listing (rel="next", ?page=N) into one document
+  --chunk-tokens int       Split pages over this many tokens into several files (default 25000, 0 = off)
+  --page-types string      Keep only these page types: docs, marketing, legal (default: all)
+  --language-filter string Flag or exclude pages whose text isn't in their locale's language: off, flag, or exclude (default "off")
+  --download-assets        Download referenced images and media into assets/
+  --air-gapped             Neutralize external links/images/embeds; fail if any remain
+  --absolute-links         Keep links between pages as absolute URLs instead of relative paths to their files
+  --sign-key string        Sign each .skill file with this private key (writes <file>.skill.sig)
+  --warning-limit int      Warnings of each kind shown before the rest are summarized (default 5, 0 = all)
+  --metrics-addr string    Serve Prometheus metrics of the crawl at http://ADDR/metrics (e.g., ":9090")
+  --log-level string       Minimum level of the log messages: debug, info, warn, or error (default "info")
+  --log-format string      Format of the log on stderr: text, or json for log pipelines (default "text")
+  --config string          Config file with generate options (default "site2skill.yaml" with --profile)
+  --profile string         Profile of the config file to use
 
 URL Filtering Notes:
   - Flags can be repeated or passed as comma-separated lists (e.g., --include "v1,docs")
@@ -157,6 +376,10 @@ Examples:
   site2skillgo generate https://f4ah6o.github.io/site2skill-go/ myskill --format codex
   site2skillgo generate --locale-priority "ja,en" https://f4ah6o.github.io/site2skill-go/ myskill
   site2skillgo generate --include "filters" --exclude "beta" https://f4ah6o.github.io/site2skill-go/ myskill
+  site2skillgo update .claude/skills/myskill
+  site2skillgo build --all-profiles
+  site2skillgo build stripe --only "/guides/**"
+  site2skillgo cron --all-profiles --every 24h
   site2skillgo search "authentication" --skill-dir .claude/skills/myskill
 
 For more information on a command, use:
@@ -170,40 +393,16 @@ Supported Formats:
 
 // runGenerate executes the generate subcommand, which converts website documentation
 // into AI skill packages. It parses command-line arguments, validates inputs, and
-// orchestrates the multi-step pipeline: fetch, convert, normalize, generate, validate, and package.
+// orchestrates the multi-step pipeline: fetch, convert, normalize, chunk, generate, validate, and package.
 //
 // args should contain the command-line arguments following the "generate" subcommand.
 // The function expects URL and skill name as positional arguments or flags.
 func runGenerate(args []string) {
 	fs := flag.NewFlagSet("generate", flag.ExitOnError)
 
-	var (
-		url              string
-		skillName        string
-		global           bool
-		tempDir          string
-		skipFetch        bool
-		clean            bool
-		format           string
-		localePriority   string
-		noLocalePriority bool
-		localeParam      string
-		includeFilters   stringList
-		excludeFilters   stringList
-	)
-
-	fs.StringVar(&url, "url", "", "URL of the documentation site (required)")
-	fs.StringVar(&skillName, "name", "", "Name of the skill (required)")
-	fs.BoolVar(&global, "global", false, "Install to global skills directory (~/.claude/skills or ~/.codex/skills)")
-	fs.StringVar(&tempDir, "temp-dir", "build", "Temporary directory for processing")
-	fs.BoolVar(&skipFetch, "skip-fetch", false, "Skip the download step (use existing files in temp dir)")
-	fs.BoolVar(&clean, "clean", false, "Clean up temporary directory after completion")
-	fs.StringVar(&format, "format", "claude", "Output format: claude, codex, or both")
-	fs.StringVar(&localePriority, "locale-priority", "en,ja", "Locale priority order (comma-separated, e.g., 'en,ja,zh')")
-	fs.BoolVar(&noLocalePriority, "no-locale-priority", false, "Disable locale priority mode")
-	fs.StringVar(&localeParam, "locale-param", "", "Query parameter name for locale (e.g., 'hl' for ?hl=ja)")
-	fs.Var(&includeFilters, "include", "Include only URLs containing this string (can be repeated or comma-separated)")
-	fs.Var(&excludeFilters, "exclude", "Exclude URLs containing this string (can be repeated or comma-separated)")
+	var opts generateOptions
+	opts.registerFlags(fs)
+	fs.BoolVar(&opts.update, "update", false, "Update the existing skill of each target instead of regenerating it, as the update command does (a target without a skill is generated)")
 
 	fs.Usage = func() {
 		fmt.Fprintf(os.Stderr, `Usage: site2skillgo generate <URL> <SKILL_NAME> [options]
@@ -222,120 +421,596 @@ Pipeline Steps:
   1. Fetch      - Download documentation site recursively
   2. Convert    - Convert HTML pages to Markdown
   3. Normalize  - Clean up links and formatting
-  4. Generate   - Create skill structure
-  5. Validate   - Check skill structure and size limits
-  6. Package    - Create .skill file (ZIP archive)
+  4. Chunk      - Split pages over the token budget along headings
+  5. Generate   - Create skill structure
+  6. Validate   - Check skill structure and size limits
+  7. Package    - Create .skill file (ZIP archive)
 
 Examples:
   site2skillgo generate https://docs.example.com example
   site2skillgo generate https://docs.python.org/3/ python3 --format claude
   site2skillgo generate https://stripe.com/docs/api stripe --format codex --global
   site2skillgo generate https://docs.example.com example --skip-fetch --clean
+  site2skillgo generate --dry-run https://docs.example.com example
   site2skillgo generate --include "filters" --exclude "beta" https://f4ah6o.github.io/site2skill-go/ filter-test
 `)
-        }
+	}
 
 	fs.Parse(args)
+	opts.loadConfig(fs)
+	setupLogging(opts.logLevel, opts.logFormat)
+	warnlog.SetLimit(opts.warningLimit)
 
 	// Handle positional arguments if provided
 	if fs.NArg() >= 2 {
-		url = fs.Arg(0)
-		skillName = fs.Arg(1)
+		opts.url = fs.Arg(0)
+		opts.skillName = fs.Arg(1)
 	}
 
-	if url == "" || skillName == "" {
+	if opts.url == "" || opts.skillName == "" {
 		fmt.Fprintf(os.Stderr, "Usage: site2skillgo generate <URL> <SKILL_NAME> [options]\n\n")
 		fs.PrintDefaults()
 		os.Exit(1)
 	}
 
 	// Validate format
-	if format != FormatClaude && format != FormatCodex && format != FormatBoth {
-		log.Fatalf("Invalid format: %s. Must be 'claude', 'codex', or 'both'", format)
+	if opts.format != FormatClaude && opts.format != FormatCodex && opts.format != FormatBoth {
+		log.Fatalf("Invalid format: %s. Must be 'claude', 'codex', or 'both'", opts.format)
 	}
 
-	executeGenerate(url, skillName, global, tempDir, skipFetch, clean, format, localePriority, noLocalePriority, localeParam, includeFilters, excludeFilters)
+	executeGenerate(opts)
 }
 
-// determineOutputPaths determines the output directories for skill generation based on
-// the output format and installation scope.
-//
-// For Claude format:
-//   - Global: ~/.claude/skills
-//   - Local: .claude/skills (current directory)
-//
-// For Codex format:
-//   - Global: $CODEX_HOME/skills or ~/.codex/skills
-//   - Local: .codex/skills (current directory)
-//
-// Parameters:
-//   - format: The target format ("claude" or "codex")
-//   - global: If true, install to user's global skills directory; if false, use local directory
-//
-// Returns:
-//   - skillStructureDir: Directory where the skill structure will be created
-//   - skillFileDir: Directory where the .skill file will be saved (same as skillStructureDir)
-func determineOutputPaths(format string, global bool) (skillStructureDir, skillFileDir string) {
-	if global {
-		if format == FormatClaude {
-			usr, err := user.Current()
-			if err != nil {
-				log.Fatalf("Failed to get current user: %v", err)
-			}
-			skillStructureDir = filepath.Join(usr.HomeDir, ".claude", "skills")
-			skillFileDir = skillStructureDir
-		} else if format == FormatCodex {
-			codexHome, err := getCodexHome()
-			if err != nil {
-				log.Fatalf("Failed to get Codex home directory: %v", err)
-			}
-			skillStructureDir = filepath.Join(codexHome, "skills")
-			skillFileDir = skillStructureDir
+// generateOptions holds the settings for one run of the generate pipeline.
+type generateOptions struct {
+	// url is the target website URL to scrape
+	url string
+	// seeds are more start URLs crawled into the same skill, each restricted to its host
+	seeds stringList
+	// skillName is the name for the generated skill package
+	skillName string
+	// global installs to the global skills directory when true
+	global bool
+	// tempDir is the temporary directory for intermediate files
+	tempDir string
+	// skipFetch skips downloading and uses existing files in tempDir
+	skipFetch bool
+	// clean removes the temporary directory after completion
+	clean bool
+	// dryRun crawls the site and prints the pages that would be saved without writing anything
+	dryRun bool
+	// format is the output format ("claude", "codex", or "both")
+	format string
+	// localePriority is a comma-separated list of preferred locale codes (e.g., "en,ja")
+	localePriority string
+	// noLocalePriority disables locale priority mode
+	noLocalePriority bool
+	// localeParam is the query parameter name for locale selection (e.g., "hl" for ?hl=ja)
+	localeParam string
+	// localeFile is a YAML/JSON file with additional locale codes and aliases
+	localeFile string
+	// eachLocale builds one documentation tree per locale of the priority
+	eachLocale bool
+	// includeFilters restricts crawling to URLs containing one of these strings
+	includeFilters stringList
+	// excludeFilters skips URLs containing any of these strings
+	excludeFilters stringList
+	// feed treats url as an RSS or Atom feed whose entries become the documents
+	feed bool
+	// feedArticles downloads the article page of every feed entry
+	feedArticles bool
+	// repo treats url as a GitHub or GitLab repository whose Markdown sources become the documents
+	repo bool
+	// confluence treats url as a Confluence space whose pages are read through the REST API
+	confluence bool
+	// confluenceToken is the resolved Confluence token of the config file; never log it
+	confluenceToken string
+	// notion treats url as a Notion page whose tree of pages is read through the Notion API
+	notion bool
+	// notionToken is the resolved Notion token of the config file; never log it
+	notionToken string
+	// includePDF downloads the linked PDF documents and converts them into Markdown
+	includePDF bool
+	// sitemaps also crawls the pages listed in the sitemaps of robots.txt
+	sitemaps bool
+	// ignoreCanonical saves pages under their own URL whatever their canonical link
+	ignoreCanonical bool
+	// skipExternalCanonical skips pages whose canonical URL is out of scope
+	skipExternalCanonical bool
+	// maxRedirects is the number of redirects followed per request
+	maxRedirects int
+	// maxPageSize is the size of the largest page downloaded, e.g., "10MB"
+	maxPageSize string
+	// maxTotalSize is the number of bytes the crawl downloads at most, e.g., "1GB"
+	maxTotalSize string
+	// contentTypes are the media types of the responses saved; empty uses the default
+	contentTypes stringList
+	// linkAttrs are the attributes of any element crawled as links; empty uses the default
+	linkAttrs stringList
+	// compressCrawl stores the crawled HTML pages gzip-compressed
+	compressCrawl bool
+	// stripParams are the query parameters removed from URLs besides the tracking parameters
+	stripParams stringList
+	// trailingSlash is the trailing slash policy of URLs: "keep", "strip", or "add"
+	trailingSlash string
+	// robots is how robots.txt is followed: "strict", "default", or "off"
+	robots string
+	// consentCookies are cookies sent with every request, "name=value"
+	consentCookies stringList
+	// noMetaRefresh saves the pages redirecting with a meta refresh as they are
+	noMetaRefresh bool
+	// keepInterstitials saves the pages that look like consent walls or JavaScript notices
+	keepInterstitials bool
+	// keepNoIndex saves the pages whose robots directives say noindex
+	keepNoIndex bool
+	// resolve lists curl-style host overrides, "host:port:address"
+	resolve stringList
+	// hostHeader crawls the server of url as this host
+	hostHeader string
+	// sandbox restricts the crawl to the hosts of its start URLs and allowHosts
+	sandbox bool
+	// allowHosts are the hosts the sandbox allows besides those of the crawl
+	allowHosts stringList
+	// maxRuntime is how long the build may run at most; zero means no limit
+	maxRuntime time.Duration
+	// recheckAfter is the freshness window of the pages in the HTTP cache, e.g., "7d"; empty re-checks every page
+	recheckAfter string
+	// proxy is the URL of the proxy requests go through; empty uses the environment's
+	proxy string
+	// caCert is the path of a PEM file of certificate authorities to trust
+	caCert string
+	// insecure skips the verification of TLS certificates
+	insecure bool
+	// politeness is the path of the file of per-host politeness settings
+	politeness string
+	// maxConnsPerHost is the number of connections opened to a host at most
+	maxConnsPerHost int
+	// noHTTP2 disables HTTP/2
+	noHTTP2 bool
+	// noAdaptiveThrottle keeps the politeness delay of the hosts answering 429, 503, or slowly
+	noAdaptiveThrottle bool
+	// device is the kind of client the crawler presents itself as: "desktop" or "mobile"
+	device string
+	// userAgent is the template of the crawler's User-Agent; empty uses the default
+	userAgent string
+	// contact is the URL or email address written in place of {contact} in the User-Agent
+	contact string
+	// runID identifies the run; empty generates one
+	runID string
+	// rewriteURLs lists URL rewrite rules, "FROM=TO", applied to the output
+	rewriteURLs stringList
+	// accessRules lists access rules, "PATTERN=LEVEL", tagging the pages
+	accessRules stringList
+	// exports lists exports, "FORMAT=DIR", of the documents in other output formats
+	exports stringList
+	// publish lists the targets the packaged skills are published to
+	publish stringList
+	// plugins lists the commands of the plugins extending the build
+	plugins stringList
+	// llmsTxt is the directory where llms.txt and llms-full.txt are written; empty disables them
+	llmsTxt string
+	// embeddingsProvider embeds the documents for semantic search: "openai" or "hash"; empty disables it
+	embeddingsProvider string
+	// embeddingsURL and embeddingsModel are the endpoint and model of the "openai" provider
+	embeddingsURL   string
+	embeddingsModel string
+	// summariesProvider writes a summary of each document: "openai" or "lead"; empty disables it
+	summariesProvider string
+	// summariesURL and summariesModel are the endpoint and model of the "openai" provider
+	summariesURL   string
+	summariesModel string
+	// translateProvider translates the documents in other languages: "openai" or "deepl"; empty disables it
+	translateProvider string
+	// translateURL and translateModel are the endpoint and model of the provider
+	translateURL   string
+	translateModel string
+	// translateTo is the language documents are translated into; empty means the first locale of the locale priority
+	translateTo string
+	// versionPriority lists the preferred documentation versions; empty crawls every version
+	versionPriority stringList
+	// cacheDir is the on-disk HTTP cache directory; empty means <tempDir>/http-cache
+	cacheDir string
+	// noCache disables the on-disk HTTP and conversion caches
+	noCache bool
+	// reportPath is where the JSON crawl report is written; empty means <tempDir>/crawl-report.json
+	reportPath string
+	// warc is the WARC archive the crawl is recorded into; empty records nothing
+	warc string
+	// contentSelector selects the main content of each page instead of Readability
+	contentSelector string
+	// platform is the extraction profile applied: "auto", "none", or a platform name
+	platform string
+	// stripSelectors match elements removed from every page before conversion
+	stripSelectors selectorList
+	// converters lists converter commands, "MATCH=COMMAND", converting the pages they match
+	converters stringList
+	// tableFallback renders tables that can't be GFM tables: "html" or "list"
+	tableFallback string
+	// tableCSVRows is the row count above which tables are also saved as CSV; 0 disables it
+	tableCSVRows int
+	// admonitions renders note/warning boxes: "github", "blockquote", or "none"
+	admonitions string
+	// dedupeSnippets moves repeated long code samples into shared snippet files
+	dedupeSnippets bool
+	// keepDuplicates keeps pages duplicating another page's content
+	keepDuplicates bool
+	// stitchPages converts each paginated article or listing into one document
+	stitchPages bool
+	// chunkTokens is the token budget per Markdown file; 0 disables chunking
+	chunkTokens int
+	// pageTypes keeps only pages classified as these types; empty keeps all
+	pageTypes stringList
+	// languageFilter handles the pages in another language than their locale: "off", "flag", or "exclude"
+	languageFilter string
+	// downloadAssets saves referenced images and media into the skill's assets/ folder
+	downloadAssets bool
+	// airGapped guarantees the output references nothing external
+	airGapped bool
+	// absoluteLinks keeps links between pages as absolute URLs
+	absoluteLinks bool
+	// signKey is the path of the Ed25519 private key used to sign packages; empty disables signing
+	signKey string
+	// configPath is the config file the options were loaded from, if any
+	configPath string
+	// profile is the config file profile in use
+	profile string
+	// localeCodes and localeAliases are extra locale definitions given inline in the config file
+	localeCodes   []string
+	localeAliases map[string]string
+	// warningLimit is the number of warnings of each kind logged in full; 0 logs all
+	warningLimit int
+	// logLevel is the minimum level of the messages logged: "debug", "info", "warn", or "error"
+	logLevel string
+	// logFormat is the format of the log on standard error: "text" or "json"
+	logFormat string
+	// authHeaders are the resolved credentials of the config file; never log their values
+	authHeaders http.Header
+	// eventsTarget is where NDJSON progress events are streamed (see events.Open); empty disables them
+	eventsTarget string
+	// metricsAddr is the address serving Prometheus metrics of the crawl at /metrics; empty disables them
+	metricsAddr string
+	// progress is how the crawl counters are shown on standard error: "plain", "bar", or "json"; empty follows the log options
+	progress string
+	// update updates the existing skills of the targets instead of regenerating them
+	update bool
+	// offline rebuilds the skills from the crawl in tempDir without network access
+	offline bool
+	// fromWARC is the WARC archive an offline build replays the crawl from; empty uses tempDir
+	fromWARC string
+	// fromMirror is the wget or HTTrack mirror an offline build crawls; empty uses tempDir
+	fromMirror string
+	// timestamp replaces the current time in the output (RFC 3339 or Unix seconds); empty uses SOURCE_DATE_EPOCH or the clock
+	timestamp string
+	// only lists the URL path patterns of the sections to rebuild in the existing skills; implies update
+	only stringList
+	// targets overrides the targets derived from format and global
+	targets []site2skill.Target
+}
+
+// registerFlags defines the generate options on fs. The update and inspect
+// subcommands accept the same options.
+func (o *generateOptions) registerFlags(fs *flag.FlagSet) {
+	fs.StringVar(&o.url, "url", "", "URL of the documentation site (required)")
+	fs.StringVar(&o.skillName, "name", "", "Name of the skill (required)")
+	fs.Var(&o.seeds, "seed", "Another start URL, possibly on another host (e.g., an API reference or a wiki), crawled into the same skill after the URL; each page records its site as source in the frontmatter, and the manifest groups the documents by source (can be repeated or comma-separated)")
+	fs.BoolVar(&o.global, "global", false, "Install to global skills directory (~/.claude/skills or ~/.codex/skills)")
+	fs.StringVar(&o.tempDir, "temp-dir", "build", "Temporary directory for processing")
+	fs.BoolVar(&o.skipFetch, "skip-fetch", false, "Skip the download step (use existing files in temp dir)")
+	fs.BoolVar(&o.clean, "clean", false, "Clean up temporary directory after completion")
+	fs.BoolVar(&o.dryRun, "dry-run", false, "Crawl the site and print the URL, locale, and skill file of every page that would be saved, without writing anything")
+	fs.StringVar(&o.format, "format", "claude", "Output format: claude, codex, or both")
+	fs.StringVar(&o.localePriority, "locale-priority", "en,ja", "Locale priority order (comma-separated, e.g., 'en,ja,zh')")
+	fs.BoolVar(&o.noLocalePriority, "no-locale-priority", false, "Disable locale priority mode")
+	fs.StringVar(&o.localeParam, "locale-param", "", "Query parameter name for locale (e.g., 'hl' for ?hl=ja)")
+	fs.StringVar(&o.localeFile, "locale-file", "", "YAML/JSON file with extra locale codes and aliases (e.g., 'pt-pt', 'en-au: en')")
+	fs.BoolVar(&o.eachLocale, "each-locale", false, "Build one documentation tree per locale of the priority (SKILL/en/docs, SKILL/ja/docs) with locales.json mapping the pages across them")
+	fs.Var(&o.includeFilters, "include", "Include only URLs containing this string (can be repeated or comma-separated)")
+	fs.Var(&o.excludeFilters, "exclude", "Exclude URLs containing this string (can be repeated or comma-separated)")
+	fs.BoolVar(&o.feed, "feed", false, "Treat the URL as an RSS or Atom feed: its entries become documents named after their publication date, with their author and publication time in the frontmatter")
+	fs.BoolVar(&o.feedArticles, "feed-articles", false, "With --feed, download the article page of every entry instead of using the content the feed carries")
+	fs.BoolVar(&o.confluence, "confluence", false, "Treat the URL as a Confluence space (e.g., https://acme.atlassian.net/wiki/spaces/ENG): read its pages through the REST API instead of crawling its web interface, with the token in $"+confluence.TokenEnv+" or auth.confluence_token (EMAIL:API_TOKEN for Confluence Cloud, a personal access token for Data Center)")
+	fs.BoolVar(&o.notion, "notion", false, "Treat the URL as a Notion page (e.g., https://www.notion.so/acme/Wiki-0123...): read it and its child pages through the Notion API instead of crawling notion.so, with the token of an integration the pages are shared with in $"+notion.TokenEnv+" or auth.notion_token")
+	fs.BoolVar(&o.repo, "repo", false, "Treat the URL as a GitHub or GitLab repository: clone it with git and read its README, the Markdown files under docs/, and its wiki instead of crawling its rendered pages")
+	fs.BoolVar(&o.includePDF, "include-pdf", false, "Download the linked PDF documents under the crawl scope, skipped otherwise, and convert their text into Markdown with heading and page structure heuristics")
+	fs.BoolVar(&o.sitemaps, "sitemaps", false, "Once the pages linked from the start URL are crawled, also crawl the pages of its host listed in the sitemaps named by its robots.txt (Sitemap: lines), at depth 1 and within the URL filters")
+	fs.BoolVar(&o.ignoreCanonical, "ignore-canonical", false, "Save every page under its own URL instead of the URL it declares with <link rel=\"canonical\">")
+	fs.Var(&o.resolve, "resolve", "Connect to ADDRESS for HOST:PORT, like curl --resolve (HOST:PORT:ADDRESS; can be repeated)")
+	fs.StringVar(&o.hostHeader, "host-header", "", "Crawl the server of the URL as this host: send it as the Host header and use it in output URLs")
+	fs.BoolVar(&o.sandbox, "sandbox", false, "Only connect to the hosts of the start URLs, --resolve, --host-header, and --allow-host: requests to other hosts fail without connecting, and the addresses of the allowed hosts are resolved once and pinned for the crawl (for unattended crawls, e.g., from CI)")
+	fs.Var(&o.allowHosts, "allow-host", "With --sandbox, also allow this host, as a host name, IP address, or wildcard such as '*.example.com' (can be repeated or comma-separated)")
+	fs.StringVar(&o.recheckAfter, "recheck-after", "", "Take the pages fetched (or found unchanged) less than this long ago from the HTTP cache without requesting them again, in days (7d) or as a duration (12h), so that recurring updates re-check only older pages (default: re-check every page)")
+	fs.DurationVar(&o.maxRuntime, "max-runtime", 0, "Stop the build with an error once it has run this long, e.g., 30m (the report of the interrupted crawl is kept; default: no limit)")
+	fs.StringVar(&o.proxy, "proxy", "", "Send every request through this HTTP, HTTPS, or SOCKS5 proxy, e.g., 'http://proxy.example.com:8080' or 'socks5://127.0.0.1:1080' (default: the HTTP_PROXY and HTTPS_PROXY environment variables)")
+	fs.StringVar(&o.caCert, "ca-cert", "", "Trust the certificate authorities of this PEM file besides those of the system")
+	fs.BoolVar(&o.insecure, "insecure", false, "Skip the verification of TLS certificates, for staging servers with self-signed certificates")
+	fs.StringVar(&o.politeness, "politeness", "", "YAML file giving hosts their own politeness delay, number of requests in flight, time-of-day window, and User-Agent suffix, for multi-site crawls (see the README)")
+	fs.IntVar(&o.maxConnsPerHost, "max-conns-per-host", site2skill.DefaultMaxConnsPerHost, "Connections opened to a host at most, and kept open between requests for reuse")
+	fs.BoolVar(&o.noHTTP2, "no-http2", false, "Disable HTTP/2, otherwise negotiated with the servers supporting it, for servers whose HTTP/2 support is broken")
+	fs.BoolVar(&o.noAdaptiveThrottle, "no-adaptive-throttle", false, "Keep requesting the hosts answering 429 or 503, or much more slowly than usual, at the politeness delay, without retrying those responses (by default, their delay doubles, honoring Retry-After, and recovers gradually)")
+	fs.StringVar(&o.device, "device", site2skill.DeviceDesktop, "Client the crawler presents itself as by its User-Agent: desktop or mobile (some sites serve mobile clients a different, cleaner page structure)")
+	fs.StringVar(&o.userAgent, "user-agent", "", "Template of the User-Agent of every request, robots.txt and assets included, with the placeholders {version}, {contact}, and {run_id}; robots.txt rules are matched against its first word up to a slash (default \"site2skillgo/{version} (+{contact})\")")
+	fs.StringVar(&o.contact, "contact", "", "URL or email address where site operators can reach whoever runs the crawl, written in place of {contact} in the User-Agent (default: the project's site)")
+	fs.StringVar(&o.runID, "run-id", "", "Identifier of the run, logged, recorded in the crawl report, and written in place of {run_id} in the User-Agent (default: the start time and random digits)")
+	fs.Var(&o.rewriteURLs, "rewrite-url", "Rewrite URLs in the output, FROM=TO with FROM a host or URL prefix (e.g., 'staging.docs.internal=docs.example.com'; can be repeated or comma-separated)")
+	fs.Var(&o.accessRules, "access-rule", "Tag the pages whose URL path matches PATTERN with an access level (public, internal, or confidential), PATTERN=LEVEL (e.g., '/internal/**=internal'; can be repeated, first match wins)")
+	fs.StringVar(&o.llmsTxt, "llms-txt", "", "Also write llms.txt (an index of the pages) and llms-full.txt (their content) of the site into this directory")
+	fs.Var(&o.plugins, "plugin", "Run a plugin, an executable speaking the site2skill plugin protocol, to extract, convert, or export pages (command with optional arguments; can be repeated)")
+	fs.Var(&o.publish, "publish", "Publish the packaged skills to a target: s3://BUCKET[/PREFIX], gs://BUCKET[/PREFIX], or git+URL[#BRANCH[:DIR]] (e.g., 'git+https://github.com/acme/skills.git#main'; can be repeated)")
+	fs.Var(&o.exports, "export", "Also write the documents of the skill in an output format (mdx, asciidoc, or markdown) into DIR/docs, FORMAT=DIR (e.g., 'mdx=site/content'; can be repeated)")
+	fs.StringVar(&o.embeddingsProvider, "embeddings", "", "Embed the documents for 'search --semantic' with this provider: openai (an OpenAI-compatible API; key in $"+embeddings.APIKeyEnv+" or $OPENAI_API_KEY) or hash (local, no model)")
+	fs.StringVar(&o.embeddingsURL, "embeddings-url", "", "Base URL of the OpenAI-compatible embeddings API, e.g., 'http://localhost:11434/v1' for a local Ollama (default \""+openai.DefaultURL+"\")")
+	fs.StringVar(&o.embeddingsModel, "embeddings-model", "", "Embedding model of the openai provider (default \""+embeddings.DefaultOpenAIModel+"\")")
+	fs.StringVar(&o.summariesProvider, "summaries", "", "Write a summary of each document into its frontmatter with this provider: openai (an OpenAI-compatible chat API; key in $"+summarize.APIKeyEnv+" or $OPENAI_API_KEY) or lead (local, the first sentences)")
+	fs.StringVar(&o.summariesURL, "summaries-url", "", "Base URL of the OpenAI-compatible chat API, e.g., 'http://localhost:11434/v1' for a local Ollama (default \""+openai.DefaultURL+"\")")
+	fs.StringVar(&o.summariesModel, "summaries-model", "", "Chat model of the openai summaries provider (default \""+summarize.DefaultOpenAIModel+"\")")
+	fs.StringVar(&o.translateProvider, "translate", "", "Translate the documents in another language than --translate-to with this provider: openai (an OpenAI-compatible chat API) or deepl (the DeepL API); key in $"+translate.APIKeyEnv+", else $OPENAI_API_KEY or $DEEPL_AUTH_KEY")
+	fs.StringVar(&o.translateURL, "translate-url", "", "Base URL of the translation API (default \""+openai.DefaultURL+"\" for openai, the DeepL API Free or Pro depending on the key for deepl)")
+	fs.StringVar(&o.translateModel, "translate-model", "", "Chat model of the openai translation provider (default \""+translate.DefaultOpenAIModel+"\")")
+	fs.StringVar(&o.translateTo, "translate-to", "", "Language to translate the documents into with --translate, e.g., ja or pt-BR (default: the first locale of --locale-priority)")
+	fs.Var(&o.versionPriority, "version-priority", "Crawl only the first of these documentation versions (/v2/, /3.11/, /latest/ path segments) that exists (comma-separated, e.g., 'latest,v2')")
+	fs.BoolVar(&o.skipExternalCanonical, "skip-external-canonical", false, "Skip pages whose canonical URL is outside the crawl scope (another host or excluded by filters)")
+	fs.IntVar(&o.maxRedirects, "max-redirects", site2skill.DefaultMaxRedirects, "Redirects followed per request; pages redirected more times, or whose redirects loop, fail")
+	fs.StringVar(&o.maxPageSize, "max-page-size", "", "Skip pages and PDF documents larger than this size, e.g., 10MB (default no limit)")
+	fs.BoolVar(&o.compressCrawl, "compress-crawl", false, "Store the crawled HTML pages gzip-compressed in the temp directory (page.html.gz)")
+	fs.Var(&o.contentTypes, "content-types", "Media types of the responses saved, or all those of a type, such as 'text/*' (comma-separated; default \""+strings.Join(site2skill.DefaultContentTypes, ",")+"\")")
+	fs.Var(&o.linkAttrs, "link-attrs", "Attributes of any element whose values are crawled as links, such as those of client-side routers, besides the href of <a> and <area>, the src of frames, and meta refresh URLs (comma-separated; default \""+strings.Join(site2skill.DefaultLinkAttributes, ",")+"\")")
+	fs.Var(&o.stripParams, "strip-params", "Query parameters removed from the URLs crawled besides \""+strings.Join(site2skill.DefaultStripParams, ",")+"\", as names or patterns such as 'session_*' (comma-separated)")
+	fs.StringVar(&o.trailingSlash, "trailing-slash", site2skill.TrailingSlashKeep, "Trailing slash policy of the URLs crawled: keep, strip (\"/guide/\" is \"/guide\"), or add (\"/guide\" is \"/guide/\")")
+	fs.StringVar(&o.robots, "robots", site2skill.RobotsDefault, "robots.txt policy: strict (disallow the pages of hosts whose robots.txt can't be fetched, failing the crawl of a start URL), default (allow them), or off (don't consult robots.txt, for sites you own)")
+	fs.Var(&o.consentCookies, "consent-cookie", "Cookie sent with every request, such as the one a consent banner sets once accepted, e.g., 'CookieConsent=true' (NAME=VALUE, repeatable or comma-separated)")
+	fs.BoolVar(&o.noMetaRefresh, "no-meta-refresh", false, "Save the pages redirecting with <meta http-equiv=\"refresh\"> as they are instead of crawling the page they redirect to")
+	fs.BoolVar(&o.keepInterstitials, "keep-interstitials", false, "Save the pages that look like consent walls or \"enable JavaScript\" notices instead of skipping them; they are flagged in the crawl report either way")
+	fs.BoolVar(&o.keepNoIndex, "keep-noindex", false, "Save the pages whose <meta name=\"robots\"> or X-Robots-Tag header says noindex instead of skipping them; the links of nofollow pages are never followed")
+	fs.StringVar(&o.maxTotalSize, "max-total-size", "", "Stop the crawl, keeping the pages saved, once it has downloaded this much, e.g., 1GB (default no limit)")
+	fs.StringVar(&o.cacheDir, "cache-dir", "", "HTTP cache directory shared across runs (default \"<temp-dir>/http-cache\")")
+	fs.BoolVar(&o.noCache, "no-cache", false, "Disable the on-disk HTTP and conversion caches")
+	fs.StringVar(&o.reportPath, "report", "", "Path of the JSON crawl report (default \"<temp-dir>/crawl-report.json\")")
+	fs.StringVar(&o.warc, "warc", "", "Also record the requests and responses of the crawl, headers included, into this WARC archive, gzip-compressed if it ends in .gz (e.g., crawl.warc.gz; see 'reconvert --from-warc')")
+	fs.StringVar(&o.contentSelector, "content-selector", "", "CSS selector for the main content (e.g., 'main, article'); default is Readability extraction")
+	fs.StringVar(&o.tableFallback, "table-fallback", converter.TableFallbackHTML, "Rendering of tables that can't be GFM tables (spans, lists in cells): html or list")
+	fs.IntVar(&o.tableCSVRows, "table-csv-rows", site2skill.DefaultTableCSVRows, "Also save tables with more than this many rows as CSV files in assets/ (0 disables)")
+	fs.StringVar(&o.admonitions, "admonitions", converter.AdmonitionStyleGitHub, "Rendering of note/warning/tip boxes: github (> [!NOTE] alerts), blockquote, or none")
+	fs.BoolVar(&o.dedupeSnippets, "dedupe-snippets", false, "Replace code samples of 10+ lines repeated across pages with links to a shared file in snippets/")
+	fs.BoolVar(&o.keepDuplicates, "keep-duplicates", false, "Keep pages whose content is identical or nearly identical to another page instead of merging them into one page with aliases")
+	fs.BoolVar(&o.stitchPages, "stitch-pages", false, "Convert each paginated article or listing, whose pages are linked with rel=\"next\" or numbered with ?page=N, into one document: the content of the following pages is appended to the first")
+	fs.IntVar(&o.chunkTokens, "chunk-tokens", site2skill.DefaultChunkTokens, "Split pages longer than this many tokens into several files along headings (0 disables)")
+	fs.Var(&o.pageTypes, "page-types", "Keep only pages classified as these types: docs, marketing, legal (comma-separated; default keeps all)")
+	fs.StringVar(&o.languageFilter, "language-filter", converter.LanguageFilterOff, "Detect the language of the text of every page and flag (record and warn) or exclude the pages in another language than their locale: off, flag, or exclude")
+	fs.StringVar(&o.platform, "platform", converter.PlatformAuto, "Extraction profile of a documentation platform: auto (detected per page), none, or one of "+strings.Join(site2skill.PlatformNames(), ", "))
+	fs.Var(&o.stripSelectors, "strip-selector", "CSS selector of elements to remove before conversion (e.g., '.ad-banner'; can be repeated)")
+	fs.Var(&o.converters, "converter", "Convert the main content of the pages matching a URL prefix, path pattern, or Content-Type with a command reading HTML and writing Markdown, MATCH=COMMAND (e.g., '/reference/**=pandoc -f html -t gfm'; can be repeated, first match wins)")
+	fs.BoolVar(&o.downloadAssets, "download-assets", false, "Download images and other media into the skill's assets/ folder and link them locally")
+	fs.BoolVar(&o.airGapped, "air-gapped", false, "Neutralize all external links, images, and embeds and fail if any remain")
+	fs.BoolVar(&o.absoluteLinks, "absolute-links", false, "Keep links between pages as absolute URLs instead of relative paths to the converted files, and leave links to pages not crawled unmarked")
+	fs.StringVar(&o.signKey, "sign-key", "", "Private key used to sign each .skill file (see 'site2skillgo keygen')")
+	fs.StringVar(&o.timestamp, "timestamp", "", "Time recorded in the output (fetched_at, manifest, package files) for reproducible builds: RFC 3339 or Unix seconds (default $SOURCE_DATE_EPOCH, else the current time)")
+	fs.Var(&o.only, "only", "Crawl and replace only the pages whose URL path matches this pattern (e.g., '/guides/**'), keeping the rest of the existing skill (can be repeated or comma-separated)")
+	fs.StringVar(&o.eventsTarget, "events", "", "Stream NDJSON progress events to fd:N, unix:PATH, a file, or - for stdout")
+	fs.StringVar(&o.metricsAddr, "metrics-addr", "", "Serve Prometheus metrics of the crawl (pages, errors by class, bytes, queue depth, stage durations) at http://ADDR/metrics while it runs (e.g., ':9090')")
+	fs.StringVar(&o.progress, "progress", "", "Show the pages fetched, queued, and failed, bytes, rate, and ETA of the crawl on stderr: bar (a live status line), plain (a line every few seconds, for logs), or json (NDJSON) (default: bar, json with --log-format json, none with --log-level warn or error)")
+	fs.IntVar(&o.warningLimit, "warning-limit", warnlog.DefaultLimit, "Warnings of each kind shown in full before the rest are summarized (0 shows all)")
+	registerLogFlags(fs, &o.logLevel, &o.logFormat)
+	fs.StringVar(&o.configPath, "config", "", "Config file with generate options (default \""+config.DefaultFileName+"\" when --profile is given)")
+	fs.StringVar(&o.profile, "profile", "", "Profile of the config file to use; flags given on the command line override it")
+}
+
+// loadConfig applies the config file profile selected by --config and
+// --profile, if any, to the options not given on the command line of fs, and
+// resolves its credentials. Exits on error.
+func (o *generateOptions) loadConfig(fs *flag.FlagSet) {
+	if o.configPath == "" && o.profile == "" {
+		return
+	}
+	if o.configPath == "" {
+		o.configPath = config.DefaultFileName
+	}
+	file, err := config.Load(o.configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	profile, err := file.Profile(o.profile)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	o.applyProfile(profile, explicit)
+	if o.authHeaders, err = profile.Auth.Resolve(); err != nil {
+		log.Fatalf("Failed to resolve credentials: %v", err)
+	}
+	if profile.Auth.ConfluenceToken != "" {
+		if o.confluenceToken, err = profile.Auth.ConfluenceToken.Resolve(); err != nil {
+			log.Fatalf("Failed to resolve credentials: auth.confluence_token: %v", err)
 		}
-	} else {
-		// local (default)
-		if format == FormatClaude {
-			skillStructureDir = filepath.Join(".claude", "skills")
-			skillFileDir = skillStructureDir
-		} else if format == FormatCodex {
-			skillStructureDir = filepath.Join(".codex", "skills")
-			skillFileDir = skillStructureDir
+	}
+	if profile.Auth.NotionToken != "" {
+		if o.notionToken, err = profile.Auth.NotionToken.Resolve(); err != nil {
+			log.Fatalf("Failed to resolve credentials: auth.notion_token: %v", err)
+		}
+	}
+}
+
+// applyProfile sets the options configured in a config file profile, except
+// those whose flag was given explicitly on the command line.
+func (o *generateOptions) applyProfile(p config.Profile, explicit map[string]bool) {
+	setString := func(flagName string, dst *string, value string) {
+		if value != "" && !explicit[flagName] {
+			*dst = value
+		}
+	}
+	setBool := func(flagName string, dst *bool, value *bool) {
+		if value != nil && !explicit[flagName] {
+			*dst = *value
 		}
 	}
 
-	return skillStructureDir, skillFileDir
+	setString("url", &o.url, p.URL)
+	if len(p.Seeds) > 0 && !explicit["seed"] {
+		o.seeds = p.Seeds
+	}
+	setString("name", &o.skillName, p.Name)
+	setString("format", &o.format, p.Format)
+	setBool("global", &o.global, p.Global)
+	setString("temp-dir", &o.tempDir, p.TempDir)
+
+	if len(p.Locale.Priority) > 0 && !explicit["locale-priority"] {
+		o.localePriority = strings.Join(p.Locale.Priority, ",")
+	}
+	setBool("no-locale-priority", &o.noLocalePriority, p.Locale.Disabled)
+	setString("locale-param", &o.localeParam, p.Locale.Param)
+	setString("locale-file", &o.localeFile, p.Locale.File)
+	setBool("each-locale", &o.eachLocale, p.Locale.Each)
+	o.localeCodes = p.Locale.Locales
+	o.localeAliases = p.Locale.Aliases
+
+	if len(p.Crawl.Include) > 0 && !explicit["include"] {
+		o.includeFilters = p.Crawl.Include
+	}
+	if len(p.Crawl.Exclude) > 0 && !explicit["exclude"] {
+		o.excludeFilters = p.Crawl.Exclude
+	}
+	setBool("feed", &o.feed, p.Crawl.Feed)
+	setBool("feed-articles", &o.feedArticles, p.Crawl.FeedArticles)
+	setBool("repo", &o.repo, p.Crawl.Repo)
+	setBool("confluence", &o.confluence, p.Crawl.Confluence)
+	setBool("notion", &o.notion, p.Crawl.Notion)
+	setBool("include-pdf", &o.includePDF, p.Crawl.IncludePDF)
+	setBool("sitemaps", &o.sitemaps, p.Crawl.Sitemaps)
+	setBool("ignore-canonical", &o.ignoreCanonical, p.Crawl.IgnoreCanonical)
+	setBool("skip-external-canonical", &o.skipExternalCanonical, p.Crawl.SkipExternalCanonical)
+	if p.Crawl.MaxRedirects != nil && !explicit["max-redirects"] {
+		o.maxRedirects = *p.Crawl.MaxRedirects
+	}
+	setString("max-page-size", &o.maxPageSize, p.Crawl.MaxPageSize)
+	setString("max-total-size", &o.maxTotalSize, p.Crawl.MaxTotalSize)
+	setBool("compress-crawl", &o.compressCrawl, p.Crawl.Compress)
+	if len(p.Crawl.ContentTypes) > 0 && !explicit["content-types"] {
+		o.contentTypes = p.Crawl.ContentTypes
+	}
+	if len(p.Crawl.LinkAttributes) > 0 && !explicit["link-attrs"] {
+		o.linkAttrs = p.Crawl.LinkAttributes
+	}
+	if len(p.Crawl.StripParams) > 0 && !explicit["strip-params"] {
+		o.stripParams = p.Crawl.StripParams
+	}
+	setString("trailing-slash", &o.trailingSlash, p.Crawl.TrailingSlash)
+	setString("robots", &o.robots, p.Crawl.Robots)
+	if len(p.Crawl.ConsentCookies) > 0 && !explicit["consent-cookie"] {
+		o.consentCookies = p.Crawl.ConsentCookies
+	}
+	setBool("no-meta-refresh", &o.noMetaRefresh, p.Crawl.NoMetaRefresh)
+	setBool("keep-interstitials", &o.keepInterstitials, p.Crawl.KeepInterstitials)
+	setBool("keep-noindex", &o.keepNoIndex, p.Crawl.KeepNoIndex)
+	if len(p.Crawl.Resolve) > 0 && !explicit["resolve"] {
+		o.resolve = p.Crawl.Resolve
+	}
+	setString("host-header", &o.hostHeader, p.Crawl.HostHeader)
+	setBool("sandbox", &o.sandbox, p.Crawl.Sandbox)
+	if len(p.Crawl.AllowHosts) > 0 && !explicit["allow-host"] {
+		o.allowHosts = p.Crawl.AllowHosts
+	}
+	if p.Crawl.MaxRuntime != "" && !explicit["max-runtime"] {
+		// The duration was checked when the config file was loaded
+		o.maxRuntime, _ = time.ParseDuration(p.Crawl.MaxRuntime)
+	}
+	setString("recheck-after", &o.recheckAfter, p.Crawl.RecheckAfter)
+	setString("proxy", &o.proxy, p.Crawl.Proxy)
+	setString("ca-cert", &o.caCert, p.Crawl.CACert)
+	setBool("insecure", &o.insecure, p.Crawl.Insecure)
+	setString("politeness", &o.politeness, p.Crawl.Politeness)
+	if p.Crawl.MaxConnsPerHost != nil && !explicit["max-conns-per-host"] {
+		o.maxConnsPerHost = *p.Crawl.MaxConnsPerHost
+	}
+	setBool("no-http2", &o.noHTTP2, p.Crawl.NoHTTP2)
+	setBool("no-adaptive-throttle", &o.noAdaptiveThrottle, p.Crawl.NoAdaptiveThrottle)
+	setString("device", &o.device, p.Crawl.Device)
+	setString("user-agent", &o.userAgent, p.Crawl.UserAgent)
+	setString("contact", &o.contact, p.Crawl.Contact)
+	if len(p.Crawl.VersionPriority) > 0 && !explicit["version-priority"] {
+		o.versionPriority = p.Crawl.VersionPriority
+	}
+
+	setString("content-selector", &o.contentSelector, p.Conversion.ContentSelector)
+	setString("platform", &o.platform, p.Conversion.Platform)
+	setString("table-fallback", &o.tableFallback, p.Conversion.TableFallback)
+	setString("admonitions", &o.admonitions, p.Conversion.Admonitions)
+	if len(p.Conversion.StripSelectors) > 0 && !explicit["strip-selector"] {
+		o.stripSelectors = p.Conversion.StripSelectors
+	}
+	if len(p.Conversion.Converters) > 0 && !explicit["converter"] {
+		o.converters = p.Conversion.Converters
+	}
+
+	setBool("dedupe-snippets", &o.dedupeSnippets, p.Conversion.DedupeSnippets)
+	setBool("keep-duplicates", &o.keepDuplicates, p.Conversion.KeepDuplicates)
+	setBool("stitch-pages", &o.stitchPages, p.Conversion.StitchPages)
+	if p.Conversion.TableCSVRows != nil && !explicit["table-csv-rows"] {
+		o.tableCSVRows = *p.Conversion.TableCSVRows
+	}
+	if p.Conversion.ChunkTokens != nil && !explicit["chunk-tokens"] {
+		o.chunkTokens = *p.Conversion.ChunkTokens
+	}
+	if len(p.Conversion.PageTypes) > 0 && !explicit["page-types"] {
+		o.pageTypes = p.Conversion.PageTypes
+	}
+	setString("language-filter", &o.languageFilter, p.Conversion.LanguageFilter)
+
+	setString("cache-dir", &o.cacheDir, p.Cache.Dir)
+	setBool("no-cache", &o.noCache, p.Cache.Disabled)
+
+	setString("report", &o.reportPath, p.Output.Report)
+	setString("warc", &o.warc, p.Output.WARC)
+	setBool("air-gapped", &o.airGapped, p.Output.AirGapped)
+	setBool("absolute-links", &o.absoluteLinks, p.Output.AbsoluteLinks)
+	setBool("download-assets", &o.downloadAssets, p.Output.DownloadAssets)
+	setString("sign-key", &o.signKey, p.Output.SignKey)
+	if len(p.Output.RewriteURLs) > 0 && !explicit["rewrite-url"] {
+		o.rewriteURLs = p.Output.RewriteURLs
+	}
+	if len(p.Output.AccessRules) > 0 && !explicit["access-rule"] {
+		o.accessRules = p.Output.AccessRules
+	}
+	if len(p.Output.Exports) > 0 && !explicit["export"] {
+		o.exports = p.Output.Exports
+	}
+	if len(p.Output.Publish) > 0 && !explicit["publish"] {
+		o.publish = p.Output.Publish
+	}
+	setString("llms-txt", &o.llmsTxt, p.Output.LLMsTxt)
+	if len(p.Plugins) > 0 && !explicit["plugin"] {
+		o.plugins = p.Plugins
+	}
+	setString("embeddings", &o.embeddingsProvider, p.Output.Embeddings.Provider)
+	setString("embeddings-url", &o.embeddingsURL, p.Output.Embeddings.URL)
+	setString("embeddings-model", &o.embeddingsModel, p.Output.Embeddings.Model)
+	setString("summaries", &o.summariesProvider, p.Output.Summaries.Provider)
+	setString("summaries-url", &o.summariesURL, p.Output.Summaries.URL)
+	setString("summaries-model", &o.summariesModel, p.Output.Summaries.Model)
+	setString("translate", &o.translateProvider, p.Output.Translation.Provider)
+	setString("translate-url", &o.translateURL, p.Output.Translation.URL)
+	setString("translate-model", &o.translateModel, p.Output.Translation.Model)
+	setString("translate-to", &o.translateTo, p.Output.Translation.To)
 }
 
 // executeGenerate performs the complete skill generation pipeline for the given website.
-// It orchestrates all steps: fetching, converting, normalizing, generating, validating, and packaging.
-//
-// Parameters:
-//   - url: The target website URL to scrape
-//   - skillName: The name for the generated skill package
-//   - global: If true, install to global skills directory
-//   - tempDir: Temporary directory for intermediate files
-//   - skipFetch: If true, skip downloading and use existing files in tempDir
-//   - clean: If true, remove temporary directory after completion
-//   - format: Output format ("claude", "codex", or "both")
-//   - localePriority: Comma-separated list of preferred locale codes (e.g., "en,ja")
-//   - noLocalePriority: If true, disable locale priority mode
-//   - localeParam: Query parameter name for locale selection (e.g., "hl" for ?hl=ja)
+// It orchestrates all steps (see site2skill.Build): fetching, converting, normalizing,
+// generating, validating, and packaging.
 //
 // The function logs progress at each step and exits with log.Fatalf on critical errors.
-func executeGenerate(url, skillName string, global bool, tempDir string, skipFetch, clean bool, format, localePriority string, noLocalePriority bool, localeParam string, includeFilters, excludeFilters []string) {
+func executeGenerate(opts generateOptions) {
 	// Check Codex skills configuration if generating codex format
-	if format == FormatCodex || format == FormatBoth {
+	if opts.format == FormatCodex || opts.format == FormatBoth {
 		enabled, configExists, err := checkCodexSkillsConfig()
 		if err != nil {
 			log.Printf("Warning: %v", err)
 		} else if !configExists {
-			codexHome, _ := getCodexHome()
+			codexHome, _ := site2skill.CodexHome()
 			configPath := filepath.Join(codexHome, "config.toml")
 			log.Printf("Info: %s not found. To enable Codex skills, create the file with:", configPath)
 			log.Printf("  [features]")
 			log.Printf("  skills = true")
 		} else if !enabled {
-			codexHome, _ := getCodexHome()
+			codexHome, _ := site2skill.CodexHome()
 			configPath := filepath.Join(codexHome, "config.toml")
 			log.Printf("Info: Codex skills feature is not enabled. To enable it, add the following to %s:", configPath)
 			log.Printf("  [features]")
@@ -343,423 +1018,3954 @@ func executeGenerate(url, skillName string, global bool, tempDir string, skipFet
 		}
 	}
 
-	// Determine output directories based on format and global flag
-	var output, skillOutput string
-
-	if format == FormatBoth {
-		// For both format, we'll handle each format separately in the generation step
-		// Set a placeholder for now
-		output = ""
-		skillOutput = ""
-	} else {
-		output, skillOutput = determineOutputPaths(format, global)
+	// The tokens of the config file win over the environment
+	confluenceToken := opts.confluenceToken
+	if confluenceToken == "" {
+		confluenceToken = os.Getenv(confluence.TokenEnv)
+	}
+	notionToken := opts.notionToken
+	if notionToken == "" {
+		notionToken = os.Getenv(notion.TokenEnv)
 	}
 
-	// Setup directories
-	tempDownloadDir := filepath.Join(tempDir, "download")
-	tempMdDir := filepath.Join(tempDir, "markdown")
-
-	if !skipFetch {
-		if err := os.RemoveAll(tempDir); err != nil && !os.IsNotExist(err) {
-			log.Printf("Warning: could not remove temp dir: %v", err)
-		}
-		if err := os.MkdirAll(tempDownloadDir, 0755); err != nil {
-			log.Fatalf("Failed to create temp download dir: %v", err)
+	// Determine output directories based on format and global flag
+	targets := opts.targets
+	var err error
+	if targets == nil {
+		if targets, err = site2skill.DefaultTargets(opts.format, opts.global); err != nil {
+			log.Fatalf("Failed to determine output directories: %v", err)
 		}
 	}
 
-	if err := os.MkdirAll(tempMdDir, 0755); err != nil {
-		log.Fatalf("Failed to create temp markdown dir: %v", err)
+	cfg := site2skill.Config{
+		URL:                   opts.url,
+		Seeds:                 opts.seeds,
+		SkillName:             opts.skillName,
+		Targets:               targets,
+		TempDir:               opts.tempDir,
+		SkipFetch:             opts.skipFetch,
+		DryRun:                opts.dryRun,
+		Clean:                 opts.clean,
+		Update:                opts.update,
+		Offline:               opts.offline,
+		WARC:                  opts.warc,
+		FromWARC:              opts.fromWARC,
+		FromMirror:            opts.fromMirror,
+		Only:                  opts.only,
+		LocaleParam:           opts.localeParam,
+		LocaleFile:            opts.localeFile,
+		LocaleCodes:           opts.localeCodes,
+		LocaleAliases:         opts.localeAliases,
+		Feed:                  opts.feed,
+		FeedArticles:          opts.feedArticles,
+		Repo:                  opts.repo,
+		Confluence:            opts.confluence,
+		ConfluenceToken:       confluenceToken,
+		Notion:                opts.notion,
+		NotionToken:           notionToken,
+		IncludePDF:            opts.includePDF,
+		Sitemaps:              opts.sitemaps,
+		Include:               opts.includeFilters,
+		Exclude:               opts.excludeFilters,
+		IgnoreCanonical:       opts.ignoreCanonical,
+		SkipExternalCanonical: opts.skipExternalCanonical,
+		MaxRedirects:          opts.maxRedirects,
+		ContentTypes:          opts.contentTypes,
+		LinkAttributes:        opts.linkAttrs,
+		CompressCrawl:         opts.compressCrawl,
+		StripParams:           opts.stripParams,
+		TrailingSlash:         opts.trailingSlash,
+		Robots:                opts.robots,
+		ConsentCookies:        opts.consentCookies,
+		NoMetaRefresh:         opts.noMetaRefresh,
+		KeepInterstitials:     opts.keepInterstitials,
+		KeepNoIndex:           opts.keepNoIndex,
+		Resolve:               opts.resolve,
+		HostHeader:            opts.hostHeader,
+		Sandbox:               opts.sandbox,
+		AllowHosts:            opts.allowHosts,
+		MaxRuntime:            opts.maxRuntime,
+		Proxy:                 opts.proxy,
+		CACert:                opts.caCert,
+		Insecure:              opts.insecure,
+		MaxConnsPerHost:       opts.maxConnsPerHost,
+		NoHTTP2:               opts.noHTTP2,
+		NoAdaptiveThrottle:    opts.noAdaptiveThrottle,
+		Device:                opts.device,
+		UserAgent:             opts.userAgent,
+		Contact:               opts.contact,
+		RunID:                 opts.runID,
+		Versions:              opts.versionPriority,
+		RewriteURLs:           opts.rewriteURLs,
+		AccessRules:           opts.accessRules,
+		Exports:               opts.exports,
+		Plugins:               opts.plugins,
+		LLMsTxt:               opts.llmsTxt,
+		Headers:               opts.authHeaders,
+		CacheDir:              opts.cacheDir,
+		NoCache:               opts.noCache,
+		ReportPath:            opts.reportPath,
+		ContentSelector:       opts.contentSelector,
+		Platform:              opts.platform,
+		StripSelectors:        opts.stripSelectors,
+		Converters:            opts.converters,
+		TableFallback:         opts.tableFallback,
+		TableCSVRows:          opts.tableCSVRows,
+		Admonitions:           opts.admonitions,
+		PageTypes:             opts.pageTypes,
+		LanguageFilter:        opts.languageFilter,
+		ChunkTokens:           opts.chunkTokens,
+		DedupeSnippets:        opts.dedupeSnippets,
+		KeepDuplicates:        opts.keepDuplicates,
+		StitchPages:           opts.stitchPages,
+		DownloadAssets:        opts.downloadAssets,
+		AirGapped:             opts.airGapped,
+		AbsoluteLinks:         opts.absoluteLinks,
 	}
-
-	fetchedAt := time.Now().UTC().Format(time.RFC3339)
-
-	// Step 1: Fetch
-	if !skipFetch {
-		log.Printf("=== Step 1: Fetching %s ===", url)
-		f := fetcher.New(tempDownloadDir)
-
-		// Configure locale priority if enabled
-		if !noLocalePriority {
-			locales := parseLocales(localePriority)
-			cfg := &fetcher.LocaleConfig{
-				Priority:  locales,
-				ParamName: localeParam,
-			}
-			f.SetLocaleConfig(cfg)
-			log.Printf("Locale priority mode enabled: %v", locales)
-			if localeParam != "" {
-				log.Printf("Using query parameter: ?%s=<locale>", localeParam)
-			}
-		}
-
-		if len(includeFilters) > 0 || len(excludeFilters) > 0 {
-			f.SetURLFilters(includeFilters, excludeFilters)
-			if len(includeFilters) > 0 {
-				log.Printf("Include filters: %v", includeFilters)
-			}
-			if len(excludeFilters) > 0 {
-				log.Printf("Exclude filters: %v", excludeFilters)
-			}
-		}
-
-		if err := f.Fetch(url); err != nil {
-			log.Fatalf("Failed to fetch site: %v", err)
-		}
-	} else {
-		log.Printf("=== Step 1: Skipped Fetching (Using %s) ===", tempDownloadDir)
+	if !opts.noLocalePriority {
+		cfg.Locales = parseLocales(opts.localePriority)
+		cfg.EachLocale = opts.eachLocale
+	} else if opts.eachLocale {
+		log.Fatalf("--each-locale requires locale priority mode, which --no-locale-priority turns off")
 	}
-
-	crawlDir := filepath.Join(tempDownloadDir, "crawl")
-
-	// Step 2: Convert HTML to Markdown
-	log.Printf("=== Step 2: Converting HTML to Markdown ===")
-	htmlFiles, err := filepath.Glob(filepath.Join(crawlDir, "**/*.html"))
-	if err != nil {
-		log.Fatalf("Failed to find HTML files: %v", err)
+	timestamp, source := opts.timestamp, "--timestamp"
+	if timestamp == "" {
+		timestamp, source = os.Getenv("SOURCE_DATE_EPOCH"), "SOURCE_DATE_EPOCH"
 	}
-
-	// Better way to find all HTML files recursively
-	htmlFiles = []string{}
-	filepath.Walk(crawlDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() && filepath.Ext(path) == ".html" {
-			htmlFiles = append(htmlFiles, path)
-		}
-		return nil
-	})
-
-	log.Printf("Found %d HTML files.", len(htmlFiles))
-
-	conv := converter.New()
-	for _, htmlFile := range htmlFiles {
-		// Security check
-		absHTMLFile, err := filepath.Abs(htmlFile)
-		if err != nil {
-			log.Printf("Warning: could not get absolute path for %s: %v", htmlFile, err)
-			continue
-		}
-		absCrawlDir, err := filepath.Abs(crawlDir)
-		if err != nil {
-			log.Printf("Warning: could not get absolute path for crawl dir: %v", err)
-			continue
+	if timestamp != "" {
+		if cfg.Timestamp, err = parseTimestamp(timestamp); err != nil {
+			log.Fatalf("Invalid %s: %v", source, err)
 		}
-
-		relPath, err := filepath.Rel(absCrawlDir, absHTMLFile)
-		if err != nil || len(relPath) > 0 && relPath[0] == '.' {
-			log.Printf("Warning: skipping potential path traversal file: %s", htmlFile)
-			continue
-		}
-
-		// Construct source URL
-		sourceURL := reconstructURL(url, relPath)
-
-		// Determine output filename
-		baseName := filepath.Base(htmlFile)
-		nameWithoutExt := baseName[:len(baseName)-len(filepath.Ext(baseName))]
-		mdFilename := sanitizeFilename(nameWithoutExt) + ".md"
-		mdPath := filepath.Join(tempMdDir, mdFilename)
-
-		if _, err := os.Stat(mdPath); err == nil {
-			log.Printf("Warning: name collision for %s. Overwriting.", mdFilename)
+		log.Printf("Timestamp: %s (from %s)", cfg.Timestamp.Format(time.RFC3339), source)
+	}
+	if opts.maxPageSize != "" {
+		if cfg.MaxPageBytes, err = fetcher.ParseSize(opts.maxPageSize); err != nil {
+			log.Fatalf("Invalid --max-page-size: %v", err)
 		}
-
-		if err := conv.ConvertFile(htmlFile, mdPath, sourceURL, fetchedAt); err != nil {
-			log.Printf("Error converting %s: %v", htmlFile, err)
+	}
+	if opts.maxTotalSize != "" {
+		if cfg.MaxTotalBytes, err = fetcher.ParseSize(opts.maxTotalSize); err != nil {
+			log.Fatalf("Invalid --max-total-size: %v", err)
 		}
 	}
-
-	// Step 3: Normalize Markdown
-	log.Printf("=== Step 3: Normalizing Markdown ===")
-	mdFiles, err := filepath.Glob(filepath.Join(tempMdDir, "*.md"))
-	if err != nil {
-		log.Fatalf("Failed to find markdown files: %v", err)
+	if opts.recheckAfter != "" {
+		if cfg.RecheckAfter, err = config.ParseDuration(opts.recheckAfter); err != nil {
+			log.Fatalf("Invalid --recheck-after: %v", err)
+		}
 	}
-
-	norm := normalizer.New()
-	for _, mdFile := range mdFiles {
-		if err := norm.NormalizeFile(mdFile, mdFile); err != nil {
-			log.Printf("Error normalizing %s: %v", mdFile, err)
+	if opts.embeddingsProvider != "" {
+		if cfg.Embedder, err = site2skill.NewEmbedder(opts.embeddingsProvider, opts.embeddingsURL, opts.embeddingsModel); err != nil {
+			log.Fatalf("Invalid --embeddings: %v", err)
 		}
 	}
-
-	// Step 4: Generate Skill Structure
-	type skillInfo struct {
-		dir        string
-		outputPath string
+	if opts.summariesProvider != "" {
+		if cfg.Summarizer, err = site2skill.NewSummarizer(opts.summariesProvider, opts.summariesURL, opts.summariesModel); err != nil {
+			log.Fatalf("Invalid --summaries: %v", err)
+		}
 	}
-	var skills []skillInfo
-
-	if format == FormatBoth {
-		// Generate both Claude and Codex formats
-		for _, fmt := range []string{FormatClaude, FormatCodex} {
-			log.Printf("=== Step 4: Generating Skill Structure (%s format) ===", fmt)
-			fmtOutput, fmtSkillOutput := determineOutputPaths(fmt, global)
-			gen := skillgen.New(fmt)
-			if err := gen.Generate(skillName, tempMdDir, fmtOutput); err != nil {
-				log.Fatalf("Failed to generate skill structure: %v", err)
-			}
-			skills = append(skills, skillInfo{
-				dir:        filepath.Join(fmtOutput, skillName),
-				outputPath: fmtSkillOutput,
-			})
+	if opts.politeness != "" {
+		if cfg.Politeness, err = site2skill.LoadPoliteness(opts.politeness); err != nil {
+			log.Fatalf("Invalid --politeness: %v", err)
 		}
-	} else {
-		log.Printf("=== Step 4: Generating Skill Structure (%s format) ===", format)
-		gen := skillgen.New(format)
-		if err := gen.Generate(skillName, tempMdDir, output); err != nil {
-			log.Fatalf("Failed to generate skill structure: %v", err)
+	}
+	if opts.translateProvider != "" {
+		if cfg.Translator, err = site2skill.NewTranslator(opts.translateProvider, opts.translateURL, opts.translateModel); err != nil {
+			log.Fatalf("Invalid --translate: %v", err)
 		}
-		skills = append(skills, skillInfo{
-			dir:        filepath.Join(output, skillName),
-			outputPath: skillOutput,
-		})
+		cfg.TranslateTo = opts.translateTo
 	}
-
-	// Step 5: Validate Skill
-	log.Printf("=== Step 5: Validating Skill ===")
-	val := validator.New()
-	for _, skill := range skills {
-		if !val.Validate(skill.dir) {
-			log.Printf("Warning: Validation failed for %s. Please check errors.", skill.dir)
+	if opts.signKey != "" {
+		if cfg.SignKey, err = packager.LoadPrivateKey(opts.signKey); err != nil {
+			log.Fatalf("Failed to load signing key: %v", err)
 		}
 	}
-
-	// Step 6: Package Skill
-	log.Printf("=== Step 6: Packaging Skill ===")
-	pkg := packager.New()
-	var skillFiles []string
-	for _, skill := range skills {
-		skillFile, err := pkg.Package(skill.dir, skill.outputPath)
+	for _, target := range opts.publish {
+		p, err := site2skill.NewPublisher(target)
 		if err != nil {
-			log.Fatalf("Failed to package skill: %v", err)
+			log.Fatalf("Invalid --publish: %v", err)
 		}
-		skillFiles = append(skillFiles, skillFile)
-	}
-
-	log.Printf("=== Done! ===")
-	for i, file := range skillFiles {
-		log.Printf("Skill package %d: %s", i+1, file)
+		cfg.Publishers = append(cfg.Publishers, p)
 	}
-
-	// Cleanup
-	if clean {
-		if err := os.RemoveAll(tempDir); err != nil {
-			log.Printf("Warning: could not remove temp dir: %v", err)
+	// By default, the progress follows the log: a bar for text, NDJSON for
+	// JSON, and nothing when info messages are silenced
+	progressMode := opts.progress
+	if progressMode == "" {
+		progressMode = site2skill.ProgressBar
+		if opts.logFormat == logging.FormatJSON {
+			progressMode = site2skill.ProgressJSON
 		}
-		log.Printf("Temporary files removed from %s", tempDir)
-	} else {
-		log.Printf("Temporary files kept in %s", tempDir)
-	}
-}
-
-// reconstructURL reconstructs the original website URL from a crawled file's relative path.
-// It removes the .html extension and prepends the appropriate scheme (http or https).
-//
-// Parameters:
-//   - baseURL: The base URL of the crawled site (used to determine scheme)
-//   - relPath: The relative file path from the crawl directory
-//
-// Returns the reconstructed URL as a string with the format "scheme://path".
-//
-// Example:
-//
-//	baseURL: "https://example.com"
-//	relPath: "docs/api/index.html"
-//	returns: "https://docs/api/index"
-func reconstructURL(baseURL, relPath string) string {
-	// Remove .html extension if present
-	if len(relPath) > 5 && relPath[len(relPath)-5:] == ".html" {
-		relPath = relPath[:len(relPath)-5]
 	}
-
-	// Parse base URL to get scheme
-	scheme := "https"
-	if len(baseURL) > 7 && baseURL[:7] == "http://" {
-		scheme = "http"
-	}
-
-	return fmt.Sprintf("%s://%s", scheme, relPath)
-}
-
-// sanitizeFilename removes invalid characters from a filename to ensure cross-platform compatibility.
-// It replaces any character that is not alphanumeric, dot, underscore, or hyphen with an underscore.
-//
-// Parameters:
-//   - name: The filename to sanitize
-//
-// Returns a sanitized filename safe for use on all major operating systems.
-//
-// Example:
-//
-//	"hello world!.txt" -> "hello_world_.txt"
-//	"file/path\\name" -> "file_path_name"
-func sanitizeFilename(name string) string {
-	// Replace non-alphanumeric characters (except ._-) with _
-	result := ""
-	for _, ch := range name {
-		if (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') ||
-			(ch >= '0' && ch <= '9') || ch == '.' || ch == '_' || ch == '-' {
-			result += string(ch)
-		} else {
-			result += "_"
+	if level, _ := logging.ParseLevel(opts.logLevel); opts.progress != "" || level <= slog.LevelInfo {
+		if cfg.ProgressReporter, err = site2skill.NewProgressReporter(progressMode, os.Stderr); err != nil {
+			log.Fatalf("Invalid --progress: %v", err)
 		}
 	}
-	return result
-}
-
-// parseLocales parses a comma-separated locale string into a slice of locale codes.
-// It trims whitespace from each locale code and filters out empty strings.
-//
-// Parameters:
-//   - s: A comma-separated string of locale codes (e.g., "en, ja, zh")
-//
-// Returns a slice of trimmed, non-empty locale codes.
-// Returns nil if the input string is empty.
-//
-// Example:
-//
-//	"en, ja , zh" -> ["en", "ja", "zh"]
-//	"" -> nil
-func parseLocales(s string) []string {
-	if s == "" {
-		return nil
-	}
-	var result []string
-	for _, part := range splitByComma(s) {
-		trimmed := trimSpace(part)
-		if trimmed != "" {
-			result = append(result, trimmed)
+	if opts.eventsTarget != "" {
+		emitter, err := events.Open(opts.eventsTarget)
+		if err != nil {
+			log.Fatalf("Failed to open event stream: %v", err)
 		}
+		defer emitter.Close()
+		cfg.Progress = emitter.Emit
 	}
-	return result
-}
 
-// splitByComma splits a string into parts separated by comma characters.
-// Unlike strings.Split, this implementation manually iterates through the string.
-//
-// Parameters:
-//   - s: The string to split
-//
-// Returns a slice of substrings separated by commas.
-// Empty parts are included in the result.
-func splitByComma(s string) []string {
-	var result []string
-	start := 0
-	for i := 0; i < len(s); i++ {
-		if s[i] == ',' {
-			result = append(result, s[start:i])
-			start = i + 1
+	ctx, stop := interruptContext()
+	defer stop()
+	if opts.metricsAddr != "" {
+		reg := metrics.NewRegistry()
+		crawl := metrics.NewCrawl(reg)
+		cfg.ProgressReporter = metrics.Reporters(cfg.ProgressReporter, crawl)
+		emit := cfg.Progress
+		cfg.Progress = func(ev site2skill.Event) {
+			crawl.Event(ev)
+			if emit != nil {
+				emit(ev)
+			}
+		}
+		if err := serveMetrics(ctx, opts.metricsAddr, reg); err != nil {
+			log.Fatalf("Failed to serve metrics: %v", err)
 		}
 	}
-	result = append(result, s[start:])
-	return result
-}
-
-// trimSpace removes leading and trailing spaces and tabs from a string.
-// Unlike strings.TrimSpace, this only removes space and tab characters, not all Unicode whitespace.
-//
-// Parameters:
-//   - s: The string to trim
-//
-// Returns the string with leading and trailing spaces and tabs removed.
-func trimSpace(s string) string {
-	start := 0
-	end := len(s)
-	for start < end && (s[start] == ' ' || s[start] == '\t') {
-		start++
+	result, err := site2skill.Build(ctx, cfg)
+	if err != nil {
+		log.Fatalf("Failed to generate skill: %v", err)
 	}
-	for end > start && (s[end-1] == ' ' || s[end-1] == '\t') {
-		end--
+	if opts.dryRun {
+		printPlan(os.Stdout, result.Plan)
+		return
 	}
-	return s[start:end]
-}
-
-type stringList []string
-
-func (s *stringList) String() string {
-	return strings.Join(*s, ",")
-}
 
-func (s *stringList) Set(value string) error {
-	for _, part := range splitByComma(value) {
-		trimmed := trimSpace(part)
-		if trimmed != "" {
-			*s = append(*s, trimmed)
+	log.Printf("=== Done! ===")
+	for i, skill := range result.Skills {
+		log.Printf("Skill package %d: %s (~%d tokens, see %s)", i+1, skill.Package, skill.Tokens,
+			filepath.Join(skill.Dir, skillgen.StatsFile))
+		if skill.Changes != nil {
+			log.Printf("Changes: %s (see %s)", skill.Changes.Summary(), filepath.Join(skill.Dir, skillgen.ChangesFile))
 		}
 	}
-	return nil
 }
 
-// runSearch executes the search subcommand, which searches through skill documentation files
-// for keywords and displays matching results with context.
-//
-// The function parses command-line arguments, performs the search operation, and formats
-// the results either as human-readable text or JSON based on the --json flag.
+// runUpdate executes the update subcommand, which crawls the site of an
+// existing skill again and updates the skill in place: pages whose content
+// hash is unchanged keep their files, and changes.md lists the pages added,
+// modified, and removed (see skillgen.Generator.Update).
 //
-// args should contain the command-line arguments following the "search" subcommand.
-// The function expects the search query as the first positional argument.
-func runSearch(args []string) {
-	fs := flag.NewFlagSet("search", flag.ExitOnError)
-
-	var (
-		skillDir   string
-		maxResults int
-		jsonOutput bool
-	)
+// args should contain the skill directory followed by generate options. The
+// site URL is read from the skill's manifest.json unless --url is given, the
+// skill name is the directory name, and the format is detected from SKILL.md
+// unless --format is given.
+func runUpdate(args []string) {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
 
-	fs.StringVar(&skillDir, "skill-dir", ".", "Path to the skill directory")
-	fs.IntVar(&maxResults, "max-results", 10, "Maximum number of results to display")
-	fs.BoolVar(&jsonOutput, "json", false, "Output results as JSON")
+	var opts generateOptions
+	opts.registerFlags(fs)
 
 	fs.Usage = func() {
-		fmt.Fprintf(os.Stderr, `Usage: site2skillgo search <QUERY> [options]
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo update <SKILL_DIR> [options]
 
-Search through skill documentation files for keywords.
+Crawl the site of a generated skill again and update the skill in place.
+Pages whose content hash is unchanged are kept as they are; added and modified
+pages are converted and copied, removed pages are deleted, and changes.md
+lists the changes. Accepts the options of the generate command, which should
+match those the skill was generated with.
 
 Arguments:
-  QUERY       Search query (space-separated keywords with OR logic)
+  SKILL_DIR     Directory of the skill to update (e.g., .claude/skills/myskill)
 
 Options:
 `)
 		fs.PrintDefaults()
 		fmt.Fprintf(os.Stderr, `
 Examples:
-  site2skillgo search "authentication"
-  site2skillgo search "api endpoint" --max-results 5
-  site2skillgo search "database" --json --skill-dir ./my-skill
+  site2skillgo update .claude/skills/example
+  site2skillgo update .codex/skills/example --exclude beta
+  site2skillgo update .claude/skills/example --only "/guides/**"
+  site2skillgo update skills/example --url https://docs.example.com/ --format claude
+`)
+	}
+
+	// Accept the skill directory before the options, like generate's positional arguments
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		args = append(args[1:], args[0])
+	}
+	fs.Parse(args)
+	opts.loadConfig(fs)
+	setupLogging(opts.logLevel, opts.logFormat)
+	warnlog.SetLimit(opts.warningLimit)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	skillDir := filepath.Clean(fs.Arg(0))
+
+	data, err := os.ReadFile(filepath.Join(skillDir, skillgen.ManifestFile))
+	if err != nil {
+		log.Fatalf("Failed to read skill: %v", err)
+	}
+	var manifest skillgen.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		log.Fatalf("Failed to read skill: invalid %s: %v", skillgen.ManifestFile, err)
+	}
+	if opts.url == "" {
+		opts.url = manifest.URL
+	}
+	if opts.url == "" {
+		log.Fatalf("%s does not record the site URL; pass it with --url", filepath.Join(skillDir, skillgen.ManifestFile))
+	}
+
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = true })
+	if !explicit["format"] {
+		opts.format = FormatCodex
+		if skillMD, err := os.ReadFile(filepath.Join(skillDir, "SKILL.md")); err == nil && strings.HasPrefix(string(skillMD), "---\n") {
+			opts.format = FormatClaude
+		}
+	}
+	if opts.format != FormatClaude && opts.format != FormatCodex {
+		log.Fatalf("Invalid format: %s. Must be 'claude' or 'codex'", opts.format)
+	}
+
+	opts.skillName = filepath.Base(skillDir)
+	opts.targets = []site2skill.Target{{Format: opts.format, Dir: filepath.Dir(skillDir)}}
+	opts.update = true
+	executeGenerate(opts)
+}
+
+// runReconvert executes the reconvert subcommand, which rebuilds a skill from
+// the raw HTML of an earlier crawl kept in the temporary directory, without any
+// network access, for iterating on the conversion options (selectors,
+// chunking, frontmatter, ...) against a finished crawl (see
+// site2skill.Config.Offline).
+//
+// args should contain the skill name followed by generate options. The site
+// URL is read from the crawl report unless --url is given.
+func runReconvert(args []string) {
+	fs := flag.NewFlagSet("reconvert", flag.ExitOnError)
+
+	var opts generateOptions
+	opts.registerFlags(fs)
+	fs.StringVar(&opts.fromWARC, "from-warc", "", "Rebuild from the crawl recorded in this WARC archive (see 'generate --warc') instead of the temporary directory: the crawl runs again against the archive")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo reconvert <SKILL_NAME> [options]
+
+Rebuild a skill from the pages crawled into the temporary directory by an
+earlier generate run (kept unless --clean was given), without any network
+access: only the conversion and the later steps run again. Accepts the options
+of the generate command; change the conversion options (--content-selector,
+--strip-selector, --chunk-tokens, ...) and keep those of the crawl.
+
+With --from-warc, the crawl is replayed from a WARC archive recorded with
+'generate --warc' instead, the archive answering every request.
+
+Requests the build would make, such as --download-assets, are answered from
+the HTTP cache, or fail. Embeddings and summaries are only written with the
+local providers (--embeddings hash, --summaries lead), and documents can't be
+translated (--translate).
+
+Arguments:
+  SKILL_NAME    Name of the skill to rebuild
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo generate https://docs.example.com example
+  site2skillgo reconvert example --content-selector "article.docs"
+  site2skillgo reconvert example --strip-selector ".feedback" --chunk-tokens 8000
+  site2skillgo reconvert example --temp-dir build/example --format codex
+  site2skillgo reconvert example --from-warc crawl.warc.gz --platform sphinx
+`)
+	}
+
+	// Accept the skill name before the options, like generate's positional arguments
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		args = append(args[1:], args[0])
+	}
+	fs.Parse(args)
+	opts.loadConfig(fs)
+	setupLogging(opts.logLevel, opts.logFormat)
+	warnlog.SetLimit(opts.warningLimit)
+
+	if fs.NArg() == 1 {
+		opts.skillName = fs.Arg(0)
+	}
+	if opts.skillName == "" || fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if opts.url == "" && opts.fromWARC != "" {
+		archive, err := warc.Open(opts.fromWARC)
+		if err != nil {
+			log.Fatalf("Failed to read the crawl to rebuild from: %v", err)
+		}
+		opts.url = archive.Info(site2skill.WARCStartURLField)
+		archive.Close()
+		if opts.url == "" {
+			log.Fatalf("%s does not record the URL of its crawl; pass it with --url", opts.fromWARC)
+		}
+	}
+	if opts.url == "" {
+		reportPath := opts.reportPath
+		if reportPath == "" {
+			reportPath = filepath.Join(opts.tempDir, "crawl-report.json")
+		}
+		report, err := fetcher.LoadCrawlReport(reportPath)
+		if err != nil {
+			log.Fatalf("Failed to read the crawl to rebuild from (pass the --temp-dir of the crawl, or its URL with --url): %v", err)
+		}
+		opts.url = report.StartURL
+	}
+	if opts.format != FormatClaude && opts.format != FormatCodex && opts.format != FormatBoth {
+		log.Fatalf("Invalid format: %s. Must be 'claude', 'codex', or 'both'", opts.format)
+	}
+	opts.offline = true
+	executeGenerate(opts)
+}
+
+// runImport executes the import subcommand, which builds a skill from an
+// existing offline copy of a site instead of crawling it: a WARC archive, such
+// as those of wget --warc-file or generate --warc, or a directory mirrored by
+// wget -m or HTTrack (see site2skill.Config.FromWARC and FromMirror). The crawl
+// runs against the copy, without any network access.
+//
+// args should contain the source and the skill name followed by generate
+// options. The site URL is read from the source unless --url is given.
+func runImport(args []string) {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+
+	var opts generateOptions
+	opts.registerFlags(fs)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo import <WARC_FILE|MIRROR_DIR> <SKILL_NAME> [options]
+
+Build a skill from an existing offline copy of a site instead of crawling it,
+without any network access: the crawl runs against the copy, and the
+conversion and skill generation as usual. Accepts the options of the generate
+command.
+
+The copy is a WARC archive (.warc or .warc.gz), such as those written by
+wget --warc-file or generate --warc, or a directory mirrored by wget -m or
+HTTrack, holding a directory per host. Pages missing from the copy fail.
+
+The site URL is the one the archive was recorded from (its first page for
+archives of other tools), or the root of the only host of the mirror; pass
+--url to start elsewhere, or to pick the host and scheme of a mirror.
+
+Arguments:
+  WARC_FILE     WARC archive of the site
+  MIRROR_DIR    Directory mirrored by wget -m or HTTrack
+  SKILL_NAME    Name of the skill to generate
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo import docs.warc.gz example
+  site2skillgo import mirror/ example --url https://docs.example.com/guide/
+  site2skillgo import mirror/ example --content-selector "article.docs" --format codex
+`)
+	}
+
+	// Accept the source and skill name before the options, like generate's
+	// positional arguments
+	n := 0
+	for n < 2 && n < len(args) && !strings.HasPrefix(args[n], "-") {
+		n++
+	}
+	args = append(append([]string(nil), args[n:]...), args[:n]...)
+	fs.Parse(args)
+	opts.loadConfig(fs)
+	setupLogging(opts.logLevel, opts.logFormat)
+	warnlog.SetLimit(opts.warningLimit)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	source := fs.Arg(0)
+	opts.skillName = fs.Arg(1)
+	info, err := os.Stat(source)
+	if err != nil {
+		log.Fatalf("Failed to read the site to import: %v", err)
+	}
+	if info.IsDir() {
+		m, err := mirror.Open(source)
+		if err != nil {
+			log.Fatalf("Failed to read the site to import: %v", err)
+		}
+		opts.fromMirror = source
+		if opts.url == "" {
+			if opts.url = m.StartURL(); opts.url == "" {
+				log.Fatalf("%s mirrors several hosts (%s); pass the URL to start from with --url", source, strings.Join(m.Hosts(), ", "))
+			}
+		}
+	} else {
+		archive, err := warc.Open(source)
+		if err != nil {
+			log.Fatalf("Failed to read the site to import: %v", err)
+		}
+		opts.fromWARC = source
+		if opts.url == "" {
+			opts.url = archive.Info(site2skill.WARCStartURLField)
+		}
+		if opts.url == "" {
+			opts.url = archive.FirstPage()
+		}
+		archive.Close()
+		if opts.url == "" {
+			log.Fatalf("%s holds no HTML page; pass the URL to start from with --url", source)
+		}
+	}
+	if opts.format != FormatClaude && opts.format != FormatCodex && opts.format != FormatBoth {
+		log.Fatalf("Invalid format: %s. Must be 'claude', 'codex', or 'both'", opts.format)
+	}
+	opts.offline = true
+	executeGenerate(opts)
+}
+
+// runBuild executes the build subcommand, which builds several profiles of a
+// config file concurrently, such as a whole skill library refreshed nightly by
+// a single CI job. Each profile is built by a generate process of its own, so
+// that its log, warnings, and failure stay separate, in the temporary directory
+// <temp-dir>/<profile>; the profiles share one HTTP cache. A summary of every
+// profile is printed at the end, and the command fails if any profile failed.
+//
+// args should contain the profile names or --all-profiles, and the options.
+func runBuild(args []string) {
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+
+	var (
+		configPath  string
+		profiles    stringList
+		allProfiles bool
+		jobs        int
+		tempDir     string
+		cacheDir    string
+		only        stringList
+		logLevel    string
+		logFormat   string
+	)
+	fs.StringVar(&configPath, "config", config.DefaultFileName, "Config file defining the profiles")
+	fs.Var(&profiles, "profile", "Profile to build, like the PROFILE arguments (can be repeated or comma-separated)")
+	fs.BoolVar(&allProfiles, "all-profiles", false, "Build every profile of the config file")
+	fs.IntVar(&jobs, "jobs", 4, "Number of profiles built at the same time")
+	fs.StringVar(&tempDir, "temp-dir", "build", "Temporary directory; each profile uses <temp-dir>/<profile>")
+	fs.StringVar(&cacheDir, "cache-dir", "", "HTTP cache directory shared by the profiles (default \"<temp-dir>/http-cache\")")
+	fs.Var(&only, "only", "Rebuild only the sections of the existing skills whose URL path matches this pattern (e.g., '/guides/**'; can be repeated or comma-separated)")
+	registerLogFlags(fs, &logLevel, &logFormat)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo build [PROFILE...] [options]
+       site2skillgo build --profile PROFILE [options]
+       site2skillgo build --all-profiles [options]
+
+Build several profiles of a config file concurrently, each as if by
+'site2skillgo generate --profile PROFILE', and print a combined summary.
+Profiles without a url or name (bases for extends) are skipped with
+--all-profiles. The temp_dir of the profiles is replaced by <temp-dir>/<profile>,
+and profiles without a cache directory share <temp-dir>/http-cache.
+With --only, each profile crawls just the matching sections of its site and
+replaces them in its existing skill, keeping the other pages.
+The log options apply to every profile; the lines of a profile are prefixed
+with its name, or with --log-format json carry it as a "profile" field.
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo build --all-profiles
+  site2skillgo build stripe stripe-ja --config configs/site2skill.yaml
+  site2skillgo build --profile gemini-docs
+  site2skillgo build --all-profiles --jobs 8 --cache-dir /var/cache/site2skill
+  site2skillgo build stripe --only "/guides/**"
+  site2skillgo build --all-profiles --log-level warn --log-format json
+`)
+	}
+
+	positional := parseArgs(fs, args)
+	setupLogging(logLevel, logFormat)
+	names := append(profiles, positional...)
+	if allProfiles == (len(names) > 0) {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if jobs < 1 {
+		log.Fatalf("Invalid --jobs: %d. Must be at least 1", jobs)
+	}
+	for _, pattern := range only {
+		if err := pathglob.Validate(pattern); err != nil {
+			log.Fatalf("Invalid --only: %v", err)
+		}
+	}
+	if cacheDir == "" {
+		cacheDir = filepath.Join(tempDir, "http-cache")
+	}
+
+	file, err := config.Load(configPath)
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if allProfiles {
+		names = file.ProfileNames()
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to locate the site2skillgo executable: %v", err)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	builds := make([]profileBuild, len(names))
+	out := &lockedWriter{w: os.Stderr}
+	slots := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		b := &builds[i]
+		b.name = name
+		p, err := file.Profile(name)
+		if err != nil {
+			b.err = err
+			continue
+		}
+		if p.URL == "" || p.Name == "" {
+			if !allProfiles {
+				b.err = fmt.Errorf("profile %q has no url or name", name)
+			} else {
+				b.skipped = "no url or name"
+			}
+			continue
+		}
+		args := []string{"generate", "--config", configPath, "--profile", name,
+			"--temp-dir", filepath.Join(tempDir, name), "--events", "fd:3",
+			"--log-level", logLevel, "--log-format", logFormat}
+		// A redrawn bar would garble the interleaved output; JSON logs and
+		// silenced info messages get the default progress
+		if level, _ := logging.ParseLevel(logLevel); logFormat != logging.FormatJSON && level <= slog.LevelInfo {
+			args = append(args, "--progress", site2skill.ProgressPlain)
+		}
+		if p.Cache.Dir == "" {
+			args = append(args, "--cache-dir", cacheDir)
+		}
+		for _, pattern := range only {
+			args = append(args, "--only", pattern)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+			if ctx.Err() != nil {
+				b.err = ctx.Err()
+				return
+			}
+			b.run(ctx, exe, args, out, logFormat == logging.FormatJSON)
+		}()
+	}
+	wg.Wait()
+
+	if failed := printBuildSummary(os.Stdout, builds); failed > 0 {
+		log.Fatalf("%d of %d profiles failed", failed, len(builds))
+	}
+}
+
+// profileBuild is the outcome of building one profile with the build command.
+type profileBuild struct {
+	// name is the profile name
+	name string
+	// skipped is why the profile was not built; empty if it was
+	skipped string
+	// err is why the build failed; nil if it succeeded
+	err error
+	// duration is how long the build took
+	duration time.Duration
+	// pages is the number of pages saved by the crawl
+	pages int
+	// packages lists the .skill files produced
+	packages []string
+	// changes counts the pages "added", "modified", and "removed" by an
+	// update; nil if the build generated its skills anew
+	changes map[string]int
+	// published is the number of skills published
+	published int
+}
+
+// run builds the profile by running exe with args, a generate command line
+// streaming its events to file descriptor 3. Output lines of the process are
+// written to out prefixed with the profile name, or if jsonLog is set, the JSON
+// objects with a "profile" field instead. The process is interrupted when ctx
+// is done.
+func (b *profileBuild) run(ctx context.Context, exe string, args []string, out io.Writer, jsonLog bool) {
+	start := time.Now()
+	defer func() { b.duration = time.Since(start) }()
+
+	eventsR, eventsW, err := os.Pipe()
+	if err != nil {
+		b.err = fmt.Errorf("failed to create event pipe: %w", err)
+		return
+	}
+	defer eventsR.Close()
+
+	cmd := exec.CommandContext(ctx, exe, args...)
+	cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+	prefixed := &prefixWriter{prefix: "[" + b.name + "] ", w: out}
+	if jsonLog {
+		name, _ := json.Marshal(b.name)
+		prefixed.jsonField = `"profile":` + string(name) + ","
+	}
+	cmd.Stdout = prefixed
+	cmd.Stderr = prefixed
+	cmd.ExtraFiles = []*os.File{eventsW}
+	if err := cmd.Start(); err != nil {
+		eventsW.Close()
+		b.err = fmt.Errorf("failed to start build: %w", err)
+		return
+	}
+	eventsW.Close()
+
+	// The stream ends when the process exits
+	scanner := bufio.NewScanner(eventsR)
+	scanner.Buffer(make([]byte, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		var ev events.Event
+		if json.Unmarshal(scanner.Bytes(), &ev) != nil || ev.Type != events.StageCompleted {
+			continue
+		}
+		switch ev.Stage {
+		case "fetch":
+			b.pages = ev.Stats[string(fetcher.OutcomeSaved)]
+		case "generate":
+			if _, ok := ev.Stats["added"]; ok {
+				b.changes = map[string]int{"added": ev.Stats["added"], "modified": ev.Stats["modified"], "removed": ev.Stats["removed"]}
+			}
+		case "package":
+			b.packages = ev.Files
+		case "publish":
+			b.published = ev.Stats["skills"]
+		}
+	}
+
+	b.err = cmd.Wait()
+	prefixed.Flush()
+}
+
+// runCron executes the cron subcommand, a daemon updating the skills of the
+// profiles of a config file on their schedule. Each due profile is updated as
+// if by 'site2skillgo generate --profile PROFILE --update', one process per
+// profile like the build command, so the publishers of a profile run only
+// when its skill changed. The time and outcome of the last run of every
+// profile are kept in <temp-dir>/cron-state.json, so that a restarted daemon
+// keeps the schedule.
+func runCron(args []string) {
+	fs := flag.NewFlagSet("cron", flag.ExitOnError)
+
+	var (
+		configPath  string
+		profiles    stringList
+		allProfiles bool
+		every       string
+		jobs        int
+		tempDir     string
+		cacheDir    string
+		once        bool
+		logLevel    string
+		logFormat   string
+	)
+	fs.StringVar(&configPath, "config", config.DefaultFileName, "Config file defining the profiles; read again before every round")
+	fs.Var(&profiles, "profile", "Profile to update, like the PROFILE arguments (can be repeated or comma-separated)")
+	fs.BoolVar(&allProfiles, "all-profiles", false, "Update every profile of the config file")
+	fs.StringVar(&every, "every", "24h", "Schedule of the profiles without a schedule key, in days (7d) or as a duration (6h)")
+	fs.IntVar(&jobs, "jobs", 4, "Number of profiles updated at the same time")
+	fs.StringVar(&tempDir, "temp-dir", "build", "Temporary directory; each profile uses <temp-dir>/<profile>, and the schedule state is kept in <temp-dir>/cron-state.json")
+	fs.StringVar(&cacheDir, "cache-dir", "", "HTTP cache directory shared by the profiles (default \"<temp-dir>/http-cache\")")
+	fs.BoolVar(&once, "once", false, "Update the profiles that are due once and exit, e.g., from a systemd timer or CI schedule")
+	registerLogFlags(fs, &logLevel, &logFormat)
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo cron [PROFILE...] [options]
+       site2skillgo cron --all-profiles [options]
+
+Run as a daemon updating the skills of config file profiles on a schedule.
+Each profile is due once its schedule key (or --every) has passed since its
+last run, and is then updated as if by
+'site2skillgo generate --profile PROFILE --update': the site is crawled again
+through the HTTP cache, with crawl.recheck_after as the freshness window of
+its pages, and only the pages that changed are converted. The publishers of
+the profile (output.publish) run only when the skill changed.
+
+The config file is read again before every round, so edits apply without a
+restart. The last run of every profile is kept in <temp-dir>/cron-state.json,
+so a restarted daemon keeps the schedule. A failed run is retried at the next
+scheduled time.
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo cron --all-profiles
+  site2skillgo cron stripe gemini-docs --every 12h --config configs/site2skill.yaml
+  site2skillgo cron --all-profiles --once --log-format json
+`)
+	}
+
+	positional := parseArgs(fs, args)
+	setupLogging(logLevel, logFormat)
+	names := append(profiles, positional...)
+	if allProfiles == (len(names) > 0) {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if jobs < 1 {
+		log.Fatalf("Invalid --jobs: %d. Must be at least 1", jobs)
+	}
+	defaultEvery, err := config.ParseDuration(every)
+	if err != nil {
+		log.Fatalf("Invalid --every: %v", err)
+	}
+	if cacheDir == "" {
+		cacheDir = filepath.Join(tempDir, "http-cache")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to locate the site2skillgo executable: %v", err)
+	}
+	statePath := filepath.Join(tempDir, "cron-state.json")
+	state, err := loadCronState(statePath)
+	if err != nil {
+		log.Fatalf("Failed to read the schedule state: %v", err)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	var file *config.File
+	for {
+		// Keep the previous config when the file was broken by an edit
+		next, err := config.Load(configPath)
+		switch {
+		case err == nil:
+			file = next
+		case file == nil:
+			log.Fatalf("Failed to load config: %v", err)
+		default:
+			log.Printf("Warning: keeping the previous config: %v", err)
+		}
+		round := names
+		if allProfiles {
+			round = file.ProfileNames()
+		}
+
+		now := time.Now()
+		wake := now.Add(defaultEvery)
+		var due []*profileBuild
+		var dueArgs [][]string
+		for _, name := range round {
+			p, err := file.Profile(name)
+			if err == nil && (p.URL == "" || p.Name == "") {
+				if allProfiles {
+					continue
+				}
+				err = fmt.Errorf("profile %q has no url or name", name)
+			}
+			if err != nil {
+				log.Printf("Warning: skipping %s: %v", name, err)
+				continue
+			}
+			interval := defaultEvery
+			if p.Schedule != "" {
+				// The schedule was checked when the config file was loaded
+				interval, _ = config.ParseDuration(p.Schedule)
+			}
+			if last := state.Profiles[name]; last != nil && now.Before(last.Time.Add(interval)) {
+				if at := last.Time.Add(interval); at.Before(wake) {
+					wake = at
+				}
+				continue
+			}
+			if now.Add(interval).Before(wake) {
+				wake = now.Add(interval)
+			}
+			args := []string{"generate", "--config", configPath, "--profile", name, "--update",
+				"--temp-dir", filepath.Join(tempDir, name), "--events", "fd:3",
+				"--log-level", logLevel, "--log-format", logFormat}
+			if level, _ := logging.ParseLevel(logLevel); logFormat != logging.FormatJSON && level <= slog.LevelInfo {
+				args = append(args, "--progress", site2skill.ProgressPlain)
+			}
+			if p.Cache.Dir == "" {
+				args = append(args, "--cache-dir", cacheDir)
+			}
+			due = append(due, &profileBuild{name: name})
+			dueArgs = append(dueArgs, args)
+		}
+
+		out := &lockedWriter{w: os.Stderr}
+		slots := make(chan struct{}, jobs)
+		var wg sync.WaitGroup
+		for i, b := range due {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				slots <- struct{}{}
+				defer func() { <-slots }()
+				if ctx.Err() != nil {
+					b.err = ctx.Err()
+					return
+				}
+				b.run(ctx, exe, dueArgs[i], out, logFormat == logging.FormatJSON)
+			}()
+		}
+		wg.Wait()
+
+		failed := 0
+		for _, b := range due {
+			// The runs cut short by an interruption are due again on restart
+			if b.err != nil && ctx.Err() != nil {
+				continue
+			}
+			log.Printf("%s: %s", b.name, cronOutcome(b))
+			run := &cronRun{Time: now, Duration: b.duration.Round(time.Second).String(), Changes: b.changes, Published: b.published}
+			if b.err != nil {
+				run.Error = b.err.Error()
+				failed++
+			}
+			state.Profiles[b.name] = run
+		}
+		if len(due) > 0 {
+			if err := saveCronState(statePath, state); err != nil {
+				log.Printf("Warning: failed to save the schedule state: %v", err)
+			}
+		}
+		if ctx.Err() != nil {
+			log.Fatalf("Interrupted")
+		}
+		if once {
+			if len(due) == 0 {
+				log.Printf("No profile is due until %s", wake.Format(time.RFC3339))
+			}
+			if failed > 0 {
+				log.Fatalf("%d of %d profiles failed", failed, len(due))
+			}
+			return
+		}
+
+		log.Printf("Next round at %s", wake.Format(time.RFC3339))
+		timer := time.NewTimer(time.Until(wake))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// cronOutcome describes the outcome of the update of a profile by the cron
+// command for its log.
+func cronOutcome(b *profileBuild) string {
+	var s string
+	switch {
+	case b.err != nil:
+		return fmt.Sprintf("failed after %s: %v", b.duration.Round(time.Second), b.err)
+	case b.changes == nil:
+		s = "generated"
+	case b.changes["added"]+b.changes["modified"]+b.changes["removed"] == 0:
+		s = "unchanged"
+	default:
+		s = fmt.Sprintf("updated: %d added, %d modified, %d removed", b.changes["added"], b.changes["modified"], b.changes["removed"])
+	}
+	s += fmt.Sprintf(" in %s", b.duration.Round(time.Second))
+	switch {
+	case b.published == 1:
+		s += ", published its skill"
+	case b.published > 1:
+		s += fmt.Sprintf(", published its %d skills", b.published)
+	}
+	return s
+}
+
+// cronState is what the cron command keeps of its runs across restarts.
+type cronState struct {
+	// Profiles maps the profile names to their last run.
+	Profiles map[string]*cronRun `json:"profiles"`
+}
+
+// cronRun is the last run of a profile by the cron command.
+type cronRun struct {
+	// Time is when the run started; the next one is due a schedule later.
+	Time time.Time `json:"time"`
+	// Duration is how long the run took.
+	Duration string `json:"duration"`
+	// Error is why the run failed; empty if it succeeded.
+	Error string `json:"error,omitempty"`
+	// Changes counts the pages added, modified, and removed by the update;
+	// absent if the skill was generated anew.
+	Changes map[string]int `json:"changes,omitempty"`
+	// Published is the number of skills published.
+	Published int `json:"published"`
+}
+
+// loadCronState reads the cron state at path; a missing file is an empty state.
+func loadCronState(path string) (*cronState, error) {
+	state := &cronState{Profiles: make(map[string]*cronRun)}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	if state.Profiles == nil {
+		state.Profiles = make(map[string]*cronRun)
+	}
+	return state, nil
+}
+
+// saveCronState writes state to path.
+func saveCronState(path string, state *cronState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// printPlan writes a table of the pages of a dry run to w.
+func printPlan(w io.Writer, plan []site2skill.PlannedPage) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "URL\tLOCALE\tFILE")
+	for _, p := range plan {
+		locale := p.Locale
+		if locale == "" {
+			locale = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", p.URL, locale, p.File)
+	}
+	tw.Flush()
+}
+
+// printBuildSummary writes a table of the profile builds to w and returns the
+// number of failed builds.
+func printBuildSummary(w io.Writer, builds []profileBuild) int {
+	failed := 0
+	fmt.Fprintln(w, "=== Build Summary ===")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROFILE\tSTATUS\tTIME\tPAGES\tPACKAGES")
+	for _, b := range builds {
+		status := "ok"
+		switch {
+		case b.skipped != "":
+			status = "skipped: " + b.skipped
+		case b.err != nil:
+			status = "failed: " + b.err.Error()
+			failed++
+		}
+		packages := strings.Join(b.packages, ", ")
+		if packages == "" {
+			packages = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\n", b.name, status, b.duration.Round(time.Second), b.pages, packages)
+	}
+	tw.Flush()
+	return failed
+}
+
+// lockedWriter serializes writes to w from several goroutines.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (l *lockedWriter) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.w.Write(p)
+}
+
+// prefixWriter writes each complete line written to it to w, preceded by
+// prefix, so that the output of concurrent builds can be told apart. Lines
+// holding a JSON object get jsonField as their first field instead, if set,
+// so that they stay valid JSON.
+type prefixWriter struct {
+	prefix    string
+	jsonField string
+	w         io.Writer
+	// partial holds the last line until its newline is written
+	partial []byte
+}
+
+func (p *prefixWriter) Write(data []byte) (int, error) {
+	p.partial = append(p.partial, data...)
+	for {
+		i := bytes.IndexByte(p.partial, '\n')
+		if i < 0 {
+			break
+		}
+		if _, err := p.w.Write(p.prefixed(p.partial[:i+1])); err != nil {
+			return 0, err
+		}
+		p.partial = p.partial[i+1:]
+	}
+	return len(data), nil
+}
+
+// Flush writes the last line if it has no newline.
+func (p *prefixWriter) Flush() {
+	if len(p.partial) > 0 {
+		p.w.Write(p.prefixed(append(p.partial, '\n')))
+		p.partial = nil
+	}
+}
+
+// prefixed returns line with its prefix or JSON field.
+func (p *prefixWriter) prefixed(line []byte) []byte {
+	if p.jsonField != "" && bytes.HasPrefix(line, []byte("{")) && !bytes.HasPrefix(line, []byte("{}")) {
+		return append([]byte("{"+p.jsonField), line[1:]...)
+	}
+	return append([]byte(p.prefix), line...)
+}
+
+// interruptContext returns a context cancelled on the first interrupt (Ctrl+C)
+// or SIGTERM, so that the command stops its requests in flight, writes what it
+// must (such as the crawl report), and exits cleanly. Signals are then handled
+// as usual again: a second one terminates the process at once. stop releases
+// the signal handler.
+func interruptContext() (ctx context.Context, stop context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			signal.Stop(signals)
+			log.Printf("Received %s: stopping cleanly; interrupt again to quit at once", sig)
+			cancel()
+		case <-ctx.Done():
+			signal.Stop(signals)
+		}
+	}()
+	return ctx, cancel
+}
+
+// serveMetrics serves the metrics of reg at http://addr/metrics until ctx is
+// done, alongside the work of the command.
+//
+// Returns an error if addr can't be listened on.
+func serveMetrics(ctx context.Context, addr string, reg *metrics.Registry) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", reg)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("Metrics server failed: %v", err)
+		}
+	}()
+	log.Printf("Serving metrics at http://%s/metrics", ln.Addr())
+	return nil
+}
+
+// registerLogFlags defines the --log-level and --log-format options on fs,
+// setting level and format.
+func registerLogFlags(fs *flag.FlagSet, level, format *string) {
+	fs.StringVar(level, "log-level", "info", "Minimum level of the log messages: "+strings.Join(logging.Levels, ", ")+" (warn silences the progress messages)")
+	fs.StringVar(format, "log-format", logging.FormatText, "Format of the log on stderr: text, or json (one object per line, for log pipelines)")
+}
+
+// parseArgs parses args, options and positional arguments in any order, with
+// fs, exiting on an invalid option, and returns the positional arguments.
+// The flag package stops at the first positional argument, so that
+// "push FILE REF --tag latest" would otherwise ignore --tag. The arguments
+// after "--" are positional.
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		rest := fs.Args()
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			return append(positional, rest...)
+		}
+		if len(rest) == 0 {
+			return positional
+		}
+		positional, args = append(positional, rest[0]), rest[1:]
+	}
+}
+
+// setupLogging configures the log of the command (see logging.Setup), exiting
+// if level or format is invalid.
+func setupLogging(level, format string) {
+	if err := logging.Setup(os.Stderr, level, format); err != nil {
+		log.Fatalf("Invalid logging options: %v", err)
+	}
+}
+
+// parseTimestamp parses a timestamp given as Unix seconds, like
+// SOURCE_DATE_EPOCH, or in RFC 3339 format, and returns it in UTC.
+func parseTimestamp(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither Unix seconds nor an RFC 3339 time", s)
+	}
+	return t.UTC(), nil
+}
+
+// parseSince parses the --fetched-after time of the search command: an RFC 3339
+// time, a date ("2024-05-01"), or an age relative to now in days ("30d") or as
+// a Go duration ("72h").
+func parseSince(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is neither a time, a date, nor an age like 30d", s)
+}
+
+// parseHalfLife parses the --recency-half-life of the search command: a
+// positive duration in days ("30d") or as a Go duration ("72h").
+func parseHalfLife(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("%q is not a positive duration like 30d or 72h", s)
+}
+
+// parseLocales parses a comma-separated locale string into a slice of locale codes.
+// It trims whitespace from each locale code and filters out empty strings.
+//
+// Parameters:
+//   - s: A comma-separated string of locale codes (e.g., "en, ja, zh")
+//
+// Returns a slice of trimmed, non-empty locale codes.
+// Returns nil if the input string is empty.
+//
+// Example:
+//
+//	"en, ja , zh" -> ["en", "ja", "zh"]
+//	"" -> nil
+func parseLocales(s string) []string {
+	if s == "" {
+		return nil
+	}
+	var result []string
+	for _, part := range splitByComma(s) {
+		trimmed := trimSpace(part)
+		if trimmed != "" {
+			result = append(result, trimmed)
+		}
+	}
+	return result
+}
+
+// splitByComma splits a string into parts separated by comma characters.
+// Unlike strings.Split, this implementation manually iterates through the string.
+//
+// Parameters:
+//   - s: The string to split
+//
+// Returns a slice of substrings separated by commas.
+// Empty parts are included in the result.
+func splitByComma(s string) []string {
+	var result []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == ',' {
+			result = append(result, s[start:i])
+			start = i + 1
+		}
+	}
+	result = append(result, s[start:])
+	return result
+}
+
+// trimSpace removes leading and trailing spaces and tabs from a string.
+// Unlike strings.TrimSpace, this only removes space and tab characters, not all Unicode whitespace.
+//
+// Parameters:
+//   - s: The string to trim
+//
+// Returns the string with leading and trailing spaces and tabs removed.
+func trimSpace(s string) string {
+	start := 0
+	end := len(s)
+	for start < end && (s[start] == ' ' || s[start] == '\t') {
+		start++
+	}
+	for end > start && (s[end-1] == ' ' || s[end-1] == '\t') {
+		end--
+	}
+	return s[start:end]
+}
+
+type stringList []string
+
+func (s *stringList) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringList) Set(value string) error {
+	for _, part := range splitByComma(value) {
+		trimmed := trimSpace(part)
+		if trimmed != "" {
+			*s = append(*s, trimmed)
+		}
+	}
+	return nil
+}
+
+// selectorList is a repeatable flag of CSS selectors. Unlike stringList, values are
+// not split on commas, since a comma is part of selector group syntax.
+type selectorList []string
+
+func (s *selectorList) String() string {
+	return strings.Join(*s, " | ")
+}
+
+func (s *selectorList) Set(value string) error {
+	if value = strings.TrimSpace(value); value != "" {
+		*s = append(*s, value)
+	}
+	return nil
+}
+
+// runSearch executes the search subcommand, which searches through skill documentation files
+// for keywords and displays matching results with context.
+//
+// The function parses command-line arguments, performs the search operation, and formats
+// the results as human-readable text, JSON, Markdown, or CSV based on the --format flag.
+//
+// args should contain the command-line arguments following the "search" subcommand.
+// The function expects the search query as the first positional argument.
+func runSearch(args []string) {
+	fs := flag.NewFlagSet("search", flag.ExitOnError)
+
+	var (
+		skillDir     string
+		maxResults   int
+		jsonOutput   bool
+		format       string
+		matchAll     bool
+		regex        bool
+		fuzzy        bool
+		stem         bool
+		stopWords    string
+		accessLevels stringList
+		locale       string
+		pathPrefix   string
+		fetchedAfter string
+		tag          string
+		halfLife     string
+		fieldWeights string
+		workers      int
+		contextLines int
+		highlight    bool
+		semantic     bool
+		groupBy      string
+		perGroup     int
+		capabilities bool
+		selfTest     bool
+	)
+
+	fs.StringVar(&skillDir, "skill-dir", ".", "Path to the skill directory")
+	fs.IntVar(&maxResults, "max-results", 10, "Maximum number of results to display (of sections with --group-by)")
+	fs.StringVar(&groupBy, "group-by", "", "Group the results under the navigation sections of the skill: section")
+	fs.IntVar(&perGroup, "per-group", 3, "Number of top results shown per section with --group-by (0 shows them all)")
+	fs.IntVar(&contextLines, "context-lines", 2, "Number of lines shown before and after the matched lines (0 shows the matched lines only)")
+	fs.BoolVar(&highlight, "highlight", false, "Highlight the matched terms: in color, or between « and » with --json")
+	fs.BoolVar(&semantic, "semantic", false, "Also find the documents similar in meaning to the query with the skill's embeddings (see 'index embed'), ranked with the keyword matches")
+	fs.StringVar(&format, "format", search.OutputText, "Output format: text, json (versioned, with the query, total, and elapsed time), markdown (a table linking to the sources, for agent prompts), or csv")
+	fs.BoolVar(&jsonOutput, "json", false, "Output results as JSON (same as --format json)")
+	fs.BoolVar(&matchAll, "all", false, "Require every keyword and phrase (AND) instead of any (OR)")
+	fs.BoolVar(&regex, "regex", false, "Treat the query as a Go regular expression matched line by line (case-sensitive; prefix (?i) to ignore case)")
+	fs.BoolVar(&fuzzy, "fuzzy", false, "Tolerate typos: keywords of 4+ characters also match words within 1 edit (2 from 8 characters)")
+	fs.BoolVar(&stem, "stem", false, "Match keywords with the words sharing their stem in each document's language and ignore stop words (English only)")
+	fs.StringVar(&stopWords, "stop-words", "", "YAML/JSON file mapping languages to stop word lists that replace the built-in ones (with --stem)")
+	fs.Var(&accessLevels, "access", "Search only the documents of these access levels: public, internal, confidential (comma-separated; default: every document)")
+	fs.StringVar(&locale, "locale", "", "Search only the documents of this locale (e.g., 'en' also matches 'en-US')")
+	fs.StringVar(&pathPrefix, "path-prefix", "", "Search only the documents whose source URL path is under this path (e.g., '/api/**')")
+	fs.StringVar(&fetchedAfter, "fetched-after", "", "Search only the documents fetched after this time: RFC 3339, a date (2024-05-01), or an age (30d, 72h)")
+	fs.StringVar(&tag, "tag", "", "Search only the documents with this tag")
+	fs.StringVar(&halfLife, "recency-half-life", "", "Boost recently modified or fetched documents: halve the score of a result for every this much older it is than the most recent one, in days (30d) or as a duration (72h)")
+	fs.StringVar(&fieldWeights, "field-weights", "", "Weights of the matches in each field of a document in the ranking, as FIELD=WEIGHT pairs of title, summary, headings (H1 and H2), and body (default title=3,summary=2,headings=2,body=1)")
+	fs.IntVar(&workers, "workers", 0, "Number of documents read and matched concurrently when the skill's index isn't used (0 means the number of CPUs, at least 4)")
+	fs.BoolVar(&capabilities, "capabilities", false, "Print the supported query syntax as JSON and exit")
+	fs.BoolVar(&selfTest, "self-test", false, "Verify every supported query construct against a built-in corpus and exit")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo search <QUERY> [options]
+
+Search through skill documentation files for keywords.
+
+Arguments:
+  QUERY       Search query: space-separated keywords and "quoted phrases" with OR logic;
+              prefix a term with + to require it or - to exclude it
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo search "authentication"
+  site2skillgo search "api endpoint" --max-results 5
+  site2skillgo search '"rate limit" +token -deprecated'
+  site2skillgo search "webhook retry" --all
+  site2skillgo search 'OnErrorResume(Next)?' --regex
+  site2skillgo search "authetication" --fuzzy
+  site2skillgo search "configuring the proxy" --stem
+  site2skillgo search --semantic "how do I rotate credentials"
+  site2skillgo search --access public,internal "deploy"
+  site2skillgo search --path-prefix "/api/**" --fetched-after 30d "rate limit"
+  site2skillgo search --recency-half-life 90d "migration guide"
+  site2skillgo search --field-weights title=5,headings=2 "webhooks"
+  site2skillgo search --context-lines 0 --highlight "timeout"
+  site2skillgo search --group-by section --per-group 2 "config"
+  site2skillgo search "database" --json --skill-dir ./my-skill
+  site2skillgo search "rate limit" --format markdown --max-results 5
+  site2skillgo search "deprecated" --format csv > results.csv
+  site2skillgo search --capabilities --skill-dir ./my-skill
+`)
+	}
+
+	fs.Parse(args)
+
+	if capabilities {
+		caps := search.GetCapabilities(skillDir, nil)
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(caps); err != nil {
+			log.Fatalf("Failed to format JSON output: %v", err)
+		}
+		return
+	}
+
+	if selfTest {
+		runSearchSelfTest()
+		return
+	}
+
+	if fs.NArg() < 1 {
+		fmt.Fprintf(os.Stderr, "Error: search query is required\n\n")
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	query := fs.Arg(0)
+
+	var stopWordLists map[string][]string
+	if stopWords != "" {
+		var err error
+		if stopWordLists, err = search.LoadStopWords(stopWords); err != nil {
+			log.Fatalf("Failed to load stop words: %v", err)
+		}
+	}
+
+	for _, level := range accessLevels {
+		if err := access.ValidateLevel(level); err != nil {
+			log.Fatalf("Invalid --access: %v", err)
+		}
+	}
+
+	if contextLines < 0 {
+		log.Fatalf("Invalid --context-lines: must not be negative")
+	}
+	outputFormat, err := search.ParseOutputFormat(format)
+	if err != nil {
+		log.Fatalf("Invalid --format: %v", err)
+	}
+	if jsonOutput {
+		if outputFormat != search.OutputText && outputFormat != search.OutputJSON {
+			log.Fatalf("--json can't be combined with --format %s", outputFormat)
+		}
+		outputFormat = search.OutputJSON
+	}
+	if groupBy != "" && outputFormat != search.OutputText && outputFormat != search.OutputJSON {
+		log.Fatalf("--group-by supports the text and json formats only")
+	}
+	if groupBy != "" && groupBy != search.GroupBySectionName {
+		log.Fatalf("Invalid --group-by: %q (expected %s)", groupBy, search.GroupBySectionName)
+	}
+	if perGroup < 0 {
+		log.Fatalf("Invalid --per-group: must not be negative")
+	}
+	if contextLines == 0 {
+		// SearchOptions takes 0 as the default window
+		contextLines = -1
+	}
+
+	var since time.Time
+	if fetchedAfter != "" {
+		var err error
+		if since, err = parseSince(fetchedAfter, time.Now()); err != nil {
+			log.Fatalf("Invalid --fetched-after: %v", err)
+		}
+	}
+	var weights search.FieldWeights
+	if fieldWeights != "" {
+		if weights, err = search.ParseFieldWeights(fieldWeights); err != nil {
+			log.Fatalf("Invalid --field-weights: %v", err)
+		}
+	}
+	var recencyHalfLife time.Duration
+	if halfLife != "" {
+		var err error
+		if recencyHalfLife, err = parseHalfLife(halfLife); err != nil {
+			log.Fatalf("Invalid --recency-half-life: %v", err)
+		}
+	}
+
+	opts := search.SearchOptions{
+		SkillDir:        skillDir,
+		Query:           query,
+		MatchAll:        matchAll,
+		Regex:           regex,
+		Fuzzy:           fuzzy,
+		Stem:            stem,
+		StopWords:       stopWordLists,
+		Access:          accessLevels,
+		Locale:          locale,
+		PathPrefix:      pathPrefix,
+		FetchedAfter:    since,
+		Tag:             tag,
+		RecencyHalfLife: recencyHalfLife,
+		FieldWeights:    weights,
+		MaxResults:      maxResults,
+		Workers:         workers,
+		ContextLines:    contextLines,
+		Highlight:       highlight,
+		Semantic:        semantic,
+		Format:          outputFormat,
+	}
+
+	if groupBy != "" {
+		// Every match counts toward the totals of the sections
+		opts.MaxResults = 0
+	}
+
+	// Interrupting cancels the scan and the requests to the embeddings provider
+	ctx, stop := interruptContext()
+	defer stop()
+	start := time.Now()
+	results, err := search.SearchDocsContext(ctx, opts)
+	if err != nil {
+		log.Fatalf("Search failed: %v", err)
+	}
+	elapsed := time.Since(start)
+
+	if groupBy != "" {
+		groups, err := search.GroupBySection(skillDir, results, perGroup)
+		if err != nil {
+			log.Fatalf("Failed to group results: %v", err)
+		}
+		if maxResults > 0 && len(groups) > maxResults {
+			groups = groups[:maxResults]
+		}
+		if outputFormat == search.OutputJSON {
+			if err := search.FormatGroupsJSON(groups); err != nil {
+				log.Fatalf("Failed to format JSON output: %v", err)
+			}
+		} else {
+			search.FormatGroups(groups, query)
+		}
+		return
+	}
+
+	env := search.NewResultsEnvelope(results, query, elapsed)
+	// An identifier query also names the symbols declared with it
+	if env.Symbols, err = search.LookupSymbols(opts); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to look up symbols: %v\n", err)
+	}
+	if len(results) == 0 {
+		// Suggest how to fix the query rather than leave the caller guessing
+		if env.Diagnosis, err = search.DiagnoseQuery(ctx, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to diagnose the query: %v\n", err)
+		}
+	}
+	if err := search.WriteResults(os.Stdout, outputFormat, env); err != nil {
+		log.Fatalf("Failed to format %s output: %v", outputFormat, err)
+	}
+}
+
+// runSearchSelfTest runs the query syntax self-test and prints one line per feature.
+// It exits with a non-zero status if any advertised feature doesn't behave as documented.
+func runSearchSelfTest() {
+	results, err := search.SelfTest(context.Background())
+	if err != nil {
+		log.Fatalf("Self-test failed: %v", err)
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Passed {
+			fmt.Printf("PASS  %s\n", r.Feature)
+		} else {
+			failed++
+			fmt.Printf("FAIL  %s: %s\n", r.Feature, r.Detail)
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d query syntax checks failed\n", failed, len(results))
+		os.Exit(1)
+	}
+}
+
+// runRelated executes the related subcommand, which lists the documents of a
+// skill most similar to a given one (see search.Searcher.Related), by term
+// vectors or, with --semantic, by embeddings.
+//
+// args should contain the command-line arguments following the "related" subcommand.
+func runRelated(args []string) {
+	fs := flag.NewFlagSet("related", flag.ExitOnError)
+	var (
+		skillDir     string
+		maxResults   int
+		semantic     bool
+		jsonOutput   bool
+		accessLevels stringList
+		locale       string
+		pathPrefix   string
+		tag          string
+	)
+	fs.StringVar(&skillDir, "skill-dir", ".", "Path to the skill directory")
+	fs.IntVar(&maxResults, "max-results", 10, "Maximum number of documents to display")
+	fs.BoolVar(&semantic, "semantic", false, "Compare the documents by the embeddings of the skill (see 'index embed') instead of their words")
+	fs.BoolVar(&jsonOutput, "json", false, "Output the documents as JSON")
+	fs.Var(&accessLevels, "access", "Consider only the documents of these access levels: public, internal, confidential (comma-separated; default: every document)")
+	fs.StringVar(&locale, "locale", "", "List only the documents of this locale (e.g., 'en' also matches 'en-US')")
+	fs.StringVar(&pathPrefix, "path-prefix", "", "List only the documents whose source URL path is under this path (e.g., '/api/**')")
+	fs.StringVar(&tag, "tag", "", "List only the documents with this tag")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo related <DOC_PATH> [options]
+
+List the documents of a skill most similar to the document at DOC_PATH
+(e.g., docs/guide/install.md), most similar first: by the words they share,
+weighted by how rare they are across the skill, or with --semantic by the
+embeddings of their sections.
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo related docs/guide/install.md
+  site2skillgo related --semantic --max-results 5 docs/api/auth.md
+  site2skillgo related --json --skill-dir .claude/skills/myskill docs/index.md
+`)
+	}
+
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	docPath := fs.Arg(0)
+	for _, level := range accessLevels {
+		if err := access.ValidateLevel(level); err != nil {
+			log.Fatalf("Invalid --access: %v", err)
+		}
+	}
+
+	searcher, err := search.NewSearcher(skillDir)
+	if err != nil {
+		log.Fatalf("Failed to open skill: %v", err)
+	}
+	ctx, stop := interruptContext()
+	defer stop()
+	related, err := searcher.Related(ctx, docPath, search.SearchOptions{
+		Semantic:   semantic,
+		Access:     accessLevels,
+		Locale:     locale,
+		PathPrefix: pathPrefix,
+		Tag:        tag,
+		MaxResults: maxResults,
+	})
+	if err != nil {
+		log.Fatalf("Failed to find related documents: %v", err)
+	}
+
+	if jsonOutput {
+		if related == nil {
+			related = []search.RelatedDocument{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(related); err != nil {
+			log.Fatalf("Failed to format JSON output: %v", err)
+		}
+		return
+	}
+	if len(related) == 0 {
+		fmt.Printf("No documents related to %s.\n", docPath)
+		return
+	}
+	fmt.Printf("Documents related to %s:\n\n", docPath)
+	for i, r := range related {
+		fmt.Printf("%d. %s (%.2f)\n", i+1, r.Path, r.Similarity)
+		fmt.Printf("   %s", r.Title)
+		if r.SourceURL != "" && r.SourceURL != "Unknown" {
+			fmt.Printf(" | %s", r.SourceURL)
+		}
+		fmt.Println()
+	}
+}
+
+// runHash executes the hash subcommand, which prints the content hash of every
+// document of a skill and of the whole corpus (see skillgen.HashDocuments), so
+// that schedulers can tell whether an update changed the skill before running
+// the pipelines downstream of it.
+//
+// args should contain the command-line arguments following the "hash" subcommand.
+func runHash(args []string) {
+	fs := flag.NewFlagSet("hash", flag.ExitOnError)
+	var jsonOutput, quiet bool
+	fs.BoolVar(&jsonOutput, "json", false, "Output the hashes as JSON")
+	fs.BoolVar(&quiet, "quiet", false, "Print only the corpus hash")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo hash [options] [SKILL_DIR]
+
+Print the content hash of the whole skill (default ".") and of every document
+in its docs/ directory, without crawling or rebuilding anything. The hashes
+leave out the fetched_at of the documents, so they only change when the
+content of the skill does: compare the corpus hash before and after an update
+to decide whether to run the pipelines downstream of the skill.
+
+The first line is the corpus hash; the next ones are the hashes of the
+documents, sorted by path.
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo hash .claude/skills/myskill
+  site2skillgo hash --json .claude/skills/myskill
+  before=$(site2skillgo hash --quiet skills/example)
+  site2skillgo update skills/example
+  [ "$(site2skillgo hash --quiet skills/example)" != "$before" ] && ./publish.sh
+`)
+	}
+
+	fs.Parse(args)
+	if fs.NArg() > 1 || (jsonOutput && quiet) {
+		fs.Usage()
+		os.Exit(1)
+	}
+	skillDir := "."
+	if fs.NArg() == 1 {
+		skillDir = fs.Arg(0)
+	}
+
+	hashes, err := skillgen.HashDocuments(skillDir)
+	if err != nil {
+		log.Fatalf("Failed to hash skill: %v", err)
+	}
+	switch {
+	case jsonOutput:
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(hashes); err != nil {
+			log.Fatalf("Failed to format JSON output: %v", err)
+		}
+	case quiet:
+		fmt.Println(hashes.Corpus)
+	default:
+		fmt.Printf("%s  (corpus)\n", hashes.Corpus)
+		for _, d := range hashes.Documents {
+			fmt.Printf("%s  %s\n", d.Hash, d.Path)
+		}
+	}
+}
+
+// runDiff executes the diff subcommand, which compares two generated
+// versions of a skill (see skillgen.DiffSkills) and reports the documents
+// added, modified, and removed, optionally with their unified diffs, so that
+// a regenerated skill can be reviewed before it is published.
+//
+// args should contain the command-line arguments following the "diff" subcommand.
+func runDiff(args []string) {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	var jsonOutput, patch, exitCode bool
+	fs.BoolVar(&jsonOutput, "json", false, "Output the report as JSON")
+	fs.BoolVar(&patch, "patch", false, "Also print the unified diff of every document added, modified, or removed")
+	fs.BoolVar(&exitCode, "exit-code", false, "Exit with status 1 if the skills differ")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo diff [options] <OLD_SKILL_DIR> <NEW_SKILL_DIR>
+
+Compare two versions of a skill, e.g., the published one and a regenerated
+one, without crawling anything. Documents are matched by path and compared by
+their content hashes (see 'hash'), which leave out fetched_at, so pages
+fetched again without change aren't reported. The titles and source URLs of
+the documents come from the manifests, and the sources that moved from the
+lockfiles.
+
+Each changed document is printed as a status (A for added, M for modified, D
+for removed), its path, and its title, followed by a summary. With --patch,
+the unified diffs of their files follow, as git diff prints them.
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo diff published/example .claude/skills/example
+  site2skillgo diff --patch published/example .claude/skills/example | less
+  site2skillgo diff --json published/example .claude/skills/example
+  site2skillgo diff --exit-code published/example build/example || ./review.sh
+`)
+	}
+
+	// Accept the skill directories before the options
+	var dirs []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		dirs, args = append(dirs, args[0]), args[1:]
+	}
+	fs.Parse(args)
+	dirs = append(dirs, fs.Args()...)
+	if len(dirs) != 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	diff, err := skillgen.DiffSkills(dirs[0], dirs[1], patch)
+	if err != nil {
+		log.Fatalf("Failed to compare skills: %v", err)
+	}
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diff); err != nil {
+			log.Fatalf("Failed to format JSON output: %v", err)
+		}
+	} else {
+		for _, c := range diff.Sources {
+			fmt.Printf("Source %s\n", c)
+		}
+		for _, section := range []struct {
+			status string
+			docs   []skillgen.DocumentDiff
+		}{
+			{"A", diff.Added},
+			{"M", diff.Modified},
+			{"D", diff.Removed},
+		} {
+			for _, d := range section.docs {
+				fmt.Printf("%s  %s  %s", section.status, d.Path, d.Title)
+				if d.URL != "" {
+					fmt.Printf(" | %s", d.URL)
+				}
+				fmt.Println()
+			}
+		}
+		fmt.Println(diff.Summary())
+		if patch {
+			for _, docs := range [][]skillgen.DocumentDiff{diff.Added, diff.Modified, diff.Removed} {
+				for _, d := range docs {
+					fmt.Printf("\n%s", d.Patch)
+				}
+			}
+		}
+	}
+	if exitCode && diff.Changed() {
+		os.Exit(1)
+	}
+}
+
+// runCheck executes the check subcommand, which checks the links and images
+// of the Markdown files of a skill that point inside it, and the heading
+// anchors of their fragments (see links.Check), and exits with status 1 if any
+// is broken, so that a skill can be trusted offline before it is published.
+//
+// args should contain the command-line arguments following the "check" subcommand.
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	var jsonOutput bool
+	fs.BoolVar(&jsonOutput, "json", false, "Output the report as JSON")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo check [options] [SKILL_DIR]
+
+Check the links and images of SKILL.md and the documents of a skill (default
+".") that point inside the skill: each must reach a file of the skill, and
+each fragment a heading (or an element with that id) of the document it
+points to. Links to absolute URLs are counted but not checked, as are those
+marked as pointing to pages of the site that aren't in the skill.
+
+Each broken link is printed as FILE:LINE: TARGET (REASON), where REASON is
+missing_file, missing_anchor, outside_skill, or invalid, followed by a
+summary. Exits with status 1 if any link is broken.
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo check .claude/skills/myskill
+  site2skillgo check --json .claude/skills/myskill
+  site2skillgo generate https://docs.example.com example && site2skillgo check .claude/skills/example
+`)
+	}
+
+	// Accept the skill directory before the options
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		args = append(args[1:], args[0])
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	skillDir := "."
+	if fs.NArg() == 1 {
+		skillDir = fs.Arg(0)
+	}
+
+	report, err := links.Check(skillDir)
+	if err != nil {
+		log.Fatalf("Failed to check skill: %v", err)
+	}
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("Failed to format JSON output: %v", err)
+		}
+	} else {
+		for _, b := range report.Broken {
+			fmt.Println(b)
+		}
+		fmt.Printf("%d documents, %d links checked, %d broken, %d external (%d not included in the skill)\n",
+			report.Documents, report.Links, len(report.Broken), report.External, report.Uncrawled)
+	}
+	if len(report.Broken) > 0 {
+		os.Exit(1)
+	}
+}
+
+// runValidate executes the validate subcommand, which checks a skill
+// directory against the skill schema (see validator.Check), optionally with
+// size limits and required frontmatter fields of its own, prints every issue,
+// and exits with status 1 if any is an error, or a warning with --strict, so
+// that CI pipelines can gate the publication of skills.
+//
+// args should contain the command-line arguments following the "validate" subcommand.
+func runValidate(args []string) {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	var jsonOutput, strict bool
+	var maxSize, maxFileSize, require string
+	var maxDocs int
+	fs.BoolVar(&jsonOutput, "json", false, "Output the report as JSON")
+	fs.BoolVar(&strict, "strict", false, "Fail on warnings as well as errors")
+	fs.StringVar(&maxSize, "max-size", "", "Largest total size of the skill's files (e.g., 8MB; default: no limit)")
+	fs.StringVar(&maxFileSize, "max-file-size", "", "Largest size of a document (e.g., 500KB; default: no limit)")
+	fs.IntVar(&maxDocs, "max-docs", 0, "Largest number of documents (default: no limit)")
+	fs.StringVar(&require, "require", strings.Join(validator.DefaultSchema().RequiredFields, ","), "Comma-separated frontmatter fields every document must have")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo validate [options] [SKILL_DIR]
+
+Check that a skill (default ".") is complete and well-formed before it is
+published:
+
+  - SKILL.md exists, with the name and description of the skill in its
+    frontmatter (a warning for Codex skills, which have none)
+  - manifest.json exists, is valid JSON, and lists documents that exist
+  - every document of docs/ has a frontmatter with the required fields and a
+    body, and isn't truncated (unterminated frontmatter or code block, or
+    invalid UTF-8)
+  - the links and images pointing inside the skill reach existing files
+    (broken heading anchors are warnings)
+  - the skill is within the size limits given
+
+Each issue is printed as FILE: SEVERITY: MESSAGE, followed by a summary.
+Exits with status 1 if any issue is an error, or any at all with --strict.
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo validate .claude/skills/myskill
+  site2skillgo validate --max-size 8MB --max-file-size 1MB --strict .claude/skills/myskill
+  site2skillgo validate --json --require title,source_url,description .claude/skills/myskill
+`)
+	}
+
+	// Accept the skill directory before the options
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		args = append(args[1:], args[0])
+	}
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	skillDir := "."
+	if fs.NArg() == 1 {
+		skillDir = fs.Arg(0)
+	}
+
+	schema := validator.Schema{MaxDocuments: maxDocs}
+	for _, field := range strings.Split(require, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			schema.RequiredFields = append(schema.RequiredFields, field)
+		}
+	}
+	var err error
+	if maxSize != "" {
+		if schema.MaxTotalBytes, err = fetcher.ParseSize(maxSize); err != nil {
+			log.Fatalf("Invalid --max-size: %v", err)
+		}
+	}
+	if maxFileSize != "" {
+		if schema.MaxFileBytes, err = fetcher.ParseSize(maxFileSize); err != nil {
+			log.Fatalf("Invalid --max-file-size: %v", err)
+		}
+	}
+	v := validator.New()
+	v.SetSchema(schema)
+
+	report, err := v.Check(skillDir)
+	if err != nil {
+		log.Fatalf("Failed to validate skill: %v", err)
+	}
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Fatalf("Failed to format JSON output: %v", err)
+		}
+	} else {
+		for _, issue := range report.Issues {
+			fmt.Println(issue)
+		}
+		fmt.Printf("%d documents, %s: %d errors, %d warnings\n",
+			report.Documents, progress.FormatBytes(report.Bytes), report.Errors(), report.Warnings())
+	}
+	if report.Errors() > 0 || strict && report.Warnings() > 0 {
+		os.Exit(1)
+	}
+}
+
+// runExport executes the export subcommand, which writes the documents of a
+// skill directory (default ".") as one file, Markdown or JSON Lines (see
+// skillgen.WriteBundle), to standard output or --output.
+func runExport(args []string) {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	var format, output string
+	fs.StringVar(&format, "format", skillgen.BundleMarkdown, "Format of the bundle: "+strings.Join(skillgen.BundleFormats, " or "))
+	fs.StringVar(&output, "output", "", "File to write the bundle to (default: standard output)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo export [options] [SKILL_DIR]
+
+Write every document of a skill (default ".") into a single file, in the order
+of its manifest, to feed the whole skill into embedding pipelines or
+fine-tuning jobs:
+
+  markdown  The documents one after another, each under an H1 with its title
+            and a "Source:" line with its URL
+  jsonl     One JSON object per line and document, with its frontmatter
+            fields, "path", "section", and its Markdown body as "content"
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo export .claude/skills/myskill > myskill.md
+  site2skillgo export --format jsonl --output myskill.jsonl .claude/skills/myskill
+`)
+	}
+
+	fs.Parse(args)
+	if fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	skillDir := "."
+	if fs.NArg() == 1 {
+		skillDir = fs.Arg(0)
+	}
+
+	var b bytes.Buffer
+	n, err := skillgen.WriteBundle(skillDir, &b, format)
+	if err != nil {
+		log.Fatalf("Failed to export skill: %v", err)
+	}
+	if output == "" {
+		if _, err := os.Stdout.Write(b.Bytes()); err != nil {
+			log.Fatalf("Failed to export skill: %v", err)
+		}
+		return
+	}
+	if err := os.WriteFile(output, b.Bytes(), 0644); err != nil {
+		log.Fatalf("Failed to export skill: %v", err)
+	}
+	log.Printf("Exported %d documents to %s", n, output)
+}
+
+// runIndex executes the index subcommand. "index optimize" rebuilds the search index
+// of a skill directory (default ".") from its docs/ and reports the size savings;
+// "index watch" updates it incrementally whenever docs/ changes, until interrupted;
+// "index embed" computes its embeddings for semantic search.
+func runIndex(args []string) {
+	fs := flag.NewFlagSet("index", flag.ExitOnError)
+	var provider, baseURL, model string
+	var interval time.Duration
+	fs.DurationVar(&interval, "interval", time.Second, "How often watch checks the documents for changes")
+	fs.StringVar(&provider, "embeddings", "", "Provider of embed: openai (an OpenAI-compatible API; key in $"+embeddings.APIKeyEnv+" or $OPENAI_API_KEY) or hash (local, no model) (default: the skill's current provider, else openai)")
+	fs.StringVar(&baseURL, "embeddings-url", "", "Base URL of the OpenAI-compatible embeddings API, e.g., 'http://localhost:11434/v1' for a local Ollama (default \""+openai.DefaultURL+"\")")
+	fs.StringVar(&model, "embeddings-model", "", "Embedding model of the openai provider (default \""+embeddings.DefaultOpenAIModel+"\")")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo index optimize [SKILL_DIR]
+       site2skillgo index watch [--interval DURATION] [SKILL_DIR]
+       site2skillgo index embed [options] [SKILL_DIR]
+
+optimize rebuilds the search index of a skill (default ".") from its docs/
+directory, dropping the documents removed since it was built and indexing those
+added or edited by hand, so searches use the index again.
+
+watch keeps the search index of a skill up to date until interrupted: whenever
+documents of its docs/ directory are added, regenerated, or removed, only those
+are indexed again, so searches (and 'serve' and 'mcp') reflect the latest
+content without a full rebuild.
+
+embed computes the embeddings of the sections of the documents of a skill for
+'search --semantic', embedding again only the sections changed since the
+skill was last embedded with the same provider and model.
+
+Options of watch and embed:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo index optimize
+  site2skillgo index optimize .claude/skills/myskill
+  site2skillgo index watch --interval 5s .claude/skills/myskill
+  site2skillgo index embed .claude/skills/myskill
+  site2skillgo index embed --embeddings openai --embeddings-url http://localhost:11434/v1 --embeddings-model nomic-embed-text .claude/skills/myskill
+`)
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() < 1 || (fs.Arg(0) != "optimize" && fs.Arg(0) != "watch" && fs.Arg(0) != "embed") {
+		fs.Usage()
+		os.Exit(1)
+	}
+	command := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+
+	skillDir := "."
+	if fs.NArg() >= 1 {
+		skillDir = fs.Arg(0)
+	}
+
+	switch command {
+	case "embed":
+		runIndexEmbed(skillDir, provider, baseURL, model)
+		return
+	case "watch":
+		if interval <= 0 {
+			log.Fatalf("Invalid --interval: must be positive")
+		}
+		ctx, stop := interruptContext()
+		defer stop()
+		log.Printf("Watching %s for changes", filepath.Join(skillDir, "docs"))
+		if err := watchIndex(ctx, skillDir, interval); err != nil {
+			log.Fatalf("Failed to watch index: %v", err)
+		}
+		return
+	}
+
+	res, err := search.OptimizeIndex(skillDir)
+	if err != nil {
+		log.Fatalf("Failed to optimize index: %v", err)
+	}
+
+	status := "up to date"
+	switch {
+	case res.SizeBefore == 0:
+		status = "missing"
+	case res.Stale:
+		status = "out of date"
+	}
+	fmt.Printf("Indexed %d documents (%d added, %d removed; the old index was %s)\n", res.Documents, res.Added, res.Removed, status)
+	fmt.Printf("Index size: %s -> %s (saved %s)\n", progress.FormatBytes(res.SizeBefore), progress.FormatBytes(res.SizeAfter), progress.FormatBytes(max(0, res.SizeBefore-res.SizeAfter)))
+}
+
+// watchIndex keeps the search index of the skill in skillDir up to date,
+// checking its documents every interval, until ctx is cancelled (see
+// search.WatchIndex), logging the updates that change it and those that
+// fail. It returns nil once ctx is cancelled.
+func watchIndex(ctx context.Context, skillDir string, interval time.Duration) error {
+	err := search.WatchIndex(ctx, skillDir, interval, func(u *search.IndexUpdate, err error) {
+		switch {
+		case err != nil:
+			log.Printf("Failed to update index: %v; retrying on the next change", err)
+		case u.Rebuilt:
+			log.Printf("Indexed %d documents", u.Documents)
+		case u.Changed():
+			log.Printf("Updated index: %d documents (%d added, %d updated, %d removed)", u.Documents, u.Added, u.Updated, u.Removed)
+		}
+	})
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// serveWatchIndex runs watchIndex alongside a server of the skill in skillDir,
+// logging a failure to watch without stopping the server: searches then fall
+// back to scanning the documents changed since the index was last updated.
+func serveWatchIndex(ctx context.Context, skillDir string, interval time.Duration) {
+	if err := watchIndex(ctx, skillDir, interval); err != nil {
+		log.Printf("Stopped watching the documents: %v", err)
+	}
+}
+
+// runMCP executes the mcp subcommand, which serves the search and the
+// documents of a skill to agents over the Model Context Protocol: over stdio
+// by default, for agents launching it as a subprocess, or over HTTP with
+// Server-Sent Events with --sse.
+//
+// args should contain the command-line arguments following the "mcp" subcommand.
+func runMCP(args []string) {
+	fs := flag.NewFlagSet("mcp", flag.ExitOnError)
+	var (
+		sseAddr       string
+		metricsAddr   string
+		accessLevels  stringList
+		watchDocs     bool
+		watchInterval time.Duration
+	)
+	fs.StringVar(&sseAddr, "sse", "", "Serve over HTTP with Server-Sent Events on this address (e.g., ':8765') instead of stdio")
+	fs.StringVar(&metricsAddr, "metrics-addr", "", "Serve Prometheus metrics (tool calls, searches, latencies, and with --sse HTTP requests) at http://ADDR/metrics (e.g., ':9090')")
+	fs.BoolVar(&watchDocs, "watch", false, "Keep the search index up to date as documents are added, regenerated, or removed (see 'index watch')")
+	fs.DurationVar(&watchInterval, "watch-interval", time.Second, "How often --watch checks the documents for changes")
+	fs.Var(&accessLevels, "access", "Serve only the documents of these access levels: public, internal, confidential (comma-separated; default: every document)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo mcp [SKILL_DIR] [options]
+
+Serve a skill (default ".") to agents over the Model Context Protocol, with
+the tools search_docs (keyword search, like the search command), get_doc
+(read a document or a range of its lines), related_docs (the documents similar
+to one, like the related command), and list_docs (list the documents).
+
+By default the server speaks over stdin and stdout, for agents launching it as
+a subprocess. With --sse, it listens on an HTTP address: clients open the event
+stream at /sse and post their messages to the endpoint it announces.
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo mcp .claude/skills/myskill
+  site2skillgo mcp .claude/skills/myskill --access public
+  site2skillgo mcp .claude/skills/myskill --sse localhost:8765
+  site2skillgo mcp .claude/skills/myskill --watch
+  site2skillgo mcp .claude/skills/myskill --sse :8765 --metrics-addr :9090
+
+Claude Code:
+  claude mcp add myskill -- site2skillgo mcp /path/to/.claude/skills/myskill
+`)
+	}
+
+	// Accept the skill directory before the options
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		args = append(args[1:], args[0])
+	}
+	fs.Parse(args)
+
+	skillDir := "."
+	if fs.NArg() >= 1 {
+		skillDir = fs.Arg(0)
+	}
+	for _, level := range accessLevels {
+		if err := access.ValidateLevel(level); err != nil {
+			log.Fatalf("Invalid --access: %v", err)
+		}
+	}
+
+	if watchDocs && watchInterval <= 0 {
+		log.Fatalf("Invalid --watch-interval: must be positive")
+	}
+
+	server, err := mcp.NewServer(skillDir, accessLevels)
+	if err != nil {
+		log.Fatalf("Failed to open skill: %v", err)
+	}
+
+	if metricsAddr != "" {
+		metricsCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		reg := metrics.NewRegistry()
+		server.SetMetrics(metrics.NewServer(reg))
+		if err := serveMetrics(metricsCtx, metricsAddr, reg); err != nil {
+			log.Fatalf("Failed to serve metrics: %v", err)
+		}
+	}
+
+	if sseAddr == "" {
+		watchCtx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		if watchDocs {
+			go serveWatchIndex(watchCtx, skillDir, watchInterval)
+		}
+		// The server stops when the agent closes stdin, or on interrupt
+		if err := server.ServeStdio(context.Background(), os.Stdin, os.Stdout); err != nil {
+			log.Fatalf("MCP server failed: %v", err)
+		}
+		return
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	if watchDocs {
+		go serveWatchIndex(ctx, skillDir, watchInterval)
+	}
+
+	httpServer := &http.Server{Addr: sseAddr, Handler: server.Handler()}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+	log.Printf("Serving %s over MCP at http://%s/sse", skillDir, sseAddr)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("MCP server failed: %v", err)
+	}
+}
+
+// runServe executes the serve subcommand, which serves the search, the
+// documents, and the manifest of a skill over an HTTP JSON API, for a team
+// hosting one skill centrally and querying it from several agents.
+//
+// args should contain the command-line arguments following the "serve" subcommand.
+func runServe(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	var (
+		addr          string
+		metricsAddr   string
+		accessLevels  stringList
+		watchDocs     bool
+		watchInterval time.Duration
+	)
+	fs.StringVar(&addr, "addr", "localhost:8080", "Address to listen on (e.g., ':8080' for every interface)")
+	fs.StringVar(&metricsAddr, "metrics-addr", "", "Serve Prometheus metrics (HTTP requests, searches, and their latencies) at http://ADDR/metrics (e.g., ':9090')")
+	fs.BoolVar(&watchDocs, "watch", false, "Keep the search index up to date as documents are added, regenerated, or removed (see 'index watch')")
+	fs.DurationVar(&watchInterval, "watch-interval", time.Second, "How often --watch checks the documents for changes")
+	fs.Var(&accessLevels, "access", "Serve only the documents of these access levels: public, internal, confidential (comma-separated; default: every document)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo serve [SKILL_DIR] [options]
+
+Serve a skill (default ".") over an HTTP JSON API:
+
+  GET /search?q=QUERY   Search the documents, like the search command
+  GET /docs             List the documents
+  GET /docs/{path}      Read a document, e.g., /docs/guide/install.md
+  GET /related/{path}   List the documents similar to one, like the related command
+  GET /manifest         Read the skill's manifest.json
+  GET /capabilities     Describe the query syntax and limits, like search --capabilities
+
+/search takes the parameters all, regex, fuzzy, stem (true or false),
+max_results (default 10, at most 100), and context_lines; /related takes semantic and
+max_results; /search, /docs, and /related take the filters locale,
+path_prefix, tag, and fetched_after (RFC 3339 time or YYYY-MM-DD date).
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo serve .claude/skills/myskill
+  site2skillgo serve .claude/skills/myskill --addr :8080 --access public
+  site2skillgo serve .claude/skills/myskill --watch
+  site2skillgo serve .claude/skills/myskill --metrics-addr :9090
+  curl 'http://localhost:8080/search?q=install&max_results=3'
+`)
+	}
+
+	// Accept the skill directory before the options
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		args = append(args[1:], args[0])
+	}
+	fs.Parse(args)
+
+	skillDir := "."
+	if fs.NArg() >= 1 {
+		skillDir = fs.Arg(0)
+	}
+	for _, level := range accessLevels {
+		if err := access.ValidateLevel(level); err != nil {
+			log.Fatalf("Invalid --access: %v", err)
+		}
+	}
+
+	if watchDocs && watchInterval <= 0 {
+		log.Fatalf("Invalid --watch-interval: must be positive")
+	}
+
+	server, err := api.NewServer(skillDir, accessLevels)
+	if err != nil {
+		log.Fatalf("Failed to open skill: %v", err)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	if metricsAddr != "" {
+		reg := metrics.NewRegistry()
+		server.SetMetrics(metrics.NewServer(reg))
+		if err := serveMetrics(ctx, metricsAddr, reg); err != nil {
+			log.Fatalf("Failed to serve metrics: %v", err)
+		}
+	}
+	if watchDocs {
+		go serveWatchIndex(ctx, skillDir, watchInterval)
+	}
+
+	httpServer := &http.Server{Addr: addr, Handler: server}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+	log.Printf("Serving %s at http://%s", skillDir, addr)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatalf("Server failed: %v", err)
+	}
+}
+
+// runDev executes the dev subcommand, which serves a local copy of a site over
+// HTTP, generates its skill, and generates it again whenever the files of the
+// site or the watched files (e.g., a config file of conversion rules) change,
+// for iterating on conversion rules against a local site.
+//
+// args should contain the source directory and the skill name, the dev
+// options, and the generate options after "--". Each build runs as a separate
+// generate process, so that a failed build is reported and the next change
+// tries again.
+func runDev(args []string) {
+	fs := flag.NewFlagSet("dev", flag.ExitOnError)
+	var (
+		port     int
+		path     string
+		interval time.Duration
+		tempDir  string
+		watched  stringList
+	)
+	fs.IntVar(&port, "port", 8080, "Port the site is served on, at http://localhost:PORT")
+	fs.StringVar(&path, "path", "/", "Path of the page the crawl starts from")
+	fs.DurationVar(&interval, "interval", time.Second, "How often the files are checked for changes")
+	fs.StringVar(&tempDir, "temp-dir", "build", "Temporary directory of the builds, which isn't watched")
+	fs.Var(&watched, "watch", "Also rebuild when these files or directories change, e.g., the config file of --config (can be repeated or comma-separated)")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo dev <SOURCE_DIR> <SKILL_NAME> [options] [-- GENERATE_OPTIONS]
+
+Serve SOURCE_DIR at http://localhost:PORT, generate the skill SKILL_NAME from it
+as 'site2skillgo generate http://localhost:PORT/PATH SKILL_NAME --no-cache
+GENERATE_OPTIONS' would, and generate it again whenever a file of SOURCE_DIR or
+of --watch changes, until interrupted. Hidden files and directories (.git,
+.claude) and --temp-dir aren't watched, so the output can be written inside
+SOURCE_DIR.
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo dev ./site example
+  site2skillgo dev ./public example --port 9000 --path /docs/
+  site2skillgo dev ./site example --watch site2skill.yaml -- --config site2skill.yaml --profile example
+`)
+	}
+
+	var generateArgs []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, generateArgs = args[:i], args[i+1:]
+			break
+		}
+	}
+	// Accept the source directory and skill name before the options
+	var positional []string
+	for len(args) > 0 && len(positional) < 2 && !strings.HasPrefix(args[0], "-") {
+		positional, args = append(positional, args[0]), args[1:]
+	}
+	fs.Parse(args)
+	positional = append(positional, fs.Args()...)
+	if len(positional) != 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	sourceDir, skillName := positional[0], positional[1]
+	if info, err := os.Stat(sourceDir); err != nil || !info.IsDir() {
+		log.Fatalf("Invalid SOURCE_DIR: %s is not a directory", sourceDir)
+	}
+	if interval <= 0 {
+		log.Fatalf("Invalid --interval: must be positive")
+	}
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to locate the site2skillgo executable: %v", err)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", port))
+	if err != nil {
+		log.Fatalf("Failed to listen: %v", err)
+	}
+	httpServer := &http.Server{Handler: http.FileServer(http.Dir(sourceDir))}
+	go httpServer.Serve(listener)
+	defer httpServer.Close()
+	siteURL := fmt.Sprintf("http://localhost:%d%s", port, path)
+	log.Printf("Serving %s at %s", sourceDir, siteURL)
+
+	// The HTTP cache would serve the pages of the previous build
+	args = append([]string{"generate", "--temp-dir", tempDir, "--no-cache"}, generateArgs...)
+	args = append(args, siteURL, skillName)
+	build := func() {
+		start := time.Now()
+		cmd := exec.CommandContext(ctx, exe, args...)
+		cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Build failed after %s: %v; waiting for changes", time.Since(start).Round(time.Millisecond), err)
+			}
+			return
+		}
+		log.Printf("Build done in %s; waiting for changes", time.Since(start).Round(time.Millisecond))
+	}
+
+	paths := append([]string{sourceDir}, watched...)
+	exclude := []string{tempDir}
+	snapshot, err := watch.Take(paths, exclude)
+	if err != nil {
+		log.Fatalf("Failed to watch files: %v", err)
+	}
+	build()
+	for {
+		next, changed, err := watch.Wait(ctx, paths, exclude, snapshot, interval)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Fatalf("Failed to watch files: %v", err)
+		}
+		snapshot = next
+		if len(changed) == 1 {
+			log.Printf("Changed: %s; rebuilding", changed[0])
+		} else {
+			log.Printf("Changed: %s and %d other files; rebuilding", changed[0], len(changed)-1)
+		}
+		build()
+	}
+}
+
+// runIndexEmbed computes the embeddings of the skill in skillDir with the
+// given provider, endpoint, and model, reusing the vectors of its current
+// embeddings for unchanged sections. An empty provider keeps the provider,
+// endpoint, and model of the current embeddings, or uses openai if there are none.
+func runIndexEmbed(skillDir, provider, baseURL, model string) {
+	prev, err := search.LoadEmbeddings(skillDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Warning: ignoring the current embeddings: %v", err)
+	}
+	var embedder site2skill.Embedder
+	switch {
+	case provider == "" && prev != nil:
+		embedder, err = embeddings.New(prev.Spec, embeddings.APIKey())
+	case provider == "":
+		provider = embeddings.ProviderOpenAI
+		fallthrough
+	default:
+		embedder, err = site2skill.NewEmbedder(provider, baseURL, model)
+	}
+	if err != nil {
+		log.Fatalf("Invalid --embeddings: %v", err)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	stats, err := search.BuildEmbeddings(ctx, skillDir, embedder, prev)
+	if err != nil {
+		log.Fatalf("Failed to embed documents: %v", err)
+	}
+	fmt.Printf("Embedded %d sections of %d documents with %s (%d unchanged)\n", stats.Chunks, stats.Documents, embedder.Spec(), stats.Reused)
+}
+
+// runInspect executes the inspect subcommand, which fetches one page (through
+// the HTTP cache) and prints how it is converted: the detected generator, the
+// extraction method, the elements matched by the selector rules and stripped as
+// boilerplate, the extracted metadata, and the final Markdown.
+//
+// args should contain the URL and generate options, of which the conversion,
+// request, and cache options apply, so a page converted badly by generate can
+// be inspected with the same options or profile.
+func runInspect(args []string) {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+
+	var opts generateOptions
+	opts.registerFlags(fs)
+	var jsonOutput bool
+	fs.BoolVar(&jsonOutput, "json", false, "Output the inspection as JSON")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo inspect <URL> [options]
+
+Fetch one page (or read it from the HTTP cache) and show how it is converted:
+the detected generator, how the main content was extracted, the elements
+matched by the content and strip selectors and removed as boilerplate, the
+extracted title and metadata, and the final Markdown. Accepts the options of
+the generate command; the conversion, request, and cache options apply.
+
+Arguments:
+  URL           Page to inspect
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo inspect https://docs.example.com/guide/install
+  site2skillgo inspect https://docs.example.com/guide/install --content-selector "div.markdown-body"
+  site2skillgo inspect https://docs.example.com/guide/install --strip-selector ".feedback" --json
+  site2skillgo inspect https://docs.example.com/guide/install --profile example
+`)
+	}
+
+	// Accept the URL before the options, like generate's positional arguments
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		args = append(args[1:], args[0])
+	}
+	fs.Parse(args)
+	opts.loadConfig(fs)
+	setupLogging(opts.logLevel, opts.logFormat)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	cfg := site2skill.Config{
+		TempDir:            opts.tempDir,
+		CacheDir:           opts.cacheDir,
+		NoCache:            opts.noCache,
+		Headers:            opts.authHeaders,
+		Resolve:            opts.resolve,
+		HostHeader:         opts.hostHeader,
+		Sandbox:            opts.sandbox,
+		AllowHosts:         opts.allowHosts,
+		Proxy:              opts.proxy,
+		CACert:             opts.caCert,
+		Insecure:           opts.insecure,
+		MaxConnsPerHost:    opts.maxConnsPerHost,
+		NoHTTP2:            opts.noHTTP2,
+		NoAdaptiveThrottle: opts.noAdaptiveThrottle,
+		ConsentCookies:     opts.consentCookies,
+		Device:             opts.device,
+		UserAgent:          opts.userAgent,
+		Contact:            opts.contact,
+		RunID:              opts.runID,
+		ContentSelector:    opts.contentSelector,
+		Platform:           opts.platform,
+		StripSelectors:     opts.stripSelectors,
+		TableFallback:      opts.tableFallback,
+		TableCSVRows:       opts.tableCSVRows,
+		Admonitions:        opts.admonitions,
+		PageTypes:          opts.pageTypes,
+		LanguageFilter:     opts.languageFilter,
+		AccessRules:        opts.accessRules,
+		Plugins:            opts.plugins,
+		Converters:         opts.converters,
+	}
+	ctx, stop := interruptContext()
+	defer stop()
+	in, err := site2skill.Inspect(ctx, cfg, fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to inspect page: %v", err)
+	}
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(in); err != nil {
+			log.Fatalf("Failed to format JSON output: %v", err)
+		}
+		return
+	}
+	printInspection(in)
+}
+
+// printInspection prints an inspection for the inspect subcommand.
+func printInspection(in *site2skill.Inspection) {
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Printf("%-13s %s\n", name+":", value)
+		}
+	}
+	field("URL", in.URL)
+	if in.FinalURL != in.URL {
+		field("Final URL", in.FinalURL)
+	}
+	status := strconv.Itoa(in.StatusCode)
+	if in.Cache != "" {
+		status += " (cache: " + in.Cache + ")"
+	}
+	field("Status", status)
+	field("Generator", in.Generator)
+	field("Platform", in.Platform)
+	switch in.Extraction {
+	case converter.ExtractionContentSelector:
+		field("Extraction", "content selector")
+	case converter.ExtractionPlatform:
+		field("Extraction", "content selector of the "+in.Container+" profile")
+	case converter.ExtractionReadability:
+		field("Extraction", "Readability")
+	case converter.ExtractionFallback:
+		field("Extraction", "fallback to the first <"+in.Container+"> element")
+	default:
+		field("Extraction", "no main content found")
+	}
+	pageType := in.PageType
+	if in.Skipped {
+		pageType += " (skipped by --page-types)"
+	}
+	field("Page type", pageType)
+	field("Title", in.Title)
+	field("Description", in.Description)
+	field("Canonical", in.CanonicalURL)
+	field("Language", in.Lang)
+	detected := in.DetectedLanguage
+	if in.LanguageSkipped {
+		detected += " (skipped by --language-filter)"
+	}
+	field("Detected language", detected)
+	field("Tags", strings.Join(in.Tags, ", "))
+	field("Breadcrumbs", strings.Join(in.Breadcrumbs, " > "))
+	field("Navigation", strings.Join(in.Nav, " > "))
+	field("Schema types", strings.Join(in.SchemaTypes, ", "))
+
+	printMatches := func(heading string, matches []converter.RuleMatch, none string) {
+		fmt.Printf("\n%s:\n", heading)
+		if len(matches) == 0 {
+			fmt.Printf("  %s\n", none)
+		}
+		for _, m := range matches {
+			label := m.Selector
+			if m.Source != converter.RuleBoilerplate {
+				label = strings.ReplaceAll(m.Source, "_", " ") + " " + m.Selector
+			}
+			switch len(m.Elements) {
+			case 0:
+				fmt.Printf("  %s: no match\n", label)
+			case 1:
+				fmt.Printf("  %s: 1 element (%s)\n", label, m.Elements[0])
+			default:
+				list := strings.Join(m.Elements[:min(len(m.Elements), 5)], ", ")
+				if len(m.Elements) > 5 {
+					list += ", ..."
+				}
+				fmt.Printf("  %s: %d elements (%s)\n", label, len(m.Elements), list)
+			}
+		}
+	}
+	printMatches("Selector rules", in.Rules, "none configured (see --content-selector and --strip-selector)")
+	none := "none"
+	if in.Extraction == converter.ExtractionReadability {
+		none = "none (Readability removes boilerplate with its own heuristics)"
+	}
+	printMatches("Stripped boilerplate", in.Stripped, none)
+
+	fmt.Printf("\nMarkdown:\n%s", in.Markdown)
+	if !strings.HasSuffix(in.Markdown, "\n") {
+		fmt.Println()
+	}
+}
+
+// runKeygen executes the keygen subcommand, which creates an Ed25519 key pair for
+// signing skill packages. The private key is written to <NAME>.key and the public
+// key, which is shared with consumers of the skill, to <NAME>.pub.
+func runKeygen(args []string) {
+	fs := flag.NewFlagSet("keygen", flag.ExitOnError)
+	var force bool
+	fs.BoolVar(&force, "force", false, "Overwrite existing key files")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo keygen <NAME> [options]
+
+Create an Ed25519 key pair for signing skill packages.
+Writes the private key to NAME.key and the public key to NAME.pub.
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo keygen team-skills
+  site2skillgo generate https://docs.example.com example --sign-key team-skills.key
+`)
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	privPath := fs.Arg(0) + ".key"
+	pubPath := fs.Arg(0) + ".pub"
+	if !force {
+		for _, path := range []string{privPath, pubPath} {
+			if _, err := os.Stat(path); err == nil {
+				log.Fatalf("%s already exists (use --force to overwrite)", path)
+			}
+		}
+	}
+
+	pub, priv, err := packager.GenerateKey()
+	if err != nil {
+		log.Fatalf("Failed to generate key: %v", err)
+	}
+	if err := packager.WriteKeyPair(privPath, pubPath, priv); err != nil {
+		log.Fatalf("Failed to write key pair: %v", err)
+	}
+
+	fmt.Printf("Private key: %s\n", privPath)
+	fmt.Printf("Public key:  %s\n", pubPath)
+	fmt.Printf("Key ID:      %s\n", packager.KeyID(pub))
+}
+
+// runPackage executes the package subcommand, which packages an existing skill
+// directory as a .skill file with its integrity manifest (see
+// packager.Integrity), e.g., after editing the skill by hand, and signs it if
+// a key is given.
+func runPackage(args []string) {
+	fs := flag.NewFlagSet("package", flag.ExitOnError)
+	var outputDir, signKey, timestamp string
+	fs.StringVar(&outputDir, "output", "", "Directory to write <NAME>.skill into (default: the parent of SKILL_DIR)")
+	fs.StringVar(&signKey, "sign-key", "", "Sign the package with this private key (writes <file>.skill.sig)")
+	fs.StringVar(&timestamp, "timestamp", "", "Time recorded in the package for reproducible builds, RFC 3339 or Unix seconds (default $SOURCE_DATE_EPOCH)")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo package <SKILL_DIR> [options]
+
+Package a skill directory as a .skill file (a ZIP archive) to distribute it.
+The archive includes integrity.json, with the SHA-256 of every file, when and
+by which site2skillgo version it was made, and the site the skill was built
+from, which "site2skillgo verify" checks.
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo package .claude/skills/example
+  site2skillgo package .claude/skills/example --output dist --sign-key team-skills.key
+`)
+	}
+
+	// Accept the skill directory before the options
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		args = append(args[1:], args[0])
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	skillDir := filepath.Clean(fs.Arg(0))
+	if outputDir == "" {
+		outputDir = filepath.Dir(skillDir)
+	}
+
+	pkg := packager.New()
+	source := "--timestamp"
+	if timestamp == "" {
+		timestamp, source = os.Getenv("SOURCE_DATE_EPOCH"), "SOURCE_DATE_EPOCH"
+	}
+	if timestamp != "" {
+		t, err := parseTimestamp(timestamp)
+		if err != nil {
+			log.Fatalf("Invalid %s: %v", source, err)
+		}
+		pkg.SetModTime(t)
+	}
+	if m, err := skillgen.ReadManifest(skillDir); err == nil {
+		pkg.SetSource(m.URL)
+	}
+	var priv ed25519.PrivateKey
+	if signKey != "" {
+		var err error
+		if priv, err = packager.LoadPrivateKey(signKey); err != nil {
+			log.Fatalf("Failed to load signing key: %v", err)
+		}
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		log.Fatalf("Failed to create output directory: %v", err)
+	}
+	skillFile, err := pkg.Package(skillDir, outputDir)
+	if err != nil {
+		log.Fatalf("Failed to package skill: %v", err)
+	}
+	fmt.Printf("Package:     %s\n", skillFile)
+	if priv != nil {
+		sigPath, err := packager.SignArchive(skillFile, priv)
+		if err != nil {
+			log.Fatalf("Failed to sign skill: %v", err)
+		}
+		fmt.Printf("Signature:   %s\n", sigPath)
+	}
+}
+
+// runVerify executes the verify subcommand, which checks a .skill file against its
+// integrity manifest and, if a public key is given, against its detached
+// signature. It exits with status 1 if the package was modified after it was
+// made or signed, or was signed by a different key.
+func runVerify(args []string) {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	var (
+		keyPath string
+		sigPath string
+	)
+	fs.StringVar(&keyPath, "key", "", "Public key of the expected signer (default: check the integrity manifest only)")
+	fs.StringVar(&sigPath, "signature", "", "Signature file (default \"<SKILL_FILE>.sig\")")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo verify <SKILL_FILE> [--key <PUBLIC_KEY>] [options]
+
+Check that a .skill file is unmodified: its files must match its integrity
+manifest (integrity.json) and, with --key, its signature by the given key.
+Packages made by site2skillgo versions without integrity manifests can only
+be checked with --key.
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo verify .claude/skills/example.skill
+  site2skillgo verify .claude/skills/example.skill --key team-skills.pub
+`)
+	}
+
+	// Accept the skill file before the options
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		args = append(args[1:], args[0])
+	}
+	fs.Parse(args)
+
+	if fs.NArg() < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	skillFile := fs.Arg(0)
+	if sigPath == "" {
+		sigPath = skillFile + packager.SignatureExt
+	}
+
+	integrity, err := packager.VerifyIntegrity(skillFile)
+	if err != nil && (keyPath == "" || !errors.Is(err, packager.ErrNoIntegrity)) {
+		fmt.Fprintf(os.Stderr, "FAIL  %s: %v\n", skillFile, err)
+		os.Exit(1)
+	}
+	var details []string
+	if integrity != nil {
+		details = append(details, fmt.Sprintf("%d files", len(integrity.Files)), "made "+integrity.GeneratedAt.Format(time.RFC3339)+" by "+integrity.Tool)
+		if integrity.SourceURL != "" {
+			details = append(details, "from "+integrity.SourceURL)
+		}
+	}
+
+	if keyPath != "" {
+		pub, err := packager.LoadPublicKey(keyPath)
+		if err != nil {
+			log.Fatalf("Failed to load public key: %v", err)
+		}
+		sig, err := packager.VerifyArchive(skillFile, sigPath, pub)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "FAIL  %s: %v\n", skillFile, err)
+			os.Exit(1)
+		}
+		if integrity == nil {
+			details = append(details, fmt.Sprintf("%d files", len(sig.Files)))
+		}
+		details = append(details, "key "+sig.KeyID, "signed "+sig.SignedAt.Format(time.RFC3339))
+	}
+
+	fmt.Printf("OK    %s (%s)\n", skillFile, strings.Join(details, ", "))
+}
+
+// runConfig executes the config subcommand. "config validate [FILE]" checks a config
+// file against the schema and reports every problem with its line and column,
+// exiting with status 1 if there are any, so it can gate changes in CI.
+func runConfig(args []string) {
+	fs := flag.NewFlagSet("config", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo config validate [FILE]
+
+Check a config file (default %q) for unknown keys, wrong value types,
+invalid values, and conflicting options.
+
+Examples:
+  site2skillgo config validate
+  site2skillgo config validate configs/site2skill.yaml
+`, config.DefaultFileName)
+	}
+
+	fs.Parse(args)
+
+	if fs.NArg() < 1 || fs.Arg(0) != "validate" {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	path := config.DefaultFileName
+	if fs.NArg() >= 2 {
+		path = fs.Arg(1)
+	}
+
+	file, err := config.Load(path)
+	var verr *config.ValidationError
+	if errors.As(err, &verr) {
+		for _, issue := range verr.Issues {
+			fmt.Fprintln(os.Stderr, issue)
+		}
+		fmt.Fprintf(os.Stderr, "%s: %d problems\n", path, len(verr.Issues))
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	fmt.Printf("%s: OK (%d profiles)\n", path, len(file.Profiles))
+}
+
+// runInit executes the init subcommand, which probes a documentation site
+// (see site2skill.Probe), asks for the settings of its skill with what it found
+// as the defaults, and writes them as a profile of a config file, so a first
+// build needs neither the flags of generate nor a config file written by hand.
+//
+// The questions are read from standard input; at its end, or with --yes, the
+// suggested settings are taken.
+func runInit(args []string) {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	var configPath, profile string
+	var yes, force bool
+	fs.StringVar(&configPath, "config", config.DefaultFileName, "Config file the profile is written to, created if it doesn't exist")
+	fs.StringVar(&profile, "profile", "", "Name of the profile (default: the skill name)")
+	fs.BoolVar(&yes, "yes", false, "Take the suggested settings without asking")
+	fs.BoolVar(&force, "force", false, "Replace a profile of the same name in the config file")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo init <URL> [options]
+
+Probe a documentation site and write a starter profile for it into a config
+file: fetch the start page and robots.txt, report the detected platform, the
+locales of its hreflang links, whether robots.txt allows the crawl, and the
+sitemaps it lists, then ask for the skill name, output format, locale priority,
+and whether to crawl the sitemaps, suggesting settings from what was found.
+
+Arguments:
+  URL           Start URL of the documentation site
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo init https://docs.example.com/
+  site2skillgo init https://docs.example.com/ --profile example --yes
+  site2skillgo init https://docs.example.com/ --config configs/site2skill.yaml --force
+`)
+	}
+
+	// Accept the URL before the options, like generate's positional arguments
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		args = append(args[1:], args[0])
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	probe, err := site2skill.Probe(ctx, site2skill.Config{NoCache: true}, fs.Arg(0))
+	if err != nil {
+		log.Fatalf("Failed to probe site: %v", err)
+	}
+	printProbe(probe)
+	fmt.Println()
+
+	ask := prompter(os.Stdin, yes)
+	p := config.Profile{URL: probe.URL}
+	p.Name = ask("Skill name", suggestSkillName(probe.FinalURL))
+	p.Format = ask("Output format (claude, codex, both)", "claude")
+	if len(probe.Locales) > 0 {
+		p.Locale.Priority = parseLocales(ask("Locale priority", strings.Join(suggestLocales(probe), ",")))
+	}
+	if len(probe.Sitemaps) > 0 && strings.HasPrefix(strings.ToLower(ask("Crawl the pages of the sitemaps (y/n)", "y")), "y") {
+		sitemaps := true
+		p.Crawl.Sitemaps = &sitemaps
+	}
+	p.Conversion.Platform = probe.Platform
+	if profile == "" {
+		profile = p.Name
+	}
+
+	if err := config.AddProfile(configPath, profile, p, force); err != nil {
+		if errors.Is(err, config.ErrProfileExists) {
+			log.Fatalf("Failed to write config: %v (use --force to replace it)", err)
+		}
+		log.Fatalf("Failed to write config: %v", err)
+	}
+	fmt.Printf("\nWrote profile %q to %s. Build the skill with:\n", profile, configPath)
+	if configPath == config.DefaultFileName {
+		fmt.Printf("  site2skillgo generate --profile %s\n", profile)
+	} else {
+		fmt.Printf("  site2skillgo generate --config %s --profile %s\n", configPath, profile)
+	}
+}
+
+// printProbe prints the findings of a site probe for the init subcommand.
+func printProbe(probe *site2skill.SiteProbe) {
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Printf("%-11s %s\n", name+":", value)
+		}
+	}
+	field("URL", probe.URL)
+	if probe.FinalURL != probe.URL {
+		field("Final URL", probe.FinalURL)
+	}
+	field("Title", probe.Title)
+	platform := probe.Platform
+	if platform == "" {
+		platform = "unknown"
+	}
+	if probe.Generator != "" {
+		platform += " (" + probe.Generator + ")"
+	}
+	field("Platform", platform)
+	field("Language", probe.Lang)
+	if len(probe.Locales) > 0 {
+		field("Locales", strings.Join(probe.Locales, ", "))
+	} else {
+		field("Locales", "none in hreflang links")
+	}
+	if probe.RobotsAllowed {
+		field("robots.txt", "allows the crawl")
+	} else {
+		field("robots.txt", "disallows the start URL; the crawl will skip it")
+	}
+	if len(probe.Sitemaps) > 0 {
+		field("Sitemaps", strings.Join(probe.Sitemaps, ", "))
+	} else {
+		field("Sitemaps", "none in robots.txt")
+	}
+}
+
+// prompter returns a function asking a question on standard output and
+// returning the answer read from r, or the suggestion def if the answer is
+// empty. Once r is exhausted, or if yes is set, it prints the questions with
+// their suggestions and takes them without reading.
+func prompter(r io.Reader, yes bool) func(question, def string) string {
+	scanner := bufio.NewScanner(r)
+	done := yes
+	return func(question, def string) string {
+		fmt.Printf("%s [%s]: ", question, def)
+		if done {
+			fmt.Println(def)
+			return def
+		}
+		if !scanner.Scan() {
+			done = true
+			fmt.Println(def)
+			return def
+		}
+		if answer := strings.TrimSpace(scanner.Text()); answer != "" {
+			return answer
+		}
+		return def
+	}
+}
+
+// skillNameNoise lists the host labels left out of suggested skill names:
+// the usual subdomains of documentation sites.
+var skillNameNoise = map[string]bool{
+	"www": true, "docs": true, "doc": true, "developer": true, "developers": true,
+	"dev": true, "api": true, "help": true, "support": true, "learn": true, "wiki": true,
+}
+
+// suggestSkillName suggests the name of the skill of the site at siteURL:
+// the most specific label of its host that isn't a usual subdomain or its
+// top-level domain, e.g., "stripe" for https://docs.stripe.com/, or "docs"
+// if there is none or the host is an IP address.
+func suggestSkillName(siteURL string) string {
+	u, err := url.Parse(siteURL)
+	if err != nil || u.Hostname() == "" || net.ParseIP(u.Hostname()) != nil {
+		return "docs"
+	}
+	labels := strings.Split(strings.ToLower(u.Hostname()), ".")
+	if len(labels) > 1 {
+		labels = labels[:len(labels)-1]
+	}
+	for i := len(labels) - 1; i >= 0; i-- {
+		if !skillNameNoise[labels[i]] && labels[i] != "" {
+			return labels[i]
+		}
+	}
+	return "docs"
+}
+
+// suggestLocales suggests the locale priority of a probed site: the locale of
+// its start page's language first, then the other locales of its hreflang
+// links.
+func suggestLocales(probe *site2skill.SiteProbe) []string {
+	var first string
+	lang := strings.ToLower(strings.ReplaceAll(probe.Lang, "_", "-"))
+	for _, locale := range probe.Locales {
+		if l := strings.ToLower(locale); l == lang || first == "" && strings.HasPrefix(lang, l+"-") {
+			first = locale
+		}
+	}
+	if first == "" {
+		return probe.Locales
+	}
+	locales := []string{first}
+	for _, locale := range probe.Locales {
+		if locale != first {
+			locales = append(locales, locale)
+		}
+	}
+	return locales
+}
+
+// subcommandWords lists the words the commands taking a subcommand take
+// first, for the shell completion scripts.
+var subcommandWords = map[string][]string{
+	"index":      {"optimize", "watch", "embed"},
+	"config":     {"validate"},
+	"completion": {"bash", "zsh", "fish"},
+}
+
+// flagLinePattern matches the line of a flag in the output of
+// flag.PrintDefaults: "  -name type", followed by its usage on the next line.
+var flagLinePattern = regexp.MustCompile(`^  -([A-Za-z][\w-]*)`)
+
+// completionFlag is a flag of a command in the shell completion scripts.
+type completionFlag struct {
+	name, usage string
+}
+
+// runCompletion executes the completion subcommand, which prints a completion
+// script of the commands, their subcommands, and their flags for bash, zsh, or
+// fish. The flags are read from the help of each command, by running the
+// executable with -h, so the scripts can't drift from the flags it defines.
+func runCompletion(args []string) {
+	fs := flag.NewFlagSet("completion", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo completion bash|zsh|fish
+
+Print a script completing the commands, subcommands, and flags of site2skillgo
+in the shell, and file names for their arguments.
+
+Examples:
+  # bash: for the current shell, or every new one
+  source <(site2skillgo completion bash)
+  site2skillgo completion bash > /etc/bash_completion.d/site2skillgo
+
+  # zsh: a directory of $fpath, with compinit run in ~/.zshrc
+  site2skillgo completion zsh > "${fpath[1]}/_site2skillgo"
+
+  # fish
+  site2skillgo completion fish > ~/.config/fish/completions/site2skillgo.fish
 `)
 	}
 
 	fs.Parse(args)
 
-	if fs.NArg() < 1 {
-		fmt.Fprintf(os.Stderr, "Error: search query is required\n\n")
+	if fs.NArg() != 1 {
 		fs.Usage()
 		os.Exit(1)
 	}
+	shell := fs.Arg(0)
+	if shell != "bash" && shell != "zsh" && shell != "fish" {
+		log.Fatalf("Unknown shell %q: must be bash, zsh, or fish", shell)
+	}
 
-	query := fs.Arg(0)
+	exe, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to find executable: %v", err)
+	}
+	flags := make(map[string][]completionFlag)
+	for _, c := range commands {
+		if c.name == "help" {
+			continue
+		}
+		// -h exits with status 0 after the help, or 1 for the commands
+		// showing it on any error, so only the output counts
+		out, _ := exec.Command(exe, c.name, "-h").CombinedOutput()
+		flags[c.name] = parseFlagHelp(string(out))
+	}
 
-	opts := search.SearchOptions{
-		SkillDir:   skillDir,
-		Query:      query,
-		MaxResults: maxResults,
-		JSONOutput: jsonOutput,
+	switch shell {
+	case "bash":
+		fmt.Print(bashCompletion(flags))
+	case "zsh":
+		fmt.Print(zshCompletion(flags))
+	case "fish":
+		fmt.Print(fishCompletion(flags))
+	}
+}
+
+// parseFlagHelp returns the flags of a command's help, as printed by
+// flag.PrintDefaults, with the first line of their usage.
+func parseFlagHelp(help string) []completionFlag {
+	var flags []completionFlag
+	lines := strings.Split(help, "\n")
+	for i, line := range lines {
+		m := flagLinePattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		f := completionFlag{name: m[1]}
+		if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "    \t") {
+			f.usage = strings.TrimSpace(lines[i+1])
+		}
+		flags = append(flags, f)
+	}
+	return flags
+}
+
+// shellQuote quotes s for bash, zsh, and fish as a single-quoted string.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// bashCompletion returns the bash completion script of the commands and
+// their flags.
+func bashCompletion(flags map[string][]completionFlag) string {
+	var b strings.Builder
+	b.WriteString(`# bash completion for site2skillgo, generated by "site2skillgo completion bash"
+_site2skillgo() {
+    local cur=${COMP_WORDS[COMP_CWORD]} words=""
+    if [[ $COMP_CWORD -eq 1 ]]; then
+        words=`)
+	var names []string
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+	b.WriteString(shellQuote(strings.Join(names, " ")))
+	b.WriteString(`
+    elif [[ $cur == -* ]]; then
+        case ${COMP_WORDS[1]} in
+`)
+	for _, c := range commands {
+		if len(flags[c.name]) == 0 {
+			continue
+		}
+		var names []string
+		for _, f := range flags[c.name] {
+			names = append(names, "--"+f.name)
+		}
+		fmt.Fprintf(&b, "            %s) words=%s ;;\n", c.name, shellQuote(strings.Join(names, " ")))
+	}
+	b.WriteString(`        esac
+    elif [[ $COMP_CWORD -eq 2 ]]; then
+        case ${COMP_WORDS[1]} in
+`)
+	for _, c := range commands {
+		if words := subcommandWords[c.name]; len(words) > 0 {
+			fmt.Fprintf(&b, "            %s) words=%s ;;\n", c.name, shellQuote(strings.Join(words, " ")))
+		}
+	}
+	b.WriteString(`        esac
+    fi
+    COMPREPLY=($(compgen -W "$words" -- "$cur"))
+}
+complete -o default -F _site2skillgo site2skillgo
+`)
+	return b.String()
+}
+
+// zshCompletion returns the zsh completion script of the commands and their
+// flags, with their descriptions.
+func zshCompletion(flags map[string][]completionFlag) string {
+	var b strings.Builder
+	b.WriteString(`#compdef site2skillgo
+# zsh completion for site2skillgo, generated by "site2skillgo completion zsh"
+_site2skillgo() {
+    local -a commands opts subs
+    commands=(
+`)
+	for _, c := range commands {
+		fmt.Fprintf(&b, "        %s\n", shellQuote(c.name+":"+c.summary))
+	}
+	b.WriteString(`    )
+    if (( CURRENT == 2 )); then
+        _describe 'command' commands
+        return
+    fi
+    case $words[2] in
+`)
+	for _, c := range commands {
+		fs, subs := flags[c.name], subcommandWords[c.name]
+		if len(fs) == 0 && len(subs) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "        %s)\n", c.name)
+		if len(fs) > 0 {
+			var opts []string
+			for _, f := range fs {
+				opts = append(opts, shellQuote("--"+f.name+":"+f.usage))
+			}
+			fmt.Fprintf(&b, "            opts=(%s)\n", strings.Join(opts, " "))
+		}
+		if len(subs) > 0 {
+			fmt.Fprintf(&b, "            subs=(%s)\n", strings.Join(subs, " "))
+		}
+		b.WriteString("            ;;\n")
+	}
+	b.WriteString(`    esac
+    if [[ $PREFIX == -* ]] && (( ${#opts} )); then
+        _describe 'option' opts
+    elif (( CURRENT == 3 )) && (( ${#subs} )); then
+        compadd -a subs
+    else
+        _files
+    fi
+}
+if [[ $funcstack[1] == _site2skillgo ]]; then
+    _site2skillgo "$@"
+else
+    compdef _site2skillgo site2skillgo
+fi
+`)
+	return b.String()
+}
+
+// fishCompletion returns the fish completion script of the commands and
+// their flags, with their descriptions.
+func fishCompletion(flags map[string][]completionFlag) string {
+	var b strings.Builder
+	b.WriteString("# fish completion for site2skillgo, generated by \"site2skillgo completion fish\"\n")
+	for _, c := range commands {
+		fmt.Fprintf(&b, "complete -c site2skillgo -n __fish_use_subcommand -f -a %s -d %s\n", c.name, shellQuote(c.summary))
+	}
+	for _, c := range commands {
+		condition := shellQuote("__fish_seen_subcommand_from " + c.name)
+		if words := subcommandWords[c.name]; len(words) > 0 {
+			fmt.Fprintf(&b, "complete -c site2skillgo -n %s -f -a %s\n", condition, shellQuote(strings.Join(words, " ")))
+		}
+		for _, f := range flags[c.name] {
+			fmt.Fprintf(&b, "complete -c site2skillgo -n %s -l %s -d %s\n", condition, f.name, shellQuote(f.usage))
+		}
+	}
+	return b.String()
+}
+
+// runSelftest executes the selftest subcommand, which builds a skill from a
+// miniature documentation site bundled in the binary and served on a local
+// port, checks the output (see selftest.Run), and prints one line per check.
+// It exits with a non-zero status if any check fails, so it can verify an
+// installation or a CI environment in one command.
+//
+// The options of the generate command that don't depend on the crawled site
+// apply, from the command line or a config file profile: the output format,
+// conversion, chunking, assets, embeddings, and plugins.
+func runSelftest(args []string) {
+	fs := flag.NewFlagSet("selftest", flag.ExitOnError)
+
+	var opts generateOptions
+	opts.registerFlags(fs)
+	var dir string
+	var jsonOutput bool
+	fs.StringVar(&dir, "dir", "", "Keep the self-test output in this directory (default: a temporary directory, removed afterwards)")
+	fs.BoolVar(&jsonOutput, "json", false, "Output the results as JSON")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo selftest [options]
+
+Build a skill from a miniature documentation site bundled in site2skillgo,
+served on a local port, and check the output: the crawl, the converted
+documents (titles, code blocks, tables, links), validation, the .skill
+package, and search. Exits with status 1 if any check fails.
+
+Accepts the options of the generate command; those that don't depend on the
+crawled site apply (format, conversion, chunking, assets, embeddings, and
+plugins), so a config file profile can be checked with --profile.
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo selftest
+  site2skillgo selftest --format both --json
+  site2skillgo selftest --profile example --dir selftest-output
+`)
+	}
+
+	fs.Parse(args)
+	opts.loadConfig(fs)
+	setupLogging(opts.logLevel, opts.logFormat)
+	warnlog.SetLimit(opts.warningLimit)
+
+	if fs.NArg() != 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if opts.format != FormatClaude && opts.format != FormatCodex && opts.format != FormatBoth {
+		log.Fatalf("Invalid format: %s. Must be 'claude', 'codex', or 'both'", opts.format)
+	}
+
+	cfg := site2skill.Config{
+		NoCache:        true,
+		Device:         opts.device,
+		UserAgent:      opts.userAgent,
+		Contact:        opts.contact,
+		RunID:          opts.runID,
+		TableFallback:  opts.tableFallback,
+		TableCSVRows:   opts.tableCSVRows,
+		Admonitions:    opts.admonitions,
+		DedupeSnippets: opts.dedupeSnippets,
+		KeepDuplicates: opts.keepDuplicates,
+		ChunkTokens:    opts.chunkTokens,
+		DownloadAssets: opts.downloadAssets,
+		AirGapped:      opts.airGapped,
+		AbsoluteLinks:  opts.absoluteLinks,
+		Plugins:        opts.plugins,
+		Converters:     opts.converters,
+	}
+	if !opts.noLocalePriority {
+		cfg.Locales = parseLocales(opts.localePriority)
+	}
+	for _, format := range []string{FormatClaude, FormatCodex} {
+		if opts.format == format || opts.format == FormatBoth {
+			cfg.Targets = append(cfg.Targets, site2skill.Target{Format: format})
+		}
+	}
+	var err error
+	if opts.embeddingsProvider != "" {
+		if cfg.Embedder, err = site2skill.NewEmbedder(opts.embeddingsProvider, opts.embeddingsURL, opts.embeddingsModel); err != nil {
+			log.Fatalf("Invalid --embeddings: %v", err)
+		}
+	}
+	if opts.summariesProvider != "" {
+		if cfg.Summarizer, err = site2skill.NewSummarizer(opts.summariesProvider, opts.summariesURL, opts.summariesModel); err != nil {
+			log.Fatalf("Invalid --summaries: %v", err)
+		}
+	}
+
+	cleanup := func() {}
+	if dir == "" {
+		if dir, err = os.MkdirTemp("", "site2skill-selftest-"); err != nil {
+			log.Fatalf("Failed to create self-test directory: %v", err)
+		}
+		cleanup = func() { os.RemoveAll(dir) }
 	}
+	defer cleanup()
 
-	results, err := search.SearchDocs(opts)
+	ctx, stop := interruptContext()
+	defer stop()
+	results, err := selftest.Run(ctx, cfg, dir)
 	if err != nil {
-		log.Fatalf("Search failed: %v", err)
+		log.Fatalf("Self-test failed: %v", err)
+	}
+	failed := selftest.Failed(results)
+
+	if jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			log.Fatalf("Failed to format JSON output: %v", err)
+		}
+	} else {
+		for _, r := range results {
+			if r.Passed {
+				fmt.Printf("PASS  %s\n", r.Check)
+			} else {
+				fmt.Printf("FAIL  %s: %s\n", r.Check, r.Detail)
+			}
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d self-test checks failed\n", failed, len(results))
+		// os.Exit skips the deferred calls
+		stop()
+		cleanup()
+		os.Exit(1)
+	}
+}
+
+// runGolden executes the golden subcommand, which records sites as fixtures
+// and verifies the skills built from them against their snapshots (see
+// package golden): record crawls a site into a fixture with generate options,
+// and verify rebuilds the skill of each fixture from its recorded crawl and
+// reports the files that differ from its snapshot.
+func runGolden(args []string) {
+	usage := func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo golden record [generate options] <URL> <SKILL_NAME> <FIXTURE_DIR>
+       site2skillgo golden verify [--update] [--json] <FIXTURE_DIR>...
+
+Golden tests of the conversion against real sites, without the network.
+
+record crawls URL with the options of the generate command, recording every
+request and response into FIXTURE_DIR/%s, and writes the options and the
+skill built from the crawl, its snapshot, into the fixture (%s and %s/).
+The time recorded in the skill is that of the recording, so that rebuilds are
+identical. The build runs in a temporary directory: options reading files,
+such as --plugin, need absolute paths, and --global and --profile aren't
+supported.
+
+verify builds the skill of each fixture again from its recorded crawl with
+its options, as 'reconvert --from-warc' does, and compares it with the
+snapshot, printing the diff of every file that differs. Exits with status 1
+if any fixture differs. With --update, the snapshots of the fixtures that
+differ are replaced, to accept an intended change of the output.
+
+Examples:
+  site2skillgo golden record https://docs.example.com/ example testdata/example
+  site2skillgo golden record --chunk-tokens 8000 --platform sphinx https://docs.python.org/3/ python testdata/python
+  site2skillgo golden verify testdata/*
+  site2skillgo golden verify --update testdata/python
+`, golden.ArchiveFile, golden.FixtureFile, golden.SnapshotDir)
+	}
+	if len(args) == 0 || (args[0] != "record" && args[0] != "verify") {
+		usage()
+		os.Exit(1)
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to locate the site2skillgo executable: %v", err)
+	}
+	if args[0] == "record" {
+		runGoldenRecord(args[1:], exe, usage)
+	} else {
+		runGoldenVerify(args[1:], exe, usage)
+	}
+}
+
+// runGoldenRecord records a fixture with golden record: args are generate
+// options followed by the URL, skill name, and fixture directory.
+func runGoldenRecord(args []string, exe string, usage func()) {
+	fs := flag.NewFlagSet("golden record", flag.ExitOnError)
+	var opts generateOptions
+	opts.registerFlags(fs)
+	fs.Usage = usage
+	fs.Parse(args)
+	if fs.NArg() != 3 {
+		usage()
+		os.Exit(1)
+	}
+	for _, name := range []string{"global", "profile", "url", "name"} {
+		if f := fs.Lookup(name); f != nil && f.Value.String() != f.DefValue {
+			log.Fatalf("--%s isn't supported by golden record", name)
+		}
+	}
+	if opts.format != FormatClaude && opts.format != FormatCodex && opts.format != FormatBoth {
+		log.Fatalf("Invalid format: %s. Must be 'claude', 'codex', or 'both'", opts.format)
+	}
+
+	fx := &golden.Fixture{URL: fs.Arg(0), SkillName: fs.Arg(1), Args: args[:len(args)-fs.NArg()]}
+	dir := fs.Arg(2)
+	ctx, stop := interruptContext()
+	defer stop()
+	log.Printf("Recording %s into %s", fx.URL, dir)
+	if err := golden.Record(ctx, dir, fx, goldenBuilder(exe)); err != nil {
+		log.Fatalf("Failed to record the fixture: %v", err)
+	}
+	files, _ := golden.ReadSnapshot(dir)
+	log.Printf("Recorded %s: %d files in %s", dir, len(files), filepath.Join(dir, golden.SnapshotDir))
+}
+
+// runGoldenVerify verifies fixtures with golden verify: args are its options
+// followed by the fixture directories.
+func runGoldenVerify(args []string, exe string, usage func()) {
+	fs := flag.NewFlagSet("golden verify", flag.ExitOnError)
+	var update, jsonOutput bool
+	fs.BoolVar(&update, "update", false, "Replace the snapshots of the fixtures that differ with the skills built from them")
+	fs.BoolVar(&jsonOutput, "json", false, "Output the results as JSON")
+	fs.Usage = func() {
+		usage()
+		fmt.Fprintf(os.Stderr, "\nOptions of verify:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+	var results []*golden.Result
+	failed := 0
+	for _, dir := range fs.Args() {
+		result, err := golden.Verify(ctx, dir, goldenBuilder(exe), update)
+		if err != nil {
+			log.Fatalf("Failed to verify %s: %v", dir, err)
+		}
+		results = append(results, result)
+		if !result.Passed() && !result.Updated {
+			failed++
+		}
 	}
 
 	if jsonOutput {
-		if err := search.FormatJSON(results); err != nil {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
 			log.Fatalf("Failed to format JSON output: %v", err)
 		}
 	} else {
-		search.FormatResults(results, query)
+		for _, r := range results {
+			switch {
+			case r.Passed():
+				fmt.Printf("PASS     %s\n", r.Fixture)
+			case r.Updated:
+				fmt.Printf("UPDATED  %s (differences: %d)\n", r.Fixture, len(r.Differences))
+			default:
+				fmt.Printf("FAIL     %s (differences: %d)\n", r.Fixture, len(r.Differences))
+				for _, d := range r.Differences {
+					fmt.Printf("  %s %s\n", d.Change, d.Path)
+					if d.Diff != "" {
+						fmt.Println(d.Diff)
+					}
+				}
+			}
+		}
+	}
+
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d of %d fixtures differ from their snapshots (accept the changes with --update)\n", failed, len(results))
+		// os.Exit skips the deferred calls
+		stop()
+		os.Exit(1)
+	}
+}
+
+// goldenBuilder returns a golden.Builder running exe, this executable: the
+// generate command with the options of the fixture to record it, and the
+// reconvert command to rebuild it from its archive. The command runs in the
+// build directory, where the skill is written; its output is kept for the
+// error of a failed build.
+func goldenBuilder(exe string) golden.Builder {
+	return func(ctx context.Context, fx *golden.Fixture, archive string, replay bool, workDir string) (string, error) {
+		args := []string{"generate"}
+		if replay {
+			args = []string{"reconvert"}
+		}
+		args = append(args, fx.Args...)
+		// The options of the harness come last, so that they win
+		if replay {
+			args = append(args, "--from-warc", archive)
+		} else {
+			args = append(args, "--warc", archive)
+		}
+		args = append(args, "--url", fx.URL, "--name", fx.SkillName,
+			"--timestamp", fx.Timestamp.Format(time.RFC3339),
+			"--temp-dir", filepath.Join(workDir, "build"), "--no-cache", "--progress", site2skill.ProgressPlain)
+
+		var output bytes.Buffer
+		cmd := exec.CommandContext(ctx, exe, args...)
+		cmd.Cancel = func() error { return cmd.Process.Signal(os.Interrupt) }
+		cmd.Dir = workDir
+		cmd.Stdout = &output
+		cmd.Stderr = &output
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("%s %s: %w\n%s", filepath.Base(exe), args[0], err, output.String())
+		}
+		// The skill of the first format is snapshotted
+		for _, format := range []string{FormatClaude, FormatCodex} {
+			skillDir := filepath.Join(workDir, "."+format, "skills", fx.SkillName)
+			if _, err := os.Stat(skillDir); err == nil {
+				return skillDir, nil
+			}
+		}
+		return "", fmt.Errorf("%s %s wrote no skill\n%s", filepath.Base(exe), args[0], output.String())
+	}
+}
+
+// registryRefHelp describes skill references and credentials for push and pull usage text.
+const registryRefHelp = `References:
+  oci://HOST/REPOSITORY[:TAG]      OCI registry artifact (tag defaults to "latest")
+  oci://HOST/REPOSITORY@sha256:... OCI artifact by digest (pull only)
+  s3://BUCKET/PREFIX/NAME[:TAG]    S3 object at PREFIX/NAME/TAG.skill
+
+Credentials:
+  OCI: SITE2SKILL_REGISTRY_USERNAME, SITE2SKILL_REGISTRY_PASSWORD
+  S3:  AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN, AWS_REGION,
+       AWS_ENDPOINT_URL_S3 (for S3-compatible storage such as MinIO)
+`
+
+// openRegistry parses a skill reference and opens the store that serves it.
+func openRegistry(rawRef string, insecure bool) (registry.Ref, registry.Store) {
+	ref, err := registry.ParseRef(rawRef)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	opts := registry.OptionsFromEnv()
+	opts.Insecure = insecure
+	store, err := registry.New(ref, opts)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	return ref, store
+}
+
+// runPush executes the push subcommand, which uploads a .skill file (and its
+// signature, if present) under one or more tags.
+func runPush(args []string) {
+	fs := flag.NewFlagSet("push", flag.ExitOnError)
+	var (
+		tags     stringList
+		insecure bool
+	)
+	fs.Var(&tags, "tag", "Additional tag to push (can be repeated or comma-separated)")
+	fs.BoolVar(&insecure, "insecure", false, "Use plain HTTP for OCI registries")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo push <SKILL_FILE> <REF> [options]
+
+Upload a skill package to an OCI registry or S3 bucket.
+The signature file (<SKILL_FILE>.sig) is uploaded alongside when present.
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\n%s", registryRefHelp)
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo push .claude/skills/stripe.skill oci://ghcr.io/acme/skills/stripe:1.2.0 --tag latest
+  site2skillgo push .claude/skills/stripe.skill s3://acme-skills/team/stripe:1.2.0
+`)
+	}
+
+	positional := parseArgs(fs, args)
+	if len(positional) != 2 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	skillFile := positional[0]
+	ref, store := openRegistry(positional[1], insecure)
+
+	refs := []registry.Ref{ref}
+	for _, tag := range tags {
+		refs = append(refs, ref.WithReference(tag))
+	}
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	for _, r := range refs {
+		digest, err := store.Push(ctx, r, skillFile)
+		if err != nil {
+			log.Fatalf("Failed to push %s: %v", r, err)
+		}
+		fmt.Printf("Pushed %s (%s)\n", r, digest)
+	}
+}
+
+// runPull executes the pull subcommand, which downloads a .skill file (and its
+// signature, if one was pushed) into a local directory.
+func runPull(args []string) {
+	fs := flag.NewFlagSet("pull", flag.ExitOnError)
+	var (
+		outputDir string
+		insecure  bool
+	)
+	fs.StringVar(&outputDir, "output", ".", "Directory to write <NAME>.skill into")
+	fs.BoolVar(&insecure, "insecure", false, "Use plain HTTP for OCI registries")
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, `Usage: site2skillgo pull <REF> [options]
+
+Download a skill package from an OCI registry or S3 bucket.
+
+Options:
+`)
+		fs.PrintDefaults()
+		fmt.Fprintf(os.Stderr, "\n%s", registryRefHelp)
+		fmt.Fprintf(os.Stderr, `
+Examples:
+  site2skillgo pull oci://ghcr.io/acme/skills/stripe:1.2.0 --output .claude/skills
+  site2skillgo pull s3://acme-skills/team/stripe:1.2.0
+`)
+	}
+
+	positional := parseArgs(fs, args)
+	if len(positional) != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	ref, store := openRegistry(positional[0], insecure)
+
+	ctx, stop := interruptContext()
+	defer stop()
+
+	path, err := store.Pull(ctx, ref, outputDir)
+	if err != nil {
+		log.Fatalf("Failed to pull %s: %v", ref, err)
 	}
+	fmt.Printf("Pulled %s -> %s\n", ref, path)
 }
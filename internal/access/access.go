@@ -0,0 +1,122 @@
+// Package access tags documents with the access level of the audience allowed
+// to read them, so that one skill can serve audiences with different
+// clearance: searches filter out the documents above the caller's levels.
+//
+// Levels are assigned to pages by URL path rules written "PATTERN=LEVEL",
+// where PATTERN is a path glob (see package pathglob) and the first matching
+// rule wins:
+//
+//	/internal/**=internal
+//	/security/incidents/**=confidential
+//	/**=public
+//
+// The level is recorded in the access frontmatter field of each page; pages
+// matching no rule have none and are public.
+package access
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/pathglob"
+)
+
+// The access levels, from the widest audience to the narrowest.
+const (
+	// Public documents are readable by anyone.
+	Public = "public"
+	// Internal documents are readable by the organization only.
+	Internal = "internal"
+	// Confidential documents are readable by a restricted audience.
+	Confidential = "confidential"
+)
+
+// Levels lists the access levels, from the widest audience to the narrowest.
+var Levels = []string{Public, Internal, Confidential}
+
+// Rule assigns an access level to the pages whose URL path matches Pattern.
+type Rule struct {
+	// Pattern is a path glob (see package pathglob).
+	Pattern string
+	// Level is one of Levels.
+	Level string
+}
+
+// ParseRule parses a rule written "PATTERN=LEVEL".
+//
+// Returns an error if PATTERN isn't a valid path glob or LEVEL isn't one of Levels.
+func ParseRule(s string) (Rule, error) {
+	pattern, level, ok := strings.Cut(s, "=")
+	r := Rule{Pattern: strings.TrimSpace(pattern), Level: strings.TrimSpace(level)}
+	if !ok || r.Pattern == "" || r.Level == "" {
+		return Rule{}, fmt.Errorf("invalid access rule %q: want PATTERN=LEVEL", s)
+	}
+	if err := pathglob.Validate(r.Pattern); err != nil {
+		return Rule{}, fmt.Errorf("invalid access rule %q: %w", s, err)
+	}
+	if err := ValidateLevel(r.Level); err != nil {
+		return Rule{}, fmt.Errorf("invalid access rule %q: %w", s, err)
+	}
+	return r, nil
+}
+
+// ParseRules parses rules written "PATTERN=LEVEL" (see ParseRule).
+func ParseRules(rules []string) ([]Rule, error) {
+	parsed := make([]Rule, 0, len(rules))
+	for _, s := range rules {
+		r, err := ParseRule(s)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, r)
+	}
+	return parsed, nil
+}
+
+// ValidateLevel returns an error if level isn't one of Levels.
+func ValidateLevel(level string) error {
+	for _, l := range Levels {
+		if level == l {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown access level %q (expected %s)", level, strings.Join(Levels, ", "))
+}
+
+// LevelOf returns the level of the first of rules matching the path of
+// pageURL, or "" if none does.
+func LevelOf(rules []Rule, pageURL string) string {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	p := u.Path
+	if p == "" {
+		p = "/"
+	}
+	for _, r := range rules {
+		if pathglob.Match(r.Pattern, p) {
+			return r.Level
+		}
+	}
+	return ""
+}
+
+// Allowed reports whether a document of the given level, "" meaning Public,
+// is readable by a caller allowed the levels of allowed. A nil allowed list
+// places no restriction; an empty one allows nothing.
+func Allowed(allowed []string, level string) bool {
+	if allowed == nil {
+		return true
+	}
+	if level == "" {
+		level = Public
+	}
+	for _, l := range allowed {
+		if l == level {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package access
+
+import "testing"
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    Rule
+		wantErr bool
+	}{
+		{"/internal/**=internal", Rule{"/internal/**", Internal}, false},
+		{" /security/** = confidential ", Rule{"/security/**", Confidential}, false},
+		{"/**=public", Rule{"/**", Public}, false},
+		{"/internal/**=secret", Rule{}, true},
+		{"internal/**=internal", Rule{}, true},
+		{"/internal/[=internal", Rule{}, true},
+		{"/internal/**=", Rule{}, true},
+		{"/internal/**", Rule{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got, err := ParseRule(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLevelOf(t *testing.T) {
+	rules, err := ParseRules([]string{
+		"/docs/internal/incidents/**=confidential",
+		"/docs/internal/**=internal",
+		"/docs/blog/**=public",
+	})
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://example.com/docs/internal/incidents/2024-01", Confidential},
+		{"https://example.com/docs/internal/runbook", Internal},
+		{"https://example.com/docs/internal", Internal},
+		{"https://example.com/docs/blog/launch", Public},
+		{"https://example.com/docs/guide", ""},
+		{"https://example.com", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if got := LevelOf(rules, tt.url); got != tt.want {
+				t.Errorf("LevelOf(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		level   string
+		want    bool
+	}{
+		{"no restriction", nil, Confidential, true},
+		{"nothing allowed", []string{}, Public, false},
+		{"untagged is public", []string{Public}, "", true},
+		{"untagged not allowed", []string{Internal}, "", false},
+		{"allowed level", []string{Public, Internal}, Internal, true},
+		{"level above", []string{Public, Internal}, Confidential, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Allowed(tt.allowed, tt.level); got != tt.want {
+				t.Errorf("Allowed(%v, %q) = %v, want %v", tt.allowed, tt.level, got, tt.want)
+			}
+		})
+	}
+}
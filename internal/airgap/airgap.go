@@ -0,0 +1,216 @@
+// Package airgap makes generated skills safe to deploy into isolated environments.
+// It rewrites Markdown so that nothing in a document can trigger a network request
+// (external links become annotated plain text, remote images and embeds are dropped)
+// and verifies that a finished skill contains no remaining external references.
+//
+// Fenced code blocks, inline code, and the YAML frontmatter are left untouched:
+// URLs there are inert text (e.g., sample code or the source_url citation).
+package airgap
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/mdprose"
+)
+
+var (
+	// imagePattern matches Markdown images: ![alt](url "title")
+	imagePattern = regexp.MustCompile(`^!\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)$`)
+	// linkPattern matches Markdown links whose text may itself contain a bracketed
+	// annotation, as produced for linked images: [text](url "title")
+	linkPattern = regexp.MustCompile(`^\[((?:[^\[\]]|\[[^\]]*\])*)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)$`)
+	// autolinkPattern matches Markdown autolinks: <https://example.com>
+	autolinkPattern = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]*:[^>\s]+)>$`)
+	// htmlPattern matches raw HTML tags carrying a src, href, srcset, poster, or data attribute
+	htmlPattern = regexp.MustCompile(`(?i)^<[a-z][a-z0-9]*\b[^>]*\b(?:src|href|srcset|poster|data)\s*=\s*["']?([^"'\s>]+)[^>]*>$`)
+	// bareURLPattern matches URLs in prose that renderers would autolink
+	bareURLPattern = regexp.MustCompile(`^(?:https?|ftp)://[^\s<>()\[\]` + "`" + `]+$`)
+
+	// referencePattern finds every candidate reference in a span of prose; each
+	// match is classified by the anchored patterns above, tried in this order
+	referencePattern = regexp.MustCompile(
+		`!\[[^\]]*\]\([^)]*\)` +
+			`|\[(?:[^\[\]]|\[[^\]]*\])*\]\([^)]*\)` +
+			`|<[a-zA-Z][a-zA-Z0-9+.-]*:[^>\s]+>` +
+			`|(?i:<[a-z][a-z0-9]*\b[^>]*\b(?:src|href|srcset|poster|data)\s*=[^>]*>)` +
+			`|(?:https?|ftp)://[^\s<>()\[\]` + "`" + `]+`)
+)
+
+// Stats counts the references neutralized by Rewrite.
+type Stats struct {
+	// Links is the number of external links turned into annotated text.
+	Links int
+	// Images is the number of remote images replaced by a placeholder.
+	Images int
+	// Embeds is the number of raw HTML tags with external sources removed.
+	Embeds int
+	// BareURLs is the number of bare URLs wrapped in inline code.
+	BareURLs int
+}
+
+// Total returns the number of references neutralized.
+func (s Stats) Total() int {
+	return s.Links + s.Images + s.Embeds + s.BareURLs
+}
+
+// Violation is an external reference left in a skill.
+type Violation struct {
+	// File is the path of the offending file, relative to the skill directory.
+	File string
+	// Line is the 1-based line number of the reference.
+	Line int
+	// Reference is the offending Markdown or HTML snippet.
+	Reference string
+}
+
+// String formats the violation as "file:line: reference".
+func (v Violation) String() string {
+	return fmt.Sprintf("%s:%d: %s", v.File, v.Line, v.Reference)
+}
+
+// IsExternal reports whether a link target points outside the skill: any URL with
+// a scheme (other than inline data: URIs) or a protocol-relative URL.
+// Relative paths and fragment-only anchors are local.
+func IsExternal(target string) bool {
+	if strings.HasPrefix(target, "//") {
+		return true
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		// Unparseable targets can't be proven local
+		return strings.Contains(target, ":")
+	}
+	return u.Scheme != "" && !strings.EqualFold(u.Scheme, "data")
+}
+
+// Rewrite neutralizes every external reference in a Markdown document:
+//
+//	[text](https://example.com/x)  -> text (external: `https://example.com/x`)
+//	![alt](https://cdn.example/a.png) -> *[image omitted: alt]*
+//	<img src="https://cdn.example/a.png"> -> *[embedded content omitted]*
+//	https://example.com            -> `https://example.com`
+//
+// Local links and images are kept. Returns the rewritten content and what was changed.
+func Rewrite(content string) (string, Stats) {
+	var stats Stats
+	out := mdprose.Map(content, func(_ int, prose string) string {
+		return referencePattern.ReplaceAllStringFunc(prose, func(ref string) string {
+			return rewriteReference(ref, &stats)
+		})
+	})
+	return out, stats
+}
+
+// rewriteReference returns the inert replacement for a single matched reference.
+func rewriteReference(ref string, stats *Stats) string {
+	if m := imagePattern.FindStringSubmatch(ref); m != nil {
+		if !IsExternal(m[2]) {
+			return ref
+		}
+		stats.Images++
+		if alt := strings.TrimSpace(m[1]); alt != "" {
+			return "*[image omitted: " + alt + "]*"
+		}
+		return "*[image omitted]*"
+	}
+	if m := linkPattern.FindStringSubmatch(ref); m != nil {
+		// The link text may contain nested references (e.g., a linked image)
+		text := referencePattern.ReplaceAllStringFunc(m[1], func(inner string) string {
+			return rewriteReference(inner, stats)
+		})
+		if !IsExternal(m[2]) {
+			// Keep the destination as written: everything after "[" + text + "]"
+			return "[" + text + "]" + ref[len(m[1])+2:]
+		}
+		stats.Links++
+		if strings.TrimSpace(text) == "" {
+			return "(external: `" + m[2] + "`)"
+		}
+		return text + " (external: `" + m[2] + "`)"
+	}
+	if m := autolinkPattern.FindStringSubmatch(ref); m != nil {
+		if !IsExternal(m[1]) {
+			return ref
+		}
+		stats.BareURLs++
+		return "`" + m[1] + "`"
+	}
+	if m := htmlPattern.FindStringSubmatch(ref); m != nil {
+		if !IsExternal(m[1]) {
+			return ref
+		}
+		stats.Embeds++
+		return "*[embedded content omitted]*"
+	}
+	if bareURLPattern.MatchString(ref) {
+		stats.BareURLs++
+		// Sentence punctuation after a URL is not part of it
+		u := strings.TrimRight(ref, ".,;:!?'\"")
+		return "`" + u + "`" + ref[len(u):]
+	}
+	return ref
+}
+
+// externalReferences returns the references in prose that point outside the skill.
+func externalReferences(prose string) []string {
+	var found []string
+	for _, ref := range referencePattern.FindAllString(prose, -1) {
+		var stats Stats
+		if rewriteReference(ref, &stats); stats.Total() > 0 {
+			found = append(found, ref)
+		}
+	}
+	return found
+}
+
+// RewriteFile rewrites the Markdown file at path in place.
+func RewriteFile(path string) (Stats, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	out, stats := Rewrite(string(content))
+	if stats.Total() == 0 {
+		return stats, nil
+	}
+	if err := os.WriteFile(path, []byte(out), 0644); err != nil {
+		return Stats{}, fmt.Errorf("failed to write file: %w", err)
+	}
+	return stats, nil
+}
+
+// Check scans every Markdown file in the skill directory and returns the external
+// references that remain. An empty result means the skill is safe for air-gapped use.
+func Check(skillDir string) ([]Violation, error) {
+	var violations []Violation
+	err := filepath.Walk(skillDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || filepath.Ext(path) != ".md" {
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(skillDir, path)
+		mdprose.Map(string(content), func(line int, prose string) string {
+			for _, ref := range externalReferences(prose) {
+				violations = append(violations, Violation{File: filepath.ToSlash(rel), Line: line, Reference: ref})
+			}
+			return prose
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan skill: %w", err)
+	}
+	return violations, nil
+}
@@ -0,0 +1,147 @@
+package airgap
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsExternal(t *testing.T) {
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{"https://example.com/a", true},
+		{"http://example.com", true},
+		{"//cdn.example.com/a.js", true},
+		{"mailto:team@example.com", true},
+		{"data:image/png;base64,AAAA", false},
+		{"guide.md", false},
+		{"../assets/logo.png", false},
+		{"#install", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsExternal(tt.target); got != tt.want {
+			t.Errorf("IsExternal(%q) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+		stats Stats
+	}{
+		{
+			name:  "external link",
+			input: "See [the guide](https://example.com/guide \"Guide\").",
+			want:  "See the guide (external: `https://example.com/guide`).",
+			stats: Stats{Links: 1},
+		},
+		{
+			name:  "local link kept",
+			input: "See [install](install.md#setup).",
+			want:  "See [install](install.md#setup).",
+		},
+		{
+			name:  "remote image",
+			input: "![Architecture](https://cdn.example.com/arch.png)",
+			want:  "*[image omitted: Architecture]*",
+			stats: Stats{Images: 1},
+		},
+		{
+			name:  "local image kept",
+			input: "![Logo](assets/logo.png)",
+			want:  "![Logo](assets/logo.png)",
+		},
+		{
+			name:  "linked remote image",
+			input: "[![badge](https://img.shields.io/x.svg)](https://ci.example.com)",
+			want:  "*[image omitted: badge]* (external: `https://ci.example.com`)",
+			stats: Stats{Links: 1, Images: 1},
+		},
+		{
+			name:  "raw html embed",
+			input: `<script src="https://cdn.example.com/lib.js"></script>`,
+			want:  `*[embedded content omitted]*</script>`,
+			stats: Stats{Embeds: 1},
+		},
+		{
+			name:  "autolink and bare url",
+			input: "Visit <https://example.com> or https://example.org/docs.",
+			want:  "Visit `https://example.com` or `https://example.org/docs`.",
+			stats: Stats{BareURLs: 2},
+		},
+		{
+			name:  "inline code untouched",
+			input: "Run `curl https://api.example.com/v1` now.",
+			want:  "Run `curl https://api.example.com/v1` now.",
+		},
+		{
+			name:  "fenced code untouched",
+			input: "```html\n<img src=\"https://cdn.example.com/a.png\">\n```\n[x](https://example.com)\n",
+			want:  "```html\n<img src=\"https://cdn.example.com/a.png\">\n```\nx (external: `https://example.com`)\n",
+			stats: Stats{Links: 1},
+		},
+		{
+			name:  "frontmatter untouched",
+			input: "---\nsource_url: \"https://example.com/a\"\n---\n\n[a](https://example.com/b)\n",
+			want:  "---\nsource_url: \"https://example.com/a\"\n---\n\na (external: `https://example.com/b`)\n",
+			stats: Stats{Links: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stats := Rewrite(tt.input)
+			if got != tt.want {
+				t.Errorf("Rewrite() =\n%q\nwant\n%q", got, tt.want)
+			}
+			if stats != tt.stats {
+				t.Errorf("stats = %+v, want %+v", stats, tt.stats)
+			}
+			if again, _ := Rewrite(got); again != got {
+				t.Errorf("Rewrite() is not idempotent: %q", again)
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	skillDir := t.TempDir()
+	files := map[string]string{
+		"SKILL.md":         "---\nname: x\n---\n\nSearch `docs/`.\n",
+		"docs/clean.md":    "---\nsource_url: \"https://example.com\"\n---\n\nSee [local](other.md).\n",
+		"docs/leaky.md":    "# Title\n\nText\n![x](https://cdn.example.com/x.png)\n",
+		"docs/ignored.txt": "https://example.com",
+	}
+	for name, content := range files {
+		path := filepath.Join(skillDir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write fixture: %v", err)
+		}
+	}
+
+	violations, err := Check(skillDir)
+	if err != nil {
+		t.Fatalf("Check() returned error: %v", err)
+	}
+	if len(violations) != 1 {
+		t.Fatalf("Check() = %v, want 1 violation", violations)
+	}
+	if got := violations[0].String(); !strings.HasPrefix(got, "docs/leaky.md:4: ") {
+		t.Errorf("violation = %q, want docs/leaky.md:4", got)
+	}
+
+	if _, err := RewriteFile(filepath.Join(skillDir, "docs/leaky.md")); err != nil {
+		t.Fatalf("RewriteFile() returned error: %v", err)
+	}
+	if violations, _ := Check(skillDir); len(violations) != 0 {
+		t.Errorf("Check() after RewriteFile = %v, want none", violations)
+	}
+}
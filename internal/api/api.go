@@ -0,0 +1,354 @@
+// Package api serves a generated skill over an HTTP JSON API, so that a team
+// can host one skill centrally and query it from several agents.
+//
+// The API has six read-only endpoints:
+//
+//	GET /search?q=...     Search the documents (see search.Searcher.Search)
+//	GET /docs             List the documents (see search.Searcher.Documents)
+//	GET /docs/{path}      Read a document (see search.Searcher.Document)
+//	GET /related/{path}   List the documents similar to one (see search.Searcher.Related)
+//	GET /manifest         Read the skill's manifest.json (see skillgen.Manifest)
+//	GET /capabilities     Describe the query syntax and limits (see search.GetCapabilities)
+//
+// Searches and related documents go through a search.Limiter, so that the
+// clients of a shared server can't run more than
+// search.DefaultMaxConcurrentQueries scans at once, nor one longer, or with
+// more results, than search.DefaultQueryLimits allow.
+//
+// Every response is JSON; errors are objects with an "error" message.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/access"
+	"github.com/f4ah6o/site2skill-go/internal/metrics"
+	"github.com/f4ah6o/site2skill-go/internal/search"
+	"github.com/f4ah6o/site2skill-go/internal/skillgen"
+)
+
+// DefaultMaxResults is the number of search results unless the request asks
+// for another with max_results.
+const DefaultMaxResults = 10
+
+// Server answers the API requests about one skill. It is safe for concurrent use.
+type Server struct {
+	searcher *search.Searcher
+	limiter  *search.Limiter
+	access   []string
+	mux      *http.ServeMux
+	metrics  *metrics.Server
+	handler  http.Handler
+}
+
+// NewServer returns a server of the skill in skillDir. access lists the access
+// levels the clients are allowed (see search.SearchOptions.Access): documents
+// of other levels are neither found, listed, read, nor shown in the manifest.
+// Nil allows every document.
+//
+// Returns an error if skillDir has no docs directory.
+func NewServer(skillDir string, access []string) (*Server, error) {
+	searcher, err := search.NewSearcher(skillDir)
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		searcher: searcher,
+		limiter:  search.NewLimiter(search.DefaultMaxConcurrentQueries, search.DefaultQueryLimits),
+		access:   access,
+		mux:      http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/search", s.search)
+	s.mux.HandleFunc("/docs", s.documents)
+	s.mux.HandleFunc("/docs/{path...}", s.document)
+	s.mux.HandleFunc("/related/{path...}", s.related)
+	s.mux.HandleFunc("/manifest", s.manifest)
+	s.mux.HandleFunc("/capabilities", s.capabilities)
+	s.mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not found: "+r.URL.Path)
+	})
+	s.handler = http.HandlerFunc(s.serve)
+	return s, nil
+}
+
+// SetMetrics records the requests and searches of the server in m (see
+// metrics.Server); nil records nothing. Call it before serving requests.
+func (s *Server) SetMetrics(m *metrics.Server) {
+	s.metrics = m
+	s.handler = m.Handler(http.HandlerFunc(s.serve), "/search", "/docs", "/related", "/manifest", "/capabilities")
+}
+
+// ServeHTTP answers an API request. The server can be mounted under a path
+// prefix with http.StripPrefix.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// serve answers an API request of a method the API accepts.
+func (s *Server) serve(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed: "+r.Method)
+		return
+	}
+	s.mux.ServeHTTP(w, r)
+}
+
+// searchResponse is the response of GET /search.
+type searchResponse struct {
+	Query   string                `json:"query"`
+	Results []search.SearchResult `json:"results"`
+}
+
+// search answers GET /search. The query parameters are q (required), all,
+// regex, fuzzy, stem, max_results (capped by the limits of the server),
+// context_lines, recency_half_life (a Go duration, e.g., 720h; see
+// SearchOptions.RecencyHalfLife), and the document filters (see
+// filterOptions).
+func (s *Server) search(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	opts, err := s.filterOptions(params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts.Query = params.Get("q")
+	if strings.TrimSpace(opts.Query) == "" {
+		writeError(w, http.StatusBadRequest, "missing query: q is required")
+		return
+	}
+	for name, flag := range map[string]*bool{"all": &opts.MatchAll, "regex": &opts.Regex, "fuzzy": &opts.Fuzzy, "stem": &opts.Stem} {
+		if *flag, err = boolParam(params, name); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if opts.MaxResults, err = intParam(params, "max_results", DefaultMaxResults); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if opts.ContextLines, err = intParam(params, "context_lines", 0); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if v := params.Get("recency_half_life"); v != "" {
+		if opts.RecencyHalfLife, err = time.ParseDuration(v); err != nil || opts.RecencyHalfLife <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid recency_half_life %q: expected a positive duration like 720h", v))
+			return
+		}
+	}
+
+	opts.SkillDir = s.searcher.SkillDir()
+	start := time.Now()
+	results, err := s.limiter.Search(r.Context(), opts)
+	s.metrics.ObserveSearch(start, err)
+	if err != nil {
+		writeError(w, searchStatus(r, err, opts.Regex), err.Error())
+		return
+	}
+	if results == nil {
+		results = []search.SearchResult{}
+	}
+	writeJSON(w, http.StatusOK, searchResponse{Query: opts.Query, Results: results})
+}
+
+// searchStatus returns the status of the response to the request r whose
+// search, or search for related documents, failed with err: queries over the
+// limits of the server, and invalid regular expressions if regex is set, are
+// the client's error, and a query running out of time makes the server
+// unavailable for it.
+func searchStatus(r *http.Request, err error, regex bool) int {
+	switch {
+	case errors.Is(err, search.ErrQueryTooLarge):
+		return http.StatusBadRequest
+	case errors.Is(err, context.DeadlineExceeded) && r.Context().Err() == nil:
+		return http.StatusServiceUnavailable
+	case regex:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// documents answers GET /docs with the documents passing the filters of the
+// query parameters (see filterOptions).
+func (s *Server) documents(w http.ResponseWriter, r *http.Request) {
+	opts, err := s.filterOptions(r.URL.Query())
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	docs, err := s.searcher.Documents(r.Context(), opts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"documents": docs})
+}
+
+// documentResponse is the response of GET /docs/{path}: the description of
+// the document and its content, frontmatter included.
+type documentResponse struct {
+	search.DocumentInfo
+	Content string `json:"content"`
+}
+
+// document answers GET /docs/{path}, path being relative to the docs
+// directory, e.g., GET /docs/guide/install.md for docs/guide/install.md.
+func (s *Server) document(w http.ResponseWriter, r *http.Request) {
+	path := r.PathValue("path")
+	content, info, err := s.searcher.Document(path, search.SearchOptions{Access: s.access})
+	if errors.Is(err, search.ErrDocumentNotFound) {
+		writeError(w, http.StatusNotFound, "document not found: "+path)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, documentResponse{DocumentInfo: info, Content: content})
+}
+
+// related answers GET /related/{path} with the description of the document at
+// path, like GET /docs/{path}, and the documents most similar to it. The query
+// parameters are semantic, max_results (capped by the limits of the server),
+// and the document filters (see filterOptions).
+func (s *Server) related(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	opts, err := s.filterOptions(params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if opts.Semantic, err = boolParam(params, "semantic"); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if opts.MaxResults, err = intParam(params, "max_results", DefaultMaxResults); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	opts.MaxResults = s.limiter.Limits().ClampResults(opts.MaxResults)
+
+	path := r.PathValue("path")
+	_, seed, err := s.searcher.Document(path, search.SearchOptions{Access: s.access})
+	var related []search.RelatedDocument
+	if err == nil {
+		err = s.limiter.Do(r.Context(), func(ctx context.Context) error {
+			var err error
+			related, err = s.searcher.Related(ctx, path, opts)
+			return err
+		})
+	}
+	if errors.Is(err, search.ErrDocumentNotFound) {
+		writeError(w, http.StatusNotFound, "document not found: "+path)
+		return
+	}
+	if err != nil {
+		writeError(w, searchStatus(r, err, false), err.Error())
+		return
+	}
+	if related == nil {
+		related = []search.RelatedDocument{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"document": seed, "related": related})
+}
+
+// manifest answers GET /manifest with the skill's manifest.json, without the
+// documents the clients aren't allowed.
+func (s *Server) manifest(w http.ResponseWriter, r *http.Request) {
+	m, err := skillgen.ReadManifest(s.searcher.SkillDir())
+	if errors.Is(err, os.ErrNotExist) {
+		writeError(w, http.StatusNotFound, "the skill has no "+skillgen.ManifestFile)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	docs := m.Documents[:0]
+	for _, doc := range m.Documents {
+		if access.Allowed(s.access, doc.Access) {
+			docs = append(docs, doc)
+		}
+	}
+	m.Documents = docs
+	writeJSON(w, http.StatusOK, m)
+}
+
+// capabilities answers GET /capabilities with the query syntax of the search,
+// its filters, and the limits of the server (see search.Capabilities).
+func (s *Server) capabilities(w http.ResponseWriter, r *http.Request) {
+	limits := s.limiter.Limits()
+	writeJSON(w, http.StatusOK, search.GetCapabilities(s.searcher.SkillDir(), &limits))
+}
+
+// filterOptions returns the search options with the access levels of the
+// server and the document filters of the query parameters: locale,
+// path_prefix, tag, and fetched_after (an RFC 3339 time or a YYYY-MM-DD date).
+func (s *Server) filterOptions(params url.Values) (search.SearchOptions, error) {
+	opts := search.SearchOptions{
+		Access:     s.access,
+		Locale:     params.Get("locale"),
+		PathPrefix: params.Get("path_prefix"),
+		Tag:        params.Get("tag"),
+	}
+	if v := params.Get("fetched_after"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			if t, err = time.Parse(time.DateOnly, v); err != nil {
+				return opts, fmt.Errorf("invalid fetched_after %q: expected an RFC 3339 time or a YYYY-MM-DD date", v)
+			}
+		}
+		opts.FetchedAfter = t
+	}
+	return opts, nil
+}
+
+// boolParam returns the boolean query parameter name, false if absent.
+func boolParam(params url.Values, name string) (bool, error) {
+	v := params.Get(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s %q: expected true or false", name, v)
+	}
+	return b, nil
+}
+
+// intParam returns the positive integer query parameter name, or def if absent.
+func intParam(params url.Values, name string, def int) (int, error) {
+	v := params.Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid %s %q: expected a positive integer", name, v)
+	}
+	return n, nil
+}
+
+// writeJSON writes v as the JSON body of a response with the given status.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writeError writes an error response with the given status and message.
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
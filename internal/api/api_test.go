@@ -0,0 +1,151 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/f4ah6o/site2skill-go/internal/metrics"
+)
+
+func writeSkill(t *testing.T) string {
+	t.Helper()
+	skillDir := t.TempDir()
+	files := map[string]string{
+		"docs/install.md":    "---\ntitle: \"Install\"\nsource_url: \"https://docs.example.com/install\"\nfetched_at: \"2024-05-01T00:00:00Z\"\n---\n# Install\n\nDownload the binary.\n",
+		"docs/api/auth.md":   "---\ntitle: \"Auth\"\nsource_url: \"https://docs.example.com/api/auth\"\nfetched_at: \"2024-01-01T00:00:00Z\"\n---\n# Auth\n\nInstall a token. Download the binary first.\n",
+		"docs/internal.md":   "---\ntitle: \"Runbook\"\naccess: \"internal\"\n---\n# Runbook\n\nInstall with the internal tool.\n",
+		"manifest.json":      `{"name":"example","documents":[{"path":"docs/install.md","title":"Install","section":""},{"path":"docs/internal.md","title":"Runbook","section":"","access":"internal"}]}`,
+		"docs/.index/x.json": "{}",
+	}
+	for name, content := range files {
+		path := filepath.Join(skillDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return skillDir
+}
+
+func TestServer(t *testing.T) {
+	s, err := NewServer(writeSkill(t), []string{"public"})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		// want and notWant are substrings of the response body
+		want    []string
+		notWant []string
+	}{
+		{"search", "GET", "/search?q=install", 200, []string{`"query": "install"`, `"file": "docs/install.md"`, `"file": "docs/api/auth.md"`}, []string{"internal.md"}},
+		{"search with filters", "GET", "/search?q=install&path_prefix=/api&max_results=5", 200, []string{"docs/api/auth.md"}, []string{"docs/install.md"}},
+		{"search fetched after", "GET", "/search?q=install&fetched_after=2024-03-01", 200, []string{"docs/install.md"}, []string{"auth.md"}},
+		{"search recency", "GET", "/search?q=install&recency_half_life=720h", 200, []string{`"file": "docs/install.md"`, `"score"`}, nil},
+		{"search invalid half-life", "GET", "/search?q=install&recency_half_life=30d", 400, []string{"recency_half_life"}, nil},
+		{"search no results", "GET", "/search?q=nothing", 200, []string{`"results": []`}, nil},
+		{"search without query", "GET", "/search", 400, []string{`"error"`}, nil},
+		{"search invalid max", "GET", "/search?q=install&max_results=x", 400, []string{"max_results"}, nil},
+		{"search invalid bool", "GET", "/search?q=install&all=maybe", 400, []string{"invalid all"}, nil},
+		{"search invalid regex", "GET", "/search?q=(&regex=true", 400, []string{`"error"`}, nil},
+		{"search too many terms", "GET", "/search?q=" + strings.Repeat("word+", 40) + "word", 400, []string{"query too large"}, nil},
+		{"list", "GET", "/docs", 200, []string{`"path": "docs/install.md"`, `"title": "Auth"`}, []string{"Runbook"}},
+		{"list with tag", "GET", "/docs?tag=none", 200, []string{`"documents": []`}, nil},
+		{"document", "GET", "/docs/api/auth.md", 200, []string{`"path": "docs/api/auth.md"`, `"content": "---\ntitle`, "Install a token."}, nil},
+		{"document not allowed", "GET", "/docs/internal.md", 404, []string{"document not found"}, []string{"internal tool"}},
+		{"document missing", "GET", "/docs/missing.md", 404, []string{"document not found"}, nil},
+		{"document outside docs", "GET", "/docs/..%2fmanifest.json", 404, []string{"document not found"}, nil},
+		{"related", "GET", "/related/install.md?max_results=5", 200, []string{`"path": "docs/install.md"`, `"path": "docs/api/auth.md"`, `"similarity"`}, []string{"internal.md"}},
+		{"related not allowed", "GET", "/related/internal.md", 404, []string{"document not found"}, nil},
+		{"related without embeddings", "GET", "/related/install.md?semantic=true", 500, []string{"no embeddings"}, nil},
+		{"manifest", "GET", "/manifest", 200, []string{`"name": "example"`, "docs/install.md"}, []string{"Runbook"}},
+		{"capabilities", "GET", "/capabilities", 200, []string{`"syntax"`, `"documents": 3`, `"MaxResults": 100`}, nil},
+		{"unknown path", "GET", "/other", 404, []string{`"error"`}, nil},
+		{"wrong method", "POST", "/search?q=install", 405, []string{"method not allowed"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, srv.URL+tt.path, nil)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("%s %s error = %v", tt.method, tt.path, err)
+			}
+			defer resp.Body.Close()
+			var body any
+			if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+				t.Fatalf("invalid JSON response: %v", err)
+			}
+			data, _ := json.MarshalIndent(body, "", "  ")
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", resp.StatusCode, tt.wantStatus, data)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(string(data), w) {
+					t.Errorf("response missing %q: %s", w, data)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(string(data), w) {
+					t.Errorf("response contains %q: %s", w, data)
+				}
+			}
+		})
+	}
+}
+
+func TestServerNoManifest(t *testing.T) {
+	skillDir := writeSkill(t)
+	os.Remove(filepath.Join(skillDir, "manifest.json"))
+	s, err := NewServer(skillDir, nil)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest("GET", "/manifest", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+
+	if _, err := NewServer(t.TempDir(), nil); err == nil {
+		t.Error("NewServer() of a directory without docs succeeded, want error")
+	}
+}
+
+func TestServerMetrics(t *testing.T) {
+	s, err := NewServer(writeSkill(t), nil)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	reg := metrics.NewRegistry()
+	s.SetMetrics(metrics.NewServer(reg))
+	for _, path := range []string{"/search?q=install", "/search?q=(&regex=true", "/docs/install.md", "/other"} {
+		s.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	var b strings.Builder
+	reg.WriteText(&b)
+	for _, want := range []string{
+		`site2skill_http_requests_total{route="/search",code="200"} 1`,
+		`site2skill_http_requests_total{route="/search",code="400"} 1`,
+		`site2skill_http_requests_total{route="/docs",code="200"} 1`,
+		`site2skill_http_requests_total{route="other",code="404"} 1`,
+		`site2skill_search_queries_total{status="ok"} 1`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, b.String())
+		}
+	}
+}
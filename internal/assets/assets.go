@@ -0,0 +1,450 @@
+// Package assets downloads the media referenced by converted documentation pages.
+// Images, SVG diagrams, and other media linked from Markdown are saved into a
+// shared assets/ folder and the Markdown is rewritten to point at the local copies,
+// so generated skills no longer depend on (or break with) the original site.
+// Assets are stored by the hash of their content, so that the same image served
+// at several URLs is stored once, and MapFile records the file of every URL.
+package assets
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+	"github.com/f4ah6o/site2skill-go/internal/mdprose"
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DirName is the name of the assets folder at the root of a skill.
+	DirName = "assets"
+	// DefaultMaxSize is the largest asset downloaded by default (10 MiB).
+	DefaultMaxSize = 10 << 20
+	// MapFile is the name of the file of the assets folder mapping the URLs of
+	// the downloaded assets to their files.
+	MapFile = "assets.json"
+)
+
+var (
+	// imagePattern matches Markdown images: ![alt](url "title")
+	imagePattern = regexp.MustCompile(`!\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(\s+"[^"]*")?\s*\)`)
+	// imgTagPattern matches the src attribute of raw HTML media tags left in the Markdown
+	imgTagPattern = regexp.MustCompile(`(?i)(<(?:img|source|video|audio)\b[^>]*\bsrc\s*=\s*["'])([^"']+)(["'])`)
+	// commonExtensions picks the usual extension for media types that have several
+	commonExtensions = map[string]string{
+		"image/jpeg":    ".jpg",
+		"image/png":     ".png",
+		"image/gif":     ".gif",
+		"image/svg+xml": ".svg",
+		"image/webp":    ".webp",
+		"video/mp4":     ".mp4",
+	}
+	// unsafeNameChars matches characters not allowed in asset file names
+	unsafeNameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]+`)
+)
+
+// Stats counts the outcome of localizing asset references.
+type Stats struct {
+	// Downloaded is the number of references rewritten to a local file.
+	Downloaded int
+	// Failed is the number of references left pointing at the remote URL.
+	Failed int
+	// Shared is the number of assets downloaded from a URL whose content was
+	// already stored from another URL, and which share its file.
+	Shared int
+}
+
+// Downloader fetches remote media into an assets directory and rewrites
+// Markdown references to them. Each URL is downloaded once per Downloader,
+// however many pages reference it, and each distinct content is stored once,
+// however many URLs serve it.
+type Downloader struct {
+	// assetsDir is where downloaded files are written
+	assetsDir string
+	// linkPrefix is prepended to file names in rewritten links (e.g., "../assets/")
+	linkPrefix string
+	// client performs the downloads
+	client *http.Client
+	// maxSize is the largest response body accepted, in bytes
+	maxSize int64
+	// userAgent is the User-Agent header of downloads; empty sends Go's default
+	userAgent string
+	// saved maps absolute asset URLs to their local file names; "" records a failure
+	saved map[string]string
+	// stored records the file names written to assetsDir
+	stored map[string]bool
+	// shared counts the downloads whose content was already stored
+	shared int
+}
+
+// New creates a Downloader that writes files into assetsDir.
+//
+// Rewritten links are relative to the skill's docs/ directory ("../assets/<file>"),
+// since that is where the Markdown files end up; use SetLinkPrefix to change this.
+func New(assetsDir string) *Downloader {
+	return &Downloader{
+		assetsDir:  assetsDir,
+		linkPrefix: "../" + DirName + "/",
+		client:     &http.Client{Timeout: 30 * time.Second},
+		maxSize:    DefaultMaxSize,
+		saved:      make(map[string]string),
+		stored:     make(map[string]bool),
+	}
+}
+
+// SetTransport sets the HTTP transport used for downloads (e.g., an httpcache.Transport).
+func (d *Downloader) SetTransport(rt http.RoundTripper) {
+	d.client.Transport = rt
+}
+
+// SetUserAgent sets the User-Agent header of downloads, that of the crawler
+// of the pages referencing the assets (see fetcher.FormatUserAgent).
+func (d *Downloader) SetUserAgent(userAgent string) {
+	d.userAgent = userAgent
+}
+
+// SetMaxSize sets the largest asset, in bytes, that will be downloaded.
+// Larger assets keep their remote reference.
+func (d *Downloader) SetMaxSize(n int64) {
+	d.maxSize = n
+}
+
+// SetLinkPrefix sets the path prepended to asset file names in rewritten links.
+func (d *Downloader) SetLinkPrefix(prefix string) {
+	d.linkPrefix = prefix
+}
+
+// LocalizeFile downloads the media referenced by the Markdown file at mdPath and
+// rewrites the references in place. Relative references are resolved against the
+// source_url in the file's frontmatter. References inside fenced code blocks are
+// left alone, as are references that fail to download.
+func (d *Downloader) LocalizeFile(mdPath string) (Stats, error) {
+	return d.LocalizeFileContext(context.Background(), mdPath)
+}
+
+// LocalizeFileContext is like LocalizeFile but stops downloading when ctx is done.
+// The file is then left unchanged and the context error is returned.
+func (d *Downloader) LocalizeFileContext(ctx context.Context, mdPath string) (Stats, error) {
+	content, err := os.ReadFile(mdPath)
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	out, stats := d.LocalizeContext(ctx, string(content), sourceURLOf(string(content)))
+	if err := ctx.Err(); err != nil {
+		return stats, err
+	}
+	if stats.Downloaded == 0 {
+		return stats, nil
+	}
+	if err := os.WriteFile(mdPath, []byte(out), 0644); err != nil {
+		return Stats{}, fmt.Errorf("failed to write file: %w", err)
+	}
+	return stats, nil
+}
+
+// Localize downloads the media referenced by a Markdown document and returns the
+// document with those references pointing at the local copies.
+// baseURL resolves relative references; it may be empty if all references are absolute.
+func (d *Downloader) Localize(content, baseURL string) (string, Stats) {
+	return d.LocalizeContext(context.Background(), content, baseURL)
+}
+
+// LocalizeContext is like Localize but aborts the download in flight when ctx is
+// done and leaves the remaining references untouched.
+func (d *Downloader) LocalizeContext(ctx context.Context, content, baseURL string) (string, Stats) {
+	var stats Stats
+	shared := d.shared
+	rewrite := func(ref string) (string, bool) {
+		if ctx.Err() != nil {
+			return ref, false
+		}
+		target, ok := resolve(baseURL, ref)
+		if !ok {
+			return ref, false
+		}
+		name, err := d.fetch(ctx, target)
+		if ctx.Err() != nil {
+			return ref, false
+		}
+		if err != nil {
+			warnlog.Printf("asset_download", "Warning: could not download asset %s: %v", target, err)
+			stats.Failed++
+			return ref, false
+		}
+		stats.Downloaded++
+		return d.linkPrefix + name, true
+	}
+
+	out := mapOutsideFences(content, func(prose string) string {
+		prose = imagePattern.ReplaceAllStringFunc(prose, func(m string) string {
+			sub := imagePattern.FindStringSubmatch(m)
+			local, ok := rewrite(sub[2])
+			if !ok {
+				return m
+			}
+			return "![" + sub[1] + "](" + local + sub[3] + ")"
+		})
+		return imgTagPattern.ReplaceAllStringFunc(prose, func(m string) string {
+			sub := imgTagPattern.FindStringSubmatch(m)
+			local, ok := rewrite(sub[2])
+			if !ok {
+				return m
+			}
+			return sub[1] + local + sub[3]
+		})
+	})
+	stats.Shared = d.shared - shared
+	return out, stats
+}
+
+// fetch downloads target into the assets directory, once, and returns the local file name.
+// Downloads aborted by ctx are not remembered as failed.
+func (d *Downloader) fetch(ctx context.Context, target string) (name string, err error) {
+	if name, ok := d.saved[target]; ok {
+		if name == "" {
+			return "", fmt.Errorf("earlier download failed")
+		}
+		return name, nil
+	}
+	d.saved[target] = ""
+	defer func() {
+		if err != nil && ctx.Err() != nil {
+			delete(d.saved, target)
+		}
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return "", err
+	}
+	if d.userAgent != "" {
+		req.Header.Set("User-Agent", d.userAgent)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		// Servers that don't label their media are trusted based on the URL's extension
+		mediaType, _, _ = mime.ParseMediaType(mime.TypeByExtension(path.Ext(resp.Request.URL.Path)))
+	}
+	if !isMedia(mediaType) {
+		return "", fmt.Errorf("unsupported content type %q", mediaType)
+	}
+	if resp.ContentLength > d.maxSize {
+		return "", fmt.Errorf("asset is larger than %d bytes", d.maxSize)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, d.maxSize+1))
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if int64(len(data)) > d.maxSize {
+		return "", fmt.Errorf("asset is larger than %d bytes", d.maxSize)
+	}
+
+	name = fileName(target, mediaType, data)
+	if err := d.store(name, data); err != nil {
+		return "", err
+	}
+	d.saved[target] = name
+	return name, nil
+}
+
+// store writes data, the content of the asset file name, to the assets
+// directory, unless a file of the same name, and so of the same content,
+// was already stored.
+func (d *Downloader) store(name string, data []byte) error {
+	if d.stored[name] {
+		d.shared++
+		return nil
+	}
+	if err := os.MkdirAll(d.assetsDir, 0755); err != nil {
+		return fmt.Errorf("failed to create assets directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(d.assetsDir, name), data, 0644); err != nil {
+		return fmt.Errorf("failed to write asset: %w", err)
+	}
+	d.stored[name] = true
+	return nil
+}
+
+// Map returns the file names of the assets downloaded so far by their URLs.
+func (d *Downloader) Map() map[string]string {
+	m := make(map[string]string, len(d.saved))
+	for target, name := range d.saved {
+		if name != "" {
+			m[target] = name
+		}
+	}
+	return m
+}
+
+// WriteMap writes MapFile in the assets directory, mapping the URLs of the
+// assets downloaded so far to their files. Nothing is written if none was.
+func (d *Downloader) WriteMap() error {
+	m := d.Map()
+	if len(m) == 0 {
+		return nil
+	}
+	return WriteMap(d.assetsDir, m)
+}
+
+// ReadMap returns the asset files by URL recorded in the MapFile of the
+// assets directory dir; an empty map if it has none.
+func ReadMap(dir string) (map[string]string, error) {
+	m := make(map[string]string)
+	data, err := os.ReadFile(filepath.Join(dir, MapFile))
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", MapFile, err)
+	}
+	return m, nil
+}
+
+// WriteMap writes m, asset files by URL, as the MapFile of the assets
+// directory dir, which must exist, sorted by URL.
+func WriteMap(dir string, m map[string]string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := atomicfile.WriteFile(filepath.Join(dir, MapFile), append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write %s: %w", MapFile, err)
+	}
+	return nil
+}
+
+// isMedia reports whether a Content-Type is an image, video, or audio format.
+func isMedia(mediaType string) bool {
+	return strings.HasPrefix(mediaType, "image/") ||
+		strings.HasPrefix(mediaType, "video/") ||
+		strings.HasPrefix(mediaType, "audio/")
+}
+
+// fileName derives the content-addressed file name of an asset: a hash of
+// data, its content, with the usual extension of its media type, or else that
+// of the URL target, so that the same content gets the same name whatever URL it
+// was downloaded from.
+//
+// Example: https://cdn.example.com/img/flow chart.svg?v=2 -> 1a2b3c4d5e6f7a8b.svg
+func fileName(target, mediaType string, data []byte) string {
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:8])
+
+	// The usual extension of the media type comes first, so that the same
+	// content at "logo.jpeg" and "logo.jpg" gets one name
+	ext := commonExtensions[mediaType]
+	if ext == "" {
+		if u, err := url.Parse(target); err == nil {
+			ext = strings.ToLower(path.Ext(path.Base(u.Path)))
+		}
+	}
+	if ext == "" || unsafeNameChars.MatchString(ext[1:]) || len(ext) > 6 {
+		ext = ""
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	return name + ext
+}
+
+// resolve returns the absolute http(s) URL of an asset reference, resolved against
+// baseURL. Local paths that can't be resolved, data: URIs, and other schemes are rejected.
+func resolve(baseURL, ref string) (string, bool) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return "", false
+	}
+	if !u.IsAbs() && !strings.HasPrefix(ref, "//") {
+		base, err := url.Parse(baseURL)
+		if err != nil || !base.IsAbs() {
+			return "", false
+		}
+		u = base.ResolveReference(u)
+	} else if u.Scheme == "" {
+		u.Scheme = "https"
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+	u.Fragment = ""
+	return u.String(), true
+}
+
+// sourceURLOf returns the source_url recorded in a document's frontmatter, if any.
+func sourceURLOf(content string) string {
+	frontmatter, _, ok := mdprose.SplitFrontmatter(content)
+	if !ok {
+		return ""
+	}
+	var fm struct {
+		SourceURL string `yaml:"source_url"`
+	}
+	if err := yaml.Unmarshal([]byte(frontmatter), &fm); err != nil {
+		return ""
+	}
+	return fm.SourceURL
+}
+
+// mapOutsideFences applies fn to every run of lines that is not inside a fenced
+// code block or the frontmatter. Sample code showing image syntax stays untouched.
+func mapOutsideFences(content string, fn func(string) string) string {
+	var b strings.Builder
+	if _, body, ok := mdprose.SplitFrontmatter(content); ok {
+		b.WriteString(content[:len(content)-len(body)])
+		content = body
+	}
+
+	var prose strings.Builder
+	flush := func() {
+		b.WriteString(fn(prose.String()))
+		prose.Reset()
+	}
+
+	fence := ""
+	for _, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			b.WriteString(line)
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flush()
+			fence = trimmed[:3]
+			b.WriteString(line)
+		default:
+			prose.WriteString(line)
+		}
+	}
+	flush()
+	return b.String()
+}
@@ -0,0 +1,216 @@
+package assets
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFileName(t *testing.T) {
+	tests := []struct {
+		target    string
+		mediaType string
+		ext       string
+	}{
+		{"https://cdn.example.com/img/flow%20chart.svg?v=2", "image/svg+xml", ".svg"},
+		{"https://example.com/diagram.PNG", "image/png", ".png"},
+		{"https://example.com/photo.jpeg", "image/jpeg", ".jpg"},
+		{"https://example.com/render?id=7", "image/png", ".png"},
+		{"https://example.com/clip.ogg", "audio/ogg", ".ogg"},
+	}
+
+	data := []byte("content")
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			got := fileName(tt.target, tt.mediaType, data)
+			if want := "ed7002b439e9ac84" + tt.ext; got != want {
+				t.Errorf("fileName(%q) = %q, want %q", tt.target, got, want)
+			}
+		})
+	}
+	if fileName("https://example.com/a.png", "image/png", []byte("other")) == fileName("https://example.com/a.png", "image/png", data) {
+		t.Error("fileName() is the same for different contents")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		base   string
+		ref    string
+		want   string
+		wantOK bool
+	}{
+		{"https://example.com/docs/page", "img/a.png", "https://example.com/docs/img/a.png", true},
+		{"https://example.com/docs/page", "https://cdn.example.com/a.png#x", "https://cdn.example.com/a.png", true},
+		{"https://example.com/docs/page", "//cdn.example.com/a.png", "https://cdn.example.com/a.png", true},
+		{"", "img/a.png", "", false},
+		{"https://example.com/", "data:image/png;base64,AAAA", "", false},
+		{"https://example.com/", "ftp://example.com/a.png", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, ok := resolve(tt.base, tt.ref)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("resolve(%q, %q) = %q, %v; want %q, %v", tt.base, tt.ref, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestLocalizeFile(t *testing.T) {
+	requests := make(map[string]int)
+	var agents []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/img/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		agents = append(agents, r.Header.Get("User-Agent"))
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("PNG"))
+	})
+	mux.HandleFunc("/img/arch.svg", func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write([]byte("<svg/>"))
+	})
+	mux.HandleFunc("/page.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html></html>"))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	doc := `---
+title: "Guide"
+source_url: "` + server.URL + `/docs/guide"
+---
+
+![Logo](/img/logo.png "The logo")
+
+<img src="../img/arch.svg" alt="Architecture">
+
+Again: ![Logo](` + server.URL + `/img/logo.png)
+
+![Missing](/img/missing.png) ![Not media](/page.html)
+
+` + "```markdown\n![Sample](/img/logo.png)\n```\n"
+
+	dir := t.TempDir()
+	mdPath := filepath.Join(dir, "guide.md")
+	if err := os.WriteFile(mdPath, []byte(doc), 0644); err != nil {
+		t.Fatalf("failed to write doc: %v", err)
+	}
+
+	d := New(filepath.Join(dir, DirName))
+	d.SetUserAgent("test-bot/1.0 (+https://example.com/bot)")
+	stats, err := d.LocalizeFile(mdPath)
+	if err != nil {
+		t.Fatalf("LocalizeFile() returned error: %v", err)
+	}
+	if stats.Downloaded != 3 || stats.Failed != 2 {
+		t.Errorf("stats = %+v, want 3 downloaded and 2 failed", stats)
+	}
+	if requests["/img/logo.png"] != 1 {
+		t.Errorf("logo downloaded %d times, want once", requests["/img/logo.png"])
+	}
+	if len(agents) != 1 || agents[0] != "test-bot/1.0 (+https://example.com/bot)" {
+		t.Errorf("User-Agent = %q, want the one set", agents)
+	}
+
+	logo := fileName(server.URL+"/img/logo.png", "image/png", []byte("PNG"))
+	arch := fileName(server.URL+"/img/arch.svg", "image/svg+xml", []byte("<svg/>"))
+	for _, name := range []string{logo, arch} {
+		if _, err := os.Stat(filepath.Join(dir, DirName, name)); err != nil {
+			t.Errorf("asset %s not saved: %v", name, err)
+		}
+	}
+
+	got, _ := os.ReadFile(mdPath)
+	for _, want := range []string{
+		`![Logo](../assets/` + logo + ` "The logo")`,
+		`<img src="../assets/` + arch + `" alt="Architecture">`,
+		`Again: ![Logo](../assets/` + logo + `)`,
+		`![Missing](/img/missing.png)`,
+		"```markdown\n![Sample](/img/logo.png)\n```",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestLocalizeSharesContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("PNG"))
+	}))
+	defer server.Close()
+
+	dir := filepath.Join(t.TempDir(), DirName)
+	d := New(dir)
+	doc := "![A](" + server.URL + "/a/logo.png) ![B](" + server.URL + "/b/logo.png?v=2)\n"
+	out, stats := d.Localize(doc, "")
+	if stats.Downloaded != 2 || stats.Shared != 1 {
+		t.Errorf("stats = %+v, want 2 downloaded and 1 shared", stats)
+	}
+	name := fileName(server.URL+"/a/logo.png", "image/png", []byte("PNG"))
+	if want := "![A](../assets/" + name + ") ![B](../assets/" + name + ")\n"; out != want {
+		t.Errorf("Localize() = %q, want %q", out, want)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Errorf("assets = %v, %v; want the one file", entries, err)
+	}
+
+	if err := d.WriteMap(); err != nil {
+		t.Fatalf("WriteMap() returned error: %v", err)
+	}
+	got, err := ReadMap(dir)
+	if err != nil {
+		t.Fatalf("ReadMap() returned error: %v", err)
+	}
+	want := map[string]string{server.URL + "/a/logo.png": name, server.URL + "/b/logo.png?v=2": name}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadMap() = %v, want %v", got, want)
+	}
+	if got, err := ReadMap(t.TempDir()); err != nil || len(got) != 0 {
+		t.Errorf("ReadMap() without a map = %v, %v; want an empty map", got, err)
+	}
+}
+
+func TestLocalizeFileContextCancelled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("PNG"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	mdPath := filepath.Join(dir, "guide.md")
+	doc := "![Logo](" + server.URL + "/logo.png)\n"
+	if err := os.WriteFile(mdPath, []byte(doc), 0644); err != nil {
+		t.Fatalf("failed to write doc: %v", err)
+	}
+
+	d := New(filepath.Join(dir, DirName))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := d.LocalizeFileContext(ctx, mdPath); !errors.Is(err, context.Canceled) {
+		t.Fatalf("LocalizeFileContext() error = %v, want context.Canceled", err)
+	}
+	if got, _ := os.ReadFile(mdPath); string(got) != doc {
+		t.Errorf("cancelled run rewrote the file:\n%s", got)
+	}
+
+	// Nothing was remembered as failed, so a later run downloads the asset
+	stats, err := d.LocalizeFile(mdPath)
+	if err != nil || stats.Downloaded != 1 {
+		t.Errorf("LocalizeFile() = %+v, %v; want 1 download", stats, err)
+	}
+}
@@ -0,0 +1,141 @@
+// Package atomicfile writes files atomically, and only when their content
+// changes.
+//
+// Every build of a skill produces its files again, most of them identical to
+// those of the previous build. Rewriting them anyway would touch their
+// modification times, so that rsync, git, and file watchers would see every
+// file as changed. WriteFile leaves a file whose content is already the one to
+// write as it is, and replaces the others through a temporary file renamed
+// over them, so that readers and interrupted builds never observe a partially
+// written file.
+package atomicfile
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Perm is the permission of the files written.
+const Perm = 0644
+
+// WriteFile writes data to the file at path, unless it already holds data:
+// the file is then left untouched, keeping its modification time. Otherwise
+// data is written to a temporary file next to path, which is renamed over
+// path once complete. The directory of path must exist.
+//
+// Returns whether the file was written, or an error if it can't be.
+func WriteFile(path string, data []byte) (bool, error) {
+	if Same(path, data) {
+		return false, nil
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return false, err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), Perm)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return false, err
+	}
+	return true, nil
+}
+
+// Same reports whether the file at path holds data, comparing their sizes and
+// then their SHA-256 checksums. A file that can't be read isn't the same.
+func Same(path string, data []byte) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() != int64(len(data)) {
+		return false
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return sha256.Sum256(content) == sha256.Sum256(data)
+}
+
+// CopyFile copies the file at src to dst with WriteFile.
+//
+// Returns whether dst was written, or an error if src can't be read or dst
+// written.
+func CopyFile(src, dst string) (bool, error) {
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", src, err)
+	}
+	written, err := WriteFile(dst, content)
+	if err != nil {
+		return false, fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	return written, nil
+}
+
+// Sync makes the directory dst a copy of the regular files of the directory
+// src, without its subdirectories: the files of src are copied with WriteFile,
+// and the regular files of dst that src doesn't have are removed. A missing
+// src empties dst of its files.
+//
+// Returns the number of files written, or an error if a file can't be read,
+// written, or removed.
+func Sync(src, dst string) (int, error) {
+	entries, err := os.ReadDir(src)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	keep := make(map[string]bool, len(entries))
+	written := 0
+	if len(entries) > 0 {
+		if err := os.MkdirAll(dst, 0755); err != nil {
+			return 0, err
+		}
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		keep[e.Name()] = true
+		w, err := CopyFile(filepath.Join(src, e.Name()), filepath.Join(dst, e.Name()))
+		if err != nil {
+			return written, err
+		}
+		if w {
+			written++
+		}
+	}
+	if err := RemoveStale(dst, keep); err != nil {
+		return written, err
+	}
+	return written, nil
+}
+
+// RemoveStale removes the regular files of the directory dir whose names
+// aren't in keep. A missing dir has nothing to remove.
+func RemoveStale(dir string, keep map[string]bool) error {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || keep[e.Name()] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, e.Name())); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", e.Name(), err)
+		}
+	}
+	return nil
+}
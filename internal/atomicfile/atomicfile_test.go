@@ -0,0 +1,129 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestWriteFile(t *testing.T) {
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name        string
+		existing    *string
+		data        string
+		wantWritten bool
+	}{
+		{name: "new file", existing: nil, data: "hello\n", wantWritten: true},
+		{name: "same content", existing: ptr("hello\n"), data: "hello\n", wantWritten: false},
+		{name: "same size", existing: ptr("hallo\n"), data: "hello\n", wantWritten: true},
+		{name: "longer", existing: ptr("hello\n"), data: "hello, world\n", wantWritten: true},
+		{name: "emptied", existing: ptr("hello\n"), data: "", wantWritten: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "doc.md")
+			if tt.existing != nil {
+				if err := os.WriteFile(path, []byte(*tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.Chtimes(path, old, old); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			written, err := WriteFile(path, []byte(tt.data))
+			if err != nil {
+				t.Fatalf("WriteFile() returned error: %v", err)
+			}
+			if written != tt.wantWritten {
+				t.Errorf("WriteFile() = %v, want %v", written, tt.wantWritten)
+			}
+			content, err := os.ReadFile(path)
+			if err != nil || string(content) != tt.data {
+				t.Errorf("file holds %q (error %v), want %q", content, err, tt.data)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if kept := info.ModTime().Equal(old); kept == tt.wantWritten {
+				t.Errorf("modification time %v, kept = %v, want kept = %v", info.ModTime(), kept, !tt.wantWritten)
+			}
+			if info.Mode().Perm() != Perm {
+				t.Errorf("permission = %v, want %v", info.Mode().Perm(), os.FileMode(Perm))
+			}
+			if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+				t.Errorf("directory holds %d files, want the file only (no temporary file left)", len(entries))
+			}
+		})
+	}
+}
+
+func TestWriteFileMissingDir(t *testing.T) {
+	if _, err := WriteFile(filepath.Join(t.TempDir(), "missing", "doc.md"), []byte("x")); err == nil {
+		t.Error("WriteFile() into a missing directory returned no error")
+	}
+}
+
+func TestSync(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "assets")
+	dst := filepath.Join(t.TempDir(), "assets")
+	write := func(dir string, files map[string]string) {
+		t.Helper()
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	names := func(dir string) []string {
+		t.Helper()
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		sort.Strings(names)
+		return names
+	}
+
+	write(src, map[string]string{"a.png": "a", "b.csv": "b"})
+	write(dst, map[string]string{"a.png": "a", "stale.png": "s"})
+	if err := os.Mkdir(filepath.Join(dst, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	written, err := Sync(src, dst)
+	if err != nil {
+		t.Fatalf("Sync() returned error: %v", err)
+	}
+	if written != 1 {
+		t.Errorf("Sync() wrote %d files, want 1 (a.png is unchanged)", written)
+	}
+	if got, want := names(dst), []string{"a.png", "b.csv", "sub"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dst holds %v, want %v", got, want)
+	}
+
+	// A missing source empties the destination of its files
+	if _, err := Sync(filepath.Join(t.TempDir(), "missing"), dst); err != nil {
+		t.Fatalf("Sync() of a missing source returned error: %v", err)
+	}
+	if got, want := names(dst), []string{"sub"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("dst holds %v, want %v", got, want)
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
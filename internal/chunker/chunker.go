@@ -0,0 +1,302 @@
+// Package chunker splits long Markdown documents into several files along
+// heading boundaries, so that every file fits a token budget that skill
+// consumers can load at once.
+package chunker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/converter"
+	"github.com/f4ah6o/site2skill-go/internal/mdprose"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultBudget is the default number of tokens per file.
+const DefaultBudget = 25000
+
+// Chunker splits Markdown documents longer than a token budget.
+type Chunker struct {
+	// budget is the largest number of tokens per file
+	budget int
+	// tokenizer counts tokens; Estimator unless set with SetTokenizer
+	tokenizer Tokenizer
+}
+
+// New creates a Chunker that splits documents longer than budget tokens,
+// counted with Estimator. A budget of 0 or less disables splitting.
+func New(budget int) *Chunker {
+	return &Chunker{budget: budget, tokenizer: Estimator{}}
+}
+
+// SetTokenizer sets the Tokenizer used to count tokens, for budgets matching a
+// specific model's encoding.
+func (c *Chunker) SetTokenizer(t Tokenizer) {
+	c.tokenizer = t
+}
+
+// Split splits a Markdown document into parts of at most the token budget,
+// returned in document order. Documents within the budget are returned whole.
+//
+// Parts are cut at headings: first before every H1, then, within sections still
+// too long, before every H2, and so on down to H6, and finally between
+// paragraphs. A section split across parts repeats its heading at the top of
+// each later part. Adjacent pieces are packed into one part while they fit.
+// Code blocks are never cut, so a part holding a single oversized block, table,
+// or paragraph may exceed the budget.
+func (c *Chunker) Split(markdown string) []string {
+	if c.budget <= 0 || c.fits(markdown, c.budget) {
+		return []string{markdown}
+	}
+	return c.pieces(strings.TrimSpace(markdown), 1, c.budget)
+}
+
+// fits reports whether text is within budget tokens.
+func (c *Chunker) fits(text string, budget int) bool {
+	return c.tokenizer.CountTokens(text) <= budget
+}
+
+// pieces splits text at headings of the given level and deeper until every
+// piece fits budget, then packs the pieces. The pieces of a section that is
+// split further are given a smaller budget, leaving room for the repeated heading.
+func (c *Chunker) pieces(text string, level, budget int) []string {
+	if c.fits(text, budget) {
+		return []string{text}
+	}
+	if level > 6 {
+		return c.pack(splitBlocks(text, func(string) bool { return true }), budget)
+	}
+
+	var out []string
+	for _, section := range splitBlocks(text, func(line string) bool { return headingLevel(line) == level }) {
+		if c.fits(section, budget) {
+			out = append(out, section)
+			continue
+		}
+		heading, _, _ := strings.Cut(section, "\n")
+		if headingLevel(heading) != level {
+			out = append(out, c.pieces(section, level+1, budget)...)
+			continue
+		}
+		sub := c.pieces(section, level+1, max(budget-c.tokenizer.CountTokens(heading+"\n\n"), 1))
+		for i := 1; i < len(sub); i++ {
+			sub[i] = heading + "\n\n" + sub[i]
+		}
+		out = append(out, sub...)
+	}
+	return c.pack(out, budget)
+}
+
+// pack joins adjacent pieces while the result fits budget. A piece holding
+// only headings always joins the next one, which is what they introduce.
+func (c *Chunker) pack(pieces []string, budget int) []string {
+	var out []string
+	for _, piece := range pieces {
+		if n := len(out); n > 0 && (headingsOnly(out[n-1]) || c.fits(out[n-1]+"\n\n"+piece, budget)) {
+			out[n-1] += "\n\n" + piece
+			continue
+		}
+		out = append(out, piece)
+	}
+	return out
+}
+
+// splitBlocks splits text before every line outside code blocks for which
+// starts returns true; at a paragraph level, before the first line after each
+// blank line. Blocks are trimmed and empty blocks dropped.
+func splitBlocks(text string, starts func(line string) bool) []string {
+	var blocks []string
+	var current []string
+	flush := func() {
+		if block := strings.TrimSpace(strings.Join(current, "\n")); block != "" {
+			blocks = append(blocks, block)
+		}
+		current = nil
+	}
+
+	fence := ""
+	blank := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			current = append(current, line)
+			continue
+		}
+		if trimmed == "" {
+			blank = true
+			current = append(current, line)
+			continue
+		}
+		if blank && starts(line) || headingLevel(line) > 0 && starts(line) {
+			flush()
+		}
+		blank = false
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			marker := trimmed[:1]
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, marker))]
+		}
+		current = append(current, line)
+	}
+	flush()
+	return blocks
+}
+
+// headingsOnly reports whether every non-blank line of text is a heading.
+func headingsOnly(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" && headingLevel(line) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// headingLevel returns the level of an ATX heading line, or 0.
+func headingLevel(line string) int {
+	n := 0
+	for n < len(line) && line[n] == '#' {
+		n++
+	}
+	if n == 0 || n > 6 || n < len(line) && line[n] != ' ' && line[n] != '\t' {
+		return 0
+	}
+	return n
+}
+
+// ChunkFile splits the Markdown file at path into parts (see Split). The first
+// part replaces the file; part N is written next to it as <name>-part-N.md.
+// Every part keeps the file's frontmatter, with the title marked "(part N of M)",
+// part and parts fields added, and word_count and outline recomputed, and ends
+// with links to the previous and next parts.
+//
+// Returns the paths of all parts, or just path if the file fits the budget.
+func (c *Chunker) ChunkFile(path string) ([]string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var meta yaml.Node
+	frontmatter, body, ok := mdprose.SplitFrontmatter(string(content))
+	if ok {
+		if err := yaml.Unmarshal([]byte(frontmatter), &meta); err != nil {
+			return nil, fmt.Errorf("failed to parse frontmatter: %w", err)
+		}
+	}
+
+	parts := c.Split(body)
+	if len(parts) == 1 {
+		return []string{path}, nil
+	}
+
+	base := strings.TrimSuffix(filepath.Base(path), ".md")
+	names := make([]string, len(parts))
+	for i := range parts {
+		names[i] = base + ".md"
+		if i > 0 {
+			names[i] = fmt.Sprintf("%s-part-%d.md", base, i+1)
+		}
+	}
+
+	// Match the headings of each part with those of the whole document, so that
+	// anchors follow their heading into its part. A heading repeated at the top
+	// of a part has already been matched and is skipped over.
+	headings := converter.Headings(body)
+	next := 0
+
+	paths := make([]string, len(parts))
+	for i, part := range parts {
+		ids := make(map[string]string)
+		for _, h := range converter.Headings(part) {
+			if next < len(headings) && headings[next].Level == h.Level && headings[next].Text == h.Text {
+				ids[headings[next].ID] = h.ID
+				next++
+			}
+		}
+
+		var nav []string
+		if i > 0 {
+			nav = append(nav, fmt.Sprintf("Previous: [part %d](%s)", i, names[i-1]))
+		}
+		if i < len(parts)-1 {
+			nav = append(nav, fmt.Sprintf("Next: [part %d](%s)", i+2, names[i+1]))
+		}
+		text := part + fmt.Sprintf("\n\n---\n\n*Part %d of %d. %s.*\n", i+1, len(parts), strings.Join(nav, " · "))
+
+		if meta.Kind == yaml.DocumentNode && len(meta.Content) == 1 && meta.Content[0].Kind == yaml.MappingNode {
+			fm, err := partFrontmatter(meta.Content[0], part, ids, i+1, len(parts))
+			if err != nil {
+				return nil, fmt.Errorf("failed to write frontmatter: %w", err)
+			}
+			text = "---\n" + fm + "---\n\n" + text
+		}
+
+		paths[i] = filepath.Join(filepath.Dir(path), names[i])
+		if err := os.WriteFile(paths[i], []byte(text), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write file: %w", err)
+		}
+	}
+	return paths, nil
+}
+
+// partFrontmatter renders the frontmatter mapping of a document for one of its
+// parts. ids maps the heading IDs of the document to those of the part; anchors
+// to headings outside the part are dropped.
+func partFrontmatter(mapping *yaml.Node, part string, ids map[string]string, n, total int) (string, error) {
+	fm := &yaml.Node{Kind: yaml.MappingNode}
+	set := func(key string, value *yaml.Node) {
+		for i := 0; i+1 < len(fm.Content); i += 2 {
+			if fm.Content[i].Value == key {
+				fm.Content[i+1] = value
+				return
+			}
+		}
+		fm.Content = append(fm.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	}
+	scalar := func(tag, value string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+	}
+
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		key, value := mapping.Content[i], mapping.Content[i+1]
+		if key.Value == "anchors" && value.Kind == yaml.MappingNode {
+			anchors := &yaml.Node{Kind: yaml.MappingNode}
+			for j := 0; j+1 < len(value.Content); j += 2 {
+				if id, ok := ids[value.Content[j+1].Value]; ok {
+					anchors.Content = append(anchors.Content, value.Content[j], scalar("!!str", id))
+				}
+			}
+			if len(anchors.Content) == 0 {
+				continue
+			}
+			value = anchors
+		}
+		fm.Content = append(fm.Content, key, value)
+	}
+	for i := 0; i+1 < len(fm.Content); i += 2 {
+		if fm.Content[i].Value == "title" {
+			title := fmt.Sprintf("%s (part %d of %d)", fm.Content[i+1].Value, n, total)
+			set("title", scalar("!!str", title))
+		}
+	}
+	set("word_count", scalar("!!int", strconv.Itoa(converter.WordCount(part))))
+	outline := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, heading := range converter.Outline(part) {
+		outline.Content = append(outline.Content, scalar("!!str", heading))
+	}
+	set("outline", outline)
+	set("part", scalar("!!int", strconv.Itoa(n)))
+	set("parts", scalar("!!int", strconv.Itoa(total)))
+
+	data, err := yaml.Marshal(fm)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
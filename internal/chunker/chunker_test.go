@@ -0,0 +1,169 @@
+package chunker
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// words counts whitespace-separated words, for budgets that are easy to reason about.
+type words struct{}
+
+func (words) CountTokens(text string) int { return len(strings.Fields(text)) }
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name     string
+		budget   int
+		markdown string
+		want     []string
+	}{
+		{
+			name:     "fits",
+			budget:   100,
+			markdown: "# Title\n\nShort page.",
+			want:     []string{"# Title\n\nShort page."},
+		},
+		{
+			name:     "disabled",
+			budget:   0,
+			markdown: "# Title\n\n" + strings.Repeat("word ", 50),
+			want:     []string{"# Title\n\n" + strings.Repeat("word ", 50)},
+		},
+		{
+			name:     "sections packed under the budget, the H1 repeated",
+			budget:   12,
+			markdown: "# API\n\nIntro text.\n\n## Auth\n\nUse a key.\n\n## Tokens\n\nTokens expire daily.\n\n## Errors\n\nErrors are JSON.",
+			want: []string{
+				"# API\n\nIntro text.\n\n## Auth\n\nUse a key.",
+				"# API\n\n## Tokens\n\nTokens expire daily.\n\n## Errors\n\nErrors are JSON.",
+			},
+		},
+		{
+			name:     "long section split deeper with its heading repeated",
+			budget:   10,
+			markdown: "## Methods\n\n### get\n\nReturns one item by id.\n\n### list\n\nReturns every item in pages.",
+			want: []string{
+				"## Methods\n\n### get\n\nReturns one item by id.",
+				"## Methods\n\n### list\n\nReturns every item in pages.",
+			},
+		},
+		{
+			name:     "paragraphs when there are no headings",
+			budget:   6,
+			markdown: "One two three four.\n\nFive six seven.\n\nEight nine ten eleven.",
+			want:     []string{"One two three four.", "Five six seven.", "Eight nine ten eleven."},
+		},
+		{
+			name:     "code blocks are not cut",
+			budget:   6,
+			markdown: "## Example\n\n```sh\n# not a heading\nmake build\n\nmake test\n```\n\n## Next\n\nDone.",
+			want: []string{
+				"## Example\n\n```sh\n# not a heading\nmake build\n\nmake test\n```",
+				"## Next\n\nDone.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New(tt.budget)
+			c.SetTokenizer(words{})
+			if got := c.Split(tt.markdown); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split() = %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "reference.md")
+	doc := `---
+title: Reference
+source_url: https://example.com/docs/reference
+word_count: 18
+outline:
+    - '# Reference'
+    - '## Auth'
+    - '## Tokens'
+anchors:
+    auth-header: auth
+    tokens: tokens
+---
+
+# Reference
+
+Everything about the API.
+
+## Auth
+
+Send the key in a header.
+
+## Tokens
+
+Tokens expire after a day.
+`
+	if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(16)
+	c.SetTokenizer(words{})
+	paths, err := c.ChunkFile(path)
+	if err != nil {
+		t.Fatalf("ChunkFile() returned error: %v", err)
+	}
+	want := []string{path, filepath.Join(dir, "reference-part-2.md")}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("ChunkFile() = %v, want %v", paths, want)
+	}
+
+	first, _ := os.ReadFile(paths[0])
+	second, _ := os.ReadFile(paths[1])
+	checks := []struct {
+		content []byte
+		want    []string
+	}{
+		{first, []string{
+			"title: Reference (part 1 of 2)\n",
+			"source_url: https://example.com/docs/reference\n",
+			"word_count: 12\n",
+			"outline:\n    - '# Reference'\n    - '## Auth'\nanchors:\n    auth-header: auth\npart: 1\nparts: 2\n---\n\n# Reference",
+			"*Part 1 of 2. Next: [part 2](reference-part-2.md).*\n",
+		}},
+		{second, []string{
+			"title: Reference (part 2 of 2)\n",
+			"outline:\n    - '# Reference'\n    - '## Tokens'\nanchors:\n    tokens: tokens\n",
+			"---\n\n# Reference\n\n## Tokens\n\nTokens expire after a day.",
+			"*Part 2 of 2. Previous: [part 1](reference.md).*\n",
+		}},
+	}
+	for i, check := range checks {
+		for _, want := range check.want {
+			if !strings.Contains(string(check.content), want) {
+				t.Errorf("part %d missing %q:\n%s", i+1, want, check.content)
+			}
+		}
+	}
+
+	// A file within the budget is left alone
+	short := filepath.Join(dir, "short.md")
+	os.WriteFile(short, []byte("---\ntitle: Short\n---\n\nBrief.\n"), 0644)
+	if paths, err := c.ChunkFile(short); err != nil || len(paths) != 1 {
+		t.Errorf("ChunkFile(short) = %v, %v; want the file unchanged", paths, err)
+	}
+
+	// Frontmatter with CRLF line endings is split from the body all the same
+	crlf := filepath.Join(dir, "crlf.md")
+	os.WriteFile(crlf, []byte(strings.ReplaceAll(doc, "\n", "\r\n")), 0644)
+	paths, err = c.ChunkFile(crlf)
+	if err != nil || len(paths) != 2 {
+		t.Fatalf("ChunkFile(crlf) = %v, %v; want 2 parts", paths, err)
+	}
+	if first, _ := os.ReadFile(paths[0]); !strings.Contains(string(first), "title: Reference (part 1 of 2)\n") {
+		t.Errorf("the first part of a CRLF file lost its title:\n%s", first)
+	}
+}
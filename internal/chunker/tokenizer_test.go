@@ -0,0 +1,25 @@
+package chunker
+
+import "testing"
+
+func TestEstimator(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello world", 2},
+		{"internationalization", 4},
+		{"version 1234567", 5},
+		{"f(x) == 42", 6},
+		{"line one\nline two", 5},
+		{"日本語のドキュメント", 10},
+		{"    indented", 3},
+	}
+
+	for _, tt := range tests {
+		if got := (Estimator{}).CountTokens(tt.text); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,559 @@
+// Package config loads site2skill.yaml, the file that keeps generate options and
+// named per-site profiles under version control instead of on the command line.
+//
+// A config file has optional defaults shared by every profile and a set of
+// named profiles:
+//
+//	version: 1
+//	defaults:
+//	  format: claude
+//	  locale:
+//	    priority: [en, ja]
+//	profiles:
+//	  stripe:
+//	    url: https://stripe.com/docs/api
+//	    name: stripe
+//	    crawl:
+//	      exclude: [changelog]
+//	  stripe-ja:
+//	    extends: stripe
+//	    name: stripe-ja
+//	    locale:
+//	      priority: [ja, en]
+//
+// Settings shared across many config files can be kept in one place and pulled
+// in with include; see Parse. Files are checked against the schema before use.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultFileName is the config file looked up when no path is given.
+const DefaultFileName = "site2skill.yaml"
+
+// CurrentVersion is the config schema version understood by this build.
+const CurrentVersion = 1
+
+// File is the parsed content of a config file.
+type File struct {
+	// Version is the schema version; 0 (unset) means CurrentVersion.
+	Version int `yaml:"version"`
+	// Include lists config files whose defaults and profiles are loaded first,
+	// relative to the including file.
+	Include []string `yaml:"include"`
+	// Defaults holds settings shared by every profile.
+	Defaults Profile `yaml:"defaults"`
+	// Profiles maps profile names to their settings.
+	Profiles map[string]Profile `yaml:"profiles"`
+}
+
+// Profile holds the generate options for one site. Unset fields (empty strings,
+// nil lists and maps, nil booleans) fall back to the defaults and then to the
+// command-line defaults.
+type Profile struct {
+	// Extends names a profile whose settings this one starts from.
+	Extends string `yaml:"extends"`
+	// URL is the documentation site to crawl.
+	URL string `yaml:"url"`
+	// Seeds are more start URLs, possibly on other hosts, crawled into the same skill.
+	Seeds []string `yaml:"seeds"`
+	// Name is the skill name.
+	Name string `yaml:"name"`
+	// Format is the output format: claude, codex, or both.
+	Format string `yaml:"format"`
+	// Global installs to the global skills directory.
+	Global *bool `yaml:"global"`
+	// TempDir is the directory for intermediate files.
+	TempDir string `yaml:"temp_dir"`
+	// Locale configures locale-aware crawling.
+	Locale Locale `yaml:"locale"`
+	// Crawl configures which URLs are crawled.
+	Crawl Crawl `yaml:"crawl"`
+	// Conversion configures HTML to Markdown conversion.
+	Conversion Conversion `yaml:"conversion"`
+	// Cache configures the on-disk HTTP and conversion caches.
+	Cache Cache `yaml:"cache"`
+	// Output configures what is produced besides the skill itself.
+	Output Output `yaml:"output"`
+	// Plugins lists the commands of the plugins extending the build.
+	Plugins []string `yaml:"plugins"`
+	// Auth holds credentials sent with every request; see Secret.
+	Auth Auth `yaml:"auth"`
+	// Schedule is how often the cron command updates the skill (e.g., 24h or 7d).
+	Schedule string `yaml:"schedule"`
+}
+
+// Locale configures locale-aware crawling.
+type Locale struct {
+	// Priority is the ordered list of preferred locales.
+	Priority []string `yaml:"priority"`
+	// Disabled turns locale priority mode off.
+	Disabled *bool `yaml:"disabled"`
+	// Mode declares where the site puts the locale: "path" (/ja/docs) or "query" (?hl=ja).
+	Mode string `yaml:"mode"`
+	// Param is the query parameter carrying the locale; required in query mode.
+	Param string `yaml:"param"`
+	// File is a locale definitions file (see fetcher.LoadLocaleDefinitions).
+	File string `yaml:"file"`
+	// Locales lists extra locale codes recognized in paths.
+	Locales []string `yaml:"locales"`
+	// Aliases maps site-specific locale codes to canonical ones.
+	Aliases map[string]string `yaml:"aliases"`
+	// Each builds one documentation tree per locale of Priority instead of
+	// picking the first available one.
+	Each *bool `yaml:"each"`
+}
+
+// Crawl configures which URLs are crawled.
+type Crawl struct {
+	// Include restricts crawling to URLs containing one of these strings.
+	Include []string `yaml:"include"`
+	// Exclude skips URLs containing any of these strings.
+	Exclude []string `yaml:"exclude"`
+	// Feed treats the URL as an RSS or Atom feed whose entries become the documents.
+	Feed *bool `yaml:"feed"`
+	// FeedArticles downloads the article page of every feed entry.
+	FeedArticles *bool `yaml:"feed_articles"`
+	// Repo treats the URL as a GitHub or GitLab repository whose Markdown sources become the documents.
+	Repo *bool `yaml:"repo"`
+	// Confluence treats the URL as a Confluence space whose pages are read through the REST API.
+	Confluence *bool `yaml:"confluence"`
+	// Notion treats the URL as a Notion page whose tree of pages is read through the Notion API.
+	Notion *bool `yaml:"notion"`
+	// IncludePDF downloads the linked PDF documents and converts them into Markdown.
+	IncludePDF *bool `yaml:"include_pdf"`
+	// Sitemaps also crawls the pages listed in the sitemaps of robots.txt.
+	Sitemaps *bool `yaml:"sitemaps"`
+	// IgnoreCanonical saves pages under their own URL whatever their canonical link.
+	IgnoreCanonical *bool `yaml:"ignore_canonical"`
+	// SkipExternalCanonical skips pages whose canonical URL is outside the crawl scope.
+	SkipExternalCanonical *bool `yaml:"skip_external_canonical"`
+	// Resolve lists curl-style host overrides, "host:port:address".
+	Resolve []string `yaml:"resolve"`
+	// HostHeader crawls the server of the URL as this host.
+	HostHeader string `yaml:"host_header"`
+	// Sandbox restricts the crawl to the hosts of its start URLs and
+	// AllowHosts, pinning their addresses.
+	Sandbox *bool `yaml:"sandbox"`
+	// AllowHosts are the hosts the sandbox allows besides those of the crawl
+	// (e.g., [cdn.example.com, "*.assets.example.com"]).
+	AllowHosts []string `yaml:"allow_hosts"`
+	// MaxRuntime is how long the build may run at most (e.g., 30m).
+	MaxRuntime string `yaml:"max_runtime"`
+	// RecheckAfter is the freshness window of the pages (e.g., 7d): only the
+	// pages fetched longer ago than it are requested again.
+	RecheckAfter string `yaml:"recheck_after"`
+	// Proxy is the URL of the HTTP, HTTPS, or SOCKS5 proxy requests go through.
+	Proxy string `yaml:"proxy"`
+	// CACert is the path of a PEM file of certificate authorities to trust.
+	CACert string `yaml:"ca_cert"`
+	// Insecure skips the verification of TLS certificates.
+	Insecure *bool `yaml:"insecure"`
+	// Politeness is the path of a politeness file giving hosts their own
+	// delay, time-of-day window, and User-Agent suffix.
+	Politeness string `yaml:"politeness"`
+	// MaxConnsPerHost is the number of connections opened to a host at most,
+	// and kept open between requests for reuse.
+	MaxConnsPerHost *int `yaml:"max_conns_per_host"`
+	// NoHTTP2 disables HTTP/2, for servers whose HTTP/2 support is broken.
+	NoHTTP2 *bool `yaml:"no_http2"`
+	// NoAdaptiveThrottle keeps the politeness delay of the hosts answering
+	// 429 or 503, or much more slowly than usual.
+	NoAdaptiveThrottle *bool `yaml:"no_adaptive_throttle"`
+	// StripParams are the query parameters removed from the URLs crawled
+	// besides the tracking parameters (e.g., [ref, "session_*"]).
+	StripParams []string `yaml:"strip_params"`
+	// TrailingSlash is the trailing slash policy of the URLs crawled: keep,
+	// strip, or add.
+	TrailingSlash string `yaml:"trailing_slash"`
+	// Robots is how robots.txt is followed: strict, default, or off.
+	Robots string `yaml:"robots"`
+	// ConsentCookies are cookies sent with every request, as "name=value",
+	// to get past consent walls (e.g., ["CookieConsent=true"]).
+	ConsentCookies []string `yaml:"consent_cookies"`
+	// NoMetaRefresh saves the pages redirecting with a meta refresh instead
+	// of crawling the page they redirect to.
+	NoMetaRefresh *bool `yaml:"no_meta_refresh"`
+	// KeepInterstitials saves the pages that look like consent walls or
+	// JavaScript notices instead of skipping them.
+	KeepInterstitials *bool `yaml:"keep_interstitials"`
+	// KeepNoIndex saves the pages whose robots directives say noindex
+	// instead of skipping them.
+	KeepNoIndex *bool `yaml:"keep_noindex"`
+	// Device is the kind of client the crawler presents itself as: "desktop" or "mobile".
+	Device string `yaml:"device"`
+	// UserAgent is the template of the User-Agent of the crawler, with the
+	// placeholders {version}, {contact}, and {run_id}.
+	UserAgent string `yaml:"user_agent"`
+	// Contact is the URL or email address where site operators can reach
+	// whoever runs the crawl, written in place of {contact} in the User-Agent.
+	Contact string `yaml:"contact"`
+	// MaxRedirects is the number of redirects followed per request.
+	MaxRedirects *int `yaml:"max_redirects"`
+	// MaxPageSize is the size of the largest page downloaded (e.g., 10MB).
+	MaxPageSize string `yaml:"max_page_size"`
+	// MaxTotalSize is the number of bytes the crawl downloads at most (e.g., 1GB).
+	MaxTotalSize string `yaml:"max_total_size"`
+	// ContentTypes are the media types of the responses saved (e.g.,
+	// [text/html, text/markdown]).
+	ContentTypes []string `yaml:"content_types"`
+	// LinkAttributes are the attributes of any element whose values are
+	// crawled as links (e.g., [data-href, data-route]).
+	LinkAttributes []string `yaml:"link_attributes"`
+	// Compress stores the crawled HTML pages gzip-compressed.
+	Compress *bool `yaml:"compress"`
+	// VersionPriority crawls only the first of these documentation versions
+	// that exists (e.g., [latest, v2]).
+	VersionPriority []string `yaml:"version_priority"`
+}
+
+// Conversion configures HTML to Markdown conversion.
+type Conversion struct {
+	// ContentSelector selects the main content by CSS selector instead of Readability.
+	ContentSelector string `yaml:"content_selector"`
+	// StripSelectors lists CSS selectors of elements removed before conversion.
+	StripSelectors []string `yaml:"strip_selectors"`
+	// Platform is the platform profile applied: "auto", "none", or a platform
+	// name such as "docusaurus".
+	Platform string `yaml:"platform"`
+	// Converters lists converter commands, "MATCH=COMMAND", converting the pages they match.
+	Converters []string `yaml:"converters"`
+	// TableFallback renders tables that can't be GFM tables: "html" or "list".
+	TableFallback string `yaml:"table_fallback"`
+	// TableCSVRows also saves tables with more than this many rows as CSV; 0 disables it.
+	TableCSVRows *int `yaml:"table_csv_rows"`
+	// Admonitions renders note/warning boxes: "github", "blockquote", or "none".
+	Admonitions string `yaml:"admonitions"`
+	// PageTypes keeps only pages classified as these types: "docs", "marketing", or "legal".
+	PageTypes []string `yaml:"page_types"`
+	// LanguageFilter handles the pages in another language than their
+	// locale: "off", "flag", or "exclude".
+	LanguageFilter string `yaml:"language_filter"`
+	// DedupeSnippets replaces repeated long code samples with links to shared files.
+	DedupeSnippets *bool `yaml:"dedupe_snippets"`
+	// KeepDuplicates keeps pages whose content duplicates another page.
+	KeepDuplicates *bool `yaml:"keep_duplicates"`
+	// StitchPages converts each paginated article or listing into one document.
+	StitchPages *bool `yaml:"stitch_pages"`
+	// ChunkTokens splits pages longer than this many tokens into several files; 0 disables it.
+	ChunkTokens *int `yaml:"chunk_tokens"`
+}
+
+// Cache configures the on-disk HTTP and conversion caches.
+type Cache struct {
+	// Dir is the HTTP cache directory.
+	Dir string `yaml:"dir"`
+	// Disabled turns both caches off.
+	Disabled *bool `yaml:"disabled"`
+}
+
+// Output configures what is produced besides the skill itself.
+type Output struct {
+	// Report is the path of the JSON crawl report.
+	Report string `yaml:"report"`
+	// WARC is the path of the WARC archive the crawl is recorded into.
+	WARC string `yaml:"warc"`
+	// AirGapped neutralizes every external reference.
+	AirGapped *bool `yaml:"air_gapped"`
+	// AbsoluteLinks keeps links between pages as absolute URLs.
+	AbsoluteLinks *bool `yaml:"absolute_links"`
+	// DownloadAssets saves referenced media into assets/.
+	DownloadAssets *bool `yaml:"download_assets"`
+	// SignKey is the private key used to sign packages.
+	SignKey string `yaml:"sign_key"`
+	// RewriteURLs lists URL rewrite rules, "FROM=TO", applied to the output.
+	RewriteURLs []string `yaml:"rewrite_urls"`
+	// AccessRules lists access rules, "PATTERN=LEVEL", tagging the pages with access levels.
+	AccessRules []string `yaml:"access_rules"`
+	// Exports lists exports, "FORMAT=DIR", of the documents in other output formats.
+	Exports []string `yaml:"exports"`
+	// Publish lists the targets the packaged skills are published to, e.g.,
+	// "s3://BUCKET/PREFIX" or "git+URL#BRANCH".
+	Publish []string `yaml:"publish"`
+	// LLMsTxt is the directory where llms.txt and llms-full.txt are written.
+	LLMsTxt string `yaml:"llms_txt"`
+	// Embeddings configures the embeddings of the documents for semantic search.
+	Embeddings Embeddings `yaml:"embeddings"`
+	// Summaries configures the summaries written into the frontmatter of the documents.
+	Summaries Summaries `yaml:"summaries"`
+	// Translation configures the translation of the documents in other languages.
+	Translation Translation `yaml:"translation"`
+}
+
+// Embeddings configures the provider computing the embeddings of the documents.
+// The API key is read from the environment, never from the config file.
+type Embeddings struct {
+	// Provider is "openai" (an OpenAI-compatible endpoint) or "hash" (local); empty disables embeddings.
+	Provider string `yaml:"provider"`
+	// URL is the base URL of the OpenAI-compatible API (e.g., "http://localhost:11434/v1").
+	URL string `yaml:"url"`
+	// Model is the embedding model.
+	Model string `yaml:"model"`
+}
+
+// Summaries configures the provider writing the summaries of the documents.
+// The API key is read from the environment, never from the config file.
+type Summaries struct {
+	// Provider is "openai" (an OpenAI-compatible endpoint) or "lead" (local); empty disables summaries.
+	Provider string `yaml:"provider"`
+	// URL is the base URL of the OpenAI-compatible API (e.g., "http://localhost:11434/v1").
+	URL string `yaml:"url"`
+	// Model is the chat model.
+	Model string `yaml:"model"`
+}
+
+// Translation configures the provider translating the documents in another
+// language than the skill's. The API key is read from the environment, never
+// from the config file.
+type Translation struct {
+	// Provider is "openai" (an OpenAI-compatible endpoint) or "deepl"; empty disables translation.
+	Provider string `yaml:"provider"`
+	// URL is the base URL of the API (e.g., "http://localhost:11434/v1").
+	URL string `yaml:"url"`
+	// Model is the chat model of the "openai" provider.
+	Model string `yaml:"model"`
+	// To is the language the documents are translated into (e.g., "ja"); empty means the first locale of the locale priority.
+	To string `yaml:"to"`
+}
+
+// ParseDuration parses a positive duration in days ("7d") or as a Go duration
+// ("36h", "1h30m"), as the schedule and recheck_after keys take them.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid duration %q (expected a positive duration such as 7d or 12h)", s)
+}
+
+// Load reads and validates the config file at path.
+// If the file doesn't match the schema, the error is a *ValidationError listing every problem.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	return Parse(data, path)
+}
+
+// Parse validates and decodes config file content. filename is used in error
+// messages and to locate included files.
+//
+// Included files are loaded in order before the file itself, recursively. Their
+// defaults are merged (later files win), and their profiles are added; a profile
+// defined again by a later file or the including file replaces the earlier one.
+// The returned File holds the combined result, with Include cleared.
+func Parse(data []byte, filename string) (*File, error) {
+	l := &loader{loading: make(map[string]bool)}
+	src, err := l.parse(data, filename)
+	if err != nil {
+		return nil, err
+	}
+	l.checkSemantics(src)
+	if len(l.issues) > 0 {
+		return nil, &ValidationError{Issues: l.issues}
+	}
+	return &src.file, nil
+}
+
+// loader parses a config file and its includes, collecting schema issues.
+type loader struct {
+	validator
+	// loading holds the absolute paths of the files being parsed, to detect include cycles
+	loading map[string]bool
+}
+
+// source records where the settings of a section came from, for error positions.
+type source struct {
+	file string
+	node *yaml.Node
+	path string
+}
+
+// assembled is a config file combined with its includes.
+type assembled struct {
+	file File
+	// defaults lists the defaults sections, highest precedence first
+	defaults []source
+	// profiles maps each profile to the section that defines it
+	profiles map[string]source
+}
+
+// parse type-checks and decodes one file and assembles it with its includes.
+// Schema issues are collected in l.issues; only unreadable or malformed YAML is an error.
+func (l *loader) parse(data []byte, filename string) (*assembled, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+
+	result := &assembled{profiles: make(map[string]source)}
+	doc := &root
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		doc = doc.Content[0]
+	}
+	if doc.Kind == 0 || doc.Kind == yaml.DocumentNode {
+		// Empty file
+		return result, nil
+	}
+
+	before := len(l.issues)
+	l.checkType(filename, doc, reflect.TypeOf(File{}), "")
+	var own File
+	if err := doc.Decode(&own); err != nil {
+		if len(l.issues) == before {
+			l.add(filename, doc, "", "%v", err)
+		}
+		return result, nil
+	}
+	if own.Version != 0 && own.Version != CurrentVersion {
+		l.add(filename, lookup(doc, "version"), "version", "unsupported version %d (this build understands version %d)", own.Version, CurrentVersion)
+	}
+
+	abs, _ := filepath.Abs(filename)
+	l.loading[abs] = true
+	defer delete(l.loading, abs)
+
+	for i, inc := range own.Include {
+		node := lookup(doc, "include").Content[i]
+		incPath := inc
+		if !filepath.IsAbs(incPath) {
+			incPath = filepath.Join(filepath.Dir(filename), incPath)
+		}
+		if incAbs, _ := filepath.Abs(incPath); l.loading[incAbs] {
+			l.add(filename, node, fmt.Sprintf("include[%d]", i), "%s includes itself", inc)
+			continue
+		}
+		incData, err := os.ReadFile(incPath)
+		if err != nil {
+			l.add(filename, node, fmt.Sprintf("include[%d]", i), "cannot read included file: %v", err)
+			continue
+		}
+		included, err := l.parse(incData, incPath)
+		if err != nil {
+			return nil, err
+		}
+		result.merge(included)
+	}
+
+	result.merge(&assembled{
+		file:     File{Version: own.Version, Defaults: own.Defaults, Profiles: own.Profiles},
+		defaults: []source{{file: filename, node: lookup(doc, "defaults"), path: "defaults"}},
+		profiles: profileSources(filename, doc, own.Profiles),
+	})
+	return result, nil
+}
+
+// merge layers next over a: next's defaults override a's, and next's profiles replace a's.
+func (a *assembled) merge(next *assembled) {
+	if next.file.Version != 0 {
+		a.file.Version = next.file.Version
+	}
+	a.file.Defaults = a.file.Defaults.Merge(next.file.Defaults)
+	a.defaults = append(append([]source(nil), next.defaults...), a.defaults...)
+	for name, p := range next.file.Profiles {
+		if a.file.Profiles == nil {
+			a.file.Profiles = make(map[string]Profile)
+		}
+		a.file.Profiles[name] = p
+		a.profiles[name] = next.profiles[name]
+	}
+}
+
+// profileSources returns the source of each profile defined in a document.
+func profileSources(filename string, doc *yaml.Node, profiles map[string]Profile) map[string]source {
+	sources := make(map[string]source, len(profiles))
+	for name := range profiles {
+		sources[name] = source{file: filename, node: lookup(doc, "profiles", name), path: "profiles." + name}
+	}
+	return sources
+}
+
+// ProfileNames returns the names of the profiles in the file, sorted.
+func (f *File) ProfileNames() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Profile returns the effective settings of the named profile: the defaults,
+// then each profile it extends (most distant ancestor first), then the profile
+// itself. An empty name returns the defaults alone.
+func (f *File) Profile(name string) (Profile, error) {
+	if name == "" {
+		return f.Defaults, nil
+	}
+	chain, err := f.chain(name)
+	if err != nil {
+		return Profile{}, err
+	}
+
+	p := f.Defaults
+	for i := len(chain) - 1; i >= 0; i-- {
+		p = p.Merge(f.Profiles[chain[i]])
+	}
+	p.Extends = ""
+	return p, nil
+}
+
+// chain returns name followed by the profiles it extends, nearest first.
+func (f *File) chain(name string) ([]string, error) {
+	var chain []string
+	seen := make(map[string]bool)
+	for name != "" {
+		if seen[name] {
+			return nil, fmt.Errorf("profile %q extends itself (%s -> %s)", chain[0], strings.Join(chain, " -> "), name)
+		}
+		seen[name] = true
+
+		p, ok := f.Profiles[name]
+		if !ok {
+			if len(chain) > 0 {
+				return nil, fmt.Errorf("profile %q extends unknown profile %q", chain[len(chain)-1], name)
+			}
+			return nil, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(f.ProfileNames(), ", "))
+		}
+		chain = append(chain, name)
+		name = p.Extends
+	}
+	return chain, nil
+}
+
+// Merge returns p with every field that is set in over replaced by over's value.
+// Nested sections are merged field by field; lists and maps are replaced, not appended.
+func (p Profile) Merge(over Profile) Profile {
+	merged := p
+	mergeValue(reflect.ValueOf(&merged).Elem(), reflect.ValueOf(over))
+	return merged
+}
+
+// mergeValue copies the set fields of src onto dst.
+func mergeValue(dst, src reflect.Value) {
+	if dst.Kind() == reflect.Struct {
+		for i := 0; i < dst.NumField(); i++ {
+			mergeValue(dst.Field(i), src.Field(i))
+		}
+		return
+	}
+	if !src.IsZero() {
+		dst.Set(src)
+	}
+}
@@ -0,0 +1,632 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const validConfig = `version: 1
+defaults:
+  format: claude
+  locale:
+    priority: [en, ja]
+  cache:
+    dir: /var/cache/site2skill
+profiles:
+  stripe:
+    url: https://stripe.com/docs/api
+    name: stripe
+    crawl:
+      exclude: [changelog]
+  gemini:
+    url: https://ai.google.dev/gemini-api/docs
+    name: gemini
+    format: both
+    locale:
+      mode: query
+      param: hl
+    output:
+      air_gapped: true
+`
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFileName)
+	if err := os.WriteFile(path, []byte(validConfig), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	if got := f.ProfileNames(); !reflect.DeepEqual(got, []string{"gemini", "stripe"}) {
+		t.Errorf("ProfileNames() = %v", got)
+	}
+
+	p, err := f.Profile("gemini")
+	if err != nil {
+		t.Fatalf("Profile() returned error: %v", err)
+	}
+	if p.Format != "both" || p.Name != "gemini" || p.Locale.Param != "hl" {
+		t.Errorf("profile settings not applied: %+v", p)
+	}
+	if !reflect.DeepEqual(p.Locale.Priority, []string{"en", "ja"}) || p.Cache.Dir != "/var/cache/site2skill" {
+		t.Errorf("defaults not inherited: %+v", p)
+	}
+	if p.Output.AirGapped == nil || !*p.Output.AirGapped {
+		t.Errorf("Output.AirGapped = %v, want true", p.Output.AirGapped)
+	}
+
+	if _, err := f.Profile("missing"); err == nil || !strings.Contains(err.Error(), "gemini, stripe") {
+		t.Errorf("Profile(missing) error = %v, want the available profiles listed", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   []string
+	}{
+		{
+			name:   "valid",
+			config: validConfig,
+		},
+		{
+			name:   "empty file",
+			config: "",
+		},
+		{
+			name: "unknown key with suggestion",
+			config: `profiles:
+  docs:
+    crawl:
+      exlude: [beta]
+`,
+			want: []string{`test.yaml:4:7: profiles.docs.crawl: unknown key "exlude" (did you mean "exclude"?)`},
+		},
+		{
+			name: "type errors",
+			config: `defaults:
+  global: "yes"
+  locale:
+    priority: en
+profiles: [a, b]
+`,
+			want: []string{
+				`test.yaml:2:11: defaults.global: expected true or false, got string "yes"`,
+				`test.yaml:4:15: defaults.locale.priority: expected a list, got string "en"`,
+				`test.yaml:5:11: profiles: expected a mapping, got a list`,
+			},
+		},
+		{
+			name: "param conflicts with path mode",
+			config: `profiles:
+  docs:
+    locale:
+      mode: path
+      param: hl
+`,
+			want: []string{`test.yaml:5:14: profiles.docs.locale.param: conflicts with locale.mode "path"`},
+		},
+		{
+			name: "inherited param conflicts with path locale codes",
+			config: `defaults:
+  locale:
+    param: hl
+profiles:
+  docs:
+    locale:
+      locales: [sr-latn]
+`,
+			want: []string{`test.yaml:7:16: profiles.docs.locale.locales: extra locale codes are only matched in URL paths`},
+		},
+		{
+			name: "one tree per locale with locale priority disabled",
+			config: `profiles:
+  docs:
+    locale:
+      disabled: true
+      each: true
+`,
+			want: []string{`test.yaml:4:17: profiles.docs.locale.disabled: locale priority is disabled but other locale settings are given`},
+		},
+		{
+			name: "negative chunk budget",
+			config: `profiles:
+  docs:
+    conversion:
+      chunk_tokens: -1
+`,
+			want: []string{`test.yaml:4:21: profiles.docs.conversion.chunk_tokens: must be 0 (disabled) or a positive token budget, got -1`},
+		},
+		{
+			name: "unknown page type",
+			config: `profiles:
+  docs:
+    conversion:
+      page_types: [docs, blog]
+`,
+			want: []string{`test.yaml:4:26: profiles.docs.conversion.page_types[1]: unknown page type "blog"`},
+		},
+		{
+			name: "relative seed",
+			config: `profiles:
+  docs:
+    url: https://docs.example.com/
+    seeds: [https://api.example.com/, /wiki]
+`,
+			want: []string{`test.yaml:4:12: profiles.docs.seeds[1]: "/wiki" is not an absolute http(s) URL`},
+		},
+		{
+			name: "unknown platform",
+			config: `profiles:
+  docs:
+    conversion:
+      platform: hugo
+`,
+			want: []string{`test.yaml:4:17: profiles.docs.conversion.platform: unknown platform "hugo" (expected auto, none, or one of confluence, docusaurus, gitbook, mkdocs, notion, sphinx)`},
+		},
+		{
+			name: "invalid crawl options",
+			config: `profiles:
+  staging:
+    crawl:
+      resolve: ["docs.example.com:443:10.0.0.5", "docs.example.com"]
+      host_header: "docs.example.com/docs"
+      proxy: ftp://proxy.example.com
+      device: tablet
+      max_redirects: 0
+      max_page_size: 10 pages
+      max_total_size: 1GB
+      content_types: [text/html, html]
+      strip_params: [ref, "session_["]
+      trailing_slash: sometimes
+      robots: ignore
+      consent_cookies: [CookieConsent=true, consent]
+      allow_hosts: [cdn.example.com, "cdn.*.com"]
+      max_runtime: 30 minutes
+      max_conns_per_host: 0
+      link_attributes: [data-href, "data href"]
+`,
+			want: []string{
+				`test.yaml:4:50: profiles.staging.crawl.resolve[1]: "docs.example.com" is not a host:port:address rule`,
+				`test.yaml:5:20: profiles.staging.crawl.host_header: "docs.example.com/docs" is not a host name`,
+				`test.yaml:6:14: profiles.staging.crawl.proxy: invalid proxy "ftp://proxy.example.com": unsupported scheme "ftp"`,
+				`test.yaml:7:15: profiles.staging.crawl.device: unknown device "tablet"`,
+				`test.yaml:8:22: profiles.staging.crawl.max_redirects: must be a positive number of redirects, got 0`,
+				`test.yaml:18:27: profiles.staging.crawl.max_conns_per_host: must be a positive number of connections, got 0`,
+				`test.yaml:9:22: profiles.staging.crawl.max_page_size: invalid size "10 pages" (expected bytes, or a number with a unit such as 500KB, 10MB, or 2GiB)`,
+				`test.yaml:11:34: profiles.staging.crawl.content_types[1]: invalid content type "html" (expected a media type such as text/html or text/*)`,
+				`test.yaml:19:36: profiles.staging.crawl.link_attributes[1]: invalid link attribute "data href" (expected an attribute name such as data-href)`,
+				`test.yaml:12:27: profiles.staging.crawl.strip_params[1]: invalid query parameter pattern "session_[": syntax error in pattern`,
+				`test.yaml:13:23: profiles.staging.crawl.trailing_slash: invalid trailing slash policy "sometimes" (expected keep, strip, or add)`,
+				`test.yaml:14:15: profiles.staging.crawl.robots: invalid robots policy "ignore" (expected strict, default, or off)`,
+				`test.yaml:15:45: profiles.staging.crawl.consent_cookies[1]: invalid cookie "consent": want NAME=VALUE`,
+				`test.yaml:16:38: profiles.staging.crawl.allow_hosts[1]: invalid allowed host "cdn.*.com": want a host name, an IP address, or *.domain`,
+				`test.yaml:17:20: profiles.staging.crawl.max_runtime: invalid duration "30 minutes" (expected a positive duration such as 30m or 1h30m)`,
+			},
+		},
+		{
+			name: "invalid rewrite rule",
+			config: `profiles:
+  staging:
+    output:
+      rewrite_urls: ["staging.internal=docs.example.com", "staging.internal"]
+`,
+			want: []string{`test.yaml:4:59: profiles.staging.output.rewrite_urls[1]: invalid rewrite rule "staging.internal": want FROM=TO`},
+		},
+		{
+			name: "invalid access rule",
+			config: `profiles:
+  docs:
+    output:
+      access_rules: ["/internal/**=internal", "/secret/**=secret"]
+`,
+			want: []string{`test.yaml:4:47: profiles.docs.output.access_rules[1]: invalid access rule "/secret/**=secret": unknown access level "secret" (expected public, internal, confidential)`},
+		},
+		{
+			name: "invalid export",
+			config: `profiles:
+  docs:
+    output:
+      exports: ["mdx=site/content", "rst=site/rst"]
+`,
+			want: []string{`test.yaml:4:37: profiles.docs.output.exports[1]: invalid export "rst=site/rst": unknown output format "rst" (expected markdown, mdx, asciidoc)`},
+		},
+		{
+			name: "invalid publish target",
+			config: `profiles:
+  docs:
+    output:
+      publish: ["s3://skills/nightly", "ftp://skills"]
+`,
+			want: []string{`test.yaml:4:40: profiles.docs.output.publish[1]: invalid publish target "ftp://skills": must start with s3://, gs://, or git+`},
+		},
+		{
+			name: "invalid schedule and recheck window",
+			config: `profiles:
+  docs:
+    schedule: nightly
+    crawl:
+      recheck_after: -7d
+`,
+			want: []string{
+				`test.yaml:5:22: profiles.docs.crawl.recheck_after: invalid duration "-7d" (expected a positive duration such as 7d or 12h)`,
+				`test.yaml:3:15: profiles.docs.schedule: invalid duration "nightly" (expected a positive duration such as 7d or 12h)`,
+			},
+		},
+		{
+			name: "invalid embeddings provider",
+			config: `profiles:
+  docs:
+    output:
+      embeddings:
+        provider: word2vec
+`,
+			want: []string{`test.yaml:5:19: profiles.docs.output.embeddings.provider: unknown embeddings provider "word2vec" (expected openai or hash)`},
+		},
+		{
+			name: "summaries model without provider",
+			config: `profiles:
+  docs:
+    output:
+      summaries:
+        model: llama3
+`,
+			want: []string{`test.yaml:5:9: profiles.docs.output.summaries: summaries url or model is given without a provider`},
+		},
+		{
+			name: "invalid translation",
+			config: `profiles:
+  docs:
+    output:
+      translation:
+        provider: deepl
+        model: gpt-4o
+        to: "Japanese!"
+`,
+			want: []string{
+				`test.yaml:5:19: profiles.docs.output.translation.provider: the deepl translation provider takes no model`,
+				`test.yaml:7:13: profiles.docs.output.translation.to: invalid translation language "Japanese!": must be a language tag such as "ja" or "pt-BR"`,
+			},
+		},
+		{
+			name: "invalid content selector",
+			config: `profiles:
+  docs:
+    conversion:
+      content_selector: "main >"
+`,
+			want: []string{`test.yaml:4:25: profiles.docs.conversion.content_selector: invalid CSS selector "main >"`},
+		},
+		{
+			name: "invalid strip selector, table fallback, and admonition style",
+			config: `defaults:
+  conversion:
+    strip_selectors: [".ad-banner", "[unclosed"]
+    table_fallback: csv
+    admonitions: docusaurus
+`,
+			want: []string{
+				`test.yaml:3:37: defaults.conversion.strip_selectors[1]: invalid CSS selector "[unclosed"`,
+				`test.yaml:4:21: defaults.conversion.table_fallback: unknown table fallback "csv"`,
+				`test.yaml:5:18: defaults.conversion.admonitions: unknown admonition style "docusaurus"`,
+			},
+		},
+		{
+			name: "malformed secret reference",
+			config: `profiles:
+  docs:
+    auth:
+      headers:
+        Authorization: "Bearer ${secret:TOKEN}"
+      confluence_token: "${env:}"
+      notion_token: "file:"
+`,
+			want: []string{
+				`test.yaml:5:24: profiles.docs.auth.headers.Authorization: unknown reference "${secret:TOKEN}"`,
+				`test.yaml:6:25: profiles.docs.auth.confluence_token: invalid environment variable name in "${env:}"`,
+				`test.yaml:7:21: profiles.docs.auth.notion_token: file: reference without a path`,
+			},
+		},
+		{
+			name: "invalid values",
+			config: `version: 2
+defaults:
+  format: gpt
+  url: docs.example.com
+  locale:
+    mode: query
+  cache:
+    disabled: true
+    dir: /tmp/cache
+`,
+			want: []string{
+				`test.yaml:1:10: version: unsupported version 2`,
+				`test.yaml:4:8: defaults.url: "docs.example.com" is not an absolute http(s) URL`,
+				`test.yaml:3:11: defaults.format: unknown format "gpt"`,
+				`test.yaml:6:11: defaults.locale.mode: query mode requires locale.param`,
+				`test.yaml:9:10: defaults.cache.dir: conflicts with cache.disabled`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.config), "test.yaml")
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("Parse() returned error: %v", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Parse() error = %v, want a *ValidationError", err)
+			}
+			if len(verr.Issues) != len(tt.want) {
+				t.Errorf("got %d issues, want %d:\n%v", len(verr.Issues), len(tt.want), verr)
+			}
+			for i, want := range tt.want {
+				if i < len(verr.Issues) && !strings.HasPrefix(verr.Issues[i].String(), want) {
+					t.Errorf("issue %d = %q, want prefix %q", i, verr.Issues[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	yes, no := true, false
+	base := Profile{Format: "claude", Global: &yes, Crawl: Crawl{Include: []string{"docs"}, Exclude: []string{"beta"}}}
+	over := Profile{Name: "x", Global: &no, Crawl: Crawl{Exclude: []string{"alpha"}}}
+
+	got := base.Merge(over)
+	want := Profile{Name: "x", Format: "claude", Global: &no, Crawl: Crawl{Include: []string{"docs"}, Exclude: []string{"alpha"}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Merge() = %+v, want %+v", got, want)
+	}
+	if !*base.Global {
+		t.Error("Merge() modified the receiver")
+	}
+}
+
+func TestProfileExtends(t *testing.T) {
+	f, err := Parse([]byte(`defaults:
+  format: codex
+profiles:
+  base:
+    crawl:
+      exclude: [beta]
+    cache:
+      dir: /cache
+  stripe:
+    extends: base
+    url: https://stripe.com/docs
+    name: stripe
+  stripe-ja:
+    extends: stripe
+    name: stripe-ja
+    locale:
+      priority: [ja]
+`), "test.yaml")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	p, err := f.Profile("stripe-ja")
+	if err != nil {
+		t.Fatalf("Profile() returned error: %v", err)
+	}
+	want := Profile{
+		URL:    "https://stripe.com/docs",
+		Name:   "stripe-ja",
+		Format: "codex",
+		Locale: Locale{Priority: []string{"ja"}},
+		Crawl:  Crawl{Exclude: []string{"beta"}},
+		Cache:  Cache{Dir: "/cache"},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Profile(stripe-ja) = %+v, want %+v", p, want)
+	}
+}
+
+func TestProfileExtendsErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		want   string
+	}{
+		{
+			name: "unknown parent",
+			config: `profiles:
+  docs:
+    extends: bsae
+`,
+			want: `test.yaml:3:14: profiles.docs.extends: profile "docs" extends unknown profile "bsae"`,
+		},
+		{
+			name: "cycle",
+			config: `profiles:
+  a:
+    extends: b
+  b:
+    extends: a
+`,
+			want: `test.yaml:3:14: profiles.a.extends: profile "a" extends itself (a -> b -> a)`,
+		},
+		{
+			name: "conflict with parent",
+			config: `profiles:
+  base:
+    locale:
+      param: hl
+  docs:
+    extends: base
+    locale:
+      mode: path
+`,
+			want: `test.yaml:4:14: profiles.base.locale.param: conflicts with locale.mode "path"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.config), "test.yaml")
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Parse() error = %v, want a *ValidationError", err)
+			}
+			if !strings.HasPrefix(verr.Issues[0].String(), tt.want) {
+				t.Errorf("issue = %q, want prefix %q", verr.Issues[0], tt.want)
+			}
+		})
+	}
+}
+
+func TestInclude(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		t.Helper()
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+
+	write("shared/politeness.yaml", `defaults:
+  cache:
+    dir: /shared/cache
+  locale:
+    priority: [en]
+profiles:
+  org-base:
+    crawl:
+      exclude: [blog]
+`)
+	path := write(DefaultFileName, `include: [shared/politeness.yaml]
+defaults:
+  locale:
+    priority: [ja, en]
+profiles:
+  docs:
+    extends: org-base
+    url: https://docs.example.com
+`)
+
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	p, err := f.Profile("docs")
+	if err != nil {
+		t.Fatalf("Profile() returned error: %v", err)
+	}
+	if p.Cache.Dir != "/shared/cache" || !reflect.DeepEqual(p.Locale.Priority, []string{"ja", "en"}) {
+		t.Errorf("included defaults not merged: %+v", p)
+	}
+	if !reflect.DeepEqual(p.Crawl.Exclude, []string{"blog"}) {
+		t.Errorf("included profile not inherited: %+v", p)
+	}
+
+	// Issues in included files are reported against that file
+	write("shared/bad.yaml", "defaults:\n  formt: codex\n")
+	write("loop.yaml", "include: [loop.yaml]\n")
+	path = write("broken.yaml", "include: [shared/bad.yaml, missing.yaml, loop.yaml]\n")
+	_, err = Load(path)
+	var verr *ValidationError
+	if !errors.As(err, &verr) || len(verr.Issues) != 3 {
+		t.Fatalf("Load() error = %v, want 3 issues", err)
+	}
+	for i, want := range []string{
+		filepath.Join(dir, "shared/bad.yaml") + `:2:3: defaults: unknown key "formt" (did you mean "format"?)`,
+		path + `:1:28: include[1]: cannot read included file`,
+		filepath.Join(dir, "loop.yaml") + `:1:11: include[0]: loop.yaml includes itself`,
+	} {
+		if !strings.HasPrefix(verr.Issues[i].String(), want) {
+			t.Errorf("issue %d = %q, want prefix %q", i, verr.Issues[i], want)
+		}
+	}
+}
+
+func TestAddProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFileName)
+	yes := true
+	docs := Profile{
+		URL:        "https://docs.example.com/",
+		Name:       "example",
+		Locale:     Locale{Priority: []string{"ja", "en"}},
+		Crawl:      Crawl{Sitemaps: &yes},
+		Conversion: Conversion{Platform: "docusaurus"},
+	}
+	if err := AddProfile(path, "example", docs, false); err != nil {
+		t.Fatalf("AddProfile() returned error: %v", err)
+	}
+	want := `version: 1
+profiles:
+  example:
+    url: https://docs.example.com/
+    name: example
+    locale:
+      priority:
+        - ja
+        - en
+    crawl:
+      sitemaps: true
+    conversion:
+      platform: docusaurus
+`
+	if got, err := os.ReadFile(path); err != nil || string(got) != want {
+		t.Errorf("config = %q, %v, want %q", got, err, want)
+	}
+
+	// Another profile is added to the file, keeping its comments
+	if err := os.WriteFile(path, []byte("# Our docs\n"+validConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddProfile(path, "example", docs, false); err != nil {
+		t.Fatalf("AddProfile() returned error: %v", err)
+	}
+	f, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	if got := f.ProfileNames(); !reflect.DeepEqual(got, []string{"example", "gemini", "stripe"}) {
+		t.Errorf("ProfileNames() = %v", got)
+	}
+	if data, _ := os.ReadFile(path); !strings.HasPrefix(string(data), "# Our docs\n") {
+		t.Errorf("comment lost:\n%s", data)
+	}
+
+	if err := AddProfile(path, "stripe", docs, false); !errors.Is(err, ErrProfileExists) {
+		t.Errorf("AddProfile() of an existing profile: error = %v, want ErrProfileExists", err)
+	}
+	if err := AddProfile(path, "stripe", Profile{URL: "https://stripe.com/docs", Name: "stripe-docs"}, true); err != nil {
+		t.Fatalf("AddProfile() replacing a profile returned error: %v", err)
+	}
+	if f, err = Load(path); err != nil {
+		t.Fatalf("Load() returned error: %v", err)
+	}
+	if p, _ := f.Profile("stripe"); p.Name != "stripe-docs" || p.Crawl.Exclude != nil {
+		t.Errorf("profile not replaced: %+v", p)
+	}
+
+	// An invalid profile leaves the file as it was
+	before, _ := os.ReadFile(path)
+	if err := AddProfile(path, "bad", Profile{URL: "https://example.com", Format: "pdf"}, false); err == nil {
+		t.Error("AddProfile() of an invalid profile: error = nil, want an error")
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(before) {
+		t.Errorf("config changed by an invalid profile:\n%s", after)
+	}
+}
@@ -0,0 +1,683 @@
+// Package config loads site2skill.yaml configuration files.
+// This file implements schema validation with precise, position-annotated errors.
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/f4ah6o/site2skill-go/internal/access"
+	"github.com/f4ah6o/site2skill-go/internal/converter"
+	"github.com/f4ah6o/site2skill-go/internal/embeddings"
+	"github.com/f4ah6o/site2skill-go/internal/fetcher"
+	"github.com/f4ah6o/site2skill-go/internal/plugin"
+	"github.com/f4ah6o/site2skill-go/internal/publish"
+	"github.com/f4ah6o/site2skill-go/internal/summarize"
+	"github.com/f4ah6o/site2skill-go/internal/translate"
+	"github.com/f4ah6o/site2skill-go/internal/urlrewrite"
+	"gopkg.in/yaml.v3"
+)
+
+// localeCodePattern matches locale codes such as "en", "pt-br", or "sr-latn".
+var localeCodePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})?$`)
+
+// Issue is one problem found in a config file.
+type Issue struct {
+	// File is the config file name.
+	File string
+	// Line and Column locate the offending key or value (1-based; 0 if unknown).
+	Line   int
+	Column int
+	// Path is the dotted location of the setting (e.g., "profiles.stripe.locale.param").
+	Path string
+	// Message describes the problem.
+	Message string
+}
+
+// String formats the issue as "file:line:column: path: message".
+func (i Issue) String() string {
+	var b strings.Builder
+	b.WriteString(i.File)
+	if i.Line > 0 {
+		fmt.Fprintf(&b, ":%d:%d", i.Line, i.Column)
+	}
+	b.WriteString(": ")
+	if i.Path != "" {
+		b.WriteString(i.Path + ": ")
+	}
+	b.WriteString(i.Message)
+	return b.String()
+}
+
+// ValidationError reports every schema problem found in a config file.
+type ValidationError struct {
+	Issues []Issue
+}
+
+// Error lists the issues, one per line.
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		lines[i] = issue.String()
+	}
+	return fmt.Sprintf("invalid config (%d problems):\n  %s", len(e.Issues), strings.Join(lines, "\n  "))
+}
+
+// validator accumulates schema issues. The checks are:
+//   - every key must be a known setting (typos get a "did you mean" hint)
+//   - every value must have the right type (string, boolean, integer, list, or mapping)
+//   - enumerated values (format, locale.mode, conversion.table_fallback,
+//     conversion.admonitions, output.embeddings.provider, output.summaries.provider,
+//     output.translation.provider)
+//     and CSS selectors must be valid
+//   - extends must name an existing profile without forming a cycle
+//   - secrets must use well-formed ${env:NAME} or file:/path references
+//   - options must not conflict, e.g. locale.param with locale.mode "path",
+//     or extra path locale codes when locale is read from a query parameter
+type validator struct {
+	issues []Issue
+}
+
+// add records an issue located at node in file. An issue already recorded (e.g.,
+// in the defaults, found again through each profile inheriting them) is reported once.
+func (v *validator) add(file string, node *yaml.Node, path, format string, args ...interface{}) {
+	issue := Issue{File: file, Path: path, Message: fmt.Sprintf(format, args...)}
+	if node != nil {
+		issue.Line, issue.Column = node.Line, node.Column
+	}
+	for _, existing := range v.issues {
+		if existing == issue {
+			return
+		}
+	}
+	v.issues = append(v.issues, issue)
+}
+
+// checkType verifies that node has the shape of Go type t, recursing into
+// mappings and lists.
+func (v *validator) checkType(file string, node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.ShortTag() == "!!null" {
+		// An explicit null leaves the setting unset
+		return
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			v.add(file, node, path, "expected a mapping, got %s", describeNode(node))
+			return
+		}
+		fields := yamlFields(t)
+		seen := make(map[string]bool)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			keyPath := joinPath(path, key.Value)
+			if seen[key.Value] {
+				v.add(file, key, keyPath, "duplicate key")
+				continue
+			}
+			seen[key.Value] = true
+
+			field, ok := fields[key.Value]
+			if !ok {
+				msg := fmt.Sprintf("unknown key %q", key.Value)
+				if s := suggest(key.Value, fields); s != "" {
+					msg += fmt.Sprintf(" (did you mean %q?)", s)
+				}
+				v.add(file, key, path, "%s", msg)
+				continue
+			}
+			v.checkType(file, value, field.Type, keyPath)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			v.add(file, node, path, "expected a mapping, got %s", describeNode(node))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			v.checkType(file, value, t.Elem(), joinPath(path, key.Value))
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			v.add(file, node, path, "expected a list, got %s", describeNode(node))
+			return
+		}
+		for i, item := range node.Content {
+			v.checkType(file, item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() == "!!bool" {
+			v.add(file, node, path, "expected a string, got %s", describeNode(node))
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!bool" {
+			v.add(file, node, path, "expected true or false, got %s", describeNode(node))
+		}
+	case reflect.Int:
+		if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!int" {
+			v.add(file, node, path, "expected an integer, got %s", describeNode(node))
+		}
+	}
+}
+
+// checkSemantics validates the values and option combinations of an assembled
+// config. Each profile is checked as layered over the profiles it extends and
+// the defaults, since it can conflict with an inherited setting.
+func (v *validator) checkSemantics(a *assembled) {
+	v.checkProfile(a.file.Defaults, a.defaults)
+
+	for _, name := range a.file.ProfileNames() {
+		own := a.profiles[name]
+		chain, err := a.file.chain(name)
+		if err != nil {
+			node := lookup(own.node, "extends")
+			if node == nil {
+				node = own.node
+			}
+			v.add(own.file, node, joinPath(own.path, "extends"), "%v", err)
+			continue
+		}
+
+		sources := make([]source, 0, len(chain)+len(a.defaults))
+		for _, p := range chain {
+			sources = append(sources, a.profiles[p])
+		}
+		sources = append(sources, a.defaults...)
+
+		p, _ := a.file.Profile(name)
+		v.checkProfile(p, sources)
+	}
+}
+
+// checkProfile validates one effective profile. sources are the sections it was
+// assembled from, highest precedence first; an issue is reported at the first
+// section that sets the offending key.
+func (v *validator) checkProfile(p Profile, sources []source) {
+	at := func(keys ...string) (string, *yaml.Node, string) {
+		for _, src := range sources {
+			if n := lookup(src.node, keys...); n != nil {
+				return src.file, n, joinPath(src.path, strings.Join(keys, "."))
+			}
+		}
+		if len(sources) == 0 {
+			return "", nil, ""
+		}
+		return sources[0].file, sources[0].node, sources[0].path
+	}
+
+	if p.URL != "" {
+		if u, err := url.Parse(p.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			file, n, path := at("url")
+			v.add(file, n, path, "%q is not an absolute http(s) URL", p.URL)
+		}
+	}
+	for i, seed := range p.Seeds {
+		if u, err := url.Parse(seed); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			file, n, path := at("seeds")
+			v.add(file, n, fmt.Sprintf("%s[%d]", path, i), "%q is not an absolute http(s) URL", seed)
+		}
+	}
+	switch p.Format {
+	case "", "claude", "codex", "both":
+	default:
+		file, n, path := at("format")
+		v.add(file, n, path, "unknown format %q (expected claude, codex, or both)", p.Format)
+	}
+
+	l := p.Locale
+	for i, code := range l.Priority {
+		if !localeCodePattern.MatchString(code) {
+			file, n, path := at("locale", "priority")
+			v.add(file, n, fmt.Sprintf("%s[%d]", path, i), "%q is not a locale code", code)
+		}
+	}
+	switch l.Mode {
+	case "", "path":
+		if l.Mode == "path" && l.Param != "" {
+			file, n, path := at("locale", "param")
+			v.add(file, n, path, "conflicts with locale.mode \"path\": a query parameter is only used in query mode")
+		}
+	case "query":
+		if l.Param == "" {
+			file, n, path := at("locale", "mode")
+			v.add(file, n, path, "query mode requires locale.param (e.g., \"hl\" for ?hl=ja)")
+		}
+	default:
+		file, n, path := at("locale", "mode")
+		v.add(file, n, path, "unknown mode %q (expected path or query)", l.Mode)
+	}
+	if l.Param != "" && (len(l.Locales) > 0 || len(l.Aliases) > 0) {
+		key := "locales"
+		if len(l.Locales) == 0 {
+			key = "aliases"
+		}
+		file, n, path := at("locale", key)
+		v.add(file, n, path, "extra locale codes are only matched in URL paths, but locale.param %q reads the locale from the query", l.Param)
+	}
+	if l.Disabled != nil && *l.Disabled && (len(l.Priority) > 0 || l.Param != "" || l.Mode != "" || (l.Each != nil && *l.Each)) {
+		file, n, path := at("locale", "disabled")
+		v.add(file, n, path, "locale priority is disabled but other locale settings are given")
+	}
+
+	for i, rule := range p.Crawl.Resolve {
+		if _, _, err := fetcher.ParseResolve(rule); err != nil {
+			file, n, path := at("crawl", "resolve")
+			if n != nil && n.Kind == yaml.SequenceNode && i < len(n.Content) {
+				n = n.Content[i]
+			}
+			v.add(file, n, fmt.Sprintf("%s[%d]", path, i), "%q is not a host:port:address rule", rule)
+		}
+	}
+	for i, rule := range p.Output.RewriteURLs {
+		if _, err := urlrewrite.ParseRule(rule); err != nil {
+			file, n, path := at("output", "rewrite_urls")
+			if n != nil && n.Kind == yaml.SequenceNode && i < len(n.Content) {
+				n = n.Content[i]
+			}
+			v.add(file, n, fmt.Sprintf("%s[%d]", path, i), "%v", err)
+		}
+	}
+	for i, rule := range p.Output.AccessRules {
+		if _, err := access.ParseRule(rule); err != nil {
+			file, n, path := at("output", "access_rules")
+			if n != nil && n.Kind == yaml.SequenceNode && i < len(n.Content) {
+				n = n.Content[i]
+			}
+			v.add(file, n, fmt.Sprintf("%s[%d]", path, i), "%v", err)
+		}
+	}
+	for i, spec := range p.Output.Exports {
+		if len(p.Plugins) > 0 {
+			// The formats of plugins are known once they are started
+			if _, _, ok := strings.Cut(spec, "="); ok {
+				continue
+			}
+		}
+		if _, _, err := converter.ParseExport(spec); err != nil {
+			file, n, path := at("output", "exports")
+			if n != nil && n.Kind == yaml.SequenceNode && i < len(n.Content) {
+				n = n.Content[i]
+			}
+			v.add(file, n, fmt.Sprintf("%s[%d]", path, i), "%v", err)
+		}
+	}
+	for i, target := range p.Output.Publish {
+		if _, err := publish.Parse(target); err != nil {
+			file, n, path := at("output", "publish")
+			if n != nil && n.Kind == yaml.SequenceNode && i < len(n.Content) {
+				n = n.Content[i]
+			}
+			v.add(file, n, fmt.Sprintf("%s[%d]", path, i), "%v", err)
+		}
+	}
+	if e := p.Output.Embeddings; e.Provider != "" {
+		if _, err := embeddings.New(embeddings.Spec{Provider: e.Provider}, ""); err != nil {
+			file, n, path := at("output", "embeddings", "provider")
+			v.add(file, n, path, "%v", err)
+		}
+	} else if e.URL != "" || e.Model != "" {
+		file, n, path := at("output", "embeddings")
+		v.add(file, n, path, "embeddings url or model is given without a provider")
+	}
+	if s := p.Output.Summaries; s.Provider != "" {
+		if _, err := summarize.New(summarize.Spec{Provider: s.Provider}, ""); err != nil {
+			file, n, path := at("output", "summaries", "provider")
+			v.add(file, n, path, "%v", err)
+		}
+	} else if s.URL != "" || s.Model != "" {
+		file, n, path := at("output", "summaries")
+		v.add(file, n, path, "summaries url or model is given without a provider")
+	}
+	if tr := p.Output.Translation; tr.Provider != "" {
+		if _, err := translate.New(translate.Spec{Provider: tr.Provider, Model: tr.Model}, ""); err != nil {
+			file, n, path := at("output", "translation", "provider")
+			v.add(file, n, path, "%v", err)
+		}
+		if tr.To != "" {
+			if err := translate.ValidateLanguage(tr.To); err != nil {
+				file, n, path := at("output", "translation", "to")
+				v.add(file, n, path, "%v", err)
+			}
+		}
+	} else if tr.URL != "" || tr.Model != "" || tr.To != "" {
+		file, n, path := at("output", "translation")
+		v.add(file, n, path, "translation url, model, or language is given without a provider")
+	}
+	if h := p.Crawl.HostHeader; h != "" {
+		if u, err := url.Parse("http://" + h); err != nil || u.Host != h || u.Hostname() == "" {
+			file, n, path := at("crawl", "host_header")
+			v.add(file, n, path, "%q is not a host name (optionally with a port)", h)
+		}
+	}
+	if proxy := p.Crawl.Proxy; proxy != "" {
+		if _, err := fetcher.ParseProxy(proxy); err != nil {
+			file, n, path := at("crawl", "proxy")
+			v.add(file, n, path, "%v", err)
+		}
+	}
+	if _, err := fetcher.UserAgentFor(p.Crawl.Device); err != nil {
+		file, n, path := at("crawl", "device")
+		v.add(file, n, path, "%v", err)
+	}
+	if p.Crawl.UserAgent != "" {
+		if _, err := fetcher.FormatUserAgent(p.Crawl.UserAgent, "", ""); err != nil {
+			file, n, path := at("crawl", "user_agent")
+			v.add(file, n, path, "%v", err)
+		}
+	}
+	if p.Crawl.Contact != "" {
+		if err := fetcher.ValidateContact(p.Crawl.Contact); err != nil {
+			file, n, path := at("crawl", "contact")
+			v.add(file, n, path, "%v", err)
+		}
+	}
+	if p.Crawl.MaxRedirects != nil && *p.Crawl.MaxRedirects < 1 {
+		file, n, path := at("crawl", "max_redirects")
+		v.add(file, n, path, "must be a positive number of redirects, got %d", *p.Crawl.MaxRedirects)
+	}
+	if p.Crawl.MaxConnsPerHost != nil && *p.Crawl.MaxConnsPerHost < 1 {
+		file, n, path := at("crawl", "max_conns_per_host")
+		v.add(file, n, path, "must be a positive number of connections, got %d", *p.Crawl.MaxConnsPerHost)
+	}
+	for _, size := range []struct{ key, value string }{
+		{"max_page_size", p.Crawl.MaxPageSize},
+		{"max_total_size", p.Crawl.MaxTotalSize},
+	} {
+		if size.value == "" {
+			continue
+		}
+		if _, err := fetcher.ParseSize(size.value); err != nil {
+			file, n, path := at("crawl", size.key)
+			v.add(file, n, path, "%v", err)
+		}
+	}
+	for i, t := range p.Crawl.ContentTypes {
+		if _, err := fetcher.ParseContentType(t); err != nil {
+			file, n, path := at("crawl", "content_types")
+			if n != nil && n.Kind == yaml.SequenceNode && i < len(n.Content) {
+				n = n.Content[i]
+			}
+			v.add(file, n, fmt.Sprintf("%s[%d]", path, i), "%v", err)
+		}
+	}
+	for i, a := range p.Crawl.LinkAttributes {
+		if _, err := fetcher.ParseLinkAttribute(a); err != nil {
+			file, n, path := at("crawl", "link_attributes")
+			if n != nil && n.Kind == yaml.SequenceNode && i < len(n.Content) {
+				n = n.Content[i]
+			}
+			v.add(file, n, fmt.Sprintf("%s[%d]", path, i), "%v", err)
+		}
+	}
+	for i, param := range p.Crawl.StripParams {
+		if err := fetcher.ValidateStripParam(param); err != nil {
+			file, n, path := at("crawl", "strip_params")
+			if n != nil && n.Kind == yaml.SequenceNode && i < len(n.Content) {
+				n = n.Content[i]
+			}
+			v.add(file, n, fmt.Sprintf("%s[%d]", path, i), "%v", err)
+		}
+	}
+	if policy := p.Crawl.TrailingSlash; policy != "" {
+		if _, err := fetcher.ParseTrailingSlash(policy); err != nil {
+			file, n, path := at("crawl", "trailing_slash")
+			v.add(file, n, path, "%v", err)
+		}
+	}
+	if policy := p.Crawl.Robots; policy != "" {
+		if _, err := fetcher.ParseRobotsPolicy(policy); err != nil {
+			file, n, path := at("crawl", "robots")
+			v.add(file, n, path, "%v", err)
+		}
+	}
+	for i, c := range p.Crawl.ConsentCookies {
+		if _, err := fetcher.ParseCookie(c); err != nil {
+			file, n, path := at("crawl", "consent_cookies")
+			if n != nil && n.Kind == yaml.SequenceNode && i < len(n.Content) {
+				n = n.Content[i]
+			}
+			v.add(file, n, fmt.Sprintf("%s[%d]", path, i), "%v", err)
+		}
+	}
+	for i, host := range p.Crawl.AllowHosts {
+		if _, err := fetcher.ParseAllowList([]string{host}); err != nil {
+			file, n, path := at("crawl", "allow_hosts")
+			if n != nil && n.Kind == yaml.SequenceNode && i < len(n.Content) {
+				n = n.Content[i]
+			}
+			v.add(file, n, fmt.Sprintf("%s[%d]", path, i), "%v", err)
+		}
+	}
+	if r := p.Crawl.RecheckAfter; r != "" {
+		if _, err := ParseDuration(r); err != nil {
+			file, n, path := at("crawl", "recheck_after")
+			v.add(file, n, path, "%v", err)
+		}
+	}
+	if s := p.Schedule; s != "" {
+		if _, err := ParseDuration(s); err != nil {
+			file, n, path := at("schedule")
+			v.add(file, n, path, "%v", err)
+		}
+	}
+	if r := p.Crawl.MaxRuntime; r != "" {
+		if d, err := time.ParseDuration(r); err != nil || d <= 0 {
+			file, n, path := at("crawl", "max_runtime")
+			v.add(file, n, path, "invalid duration %q (expected a positive duration such as 30m or 1h30m)", r)
+		}
+	}
+
+	if sel := p.Conversion.ContentSelector; sel != "" {
+		if _, err := cascadia.ParseGroup(sel); err != nil {
+			file, n, path := at("conversion", "content_selector")
+			v.add(file, n, path, "invalid CSS selector %q: %v", sel, err)
+		}
+	}
+	for i, sel := range p.Conversion.StripSelectors {
+		if _, err := cascadia.ParseGroup(sel); err != nil {
+			file, n, path := at("conversion", "strip_selectors")
+			if n != nil && n.Kind == yaml.SequenceNode && i < len(n.Content) {
+				n = n.Content[i]
+			}
+			v.add(file, n, fmt.Sprintf("%s[%d]", path, i), "invalid CSS selector %q: %v", sel, err)
+		}
+	}
+	for i, spec := range p.Conversion.Converters {
+		if _, err := plugin.ParseCommand(spec); err != nil {
+			file, n, path := at("conversion", "converters")
+			if n != nil && n.Kind == yaml.SequenceNode && i < len(n.Content) {
+				n = n.Content[i]
+			}
+			v.add(file, n, fmt.Sprintf("%s[%d]", path, i), "%v", err)
+		}
+	}
+
+	switch p.Conversion.LanguageFilter {
+	case "", "off", "flag", "exclude":
+	default:
+		file, n, path := at("conversion", "language_filter")
+		v.add(file, n, path, "unknown language filter %q (expected off, flag, or exclude)", p.Conversion.LanguageFilter)
+	}
+
+	switch p.Conversion.TableFallback {
+	case "", "html", "list":
+	default:
+		file, n, path := at("conversion", "table_fallback")
+		v.add(file, n, path, "unknown table fallback %q (expected html or list)", p.Conversion.TableFallback)
+	}
+
+	if p.Conversion.TableCSVRows != nil && *p.Conversion.TableCSVRows < 0 {
+		file, n, path := at("conversion", "table_csv_rows")
+		v.add(file, n, path, "must be 0 (disabled) or a positive row count, got %d", *p.Conversion.TableCSVRows)
+	}
+
+	switch p.Conversion.Admonitions {
+	case "", "github", "blockquote", "none":
+	default:
+		file, n, path := at("conversion", "admonitions")
+		v.add(file, n, path, "unknown admonition style %q (expected github, blockquote, or none)", p.Conversion.Admonitions)
+	}
+
+	if name := p.Conversion.Platform; name != "" {
+		if _, err := converter.ParsePlatform(name); err != nil {
+			file, n, path := at("conversion", "platform")
+			v.add(file, n, path, "%v", err)
+		}
+	}
+
+	for i, t := range p.Conversion.PageTypes {
+		switch t {
+		case "docs", "marketing", "legal":
+		default:
+			file, n, path := at("conversion", "page_types")
+			if n != nil && n.Kind == yaml.SequenceNode && i < len(n.Content) {
+				n = n.Content[i]
+			}
+			v.add(file, n, fmt.Sprintf("%s[%d]", path, i), "unknown page type %q (expected docs, marketing, or legal)", t)
+		}
+	}
+
+	if p.Conversion.ChunkTokens != nil && *p.Conversion.ChunkTokens < 0 {
+		file, n, path := at("conversion", "chunk_tokens")
+		v.add(file, n, path, "must be 0 (disabled) or a positive token budget, got %d", *p.Conversion.ChunkTokens)
+	}
+
+	if p.Cache.Disabled != nil && *p.Cache.Disabled && p.Cache.Dir != "" {
+		file, n, path := at("cache", "dir")
+		v.add(file, n, path, "conflicts with cache.disabled")
+	}
+
+	// Secrets are only checked for well-formed references here; they are resolved at run time
+	for _, section := range []struct {
+		key     string
+		secrets map[string]Secret
+	}{{"headers", p.Auth.Headers}, {"cookies", p.Auth.Cookies}} {
+		names := make([]string, 0, len(section.secrets))
+		for name := range section.secrets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := section.secrets[name].check(); err != nil {
+				file, n, path := at("auth", section.key, name)
+				v.add(file, n, path, "%v", err)
+			}
+		}
+	}
+	for _, token := range []struct {
+		key    string
+		secret Secret
+	}{{"confluence_token", p.Auth.ConfluenceToken}, {"notion_token", p.Auth.NotionToken}} {
+		if err := token.secret.check(); err != nil {
+			file, n, path := at("auth", token.key)
+			v.add(file, n, path, "%v", err)
+		}
+	}
+}
+
+// yamlFields maps the yaml keys of struct type t to their fields.
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// lookup returns the value node at the given key path below node, or nil.
+func lookup(node *yaml.Node, keys ...string) *yaml.Node {
+	for _, key := range keys {
+		if node == nil || node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		node = next
+	}
+	return node
+}
+
+// joinPath appends key to a dotted path.
+func joinPath(path, key string) string {
+	switch {
+	case path == "":
+		return key
+	case key == "":
+		return path
+	}
+	return path + "." + key
+}
+
+// describeNode names the kind of value a node holds, for error messages.
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	switch node.ShortTag() {
+	case "!!bool":
+		return "boolean " + node.Value
+	case "!!int", "!!float":
+		return "number " + node.Value
+	}
+	return fmt.Sprintf("string %q", node.Value)
+}
+
+// suggest returns the known key closest to an unknown one, if it is close enough
+// to be a likely typo.
+func suggest(key string, fields map[string]reflect.StructField) string {
+	best, bestDist := "", 3
+	for name := range fields {
+		d := editDistance(strings.ToLower(key), name)
+		if d < bestDist || (d == bestDist && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,142 @@
+// Package config loads site2skill.yaml configuration files.
+// This file implements secret references, which keep credentials out of config files.
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// envRefPattern matches an environment variable reference: ${env:NAME}
+var envRefPattern = regexp.MustCompile(`\$\{([^}:]*):?([^}]*)\}`)
+
+// envNamePattern matches a valid environment variable name.
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Secret is a config value holding a credential. Instead of the credential itself,
+// it may reference where to find it, so config files can be committed safely:
+//
+//	${env:DOCS_TOKEN}            the value of an environment variable
+//	Bearer ${env:DOCS_TOKEN}     references can be embedded in a longer value
+//	file:/run/secrets/session    the content of a file, without the trailing newline
+//
+// Any other value is used literally. A Secret formats as "[redacted]" so it is
+// never echoed in logs; use Resolve to get the value.
+type Secret string
+
+// String returns a placeholder instead of the secret.
+func (s Secret) String() string {
+	return "[redacted]"
+}
+
+// GoString returns a placeholder instead of the secret, for %#v.
+func (s Secret) GoString() string {
+	return `config.Secret("[redacted]")`
+}
+
+// Resolve returns the value of the secret, reading the environment variable or
+// file it references. A referenced variable that isn't set is an error, so a
+// missing credential is noticed before crawling instead of producing a skill of
+// login pages.
+func (s Secret) Resolve() (string, error) {
+	if err := s.check(); err != nil {
+		return "", err
+	}
+	raw := string(s)
+	if path, ok := strings.CutPrefix(raw, "file:"); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	var missing []string
+	value := envRefPattern.ReplaceAllStringFunc(raw, func(ref string) string {
+		name := envRefPattern.FindStringSubmatch(ref)[2]
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, name)
+		}
+		return v
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return value, nil
+}
+
+// check reports malformed references without resolving them.
+func (s Secret) check() error {
+	raw := string(s)
+	if path, ok := strings.CutPrefix(raw, "file:"); ok {
+		if path == "" {
+			return fmt.Errorf("file: reference without a path")
+		}
+		return nil
+	}
+	for _, m := range envRefPattern.FindAllStringSubmatch(raw, -1) {
+		if m[1] != "env" {
+			return fmt.Errorf("unknown reference %q (expected ${env:NAME} or file:/path)", m[0])
+		}
+		if !envNamePattern.MatchString(m[2]) {
+			return fmt.Errorf("invalid environment variable name in %q", m[0])
+		}
+	}
+	return nil
+}
+
+// Auth holds credentials for documentation sites behind a login.
+type Auth struct {
+	// Headers are sent with every request (e.g., Authorization: "Bearer ${env:TOKEN}").
+	Headers map[string]Secret `yaml:"headers"`
+	// Cookies are sent with every request as a Cookie header.
+	Cookies map[string]Secret `yaml:"cookies"`
+	// ConfluenceToken authenticates the requests to the Confluence REST API in
+	// Confluence mode ("EMAIL:API_TOKEN" for Confluence Cloud, a personal
+	// access token for Confluence Data Center); it isn't sent with page requests.
+	ConfluenceToken Secret `yaml:"confluence_token"`
+	// NotionToken is the token of the Notion integration the pages are shared
+	// with in Notion mode; it isn't sent with page requests.
+	NotionToken Secret `yaml:"notion_token"`
+}
+
+// Resolve resolves every secret and returns the request headers to send,
+// with cookies combined into one Cookie header. Returns nil if no credentials
+// are configured.
+func (a Auth) Resolve() (http.Header, error) {
+	if len(a.Headers) == 0 && len(a.Cookies) == 0 {
+		return nil, nil
+	}
+
+	header := make(http.Header)
+	for name, secret := range a.Headers {
+		value, err := secret.Resolve()
+		if err != nil {
+			return nil, fmt.Errorf("auth.headers.%s: %w", name, err)
+		}
+		header.Set(name, value)
+	}
+
+	names := make([]string, 0, len(a.Cookies))
+	for name := range a.Cookies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var cookies []string
+	for _, name := range names {
+		value, err := a.Cookies[name].Resolve()
+		if err != nil {
+			return nil, fmt.Errorf("auth.cookies.%s: %w", name, err)
+		}
+		cookies = append(cookies, (&http.Cookie{Name: name, Value: value}).String())
+	}
+	if len(cookies) > 0 {
+		header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+	return header, nil
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretResolve(t *testing.T) {
+	t.Setenv("SITE2SKILL_TEST_TOKEN", "tok-123")
+	path := filepath.Join(t.TempDir(), "session")
+	if err := os.WriteFile(path, []byte("abc=def\n"), 0600); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+
+	tests := []struct {
+		secret  Secret
+		want    string
+		wantErr string
+	}{
+		{secret: "${env:SITE2SKILL_TEST_TOKEN}", want: "tok-123"},
+		{secret: "Bearer ${env:SITE2SKILL_TEST_TOKEN}", want: "Bearer tok-123"},
+		{secret: Secret("file:" + path), want: "abc=def"},
+		{secret: "plain-value", want: "plain-value"},
+		{secret: "${env:SITE2SKILL_TEST_UNSET}", wantErr: "SITE2SKILL_TEST_UNSET is not set"},
+		{secret: "${vault:token}", wantErr: "unknown reference"},
+		{secret: "${env:1BAD}", wantErr: "invalid environment variable name"},
+		{secret: "file:", wantErr: "without a path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.secret), func(t *testing.T) {
+			got, err := tt.secret.Resolve()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Resolve() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() returned error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Resolve() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecretRedacted(t *testing.T) {
+	auth := Auth{Headers: map[string]Secret{"Authorization": "Bearer literal-token"}}
+	for _, format := range []string{"%v", "%+v", "%#v", "%s"} {
+		if out := fmt.Sprintf(format, auth); strings.Contains(out, "literal-token") {
+			t.Errorf("Sprintf(%q) leaked the secret: %s", format, out)
+		}
+	}
+}
+
+func TestAuthResolve(t *testing.T) {
+	t.Setenv("SITE2SKILL_TEST_TOKEN", "tok-123")
+	f, err := Parse([]byte(`defaults:
+  auth:
+    headers:
+      Authorization: "Bearer ${env:SITE2SKILL_TEST_TOKEN}"
+profiles:
+  docs:
+    auth:
+      cookies:
+        session: "${env:SITE2SKILL_TEST_TOKEN}"
+        theme: dark
+`), "test.yaml")
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+	p, err := f.Profile("docs")
+	if err != nil {
+		t.Fatalf("Profile() returned error: %v", err)
+	}
+
+	header, err := p.Auth.Resolve()
+	if err != nil {
+		t.Fatalf("Resolve() returned error: %v", err)
+	}
+	if got := header.Get("Authorization"); got != "Bearer tok-123" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if got := header.Get("Cookie"); got != "session=tok-123; theme=dark" {
+		t.Errorf("Cookie = %q", got)
+	}
+
+	if header, err := (Auth{}).Resolve(); header != nil || err != nil {
+		t.Errorf("empty Auth resolved to %v, %v; want nil, nil", header, err)
+	}
+}
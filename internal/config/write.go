@@ -0,0 +1,111 @@
+// Package config loads site2skill.yaml.
+// This file implements the writing of profiles into config files, for the
+// init command.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrProfileExists is returned by AddProfile when the config file already
+// has a profile of the name.
+var ErrProfileExists = errors.New("profile already exists")
+
+// AddProfile writes the profile p named name into the config file at path,
+// creating the file with version CurrentVersion if it doesn't exist. The
+// settings left unset in p are left out. The rest of an existing file, its
+// comments included, is kept; a profile of the same name is replaced if
+// replace is set.
+//
+// Returns an error wrapping ErrProfileExists if the file has a profile named
+// name and replace isn't set, or an error if the file can't be read or
+// written, isn't a YAML mapping, or wouldn't be valid with the profile (see
+// Parse), in which case it is left as it was.
+func AddProfile(path, name string, p Profile, replace bool) error {
+	var doc yaml.Node
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		data = nil
+	case err != nil:
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode}}}
+		setKey(doc.Content[0], "version", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: fmt.Sprint(CurrentVersion)})
+	} else if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+	if len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("failed to add profile to %s: not a YAML mapping", path)
+	}
+	root := doc.Content[0]
+
+	profiles := lookup(root, "profiles")
+	if profiles == nil || profiles.Kind != yaml.MappingNode {
+		profiles = &yaml.Node{Kind: yaml.MappingNode}
+		setKey(root, "profiles", profiles)
+	}
+	if lookup(profiles, name) != nil && !replace {
+		return fmt.Errorf("failed to add profile %q to %s: %w", name, path, ErrProfileExists)
+	}
+	var value yaml.Node
+	if err := value.Encode(p); err != nil {
+		return fmt.Errorf("failed to encode profile: %w", err)
+	}
+	prune(&value)
+	setKey(profiles, name, &value)
+
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if _, err := Parse([]byte(b.String()), path); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// setKey sets the value of key in mapping, replacing its value if it has
+// one, or else appending it.
+func setKey(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+}
+
+// prune removes the unset settings of node, an encoded Profile: the keys of
+// its mappings whose value is null, an empty string, list, or mapping, or
+// pruned to one. Returns whether node is left empty.
+func prune(node *yaml.Node) bool {
+	switch node.Kind {
+	case yaml.MappingNode:
+		var content []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if !prune(node.Content[i+1]) {
+				content = append(content, node.Content[i], node.Content[i+1])
+			}
+		}
+		node.Content = content
+		return len(content) == 0
+	case yaml.SequenceNode:
+		return len(node.Content) == 0
+	case yaml.ScalarNode:
+		return node.Tag == "!!null" || node.Tag == "!!str" && node.Value == ""
+	}
+	return false
+}
@@ -0,0 +1,602 @@
+// Package confluence reads the pages of a Confluence space through its REST
+// API instead of crawling its web interface, which renders little without
+// JavaScript. Pages are read in their storage format, Confluence's XHTML with
+// macros, which is turned back into plain HTML (see Content.HTML), and keep
+// the hierarchy of the space in their URLs (see Content.URL).
+//
+// Confluence Cloud ("https://acme.atlassian.net/wiki") and Confluence Data
+// Center both serve the version 1 content API this package uses. Private
+// spaces need a token (see Authorization).
+package confluence
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/slug"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// ErrNotSpace is returned by Parse for a URL that isn't the URL of a
+// Confluence space.
+var ErrNotSpace = errors.New("not a Confluence space URL")
+
+// TokenEnv is the environment variable the command line reads the token
+// authenticating the requests to the REST API from (see Authorization).
+const TokenEnv = "SITE2SKILL_CONFLUENCE_TOKEN"
+
+// pageLimit is the number of pages requested at a time.
+const pageLimit = 50
+
+// cdataPattern matches a CDATA section of the storage format, which the HTML
+// parser would end at its first ">".
+var cdataPattern = regexp.MustCompile(`(?s)<!\[CDATA\[(.*?)\]\]>`)
+
+// selfClosingPattern matches a self-closing Confluence element of the storage
+// format (e.g., <ri:page ri:content-title="Setup" />), which the HTML parser
+// would leave open around the elements following it.
+var selfClosingPattern = regexp.MustCompile(`<((?:ac|ri):[\w-]+|time)(\s[^>]*?)?\s*/>`)
+
+// Space is a Confluence space.
+type Space struct {
+	// BaseURL is the URL of the Confluence site the REST API is under, e.g.,
+	// "https://acme.atlassian.net/wiki".
+	BaseURL string
+	// Key is the key of the space, e.g., "ENG".
+	Key string
+	// URL is the web URL of the space, e.g.,
+	// "https://acme.atlassian.net/wiki/spaces/ENG"; the pages of the space
+	// are recorded under it (see Content.URL).
+	URL string
+}
+
+// Parse returns the space at rawURL, the URL of a space or of one of its
+// pages: "<base>/spaces/<KEY>/..." (Confluence Cloud, e.g.,
+// "https://acme.atlassian.net/wiki/spaces/ENG/overview") or
+// "<base>/display/<KEY>/..." (Confluence Data Center, e.g.,
+// "https://wiki.acme.com/display/ENG").
+//
+// Returns an error wrapping ErrNotSpace if rawURL isn't such a URL.
+func Parse(rawURL string) (*Space, error) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotSpace, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNotSpace, rawURL)
+	}
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for i := 0; i+1 < len(segments); i++ {
+		if (segments[i] == "spaces" || segments[i] == "display") && segments[i+1] != "" {
+			base := u.Scheme + "://" + u.Host
+			if i > 0 {
+				base += "/" + strings.Join(segments[:i], "/")
+			}
+			return &Space{BaseURL: base, Key: segments[i+1], URL: base + "/" + segments[i] + "/" + segments[i+1]}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s (expected .../spaces/<KEY> or .../display/<KEY>)", ErrNotSpace, rawURL)
+}
+
+// Authorization returns the Authorization header sending token: basic
+// authentication for a Confluence Cloud API token given as
+// "EMAIL:API_TOKEN", and a bearer token for a Confluence Data Center
+// personal access token.
+func Authorization(token string) string {
+	if strings.Contains(token, ":") {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(token))
+	}
+	return "Bearer " + token
+}
+
+// Getter returns the body of the successful response to a GET request to
+// apiURL, a URL of the REST API.
+type Getter func(ctx context.Context, apiURL string) ([]byte, error)
+
+// Ref identifies a page.
+type Ref struct {
+	// ID is the content ID of the page.
+	ID string `json:"id"`
+	// Title is the title of the page.
+	Title string `json:"title"`
+}
+
+// Page is a page of a space.
+type Page struct {
+	Ref
+	// Ancestors are the pages above the page in the hierarchy of the space,
+	// the root first.
+	Ancestors []Ref
+	// Body is the content of the page in the storage format.
+	Body string
+	// WebURL is the URL of the page in the web interface.
+	WebURL string
+	// Version is the version number of the page.
+	Version int
+	// Updated is when the version was created; zero if the API doesn't say.
+	Updated time.Time
+}
+
+// Content is the content of a space read by Fetch.
+type Content struct {
+	// Space is the space read.
+	Space *Space
+	// Name is the name of the space.
+	Name string
+	// HomeID is the content ID of the home page of the space; empty if it
+	// has none.
+	HomeID string
+	// Pages lists the current pages of the space, parents before their
+	// children.
+	Pages []Page
+
+	// urls maps the ID of each page to its URL.
+	urls map[string]string
+	// titles maps the title of each page to its URL.
+	titles map[string]string
+}
+
+// apiPage is a page in the responses of the content API.
+type apiPage struct {
+	Ref
+	Ancestors []Ref `json:"ancestors"`
+	Body      struct {
+		Storage struct {
+			Value string `json:"value"`
+		} `json:"storage"`
+	} `json:"body"`
+	Version struct {
+		Number int    `json:"number"`
+		When   string `json:"when"`
+	} `json:"version"`
+	Links struct {
+		WebUI string `json:"webui"`
+	} `json:"_links"`
+}
+
+// Fetch reads the current pages of s and their storage format through its
+// REST API with get.
+//
+// Returns an error if the space or a batch of its pages can't be read.
+func Fetch(ctx context.Context, s *Space, get Getter) (*Content, error) {
+	data, err := get(ctx, s.BaseURL+"/rest/api/space/"+url.PathEscape(s.Key)+"?expand=homepage")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read space %s: %w", s.Key, err)
+	}
+	var space struct {
+		Name     string `json:"name"`
+		Homepage *Ref   `json:"homepage"`
+	}
+	if err := json.Unmarshal(data, &space); err != nil {
+		return nil, fmt.Errorf("failed to parse space %s: %w", s.Key, err)
+	}
+	c := &Content{Space: s, Name: space.Name}
+	if space.Homepage != nil {
+		c.HomeID = space.Homepage.ID
+	}
+
+	query := url.Values{
+		"spaceKey": {s.Key},
+		"type":     {"page"},
+		"status":   {"current"},
+		"limit":    {fmt.Sprint(pageLimit)},
+		"expand":   {"body.storage,ancestors,version"},
+	}
+	next := s.BaseURL + "/rest/api/content?" + query.Encode()
+	for next != "" {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		data, err := get(ctx, next)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list the pages of space %s: %w", s.Key, err)
+		}
+		var batch struct {
+			Results []apiPage `json:"results"`
+			Links   struct {
+				Next string `json:"next"`
+			} `json:"_links"`
+		}
+		if err := json.Unmarshal(data, &batch); err != nil {
+			return nil, fmt.Errorf("failed to parse the pages of space %s: %w", s.Key, err)
+		}
+		for _, p := range batch.Results {
+			page := Page{Ref: p.Ref, Ancestors: p.Ancestors, Body: p.Body.Storage.Value, Version: p.Version.Number}
+			if p.Links.WebUI != "" {
+				page.WebURL = s.BaseURL + p.Links.WebUI
+			}
+			if t, err := time.Parse(time.RFC3339, p.Version.When); err == nil {
+				page.Updated = t.UTC()
+			}
+			c.Pages = append(c.Pages, page)
+		}
+		// The next link is relative to the base URL
+		next = batch.Links.Next
+		if next != "" && !strings.HasPrefix(next, "http") {
+			next = s.BaseURL + next
+		}
+		if len(batch.Results) == 0 {
+			next = ""
+		}
+	}
+	c.index()
+	return c, nil
+}
+
+// index sorts the pages of c, parents first, and assigns them their URLs.
+func (c *Content) index() {
+	sort.SliceStable(c.Pages, func(i, j int) bool {
+		return len(c.Pages[i].Ancestors) < len(c.Pages[j].Ancestors)
+	})
+	c.urls = make(map[string]string, len(c.Pages))
+	c.titles = make(map[string]string, len(c.Pages))
+	taken := make(map[string]bool)
+	for _, p := range c.Pages {
+		u := c.Space.URL
+		if p.ID != c.HomeID {
+			parent := c.Space.URL
+			if len(p.Ancestors) > 0 {
+				if pu, ok := c.urls[p.Ancestors[len(p.Ancestors)-1].ID]; ok {
+					parent = pu
+				} else {
+					// The parent wasn't read (e.g., it is a draft); the titles of
+					// the ancestors below the home page stand in for its URL
+					for _, a := range c.Ancestors(p) {
+						parent += "/" + slug.Title(a.Title, a.ID)
+					}
+				}
+			}
+			u = parent + "/" + slug.Title(p.Title, p.ID)
+			if taken[u] {
+				u += "-" + p.ID
+			}
+		}
+		taken[u] = true
+		c.urls[p.ID] = u
+		c.titles[p.Title] = u
+	}
+}
+
+// Ancestors returns the ancestors of p below the home page of the space.
+func (c *Content) Ancestors(p Page) []Ref {
+	for i, a := range p.Ancestors {
+		if a.ID == c.HomeID {
+			return p.Ancestors[i+1:]
+		}
+	}
+	return p.Ancestors
+}
+
+// URL returns the URL p is recorded under: the URL of the space for its home
+// page, and otherwise the URL of its parent, or of the space for the pages
+// directly under the home page, followed by a slug of its title (e.g.,
+// ".../spaces/ENG/onboarding/local-setup"), so that the hierarchy of the
+// space becomes the sections of the skill. A slug already taken by a sibling
+// is suffixed with the ID of the page.
+func (c *Content) URL(p Page) string {
+	return c.urls[p.ID]
+}
+
+// Depth returns the depth of p in the hierarchy of the space: 0 for its home
+// page, 1 for the pages directly under it, and so on.
+func (c *Content) Depth(p Page) int {
+	if p.ID == c.HomeID {
+		return 0
+	}
+	return len(c.Ancestors(p)) + 1
+}
+
+// HTML returns p as an HTML page, with its storage format turned into plain
+// HTML: code macros become code blocks, info, note, tip, and warning macros
+// admonitions, expand macros their title and content, task lists lists of
+// checkboxes, links to other pages links to their URL (see URL), and images
+// attached to pages links to their download URL. Macros generating content
+// from elsewhere (tables of contents, child page lists, Jira issues, ...)
+// are dropped, and other macros are replaced by their content.
+func (c *Content) HTML(p Page) string {
+	ctx := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	storage := cdataPattern.ReplaceAllStringFunc(p.Body, func(section string) string {
+		return html.EscapeString(cdataPattern.FindStringSubmatch(section)[1])
+	})
+	storage = selfClosingPattern.ReplaceAllString(storage, "<$1$2></$1>")
+	nodes, err := html.ParseFragment(strings.NewReader(storage), ctx)
+	if err != nil {
+		nodes = []*html.Node{{Type: html.TextNode, Data: p.Body}}
+	}
+	main := element("div", "id", "main-content", "class", "wiki-content")
+	h1 := element("h1")
+	h1.AppendChild(text(p.Title))
+	main.AppendChild(h1)
+	for _, n := range nodes {
+		main.AppendChild(n)
+	}
+	c.rewrite(main, p)
+
+	var body bytes.Buffer
+	html.Render(&body, main)
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>%s</title><meta name="generator" content="Confluence"></head>
+<body>%s</body></html>
+`, html.EscapeString(p.Title), body.String())
+}
+
+// rewrite replaces the Confluence elements under parent, of the storage
+// format of p, by plain HTML.
+func (c *Content) rewrite(parent *html.Node, p Page) {
+	for n := parent.FirstChild; n != nil; {
+		next := n.NextSibling
+		switch {
+		case n.Type == html.CommentNode:
+			parent.RemoveChild(n)
+		case n.Type == html.ElementNode && strings.Contains(n.Data, ":"):
+			for _, r := range c.replace(n, p) {
+				parent.InsertBefore(r, n)
+			}
+			parent.RemoveChild(n)
+		default:
+			c.rewrite(n, p)
+		}
+		n = next
+	}
+}
+
+// replace returns the plain HTML replacing n, a Confluence element of the
+// storage format of p.
+func (c *Content) replace(n *html.Node, p Page) []*html.Node {
+	switch n.Data {
+	case "ac:structured-macro", "ac:macro":
+		return c.macro(n, p)
+	case "ac:link":
+		return []*html.Node{c.link(n, p)}
+	case "ac:image":
+		if src := c.resourceURL(n, p); src != "" {
+			return []*html.Node{element("img", "src", src, "alt", attr(n, "ac:alt"))}
+		}
+		return nil
+	case "ac:task-list":
+		ul := element("ul")
+		for t := n.FirstChild; t != nil; t = t.NextSibling {
+			if t.Type != html.ElementNode || t.Data != "ac:task" {
+				continue
+			}
+			li := element("li")
+			box := "[ ] "
+			if strings.TrimSpace(textContent(child(t, "ac:task-status"))) == "complete" {
+				box = "[x] "
+			}
+			li.AppendChild(text(box))
+			if body := child(t, "ac:task-body"); body != nil {
+				c.rewrite(body, p)
+				moveChildren(li, body)
+			}
+			ul.AppendChild(li)
+		}
+		return []*html.Node{ul}
+	case "ac:emoticon":
+		if fallback := attr(n, "ac:emoji-fallback"); fallback != "" {
+			return []*html.Node{text(fallback)}
+		}
+		return nil
+	case "ac:placeholder", "ac:parameter", "ri:user", "ri:attachment", "ri:page", "ri:url":
+		return nil
+	}
+	// Layouts, inline comment markers, and the like are replaced by their content
+	c.rewrite(n, p)
+	return detach(n)
+}
+
+// macro returns the plain HTML replacing the macro n of p.
+func (c *Content) macro(n *html.Node, p Page) []*html.Node {
+	name := attr(n, "ac:name")
+	switch name {
+	case "code", "noformat":
+		code := element("code")
+		if lang := parameter(n, "language"); lang != "" {
+			code.Attr = append(code.Attr, html.Attribute{Key: "class", Val: "language-" + lang})
+		}
+		code.AppendChild(text(textContent(child(n, "ac:plain-text-body"))))
+		pre := element("pre")
+		pre.AppendChild(code)
+		return []*html.Node{pre}
+	case "info", "note", "tip", "warning", "panel":
+		kind := map[string]string{"info": "info", "note": "warning", "tip": "tip", "warning": "danger", "panel": "note"}[name]
+		div := element("div", "class", "admonition "+kind)
+		if title := parameter(n, "title"); title != "" {
+			t := element("p", "class", "admonition-title")
+			t.AppendChild(text(title))
+			div.AppendChild(t)
+		}
+		if body := child(n, "ac:rich-text-body"); body != nil {
+			c.rewrite(body, p)
+			moveChildren(div, body)
+		}
+		return []*html.Node{div}
+	case "toc", "toc-zone", "children", "pagetree", "pagetreesearch", "recently-updated",
+		"contentbylabel", "content-report-table", "livesearch", "jira", "attachments",
+		"anchor", "create-from-template", "profile", "status":
+		return nil
+	}
+	body := child(n, "ac:rich-text-body")
+	if body == nil {
+		return nil
+	}
+	c.rewrite(body, p)
+	nodes := detach(body)
+	if name == "expand" {
+		title := parameter(n, "title")
+		if title == "" {
+			title = "Details"
+		}
+		strong := element("strong")
+		strong.AppendChild(text(title))
+		para := element("p")
+		para.AppendChild(strong)
+		nodes = append([]*html.Node{para}, nodes...)
+	}
+	return nodes
+}
+
+// link returns the plain HTML link replacing the link n of p.
+func (c *Content) link(n *html.Node, p Page) *html.Node {
+	href, label := "", ""
+	if target := child(n, "ri:page"); target != nil {
+		title := attr(target, "ri:content-title")
+		label = title
+		if key := attr(target, "ri:space-key"); key != "" && key != c.Space.Key {
+			href = c.Space.BaseURL + "/display/" + url.PathEscape(key) + "/" + url.QueryEscape(title)
+		} else if u, ok := c.titles[title]; ok {
+			href = u
+		} else if title == "" {
+			href = c.URL(p)
+		} else {
+			href = c.Space.BaseURL + "/display/" + url.PathEscape(c.Space.Key) + "/" + url.QueryEscape(title)
+		}
+	} else if target := child(n, "ri:space"); target != nil {
+		label = attr(target, "ri:space-key")
+		href = c.Space.BaseURL + "/display/" + url.PathEscape(label)
+	} else {
+		href = c.resourceURL(n, p)
+		if a := child(n, "ri:attachment"); a != nil {
+			label = attr(a, "ri:filename")
+		}
+	}
+	if anchor := attr(n, "ac:anchor"); anchor != "" {
+		if href == "" {
+			href = c.URL(p)
+		}
+		href += "#" + anchor
+	}
+
+	a := element("a", "href", href)
+	if body := child(n, "ac:link-body"); body != nil {
+		c.rewrite(body, p)
+		moveChildren(a, body)
+	} else if body := child(n, "ac:plain-text-link-body"); body != nil {
+		a.AppendChild(text(textContent(body)))
+	}
+	if a.FirstChild == nil {
+		a.AppendChild(text(label))
+	}
+	return a
+}
+
+// resourceURL returns the URL of the attachment or external resource n, a
+// link or image of p, refers to; "" if it refers to neither.
+func (c *Content) resourceURL(n *html.Node, p Page) string {
+	if u := child(n, "ri:url"); u != nil {
+		return attr(u, "ri:value")
+	}
+	a := child(n, "ri:attachment")
+	if a == nil {
+		return ""
+	}
+	id := p.ID
+	if owner := child(a, "ri:page"); owner != nil {
+		for _, other := range c.Pages {
+			if other.Title == attr(owner, "ri:content-title") {
+				id = other.ID
+			}
+		}
+	}
+	return c.Space.BaseURL + "/download/attachments/" + url.PathEscape(id) + "/" + url.PathEscape(attr(a, "ri:filename"))
+}
+
+// element returns an element named tag with the attributes attrs, given as
+// key and value pairs.
+func element(tag string, attrs ...string) *html.Node {
+	n := &html.Node{Type: html.ElementNode, Data: tag, DataAtom: atom.Lookup([]byte(tag))}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if attrs[i+1] != "" {
+			n.Attr = append(n.Attr, html.Attribute{Key: attrs[i], Val: attrs[i+1]})
+		}
+	}
+	return n
+}
+
+// text returns a text node of s.
+func text(s string) *html.Node {
+	return &html.Node{Type: html.TextNode, Data: s}
+}
+
+// attr returns the value of the attribute key of n, or "".
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// child returns the first child element of n named tag, or nil.
+func child(n *html.Node, tag string) *html.Node {
+	if n == nil {
+		return nil
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == tag {
+			return c
+		}
+	}
+	return nil
+}
+
+// textContent returns the text under n; "" if n is nil.
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	if n != nil {
+		walk(n)
+	}
+	return b.String()
+}
+
+// parameter returns the value of the parameter name of the macro n, or "".
+func parameter(n *html.Node, name string) string {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "ac:parameter" && attr(c, "ac:name") == name {
+			return strings.TrimSpace(textContent(c))
+		}
+	}
+	return ""
+}
+
+// moveChildren moves the children of src to the end of dst.
+func moveChildren(dst, src *html.Node) {
+	for _, c := range detach(src) {
+		dst.AppendChild(c)
+	}
+}
+
+// detach removes the children of n and returns them.
+func detach(n *html.Node) []*html.Node {
+	var nodes []*html.Node
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		n.RemoveChild(c)
+		nodes = append(nodes, c)
+		c = next
+	}
+	return nodes
+}
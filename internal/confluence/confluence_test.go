@@ -0,0 +1,138 @@
+package confluence
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Space
+		wantErr bool
+	}{
+		{
+			in:   "https://acme.atlassian.net/wiki/spaces/ENG/overview",
+			want: Space{BaseURL: "https://acme.atlassian.net/wiki", Key: "ENG", URL: "https://acme.atlassian.net/wiki/spaces/ENG"},
+		},
+		{
+			in:   "acme.atlassian.net/wiki/spaces/ENG/pages/123/Setup",
+			want: Space{BaseURL: "https://acme.atlassian.net/wiki", Key: "ENG", URL: "https://acme.atlassian.net/wiki/spaces/ENG"},
+		},
+		{
+			in:   "https://wiki.acme.com/confluence/display/OPS/",
+			want: Space{BaseURL: "https://wiki.acme.com/confluence", Key: "OPS", URL: "https://wiki.acme.com/confluence/display/OPS"},
+		},
+		{in: "https://acme.atlassian.net/wiki/spaces/", wantErr: true},
+		{in: "https://docs.example.com/guide", wantErr: true},
+		{in: "ftp://wiki.acme.com/display/OPS", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if tt.wantErr {
+				if !errors.Is(err, ErrNotSpace) {
+					t.Errorf("Parse() error = %v, want ErrNotSpace", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() returned error: %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("Parse() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorization(t *testing.T) {
+	if got := Authorization("me@acme.com:secret"); got != "Basic bWVAYWNtZS5jb206c2VjcmV0" {
+		t.Errorf("Authorization(Cloud token) = %q", got)
+	}
+	if got := Authorization("pat"); got != "Bearer pat" {
+		t.Errorf("Authorization(personal access token) = %q", got)
+	}
+}
+
+func TestContentURL(t *testing.T) {
+	s := &Space{BaseURL: "https://acme.atlassian.net/wiki", Key: "ENG", URL: "https://acme.atlassian.net/wiki/spaces/ENG"}
+	home := Ref{ID: "1", Title: "Engineering"}
+	onboarding := Ref{ID: "2", Title: "Onboarding"}
+	c := &Content{Space: s, HomeID: "1", Pages: []Page{
+		{Ref: Ref{ID: "3", Title: "Local Setup (macOS)"}, Ancestors: []Ref{home, onboarding}},
+		{Ref: onboarding, Ancestors: []Ref{home}},
+		{Ref: home},
+		{Ref: Ref{ID: "4", Title: "Onboarding!"}, Ancestors: []Ref{home}},
+		{Ref: Ref{ID: "5", Title: "v1.2"}, Ancestors: []Ref{home, {ID: "9", Title: "Release Notes"}}},
+		{Ref: Ref{ID: "6", Title: "日本語"}, Ancestors: []Ref{home}},
+		{Ref: Ref{ID: "7", Title: "???"}, Ancestors: []Ref{home}},
+	}}
+	c.index()
+
+	base := s.URL
+	want := map[string]struct {
+		url   string
+		depth int
+	}{
+		"1": {base, 0},
+		"2": {base + "/onboarding", 1},
+		"3": {base + "/onboarding/local-setup-macos", 2},
+		"4": {base + "/onboarding-4", 1},
+		"5": {base + "/release-notes/v1-2", 2},
+		"6": {base + "/日本語", 1},
+		"7": {base + "/page-7", 1},
+	}
+	for _, p := range c.Pages {
+		if got := c.URL(p); got != want[p.ID].url {
+			t.Errorf("URL(%s) = %q, want %q", p.Title, got, want[p.ID].url)
+		}
+		if got := c.Depth(p); got != want[p.ID].depth {
+			t.Errorf("Depth(%s) = %d, want %d", p.Title, got, want[p.ID].depth)
+		}
+	}
+}
+
+func TestContentHTML(t *testing.T) {
+	s := &Space{BaseURL: "https://acme.atlassian.net/wiki", Key: "ENG", URL: "https://acme.atlassian.net/wiki/spaces/ENG"}
+	c := &Content{Space: s, HomeID: "1", Pages: []Page{
+		{Ref: Ref{ID: "1", Title: "Engineering"}},
+		{Ref: Ref{ID: "2", Title: "Local Setup"}, Ancestors: []Ref{{ID: "1", Title: "Engineering"}}},
+	}}
+	c.index()
+
+	storage := `<p>Read <ac:link><ri:page ri:content-title="Local Setup" /><ac:plain-text-link-body><![CDATA[the setup guide]]></ac:plain-text-link-body></ac:link> first.</p>
+<ac:structured-macro ac:name="toc" ac:schema-version="1" />
+<ac:structured-macro ac:name="code"><ac:parameter ac:name="language">go</ac:parameter><ac:plain-text-body><![CDATA[if a > b && b < c {
+	return "<div>"
+}]]></ac:plain-text-body></ac:structured-macro>
+<ac:structured-macro ac:name="warning"><ac:parameter ac:name="title">Careful</ac:parameter><ac:rich-text-body><p>Back up first.</p></ac:rich-text-body></ac:structured-macro>
+<ac:structured-macro ac:name="expand"><ac:rich-text-body><p>Hidden text.</p></ac:rich-text-body></ac:structured-macro>
+<ac:task-list><ac:task><ac:task-status>complete</ac:task-status><ac:task-body>Install Go</ac:task-body></ac:task><ac:task><ac:task-status>incomplete</ac:task-status><ac:task-body>Clone the repo</ac:task-body></ac:task></ac:task-list>
+<p><ac:image ac:alt="Diagram"><ri:attachment ri:filename="arch diagram.png" /></ac:image><ac:emoticon ac:name="smile" /> Done</p>
+<p>See <ac:link ac:anchor="proxy"><ri:page ri:content-title="Networking" ri:space-key="OPS" /></ac:link>.</p>`
+	got := c.HTML(Page{Ref: Ref{ID: "1", Title: "Engineering"}, Body: storage})
+
+	for _, want := range []string{
+		`<title>Engineering</title>`,
+		`<meta name="generator" content="Confluence">`,
+		`<div id="main-content" class="wiki-content"><h1>Engineering</h1>`,
+		`<a href="https://acme.atlassian.net/wiki/spaces/ENG/local-setup">the setup guide</a> first.`,
+		"<pre><code class=\"language-go\">if a &gt; b &amp;&amp; b &lt; c {\n\treturn &#34;&lt;div&gt;&#34;\n}</code></pre>",
+		`<div class="admonition danger"><p class="admonition-title">Careful</p><p>Back up first.</p></div>`,
+		`<p><strong>Details</strong></p><p>Hidden text.</p>`,
+		`<ul><li>[x] Install Go</li><li>[ ] Clone the repo</li></ul>`,
+		`<img src="https://acme.atlassian.net/wiki/download/attachments/1/arch%20diagram.png" alt="Diagram"/> Done`,
+		`<a href="https://acme.atlassian.net/wiki/display/OPS/Networking#proxy">Networking</a>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("HTML() doesn't contain %q:\n%s", want, got)
+		}
+	}
+	for _, unwanted := range []string{"ac:", "ri:", "CDATA", "toc"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("HTML() contains %q:\n%s", unwanted, got)
+		}
+	}
+}
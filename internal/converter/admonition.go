@@ -0,0 +1,231 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the conversion of admonitions (notes, warnings, tips) to
+// Markdown blockquote alerts.
+package converter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Admonition styles select how admonitions are rendered.
+const (
+	// AdmonitionStyleGitHub renders GitHub alerts ("> [!NOTE]"), the default.
+	AdmonitionStyleGitHub = "github"
+	// AdmonitionStyleBlockquote renders a blockquote led by the bolded title
+	// ("> **Note**"), for renderers without alert support.
+	AdmonitionStyleBlockquote = "blockquote"
+	// AdmonitionStyleNone leaves admonitions undetected; their content becomes
+	// plain paragraphs.
+	AdmonitionStyleNone = "none"
+)
+
+const (
+	// admonitionAttr records the alert type of a detected admonition; see annotateAdmonitions.
+	admonitionAttr = "data-admonition"
+	// admonitionTitleAttr records the admonition's own title, if it had one.
+	admonitionTitleAttr = "data-admonition-title"
+)
+
+// blankLines matches runs of blank lines, which would become runs of empty quote lines.
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// admonitionLabels are the GitHub alert types and their default titles.
+var admonitionLabels = map[string]string{
+	"note":      "Note",
+	"tip":       "Tip",
+	"important": "Important",
+	"warning":   "Warning",
+	"caution":   "Caution",
+}
+
+// admonitionTypes maps the admonition names used by docs frameworks (Docusaurus,
+// MkDocs Material, Sphinx, Starlight, Bootstrap alerts) to GitHub alert types.
+var admonitionTypes = map[string]string{
+	"note": "note", "info": "note", "information": "note", "seealso": "note",
+	"abstract": "note", "summary": "note", "tldr": "note", "todo": "note",
+	"example": "note", "quote": "note", "question": "note", "faq": "note",
+	"primary": "note", "secondary": "note",
+	"tip": "tip", "hint": "tip", "success": "tip", "check": "tip", "done": "tip",
+	"important": "important", "attention": "important",
+	"warning": "warning", "warn": "warning",
+	"caution": "caution", "danger": "caution", "error": "caution", "bug": "caution",
+	"failure": "caution", "fail": "caution",
+}
+
+// admonitionContainers are the classes marking an element as an admonition.
+var admonitionContainers = map[string]bool{
+	"admonition": true, "theme-admonition": true, "markdown-alert": true,
+	"alert": true, "callout": true, "starlight-aside": true, "notice": true,
+}
+
+// admonitionTypePrefixes are stripped from class names to find the admonition
+// name in modifiers such as "admonition-note" or "alert--warning".
+var admonitionTypePrefixes = []string{
+	"theme-admonition-", "admonition-", "markdown-alert-", "alert--", "alert-",
+	"callout-", "starlight-aside--", "notice--", "notice-",
+}
+
+// admonitionTitleClasses mark the title element inside an admonition. Class
+// names ending in "_" are prefixes of CSS module names (e.g., "admonitionHeading_Gvgb").
+var admonitionTitleClasses = []string{
+	"admonition-title", "admonition-heading", "admonitionHeading_", "markdown-alert-title",
+	"starlight-aside__title", "callout-title", "alert-heading", "notice-title",
+}
+
+// SetAdmonitionStyle chooses how admonitions (the note, tip, and warning boxes of
+// Docusaurus, MkDocs Material, Sphinx, and similar frameworks) are rendered:
+// AdmonitionStyleGitHub, AdmonitionStyleBlockquote, or AdmonitionStyleNone.
+//
+// Returns an error for an unknown style.
+func (c *Converter) SetAdmonitionStyle(style string) error {
+	switch style {
+	case AdmonitionStyleGitHub, AdmonitionStyleBlockquote, AdmonitionStyleNone:
+		c.admonitionStyle = style
+		return nil
+	}
+	return fmt.Errorf("unknown admonition style %q (expected %s, %s, or %s)",
+		style, AdmonitionStyleGitHub, AdmonitionStyleBlockquote, AdmonitionStyleNone)
+}
+
+// annotateAdmonitions turns every admonition in doc into a blockquote carrying
+// its alert type and title as data attributes, and removes the title element.
+// Readability drops the classes that identify admonitions and may drop aside
+// elements, but keeps blockquotes and data attributes.
+//
+// Returns whether any admonition was found.
+func annotateAdmonitions(doc *goquery.Document) bool {
+	found := false
+	doc.Find("div, aside, section, details").Each(func(_ int, sel *goquery.Selection) {
+		kind, ok := admonitionType(sel)
+		if !ok || sel.Closest("pre, code").Length() > 0 {
+			return
+		}
+
+		n := sel.Get(0)
+		title := ""
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			if isAdmonitionTitle(child) {
+				title = strings.Join(strings.Fields(goquery.NewDocumentFromNode(child).Text()), " ")
+				n.RemoveChild(child)
+				break
+			}
+		}
+
+		n.Data = "blockquote"
+		n.DataAtom = atom.Blockquote
+		n.Attr = []html.Attribute{{Key: admonitionAttr, Val: kind}}
+		if title != "" {
+			n.Attr = append(n.Attr, html.Attribute{Key: admonitionTitleAttr, Val: title})
+		}
+		found = true
+	})
+	return found
+}
+
+// admonitionType returns the GitHub alert type of an element if it is an
+// admonition. An element is an admonition if it has a container class (e.g.,
+// "admonition note") or is a details element with an admonition name as class
+// (MkDocs Material's collapsible blocks). Containers without a recognized name
+// are notes.
+func admonitionType(sel *goquery.Selection) (string, bool) {
+	classes := strings.Fields(sel.AttrOr("class", ""))
+	container := false
+	kind := ""
+	for _, class := range classes {
+		class = strings.ToLower(class)
+		if admonitionContainers[class] {
+			container = true
+			continue
+		}
+		if kind != "" {
+			continue
+		}
+		if t, ok := admonitionTypes[class]; ok {
+			kind = t
+			continue
+		}
+		for _, prefix := range admonitionTypePrefixes {
+			if t, ok := admonitionTypes[strings.TrimPrefix(class, prefix)]; ok && strings.HasPrefix(class, prefix) {
+				kind = t
+				break
+			}
+		}
+	}
+
+	switch {
+	case container && kind == "":
+		return "note", true
+	case container, kind != "" && goquery.NodeName(sel) == "details":
+		return kind, true
+	}
+	return "", false
+}
+
+// isAdmonitionTitle reports whether n is the title element of an admonition.
+func isAdmonitionTitle(n *html.Node) bool {
+	if n.Type != html.ElementNode {
+		return false
+	}
+	if n.DataAtom == atom.Summary {
+		return true
+	}
+	for _, attr := range n.Attr {
+		if attr.Key != "class" {
+			continue
+		}
+		for _, class := range strings.Fields(attr.Val) {
+			for _, title := range admonitionTitleClasses {
+				if class == title || strings.HasSuffix(title, "_") && strings.HasPrefix(class, title) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// convertAdmonition renders a blockquote annotated by annotateAdmonitions in the
+// configured style. Other blockquotes fall through to the default rule.
+func (c *Converter) convertAdmonition(content string, sel *goquery.Selection, _ *md.Options) *string {
+	kind, ok := sel.Attr(admonitionAttr)
+	if !ok {
+		return nil
+	}
+	title := sel.AttrOr(admonitionTitleAttr, "")
+
+	var lines []string
+	if c.admonitionStyle == AdmonitionStyleBlockquote {
+		if title == "" {
+			title = admonitionLabels[kind]
+		}
+		lines = append(lines, "**"+title+"**", "")
+	} else {
+		lines = append(lines, "[!"+strings.ToUpper(kind)+"]")
+		// Titles that only name the type ("Note", "Danger") are implied by the alert
+		if _, generic := admonitionTypes[strings.ToLower(title)]; title != "" && !generic {
+			lines = append(lines, "**"+title+"**", "")
+		}
+	}
+	if body := blankLines.ReplaceAllString(strings.TrimSpace(content), "\n\n"); body != "" {
+		lines = append(lines, strings.Split(body, "\n")...)
+	} else if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\n")
+	for _, line := range lines {
+		b.WriteString(strings.TrimRight("> "+line, " "))
+		b.WriteString("\n")
+	}
+	b.WriteString("\n")
+	text := b.String()
+	return &text
+}
@@ -0,0 +1,106 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestAdmonitionType(t *testing.T) {
+	tests := []struct {
+		name     string
+		html     string
+		wantType string
+		wantOK   bool
+	}{
+		{"mkdocs note", `<div class="admonition note"></div>`, "note", true},
+		{"sphinx danger", `<div class="admonition danger"></div>`, "caution", true},
+		{"sphinx custom", `<div class="admonition admonition-custom"></div>`, "note", true},
+		{"docusaurus v3", `<div class="theme-admonition theme-admonition-tip admonition_xBdr alert alert--success"></div>`, "tip", true},
+		{"docusaurus v2", `<div class="admonition admonition-warning alert alert--danger"></div>`, "warning", true},
+		{"github", `<div class="markdown-alert markdown-alert-important"></div>`, "important", true},
+		{"starlight", `<aside class="starlight-aside starlight-aside--caution"></aside>`, "caution", true},
+		{"bootstrap", `<div class="alert alert-info" role="alert"></div>`, "note", true},
+		{"mkdocs details", `<details class="warning"></details>`, "warning", true},
+		{"plain note class", `<div class="note"></div>`, "", false},
+		{"unrelated", `<div class="content"></div>`, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, ok := admonitionType(doc.Find("div, aside, details").First())
+			if got != tt.wantType || ok != tt.wantOK {
+				t.Errorf("admonitionType() = %q, %v; want %q, %v", got, ok, tt.wantType, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestConvertAdmonitions(t *testing.T) {
+	para := "<p>" + strings.Repeat("This guide explains the installation in detail, with commas, clauses, and plenty of words. ", 8) + "</p>"
+	page := `<html><head><title>Install</title></head><body><article><h1>Install</h1>` + para + `
+<div class="admonition note"><p class="admonition-title">Note</p><p>Requires Go 1.22.</p></div>
+<div class="theme-admonition theme-admonition-danger alert alert--danger"><div class="admonitionHeading_Gvgb"><span class="admonitionIcon_Rf37"></span>Data loss</div><div class="admonitionContent_BuS1"><p>Back up first.</p><ul><li>Export the data</li></ul></div></div>
+<aside class="starlight-aside starlight-aside--tip"><p class="starlight-aside__title">Tip</p><p>Use the <code>--fast</code> flag.</p></aside>
+<blockquote><p>A regular quote.</p></blockquote>` + para + `</article></body></html>`
+
+	tests := []struct {
+		style string
+		want  []string
+	}{
+		{
+			style: AdmonitionStyleGitHub,
+			want: []string{
+				"> [!NOTE]\n> Requires Go 1.22.",
+				"> [!CAUTION]\n> **Data loss**\n>\n> Back up first.\n>\n> - Export the data",
+				"> [!TIP]\n> Use the `--fast` flag.",
+				"> A regular quote.",
+			},
+		},
+		{
+			style: AdmonitionStyleBlockquote,
+			want: []string{
+				"> **Note**\n>\n> Requires Go 1.22.",
+				"> **Data loss**\n>\n> Back up first.",
+				"> **Tip**\n>\n> Use the `--fast` flag.",
+			},
+		},
+		{
+			style: AdmonitionStyleNone,
+			want: []string{
+				"Note\n\nRequires Go 1.22.",
+				"Data loss\n\nBack up first.",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			c := New()
+			if err := c.SetAdmonitionStyle(tt.style); err != nil {
+				t.Fatal(err)
+			}
+			got, err := c.convertHTML([]byte(page), "page.html")
+			if err != nil {
+				t.Fatalf("convertHTML returned error: %v", err)
+			}
+			markdown := got.Markdown
+			for _, want := range tt.want {
+				if !strings.Contains(markdown, want) {
+					t.Errorf("markdown missing %q:\n%s", want, markdown)
+				}
+			}
+		})
+	}
+}
+
+func TestSetAdmonitionStyle(t *testing.T) {
+	if err := New().SetAdmonitionStyle("docusaurus"); err == nil {
+		t.Error("SetAdmonitionStyle(\"docusaurus\") should return an error")
+	}
+}
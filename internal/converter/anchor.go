@@ -0,0 +1,180 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the mapping of the anchors of the original HTML headings
+// to the IDs that Markdown renderers generate for the converted headings.
+package converter
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+var (
+	// headingLinkPattern matches Markdown links and images, capturing their text.
+	headingLinkPattern = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	// escapePattern matches backslash escapes of the Markdown converter.
+	escapePattern = regexp.MustCompile(`\\([[:punct:]])`)
+)
+
+// Heading is an ATX heading of a Markdown document.
+type Heading struct {
+	// Level is the heading level, 1 to 6.
+	Level int
+	// Text is the heading text, without Markdown syntax.
+	Text string
+	// ID is the anchor a renderer such as GitHub's generates for the heading.
+	ID string
+}
+
+// htmlHeading is a heading of the original HTML document that can be linked to.
+type htmlHeading struct {
+	// key is the text of the heading, normalized for matching (see headingKey)
+	key string
+	// id is the fragment that links to the heading on the original page
+	id string
+}
+
+// Headings returns the headings of a Markdown document outside code blocks,
+// in order. IDs follow GitHub's rules: the lowercased text without punctuation,
+// spaces replaced with hyphens, and "-1", "-2", and so on appended to repeats.
+func Headings(markdown string) []Heading {
+	var headings []Heading
+	var ids Slugger
+	eachProseLine(markdown, func(line string) {
+		m := headingPattern.FindStringSubmatch(line)
+		if m == nil {
+			return
+		}
+		text := HeadingText(m[2])
+		headings = append(headings, Heading{Level: len(m[1]), Text: text, ID: ids.Slug(text)})
+	})
+	return headings
+}
+
+// HeadingText returns the text of a Markdown heading without Markdown syntax:
+// links and images replaced with their text, code spans and backslash escapes
+// unwrapped.
+func HeadingText(heading string) string {
+	text := headingLinkPattern.ReplaceAllString(heading, "$1")
+	return escapePattern.ReplaceAllString(strings.ReplaceAll(text, "`", ""), "$1")
+}
+
+// Slugger generates the IDs of the successive headings of a document the way
+// Headings does, for callers that parse the headings themselves: use one per
+// document, and pass it the heading texts in order. The zero value is ready
+// to use.
+type Slugger struct {
+	// seen counts the headings of each slug so far
+	seen map[string]int
+}
+
+// Slug returns the ID of the next heading of the document, whose text is
+// text (see HeadingText): its Slug, with "-1", "-2", and so on appended to
+// repeats.
+func (s *Slugger) Slug(text string) string {
+	if s.seen == nil {
+		s.seen = make(map[string]int)
+	}
+	id := Slug(text)
+	if n := s.seen[id]; n > 0 {
+		s.seen[id]++
+		return id + "-" + strconv.Itoa(n)
+	}
+	s.seen[id] = 1
+	return id
+}
+
+// Slug returns the anchor GitHub generates for a heading text: the text
+// lowercased, with characters other than letters, digits, spaces, hyphens,
+// and underscores removed, and spaces replaced with hyphens.
+func Slug(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r == ' ':
+			b.WriteRune('-')
+		case r == '-' || r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// headingKey normalizes a heading text for matching HTML headings with
+// converted ones, ignoring whitespace and the permalink symbols (¶, #) that
+// sites append to headings.
+func headingKey(text string) string {
+	return Slug(strings.Join(strings.FieldsFunc(text, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}), " "))
+}
+
+// collectHeadings returns the headings of doc that can be linked to, in
+// document order. A heading's fragment is its own id; failing that, the id or
+// name of an element inside it, the target of a permalink inside it, or the id
+// of the section it opens.
+func collectHeadings(doc *goquery.Document) []htmlHeading {
+	var headings []htmlHeading
+	doc.Find("h1, h2, h3, h4, h5, h6").Each(func(_ int, h *goquery.Selection) {
+		id := strings.TrimSpace(h.AttrOr("id", ""))
+		if id == "" {
+			id = strings.TrimSpace(h.Find("[id]").First().AttrOr("id", ""))
+		}
+		if id == "" {
+			id = strings.TrimSpace(h.Find("a[name]").First().AttrOr("name", ""))
+		}
+		if id == "" {
+			if href := h.Find(`a[href^="#"]`).First().AttrOr("href", ""); len(href) > 1 {
+				if unescaped, err := url.PathUnescape(href[1:]); err == nil {
+					id = unescaped
+				}
+			}
+		}
+		if id == "" && h.Prev().Length() == 0 {
+			id = strings.TrimSpace(h.Parent().AttrOr("id", ""))
+		}
+		if key := headingKey(h.Text()); id != "" && key != "" {
+			headings = append(headings, htmlHeading{key: key, id: id})
+		}
+	})
+	return headings
+}
+
+// removePermalinks removes the permalinks that sites put in headings, such as
+// "¶" or "#" linking to the heading itself, which would otherwise end up in
+// the Markdown heading text and its ID. Reports whether any were removed.
+func removePermalinks(doc *goquery.Document) bool {
+	links := doc.Find("h1, h2, h3, h4, h5, h6").Find(`a[href^="#"]`).FilterFunction(func(_ int, a *goquery.Selection) bool {
+		return headingKey(a.Text()) == ""
+	})
+	links.Remove()
+	return links.Length() > 0
+}
+
+// matchAnchors maps the fragments of the original headings to the IDs of the
+// converted headings with the same text. Both are in document order; HTML
+// headings left out of the converted content are skipped over.
+func matchAnchors(original []htmlHeading, markdown string) map[string]string {
+	anchors := make(map[string]string)
+	next := 0
+	for _, h := range Headings(markdown) {
+		key := headingKey(h.Text)
+		for i := next; i < len(original); i++ {
+			if original[i].key == key {
+				if _, ok := anchors[original[i].id]; !ok {
+					anchors[original[i].id] = h.ID
+				}
+				next = i + 1
+				break
+			}
+		}
+	}
+	if len(anchors) == 0 {
+		return nil
+	}
+	return anchors
+}
@@ -0,0 +1,81 @@
+package converter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHeadings(t *testing.T) {
+	markdown := "# Install `foo_bar` now [¶](\\#install)\n\n## Examples\n\n```sh\n# not a heading\n```\n\n## Examples\n\n### Déjà vu: C++ & Go!\n"
+	want := []Heading{
+		{Level: 1, Text: "Install foo_bar now ¶", ID: "install-foo_bar-now-"},
+		{Level: 2, Text: "Examples", ID: "examples"},
+		{Level: 2, Text: "Examples", ID: "examples-1"},
+		{Level: 3, Text: "Déjà vu: C++ & Go!", ID: "déjà-vu-c--go"},
+	}
+	if got := Headings(markdown); !reflect.DeepEqual(got, want) {
+		t.Errorf("Headings() = %+v, want %+v", got, want)
+	}
+}
+
+func TestSlugger(t *testing.T) {
+	var ids Slugger
+	var got []string
+	for _, heading := range []string{"[Setup](setup.md)", "Setup", "`Setup`", "Setup \\#2"} {
+		got = append(got, ids.Slug(HeadingText(heading)))
+	}
+	want := []string{"setup", "setup-1", "setup-2", "setup-2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Slug() = %q, want %q", got, want)
+	}
+}
+
+func TestAnchors(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want map[string]string
+	}{
+		{
+			name: "heading ids",
+			html: `<h1 id="top">Guide</h1><p>Intro text for the guide.</p><h2 id="setup-step">Set up</h2><p>Run it.</p>`,
+			want: map[string]string{"top": "guide", "setup-step": "set-up"},
+		},
+		{
+			name: "permalinks and sections",
+			html: `<section id="install"><h2>Install<a class="headerlink" href="#install">¶</a></h2><p>Download it.</p></section>
+<h2><a name="old-style"></a>Configure</h2><p>Edit the file.</p>`,
+			want: map[string]string{"install": "install", "old-style": "configure"},
+		},
+		{
+			name: "repeated headings",
+			html: `<h2 id="ex-a">Example</h2><p>One.</p><h2 id="ex-b">Example</h2><p>Two.</p>`,
+			want: map[string]string{"ex-a": "example", "ex-b": "example-1"},
+		},
+		{
+			name: "headings without ids",
+			html: `<h2>Plain</h2><p>Nothing to link.</p>`,
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			if err := c.SetContentSelector("main"); err != nil {
+				t.Fatal(err)
+			}
+			p, err := c.convertHTML([]byte("<html><body><nav><h2 id=\"menu\">Menu</h2></nav><main>"+tt.html+"</main></body></html>"), "test.html")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(p.Anchors, tt.want) {
+				t.Errorf("Anchors = %v, want %v\nMarkdown:\n%s", p.Anchors, tt.want, p.Markdown)
+			}
+			if len(tt.want) > 0 && !strings.Contains(p.frontmatter(PageMeta{}, ""), "anchors:\n") {
+				t.Errorf("frontmatter missing anchors:\n%s", p.frontmatter(PageMeta{}, ""))
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the extraction of the breadcrumb trail of a page, which
+// places the page in the hierarchy of its site before the trail is removed as
+// boilerplate.
+package converter
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// breadcrumbSelectors match the breadcrumb trails of the common documentation
+// themes, in order of preference: schema.org microdata, ARIA-labelled
+// navigation, then class names.
+var breadcrumbSelectors = []string{
+	`[itemtype$="schema.org/BreadcrumbList"]`,
+	`nav[aria-label*="readcrumb"]`,
+	`.breadcrumbs, .breadcrumb, .md-path, .theme-doc-breadcrumbs`,
+}
+
+// breadcrumbSeparators are the items of a trail that only separate the others.
+var breadcrumbSeparators = map[string]bool{">": true, "/": true, "›": true, "»": true, "\\": true, "|": true, "→": true}
+
+// readBreadcrumbs fills in the breadcrumb trail of p from doc: the names of
+// the items of a schema.org BreadcrumbList in its JSON-LD, ordered by position,
+// or else the list items, or links, of the first breadcrumb navigation of the
+// page (see breadcrumbSelectors). Trails of a single item, the page itself,
+// are ignored.
+func (p *page) readBreadcrumbs(doc *goquery.Document) {
+	trail := jsonLDBreadcrumbs(doc)
+	if len(trail) == 0 {
+		for _, selector := range breadcrumbSelectors {
+			if sel := doc.Find(selector).First(); sel.Length() > 0 {
+				trail = htmlBreadcrumbs(sel)
+				break
+			}
+		}
+	}
+	if len(trail) > 1 {
+		p.Breadcrumbs = trail
+	}
+}
+
+// jsonLDBreadcrumbs returns the names of the items of the first schema.org
+// BreadcrumbList declared in the JSON-LD scripts of doc, ordered by position,
+// or nil.
+func jsonLDBreadcrumbs(doc *goquery.Document) []string {
+	var trail []string
+	doc.Find(`script[type="application/ld+json"]`).EachWithBreak(func(_ int, script *goquery.Selection) bool {
+		var data any
+		if json.Unmarshal([]byte(script.Text()), &data) != nil {
+			return true
+		}
+		trail = findBreadcrumbList(data)
+		return len(trail) == 0
+	})
+	return trail
+}
+
+// findBreadcrumbList returns the names of the items of the first BreadcrumbList
+// in data, decoded JSON-LD: an object, an array of objects, or an object with
+// a @graph.
+func findBreadcrumbList(data any) []string {
+	switch v := data.(type) {
+	case []any:
+		for _, item := range v {
+			if trail := findBreadcrumbList(item); len(trail) > 0 {
+				return trail
+			}
+		}
+	case map[string]any:
+		if v["@type"] != "BreadcrumbList" {
+			return findBreadcrumbList(v["@graph"])
+		}
+		elements, _ := v["itemListElement"].([]any)
+		type crumb struct {
+			position float64
+			name     string
+		}
+		var crumbs []crumb
+		for _, element := range elements {
+			e, ok := element.(map[string]any)
+			if !ok {
+				continue
+			}
+			name, _ := e["name"].(string)
+			if item, ok := e["item"].(map[string]any); ok && name == "" {
+				name, _ = item["name"].(string)
+			}
+			position, _ := e["position"].(float64)
+			if name = strings.Join(strings.Fields(name), " "); name != "" {
+				crumbs = append(crumbs, crumb{position, name})
+			}
+		}
+		sort.SliceStable(crumbs, func(i, j int) bool { return crumbs[i].position < crumbs[j].position })
+		var trail []string
+		for _, c := range crumbs {
+			trail = append(trail, c.name)
+		}
+		return trail
+	}
+	return nil
+}
+
+// htmlBreadcrumbs returns the items of the breadcrumb trail sel: the text of
+// its microdata names, list items, or links, whichever it has first, without
+// separators.
+func htmlBreadcrumbs(sel *goquery.Selection) []string {
+	items := sel.Find(`[itemprop="name"]`)
+	if items.Length() == 0 {
+		items = sel.Find("li")
+	}
+	if items.Length() == 0 {
+		items = sel.Find("a")
+	}
+	var trail []string
+	items.Each(func(_ int, item *goquery.Selection) {
+		name := strings.Join(strings.Fields(item.Text()), " ")
+		if name != "" && !breadcrumbSeparators[name] {
+			trail = append(trail, name)
+		}
+	})
+	return trail
+}
@@ -0,0 +1,58 @@
+package converter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadBreadcrumbs(t *testing.T) {
+	para := "<p>" + strings.Repeat("Sign every request with a key of the account, and rotate keys regularly. ", 8) + "</p>"
+	tests := []struct {
+		name string
+		html string
+		want []string
+	}{
+		{
+			"JSON-LD",
+			`<script type="application/ld+json">{"@context":"https://schema.org","@graph":[{"@type":"WebPage"},{"@type":"BreadcrumbList","itemListElement":[` +
+				`{"@type":"ListItem","position":2,"name":"API"},{"@type":"ListItem","position":1,"item":{"@id":"https://example.com/","name":"Docs"}},{"@type":"ListItem","position":3,"name":"Auth"}]}]}</script>`,
+			[]string{"Docs", "API", "Auth"},
+		},
+		{
+			"ARIA navigation",
+			`<nav aria-label="Breadcrumbs"><ol><li><a href="/">Docs</a></li><li>›</li><li><a href="/api/">API  reference</a></li><li>Auth</li></ol></nav>`,
+			[]string{"Docs", "API reference", "Auth"},
+		},
+		{
+			"links of a classed trail",
+			`<div class="breadcrumbs"><a href="/">Docs</a> / <a href="/api/">API</a></div>`,
+			[]string{"Docs", "API"},
+		},
+		{
+			"single item",
+			`<nav aria-label="breadcrumb"><ol><li>Auth</li></ol></nav>`,
+			nil,
+		},
+		{
+			"none",
+			``,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := `<html><head><title>Auth</title></head><body>` + tt.html + `<article><h1>Auth</h1>` + para + `</article></body></html>`
+			got, err := New().convertHTML([]byte(page), "page.html")
+			if err != nil {
+				t.Fatalf("convertHTML returned error: %v", err)
+			}
+			if !reflect.DeepEqual(got.Breadcrumbs, tt.want) {
+				t.Errorf("Breadcrumbs = %q, want %q", got.Breadcrumbs, tt.want)
+			}
+			if strings.Contains(got.Markdown, "API") {
+				t.Errorf("breadcrumb trail kept in the content:\n%s", got.Markdown)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the on-disk conversion cache.
+package converter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+)
+
+// Version identifies the conversion rules implemented by this package.
+// Bump it whenever a change alters the Markdown produced for the same HTML,
+// so cached conversions made by older builds are discarded.
+const Version = "15"
+
+// SetCache enables reuse of earlier conversions stored under dir.
+//
+// Entries are keyed by the SHA-256 of the HTML input and kept in a subdirectory
+// named after the converter's Fingerprint, so upgrading site2skill or changing
+// conversion settings or extensions starts from an empty cache. Subdirectories
+// left by other fingerprints are removed.
+//
+// Call SetCache after all other configuration, since the fingerprint covers it.
+func (c *Converter) SetCache(dir string) error {
+	fingerprint := c.Fingerprint()
+	cacheDir := filepath.Join(dir, fingerprint[:16])
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return fmt.Errorf("failed to create conversion cache: %w", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read conversion cache: %w", err)
+	}
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != fingerprint[:16] {
+			os.RemoveAll(filepath.Join(dir, e.Name()))
+		}
+	}
+
+	c.cacheDir = cacheDir
+	return nil
+}
+
+// CacheStats returns how many conversions were served from the cache and how
+// many had to be computed since SetCache was called.
+func (c *Converter) CacheStats() (hits, misses int) {
+	return c.cacheHits, c.cacheMisses
+}
+
+// Fingerprint returns a hex SHA-256 identifying everything that affects
+// conversion output: the package Version, the versions of the site2skill build
+// and of the HTML parsing and conversion libraries, and the converter settings
+// and extensions.
+func (c *Converter) Fingerprint() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "converter %s\n", Version)
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		fmt.Fprintf(h, "main %s\n", info.Main.Version)
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" || s.Key == "vcs.modified" {
+				fmt.Fprintf(h, "%s %s\n", s.Key, s.Value)
+			}
+		}
+		var deps []string
+		for _, dep := range info.Deps {
+			if isConversionDependency(dep.Path) {
+				deps = append(deps, dep.Path+" "+dep.Version)
+			}
+		}
+		sort.Strings(deps)
+		for _, d := range deps {
+			fmt.Fprintf(h, "dep %s\n", d)
+		}
+	}
+
+	for _, setting := range c.settings() {
+		fmt.Fprintf(h, "setting %s\n", setting)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// isConversionDependency reports whether a module influences conversion output.
+func isConversionDependency(path string) bool {
+	for _, prefix := range []string{
+		"github.com/JohannesKaufmann/html-to-markdown",
+		"github.com/PuerkitoBio/goquery",
+		"github.com/mackee/go-readability",
+		"golang.org/x/net",
+		"golang.org/x/text",
+	} {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// settings lists the converter configuration that affects output, one
+// "name=value" string per setting, for inclusion in the fingerprint.
+func (c *Converter) settings() []string {
+	var settings []string
+	if c.contentSelector != "" {
+		settings = append(settings, "content-selector="+c.contentSelector)
+	}
+	for _, selector := range c.stripSelectors {
+		settings = append(settings, "strip-selector="+selector)
+	}
+	if c.platform != "" && c.platform != PlatformAuto {
+		settings = append(settings, "platform="+c.platform)
+	}
+	settings = append(settings, "table-fallback="+c.tableFallback)
+	settings = append(settings, fmt.Sprintf("table-csv-rows=%d", c.tableCSVRows))
+	settings = append(settings, "admonitions="+c.admonitionStyle)
+	for _, ext := range c.extensions {
+		settings = append(settings, "extension="+ext.Fingerprint())
+	}
+	return settings
+}
+
+// convertCached returns the conversion of htmlContent, from the cache when enabled.
+func (c *Converter) convertCached(htmlContent []byte, htmlPath string) (page, error) {
+	if c.cacheDir == "" || len(c.transformers) > 0 {
+		// The output of transformers isn't identified by the fingerprint
+		return c.convertHTML(htmlContent, htmlPath)
+	}
+
+	sum := sha256.Sum256(htmlContent)
+	entryPath := filepath.Join(c.cacheDir, hex.EncodeToString(sum[:])+".json")
+
+	if data, err := os.ReadFile(entryPath); err == nil {
+		var entry page
+		if json.Unmarshal(data, &entry) == nil && entry.Title != "" {
+			c.cacheHits++
+			return entry, nil
+		}
+	}
+
+	c.cacheMisses++
+	p, err := c.convertHTML(htmlContent, htmlPath)
+	if err != nil || p.Title == "" {
+		return p, err
+	}
+
+	if data, err := json.Marshal(p); err == nil {
+		// A failed cache write only costs a re-conversion next time
+		tmp := entryPath + ".tmp"
+		if os.WriteFile(tmp, data, 0644) == nil {
+			os.Rename(tmp, entryPath)
+		}
+	}
+	return p, nil
+}
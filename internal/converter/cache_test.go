@@ -0,0 +1,75 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertPageUsesCache(t *testing.T) {
+	tmpDir := t.TempDir()
+	cacheDir := filepath.Join(tmpDir, "cache")
+	htmlPath := filepath.Join(tmpDir, "page.html")
+	html := `<html><head><title>Cached</title></head><body><main><p>Hello cache</p></main></body></html>`
+	if err := os.WriteFile(htmlPath, []byte(html), 0644); err != nil {
+		t.Fatalf("failed to write html fixture: %v", err)
+	}
+
+	// A directory left by another fingerprint must be pruned
+	stale := filepath.Join(cacheDir, "0000000000000000")
+	if err := os.MkdirAll(stale, 0755); err != nil {
+		t.Fatalf("failed to create stale cache dir: %v", err)
+	}
+
+	c := New()
+	if err := c.SetCache(cacheDir); err != nil {
+		t.Fatalf("SetCache returned error: %v", err)
+	}
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("stale fingerprint directory was not removed")
+	}
+
+	meta := PageMeta{SourceURL: "https://example.com/page", FetchedAt: "2024-01-01T00:00:00Z"}
+	first := filepath.Join(tmpDir, "first.md")
+	second := filepath.Join(tmpDir, "second.md")
+	if err := c.ConvertPage(htmlPath, first, meta); err != nil {
+		t.Fatalf("ConvertPage returned error: %v", err)
+	}
+
+	meta.FetchedAt = "2024-02-01T00:00:00Z"
+	if err := c.ConvertPage(htmlPath, second, meta); err != nil {
+		t.Fatalf("ConvertPage returned error: %v", err)
+	}
+
+	if hits, misses := c.CacheStats(); hits != 1 || misses != 1 {
+		t.Errorf("CacheStats() = (%d, %d), want (1, 1)", hits, misses)
+	}
+
+	got, err := os.ReadFile(second)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	// The body comes from the cache, the frontmatter from the current metadata
+	if !strings.Contains(string(got), "Hello cache") || !strings.Contains(string(got), `fetched_at: "2024-02-01T00:00:00Z"`) {
+		t.Errorf("cached conversion output is wrong:\n%s", got)
+	}
+
+	// Changed HTML is a miss
+	if err := os.WriteFile(htmlPath, []byte(strings.Replace(html, "Hello", "Goodbye", 1)), 0644); err != nil {
+		t.Fatalf("failed to update html fixture: %v", err)
+	}
+	if err := c.ConvertPage(htmlPath, second, meta); err != nil {
+		t.Fatalf("ConvertPage returned error: %v", err)
+	}
+	if _, misses := c.CacheStats(); misses != 2 {
+		t.Errorf("misses = %d after changing the HTML, want 2", misses)
+	}
+}
+
+func TestFingerprintIsStable(t *testing.T) {
+	a, b := New().Fingerprint(), New().Fingerprint()
+	if a != b || len(a) != 64 {
+		t.Errorf("Fingerprint() = %q and %q, want equal 64-char hex digests", a, b)
+	}
+}
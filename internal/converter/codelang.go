@@ -0,0 +1,250 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements language detection for code blocks.
+package converter
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// codeLanguageAttr carries the language of a code block found from its classes.
+// Readability strips class attributes but keeps data attributes, so the language
+// is recorded before extraction.
+const codeLanguageAttr = "data-code-language"
+
+// plainLanguages are class names that mark a code block as deliberately unhighlighted.
+var plainLanguages = map[string]bool{
+	"text": true, "plaintext": true, "plain": true, "txt": true, "none": true, "nohighlight": true, "no-highlight": true,
+}
+
+// languageAliases maps the names used by highlighters to common fence tags.
+var languageAliases = map[string]string{
+	"golang": "go", "py": "python", "python3": "python", "py3": "python",
+	"js": "javascript", "node": "javascript", "jsx": "jsx", "mjs": "javascript",
+	"ts": "typescript", "rb": "ruby", "rs": "rust", "kt": "kotlin", "cs": "csharp", "c#": "csharp",
+	"c++": "cpp", "cxx": "cpp", "hpp": "cpp", "h": "c", "objc": "objectivec", "objective-c": "objectivec",
+	"sh": "bash", "shell": "bash", "zsh": "bash", "shellscript": "bash", "shell-session": "console",
+	"ps1": "powershell", "pwsh": "powershell", "bat": "batch", "cmd": "batch",
+	"yml": "yaml", "md": "markdown", "htm": "html", "xhtml": "html", "svg": "xml",
+	"dockerfile": "dockerfile", "docker": "dockerfile", "tf": "hcl", "terraform": "hcl",
+	"proto": "protobuf", "gql": "graphql", "jsonc": "json", "json5": "json", "psql": "sql", "mysql": "sql",
+}
+
+// knownLanguages are fence tags accepted from bare class names (e.g., highlight.js's
+// "hljs python"), where a class could otherwise be any styling hook.
+var knownLanguages = map[string]bool{
+	"go": true, "python": true, "javascript": true, "typescript": true, "jsx": true, "tsx": true,
+	"java": true, "kotlin": true, "scala": true, "swift": true, "rust": true, "c": true, "cpp": true,
+	"csharp": true, "fsharp": true, "objectivec": true, "ruby": true, "php": true, "perl": true,
+	"lua": true, "r": true, "julia": true, "dart": true, "elixir": true, "erlang": true, "haskell": true,
+	"clojure": true, "ocaml": true, "zig": true, "bash": true, "console": true, "powershell": true,
+	"batch": true, "sql": true, "graphql": true, "json": true, "yaml": true, "toml": true, "ini": true,
+	"xml": true, "html": true, "css": true, "scss": true, "sass": true, "less": true, "markdown": true,
+	"dockerfile": true, "makefile": true, "hcl": true, "protobuf": true, "diff": true, "http": true,
+	"nginx": true, "vue": true, "svelte": true,
+}
+
+// languageClassPrefixes are the class prefixes highlighters use to name the language:
+// Prism, highlight.js, and CommonMark ("language-go"), SyntaxHighlighter
+// ("brush: go"), Google Prettify ("lang-go"), and GitHub ("highlight-source-go").
+// The value reports whether any name is trusted; otherwise it must be a known
+// language, since such a class may just as well be a styling hook ("lang-switcher").
+var languageClassPrefixes = []struct {
+	prefix  string
+	trusted bool
+}{
+	{"language-", true},
+	{"brush:", true},
+	{"lang-", false},
+	{"highlight-source-", false},
+}
+
+// normalizeLanguage maps a highlighter's language name to a fence tag.
+func normalizeLanguage(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := languageAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// languageFromClasses returns the language named by a class attribute, or "".
+// Prefixed classes ("language-go") are trusted whatever the name; bare classes
+// ("hljs python", "sourceCode python") only when they name a known language.
+func languageFromClasses(class string) string {
+	fields := strings.Fields(class)
+	for i, field := range fields {
+		lower := strings.ToLower(field)
+		for _, p := range languageClassPrefixes {
+			name, ok := strings.CutPrefix(lower, p.prefix)
+			if !ok {
+				continue
+			}
+			if name == "" && p.prefix == "brush:" && i+1 < len(fields) {
+				// SyntaxHighlighter: class="brush: js"
+				name = fields[i+1]
+			}
+			name = normalizeLanguage(strings.TrimSuffix(name, ";"))
+			switch {
+			case plainLanguages[name]:
+				return "text"
+			case name != "" && (p.trusted || knownLanguages[name]):
+				return name
+			}
+		}
+	}
+	for _, field := range fields {
+		name := normalizeLanguage(field)
+		if plainLanguages[name] {
+			return "text"
+		}
+		if knownLanguages[name] {
+			return name
+		}
+	}
+	return ""
+}
+
+// elementLanguage returns the language declared for a pre element by the classes
+// or data-lang/data-language attributes of the element, its code child, or the
+// wrappers highlighters put around it (e.g., GitHub's div.highlight-source-go).
+func elementLanguage(pre *goquery.Selection) string {
+	// Inner elements are more specific than their wrappers
+	candidates := []*goquery.Selection{pre.ChildrenFiltered("code").First(), pre}
+	if parent := pre.Parent(); parent.Is("div, figure, span") {
+		candidates = append(candidates, parent)
+		if grandparent := parent.Parent(); grandparent.Is("div, figure") {
+			candidates = append(candidates, grandparent)
+		}
+	}
+
+	for _, sel := range candidates {
+		if sel.Length() == 0 {
+			continue
+		}
+		if lang, ok := sel.Attr(codeLanguageAttr); ok && lang != "" {
+			return lang
+		}
+		for _, attr := range []string{"data-lang", "data-language"} {
+			if lang := normalizeLanguage(sel.AttrOr(attr, "")); lang != "" {
+				return lang
+			}
+		}
+		if lang := languageFromClasses(sel.AttrOr("class", "")); lang != "" {
+			return lang
+		}
+	}
+	return ""
+}
+
+// annotateCodeLanguages records the declared language of every pre element in
+// the codeLanguageAttr attribute. Returns whether any element was annotated.
+func annotateCodeLanguages(doc *goquery.Document) bool {
+	annotated := false
+	doc.Find("pre").Each(func(_ int, pre *goquery.Selection) {
+		if lang := elementLanguage(pre); lang != "" {
+			pre.SetAttr(codeLanguageAttr, lang)
+			annotated = true
+		}
+	})
+	return annotated
+}
+
+// sniffRule recognizes a language from the content of a code block.
+type sniffRule struct {
+	language string
+	pattern  *regexp.Regexp
+}
+
+// sniffRules are tried in order; the first match wins, so rules with the most
+// distinctive signals come first.
+var sniffRules = []sniffRule{
+	{"bash", regexp.MustCompile(`\A#!\s*/(?:usr/)?bin/(?:env\s+)?(?:ba|z)?sh\b`)},
+	{"python", regexp.MustCompile(`\A#!\s*/(?:usr/)?bin/(?:env\s+)?python`)},
+	{"php", regexp.MustCompile(`\A<\?php`)},
+	{"xml", regexp.MustCompile(`\A<\?xml`)},
+	{"html", regexp.MustCompile(`(?i)\A<!doctype html|\A<(?:html|head|body|div|span|p|a|script|link|meta)[\s>]`)},
+	{"go", regexp.MustCompile(`(?m)^package \w+\s*$|^func (?:\(\w+ \*?\w+\) )?\w+\(|^import \(\s*$|\b\w+ := `)},
+	{"rust", regexp.MustCompile(`(?m)^\s*(?:pub )?fn \w+|\blet mut \b|\bprintln!\(|^use \w+(?:::\w+)+;`)},
+	{"java", regexp.MustCompile(`(?m)^\s*(?:public|private|protected) (?:static )?(?:final )?(?:class|void|interface) |System\.out\.println\(`)},
+	{"csharp", regexp.MustCompile(`(?m)^using System(?:\.\w+)*;|Console\.WriteLine\(|\bnamespace \w+(?:\.\w+)*\s*\{?$`)},
+	{"dockerfile", regexp.MustCompile(`(?m)\A(?:#.*\n)*FROM \S+(?:\s+AS \S+)?\s*$`)},
+	{"sql", regexp.MustCompile(`(?i)\A\s*(?:SELECT\s.+\sFROM\s|INSERT INTO\s|UPDATE \w+ SET\s|DELETE FROM\s|CREATE (?:TABLE|INDEX|VIEW|DATABASE)\s|ALTER TABLE\s)`)},
+	{"python", regexp.MustCompile(`(?m)^\s*(?:def|class) \w+(?:\(.*\))?:\s*$|^from [\w.]+ import \w|^import \w+(?:\.\w+)*\s*$|\bprint\(f?["']|^\s*if __name__ == `)},
+	{"typescript", regexp.MustCompile(`(?m)^\s*(?:export )?(?:interface|type) \w+(?:<.*>)? (?:=|\{)|\b(?:const|let) \w+: \w+(?:\[\])? =|\): (?:string|number|boolean|void|Promise<)`)},
+	{"javascript", regexp.MustCompile(`(?m)\b(?:const|let|var) \w+ = |\bfunction\s*\w*\(|=> \{|\bconsole\.log\(|\brequire\(["']|^import .+ from ["']|^export (?:default|const|function) `)},
+	{"ruby", regexp.MustCompile(`(?m)^\s*require ["']\w|^\s*puts |^\s*def \w+[?!]?(?:\(.*\))?\s*$|\bdo \|\w+\|`)},
+	{"console", regexp.MustCompile(`(?m)\A\$ \S`)},
+	{"bash", regexp.MustCompile(`(?m)\A(?:sudo )?(?:npm|npx|yarn|pnpm|pip3?|pipx|brew|apt(?:-get)?|go|cargo|git|curl|wget|docker|kubectl|helm|make|cd|export|mkdir|chmod|gem|bundle|composer|dotnet|mvn|gradle|uv|poetry)\s`)},
+	{"css", regexp.MustCompile(`(?m)^[.#]?[\w-]+(?:[\s>+~:.#][\w-]+)*\s*\{\s*$\s*^\s*[\w-]+\s*:\s*[^;]+;`)},
+	{"yaml", regexp.MustCompile(`(?m)\A(?:---\s*\n)?(?:#.*\n)*[\w.-]+:(?:\s+\S.*)?\n(?:\s+-?\s*[\w.-]+:|[\w.-]+:|\s+- )`)},
+	{"toml", regexp.MustCompile(`(?m)\A(?:#.*\n)*\[[\w.-]+\]\s*\n[\w.-]+\s*=\s*\S`)},
+}
+
+// sniffLanguage guesses the language of a code block from its content, or
+// returns "" when no rule matches.
+func sniffLanguage(code string) string {
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return ""
+	}
+	if (code[0] == '{' || code[0] == '[') && json.Valid([]byte(code)) {
+		return "json"
+	}
+	for _, rule := range sniffRules {
+		if rule.pattern.MatchString(code) {
+			return rule.language
+		}
+	}
+	return ""
+}
+
+// convertCodeBlock is the html-to-markdown rule for pre elements. It emits a
+// fenced code block tagged with the declared language or, failing that, the
+// language sniffed from the content.
+func convertCodeBlock(_ string, pre *goquery.Selection, opt *md.Options) *string {
+	code := codeText(pre)
+
+	language := elementLanguage(pre)
+	if language == "" {
+		language = sniffLanguage(code)
+	}
+
+	fence := md.CalculateCodeFence('`', code)
+	if strings.HasPrefix(opt.Fence, "~") {
+		fence = md.CalculateCodeFence('~', code)
+	}
+	text := "\n\n" + fence + language + "\n" + strings.TrimSuffix(code, "\n") + "\n" + fence + "\n\n"
+	return &text
+}
+
+// codeText returns the text of a code block. br and div elements (used by some
+// highlighters for lines) become line breaks.
+func codeText(sel *goquery.Selection) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && (n.Data == "style" || n.Data == "script" || n.Data == "textarea") {
+			return
+		}
+		if n.Type == html.ElementNode && (n.Data == "br" || n.Data == "div") {
+			b.WriteString("\n")
+		}
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	for _, n := range sel.Nodes {
+		walk(n)
+	}
+	return b.String()
+}
@@ -0,0 +1,97 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLanguageFromClasses(t *testing.T) {
+	tests := []struct {
+		class string
+		want  string
+	}{
+		{"language-go", "go"},
+		{"hljs language-golang", "go"},
+		{"line-numbers language-mermaid", "mermaid"},
+		{"hljs python", "python"},
+		{"sourceCode py", "python"},
+		{"highlight highlight-source-shell", "bash"},
+		{"prettyprint lang-js", "javascript"},
+		{"brush: ruby;", "ruby"},
+		{"language-plaintext", "text"},
+		{"nohighlight", "text"},
+		{"lang-switcher", ""},
+		{"highlight notranslate", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.class, func(t *testing.T) {
+			if got := languageFromClasses(tt.class); got != tt.want {
+				t.Errorf("languageFromClasses(%q) = %q, want %q", tt.class, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSniffLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want string
+	}{
+		{"shebang", "#!/usr/bin/env bash\nset -e", "bash"},
+		{"json", `{"name": "demo", "tags": [1, 2]}`, "json"},
+		{"go", "package main\n\nfunc main() {\n\tx := 1\n}", "go"},
+		{"python", "import os\n\ndef main():\n    print('hi')", "python"},
+		{"typescript", "interface User {\n  name: string\n}", "typescript"},
+		{"javascript", "const client = require('demo');\nclient.run(() => {\n});", "javascript"},
+		{"rust", "fn main() {\n    println!(\"hi\");\n}", "rust"},
+		{"java", "public class Main {\n  public static void main(String[] args) {}\n}", "java"},
+		{"sql", "SELECT id, name FROM users WHERE id = 1;", "sql"},
+		{"html", "<!DOCTYPE html>\n<html></html>", "html"},
+		{"dockerfile", "FROM golang:1.22 AS build\nRUN go build", "dockerfile"},
+		{"prompt", "$ site2skillgo generate https://example.com demo", "console"},
+		{"command", "npm install --save demo", "bash"},
+		{"yaml", "server:\n  port: 8080\n  host: localhost", "yaml"},
+		{"toml", "[package]\nname = \"demo\"", "toml"},
+		{"css", ".button {\n  color: red;\n}", "css"},
+		{"prose", "Hello, world. This is output text.", ""},
+		{"invalid json", "{ not json", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sniffLanguage(tt.code); got != tt.want {
+				t.Errorf("sniffLanguage(%q) = %q, want %q", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertCodeBlocks(t *testing.T) {
+	para := "<p>" + strings.Repeat("This guide explains the installation in detail, with commas, clauses, and plenty of words. ", 8) + "</p>"
+	page := `<html><head><title>Install</title></head><body><article><h1>Install</h1>` + para + para + `
+<div class="highlight highlight-source-python"><pre class="notranslate">import demo</pre></div>
+<pre><code class="hljs language-golang">x := demo.New()</code></pre>
+<pre><code>{"debug": true}</code></pre>
+<pre><code class="language-text">x := not code</code></pre>
+<pre><code>Some ` + "```" + ` output</code></pre>` + para + `</article></body></html>`
+
+	got, err := New().convertHTML([]byte(page), "page.html")
+	if err != nil {
+		t.Fatalf("convertHTML returned error: %v", err)
+	}
+	markdown := got.Markdown
+	for _, want := range []string{
+		"```python\nimport demo\n```",
+		"```go\nx := demo.New()\n```",
+		"```json\n{\"debug\": true}\n```",
+		"```text\nx := not code\n```",
+		"````\nSome ``` output\n````",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("markdown missing %q:\n%s", want, markdown)
+		}
+	}
+}
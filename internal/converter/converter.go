@@ -5,6 +5,8 @@ package converter
 
 import (
 	"bytes"
+	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -15,12 +17,34 @@ import (
 
 	md "github.com/JohannesKaufmann/html-to-markdown"
 	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/f4ah6o/site2skill-go/internal/assets"
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+	"github.com/f4ah6o/site2skill-go/internal/pdf"
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
 	"github.com/mackee/go-readability"
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/htmlindex"
 	"golang.org/x/text/transform"
 )
 
+// ErrConversionFailed is returned by ConvertFile and ConvertPage when the HTML
+// can't be parsed or converted to Markdown. Errors reading or writing files don't wrap it.
+var ErrConversionFailed = errors.New("conversion failed")
+
+// Errors wrapped, along with ErrConversionFailed, by the errors of the
+// conversions, to tell why a page couldn't be converted.
+var (
+	// ErrParseFailed means a page couldn't be parsed as HTML or PDF.
+	ErrParseFailed = errors.New("failed to parse the page")
+	// ErrEncryptedPDF means a PDF document is encrypted, so its text can't be
+	// extracted. It is wrapped with ErrParseFailed.
+	ErrEncryptedPDF = pdf.ErrEncrypted
+	// ErrTransformFailed means a Markdown transformer (see
+	// AddMarkdownTransformer) returned an error.
+	ErrTransformFailed = errors.New("failed to transform markdown")
+)
+
 // Converter converts HTML content to Markdown format with YAML frontmatter metadata.
 // It extracts main content from HTML documents, cleans unwanted elements, and generates
 // Markdown suitable for documentation skill packages. The converter handles character
@@ -28,17 +52,162 @@ import (
 type Converter struct {
 	// mdConverter is the underlying HTML to Markdown conversion engine
 	mdConverter *md.Converter
+	// contentSelector, when set, selects the main content instead of Readability
+	contentSelector string
+	// stripSelectors match elements removed from every page before extraction
+	stripSelectors []string
+	// platform is the platform profile applied to the pages: PlatformAuto (or ""), PlatformNone, or a platform name
+	platform string
+	// tableFallback renders tables that can't be GFM tables (TableFallbackHTML or TableFallbackList)
+	tableFallback string
+	// admonitionStyle renders admonitions (AdmonitionStyleGitHub, AdmonitionStyleBlockquote, or AdmonitionStyleNone)
+	admonitionStyle string
+	// tableCSVRows is the body row count above which tables are saved as CSV; 0 disables it
+	tableCSVRows int
+	// tables collects the CSV files of the large tables of the page being converted
+	tables []string
+	// trace records how the page being converted is extracted; nil outside Inspect
+	trace *Inspection
+	// meta is the metadata of the page being converted, for the extensions and transformers
+	meta PageMeta
+	// extensions extract and convert the pages they handle before the built-in rules
+	extensions []Extension
+	// transformers and markdownTransformers rewrite every page (see AddTransformer)
+	transformers         []Transformer
+	markdownTransformers []MarkdownTransformer
+	// pageTypes are the page types kept (see SetPageTypes); nil keeps every page
+	pageTypes map[string]bool
+	// languageFilter is LanguageFilterFlag or LanguageFilterExclude; empty turns the filter off
+	languageFilter string
+	// languages are the languages of the locale priority, expected of pages without a locale (see SetLanguageFilter)
+	languages []string
+	// cacheDir holds cached conversions for the current fingerprint; empty disables caching
+	cacheDir string
+	// cacheHits and cacheMisses count cache lookups since SetCache
+	cacheHits   int
+	cacheMisses int
 }
 
 // New creates a new Converter instance with default configuration.
-// The converter is configured to preserve links and use standard Markdown formatting.
+// The converter is configured to preserve links and use standard Markdown formatting,
+// with tables converted to GFM tables (see SetTableFallback), code blocks
+// fenced with their detected language, admonitions converted to GitHub
+// alerts (see SetAdmonitionStyle), math kept as $...$ LaTeX, definition
+// lists kept as Markdown definition lists, and defining terms (dfn) in bold.
 //
 // Returns a Converter ready to process HTML files.
 func New() *Converter {
-	converter := md.NewConverter("", true, nil)
-	return &Converter{
-		mdConverter: converter,
+	c := &Converter{
+		mdConverter:     md.NewConverter("", true, nil),
+		tableFallback:   TableFallbackHTML,
+		admonitionStyle: AdmonitionStyleGitHub,
+	}
+	c.mdConverter.AddRules(
+		md.Rule{Filter: []string{"table"}, Replacement: c.convertTable},
+		md.Rule{Filter: []string{"pre"}, Replacement: convertCodeBlock},
+		md.Rule{Filter: []string{"blockquote"}, Replacement: c.convertAdmonition},
+		md.Rule{Filter: []string{"var", "div"}, Replacement: convertMath},
+		md.Rule{Filter: []string{"dl"}, Replacement: c.convertDefinitionList},
+		md.Rule{Filter: []string{"dfn"}, Replacement: convertDefiningTerm},
+	)
+	return c
+}
+
+// SetContentSelector makes the converter take the main content from the elements
+// matching a CSS selector group (e.g., "main, article" or "div.markdown-body")
+// instead of Readability. Matches nested inside other matches are ignored. Pages
+// where the selector matches nothing fall back to Readability.
+//
+// Returns an error if the selector is not valid CSS.
+func (c *Converter) SetContentSelector(selector string) error {
+	if _, err := cascadia.ParseGroup(selector); err != nil {
+		return fmt.Errorf("invalid content selector %q: %w", selector, err)
+	}
+	c.contentSelector = selector
+	return nil
+}
+
+// SetStripSelectors sets CSS selectors (e.g., ".ad-banner", "#feedback-widget",
+// "nav.breadcrumbs") whose matching elements are removed from every page before the
+// main content is extracted, in addition to the built-in boilerplate rules.
+//
+// Returns an error if any selector is not valid CSS.
+func (c *Converter) SetStripSelectors(selectors []string) error {
+	for _, selector := range selectors {
+		if _, err := cascadia.ParseGroup(selector); err != nil {
+			return fmt.Errorf("invalid strip selector %q: %w", selector, err)
+		}
 	}
+	c.stripSelectors = selectors
+	return nil
+}
+
+// Extension is a site-specific extractor and converter plugged into the
+// converter, such as a plugin (see package plugin).
+type Extension interface {
+	// Name identifies the extension in warnings and inspections.
+	Name() string
+	// Fingerprint identifies the output of the extension, e.g., its name and
+	// version, for the conversion cache.
+	Fingerprint() string
+	// Handles reports whether the extension applies to the page at pageURL,
+	// served with the Content-Type contentType (empty if unknown).
+	Handles(pageURL, contentType string) bool
+	// Extract returns the HTML of the main content of the page at pageURL
+	// with the HTML html, and optionally its title; an empty content declines.
+	Extract(pageURL, html string) (content, title string, err error)
+	// Convert returns the Markdown of html, the main content of the page at
+	// pageURL; an empty result declines.
+	Convert(pageURL, html string) (string, error)
+}
+
+// AddExtension makes the converter offer the pages ext handles to it, after
+// the extensions added before: the main content is taken from the first
+// extension extracting it, instead of the content selector or Readability,
+// and converted by the first extension converting it, instead of the built-in
+// rules. The built-in rules take over for the pages every extension declines,
+// and, with a warning, when an extension fails.
+func (c *Converter) AddExtension(ext Extension) {
+	c.extensions = append(c.extensions, ext)
+}
+
+// PageMeta carries per-page metadata written into the Markdown frontmatter.
+type PageMeta struct {
+	// SourceURL is the original URL where the HTML was fetched from.
+	SourceURL string
+	// Source names the site the page comes from when a skill is built from
+	// several (e.g., "api.example.com"); omitted from the frontmatter when empty.
+	Source string
+	// FetchedAt is the ISO 8601 timestamp when the page was fetched.
+	FetchedAt string
+	// ModifiedAt is the RFC 3339 time the server reports the page was last
+	// modified; omitted from the frontmatter when empty.
+	ModifiedAt string
+	// Author is the author of the page, as named by the feed entry it was
+	// built from; omitted from the frontmatter when empty.
+	Author string
+	// Published is the RFC 3339 time the feed entry of the page was
+	// published; omitted from the frontmatter when empty.
+	Published string
+	// Locale is the locale of the fetched variant; omitted from the frontmatter when empty.
+	Locale string
+	// Version is the documentation version of the page when the crawl selected
+	// one; omitted from the frontmatter when empty.
+	Version string
+	// StatusCode is the HTTP status of the response; omitted from the frontmatter when 0.
+	StatusCode int
+	// FinalURL is the URL of the response after redirects and locale fallback;
+	// omitted from the frontmatter when empty.
+	FinalURL string
+	// ContentLanguage is the Content-Language header of the response; omitted
+	// from the frontmatter when empty.
+	ContentLanguage string
+	// Access is the access level of the page assigned by the access rules (see
+	// package access); omitted from the frontmatter when empty.
+	Access string
+	// ContentType is the Content-Type header of the response, which selects
+	// the extensions applying to the page; never written to the frontmatter.
+	ContentType string
 }
 
 // ConvertFile converts an HTML file to Markdown with YAML frontmatter metadata.
@@ -58,22 +227,177 @@ func New() *Converter {
 //   - sourceURL: Original URL where the HTML was fetched from (included in frontmatter)
 //   - fetchedAt: ISO 8601 timestamp when the page was fetched (included in frontmatter)
 //
-// Returns an error if the HTML file cannot be read, parsed, or if the output file cannot be written.
+// Returns an error if the HTML file cannot be read, parsed (wrapping ErrConversionFailed),
+// or if the output file cannot be written.
 // Logs a warning and returns nil if no main content is found in the HTML.
 func (c *Converter) ConvertFile(htmlPath, outputPath, sourceURL, fetchedAt string) error {
-	// Read HTML file
-	htmlContent, err := os.ReadFile(htmlPath)
-	if err != nil {
-		return fmt.Errorf("failed to read HTML file: %w", err)
+	return c.ConvertPage(htmlPath, outputPath, PageMeta{SourceURL: sourceURL, FetchedAt: fetchedAt})
+}
+
+// ConvertPage is like ConvertFile but takes the frontmatter metadata as a PageMeta,
+// which also allows recording the locale of the page. An HTML file whose name
+// ends with .gz is decompressed (see fetcher.SetCompressCrawl).
+func (c *Converter) ConvertPage(htmlPath, outputPath string, meta PageMeta) error {
+	return c.ConvertSeries([]string{htmlPath}, outputPath, meta)
+}
+
+// ConvertSeries is like ConvertPage but converts the pages of a paginated
+// article or listing, the HTML files htmlPaths in order, into one Markdown
+// file. The first page, described by meta, provides the title and the
+// frontmatter; the content of every other page is appended to its content
+// (see page.appendPage), and the content hash covers every page. Pages without
+// main content are left out, and nothing is written if the first has none.
+func (c *Converter) ConvertSeries(htmlPaths []string, outputPath string, meta PageMeta) error {
+	if len(htmlPaths) == 0 {
+		return fmt.Errorf("%w: no page to convert", ErrConversionFailed)
 	}
+	htmlPath := htmlPaths[0]
+	var p page
+	var content []byte
+	for i, path := range htmlPaths {
+		// Read HTML file
+		htmlContent, err := readPage(path)
+		if err != nil {
+			return fmt.Errorf("failed to read HTML file: %w", err)
+		}
 
+		c.meta = meta
+		next, err := c.convertCached(htmlContent, path)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrConversionFailed, err)
+		}
+		content = append(content, htmlContent...)
+		if i > 0 {
+			if next.Title != "" {
+				p.appendPage(next)
+			}
+			continue
+		}
+		p = next
+		if p.Title == "" {
+			// No main content; convertHTML already logged a warning
+			return nil
+		}
+		if pageType := p.pageType(meta.SourceURL); !c.keepsPageType(pageType) {
+			warnlog.Printf("page_type", "Skipping %s page %s", pageType, htmlPath)
+			return nil
+		}
+		if !c.keepsLanguage(&p, meta, htmlPath) {
+			return nil
+		}
+	}
+	if err := c.transformMarkdown(&p, meta); err != nil {
+		return fmt.Errorf("%w: %w", ErrConversionFailed, err)
+	}
+
+	finalMD := p.frontmatter(meta, contentHash(content)) + p.Markdown
+
+	// Ensure output directory exists when one is specified
+	outputDir := filepath.Dir(outputPath)
+	if outputDir != "" && outputDir != "." {
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+
+	// Save large tables next to the page and point their links at the files
+	if len(p.Tables) > 0 {
+		tablesDir := filepath.Join(outputDir, assets.DirName)
+		if err := os.MkdirAll(tablesDir, 0755); err != nil {
+			return fmt.Errorf("failed to create tables directory: %w", err)
+		}
+		base := strings.TrimSuffix(filepath.Base(outputPath), filepath.Ext(outputPath))
+		for i, table := range p.Tables {
+			name := fmt.Sprintf("%s-table-%d.csv", base, i+1)
+			if err := os.WriteFile(filepath.Join(tablesDir, name), []byte(table), 0644); err != nil {
+				return fmt.Errorf("failed to write table: %w", err)
+			}
+			link := fmt.Sprintf("](%s%d)", tableLinkPrefix, i+1)
+			finalMD = strings.Replace(finalMD, link, "](../"+assets.DirName+"/"+name+")", 1)
+		}
+	}
+
+	// Write output
+	if _, err := atomicfile.WriteFile(outputPath, []byte(finalMD)); err != nil {
+		return fmt.Errorf("failed to write markdown file: %w", err)
+	}
+
+	if len(htmlPaths) > 1 {
+		log.Printf("Converted: %s and %d following pages -> %s", htmlPath, len(htmlPaths)-1, outputPath)
+		return nil
+	}
+	log.Printf("Converted: %s -> %s", htmlPath, outputPath)
+	return nil
+}
+
+// convertHTML extracts the main content of an HTML document and converts it to Markdown,
+// along with the metadata in the document head.
+// htmlPath is only used in log messages. Returns an empty page when no main content is found.
+func (c *Converter) convertHTML(htmlContent []byte, htmlPath string) (page, error) {
 	// Decode HTML with proper charset handling
 	htmlString := decodeHTML(htmlContent)
 
 	// Parse HTML
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(htmlString))
 	if err != nil {
-		return fmt.Errorf("failed to parse HTML: %w", err)
+		return page{}, fmt.Errorf("%w: %w", ErrParseFailed, err)
+	}
+	c.tables = nil
+	var p page
+	p.readHead(doc)
+	p.readStructuredData(doc)
+	// The trail and sidebar are read before boilerplate removal drops them
+	p.readBreadcrumbs(doc)
+	p.readNavigation(doc)
+	p.Signals = scorePage(doc)
+	headings := collectHeadings(doc)
+	platform := c.pagePlatform(doc)
+	if c.trace != nil {
+		c.trace.Generator = metaContent(doc, `meta[name="generator"]`)
+		if platform != nil {
+			c.trace.Platform = platform.Name
+		}
+	}
+
+	// Remove site-specific clutter before any extraction sees it
+	for _, selector := range c.stripSelectors {
+		c.remove(doc.Find(selector), selector, RuleStripSelector)
+	}
+	if platform != nil {
+		for _, selectors := range [][]string{platform.StripSelectors, platform.Pagination} {
+			for _, selector := range selectors {
+				c.remove(doc.Find(selector), selector, RulePlatform)
+			}
+		}
+	}
+	// Then let the transformers rewrite what is left
+	for _, t := range c.transformers {
+		if err := t.Transform(doc.Nodes[0], c.meta); err != nil {
+			return page{}, fmt.Errorf("failed to transform HTML: %w", err)
+		}
+	}
+	// Record code block languages, since Readability drops the classes naming them
+	annotated := annotateCodeLanguages(doc)
+	// Likewise for admonitions, whose classes name their type
+	if c.admonitionStyle != AdmonitionStyleNone && annotateAdmonitions(doc) {
+		annotated = true
+	}
+	// And for formulas, whose source is in scripts and MathML annotations
+	if annotateMath(doc) {
+		annotated = true
+	}
+	// Heading permalinks are dropped once the headings' anchors are recorded
+	if removePermalinks(doc) {
+		annotated = true
+	}
+	// FAQ pages keep their questions as headings
+	if p.readKind(doc, c.meta.SourceURL) {
+		annotated = true
+	}
+	if len(c.stripSelectors) > 0 || platform != nil || len(c.transformers) > 0 || annotated {
+		if htmlString, err = doc.Html(); err != nil {
+			return page{}, fmt.Errorf("failed to render HTML: %w", err)
+		}
 	}
 
 	// Extract title
@@ -84,38 +408,67 @@ func (c *Converter) ConvertFile(htmlPath, outputPath, sourceURL, fetchedAt strin
 		title = strings.TrimSpace(h1Text)
 	}
 
-	mainHTML := ""
+	// An extension handling the page takes precedence over the built-in rules
+	mainHTML, extTitle := c.extract(htmlString, htmlPath)
+	if extTitle != "" {
+		title = extTitle
+	}
 
-	// First, try Readability extraction for more accurate content isolation
-	if article, err := readability.Extract(htmlString, readability.DefaultOptions()); err == nil {
-		if content := strings.TrimSpace(readability.ToHTML(article.Root)); content != "" {
-			mainHTML = content
-			if readableTitle := strings.TrimSpace(article.Title); readableTitle != "" {
-				title = readableTitle
+	// A configured content selector takes precedence over heuristics
+	if mainHTML == "" && c.contentSelector != "" {
+		mainHTML, err = c.selectContent(doc, c.contentSelector, RuleContentSelector)
+		if err != nil {
+			return page{}, err
+		}
+		if mainHTML == "" {
+			warnlog.Printf("content_selector", "Warning: content selector %q matched nothing in %s, falling back to Readability", c.contentSelector, htmlPath)
+		} else {
+			c.traceExtraction(ExtractionContentSelector, "")
+		}
+	}
+
+	// And so does the content selector of the platform of the page
+	if mainHTML == "" && c.contentSelector == "" && platform != nil && platform.ContentSelector != "" {
+		mainHTML, err = c.selectContent(doc, platform.ContentSelector, RulePlatform)
+		if err != nil {
+			return page{}, err
+		}
+		if mainHTML != "" {
+			c.traceExtraction(ExtractionPlatform, platform.Name)
+		}
+	}
+
+	// Otherwise, try Readability extraction for more accurate content isolation
+	if mainHTML == "" {
+		if article, err := readability.Extract(htmlString, readability.DefaultOptions()); err == nil {
+			if content := strings.TrimSpace(readability.ToHTML(article.Root)); content != "" {
+				mainHTML = content
+				c.traceExtraction(ExtractionReadability, "")
+				if readableTitle := strings.TrimSpace(article.Title); readableTitle != "" {
+					title = readableTitle
+				}
+			} else {
+				warnlog.Printf("readability", "Warning: Readability returned empty content for %s, falling back to selector extraction", htmlPath)
 			}
 		} else {
-			log.Printf("Warning: Readability returned empty content for %s, falling back to selector extraction", htmlPath)
+			warnlog.Printf("readability", "Warning: Readability parsing failed for %s: %v; falling back to selector extraction", htmlPath, err)
 		}
-	} else {
-		log.Printf("Warning: Readability parsing failed for %s: %v; falling back to selector extraction", htmlPath, err)
 	}
 
 	if mainHTML == "" {
 		// Fallback to existing DOM-based extraction
 		var mainContent *goquery.Selection
-		if main := doc.Find("main").First(); main.Length() > 0 {
-			mainContent = main
-		} else if article := doc.Find("article").First(); article.Length() > 0 {
-			mainContent = article
-		} else if content := doc.Find("div.content").First(); content.Length() > 0 {
-			mainContent = content
-		} else if body := doc.Find("body").First(); body.Length() > 0 {
-			mainContent = body
-		}
-
-		if mainContent == nil || mainContent.Length() == 0 {
-			log.Printf("Warning: No main content found in %s", htmlPath)
-			return nil
+		for _, selector := range []string{"main", "article", "div.content", "body"} {
+			if sel := doc.Find(selector).First(); sel.Length() > 0 {
+				mainContent = sel
+				c.traceExtraction(ExtractionFallback, selector)
+				break
+			}
+		}
+
+		if mainContent == nil {
+			warnlog.Printf("no_content", "Warning: No main content found in %s", htmlPath)
+			return page{}, nil
 		}
 
 		// Clean HTML
@@ -124,45 +477,95 @@ func (c *Converter) ConvertFile(htmlPath, outputPath, sourceURL, fetchedAt strin
 		var err error
 		mainHTML, err = mainContent.Html()
 		if err != nil {
-			return fmt.Errorf("failed to get HTML: %w", err)
+			return page{}, fmt.Errorf("failed to get HTML: %w", err)
 		}
 	}
 
-	markdown, err := c.mdConverter.ConvertString(mainHTML)
-	if err != nil {
-		return fmt.Errorf("failed to convert to markdown: %w", err)
+	markdown := c.convertExtension(mainHTML, htmlPath)
+	if markdown == "" {
+		if markdown, err = c.mdConverter.ConvertString(mainHTML); err != nil {
+			return page{}, fmt.Errorf("failed to convert to markdown: %w", err)
+		}
 	}
 
 	// Post-process markdown
 	markdown = c.postProcessMarkdown(markdown)
+	if sections := c.structuredSections(p.Structured, markdown); sections != "" {
+		markdown = strings.TrimRight(markdown, "\n") + sections
+	}
 
-	// Create frontmatter
-	escapedTitle := strings.ReplaceAll(title, `"`, `\"`)
-	frontmatter := fmt.Sprintf(`---
-title: "%s"
-source_url: "%s"
-fetched_at: "%s"
----
-
-`, escapedTitle, sourceURL, fetchedAt)
+	p.Title = title
+	p.Markdown = markdown
+	p.Tables = c.tables
+	p.Anchors = matchAnchors(headings, markdown)
+	return p, nil
+}
 
-	finalMD := frontmatter + markdown
+// extract returns the main content and title of the page with the HTML html
+// from the first extension extracting it, or "" if none does.
+func (c *Converter) extract(html, htmlPath string) (string, string) {
+	for _, ext := range c.extensions {
+		if !ext.Handles(c.meta.SourceURL, c.meta.ContentType) {
+			continue
+		}
+		content, title, err := ext.Extract(c.meta.SourceURL, html)
+		if err != nil {
+			warnlog.Printf("plugin", "Warning: %s failed to extract %s, using the built-in extraction: %v", ext.Name(), htmlPath, err)
+			continue
+		}
+		if content = strings.TrimSpace(content); content != "" {
+			c.traceExtraction(ExtractionExtension, ext.Name())
+			return content, strings.TrimSpace(title)
+		}
+	}
+	return "", ""
+}
 
-	// Ensure output directory exists when one is specified
-	outputDir := filepath.Dir(outputPath)
-	if outputDir != "" && outputDir != "." {
-		if err := os.MkdirAll(outputDir, 0755); err != nil {
-			return fmt.Errorf("failed to create output directory: %w", err)
+// convertExtension returns the Markdown of the main content mainHTML from
+// the first extension converting it, or "" if none does.
+func (c *Converter) convertExtension(mainHTML, htmlPath string) string {
+	for _, ext := range c.extensions {
+		if !ext.Handles(c.meta.SourceURL, c.meta.ContentType) {
+			continue
+		}
+		markdown, err := ext.Convert(c.meta.SourceURL, mainHTML)
+		if err != nil {
+			warnlog.Printf("plugin", "Warning: %s failed to convert %s, using the built-in conversion: %v", ext.Name(), htmlPath, err)
+			continue
+		}
+		if strings.TrimSpace(markdown) != "" {
+			return markdown
 		}
 	}
+	return ""
+}
 
-	// Write output
-	if err := os.WriteFile(outputPath, []byte(finalMD), 0644); err != nil {
-		return fmt.Errorf("failed to write markdown file: %w", err)
+// selectContent returns the cleaned HTML of the elements matching selector, a
+// content selector of the rule source, outermost matches only, or "" if
+// nothing matches.
+func (c *Converter) selectContent(doc *goquery.Document, selector, source string) (string, error) {
+	matches := doc.Find(selector).FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return s.ParentsFiltered(selector).Length() == 0
+	})
+	if c.trace != nil {
+		c.trace.record(selector, source, matches)
 	}
 
-	log.Printf("Converted: %s -> %s", htmlPath, outputPath)
-	return nil
+	var parts []string
+	var err error
+	matches.EachWithBreak(func(_ int, s *goquery.Selection) bool {
+		c.cleanHTML(s)
+		var part string
+		if part, err = s.Html(); err != nil {
+			err = fmt.Errorf("failed to get HTML: %w", err)
+			return false
+		}
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+		return true
+	})
+	return strings.Join(parts, "\n"), err
 }
 
 // cleanHTML removes unwanted elements from the selected HTML content.
@@ -174,10 +577,14 @@ func (c *Converter) cleanHTML(sel *goquery.Selection) {
 		"script", "style", "meta", "link", "noscript", "iframe", "svg",
 		".sidebar", "header", "footer", ".nav", ".menu", "#sidebar",
 		".navigation", ".toc", "#toc", ".footer", "#footer",
+		"nav", "aside", "[role=navigation]", "[role=banner]", "[role=contentinfo]",
+		// Cookie consent banners
+		"#cookie-banner", ".cookie-banner", "#cookie-consent", ".cookie-consent",
+		"#onetrust-consent-sdk", "#CybotCookiebotDialog", ".cc-window",
 	}
 
 	for _, selector := range unwantedSelectors {
-		sel.Find(selector).Remove()
+		c.remove(sel.Find(selector), selector, RuleBoilerplate)
 	}
 }
 
@@ -197,6 +604,21 @@ func (c *Converter) postProcessMarkdown(md string) string {
 	return strings.Join(lines, "\n")
 }
 
+// readPage reads the HTML file at path, decompressing it if its name ends
+// with .gz, as the fetcher stores the crawled pages when it compresses them.
+func readPage(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.HasSuffix(path, ".gz") {
+		return data, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
 // decodeHTML decodes HTML bytes to a UTF-8 string using character encoding detection.
 // It attempts to detect the character encoding from HTML meta tags (both <meta charset>
 // and <meta http-equiv="Content-Type"> formats) and decodes accordingly.
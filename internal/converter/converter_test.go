@@ -1,8 +1,14 @@
 package converter
 
 import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -71,3 +77,328 @@ func TestConvertFileCreatesOutputInCurrentDir(t *testing.T) {
 		t.Fatalf("expected output file to be created, got error: %v", err)
 	}
 }
+
+func TestConvertPageWritesLocale(t *testing.T) {
+	tmpDir := t.TempDir()
+	htmlPath := filepath.Join(tmpDir, "input.html")
+	html := `<html><head><title>Guide</title></head><body><main><p>Hello</p></main></body></html>`
+	if err := os.WriteFile(htmlPath, []byte(html), 0644); err != nil {
+		t.Fatalf("failed to write html fixture: %v", err)
+	}
+
+	c := New()
+	tests := []struct {
+		name   string
+		locale string
+		want   bool
+	}{
+		{"with locale", "ja", true},
+		{"without locale", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputPath := filepath.Join(tmpDir, tt.name+".md")
+			meta := PageMeta{SourceURL: "https://example.com/guide", FetchedAt: "2024-01-01T00:00:00Z", Locale: tt.locale}
+			if err := c.ConvertPage(htmlPath, outputPath, meta); err != nil {
+				t.Fatalf("ConvertPage returned error: %v", err)
+			}
+			data, err := os.ReadFile(outputPath)
+			if err != nil {
+				t.Fatalf("failed to read output: %v", err)
+			}
+			if got := strings.Contains(string(data), "locale: \"ja\"\n"); got != tt.want {
+				t.Errorf("locale in frontmatter = %v, want %v:\n%s", got, tt.want, data)
+			}
+		})
+	}
+}
+
+func TestConvertPageCompressed(t *testing.T) {
+	tmpDir := t.TempDir()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(`<html><head><title>Guide</title></head><body><main><p>Compressed page</p></main></body></html>`))
+	zw.Close()
+	htmlPath := filepath.Join(tmpDir, "input.html.gz")
+	if err := os.WriteFile(htmlPath, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("failed to write html fixture: %v", err)
+	}
+
+	outputPath := filepath.Join(tmpDir, "output.md")
+	if err := New().ConvertPage(htmlPath, outputPath, PageMeta{SourceURL: "https://example.com/guide"}); err != nil {
+		t.Fatalf("ConvertPage returned error: %v", err)
+	}
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	if !strings.Contains(string(data), "Compressed page") {
+		t.Errorf("output doesn't contain the page:\n%s", data)
+	}
+}
+
+func TestContentSelector(t *testing.T) {
+	page := `<html><head><title>Guide</title></head><body>
+<nav><a href="/">Home</a> | <a href="/pricing">Pricing</a></nav>
+<div class="layout">
+  <aside class="sidebar">Sidebar links</aside>
+  <div class="markdown-body">
+    <h1>Install</h1><p>Run the installer.</p>
+    <div id="cookie-consent">We use cookies</div>
+    <div class="markdown-body"><p>Nested note.</p></div>
+  </div>
+  <div class="markdown-body"><h2>Next steps</h2><p>Configure it.</p></div>
+</div>
+<footer>Copyright</footer>
+</body></html>`
+
+	tests := []struct {
+		name     string
+		selector string
+		want     []string
+		unwanted []string
+	}{
+		{
+			name:     "all outermost matches in document order",
+			selector: "div.markdown-body",
+			want:     []string{"# Install", "Run the installer.", "Nested note.", "## Next steps"},
+			unwanted: []string{"Pricing", "Sidebar links", "We use cookies", "Copyright"},
+		},
+		{
+			name:     "selector matching nothing falls back",
+			selector: "article.docs",
+			want:     []string{"Run the installer."},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			if err := c.SetContentSelector(tt.selector); err != nil {
+				t.Fatalf("SetContentSelector returned error: %v", err)
+			}
+			got, err := c.convertHTML([]byte(page), "page.html")
+			if err != nil {
+				t.Fatalf("convertHTML returned error: %v", err)
+			}
+			title, markdown := got.Title, got.Markdown
+			if title != "Guide" {
+				t.Errorf("title = %q, want Guide", title)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(markdown, want) {
+					t.Errorf("markdown missing %q:\n%s", want, markdown)
+				}
+			}
+			for _, unwanted := range tt.unwanted {
+				if strings.Contains(markdown, unwanted) {
+					t.Errorf("markdown contains boilerplate %q:\n%s", unwanted, markdown)
+				}
+			}
+			if strings.Count(markdown, "Nested note.") > 1 {
+				t.Errorf("nested match converted twice:\n%s", markdown)
+			}
+		})
+	}
+
+	if err := New().SetContentSelector("main >"); err == nil {
+		t.Error("SetContentSelector accepted an invalid selector")
+	}
+	withSelector := New()
+	withSelector.SetContentSelector("main")
+	if New().Fingerprint() == withSelector.Fingerprint() {
+		t.Error("content selector does not change the cache fingerprint")
+	}
+}
+
+func TestStripSelectors(t *testing.T) {
+	page := `<html><head><title>API</title></head><body><main>
+<nav class="breadcrumbs"><a href="/">Docs</a> / API</nav>
+<h1>API reference</h1>
+<div class="ad-banner">Try our premium plan!</div>
+<p>Authenticate with an API key.</p>
+<div id="feedback-widget">Was this page helpful?</div>
+</main></body></html>`
+
+	c := New()
+	if err := c.SetStripSelectors([]string{".ad-banner", "#feedback-widget, nav.breadcrumbs"}); err != nil {
+		t.Fatalf("SetStripSelectors returned error: %v", err)
+	}
+	got, err := c.convertHTML([]byte(page), "page.html")
+	if err != nil {
+		t.Fatalf("convertHTML returned error: %v", err)
+	}
+	markdown := got.Markdown
+	if !strings.Contains(markdown, "Authenticate with an API key.") {
+		t.Errorf("markdown lost the content:\n%s", markdown)
+	}
+	for _, unwanted := range []string{"premium plan", "helpful", "Docs"} {
+		if strings.Contains(markdown, unwanted) {
+			t.Errorf("markdown contains stripped element %q:\n%s", unwanted, markdown)
+		}
+	}
+
+	if err := New().SetStripSelectors([]string{".ok", "[unclosed"}); err == nil {
+		t.Error("SetStripSelectors accepted an invalid selector")
+	}
+}
+
+// stubExtension extracts the <section> of the pages under its prefix and, if
+// convert is set, converts them to a fixed Markdown.
+type stubExtension struct {
+	prefix  string
+	convert bool
+	fail    bool
+}
+
+func (e stubExtension) Name() string        { return "stub" }
+func (e stubExtension) Fingerprint() string { return "stub 1" }
+func (e stubExtension) Handles(pageURL, _ string) bool {
+	return strings.HasPrefix(pageURL, e.prefix)
+}
+
+func (e stubExtension) Extract(_, html string) (string, string, error) {
+	if e.fail {
+		return "", "", errors.New("plugin stopped")
+	}
+	start, end := strings.Index(html, "<section>"), strings.Index(html, "</section>")
+	if start < 0 || end < start {
+		return "", "", nil
+	}
+	return html[start+len("<section>") : end], "Section title", nil
+}
+
+func (e stubExtension) Convert(pageURL, _ string) (string, error) {
+	if !e.convert {
+		return "", nil
+	}
+	return "Converted by the extension", nil
+}
+
+func TestExtensions(t *testing.T) {
+	page := []byte(`<html><head><title>Page</title></head><body><main><h1>Main</h1><p>Main content of the page.</p>
+<section><p>Section content.</p></section></main></body></html>`)
+	tests := []struct {
+		name       string
+		extensions []Extension
+		pageURL    string
+		want       string
+		extraction string
+	}{
+		{name: "extracted", extensions: []Extension{stubExtension{prefix: "https://docs.example.com/"}}, pageURL: "https://docs.example.com/a", want: "Section content.", extraction: ExtractionExtension},
+		{name: "converted", extensions: []Extension{stubExtension{prefix: "https://docs.example.com/", convert: true}}, pageURL: "https://docs.example.com/a", want: "Converted by the extension", extraction: ExtractionExtension},
+		{name: "not handled", extensions: []Extension{stubExtension{prefix: "https://docs.example.com/"}}, pageURL: "https://blog.example.com/a", want: "Main content of the page.", extraction: ExtractionContentSelector},
+		{name: "failed", extensions: []Extension{stubExtension{prefix: "https://", fail: true}, stubExtension{prefix: "https://", convert: true}}, pageURL: "https://docs.example.com/a", want: "Converted by the extension", extraction: ExtractionExtension},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			c.SetContentSelector("main")
+			for _, ext := range tt.extensions {
+				c.AddExtension(ext)
+			}
+			in, err := c.Inspect(page, PageMeta{SourceURL: tt.pageURL})
+			if err != nil {
+				t.Fatalf("Inspect() error = %v", err)
+			}
+			if !strings.Contains(in.Markdown, tt.want) || in.Extraction != tt.extraction {
+				t.Errorf("extraction %s, Markdown:\n%s\nwant %s and %q", in.Extraction, in.Markdown, tt.extraction, tt.want)
+			}
+		})
+	}
+
+	c := New()
+	fingerprint := c.Fingerprint()
+	c.AddExtension(stubExtension{})
+	if c.Fingerprint() == fingerprint {
+		t.Error("extensions do not change the cache fingerprint")
+	}
+}
+
+func TestConvertPageFrontmatter(t *testing.T) {
+	tmpDir := t.TempDir()
+	htmlPath := filepath.Join(tmpDir, "input.html")
+	html := `<html lang="en-US"><head><title>Install "fast"</title>
+<meta name="description" content="How to install
+  the CLI.">
+<meta property="og:description" content="Ignored">
+<meta name="keywords" content="install, cli, Install">
+<link rel="canonical" href="/docs/install">
+</head><body><main><h1>Install</h1><p>Download the latest release.</p>
+<h2>Requirements</h2><p>Go 1.22 or newer.</p>
+<pre><code># not a heading
+go install example.com/cli@latest</code></pre>
+<h4>Details</h4><p>Nothing else.</p></main></body></html>`
+	if err := os.WriteFile(htmlPath, []byte(html), 0644); err != nil {
+		t.Fatalf("failed to write html fixture: %v", err)
+	}
+
+	outputPath := filepath.Join(tmpDir, "install.md")
+	meta := PageMeta{
+		SourceURL:       "https://example.com/docs/install?ref=nav",
+		Source:          "example.com/docs",
+		FetchedAt:       "2024-01-01T00:00:00Z",
+		ModifiedAt:      "2023-12-24T10:00:00Z",
+		StatusCode:      200,
+		FinalURL:        "https://example.com/docs/install/?ref=nav",
+		ContentLanguage: "en",
+		Version:         "v2",
+		Access:          "internal",
+	}
+	if err := New().ConvertPage(htmlPath, outputPath, meta); err != nil {
+		t.Fatalf("ConvertPage returned error: %v", err)
+	}
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+
+	sum := sha256.Sum256([]byte(html))
+	want := `---
+title: "Install \"fast\""
+description: "How to install the CLI."
+source: "example.com/docs"
+source_url: "https://example.com/docs/install?ref=nav"
+canonical_url: "https://example.com/docs/install"
+fetched_at: "2024-01-01T00:00:00Z"
+modified_at: "2023-12-24T10:00:00Z"
+http_status: 200
+final_url: "https://example.com/docs/install/?ref=nav"
+content_language: "en"
+content_hash: "sha256:` + hex.EncodeToString(sum[:]) + `"
+locale: "en-US"
+version: "v2"
+word_count: 14
+page_type: "docs"
+access: "internal"
+tags:
+  - "install"
+  - "cli"
+outline:
+  - "# Install"
+  - "## Requirements"
+---
+`
+	if !strings.HasPrefix(string(data), want) {
+		t.Errorf("frontmatter mismatch:\ngot:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestWordCount(t *testing.T) {
+	tests := []struct {
+		markdown string
+		want     int
+	}{
+		{"", 0},
+		{"# Install\n\nDon't use the well-known API.", 6},
+		{"Run:\n\n```bash\ngo build ./...\n```\n\nDone.", 2},
+		{"インストール手順", 8},
+	}
+
+	for _, tt := range tests {
+		if got := WordCount(tt.markdown); got != tt.want {
+			t.Errorf("WordCount(%q) = %d, want %d", tt.markdown, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the conversion of definition lists and defining
+// instances of terms, which the glossary of a skill is extracted from.
+package converter
+
+import (
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// convertDefinitionList renders a dl element as a Markdown definition list,
+// as written by PHP Markdown Extra and Pandoc: each term on a line of its own,
+// the terms sharing definitions on consecutive lines, followed by each of
+// their definitions on a line starting with ": ", the lines after the first
+// of a definition indented by four spaces:
+//
+//	Token
+//	: A credential sent with requests.
+//
+// Renderers without definition lists show the terms and definitions as
+// paragraphs, instead of the run-on text of the dt and dd elements. Returns
+// nil, leaving the default conversion, for a list without terms.
+func (c *Converter) convertDefinitionList(_ string, sel *goquery.Selection, _ *md.Options) *string {
+	var items []string
+	// Sites wrap the groups of a term and its definitions in divs (allowed by HTML)
+	sel.Find("dt, dd").Each(func(_ int, item *goquery.Selection) {
+		if item.ParentsFiltered("dl").First().Get(0) != sel.Get(0) {
+			return
+		}
+		if goquery.NodeName(item) == "dt" {
+			if term := strings.Join(strings.Fields(c.mdConverter.Convert(item)), " "); term != "" {
+				// The terms sharing definitions are on consecutive lines
+				if len(items) > 0 && strings.HasPrefix(items[len(items)-1], ": ") {
+					term = "\n" + term
+				}
+				items = append(items, term)
+			}
+			return
+		}
+		definition := strings.TrimSpace(c.mdConverter.Convert(item))
+		if definition == "" || len(items) == 0 {
+			return
+		}
+		lines := strings.Split(blankLines.ReplaceAllString(definition, "\n\n"), "\n")
+		for i := 1; i < len(lines); i++ {
+			if lines[i] != "" {
+				lines[i] = "    " + lines[i]
+			}
+		}
+		items = append(items, ": "+strings.Join(lines, "\n"))
+	})
+	if len(items) == 0 {
+		return nil
+	}
+	out := "\n\n" + strings.Join(items, "\n") + "\n\n"
+	return &out
+}
+
+// convertDefiningTerm renders a dfn element, the defining instance of a term,
+// in bold: "A <dfn>widget</dfn> is ..." becomes "A **widget** is ...", which
+// the glossary of the skill recognizes as a definition.
+func convertDefiningTerm(content string, _ *goquery.Selection, _ *md.Options) *string {
+	term := strings.TrimSpace(content)
+	if term == "" || strings.Contains(term, "**") {
+		return &content
+	}
+	out := "**" + term + "**"
+	return &out
+}
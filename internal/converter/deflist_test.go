@@ -0,0 +1,45 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestConvertDefinitions(t *testing.T) {
+	para := "<p>" + strings.Repeat("The terms below are used throughout the reference, so read them before the guides. ", 8) + "</p>"
+	tests := []struct {
+		name string
+		html string
+		want []string
+	}{
+		{
+			"definition list",
+			`<dl><dt>Token</dt><dd>A credential sent with <code>Authorization</code> headers.</dd><dt>Scope</dt><dd>What a token may access.</dd></dl>`,
+			[]string{"Token\n: A credential sent with `Authorization` headers.\n\nScope\n: What a token may access."},
+		},
+		{
+			"grouped terms and several definitions",
+			`<dl><div><dt>Region</dt><dt>Zone</dt><dd><p>Where resources run.</p><p>See <a href="/regions">regions</a>.</p></dd></div></dl>`,
+			[]string{"Region\nZone\n: Where resources run.\n\n    See [regions](/regions)."},
+		},
+		{
+			"defining term",
+			`<p>A <dfn>widget</dfn> is a small reusable component.</p>`,
+			[]string{"A **widget** is a small reusable component."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := `<html><head><title>Glossary</title></head><body><article><h1>Glossary</h1>` + para + tt.html + para + `</article></body></html>`
+			got, err := New().convertHTML([]byte(page), "page.html")
+			if err != nil {
+				t.Fatalf("convertHTML returned error: %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got.Markdown, want) {
+					t.Errorf("markdown missing %q:\n%s", want, got.Markdown)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,559 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the output formats of converted documents: Markdown,
+// MDX for React-based documentation sites, and AsciiDoc.
+package converter
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/mdprose"
+	"gopkg.in/yaml.v3"
+)
+
+// Output formats of NewOutputFormat.
+const (
+	// FormatMarkdown writes Markdown with a YAML frontmatter (.md), the default.
+	FormatMarkdown = "markdown"
+	// FormatMDX writes MDX (.mdx): Markdown whose braces, stray angle brackets,
+	// and raw HTML are made valid for the MDX compiler.
+	FormatMDX = "mdx"
+	// FormatAsciiDoc writes AsciiDoc (.adoc), with the frontmatter fields as
+	// document attributes.
+	FormatAsciiDoc = "asciidoc"
+)
+
+// OutputFormats lists the names of the output formats.
+var OutputFormats = []string{FormatMarkdown, FormatMDX, FormatAsciiDoc}
+
+// OutputFormat renders converted documents in a document format. Pages are
+// always converted to Markdown with a YAML frontmatter first, which the other
+// formats are rendered from, so that every format gets the same content
+// extraction, tables, code languages, and admonitions.
+type OutputFormat interface {
+	// Name returns the name of the format, one of OutputFormats.
+	Name() string
+	// Extension returns the file extension of the documents, with its dot.
+	Extension() string
+	// Render returns doc, a Markdown document with an optional YAML
+	// frontmatter, in the format. The built-in formats never fail; formats
+	// of plugins may.
+	Render(doc string) (string, error)
+}
+
+// NewOutputFormat returns the output format named name, one of OutputFormats.
+//
+// Returns an error if the format is unknown.
+func NewOutputFormat(name string) (OutputFormat, error) {
+	switch name {
+	case FormatMarkdown:
+		return markdownFormat{}, nil
+	case FormatMDX:
+		return mdxFormat{}, nil
+	case FormatAsciiDoc:
+		return asciiDocFormat{}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q (expected %s)", name, strings.Join(OutputFormats, ", "))
+	}
+}
+
+// ParseExport parses an export written "FORMAT=DIR": the documents of a skill
+// rendered in the output format FORMAT into the directory DIR. FORMAT is one
+// of OutputFormats or the name of one of extra, the formats of plugins.
+//
+// Returns an error if the export is malformed or the format unknown.
+func ParseExport(s string, extra ...OutputFormat) (OutputFormat, string, error) {
+	name, dir, ok := strings.Cut(s, "=")
+	name, dir = strings.TrimSpace(name), strings.TrimSpace(dir)
+	if !ok || name == "" || dir == "" {
+		return nil, "", fmt.Errorf("invalid export %q: want FORMAT=DIR", s)
+	}
+	for _, f := range extra {
+		if f.Name() == name {
+			return f, dir, nil
+		}
+	}
+	f, err := NewOutputFormat(name)
+	if err != nil {
+		if len(extra) > 0 {
+			names := slices.Clone(OutputFormats)
+			for _, f := range extra {
+				names = append(names, f.Name())
+			}
+			err = fmt.Errorf("unknown output format %q (expected %s)", name, strings.Join(names, ", "))
+		}
+		return nil, "", fmt.Errorf("invalid export %q: %w", s, err)
+	}
+	return f, dir, nil
+}
+
+var (
+	// fencePattern matches the opening line of a fenced code block
+	fencePattern = regexp.MustCompile("^\\s*(`{3,}|~{3,})\\s*([^`\\s]*)")
+)
+
+// splitDocument returns the frontmatter of doc, without its delimiters, and
+// its body.
+func splitDocument(doc string) (string, string) {
+	frontmatter, body, _ := mdprose.SplitFrontmatter(doc)
+	return frontmatter, body
+}
+
+// mapBody calls text for every line of body outside fenced code blocks, and
+// code for every fenced block with its lines, fences included, concatenating
+// what they return.
+func mapBody(body string, text func(line string) string, code func(lines []string) string) string {
+	lines := strings.Split(body, "\n")
+	var b strings.Builder
+	for i := 0; i < len(lines); i++ {
+		m := fencePattern.FindStringSubmatch(lines[i])
+		if m == nil {
+			b.WriteString(text(lines[i]))
+			if i < len(lines)-1 {
+				b.WriteByte('\n')
+			}
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && !isClosingFence(lines[end], m[1]) {
+			end++
+		}
+		if end == len(lines) {
+			end--
+		}
+		b.WriteString(code(lines[i : end+1]))
+		if end < len(lines)-1 {
+			b.WriteByte('\n')
+		}
+		i = end
+	}
+	return b.String()
+}
+
+// isClosingFence reports whether line closes a code block opened by fence.
+func isClosingFence(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == ""
+}
+
+// span is a piece of a line: inline code or text.
+type span struct {
+	text string
+	code bool
+}
+
+// splitCodeSpans splits line into its inline code spans, backticks included,
+// and the text between them.
+func splitCodeSpans(line string) []span {
+	var spans []span
+	for {
+		start := strings.IndexByte(line, '`')
+		if start < 0 {
+			break
+		}
+		n := start
+		for n < len(line) && line[n] == '`' {
+			n++
+		}
+		ticks := line[start:n]
+		end := -1
+		for i := n; i < len(line); {
+			j := strings.Index(line[i:], ticks)
+			if j < 0 {
+				break
+			}
+			j += i
+			k := j + len(ticks)
+			if (k == len(line) || line[k] != '`') && (j == 0 || line[j-1] != '`') {
+				end = k
+				break
+			}
+			for k < len(line) && line[k] == '`' {
+				k++
+			}
+			i = k
+		}
+		if end < 0 {
+			break
+		}
+		if start > 0 {
+			spans = append(spans, span{text: line[:start]})
+		}
+		spans = append(spans, span{text: line[start:end], code: true})
+		line = line[end:]
+	}
+	if line != "" {
+		spans = append(spans, span{text: line})
+	}
+	return spans
+}
+
+// mapText calls fn for the text of line outside inline code spans.
+func mapText(line string, fn func(string) string) string {
+	var b strings.Builder
+	for _, s := range splitCodeSpans(line) {
+		if s.code {
+			b.WriteString(s.text)
+		} else {
+			b.WriteString(fn(s.text))
+		}
+	}
+	return b.String()
+}
+
+// markdownFormat implements FormatMarkdown.
+type markdownFormat struct{}
+
+func (markdownFormat) Name() string                      { return FormatMarkdown }
+func (markdownFormat) Extension() string                 { return ".md" }
+func (markdownFormat) Render(doc string) (string, error) { return doc, nil }
+
+// mdxFormat implements FormatMDX.
+type mdxFormat struct{}
+
+var (
+	// mdxTokenPattern matches what MDX parses as JSX or expressions: comments,
+	// autolinks, tags, braces, and angle brackets
+	mdxTokenPattern = regexp.MustCompile(`<!--.*?-->|<https?://[^>\s]+>|</?([A-Za-z][A-Za-z0-9-]*)(?:\s[^<>]*)?/?>|[{}<]`)
+	// jsxAttrPattern matches the attributes of an HTML tag renamed or dropped in JSX
+	jsxAttrPattern = regexp.MustCompile(`\s(class|for|style)(\s*=\s*("[^"]*"|'[^']*'|[^\s>]+))?`)
+	// htmlElements are the elements kept as JSX; other tag-like text is escaped
+	htmlElements = map[string]bool{
+		"a": true, "abbr": true, "b": true, "blockquote": true, "br": true, "caption": true,
+		"code": true, "col": true, "colgroup": true, "dd": true, "del": true, "details": true,
+		"div": true, "dl": true, "dt": true, "em": true, "figcaption": true, "figure": true,
+		"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "hr": true,
+		"i": true, "img": true, "input": true, "ins": true, "kbd": true, "label": true, "li": true,
+		"mark": true, "ol": true, "q": true, "samp": true, "section": true, "time": true, "var": true,
+		"p": true, "picture": true, "pre": true, "s": true, "small": true, "source": true,
+		"span": true, "strong": true, "sub": true, "summary": true, "sup": true, "table": true,
+		"tbody": true, "td": true, "tfoot": true, "th": true, "thead": true, "tr": true,
+		"u": true, "ul": true, "video": true, "audio": true, "wbr": true,
+	}
+	// voidElements are the elements without content, self-closed in JSX
+	voidElements = map[string]bool{"br": true, "col": true, "hr": true, "img": true, "input": true, "source": true, "wbr": true}
+)
+
+func (mdxFormat) Name() string      { return FormatMDX }
+func (mdxFormat) Extension() string { return ".mdx" }
+
+// Render keeps the frontmatter, which MDX tooling reads as YAML, and code, and
+// escapes the text for MDX: braces and stray "<" become character references,
+// HTML comments become JSX comments, autolinks become links, and raw HTML
+// elements become JSX (className, htmlFor, no style strings, self-closed void
+// elements).
+func (mdxFormat) Render(doc string) (string, error) {
+	frontmatter, body := splitDocument(doc)
+	out := mapBody(body, func(line string) string {
+		return mapText(line, mdxText)
+	}, func(lines []string) string {
+		return strings.Join(lines, "\n")
+	})
+	if frontmatter == "" {
+		return out, nil
+	}
+	return "---\n" + frontmatter + "\n---\n" + out, nil
+}
+
+// mdxText escapes text outside code for MDX.
+func mdxText(text string) string {
+	return mdxTokenPattern.ReplaceAllStringFunc(text, func(tok string) string {
+		switch {
+		case tok == "{":
+			return "&#123;"
+		case tok == "}":
+			return "&#125;"
+		case tok == "<":
+			return "&lt;"
+		case strings.HasPrefix(tok, "<!--"):
+			comment := strings.ReplaceAll(tok[4:len(tok)-3], "*/", "* /")
+			return "{/*" + comment + "*/}"
+		case strings.HasPrefix(tok, "<http"):
+			u := tok[1 : len(tok)-1]
+			return "[" + u + "](" + u + ")"
+		}
+		name := strings.ToLower(mdxTokenPattern.FindStringSubmatch(tok)[1])
+		if !htmlElements[name] {
+			return "&lt;" + tok[1:]
+		}
+		return jsxTag(tok, name)
+	})
+}
+
+// jsxTag returns the HTML tag tok of the element name as JSX.
+func jsxTag(tok, name string) string {
+	tok = jsxAttrPattern.ReplaceAllStringFunc(tok, func(attr string) string {
+		m := jsxAttrPattern.FindStringSubmatch(attr)
+		switch m[1] {
+		case "class":
+			return " className" + m[2]
+		case "for":
+			return " htmlFor" + m[2]
+		default:
+			return ""
+		}
+	})
+	if voidElements[name] && !strings.HasSuffix(tok, "/>") {
+		tok = strings.TrimSpace(strings.TrimSuffix(tok, ">")) + " />"
+	}
+	return tok
+}
+
+// asciiDocFormat implements FormatAsciiDoc.
+type asciiDocFormat struct{}
+
+var (
+	// adocImagePattern matches a Markdown image
+	adocImagePattern = regexp.MustCompile(`!\[([^\]]*)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	// adocLinkPattern matches a Markdown link, whose text may hold an image
+	adocLinkPattern = regexp.MustCompile(`\[((?:[^\[\]]|\[[^\]]*\])*)\]\(\s*<?([^)\s>]+)>?(?:\s+"[^"]*")?\s*\)`)
+	// adocBoldPattern matches strong emphasis
+	adocBoldPattern = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*`)
+	// adocStrikePattern matches strikethrough
+	adocStrikePattern = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	// adocListPattern matches a list item
+	adocListPattern = regexp.MustCompile(`^(\s*)([-*+]|\d+[.)])\s+(.*)$`)
+	// adocRulePattern matches a thematic break
+	adocRulePattern = regexp.MustCompile(`^\s{0,3}(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	// adocTableSeparatorPattern matches the delimiter row of a GFM table
+	adocTableSeparatorPattern = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(\|\s*:?-+:?\s*)*\|?\s*$`)
+	// adocAlertPattern matches the first line of a GitHub alert
+	adocAlertPattern = regexp.MustCompile(`^\[!(NOTE|TIP|IMPORTANT|WARNING|CAUTION)\]\s*$`)
+)
+
+func (asciiDocFormat) Name() string      { return FormatAsciiDoc }
+func (asciiDocFormat) Extension() string { return ".adoc" }
+
+// Render writes the document title and the other frontmatter fields as the
+// document header ("= Title", ":source_url: ..."), lists of values joined with
+// commas and maps left out, followed by the body: headings one level down
+// from the title, source blocks, tables, admonitions from GitHub alerts,
+// block quotes, lists, block math as stem blocks, and raw HTML as passthrough
+// blocks, with links, images, and emphasis in AsciiDoc syntax.
+func (asciiDocFormat) Render(doc string) (string, error) {
+	frontmatter, body := splitDocument(doc)
+	header := asciiDocHeader(frontmatter)
+	out := strings.Join(asciiDocBlocks(strings.Split(strings.TrimLeft(body, "\n"), "\n")), "\n")
+	if header == "" {
+		return out, nil
+	}
+	return header + "\n" + out, nil
+}
+
+// asciiDocHeader returns the document header of the YAML frontmatter, or ""
+// if it has none.
+func asciiDocHeader(frontmatter string) string {
+	var node yaml.Node
+	if frontmatter == "" || yaml.Unmarshal([]byte(frontmatter), &node) != nil ||
+		len(node.Content) == 0 || node.Content[0].Kind != yaml.MappingNode {
+		return ""
+	}
+	fields := node.Content[0].Content
+	var b strings.Builder
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i].Value == "title" {
+			b.WriteString("= " + oneLine(fields[i+1].Value) + "\n")
+		}
+	}
+	for i := 0; i+1 < len(fields); i += 2 {
+		key, value := fields[i].Value, fields[i+1]
+		if key == "title" {
+			continue
+		}
+		switch value.Kind {
+		case yaml.ScalarNode:
+			fmt.Fprintf(&b, ":%s: %s\n", key, oneLine(value.Value))
+		case yaml.SequenceNode:
+			var items []string
+			for _, item := range value.Content {
+				if item.Kind == yaml.ScalarNode {
+					items = append(items, oneLine(item.Value))
+				}
+			}
+			if len(items) > 0 {
+				fmt.Fprintf(&b, ":%s: %s\n", key, strings.Join(items, ", "))
+			}
+		}
+	}
+	return b.String()
+}
+
+// oneLine joins the lines of s with spaces.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// asciiDocBlocks converts the lines of a Markdown body to AsciiDoc.
+func asciiDocBlocks(lines []string) []string {
+	var out []string
+	var indents []int // indentation of the open list levels
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			indents = nil
+			out = append(out, "")
+			continue
+		}
+
+		if m := fencePattern.FindStringSubmatch(line); m != nil {
+			if m[2] != "" {
+				out = append(out, "[source,"+m[2]+"]")
+			}
+			out = append(out, "----")
+			for i++; i < len(lines) && !isClosingFence(lines[i], m[1]); i++ {
+				out = append(out, lines[i])
+			}
+			out = append(out, "----")
+			continue
+		}
+
+		if trimmed == "$$" {
+			out = append(out, "[stem]", "++++")
+			for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "$$"; i++ {
+				out = append(out, lines[i])
+			}
+			out = append(out, "++++")
+			continue
+		}
+
+		if m := headingPattern.FindStringSubmatch(trimmed); m != nil {
+			out = append(out, strings.Repeat("=", len(m[1])+1)+" "+asciiDocInline(m[2]))
+			continue
+		}
+
+		if adocRulePattern.MatchString(line) {
+			out = append(out, "'''")
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, ">") {
+			var quoted []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), ">"); i++ {
+				q := strings.TrimPrefix(strings.TrimSpace(lines[i]), ">")
+				quoted = append(quoted, strings.TrimPrefix(q, " "))
+			}
+			i--
+			delimiter := "____"
+			if m := adocAlertPattern.FindStringSubmatch(quoted[0]); m != nil {
+				out = append(out, "["+m[1]+"]")
+				delimiter = "===="
+				quoted = quoted[1:]
+			}
+			out = append(out, delimiter)
+			out = append(out, asciiDocBlocks(quoted)...)
+			out = append(out, delimiter)
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "|") && i+1 < len(lines) && adocTableSeparatorPattern.MatchString(lines[i+1]) {
+			var rows [][]string
+			rows = append(rows, tableCells(trimmed))
+			for i += 2; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "|"); i++ {
+				rows = append(rows, tableCells(strings.TrimSpace(lines[i])))
+			}
+			i--
+			out = append(out, fmt.Sprintf(`[cols="%d*",options="header"]`, len(rows[0])), "|===")
+			for _, row := range rows {
+				cells := make([]string, len(row))
+				for j, cell := range row {
+					cells[j] = "|" + asciiDocInline(cell)
+				}
+				out = append(out, strings.Join(cells, " "))
+			}
+			out = append(out, "|===")
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, "<") && !strings.HasPrefix(trimmed, "<http") {
+			out = append(out, "++++")
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != ""; i++ {
+				out = append(out, lines[i])
+			}
+			i--
+			out = append(out, "++++")
+			continue
+		}
+
+		if m := adocListPattern.FindStringSubmatch(line); m != nil {
+			indent := len(m[1])
+			for len(indents) > 0 && indent < indents[len(indents)-1] {
+				indents = indents[:len(indents)-1]
+			}
+			if len(indents) == 0 || indent > indents[len(indents)-1] {
+				indents = append(indents, indent)
+			}
+			marker := "*"
+			if m[2][0] >= '0' && m[2][0] <= '9' {
+				marker = "."
+			}
+			out = append(out, strings.Repeat(marker, len(indents))+" "+asciiDocInline(m[3]))
+			continue
+		}
+
+		text := asciiDocInline(trimmed)
+		if strings.HasSuffix(line, "  ") {
+			text += " +"
+		}
+		if len(indents) > 0 {
+			// A paragraph continuing a list item
+			out = append(out, "+")
+		}
+		out = append(out, text)
+	}
+	return out
+}
+
+// tableCells returns the cells of a GFM table row. Escaped pipes stay
+// escaped, as AsciiDoc escapes them the same way.
+func tableCells(row string) []string {
+	row = strings.TrimSuffix(strings.TrimPrefix(row, "|"), "|")
+	var cells []string
+	start := 0
+	for i := 0; i < len(row); i++ {
+		if row[i] == '|' && (i == 0 || row[i-1] != '\\') {
+			cells = append(cells, strings.TrimSpace(row[start:i]))
+			start = i + 1
+		}
+	}
+	return append(cells, strings.TrimSpace(row[start:]))
+}
+
+// asciiDocInline converts the inline Markdown of text to AsciiDoc: code spans
+// become literal passthroughs, and images, links, strong emphasis, and
+// strikethrough their AsciiDoc forms. Emphasis with underscores is the same in
+// both.
+func asciiDocInline(text string) string {
+	var b strings.Builder
+	for _, s := range splitCodeSpans(text) {
+		if s.code {
+			code := strings.Trim(s.text, "`")
+			if strings.HasPrefix(code, " ") && strings.HasSuffix(code, " ") && strings.TrimSpace(code) != "" {
+				code = code[1 : len(code)-1]
+			}
+			b.WriteString("`+" + code + "+`")
+			continue
+		}
+		t := s.text
+		if m := adocImagePattern.FindStringSubmatchIndex(strings.TrimSpace(t)); m != nil && m[0] == 0 && m[1] == len(strings.TrimSpace(t)) && len(splitCodeSpans(text)) == 1 {
+			// An image alone on its line is a block image
+			m := adocImagePattern.FindStringSubmatch(t)
+			return "image::" + m[2] + "[" + m[1] + "]"
+		}
+		t = adocImagePattern.ReplaceAllString(t, "image:$2[$1]")
+		t = adocLinkPattern.ReplaceAllStringFunc(t, func(link string) string {
+			m := adocLinkPattern.FindStringSubmatch(link)
+			label, target := m[1], m[2]
+			if strings.HasPrefix(target, "#") {
+				return "<<" + target[1:] + "," + label + ">>"
+			}
+			return "link:" + target + "[" + label + "]"
+		})
+		t = adocBoldPattern.ReplaceAllString(t, "*$1*")
+		t = adocStrikePattern.ReplaceAllString(t, "[.line-through]#$1#")
+		b.WriteString(t)
+	}
+	return b.String()
+}
@@ -0,0 +1,186 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseExport(t *testing.T) {
+	tests := []struct {
+		spec    string
+		format  string
+		dir     string
+		wantErr bool
+	}{
+		{spec: "mdx=site/content", format: FormatMDX, dir: "site/content"},
+		{spec: " asciidoc = docs/modules ", format: FormatAsciiDoc, dir: "docs/modules"},
+		{spec: "markdown=out", format: FormatMarkdown, dir: "out"},
+		{spec: "rst=out", wantErr: true},
+		{spec: "mdx", wantErr: true},
+		{spec: "mdx=", wantErr: true},
+	}
+	for _, tt := range tests {
+		f, dir, err := ParseExport(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseExport(%q) succeeded, want an error", tt.spec)
+			}
+			continue
+		}
+		if err != nil || f.Name() != tt.format || dir != tt.dir {
+			t.Errorf("ParseExport(%q) = %v, %q, %v; want %s, %q", tt.spec, f, dir, err, tt.format, tt.dir)
+		}
+	}
+}
+
+func TestOutputFormatExtension(t *testing.T) {
+	want := map[string]string{FormatMarkdown: ".md", FormatMDX: ".mdx", FormatAsciiDoc: ".adoc"}
+	for _, name := range OutputFormats {
+		f, err := NewOutputFormat(name)
+		if err != nil {
+			t.Fatalf("NewOutputFormat(%q) error = %v", name, err)
+		}
+		if f.Extension() != want[name] {
+			t.Errorf("%s extension = %q, want %q", name, f.Extension(), want[name])
+		}
+	}
+}
+
+func TestMDXRender(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "frontmatter and code kept",
+			in:   "---\ntitle: Config {v2}\n---\n# Options\n\n```json\n{\"a\": 1}\n```\n",
+			want: "---\ntitle: Config {v2}\n---\n# Options\n\n```json\n{\"a\": 1}\n```\n",
+		},
+		{
+			name: "braces and stray angle brackets escaped",
+			in:   "Use {name} when a < b, not `{name}`.",
+			want: "Use &#123;name&#125; when a &lt; b, not `{name}`.",
+		},
+		{
+			name: "unknown tags escaped",
+			in:   "Returns Vec<T> or Option<String>.",
+			want: "Returns Vec&lt;T> or Option&lt;String>.",
+		},
+		{
+			name: "comments and autolinks",
+			in:   "<!-- hidden --> See <https://example.com/a>.",
+			want: "{/* hidden */} See [https://example.com/a](https://example.com/a).",
+		},
+		{
+			name: "HTML becomes JSX",
+			in:   `<div class="note" style="color: red"><label for="x">X</label><br><img src="a.png" alt="A"></div>`,
+			want: `<div className="note"><label htmlFor="x">X</label><br /><img src="a.png" alt="A" /></div>`,
+		},
+	}
+	f, _ := NewOutputFormat(FormatMDX)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := f.Render(tt.in); got != tt.want {
+				t.Errorf("Render() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAsciiDocRender(t *testing.T) {
+	in := strings.Join([]string{
+		"---",
+		"title: Getting Started",
+		"source_url: https://docs.example.com/start",
+		"tags:",
+		"  - setup",
+		"  - install",
+		"outline:",
+		"  - level: 2",
+		"    text: Install",
+		"---",
+		"# Getting Started",
+		"",
+		"Install **now** with `npm i` or see [the API](api.md#auth) and [below](#usage).  ",
+		"Line two with ~~old~~ text.",
+		"",
+		"![Diagram](../assets/d.png)",
+		"",
+		"## Usage",
+		"",
+		"- one",
+		"  - nested",
+		"    1. ordered",
+		"- two",
+		"",
+		"```go",
+		"fmt.Println(\"**x**\")",
+		"```",
+		"",
+		"> [!WARNING]",
+		"> Back up first.",
+		"",
+		"> Quoted",
+		"",
+		"| Name | Type |",
+		"| --- | --- |",
+		"| `id` | int |",
+		"",
+		"$$",
+		"E = mc^2",
+		"$$",
+		"",
+		"* * *",
+	}, "\n")
+	want := strings.Join([]string{
+		"= Getting Started",
+		":source_url: https://docs.example.com/start",
+		":tags: setup, install",
+		"",
+		"== Getting Started",
+		"",
+		"Install *now* with `+npm i+` or see link:api.md#auth[the API] and <<usage,below>>. +",
+		"Line two with [.line-through]#old# text.",
+		"",
+		"image::../assets/d.png[Diagram]",
+		"",
+		"=== Usage",
+		"",
+		"* one",
+		"** nested",
+		"... ordered",
+		"* two",
+		"",
+		"[source,go]",
+		"----",
+		"fmt.Println(\"**x**\")",
+		"----",
+		"",
+		"[WARNING]",
+		"====",
+		"Back up first.",
+		"====",
+		"",
+		"____",
+		"Quoted",
+		"____",
+		"",
+		`[cols="2*",options="header"]`,
+		"|===",
+		"|Name |Type",
+		"|`+id+` |int",
+		"|===",
+		"",
+		"[stem]",
+		"++++",
+		"E = mc^2",
+		"++++",
+		"",
+		"'''",
+	}, "\n")
+	f, _ := NewOutputFormat(FormatAsciiDoc)
+	if got, _ := f.Render(in); got != want {
+		t.Errorf("Render() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,270 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the page metadata written into the Markdown frontmatter.
+package converter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// outlineDepth is the deepest heading level listed in the frontmatter outline.
+const outlineDepth = 3
+
+// headingPattern matches an ATX heading line of the converted Markdown.
+var headingPattern = regexp.MustCompile(`^(#{1,6})\s+(.+?)(?:\s+#+)?\s*$`)
+
+// page is the result of converting one HTML document, and the cached form of it.
+type page struct {
+	// Title is the document title; empty when no main content was found.
+	Title string `json:"title"`
+	// Markdown is the converted main content.
+	Markdown string `json:"markdown"`
+	// Description is the meta description (or og:description) of the document.
+	Description string `json:"description,omitempty"`
+	// Canonical is the href of the canonical link, as written in the document.
+	Canonical string `json:"canonical,omitempty"`
+	// Lang is the language declared by the html element's lang attribute.
+	Lang string `json:"lang,omitempty"`
+	// DetectedLanguage is the language detected from the converted text when
+	// the language filter is on (see Converter.SetLanguageFilter); set after
+	// conversion, it isn't cached.
+	DetectedLanguage string `json:"-"`
+	// Tags are the meta keywords and article:tag values of the document.
+	Tags []string `json:"tags,omitempty"`
+	// Breadcrumbs is the breadcrumb trail of the document, from the top of
+	// the site to the document (see readBreadcrumbs).
+	Breadcrumbs []string `json:"breadcrumbs,omitempty"`
+	// Nav is the trail of the entries of the sidebar navigation leading to
+	// the document, from the top of the sidebar to the document, and
+	// NavPosition the index of each of the elements leading to its entry
+	// among their siblings, which orders the documents as the sidebar does
+	// (see readNavigation).
+	Nav         []string `json:"nav,omitempty"`
+	NavPosition []int    `json:"nav_position,omitempty"`
+	// Kind is the kind of the document, KindFAQ or KindChangelog, or empty
+	// for other documents (see readKind).
+	Kind string `json:"kind,omitempty"`
+	// Structured is the schema.org JSON-LD and OpenGraph data of the
+	// document (see readStructuredData).
+	Structured structuredData `json:"structured"`
+	// Tables holds the CSV of each table saved as a file, linked from Markdown
+	// as tableLinkPrefix followed by the table's number, from 1.
+	Tables []string `json:"tables,omitempty"`
+	// Anchors maps the fragments of the original page's headings to the IDs of
+	// the converted headings (see Headings).
+	Anchors map[string]string `json:"anchors,omitempty"`
+	// Signals score the document as each page type; the URL is scored by pageType.
+	Signals pageSignals `json:"signals"`
+}
+
+// readHead fills in the metadata of p declared in the head of doc.
+func (p *page) readHead(doc *goquery.Document) {
+	p.Description = metaContent(doc, `meta[name="description"]`)
+	if p.Description == "" {
+		p.Description = metaContent(doc, `meta[property="og:description"]`)
+	}
+	p.Canonical = strings.TrimSpace(doc.Find(`link[rel="canonical"]`).First().AttrOr("href", ""))
+	p.Lang = strings.TrimSpace(doc.Find("html").First().AttrOr("lang", ""))
+
+	seen := make(map[string]bool)
+	addTag := func(tag string) {
+		tag = strings.Join(strings.Fields(tag), " ")
+		if tag != "" && !seen[strings.ToLower(tag)] {
+			seen[strings.ToLower(tag)] = true
+			p.Tags = append(p.Tags, tag)
+		}
+	}
+	for _, keyword := range strings.Split(metaContent(doc, `meta[name="keywords"]`), ",") {
+		addTag(keyword)
+	}
+	doc.Find(`meta[property="article:tag"]`).Each(func(_ int, sel *goquery.Selection) {
+		addTag(sel.AttrOr("content", ""))
+	})
+}
+
+// metaContent returns the whitespace-normalized content of the first element
+// matching selector, or "".
+func metaContent(doc *goquery.Document, selector string) string {
+	return strings.Join(strings.Fields(doc.Find(selector).First().AttrOr("content", "")), " ")
+}
+
+// frontmatter renders the YAML frontmatter of a converted page. The locale is
+// meta.Locale when the fetcher chose a locale variant, otherwise the language
+// the page declares; the canonical URL is resolved against meta.SourceURL.
+// hash is the content hash of the HTML the page was converted from.
+func (p page) frontmatter(meta PageMeta, hash string) string {
+	var b strings.Builder
+	field := func(key, value string) {
+		if value != "" {
+			b.WriteString(key + ": " + strconv.Quote(value) + "\n")
+		}
+	}
+	list := func(key string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		b.WriteString(key + ":\n")
+		for _, value := range values {
+			b.WriteString("  - " + strconv.Quote(value) + "\n")
+		}
+	}
+
+	locale := meta.Locale
+	if locale == "" {
+		locale = p.Lang
+	}
+
+	b.WriteString("---\n")
+	b.WriteString("title: " + strconv.Quote(p.Title) + "\n")
+	field("description", p.Description)
+	field("source", meta.Source)
+	b.WriteString("source_url: " + strconv.Quote(meta.SourceURL) + "\n")
+	field("canonical_url", resolveCanonical(meta.SourceURL, p.Canonical))
+	b.WriteString("fetched_at: " + strconv.Quote(meta.FetchedAt) + "\n")
+	// The feed and the Last-Modified header take precedence over the dates
+	// the page declares in its JSON-LD
+	field("modified_at", orString(meta.ModifiedAt, p.Structured.Modified))
+	field("author", orString(meta.Author, p.Structured.Author))
+	field("published", orString(meta.Published, p.Structured.Published))
+	if meta.StatusCode != 0 {
+		b.WriteString("http_status: " + strconv.Itoa(meta.StatusCode) + "\n")
+	}
+	field("final_url", meta.FinalURL)
+	field("content_language", meta.ContentLanguage)
+	field("content_hash", hash)
+	field("locale", locale)
+	field("detected_language", p.DetectedLanguage)
+	field("version", meta.Version)
+	b.WriteString("word_count: " + strconv.Itoa(WordCount(p.Markdown)) + "\n")
+	b.WriteString("page_type: " + strconv.Quote(p.pageType(meta.SourceURL)) + "\n")
+	field("kind", p.Kind)
+	field("access", meta.Access)
+	list("schema_types", p.Structured.Types)
+	field("og_type", p.Structured.OGType)
+	field("image", resolveCanonical(meta.SourceURL, p.Structured.Image))
+	field("site_name", p.Structured.SiteName)
+	if sw := p.Structured.Software; sw != nil {
+		b.WriteString("software:\n")
+		for _, f := range [][2]string{{"name", sw.Name}, {"version", sw.Version}, {"operating_system", sw.OperatingSystem}, {"category", sw.Category}} {
+			if f[1] != "" {
+				b.WriteString("  " + f[0] + ": " + strconv.Quote(f[1]) + "\n")
+			}
+		}
+	}
+	list("tags", p.Tags)
+	list("breadcrumbs", p.Breadcrumbs)
+	list("nav", p.Nav)
+	if len(p.NavPosition) > 0 {
+		position := make([]string, len(p.NavPosition))
+		for i, index := range p.NavPosition {
+			position[i] = strconv.Itoa(index)
+		}
+		b.WriteString("nav_position: [" + strings.Join(position, ", ") + "]\n")
+	}
+	list("outline", Outline(p.Markdown))
+	if len(p.Anchors) > 0 {
+		b.WriteString("anchors:\n")
+		ids := make([]string, 0, len(p.Anchors))
+		for id := range p.Anchors {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		for _, id := range ids {
+			b.WriteString("  " + strconv.Quote(id) + ": " + strconv.Quote(p.Anchors[id]) + "\n")
+		}
+	}
+	b.WriteString("---\n\n")
+	return b.String()
+}
+
+// contentHash returns the SHA-256 of the fetched HTML as "sha256:<hex>", for
+// consumers to tell whether a page changed since it was converted.
+func contentHash(html []byte) string {
+	sum := sha256.Sum256(html)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// resolveCanonical resolves the canonical href of a page against its source URL.
+func resolveCanonical(sourceURL, href string) string {
+	if href == "" {
+		return ""
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	base, err := url.Parse(sourceURL)
+	if err != nil || !base.IsAbs() {
+		return ref.String()
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// Outline lists the headings of a Markdown document down to H3, each with its
+// "#" markers (e.g., "## Install"), as written to the outline frontmatter field.
+// Headings inside code blocks are ignored.
+func Outline(markdown string) []string {
+	var headings []string
+	eachProseLine(markdown, func(line string) {
+		if m := headingPattern.FindStringSubmatch(line); m != nil && len(m[1]) <= outlineDepth {
+			headings = append(headings, m[1]+" "+m[2])
+		}
+	})
+	return headings
+}
+
+// WordCount counts the words of a Markdown document outside code blocks, as
+// written to the word_count frontmatter field. Words are runs of letters or
+// digits; in scripts written without spaces (Chinese, Japanese), every
+// character counts as a word.
+func WordCount(markdown string) int {
+	count := 0
+	eachProseLine(markdown, func(line string) {
+		inWord := false
+		for _, r := range line {
+			switch {
+			case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana):
+				count++
+				inWord = false
+			case unicode.IsLetter(r) || unicode.IsDigit(r):
+				if !inWord {
+					count++
+				}
+				inWord = true
+			case r == '\'' || r == '’' || r == '-':
+				// Part of the word: don't, well-known
+			default:
+				inWord = false
+			}
+		}
+	})
+	return count
+}
+
+// eachProseLine calls fn for every line of a Markdown document outside fenced code blocks.
+func eachProseLine(markdown string, fn func(string)) {
+	fence := ""
+	for _, line := range strings.Split(markdown, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			marker := trimmed[:1]
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, marker))]
+			continue
+		}
+		fn(line)
+	}
+}
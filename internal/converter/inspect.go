@@ -0,0 +1,206 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the inspection of the conversion of one page, which
+// shows how its content was extracted to debug bad conversions.
+package converter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/f4ah6o/site2skill-go/internal/lang"
+)
+
+// How the main content of a page was found (Inspection.Extraction).
+const (
+	// ExtractionContentSelector means the content selector matched.
+	ExtractionContentSelector = "content_selector"
+	// ExtractionReadability means Readability found the main content.
+	ExtractionReadability = "readability"
+	// ExtractionFallback means the first of main, article, div.content, and
+	// body was taken (Inspection.Container).
+	ExtractionFallback = "fallback"
+	// ExtractionExtension means an extension, such as a plugin, extracted the
+	// main content (Inspection.Container names it).
+	ExtractionExtension = "extension"
+	// ExtractionPlatform means the content selector of the platform profile
+	// of the page matched (Inspection.Container names the platform).
+	ExtractionPlatform = "platform"
+)
+
+// The origins of the selector rules of an inspection (RuleMatch.Source).
+const (
+	// RuleContentSelector is the content selector (see SetContentSelector).
+	RuleContentSelector = "content_selector"
+	// RuleStripSelector is a strip selector (see SetStripSelectors).
+	RuleStripSelector = "strip_selector"
+	// RuleBoilerplate is a built-in rule removing navigation, scripts, and
+	// other boilerplate from the main content.
+	RuleBoilerplate = "boilerplate"
+	// RulePlatform is a content, strip, or pagination selector of the
+	// platform profile of the page (see SetPlatform).
+	RulePlatform = "platform"
+)
+
+// Inspection describes how a page was converted: see Inspect.
+type Inspection struct {
+	// Generator is the content of the page's generator meta tag (e.g.,
+	// "Docusaurus v3.1.0"); empty when it has none.
+	Generator string `json:"generator,omitempty"`
+	// Platform is the name of the platform profile applied to the page (see
+	// SetPlatform); empty when none was.
+	Platform string `json:"platform,omitempty"`
+	// Extraction is how the main content was found: ExtractionExtension,
+	// ExtractionContentSelector, ExtractionPlatform, ExtractionReadability, or
+	// ExtractionFallback;
+	// empty when no main content was found.
+	Extraction string `json:"extraction"`
+	// Container is the element taken as main content with ExtractionFallback
+	// (e.g., "main"), the extension with ExtractionExtension, or the platform
+	// with ExtractionPlatform.
+	Container string `json:"container,omitempty"`
+	// Rules lists the configured content and strip selectors with the elements
+	// they matched, including those that matched nothing.
+	Rules []RuleMatch `json:"rules"`
+	// Stripped lists the elements removed by the built-in boilerplate rules.
+	// Readability removes boilerplate with heuristics of its own, which aren't listed.
+	Stripped []RuleMatch `json:"stripped"`
+	// Title, Description, CanonicalURL, Lang, Tags, Breadcrumbs, Nav, and
+	// SchemaTypes are the metadata extracted from the page, as written to its frontmatter.
+	Title        string   `json:"title"`
+	Description  string   `json:"description,omitempty"`
+	CanonicalURL string   `json:"canonical_url,omitempty"`
+	Lang         string   `json:"lang,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	Breadcrumbs  []string `json:"breadcrumbs,omitempty"`
+	Nav          []string `json:"nav,omitempty"`
+	SchemaTypes  []string `json:"schema_types,omitempty"`
+	// PageType is the type the page was classified as (see SetPageTypes).
+	PageType string `json:"page_type"`
+	// DetectedLanguage is the language detected from the converted text of
+	// the page (see SetLanguageFilter); empty if it can't be told.
+	DetectedLanguage string `json:"detected_language,omitempty"`
+	// Skipped reports whether a converter restricted to other page types skips the page.
+	Skipped bool `json:"skipped,omitempty"`
+	// LanguageSkipped reports whether a converter excluding the pages in
+	// another language than their locale skips the page.
+	LanguageSkipped bool `json:"language_skipped,omitempty"`
+	// Markdown is the converted page with its frontmatter, as written by
+	// ConvertPage; empty when no main content was found.
+	Markdown string `json:"markdown"`
+}
+
+// RuleMatch lists the elements matched by a selector rule.
+type RuleMatch struct {
+	// Selector is the CSS selector of the rule.
+	Selector string `json:"selector"`
+	// Source is the origin of the rule: RuleContentSelector, RuleStripSelector,
+	// RulePlatform, or RuleBoilerplate.
+	Source string `json:"source"`
+	// Elements describe the matched elements as tag#id.class (e.g., "nav#menu.sidebar").
+	Elements []string `json:"elements"`
+}
+
+// Inspect converts an HTML document like ConvertPage, bypassing the conversion
+// cache and writing nothing, and reports how it was converted: the generator
+// of the page, how its main content was found, the elements matched by the
+// selector rules, the metadata extracted, and the resulting Markdown.
+//
+// Returns an error wrapping ErrConversionFailed if the HTML can't be parsed or converted.
+func (c *Converter) Inspect(htmlContent []byte, meta PageMeta) (*Inspection, error) {
+	in := &Inspection{Rules: []RuleMatch{}, Stripped: []RuleMatch{}}
+	c.trace = in
+	c.meta = meta
+	defer func() { c.trace = nil }()
+
+	p, err := c.convertHTML(htmlContent, meta.SourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConversionFailed, err)
+	}
+	for _, selector := range c.stripSelectors {
+		in.record(selector, RuleStripSelector, nil)
+	}
+	if c.contentSelector != "" {
+		in.record(c.contentSelector, RuleContentSelector, nil)
+	}
+
+	in.Description = p.Description
+	in.CanonicalURL = resolveCanonical(meta.SourceURL, p.Canonical)
+	in.Lang = p.Lang
+	in.Tags = p.Tags
+	in.Breadcrumbs = p.Breadcrumbs
+	in.Nav = p.Nav
+	in.SchemaTypes = p.Structured.Types
+	in.PageType = p.pageType(meta.SourceURL)
+	in.Skipped = !c.keepsPageType(in.PageType)
+	in.LanguageSkipped = !c.keepsLanguage(&p, meta, meta.SourceURL)
+	in.DetectedLanguage = orString(p.DetectedLanguage, lang.Detect(proseText(p.Markdown)))
+	if p.Title != "" {
+		if err := c.transformMarkdown(&p, meta); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrConversionFailed, err)
+		}
+		in.Title = p.Title
+		in.Markdown = p.frontmatter(meta, contentHash(htmlContent)) + p.Markdown
+	}
+	return in, nil
+}
+
+// remove removes the elements of sel matched by a selector rule, recording
+// them in the inspection in progress, if any.
+func (c *Converter) remove(sel *goquery.Selection, selector, source string) {
+	if c.trace != nil {
+		c.trace.record(selector, source, sel)
+	}
+	sel.Remove()
+}
+
+// traceExtraction records how the main content was found in the inspection in progress, if any.
+func (c *Converter) traceExtraction(extraction, container string) {
+	if c.trace != nil {
+		c.trace.Extraction = extraction
+		c.trace.Container = container
+	}
+}
+
+// record adds the elements of sel, which may be nil, to the match of a rule,
+// adding the rule if needed. Boilerplate and platform rules matching nothing
+// aren't listed.
+func (in *Inspection) record(selector, source string, sel *goquery.Selection) {
+	if (source == RuleBoilerplate || source == RulePlatform) && (sel == nil || sel.Length() == 0) {
+		return
+	}
+	list := &in.Rules
+	if source == RuleBoilerplate {
+		list = &in.Stripped
+	}
+
+	i := 0
+	for i < len(*list) && ((*list)[i].Selector != selector || (*list)[i].Source != source) {
+		i++
+	}
+	if i == len(*list) {
+		*list = append(*list, RuleMatch{Selector: selector, Source: source, Elements: []string{}})
+	}
+	if sel != nil {
+		sel.Each(func(_ int, s *goquery.Selection) {
+			(*list)[i].Elements = append((*list)[i].Elements, describeElement(s))
+		})
+	}
+}
+
+// describeElement describes an element as its tag, id, and first three classes
+// (e.g., "nav#menu.sidebar.dark").
+func describeElement(s *goquery.Selection) string {
+	desc := goquery.NodeName(s)
+	if id, ok := s.Attr("id"); ok && id != "" {
+		desc += "#" + id
+	}
+	classes := strings.Fields(s.AttrOr("class", ""))
+	if len(classes) > 3 {
+		classes = classes[:3]
+	}
+	for _, class := range classes {
+		desc += "." + class
+	}
+	return desc
+}
@@ -0,0 +1,101 @@
+package converter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	html := []byte(`<html lang="en"><head><title>Install</title>
+<meta name="generator" content="Docusaurus v3.1.0">
+<meta name="description" content="How to install.">
+</head><body>
+<div id="promo" class="banner ad">Try the cloud!</div>
+<main class="docs"><h1>Install</h1><p>Download the latest release and run the installer.</p>
+<nav class="toc">On this page</nav><script>track()</script></main>
+<footer>Copyright</footer></body></html>`)
+
+	tests := []struct {
+		name           string
+		content        string
+		strip          []string
+		wantExtraction string
+		wantContainer  string
+		wantRules      []RuleMatch
+		wantStripped   []RuleMatch
+	}{
+		{
+			name:           "content selector",
+			content:        "main",
+			strip:          []string{"#promo", ".missing"},
+			wantExtraction: ExtractionContentSelector,
+			wantRules: []RuleMatch{
+				{Selector: "#promo", Source: RuleStripSelector, Elements: []string{"div#promo.banner.ad"}},
+				{Selector: ".missing", Source: RuleStripSelector, Elements: []string{}},
+				{Selector: "main", Source: RuleContentSelector, Elements: []string{"main.docs"}},
+			},
+			wantStripped: []RuleMatch{
+				{Selector: "script", Source: RuleBoilerplate, Elements: []string{"script"}},
+				{Selector: ".toc", Source: RuleBoilerplate, Elements: []string{"nav.toc"}},
+			},
+		},
+		{
+			// Readability finds too little text in the page and the main element is taken
+			name:           "content selector matching nothing",
+			content:        "article",
+			wantExtraction: ExtractionFallback,
+			wantContainer:  "main",
+			wantRules: []RuleMatch{
+				{Selector: "article", Source: RuleContentSelector, Elements: []string{}},
+			},
+			wantStripped: []RuleMatch{
+				{Selector: "script", Source: RuleBoilerplate, Elements: []string{"script"}},
+				{Selector: ".toc", Source: RuleBoilerplate, Elements: []string{"nav.toc"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			if err := c.SetContentSelector(tt.content); err != nil {
+				t.Fatal(err)
+			}
+			if err := c.SetStripSelectors(tt.strip); err != nil {
+				t.Fatal(err)
+			}
+			in, err := c.Inspect(html, PageMeta{SourceURL: "https://example.com/docs/install", FetchedAt: "2024-01-01T00:00:00Z"})
+			if err != nil {
+				t.Fatalf("Inspect() returned error: %v", err)
+			}
+			if in.Generator != "Docusaurus v3.1.0" || in.Title != "Install" || in.Description != "How to install." || in.Lang != "en" {
+				t.Errorf("metadata = %q, %q, %q, %q", in.Generator, in.Title, in.Description, in.Lang)
+			}
+			if in.Extraction != tt.wantExtraction || in.Container != tt.wantContainer {
+				t.Errorf("Extraction = %q (%q), want %q (%q)", in.Extraction, in.Container, tt.wantExtraction, tt.wantContainer)
+			}
+			if !reflect.DeepEqual(in.Rules, tt.wantRules) {
+				t.Errorf("Rules = %+v, want %+v", in.Rules, tt.wantRules)
+			}
+			if !reflect.DeepEqual(in.Stripped, tt.wantStripped) {
+				t.Errorf("Stripped = %+v, want %+v", in.Stripped, tt.wantStripped)
+			}
+			if !strings.HasPrefix(in.Markdown, "---\ntitle: \"Install\"\n") || !strings.Contains(in.Markdown, "Download the latest release") {
+				t.Errorf("Markdown = %q", in.Markdown)
+			}
+			if strings.Contains(in.Markdown, "Try the cloud") || strings.Contains(in.Markdown, "track()") {
+				t.Errorf("Markdown kept boilerplate: %q", in.Markdown)
+			}
+		})
+	}
+
+	// Inspecting doesn't leave the converter tracing
+	c := New()
+	if _, err := c.Inspect(html, PageMeta{SourceURL: "https://example.com/"}); err != nil {
+		t.Fatal(err)
+	}
+	if c.trace != nil {
+		t.Error("Inspect() left the converter tracing")
+	}
+}
@@ -0,0 +1,124 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the recognition of FAQ and changelog pages, whose
+// structure (questions and their answers, releases and their dates) generic
+// conversion would flatten, and which the skill generator collects into
+// faq.md and changelog.md.
+package converter
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Page kinds, written to the kind frontmatter field of the pages recognized
+// as one.
+const (
+	// KindFAQ is a page of questions and their answers.
+	KindFAQ = "faq"
+	// KindChangelog is a changelog or release notes page, a section per release.
+	KindChangelog = "changelog"
+)
+
+const (
+	// minQuestions is the number of questions that makes a page a FAQ when
+	// neither its title nor its URL says it is one.
+	minQuestions = 3
+	// minReleases is the number of release headings a changelog has.
+	minReleases = 2
+	// maxReleaseHeadingWords is the longest release heading, in words; longer
+	// headings mentioning a version are prose.
+	maxReleaseHeadingWords = 8
+)
+
+var (
+	// faqPattern matches the titles and URLs of FAQ pages.
+	faqPattern = regexp.MustCompile(`(?i)\bfaqs?\b|frequently[ -]asked|common[ -]questions|questions[ -](?:and|&)[ -]answers|\bq\s*&\s*a\b`)
+	// changelogPattern matches the titles and URLs of changelog pages.
+	changelogPattern = regexp.MustCompile(`(?i)change[ -]?log|release[ -]?notes|\breleases\b|release[ -]history|version[ -]history|what'?s[ -]new|\bhistory\b`)
+	// releasePattern matches the version a release heading names, such as
+	// "v1.2.0", "[1.2.0] - 2024-01-02", or "Version 2.1 (March 2024)".
+	releasePattern = regexp.MustCompile(`(?i)(?:^|[\s\[(])v?(\d+\.\d+(?:\.\d+)?(?:-[0-9a-z.]+)?)(?:$|[\s\]),:])`)
+)
+
+// readKind recognizes the kind of the page of doc, with the source URL
+// sourceURL, and normalizes the structure of FAQ pages, before extraction:
+//   - a changelog has minReleases headings naming a version, and a title or
+//     URL saying it is one, or else more release headings than others;
+//   - a FAQ declares a schema.org FAQPage, has a title or URL saying it is one
+//     and asks a question, or else asks minQuestions questions, the most of
+//     its headings: questions are the headings and the summaries of details
+//     elements ending with a question mark.
+//
+// Returns whether doc was changed: the details elements of a FAQ asking a
+// question are replaced by a heading of the question followed by the answer,
+// since the Markdown conversion would leave the question as a paragraph.
+func (p *page) readKind(doc *goquery.Document, sourceURL string) bool {
+	var name string
+	if u, err := url.Parse(sourceURL); err == nil {
+		name = u.Path
+	}
+	name += " " + doc.Find("title").First().Text() + " " + doc.Find("h1").First().Text()
+
+	headings := doc.Find("body h2, body h3, body h4")
+	releases := headings.FilterFunction(func(_ int, sel *goquery.Selection) bool {
+		return isReleaseHeading(sel.Text())
+	}).Length()
+	if releases >= minReleases && (changelogPattern.MatchString(name) || 2*releases > headings.Length()) {
+		p.Kind = KindChangelog
+		return false
+	}
+
+	summaries := doc.Find("body details > summary")
+	questions := headings.AddSelection(summaries).FilterFunction(func(_ int, sel *goquery.Selection) bool {
+		return isQuestion(sel.Text())
+	}).Length()
+	switch {
+	case containsFold(p.Structured.Types, "FAQPage"),
+		questions > 0 && faqPattern.MatchString(name),
+		questions >= minQuestions && 2*questions > headings.Length()+summaries.Length():
+		p.Kind = KindFAQ
+	default:
+		return false
+	}
+
+	// Questions are nested under the headings of the page
+	level := atom.H2
+	if headings.Not("details *").Filter("h2").Length() > 0 {
+		level = atom.H3
+	}
+	changed := false
+	summaries.Each(func(_ int, summary *goquery.Selection) {
+		if !isQuestion(summary.Text()) {
+			return
+		}
+		details := summary.Parent().Get(0)
+		heading := &html.Node{Type: html.ElementNode, DataAtom: level, Data: level.String()}
+		heading.AppendChild(&html.Node{Type: html.TextNode, Data: strings.Join(strings.Fields(summary.Text()), " ")})
+		details.Parent.InsertBefore(heading, details)
+		summary.Remove()
+		for c := details.FirstChild; c != nil; c = details.FirstChild {
+			details.RemoveChild(c)
+			details.Parent.InsertBefore(c, details)
+		}
+		details.Parent.RemoveChild(details)
+		changed = true
+	})
+	return changed
+}
+
+// isQuestion reports whether text, a heading or summary, asks a question.
+func isQuestion(text string) bool {
+	text = strings.TrimSpace(text)
+	return strings.HasSuffix(text, "?") || strings.HasSuffix(text, "？")
+}
+
+// isReleaseHeading reports whether text, a heading, names a release: a short
+// heading holding a version.
+func isReleaseHeading(text string) bool {
+	return len(strings.Fields(text)) <= maxReleaseHeadingWords && releasePattern.MatchString(strings.TrimSpace(text))
+}
@@ -0,0 +1,91 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestReadKind(t *testing.T) {
+	para := "<p>" + strings.Repeat("Everything you need to know about accounts, billing, and the command line tool. ", 6) + "</p>"
+	tests := []struct {
+		name         string
+		url          string
+		head         string
+		body         string
+		wantKind     string
+		wantMarkdown []string
+	}{
+		{
+			name: "FAQ of details elements",
+			url:  "https://example.com/support/faq",
+			body: `<h2>Billing</h2>` +
+				`<details><summary>Is it free?</summary><p>Yes, for open source projects.</p></details>` +
+				`<details><summary>Can I cancel?</summary><p>At any time.</p></details>`,
+			wantKind:     KindFAQ,
+			wantMarkdown: []string{"## Billing\n\n### Is it free?\n\nYes, for open source projects.\n\n### Can I cancel?\n\nAt any time."},
+		},
+		{
+			name: "FAQ of question headings",
+			url:  "https://example.com/help",
+			body: `<h2>How do I sign in?</h2><p>With your email.</p><h2>Where are my keys?</h2><p>In the settings.</p>` +
+				`<h2>Why was I billed?</h2><p>For the last month.</p>`,
+			wantKind: KindFAQ,
+		},
+		{
+			name:     "schema.org FAQPage",
+			url:      "https://example.com/help",
+			head:     `<script type="application/ld+json">{"@type":"FAQPage","mainEntity":[]}</script>`,
+			body:     `<h2>Accounts</h2><p>Sign in with your email.</p>`,
+			wantKind: KindFAQ,
+		},
+		{
+			name: "changelog",
+			url:  "https://example.com/docs/changelog",
+			body: `<h2>[1.2.0] - 2024-03-01</h2><h3>Added</h3><ul><li>Retries.</li></ul>` +
+				`<h2>[1.1.0] - 2024-01-15</h2><h3>Fixed</h3><ul><li>Timeouts.</li></ul>`,
+			wantKind:     KindChangelog,
+			wantMarkdown: []string{"## \\[1.2.0\\] - 2024-03-01"},
+		},
+		{
+			name:     "release notes by heading count",
+			url:      "https://example.com/docs/news",
+			body:     `<h2>v3.0.0</h2><p>Breaking changes.</p><h2>v2.4.1</h2><p>Fixes.</p><h2>v2.4.0</h2><p>Features.</p>`,
+			wantKind: KindChangelog,
+		},
+		{
+			name: "guide with a question",
+			url:  "https://example.com/docs/install",
+			body: `<h2>Install</h2><p>Run the installer.</p><h2>Configure</h2><p>Edit the file.</p>` +
+				`<details><summary>Why root?</summary><p>To write to /usr.</p></details>`,
+			wantKind: "",
+		},
+		{
+			name:     "guide mentioning a version",
+			url:      "https://example.com/docs/upgrade",
+			body:     `<h2>Upgrade to 2.0</h2><p>Run the upgrade.</p><h2>Verify</h2><p>Check the version.</p>`,
+			wantKind: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			c.meta = PageMeta{SourceURL: tt.url}
+			html := `<html><head><title>Help</title>` + tt.head + `</head><body><article><h1>Help</h1>` + para + tt.body + `</article></body></html>`
+			p, err := c.convertHTML([]byte(html), "page.html")
+			if err != nil {
+				t.Fatalf("convertHTML returned error: %v", err)
+			}
+			if p.Kind != tt.wantKind {
+				t.Errorf("Kind = %q, want %q", p.Kind, tt.wantKind)
+			}
+			for _, want := range tt.wantMarkdown {
+				if !strings.Contains(p.Markdown, want) {
+					t.Errorf("Markdown lacks %q:\n%s", want, p.Markdown)
+				}
+			}
+			if front := p.frontmatter(c.meta, ""); tt.wantKind != "" && !strings.Contains(front, `kind: "`+tt.wantKind+`"`) {
+				t.Errorf("frontmatter lacks the kind:\n%s", front)
+			}
+		})
+	}
+}
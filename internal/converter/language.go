@@ -0,0 +1,107 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the language filter, which detects the language of the
+// converted text of every page and flags or leaves out the pages in another
+// language than their locale, such as the untranslated pages of a partially
+// translated site served in English under /ja/ URLs.
+package converter
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/lang"
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+)
+
+// Language filter modes; see SetLanguageFilter.
+const (
+	// LanguageFilterOff doesn't detect the language of pages (the default).
+	LanguageFilterOff = "off"
+	// LanguageFilterFlag records the detected language of every page and
+	// warns about the pages in another language than their locale.
+	LanguageFilterFlag = "flag"
+	// LanguageFilterExclude is like LanguageFilterFlag, but leaves the pages
+	// in another language than their locale out.
+	LanguageFilterExclude = "exclude"
+)
+
+var (
+	// inlineCodePattern matches the inline code spans of Markdown.
+	inlineCodePattern = regexp.MustCompile("`[^`\n]*`")
+	// linkTargetPattern matches the targets of Markdown links and images.
+	linkTargetPattern = regexp.MustCompile(`\]\([^)\n]*\)`)
+	// bareURLPattern matches the URLs written out in Markdown.
+	bareURLPattern = regexp.MustCompile(`https?://\S+`)
+)
+
+// SetLanguageFilter sets how pages in another language than their locale
+// are handled: LanguageFilterOff ("" too), LanguageFilterFlag, or
+// LanguageFilterExclude. The language of a page is detected from the prose of
+// its converted Markdown (see lang.Detect) and recorded in its
+// detected_language frontmatter field; it mismatches when it isn't the
+// language of the locale the fetcher chose, else of the language the page
+// declares, else, for pages with neither, one of the languages of locales,
+// the locale priority. Pages whose language can't be detected, too short or
+// ambiguous, are kept.
+//
+// Returns an error if mode is unknown.
+func (c *Converter) SetLanguageFilter(mode string, locales []string) error {
+	switch mode {
+	case "", LanguageFilterOff:
+		c.languageFilter = ""
+	case LanguageFilterFlag, LanguageFilterExclude:
+		c.languageFilter = mode
+	default:
+		return fmt.Errorf("unknown language filter %q (expected %s, %s, or %s)",
+			mode, LanguageFilterOff, LanguageFilterFlag, LanguageFilterExclude)
+	}
+	c.languages = nil
+	for _, locale := range locales {
+		if l := lang.Language(locale); l != "" && !slices.Contains(c.languages, l) {
+			c.languages = append(c.languages, l)
+		}
+	}
+	return nil
+}
+
+// keepsLanguage detects the language of p, the page converted from path and
+// described by meta, when the language filter is on, and reports whether the
+// page is kept: false if it is in another language than expected with
+// LanguageFilterExclude. Mismatches are logged as language warnings.
+func (c *Converter) keepsLanguage(p *page, meta PageMeta, path string) bool {
+	if c.languageFilter == "" {
+		return true
+	}
+	p.DetectedLanguage = lang.Detect(proseText(p.Markdown))
+	if p.DetectedLanguage == "" {
+		return true
+	}
+	expected := c.languages
+	if l := lang.Language(orString(meta.Locale, p.Lang)); l != "" {
+		expected = []string{l}
+	}
+	if len(expected) == 0 || slices.Contains(expected, p.DetectedLanguage) {
+		return true
+	}
+	if c.languageFilter == LanguageFilterExclude {
+		warnlog.Printf("language", "Skipping %s page %s (expected %s)", p.DetectedLanguage, path, strings.Join(expected, " or "))
+		return false
+	}
+	warnlog.Printf("language", "Warning: %s is in %s, not %s", path, p.DetectedLanguage, strings.Join(expected, " or "))
+	return true
+}
+
+// proseText returns the prose of markdown, whose language is detected: its
+// lines outside code blocks, without inline code, link targets, and URLs.
+func proseText(markdown string) string {
+	var b strings.Builder
+	eachProseLine(markdown, func(line string) {
+		line = inlineCodePattern.ReplaceAllString(line, " ")
+		line = linkTargetPattern.ReplaceAllString(line, "]")
+		line = bareURLPattern.ReplaceAllString(line, " ")
+		b.WriteString(line + "\n")
+	})
+	return b.String()
+}
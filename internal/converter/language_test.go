@@ -0,0 +1,76 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLanguageFilter(t *testing.T) {
+	english := "<p>To install the command line tool, download the archive for your platform and add it to your path. " +
+		"The tool reads its settings from the configuration file in your home directory, which you can edit with any text editor.</p>"
+	japanese := "<p>コマンドラインツールをインストールするには、お使いのプラットフォーム用のアーカイブをダウンロードし、<code>PATH</code> に追加します。" +
+		"ツールはホームディレクトリにある設定ファイルから設定を読み込みます。このファイルは任意のテキストエディタで編集できます。</p>"
+	tests := []struct {
+		name     string
+		mode     string
+		locales  []string
+		locale   string
+		htmlLang string
+		body     string
+		want     string // detected_language field; "" for none
+		wantSkip bool
+	}{
+		{name: "off", mode: LanguageFilterOff, locale: "ja", body: english},
+		{name: "flag keeps an English page under ja", mode: LanguageFilterFlag, locale: "ja", body: english, want: "en"},
+		{name: "exclude skips an English page under ja", mode: LanguageFilterExclude, locale: "ja", body: english, wantSkip: true},
+		{name: "exclude keeps a Japanese page under ja", mode: LanguageFilterExclude, locale: "ja-JP", body: japanese, want: "ja"},
+		{name: "declared language", mode: LanguageFilterExclude, htmlLang: "en", locales: []string{"ja"}, body: english, want: "en"},
+		{name: "locale priority without a locale", mode: LanguageFilterExclude, locales: []string{"en", "ja"}, body: japanese, want: "ja"},
+		{name: "locale priority mismatch", mode: LanguageFilterExclude, locales: []string{"ja"}, body: english, wantSkip: true},
+		{name: "no expected language", mode: LanguageFilterExclude, body: english, want: "en"},
+		{name: "too short to tell", mode: LanguageFilterExclude, locale: "ja", body: "<p>See the reference.</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			if err := c.SetLanguageFilter(tt.mode, tt.locales); err != nil {
+				t.Fatalf("SetLanguageFilter returned error: %v", err)
+			}
+			dir := t.TempDir()
+			htmlPath := filepath.Join(dir, "page.html")
+			html := `<html lang="` + tt.htmlLang + `"><head><title>Install</title></head><body><article><h1>Install</h1>` + tt.body + `</article></body></html>`
+			if err := os.WriteFile(htmlPath, []byte(html), 0644); err != nil {
+				t.Fatal(err)
+			}
+			mdPath := filepath.Join(dir, "page.md")
+			meta := PageMeta{SourceURL: "https://example.com/ja/docs/install", Locale: tt.locale}
+			if err := c.ConvertPage(htmlPath, mdPath, meta); err != nil {
+				t.Fatalf("ConvertPage returned error: %v", err)
+			}
+			got, err := os.ReadFile(mdPath)
+			if tt.wantSkip {
+				if err == nil {
+					t.Errorf("page written, want it skipped:\n%s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("page not written: %v", err)
+			}
+			field := `detected_language: "` + tt.want + `"`
+			if tt.want == "" {
+				if strings.Contains(string(got), "detected_language:") {
+					t.Errorf("frontmatter has a detected language:\n%s", got)
+				}
+			} else if !strings.Contains(string(got), field) {
+				t.Errorf("frontmatter lacks %s:\n%s", field, got)
+			}
+		})
+	}
+
+	if err := New().SetLanguageFilter("drop", nil); err == nil {
+		t.Error("SetLanguageFilter accepted an unknown mode")
+	}
+}
@@ -0,0 +1,87 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the conversion of Markdown sources, such as the
+// documentation of a repository, which only gain the frontmatter of a page.
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+	"github.com/f4ah6o/site2skill-go/internal/mdprose"
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+	"gopkg.in/yaml.v3"
+)
+
+// ConvertMarkdown is like ConvertPage for a Markdown file: its content is
+// kept as written, and only the frontmatter of a page is added, replacing
+// any frontmatter of its own. The title is that of its frontmatter, else its
+// first H1 heading, else its file name; the description is that of its
+// frontmatter. The content hash is that of the file.
+//
+// Returns an error if the file can't be read, a Markdown transformer fails
+// (wrapping ErrConversionFailed), or the output can't be written.
+func (c *Converter) ConvertMarkdown(mdPath, outputPath string, meta PageMeta) error {
+	content, err := os.ReadFile(mdPath)
+	if err != nil {
+		return fmt.Errorf("failed to read Markdown file: %w", err)
+	}
+
+	p := markdownPage(content)
+	if p.Title == "" {
+		p.Title = strings.TrimSuffix(filepath.Base(mdPath), filepath.Ext(mdPath))
+	}
+	if pageType := p.pageType(meta.SourceURL); !c.keepsPageType(pageType) {
+		warnlog.Printf("page_type", "Skipping %s page %s", pageType, mdPath)
+		return nil
+	}
+	if !c.keepsLanguage(&p, meta, mdPath) {
+		return nil
+	}
+	if err := c.transformMarkdown(&p, meta); err != nil {
+		return fmt.Errorf("%w: %w", ErrConversionFailed, err)
+	}
+
+	if dir := filepath.Dir(outputPath); dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+	if _, err := atomicfile.WriteFile(outputPath, []byte(p.frontmatter(meta, contentHash(content))+p.Markdown)); err != nil {
+		return fmt.Errorf("failed to write markdown file: %w", err)
+	}
+	log.Printf("Converted: %s -> %s", mdPath, outputPath)
+	return nil
+}
+
+// markdownPage returns the page of Markdown content: its body without its
+// frontmatter, with the title and description of the frontmatter or, without
+// a title, of its first H1 heading.
+func markdownPage(content []byte) page {
+	body := strings.ReplaceAll(string(bytes.TrimPrefix(content, []byte("\xef\xbb\xbf"))), "\r\n", "\n")
+	var fm struct {
+		Title       string `yaml:"title"`
+		Description string `yaml:"description"`
+	}
+	if frontmatter, rest, ok := mdprose.SplitFrontmatter(body); ok {
+		// Malformed frontmatter is dropped all the same
+		yaml.Unmarshal([]byte(frontmatter), &fm)
+		body = rest
+	}
+
+	p := page{Title: strings.TrimSpace(fm.Title), Description: strings.TrimSpace(fm.Description)}
+	p.Markdown = strings.TrimSpace(body) + "\n"
+	if p.Title == "" {
+		for _, h := range Headings(p.Markdown) {
+			if h.Level == 1 {
+				p.Title = h.Text
+				break
+			}
+		}
+	}
+	return p
+}
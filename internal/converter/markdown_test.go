@@ -0,0 +1,96 @@
+package converter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConvertMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{
+			name:     "title from the first H1",
+			markdown: "Intro line.\r\n\r\n# Install\r\n\r\nRun `make`.\r\n",
+			want: `title: "Install"
+source_url: "https://github.com/acme/widgets/blob/main/docs/install.md"
+fetched_at: "2024-01-01T00:00:00Z"
+content_hash: "sha256:%s"
+word_count: 5
+page_type: "docs"
+outline:
+  - "# Install"
+---
+
+Intro line.
+
+# Install
+
+Run ` + "`make`" + `.
+`,
+		},
+		{
+			name:     "frontmatter replaced",
+			markdown: "---\ntitle: Setup guide\ndescription: How to set up.\nsidebar_position: 2\n---\n\n## Steps\n\nOne.\n",
+			want: `title: "Setup guide"
+description: "How to set up."
+source_url: "https://github.com/acme/widgets/blob/main/docs/install.md"
+fetched_at: "2024-01-01T00:00:00Z"
+content_hash: "sha256:%s"
+word_count: 2
+page_type: "docs"
+outline:
+  - "## Steps"
+---
+
+## Steps
+
+One.
+`,
+		},
+		{
+			name:     "title from the file name",
+			markdown: "Just text.",
+			want: `title: "install"
+source_url: "https://github.com/acme/widgets/blob/main/docs/install.md"
+fetched_at: "2024-01-01T00:00:00Z"
+content_hash: "sha256:%s"
+word_count: 2
+page_type: "docs"
+---
+
+Just text.
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			mdPath := filepath.Join(dir, "install.md")
+			if err := os.WriteFile(mdPath, []byte(tt.markdown), 0644); err != nil {
+				t.Fatal(err)
+			}
+			outputPath := filepath.Join(dir, "out", "install.md")
+			meta := PageMeta{SourceURL: "https://github.com/acme/widgets/blob/main/docs/install.md", FetchedAt: "2024-01-01T00:00:00Z"}
+			if err := New().ConvertMarkdown(mdPath, outputPath, meta); err != nil {
+				t.Fatalf("ConvertMarkdown() error = %v", err)
+			}
+			data, err := os.ReadFile(outputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sum := sha256.Sum256([]byte(tt.markdown))
+			want := "---\n" + fmt.Sprintf(tt.want, hex.EncodeToString(sum[:]))
+			if string(data) != want {
+				t.Errorf("ConvertMarkdown() wrote:\n%s\nwant:\n%s", data, want)
+			}
+		})
+	}
+}
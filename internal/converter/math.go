@@ -0,0 +1,365 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the preservation of math (MathJax, KaTeX, and MathML) as
+// LaTeX delimited by $...$ or $$...$$.
+package converter
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// mathAttr carries the LaTeX source of a formula found by annotateMath.
+	// Inline formulas become var elements (Readability unwraps spans) and display
+	// formulas div elements.
+	mathAttr = "data-math"
+	// texEncoding is the annotation encoding under which MathML embeds LaTeX source.
+	texEncoding = "application/x-tex"
+)
+
+// displayStyle matches the \displaystyle wrapper Wikipedia puts around every formula.
+var displayStyle = regexp.MustCompile(`^\{\\(?:display|text)style\s*(.*)\}$`)
+
+// mathFunctions are the multi-letter identifiers with a LaTeX command of their own.
+var mathFunctions = map[string]bool{
+	"sin": true, "cos": true, "tan": true, "cot": true, "sec": true, "csc": true,
+	"arcsin": true, "arccos": true, "arctan": true, "sinh": true, "cosh": true, "tanh": true,
+	"log": true, "ln": true, "lg": true, "exp": true, "lim": true, "max": true, "min": true,
+	"sup": true, "inf": true, "det": true, "dim": true, "ker": true, "gcd": true, "deg": true,
+	"arg": true, "Pr": true,
+}
+
+// mathSymbols maps the characters MathML uses for operators and identifiers to LaTeX.
+var mathSymbols = map[string]string{
+	"α": `\alpha`, "β": `\beta`, "γ": `\gamma`, "δ": `\delta`, "ε": `\epsilon`, "ϵ": `\epsilon`,
+	"ζ": `\zeta`, "η": `\eta`, "θ": `\theta`, "ι": `\iota`, "κ": `\kappa`, "λ": `\lambda`,
+	"μ": `\mu`, "ν": `\nu`, "ξ": `\xi`, "π": `\pi`, "ρ": `\rho`, "σ": `\sigma`, "τ": `\tau`,
+	"υ": `\upsilon`, "φ": `\phi`, "ϕ": `\phi`, "χ": `\chi`, "ψ": `\psi`, "ω": `\omega`,
+	"Γ": `\Gamma`, "Δ": `\Delta`, "Θ": `\Theta`, "Λ": `\Lambda`, "Ξ": `\Xi`, "Π": `\Pi`,
+	"Σ": `\Sigma`, "Φ": `\Phi`, "Ψ": `\Psi`, "Ω": `\Omega`,
+	"∑": `\sum`, "∏": `\prod`, "∫": `\int`, "∮": `\oint`, "∞": `\infty`, "∂": `\partial`,
+	"∇": `\nabla`, "±": `\pm`, "∓": `\mp`, "×": `\times`, "÷": `\div`, "·": `\cdot`, "⋅": `\cdot`,
+	"∘": `\circ`, "−": "-", "≤": `\leq`, "≥": `\geq`, "≠": `\neq`, "≈": `\approx`, "≡": `\equiv`,
+	"∼": `\sim`, "≅": `\cong`, "∝": `\propto`, "∈": `\in`, "∉": `\notin`, "⊂": `\subset`,
+	"⊆": `\subseteq`, "⊃": `\supset`, "⊇": `\supseteq`, "∪": `\cup`, "∩": `\cap`, "∅": `\emptyset`,
+	"∀": `\forall`, "∃": `\exists`, "¬": `\neg`, "∧": `\wedge`, "∨": `\vee`, "→": `\to`,
+	"←": `\leftarrow`, "↔": `\leftrightarrow`, "⇒": `\Rightarrow`, "⇐": `\Leftarrow`,
+	"⇔": `\Leftrightarrow`, "↦": `\mapsto`, "…": `\ldots`, "⋯": `\cdots`, "ℝ": `\mathbb{R}`,
+	"ℕ": `\mathbb{N}`, "ℤ": `\mathbb{Z}`, "ℚ": `\mathbb{Q}`, "ℂ": `\mathbb{C}`, "ℓ": `\ell`,
+	"ħ": `\hbar`, "′": "'", "{": `\{`, "}": `\}`, "%": `\%`, "#": `\#`, "&": `\&`,
+	"\u2061": "", "\u2062": "", "\u2063": "", "\u2064": "", // invisible operators
+}
+
+// mathAccents maps the over-scripts MathML uses as accents to LaTeX commands.
+var mathAccents = map[string]string{
+	"^": `\hat`, "ˆ": `\hat`, "~": `\tilde`, "˜": `\tilde`, "¯": `\overline`, "‾": `\overline`,
+	"→": `\vec`, "⃗": `\vec`, "˙": `\dot`, "¨": `\ddot`,
+}
+
+// annotateMath replaces every formula in doc with an element carrying its LaTeX
+// source in mathAttr. Formulas are found in MathJax 2 script elements, KaTeX
+// output, MathJax 3 containers, and MathML; the rendered copies MathJax and KaTeX
+// show next to the source are removed. Readability and cleanHTML drop scripts
+// and rendered math, so formulas are recorded before extraction.
+//
+// Returns whether any formula was found.
+func annotateMath(doc *goquery.Document) bool {
+	found := false
+	replace := func(sel *goquery.Selection, tex string, display bool) {
+		n := sel.Get(0)
+		if tex = strings.TrimSpace(tex); tex == "" || !attached(n) {
+			return
+		}
+		if m := displayStyle.FindStringSubmatch(tex); m != nil {
+			tex = strings.TrimSpace(m[1])
+		}
+		if !display {
+			tex = strings.Join(strings.Fields(tex), " ")
+		}
+
+		el := &html.Node{Type: html.ElementNode, Data: "var", DataAtom: atom.Var}
+		if display {
+			el.Data, el.DataAtom = "div", atom.Div
+		}
+		el.Attr = []html.Attribute{{Key: mathAttr, Val: tex}}
+		// The source as text keeps Readability from dropping the element as empty
+		el.AppendChild(&html.Node{Type: html.TextNode, Data: tex})
+		n.Parent.InsertBefore(el, n)
+		n.Parent.RemoveChild(n)
+		found = true
+	}
+
+	// MathJax 2 keeps the source in scripts preceded by the rendered output
+	doc.Find(`script[type^="math/tex"]`).Each(func(_ int, sel *goquery.Selection) {
+		for prev := sel.Prev(); prev.Length() > 0 && isMathJaxOutput(prev); prev = sel.Prev() {
+			prev.Remove()
+		}
+		replace(sel, sel.Text(), strings.Contains(sel.AttrOr("type", ""), "mode=display"))
+	})
+
+	// KaTeX and Wikipedia embed the source as a MathML annotation next to the rendering
+	doc.Find(".katex-display, .katex, .mwe-math-element").Each(func(_ int, sel *goquery.Selection) {
+		math := sel.Find("math").First()
+		if math.Length() == 0 {
+			return
+		}
+		display := sel.HasClass("katex-display") || sel.Find(".mwe-math-fallback-image-display").Length() > 0 ||
+			isDisplayMath(math)
+		replace(sel, mathMLSource(math), display)
+	})
+
+	// MathJax 3 renders into containers with the MathML as assistive content
+	doc.Find("mjx-container").Each(func(_ int, sel *goquery.Selection) {
+		math := sel.Find("math").First()
+		if math.Length() == 0 {
+			return
+		}
+		replace(sel, mathMLSource(math), sel.AttrOr("display", "") == "true" || isDisplayMath(math))
+	})
+
+	doc.Find("math").Each(func(_ int, sel *goquery.Selection) {
+		replace(sel, mathMLSource(sel), isDisplayMath(sel))
+	})
+	return found
+}
+
+// attached reports whether n is still part of the document, that is, not inside
+// a formula already replaced.
+func attached(n *html.Node) bool {
+	for ; n.Parent != nil; n = n.Parent {
+	}
+	return n.Type == html.DocumentNode
+}
+
+// isMathJaxOutput reports whether sel is an element MathJax 2 rendered in front of
+// a math script (e.g., span.MathJax_Preview, span.MathJax, or div.MathJax_Display).
+func isMathJaxOutput(sel *goquery.Selection) bool {
+	for _, class := range strings.Fields(sel.AttrOr("class", "")) {
+		if class == "MathJax" || strings.HasPrefix(class, "MathJax_") {
+			return true
+		}
+	}
+	return false
+}
+
+// isDisplayMath reports whether a MathML math element is a display formula.
+func isDisplayMath(math *goquery.Selection) bool {
+	return math.AttrOr("display", "") == "block" || math.AttrOr("mode", "") == "display"
+}
+
+// mathMLSource returns the LaTeX for a MathML math element: its TeX annotation or
+// alttext when present, otherwise LaTeX translated from the presentation markup.
+func mathMLSource(math *goquery.Selection) string {
+	if tex := math.Find(`annotation[encoding="` + texEncoding + `"]`).First(); tex.Length() > 0 {
+		return tex.Text()
+	}
+	if alt := math.AttrOr("alttext", ""); alt != "" {
+		return alt
+	}
+	return mathMLToTeX(math.Get(0))
+}
+
+// mathMLToTeX translates presentation MathML to LaTeX. Elements without a LaTeX
+// counterpart contribute the translation of their children.
+func mathMLToTeX(n *html.Node) string {
+	args := mathArgs(n)
+	arg := func(i int) string {
+		if i < len(args) {
+			return args[i]
+		}
+		return ""
+	}
+
+	switch n.Data {
+	case "semantics":
+		// The first child is the presentation markup, the rest annotations
+		return arg(0)
+	case "annotation", "annotation-xml", "mphantom":
+		return ""
+	case "mi":
+		text := strings.TrimSpace(nodeText(n))
+		switch {
+		case mathFunctions[text]:
+			return `\` + text
+		case utf8.RuneCountInString(text) > 1 && mathSymbols[text] == "":
+			return `\mathrm{` + text + `}`
+		}
+		return mathText(text)
+	case "mn", "mo":
+		return mathText(strings.TrimSpace(nodeText(n)))
+	case "mtext":
+		if text := strings.TrimSpace(nodeText(n)); text != "" {
+			return `\text{` + text + `}`
+		}
+		return ""
+	case "mspace":
+		return `\,`
+	case "msup":
+		return scriptBase(arg(0)) + "^{" + arg(1) + "}"
+	case "msub":
+		return scriptBase(arg(0)) + "_{" + arg(1) + "}"
+	case "msubsup", "munderover":
+		return scriptBase(arg(0)) + "_{" + arg(1) + "}^{" + arg(2) + "}"
+	case "munder":
+		if isLargeOperator(arg(0)) {
+			return arg(0) + "_{" + arg(1) + "}"
+		}
+		return `\underset{` + arg(1) + "}{" + arg(0) + "}"
+	case "mover":
+		if accent, ok := mathAccents[strings.TrimSpace(nodeText(lastElement(n)))]; ok {
+			return accent + "{" + arg(0) + "}"
+		}
+		if isLargeOperator(arg(0)) {
+			return arg(0) + "^{" + arg(1) + "}"
+		}
+		return `\overset{` + arg(1) + "}{" + arg(0) + "}"
+	case "mfrac":
+		return `\frac{` + arg(0) + "}{" + arg(1) + "}"
+	case "msqrt":
+		return `\sqrt{` + joinTeX(args) + "}"
+	case "mroot":
+		return `\sqrt[` + arg(1) + "]{" + arg(0) + "}"
+	case "mfenced":
+		open := attrOr(n, "open", "(")
+		close := attrOr(n, "close", ")")
+		sep := attrOr(n, "separators", ",")
+		if sep != "" {
+			sep = sep[:1]
+		}
+		return mathText(open) + strings.Join(args, sep) + mathText(close)
+	case "mtable":
+		return `\begin{matrix} ` + strings.Join(args, ` \\ `) + ` \end{matrix}`
+	case "mtr", "mlabeledtr":
+		return strings.Join(args, " & ")
+	}
+	return joinTeX(args)
+}
+
+// mathArgs translates the element children of a MathML element.
+func mathArgs(n *html.Node) []string {
+	var args []string
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode {
+			args = append(args, mathMLToTeX(c))
+		}
+	}
+	return args
+}
+
+// joinTeX concatenates LaTeX fragments, separating a command from a following letter.
+func joinTeX(parts []string) string {
+	var b strings.Builder
+	for _, part := range parts {
+		if part == "" {
+			continue
+		}
+		if s := b.String(); endsWithCommand(s) && isLetter(part[0]) {
+			b.WriteString(" ")
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+// endsWithCommand reports whether s ends with a LaTeX command name such as \alpha.
+func endsWithCommand(s string) bool {
+	i := len(s)
+	for i > 0 && isLetter(s[i-1]) {
+		i--
+	}
+	return i < len(s) && i > 0 && s[i-1] == '\\'
+}
+
+// isLetter reports whether c is an ASCII letter.
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// mathText maps the characters of MathML text to LaTeX.
+func mathText(text string) string {
+	if tex, ok := mathSymbols[text]; ok {
+		return tex
+	}
+	var parts []string
+	for _, r := range text {
+		if tex, ok := mathSymbols[string(r)]; ok {
+			parts = append(parts, tex)
+		} else {
+			parts = append(parts, string(r))
+		}
+	}
+	return joinTeX(parts)
+}
+
+// scriptBase braces the base of a sub- or superscript unless it is a single
+// character or command.
+func scriptBase(base string) string {
+	if utf8.RuneCountInString(base) <= 1 || base[0] == '\\' && !strings.ContainsAny(base[1:], `\{}^_ `) {
+		return base
+	}
+	return "{" + base + "}"
+}
+
+// isLargeOperator reports whether tex is a sum, product, integral, or limit,
+// whose under- and over-scripts are LaTeX subscripts and superscripts.
+func isLargeOperator(tex string) bool {
+	switch tex {
+	case `\sum`, `\prod`, `\int`, `\oint`, `\lim`, `\max`, `\min`, `\sup`, `\inf`:
+		return true
+	}
+	return false
+}
+
+// nodeText returns the concatenated text inside n.
+func nodeText(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+	return goquery.NewDocumentFromNode(n).Text()
+}
+
+// lastElement returns the last element child of n, or nil.
+func lastElement(n *html.Node) *html.Node {
+	for c := n.LastChild; c != nil; c = c.PrevSibling {
+		if c.Type == html.ElementNode {
+			return c
+		}
+	}
+	return nil
+}
+
+// attrOr returns the value of the attribute key of n, or def if n doesn't have it.
+func attrOr(n *html.Node, key, def string) string {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val
+		}
+	}
+	return def
+}
+
+// convertMath is the html-to-markdown rule for the var and div elements
+// annotateMath creates: inline formulas become $...$ and display formulas a
+// $$ block. Other divs fall through to the default rule.
+func convertMath(content string, sel *goquery.Selection, _ *md.Options) *string {
+	tex, ok := sel.Attr(mathAttr)
+	if !ok {
+		if goquery.NodeName(sel) == "var" {
+			// There is no default var rule to fall back to
+			return &content
+		}
+		return nil
+	}
+
+	text := "$" + tex + "$"
+	if goquery.NodeName(sel) == "div" {
+		text = "\n\n$$\n" + tex + "\n$$\n\n"
+	}
+	return &text
+}
@@ -0,0 +1,72 @@
+package converter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestMathMLToTeX(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"superscript", `<math><msup><mi>x</mi><mn>2</mn></msup></math>`, `x^{2}`},
+		{"fraction", `<math><mfrac><mrow><mi>a</mi><mo>+</mo><mi>b</mi></mrow><mi>c</mi></mfrac></math>`, `\frac{a+b}{c}`},
+		{"greek and root", `<math><mi>α</mi><mo>=</mo><msqrt><mi>β</mi></msqrt></math>`, `\alpha=\sqrt{\beta}`},
+		{"command before letter", `<math><mi>π</mi><mi>r</mi></math>`, `\pi r`},
+		{"function", `<math><mi>sin</mi><mo>&#x2061;</mo><mi>x</mi></math>`, `\sin x`},
+		{"sum", `<math><munderover><mo>∑</mo><mrow><mi>i</mi><mo>=</mo><mn>1</mn></mrow><mi>n</mi></munderover><msub><mi>x</mi><mi>i</mi></msub></math>`, `\sum_{i=1}^{n}x_{i}`},
+		{"accent", `<math><mover><mi>v</mi><mo>→</mo></mover></math>`, `\vec{v}`},
+		{"braced base", `<math><msup><mrow><mo>(</mo><mi>a</mi><mo>+</mo><mi>b</mi><mo>)</mo></mrow><mn>2</mn></msup></math>`, `{(a+b)}^{2}`},
+		{"matrix", `<math><mtable><mtr><mtd><mn>1</mn></mtd><mtd><mn>0</mn></mtd></mtr><mtr><mtd><mn>0</mn></mtd><mtd><mn>1</mn></mtd></mtr></mtable></math>`, `\begin{matrix} 1 & 0 \\ 0 & 1 \end{matrix}`},
+		{"tex annotation", `<math><semantics><mi>x</mi><annotation encoding="application/x-tex">\mathbf{x}</annotation></semantics></math>`, `\mathbf{x}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := mathMLSource(doc.Find("math").First()); got != tt.want {
+				t.Errorf("mathMLSource() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertMath(t *testing.T) {
+	para := "<p>" + strings.Repeat("The energy of a body follows from its mass, as the derivation below shows in detail. ", 8) + "</p>"
+	page := `<html><head><title>Energy</title></head><body><article><h1>Energy</h1>` + para + `
+<p>MathJax: <span class="MathJax_Preview">E=mc2</span><span class="MathJax" id="MathJax-Element-1-Frame">E=mc2</span><script type="math/tex" id="MathJax-Element-1">E = mc^2</script> holds.</p>
+<div class="MathJax_Display"><span class="MathJax">rendered</span></div><script type="math/tex; mode=display">\int_0^1 x\,dx = \frac{1}{2}</script>
+<p>KaTeX: <span class="katex"><span class="katex-mathml"><math><semantics><mrow><mi>a</mi><mo>*</mo><mi>b</mi></mrow><annotation encoding="application/x-tex">a_1 * b_1</annotation></semantics></math></span><span class="katex-html" aria-hidden="true">a1∗b1</span></span>.</p>
+<span class="katex-display"><span class="katex"><span class="katex-mathml"><math display="block"><semantics><mi>y</mi><annotation encoding="application/x-tex">y = \sqrt{x}</annotation></semantics></math></span><span class="katex-html">y=√x</span></span></span>
+<p>MathML: <math><msup><mi>x</mi><mn>2</mn></msup></math> and <mjx-container class="MathJax" jax="CHTML"><mjx-math>n</mjx-math><mjx-assistive-mml><math><mi>n</mi></math></mjx-assistive-mml></mjx-container>.</p>
+<p>Prices are $5 and $10.</p>` + para + `</article></body></html>`
+
+	got, err := New().convertHTML([]byte(page), "page.html")
+	if err != nil {
+		t.Fatalf("convertHTML returned error: %v", err)
+	}
+	markdown := got.Markdown
+	for _, want := range []string{
+		"MathJax: $E = mc^2$ holds.",
+		"$$\n\\int_0^1 x\\,dx = \\frac{1}{2}\n$$",
+		"KaTeX: $a_1 * b_1$.",
+		"$$\ny = \\sqrt{x}\n$$",
+		"MathML: $x^{2}$ and $n$.",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("markdown missing %q:\n%s", want, markdown)
+		}
+	}
+	for _, unwanted := range []string{"E=mc2", "rendered", "a1∗b1", "y=√x"} {
+		if strings.Contains(markdown, unwanted) {
+			t.Errorf("markdown contains rendered math %q:\n%s", unwanted, markdown)
+		}
+	}
+}
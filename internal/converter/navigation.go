@@ -0,0 +1,155 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the extraction of the position of a page in the sidebar
+// navigation of its site, which orders and nests the documents of a skill as
+// the site does rather than by URL, before the sidebar is removed as
+// boilerplate.
+package converter
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// sidebarSelectors match the sidebar navigation of the common documentation
+// themes, in order of preference: Docusaurus, MkDocs Material, Read the Docs
+// and Sphinx, VitePress, Docsy, GitBook, then generic names.
+var sidebarSelectors = []string{
+	`.theme-doc-sidebar-container`,
+	`.md-nav--primary`,
+	`.wy-menu-vertical`,
+	`.sphinxsidebar`,
+	`.VPSidebar`,
+	`#td-sidebar-menu`,
+	`.book-summary`,
+	`.docs-sidebar, #sidebar, nav.sidebar, .sidebar, aside nav`,
+}
+
+// activeSelector matches the links a sidebar marks as the current page, or
+// the items holding them.
+const activeSelector = `[aria-current="page"], .active, .current, .is-active, [class*="--active"]`
+
+// navCaptionSelector matches the headings captioning the lists of a sidebar,
+// such as the captions of Sphinx's table of contents.
+const navCaptionSelector = `h1, h2, h3, h4, h5, h6, .caption`
+
+// readNavigation fills in the position of p in the sidebar navigation of doc,
+// found by the link the sidebar marks as the current page (see
+// activeSelector), the deepest one if several are: the labels of the entries
+// leading to the page, from the top of the sidebar to the page, and its
+// position, the index of each of those entries among its siblings. Pages
+// without a marked link are left without one.
+//
+// Positions are read from the structure of the sidebar rather than counted
+// across its links, so that the pages whose sidebar expands another section
+// still compare in the order of the site.
+func (p *page) readNavigation(doc *goquery.Document) {
+	for _, selector := range sidebarSelectors {
+		sidebar := doc.Find(selector).First()
+		if sidebar.Length() == 0 {
+			continue
+		}
+		link := currentLink(sidebar)
+		if link == nil {
+			continue
+		}
+		p.Nav, p.NavPosition = navTrail(sidebar.Get(0), link)
+		return
+	}
+}
+
+// currentLink returns the link of sidebar marked as the current page, or nil:
+// one with aria-current="page", or else the deepest link marked active or
+// held by the deepest item marked active.
+func currentLink(sidebar *goquery.Selection) *html.Node {
+	if current := sidebar.Find(`a[aria-current="page"]`).First(); current.Length() > 0 {
+		return current.Get(0)
+	}
+	var best *html.Node
+	bestDepth := -1
+	sidebar.Find(activeSelector).Each(func(_ int, sel *goquery.Selection) {
+		link := sel
+		if goquery.NodeName(sel) != "a" {
+			// The item's own link, not those of its subentries
+			link = sel.ChildrenFiltered("a").First()
+			if link.Length() == 0 {
+				link = sel.Children().Not("ul, ol, nav").Find("a").First()
+			}
+		}
+		if link.Length() == 0 || strings.TrimSpace(link.Text()) == "" {
+			return
+		}
+		if depth := link.Parents().Length(); depth >= bestDepth {
+			best, bestDepth = link.Get(0), depth
+		}
+	})
+	return best
+}
+
+// navTrail returns the labels of the entries of sidebar leading to link,
+// ending with the text of link, and the index of link and each of its
+// ancestors below sidebar among its sibling elements, from 1.
+func navTrail(sidebar, link *html.Node) ([]string, []int) {
+	var trail []string
+	var position []int
+	var outermost *html.Node
+	for n := link; n != nil && n != sidebar; n = n.Parent {
+		if n.Type != html.ElementNode {
+			continue
+		}
+		position = append([]int{siblingIndex(n)}, position...)
+		if n.Data == "li" {
+			outermost = n
+			if label := itemLabel(n); label != "" {
+				trail = append([]string{label}, trail...)
+			}
+		}
+	}
+	label := strings.Join(strings.Fields(goquery.NewDocumentFromNode(link).Text()), " ")
+	if outermost == nil || len(trail) == 0 || trail[len(trail)-1] != label {
+		trail = append(trail, label)
+	}
+	// The list of the outermost entry may be captioned, as the sections of
+	// Sphinx's table of contents are
+	if outermost != nil && outermost.Parent != nil {
+		for prev := outermost.Parent.PrevSibling; prev != nil; prev = prev.PrevSibling {
+			if prev.Type != html.ElementNode {
+				continue
+			}
+			if sel := goquery.NewDocumentFromNode(prev).Selection; sel.Is(navCaptionSelector) {
+				if caption := strings.Join(strings.Fields(sel.Text()), " "); caption != "" {
+					trail = append([]string{caption}, trail...)
+				}
+			}
+			break
+		}
+	}
+	return trail, position
+}
+
+// itemLabel returns the label of the sidebar entry li: the text of its first
+// child that isn't a list of subentries, usually its link.
+func itemLabel(li *html.Node) string {
+	for c := li.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type != html.ElementNode || c.Data == "ul" || c.Data == "ol" || c.Data == "nav" {
+			continue
+		}
+		sel := goquery.NewDocumentFromNode(c).Selection
+		if label := strings.Join(strings.Fields(sel.Text()), " "); label != "" {
+			return label
+		}
+	}
+	return ""
+}
+
+// siblingIndex returns the index of n among the elements of its parent, from 1.
+func siblingIndex(n *html.Node) int {
+	i := 1
+	for s := n.PrevSibling; s != nil; s = s.PrevSibling {
+		if s.Type == html.ElementNode {
+			i++
+		}
+	}
+	return i
+}
@@ -0,0 +1,80 @@
+package converter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadNavigation(t *testing.T) {
+	para := "<p>" + strings.Repeat("Sign every request with a key of the account, and rotate keys regularly. ", 8) + "</p>"
+	tests := []struct {
+		name         string
+		html         string
+		wantNav      []string
+		wantPosition []int
+	}{
+		{
+			"Docusaurus",
+			`<aside class="theme-doc-sidebar-container"><nav><ul class="menu__list">` +
+				`<li class="menu__list-item"><a class="menu__link" href="/intro">Introduction</a></li>` +
+				`<li class="menu__list-item"><div><a class="menu__link menu__link--sublist menu__link--active" href="#">API</a></div>` +
+				`<ul class="menu__list"><li><a class="menu__link" href="/api/errors">Errors</a></li>` +
+				`<li><a class="menu__link menu__link--active" aria-current="page" href="/api/auth">Authentication</a></li></ul></li>` +
+				`</ul></nav></aside>`,
+			[]string{"API", "Authentication"},
+			[]int{1, 1, 2, 2, 2, 1},
+		},
+		{
+			"MkDocs Material",
+			`<nav class="md-nav md-nav--primary"><ul class="md-nav__list">` +
+				`<li class="md-nav__item"><a class="md-nav__link" href="/">Home</a></li>` +
+				`<li class="md-nav__item md-nav__item--active md-nav__item--nested"><label class="md-nav__link">API</label>` +
+				`<nav class="md-nav"><ul class="md-nav__list">` +
+				`<li class="md-nav__item md-nav__item--active"><a class="md-nav__link md-nav__link--active" href="auth/">Authentication</a></li>` +
+				`</ul></nav></li></ul></nav>`,
+			[]string{"API", "Authentication"},
+			[]int{1, 2, 2, 1, 1, 1},
+		},
+		{
+			"Sphinx with captions",
+			`<div class="wy-menu wy-menu-vertical"><p class="caption"><span>User Guide</span></p>` +
+				`<ul><li class="toctree-l1"><a class="reference internal" href="install.html">Install</a></li></ul>` +
+				`<p class="caption"><span>Reference</span></p>` +
+				`<ul class="current"><li class="toctree-l1 current"><a class="reference internal" href="api.html">API</a>` +
+				`<ul class="current"><li class="toctree-l2 current"><a class="current reference internal" href="#">Authentication</a></li></ul></li></ul></div>`,
+			[]string{"Reference", "API", "Authentication"},
+			[]int{4, 1, 2, 1, 1},
+		},
+		{
+			"flat sidebar",
+			`<div class="sidebar"><a href="/intro">Introduction</a><a class="active" href="/auth">Authentication</a></div>`,
+			[]string{"Authentication"},
+			[]int{2},
+		},
+		{
+			"no current page",
+			`<div class="sidebar"><ul><li><a href="/intro">Introduction</a></li></ul></div>`,
+			nil,
+			nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := `<html><head><title>Authentication</title></head><body>` + tt.html + `<article><h1>Authentication</h1>` + para + `</article></body></html>`
+			got, err := New().convertHTML([]byte(page), "page.html")
+			if err != nil {
+				t.Fatalf("convertHTML returned error: %v", err)
+			}
+			if !reflect.DeepEqual(got.Nav, tt.wantNav) {
+				t.Errorf("Nav = %q, want %q", got.Nav, tt.wantNav)
+			}
+			if !reflect.DeepEqual(got.NavPosition, tt.wantPosition) {
+				t.Errorf("NavPosition = %v, want %v", got.NavPosition, tt.wantPosition)
+			}
+			if strings.Contains(got.Markdown, "Introduction") || strings.Contains(got.Markdown, "Install") {
+				t.Errorf("sidebar kept in the content:\n%s", got.Markdown)
+			}
+		})
+	}
+}
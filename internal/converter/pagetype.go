@@ -0,0 +1,243 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the classification of pages as documentation, marketing,
+// or legal pages.
+package converter
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Page types classify what a page is for; see SetPageTypes.
+const (
+	// PageTypeDocs is documentation: guides, references, tutorials. Pages without
+	// clear signals of another type are documentation.
+	PageTypeDocs = "docs"
+	// PageTypeMarketing is a landing, pricing, features, or customer page.
+	PageTypeMarketing = "marketing"
+	// PageTypeLegal is a privacy policy, terms of service, or similar page.
+	PageTypeLegal = "legal"
+)
+
+// pageTypeThreshold is the score a page needs before it is classified as
+// anything other than documentation.
+const pageTypeThreshold = 4
+
+// pricePattern matches prices with a billing period, such as "$29/month" or "€10 per user".
+var pricePattern = regexp.MustCompile(`(?i)[$€£¥]\s?\d+(?:[.,]\d+)?\s*(?:/|per)\s*(?:mo|month|yr|year|user|seat)\b`)
+
+// docsGenerators are the names, in meta generator tags, of static site
+// generators built for documentation.
+var docsGenerators = []string{
+	"docusaurus", "mkdocs", "sphinx", "vitepress", "vuepress", "starlight",
+	"gitbook", "docsify", "mintlify", "nextra", "readme.io", "antora",
+}
+
+// docsChrome selects the navigation that documentation sites put around their pages.
+const docsChrome = ".sidebar, .toc, #toc, .table-of-contents, .theme-doc-sidebar-container, " +
+	".md-sidebar, .sphinxsidebar, .wy-nav-side, .docs-sidebar, .breadcrumbs, .breadcrumb, " +
+	`nav[aria-label*="readcrumb"]`
+
+// docsPhrases appear in the page furniture of documentation sites.
+var docsPhrases = []string{"edit this page", "was this page helpful", "on this page", "next page", "previous page"}
+
+// marketingCalls are calls to action found in the links and buttons of marketing pages.
+// "Get started" is left out, since documentation uses it just as much.
+var marketingCalls = []string{
+	"sign up", "start free trial", "free trial", "start for free", "try for free", "try it free",
+	"contact sales", "talk to sales", "request a demo", "book a demo", "get a demo", "buy now",
+}
+
+// marketingClasses are words in the class names of marketing page sections
+// (e.g., "hero", "pricing-table", "cta_banner").
+var marketingClasses = map[string]bool{
+	"hero": true, "pricing": true, "testimonial": true, "testimonials": true, "cta": true, "logos": true,
+}
+
+// legalTitles name legal documents; a page titled with one is almost certainly legal.
+var legalTitles = []string{
+	"privacy policy", "privacy notice", "privacy statement", "terms of service", "terms of use",
+	"terms and conditions", "cookie policy", "cookie notice", "legal notice", "imprint", "impressum",
+	"data processing", "acceptable use policy", "license agreement", "service level agreement",
+}
+
+// legalPhrases are the vocabulary of contracts and policies.
+var legalPhrases = []string{
+	"shall", "hereby", "liability", "governing law", "indemnif", "personal data",
+	"third parties", "pursuant", "warrant", "jurisdiction", "last updated", "effective date",
+}
+
+// pathTypes maps URL path segments to the page type they suggest.
+var pathTypes = map[string]string{
+	"docs": PageTypeDocs, "doc": PageTypeDocs, "documentation": PageTypeDocs, "guide": PageTypeDocs,
+	"guides": PageTypeDocs, "reference": PageTypeDocs, "api": PageTypeDocs, "tutorial": PageTypeDocs,
+	"tutorials": PageTypeDocs, "manual": PageTypeDocs, "learn": PageTypeDocs, "handbook": PageTypeDocs,
+	"getting-started": PageTypeDocs, "quickstart": PageTypeDocs, "how-to": PageTypeDocs, "kb": PageTypeDocs,
+
+	"pricing": PageTypeMarketing, "plans": PageTypeMarketing, "features": PageTypeMarketing,
+	"customers": PageTypeMarketing, "solutions": PageTypeMarketing, "enterprise": PageTypeMarketing,
+	"contact": PageTypeMarketing, "contact-sales": PageTypeMarketing, "demo": PageTypeMarketing,
+	"about": PageTypeMarketing, "company": PageTypeMarketing, "careers": PageTypeMarketing,
+	"jobs": PageTypeMarketing, "partners": PageTypeMarketing, "compare": PageTypeMarketing,
+	"case-studies": PageTypeMarketing, "signup": PageTypeMarketing, "sign-up": PageTypeMarketing,
+
+	"legal": PageTypeLegal, "privacy": PageTypeLegal, "privacy-policy": PageTypeLegal,
+	"terms": PageTypeLegal, "terms-of-service": PageTypeLegal, "terms-of-use": PageTypeLegal,
+	"tos": PageTypeLegal, "cookies": PageTypeLegal, "cookie-policy": PageTypeLegal, "gdpr": PageTypeLegal,
+	"dpa": PageTypeLegal, "imprint": PageTypeLegal, "impressum": PageTypeLegal, "eula": PageTypeLegal,
+}
+
+// pageSignals scores how strongly a page looks like each page type.
+type pageSignals struct {
+	Docs      int `json:"docs"`
+	Marketing int `json:"marketing"`
+	Legal     int `json:"legal"`
+}
+
+// SetPageTypes makes the converter keep only pages classified as one of types
+// (PageTypeDocs, PageTypeMarketing, PageTypeLegal); other pages are skipped
+// with a warning. An empty list keeps every page, the default.
+//
+// Pages are classified from their URL path, structure (code blocks, docs
+// navigation, pricing tables, calls to action), and wording. Every converted
+// page records its type in the page_type frontmatter field.
+//
+// Returns an error if any type is unknown.
+func (c *Converter) SetPageTypes(types []string) error {
+	keep := make(map[string]bool)
+	for _, t := range types {
+		switch t {
+		case PageTypeDocs, PageTypeMarketing, PageTypeLegal:
+			keep[t] = true
+		default:
+			return fmt.Errorf("unknown page type %q (expected %s, %s, or %s)",
+				t, PageTypeDocs, PageTypeMarketing, PageTypeLegal)
+		}
+	}
+	if len(keep) == 0 {
+		keep = nil
+	}
+	c.pageTypes = keep
+	return nil
+}
+
+// keepsPageType reports whether pages of type t are converted.
+func (c *Converter) keepsPageType(t string) bool {
+	return c.pageTypes == nil || c.pageTypes[t]
+}
+
+// scorePage scores the structure and wording of a whole HTML document, before
+// any boilerplate is removed, since the navigation around a page is telling.
+func scorePage(doc *goquery.Document) pageSignals {
+	var s pageSignals
+	text := strings.ToLower(strings.Join(strings.Fields(doc.Find("body").Text()), " "))
+	title := strings.ToLower(doc.Find("title").First().Text() + " " + doc.Find("h1").First().Text())
+
+	// Documentation: code, docs navigation and furniture, docs generators
+	s.Docs += 2 * min(doc.Find("pre").Length(), 3)
+	s.Docs += min(doc.Find("code").Not("pre code").Length()/3, 2)
+	if doc.Find(docsChrome).Length() > 0 {
+		s.Docs += 2
+	}
+	if containsAny(text, docsPhrases) {
+		s.Docs++
+	}
+	generator := strings.ToLower(doc.Find(`meta[name="generator"]`).AttrOr("content", ""))
+	if containsAny(generator, docsGenerators) {
+		s.Docs += 2
+	}
+
+	// Marketing: prices, calls to action, landing page sections, lead forms
+	if pricePattern.MatchString(text) {
+		s.Marketing += 3
+	}
+	var calls []string
+	doc.Find("a, button").Each(func(_ int, sel *goquery.Selection) {
+		calls = append(calls, strings.ToLower(strings.Join(strings.Fields(sel.Text()), " ")))
+	})
+	s.Marketing += min(countMatches(strings.Join(calls, "\n"), marketingCalls), 3)
+	sections := make(map[string]bool)
+	doc.Find("[class]").Each(func(_ int, sel *goquery.Selection) {
+		words := strings.FieldsFunc(strings.ToLower(sel.AttrOr("class", "")), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, word := range words {
+			if marketingClasses[word] {
+				sections[word] = true
+			}
+		}
+	})
+	s.Marketing += min(len(sections), 3)
+	if doc.Find(`form input[type="email"]`).Length() > 0 {
+		s.Marketing++
+	}
+
+	// Legal: the title names a policy, the text reads like a contract
+	if containsAny(title, legalTitles) {
+		s.Legal += 4
+	}
+	s.Legal += min(countMatches(text, legalPhrases), 5)
+	return s
+}
+
+// scoreURL scores the path segments and host of a page's URL.
+func scoreURL(sourceURL string) pageSignals {
+	var s pageSignals
+	u, err := url.Parse(sourceURL)
+	if err != nil {
+		return s
+	}
+	if strings.HasPrefix(u.Hostname(), "docs.") {
+		s.Docs += 2
+	}
+	for _, segment := range strings.Split(strings.ToLower(u.Path), "/") {
+		switch pathTypes[strings.TrimSuffix(segment, ".html")] {
+		case PageTypeDocs:
+			s.Docs += 3
+		case PageTypeMarketing:
+			s.Marketing += 3
+		case PageTypeLegal:
+			s.Legal += 4
+		}
+	}
+	return s
+}
+
+// pageType classifies a page from its document signals and URL. A page is
+// marketing or legal when that score reaches pageTypeThreshold and beats the
+// documentation score; otherwise it is documentation.
+func (p page) pageType(sourceURL string) string {
+	u := scoreURL(sourceURL)
+	docs := p.Signals.Docs + u.Docs
+	marketing := p.Signals.Marketing + u.Marketing
+	legal := p.Signals.Legal + u.Legal
+
+	switch {
+	case legal >= pageTypeThreshold && legal > docs && legal >= marketing:
+		return PageTypeLegal
+	case marketing >= pageTypeThreshold && marketing > docs:
+		return PageTypeMarketing
+	}
+	return PageTypeDocs
+}
+
+// containsAny reports whether s contains any of the substrings.
+func containsAny(s string, substrings []string) bool {
+	return countMatches(s, substrings) > 0
+}
+
+// countMatches returns how many of the substrings occur in s.
+func countMatches(s string, substrings []string) int {
+	n := 0
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			n++
+		}
+	}
+	return n
+}
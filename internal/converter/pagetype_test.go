@@ -0,0 +1,138 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestPageType(t *testing.T) {
+	para := "<p>" + strings.Repeat("This section explains how the client works and what it sends. ", 6) + "</p>"
+	tests := []struct {
+		name string
+		url  string
+		html string
+		want string
+	}{
+		{
+			name: "reference page",
+			url:  "https://example.com/docs/api/tokens",
+			html: `<nav class="sidebar"><a href="/docs">Docs</a></nav><main><h1>Tokens</h1>` + para + `<pre><code>curl /tokens</code></pre></main>`,
+			want: PageTypeDocs,
+		},
+		{
+			name: "prose docs page without code",
+			url:  "https://example.com/concepts",
+			html: `<main><h1>Concepts</h1>` + para + `</main>`,
+			want: PageTypeDocs,
+		},
+		{
+			name: "pricing page",
+			url:  "https://example.com/pricing",
+			html: `<section class="hero"><h1>Simple pricing</h1><a class="btn">Start free trial</a></section>
+<div class="pricing-table"><p>Pro: $29/month</p><button>Contact sales</button></div>`,
+			want: PageTypeMarketing,
+		},
+		{
+			name: "landing page at the root",
+			url:  "https://example.com/",
+			html: `<section class="hero hero--primary"><h1>Ship faster</h1><a>Sign up</a><a>Book a demo</a></section>
+<section class="testimonials"><p>We love it.</p></section>` + para,
+			want: PageTypeMarketing,
+		},
+		{
+			name: "docs site home with a hero",
+			url:  "https://docs.example.com/",
+			html: `<meta name="generator" content="Docusaurus v3"><header class="hero"><h1>Example Docs</h1><a>Get started</a></header>` + para,
+			want: PageTypeDocs,
+		},
+		{
+			name: "privacy policy",
+			url:  "https://example.com/privacy",
+			html: `<title>Privacy Policy</title><main><h1>Privacy Policy</h1><p>Last updated: May 1. We process personal data and share it with third parties pursuant to the governing law.</p></main>`,
+			want: PageTypeLegal,
+		},
+		{
+			name: "terms under an unremarkable URL",
+			url:  "https://example.com/p/1234",
+			html: `<title>Terms of Service</title><main><p>The customer shall indemnify us. Our liability is limited.</p></main>`,
+			want: PageTypeLegal,
+		},
+		{
+			name: "docs page mentioning pricing",
+			url:  "https://example.com/docs/billing",
+			html: `<nav class="toc"></nav><main><h1>Billing API</h1><p>Plans start at $10/month.</p><pre><code>GET /invoices</code></pre><pre><code>GET /plans</code></pre></main>`,
+			want: PageTypeDocs,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html><body>" + tt.html + "</body></html>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			p := page{Signals: scorePage(doc)}
+			if got := p.pageType(tt.url); got != tt.want {
+				t.Errorf("pageType() = %q, want %q (signals %+v, URL %+v)", got, tt.want, p.Signals, scoreURL(tt.url))
+			}
+		})
+	}
+}
+
+func TestSetPageTypes(t *testing.T) {
+	dir := t.TempDir()
+	htmlPath := filepath.Join(dir, "pricing.html")
+	html := `<html><head><title>Pricing</title></head><body><article><h1>Pricing</h1>
+<p>` + strings.Repeat("Every plan includes unlimited projects, priority support, and a generous free tier. ", 6) + `</p>
+<div class="pricing-card"><p>Team: $49/month</p><a>Start free trial</a></div></article></body></html>`
+	if err := os.WriteFile(htmlPath, []byte(html), 0644); err != nil {
+		t.Fatal(err)
+	}
+	meta := PageMeta{SourceURL: "https://example.com/pricing", FetchedAt: "2024-01-01T00:00:00Z"}
+
+	tests := []struct {
+		name      string
+		types     []string
+		wantFile  bool
+		wantError bool
+	}{
+		{name: "default keeps every page", wantFile: true},
+		{name: "marketing kept", types: []string{PageTypeDocs, PageTypeMarketing}, wantFile: true},
+		{name: "docs only", types: []string{PageTypeDocs}},
+		{name: "unknown type", types: []string{"blog"}, wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			if err := c.SetPageTypes(tt.types); (err != nil) != tt.wantError {
+				t.Fatalf("SetPageTypes(%v) error = %v, wantError %v", tt.types, err, tt.wantError)
+			}
+			if tt.wantError {
+				return
+			}
+
+			outPath := filepath.Join(t.TempDir(), "pricing.md")
+			if err := c.ConvertPage(htmlPath, outPath, meta); err != nil {
+				t.Fatalf("ConvertPage() returned error: %v", err)
+			}
+			got, err := os.ReadFile(outPath)
+			if !tt.wantFile {
+				if err == nil {
+					t.Errorf("marketing page was written:\n%s", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("output missing: %v", err)
+			}
+			if !strings.Contains(string(got), "page_type: \"marketing\"\n") {
+				t.Errorf("frontmatter missing page type:\n%s", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the conversion of PDF documents, such as the manuals
+// linked from a docs site, whose text is extracted with the pdf package.
+package converter
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+	"github.com/f4ah6o/site2skill-go/internal/pdf"
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+)
+
+// ConvertPDF is like ConvertPage for a PDF document: its text is turned into
+// Markdown with the heading and page structure heuristics of
+// pdf.Document.Markdown. The title is that of the document information,
+// else its first H1 heading, else its file name; the title is added as an H1
+// heading if the text has none. The content hash is that of the file.
+//
+// Returns an error wrapping ErrConversionFailed if the document can't be
+// parsed or is encrypted or a Markdown transformer fails, or an error if the file can't be read or the
+// output can't be written. Logs a warning and returns nil if the document
+// has no text, as scanned documents don't.
+func (c *Converter) ConvertPDF(pdfPath, outputPath string, meta PageMeta) error {
+	content, err := os.ReadFile(pdfPath)
+	if err != nil {
+		return fmt.Errorf("failed to read PDF file: %w", err)
+	}
+	doc, err := pdf.Parse(content)
+	if err != nil {
+		return fmt.Errorf("%w: %w: %w", ErrConversionFailed, ErrParseFailed, err)
+	}
+
+	md := doc.Markdown()
+	if strings.TrimSpace(md) == "" {
+		warnlog.Printf("no_content", "Warning: No text found in %s", pdfPath)
+		return nil
+	}
+	p := page{Title: strings.TrimSpace(doc.Title), Markdown: md + "\n"}
+	hasH1 := false
+	for _, h := range Headings(p.Markdown) {
+		if h.Level == 1 {
+			hasH1 = true
+			if p.Title == "" {
+				p.Title = h.Text
+			}
+			break
+		}
+	}
+	if p.Title == "" {
+		p.Title = strings.TrimSuffix(filepath.Base(pdfPath), filepath.Ext(pdfPath))
+	}
+	if !hasH1 {
+		p.Markdown = "# " + p.Title + "\n\n" + p.Markdown
+	}
+	if pageType := p.pageType(meta.SourceURL); !c.keepsPageType(pageType) {
+		warnlog.Printf("page_type", "Skipping %s page %s", pageType, pdfPath)
+		return nil
+	}
+	if !c.keepsLanguage(&p, meta, pdfPath) {
+		return nil
+	}
+	if err := c.transformMarkdown(&p, meta); err != nil {
+		return fmt.Errorf("%w: %w", ErrConversionFailed, err)
+	}
+
+	if dir := filepath.Dir(outputPath); dir != "" && dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+	if _, err := atomicfile.WriteFile(outputPath, []byte(p.frontmatter(meta, contentHash(content))+p.Markdown)); err != nil {
+		return fmt.Errorf("failed to write markdown file: %w", err)
+	}
+	log.Printf("Converted: %s -> %s", pdfPath, outputPath)
+	return nil
+}
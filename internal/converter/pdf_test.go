@@ -0,0 +1,109 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testPDF returns a one-page PDF document drawing content with Helvetica as
+// F1, with the information dictionary info.
+func testPDF(info, content string) string {
+	return fmt.Sprintf(`%%PDF-1.4
+1 0 obj
+<< /Type /Catalog /Pages 2 0 R >>
+endobj
+2 0 obj
+<< /Type /Pages /Kids [3 0 R] /Count 1 >>
+endobj
+3 0 obj
+<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>
+endobj
+4 0 obj
+<< /Length %d >>
+stream
+%s
+endstream
+endobj
+5 0 obj
+<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>
+endobj
+6 0 obj
+%s
+endobj
+trailer
+<< /Root 1 0 R /Info 6 0 R >>
+%%%%EOF
+`, len(content), content, info)
+}
+
+func TestConvertPDF(t *testing.T) {
+	tests := []struct {
+		name     string
+		pdf      string
+		want     []string
+		wantNone bool
+		wantErr  error
+	}{
+		{
+			name: "title from the document information",
+			pdf:  testPDF("<< /Title (Widget Manual) >>", "BT /F1 20 Tf 72 700 Td (Overview) Tj ET BT /F1 10 Tf 72 670 Td (Widgets turn.) Tj ET"),
+			want: []string{`title: "Widget Manual"`, `source_url: "https://example.com/files/manual.pdf"`, "\n# Overview\n\nWidgets turn.\n"},
+		},
+		{
+			name: "title from the file name",
+			pdf:  testPDF("<< >>", "BT /F1 10 Tf 72 670 Td (Widgets turn.) Tj ET"),
+			want: []string{`title: "manual"`, "\n# manual\n\nWidgets turn.\n"},
+		},
+		{
+			name:     "no text",
+			pdf:      testPDF("<< >>", "0 0 m 10 10 l S"),
+			wantNone: true,
+		},
+		{
+			name:    "not a PDF",
+			pdf:     "<html></html>",
+			wantErr: ErrParseFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			pdfPath := filepath.Join(dir, "manual.pdf")
+			if err := os.WriteFile(pdfPath, []byte(tt.pdf), 0644); err != nil {
+				t.Fatal(err)
+			}
+			outputPath := filepath.Join(dir, "out", "manual.md")
+			meta := PageMeta{SourceURL: "https://example.com/files/manual.pdf", FetchedAt: "2024-01-01T00:00:00Z"}
+			err := New().ConvertPDF(pdfPath, outputPath, meta)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || !errors.Is(err, ErrConversionFailed) {
+					t.Errorf("ConvertPDF() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ConvertPDF() error = %v", err)
+			}
+			data, err := os.ReadFile(outputPath)
+			if tt.wantNone {
+				if err == nil {
+					t.Errorf("ConvertPDF() wrote %s for a document without text", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("ConvertPDF() wrote:\n%s\nwant it to contain %q", data, want)
+				}
+			}
+		})
+	}
+}
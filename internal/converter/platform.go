@@ -0,0 +1,225 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the extraction profiles of popular documentation
+// platforms, which know where each platform puts the content of a page and
+// which of its furniture to strip, so that their sites convert well without
+// a selector rule of their own.
+package converter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Special platform names of SetPlatform.
+const (
+	// PlatformAuto detects the platform of every page from its markup.
+	PlatformAuto = "auto"
+	// PlatformNone applies no platform profile.
+	PlatformNone = "none"
+)
+
+// Platform is the extraction profile of a documentation platform.
+type Platform struct {
+	// Name identifies the platform in options and inspections (e.g., "docusaurus").
+	Name string
+	// Generators are words of the generator meta tags of the platform's
+	// pages, in lower case (e.g., "docusaurus").
+	Generators []string
+	// Markers is a CSS selector group matching elements only the platform's
+	// pages have, for the pages without a generator meta tag.
+	Markers string
+	// ContentSelector selects the main content of the platform's pages, as
+	// SetContentSelector does; a content selector set explicitly wins.
+	ContentSelector string
+	// StripSelectors match the furniture removed from the platform's pages,
+	// such as breadcrumbs and "edit this page" links, besides the strip
+	// selectors set explicitly.
+	StripSelectors []string
+	// Pagination matches the previous and next page links of the platform,
+	// removed from the content like StripSelectors; the crawl still follows
+	// them, since it follows the links of the whole page.
+	Pagination []string
+	// LocaleAliases maps the locale codes the platform uses in its URLs to
+	// their canonical form (e.g., "zh-hans" to "zh-cn"), for locale priority
+	// mode.
+	LocaleAliases map[string]string
+}
+
+// Platforms are the built-in platform profiles, in the order they are
+// detected in.
+var Platforms = []Platform{
+	{
+		Name:            "docusaurus",
+		Generators:      []string{"docusaurus"},
+		Markers:         "#__docusaurus, .theme-doc-markdown",
+		ContentSelector: ".theme-doc-markdown",
+		StripSelectors: []string{
+			".theme-doc-breadcrumbs", ".theme-doc-version-badge", ".theme-doc-version-banner",
+			".theme-doc-toc-mobile", ".theme-doc-footer", ".theme-edit-this-page", ".theme-last-updated",
+		},
+		Pagination:    []string{".pagination-nav"},
+		LocaleAliases: map[string]string{"zh-hans": "zh-cn", "zh-hant": "zh-tw"},
+	},
+	{
+		Name:            "mkdocs",
+		Generators:      []string{"mkdocs"},
+		Markers:         ".md-content, [data-md-component]",
+		ContentSelector: `.md-content__inner, div[role="main"]`,
+		StripSelectors: []string{
+			".md-content__button", ".md-source-file", ".md-feedback", ".md-top", ".headerlink",
+		},
+		Pagination:    []string{".md-footer__inner", "ul.pager"},
+		LocaleAliases: map[string]string{"zh-hans": "zh-cn", "zh-hant": "zh-tw"},
+	},
+	{
+		Name:            "sphinx",
+		Generators:      []string{"sphinx", "docutils"},
+		Markers:         `.wy-nav-content, .sphinxsidebar, script[src*="documentation_options.js"]`,
+		ContentSelector: `div[itemprop="articleBody"], div.body[role="main"], article[role="main"]`,
+		StripSelectors: []string{
+			".wy-breadcrumbs", ".rst-versions", ".headerlink", "#furo-readthedocs-versions",
+			"readthedocs-flyout", ".edit-this-page", ".theme-switch-button",
+		},
+		Pagination: []string{".rst-footer-buttons", ".related-pages", ".prev-next-area", "div.related"},
+	},
+	{
+		Name:            "gitbook",
+		Generators:      []string{"gitbook"},
+		Markers:         `.book .book-body, .gitbook-root, [data-gb-custom-block]`,
+		ContentSelector: `section.markdown-section, main .page-body, main`,
+		StripSelectors: []string{
+			".book-header", ".page-footer", ".gitbook-link", `[aria-label="Edit on GitHub"]`,
+		},
+		Pagination: []string{".navigation-prev", ".navigation-next", `a[aria-label="Previous page"]`, `a[aria-label="Next page"]`},
+	},
+	{
+		Name:            "confluence",
+		Generators:      []string{"confluence"},
+		Markers:         `meta[name="confluence-request-time"], meta[name="ajs-page-id"], #com-atlassian-confluence`,
+		ContentSelector: "#main-content",
+		StripSelectors: []string{
+			"#likes-and-labels-container", ".page-metadata", "#comments-section",
+			".confluence-information-macro-icon", "#page-metadata-banner",
+		},
+	},
+	{
+		Name:            "notion",
+		Generators:      []string{"notion"},
+		Markers:         ".notion-page-content, #notion-app",
+		ContentSelector: ".notion-page-content",
+		StripSelectors: []string{
+			".notion-topbar", ".notion-page-controls", ".notion-collection-view-header",
+		},
+	},
+}
+
+// PlatformNames returns the names of the built-in platforms, sorted.
+func PlatformNames() []string {
+	names := make([]string, 0, len(Platforms))
+	for _, p := range Platforms {
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupPlatform returns the built-in platform named name, case-insensitively.
+//
+// Returns an error if there is none.
+func LookupPlatform(name string) (*Platform, error) {
+	for i := range Platforms {
+		if strings.EqualFold(Platforms[i].Name, strings.TrimSpace(name)) {
+			return &Platforms[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown platform %q (expected auto, none, or one of %s)", name, strings.Join(PlatformNames(), ", "))
+}
+
+// ParsePlatform returns name, PlatformAuto, PlatformNone, or the name of a
+// built-in platform, in lower case; "" is PlatformAuto.
+//
+// Returns an error if name is none of them.
+func ParsePlatform(name string) (string, error) {
+	switch normalized := strings.ToLower(strings.TrimSpace(name)); normalized {
+	case "", PlatformAuto:
+		return PlatformAuto, nil
+	case PlatformNone:
+		return PlatformNone, nil
+	default:
+		p, err := LookupPlatform(normalized)
+		if err != nil {
+			return "", err
+		}
+		return p.Name, nil
+	}
+}
+
+// PlatformLocaleAliases returns the locale aliases of the platform name (see
+// ParsePlatform): those of the named platform, those of every platform for
+// PlatformAuto, since the platform of the pages isn't known before they are
+// crawled, and none for PlatformNone.
+func PlatformLocaleAliases(name string) map[string]string {
+	aliases := make(map[string]string)
+	name, _ = ParsePlatform(name)
+	for _, p := range Platforms {
+		if name == PlatformAuto || name == p.Name {
+			for alias, canonical := range p.LocaleAliases {
+				aliases[alias] = canonical
+			}
+		}
+	}
+	return aliases
+}
+
+// DetectPlatform returns the built-in platform doc was built with, from its
+// generator meta tag or, failing that, the markers of the platforms, or nil
+// if it is none of them.
+func DetectPlatform(doc *goquery.Document) *Platform {
+	generator := strings.ToLower(doc.Find(`meta[name="generator"]`).AttrOr("content", ""))
+	if generator != "" {
+		for i := range Platforms {
+			if containsAny(generator, Platforms[i].Generators) {
+				return &Platforms[i]
+			}
+		}
+	}
+	for i := range Platforms {
+		if doc.Find(Platforms[i].Markers).Length() > 0 {
+			return &Platforms[i]
+		}
+	}
+	return nil
+}
+
+// SetPlatform sets the platform profile applied to the pages (see
+// ParsePlatform): PlatformAuto, the default, detects the platform of every
+// page (see DetectPlatform), a platform name applies its profile to every
+// page, and PlatformNone applies none. The profile of a page adds its strip
+// and pagination selectors to those of SetStripSelectors and selects the
+// main content unless SetContentSelector was called; Readability takes over
+// when its content selector matches nothing.
+//
+// Returns an error if name is unknown.
+func (c *Converter) SetPlatform(name string) error {
+	normalized, err := ParsePlatform(name)
+	if err != nil {
+		return err
+	}
+	c.platform = normalized
+	return nil
+}
+
+// pagePlatform returns the platform profile applied to doc, or nil.
+func (c *Converter) pagePlatform(doc *goquery.Document) *Platform {
+	switch c.platform {
+	case "", PlatformAuto:
+		return DetectPlatform(doc)
+	case PlatformNone:
+		return nil
+	}
+	p, _ := LookupPlatform(c.platform)
+	return p
+}
@@ -0,0 +1,157 @@
+package converter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+func TestDetectPlatform(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{name: "Docusaurus generator", html: `<head><meta name="generator" content="Docusaurus v3.1.0"></head>`, want: "docusaurus"},
+		{name: "Material for MkDocs generator", html: `<head><meta name="generator" content="mkdocs-1.5.3, mkdocs-material-9.5.2"></head>`, want: "mkdocs"},
+		{name: "Sphinx generator", html: `<head><meta name="generator" content="Docutils 0.19: https://docutils.sourceforge.io/"></head>`, want: "sphinx"},
+		{name: "Read the Docs theme", html: `<body><div class="wy-nav-content"><p>Text</p></div></body>`, want: "sphinx"},
+		{name: "legacy GitBook", html: `<body><div class="book"><div class="book-body"></div></div></body>`, want: "gitbook"},
+		{name: "Confluence", html: `<head><meta name="ajs-page-id" content="12345"></head><body><div id="main-content"></div></body>`, want: "confluence"},
+		{name: "Notion", html: `<body><div class="notion-page-content"></div></body>`, want: "notion"},
+		{name: "unknown generator with markers", html: `<head><meta name="generator" content="Hugo 0.120"></head><body><div id="__docusaurus"></div></body>`, want: "docusaurus"},
+		{name: "none", html: `<head><meta name="generator" content="Hugo 0.120"></head><body><main>Text</main></body>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := goquery.NewDocumentFromReader(strings.NewReader("<html>" + tt.html + "</html>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ""
+			if p := DetectPlatform(doc); p != nil {
+				got = p.Name
+			}
+			if got != tt.want {
+				t.Errorf("DetectPlatform() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePlatform(t *testing.T) {
+	for in, want := range map[string]string{"": PlatformAuto, "Auto": PlatformAuto, "none": PlatformNone, " Sphinx ": "sphinx"} {
+		if got, err := ParsePlatform(in); err != nil || got != want {
+			t.Errorf("ParsePlatform(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParsePlatform("hugo"); err == nil {
+		t.Error("ParsePlatform(\"hugo\") error = nil, want an error")
+	}
+}
+
+func TestPlatformLocaleAliases(t *testing.T) {
+	tests := []struct {
+		platform string
+		want     map[string]string
+	}{
+		{platform: "docusaurus", want: map[string]string{"zh-hans": "zh-cn", "zh-hant": "zh-tw"}},
+		{platform: "", want: map[string]string{"zh-hans": "zh-cn", "zh-hant": "zh-tw"}},
+		{platform: "confluence", want: map[string]string{}},
+		{platform: PlatformNone, want: map[string]string{}},
+	}
+	for _, tt := range tests {
+		if got := PlatformLocaleAliases(tt.platform); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("PlatformLocaleAliases(%q) = %v, want %v", tt.platform, got, tt.want)
+		}
+	}
+}
+
+func TestInspectPlatform(t *testing.T) {
+	html := []byte(`<html><head><title>Install | Docs</title>
+<meta name="generator" content="Docusaurus v3.1.0"></head><body>
+<div id="__docusaurus"><nav class="navbar">Docs Blog GitHub</nav>
+<main><article>
+<nav class="theme-doc-breadcrumbs">Home / Guides / Install</nav>
+<div class="theme-doc-markdown markdown"><h1>Install</h1>
+<p>Download the latest release for your platform and run the installer.</p></div>
+<footer class="theme-doc-footer"><a class="theme-edit-this-page" href="#">Edit this page</a></footer>
+<nav class="pagination-nav"><a href="/intro">Previous Introduction</a><a href="/usage">Next Usage</a></nav>
+</article></main></div></body></html>`)
+
+	tests := []struct {
+		name           string
+		platform       string
+		content        string
+		wantPlatform   string
+		wantExtraction string
+		wantContainer  string
+		wantMarkdown   []string
+		wantNot        []string
+	}{
+		{
+			name:           "detected",
+			wantPlatform:   "docusaurus",
+			wantExtraction: ExtractionPlatform,
+			wantContainer:  "docusaurus",
+			wantMarkdown:   []string{"# Install", "Download the latest release"},
+			wantNot:        []string{"Home / Guides", "Edit this page", "Next Usage"},
+		},
+		{
+			name:           "content selector wins",
+			content:        "article",
+			wantPlatform:   "docusaurus",
+			wantExtraction: ExtractionContentSelector,
+			wantMarkdown:   []string{"Download the latest release"},
+			wantNot:        []string{"Home / Guides", "Next Usage"},
+		},
+		{
+			name:           "forced",
+			platform:       "mkdocs",
+			wantPlatform:   "mkdocs",
+			wantExtraction: ExtractionFallback,
+			wantContainer:  "main",
+			wantMarkdown:   []string{"Download the latest release"},
+		},
+		{
+			name:           "none",
+			platform:       PlatformNone,
+			wantExtraction: ExtractionFallback,
+			wantContainer:  "main",
+			wantMarkdown:   []string{"Download the latest release"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			if err := c.SetPlatform(tt.platform); err != nil {
+				t.Fatal(err)
+			}
+			if tt.content != "" {
+				if err := c.SetContentSelector(tt.content); err != nil {
+					t.Fatal(err)
+				}
+			}
+			in, err := c.Inspect(html, PageMeta{SourceURL: "https://docs.example.com/install", FetchedAt: "2024-01-01T00:00:00Z"})
+			if err != nil {
+				t.Fatalf("Inspect() returned error: %v", err)
+			}
+			if in.Platform != tt.wantPlatform || in.Extraction != tt.wantExtraction || in.Container != tt.wantContainer {
+				t.Errorf("Platform, Extraction, Container = %q, %q, %q, want %q, %q, %q",
+					in.Platform, in.Extraction, in.Container, tt.wantPlatform, tt.wantExtraction, tt.wantContainer)
+			}
+			for _, want := range tt.wantMarkdown {
+				if !strings.Contains(in.Markdown, want) {
+					t.Errorf("Markdown doesn't contain %q:\n%s", want, in.Markdown)
+				}
+			}
+			for _, unwanted := range tt.wantNot {
+				if strings.Contains(in.Markdown, unwanted) {
+					t.Errorf("Markdown contains %q:\n%s", unwanted, in.Markdown)
+				}
+			}
+		})
+	}
+}
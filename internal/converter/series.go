@@ -0,0 +1,50 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the stitching of the pages of a paginated article or
+// listing into one document (see Converter.ConvertSeries).
+package converter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// appendPage appends the content of next, the following page of the same
+// article or listing, to p: its Markdown, without the title heading it
+// repeats from p, with its tables numbered after those of p, and its heading
+// anchors and tags not already recorded by p.
+func (p *page) appendPage(next page) {
+	markdown := next.Markdown
+	first, rest, _ := strings.Cut(markdown, "\n")
+	if title, _, _ := strings.Cut(p.Markdown, "\n"); strings.HasPrefix(title, "# ") && strings.TrimSpace(first) == strings.TrimSpace(title) {
+		markdown = strings.TrimLeft(rest, "\n")
+	}
+	// Renumbered from the last table, so that no link is renumbered twice
+	for i := len(next.Tables); i >= 1; i-- {
+		markdown = strings.ReplaceAll(markdown,
+			fmt.Sprintf("](%s%d)", tableLinkPrefix, i),
+			fmt.Sprintf("](%s%d)", tableLinkPrefix, i+len(p.Tables)))
+	}
+	p.Tables = append(p.Tables, next.Tables...)
+	if strings.TrimSpace(markdown) != "" {
+		p.Markdown = strings.TrimRight(p.Markdown, "\n") + "\n\n" + markdown
+	}
+
+	for fragment, id := range next.Anchors {
+		if _, ok := p.Anchors[fragment]; !ok {
+			if p.Anchors == nil {
+				p.Anchors = make(map[string]string)
+			}
+			p.Anchors[fragment] = id
+		}
+	}
+	seen := make(map[string]bool, len(p.Tags))
+	for _, tag := range p.Tags {
+		seen[strings.ToLower(tag)] = true
+	}
+	for _, tag := range next.Tags {
+		if !seen[strings.ToLower(tag)] {
+			seen[strings.ToLower(tag)] = true
+			p.Tags = append(p.Tags, tag)
+		}
+	}
+}
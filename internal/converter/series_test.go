@@ -0,0 +1,78 @@
+package converter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConvertSeries(t *testing.T) {
+	dir := t.TempDir()
+	pages := []string{
+		`<html><head><title>Guide</title><meta name="keywords" content="guide"></head><body><main><h1>Guide</h1><p>Part one.</p></main></body></html>`,
+		`<html><head><title>Guide - Page 2</title><meta name="keywords" content="Guide, setup"></head><body><main><h1>Guide</h1><h2>Setup</h2><p>Part two.</p></main></body></html>`,
+	}
+	var paths []string
+	var all []byte
+	for i, html := range pages {
+		path := filepath.Join(dir, fmt.Sprintf("page%d.html", i+1))
+		if err := os.WriteFile(path, []byte(html), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+		all = append(all, html...)
+	}
+
+	out := filepath.Join(dir, "guide.md")
+	if err := New().ConvertSeries(paths, out, PageMeta{SourceURL: "https://example.com/guide", FetchedAt: "2024-01-01T00:00:00Z"}); err != nil {
+		t.Fatalf("ConvertSeries() returned error: %v", err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(data)
+	sum := sha256.Sum256(all)
+	for _, want := range []string{
+		`title: "Guide"`,
+		`content_hash: "sha256:` + hex.EncodeToString(sum[:]) + `"`,
+		"tags:\n  - \"guide\"\n  - \"setup\"\n",
+		"# Guide\n\nPart one.\n\n## Setup\n\nPart two.",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output doesn't contain %q:\n%s", want, got)
+		}
+	}
+
+	if err := New().ConvertSeries(nil, out, PageMeta{}); err == nil {
+		t.Error("ConvertSeries() without pages returned no error")
+	}
+}
+
+func TestAppendPage(t *testing.T) {
+	p := page{
+		Markdown: "# Changelog\n\nSee [table](" + tableLinkPrefix + "1).\n",
+		Tables:   []string{"a\n"},
+		Anchors:  map[string]string{"v2": "v2"},
+	}
+	p.appendPage(page{
+		Markdown: "# Changelog\n\n## v1\n\n[First](" + tableLinkPrefix + "1) and [second](" + tableLinkPrefix + "2).",
+		Tables:   []string{"b\n", "c\n"},
+		Anchors:  map[string]string{"v2": "v2-1", "v1": "v1"},
+	})
+	want := "# Changelog\n\nSee [table](" + tableLinkPrefix + "1).\n\n## v1\n\n[First](" + tableLinkPrefix + "2) and [second](" + tableLinkPrefix + "3)."
+	if p.Markdown != want {
+		t.Errorf("Markdown = %q, want %q", p.Markdown, want)
+	}
+	if !reflect.DeepEqual(p.Tables, []string{"a\n", "b\n", "c\n"}) {
+		t.Errorf("Tables = %q", p.Tables)
+	}
+	if !reflect.DeepEqual(p.Anchors, map[string]string{"v2": "v2", "v1": "v1"}) {
+		t.Errorf("Anchors = %v", p.Anchors)
+	}
+}
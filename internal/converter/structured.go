@@ -0,0 +1,403 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the extraction of the structured data of a page: its
+// schema.org JSON-LD (Article, FAQPage, HowTo, and SoftwareApplication) and
+// its OpenGraph metadata, which are written to the frontmatter, and for FAQ
+// and how-to pages rendered as Markdown sections, since they are more reliable
+// than the rendered markup.
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// structuredData is the structured data of a page.
+type structuredData struct {
+	// Types are the schema.org types declared in the JSON-LD of the page, in
+	// order (e.g., "TechArticle", "FAQPage").
+	Types []string `json:"types,omitempty"`
+	// Author, Published, and Modified are the author and the publication and
+	// modification dates of the Article of the page.
+	Author    string `json:"author,omitempty"`
+	Published string `json:"published,omitempty"`
+	Modified  string `json:"modified,omitempty"`
+	// Software describes the SoftwareApplication of the page.
+	Software *software `json:"software,omitempty"`
+	// FAQ lists the questions of the FAQPage of the page, with their answers.
+	FAQ []faqEntry `json:"faq,omitempty"`
+	// HowTo is the HowTo of the page.
+	HowTo *howTo `json:"how_to,omitempty"`
+	// OGType, Image, and SiteName are the og:type, og:image, and
+	// og:site_name of the page.
+	OGType   string `json:"og_type,omitempty"`
+	Image    string `json:"image,omitempty"`
+	SiteName string `json:"site_name,omitempty"`
+}
+
+// software describes a schema.org SoftwareApplication.
+type software struct {
+	Name            string `json:"name,omitempty"`
+	Version         string `json:"version,omitempty"`
+	OperatingSystem string `json:"operating_system,omitempty"`
+	Category        string `json:"category,omitempty"`
+}
+
+// faqEntry is a question of a schema.org FAQPage and its accepted answer, as
+// HTML.
+type faqEntry struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+// howTo is a schema.org HowTo: its name and steps, in order.
+type howTo struct {
+	Name  string      `json:"name,omitempty"`
+	Steps []howToStep `json:"steps"`
+}
+
+// howToStep is a step of a HowTo: its name and text, as HTML, and the name of
+// the HowToSection it belongs to, if any.
+type howToStep struct {
+	Section string `json:"section,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Text    string `json:"text,omitempty"`
+}
+
+// readStructuredData fills in the structured data of p from doc: the
+// OpenGraph properties of its head and the schema.org objects of its JSON-LD
+// scripts, the first of each type of interest counting. Malformed scripts are
+// ignored.
+func (p *page) readStructuredData(doc *goquery.Document) {
+	sd := &p.Structured
+	sd.OGType = metaContent(doc, `meta[property="og:type"]`)
+	sd.Image = metaContent(doc, `meta[property="og:image"]`)
+	sd.SiteName = metaContent(doc, `meta[property="og:site_name"]`)
+
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, script *goquery.Selection) {
+		var data any
+		if json.Unmarshal([]byte(script.Text()), &data) != nil {
+			return
+		}
+		for _, obj := range jsonLDObjects(data) {
+			sd.add(obj)
+		}
+	})
+}
+
+// jsonLDObjects returns the typed objects of data, decoded JSON-LD: the
+// object itself, the objects of an array or a @graph, and the main entity of
+// a page (as FAQPages declared as the mainEntity of a WebPage).
+func jsonLDObjects(data any) []map[string]any {
+	switch v := data.(type) {
+	case []any:
+		var objs []map[string]any
+		for _, item := range v {
+			objs = append(objs, jsonLDObjects(item)...)
+		}
+		return objs
+	case map[string]any:
+		var objs []map[string]any
+		if len(jsonLDTypes(v)) > 0 {
+			objs = append(objs, v)
+		}
+		objs = append(objs, jsonLDObjects(v["@graph"])...)
+		if entity, ok := v["mainEntity"].(map[string]any); ok {
+			objs = append(objs, jsonLDObjects(entity)...)
+		}
+		return objs
+	}
+	return nil
+}
+
+// jsonLDTypes returns the @type of obj, a string or an array of strings.
+func jsonLDTypes(obj map[string]any) []string {
+	switch t := obj["@type"].(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var types []string
+		for _, item := range t {
+			if s, ok := item.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// add records the schema.org object obj, if of a type of interest not yet
+// recorded.
+func (sd *structuredData) add(obj map[string]any) {
+	for _, t := range jsonLDTypes(obj) {
+		if !containsFold(sd.Types, t) {
+			sd.Types = append(sd.Types, t)
+		}
+		switch {
+		case isArticleType(t):
+			if sd.Author == "" && sd.Published == "" && sd.Modified == "" {
+				sd.Author = jsonLDNames(obj["author"])
+				sd.Published = jsonLDText(obj["datePublished"])
+				sd.Modified = jsonLDText(obj["dateModified"])
+			}
+		case t == "SoftwareApplication" || t == "WebApplication" || t == "MobileApplication":
+			if sd.Software == nil {
+				sw := &software{
+					Name:            jsonLDText(obj["name"]),
+					Version:         jsonLDText(obj["softwareVersion"]),
+					OperatingSystem: jsonLDText(obj["operatingSystem"]),
+					Category:        jsonLDText(obj["applicationCategory"]),
+				}
+				if *sw != (software{}) {
+					sd.Software = sw
+				}
+			}
+		case t == "FAQPage":
+			if sd.FAQ == nil {
+				sd.FAQ = faqEntries(obj["mainEntity"])
+			}
+		case t == "HowTo":
+			if sd.HowTo == nil {
+				h := &howTo{Name: jsonLDText(obj["name"]), Steps: howToSteps(obj["step"], "")}
+				if len(h.Steps) > 0 {
+					sd.HowTo = h
+				}
+			}
+		}
+	}
+}
+
+// isArticleType reports whether t is schema.org Article or one of its
+// subtypes documentation sites declare.
+func isArticleType(t string) bool {
+	return strings.HasSuffix(t, "Article") || t == "BlogPosting"
+}
+
+// faqEntries returns the questions of the mainEntity of a FAQPage with their
+// accepted answer, or else their first suggested one.
+func faqEntries(entity any) []faqEntry {
+	var entries []faqEntry
+	for _, q := range jsonLDList(entity) {
+		question := jsonLDText(q["name"])
+		answers := jsonLDList(q["acceptedAnswer"])
+		if len(answers) == 0 {
+			answers = jsonLDList(q["suggestedAnswer"])
+		}
+		if question == "" || len(answers) == 0 {
+			continue
+		}
+		if answer := strings.TrimSpace(jsonLDString(answers[0]["text"])); answer != "" {
+			entries = append(entries, faqEntry{Question: question, Answer: answer})
+		}
+	}
+	return entries
+}
+
+// howToSteps returns the steps of the step property of a HowTo, or of the
+// itemListElement of a HowToSection named section: HowToSteps, their
+// sections flattened, and steps given as text.
+func howToSteps(value any, section string) []howToStep {
+	var steps []howToStep
+	items, ok := value.([]any)
+	if !ok {
+		items = []any{value}
+	}
+	for _, item := range items {
+		switch v := item.(type) {
+		case string:
+			if text := strings.TrimSpace(v); text != "" {
+				steps = append(steps, howToStep{Section: section, Text: text})
+			}
+		case map[string]any:
+			if containsFold(jsonLDTypes(v), "HowToSection") {
+				steps = append(steps, howToSteps(v["itemListElement"], jsonLDText(v["name"]))...)
+				continue
+			}
+			step := howToStep{Section: section, Name: jsonLDText(v["name"]), Text: strings.TrimSpace(jsonLDString(v["text"]))}
+			if step.Text == "" {
+				// Steps may hold their directions as a list of their own
+				var texts []string
+				for _, direction := range jsonLDList(v["itemListElement"]) {
+					if text := strings.TrimSpace(jsonLDString(direction["text"])); text != "" {
+						texts = append(texts, text)
+					}
+				}
+				step.Text = strings.Join(texts, " ")
+			}
+			if step.Name != "" || step.Text != "" {
+				steps = append(steps, step)
+			}
+		}
+	}
+	return steps
+}
+
+// jsonLDList returns value, an object or an array of objects, as a list of
+// objects.
+func jsonLDList(value any) []map[string]any {
+	switch v := value.(type) {
+	case map[string]any:
+		return []map[string]any{v}
+	case []any:
+		var objs []map[string]any
+		for _, item := range v {
+			if obj, ok := item.(map[string]any); ok {
+				objs = append(objs, obj)
+			}
+		}
+		return objs
+	}
+	return nil
+}
+
+// jsonLDString returns value if it is a string, or "".
+func jsonLDString(value any) string {
+	s, _ := value.(string)
+	return s
+}
+
+// jsonLDText returns value, a string or a number, with its whitespace
+// normalized, or "".
+func jsonLDText(value any) string {
+	switch v := value.(type) {
+	case string:
+		return strings.Join(strings.Fields(v), " ")
+	case float64:
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// jsonLDNames returns the names of value, a person or organization given as
+// a name, an object with a name, or an array of them, separated by commas.
+func jsonLDNames(value any) string {
+	switch v := value.(type) {
+	case string:
+		return jsonLDText(v)
+	case map[string]any:
+		return jsonLDText(v["name"])
+	case []any:
+		var names []string
+		for _, item := range v {
+			if name := jsonLDNames(item); name != "" {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
+
+// containsFold reports whether values holds s, case-insensitively.
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// structuredSections returns the Markdown sections rendering the FAQ and
+// HowTo of sd that markdown, the converted content of the page, lacks: the
+// questions of the FAQ missing from it under "Frequently Asked Questions",
+// and the steps of the HowTo under its name unless the content already holds
+// every one of them. Returns "" if there is nothing to add.
+func (c *Converter) structuredSections(sd structuredData, markdown string) string {
+	content := normalizeForMatch(markdown)
+	var b strings.Builder
+	var faq []faqEntry
+	for _, entry := range sd.FAQ {
+		if !strings.Contains(content, normalizeForMatch(entry.Question)) {
+			faq = append(faq, entry)
+		}
+	}
+	if len(faq) > 0 {
+		b.WriteString("\n\n## Frequently Asked Questions\n")
+		for _, entry := range faq {
+			fmt.Fprintf(&b, "\n### %s\n\n%s\n", entry.Question, c.htmlText(entry.Answer))
+		}
+	}
+
+	if sd.HowTo != nil && !c.stepsPresent(sd.HowTo.Steps, content) {
+		name := sd.HowTo.Name
+		if name == "" {
+			name = "Steps"
+		}
+		fmt.Fprintf(&b, "\n\n## %s\n", name)
+		section, n := "\x00", 0
+		for _, step := range sd.HowTo.Steps {
+			if step.Section != section {
+				section, n = step.Section, 0
+				if section != "" {
+					fmt.Fprintf(&b, "\n### %s\n", section)
+				}
+				b.WriteString("\n")
+			}
+			n++
+			text := c.htmlText(step.Text)
+			switch {
+			case step.Name == "" || normalizeForMatch(step.Name) == normalizeForMatch(text):
+				fmt.Fprintf(&b, "%d. %s\n", n, oneLineText(orString(text, step.Name)))
+			case text == "":
+				fmt.Fprintf(&b, "%d. %s\n", n, step.Name)
+			default:
+				fmt.Fprintf(&b, "%d. **%s**: %s\n", n, step.Name, oneLineText(text))
+			}
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return b.String() + "\n"
+}
+
+// stepsPresent reports whether content, normalized (see normalizeForMatch),
+// holds the text, or else the name, of every step.
+func (c *Converter) stepsPresent(steps []howToStep, content string) bool {
+	for _, step := range steps {
+		text := normalizeForMatch(c.htmlText(step.Text))
+		if text == "" {
+			text = normalizeForMatch(step.Name)
+		}
+		if !strings.Contains(content, text) {
+			return false
+		}
+	}
+	return true
+}
+
+// htmlText returns s, text that may hold HTML, as Markdown.
+func (c *Converter) htmlText(s string) string {
+	if !strings.Contains(s, "<") {
+		return strings.Join(strings.Fields(s), " ")
+	}
+	markdown, err := c.mdConverter.ConvertString(s)
+	if err != nil {
+		return strings.Join(strings.Fields(s), " ")
+	}
+	return strings.TrimSpace(markdown)
+}
+
+// normalizeForMatch lowercases s and removes its Markdown emphasis and extra
+// whitespace, for finding a text in the converted content.
+func normalizeForMatch(s string) string {
+	s = strings.NewReplacer("*", "", "_", "", "`", "").Replace(strings.ToLower(s))
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// oneLineText joins the lines of s, so that it fits a list item.
+func oneLineText(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// orString returns s, or fallback if s is empty.
+func orString(s, fallback string) string {
+	if s != "" {
+		return s
+	}
+	return fallback
+}
@@ -0,0 +1,118 @@
+package converter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadStructuredData(t *testing.T) {
+	para := "<p>" + strings.Repeat("Install the command line tool and configure it for the account you use. ", 8) + "</p>"
+	tests := []struct {
+		name         string
+		head         string
+		wantTypes    []string
+		wantFields   []string
+		wantMarkdown []string
+		notMarkdown  []string
+	}{
+		{
+			name: "article and OpenGraph",
+			head: `<meta property="og:type" content="article"><meta property="og:image" content="/img/cover.png">` +
+				`<meta property="og:site_name" content="Example Docs">` +
+				`<script type="application/ld+json">{"@context":"https://schema.org","@graph":[` +
+				`{"@type":"WebSite","name":"Example"},` +
+				`{"@type":"TechArticle","author":[{"@type":"Person","name":"Ada"},{"@type":"Person","name":"Grace"}],` +
+				`"datePublished":"2024-01-02","dateModified":"2024-03-04"}]}</script>`,
+			wantTypes: []string{"WebSite", "TechArticle"},
+			wantFields: []string{
+				`author: "Ada, Grace"`,
+				`published: "2024-01-02"`,
+				`modified_at: "2024-03-04"`,
+				`og_type: "article"`,
+				`image: "https://docs.example.com/img/cover.png"`,
+				`site_name: "Example Docs"`,
+				"schema_types:\n  - \"WebSite\"\n  - \"TechArticle\"\n",
+			},
+		},
+		{
+			name: "FAQ page",
+			head: `<script type="application/ld+json">{"@type":"WebPage","mainEntity":{"@type":"FAQPage","mainEntity":[` +
+				`{"@type":"Question","name":"Is it free?","acceptedAnswer":{"@type":"Answer","text":"Yes, <strong>always</strong>."}},` +
+				`{"@type":"Question","name":"Install the command line tool","acceptedAnswer":{"@type":"Answer","text":"See above."}},` +
+				`{"@type":"Question","name":"Unanswered?"}]}}</script>`,
+			wantTypes:    []string{"WebPage", "FAQPage"},
+			wantMarkdown: []string{"## Frequently Asked Questions\n\n### Is it free?\n\nYes, **always**.\n"},
+			notMarkdown:  []string{"See above.", "Unanswered?"},
+		},
+		{
+			name: "how-to with sections",
+			head: `<script type="application/ld+json">[{"@type":"HowTo","name":"Set up the CLI","step":[` +
+				`{"@type":"HowToSection","name":"Install","itemListElement":[` +
+				`{"@type":"HowToStep","name":"Download","text":"Download the archive."},` +
+				`{"@type":"HowToStep","text":"Unpack it."}]},` +
+				`{"@type":"HowToSection","name":"Configure","itemListElement":[{"@type":"HowToStep","text":"Run <code>cli init</code>."}]}]}]</script>`,
+			wantTypes: []string{"HowTo"},
+			wantMarkdown: []string{
+				"## Set up the CLI\n\n### Install\n\n1. **Download**: Download the archive.\n2. Unpack it.\n\n### Configure\n\n1. Run `cli init`.\n",
+			},
+		},
+		{
+			name:        "how-to already in the content",
+			head:        `<script type="application/ld+json">{"@type":"HowTo","step":["Install the command line tool"]}</script>`,
+			wantTypes:   []string{"HowTo"},
+			notMarkdown: []string{"## Steps"},
+		},
+		{
+			name: "software application",
+			head: `<script type="application/ld+json">{"@type":"SoftwareApplication","name":"Example CLI","softwareVersion":"2.1.0",` +
+				`"operatingSystem":"Linux, macOS","applicationCategory":"DeveloperApplication"}</script>`,
+			wantTypes: []string{"SoftwareApplication"},
+			wantFields: []string{
+				"software:\n  name: \"Example CLI\"\n  version: \"2.1.0\"\n  operating_system: \"Linux, macOS\"\n  category: \"DeveloperApplication\"\n",
+			},
+		},
+		{
+			name: "malformed JSON-LD",
+			head: `<script type="application/ld+json">{"@type": "FAQPage",</script>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html := `<html><head><title>Setup</title>` + tt.head + `</head><body><article><h1>Setup</h1>` + para + `</article></body></html>`
+			p, err := New().convertHTML([]byte(html), "page.html")
+			if err != nil {
+				t.Fatalf("convertHTML returned error: %v", err)
+			}
+			if !reflect.DeepEqual(p.Structured.Types, tt.wantTypes) {
+				t.Errorf("Types = %q, want %q", p.Structured.Types, tt.wantTypes)
+			}
+			front := p.frontmatter(PageMeta{SourceURL: "https://docs.example.com/guide/setup"}, "")
+			for _, want := range tt.wantFields {
+				if !strings.Contains(front, want) {
+					t.Errorf("frontmatter lacks %q:\n%s", want, front)
+				}
+			}
+			for _, want := range tt.wantMarkdown {
+				if !strings.Contains(p.Markdown, want) {
+					t.Errorf("Markdown lacks %q:\n%s", want, p.Markdown)
+				}
+			}
+			for _, unwanted := range tt.notMarkdown {
+				if strings.Contains(p.Markdown, unwanted) {
+					t.Errorf("Markdown holds %q:\n%s", unwanted, p.Markdown)
+				}
+			}
+		})
+	}
+}
+
+func TestStructuredDataPrecedence(t *testing.T) {
+	p := page{Title: "Post", Structured: structuredData{Author: "Ada", Published: "2024-01-02", Modified: "2024-03-04"}}
+	front := p.frontmatter(PageMeta{SourceURL: "https://example.com/post", Author: "Grace", ModifiedAt: "2024-05-06"}, "")
+	for _, want := range []string{`author: "Grace"`, `published: "2024-01-02"`, `modified_at: "2024-05-06"`} {
+		if !strings.Contains(front, want) {
+			t.Errorf("frontmatter lacks %q:\n%s", want, front)
+		}
+	}
+}
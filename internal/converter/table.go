@@ -0,0 +1,412 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements the conversion of HTML tables to GFM tables.
+package converter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+
+	md "github.com/JohannesKaufmann/html-to-markdown"
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Table fallbacks select how tables that can't be represented as a GFM table are rendered.
+const (
+	// TableFallbackHTML keeps the table as a cleaned-up HTML block (the default).
+	TableFallbackHTML = "html"
+	// TableFallbackList renders each row as a list of "header: value" entries.
+	TableFallbackList = "list"
+)
+
+// DefaultTableCSVRows is the default number of body rows above which a table is
+// also saved as CSV; see SetTableCSVRows.
+const DefaultTableCSVRows = 50
+
+// tableLinkPrefix marks the link to a table's CSV file until ConvertPage names
+// the file after the page (see page.Tables).
+const tableLinkPrefix = "site2skill-table:"
+
+// blockSelector matches cell content that a GFM table cell cannot hold.
+const blockSelector = "ul, ol, dl, pre, table, blockquote, h1, h2, h3, h4, h5, h6, hr"
+
+// keptTableAttributes are the attributes preserved when a table falls back to HTML.
+var keptTableAttributes = map[string]bool{
+	"colspan": true, "rowspan": true, "scope": true, "align": true,
+	"href": true, "src": true, "alt": true, "title": true,
+}
+
+// SetTableFallback chooses how tables that can't be represented as a GFM table
+// are rendered: TableFallbackHTML or TableFallbackList.
+//
+// A table can be represented as GFM when it has at most one header row, no cell
+// spans more than one row or column, and no cell holds block content such as a
+// list, code block, or nested table. Tables without a header row use their first
+// row as the header.
+//
+// Returns an error for an unknown fallback.
+func (c *Converter) SetTableFallback(fallback string) error {
+	switch fallback {
+	case TableFallbackHTML, TableFallbackList:
+		c.tableFallback = fallback
+		return nil
+	}
+	return fmt.Errorf("unknown table fallback %q (expected %s or %s)", fallback, TableFallbackHTML, TableFallbackList)
+}
+
+// SetTableCSVRows makes the converter save every table with more than rows body
+// rows as a CSV file as well, in the assets/ folder next to the Markdown file
+// (<page>-table-N.csv), and link it above the table. Large data tables such as
+// compatibility matrices are easier to read, and cheaper to load, as CSV.
+// Cell text is written without formatting; cells spanning several rows or
+// columns are repeated in each. A value of 0 disables CSV files, the default.
+func (c *Converter) SetTableCSVRows(rows int) {
+	c.tableCSVRows = max(rows, 0)
+}
+
+// tableCell is one th or td element of a table.
+type tableCell struct {
+	sel    *goquery.Selection
+	header bool
+}
+
+// tableGrid is a table laid out on a grid, with spanning cells occupying every
+// slot they cover.
+type tableGrid struct {
+	// rows holds the cells of each row, one per column
+	rows [][]*tableCell
+	// headerRows is the number of leading rows forming the table header
+	headerRows int
+	// spans reports whether any cell spans several rows or columns
+	spans bool
+}
+
+// convertTable is the html-to-markdown rule for table elements.
+func (c *Converter) convertTable(_ string, table *goquery.Selection, _ *md.Options) *string {
+	grid := layoutTable(table)
+	if len(grid.rows) == 0 {
+		empty := ""
+		return &empty
+	}
+
+	var b strings.Builder
+	b.WriteString("\n\n")
+	if caption := strings.TrimSpace(c.mdConverter.Convert(table.ChildrenFiltered("caption"))); caption != "" {
+		b.WriteString(caption + "\n\n")
+	}
+	if c.tableCSVRows > 0 && len(grid.rows)-grid.headerRows > c.tableCSVRows {
+		c.tables = append(c.tables, grid.csv())
+		fmt.Fprintf(&b, "*This table is also available as [CSV](%s%d).*\n\n", tableLinkPrefix, len(c.tables))
+	}
+	switch {
+	case grid.isGFM():
+		c.writeGFMTable(&b, grid)
+	case c.tableFallback == TableFallbackList:
+		c.writeTableList(&b, grid)
+	default:
+		writeTableHTML(&b, table)
+	}
+	b.WriteString("\n\n")
+
+	result := b.String()
+	return &result
+}
+
+// layoutTable places the cells of a table on a grid. Rows come from thead, then
+// tbody (and rows directly under the table), then tfoot. The header is the thead
+// rows or, without a thead, the leading rows made only of th cells.
+func layoutTable(table *goquery.Selection) *tableGrid {
+	var head, body, foot []*goquery.Selection
+	table.Children().Each(func(_ int, child *goquery.Selection) {
+		switch goquery.NodeName(child) {
+		case "thead":
+			child.ChildrenFiltered("tr").Each(func(_ int, tr *goquery.Selection) { head = append(head, tr) })
+		case "tbody":
+			child.ChildrenFiltered("tr").Each(func(_ int, tr *goquery.Selection) { body = append(body, tr) })
+		case "tfoot":
+			child.ChildrenFiltered("tr").Each(func(_ int, tr *goquery.Selection) { foot = append(foot, tr) })
+		case "tr":
+			body = append(body, child)
+		}
+	})
+	trs := append(append(head, body...), foot...)
+
+	grid := &tableGrid{rows: make([][]*tableCell, len(trs)), headerRows: len(head)}
+	for r, tr := range trs {
+		col := 0
+		tr.ChildrenFiltered("th, td").Each(func(_ int, td *goquery.Selection) {
+			// Skip the slots taken by cells spanning down from earlier rows
+			for col < len(grid.rows[r]) && grid.rows[r][col] != nil {
+				col++
+			}
+			cell := &tableCell{sel: td, header: goquery.NodeName(td) == "th"}
+			colspan := spanAttr(td, "colspan", 1)
+			rowspan := spanAttr(td, "rowspan", 1)
+			if rowspan == 0 || r+rowspan > len(trs) {
+				// rowspan="0" spans the rest of the table
+				rowspan = len(trs) - r
+			}
+			if colspan > 1 || rowspan > 1 {
+				grid.spans = true
+			}
+			for dr := 0; dr < rowspan; dr++ {
+				for dc := 0; dc < colspan; dc++ {
+					row := grid.rows[r+dr]
+					for len(row) <= col+dc {
+						row = append(row, nil)
+					}
+					row[col+dc] = cell
+					grid.rows[r+dr] = row
+				}
+			}
+			col += colspan
+		})
+	}
+
+	// Drop rows without cells and pad the others to the full width
+	width := 0
+	rows := grid.rows[:0]
+	for r, row := range grid.rows {
+		if len(row) == 0 {
+			if r < len(head) {
+				grid.headerRows--
+			}
+			continue
+		}
+		rows = append(rows, row)
+		width = max(width, len(row))
+	}
+	grid.rows = rows
+	for r, row := range grid.rows {
+		for len(row) < width {
+			row = append(row, &tableCell{})
+		}
+		grid.rows[r] = row
+	}
+
+	if grid.headerRows == 0 {
+		for _, row := range grid.rows {
+			if !allHeaders(row) {
+				break
+			}
+			grid.headerRows++
+		}
+	}
+	return grid
+}
+
+// spanAttr returns the colspan or rowspan of a cell, or def if unset or invalid.
+func spanAttr(td *goquery.Selection, name string, def int) int {
+	value, ok := td.Attr(name)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || n < 0 {
+		return def
+	}
+	// Browsers cap spans the same way
+	return min(n, 1000)
+}
+
+// allHeaders reports whether every cell of a row is a th.
+func allHeaders(row []*tableCell) bool {
+	for _, cell := range row {
+		if !cell.header {
+			return false
+		}
+	}
+	return true
+}
+
+// csv renders the grid as CSV, one record per row, with the text of each cell.
+func (g *tableGrid) csv() string {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	for _, row := range g.rows {
+		record := make([]string, len(row))
+		for col, cell := range row {
+			if cell.sel != nil {
+				record[col] = strings.Join(strings.Fields(cell.sel.Text()), " ")
+			}
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	return b.String()
+}
+
+// isGFM reports whether the grid can be written as a GFM table.
+func (g *tableGrid) isGFM() bool {
+	if g.spans || g.headerRows > 1 {
+		return false
+	}
+	for _, row := range g.rows {
+		for _, cell := range row {
+			if cell.sel != nil && cell.sel.Find(blockSelector).Length() > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// writeGFMTable writes a grid without spans as a GFM table.
+func (c *Converter) writeGFMTable(b *strings.Builder, grid *tableGrid) {
+	for r, row := range grid.rows {
+		b.WriteString("|")
+		for _, cell := range row {
+			b.WriteString(" " + c.inlineCell(cell) + " |")
+		}
+		b.WriteString("\n")
+
+		if r == 0 {
+			b.WriteString("|")
+			for _, cell := range row {
+				b.WriteString(" " + alignmentRule(cell) + " |")
+			}
+			b.WriteString("\n")
+		}
+	}
+}
+
+// inlineCell converts a cell to Markdown that fits on one table line: line
+// breaks and paragraphs become <br> and pipes are escaped.
+func (c *Converter) inlineCell(cell *tableCell) string {
+	if cell.sel == nil {
+		return ""
+	}
+	var lines []string
+	for _, line := range strings.Split(c.mdConverter.Convert(cell.sel), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return escapePipes(strings.Join(lines, "<br>"))
+}
+
+// escapePipes escapes the pipes of cell content that aren't escaped yet (pipes
+// in text are escaped by the Markdown converter, but not those in code spans).
+func escapePipes(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '|' && (i == 0 || s[i-1] != '\\') {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// alignmentRule returns the delimiter row entry for a column, from the align
+// attribute or text-align style of its header cell.
+func alignmentRule(cell *tableCell) string {
+	if cell.sel == nil {
+		return "---"
+	}
+	align := strings.ToLower(cell.sel.AttrOr("align", ""))
+	if style := strings.ToLower(cell.sel.AttrOr("style", "")); strings.Contains(style, "text-align") {
+		for _, value := range []string{"left", "center", "right"} {
+			if strings.Contains(style, "text-align:"+value) || strings.Contains(style, "text-align: "+value) {
+				align = value
+			}
+		}
+	}
+	switch align {
+	case "left":
+		return ":---"
+	case "center":
+		return ":---:"
+	case "right":
+		return "---:"
+	}
+	return "---"
+}
+
+// writeTableList writes each body row as its first cell in bold followed by a
+// list of "header: value" entries, one per remaining column. Cells spanning
+// several rows are repeated in each row; multi-row headers are joined with " / ".
+func (c *Converter) writeTableList(b *strings.Builder, grid *tableGrid) {
+	labels := make([]string, len(grid.rows[0]))
+	for col := range labels {
+		var parts []string
+		for r := 0; r < grid.headerRows; r++ {
+			part := c.inlineCell(grid.rows[r][col])
+			if part != "" && (len(parts) == 0 || parts[len(parts)-1] != part) {
+				parts = append(parts, part)
+			}
+		}
+		labels[col] = strings.Join(parts, " / ")
+	}
+
+	for r := grid.headerRows; r < len(grid.rows); r++ {
+		row := grid.rows[r]
+		if r > grid.headerRows {
+			b.WriteString("\n")
+		}
+		first := true
+		for col, cell := range row {
+			if col > 0 && cell == row[col-1] {
+				// A cell spanning columns is listed once
+				continue
+			}
+			value := c.blockCell(cell)
+			if value == "" {
+				continue
+			}
+			if first && len(row) > 1 {
+				b.WriteString("**" + strings.ReplaceAll(value, "\n", " ") + "**\n\n")
+				first = false
+				continue
+			}
+			first = false
+			if labels[col] != "" {
+				value = "**" + labels[col] + "**: " + value
+			}
+			b.WriteString("- " + strings.ReplaceAll(value, "\n", "\n  ") + "\n")
+		}
+	}
+}
+
+// blockCell converts a cell to Markdown, keeping its block structure.
+func (c *Converter) blockCell(cell *tableCell) string {
+	if cell.sel == nil {
+		return ""
+	}
+	markdown := strings.TrimSpace(c.mdConverter.Convert(cell.sel))
+	// Start nested block content on its own line below the label
+	if strings.HasPrefix(markdown, "- ") || strings.HasPrefix(markdown, "1. ") || strings.HasPrefix(markdown, "```") {
+		markdown = "\n" + markdown
+	}
+	return markdown
+}
+
+// writeTableHTML writes a table as an HTML block, without presentational
+// attributes and blank lines (which would end the block).
+func writeTableHTML(b *strings.Builder, table *goquery.Selection) {
+	clone := table.Clone()
+	clone.Find("*").AddSelection(clone).Each(func(_ int, s *goquery.Selection) {
+		for _, node := range s.Nodes {
+			attrs := node.Attr[:0]
+			for _, attr := range node.Attr {
+				if keptTableAttributes[attr.Key] {
+					attrs = append(attrs, attr)
+				}
+			}
+			node.Attr = attrs
+		}
+	})
+
+	html, err := goquery.OuterHtml(clone)
+	if err != nil {
+		return
+	}
+	var lines []string
+	for _, line := range strings.Split(html, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	b.WriteString(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,126 @@
+package converter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertTable(t *testing.T) {
+	tests := []struct {
+		name     string
+		fallback string
+		html     string
+		want     string
+	}{
+		{
+			name: "simple table with alignment",
+			html: `<table>
+<thead><tr><th>Name</th><th align="right">Limit</th><th style="text-align: center">Notes</th></tr></thead>
+<tbody>
+<tr><td><code>list</code></td><td>100</td><td><p>Paged.</p><p>Use <a href="/cursor">cursors</a>.</p></td></tr>
+<tr><td>a|b</td><td><code>x|y</code></td><td>Line one<br>line two</td></tr>
+</tbody></table>`,
+			want: "| Name | Limit | Notes |\n" +
+				"| --- | ---: | :---: |\n" +
+				"| `list` | 100 | Paged.<br>Use [cursors](/cursor). |\n" +
+				"| a\\|b | `x\\|y` | Line one<br>line two |",
+		},
+		{
+			name: "first row becomes the header",
+			html: `<table><tr><td>Key</td><td>Value</td></tr><tr><td>a</td><td>1</td></tr></table>`,
+			want: "| Key | Value |\n| --- | --- |\n| a | 1 |",
+		},
+		{
+			name: "spans fall back to HTML",
+			html: `<table class="fancy"><tr><th colspan="2" style="color:red">Plan</th></tr>
+<tr><td rowspan="2">Free</td><td>1 GB</td></tr>
+
+<tr><td>1 user</td></tr></table>`,
+			want: "<table><tbody><tr><th colspan=\"2\">Plan</th></tr>\n<tr><td rowspan=\"2\">Free</td><td>1 GB</td></tr>\n<tr><td>1 user</td></tr></tbody></table>",
+		},
+		{
+			name:     "spans and lists fall back to a list",
+			fallback: TableFallbackList,
+			html: `<table>
+<thead><tr><th rowspan="2">Plan</th><th colspan="2">Limits</th></tr><tr><th>Storage</th><th>Features</th></tr></thead>
+<tbody>
+<tr><td>Free</td><td>1 GB</td><td><ul><li>Search</li><li>Export</li></ul></td></tr>
+<tr><td>Team</td><td colspan="2">Unlimited</td></tr>
+</tbody></table>`,
+			want: "**Free**\n\n" +
+				"- **Limits / Storage**: 1 GB\n" +
+				"- **Limits / Features**:\n  - Search\n  - Export\n\n" +
+				"**Team**\n\n" +
+				"- **Limits / Storage**: Unlimited",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := New()
+			if tt.fallback != "" {
+				if err := c.SetTableFallback(tt.fallback); err != nil {
+					t.Fatalf("SetTableFallback returned error: %v", err)
+				}
+			}
+			markdown, err := c.mdConverter.ConvertString("<p>Intro</p>" + tt.html + "<p>Outro</p>")
+			if err != nil {
+				t.Fatalf("ConvertString returned error: %v", err)
+			}
+			want := "Intro\n\n" + tt.want + "\n\nOutro"
+			if markdown != want {
+				t.Errorf("got:\n%s\n\nwant:\n%s", markdown, want)
+			}
+		})
+	}
+
+	if err := New().SetTableFallback("csv"); err == nil {
+		t.Error("SetTableFallback accepted an unknown fallback")
+	}
+}
+
+func TestTableCSV(t *testing.T) {
+	dir := t.TempDir()
+	htmlPath := filepath.Join(dir, "regions.html")
+	html := `<html><head><title>Regions</title></head><body><article><h1>Regions</h1>
+<p>` + strings.Repeat("Each region hosts its own copy of the service and keeps data local to the region. ", 5) + `</p>
+<table><thead><tr><th>Region</th><th>Location</th></tr></thead><tbody>
+<tr><td><code>us-east-1</code></td><td>Virginia, US</td></tr>
+<tr><td><code>eu-west-1</code></td><td>Dublin</td></tr>
+<tr><td><code>ap-south-1</code></td><td>"Mumbai"</td></tr>
+</tbody></table>
+<table><tr><th>Tier</th><th>Limit</th></tr><tr><td>Free</td><td>10</td></tr></table>
+</article></body></html>`
+	if err := os.WriteFile(htmlPath, []byte(html), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := New()
+	c.SetTableCSVRows(2)
+	outPath := filepath.Join(dir, "out", "regions.md")
+	if err := c.ConvertPage(htmlPath, outPath, PageMeta{SourceURL: "https://example.com/regions"}); err != nil {
+		t.Fatalf("ConvertPage() returned error: %v", err)
+	}
+
+	markdown, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(markdown), "*This table is also available as [CSV](../assets/regions-table-1.csv).*\n\n| Region | Location |") {
+		t.Errorf("missing CSV link above the large table:\n%s", markdown)
+	}
+	if strings.Count(string(markdown), "[CSV]") != 1 {
+		t.Errorf("small table linked to a CSV file:\n%s", markdown)
+	}
+
+	got, err := os.ReadFile(filepath.Join(dir, "out", "assets", "regions-table-1.csv"))
+	if err != nil {
+		t.Fatalf("CSV file missing: %v", err)
+	}
+	want := "Region,Location\nus-east-1,\"Virginia, US\"\neu-west-1,Dublin\nap-south-1,\"\"\"Mumbai\"\"\"\n"
+	if string(got) != want {
+		t.Errorf("CSV = %q, want %q", got, want)
+	}
+}
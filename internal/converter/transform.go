@@ -0,0 +1,77 @@
+// Package converter provides HTML to Markdown conversion functionality.
+// This file implements transformers: Go hooks of library users rewriting the
+// DOM or the Markdown of every page, e.g., to map internal links or normalize
+// terminology, without changes to the converter.
+package converter
+
+import (
+	"fmt"
+
+	"golang.org/x/net/html"
+)
+
+// Transformer rewrites the DOM of every HTML page before its main content is
+// extracted.
+type Transformer interface {
+	// Transform mutates doc, the root node of the page with the metadata
+	// meta, in place. The elements of the strip selectors are already
+	// removed; Readability, the content selector, and the built-in cleanup
+	// rules see the result. An error fails the conversion of the page.
+	Transform(doc *html.Node, meta PageMeta) error
+}
+
+// TransformerFunc adapts a function to a Transformer.
+type TransformerFunc func(doc *html.Node, meta PageMeta) error
+
+// Transform calls f(doc, meta).
+func (f TransformerFunc) Transform(doc *html.Node, meta PageMeta) error {
+	return f(doc, meta)
+}
+
+// MarkdownTransformer rewrites the Markdown of every page before it is
+// written.
+type MarkdownTransformer interface {
+	// TransformMarkdown returns the rewritten markdown, the body of the page
+	// with the metadata meta, without its frontmatter. An error fails the
+	// conversion of the page.
+	TransformMarkdown(markdown string, meta PageMeta) (string, error)
+}
+
+// MarkdownTransformerFunc adapts a function to a MarkdownTransformer.
+type MarkdownTransformerFunc func(markdown string, meta PageMeta) (string, error)
+
+// TransformMarkdown calls f(markdown, meta).
+func (f MarkdownTransformerFunc) TransformMarkdown(markdown string, meta PageMeta) (string, error) {
+	return f(markdown, meta)
+}
+
+// AddTransformer makes the converter run t on the DOM of every HTML page,
+// after the transformers added before. Markdown and PDF documents have no
+// DOM and aren't offered to it.
+//
+// Since the output of a transformer can't be fingerprinted, the conversion
+// cache is bypassed while a transformer is added.
+func (c *Converter) AddTransformer(t Transformer) {
+	c.transformers = append(c.transformers, t)
+}
+
+// AddMarkdownTransformer makes the converter run t on the Markdown of every
+// page, HTML, Markdown, or PDF, after the Markdown transformers added before.
+// The pages skipped for their page type aren't offered to it. Unlike
+// transformers, it runs on the cached conversions.
+func (c *Converter) AddMarkdownTransformer(t MarkdownTransformer) {
+	c.markdownTransformers = append(c.markdownTransformers, t)
+}
+
+// transformMarkdown runs the Markdown transformers on the Markdown of p, the
+// page with the metadata meta.
+func (c *Converter) transformMarkdown(p *page, meta PageMeta) error {
+	for _, t := range c.markdownTransformers {
+		markdown, err := t.TransformMarkdown(p.Markdown, meta)
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrTransformFailed, err)
+		}
+		p.Markdown = markdown
+	}
+	return nil
+}
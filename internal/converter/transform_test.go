@@ -0,0 +1,115 @@
+package converter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// internalLinks maps the links to the internal wiki to the public docs.
+var internalLinks = TransformerFunc(func(doc *html.Node, meta PageMeta) error {
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		for i, a := range n.Attr {
+			if n.Data == "a" && a.Key == "href" && strings.HasPrefix(a.Val, "https://wiki.internal/") {
+				n.Attr[i].Val = "https://docs.example.com/" + strings.TrimPrefix(a.Val, "https://wiki.internal/")
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+	return nil
+})
+
+// terminology renames the product in the Markdown of the pages under /docs/.
+var terminology = MarkdownTransformerFunc(func(markdown string, meta PageMeta) (string, error) {
+	if !strings.Contains(meta.SourceURL, "/docs/") {
+		return markdown, nil
+	}
+	return strings.ReplaceAll(markdown, "Widgetron", "Widget Server"), nil
+})
+
+func TestTransformers(t *testing.T) {
+	const page = `<html><head><title>Setup</title></head><body><main><h1>Setup</h1>
+<p>Install Widgetron as described in <a href="https://wiki.internal/install">the install guide</a>.</p></main></body></html>`
+	failing := MarkdownTransformerFunc(func(string, PageMeta) (string, error) {
+		return "", errors.New("glossary unavailable")
+	})
+
+	tests := []struct {
+		name         string
+		transformers []Transformer
+		markdown     []MarkdownTransformer
+		sourceURL    string
+		want         []string
+		wantErr      bool
+	}{
+		{
+			name:      "none",
+			sourceURL: "https://example.com/docs/setup",
+			want:      []string{"Install Widgetron", "(https://wiki.internal/install)"},
+		},
+		{
+			name:         "DOM and Markdown",
+			transformers: []Transformer{internalLinks},
+			markdown:     []MarkdownTransformer{terminology},
+			sourceURL:    "https://example.com/docs/setup",
+			want:         []string{"Install Widget Server", "(https://docs.example.com/install)"},
+		},
+		{
+			name:      "Markdown transformer using the metadata",
+			markdown:  []MarkdownTransformer{terminology},
+			sourceURL: "https://example.com/blog/setup",
+			want:      []string{"Install Widgetron"},
+		},
+		{
+			name:      "failing transformer",
+			markdown:  []MarkdownTransformer{terminology, failing},
+			sourceURL: "https://example.com/docs/setup",
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			htmlPath := filepath.Join(dir, "setup.html")
+			if err := os.WriteFile(htmlPath, []byte(page), 0644); err != nil {
+				t.Fatal(err)
+			}
+			c := New()
+			for _, tr := range tt.transformers {
+				c.AddTransformer(tr)
+			}
+			for _, tr := range tt.markdown {
+				c.AddMarkdownTransformer(tr)
+			}
+			outputPath := filepath.Join(dir, "setup.md")
+			err := c.ConvertPage(htmlPath, outputPath, PageMeta{SourceURL: tt.sourceURL})
+			if tt.wantErr {
+				if !errors.Is(err, ErrConversionFailed) || !errors.Is(err, ErrTransformFailed) || !strings.Contains(err.Error(), "glossary unavailable") {
+					t.Errorf("ConvertPage() error = %v, want a conversion failure", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ConvertPage() error = %v", err)
+			}
+			data, err := os.ReadFile(outputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("ConvertPage() wrote:\n%s\nwant it to contain %q", data, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+// Package dedupe removes converted pages whose content duplicates another page.
+// Sites often serve one page at several URLs (with and without a trailing slash
+// or index.html, printer-friendly and AMP versions); keeping one copy avoids
+// inflating the skill and splitting search results across identical files.
+package dedupe
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/f4ah6o/site2skill-go/internal/assets"
+	"github.com/f4ah6o/site2skill-go/internal/mdprose"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultMaxDistance is the largest Hamming distance between the simhashes
+	// of two pages considered near-duplicates.
+	DefaultMaxDistance = 3
+	// minNearWords is the fewest words a page needs to be compared by simhash;
+	// the simhashes of shorter pages are too similar to tell them apart.
+	minNearWords = 50
+	// shingleSize is the number of words in each simhash feature.
+	shingleSize = 3
+)
+
+// alternateSegments are URL path segments and hosts marking alternate
+// versions of a page.
+var alternateSegments = map[string]bool{
+	"amp": true, "print": true, "printable": true, "printer-friendly": true, "mobile": true, "m": true,
+}
+
+// Stats counts the pages removed by Deduplicate.
+type Stats struct {
+	// Duplicates is the number of pages removed for content identical to a kept page.
+	Duplicates int
+	// NearDuplicates is the number of pages removed for content nearly identical
+	// to a kept page.
+	NearDuplicates int
+}
+
+// Deduplicator finds and removes duplicate Markdown pages.
+type Deduplicator struct {
+	// maxDistance is the largest simhash distance of near-duplicates; negative
+	// disables near-duplicate detection
+	maxDistance int
+}
+
+// doc is a Markdown page considered by Deduplicate.
+type doc struct {
+	path string
+	// meta is the frontmatter mapping, nil if the page has none
+	meta *yaml.Node
+	body string
+	// sourceURL, canonical, and aliases are read from the frontmatter
+	sourceURL string
+	canonical string
+	aliases   []string
+	// hash is the SHA-256 of the normalized text; simhash its 64-bit simhash
+	hash    [32]byte
+	simhash uint64
+	words   int
+}
+
+// New creates a Deduplicator detecting near-duplicates within DefaultMaxDistance.
+func New() *Deduplicator {
+	return &Deduplicator{maxDistance: DefaultMaxDistance}
+}
+
+// SetMaxDistance sets the largest Hamming distance, out of 64 bits, between the
+// simhashes of near-duplicate pages. A negative distance removes exact duplicates only.
+func (d *Deduplicator) SetMaxDistance(n int) {
+	d.maxDistance = n
+}
+
+// Deduplicate removes the Markdown files at paths whose content duplicates
+// another file: identical text once whitespace and case are normalized, or,
+// for pages of at least 50 words, a simhash of their word shingles within the
+// maximum distance. The frontmatter is ignored.
+//
+// Of each group of duplicates, the page kept is the one the others declare as
+// their canonical_url, then the one whose URL doesn't look like an alternate
+// version (AMP, printer-friendly, index.html, query string), then the one with
+// the shortest URL. The source_url of every removed page is added to the
+// aliases frontmatter field of the kept page, along with its own aliases. Large
+// tables saved for a removed page are removed with it.
+//
+// Returns an error if a file can't be read, written, or removed.
+func (d *Deduplicator) Deduplicate(paths []string) (Stats, error) {
+	var stats Stats
+	docs := make([]*doc, 0, len(paths))
+	declared := make(map[string]bool)
+	for _, path := range paths {
+		p, err := readDoc(path)
+		if err != nil {
+			return stats, err
+		}
+		docs = append(docs, p)
+		if p.canonical != "" && p.canonical != p.sourceURL {
+			declared[p.canonical] = true
+		}
+	}
+
+	sort.SliceStable(docs, func(i, j int) bool {
+		a, b := docs[i], docs[j]
+		if declared[a.sourceURL] != declared[b.sourceURL] {
+			return declared[a.sourceURL]
+		}
+		if alternate(a.sourceURL) != alternate(b.sourceURL) {
+			return !alternate(a.sourceURL)
+		}
+		if len(a.sourceURL) != len(b.sourceURL) {
+			return len(a.sourceURL) < len(b.sourceURL)
+		}
+		if a.sourceURL != b.sourceURL {
+			return a.sourceURL < b.sourceURL
+		}
+		return a.path < b.path
+	})
+
+	var kept []*doc
+	merged := make(map[*doc][]*doc)
+	for _, p := range docs {
+		original, near := d.match(kept, p)
+		if original == nil {
+			kept = append(kept, p)
+			continue
+		}
+		if near {
+			stats.NearDuplicates++
+		} else {
+			stats.Duplicates++
+		}
+		merged[original] = append(merged[original], p)
+	}
+
+	for _, k := range kept {
+		dups := merged[k]
+		if len(dups) == 0 {
+			continue
+		}
+		aliases := append([]string(nil), k.aliases...)
+		for _, p := range dups {
+			aliases = append(aliases, p.sourceURL)
+			aliases = append(aliases, p.aliases...)
+			if err := remove(p.path); err != nil {
+				return stats, err
+			}
+		}
+		if err := k.writeAliases(aliases); err != nil {
+			return stats, err
+		}
+	}
+	return stats, nil
+}
+
+// match returns the kept page that p duplicates, preferring an identical page
+// to a nearly identical one, and whether it is only nearly identical. Returns
+// nil if p duplicates no kept page.
+func (d *Deduplicator) match(kept []*doc, p *doc) (*doc, bool) {
+	for _, k := range kept {
+		if k.hash == p.hash {
+			return k, false
+		}
+	}
+	if d.maxDistance < 0 || p.words < minNearWords {
+		return nil, false
+	}
+	for _, k := range kept {
+		if k.words >= minNearWords && bits.OnesCount64(k.simhash^p.simhash) <= d.maxDistance {
+			return k, true
+		}
+	}
+	return nil, false
+}
+
+// readDoc reads and fingerprints a Markdown file.
+func readDoc(path string) (*doc, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	p := &doc{path: path, body: string(content)}
+	if frontmatter, body, ok := mdprose.SplitFrontmatter(p.body); ok {
+		var meta yaml.Node
+		var fields struct {
+			SourceURL    string   `yaml:"source_url"`
+			CanonicalURL string   `yaml:"canonical_url"`
+			Aliases      []string `yaml:"aliases"`
+		}
+		if yaml.Unmarshal([]byte(frontmatter), &meta) == nil && meta.Decode(&fields) == nil &&
+			len(meta.Content) == 1 && meta.Content[0].Kind == yaml.MappingNode {
+			p.meta = meta.Content[0]
+			p.sourceURL, p.canonical, p.aliases = fields.SourceURL, fields.CanonicalURL, fields.Aliases
+		}
+		p.body = body
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(p.body), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	p.words = len(words)
+	p.hash = sha256.Sum256([]byte(strings.Join(strings.Fields(strings.ToLower(p.body)), " ")))
+	p.simhash = simhash(words)
+	return p, nil
+}
+
+// simhash computes the 64-bit simhash of the word shingles of a text: each bit
+// is set when more shingle hashes have it set than not.
+func simhash(words []string) uint64 {
+	var weights [64]int
+	for i := 0; i+shingleSize <= len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:i+shingleSize], " ")))
+		sum := h.Sum64()
+		for bit := range weights {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	var hash uint64
+	for bit, w := range weights {
+		if w > 0 {
+			hash |= 1 << bit
+		}
+	}
+	return hash
+}
+
+// alternate reports whether a URL looks like an alternate version of a page:
+// an AMP, printer-friendly, or mobile version, an explicit index.html, or a
+// URL with a query string.
+func alternate(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	if u.RawQuery != "" || strings.HasSuffix(u.Path, "/index.html") || strings.HasSuffix(u.Path, ".amp") {
+		return true
+	}
+	if host, _, _ := strings.Cut(u.Hostname(), "."); alternateSegments[host] {
+		return true
+	}
+	for _, segment := range strings.Split(strings.ToLower(u.Path), "/") {
+		if alternateSegments[segment] {
+			return true
+		}
+	}
+	return false
+}
+
+// writeAliases rewrites the frontmatter of p with aliases, sorted and without
+// duplicates or p's own URL, as the aliases field.
+func (p *doc) writeAliases(aliases []string) error {
+	if p.meta == nil {
+		return nil
+	}
+	list := &yaml.Node{Kind: yaml.SequenceNode}
+	seen := map[string]bool{p.sourceURL: true}
+	sort.Strings(aliases)
+	for _, alias := range aliases {
+		if alias != "" && !seen[alias] {
+			seen[alias] = true
+			list.Content = append(list.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: alias})
+		}
+	}
+
+	replaced := false
+	for i := 0; i+1 < len(p.meta.Content); i += 2 {
+		if p.meta.Content[i].Value == "aliases" {
+			p.meta.Content[i+1] = list
+			replaced = true
+		}
+	}
+	if !replaced {
+		p.meta.Content = append(p.meta.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: "aliases"}, list)
+	}
+	fm, err := yaml.Marshal(p.meta)
+	if err != nil {
+		return fmt.Errorf("failed to write frontmatter of %s: %w", p.path, err)
+	}
+	if err := os.WriteFile(p.path, []byte("---\n"+string(fm)+"---\n"+p.body), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", p.path, err)
+	}
+	return nil
+}
+
+// remove deletes a Markdown file and the large tables saved for it in the
+// assets folder next to it (<name>-table-N.csv).
+func remove(path string) error {
+	if err := os.Remove(path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	base := strings.TrimSuffix(filepath.Base(path), ".md")
+	tables, _ := filepath.Glob(filepath.Join(filepath.Dir(path), assets.DirName, base+"-table-*.csv"))
+	for _, table := range tables {
+		if err := os.Remove(table); err != nil {
+			return fmt.Errorf("failed to remove %s: %w", table, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package dedupe
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/f4ah6o/site2skill-go/internal/mdprose"
+	"gopkg.in/yaml.v3"
+)
+
+// prose returns n words of text varying with seed, long enough for simhash comparisons.
+func prose(seed, n int) string {
+	vocabulary := strings.Fields("the client sends a request with its token and the server checks " +
+		"scopes before returning data or an error describing which permission is missing from key")
+	words := make([]string, n)
+	for i := range words {
+		words[i] = vocabulary[(i*7+seed*13+i*i*seed)%len(vocabulary)]
+	}
+	return strings.Join(words, " ")
+}
+
+func TestDeduplicate(t *testing.T) {
+	guide := "# Guide\n\n" + prose(1, 120) + "\n"
+	edited := strings.Replace(guide, "client", "customer", 1)
+	page := func(url, extra, body string) string {
+		return "---\ntitle: \"Guide\"\nsource_url: \"" + url + "\"\n" + extra + "---\n\n" + body
+	}
+
+	tests := []struct {
+		name        string
+		maxDistance int
+		files       map[string]string
+		wantKept    map[string][]string
+		wantStats   Stats
+	}{
+		{
+			name:        "same page at several URLs",
+			maxDistance: DefaultMaxDistance,
+			files: map[string]string{
+				"index.md":  page("https://example.com/docs/guide/index.html", "", guide),
+				"guide.md":  page("https://example.com/docs/guide/", "", guide),
+				"print.md":  page("https://example.com/print/docs/guide/", "", strings.ReplaceAll(guide, "\n\n", "\n\n\n")),
+				"other.md":  page("https://example.com/docs/other", "", "# Other\n\n"+prose(2, 120)+"\n"),
+				"short.md":  page("https://example.com/docs/short", "", "# Short\n\nBrief.\n"),
+				"short2.md": page("https://example.com/docs/short2", "", "# Short\n\nTerse.\n"),
+			},
+			wantKept: map[string][]string{
+				"guide.md":  {"https://example.com/docs/guide/index.html", "https://example.com/print/docs/guide/"},
+				"other.md":  nil,
+				"short.md":  nil,
+				"short2.md": nil,
+			},
+			wantStats: Stats{Duplicates: 2},
+		},
+		{
+			name:        "near duplicates",
+			maxDistance: DefaultMaxDistance,
+			files: map[string]string{
+				"guide.md": page("https://example.com/docs/guide", "", guide),
+				"amp.md":   page("https://example.com/docs/guide.amp", "aliases:\n  - \"https://example.com/g\"\n", edited),
+			},
+			wantKept:  map[string][]string{"guide.md": {"https://example.com/docs/guide.amp", "https://example.com/g"}},
+			wantStats: Stats{NearDuplicates: 1},
+		},
+		{
+			name:        "near duplicates kept when disabled",
+			maxDistance: -1,
+			files: map[string]string{
+				"guide.md": page("https://example.com/docs/guide", "", guide),
+				"amp.md":   page("https://example.com/docs/guide.amp", "", edited),
+			},
+			wantKept: map[string][]string{"guide.md": nil, "amp.md": nil},
+		},
+		{
+			name:        "declared canonical wins",
+			maxDistance: DefaultMaxDistance,
+			files: map[string]string{
+				"a.md": page("https://example.com/a", "canonical_url: \"https://example.com/docs/getting-started\"\n", guide),
+				"b.md": page("https://example.com/docs/getting-started", "", guide),
+			},
+			wantKept:  map[string][]string{"b.md": {"https://example.com/a"}},
+			wantStats: Stats{Duplicates: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var paths []string
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+				paths = append(paths, path)
+			}
+
+			d := New()
+			d.SetMaxDistance(tt.maxDistance)
+			stats, err := d.Deduplicate(paths)
+			if err != nil {
+				t.Fatalf("Deduplicate() returned error: %v", err)
+			}
+			if stats != tt.wantStats {
+				t.Errorf("Deduplicate() = %+v, want %+v", stats, tt.wantStats)
+			}
+
+			got := make(map[string][]string)
+			files, _ := filepath.Glob(filepath.Join(dir, "*.md"))
+			for _, file := range files {
+				content, _ := os.ReadFile(file)
+				frontmatter, _, ok := mdprose.SplitFrontmatter(string(content))
+				if !ok {
+					t.Fatalf("%s lost its frontmatter:\n%s", file, content)
+				}
+				var fm struct {
+					Aliases []string `yaml:"aliases"`
+				}
+				if err := yaml.Unmarshal([]byte(frontmatter), &fm); err != nil {
+					t.Fatalf("%s has invalid frontmatter: %v", file, err)
+				}
+				got[filepath.Base(file)] = fm.Aliases
+			}
+			if !reflect.DeepEqual(got, tt.wantKept) {
+				t.Errorf("kept pages and aliases = %v, want %v", got, tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestDeduplicateRemovesTables(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "assets"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	body := "---\nsource_url: \"%s\"\n---\n\n# Regions\n\n*This table is also available as [CSV](../assets/%s-table-1.csv).*\n"
+	files := map[string]string{
+		"regions.md":                       fmt.Sprintf(body, "https://example.com/regions", "regions"),
+		"regions-print.md":                 fmt.Sprintf(body, "https://example.com/regions?print=1", "regions"),
+		"assets/regions-table-1.csv":       "a,b\n",
+		"assets/regions-print-table-1.csv": "a,b\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	paths := []string{filepath.Join(dir, "regions.md"), filepath.Join(dir, "regions-print.md")}
+	if _, err := New().Deduplicate(paths); err != nil {
+		t.Fatalf("Deduplicate() returned error: %v", err)
+	}
+	for name, want := range map[string]bool{
+		"regions.md": true, "assets/regions-table-1.csv": true,
+		"regions-print.md": false, "assets/regions-print-table-1.csv": false,
+	} {
+		if _, err := os.Stat(filepath.Join(dir, name)); (err == nil) != want {
+			t.Errorf("%s exists = %v, want %v", name, err == nil, want)
+		}
+	}
+}
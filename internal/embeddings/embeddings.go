@@ -0,0 +1,136 @@
+// Package embeddings computes vector embeddings of text, which place texts of
+// related meaning close together, so that searches find the documents about a
+// query even when they don't use its words.
+//
+// Embeddings come from a Provider:
+//
+//   - ProviderOpenAI calls an OpenAI-compatible /embeddings endpoint: the
+//     OpenAI API, or a local model served by Ollama, LM Studio, llama.cpp, or
+//     vLLM (e.g., "http://localhost:11434/v1").
+//   - ProviderHash computes feature-hashed bags of stemmed words locally,
+//     without a model or network access. It captures shared vocabulary rather
+//     than meaning, and suits air-gapped builds and tests.
+//
+// Vectors are only comparable when computed by the same provider and model, so
+// a Spec identifies them and is recorded with stored embeddings.
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// The providers of New.
+const (
+	// ProviderOpenAI calls an OpenAI-compatible embeddings endpoint (see OpenAI).
+	ProviderOpenAI = "openai"
+	// ProviderHash computes embeddings locally by feature hashing (see Hash).
+	ProviderHash = "hash"
+)
+
+// Providers lists the providers of New.
+var Providers = []string{ProviderOpenAI, ProviderHash}
+
+const (
+	// DefaultOpenAIModel is the embedding model used with ProviderOpenAI when none is given.
+	DefaultOpenAIModel = "text-embedding-3-small"
+	// DefaultHashDimensions is the number of dimensions of ProviderHash when none is given.
+	DefaultHashDimensions = 512
+
+	// APIKeyEnv is the environment variable holding the API key of
+	// ProviderOpenAI; OPENAI_API_KEY is used when it isn't set.
+	APIKeyEnv = "SITE2SKILL_EMBEDDINGS_API_KEY"
+)
+
+// Provider computes the embeddings of texts.
+type Provider interface {
+	// Embed returns the embedding of each of texts, in order, normalized to
+	// unit length. All embeddings of a provider have the same number of dimensions.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Spec identifies the provider and model computing the embeddings.
+	Spec() Spec
+}
+
+// Spec identifies the provider and model of embeddings. Embeddings with
+// different specs aren't comparable.
+type Spec struct {
+	// Provider is ProviderOpenAI or ProviderHash.
+	Provider string `json:"provider"`
+	// URL is the base URL of the endpoint of ProviderOpenAI (e.g., openai.DefaultURL).
+	URL string `json:"url,omitempty"`
+	// Model is the embedding model of ProviderOpenAI (e.g., DefaultOpenAIModel).
+	Model string `json:"model,omitempty"`
+	// Dimensions is the number of dimensions of ProviderHash.
+	Dimensions int `json:"dimensions,omitempty"`
+}
+
+// String describes the spec (e.g., "openai text-embedding-3-small").
+func (s Spec) String() string {
+	switch s.Provider {
+	case ProviderOpenAI:
+		return s.Provider + " " + s.Model
+	case ProviderHash:
+		return fmt.Sprintf("%s %d", s.Provider, s.Dimensions)
+	}
+	return s.Provider
+}
+
+// New returns the provider of spec, filling in its defaults: openai.DefaultURL
+// and DefaultOpenAIModel for ProviderOpenAI, which authenticates with apiKey
+// (none if empty), and DefaultHashDimensions for ProviderHash.
+//
+// Returns an error if the provider is unknown.
+func New(spec Spec, apiKey string) (Provider, error) {
+	switch spec.Provider {
+	case ProviderOpenAI:
+		return NewOpenAI(spec.URL, spec.Model, apiKey), nil
+	case ProviderHash:
+		return NewHash(spec.Dimensions), nil
+	}
+	return nil, fmt.Errorf("unknown embeddings provider %q (expected %s)", spec.Provider, strings.Join(Providers, " or "))
+}
+
+// APIKey returns the API key of ProviderOpenAI from the environment: APIKeyEnv,
+// or else OPENAI_API_KEY.
+func APIKey() string {
+	if key := os.Getenv(APIKeyEnv); key != "" {
+		return key
+	}
+	return os.Getenv("OPENAI_API_KEY")
+}
+
+// Cosine returns the cosine similarity of two vectors, from -1 to 1, or 0 if
+// their lengths differ or either is zero.
+func Cosine(a, b []float32) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// normalize scales v to unit length in place, unless it is zero.
+func normalize(v []float32) {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+}
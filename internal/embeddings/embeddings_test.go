@@ -0,0 +1,106 @@
+package embeddings
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/f4ah6o/site2skill-go/internal/openai"
+)
+
+func TestHash(t *testing.T) {
+	p := NewHash(0)
+	if got := p.Spec(); got != (Spec{Provider: ProviderHash, Dimensions: DefaultHashDimensions}) {
+		t.Errorf("Spec() = %+v", got)
+	}
+
+	vectors, err := p.Embed(context.Background(), []string{
+		"Configuring the proxy",
+		"Proxy configuration",
+		"Billing and invoices",
+		"",
+	})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	related := Cosine(vectors[0], vectors[1])
+	unrelated := Cosine(vectors[0], vectors[2])
+	if related < 0.9 {
+		t.Errorf("similarity of inflected forms = %v, want >= 0.9", related)
+	}
+	if unrelated >= related {
+		t.Errorf("similarity of unrelated texts = %v, want < %v", unrelated, related)
+	}
+	if got := Cosine(vectors[0], vectors[3]); got != 0 {
+		t.Errorf("similarity with empty text = %v, want 0", got)
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Model != "test-model" {
+			http.Error(w, "bad body", http.StatusBadRequest)
+			return
+		}
+		// Answer in reverse order: vectors are matched to texts by index
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		var data []item
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, item{Index: i, Embedding: []float32{float32(len(req.Input[i])), 0}})
+		}
+		json.NewEncoder(w).Encode(map[string]any{"data": data})
+	}))
+	defer srv.Close()
+
+	p := NewOpenAI(srv.URL+"/v1/", "test-model", "secret")
+	if got := p.Spec(); got != (Spec{Provider: ProviderOpenAI, URL: srv.URL + "/v1", Model: "test-model"}) {
+		t.Errorf("Spec() = %+v", got)
+	}
+
+	texts := make([]string, openAIBatchSize+1)
+	for i := range texts {
+		texts[i] = "text"
+	}
+	vectors, err := p.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+	if len(vectors) != len(texts) || requests != 2 {
+		t.Fatalf("Embed() returned %d vectors in %d requests, want %d in 2", len(vectors), requests, len(texts))
+	}
+	if v := vectors[0]; v[0] != 1 || v[1] != 0 {
+		t.Errorf("Embed() vector = %v, want normalized [1 0]", v)
+	}
+
+	if _, err := NewOpenAI(srv.URL+"/v1", "other-model", "secret").Embed(context.Background(), []string{"x"}); err == nil {
+		t.Error("Embed() with a rejected request succeeded, want error")
+	}
+}
+
+func TestNew(t *testing.T) {
+	p, err := New(Spec{Provider: ProviderOpenAI}, "")
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if got := p.Spec(); got != (Spec{Provider: ProviderOpenAI, URL: openai.DefaultURL, Model: DefaultOpenAIModel}) {
+		t.Errorf("Spec() = %+v", got)
+	}
+	if _, err := New(Spec{Provider: "word2vec"}, ""); err == nil {
+		t.Error("New() with an unknown provider succeeded, want error")
+	}
+}
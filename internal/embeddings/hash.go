@@ -0,0 +1,68 @@
+// Package embeddings computes vector embeddings of text.
+// This file implements the local provider computing feature-hashed embeddings.
+package embeddings
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+	"unicode"
+
+	"github.com/f4ah6o/site2skill-go/internal/lang"
+)
+
+// Hash computes embeddings locally as feature-hashed bags of words: each word
+// of a text, lowercased, stemmed (in English), and without stop words, adds
+// ±1 to the dimension its hash picks. Texts sharing words, or their inflected
+// forms, get close vectors; synonyms don't.
+type Hash struct {
+	dimensions int
+}
+
+// NewHash returns a provider of embeddings with the given number of dimensions
+// (DefaultHashDimensions if 0 or less).
+func NewHash(dimensions int) *Hash {
+	if dimensions <= 0 {
+		dimensions = DefaultHashDimensions
+	}
+	return &Hash{dimensions: dimensions}
+}
+
+// Spec returns the spec of the provider.
+func (p *Hash) Spec() Spec {
+	return Spec{Provider: ProviderHash, Dimensions: p.dimensions}
+}
+
+// Embed returns the embeddings of texts. It never fails.
+func (p *Hash) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	stopWords := make(map[string]bool)
+	for _, w := range lang.StopWords("en") {
+		stopWords[w] = true
+	}
+
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, p.dimensions)
+		words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		for _, w := range words {
+			if stopWords[w] {
+				continue
+			}
+			h := fnv.New64a()
+			h.Write([]byte(lang.English(w)))
+			sum := h.Sum64()
+			// The low bits pick the dimension and the high bit the sign, so
+			// that collisions cancel out instead of adding up
+			if sum>>63 == 0 {
+				v[sum%uint64(p.dimensions)]++
+			} else {
+				v[sum%uint64(p.dimensions)]--
+			}
+		}
+		normalize(v)
+		vectors[i] = v
+	}
+	return vectors, nil
+}
@@ -0,0 +1,80 @@
+// Package embeddings computes vector embeddings of text.
+// This file implements the provider of OpenAI-compatible embeddings endpoints.
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/openai"
+)
+
+// openAIBatchSize is the number of texts sent per request.
+const openAIBatchSize = 64
+
+// OpenAI computes embeddings with an OpenAI-compatible endpoint: a POST of
+// {"model": ..., "input": [...]} to <URL>/embeddings answered by
+// {"data": [{"index": ..., "embedding": [...]}]}.
+type OpenAI struct {
+	spec   Spec
+	client *openai.Client
+}
+
+// NewOpenAI returns a provider calling the embeddings endpoint of the API at
+// baseURL (openai.DefaultURL if empty) with model (DefaultOpenAIModel if
+// empty), authenticating with apiKey as a bearer token unless it is empty.
+// Its requests time out after 60 seconds.
+func NewOpenAI(baseURL, model, apiKey string) *OpenAI {
+	if model == "" {
+		model = DefaultOpenAIModel
+	}
+	client := openai.New(baseURL, apiKey, 60*time.Second)
+	return &OpenAI{spec: Spec{Provider: ProviderOpenAI, URL: client.URL, Model: model}, client: client}
+}
+
+// Spec returns the spec of the provider.
+func (p *OpenAI) Spec() Spec {
+	return p.spec
+}
+
+// Embed returns the embeddings of texts, requesting them in batches.
+//
+// Returns an error if a request fails, the endpoint answers with an error
+// status, or its response doesn't have one embedding per text.
+func (p *OpenAI) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += openAIBatchSize {
+		batch, err := p.embedBatch(ctx, texts[start:min(start+openAIBatchSize, len(texts))])
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+// embedBatch requests the embeddings of one batch of texts.
+func (p *OpenAI) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	var parsed struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := p.client.Post(ctx, "/embeddings", map[string]any{"model": p.spec.Model, "input": texts}, &parsed); err != nil {
+		return nil, fmt.Errorf("failed to request embeddings: %w", err)
+	}
+	if len(parsed.Data) != len(texts) {
+		return nil, fmt.Errorf("failed to request embeddings: got %d embeddings for %d texts", len(parsed.Data), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, d := range parsed.Data {
+		if d.Index < 0 || d.Index >= len(texts) || vectors[d.Index] != nil {
+			return nil, fmt.Errorf("failed to request embeddings: invalid index %d in response", d.Index)
+		}
+		normalize(d.Embedding)
+		vectors[d.Index] = d.Embedding
+	}
+	return vectors, nil
+}
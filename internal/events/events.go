@@ -0,0 +1,163 @@
+// Package events streams build progress as newline-delimited JSON (NDJSON), so
+// orchestration systems (Airflow, Temporal workers, CI dashboards) can track a
+// build and react while it runs instead of parsing log output.
+//
+// Each line is one Event. Page events are emitted as the crawler settles each URL;
+// a stage_completed event follows every pipeline step:
+//
+//	{"type":"page_fetched","time":"2024-05-01T12:00:00Z","url":"https://docs.example.com/","status_code":200,"output_file":"crawl/docs.example.com/index.html"}
+//	{"type":"page_skipped","time":"2024-05-01T12:00:01Z","url":"https://docs.example.com/blog","reason":"excluded"}
+//	{"type":"stage_completed","time":"2024-05-01T12:00:09Z","stage":"fetch","duration_ms":9000,"stats":{"saved":1,"skipped":1}}
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event types.
+const (
+	// PageFetched means a page was downloaded and saved.
+	PageFetched = "page_fetched"
+	// PageFailed means a network, HTTP, or I/O error prevented saving a page.
+	PageFailed = "page_failed"
+	// PageSkipped means a URL was deliberately not fetched or saved (filtered,
+	// out of scope, disallowed by robots.txt, ...); Reason explains why.
+	PageSkipped = "page_skipped"
+	// StageCompleted means a pipeline step (fetch, convert, normalize, chunk,
+	// translate, summarize, generate, validate, package, publish, export,
+	// llms_txt) finished.
+	StageCompleted = "stage_completed"
+)
+
+// Event is one line of the stream. Fields that don't apply to a type are omitted.
+type Event struct {
+	// Type is one of the event type constants.
+	Type string `json:"type"`
+	// Time is when the event occurred.
+	Time time.Time `json:"time"`
+	// URL is the page URL, for page events.
+	URL string `json:"url,omitempty"`
+	// StatusCode is the HTTP status of the page response, if one was received.
+	StatusCode int `json:"status_code,omitempty"`
+	// OutputFile is where a fetched page was saved, relative to the temp directory.
+	OutputFile string `json:"output_file,omitempty"`
+	// Reason explains a skipped page (e.g., "excluded", "robots_txt"), or
+	// classifies the error of a failed one (e.g., "dns_error").
+	Reason string `json:"reason,omitempty"`
+	// Error describes a failed page.
+	Error string `json:"error,omitempty"`
+	// Err is the typed error of a failed page, or of a page skipped for a
+	// reason that maps to one (see fetcher.PageRecord.Err), to be checked with
+	// errors.Is and errors.As. It isn't streamed.
+	Err error `json:"-"`
+	// Stage names the completed pipeline step, for stage events.
+	Stage string `json:"stage,omitempty"`
+	// DurationMS is how long the stage took, in milliseconds.
+	DurationMS int64 `json:"duration_ms,omitempty"`
+	// Stats holds stage counters (e.g., pages per outcome for the fetch stage).
+	Stats map[string]int `json:"stats,omitempty"`
+	// Files lists the files a stage produced (e.g., the .skill packages).
+	Files []string `json:"files,omitempty"`
+}
+
+// Emitter writes events to a stream. It is safe for concurrent use, and a nil
+// *Emitter discards events, so callers need not check whether streaming is on.
+//
+// A failed write (e.g., the orchestrator closed its end of the socket) is logged
+// once and disables the emitter; the build itself carries on.
+type Emitter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	failed bool
+	now    func() time.Time
+}
+
+// New creates an Emitter writing to w.
+func New(w io.Writer) *Emitter {
+	return &Emitter{w: w, now: time.Now}
+}
+
+// Open creates an Emitter for a target given on the command line:
+//
+//	fd:N         an inherited file descriptor, e.g. fd:3 (set up by the parent process)
+//	unix:PATH    a Unix domain socket the orchestrator is listening on
+//	-            standard output
+//	PATH         a file, created or truncated
+//
+// Close the Emitter when the build ends.
+func Open(target string) (*Emitter, error) {
+	switch {
+	case target == "-":
+		return New(os.Stdout), nil
+	case strings.HasPrefix(target, "fd:"):
+		fd, err := strconv.Atoi(strings.TrimPrefix(target, "fd:"))
+		if err != nil || fd < 1 {
+			return nil, fmt.Errorf("invalid event stream target %q: expected fd:N with N >= 1", target)
+		}
+		f := os.NewFile(uintptr(fd), target)
+		if f == nil {
+			return nil, fmt.Errorf("invalid event stream target %q: bad file descriptor", target)
+		}
+		if _, err := f.Stat(); err != nil {
+			return nil, fmt.Errorf("failed to open event stream %s: %w", target, err)
+		}
+		return &Emitter{w: f, closer: f, now: time.Now}, nil
+	case strings.HasPrefix(target, "unix:"):
+		conn, err := net.Dial("unix", strings.TrimPrefix(target, "unix:"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to event stream socket: %w", err)
+		}
+		return &Emitter{w: conn, closer: conn, now: time.Now}, nil
+	}
+
+	f, err := os.Create(target)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event stream file: %w", err)
+	}
+	return &Emitter{w: f, closer: f, now: time.Now}, nil
+}
+
+// Emit writes ev as one JSON line, setting Time to now if it is zero.
+func (e *Emitter) Emit(ev Event) {
+	if e == nil {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.failed {
+		return
+	}
+
+	if ev.Time.IsZero() {
+		ev.Time = e.now().UTC()
+	}
+	data, err := json.Marshal(ev)
+	if err == nil {
+		// One Write per line, so readers never see a partial event from a concurrent writer
+		_, err = e.w.Write(append(data, '\n'))
+	}
+	if err != nil {
+		e.failed = true
+		log.Printf("Warning: event stream disabled: %v", err)
+	}
+}
+
+// Close closes the underlying file or connection, if the Emitter opened one.
+func (e *Emitter) Close() error {
+	if e == nil || e.closer == nil {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.closer.Close()
+}
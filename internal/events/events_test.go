@@ -0,0 +1,155 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func fixedClock(t time.Time) func() time.Time {
+	return func() time.Time { return t }
+}
+
+func TestEmit(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 9, 0, time.UTC)
+	var buf bytes.Buffer
+	e := New(&buf)
+	e.now = fixedClock(now)
+
+	e.Emit(Event{Type: PageFetched, URL: "https://docs.example.com/", StatusCode: 200, OutputFile: "crawl/index.html"})
+	e.Emit(Event{Type: PageSkipped, URL: "https://docs.example.com/blog", Reason: "excluded"})
+	e.Emit(Event{Type: StageCompleted, Stage: "fetch", DurationMS: 9000, Stats: map[string]int{"saved": 1, "skipped": 1}})
+
+	want := []string{
+		`{"type":"page_fetched","time":"2024-05-01T12:00:09Z","url":"https://docs.example.com/","status_code":200,"output_file":"crawl/index.html"}`,
+		`{"type":"page_skipped","time":"2024-05-01T12:00:09Z","url":"https://docs.example.com/blog","reason":"excluded"}`,
+		`{"type":"stage_completed","time":"2024-05-01T12:00:09Z","stage":"fetch","duration_ms":9000,"stats":{"saved":1,"skipped":1}}`,
+	}
+	got := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines, want %d:\n%s", len(got), len(want), buf.String())
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d:\ngot  %s\nwant %s", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestNilEmitter(t *testing.T) {
+	var e *Emitter
+	e.Emit(Event{Type: PageFetched})
+	if err := e.Close(); err != nil {
+		t.Errorf("Close() on nil Emitter returned error: %v", err)
+	}
+}
+
+type failingWriter struct{ writes int }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return 0, errors.New("broken pipe")
+}
+
+func TestEmitDisablesOnWriteError(t *testing.T) {
+	w := &failingWriter{}
+	e := New(w)
+	e.Emit(Event{Type: PageFetched})
+	e.Emit(Event{Type: PageFetched})
+	if w.writes != 1 {
+		t.Errorf("got %d writes, want 1 (emitter should stop after a failed write)", w.writes)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		wantErr string
+	}{
+		{name: "malformed fd", target: "fd:three", wantErr: "expected fd:N"},
+		{name: "fd zero", target: "fd:0", wantErr: "expected fd:N"},
+		{name: "closed fd", target: "fd:987", wantErr: "failed to open event stream"},
+		{name: "missing socket", target: "unix:" + filepath.Join(t.TempDir(), "none.sock"), wantErr: "failed to connect"},
+		{name: "missing directory", target: filepath.Join(t.TempDir(), "no", "events.ndjson"), wantErr: "failed to create"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Open(tt.target)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Open(%q) error = %v, want it to contain %q", tt.target, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	e, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() returned error: %v", err)
+	}
+	e.Emit(Event{Type: StageCompleted, Stage: "convert"})
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close() returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ev Event
+	if err := json.Unmarshal(data, &ev); err != nil {
+		t.Fatalf("invalid event line %q: %v", data, err)
+	}
+	if ev.Type != StageCompleted || ev.Stage != "convert" || ev.Time.IsZero() {
+		t.Errorf("got %+v", ev)
+	}
+}
+
+func TestOpenUnixSocket(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "events.sock")
+	ln, err := net.Listen("unix", sock)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+	defer ln.Close()
+
+	lines := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			close(lines)
+			return
+		}
+		defer conn.Close()
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	e, err := Open("unix:" + sock)
+	if err != nil {
+		t.Fatalf("Open() returned error: %v", err)
+	}
+	e.Emit(Event{Type: PageFailed, URL: "https://docs.example.com/x", Error: "timeout"})
+	e.Close()
+
+	line, ok := <-lines
+	if !ok {
+		t.Fatal("no event received on the socket")
+	}
+	if !strings.Contains(line, `"type":"page_failed"`) || !strings.Contains(line, `"error":"timeout"`) {
+		t.Errorf("unexpected event %s", line)
+	}
+}
@@ -0,0 +1,415 @@
+// Package feed parses RSS and Atom feeds, so that the entries of engineering
+// blogs and changelogs can be turned into skill documents like the pages of a
+// documentation site.
+//
+// RSS 2.0, RSS 1.0 (RDF), and Atom 1.0 are supported, with the common
+// extensions for full content (content:encoded) and authors (dc:creator).
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// ErrNotFeed is returned by Parse for a document that isn't an RSS or Atom feed.
+var ErrNotFeed = errors.New("not an RSS or Atom feed")
+
+// Feed is a parsed RSS or Atom feed.
+type Feed struct {
+	// Title is the title of the feed.
+	Title string
+	// Description is the description (RSS) or subtitle (Atom) of the feed.
+	Description string
+	// URL is the URL of the site the feed belongs to; empty if it declares none.
+	URL string
+	// Entries lists the entries of the feed, in the order of the feed.
+	Entries []Entry
+}
+
+// Entry is one entry (an RSS item) of a feed.
+type Entry struct {
+	// Title is the title of the entry.
+	Title string
+	// URL is the absolute URL of the article of the entry; empty if it has
+	// no link and its ID isn't a URL.
+	URL string
+	// ID is the unique identifier of the entry (its RSS guid or Atom id).
+	ID string
+	// Author is the name of the author of the entry, or of the feed if the
+	// entry names none.
+	Author string
+	// Published is when the entry was published; zero if the feed doesn't say.
+	Published time.Time
+	// Updated is when the entry was last updated; zero if the feed doesn't say.
+	Updated time.Time
+	// Summary is the summary of the entry, as HTML.
+	Summary string
+	// Content is the full content of the entry, as HTML; empty if the feed
+	// only carries summaries.
+	Content string
+}
+
+// nsAtom is the XML namespace of Atom.
+const nsAtom = "http://www.w3.org/2005/Atom"
+
+// rssDocument is an RSS 2.0 or RSS 1.0 (RDF) document. RSS 1.0 puts its
+// items next to its channel rather than in it.
+type rssDocument struct {
+	Channel struct {
+		Title       string    `xml:"title"`
+		Links       []rssLink `xml:"link"`
+		Description string    `xml:"description"`
+		Creator     string    `xml:"http://purl.org/dc/elements/1.1/ creator"`
+		Items       []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items []rssItem `xml:"item"`
+}
+
+// rssItem is an item of an RSS feed.
+type rssItem struct {
+	Title       string    `xml:"title"`
+	Links       []rssLink `xml:"link"`
+	GUID        string    `xml:"guid"`
+	Author      string    `xml:"author"`
+	Creator     string    `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	PubDate     string    `xml:"pubDate"`
+	Date        string    `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Description string    `xml:"description"`
+	Encoded     string    `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+}
+
+// rssLink is a link of an RSS channel or item. RSS 2.0 feeds often carry an
+// atom:link to themselves too, which isn't the link of the channel.
+type rssLink struct {
+	XMLName xml.Name
+	Text    string `xml:",chardata"`
+}
+
+// rssLinkOf returns the RSS link among links.
+func rssLinkOf(links []rssLink) string {
+	for _, l := range links {
+		if l.XMLName.Space != nsAtom {
+			return l.Text
+		}
+	}
+	return ""
+}
+
+// atomFeed is an Atom feed.
+type atomFeed struct {
+	Title    atomText    `xml:"http://www.w3.org/2005/Atom title"`
+	Subtitle atomText    `xml:"http://www.w3.org/2005/Atom subtitle"`
+	Links    []atomLink  `xml:"http://www.w3.org/2005/Atom link"`
+	Authors  []atomName  `xml:"http://www.w3.org/2005/Atom author"`
+	Entries  []atomEntry `xml:"http://www.w3.org/2005/Atom entry"`
+}
+
+// atomEntry is an entry of an Atom feed.
+type atomEntry struct {
+	Title     atomText   `xml:"http://www.w3.org/2005/Atom title"`
+	Links     []atomLink `xml:"http://www.w3.org/2005/Atom link"`
+	ID        string     `xml:"http://www.w3.org/2005/Atom id"`
+	Authors   []atomName `xml:"http://www.w3.org/2005/Atom author"`
+	Published string     `xml:"http://www.w3.org/2005/Atom published"`
+	Updated   string     `xml:"http://www.w3.org/2005/Atom updated"`
+	Summary   atomText   `xml:"http://www.w3.org/2005/Atom summary"`
+	Content   atomText   `xml:"http://www.w3.org/2005/Atom content"`
+}
+
+// atomLink is a link of an Atom feed or entry.
+type atomLink struct {
+	Rel  string `xml:"rel,attr"`
+	Href string `xml:"href,attr"`
+}
+
+// atomName is an Atom person construct.
+type atomName struct {
+	Name string `xml:"http://www.w3.org/2005/Atom name"`
+}
+
+// atomText is an Atom text construct: plain text, escaped HTML, or inline XHTML.
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// html returns the text as HTML.
+func (t atomText) html() string {
+	switch t.Type {
+	case "html":
+		return strings.TrimSpace(t.Text)
+	case "xhtml":
+		return strings.TrimSpace(t.Inner)
+	default:
+		return html.EscapeString(strings.TrimSpace(t.Text))
+	}
+}
+
+// plain returns the text without markup, e.g., for a title.
+func (t atomText) plain() string {
+	if t.Type == "html" || t.Type == "xhtml" {
+		return stripTags(t.html())
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+// Parse parses data, an RSS or Atom feed fetched from feedURL, against which
+// relative links are resolved.
+//
+// Returns ErrNotFeed if data is XML but not a feed, or an error if it isn't
+// well-formed XML.
+func Parse(data []byte, feedURL string) (*Feed, error) {
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, err
+	}
+	base, _ := url.Parse(feedURL)
+
+	switch {
+	case root.Space == nsAtom && root.Local == "feed":
+		var doc atomFeed
+		if err := decode(data, &doc); err != nil {
+			return nil, err
+		}
+		return doc.feed(base), nil
+	case root.Local == "rss" || root.Local == "RDF":
+		var doc rssDocument
+		if err := decode(data, &doc); err != nil {
+			return nil, err
+		}
+		return doc.feed(base), nil
+	default:
+		return nil, fmt.Errorf("%w: root element <%s>", ErrNotFeed, root.Local)
+	}
+}
+
+// rootElement returns the name of the root element of data.
+func rootElement(data []byte) (xml.Name, error) {
+	d := newDecoder(data)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return xml.Name{}, fmt.Errorf("failed to parse feed: %w", err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+// decode decodes data into v.
+func decode(data []byte, v any) error {
+	if err := newDecoder(data).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse feed: %w", err)
+	}
+	return nil
+}
+
+// newDecoder returns a lenient XML decoder of data: feeds in the wild use
+// HTML entities and non-UTF-8 encodings.
+func newDecoder(data []byte) *xml.Decoder {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	d.CharsetReader = charset.NewReaderLabel
+	return d
+}
+
+// feed converts an RSS document.
+func (doc rssDocument) feed(base *url.URL) *Feed {
+	ch := doc.Channel
+	f := &Feed{
+		Title:       strings.TrimSpace(ch.Title),
+		Description: strings.TrimSpace(ch.Description),
+		URL:         resolve(base, rssLinkOf(ch.Links)),
+	}
+	for _, item := range append(ch.Items, doc.Items...) {
+		e := Entry{
+			Title:     strings.TrimSpace(stripTags(item.Title)),
+			URL:       resolve(base, rssLinkOf(item.Links)),
+			ID:        strings.TrimSpace(item.GUID),
+			Author:    firstNonEmpty(item.Creator, rssAuthor(item.Author), ch.Creator),
+			Published: parseTime(firstNonEmpty(item.PubDate, item.Date)),
+			Summary:   strings.TrimSpace(item.Description),
+			Content:   strings.TrimSpace(item.Encoded),
+		}
+		if e.URL == "" && isURL(e.ID) {
+			e.URL = e.ID
+		}
+		f.Entries = append(f.Entries, e)
+	}
+	return f
+}
+
+// feed converts an Atom feed.
+func (doc atomFeed) feed(base *url.URL) *Feed {
+	f := &Feed{
+		Title:       doc.Title.plain(),
+		Description: doc.Subtitle.plain(),
+		URL:         resolve(base, alternateLink(doc.Links)),
+	}
+	feedAuthor := ""
+	if len(doc.Authors) > 0 {
+		feedAuthor = doc.Authors[0].Name
+	}
+	for _, entry := range doc.Entries {
+		author := feedAuthor
+		if len(entry.Authors) > 0 {
+			author = entry.Authors[0].Name
+		}
+		e := Entry{
+			Title:     entry.Title.plain(),
+			URL:       resolve(base, alternateLink(entry.Links)),
+			ID:        strings.TrimSpace(entry.ID),
+			Author:    strings.TrimSpace(author),
+			Published: parseTime(entry.Published),
+			Updated:   parseTime(entry.Updated),
+			Summary:   entry.Summary.html(),
+			Content:   entry.Content.html(),
+		}
+		if e.Published.IsZero() {
+			e.Published = e.Updated
+		}
+		if e.URL == "" && isURL(e.ID) {
+			e.URL = e.ID
+		}
+		f.Entries = append(f.Entries, e)
+	}
+	return f
+}
+
+// alternateLink returns the href of the alternate link among links: the one
+// with rel "alternate" or no rel.
+func alternateLink(links []atomLink) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+// rssAuthor returns the name in an RSS author, which is an email address
+// optionally followed by the name in parentheses, e.g.,
+// "jane@example.com (Jane Doe)".
+func rssAuthor(author string) string {
+	author = strings.TrimSpace(author)
+	if open := strings.Index(author, "("); open >= 0 && strings.HasSuffix(author, ")") {
+		return strings.TrimSpace(author[open+1 : len(author)-1])
+	}
+	return author
+}
+
+// timeLayouts lists the date formats of feeds, RFC 822 variants for RSS and
+// RFC 3339 for Atom and Dublin Core.
+var timeLayouts = []string{
+	time.RFC3339,
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 02 Jan 2006 15:04 -0700",
+	"02 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseTime parses a feed date, returning the zero time if it's missing or in
+// an unknown format.
+func parseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+// resolve resolves href against base; it returns "" for an empty or invalid href.
+func resolve(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return ""
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if base == nil {
+		return ref.String()
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// isURL reports whether s is an absolute http or https URL.
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// firstNonEmpty returns the first of values that isn't blank, trimmed.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// stripTags removes the tags of an HTML fragment and unescapes its entities.
+func stripTags(s string) string {
+	var b strings.Builder
+	inTag := false
+	for _, r := range s {
+		switch {
+		case r == '<':
+			inTag = true
+		case r == '>' && inTag:
+			inTag = false
+		case !inTag:
+			b.WriteRune(r)
+		}
+	}
+	return strings.TrimSpace(html.UnescapeString(b.String()))
+}
+
+// HTML returns a standalone HTML page holding the entry, for conversion like
+// a fetched page: its title as <title> and <h1>, its author and publication
+// date as <meta> tags, and its content (its summary if the feed carries no
+// content) in <main><article>.
+func (e Entry) HTML() string {
+	var b strings.Builder
+	title := html.EscapeString(e.Title)
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	b.WriteString("<title>" + title + "</title>\n")
+	if e.Author != "" {
+		b.WriteString(`<meta name="author" content="` + html.EscapeString(e.Author) + "\">\n")
+	}
+	if !e.Published.IsZero() {
+		b.WriteString(`<meta property="article:published_time" content="` + e.Published.Format(time.RFC3339) + "\">\n")
+	}
+	if summary := stripTags(e.Summary); summary != "" && e.Content != "" {
+		b.WriteString(`<meta name="description" content="` + html.EscapeString(summary) + "\">\n")
+	}
+	b.WriteString("</head>\n<body>\n<main>\n<article>\n")
+	b.WriteString("<h1>" + title + "</h1>\n")
+	body := e.Content
+	if body == "" {
+		body = e.Summary
+	}
+	b.WriteString(body + "\n")
+	b.WriteString("</article>\n</main>\n</body>\n</html>\n")
+	return b.String()
+}
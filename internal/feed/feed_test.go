@@ -0,0 +1,177 @@
+package feed
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const rss2 = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+  <title>Example Engineering</title>
+  <atom:link href="https://example.com/feed.xml" rel="self" type="application/rss+xml"/>
+  <link>https://example.com/blog/</link>
+  <description>Posts from the Example team.</description>
+  <item>
+    <title>Faster builds &amp; smaller images</title>
+    <link>/blog/faster-builds</link>
+    <guid isPermaLink="false">post-2</guid>
+    <dc:creator>Jane Doe</dc:creator>
+    <pubDate>Tue, 04 Jun 2024 10:00:00 +0200</pubDate>
+    <description>How we cut build times in half.</description>
+    <content:encoded><![CDATA[<p>We cut build times <em>in half</em>.</p>]]></content:encoded>
+  </item>
+  <item>
+    <title>Release 1.0</title>
+    <guid>https://example.com/blog/release-1-0</guid>
+    <author>john@example.com (John Roe)</author>
+    <pubDate>Mon, 3 Jun 2024 09:30:00 GMT</pubDate>
+    <description>&lt;p&gt;Version 1.0 is out.&lt;/p&gt;</description>
+  </item>
+</channel>
+</rss>`
+
+const atom = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title type="html">Example &lt;b&gt;Changelog&lt;/b&gt;</title>
+  <subtitle>Every change to Example.</subtitle>
+  <link href="https://example.com/changelog.atom" rel="self"/>
+  <link href="https://example.com/changelog/"/>
+  <author><name>Example Team</name></author>
+  <entry>
+    <title>v2.1.0</title>
+    <link rel="alternate" href="https://example.com/changelog/v2-1-0"/>
+    <id>urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a</id>
+    <updated>2024-05-02T08:00:00Z</updated>
+    <summary>Adds retries.</summary>
+    <content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Adds <code>retries</code>.</p></div></content>
+  </entry>
+  <entry>
+    <title>v2.0.0</title>
+    <id>https://example.com/changelog/v2-0-0</id>
+    <author><name>Jane Doe</name></author>
+    <published>2024-04-01T12:00:00+09:00</published>
+    <content type="html">&lt;p&gt;Breaking changes.&lt;/p&gt;</content>
+  </entry>
+</feed>`
+
+const rss1 = `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel rdf:about="https://example.com/">
+    <title>Example News</title>
+    <link>https://example.com/</link>
+    <description>News.</description>
+  </channel>
+  <item rdf:about="https://example.com/news/1">
+    <title>First</title>
+    <link>https://example.com/news/1</link>
+    <dc:date>2024-01-15</dc:date>
+    <dc:creator>Jane Doe</dc:creator>
+    <description>The first news.</description>
+  </item>
+</rdf:RDF>`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  string
+		title string
+		url   string
+		want  []Entry
+	}{
+		{
+			name:  "rss 2.0",
+			data:  rss2,
+			title: "Example Engineering",
+			url:   "https://example.com/blog/",
+			want: []Entry{
+				{Title: "Faster builds & smaller images", URL: "https://example.com/blog/faster-builds", ID: "post-2", Author: "Jane Doe",
+					Published: time.Date(2024, 6, 4, 8, 0, 0, 0, time.UTC), Summary: "How we cut build times in half.", Content: "<p>We cut build times <em>in half</em>.</p>"},
+				{Title: "Release 1.0", URL: "https://example.com/blog/release-1-0", ID: "https://example.com/blog/release-1-0", Author: "John Roe",
+					Published: time.Date(2024, 6, 3, 9, 30, 0, 0, time.UTC), Summary: "<p>Version 1.0 is out.</p>"},
+			},
+		},
+		{
+			name:  "atom",
+			data:  atom,
+			title: "Example Changelog",
+			url:   "https://example.com/changelog/",
+			want: []Entry{
+				{Title: "v2.1.0", URL: "https://example.com/changelog/v2-1-0", ID: "urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a", Author: "Example Team",
+					Published: time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC), Updated: time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC),
+					Summary: "Adds retries.", Content: `<div xmlns="http://www.w3.org/1999/xhtml"><p>Adds <code>retries</code>.</p></div>`},
+				{Title: "v2.0.0", URL: "https://example.com/changelog/v2-0-0", ID: "https://example.com/changelog/v2-0-0", Author: "Jane Doe",
+					Published: time.Date(2024, 4, 1, 3, 0, 0, 0, time.UTC), Content: "<p>Breaking changes.</p>"},
+			},
+		},
+		{
+			name:  "rss 1.0",
+			data:  rss1,
+			title: "Example News",
+			url:   "https://example.com/",
+			want: []Entry{
+				{Title: "First", URL: "https://example.com/news/1", Author: "Jane Doe", Published: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC), Summary: "The first news."},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Parse([]byte(tt.data), "https://example.com/feed.xml")
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if f.Title != tt.title || f.URL != tt.url {
+				t.Errorf("Parse() title = %q, URL = %q; want %q, %q", f.Title, f.URL, tt.title, tt.url)
+			}
+			if len(f.Entries) != len(tt.want) {
+				t.Fatalf("Parse() got %d entries, want %d: %+v", len(f.Entries), len(tt.want), f.Entries)
+			}
+			for i, want := range tt.want {
+				got := f.Entries[i]
+				if got.Title != want.Title || got.URL != want.URL || got.ID != want.ID || got.Author != want.Author ||
+					!got.Published.Equal(want.Published) || !got.Updated.Equal(want.Updated) || got.Summary != want.Summary || got.Content != want.Content {
+					t.Errorf("entry %d =\n%+v\nwant\n%+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	if _, err := Parse([]byte(`<html><body>Not a feed</body></html>`), ""); !errors.Is(err, ErrNotFeed) {
+		t.Errorf("Parse() of HTML error = %v, want ErrNotFeed", err)
+	}
+	if _, err := Parse([]byte(`not xml at all`), ""); err == nil {
+		t.Error("Parse() of text succeeded")
+	}
+}
+
+func TestEntryHTML(t *testing.T) {
+	e := Entry{
+		Title:     "Faster builds & smaller images",
+		Author:    "Jane Doe",
+		Published: time.Date(2024, 6, 4, 8, 0, 0, 0, time.UTC),
+		Summary:   "How we cut <b>build times</b>.",
+		Content:   "<p>We cut build times.</p>",
+	}
+	page := e.HTML()
+	for _, want := range []string{
+		"<title>Faster builds &amp; smaller images</title>",
+		`<meta name="author" content="Jane Doe">`,
+		`<meta property="article:published_time" content="2024-06-04T08:00:00Z">`,
+		`<meta name="description" content="How we cut build times.">`,
+		"<h1>Faster builds &amp; smaller images</h1>\n<p>We cut build times.</p>",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("HTML() doesn't contain %q:\n%s", want, page)
+		}
+	}
+
+	// Without content, the summary is the body
+	e.Content = ""
+	if page := e.HTML(); !strings.Contains(page, "<h1>Faster builds &amp; smaller images</h1>\nHow we cut <b>build times</b>.") || strings.Contains(page, `name="description"`) {
+		t.Errorf("HTML() of an entry without content =\n%s", page)
+	}
+}
@@ -0,0 +1,160 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the download size budgets, which keep a runaway crawl
+// (huge binary downloads, endlessly generated pages) from filling the disk.
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/progress"
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+)
+
+// ErrPageTooLarge is returned, wrapped, when a response body is larger than
+// the page size limit (see SetSizeLimits).
+var ErrPageTooLarge = errors.New("page exceeds the size limit")
+
+// ErrSizeBudget is returned, wrapped, when a response body would take the
+// bytes downloaded by a crawl past its total size budget (see SetSizeLimits).
+var ErrSizeBudget = errors.New("crawl exceeds the size budget")
+
+// Reasons of the records of the pages skipped by the size limits.
+const (
+	// ReasonPageTooLarge is the Reason of the pages skipped because they are
+	// larger than the page size limit.
+	ReasonPageTooLarge = "page_too_large"
+	// ReasonSizeBudget is the Reason of the page at which the crawl stopped
+	// because it would have exceeded the total size budget.
+	ReasonSizeBudget = "size_budget"
+)
+
+// SetSizeLimits limits the size of the downloads of the crawls: a response
+// body larger than maxPageBytes is skipped with the reason ReasonPageTooLarge,
+// and the crawl stops at the response that would take the bytes downloaded
+// past maxTotalBytes, recording it with the reason ReasonSizeBudget and
+// marking the report SizeBudgetExceeded; the pages saved until then are kept
+// and the crawl succeeds. Both limits are checked against the Content-Length
+// of a response before its body is read, and while it is read, which is
+// aborted as soon as a limit is exceeded. 0 disables a limit (the default).
+func (f *Fetcher) SetSizeLimits(maxPageBytes, maxTotalBytes int64) {
+	f.maxPageBytes = max(maxPageBytes, 0)
+	f.maxTotalBytes = max(maxTotalBytes, 0)
+}
+
+// readBody reads body, the body of a response declaring contentLength bytes
+// (-1 if unknown), within the size limits, and counts it against the total
+// budget.
+//
+// Returns an error wrapping ErrPageTooLarge or ErrSizeBudget if a limit is
+// exceeded, without reading the rest of the body, or the error of the read.
+func (f *Fetcher) readBody(body io.Reader, contentLength int64) ([]byte, error) {
+	limit, budget := f.maxPageBytes, false
+	if f.maxTotalBytes > 0 {
+		f.mu.Lock()
+		remaining := max(f.maxTotalBytes-f.downloaded, 0)
+		f.mu.Unlock()
+		if limit == 0 || remaining < limit {
+			limit, budget = remaining, true
+		}
+	}
+	if limit == 0 && !budget {
+		data, err := io.ReadAll(body)
+		f.countDownload(len(data))
+		return data, err
+	}
+
+	exceeded := func(n string) error {
+		if budget {
+			return fmt.Errorf("%w of %s (%s downloaded before this page)", ErrSizeBudget, progress.FormatBytes(f.maxTotalBytes), progress.FormatBytes(f.maxTotalBytes-limit))
+		}
+		return fmt.Errorf("%w of %s (%s)", ErrPageTooLarge, progress.FormatBytes(f.maxPageBytes), n)
+	}
+	if contentLength > limit {
+		return nil, exceeded(progress.FormatBytes(contentLength))
+	}
+	data, err := io.ReadAll(io.LimitReader(body, limit+1))
+	f.countDownload(len(data))
+	if int64(len(data)) > limit {
+		return nil, exceeded("over " + progress.FormatBytes(limit))
+	}
+	return data, err
+}
+
+// countDownload adds n bytes to the bytes downloaded by the crawl.
+func (f *Fetcher) countDownload(n int) {
+	f.mu.Lock()
+	f.downloaded += int64(n)
+	f.mu.Unlock()
+}
+
+// sizeFailure records rec, whose body couldn't be read with err, as skipped
+// if a size limit was exceeded, and reports whether one was. It returns the
+// error stopping the crawl when the total budget was exceeded.
+func (f *Fetcher) sizeFailure(rec *PageRecord, err error) (bool, error) {
+	switch {
+	case errors.Is(err, ErrPageTooLarge):
+		rec.Reason = ReasonPageTooLarge
+	case errors.Is(err, ErrSizeBudget):
+		rec.Reason = ReasonSizeBudget
+	default:
+		return false, nil
+	}
+	warnlog.Printf("size_limit", "Warning: skipping %s: %v", rec.URL, err)
+	rec.Outcome = OutcomeSkipped
+	rec.Error = err.Error()
+	f.record(*rec)
+	if rec.Reason == ReasonSizeBudget {
+		return true, err
+	}
+	return true, nil
+}
+
+// budgetStop reports whether err, returned by a crawl, stopped it at the total
+// size budget, marking the report if so.
+func (f *Fetcher) budgetStop(err error) bool {
+	if !errors.Is(err, ErrSizeBudget) {
+		return false
+	}
+	f.report.SizeBudgetExceeded = true
+	slog.Warn("Download stopped at the size budget", "budget", progress.FormatBytes(f.maxTotalBytes), "pages", f.downloadCount)
+	return true
+}
+
+// sizeUnits are the units ParseSize accepts, by suffix, longest first.
+var sizeUnits = []struct {
+	suffix string
+	bytes  int64
+}{
+	{"KIB", 1 << 10}, {"MIB", 1 << 20}, {"GIB", 1 << 30}, {"TIB", 1 << 40},
+	{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"TB", 1 << 40},
+	{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+	{"B", 1},
+}
+
+// ParseSize parses a size in bytes, optionally with a unit: "500", "500B",
+// "10KB", "1.5MB", "2GB", or the same as "10KiB" or "10K". Units are powers of
+// 1024, as sizes are displayed (see progress.FormatBytes), case-insensitive,
+// and may be separated from the number by a space.
+//
+// Returns an error if s isn't a size or is negative.
+func ParseSize(s string) (int64, error) {
+	number := strings.ToUpper(strings.TrimSpace(s))
+	unit := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(number, u.suffix) {
+			number, unit = strings.TrimSpace(strings.TrimSuffix(number, u.suffix)), u.bytes
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 || math.IsInf(n, 0) || math.IsNaN(n) {
+		return 0, fmt.Errorf("invalid size %q (expected bytes, or a number with a unit such as 500KB, 10MB, or 2GiB)", s)
+	}
+	return int64(n * float64(unit)), nil
+}
@@ -0,0 +1,156 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/f4ah6o/site2skill-go/internal/httpcache"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int64
+		wantErr bool
+	}{
+		{in: "500", want: 500},
+		{in: "500B", want: 500},
+		{in: "10KB", want: 10 << 10},
+		{in: "1.5MB", want: 3 << 19},
+		{in: "2 gb", want: 2 << 30},
+		{in: "10KiB", want: 10240},
+		{in: "10mib", want: 10 << 20},
+		{in: "10M", want: 10 << 20},
+		{in: "1G", want: 1 << 30},
+		{in: "", wantErr: true},
+		{in: "-1MB", wantErr: true},
+		{in: "ten MB", wantErr: true},
+		{in: "Inf", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseSize(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSize(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseSize(%q) = %d, want %d", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchSizeLimits(t *testing.T) {
+	page := func(size int) string {
+		return "<html><body><p>" + strings.Repeat("x", size) + "</p></body></html>"
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<html><body><a href="/big">Big</a><a href="/stream">Stream</a>
+<a href="/a">A</a><a href="/b">B</a><a href="/c">C</a></body></html>`))
+		case "/small/":
+			w.Write([]byte(`<html><body><a href="/a">A</a><a href="/b">B</a><a href="/c">C</a></body></html>`))
+		case "/big":
+			w.Write([]byte(page(5000)))
+		case "/stream":
+			// Without a Content-Length, the size is only known while reading
+			w.Write([]byte(page(100)))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(page(5000)))
+		case "/a", "/b", "/c":
+			w.Write([]byte(page(1000)))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		start         string
+		maxPage       int64
+		maxTotal      int64
+		cache         bool
+		want          map[string]string
+		budgetStopped bool
+	}{
+		{
+			name:    "page limit",
+			start:   "/",
+			maxPage: 2000,
+			want: map[string]string{
+				"/big":    "skipped:" + ReasonPageTooLarge,
+				"/stream": "skipped:" + ReasonPageTooLarge,
+				"/a":      "saved",
+				"/c":      "saved",
+			},
+		},
+		{
+			name:    "page limit with the HTTP cache",
+			start:   "/",
+			maxPage: 2000,
+			cache:   true,
+			want: map[string]string{
+				"/big":    "skipped:" + ReasonPageTooLarge,
+				"/stream": "skipped:" + ReasonPageTooLarge,
+				"/a":      "saved",
+			},
+		},
+		{
+			name:     "total budget",
+			start:    "/small/",
+			maxTotal: 2500,
+			want: map[string]string{
+				"/a": "saved",
+				"/b": "saved",
+				"/c": "skipped:" + ReasonSizeBudget,
+			},
+			budgetStopped: true,
+		},
+		{
+			name:  "no limits",
+			start: "/",
+			want: map[string]string{
+				"/big":    "saved",
+				"/stream": "saved",
+				"/c":      "saved",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := New(t.TempDir())
+			f.delay = 0
+			f.SetSizeLimits(tt.maxPage, tt.maxTotal)
+			if tt.cache {
+				cache := httpcache.New(t.TempDir(), nil)
+				cache.MaxBodyBytes = tt.maxPage
+				f.SetTransport(cache)
+			}
+			if err := f.Fetch(server.URL + tt.start); err != nil {
+				t.Fatalf("Fetch() returned error: %v", err)
+			}
+
+			outcomes := make(map[string]string)
+			for _, rec := range f.Report().Pages {
+				outcome := string(rec.Outcome)
+				if rec.Reason != "" {
+					outcome += ":" + rec.Reason
+				}
+				outcomes[strings.TrimPrefix(rec.URL, server.URL)] = outcome
+			}
+			for u, want := range tt.want {
+				if outcomes[u] != want {
+					t.Errorf("%s = %q, want %q (all: %v)", u, outcomes[u], want, outcomes)
+				}
+			}
+			if f.Report().SizeBudgetExceeded != tt.budgetStopped {
+				t.Errorf("SizeBudgetExceeded = %t, want %t", f.Report().SizeBudgetExceeded, tt.budgetStopped)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the handling of canonical URLs declared with
+// <link rel="canonical">.
+package fetcher
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// SetIgnoreCanonical makes the fetcher ignore the canonical URLs that pages
+// declare, saving every page under its own URL. Use it for sites whose
+// canonical links are wrong, such as every page naming the home page.
+func (f *Fetcher) SetIgnoreCanonical(ignore bool) {
+	f.ignoreCanonical = ignore
+}
+
+// SetSkipExternalCanonical makes the fetcher skip pages whose canonical URL is
+// outside the crawl scope (another host, excluded by the URL filters, or not a
+// page), such as copies of content whose original lives elsewhere. By default
+// they are saved under their own URL.
+func (f *Fetcher) SetSkipExternalCanonical(skip bool) {
+	f.skipExternalCanonical = skip
+}
+
+// ExtractCanonical returns the href of the first <link rel="canonical"> of doc,
+// or "" if it has none.
+func ExtractCanonical(doc *html.Node) string {
+	var href string
+	var find func(*html.Node) bool
+	find = func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "link" {
+			var rel, h string
+			for _, attr := range n.Attr {
+				switch attr.Key {
+				case "rel":
+					rel = attr.Val
+				case "href":
+					h = attr.Val
+				}
+			}
+			for _, r := range strings.Fields(strings.ToLower(rel)) {
+				if r == "canonical" && strings.TrimSpace(h) != "" {
+					href = strings.TrimSpace(h)
+					return true
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if find(c) {
+				return true
+			}
+		}
+		return false
+	}
+	if doc != nil {
+		find(doc)
+	}
+	return href
+}
+
+// claimCanonical decides the identity of a fetched page from its canonical
+// link: the canonical URL when it is in the crawl scope, otherwise pageURL.
+// The identity is claimed so that no other page is saved as the same page,
+// and a canonical URL is marked visited since its content has been fetched.
+// rec.Canonical records a canonical URL differing from pageURL.
+//
+// Returns the identity, or "" and the reason to skip the page: another page
+// was already saved with this identity ("duplicate_canonical"), or the
+// canonical URL is out of scope and SetSkipExternalCanonical is on
+// ("canonical_out_of_scope").
+func (f *Fetcher) claimCanonical(doc *html.Node, pageURL string, rec *PageRecord) (string, string) {
+	identity := pageURL
+	if href := ExtractCanonical(doc); href != "" && !f.ignoreCanonical {
+		canonical, err := resolveURL(pageURL, href)
+		canonical = f.normalizeURL(canonical)
+		if err == nil && canonical != pageURL {
+			rec.Canonical = canonical
+			if f.inScope(canonical) {
+				identity = canonical
+			} else if f.skipExternalCanonical {
+				return "", "canonical_out_of_scope"
+			}
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.savedCanonical[identity] {
+		return "", "duplicate_canonical"
+	}
+	f.savedCanonical[identity] = true
+	f.visited[identity] = true
+	return identity, ""
+}
+
+// inScope reports whether the crawl would save targetURL if it found a link to it.
+func (f *Fetcher) inScope(targetURL string) bool {
+	u, err := url.Parse(targetURL)
+	return err == nil && u.Host == f.domain && f.shouldCrawlURL(targetURL, 1) && !isNonHTMLResource(targetURL)
+}
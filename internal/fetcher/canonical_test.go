@@ -0,0 +1,136 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestExtractCanonical(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{name: "canonical link", html: `<link rel="canonical" href="https://example.com/docs/guide">`, want: "https://example.com/docs/guide"},
+		{name: "several rel values", html: `<link rel="alternate Canonical" href=" /guide ">`, want: "/guide"},
+		{name: "first link wins", html: `<link rel="canonical" href="/a"><link rel="canonical" href="/b">`, want: "/a"},
+		{name: "empty href", html: `<link rel="canonical" href="">`, want: ""},
+		{name: "no canonical", html: `<link rel="alternate" hreflang="ja" href="/ja/">`, want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader("<html><head>" + tt.html + "</head><body></body></html>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ExtractCanonical(doc); got != tt.want {
+				t.Errorf("ExtractCanonical() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchCanonical(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/docs/":
+			w.Write([]byte(`<html><body><a href="/docs/guide?ref=nav">Guide</a><a href="/docs/guide?ref=footer">Guide</a>
+<a href="/docs/guide">Guide</a><a href="/docs/mirror">Mirror</a></body></html>`))
+		case "/docs/guide":
+			w.Write([]byte(`<html><head><link rel="canonical" href="/docs/guide"></head><body>Guide</body></html>`))
+		case "/docs/mirror":
+			w.Write([]byte(`<html><head><link rel="canonical" href="https://elsewhere.example/original"></head><body>Copy</body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		name         string
+		ignore       bool
+		skipExternal bool
+		wantOutcomes map[string]string
+		wantFiles    []string
+	}{
+		{
+			name: "canonical URL is the identity",
+			wantOutcomes: map[string]string{
+				"/docs/guide?ref=nav":    "saved",
+				"/docs/guide?ref=footer": "skipped:duplicate_canonical",
+				"/docs/mirror":           "saved",
+			},
+			wantFiles: []string{"docs/guide.html", "docs/mirror.html"},
+		},
+		{
+			name:         "external canonical skipped",
+			skipExternal: true,
+			wantOutcomes: map[string]string{
+				"/docs/guide?ref=nav": "saved",
+				"/docs/mirror":        "skipped:canonical_out_of_scope",
+			},
+			wantFiles: []string{"docs/guide.html"},
+		},
+		{
+			name:   "canonical ignored",
+			ignore: true,
+			wantOutcomes: map[string]string{
+				"/docs/guide?ref=nav":    "saved",
+				"/docs/guide?ref=footer": "saved",
+				"/docs/guide":            "saved",
+			},
+			wantFiles: []string{"docs/guide_q_ref_nav.html", "docs/guide_q_ref_footer.html", "docs/guide.html"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			f := New(dir)
+			f.delay = 0
+			f.SetIgnoreCanonical(tt.ignore)
+			f.SetSkipExternalCanonical(tt.skipExternal)
+			if err := f.Fetch(server.URL + "/docs/"); err != nil {
+				t.Fatalf("Fetch() returned error: %v", err)
+			}
+
+			outcomes := make(map[string]string)
+			for _, rec := range f.Report().Pages {
+				outcome := string(rec.Outcome)
+				if rec.Reason != "" {
+					outcome += ":" + rec.Reason
+				}
+				outcomes[strings.TrimPrefix(rec.URL, server.URL)] = outcome
+			}
+			for u, want := range tt.wantOutcomes {
+				if outcomes[u] != want {
+					t.Errorf("%s = %q, want %q (all: %v)", u, outcomes[u], want, outcomes)
+				}
+			}
+			for _, file := range tt.wantFiles {
+				if _, err := os.Stat(filepath.Join(dir, "crawl", host, filepath.FromSlash(file))); err != nil {
+					t.Errorf("%s not saved: %v", file, err)
+				}
+			}
+			if !tt.ignore {
+				if _, err := os.Stat(filepath.Join(dir, "crawl", host, "docs", "guide_q_ref_nav.html")); err == nil {
+					t.Error("query-parameter variant saved under its own URL")
+				}
+				for _, rec := range f.Report().Pages {
+					if rec.URL == server.URL+"/docs/guide?ref=nav" && rec.Canonical != server.URL+"/docs/guide" {
+						t.Errorf("Canonical = %q, want %s/docs/guide", rec.Canonical, server.URL)
+					}
+				}
+			}
+		})
+	}
+}
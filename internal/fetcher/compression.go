@@ -0,0 +1,122 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements compression: responses are requested gzip or brotli
+// compressed and decompressed as they are read, and the crawled HTML pages
+// can be stored gzip-compressed, which divides the size of the crawl
+// directory of a large site several times.
+package fetcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// AcceptEncoding is the Accept-Encoding header of the requests of the
+// transports returned by CompressionTransport.
+const AcceptEncoding = "gzip, br"
+
+// CompressedExt is the extension appended to the crawled pages stored
+// compressed (see SetCompressCrawl).
+const CompressedExt = ".gz"
+
+// CompressionTransport returns a transport sending the requests of base with
+// the Accept-Encoding AcceptEncoding, and decompressing the gzip or brotli
+// responses, which lose their Content-Encoding and Content-Length headers and
+// are marked Uncompressed. Requests carrying an Accept-Encoding of their own
+// or a Range, and their responses, are left as they are. A caching transport
+// (see package httpcache) placed on top of it stores the decompressed
+// responses.
+func CompressionTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &compressionTransport{base: base}
+}
+
+// compressionTransport is the transport returned by CompressionTransport.
+type compressionTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *compressionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Accept-Encoding") != "" || req.Header.Get("Range") != "" {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Accept-Encoding", AcceptEncoding)
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if req.Method == http.MethodHead || resp.StatusCode == http.StatusNoContent || resp.StatusCode == http.StatusNotModified {
+		return resp, nil
+	}
+
+	var decode func(io.Reader) (io.Reader, error)
+	switch strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))) {
+	case "gzip", "x-gzip":
+		decode = func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) }
+	case "br":
+		decode = func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil }
+	default:
+		return resp, nil
+	}
+	resp.Body = &decodedBody{body: resp.Body, decode: decode}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
+
+// decodedBody is the body of a compressed response, decompressed as it is
+// read. The decompressor is created on the first read, since gzip reads its
+// header when it is.
+type decodedBody struct {
+	body   io.ReadCloser
+	decode func(io.Reader) (io.Reader, error)
+	r      io.Reader
+	err    error
+}
+
+// Read implements io.Reader.
+func (b *decodedBody) Read(p []byte) (int, error) {
+	if b.r == nil && b.err == nil {
+		b.r, b.err = b.decode(b.body)
+	}
+	if b.err != nil {
+		return 0, b.err
+	}
+	return b.r.Read(p)
+}
+
+// Close implements io.Closer.
+func (b *decodedBody) Close() error {
+	return b.body.Close()
+}
+
+// SetCompressCrawl enables or disables the compression of the crawled HTML
+// pages: when enabled, they are stored gzip-compressed, with the extension
+// CompressedExt appended (page.html.gz), which the converter decompresses as
+// it reads them (see converter.ConvertPage).
+func (f *Fetcher) SetCompressCrawl(compress bool) {
+	f.compressCrawl = compress
+}
+
+// compressPage returns body, the HTML page to be saved at filePath, and the
+// path to save it at: both compressed if the crawl is.
+func (f *Fetcher) compressPage(filePath string, body []byte) (string, []byte) {
+	if !f.compressCrawl || !strings.HasSuffix(filePath, ".html") {
+		return filePath, body
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(body)
+	zw.Close()
+	return filePath + CompressedExt, buf.Bytes()
+}
@@ -0,0 +1,104 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestCompressionTransport(t *testing.T) {
+	const page = "<html><body><p>Compressed</p></body></html>"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Accept-Encoding", r.Header.Get("Accept-Encoding"))
+		var buf bytes.Buffer
+		switch r.URL.Path {
+		case "/gzip":
+			zw := gzip.NewWriter(&buf)
+			zw.Write([]byte(page))
+			zw.Close()
+			w.Header().Set("Content-Encoding", "gzip")
+		case "/br":
+			bw := brotli.NewWriter(&buf)
+			bw.Write([]byte(page))
+			bw.Close()
+			w.Header().Set("Content-Encoding", "br")
+		default:
+			buf.WriteString(page)
+		}
+		w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: CompressionTransport(http.DefaultTransport)}
+	for _, path := range []string{"/gzip", "/br", "/identity"} {
+		t.Run(path, func(t *testing.T) {
+			resp, err := client.Get(server.URL + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != page {
+				t.Errorf("body = %q, want %q", body, page)
+			}
+			if got := resp.Header.Get("X-Accept-Encoding"); got != AcceptEncoding {
+				t.Errorf("Accept-Encoding = %q, want %q", got, AcceptEncoding)
+			}
+			if got := resp.Header.Get("Content-Encoding"); got != "" {
+				t.Errorf("Content-Encoding = %q, want none", got)
+			}
+		})
+	}
+}
+
+func TestFetchCompressCrawl(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<html><body><a href="/guide">Guide</a></body></html>`))
+		case "/guide":
+			w.Write([]byte(`<html><body><p>Guide</p></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	f := New(dir)
+	f.delay = 0
+	f.SetCompressCrawl(true)
+	if err := f.Fetch(server.URL + "/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	data, err := os.ReadFile(filepath.Join(dir, "crawl", host, "guide.html.gz"))
+	if err != nil {
+		t.Fatalf("compressed page not saved: %v", err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page, _ := io.ReadAll(zr); !strings.Contains(string(page), "<p>Guide</p>") {
+		t.Errorf("decompressed page = %q", page)
+	}
+	for _, rec := range f.Report().Pages {
+		if rec.Outcome == OutcomeSaved && !strings.HasSuffix(rec.OutputFile, ".html.gz") {
+			t.Errorf("OutputFile = %q, want a compressed page", rec.OutputFile)
+		}
+	}
+}
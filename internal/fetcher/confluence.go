@@ -0,0 +1,141 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements Confluence mode, which reads the pages of a
+// Confluence space through its REST API instead of crawling its web
+// interface.
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/confluence"
+)
+
+// ErrConfluenceUnavailable is returned by FetchConfluence when the space or
+// its pages can't be read through the REST API.
+var ErrConfluenceUnavailable = errors.New("confluence space unavailable")
+
+// SetConfluenceToken sets the token authenticating the requests of
+// FetchConfluence to the REST API (see confluence.Authorization): a
+// Confluence Cloud API token given as "EMAIL:API_TOKEN", or a Confluence Data
+// Center personal access token. Without a token only public spaces can be
+// read. The token is never logged.
+func (f *Fetcher) SetConfluenceToken(token string) {
+	f.confluenceToken = token
+}
+
+// FetchConfluence reads the current pages of the Confluence space s through
+// its REST API into the crawl directory, one page per Confluence page, built
+// from its storage format (see confluence.Content.HTML) and saved like the
+// page at its URL in the hierarchy of the space (see confluence.Content.URL),
+// e.g., ".../spaces/ENG/onboarding/local-setup". The home page of the space
+// is saved at the URL of the space, with depth 0, and every other page at the
+// depth of its level in the hierarchy.
+//
+// The saved records carry the URL of the page in the web interface as their
+// FinalURL and the time of its current version as their LastModified. The URL
+// filters and path patterns apply to the URLs in the hierarchy; the depth
+// limit doesn't, since every page of the space is read.
+// The requests are sent with the headers set with SetHeaders and the token
+// set with SetConfluenceToken; robots.txt doesn't apply to the API.
+//
+// Returns an error wrapping ErrConfluenceUnavailable if the space or its
+// pages can't be read.
+func (f *Fetcher) FetchConfluence(s *confluence.Space) error {
+	return f.FetchConfluenceContext(context.Background(), s)
+}
+
+// FetchConfluenceContext is like FetchConfluence but stops when ctx is
+// cancelled or its deadline expires, like FetchContext.
+func (f *Fetcher) FetchConfluenceContext(ctx context.Context, s *confluence.Space) error {
+	_, crawlDir, err := f.begin(s.URL)
+	if err != nil {
+		return err
+	}
+	return f.end(ctx, f.crawlConfluence(ctx, s, crawlDir))
+}
+
+// crawlConfluence reads the pages of s and saves them in crawlDir.
+func (f *Fetcher) crawlConfluence(ctx context.Context, s *confluence.Space, crawlDir string) error {
+	content, err := confluence.Fetch(ctx, s, f.getConfluence)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %v", ErrConfluenceUnavailable, err)
+	}
+	slog.Info("Confluence space", "key", s.Key, "name", content.Name, "pages", len(content.Pages))
+
+	f.queue(len(content.Pages))
+	for _, p := range content.Pages {
+		f.queue(-1)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		pageURL, depth := content.URL(p), content.Depth(p)
+		if !f.shouldCrawlURL(pageURL, depth) {
+			f.recordSkip(pageURL, depth, "url_filter")
+			continue
+		}
+		f.mu.Lock()
+		seen := f.visited[pageURL]
+		f.visited[pageURL] = true
+		f.mu.Unlock()
+		if seen {
+			continue
+		}
+
+		parsedURL, err := url.Parse(pageURL)
+		if err != nil {
+			f.recordSkip(pageURL, depth, "invalid_url")
+			continue
+		}
+		body := []byte(content.HTML(p))
+		rec := PageRecord{
+			URL:         pageURL,
+			FinalURL:    p.WebURL,
+			Depth:       depth,
+			StatusCode:  http.StatusOK,
+			ContentType: "text/html; charset=utf-8",
+			Bytes:       int64(len(body)),
+			Outcome:     OutcomeFailed,
+		}
+		if !p.Updated.IsZero() {
+			rec.LastModified = p.Updated.Format(time.RFC3339)
+		}
+		if f.save(&rec, parsedURL.String(), f.getFilePath(crawlDir, parsedURL), body) {
+			f.downloadCount++
+		}
+	}
+	return nil
+}
+
+// getConfluence returns the body of the successful response to a GET
+// request to apiURL, a URL of the Confluence REST API.
+func (f *Fetcher) getConfluence(ctx context.Context, apiURL string) ([]byte, error) {
+	req, err := f.newRequest(ctx, "GET", apiURL)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if f.confluenceToken != "" {
+		req.Header.Set("Authorization", confluence.Authorization(f.confluenceToken))
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, fmt.Errorf("%s returned status %d; check the Confluence token", apiURL, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("%s returned status %d", apiURL, resp.StatusCode)
+	}
+	return f.readBody(resp.Body, resp.ContentLength)
+}
@@ -0,0 +1,99 @@
+package fetcher
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/f4ah6o/site2skill-go/internal/confluence"
+)
+
+func TestFetchConfluence(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer pat" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/wiki/rest/api/space/ENG":
+			w.Write([]byte(`{"key":"ENG","name":"Engineering","homepage":{"id":"1","title":"Engineering"}}`))
+		case r.URL.Path == "/wiki/rest/api/content" && r.URL.Query().Get("start") == "":
+			if r.URL.Query().Get("spaceKey") != "ENG" || !strings.Contains(r.URL.Query().Get("expand"), "body.storage") {
+				t.Errorf("unexpected content query %q", r.URL.RawQuery)
+			}
+			w.Write([]byte(`{"results":[
+{"id":"1","title":"Engineering","ancestors":[],"body":{"storage":{"value":"<p>Welcome.</p>"}},
+ "version":{"number":3,"when":"2024-05-01T10:00:00.000Z"},"_links":{"webui":"/spaces/ENG/overview"}},
+{"id":"2","title":"Onboarding","ancestors":[{"id":"1","title":"Engineering"}],"body":{"storage":{"value":"<p>Start here.</p>"}},
+ "version":{"number":1,"when":"2024-05-02T10:00:00.000Z"},"_links":{"webui":"/spaces/ENG/pages/2/Onboarding"}}
+],"_links":{"next":"/rest/api/content?spaceKey=ENG&start=2"}}`))
+		case r.URL.Path == "/wiki/rest/api/content":
+			w.Write([]byte(`{"results":[
+{"id":"3","title":"Local Setup","ancestors":[{"id":"1","title":"Engineering"},{"id":"2","title":"Onboarding"}],
+ "body":{"storage":{"value":"<p>Install Go.</p>"}},"version":{"number":2,"when":"2024-05-03T10:00:00.000Z"},
+ "_links":{"webui":"/spaces/ENG/pages/3/Local+Setup"}},
+{"id":"4","title":"Internal","ancestors":[{"id":"1","title":"Engineering"}],"body":{"storage":{"value":"<p>Secret.</p>"}}}
+],"_links":{}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	s, err := confluence.Parse(server.URL + "/wiki/spaces/ENG")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	f := New(dir)
+	f.delay = 0
+	f.SetURLFilters(nil, []string{"internal"})
+	f.SetConfluenceToken("pat")
+	if err := f.FetchConfluence(s); err != nil {
+		t.Fatalf("FetchConfluence() returned error: %v", err)
+	}
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	space := server.URL + "/wiki/spaces/ENG"
+	want := map[string]struct {
+		file, final, modified string
+		depth                 int
+	}{
+		space:                             {"crawl/" + host + "/wiki/spaces/ENG.html", server.URL + "/wiki/spaces/ENG/overview", "2024-05-01T10:00:00Z", 0},
+		space + "/onboarding":             {"crawl/" + host + "/wiki/spaces/ENG/onboarding.html", server.URL + "/wiki/spaces/ENG/pages/2/Onboarding", "2024-05-02T10:00:00Z", 1},
+		space + "/onboarding/local-setup": {"crawl/" + host + "/wiki/spaces/ENG/onboarding/local-setup.html", server.URL + "/wiki/spaces/ENG/pages/3/Local+Setup", "2024-05-03T10:00:00Z", 2},
+	}
+	saved := f.Report().SavedPages()
+	if len(saved) != len(want) {
+		t.Errorf("saved %d pages, want %d: %v", len(saved), len(want), saved)
+	}
+	for _, rec := range saved {
+		w, ok := want[rec.URL]
+		if !ok {
+			t.Errorf("unexpected page %s", rec.URL)
+			continue
+		}
+		if rec.OutputFile != w.file || rec.FinalURL != w.final || rec.LastModified != w.modified || rec.Depth != w.depth {
+			t.Errorf("%s: OutputFile, FinalURL, LastModified, Depth = %q, %q, %q, %d, want %q, %q, %q, %d",
+				rec.URL, rec.OutputFile, rec.FinalURL, rec.LastModified, rec.Depth, w.file, w.final, w.modified, w.depth)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "crawl", host, "wiki", "spaces", "ENG", "onboarding", "local-setup.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "<h1>Local Setup</h1><p>Install Go.</p>") {
+		t.Errorf("page = %s", data)
+	}
+
+	f = New(t.TempDir())
+	f.delay = 0
+	if err := f.FetchConfluence(s); !errors.Is(err, ErrConfluenceUnavailable) || !strings.Contains(err.Error(), "token") {
+		t.Errorf("FetchConfluence() without a token error = %v, want ErrConfluenceUnavailable", err)
+	}
+}
@@ -0,0 +1,211 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the content-type filter, which selects the responses
+// saved by their media type, and the normalization of their character
+// encoding: every page is saved in UTF-8, whatever the site serves, so that
+// the conversion never sees Shift_JIS or windows-1252 bytes.
+package fetcher
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// DefaultContentTypes are the media types of the responses saved by default.
+var DefaultContentTypes = []string{"text/html", "text/plain", "text/markdown"}
+
+// ReasonContentType is the Reason of the pages skipped because their media
+// type isn't one of the content types saved (see SetContentTypes).
+const ReasonContentType = "non_html_content_type"
+
+// SetContentTypes sets the media types of the responses saved, by default
+// DefaultContentTypes: "text/html", or all those of a type, such as "text/*".
+// Responses of other types are skipped with the reason ReasonContentType, and
+// responses without a Content-Type are taken for HTML. HTML pages are crawled
+// for links; the other documents, such as text/markdown and text/plain
+// responses, are saved as Markdown files (see textFilePath) and aren't. A nil
+// or empty types restores the default.
+//
+// Returns an error if a type isn't a media type (see ParseContentType).
+func (f *Fetcher) SetContentTypes(types []string) error {
+	normalized := make([]string, 0, len(types))
+	for _, t := range types {
+		mediaType, err := ParseContentType(t)
+		if err != nil {
+			return err
+		}
+		normalized = append(normalized, mediaType)
+	}
+	if len(normalized) == 0 {
+		normalized = DefaultContentTypes
+	}
+	f.contentTypes = normalized
+	return nil
+}
+
+// ParseContentType returns t, a media type such as "text/html" or a whole
+// type such as "text/*", in lower case.
+//
+// Returns an error if t isn't a media type.
+func ParseContentType(t string) (string, error) {
+	mediaType := strings.ToLower(strings.TrimSpace(t))
+	typ, sub, ok := strings.Cut(mediaType, "/")
+	if !ok || typ == "" || typ == "*" || sub == "" || strings.ContainsAny(mediaType, ";, ") {
+		return "", fmt.Errorf("invalid content type %q (expected a media type such as text/html or text/*)", t)
+	}
+	return mediaType, nil
+}
+
+// acceptsContentType returns the media type of contentType, the Content-Type
+// of a response, and reports whether the response is saved. A response
+// without a Content-Type is taken for HTML.
+func (f *Fetcher) acceptsContentType(contentType string) (string, bool) {
+	if contentType == "" {
+		return "text/html", true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", false
+	}
+	types := f.contentTypes
+	if types == nil {
+		types = DefaultContentTypes
+	}
+	for _, t := range types {
+		if t == mediaType || strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*")) {
+			return mediaType, true
+		}
+	}
+	return mediaType, false
+}
+
+// isHTMLMediaType reports whether the documents of mediaType are HTML pages.
+func isHTMLMediaType(mediaType string) bool {
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// textFilePath returns the path in crawlDir of the text document at
+// parsedURL, saved as a Markdown file: its path with the extension .md, which
+// is appended to any other extension ("notes.txt.md"; see
+// site2skill.reconstructURL).
+func (f *Fetcher) textFilePath(crawlDir string, parsedURL *url.URL) string {
+	filePath := f.getFilePath(crawlDir, parsedURL)
+	if path.Ext(parsedURL.Path) == "" {
+		filePath = strings.TrimSuffix(filePath, ".html")
+	}
+	if !strings.EqualFold(path.Ext(filePath), ".md") {
+		filePath += ".md"
+	}
+	return filePath
+}
+
+// languageEncodings are the legacy encodings tried, in order, for the pages
+// of a language that declare no encoding and aren't valid UTF-8. Japanese
+// pages are tried in EUC-JP first: Shift_JIS text is rarely valid EUC-JP,
+// while EUC-JP text usually decodes as Shift_JIS half-width katakana.
+var languageEncodings = map[string][]string{
+	"ja": {"euc-jp", "shift_jis"},
+	"ko": {"euc-kr"},
+	"zh": {"gbk", "big5"},
+}
+
+// htmlLangPattern matches the lang attribute of the html element.
+var htmlLangPattern = regexp.MustCompile(`(?i)<html[^>]+lang=["']?([a-zA-Z]+)`)
+
+// metaCharsetPattern matches the charset declared by a meta tag, in either
+// form: <meta charset="..."> or <meta http-equiv="Content-Type"
+// content="text/html; charset=...">.
+var metaCharsetPattern = regexp.MustCompile(`(?i)(<meta[^>]+charset=["']?)([^"'\s;>]+)`)
+
+// toUTF8 returns body, a response of mediaType with the Content-Type
+// contentType and the Content-Language language, in UTF-8, with the name of
+// the encoding it was decoded from, or "" if it was UTF-8 already; the meta
+// tags of an HTML page declare UTF-8 in any case. The encoding is the first
+// of:
+//
+//  1. the byte order mark of the body
+//  2. the charset of contentType
+//  3. for HTML, the charset of its meta tags
+//  4. UTF-8, if the body is valid UTF-8
+//  5. the legacy encodings of its language (the Content-Language, else the
+//     lang attribute of an HTML page; see languageEncodings) the body is
+//     valid in
+//  6. windows-1252, the default encoding of the web
+func toUTF8(body []byte, mediaType, contentType, language string) ([]byte, string) {
+	html := isHTMLMediaType(mediaType)
+	enc := bomEncoding(body)
+	if enc == nil {
+		enc = getEncodingFromContentType(contentType)
+	}
+	if enc == nil && html {
+		enc = getEncodingFromMeta(body)
+	}
+	if enc == nil && utf8.Valid(body) {
+		return body, ""
+	}
+	if enc == nil {
+		if language == "" && html {
+			if m := htmlLangPattern.FindSubmatch(body); m != nil {
+				language = string(m[1])
+			}
+		}
+		enc = guessEncoding(body, language)
+	}
+
+	out, name := bytes.TrimPrefix(body, []byte("\xef\xbb\xbf")), ""
+	if n, _ := htmlindex.Name(enc); n != "utf-8" {
+		decoded, err := decodeWithEncoding(body, enc)
+		if err != nil {
+			return body, ""
+		}
+		out, name = []byte(strings.TrimPrefix(decoded, "\ufeff")), n
+	}
+	if html {
+		// The page is read as it declares (see converter.ConvertPage)
+		out = metaCharsetPattern.ReplaceAll(out, []byte("${1}utf-8"))
+	}
+	return out, name
+}
+
+// bomEncoding returns the encoding of the byte order mark body starts with,
+// or nil if it has none.
+func bomEncoding(body []byte) encoding.Encoding {
+	switch {
+	case bytes.HasPrefix(body, []byte("\xef\xbb\xbf")):
+		return unicode.UTF8
+	case bytes.HasPrefix(body, []byte("\xfe\xff")):
+		return unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	case bytes.HasPrefix(body, []byte("\xff\xfe")):
+		return unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	}
+	return nil
+}
+
+// guessEncoding returns the first of the legacy encodings of language (see
+// languageEncodings) in which body decodes without invalid sequences, or
+// windows-1252.
+func guessEncoding(body []byte, language string) encoding.Encoding {
+	// A Content-Language may list several languages: "ja-JP, en"
+	language, _, _ = strings.Cut(language, ",")
+	language, _, _ = strings.Cut(strings.ToLower(strings.TrimSpace(language)), "-")
+	for _, name := range languageEncodings[language] {
+		enc, err := htmlindex.Get(name)
+		if err != nil {
+			continue
+		}
+		if decoded, err := decodeWithEncoding(body, enc); err == nil && !strings.ContainsRune(decoded, utf8.RuneError) {
+			return enc
+		}
+	}
+	enc, _ := htmlindex.Get("windows-1252")
+	return enc
+}
@@ -0,0 +1,226 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func encode(t *testing.T, enc encoding.Encoding, s string) []byte {
+	t.Helper()
+	b, err := enc.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestToUTF8(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		mediaType   string
+		contentType string
+		language    string
+		want        string
+		wantCharset string
+	}{
+		{
+			name:        "charset of the Content-Type",
+			body:        encode(t, japanese.ShiftJIS, "<p>日本語のページ</p>"),
+			mediaType:   "text/html",
+			contentType: "text/html; charset=Shift_JIS",
+			want:        "<p>日本語のページ</p>",
+			wantCharset: "shift_jis",
+		},
+		{
+			name:        "charset of a meta tag, rewritten",
+			body:        encode(t, korean.EUCKR, `<meta charset="euc-kr"><p>한국어</p>`),
+			mediaType:   "text/html",
+			want:        `<meta charset="utf-8"><p>한국어</p>`,
+			wantCharset: "euc-kr",
+		},
+		{
+			name:        "meta tag contradicting the Content-Type",
+			body:        []byte(`<meta http-equiv="Content-Type" content="text/html; charset=Shift_JIS"><p>日本語</p>`),
+			mediaType:   "text/html",
+			contentType: "text/html; charset=utf-8",
+			want:        `<meta http-equiv="Content-Type" content="text/html; charset=utf-8"><p>日本語</p>`,
+		},
+		{
+			name:        "undeclared EUC-JP page",
+			body:        encode(t, japanese.EUCJP, `<html lang="ja"><p>日本語のページです</p></html>`),
+			mediaType:   "text/html",
+			want:        `<html lang="ja"><p>日本語のページです</p></html>`,
+			wantCharset: "euc-jp",
+		},
+		{
+			name:        "undeclared Shift_JIS text",
+			body:        encode(t, japanese.ShiftJIS, "日本語のテキストです"),
+			mediaType:   "text/plain",
+			language:    "ja-JP, en",
+			want:        "日本語のテキストです",
+			wantCharset: "shift_jis",
+		},
+		{
+			name:        "undeclared GBK text",
+			body:        encode(t, simplifiedchinese.GBK, "中文文档"),
+			mediaType:   "text/markdown",
+			language:    "zh-CN",
+			want:        "中文文档",
+			wantCharset: "gbk",
+		},
+		{
+			name:        "undeclared legacy page of unknown language",
+			body:        encode(t, charmap.Windows1252, "<p>Café – déjà vu</p>"),
+			mediaType:   "text/html",
+			want:        "<p>Café – déjà vu</p>",
+			wantCharset: "windows-1252",
+		},
+		{
+			name:      "UTF-8 with a byte order mark",
+			body:      []byte("\xef\xbb\xbf# Überblick"),
+			mediaType: "text/markdown",
+			want:      "# Überblick",
+		},
+		{
+			name:      "UTF-8",
+			body:      []byte("<p>日本語</p>"),
+			mediaType: "text/html",
+			want:      "<p>日本語</p>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, charset := toUTF8(tt.body, tt.mediaType, tt.contentType, tt.language)
+			if string(got) != tt.want || charset != tt.wantCharset {
+				t.Errorf("toUTF8() = %q, %q, want %q, %q", got, charset, tt.want, tt.wantCharset)
+			}
+		})
+	}
+}
+
+func TestFetchContentTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html; charset=Shift_JIS")
+			w.Write(encode(t, japanese.ShiftJIS, `<html><head><meta charset="Shift_JIS"></head><body><p>ようこそ</p>
+<a href="/guide">Guide</a><a href="/notes">Notes</a><a href="/data">Data</a></body></html>`))
+		case "/guide":
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+			w.Write([]byte("# Guide\n\n[Not followed](/hidden)\n"))
+		case "/notes":
+			w.Header().Set("Content-Type", "text/plain; charset=euc-jp")
+			w.Write(encode(t, japanese.EUCJP, "メモ"))
+		case "/data":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name  string
+		types []string
+		want  map[string]string
+		files map[string]string
+	}{
+		{
+			name: "default",
+			want: map[string]string{
+				"/":       "saved",
+				"/guide":  "saved",
+				"/notes":  "saved",
+				"/data":   "skipped:" + ReasonContentType,
+				"/hidden": "",
+			},
+			files: map[string]string{
+				"index.html": "ようこそ",
+				"guide.md":   "# Guide",
+				"notes.md":   "メモ",
+			},
+		},
+		{
+			name:  "custom",
+			types: []string{"text/html", "application/*"},
+			want: map[string]string{
+				"/guide": "skipped:" + ReasonContentType,
+				"/notes": "skipped:" + ReasonContentType,
+				"/data":  "saved",
+			},
+			files: map[string]string{"data.md": "{}"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			f := New(dir)
+			f.delay = 0
+			if err := f.SetContentTypes(tt.types); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Fetch(server.URL + "/"); err != nil {
+				t.Fatalf("Fetch() returned error: %v", err)
+			}
+
+			outcomes := make(map[string]string)
+			for _, rec := range f.Report().Pages {
+				outcome := string(rec.Outcome)
+				if rec.Reason != "" {
+					outcome += ":" + rec.Reason
+				}
+				outcomes[strings.TrimPrefix(rec.URL, server.URL)] = outcome
+			}
+			for u, want := range tt.want {
+				if outcomes[u] != want {
+					t.Errorf("%s = %q, want %q (all: %v)", u, outcomes[u], want, outcomes)
+				}
+			}
+			host := strings.TrimPrefix(server.URL, "http://")
+			for name, want := range tt.files {
+				data, err := os.ReadFile(filepath.Join(dir, "crawl", host, name))
+				if err != nil {
+					t.Errorf("%s not saved: %v", name, err)
+					continue
+				}
+				if !strings.Contains(string(data), want) {
+					t.Errorf("%s = %q, want it to contain %q in UTF-8", name, data, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSetContentTypes(t *testing.T) {
+	f := New(t.TempDir())
+	for _, types := range [][]string{{"html"}, {"text/html; charset=utf-8"}, {"*/*"}, {"text/"}} {
+		if err := f.SetContentTypes(types); err == nil {
+			t.Errorf("SetContentTypes(%q) error = nil, want an error", types)
+		}
+	}
+	if err := f.SetContentTypes([]string{" Text/HTML ", "text/*"}); err != nil {
+		t.Fatal(err)
+	}
+	for contentType, want := range map[string]bool{
+		"text/html; charset=utf-8": true,
+		"text/csv":                 true,
+		"application/json":         false,
+		"":                         true,
+	} {
+		if _, got := f.acceptsContentType(contentType); got != want {
+			t.Errorf("acceptsContentType(%q) = %t, want %t", contentType, got, want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the robots directives of pages, given by their
+// <meta name="robots"> elements and X-Robots-Tag headers: noindex pages are
+// left out of the skill, and the links of nofollow pages aren't followed.
+package fetcher
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+	"golang.org/x/net/html"
+)
+
+// ReasonNoIndex is the Reason of the pages skipped because their robots
+// directives say noindex (see SetKeepNoIndex).
+const ReasonNoIndex = "noindex"
+
+// robotsToken is the name of the crawler in robots directives addressed to
+// it (<meta name="site2skillgo">, X-Robots-Tag: site2skillgo: noindex).
+const robotsToken = ProductToken
+
+// RobotsDirectives are the robots directives of a page that the crawl obeys.
+type RobotsDirectives struct {
+	// NoIndex asks that the page be left out of indexes, the skill included.
+	NoIndex bool
+	// NoFollow asks that the links of the page not be followed.
+	NoFollow bool
+}
+
+// SetKeepNoIndex sets whether the pages whose robots directives say noindex
+// are saved. By default they are skipped with the reason ReasonNoIndex, their
+// links still followed unless they also say nofollow; kept, they are saved
+// and flagged in the crawl report (PageRecord.NoIndex). The directives are
+// ignored altogether with the robots policy RobotsOff.
+func (f *Fetcher) SetKeepNoIndex(keep bool) {
+	f.keepNoIndex = keep
+}
+
+// ParseRobotsDirectives returns the robots directives of a page from the
+// X-Robots-Tag headers of its response, header, and the <meta> elements of
+// doc, which may be nil, named "robots" or after this crawler. The values are
+// comma-separated lists of directives: "noindex", "nofollow", and "none",
+// which is both; directives prefixed with the name of another crawler
+// ("googlebot: noindex") don't apply.
+func ParseRobotsDirectives(header http.Header, doc *html.Node) RobotsDirectives {
+	var d RobotsDirectives
+	for _, value := range header.Values("X-Robots-Tag") {
+		d.add(value, true)
+	}
+	if doc != nil {
+		forEachRobotsMeta(doc, func(content string) { d.add(content, false) })
+	}
+	return d
+}
+
+// add adds the directives of value, a comma-separated list, to d. In an
+// X-Robots-Tag header, a list may be prefixed with the name of the crawler
+// it applies to.
+func (d *RobotsDirectives) add(value string, header bool) {
+	if header {
+		if agent, rest, ok := strings.Cut(value, ":"); ok && !strings.ContainsAny(agent, ",") {
+			agent = strings.ToLower(strings.TrimSpace(agent))
+			if agent != robotsToken && !isDirective(agent) {
+				return
+			}
+			if agent == robotsToken {
+				value = rest
+			}
+		}
+	}
+	for _, token := range strings.Split(value, ",") {
+		switch strings.ToLower(strings.TrimSpace(token)) {
+		case "noindex":
+			d.NoIndex = true
+		case "nofollow":
+			d.NoFollow = true
+		case "none":
+			d.NoIndex, d.NoFollow = true, true
+		}
+	}
+}
+
+// isDirective reports whether name, the text before a colon in an
+// X-Robots-Tag header, is a directive taking a value (unavailable_after:
+// <date>, max-snippet: 20) rather than the name of a crawler.
+func isDirective(name string) bool {
+	switch name {
+	case "unavailable_after", "max-snippet", "max-image-preview", "max-video-preview":
+		return true
+	}
+	return false
+}
+
+// forEachRobotsMeta calls fn with the content of every <meta> element of doc
+// named "robots" or after this crawler.
+func forEachRobotsMeta(n *html.Node, fn func(content string)) {
+	if n.Type == html.ElementNode && n.Data == "meta" {
+		var name, content string
+		for _, attr := range n.Attr {
+			switch attr.Key {
+			case "name":
+				name = strings.ToLower(strings.TrimSpace(attr.Val))
+			case "content":
+				content = attr.Val
+			}
+		}
+		if name == "robots" || name == robotsToken {
+			fn(content)
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		forEachRobotsMeta(c, fn)
+	}
+}
+
+// obeyDirectives reads the robots directives of the page of rec, whose
+// response had header and which parses as doc (nil if it isn't HTML), into
+// rec, unless the robots policy is RobotsOff. It reports whether the page is
+// to be skipped as noindex, after recording it so.
+func (f *Fetcher) obeyDirectives(rec *PageRecord, header http.Header, doc *html.Node) bool {
+	if f.robotsChecker.policy == RobotsOff {
+		return false
+	}
+	d := ParseRobotsDirectives(header, doc)
+	rec.NoIndex, rec.NoFollow = d.NoIndex, d.NoFollow
+	if !d.NoIndex || f.keepNoIndex {
+		return false
+	}
+	warnlog.Printf("noindex", "Skipping %s: its robots directives say noindex", rec.URL)
+	rec.Outcome = OutcomeSkipped
+	rec.Reason = ReasonNoIndex
+	f.record(*rec)
+	return true
+}
@@ -0,0 +1,145 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestParseRobotsDirectives(t *testing.T) {
+	tests := []struct {
+		name   string
+		header []string
+		page   string
+		want   RobotsDirectives
+	}{
+		{"none", nil, `<html><head><title>Docs</title></head></html>`, RobotsDirectives{}},
+		{"meta noindex", nil, `<meta name="robots" content="noindex">`, RobotsDirectives{NoIndex: true}},
+		{"meta both", nil, `<meta name="Robots" content="NOINDEX, nofollow">`, RobotsDirectives{NoIndex: true, NoFollow: true}},
+		{"meta none", nil, `<meta name="robots" content="none">`, RobotsDirectives{NoIndex: true, NoFollow: true}},
+		{"meta crawler", nil, `<meta name="site2skillgo" content="nofollow">`, RobotsDirectives{NoFollow: true}},
+		{"meta other crawler", nil, `<meta name="googlebot" content="noindex">`, RobotsDirectives{}},
+		{"meta index", nil, `<meta name="robots" content="index, follow">`, RobotsDirectives{}},
+		{"header", []string{"noindex"}, "", RobotsDirectives{NoIndex: true}},
+		{"header list", []string{"noarchive, nofollow"}, "", RobotsDirectives{NoFollow: true}},
+		{"header crawler", []string{"site2skillgo: noindex"}, "", RobotsDirectives{NoIndex: true}},
+		{"header other crawler", []string{"googlebot: noindex, nofollow"}, "", RobotsDirectives{}},
+		{"header directive with value", []string{"unavailable_after: 25 Jun 2010 15:00:00 PST, noindex"}, "", RobotsDirectives{NoIndex: true}},
+		{"header and meta", []string{"nofollow"}, `<meta name="robots" content="noindex">`, RobotsDirectives{NoIndex: true, NoFollow: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := make(http.Header)
+			for _, v := range tt.header {
+				header.Add("X-Robots-Tag", v)
+			}
+			var doc *html.Node
+			if tt.page != "" {
+				var err error
+				if doc, err = html.Parse(strings.NewReader(tt.page)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := ParseRobotsDirectives(header, doc); got != tt.want {
+				t.Errorf("ParseRobotsDirectives() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchRobotsDirectives(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<html><body><a href="/hidden">Hidden</a><a href="/closed">Closed</a><a href="/notes">Notes</a></body></html>`))
+		case "/hidden":
+			w.Write([]byte(`<html><head><meta name="robots" content="noindex"></head><body><a href="/behind-hidden">Next</a></body></html>`))
+		case "/closed":
+			w.Header().Set("X-Robots-Tag", "nofollow")
+			w.Write([]byte(`<html><body><h1>Closed</h1><a href="/behind-closed">Next</a></body></html>`))
+		case "/notes":
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("X-Robots-Tag", "noindex")
+			w.Write([]byte("Notes"))
+		case "/behind-hidden", "/behind-closed":
+			w.Write([]byte(`<html><body><h1>Behind</h1></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		keep   bool
+		policy string
+		want   map[string]string
+	}{
+		{
+			name: "default",
+			want: map[string]string{
+				"/hidden":        "skipped:" + ReasonNoIndex,
+				"/behind-hidden": "saved",
+				"/closed":        "saved",
+				"/behind-closed": "",
+				"/notes":         "skipped:" + ReasonNoIndex,
+			},
+		},
+		{
+			name: "kept",
+			keep: true,
+			want: map[string]string{
+				"/hidden":        "saved",
+				"/behind-hidden": "saved",
+				"/closed":        "saved",
+				"/behind-closed": "",
+				"/notes":         "saved",
+			},
+		},
+		{
+			name:   "robots off",
+			policy: RobotsOff,
+			want: map[string]string{
+				"/hidden":        "saved",
+				"/closed":        "saved",
+				"/behind-closed": "saved",
+				"/notes":         "saved",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := New(t.TempDir())
+			f.delay = 0
+			f.SetKeepNoIndex(tt.keep)
+			if tt.policy != "" {
+				f.SetRobotsPolicy(tt.policy)
+			}
+			if err := f.Fetch(server.URL + "/"); err != nil {
+				t.Fatalf("Fetch() returned error: %v", err)
+			}
+
+			outcomes := make(map[string]string)
+			for _, rec := range f.Report().Pages {
+				outcome := string(rec.Outcome)
+				if rec.Reason != "" {
+					outcome += ":" + rec.Reason
+				}
+				path := strings.TrimPrefix(rec.URL, server.URL)
+				outcomes[path] = outcome
+				if tt.policy == "" && (rec.NoIndex != (path == "/hidden" || path == "/notes") || rec.NoFollow != (path == "/closed")) {
+					t.Errorf("%s: NoIndex = %v, NoFollow = %v", path, rec.NoIndex, rec.NoFollow)
+				}
+			}
+			for u, want := range tt.want {
+				if outcomes[u] != want {
+					t.Errorf("%s = %q, want %q (all: %v)", u, outcomes[u], want, outcomes)
+				}
+			}
+		})
+	}
+}
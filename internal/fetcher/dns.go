@@ -0,0 +1,287 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the DNS cache of the crawler, which resolves the hosts
+// of queued URLs in the background so requests don't wait on cold lookups.
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+)
+
+const (
+	// dnsTTL is how long resolved addresses are reused. The system resolver
+	// doesn't report record TTLs, so a fixed one is used.
+	dnsTTL = 5 * time.Minute
+	// dnsFailureTTL is how long a failed lookup is reused before the host is
+	// resolved again.
+	dnsFailureTTL = 30 * time.Second
+	// dnsRefreshWindow is how long before they expire addresses are refreshed
+	// in the background when their host is prefetched again.
+	dnsRefreshWindow = time.Minute
+	// dnsPrefetchLimit is the number of background lookups run at once.
+	dnsPrefetchLimit = 4
+	// dnsPrefetchTimeout bounds a background lookup.
+	dnsPrefetchTimeout = 10 * time.Second
+)
+
+// ReasonDNS is the Reason of the pages that failed because their host couldn't
+// be resolved.
+const ReasonDNS = "dns_error"
+
+// DNSCache resolves host names for the connections of the crawler and caches
+// the results, successes for dnsTTL and failures for dnsFailureTTL.
+//
+// Prefetch resolves the host of a queued URL in the background, a few hosts at
+// a time, so that the connection to it finds the addresses ready instead of
+// stalling on the lookup; a host about to expire is refreshed the same way
+// while its cached addresses are still used. Failure reports a host known not
+// to resolve, so the crawler can fail its pages at once as DNS failures
+// instead of requesting them.
+//
+// A DNSCache is safe for concurrent use.
+type DNSCache struct {
+	// lookup resolves a host name; net.DefaultResolver.LookupHost by default
+	lookup func(ctx context.Context, host string) ([]string, error)
+	// now returns the current time; time.Now by default
+	now func() time.Time
+	// prefetches limits the background lookups running at once
+	prefetches chan struct{}
+
+	mu      sync.Mutex
+	entries map[string]*dnsEntry
+	// overridden holds the hosts connected to by resolve rules, never looked up
+	overridden map[string]bool
+	// allow restricts the hosts looked up, whose addresses then never expire
+	// (see Pin); nil allows every host
+	allow *AllowList
+}
+
+// dnsEntry is the cached lookup of one host.
+type dnsEntry struct {
+	// ready is closed when the first lookup of the host settles
+	ready chan struct{}
+	addrs []string
+	err   error
+	// expires is when the result must be looked up again
+	expires time.Time
+	// refreshing is set while a background lookup refreshes the entry
+	refreshing bool
+}
+
+// NewDNSCache creates an empty DNS cache using the system resolver.
+func NewDNSCache() *DNSCache {
+	return &DNSCache{
+		lookup:     net.DefaultResolver.LookupHost,
+		now:        time.Now,
+		prefetches: make(chan struct{}, dnsPrefetchLimit),
+		entries:    make(map[string]*dnsEntry),
+		overridden: make(map[string]bool),
+	}
+}
+
+// override marks host as connected to by a resolve rule (see
+// ResolveTransport): it is neither prefetched nor reported as a failure.
+func (c *DNSCache) override(host string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.overridden[strings.ToLower(host)] = true
+}
+
+// Prefetch starts resolving host in the background unless its addresses are
+// cached, or being resolved, already. Addresses that expire within
+// dnsRefreshWindow are refreshed, and kept until the new ones arrive. IP
+// addresses, empty hosts, and hosts of resolve rules are ignored, as are all
+// hosts by a nil cache. Prefetch never blocks: the lookups wait their turn in
+// the background.
+func (c *DNSCache) Prefetch(host string) {
+	if c == nil || host == "" || net.ParseIP(host) != nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.overridden[strings.ToLower(host)] || !c.allowed(host) {
+		return
+	}
+	e, ok := c.entries[host]
+	switch {
+	case !ok || c.expired(e):
+		e = &dnsEntry{ready: make(chan struct{})}
+		c.entries[host] = e
+	case !settled(e) || e.refreshing || e.err != nil || c.allow != nil || e.expires.Sub(c.now()) > dnsRefreshWindow:
+		return
+	default:
+		e.refreshing = true
+	}
+	go c.prefetch(host, e)
+}
+
+// prefetch resolves host for the entry e in the background.
+func (c *DNSCache) prefetch(host string, e *dnsEntry) {
+	c.prefetches <- struct{}{}
+	defer func() { <-c.prefetches }()
+	ctx, cancel := context.WithTimeout(context.Background(), dnsPrefetchTimeout)
+	defer cancel()
+	addrs, err := c.lookup(ctx, host)
+	c.settle(e, addrs, err)
+}
+
+// LookupHost returns the addresses of host, from the cache when it holds them.
+// It waits for a lookup of host in progress, or resolves host itself.
+//
+// Returns the lookup error, cached or not, or the error of ctx if it is done
+// first.
+func (c *DNSCache) LookupHost(ctx context.Context, host string) ([]string, error) {
+	c.mu.Lock()
+	if !c.allowed(host) {
+		c.mu.Unlock()
+		return nil, fmt.Errorf("%w: %s", ErrHostNotAllowed, host)
+	}
+	e, ok := c.entries[host]
+	if ok && !c.expired(e) {
+		c.mu.Unlock()
+		select {
+		case <-e.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return e.addrs, e.err
+	}
+	e = &dnsEntry{ready: make(chan struct{})}
+	c.entries[host] = e
+	c.mu.Unlock()
+
+	addrs, err := c.lookup(ctx, host)
+	if ctx.Err() != nil && err != nil {
+		// The lookup was abandoned rather than failed: let the next one retry
+		c.mu.Lock()
+		if c.entries[host] == e {
+			delete(c.entries, host)
+		}
+		e.err = err
+		close(e.ready)
+		c.mu.Unlock()
+		return nil, err
+	}
+	c.settle(e, addrs, err)
+	return addrs, err
+}
+
+// settle stores the result of a lookup in e. A failed refresh keeps the
+// addresses e holds.
+func (c *DNSCache) settle(e *dnsEntry, addrs []string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	first := !settled(e)
+	e.refreshing = false
+	if first || err == nil {
+		e.addrs, e.err = addrs, err
+		if err != nil {
+			e.expires = c.now().Add(dnsFailureTTL)
+		} else {
+			e.expires = c.now().Add(dnsTTL)
+		}
+	}
+	if first {
+		close(e.ready)
+	}
+}
+
+// Failure returns the error of the last lookup of host if it failed and hasn't
+// expired yet, or nil if host resolved, hasn't been resolved, is still being
+// resolved, or is the host of a resolve rule. It never blocks. A nil cache
+// reports no failure.
+func (c *DNSCache) Failure(host string) error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[host]
+	if !ok || c.overridden[strings.ToLower(host)] || !settled(e) || c.expired(e) {
+		return nil
+	}
+	return e.err
+}
+
+// DialContext returns a dial function for http.Transport that connects with
+// dialer to the addresses of the host of addr looked up through the cache,
+// trying them in turn. Addresses that are IP addresses are dialled directly.
+func (c *DNSCache) DialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil || net.ParseIP(host) != nil {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		addrs, err := c.LookupHost(ctx, host)
+		if err != nil {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: err}
+		}
+		var errs []error
+		for _, ip := range addrs {
+			conn, err := dialer.DialContext(ctx, network, net.JoinHostPort(ip, port))
+			if err == nil {
+				return conn, nil
+			}
+			errs = append(errs, err)
+			if ctx.Err() != nil {
+				break
+			}
+		}
+		if len(errs) == 0 {
+			return nil, &net.OpError{Op: "dial", Net: network, Err: &net.DNSError{Err: "no addresses", Name: host, IsNotFound: true}}
+		}
+		return nil, errors.Join(errs...)
+	}
+}
+
+// expired reports whether the result of e must be looked up again. Entries
+// being resolved never expire, nor do the addresses pinned by Pin.
+func (c *DNSCache) expired(e *dnsEntry) bool {
+	if c.allow != nil && settled(e) && e.err == nil {
+		return false
+	}
+	return settled(e) && !c.now().Before(e.expires)
+}
+
+// settled reports whether the first lookup of e is done.
+func settled(e *dnsEntry) bool {
+	select {
+	case <-e.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// prefetch resolves the host of the queued URL targetURL in the background if
+// the crawl will request it.
+func (f *Fetcher) prefetch(targetURL string) {
+	if u, err := url.Parse(targetURL); err == nil && u.Host == f.domain {
+		f.dns.Prefetch(u.Hostname())
+	}
+}
+
+// dnsFailure records rec, the failure of a page whose request to targetURL
+// couldn't resolve its host, with the lookup error err.
+func (f *Fetcher) dnsFailure(rec *PageRecord, targetURL string, err error) {
+	warnlog.Printf("dns", "Warning: failed to resolve the host of %s: %v", targetURL, err)
+	rec.Reason = ReasonDNS
+	rec.Error = err.Error()
+	f.record(*rec)
+}
+
+// isDNSError reports whether err is, or wraps, a failure to resolve a host.
+func isDNSError(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
@@ -0,0 +1,193 @@
+package fetcher
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeLookup resolves the hosts of addrs, counting the lookups; the others
+// aren't found.
+type fakeLookup struct {
+	mu    sync.Mutex
+	addrs map[string][]string
+	calls map[string]int
+}
+
+func (l *fakeLookup) lookup(_ context.Context, host string) ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.calls[host]++
+	if addrs, ok := l.addrs[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+func (l *fakeLookup) count(host string) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.calls[host]
+}
+
+// waitFor polls cond until it holds or a second has passed.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within a second")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDNSCache(t *testing.T) {
+	fake := &fakeLookup{addrs: map[string][]string{"docs.example.test": {"10.0.0.5"}}, calls: map[string]int{}}
+	var clock sync.Mutex
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	advance := func(d time.Duration) {
+		clock.Lock()
+		defer clock.Unlock()
+		now = now.Add(d)
+	}
+	c := NewDNSCache()
+	c.lookup = fake.lookup
+	c.now = func() time.Time {
+		clock.Lock()
+		defer clock.Unlock()
+		return now
+	}
+	ctx := context.Background()
+
+	c.Prefetch("docs.example.test")
+	c.Prefetch("docs.example.test")
+	c.Prefetch("10.0.0.6")
+	addrs, err := c.LookupHost(ctx, "docs.example.test")
+	if err != nil || len(addrs) != 1 || addrs[0] != "10.0.0.5" {
+		t.Fatalf("LookupHost() = %v, %v; want [10.0.0.5]", addrs, err)
+	}
+	if _, err := c.LookupHost(ctx, "docs.example.test"); err != nil || fake.count("docs.example.test") != 1 {
+		t.Errorf("looked up %d times, want the prefetched addresses reused", fake.count("docs.example.test"))
+	}
+	if fake.count("10.0.0.6") != 0 {
+		t.Error("Prefetch() looked up an IP address")
+	}
+
+	// Failures are cached for a while, then looked up again
+	if err := c.Failure("missing.test"); err != nil {
+		t.Errorf("Failure() of an unresolved host = %v, want nil", err)
+	}
+	if _, err := c.LookupHost(ctx, "missing.test"); !isDNSError(err) {
+		t.Fatalf("LookupHost() of a missing host error = %v, want a DNS error", err)
+	}
+	if err := c.Failure("missing.test"); !isDNSError(err) {
+		t.Errorf("Failure() = %v, want the lookup error", err)
+	}
+	if c.Failure("docs.example.test") != nil {
+		t.Error("Failure() of a resolved host is not nil")
+	}
+	advance(dnsFailureTTL)
+	if err := c.Failure("missing.test"); err != nil {
+		t.Errorf("Failure() after the failure expired = %v, want nil", err)
+	}
+
+	// Addresses about to expire are refreshed in the background
+	advance(dnsTTL - dnsFailureTTL - dnsRefreshWindow/2)
+	c.Prefetch("docs.example.test")
+	waitFor(t, func() bool { return fake.count("docs.example.test") == 2 })
+	advance(dnsTTL - dnsRefreshWindow)
+	if _, err := c.LookupHost(ctx, "docs.example.test"); err != nil || fake.count("docs.example.test") != 2 {
+		t.Errorf("looked up %d times, want the refreshed addresses reused", fake.count("docs.example.test"))
+	}
+}
+
+func TestDNSCachePrefetchLimit(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+	release := make(chan struct{})
+	c := NewDNSCache()
+	c.lookup = func(_ context.Context, host string) ([]string, error) {
+		mu.Lock()
+		running++
+		peak = max(peak, running)
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return []string{"10.0.0.5"}, nil
+	}
+
+	for i := range 3 * dnsPrefetchLimit {
+		c.Prefetch(strings.Repeat("a", i+1) + ".example.test")
+	}
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return running == dnsPrefetchLimit
+	})
+	time.Sleep(10 * time.Millisecond)
+	mu.Lock()
+	if peak != dnsPrefetchLimit {
+		t.Errorf("ran %d lookups at once, want %d", peak, dnsPrefetchLimit)
+	}
+	mu.Unlock()
+	close(release)
+	if _, err := c.LookupHost(context.Background(), strings.Repeat("a", 3*dnsPrefetchLimit)+".example.test"); err != nil {
+		t.Errorf("LookupHost() of the last prefetched host error = %v", err)
+	}
+}
+
+func TestFetchDNS(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/docs/":
+			w.Write([]byte(`<html><body><a href="/docs/guide">Guide</a><a href="/docs/api">API</a></body></html>`))
+		case "/docs/guide", "/docs/api":
+			w.Write([]byte(`<html><body>Page</body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	port := server.URL[strings.LastIndex(server.URL, ":")+1:]
+
+	fake := &fakeLookup{addrs: map[string][]string{"docs.example.test": {"127.0.0.1"}}, calls: map[string]int{}}
+	f := New(t.TempDir())
+	f.delay = 0
+	f.dns.lookup = fake.lookup
+	startURL := "http://docs.example.test:" + port + "/docs/"
+	if err := f.Fetch(startURL); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+	if got := f.Report().Summary[OutcomeSaved]; got != 3 {
+		t.Errorf("saved %d pages, want 3", got)
+	}
+	if got := fake.count("docs.example.test"); got != 1 {
+		t.Errorf("looked up the host %d times, want once", got)
+	}
+
+	// A host that doesn't resolve fails as a DNS failure
+	f = New(t.TempDir())
+	f.delay = 0
+	f.dns.lookup = fake.lookup
+	missingURL := "http://missing.example.test:" + port + "/docs/"
+	if err := f.Fetch(missingURL); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+	pages := f.Report().Pages
+	if len(pages) != 1 || pages[0].Outcome != OutcomeFailed || pages[0].Reason != ReasonDNS {
+		t.Fatalf("report pages = %+v, want one %s failure", pages, ReasonDNS)
+	}
+	if u, _ := url.Parse(missingURL); !strings.Contains(pages[0].Error, u.Hostname()) {
+		t.Errorf("error = %q, want it to name the host", pages[0].Error)
+	}
+}
@@ -0,0 +1,221 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the error taxonomy of the crawl: the Reason of every
+// failed page, and the typed errors the records of the crawl report map to,
+// so that callers can branch on failures with errors.Is and errors.As instead
+// of matching messages.
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+)
+
+// Reasons of the failed pages. The HTTP status reasons are the error classes
+// of the crawl metrics (see metrics.ErrorClass).
+const (
+	// ReasonHTTPClientError is the Reason of the pages answered with a 4xx status.
+	ReasonHTTPClientError = "http_4xx"
+	// ReasonHTTPServerError is the Reason of the pages answered with a 5xx status.
+	ReasonHTTPServerError = "http_5xx"
+	// ReasonHTTPStatus is the Reason of the pages answered with another
+	// status than 200, 4xx, or 5xx (e.g., 204).
+	ReasonHTTPStatus = "http_status"
+	// ReasonTimeout is the Reason of the pages whose request timed out.
+	ReasonTimeout = "timeout"
+	// ReasonNetwork is the Reason of the pages that got no response for
+	// another reason: refused or reset connections, TLS errors, ...
+	ReasonNetwork = "network"
+	// ReasonHostNotAllowed is the Reason of the pages whose request went to a
+	// host outside the allow-list of the sandbox (see SetSandbox).
+	ReasonHostNotAllowed = "host_not_allowed"
+	// ReasonInvalidRequest is the Reason of the pages whose request couldn't
+	// be built.
+	ReasonInvalidRequest = "invalid_request"
+	// ReasonReadError is the Reason of the pages whose response body couldn't
+	// be read.
+	ReasonReadError = "read_error"
+	// ReasonWriteError is the Reason of the pages that couldn't be written to
+	// the crawl directory.
+	ReasonWriteError = "write_error"
+)
+
+// Errors of the records of the crawl report (see PageRecord.Err), in addition
+// to ErrRobotsBlocked, ErrRobotsUnavailable, ErrOutOfScope, ErrPageTooLarge,
+// ErrSizeBudget, ErrRedirectLoop, ErrTooManyRedirects, and ErrHostNotAllowed.
+var (
+	// ErrUnsupportedContentType means a page was skipped for its content type.
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+	// ErrHTTPStatus means a page was answered with another status than 200.
+	// The errors wrapping it are *StatusError, which holds the status.
+	ErrHTTPStatus = errors.New("unexpected HTTP status")
+	// ErrDNS means the host of a page couldn't be resolved.
+	ErrDNS = errors.New("host not resolved")
+	// ErrTimeout means the request of a page timed out.
+	ErrTimeout = errors.New("request timed out")
+	// ErrNetwork means the request of a page got no response.
+	ErrNetwork = errors.New("network error")
+	// ErrInvalidRequest means the request of a page couldn't be built.
+	ErrInvalidRequest = errors.New("invalid request")
+	// ErrReadFailed means the response body of a page couldn't be read.
+	ErrReadFailed = errors.New("failed to read the response body")
+	// ErrWriteFailed means a page couldn't be written to the crawl directory.
+	ErrWriteFailed = errors.New("failed to write the page")
+)
+
+// StatusError is the error of a page answered with another status than 200.
+// It matches ErrHTTPStatus with errors.Is.
+type StatusError struct {
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+}
+
+// Error returns the message of the error.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d", e.StatusCode)
+}
+
+// Is reports whether target is ErrHTTPStatus.
+func (e *StatusError) Is(target error) bool {
+	return target == ErrHTTPStatus
+}
+
+// PageError is the error of a page that failed, or was skipped or blocked for
+// a reason that maps to an error (see PageRecord.Err). It wraps the error the
+// Reason of the page maps to, e.g., ErrTimeout or a *StatusError.
+type PageError struct {
+	// URL is the URL of the page.
+	URL string
+	// Reason is the Reason of the page record.
+	Reason string
+	// Err is the error the reason maps to.
+	Err error
+	// msg is the Error of the page record, if it has one
+	msg string
+}
+
+// Error returns the URL and the message of the error.
+func (e *PageError) Error() string {
+	msg := e.msg
+	if msg == "" {
+		msg = e.Err.Error()
+	}
+	return e.URL + ": " + msg
+}
+
+// Unwrap returns Err.
+func (e *PageError) Unwrap() error {
+	return e.Err
+}
+
+// reasonErrors maps the reasons of the page records to their errors. The
+// reasons of the pages deliberately skipped (noindex, duplicates, ...) map to
+// none.
+var reasonErrors = map[string]error{
+	"robots_txt":              ErrRobotsBlocked,
+	ReasonRobotsUnavailable:   ErrRobotsUnavailable,
+	"out_of_domain":           ErrOutOfScope,
+	"non_html_extension":      ErrOutOfScope,
+	ReasonRedirectOutOfDomain: ErrOutOfScope,
+	ReasonContentType:         ErrUnsupportedContentType,
+	"not_pdf":                 ErrUnsupportedContentType,
+	ReasonPageTooLarge:        ErrPageTooLarge,
+	ReasonSizeBudget:          ErrSizeBudget,
+	ReasonRedirectLoop:        ErrRedirectLoop,
+	ReasonTooManyRedirects:    ErrTooManyRedirects,
+	ReasonDNS:                 ErrDNS,
+	ReasonTimeout:             ErrTimeout,
+	ReasonNetwork:             ErrNetwork,
+	ReasonHostNotAllowed:      ErrHostNotAllowed,
+	ReasonInvalidRequest:      ErrInvalidRequest,
+	ReasonReadError:           ErrReadFailed,
+	ReasonWriteError:          ErrWriteFailed,
+}
+
+// Err returns the error of the page as a *PageError, to be checked with
+// errors.Is and errors.As: for example, errors.Is(rec.Err(), ErrTimeout), or
+// errors.As(rec.Err(), &statusErr) for the status of a page answered with an
+// error. Returns nil for saved and planned pages, and for pages deliberately
+// skipped (filtered, noindex, duplicates, ...).
+func (r PageRecord) Err() error {
+	if r.Outcome == OutcomeSaved || r.Outcome == OutcomePlanned {
+		return nil
+	}
+	err := reasonErrors[r.Reason]
+	switch r.Reason {
+	case ReasonHTTPClientError, ReasonHTTPServerError, ReasonHTTPStatus:
+		err = &StatusError{StatusCode: r.StatusCode}
+	}
+	if err == nil {
+		if r.Outcome != OutcomeFailed {
+			return nil
+		}
+		// A failure of a record written before failures had reasons
+		if r.StatusCode != 0 && r.StatusCode != http.StatusOK {
+			err = &StatusError{StatusCode: r.StatusCode}
+		} else {
+			err = ErrNetwork
+		}
+	}
+	return &PageError{URL: r.URL, Reason: r.Reason, Err: err, msg: r.Error}
+}
+
+// statusReason returns the Reason of a page answered with statusCode.
+func statusReason(statusCode int) string {
+	switch {
+	case statusCode >= 500:
+		return ReasonHTTPServerError
+	case statusCode >= 400:
+		return ReasonHTTPClientError
+	}
+	return ReasonHTTPStatus
+}
+
+// fetchReason returns the Reason of a page whose request failed with err:
+// ReasonHostNotAllowed, ReasonTimeout, or ReasonNetwork.
+func fetchReason(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrHostNotAllowed):
+		return ReasonHostNotAllowed
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &netErr) && netErr.Timeout():
+		return ReasonTimeout
+	}
+	return ReasonNetwork
+}
+
+// statusFailure records rec, the page at targetURL answered with another
+// status than 200, as failed.
+func (f *Fetcher) statusFailure(rec *PageRecord, targetURL string) {
+	warnlog.Printf("http_status", "Warning: %s returned status %d", targetURL, rec.StatusCode)
+	rec.Reason = statusReason(rec.StatusCode)
+	rec.Error = (&StatusError{StatusCode: rec.StatusCode}).Error()
+	f.record(*rec)
+}
+
+// fetchFailure records rec, the page whose request of targetURL failed with
+// err, as failed, classifying err (see fetchReason).
+func (f *Fetcher) fetchFailure(rec *PageRecord, targetURL string, err error) {
+	warnlog.Printf("fetch_error", "Warning: failed to fetch %s: %v", targetURL, err)
+	rec.Reason = fetchReason(err)
+	rec.Error = err.Error()
+	f.record(*rec)
+}
+
+// readFailure records rec, the page whose response body from targetURL
+// couldn't be read for err, as failed.
+func (f *Fetcher) readFailure(rec *PageRecord, targetURL string, err error) {
+	warnlog.Printf("read_error", "Warning: failed to read body from %s: %v", targetURL, err)
+	rec.Reason = ReasonReadError
+	if fetchReason(err) == ReasonTimeout {
+		rec.Reason = ReasonTimeout
+	}
+	rec.Error = err.Error()
+	f.record(*rec)
+}
@@ -0,0 +1,140 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPageRecordErr(t *testing.T) {
+	tests := []struct {
+		name string
+		rec  PageRecord
+		want error
+		msg  string
+	}{
+		{name: "saved", rec: PageRecord{Outcome: OutcomeSaved}},
+		{name: "noindex", rec: PageRecord{Outcome: OutcomeSkipped, Reason: ReasonNoIndex}},
+		{name: "robots", rec: PageRecord{Outcome: OutcomeBlocked, Reason: "robots_txt"}, want: ErrRobotsBlocked, msg: "blocked by robots.txt"},
+		{name: "content type", rec: PageRecord{Outcome: OutcomeSkipped, Reason: ReasonContentType}, want: ErrUnsupportedContentType},
+		{name: "too large", rec: PageRecord{Outcome: OutcomeSkipped, Reason: ReasonPageTooLarge, Error: "page exceeds the size limit (2 MiB)"}, want: ErrPageTooLarge, msg: "page exceeds the size limit (2 MiB)"},
+		{name: "status", rec: PageRecord{Outcome: OutcomeFailed, Reason: ReasonHTTPServerError, StatusCode: 503, Error: "unexpected status 503"}, want: ErrHTTPStatus},
+		{name: "timeout", rec: PageRecord{Outcome: OutcomeFailed, Reason: ReasonTimeout, Error: "context deadline exceeded"}, want: ErrTimeout},
+		{name: "dns", rec: PageRecord{Outcome: OutcomeFailed, Reason: ReasonDNS}, want: ErrDNS},
+		{name: "status without reason", rec: PageRecord{Outcome: OutcomeFailed, StatusCode: 404}, want: ErrHTTPStatus},
+		{name: "failure without reason", rec: PageRecord{Outcome: OutcomeFailed, Error: "connection refused"}, want: ErrNetwork},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.rec.URL = "https://example.com/page"
+			err := tt.rec.Err()
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("Err() = %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Err() = %v, want an error wrapping %v", err, tt.want)
+			}
+			var pageErr *PageError
+			if !errors.As(err, &pageErr) || pageErr.URL != tt.rec.URL || pageErr.Reason != tt.rec.Reason {
+				t.Errorf("Err() = %#v, want a *PageError of the record", err)
+			}
+			if tt.msg != "" && err.Error() != tt.rec.URL+": "+tt.msg {
+				t.Errorf("Err().Error() = %q, want %q", err.Error(), tt.rec.URL+": "+tt.msg)
+			}
+		})
+	}
+
+	var statusErr *StatusError
+	err := PageRecord{Outcome: OutcomeFailed, Reason: ReasonHTTPClientError, StatusCode: 410}.Err()
+	if !errors.As(err, &statusErr) || statusErr.StatusCode != 410 {
+		t.Errorf("errors.As(Err(), *StatusError) = %v, want status 410", statusErr)
+	}
+}
+
+// timeoutError is a net.Error that timed out.
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestFetchReason(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{fmt.Errorf("Get %q: %w", "https://example.com/", context.DeadlineExceeded), ReasonTimeout},
+		{fmt.Errorf("read tcp: %w", timeoutError{}), ReasonTimeout},
+		{fmt.Errorf("%w: evil.example.com", ErrHostNotAllowed), ReasonHostNotAllowed},
+		{errors.New("connection refused"), ReasonNetwork},
+	}
+	for _, tt := range tests {
+		if got := fetchReason(tt.err); got != tt.want {
+			t.Errorf("fetchReason(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestFetchClassifiesFailures(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<a href="/broken">Broken</a> <a href="/slow">Slow</a> <a href="/gone">Gone</a>`))
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusInternalServerError)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(2 * time.Second):
+		}
+	})
+	mux.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	f := New(t.TempDir())
+	f.delay = 0
+	f.client.Timeout = 200 * time.Millisecond
+	if err := f.Fetch(server.URL + "/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+
+	want := map[string]error{
+		"/broken": ErrHTTPStatus,
+		"/slow":   ErrTimeout,
+		"/gone":   ErrHTTPStatus,
+	}
+	wantReason := map[string]string{
+		"/broken": ReasonHTTPServerError,
+		"/slow":   ReasonTimeout,
+		"/gone":   ReasonHTTPClientError,
+	}
+	for _, rec := range f.Report().Pages {
+		path := rec.URL[len(server.URL):]
+		target, ok := want[path]
+		if !ok {
+			continue
+		}
+		if rec.Outcome != OutcomeFailed || rec.Reason != wantReason[path] {
+			t.Errorf("%s: outcome %s, reason %q, want failed, %q", path, rec.Outcome, rec.Reason, wantReason[path])
+		}
+		if err := rec.Err(); !errors.Is(err, target) {
+			t.Errorf("%s: Err() = %v, want an error wrapping %v", path, err, target)
+		}
+		delete(want, path)
+	}
+	if len(want) > 0 {
+		t.Errorf("no report entries for %v", want)
+	}
+}
@@ -0,0 +1,166 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements feed mode, which downloads the entries of an RSS or
+// Atom feed instead of crawling a site.
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/feed"
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+)
+
+// ErrFeedUnavailable is returned by FetchFeed when the feed can't be
+// downloaded or isn't an RSS or Atom feed.
+var ErrFeedUnavailable = errors.New("feed unavailable")
+
+// FetchFeed downloads the entries of the RSS or Atom feed at feedURL into the
+// crawl directory, one page per entry, saved like the page at the URL of the
+// entry.
+//
+// An entry carrying its content (or, failing that, its summary) is saved as
+// a page built from the feed (see feed.Entry.HTML), without requesting its
+// article; with articles, or if the entry carries neither, the article page is
+// downloaded instead, whatever its host. Either way links are not followed.
+// Entries without a URL are skipped with a warning.
+//
+// The saved records carry the author and the publication time of their
+// entries (PageRecord.Author and PageRecord.Published). The URL filters and
+// path patterns apply to the URLs of the entries.
+func (f *Fetcher) FetchFeed(feedURL string, articles bool) error {
+	return f.FetchFeedContext(context.Background(), feedURL, articles)
+}
+
+// FetchFeedContext is like FetchFeed but stops when ctx is cancelled or its
+// deadline expires, like FetchContext.
+func (f *Fetcher) FetchFeedContext(ctx context.Context, feedURL string, articles bool) error {
+	feedURL, crawlDir, err := f.begin(feedURL)
+	if err != nil {
+		return err
+	}
+	f.feed = true
+	f.feedEntries = make(map[string]feed.Entry)
+	defer func() { f.feed = false }()
+	return f.end(ctx, f.crawlFeed(ctx, feedURL, crawlDir, articles))
+}
+
+// crawlFeed downloads the feed at feedURL and saves its entries in crawlDir.
+func (f *Fetcher) crawlFeed(ctx context.Context, feedURL, crawlDir string, articles bool) error {
+	if err := f.robotsChecker.Check(ctx, feedURL); err != nil {
+		warnlog.Printf("robots", "Blocked by robots.txt: %s", feedURL)
+		return fmt.Errorf("%w: %s", err, feedURL)
+	}
+
+	req, err := f.newRequest(ctx, "GET", feedURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFeedUnavailable, err)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %v", ErrFeedUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: %s returned status %d", ErrFeedUnavailable, feedURL, resp.StatusCode)
+	}
+	data, err := f.readBody(resp.Body, resp.ContentLength)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: failed to read %s: %v", ErrFeedUnavailable, feedURL, err)
+	}
+	parsed, err := feed.Parse(data, feedURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrFeedUnavailable, err)
+	}
+	slog.Info("Feed", "title", parsed.Title, "entries", len(parsed.Entries))
+
+	f.queue(len(parsed.Entries))
+	for _, e := range parsed.Entries {
+		f.queue(-1)
+		if e.URL == "" {
+			warnlog.Printf("feed", "Warning: skipping feed entry %q without a URL", e.Title)
+			continue
+		}
+		f.mu.Lock()
+		f.feedEntries[e.URL] = e
+		f.mu.Unlock()
+
+		if articles || (e.Content == "" && e.Summary == "") {
+			if err := f.crawl(ctx, e.URL, crawlDir, 1); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		f.saveEntry(e, crawlDir, resp)
+	}
+	return nil
+}
+
+// saveEntry saves the page built from e, an entry of the feed answered with
+// resp, like the page at the URL of e.
+func (f *Fetcher) saveEntry(e feed.Entry, crawlDir string, resp *http.Response) {
+	if !f.shouldCrawlURL(e.URL, 1) {
+		f.recordSkip(e.URL, 1, "url_filter")
+		return
+	}
+	f.mu.Lock()
+	if f.visited[e.URL] {
+		f.mu.Unlock()
+		return
+	}
+	f.visited[e.URL] = true
+	f.mu.Unlock()
+
+	parsedURL, err := url.Parse(e.URL)
+	if err != nil {
+		f.recordSkip(e.URL, 1, "invalid_url")
+		return
+	}
+	body := []byte(e.HTML())
+	rec := PageRecord{
+		URL:         e.URL,
+		Depth:       1,
+		StatusCode:  resp.StatusCode,
+		ContentType: "text/html; charset=utf-8",
+		Bytes:       int64(len(body)),
+		Outcome:     OutcomeFailed,
+	}
+	if !e.Updated.IsZero() {
+		rec.LastModified = e.Updated.Format(time.RFC3339)
+	}
+	if f.save(&rec, parsedURL.String(), f.getFilePath(crawlDir, parsedURL), body) {
+		f.downloadCount++
+	}
+}
+
+// feedDetails adds the author and publication time of the feed entry at the
+// URL of rec, if any, to rec.
+func (f *Fetcher) feedDetails(rec *PageRecord) {
+	if !f.feed {
+		return
+	}
+	f.mu.Lock()
+	e, ok := f.feedEntries[rec.URL]
+	f.mu.Unlock()
+	if !ok {
+		return
+	}
+	rec.Author = e.Author
+	if !e.Published.IsZero() {
+		rec.Published = e.Published.Format(time.RFC3339)
+	}
+}
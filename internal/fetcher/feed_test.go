@@ -0,0 +1,116 @@
+package fetcher
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFetchFeed(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		base := "http://" + r.Host
+		switch r.URL.Path {
+		case "/feed.xml":
+			w.Header().Set("Content-Type", "application/rss+xml")
+			w.Write([]byte(`<?xml version="1.0"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<channel><title>Engineering</title><link>` + base + `/blog/</link>
+<item><title>Full post</title><link>` + base + `/blog/full</link><dc:creator>Ana</dc:creator>
+<pubDate>Wed, 01 May 2024 10:00:00 GMT</pubDate><content:encoded><![CDATA[<p>Full content</p>]]></content:encoded></item>
+<item><title>Link only</title><link>` + base + `/blog/link</link><pubDate>Thu, 02 May 2024 10:00:00 GMT</pubDate></item>
+<item><title>No link</title><description>Orphan</description></item>
+</channel></rss>`))
+		case "/blog/full", "/blog/link":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Article</title></head><body><p>Article ` + r.URL.Path + `</p><a href="/blog/other">Other</a></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		name     string
+		articles bool
+		// wantContent maps the saved files to text they must contain
+		wantContent map[string]string
+	}{
+		{
+			name: "entries from the feed",
+			wantContent: map[string]string{
+				"blog/full.html": "<p>Full content</p>",
+				"blog/link.html": "Article /blog/link",
+			},
+		},
+		{
+			name:     "article pages",
+			articles: true,
+			wantContent: map[string]string{
+				"blog/full.html": "Article /blog/full",
+				"blog/link.html": "Article /blog/link",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			f := New(dir)
+			f.delay = 0
+			if err := f.FetchFeed(server.URL+"/feed.xml", tt.articles); err != nil {
+				t.Fatalf("FetchFeed() returned error: %v", err)
+			}
+
+			for file, want := range tt.wantContent {
+				content, err := os.ReadFile(filepath.Join(dir, "crawl", host, filepath.FromSlash(file)))
+				if err != nil {
+					t.Errorf("%s not saved: %v", file, err)
+					continue
+				}
+				if !strings.Contains(string(content), want) {
+					t.Errorf("%s = %q, want it to contain %q", file, content, want)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dir, "crawl", host, "blog", "other.html")); err == nil {
+				t.Error("link of an article followed in feed mode")
+			}
+
+			saved := f.Report().SavedPages()
+			full := saved["crawl/"+host+"/blog/full.html"]
+			if full.Author != "Ana" || full.Published != "2024-05-01T10:00:00Z" {
+				t.Errorf("full post record = author %q, published %q, want Ana, 2024-05-01T10:00:00Z", full.Author, full.Published)
+			}
+			if link := saved["crawl/"+host+"/blog/link.html"]; link.Published != "2024-05-02T10:00:00Z" {
+				t.Errorf("link-only post published = %q, want 2024-05-02T10:00:00Z", link.Published)
+			}
+			if len(saved) != 2 {
+				t.Errorf("saved %d pages, want 2: %v", len(saved), saved)
+			}
+		})
+	}
+}
+
+func TestFetchFeedErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/page" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body>Hello</body></html>`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	for _, path := range []string{"/missing.xml", "/page"} {
+		f := New(t.TempDir())
+		f.delay = 0
+		if err := f.FetchFeed(server.URL+path, false); !errors.Is(err, ErrFeedUnavailable) {
+			t.Errorf("FetchFeed(%s) error = %v, want ErrFeedUnavailable", path, err)
+		}
+	}
+}
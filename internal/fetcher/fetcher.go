@@ -5,9 +5,11 @@ package fetcher
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
@@ -17,44 +19,236 @@ import (
 	"sync"
 	"time"
 
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+	"github.com/f4ah6o/site2skill-go/internal/feed"
+	"github.com/f4ah6o/site2skill-go/internal/idn"
+	"github.com/f4ah6o/site2skill-go/internal/pathglob"
+	"github.com/f4ah6o/site2skill-go/internal/progress"
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
 	"golang.org/x/net/html"
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/htmlindex"
 	"golang.org/x/text/transform"
 )
 
+// ErrRobotsBlocked is returned by Fetch when robots.txt disallows the start URL.
+var ErrRobotsBlocked = errors.New("blocked by robots.txt")
+
+// ReasonRobotsUnavailable is the Reason of the URLs blocked because the
+// robots.txt of their host can't be fetched in strict mode (see RobotsStrict).
+const ReasonRobotsUnavailable = "robots_unavailable"
+
+// ErrOutOfScope is returned by Fetch when the start URL is excluded from the crawl
+// by the URL filters or by its file extension.
+var ErrOutOfScope = errors.New("URL is out of crawl scope")
+
 // Fetcher crawls and downloads website content.
 type Fetcher struct {
 	outputDir        string
 	domain           string
+	seed             string // start URL of the current crawl in FetchSeeds, recorded in every PageRecord
 	visited          map[string]bool
 	visitedCanonical map[string]bool // canonical path の重複管理（ロケール優先モード用）
 	mu               sync.Mutex
 	maxDepth         int
+	delay            time.Duration // politeness delay before each page request
+	politeness       *Politeness   // per-host politeness settings; see SetPoliteness
 	downloadCount    int
 	startTime        time.Time
 	client           *http.Client
+	dns              *DNSCache      // resolves the hosts of requests and queued links; see SetDNSCache
 	localeConfig     *LocaleConfig  // ロケール優先設定（nil で無効）
 	robotsChecker    *RobotsChecker // robots.txt チェッカー
 	includeFilters   []string
 	excludeFilters   []string
+	pathPatterns     []string         // URL path patterns of the pages crawled beyond the start URL
+	report           *CrawlReport     // per-URL outcomes of the current crawl
+	headers          http.Header      // extra headers (e.g., credentials) sent with every request
+	userAgent        string           // User-Agent of page and probe requests; see SetDevice
+	crawlerAgent     string           // User-Agent identifying the crawler, that of robots.txt requests; see SetUserAgent
+	device           string           // client the fetcher presents itself as; see SetDevice
+	runID            string           // identifier of the run, recorded in the crawl report; see SetRunID
+	onPage           func(PageRecord) // called with every record; see SetPageHook
+	// savedCanonical holds the identities of saved pages: their canonical URL when in scope, else their own
+	savedCanonical        map[string]bool
+	ignoreCanonical       bool              // save pages under their own URL whatever their canonical link
+	skipExternalCanonical bool              // skip pages whose canonical URL is out of scope
+	versionConfig         *VersionConfig    // version selection (nil crawls every version)
+	versions              map[string]string // selected version per host and path prefix
+	dryRun                bool              // record the pages that would be saved without writing them; see SetDryRun
+	reporter              progress.Reporter // renders the progress of the crawl; nil reports nothing
+	// progressMu guards meter, queued, and bytes, the progress counters not kept by the report
+	progressMu sync.Mutex
+	meter      *progress.Meter
+	queued     int
+	bytes      int64
+	// alternates holds the trusted hreflang alternates of the pages parsed in locale priority mode, by page URL
+	alternates map[string]map[string]string
+	// feed is set while downloading the entries of a feed, which may be on any host and whose links aren't followed
+	feed bool
+	// feedEntries holds the entries of the feed being downloaded, by URL; see FetchFeed
+	feedEntries map[string]feed.Entry
+	// series places the pages following others in paginated series, by URL
+	series map[string]seriesPage
+	// index maps the files saved by the current crawl to their URLs; see PageIndex
+	index *PageIndex
+	// negotiating holds the hosts found to vary their pages on Accept-Language; see noteNegotiation
+	negotiating map[string]bool
+	// includePDF downloads the linked PDF documents; see SetIncludePDF
+	includePDF bool
+	// sitemaps crawls the pages listed in the sitemaps of robots.txt; see SetSitemaps
+	sitemaps bool
+	// fromSitemap holds the sitemaps the pages discovered from them were listed in, by URL
+	fromSitemap map[string]string
+	// maxRedirects is the number of redirects followed per request; see SetMaxRedirects
+	maxRedirects int
+	// compressCrawl stores the crawled HTML pages gzip-compressed; see SetCompressCrawl
+	compressCrawl bool
+	// contentTypes are the media types of the responses saved; see SetContentTypes
+	contentTypes []string
+	// maxPageBytes and maxTotalBytes limit the size of the downloads, 0 for no limit; see SetSizeLimits
+	maxPageBytes  int64
+	maxTotalBytes int64
+	// downloaded is the number of response bytes read by the current crawl
+	downloaded int64
+	// normalizer rewrites the URLs crawled in a canonical form; see SetURLNormalizer
+	normalizer *URLNormalizer
+	// consentCookies are sent with every request; see SetConsentCookies
+	consentCookies []*http.Cookie
+	// linkAttributes are the attributes of any element holding links; see SetLinkAttributes
+	linkAttributes []string
+	// noMetaRefresh saves the pages redirecting with a meta refresh instead of following them; see SetFollowMetaRefresh
+	noMetaRefresh bool
+	// keepInterstitials saves the pages taken for interstitials; see SetKeepInterstitials
+	keepInterstitials bool
+	// keepNoIndex saves the pages whose robots directives say noindex; see SetKeepNoIndex
+	keepNoIndex bool
+	// confluenceToken authenticates the requests to the Confluence REST API; see SetConfluenceToken
+	confluenceToken string
+	// notionToken authenticates the requests to the Notion API; see SetNotionToken
+	notionToken string
+	// noAdaptiveThrottle keeps the politeness delay of throttled hosts; see SetAdaptiveThrottle
+	noAdaptiveThrottle bool
+	// throttles holds the adaptive throttle state of the hosts requested by the current crawl
+	throttles map[string]*hostThrottle
+}
+
+// mobileBrowser is the User-Agent of Safari on an iPhone, which sites
+// serving mobile clients differently recognize.
+const mobileBrowser = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"
+
+// MobileUserAgent is the user agent string used by the fetcher presenting
+// itself as a mobile device: that of Safari on an iPhone, which sites serving
+// mobile clients differently recognize, followed by UserAgent, which
+// robots.txt rules and server logs still match.
+var MobileUserAgent = mobileBrowser + " " + UserAgent
+
+// Devices the fetcher can present itself as (see SetDevice).
+const (
+	// DeviceDesktop sends UserAgent (the default).
+	DeviceDesktop = "desktop"
+	// DeviceMobile sends MobileUserAgent.
+	DeviceMobile = "mobile"
+)
+
+// UserAgentFor returns the user agent string of device: UserAgent for
+// DeviceDesktop or "", MobileUserAgent for DeviceMobile.
+//
+// Returns an error if device is unknown.
+func UserAgentFor(device string) (string, error) {
+	return DeviceUserAgent(device, UserAgent)
 }
 
-// UserAgent is the user agent string used by the fetcher.
-const UserAgent = "site2skillgo/1.0 (+https://github.com/f4ah6o/site2skill-go)"
+// DeviceUserAgent returns the user agent string of device for the crawler
+// identified by userAgent (see FormatUserAgent): userAgent itself for
+// DeviceDesktop or "", and for DeviceMobile, that of Safari on an iPhone
+// followed by userAgent.
+//
+// Returns an error if device is unknown.
+func DeviceUserAgent(device, userAgent string) (string, error) {
+	switch device {
+	case "", DeviceDesktop:
+		return userAgent, nil
+	case DeviceMobile:
+		return mobileBrowser + " " + userAgent, nil
+	default:
+		return "", fmt.Errorf("unknown device %q (expected %s or %s)", device, DeviceDesktop, DeviceMobile)
+	}
+}
 
 // New creates a new Fetcher instance configured to save downloads to outputDir.
 func New(outputDir string) *Fetcher {
-	return &Fetcher{
+	f := &Fetcher{
 		outputDir:        outputDir,
 		visited:          make(map[string]bool),
 		visitedCanonical: make(map[string]bool),
+		savedCanonical:   make(map[string]bool),
+		versions:         make(map[string]string),
+		alternates:       make(map[string]map[string]string),
 		maxDepth:         5,
+		delay:            1 * time.Second,
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		robotsChecker: NewRobotsChecker(UserAgent),
+		robotsChecker:  NewRobotsChecker(UserAgent),
+		userAgent:      UserAgent,
+		crawlerAgent:   UserAgent,
+		reporter:       defaultReporter(),
+		maxRedirects:   DefaultMaxRedirects,
+		linkAttributes: DefaultLinkAttributes,
+	}
+	f.client.CheckRedirect = f.checkRedirect
+	dns := NewDNSCache()
+	t, _ := ResolveTransport(nil, dns)
+	f.SetTransport(CompressionTransport(t))
+	f.SetDNSCache(dns)
+	return f
+}
+
+// defaultReporter returns the progress reporter of new fetchers: a bar on
+// standard error.
+func defaultReporter() progress.Reporter {
+	r, _ := progress.NewReporter(progress.ModeBar, os.Stderr)
+	return r
+}
+
+// SetDevice sets the kind of client the fetcher presents itself as to the
+// sites it crawls, by its User-Agent: DeviceDesktop (the default) or
+// DeviceMobile, for sites serving mobile clients a different, sometimes
+// cleaner, page structure. robots.txt is still requested and checked as the
+// crawler (see SetUserAgent).
+//
+// Returns an error if device is unknown.
+func (f *Fetcher) SetDevice(device string) error {
+	ua, err := DeviceUserAgent(device, f.crawlerAgent)
+	if err != nil {
+		return err
 	}
+	f.device = device
+	f.userAgent = ua
+	return nil
+}
+
+// SetUserAgent sets the User-Agent identifying the crawler (UserAgent by
+// default; see FormatUserAgent), which every request of the fetcher carries:
+// robots.txt requests as is, page and probe requests as the device set with
+// SetDevice. robots.txt rules are matched against its product token.
+func (f *Fetcher) SetUserAgent(userAgent string) {
+	f.crawlerAgent = userAgent
+	f.userAgent, _ = DeviceUserAgent(f.device, userAgent)
+	f.robotsChecker.SetUserAgent(userAgent)
+}
+
+// UserAgent returns the User-Agent of the page requests of the fetcher.
+func (f *Fetcher) UserAgent() string {
+	return f.userAgent
+}
+
+// SetRunID sets the identifier of the run the crawls of the fetcher belong to
+// (see NewRunID), recorded in their crawl reports so that they can be told
+// apart from the reports, logs, and server logs of other runs.
+func (f *Fetcher) SetRunID(runID string) {
+	f.runID = runID
 }
 
 // SetLocaleConfig configures the fetcher to use locale priority-based content negotiation.
@@ -64,6 +258,81 @@ func (f *Fetcher) SetLocaleConfig(cfg *LocaleConfig) {
 	f.localeConfig = cfg
 }
 
+// SetTransport replaces the HTTP transport used for page, HEAD probe, and robots.txt requests.
+// It is typically used to install a caching transport (see the httpcache package) so that
+// repeated runs revalidate pages instead of downloading them again. Passing nil restores
+// http.DefaultTransport.
+//
+// The default transport resolves hosts through the DNS cache of the fetcher.
+// Since a replacement may not, it turns the cache off: pass the cache the new
+// transport dials through to SetDNSCache afterwards (see ResolveTransport).
+func (f *Fetcher) SetTransport(rt http.RoundTripper) {
+	f.client.Transport = rt
+	f.robotsChecker.SetTransport(rt)
+	f.dns = nil
+}
+
+// SetRobotsPolicy sets how robots.txt is followed: RobotsDefault,
+// RobotsStrict, or RobotsOff (see ParseRobotsPolicy and RobotsChecker.Check).
+// Pages blocked in strict mode because the robots.txt of their host can't be
+// fetched are recorded with ReasonRobotsUnavailable, and a start URL on such
+// a host fails the crawl with an error wrapping ErrRobotsUnavailable.
+func (f *Fetcher) SetRobotsPolicy(policy string) {
+	f.robotsChecker.SetPolicy(policy)
+}
+
+// SetDelay sets the politeness delay before each page request (1 second by
+// default). A crawl replayed from an archive, which loads no server, needs
+// none.
+func (f *Fetcher) SetDelay(d time.Duration) {
+	f.delay = d
+}
+
+// SetDNSCache sets the DNS cache the transport of the fetcher dials through
+// (see SetTransport). The fetcher resolves the hosts of queued URLs into it in
+// the background, and fails the pages of hosts it knows don't resolve as
+// ReasonDNS failures without requesting them. A nil cache turns this off.
+func (f *Fetcher) SetDNSCache(c *DNSCache) {
+	f.dns = c
+}
+
+// SetHeaders sets extra headers sent with every page and probe request, such as
+// Authorization or Cookie for documentation behind a login. The values are never
+// logged or written to the crawl report. None of them is sent on when a
+// redirect leaves the host of the request (see checkRedirect).
+func (f *Fetcher) SetHeaders(h http.Header) {
+	f.headers = h.Clone()
+}
+
+// newRequest creates a request bound to ctx carrying the fetcher's User-Agent,
+// extra headers, consent cookies, and, in locale priority mode, an
+// Accept-Language header asking for the locales in priority order.
+func (f *Fetcher) newRequest(ctx context.Context, method, targetURL string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, targetURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range f.headers {
+		req.Header[name] = append([]string(nil), values...)
+	}
+	for _, c := range f.consentCookies {
+		req.AddCookie(c)
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	if lang := f.acceptLanguage(); lang != "" {
+		req.Header.Set("Accept-Language", lang)
+	}
+	return req, nil
+}
+
+// SetPageHook registers fn to be called with the record of every URL as soon as
+// its outcome is known, e.g. to stream crawl progress. A URL first skipped and
+// later fetched (such as a locale variant reached again) is reported twice.
+// fn may be called from several goroutines.
+func (f *Fetcher) SetPageHook(fn func(PageRecord)) {
+	f.onPage = fn
+}
+
 // SetURLFilters configures include/exclude filters used to decide which URLs to crawl.
 // When includeFilters is non-empty, only URLs containing one of the filters are crawled
 // (depth 0 is always allowed unless excluded). URLs containing any exclude filter are skipped.
@@ -72,88 +341,350 @@ func (f *Fetcher) SetURLFilters(includeFilters, excludeFilters []string) {
 	f.excludeFilters = normalizeFilters(excludeFilters)
 }
 
+// SetDryRun enables or disables dry-run mode, in which the fetcher crawls the
+// site as usual, following the links of every page, but writes nothing to the
+// output directory: the pages it would save are recorded as OutcomePlanned,
+// with the OutputFile they would be saved to. Responses still go through the
+// transport, so a caching transport (see SetTransport) is filled for the real
+// crawl.
+func (f *Fetcher) SetDryRun(dryRun bool) {
+	f.dryRun = dryRun
+}
+
+// SetProgressReporter sets the reporter of the progress of the crawls (see
+// package progress), by default a progress.ModeBar reporter writing to
+// os.Stderr. nil reports nothing.
+func (f *Fetcher) SetProgressReporter(r progress.Reporter) {
+	f.reporter = r
+}
+
+// SetPathPatterns restricts the crawl to the sections of the site whose URL
+// paths match one of patterns (see the pathglob package), e.g. "/guides/**".
+// The start URL is always crawled, so that the sections are reached from it;
+// links are followed only to pages matching a pattern.
+func (f *Fetcher) SetPathPatterns(patterns []string) {
+	f.pathPatterns = patterns
+}
+
 // Fetch downloads the website starting at targetURL, recursively following
 // same-domain links up to maxDepth. It validates the URL scheme and saves
 // all HTML files to the output directory in a structure preserving the original paths.
 // targetURL must be a valid http or https URL with a domain.
 // If the URL scheme is omitted, https:// is automatically prepended.
+//
+// Returns an error wrapping ErrRobotsBlocked or ErrOutOfScope if the start URL
+// itself can't be crawled.
 func (f *Fetcher) Fetch(targetURL string) error {
+	return f.FetchContext(context.Background(), targetURL)
+}
+
+// FetchContext is like Fetch but stops when ctx is cancelled or its deadline
+// expires: requests in flight are aborted, no further URLs are crawled, and the
+// context error is returned. Report then covers the URLs settled so far.
+func (f *Fetcher) FetchContext(ctx context.Context, targetURL string) error {
+	targetURL, crawlDir, err := f.begin(targetURL)
+	if err != nil {
+		return err
+	}
+	err = f.crawl(ctx, targetURL, crawlDir, 0)
+	if err == nil {
+		err = f.crawlSitemaps(ctx, targetURL, crawlDir)
+	}
+	return f.end(ctx, err)
+}
+
+// begin starts a crawl from targetURL: it normalizes the URL, restricts the
+// crawl to its domain, prepares the crawl directory (unless in dry-run mode),
+// and resets the report and progress counters. Returns the normalized URL and
+// the crawl directory.
+func (f *Fetcher) begin(targetURL string) (string, string, error) {
+	targetURL, err := f.restrict(targetURL)
+	if err != nil {
+		return "", "", err
+	}
+	crawlDir := filepath.Join(f.outputDir, "crawl")
+
+	if f.dryRun {
+		slog.Info("Dry run: planning the crawl without saving pages", "url", targetURL)
+	} else {
+		// Clean/Create crawl directory
+		if err := os.RemoveAll(crawlDir); err != nil && !os.IsNotExist(err) {
+			return "", "", fmt.Errorf("failed to remove crawl dir: %w", err)
+		}
+		if err := os.MkdirAll(crawlDir, 0755); err != nil {
+			return "", "", fmt.Errorf("failed to create crawl dir: %w", err)
+		}
+		slog.Info("Fetching site", "url", targetURL, "dir", crawlDir)
+	}
+	slog.Info("Domain restricted", "domain", f.domain)
+
+	f.startTime = time.Now()
+	f.downloadCount = 0
+	f.seed = ""
+	f.series = nil
+	f.fromSitemap = nil
+	f.index = newPageIndex()
+	f.report = newCrawlReport(targetURL, f.startTime.UTC())
+	f.report.RunID, f.report.UserAgent = f.runID, f.userAgent
+	f.meter = progress.NewMeter(f.startTime)
+	f.queued, f.bytes = 0, 0
+	f.downloaded = 0
+	f.throttles = nil
+	return targetURL, crawlDir, nil
+}
+
+// restrict normalizes targetURL, the start URL of a crawl, and restricts the
+// crawl to its domain, looking up robots.txt under the first segment of its
+// path. Returns the normalized URL.
+func (f *Fetcher) restrict(targetURL string) (string, error) {
 	// Auto-prepend https:// if no scheme is provided
 	if !strings.HasPrefix(targetURL, "http://") && !strings.HasPrefix(targetURL, "https://") {
 		targetURL = "https://" + targetURL
-		log.Printf("No scheme provided, using: %s", targetURL)
+		slog.Info("No scheme provided, using https", "url", targetURL)
 	}
+	// Internationalized hosts and paths are requested and compared in their
+	// punycode and percent-encoded form
+	targetURL = f.normalizeURL(idn.ToASCII(targetURL))
 
 	parsedURL, err := url.Parse(targetURL)
 	if err != nil {
-		return fmt.Errorf("invalid URL: %w", err)
+		return "", fmt.Errorf("invalid URL: %w", err)
 	}
 
 	if parsedURL.Scheme != "http" && parsedURL.Scheme != "https" {
-		return fmt.Errorf("invalid URL scheme: %s. Only http and https are supported", parsedURL.Scheme)
+		return "", fmt.Errorf("invalid URL scheme: %s. Only http and https are supported", parsedURL.Scheme)
 	}
 
 	if parsedURL.Host == "" {
-		return fmt.Errorf("invalid URL: domain is missing")
+		return "", fmt.Errorf("invalid URL: domain is missing")
 	}
 
 	f.domain = parsedURL.Host
-	crawlDir := filepath.Join(f.outputDir, "crawl")
+	f.dns.Prefetch(parsedURL.Hostname())
+
+	f.setRobotsBasePath(parsedURL)
+	return targetURL, nil
+}
 
-	// Set base path for robots.txt lookup (for subdirectory deployments like GitHub Pages)
+// setRobotsBasePath sets the base path for the robots.txt lookup of the host
+// of startURL, a start URL of the crawl (for subdirectory deployments like
+// GitHub Pages): the first segment of its path.
+func (f *Fetcher) setRobotsBasePath(startURL *url.URL) {
 	// Extract the first path segment as base path (e.g., "/site2skill-go" from "/site2skill-go/docs/")
-	if parsedURL.Path != "" && parsedURL.Path != "/" {
-		pathParts := strings.Split(strings.Trim(parsedURL.Path, "/"), "/")
+	basePath := ""
+	if startURL.Path != "" && startURL.Path != "/" {
+		pathParts := strings.Split(strings.Trim(startURL.Path, "/"), "/")
 		if len(pathParts) > 0 && pathParts[0] != "" {
-			basePath := "/" + pathParts[0]
-			f.robotsChecker.SetBasePath(basePath)
-			log.Printf("Set robots.txt base path: %s", basePath)
+			basePath = "/" + pathParts[0]
+			slog.Debug("Set robots.txt base path", "host", startURL.Host, "path", basePath)
 		}
 	}
+	f.robotsChecker.SetHostBasePath(startURL.Scheme+"://"+startURL.Host, basePath)
+}
 
-	// Clean/Create crawl directory
-	if err := os.RemoveAll(crawlDir); err != nil && !os.IsNotExist(err) {
-		return fmt.Errorf("failed to remove crawl dir: %w", err)
+// end finishes the crawl begun by begin, whose crawling returned err: it
+// completes the report, marking it interrupted if ctx is done, and logs the
+// outcome. Returns err, or nil if the crawl stopped at the size budget.
+func (f *Fetcher) end(ctx context.Context, err error) error {
+	f.report.FinishedAt = time.Now().UTC()
+	if !f.dryRun {
+		if err := f.index.write(f.outputDir); err != nil {
+			warnlog.Printf("write_error", "Warning: failed to write %s: %v", PageIndexFile, err)
+		}
+	}
+	f.reportProgress("", true)
+	if f.budgetStop(err) {
+		err = nil
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			f.report.Interrupted = true
+			slog.Warn("Download interrupted", "pages", f.downloadCount, "error", err)
+		}
+		return err
 	}
-	if err := os.MkdirAll(crawlDir, 0755); err != nil {
-		return fmt.Errorf("failed to create crawl dir: %w", err)
+
+	attrs := []any{"pages", f.downloadCount, "elapsed", time.Since(f.startTime).Round(time.Second)}
+	for _, outcome := range f.report.Outcomes() {
+		attrs = append(attrs, string(outcome), f.report.Summary[outcome])
 	}
+	slog.Info("Download complete", attrs...)
 
-	log.Printf("Fetching %s to %s...", targetURL, crawlDir)
-	log.Printf("Domain restricted to: %s", f.domain)
+	return nil
+}
 
-	f.startTime = time.Now()
-	f.downloadCount = 0
+// Report returns the per-URL report of the most recent Fetch call,
+// or nil if Fetch has not been called.
+func (f *Fetcher) Report() *CrawlReport {
+	return f.report
+}
 
-	// Start crawling
-	if err := f.crawl(targetURL, crawlDir, 0); err != nil {
-		return err
+// record adds rec to the crawl report and reports the progress of the crawl.
+func (f *Fetcher) record(rec PageRecord) {
+	f.feedDetails(&rec)
+	f.seriesDetails(&rec)
+	f.mu.Lock()
+	rec.Sitemap = f.fromSitemap[rec.URL]
+	f.mu.Unlock()
+	rec.Seed = f.seed
+	if slog.Default().Enabled(context.Background(), slog.LevelDebug) {
+		attrs := []any{"url", rec.URL, "outcome", rec.Outcome, "depth", rec.Depth}
+		if rec.StatusCode != 0 {
+			attrs = append(attrs, "status", rec.StatusCode)
+		}
+		if rec.Reason != "" {
+			attrs = append(attrs, "reason", rec.Reason)
+		}
+		slog.Debug("URL settled", attrs...)
+	}
+	if f.report != nil {
+		f.report.add(rec)
+	}
+	if f.onPage != nil {
+		f.onPage(rec)
+	}
+	if f.reporter == nil || f.meter == nil {
+		return
 	}
+	f.progressMu.Lock()
+	f.bytes += rec.Bytes
+	if rec.Outcome != OutcomeSkipped && rec.Outcome != OutcomeBlocked {
+		f.meter.Tick(time.Now())
+	}
+	f.progressMu.Unlock()
+	url := rec.URL
+	if rec.FetchedURL != "" {
+		url = rec.FetchedURL
+	}
+	f.reportProgress(url, false)
+}
+
+// queue adds n, which may be negative, to the number of queued links.
+func (f *Fetcher) queue(n int) {
+	f.progressMu.Lock()
+	f.queued += n
+	f.progressMu.Unlock()
+}
 
-	elapsed := time.Since(f.startTime)
-	mins := int(elapsed.Minutes())
-	secs := int(elapsed.Seconds()) % 60
-	log.Printf("Download complete. %d pages in %dm%02ds.", f.downloadCount, mins, secs)
+// reportProgress sends the counters of the crawl to the progress reporter,
+// with lastURL the URL settled last, and done on the last call.
+func (f *Fetcher) reportProgress(lastURL string, done bool) {
+	if f.reporter == nil || f.meter == nil {
+		return
+	}
+	summary := f.report.counts()
+	throttled := f.throttledHosts()
+	f.progressMu.Lock()
+	defer f.progressMu.Unlock()
+	s := progress.Snapshot{
+		Fetched:   summary[OutcomeSaved] + summary[OutcomePlanned],
+		Queued:    f.queued,
+		Failed:    summary[OutcomeFailed],
+		Skipped:   summary[OutcomeSkipped] + summary[OutcomeBlocked],
+		Throttled: throttled,
+		Bytes:     f.bytes,
+		URL:       lastURL,
+		Done:      done,
+	}
+	f.meter.Fill(&s, time.Now())
+	f.reporter.Report(s)
+}
 
-	return nil
+// recordSkip records a URL that was deliberately not fetched.
+func (f *Fetcher) recordSkip(targetURL string, depth int, reason string) {
+	f.record(PageRecord{URL: targetURL, Depth: depth, Outcome: OutcomeSkipped, Reason: reason})
+}
+
+// relOutputPath returns filePath relative to the fetcher output directory for reporting.
+func (f *Fetcher) relOutputPath(filePath string) string {
+	if rel, err := filepath.Rel(f.outputDir, filePath); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return filePath
+}
+
+// save writes body, the page of rec, to filePath, compressed if the crawl is
+// (see SetCompressCrawl), and records rec as saved, or as planned in dry-run
+// mode without writing anything. It returns false after
+// recording rec as failed if the file can't be written.
+//
+// pageURL is the URL the file stands for, which filePath was derived from; it
+// is recorded in the page index, and a file already holding another URL is
+// saved under another name (see PageIndex).
+func (f *Fetcher) save(rec *PageRecord, pageURL, filePath string, body []byte) bool {
+	f.mu.Lock()
+	if f.index == nil {
+		f.index = newPageIndex()
+	}
+	file := f.index.claim(f.relOutputPath(filePath), pageURL)
+	f.mu.Unlock()
+	filePath = filepath.Join(f.outputDir, filepath.FromSlash(file))
+	content := body
+	filePath, body = f.compressPage(filePath, body)
+	rec.OutputFile = f.relOutputPath(filePath)
+	if f.dryRun {
+		rec.Outcome = OutcomePlanned
+		f.record(*rec)
+		return true
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		warnlog.Printf("write_error", "Warning: failed to create directory for %s: %v", filePath, err)
+		rec.OutputFile = ""
+		rec.Reason = ReasonWriteError
+		rec.Error = err.Error()
+		f.record(*rec)
+		return false
+	}
+	if _, err := atomicfile.WriteFile(filePath, body); err != nil {
+		warnlog.Printf("write_error", "Warning: failed to write file %s: %v", filePath, err)
+		rec.OutputFile = ""
+		rec.Reason = ReasonWriteError
+		rec.Error = err.Error()
+		f.record(*rec)
+		return false
+	}
+	f.mu.Lock()
+	f.index.add(file, pageURL, rec, content)
+	f.mu.Unlock()
+	rec.Outcome = OutcomeSaved
+	f.record(*rec)
+	return true
 }
 
 // crawl recursively downloads a page and follows links up to the maximum depth.
 // It respects the domain restriction and uses locale priority mode if configured.
 // crawl logs progress and silently skips errors to continue crawling other pages.
-func (f *Fetcher) crawl(targetURL, crawlDir string, depth int) error {
+// It returns the error of ctx once it is done and, when the start URL (depth 0)
+// itself is skipped, ErrRobotsBlocked or ErrOutOfScope.
+func (f *Fetcher) crawl(ctx context.Context, targetURL, crawlDir string, depth int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	if depth > f.maxDepth {
+		f.recordSkip(targetURL, depth, "max_depth")
 		return nil
 	}
 
 	if !f.shouldCrawlURL(targetURL, depth) {
-		return nil
+		f.recordSkip(targetURL, depth, "url_filter")
+		return startError(depth, ErrOutOfScope, targetURL)
+	}
+
+	// Only the selected version of versioned documentation is crawled
+	if f.versionConfig != nil {
+		selected := f.selectVersion(ctx, targetURL)
+		if selected != targetURL {
+			f.recordSkip(targetURL, depth, "other_version")
+			if selected == "" {
+				return nil
+			}
+			return f.crawl(ctx, selected, crawlDir, depth)
+		}
 	}
 
 	// Check robots.txt
-	if !f.robotsChecker.IsAllowed(targetURL) {
-		log.Printf("Blocked by robots.txt: %s", targetURL)
-		return nil
+	if err := f.robotsChecker.Check(ctx, targetURL); err != nil {
+		return f.blockRobots(targetURL, depth, err)
 	}
 
 	// ロケール優先モードの場合、canonical path ベースで重複チェック
@@ -174,7 +705,7 @@ func (f *Fetcher) crawl(targetURL, crawlDir string, depth int) error {
 		f.mu.Unlock()
 
 		// ロケール優先クロール
-		return f.crawlWithLocalePriority(targetURL, canonical, crawlDir, depth)
+		return f.crawlWithLocalePriority(ctx, targetURL, canonical, crawlDir, depth)
 	}
 
 	// Check if already visited (従来モード)
@@ -188,106 +719,224 @@ func (f *Fetcher) crawl(targetURL, crawlDir string, depth int) error {
 
 	parsedURL, err := url.Parse(targetURL)
 	if err != nil {
+		f.recordSkip(targetURL, depth, "invalid_url")
 		return nil // Skip invalid URLs
 	}
 
-	// Only crawl same domain
-	if parsedURL.Host != f.domain {
-		return nil
+	// Only crawl same domain (feed entries may link anywhere)
+	if parsedURL.Host != f.domain && !f.feed {
+		f.recordSkip(targetURL, depth, "out_of_domain")
+		return startError(depth, ErrOutOfScope, targetURL)
 	}
 
 	// Skip non-HTML resources
-	if isNonHTMLResource(targetURL) {
+	if f.skipsResource(targetURL) {
+		f.recordSkip(targetURL, depth, "non_html_extension")
+		return startError(depth, ErrOutOfScope, targetURL)
+	}
+
+	rec := PageRecord{URL: targetURL, Depth: depth, Outcome: OutcomeFailed, Version: f.pageVersion(targetURL)}
+
+	// A host known not to resolve fails its pages without waiting
+	if err := f.dns.Failure(parsedURL.Hostname()); err != nil {
+		f.dnsFailure(&rec, targetURL, err)
 		return nil
 	}
 
 	// Fetch the page
-	req, err := http.NewRequest("GET", targetURL, nil)
+	req, err := f.newRequest(ctx, "GET", targetURL)
 	if err != nil {
+		rec.Reason = ReasonInvalidRequest
+		rec.Error = err.Error()
+		f.record(rec)
 		return nil
 	}
-	req.Header.Set("User-Agent", UserAgent)
-
-	// Be polite: wait 1 second between requests
-	time.Sleep(1 * time.Second)
 
-	resp, err := f.client.Do(req)
+	// Be polite: wait between requests, as long as the settings of the host
+	// say, and longer for throttled hosts
+	resp, err := f.do(ctx, req)
 	if err != nil {
-		log.Printf("Warning: failed to fetch %s: %v", targetURL, err)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if isDNSError(err) {
+			f.dnsFailure(&rec, targetURL, err)
+			return nil
+		}
+		if f.redirectFailure(&rec, err) {
+			return nil
+		}
+		f.fetchFailure(&rec, targetURL, err)
 		return nil
 	}
 	defer resp.Body.Close()
+	responseDetails(&rec, resp)
 
 	if resp.StatusCode != http.StatusOK {
-		log.Printf("Warning: %s returned status %d", targetURL, resp.StatusCode)
+		f.statusFailure(&rec, targetURL)
 		return nil
 	}
 
+	// A redirected page is known by its final URL
+	pageURL := targetURL
+	if rec.FinalURL != "" {
+		if reason := f.claimRedirect(targetURL, rec.FinalURL, depth); reason != "" {
+			rec.Outcome = OutcomeSkipped
+			rec.Reason = reason
+			f.record(rec)
+			return nil
+		}
+		if u, err := url.Parse(rec.FinalURL); err == nil {
+			pageURL, parsedURL = rec.FinalURL, u
+		}
+	}
+
 	// Check content type
 	contentType := resp.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "text/html") && contentType != "" {
-		return nil // Skip non-HTML content
+	if f.includePDF && isPDFResponse(contentType, pageURL) {
+		if f.obeyDirectives(&rec, resp.Header, nil) {
+			return nil
+		}
+		return f.savePDF(ctx, &rec, resp.Body, resp.ContentLength, parsedURL, crawlDir, pageURL)
+	}
+	mediaType, accepted := f.acceptsContentType(contentType)
+	if !accepted {
+		rec.Outcome = OutcomeSkipped
+		rec.Reason = ReasonContentType
+		f.record(rec)
+		return nil // Skip the content types not saved
 	}
 
 	// Read body
-	body, err := io.ReadAll(resp.Body)
+	body, err := f.readBody(resp.Body, resp.ContentLength)
+	rec.Bytes = int64(len(body))
 	if err != nil {
-		log.Printf("Warning: failed to read body from %s: %v", targetURL, err)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if skipped, stop := f.sizeFailure(&rec, err); skipped {
+			return stop
+		}
+		f.readFailure(&rec, targetURL, err)
 		return nil
 	}
 
-	// Save to file
-	filePath := f.getFilePath(crawlDir, parsedURL)
-	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
-		log.Printf("Warning: failed to create directory for %s: %v", filePath, err)
+	// Pages are saved in UTF-8; text documents have no links to follow
+	body, rec.Charset = toUTF8(body, mediaType, contentType, resp.Header.Get("Content-Language"))
+	if !isHTMLMediaType(mediaType) {
+		if f.obeyDirectives(&rec, resp.Header, nil) {
+			return nil
+		}
+		if f.save(&rec, parsedURL.String(), f.textFilePath(crawlDir, parsedURL), body) {
+			f.downloadCount++
+		}
 		return nil
 	}
 
-	if err := os.WriteFile(filePath, body, 0644); err != nil {
-		log.Printf("Warning: failed to write file %s: %v", filePath, err)
-		return nil
+	// Parse HTML
+	doc, parseErr := html.Parse(bytes.NewReader(body))
+
+	// Meta refresh redirects are followed, and interstitials aren't saved as content
+	if parseErr == nil {
+		if handled, err := f.handleInterstitial(ctx, doc, &rec, pageURL, crawlDir, depth); handled {
+			return err
+		}
 	}
 
-	f.downloadCount++
-	elapsed := time.Since(f.startTime)
-	rate := float64(f.downloadCount) / elapsed.Seconds()
-	mins := int(elapsed.Minutes())
-	secs := int(elapsed.Seconds()) % 60
-	shortURL := targetURL
-	if len(shortURL) > 60 {
-		shortURL = shortURL[len(shortURL)-60:]
-	}
-	fmt.Printf("\r[%d pages | %dm%02ds | %.1f/s] %s", f.downloadCount, mins, secs, rate, shortURL)
-
-	// Parse HTML and extract links
-	htmlString := decodeHTML(body, resp.Header.Get("Content-Type"))
-	doc, err := html.Parse(strings.NewReader(htmlString))
-	if err != nil {
-		return nil // Skip if we can't parse
+	// Pages that ask not to be indexed aren't saved, and their nofollow is obeyed
+	var directivesDoc *html.Node
+	if parseErr == nil {
+		directivesDoc = doc
+	}
+	if f.obeyDirectives(&rec, resp.Header, directivesDoc) {
+		if parseErr != nil {
+			return nil
+		}
+		return f.crawlLinks(ctx, doc, &rec, pageURL, crawlDir, depth)
+	}
+
+	// A page is saved under its canonical URL, by default its final URL, once
+	saveURL := parsedURL
+	if parseErr == nil {
+		identity, reason := f.claimCanonical(doc, pageURL, &rec)
+		if reason != "" {
+			rec.Outcome = OutcomeSkipped
+			rec.Reason = reason
+			f.record(rec)
+			return f.crawlLinks(ctx, doc, &rec, pageURL, crawlDir, depth)
+		}
+		if saveURL, err = url.Parse(identity); err != nil {
+			saveURL = parsedURL
+		}
 	}
 
-	links := f.extractLinks(doc, targetURL)
+	// Save to file
+	if !f.save(&rec, saveURL.String(), f.getFilePath(crawlDir, saveURL), body) {
+		return nil
+	}
 
-	// Crawl links (with depth limit)
-	for _, link := range links {
-		f.crawl(link, crawlDir, depth+1)
+	f.downloadCount++
+
+	if parseErr != nil {
+		return nil // Skip link extraction if we can't parse
 	}
+	return f.crawlLinks(ctx, doc, &rec, pageURL, crawlDir, depth)
+}
 
+// crawlLinks crawls the links of doc, a page at depth fetched from baseURL
+// whose record is rec. The links to URLs not seen yet count as queued until
+// they are crawled. Links are not followed in feed mode, nor from pages whose
+// robots directives say nofollow (see PageRecord.NoFollow).
+//
+// The next page of a paginated series (see nextPage) is crawled first, at
+// the same depth (or 1 from the start URL), so that multi-page articles and
+// listings are captured whole whatever the depth limit.
+func (f *Fetcher) crawlLinks(ctx context.Context, doc *html.Node, rec *PageRecord, baseURL, crawlDir string, depth int) error {
+	if f.feed || rec.NoFollow {
+		return nil
+	}
+	links := f.extractLinks(doc, baseURL)
+	if next := f.nextPage(doc, baseURL, links); next != "" && f.continueSeries(baseURL, next) {
+		if err := f.crawl(ctx, next, crawlDir, max(depth, 1)); err != nil {
+			return err
+		}
+	}
+	queued := make([]bool, len(links))
+	seen := make(map[string]bool, len(links))
+	n := 0
+	for i, link := range links {
+		if !seen[link] && !f.report.has(link) {
+			queued[i] = true
+			n++
+			f.prefetch(link)
+		}
+		seen[link] = true
+	}
+	f.queue(n)
+	for i, link := range links {
+		if queued[i] {
+			f.queue(-1)
+		}
+		if err := f.crawl(ctx, link, crawlDir, depth+1); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 // getFilePath constructs a file path for saving a downloaded page.
 // It creates a structure like crawl/domain.com/path/to/page.html, using index.html for root paths.
 // If query parameters are present, they are encoded into the filename to avoid collisions.
+// Internationalized hosts and paths are written in Unicode (crawl/例え.jp/ドキュメント.html),
+// as the site displays them. Every segment of the path is made a safe file name (see
+// safeName): dot segments can't escape crawlDir, and overlong names are shortened with a hash.
 func (f *Fetcher) getFilePath(crawlDir string, parsedURL *url.URL) string {
 	// Create path like: crawl/domain.com/path/to/page.html
-	path := parsedURL.Path
-	if path == "" || path == "/" {
-		path = "/index"
+	segments := pathSegments(parsedURL)
+	if len(segments) == 0 {
+		segments = []string{"index"}
 	}
-
-	// Remove trailing slash
-	path = strings.TrimSuffix(path, "/")
+	name := segments[len(segments)-1]
 
 	// Handle query parameters
 	query := parsedURL.Query()
@@ -303,50 +952,20 @@ func (f *Fetcher) getFilePath(crawlDir string, parsedURL *url.URL) string {
 		safeQuery = strings.ReplaceAll(safeQuery, "=", "_")
 		safeQuery = strings.ReplaceAll(safeQuery, "%", "")
 
-		path += "_q_" + safeQuery
+		name += "_q_" + safeQuery
 	}
 
 	// Add .html if no extension
-	if filepath.Ext(path) == "" {
-		path += ".html"
-	}
-
-	return filepath.Join(crawlDir, parsedURL.Host, path)
-}
-
-// extractLinks recursively extracts all absolute URLs from href attributes in an HTML node tree.
-// It resolves relative URLs using the provided base URL.
-func (f *Fetcher) extractLinks(n *html.Node, baseURL string) []string {
-	var links []string
-
-	var extract func(*html.Node)
-	extract = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "a" {
-			for _, attr := range n.Attr {
-				if attr.Key == "href" {
-					link := attr.Val
-					// Resolve relative URLs
-					absoluteURL, err := url.Parse(link)
-					if err != nil {
-						continue
-					}
-					base, err := url.Parse(baseURL)
-					if err != nil {
-						continue
-					}
-					resolvedURL := base.ResolveReference(absoluteURL)
-					links = append(links, resolvedURL.String())
-					break
-				}
-			}
-		}
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			extract(c)
-		}
+	if filepath.Ext(name) == "" {
+		name += ".html"
 	}
+	segments[len(segments)-1] = name
 
-	extract(n)
-	return links
+	elems := []string{crawlDir, idn.HostToDisplay(parsedURL.Host)}
+	for _, seg := range segments {
+		elems = append(elems, safeName(seg))
+	}
+	return filepath.Join(elems...)
 }
 
 // isNonHTMLResource checks if a URL points to a non-HTML resource based on file extension.
@@ -367,31 +986,6 @@ func isNonHTMLResource(urlStr string) bool {
 	return false
 }
 
-// decodeHTML decodes HTML bytes to string using detected charset.
-// It tries to extract charset from Content-Type header or HTML meta tag.
-func decodeHTML(body []byte, contentType string) string {
-	// Try to get charset from Content-Type header
-	enc := getEncodingFromContentType(contentType)
-	if enc != nil {
-		decoded, err := decodeWithEncoding(body, enc)
-		if err == nil {
-			return decoded
-		}
-	}
-
-	// Try to get charset from HTML meta tag
-	enc = getEncodingFromMeta(body)
-	if enc != nil {
-		decoded, err := decodeWithEncoding(body, enc)
-		if err == nil {
-			return decoded
-		}
-	}
-
-	// Fallback to UTF-8
-	return string(body)
-}
-
 // getEncodingFromContentType extracts charset from Content-Type header.
 func getEncodingFromContentType(contentType string) encoding.Encoding {
 	if contentType == "" {
@@ -475,32 +1069,37 @@ func decodeWithEncoding(body []byte, enc encoding.Encoding) (string, error) {
 // It attempts to fetch the page in languages specified by the LocaleConfig.Priority order,
 // using HEAD requests to check availability before fetching the full content.
 // It falls back to the original URL if no preferred locale version is found.
-func (f *Fetcher) crawlWithLocalePriority(originalURL, canonical, crawlDir string, depth int) error {
+func (f *Fetcher) crawlWithLocalePriority(ctx context.Context, originalURL, canonical, crawlDir string, depth int) error {
 	parsedURL, err := url.Parse(originalURL)
 	if err != nil {
+		f.recordSkip(originalURL, depth, "invalid_url")
 		return nil
 	}
 
 	if !f.shouldCrawlURL(originalURL, depth) {
-		return nil
+		f.recordSkip(originalURL, depth, "url_filter")
+		return startError(depth, ErrOutOfScope, originalURL)
 	}
 
-	// Only crawl same domain
-	if parsedURL.Host != f.domain {
-		return nil
+	// Only crawl same domain (feed entries may link anywhere)
+	if parsedURL.Host != f.domain && !f.feed {
+		f.recordSkip(originalURL, depth, "out_of_domain")
+		return startError(depth, ErrOutOfScope, originalURL)
 	}
 
 	// Skip non-HTML resources
-	if isNonHTMLResource(originalURL) {
-		return nil
+	if f.skipsResource(originalURL) {
+		f.recordSkip(originalURL, depth, "non_html_extension")
+		return startError(depth, ErrOutOfScope, originalURL)
 	}
 
 	// Check robots.txt
-	if !f.robotsChecker.IsAllowed(originalURL) {
-		log.Printf("Blocked by robots.txt: %s", originalURL)
-		return nil
+	if err := f.robotsChecker.Check(ctx, originalURL); err != nil {
+		return f.blockRobots(originalURL, depth, err)
 	}
 
+	rec := PageRecord{URL: originalURL, Depth: depth, Outcome: OutcomeFailed, Version: f.pageVersion(originalURL)}
+
 	baseURL := parsedURL.Scheme + "://" + parsedURL.Host
 
 	// 優先順位に従ってロケールを試行
@@ -509,134 +1108,356 @@ func (f *Fetcher) crawlWithLocalePriority(originalURL, canonical, crawlDir strin
 		priority = DefaultLocalePriority
 	}
 
+	// Walk the fallback chain until one candidate returns a page.
+	var resp *http.Response
 	var fetchURL string
 	var foundLocale string
 
-	// まず各ロケールでHEADリクエストを試行
-	for _, locale := range priority {
-		testURL := BuildLocaleURL(baseURL, locale, canonical, f.localeConfig)
-		exists, statusCode := f.checkURLExists(testURL)
-
-		if exists {
-			fetchURL = testURL
-			foundLocale = locale
-			break
+	candidates := localeCandidates(baseURL, canonical, originalURL, priority, f.localeConfig)
+	if f.negotiates(parsedURL.Host) {
+		candidates = negotiatedFirst(candidates)
+	}
+	for _, cand := range candidates {
+		// A host known not to resolve fails the page without probing further
+		if err := f.dns.Failure(parsedURL.Hostname()); err != nil {
+			rec.FetchedURL = cand.url
+			f.dnsFailure(&rec, cand.url, err)
+			return nil
 		}
 
-		// 404以外のエラーは異常系として中断
-		if statusCode != http.StatusNotFound && statusCode != 0 {
+		// まず HEAD リクエストで存在確認
+		exists, statusCode := f.checkURLExists(ctx, cand.url)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !exists {
+			// 404以外のエラーは異常系として中断
 			if statusCode == http.StatusForbidden || statusCode == http.StatusTooManyRequests || statusCode >= 500 {
-				log.Printf("Warning: %s returned status %d, skipping canonical %s", testURL, statusCode, canonical)
+				warnlog.Printf("http_status", "Warning: %s returned status %d, skipping canonical %s", cand.url, statusCode, canonical)
+				rec.FetchedURL = cand.url
+				rec.StatusCode = statusCode
+				rec.Reason = statusReason(statusCode)
+				rec.Error = fmt.Sprintf("locale probe returned status %d", statusCode)
+				f.record(rec)
 				return nil
 			}
+			continue
 		}
-	}
 
-	// 優先ロケールで見つからない場合、元のURLを試す
-	if fetchURL == "" {
-		exists, _ := f.checkURLExists(originalURL)
-		if exists {
-			fetchURL = originalURL
-		} else {
+		// 本文を取得
+		req, err := f.newRequest(ctx, "GET", cand.url)
+		if err != nil {
+			continue
+		}
+
+		// Be polite: wait between requests, as long as the settings of the host
+		// say, and longer for throttled hosts
+		r, err := f.do(ctx, req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			rec.FetchedURL = cand.url
+			if isDNSError(err) {
+				f.dnsFailure(&rec, cand.url, err)
+				return nil
+			}
+			if f.redirectFailure(&rec, err) {
+				return nil
+			}
+			f.fetchFailure(&rec, cand.url, err)
 			return nil
 		}
-	}
 
-	// Be polite: wait 1 second between requests
-	time.Sleep(1 * time.Second)
+		// HEAD succeeded but GET says the page is gone: fall back to the next candidate
+		if r.StatusCode == http.StatusNotFound || r.StatusCode == http.StatusGone {
+			r.Body.Close()
+			warnlog.Printf("locale_fallback", "Warning: %s returned status %d, trying next locale", cand.url, r.StatusCode)
+			continue
+		}
 
-	// 本文を取得
-	req, err := http.NewRequest("GET", fetchURL, nil)
-	if err != nil {
-		return nil
+		// A variant redirected to another locale's (e.g., by geo-IP) isn't
+		// available in its locale; a URL without a locale redirected to a
+		// variant serves the variant's
+		locale := cand.locale
+		if final := r.Request.URL; final.String() != cand.url {
+			redirected := f.redirectLocale(final)
+			if cand.locale != "" && redirected != cand.locale {
+				r.Body.Close()
+				warnlog.Printf("locale_fallback", "Warning: %s redirects to %s, another locale, trying next locale", cand.url, final)
+				continue
+			}
+			locale = redirected
+		}
+
+		resp, fetchURL, foundLocale = r, cand.url, locale
+		break
 	}
-	req.Header.Set("User-Agent", UserAgent)
 
-	resp, err := f.client.Do(req)
-	if err != nil {
-		log.Printf("Warning: failed to fetch %s: %v", fetchURL, err)
+	if resp == nil {
+		rec.Outcome = OutcomeSkipped
+		rec.Reason = "no_locale_variant_available"
+		f.record(rec)
 		return nil
 	}
 	defer resp.Body.Close()
 
+	rec.Locale = foundLocale
+	rec.LocaleMatch = DescribeLocaleMatch(foundLocale, priority, f.localeConfig)
+	rec.Negotiated = f.noteNegotiation(parsedURL.Host, resp.Header)
+	if fetchURL != originalURL {
+		rec.FetchedURL = fetchURL
+	}
+	responseDetails(&rec, resp)
+
 	if resp.StatusCode != http.StatusOK {
-		log.Printf("Warning: %s returned status %d", fetchURL, resp.StatusCode)
+		f.statusFailure(&rec, fetchURL)
 		return nil
 	}
 
+	// A redirected page is known by its final URL
+	if rec.FinalURL != "" {
+		if reason := f.claimRedirect(fetchURL, rec.FinalURL, depth); reason != "" {
+			rec.Outcome = OutcomeSkipped
+			rec.Reason = reason
+			f.record(rec)
+			return nil
+		}
+		fetchURL = rec.FinalURL
+		// Unless it only moved to another locale of the page, it is saved
+		// under its final URL, as without locale priority
+		if u, err := url.Parse(rec.FinalURL); err == nil {
+			_, finalCanonical := ExtractLocale(u, f.localeConfig)
+			if _, canonical := ExtractLocale(parsedURL, f.localeConfig); canonical != finalCanonical {
+				parsedURL = u
+			}
+		}
+	}
+
 	// Check content type
 	contentType := resp.Header.Get("Content-Type")
-	if !strings.Contains(contentType, "text/html") && contentType != "" {
+	if f.includePDF && isPDFResponse(contentType, fetchURL) {
+		if f.obeyDirectives(&rec, resp.Header, nil) {
+			return nil
+		}
+		return f.savePDF(ctx, &rec, resp.Body, resp.ContentLength, parsedURL, crawlDir, fetchURL)
+	}
+	mediaType, accepted := f.acceptsContentType(contentType)
+	if !accepted {
+		rec.Outcome = OutcomeSkipped
+		rec.Reason = ReasonContentType
+		f.record(rec)
 		return nil
 	}
 
 	// Read body
-	body, err := io.ReadAll(resp.Body)
+	body, err := f.readBody(resp.Body, resp.ContentLength)
+	rec.Bytes = int64(len(body))
 	if err != nil {
-		log.Printf("Warning: failed to read body from %s: %v", fetchURL, err)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if skipped, stop := f.sizeFailure(&rec, err); skipped {
+			return stop
+		}
+		f.readFailure(&rec, fetchURL, err)
 		return nil
 	}
 
-	// Save to file
-	filePath := f.getFilePath(crawlDir, parsedURL)
-	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
-		log.Printf("Warning: failed to create directory for %s: %v", filePath, err)
+	body, rec.Charset = toUTF8(body, mediaType, contentType, resp.Header.Get("Content-Language"))
+	if !isHTMLMediaType(mediaType) {
+		if f.obeyDirectives(&rec, resp.Header, nil) {
+			return nil
+		}
+		if f.save(&rec, parsedURL.String(), f.textFilePath(crawlDir, parsedURL), body) {
+			f.downloadCount++
+		}
 		return nil
 	}
 
-	if err := os.WriteFile(filePath, body, 0644); err != nil {
-		log.Printf("Warning: failed to write file %s: %v", filePath, err)
+	doc, parseErr := html.Parse(bytes.NewReader(body))
+	if parseErr == nil {
+		if handled, err := f.handleInterstitial(ctx, doc, &rec, fetchURL, crawlDir, depth); handled {
+			return err
+		}
+	}
+	var directivesDoc *html.Node
+	if parseErr == nil {
+		directivesDoc = doc
+	}
+	if f.obeyDirectives(&rec, resp.Header, directivesDoc) {
+		if parseErr != nil {
+			return nil
+		}
+		return f.crawlLinks(ctx, doc, &rec, fetchURL, crawlDir, depth)
+	}
+	var alternates map[string]string
+	if parseErr == nil {
+		alternates = f.hreflangAlternates(doc, fetchURL)
+	}
+
+	// Opaque URLs carry no locale: fall back to what the response declares,
+	// and use its hreflang alternates to group the language variants
+	if foundLocale == "" && parseErr == nil {
+		if declared := DetectContentLocale(resp.Header, doc); declared != "" {
+			foundLocale = f.localeConfig.NormalizeLocale(declared)
+			rec.Locale = foundLocale
+			rec.LocaleMatch = DescribeLocaleMatch(foundLocale, priority, f.localeConfig)
+
+			// The preferred variant is crawled like any other page, then kept
+			// in place of this one only if it links back to it
+			for _, preferred := range f.preferredAlternates(alternates, fetchURL, foundLocale, priority) {
+				slog.Info("Fetching preferred locale variant", "url", fetchURL, "locale", foundLocale, "variant", preferred)
+				if err := f.crawl(ctx, preferred, crawlDir, depth); err != nil {
+					return err
+				}
+				if reciprocal, _ := f.linksBack(preferred, fetchURL); reciprocal {
+					rec.Outcome = OutcomeSkipped
+					rec.Reason = "preferred_locale_variant"
+					f.record(rec)
+					return nil
+				}
+				warnlog.Printf("hreflang", "Warning: hreflang alternate %s of %s doesn't link back; ignoring it", preferred, fetchURL)
+			}
+			f.markAlternatesVisited(alternates, fetchURL)
+		}
+	}
+
+	// Locale variants are saved under their locale-neutral path, but a page
+	// whose canonical URL was already saved is still a duplicate
+	if parseErr == nil {
+		if _, reason := f.claimCanonical(doc, fetchURL, &rec); reason != "" {
+			rec.Outcome = OutcomeSkipped
+			rec.Reason = reason
+			f.record(rec)
+			return f.crawlLinks(ctx, doc, &rec, fetchURL, crawlDir, depth)
+		}
+	}
+
+	// Save to file
+	if !f.save(&rec, parsedURL.String(), f.getFilePath(crawlDir, parsedURL), body) {
 		return nil
 	}
 
 	f.downloadCount++
-	elapsed := time.Since(f.startTime)
-	rate := float64(f.downloadCount) / elapsed.Seconds()
-	mins := int(elapsed.Minutes())
-	secs := int(elapsed.Seconds()) % 60
-	shortURL := fetchURL
-	if len(shortURL) > 60 {
-		shortURL = shortURL[len(shortURL)-60:]
-	}
-	localeInfo := ""
-	if foundLocale != "" {
-		localeInfo = fmt.Sprintf(" [%s]", foundLocale)
-	}
-	fmt.Printf("\r[%d pages | %dm%02ds | %.1f/s]%s %s", f.downloadCount, mins, secs, rate, localeInfo, shortURL)
-
-	// Parse HTML and extract links
-	htmlString := decodeHTML(body, resp.Header.Get("Content-Type"))
-	doc, err := html.Parse(strings.NewReader(htmlString))
-	if err != nil {
+
+	// Extract links
+	if parseErr != nil {
 		return nil
 	}
+	return f.crawlLinks(ctx, doc, &rec, fetchURL, crawlDir, depth)
+}
 
-	links := f.extractLinks(doc, fetchURL)
+// preferredAlternates returns the URLs of the hreflang alternates of a page
+// whose locale ranks higher in priority than locale, best first, or none if
+// the current page is the best variant. alternates are the page's trusted
+// alternates (see hreflangAlternates). Alternates already known not to link
+// back to the page are left out: a broken hreflang declaration would
+// otherwise send the crawler to unrelated pages.
+func (f *Fetcher) preferredAlternates(alternates map[string]string, pageURL, locale string, priority []string) []string {
+	current := localeRank(locale, priority, f.localeConfig)
+
+	var urls []string
+	for _, altLocale := range f.betterAlternates(alternates, pageURL, current, priority) {
+		altURL := alternates[altLocale]
+		if reciprocal, known := f.linksBack(altURL, pageURL); known && !reciprocal {
+			warnlog.Printf("hreflang", "Warning: hreflang %q alternate %s of %s doesn't link back; ignoring it", altLocale, altURL, pageURL)
+			continue
+		}
+		urls = append(urls, altURL)
+	}
+	return urls
+}
 
-	// Crawl links (with depth limit)
-	for _, link := range links {
-		f.crawl(link, crawlDir, depth+1)
+// markAlternatesVisited records the canonical paths of a saved page's hreflang
+// alternates as visited, so its other language variants are not fetched.
+// Only the alternates already known to link back to the page are marked: the
+// others are checked when the crawl reaches them.
+func (f *Fetcher) markAlternatesVisited(alternates map[string]string, pageURL string) {
+	for _, altURL := range alternates {
+		if reciprocal, known := f.linksBack(altURL, pageURL); !known || !reciprocal {
+			continue
+		}
+		u, err := url.Parse(altURL)
+		if err != nil {
+			continue
+		}
+		_, canonical := ExtractLocale(u, f.localeConfig)
+		f.mu.Lock()
+		f.visitedCanonical[canonical] = true
+		f.mu.Unlock()
 	}
+}
 
-	return nil
+// resolveURL resolves href against base, in its request form (see idn.ToASCII).
+func resolveURL(base, href string) (string, error) {
+	b, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	h, err := url.Parse(href)
+	if err != nil {
+		return "", err
+	}
+	return idn.ToASCII(b.ResolveReference(h).String()), nil
+}
+
+// localeCandidate is one URL to try in the locale fallback chain.
+type localeCandidate struct {
+	// locale is the locale served by url, or "" for the no-locale URL
+	locale string
+	url    string
+}
+
+// localeCandidates builds the ordered fallback chain for a canonical path:
+// every locale in priority order (with any configured aliases of it), then the
+// canonical URL without a locale, then the URL as originally discovered.
+// Duplicate URLs are dropped.
+func localeCandidates(baseURL, canonical, originalURL string, priority []string, cfg *LocaleConfig) []localeCandidate {
+	var candidates []localeCandidate
+	seen := make(map[string]bool)
+	add := func(locale, u string) {
+		if u == "" || seen[u] {
+			return
+		}
+		seen[u] = true
+		candidates = append(candidates, localeCandidate{locale: locale, url: u})
+	}
+
+	for _, locale := range priority {
+		for _, code := range cfg.variants(locale) {
+			add(locale, BuildLocaleURL(baseURL, code, canonical, cfg))
+		}
+	}
+	add("", BuildLocaleURL(baseURL, "", canonical, cfg))
+
+	originalLocale := ""
+	if u, err := url.Parse(originalURL); err == nil {
+		originalLocale, _ = ExtractLocale(u, cfg)
+		if originalLocale != "" {
+			originalLocale = cfg.NormalizeLocale(originalLocale)
+		}
+	}
+	add(originalLocale, originalURL)
+
+	return candidates
 }
 
 // checkURLExists checks if a URL is accessible using a HEAD request.
 // It returns both a boolean indicating success and the HTTP status code.
 // If HEAD fails, it falls back to a GET request with a Range header.
-func (f *Fetcher) checkURLExists(targetURL string) (bool, int) {
-	req, err := http.NewRequest("HEAD", targetURL, nil)
+func (f *Fetcher) checkURLExists(ctx context.Context, targetURL string) (bool, int) {
+	req, err := f.newRequest(ctx, "HEAD", targetURL)
 	if err != nil {
 		return false, 0
 	}
-	req.Header.Set("User-Agent", UserAgent)
 
 	// HEAD リクエストは短いタイムアウトで
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := &http.Client{Timeout: 10 * time.Second, Transport: f.client.Transport, CheckRedirect: f.client.CheckRedirect}
 	resp, err := client.Do(req)
 	if err != nil {
 		// HEAD が失敗した場合、GET + Range を試す
-		return f.checkURLExistsWithRange(targetURL)
+		return f.checkURLExistsWithRange(ctx, targetURL)
 	}
 	defer resp.Body.Close()
 
@@ -646,15 +1467,14 @@ func (f *Fetcher) checkURLExists(targetURL string) (bool, int) {
 // checkURLExistsWithRange checks if a URL is accessible using a GET request with a Range header.
 // This is a fallback for servers that don't support HEAD requests.
 // It requests only the first byte to minimize bandwidth usage.
-func (f *Fetcher) checkURLExistsWithRange(targetURL string) (bool, int) {
-	req, err := http.NewRequest("GET", targetURL, nil)
+func (f *Fetcher) checkURLExistsWithRange(ctx context.Context, targetURL string) (bool, int) {
+	req, err := f.newRequest(ctx, "GET", targetURL)
 	if err != nil {
 		return false, 0
 	}
-	req.Header.Set("User-Agent", UserAgent)
 	req.Header.Set("Range", "bytes=0-0")
 
-	client := &http.Client{Timeout: 10 * time.Second}
+	client := &http.Client{Timeout: 10 * time.Second, Transport: f.client.Transport, CheckRedirect: f.client.CheckRedirect}
 	resp, err := client.Do(req)
 	if err != nil {
 		return false, 0
@@ -665,10 +1485,57 @@ func (f *Fetcher) checkURLExistsWithRange(targetURL string) (bool, int) {
 	return resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusPartialContent, resp.StatusCode
 }
 
+// blockRobots records targetURL, a URL at depth that robots.txt doesn't
+// allow, as blocked: err is the error of RobotsChecker.Check, which gives the
+// reason "robots_txt", or ReasonRobotsUnavailable if the robots.txt of its
+// host can't be fetched in strict mode. Returns the error of the start URL
+// (see startError).
+func (f *Fetcher) blockRobots(targetURL string, depth int, err error) error {
+	reason := "robots_txt"
+	if errors.Is(err, ErrRobotsUnavailable) {
+		reason = ReasonRobotsUnavailable
+		warnlog.Printf("robots", "Blocked, %v: %s", err, targetURL)
+	} else {
+		warnlog.Printf("robots", "Blocked by robots.txt: %s", targetURL)
+	}
+	f.record(PageRecord{URL: targetURL, Depth: depth, Outcome: OutcomeBlocked, Reason: reason})
+	return startError(depth, err, targetURL)
+}
+
+// startError returns err wrapped with targetURL when targetURL is the start URL
+// (depth 0), whose skipping leaves nothing to crawl, and nil otherwise.
+func startError(depth int, err error, targetURL string) error {
+	if depth > 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", err, targetURL)
+}
+
+// sleep waits for d, or returns the error of ctx if it is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
 func (f *Fetcher) shouldCrawlURL(targetURL string, depth int) bool {
 	if matchesAnyFilter(targetURL, f.excludeFilters) {
 		return false
 	}
+	if depth > 0 && len(f.pathPatterns) > 0 {
+		u, err := url.Parse(targetURL)
+		if err != nil || !pathglob.MatchAny(f.pathPatterns, u.Path) {
+			return false
+		}
+	}
 	if depth == 0 && len(f.includeFilters) > 0 {
 		return true
 	}
@@ -690,9 +1557,13 @@ func normalizeFilters(filters []string) []string {
 	return normalized
 }
 
+// matchesAnyFilter reports whether targetURL contains any of filters, in its
+// request form or in its display form (see idn.ToDisplay), so that filters may
+// spell internationalized hosts and paths either way.
 func matchesAnyFilter(targetURL string, filters []string) bool {
+	display := idn.ToDisplay(targetURL)
 	for _, filter := range filters {
-		if strings.Contains(targetURL, filter) {
+		if strings.Contains(targetURL, filter) || strings.Contains(display, filter) {
 			return true
 		}
 	}
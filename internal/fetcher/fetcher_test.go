@@ -1,9 +1,19 @@
 package fetcher
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/progress"
+	"golang.org/x/net/html"
 )
 
 func TestNewFetcher(t *testing.T) {
@@ -61,6 +71,31 @@ func TestGetFilePath(t *testing.T) {
 			// % is stripped
 			wantPath: "example.com/path_q_key_val2Fue.html",
 		},
+		{
+			name:     "Internationalized host and path",
+			urlStr:   "https://xn--r8jz45g.jp/%E3%83%89%E3%82%AD%E3%83%A5%E3%83%A1%E3%83%B3%E3%83%88/%E3%81%AF%E3%81%98%E3%82%81%E3%81%AB",
+			wantPath: "例え.jp/ドキュメント/はじめに.html",
+		},
+		{
+			name:     "Dot segments",
+			urlStr:   "https://example.com/../../etc/passwd",
+			wantPath: "example.com/__/__/etc/passwd.html",
+		},
+		{
+			name:     "Encoded slash and reserved characters",
+			urlStr:   "https://example.com/api:v1/a%2Fb",
+			wantPath: "example.com/api_v1/a_b.html",
+		},
+		{
+			name:     "Segment that isn't UTF-8",
+			urlStr:   "https://example.com/%FF%FE",
+			wantPath: "example.com/%FF%FE.html",
+		},
+		{
+			name:     "Trailing slash",
+			urlStr:   "https://example.com/docs/",
+			wantPath: "example.com/docs.html",
+		},
 	}
 
 	for _, tt := range tests {
@@ -83,3 +118,323 @@ func TestGetFilePath(t *testing.T) {
 		})
 	}
 }
+
+func TestExtractLinks(t *testing.T) {
+	doc, err := html.Parse(strings.NewReader(`<html><body>
+		<a href="https://例え.jp/ドキュメント">Unicode</a>
+		<a href="/%e3%83%89%e3%82%ad%e3%83%a5%e3%83%a1%e3%83%b3%e3%83%88">Lower-case escapes</a>
+		<a href="はじめに?q=日本">Relative</a>
+		<a href="https://docs.example.com/guide">ASCII</a>
+	</body></html>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	docsPath := "/%E3%83%89%E3%82%AD%E3%83%A5%E3%83%A1%E3%83%B3%E3%83%88"
+	want := []string{
+		"https://xn--r8jz45g.jp" + docsPath,
+		"https://xn--r8jz45g.jp" + docsPath,
+		"https://xn--r8jz45g.jp/%E3%81%AF%E3%81%98%E3%82%81%E3%81%AB?q=%E6%97%A5%E6%9C%AC",
+		"https://docs.example.com/guide",
+	}
+	got := New(t.TempDir()).extractLinks(doc, "https://xn--r8jz45g.jp/")
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("extractLinks() = %q, want %q", got, want)
+	}
+}
+
+func TestFetchSendsHeaders(t *testing.T) {
+	var unauthorized int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/robots.txt") {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer s3cret" || r.Header.Get("User-Agent") != UserAgent {
+			unauthorized++
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="/docs/next">Next</a></body></html>`))
+	}))
+	defer server.Close()
+
+	f := New(t.TempDir())
+	f.delay = 0
+	f.SetHeaders(http.Header{"Authorization": {"Bearer s3cret"}, "User-Agent": {"ignored"}})
+
+	if err := f.Fetch(server.URL + "/docs/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+	if unauthorized > 0 {
+		t.Errorf("%d requests were sent without the configured headers", unauthorized)
+	}
+	if got := len(f.Report().Pages); got != 2 {
+		t.Errorf("got %d report entries, want 2", got)
+	}
+}
+
+func TestFetchDevice(t *testing.T) {
+	tests := []struct {
+		device string
+		want   string
+	}{
+		{"", UserAgent},
+		{DeviceDesktop, UserAgent},
+		{DeviceMobile, MobileUserAgent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.device, func(t *testing.T) {
+			var agents []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "/robots.txt") {
+					http.NotFound(w, r)
+					return
+				}
+				agents = append(agents, r.Header.Get("User-Agent"))
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte(`<html><body>Docs</body></html>`))
+			}))
+			defer server.Close()
+
+			f := New(t.TempDir())
+			f.delay = 0
+			if err := f.SetDevice(tt.device); err != nil {
+				t.Fatalf("SetDevice() returned error: %v", err)
+			}
+			if err := f.Fetch(server.URL + "/docs/"); err != nil {
+				t.Fatalf("Fetch() returned error: %v", err)
+			}
+			if len(agents) != 1 || agents[0] != tt.want {
+				t.Errorf("User-Agent = %q, want %q", agents, tt.want)
+			}
+		})
+	}
+
+	if err := New(t.TempDir()).SetDevice("tablet"); err == nil {
+		t.Error("SetDevice() accepted an unknown device")
+	}
+}
+
+func TestFetchPageHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><a href="/docs/guide">Guide</a><a href="/docs/spec.pdf">Spec</a></body></html>`))
+		case "/docs/spec.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte("%PDF-1.4"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	f := New(t.TempDir())
+	f.delay = 0
+	var mu sync.Mutex
+	outcomes := make(map[string]Outcome)
+	f.SetPageHook(func(rec PageRecord) {
+		mu.Lock()
+		defer mu.Unlock()
+		outcomes[strings.TrimPrefix(rec.URL, server.URL)] = rec.Outcome
+	})
+
+	if err := f.Fetch(server.URL + "/docs/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+
+	want := map[string]Outcome{
+		"/docs/":         OutcomeSaved,
+		"/docs/guide":    OutcomeFailed,
+		"/docs/spec.pdf": OutcomeSkipped,
+	}
+	for url, outcome := range want {
+		if outcomes[url] != outcome {
+			t.Errorf("hook saw %s as %q, want %q", url, outcomes[url], outcome)
+		}
+	}
+	if len(outcomes) != len(f.Report().Pages) {
+		t.Errorf("hook saw %d URLs, report has %d", len(outcomes), len(f.Report().Pages))
+	}
+}
+
+// recordingReporter records the snapshots of a crawl.
+type recordingReporter struct {
+	snapshots []progress.Snapshot
+}
+
+func (r *recordingReporter) Report(s progress.Snapshot) {
+	r.snapshots = append(r.snapshots, s)
+}
+
+func TestFetchProgress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><a href="/docs/a">A</a><a href="/docs/b">B</a><a href="/docs/a">A again</a><a href="/other">Out</a></body></html>`))
+		case "/docs/a", "/docs/b":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><a href="/docs/">Home</a><a href="/docs/b">B</a></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	f := New(t.TempDir())
+	f.delay = 0
+	f.SetURLFilters([]string{"/docs/"}, nil)
+	r := &recordingReporter{}
+	f.SetProgressReporter(r)
+	if err := f.Fetch(server.URL + "/docs/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+
+	if len(r.snapshots) < 2 {
+		t.Fatalf("got %d snapshots, want one per URL and a last one", len(r.snapshots))
+	}
+	maxQueued := 0
+	for _, s := range r.snapshots[:len(r.snapshots)-1] {
+		maxQueued = max(maxQueued, s.Queued)
+		if s.Done {
+			t.Errorf("snapshot before the last is done: %+v", s)
+		}
+	}
+	// The start page queues /docs/a and /docs/b once each
+	if maxQueued != 2 {
+		t.Errorf("max queued = %d, want 2", maxQueued)
+	}
+	last := r.snapshots[len(r.snapshots)-1]
+	if !last.Done || last.Fetched != 3 || last.Skipped != 1 || last.Queued != 0 || last.Bytes == 0 || last.Rate <= 0 {
+		t.Errorf("last snapshot = %+v, want done with 3 fetched, 1 skipped, none queued", last)
+	}
+}
+
+func TestFetchContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><a href="/docs/a">A</a><a href="/docs/slow">Slow</a></body></html>`))
+		case "/docs/a":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><a href="/docs/b">B</a></body></html>`))
+		case "/docs/slow":
+			<-r.Context().Done()
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Run("cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		f := New(t.TempDir())
+		f.delay = 0
+		f.SetPageHook(func(rec PageRecord) {
+			if rec.Outcome == OutcomeSaved {
+				cancel()
+			}
+		})
+
+		err := f.FetchContext(ctx, server.URL+"/docs/")
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("FetchContext() error = %v, want context.Canceled", err)
+		}
+		if got := f.Report().Summary; got[OutcomeSaved] != 1 || got[OutcomeFailed] != 0 {
+			t.Errorf("Summary = %v, want only the first page saved", got)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+
+		f := New(t.TempDir())
+		f.delay = 0
+		err := f.FetchContext(ctx, server.URL+"/docs/")
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("FetchContext() error = %v, want context.DeadlineExceeded", err)
+		}
+		if f.Report().FinishedAt.IsZero() {
+			t.Error("report of an interrupted crawl should have FinishedAt set")
+		}
+	})
+}
+
+func TestFetchStartURLErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body>ok</body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		path    string
+		exclude []string
+		wantErr error
+	}{
+		{"allowed", "/docs/", nil, nil},
+		{"disallowed by robots.txt", "/private/", nil, ErrRobotsBlocked},
+		{"excluded by filter", "/docs/", []string{"/docs/"}, ErrOutOfScope},
+		{"non-HTML resource", "/docs/manual.pdf", nil, ErrOutOfScope},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := New(t.TempDir())
+			f.delay = 0
+			f.SetURLFilters(nil, tt.exclude)
+			err := f.Fetch(server.URL + tt.path)
+			if !errors.Is(err, tt.wantErr) || (tt.wantErr == nil) != (err == nil) {
+				t.Errorf("Fetch() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFetchPathPatterns(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="/docs/guides/intro">Intro</a><a href="/docs/guides/setup/linux">Linux</a><a href="/docs/api/auth">Auth</a></body></html>`))
+	}))
+	defer server.Close()
+
+	f := New(t.TempDir())
+	f.delay = 0
+	f.SetPathPatterns([]string{"/docs/guides/**"})
+	if err := f.Fetch(server.URL + "/docs/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+
+	want := map[string]Outcome{
+		"/docs/":                   OutcomeSaved,
+		"/docs/guides/intro":       OutcomeSaved,
+		"/docs/guides/setup/linux": OutcomeSaved,
+		"/docs/api/auth":           OutcomeSkipped,
+	}
+	report := f.Report()
+	if len(report.Pages) != len(want) {
+		t.Errorf("got %d report entries, want %d", len(report.Pages), len(want))
+	}
+	for _, rec := range report.Pages {
+		if path := strings.TrimPrefix(rec.URL, server.URL); rec.Outcome != want[path] {
+			t.Errorf("%s: outcome %q, want %q", path, rec.Outcome, want[path])
+		}
+	}
+}
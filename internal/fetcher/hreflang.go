@@ -0,0 +1,149 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the validation of hreflang alternates before the
+// crawler trusts them to group the language variants of a page.
+package fetcher
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+	"golang.org/x/net/html"
+)
+
+// hreflangLink is one <link rel="alternate" hreflang="..."> of a page.
+type hreflangLink struct {
+	locale string
+	href   string
+}
+
+// extractHreflangLinks returns every hreflang alternate link of doc in
+// document order, with lowercased locales.
+func extractHreflangLinks(doc *html.Node) []hreflangLink {
+	var links []hreflangLink
+	if doc == nil {
+		return links
+	}
+
+	var extract func(*html.Node)
+	extract = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.Data == "link" {
+			var rel, hreflang, href string
+			for _, attr := range n.Attr {
+				switch attr.Key {
+				case "rel":
+					rel = attr.Val
+				case "hreflang":
+					hreflang = attr.Val
+				case "href":
+					href = attr.Val
+				}
+			}
+			if rel == "alternate" && hreflang != "" && href != "" {
+				links = append(links, hreflangLink{locale: strings.ToLower(hreflang), href: href})
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			extract(c)
+		}
+	}
+
+	extract(doc)
+	return links
+}
+
+// hreflangAlternates returns the hreflang alternates of the page at pageURL
+// that can be trusted, keyed by locale with absolute URLs, and remembers them
+// for the reciprocity checks of other pages (see linksBack). It is called for
+// every page parsed in locale priority mode. Alternates on
+// another host or excluded by the URL filters are dropped, as are the locales
+// declared with several different URLs; both are logged as warnings.
+func (f *Fetcher) hreflangAlternates(doc *html.Node, pageURL string) map[string]string {
+	declared := make(map[string][]string)
+	var locales []string
+	for _, link := range extractHreflangLinks(doc) {
+		resolved, err := resolveURL(pageURL, link.href)
+		if err != nil {
+			continue
+		}
+		if len(declared[link.locale]) == 0 {
+			locales = append(locales, link.locale)
+		}
+		if !contains(declared[link.locale], resolved) {
+			declared[link.locale] = append(declared[link.locale], resolved)
+		}
+	}
+
+	alternates := make(map[string]string)
+	for _, locale := range locales {
+		urls := declared[locale]
+		if len(urls) > 1 {
+			warnlog.Printf("hreflang", "Warning: %s declares conflicting hreflang %q alternates %s; ignoring them", pageURL, locale, strings.Join(urls, ", "))
+			continue
+		}
+		u, err := url.Parse(urls[0])
+		if err != nil || u.Host != f.domain || !f.shouldCrawlURL(urls[0], 1) {
+			warnlog.Printf("hreflang", "Warning: %s declares hreflang %q alternate %s outside the crawl scope; ignoring it", pageURL, locale, urls[0])
+			continue
+		}
+		alternates[locale] = urls[0]
+	}
+
+	f.mu.Lock()
+	f.alternates[pageURL] = alternates
+	f.mu.Unlock()
+	return alternates
+}
+
+// linksBack reports whether the page at altURL declares pageURL among its
+// hreflang alternates, i.e. whether the alternate relation is reciprocal.
+// known is false if altURL hasn't been parsed yet. Alternates are never
+// fetched just for this check, which would add requests outside the crawl's
+// pacing: they are crawled as pages first (see crawlWithLocalePriority).
+func (f *Fetcher) linksBack(altURL, pageURL string) (reciprocal, known bool) {
+	f.mu.Lock()
+	alternates, known := f.alternates[altURL]
+	f.mu.Unlock()
+	return known && linksTo(alternates, pageURL), known
+}
+
+// linksTo reports whether alternates include pageURL.
+func linksTo(alternates map[string]string, pageURL string) bool {
+	for _, u := range alternates {
+		if u == pageURL {
+			return true
+		}
+	}
+	return false
+}
+
+// betterAlternates returns the locales of alternates other than pageURL that
+// rank higher in priority than current, best first and in a stable order.
+func (f *Fetcher) betterAlternates(alternates map[string]string, pageURL string, current int, priority []string) []string {
+	var locales []string
+	for locale, u := range alternates {
+		if u != pageURL && localeRank(locale, priority, f.localeConfig) < current {
+			locales = append(locales, locale)
+		}
+	}
+	sort.Slice(locales, func(i, j int) bool {
+		ri := localeRank(locales[i], priority, f.localeConfig)
+		rj := localeRank(locales[j], priority, f.localeConfig)
+		if ri != rj {
+			return ri < rj
+		}
+		return locales[i] < locales[j]
+	})
+	return locales
+}
+
+// contains reports whether list contains s.
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
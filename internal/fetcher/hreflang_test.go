@@ -0,0 +1,99 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchValidatesHreflangAlternates(t *testing.T) {
+	// The ja page declares an en alternate, which is preferred if the
+	// declaration can be trusted.
+	tests := []struct {
+		name         string
+		jaAlternates string
+		enAlternates string
+		// wantJA is the outcome of the ja page
+		wantJA    Outcome
+		wantSaved int
+		// wantEnRequests counts the GET requests for the en page, which is
+		// crawled as a page rather than fetched apart for the checks
+		wantEnRequests int
+	}{
+		{
+			name:           "reciprocal",
+			jaAlternates:   `<link rel="alternate" hreflang="en" href="/page-1001">`,
+			enAlternates:   `<link rel="alternate" hreflang="ja" href="/page-2002">`,
+			wantJA:         OutcomeSkipped,
+			wantSaved:      1,
+			wantEnRequests: 1,
+		},
+		{
+			name:           "not reciprocal",
+			jaAlternates:   `<link rel="alternate" hreflang="en" href="/page-1001">`,
+			enAlternates:   `<link rel="alternate" hreflang="en" href="/page-1001">`,
+			wantJA:         OutcomeSaved,
+			wantSaved:      2,
+			wantEnRequests: 1,
+		},
+		{
+			name:         "other domain",
+			jaAlternates: `<link rel="alternate" hreflang="en" href="https://unrelated.example/page-1001">`,
+			wantJA:       OutcomeSaved,
+			wantSaved:    1,
+		},
+		{
+			name: "conflicting declarations",
+			jaAlternates: `<link rel="alternate" hreflang="en" href="/page-1001">
+<link rel="alternate" hreflang="en" href="/page-3003">`,
+			enAlternates: `<link rel="alternate" hreflang="ja" href="/page-2002">`,
+			wantJA:       OutcomeSaved,
+			wantSaved:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var enRequests int
+			mux := http.NewServeMux()
+			mux.HandleFunc("/robots.txt", http.NotFound)
+			mux.HandleFunc("/page-2002", func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "text/html")
+				w.Header().Set("Content-Language", "ja")
+				w.Write([]byte(`<html><head>` + tt.jaAlternates + `</head><body>日本語</body></html>`))
+			})
+			enPage := func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodGet {
+					enRequests++
+				}
+				w.Header().Set("Content-Type", "text/html")
+				w.Write([]byte(`<html lang="en"><head>` + tt.enAlternates + `</head><body>English</body></html>`))
+			}
+			mux.HandleFunc("/page-1001", enPage)
+			mux.HandleFunc("/page-3003", enPage)
+			server := httptest.NewServer(mux)
+			defer server.Close()
+
+			f := New(t.TempDir())
+			f.delay = 0
+			f.SetLocaleConfig(&LocaleConfig{Priority: []string{"en", "ja"}})
+
+			if err := f.Fetch(server.URL + "/page-2002"); err != nil {
+				t.Fatalf("Fetch() returned error: %v", err)
+			}
+
+			for _, p := range f.Report().Pages {
+				if strings.HasSuffix(p.URL, "/page-2002") && (p.Outcome != tt.wantJA || p.Locale != "ja") {
+					t.Errorf("ja variant = %+v, want %s with locale ja", p, tt.wantJA)
+				}
+			}
+			if n := f.Report().Summary[OutcomeSaved]; n != tt.wantSaved {
+				t.Errorf("saved %d pages, want %d", n, tt.wantSaved)
+			}
+			if enRequests != tt.wantEnRequests {
+				t.Errorf("fetched the en page %d times, want %d", enRequests, tt.wantEnRequests)
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the handling of the interstitials some sites gate
+// their content behind: consent cookies are sent with every request so that
+// cookie walls aren't served, meta refresh redirects are followed like HTTP
+// redirects, and the pages that are still only a consent wall or a "please
+// enable JavaScript" notice are flagged instead of being converted as content.
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+	"golang.org/x/net/html"
+)
+
+// Reasons of the pages skipped as interstitials, also recorded as their
+// PageRecord.Interstitial.
+const (
+	// ReasonConsentWall is the Reason of a page that is only a cookie or
+	// privacy consent wall.
+	ReasonConsentWall = "consent_wall"
+	// ReasonJavaScriptRequired is the Reason of a page that renders nothing
+	// without JavaScript, or only a notice asking to enable it.
+	ReasonJavaScriptRequired = "javascript_required"
+	// ReasonMetaRefresh is the Reason of a page redirecting to another with a
+	// <meta http-equiv="refresh">, which is crawled in its place.
+	ReasonMetaRefresh = "meta_refresh"
+)
+
+// metaRefreshMaxDelay is the longest delay, in seconds, of the meta refresh
+// redirects followed: pages refreshing later, such as status pages reloading
+// themselves, are content.
+const metaRefreshMaxDelay = 5
+
+// interstitialMaxWords is the number of words of text a page has at most to
+// be taken for an interstitial: a documentation page with a cookie banner or
+// a notice asking to enable JavaScript has more.
+const interstitialMaxWords = 150
+
+// shellMaxWords is the number of words of text, besides its <noscript>
+// notice, a page has at most to be taken for the empty shell of a JavaScript
+// application: short pages also carry such notices.
+const shellMaxWords = 20
+
+// javaScriptPhrases are the phrases of the notices of pages requiring
+// JavaScript, in lower case.
+var javaScriptPhrases = []string{
+	"enable javascript", "javascript is required", "javascript is disabled",
+	"requires javascript", "javascript must be enabled", "turn on javascript",
+	"javascript to run this app", "please enable js",
+}
+
+// consentPhrases are the phrases of cookie and privacy consent walls, in
+// lower case.
+var consentPhrases = []string{
+	"accept cookies", "accept all cookies", "cookie consent", "we use cookies",
+	"use of cookies", "manage cookies", "cookie settings", "cookie preferences",
+	"your privacy choices", "consent to continue", "before you continue",
+}
+
+// ParseCookie parses s, a cookie given as "name=value".
+//
+// Returns an error if s has no name or the name isn't a valid cookie name.
+func ParseCookie(s string) (*http.Cookie, error) {
+	name, value, ok := strings.Cut(s, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return nil, fmt.Errorf("invalid cookie %q: want NAME=VALUE", s)
+	}
+	c := &http.Cookie{Name: name, Value: strings.TrimSpace(value)}
+	if err := c.Valid(); err != nil {
+		return nil, fmt.Errorf("invalid cookie %q: %w", s, err)
+	}
+	return c, nil
+}
+
+// SetConsentCookies sets cookies, given as "name=value" (see ParseCookie),
+// sent with every request besides those of SetHeaders, such as the cookie a
+// consent banner sets once it is accepted ("CookieConsent=true"), so that the
+// site serves its pages instead of a consent wall. Unlike credentials, they
+// may be logged.
+//
+// Returns an error if a cookie is invalid.
+func (f *Fetcher) SetConsentCookies(cookies []string) error {
+	parsed := make([]*http.Cookie, 0, len(cookies))
+	for _, s := range cookies {
+		c, err := ParseCookie(s)
+		if err != nil {
+			return err
+		}
+		parsed = append(parsed, c)
+	}
+	f.consentCookies = parsed
+	return nil
+}
+
+// SetFollowMetaRefresh enables or disables following meta refresh redirects
+// (enabled by default): a page whose <meta http-equiv="refresh"> sends the
+// browser to another URL within metaRefreshMaxDelay seconds is skipped with
+// the reason ReasonMetaRefresh, and that URL is crawled at the same depth, as
+// if the page had been redirected by HTTP. When disabled, such pages are
+// saved as they are.
+func (f *Fetcher) SetFollowMetaRefresh(follow bool) {
+	f.noMetaRefresh = !follow
+}
+
+// SetKeepInterstitials enables or disables saving the pages taken for
+// interstitials (see DetectInterstitial). They are always flagged with their
+// PageRecord.Interstitial and a warning; unless kept, they are skipped with
+// their kind as Reason, and their links aren't followed.
+func (f *Fetcher) SetKeepInterstitials(keep bool) {
+	f.keepInterstitials = keep
+}
+
+// handleInterstitial handles doc, the page fetched from pageURL at depth
+// whose record is rec, if it is a meta refresh redirect or an interstitial.
+// Returns whether the page was handled, and then recorded, with the error
+// of crawling the target of its redirect.
+func (f *Fetcher) handleInterstitial(ctx context.Context, doc *html.Node, rec *PageRecord, pageURL, crawlDir string, depth int) (bool, error) {
+	if !f.noMetaRefresh {
+		if target := f.normalizeURL(MetaRefreshTarget(doc, pageURL)); target != "" && target != pageURL {
+			rec.Outcome = OutcomeSkipped
+			rec.Reason = ReasonMetaRefresh
+			rec.RefreshURL = target
+			f.record(*rec)
+			return true, f.crawl(ctx, target, crawlDir, depth)
+		}
+	}
+
+	kind := DetectInterstitial(doc)
+	if kind == "" {
+		return false, nil
+	}
+	rec.Interstitial = kind
+	if f.keepInterstitials {
+		warnlog.Printf("interstitial", "Warning: %s looks like an interstitial (%s); saving it anyway", pageURL, kind)
+		return false, nil
+	}
+	warnlog.Printf("interstitial", "Warning: %s looks like an interstitial (%s); skipping it", pageURL, kind)
+	rec.Outcome = OutcomeSkipped
+	rec.Reason = kind
+	f.record(*rec)
+	return true, nil
+}
+
+// MetaRefreshTarget returns the URL the <meta http-equiv="refresh"> of doc,
+// a page fetched from pageURL, redirects to within metaRefreshMaxDelay
+// seconds, resolved against pageURL, or "" if it has none.
+func MetaRefreshTarget(doc *html.Node, pageURL string) string {
+	var content string
+	var find func(*html.Node) bool
+	find = func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "meta" {
+			var httpEquiv, c string
+			for _, attr := range n.Attr {
+				switch attr.Key {
+				case "http-equiv":
+					httpEquiv = attr.Val
+				case "content":
+					c = attr.Val
+				}
+			}
+			if strings.EqualFold(strings.TrimSpace(httpEquiv), "refresh") {
+				content = c
+				return true
+			}
+		}
+		if n.Type == html.ElementNode && n.Data == "body" {
+			return false
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if find(c) {
+				return true
+			}
+		}
+		return false
+	}
+	if doc == nil || !find(doc) {
+		return ""
+	}
+
+	seconds, target, ok := parseRefresh(content)
+	if !ok || seconds > metaRefreshMaxDelay {
+		return ""
+	}
+	resolved, err := resolveURL(pageURL, target)
+	if err != nil || !strings.HasPrefix(resolved, "http") {
+		return ""
+	}
+	return resolved
+}
+
+// parseRefresh returns the delay in seconds and the URL, unresolved, of
+// content, the content of a <meta http-equiv="refresh">. ok is false if
+// content has no URL, as "5" reloading the page.
+func parseRefresh(content string) (seconds float64, target string, ok bool) {
+	// content is "5; url=/new", "0;URL='/new'", or "5" to reload the page
+	delay, target, ok := strings.Cut(content, ";")
+	if !ok {
+		delay, target, ok = strings.Cut(content, ",")
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(delay), 64)
+	if !ok || err != nil {
+		return 0, "", false
+	}
+	target = strings.TrimSpace(target)
+	if len(target) > 4 && strings.EqualFold(target[:4], "url=") {
+		target = strings.TrimSpace(target[4:])
+	}
+	target = strings.Trim(target, `"'`)
+	return seconds, target, target != ""
+}
+
+// DetectInterstitial returns the kind of interstitial doc is, or "" if it
+// looks like content: ReasonJavaScriptRequired for a page without any text
+// but scripts, whose little text asks to enable JavaScript, or which shows
+// next to nothing but a <noscript> notice doing so, and ReasonConsentWall for
+// a page whose little text is a cookie or privacy consent request. Pages with
+// more than interstitialMaxWords words of text are content.
+func DetectInterstitial(doc *html.Node) string {
+	var text, noscript strings.Builder
+	scripts := false
+	var walk func(n *html.Node, inNoscript bool)
+	walk = func(n *html.Node, inNoscript bool) {
+		switch {
+		case n.Type == html.TextNode:
+			if inNoscript {
+				noscript.WriteString(n.Data + " ")
+			} else {
+				text.WriteString(n.Data + " ")
+			}
+			return
+		case n.Type == html.ElementNode && n.Data == "script":
+			scripts = true
+			return
+		case n.Type == html.ElementNode && (n.Data == "style" || n.Data == "template" || n.Data == "head"):
+			return
+		case n.Type == html.ElementNode && n.Data == "noscript":
+			inNoscript = true
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c, inNoscript)
+		}
+	}
+	if doc == nil {
+		return ""
+	}
+	walk(doc, false)
+
+	words := len(strings.FieldsFunc(text.String(), unicode.IsSpace))
+	if words > interstitialMaxWords {
+		return ""
+	}
+	if words == 0 && scripts {
+		return ReasonJavaScriptRequired
+	}
+	visible := strings.ToLower(strings.Join(strings.Fields(text.String()), " "))
+	notice := strings.ToLower(strings.Join(strings.Fields(noscript.String()), " "))
+	for _, phrase := range javaScriptPhrases {
+		if strings.Contains(visible, phrase) || words <= shellMaxWords && strings.Contains(notice, phrase) {
+			return ReasonJavaScriptRequired
+		}
+	}
+	for _, phrase := range consentPhrases {
+		if strings.Contains(visible, phrase) {
+			return ReasonConsentWall
+		}
+	}
+	return ""
+}
@@ -0,0 +1,203 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestParseCookie(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "CookieConsent=true", want: "CookieConsent=true"},
+		{in: " cc_status = dismiss ", want: "cc_status=dismiss"},
+		{in: "consent=", want: "consent="},
+		{in: "consent", wantErr: true},
+		{in: "=true", wantErr: true},
+		{in: "bad name=true", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			c, err := ParseCookie(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseCookie(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			}
+			if err == nil && c.String() != tt.want {
+				t.Errorf("ParseCookie(%q) = %q, want %q", tt.in, c.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestMetaRefreshTarget(t *testing.T) {
+	tests := []struct {
+		name string
+		meta string
+		want string
+	}{
+		{name: "relative", meta: `<meta http-equiv="refresh" content="0; url=/new">`, want: "https://docs.example.com/new"},
+		{name: "quoted", meta: `<meta http-equiv="Refresh" content="2;URL='guide.html'">`, want: "https://docs.example.com/docs/guide.html"},
+		{name: "absolute", meta: `<meta http-equiv="refresh" content="0,url=https://www.example.com/">`, want: "https://www.example.com/"},
+		{name: "reload", meta: `<meta http-equiv="refresh" content="30">`},
+		{name: "slow", meta: `<meta http-equiv="refresh" content="60; url=/new">`},
+		{name: "script", meta: `<meta http-equiv="refresh" content="0; url=javascript:go()">`},
+		{name: "none", meta: `<meta charset="utf-8">`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader("<html><head>" + tt.meta + "</head><body></body></html>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := MetaRefreshTarget(doc, "https://docs.example.com/docs/old"); got != tt.want {
+				t.Errorf("MetaRefreshTarget() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectInterstitial(t *testing.T) {
+	content := strings.Repeat("The configuration file lists the options of the crawler. ", 30)
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{name: "content", body: `<h1>Guide</h1><p>` + content + `</p>`},
+		{name: "short content", body: `<h1>Changelog</h1><p>Version 1.2 fixes the crawler.</p>`},
+		{name: "application shell", body: `<div id="root"></div><script src="/app.js"></script>`, want: ReasonJavaScriptRequired},
+		{
+			name: "JavaScript notice",
+			body: `<noscript>You need to enable JavaScript to run this app.</noscript><div id="root"></div>`,
+			want: ReasonJavaScriptRequired,
+		},
+		{
+			name: "visible JavaScript notice",
+			body: `<p>Please enable JavaScript in your browser to view the documentation.</p>`,
+			want: ReasonJavaScriptRequired,
+		},
+		{
+			name: "short page with a JavaScript notice",
+			body: `<noscript>Please enable JavaScript.</noscript><h1>Install</h1><p>Download the archive for your platform, extract it somewhere on your PATH, and run the binary once to create its configuration file.</p>`,
+		},
+		{
+			name: "consent wall",
+			body: `<h2>Before you continue</h2><p>We use cookies and data to deliver our services.</p><button>Accept all</button>`,
+			want: ReasonConsentWall,
+		},
+		{
+			name: "cookie banner on a page",
+			body: `<div class="cookie-banner">We use cookies. <button>Accept cookies</button></div><p>` + content + `</p>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader("<html><head><title>Docs</title></head><body>" + tt.body + "</body></html>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := DetectInterstitial(doc); got != tt.want {
+				t.Errorf("DetectInterstitial() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchInterstitials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<html><body><h1>Docs</h1>
+<a href="/old">Old</a><a href="/gated">Gated</a><a href="/app">App</a></body></html>`))
+		case "/old":
+			w.Write([]byte(`<html><head><meta http-equiv="refresh" content="0; url=/new"></head><body>Redirecting…</body></html>`))
+		case "/new":
+			w.Write([]byte(`<html><body><h1>New</h1><p>The new page.</p></body></html>`))
+		case "/gated":
+			if c, err := r.Cookie("CookieConsent"); err != nil || c.Value != "true" {
+				w.Write([]byte(`<html><body><p>We use cookies to improve the site.</p><button>Accept cookies</button></body></html>`))
+				return
+			}
+			w.Write([]byte(`<html><body><h1>Gated</h1><p>The gated page.</p></body></html>`))
+		case "/app":
+			w.Write([]byte(`<html><body><div id="root"></div><script src="/app.js"></script></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		cookies []string
+		noMeta  bool
+		keep    bool
+		want    map[string]string
+	}{
+		{
+			name: "default",
+			want: map[string]string{
+				"/old":   "skipped:" + ReasonMetaRefresh,
+				"/new":   "saved",
+				"/gated": "skipped:" + ReasonConsentWall,
+				"/app":   "skipped:" + ReasonJavaScriptRequired,
+			},
+		},
+		{
+			name:    "consent cookie",
+			cookies: []string{"CookieConsent=true"},
+			want:    map[string]string{"/gated": "saved"},
+		},
+		{
+			name:   "kept",
+			noMeta: true,
+			keep:   true,
+			want: map[string]string{
+				"/old":   "saved",
+				"/new":   "",
+				"/gated": "saved:" + ReasonConsentWall,
+				"/app":   "saved:" + ReasonJavaScriptRequired,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := New(t.TempDir())
+			f.delay = 0
+			if err := f.SetConsentCookies(tt.cookies); err != nil {
+				t.Fatal(err)
+			}
+			f.SetFollowMetaRefresh(!tt.noMeta)
+			f.SetKeepInterstitials(tt.keep)
+			if err := f.Fetch(server.URL + "/"); err != nil {
+				t.Fatalf("Fetch() returned error: %v", err)
+			}
+
+			outcomes := make(map[string]string)
+			for _, rec := range f.Report().Pages {
+				outcome := string(rec.Outcome)
+				if rec.Reason != "" {
+					outcome += ":" + rec.Reason
+				} else if rec.Interstitial != "" {
+					outcome += ":" + rec.Interstitial
+				}
+				outcomes[strings.TrimPrefix(rec.URL, server.URL)] = outcome
+				if rec.Reason == ReasonMetaRefresh && rec.RefreshURL != server.URL+"/new" {
+					t.Errorf("RefreshURL = %q, want %q", rec.RefreshURL, server.URL+"/new")
+				}
+			}
+			for u, want := range tt.want {
+				if outcomes[u] != want {
+					t.Errorf("%s = %q, want %q (all: %v)", u, outcomes[u], want, outcomes)
+				}
+			}
+		})
+	}
+}
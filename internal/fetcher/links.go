@@ -0,0 +1,154 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements link discovery: the URLs of a page crawled next are
+// those of its anchors, and of the navigation that isn't written as anchors,
+// such as frames, image maps, meta refreshes, and the data attributes of the
+// client-side routers of documentation frameworks.
+package fetcher
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/idn"
+	"golang.org/x/net/html"
+)
+
+// DefaultLinkAttributes are the attributes of any element whose values are
+// taken for links by default (see SetLinkAttributes).
+var DefaultLinkAttributes = []string{"data-href", "data-url", "data-link"}
+
+// SetLinkAttributes sets the attributes of any element whose values are
+// crawled as links, such as the data-href of the elements navigating with a
+// client-side router, besides the href of <a> and <area> elements, the src of
+// <iframe> and <frame> elements, and the URL of a <meta http-equiv="refresh">
+// (see linkValues). A nil or empty attrs restores DefaultLinkAttributes.
+// Values that don't look like URLs, containing spaces or template braces, are
+// ignored.
+//
+// Returns an error if an attribute isn't an attribute name (see
+// ParseLinkAttribute).
+func (f *Fetcher) SetLinkAttributes(attrs []string) error {
+	names := make([]string, 0, len(attrs))
+	for _, a := range attrs {
+		name, err := ParseLinkAttribute(a)
+		if err != nil {
+			return err
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		names = DefaultLinkAttributes
+	}
+	f.linkAttributes = names
+	return nil
+}
+
+// ParseLinkAttribute returns a, the name of an attribute holding links such
+// as "data-href", in lower case, as the HTML parser reports it.
+//
+// Returns an error if a isn't an attribute name.
+func ParseLinkAttribute(a string) (string, error) {
+	name := strings.ToLower(strings.TrimSpace(a))
+	if name == "" || strings.ContainsAny(name, " \t\n\"'<>/=`") {
+		return "", fmt.Errorf("invalid link attribute %q (expected an attribute name such as data-href)", a)
+	}
+	return name, nil
+}
+
+// extractLinks returns the links of the HTML node tree n, a page fetched from
+// baseURL, in document order (see linkValues), resolved against baseURL in
+// their request form (see idn.ToASCII) and normalized (see SetURLNormalizer).
+func (f *Fetcher) extractLinks(n *html.Node, baseURL string) []string {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil
+	}
+	var links []string
+
+	var extract func(*html.Node)
+	extract = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			for _, v := range f.linkValues(n) {
+				ref, err := url.Parse(v.value)
+				if err != nil {
+					continue
+				}
+				resolved := base.ResolveReference(ref)
+				// Anchors are crawled whatever their scheme, as they always were; the
+				// other sources often hold script or data URLs
+				if !v.anchor && resolved.Scheme != "http" && resolved.Scheme != "https" {
+					continue
+				}
+				links = append(links, f.normalizeURL(idn.ToASCII(resolved.String())))
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			extract(c)
+		}
+	}
+
+	extract(n)
+	return links
+}
+
+// linkValue is an unresolved link of an element.
+type linkValue struct {
+	value string
+	// anchor is set for the href of an <a> element
+	anchor bool
+}
+
+// linkValues returns the unresolved links of the element n: the href of an
+// <a> or <area>, the src of an <iframe> or <frame>, the URL of a meta
+// refresh too late to be followed as a redirect, unless meta refreshes aren't
+// followed at all (see SetFollowMetaRefresh), and the values of the link
+// attributes.
+func (f *Fetcher) linkValues(n *html.Node) []linkValue {
+	var values []linkValue
+	switch n.Data {
+	case "a", "area":
+		if href, ok := attrValue(n, "href"); ok {
+			values = append(values, linkValue{value: href, anchor: n.Data == "a"})
+		}
+	case "iframe", "frame":
+		if src, ok := attrValue(n, "src"); ok && strings.TrimSpace(src) != "" {
+			values = append(values, linkValue{value: src})
+		}
+	case "meta":
+		if f.noMetaRefresh {
+			break
+		}
+		if httpEquiv, _ := attrValue(n, "http-equiv"); strings.EqualFold(strings.TrimSpace(httpEquiv), "refresh") {
+			content, _ := attrValue(n, "content")
+			// Earlier refreshes are followed as redirects (see handleInterstitial)
+			if seconds, target, ok := parseRefresh(content); ok && seconds > metaRefreshMaxDelay {
+				values = append(values, linkValue{value: target})
+			}
+		}
+	}
+	for _, name := range f.linkAttributes {
+		if v, ok := attrValue(n, name); ok && looksLikeURL(v) {
+			values = append(values, linkValue{value: strings.TrimSpace(v)})
+		}
+	}
+	return values
+}
+
+// attrValue returns the value of the attribute key of n, and whether n has it.
+func attrValue(n *html.Node, key string) (string, bool) {
+	for _, attr := range n.Attr {
+		if attr.Key == key {
+			return attr.Val, true
+		}
+	}
+	return "", false
+}
+
+// looksLikeURL reports whether v, the value of a link attribute, may be a
+// URL: not empty, not a fragment of the page, and without the spaces and
+// template braces of the values that hold something else.
+func looksLikeURL(v string) bool {
+	v = strings.TrimSpace(v)
+	return v != "" && !strings.HasPrefix(v, "#") && !strings.ContainsAny(v, " \t\n{}<>")
+}
@@ -0,0 +1,110 @@
+package fetcher
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestExtractLinksNavigation(t *testing.T) {
+	page := `<html><head><meta http-equiv="refresh" content="30; url=/moved"></head><body>
+		<iframe src="/embed/example.html"></iframe>
+		<iframe src="javascript:void(0)"></iframe>
+		<img src="/map.png" usemap="#nav"><map name="nav"><area href="/area" shape="rect" coords="0,0,10,10"></map>
+		<div data-href="/router/page" role="link">Routed</div>
+		<button data-url="https://docs.example.com/guide">Guide</button>
+		<li data-link="{{ item.url }}">Template</li>
+		<span data-href="#local">Fragment</span>
+		<div data-route="/custom">Custom</div>
+		<a href="/anchor">Anchor</a>
+	</body></html>`
+	frameset := `<html><frameset cols="20%,80%"><frame src="toc.html"><frame src="content/intro.html"></frameset></html>`
+
+	tests := []struct {
+		name    string
+		page    string
+		attrs   []string
+		baseURL string
+		want    []string
+	}{
+		{
+			name:    "default attributes",
+			page:    page,
+			baseURL: "https://docs.example.com/start",
+			want: []string{
+				"https://docs.example.com/moved",
+				"https://docs.example.com/embed/example.html",
+				"https://docs.example.com/area",
+				"https://docs.example.com/router/page",
+				"https://docs.example.com/guide",
+				"https://docs.example.com/anchor",
+			},
+		},
+		{
+			name:    "configured attributes",
+			page:    page,
+			attrs:   []string{"Data-Route"},
+			baseURL: "https://docs.example.com/start",
+			want: []string{
+				"https://docs.example.com/moved",
+				"https://docs.example.com/embed/example.html",
+				"https://docs.example.com/area",
+				"https://docs.example.com/custom",
+				"https://docs.example.com/anchor",
+			},
+		},
+		{
+			name:    "meta refresh redirect",
+			page:    `<html><head><meta http-equiv="refresh" content="0; url=/moved"></head><body><a href="/anchor">Anchor</a></body></html>`,
+			baseURL: "https://docs.example.com/start",
+			want:    []string{"https://docs.example.com/anchor"},
+		},
+		{
+			name:    "frames",
+			page:    frameset,
+			baseURL: "https://docs.example.com/manual/",
+			want: []string{
+				"https://docs.example.com/manual/toc.html",
+				"https://docs.example.com/manual/content/intro.html",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader(tt.page))
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := New(t.TempDir())
+			if err := f.SetLinkAttributes(tt.attrs); err != nil {
+				t.Fatal(err)
+			}
+			got := f.extractLinks(doc, tt.baseURL)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("extractLinks() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLinkAttribute(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "data-href", want: "data-href"},
+		{in: " Data-Nav-URL ", want: "data-nav-url"},
+		{in: "v-bind:href", want: "v-bind:href"},
+		{in: "", wantErr: true},
+		{in: "data href", wantErr: true},
+		{in: "data-href=x", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLinkAttribute(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseLinkAttribute(%q) = %q, %v; want %q (error %t)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -2,11 +2,16 @@
 package fetcher
 
 import (
+	"fmt"
+	"net/http"
 	"net/url"
+	"os"
 	"regexp"
+	"sort"
 	"strings"
 
 	"golang.org/x/net/html"
+	"gopkg.in/yaml.v3"
 )
 
 // LocaleConfig specifies locale priority and parameter configuration for content negotiation.
@@ -18,6 +23,68 @@ type LocaleConfig struct {
 	// ParamName is the query parameter name used for locale selection (e.g., "hl" for ?hl=ja).
 	// If empty, path-based locale detection is used instead (e.g., /ja/docs).
 	ParamName string
+	// Locales lists additional locale codes to recognize in paths, on top of
+	// KnownLocales (e.g., "pt-pt", "sr-latn", "en-au").
+	Locales []string
+	// Aliases maps site-specific locale codes to their canonical form
+	// (e.g., "en-au" -> "en"). Alias keys are recognized in paths as well.
+	Aliases map[string]string
+}
+
+// IsKnownLocale reports whether code is recognized as a locale: a built-in
+// KnownLocales entry or one added through Locales or Aliases.
+// Matching is case-insensitive. A nil config only knows the built-in codes.
+func (c *LocaleConfig) IsKnownLocale(code string) bool {
+	code = strings.ToLower(code)
+	if KnownLocales[code] {
+		return true
+	}
+	if c == nil {
+		return false
+	}
+	for _, l := range c.Locales {
+		if strings.ToLower(l) == code {
+			return true
+		}
+	}
+	for alias := range c.Aliases {
+		if strings.ToLower(alias) == code {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeLocale normalizes a locale code using the configured aliases first,
+// then the built-in aliases of the package-level NormalizeLocale.
+func (c *LocaleConfig) NormalizeLocale(code string) string {
+	code = strings.ToLower(code)
+	if c != nil {
+		for alias, canonical := range c.Aliases {
+			if strings.ToLower(alias) == code {
+				return strings.ToLower(canonical)
+			}
+		}
+	}
+	return NormalizeLocale(code)
+}
+
+// variants returns the codes a site may use for locale in URLs: locale itself
+// followed by every configured alias that normalizes to it, sorted.
+func (c *LocaleConfig) variants(locale string) []string {
+	result := []string{locale}
+	if c == nil {
+		return result
+	}
+	var aliases []string
+	for alias, canonical := range c.Aliases {
+		alias = strings.ToLower(alias)
+		if alias != locale && strings.ToLower(canonical) == strings.ToLower(locale) {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	return append(result, aliases...)
 }
 
 // DefaultLocalePriority is the default locale preference order used when none is specified.
@@ -25,6 +92,7 @@ var DefaultLocalePriority = []string{"en", "ja"}
 
 // KnownLocales is a map of recognized locale codes for path-based locale detection.
 // It supports both language codes (e.g., "en", "ja") and region-specific codes (e.g., "en-us", "zh-tw").
+// Additional codes can be supplied per crawl through LocaleConfig.Locales and LocaleConfig.Aliases.
 var KnownLocales = map[string]bool{
 	"en": true, "en-us": true, "en-gb": true,
 	"ja": true, "ja-jp": true,
@@ -41,8 +109,8 @@ var KnownLocales = map[string]bool{
 }
 
 // localePathPattern matches path-based locale patterns in URLs.
-// It looks for locale codes enclosed in slashes (e.g., /ja/ or /en-us/) anywhere in the path.
-var localePathPattern = regexp.MustCompile(`/([a-z]{2}(?:-[a-zA-Z]{2,4})?)/`)
+// It looks for locale codes enclosed in slashes (e.g., /ja/, /en-us/, /sr-latn/, /es-419/) anywhere in the path.
+var localePathPattern = regexp.MustCompile(`/([a-z]{2,3}(?:-[a-zA-Z0-9]{2,8})?)/`)
 
 // ExtractLocale extracts the locale and canonical path from a URL based on the provided configuration.
 //
@@ -77,7 +145,7 @@ func ExtractLocale(u *url.URL, cfg *LocaleConfig) (locale, canonical string) {
 		localePart := path[match[2]:match[3]]
 		potentialLocale := strings.ToLower(localePart) // インデックス部分のスライス
 
-		if KnownLocales[potentialLocale] {
+		if cfg.IsKnownLocale(potentialLocale) {
 			locale = potentialLocale
 
 			// canonical path の再構築
@@ -139,68 +207,260 @@ func BuildLocaleURL(baseURL, locale, canonical string, cfg *LocaleConfig) string
 // Locales are normalized to lowercase. Returns an empty map if no hreflang links are found.
 func ExtractHreflang(doc *html.Node) map[string]string {
 	result := make(map[string]string)
-	if doc == nil {
-		return result
+	for _, link := range extractHreflangLinks(doc) {
+		result[link.locale] = link.href
+	}
+	return result
+}
+
+// LocaleMatch is the quality of the match between a locale and the locale
+// priority, from best to worst: how SelectLocale chose a variant, and how the
+// locale of a page matched in the crawl report (PageRecord.LocaleMatch).
+type LocaleMatch string
+
+const (
+	// LocaleMatchExact is a locale equal to a language range of the priority,
+	// once normalized (see LocaleConfig.NormalizeLocale): en-US for "en".
+	LocaleMatchExact LocaleMatch = "exact"
+	// LocaleMatchRange is a locale within a language range of the priority,
+	// as RFC 4647 extended filtering matches it: en-AU for "en", zh-Hant-TW for "zh-*-tw".
+	LocaleMatchRange LocaleMatch = "range"
+	// LocaleMatchLookup is a locale within a language range of the priority
+	// once its subtags are removed from the end, as in RFC 4647 lookup: en
+	// and en-AU for "en-ca", when no en-CA variant is available.
+	LocaleMatchLookup LocaleMatch = "lookup"
+	// LocaleMatchDefault is the x-default variant of an hreflang map, which
+	// sites declare for users whose language they don't serve.
+	LocaleMatchDefault LocaleMatch = "x-default"
+	// LocaleMatchFallback is a locale outside the priority.
+	LocaleMatchFallback LocaleMatch = "fallback"
+)
+
+// xDefault is the hreflang value of the variant for unmatched languages.
+const xDefault = "x-default"
+
+// LocaleChoice is the variant SelectLocale chose from an hreflang map.
+type LocaleChoice struct {
+	// Locale is the locale of the variant, as in the map; empty if the map is empty.
+	Locale string
+	// URL is the URL of the variant.
+	URL string
+	// Match is the quality of the match.
+	Match LocaleMatch
+	// Range is the language range of the priority the locale matched; empty
+	// for the x-default variant and fallbacks.
+	Range string
+}
+
+// String explains the choice for reports: "en-au (range match for en)",
+// "x-default (no match for ja, de)", "fr (fallback)".
+func (c LocaleChoice) String() string {
+	switch {
+	case c.Locale == "":
+		return ""
+	case c.Range != "":
+		return fmt.Sprintf("%s (%s match for %s)", c.Locale, c.Match, c.Range)
+	default:
+		return fmt.Sprintf("%s (%s)", c.Locale, c.Match)
+	}
+}
+
+// SelectPreferredLocaleURL selects the URL of the preferred locale from a hreflang map.
+//
+// It uses the provided priority list to select the best match from available
+// locales (see SelectLocale). Returns both the matched locale code and its
+// URL. Returns empty strings if the map is empty.
+func SelectPreferredLocaleURL(hreflangMap map[string]string, priority []string) (locale, url string) {
+	c := SelectLocale(hreflangMap, priority, nil)
+	return c.Locale, c.URL
+}
+
+// SelectLocale selects the preferred variant of an hreflang map, keyed by
+// locale, for the language ranges of priority, best first, matched as RFC
+// 4647 does with the locales normalized by cfg (which may be nil).
+//
+// Each range is tried in turn: a locale equal to it, then the locales within
+// it ("en" matches every en-* variant; "*" matches any subtags), then, with
+// its subtags removed from the end, the locales within what is left ("en-ca"
+// matches en and en-AU). Among several locales of the same quality, the
+// shortest, then the first in alphabetical order, is chosen. Without a match,
+// the x-default variant is chosen, or else the first locale in alphabetical
+// order.
+func SelectLocale(hreflangMap map[string]string, priority []string, cfg *LocaleConfig) LocaleChoice {
+	if len(hreflangMap) == 0 {
+		return LocaleChoice{}
+	}
+	locales := make([]string, 0, len(hreflangMap))
+	for loc := range hreflangMap {
+		locales = append(locales, loc)
+	}
+	sort.Slice(locales, func(i, j int) bool {
+		if len(locales[i]) != len(locales[j]) {
+			return len(locales[i]) < len(locales[j])
+		}
+		return locales[i] < locales[j]
+	})
+
+	best := LocaleChoice{}
+	bestRank := -1
+	for _, loc := range locales {
+		rank, match, rng := rankLocale(loc, priority, cfg)
+		if bestRank < 0 || rank < bestRank {
+			best = LocaleChoice{Locale: loc, URL: hreflangMap[loc], Match: match, Range: rng}
+			bestRank = rank
+		}
+	}
+	if best.Match == LocaleMatchFallback {
+		// The first in alphabetical order, not the shortest
+		sort.Strings(locales)
+		for _, loc := range locales {
+			if loc != xDefault {
+				best = LocaleChoice{Locale: loc, URL: hreflangMap[loc], Match: LocaleMatchFallback}
+				break
+			}
+		}
+	}
+	return best
+}
+
+// DetectContentLocale returns the locale a response declares for itself: the first
+// language of the Content-Language header, or else the lang (or xml:lang) attribute
+// of the <html> element. Used when neither the path nor a query parameter carries a
+// locale. Returns the lowercased code, or "" if the page declares none.
+func DetectContentLocale(header http.Header, doc *html.Node) string {
+	if cl := header.Get("Content-Language"); cl != "" {
+		first, _, _ := strings.Cut(cl, ",")
+		if first = strings.ToLower(strings.TrimSpace(first)); first != "" && first != "*" {
+			return first
+		}
 	}
 
-	var extract func(*html.Node)
-	extract = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "link" {
-			var rel, hreflang, href string
+	var lang, xmlLang string
+	var find func(*html.Node) bool
+	find = func(n *html.Node) bool {
+		if n.Type == html.ElementNode && n.Data == "html" {
 			for _, attr := range n.Attr {
 				switch attr.Key {
-				case "rel":
-					rel = attr.Val
-				case "hreflang":
-					hreflang = attr.Val
-				case "href":
-					href = attr.Val
+				case "lang":
+					lang = attr.Val
+				case "xml:lang":
+					xmlLang = attr.Val
 				}
 			}
-			if rel == "alternate" && hreflang != "" && href != "" {
-				// ロケールを正規化（小文字、ja-JP -> ja-jp）
-				normalizedLocale := strings.ToLower(hreflang)
-				result[normalizedLocale] = href
-			}
+			return true
 		}
 		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			extract(c)
+			if find(c) {
+				return true
+			}
 		}
+		return false
 	}
+	if doc != nil {
+		find(doc)
+	}
+	if lang == "" {
+		lang = xmlLang
+	}
+	return strings.ToLower(strings.TrimSpace(lang))
+}
 
-	extract(doc)
-	return result
+// localeRank returns the rank of locale in priority, lower ranks being
+// preferred: by the position of the language range it matches, then by the
+// quality of the match (see rankLocale). The x-default variant ranks after
+// the locales matching priority, and other locales last.
+func localeRank(locale string, priority []string, cfg *LocaleConfig) int {
+	rank, _, _ := rankLocale(locale, priority, cfg)
+	return rank
 }
 
-// SelectPreferredLocaleURL selects the URL of the preferred locale from a hreflang map.
-//
-// It uses the provided priority list to select the best match from available locales.
-// If an exact match isn't found, it attempts expanded forms (e.g., "ja" -> "ja-jp").
-// If no matches in the priority list, returns the first available locale.
-//
-// Returns both the matched locale code and its URL. Returns empty strings if the map is empty.
-func SelectPreferredLocaleURL(hreflangMap map[string]string, priority []string) (locale, url string) {
-	if len(hreflangMap) == 0 {
-		return "", ""
+// rankLocale returns the rank of locale in priority (see localeRank), the
+// quality of its match, and the language range it matched.
+func rankLocale(locale string, priority []string, cfg *LocaleConfig) (int, LocaleMatch, string) {
+	const qualities = 3 // exact, range, and lookup matches of each range
+	if strings.EqualFold(locale, xDefault) {
+		return len(priority) * qualities, LocaleMatchDefault, ""
+	}
+	for i, rng := range priority {
+		switch matchLocaleRange(locale, rng, cfg) {
+		case LocaleMatchExact:
+			return i * qualities, LocaleMatchExact, rng
+		case LocaleMatchRange:
+			return i*qualities + 1, LocaleMatchRange, rng
+		case LocaleMatchLookup:
+			return i*qualities + 2, LocaleMatchLookup, rng
+		}
 	}
+	return len(priority)*qualities + 1, LocaleMatchFallback, ""
+}
 
-	// Select according to priority order
-	for _, loc := range priority {
-		if u, ok := hreflangMap[loc]; ok {
-			return loc, u
+// DescribeLocaleMatch explains how locale matched priority for the crawl
+// report, as LocaleChoice.String does; "" if locale is empty.
+func DescribeLocaleMatch(locale string, priority []string, cfg *LocaleConfig) string {
+	if locale == "" {
+		return ""
+	}
+	_, match, rng := rankLocale(locale, priority, cfg)
+	return LocaleChoice{Locale: locale, Match: match, Range: rng}.String()
+}
+
+// matchLocaleRange returns the quality of the match between locale and the
+// language range rng, both normalized with cfg, or "" if they don't match:
+// LocaleMatchExact, LocaleMatchRange (RFC 4647 extended filtering), or
+// LocaleMatchLookup (filtering with the subtags of rng removed from the end,
+// along with singletons left last, as RFC 4647 lookup does).
+func matchLocaleRange(locale, rng string, cfg *LocaleConfig) LocaleMatch {
+	tag := cfg.NormalizeLocale(strings.ReplaceAll(locale, "_", "-"))
+	rng = cfg.NormalizeLocale(strings.ReplaceAll(rng, "_", "-"))
+	if tag == "" || rng == "" {
+		return ""
+	}
+	if tag == rng {
+		return LocaleMatchExact
+	}
+	if filterLocale(tag, rng) {
+		return LocaleMatchRange
+	}
+	subtags := strings.Split(rng, "-")
+	for len(subtags) > 1 {
+		subtags = subtags[:len(subtags)-1]
+		if len(subtags) > 1 && len(subtags[len(subtags)-1]) == 1 {
+			subtags = subtags[:len(subtags)-1]
 		}
-		// Try expanded forms like ja -> ja-jp
-		if u, ok := hreflangMap[loc+"-"+loc]; ok {
-			return loc + "-" + loc, u
+		if subtags[0] != "*" && filterLocale(tag, strings.Join(subtags, "-")) {
+			return LocaleMatchLookup
 		}
 	}
+	return ""
+}
 
-	// If no priority match, return the first available
-	for loc, u := range hreflangMap {
-		return loc, u
+// filterLocale reports whether the lowercase language tag tag is within the
+// lowercase extended language range rng, per RFC 4647 section 3.3.2: the
+// subtags of rng appear in tag in order, "*" matching any subtags, and tag
+// may have more subtags, except singletons, between and after them.
+func filterLocale(tag, rng string) bool {
+	t := strings.Split(tag, "-")
+	r := strings.Split(rng, "-")
+	if r[0] != "*" && r[0] != t[0] {
+		return false
 	}
-
-	return "", ""
+	ti, ri := 1, 1
+	for ri < len(r) {
+		switch {
+		case r[ri] == "*":
+			ri++
+		case ti >= len(t):
+			return false
+		case r[ri] == t[ti]:
+			ri++
+			ti++
+		case len(t[ti]) == 1:
+			return false
+		default:
+			ti++
+		}
+	}
+	return true
 }
 
 // NormalizeLocale normalizes locale codes to a canonical form.
@@ -227,3 +487,43 @@ func NormalizeLocale(locale string) string {
 	}
 	return locale
 }
+
+// LocaleDefinitions holds locale codes and aliases loaded from a file,
+// for sites whose locale codes aren't in KnownLocales.
+//
+// Example (YAML or JSON):
+//
+//	locales: [pt-pt, sr-latn]
+//	aliases:
+//	  en-au: en
+//	  pt-pt: pt
+type LocaleDefinitions struct {
+	// Locales lists additional locale codes to recognize.
+	Locales []string `yaml:"locales"`
+	// Aliases maps site-specific codes to their canonical form.
+	Aliases map[string]string `yaml:"aliases"`
+}
+
+// LoadLocaleDefinitions reads locale definitions from a YAML or JSON file.
+func LoadLocaleDefinitions(path string) (*LocaleDefinitions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read locale file: %w", err)
+	}
+	var defs LocaleDefinitions
+	if err := yaml.Unmarshal(data, &defs); err != nil {
+		return nil, fmt.Errorf("failed to parse locale file %s: %w", path, err)
+	}
+	return &defs, nil
+}
+
+// Apply adds the definitions to cfg.
+func (d *LocaleDefinitions) Apply(cfg *LocaleConfig) {
+	cfg.Locales = append(cfg.Locales, d.Locales...)
+	if len(d.Aliases) > 0 && cfg.Aliases == nil {
+		cfg.Aliases = make(map[string]string, len(d.Aliases))
+	}
+	for alias, canonical := range d.Aliases {
+		cfg.Aliases[alias] = canonical
+	}
+}
@@ -1,7 +1,11 @@
 package fetcher
 
 import (
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -264,6 +268,72 @@ func TestSelectPreferredLocaleURL(t *testing.T) {
 	}
 }
 
+func TestSelectLocale(t *testing.T) {
+	hreflangMap := map[string]string{
+		"x-default":  "https://example.com/docs",
+		"en-AU":      "https://example.com/en-au/docs",
+		"en-GB":      "https://example.com/en-gb/docs",
+		"fr":         "https://example.com/fr/docs",
+		"zh-Hant-TW": "https://example.com/zh-hant-tw/docs",
+	}
+
+	tests := []struct {
+		name     string
+		priority []string
+		want     string
+	}{
+		{"exact after normalization", []string{"en"}, "en-GB (exact match for en)"},
+		{"range", []string{"zh"}, "zh-Hant-TW (range match for zh)"},
+		{"extended range", []string{"zh-*-tw"}, "zh-Hant-TW (range match for zh-*-tw)"},
+		{"lookup", []string{"fr-ca"}, "fr (lookup match for fr-ca)"},
+		{"earlier range wins over quality", []string{"fr-ca", "en"}, "fr (lookup match for fr-ca)"},
+		{"x-default", []string{"ja", "de"}, "x-default (x-default)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SelectLocale(hreflangMap, tt.priority, nil)
+			if got.String() != tt.want || got.URL != hreflangMap[got.Locale] {
+				t.Errorf("SelectLocale() = %+v (%s), want %s", got, got, tt.want)
+			}
+		})
+	}
+
+	delete(hreflangMap, "x-default")
+	if got := SelectLocale(hreflangMap, []string{"ja"}, nil); got.String() != "en-AU (fallback)" {
+		t.Errorf("SelectLocale() without x-default = %s, want the first locale", got)
+	}
+	if got := SelectLocale(nil, []string{"ja"}, nil); got != (LocaleChoice{}) {
+		t.Errorf("SelectLocale(nil) = %+v", got)
+	}
+	cfg := &LocaleConfig{Aliases: map[string]string{"en-au": "en"}}
+	if got := SelectLocale(hreflangMap, []string{"en"}, cfg); got.Locale != "en-AU" {
+		t.Errorf("SelectLocale() with an alias = %s, want en-AU (the shortest exact match)", got)
+	}
+}
+
+func TestFilterLocale(t *testing.T) {
+	tests := []struct {
+		tag, rng string
+		want     bool
+	}{
+		{"en", "en", true},
+		{"en-au", "en", true},
+		{"de-latn-de", "de-de", true},
+		{"de-de-x-goethe", "de-de", true},
+		{"de-x-de", "de-de", false},
+		{"de-latn-de", "de-*-de", true},
+		{"fr", "*", true},
+		{"fr", "*-fr", false},
+		{"eng", "en", false},
+		{"en", "en-au", false},
+	}
+	for _, tt := range tests {
+		if got := filterLocale(tt.tag, tt.rng); got != tt.want {
+			t.Errorf("filterLocale(%q, %q) = %v, want %v", tt.tag, tt.rng, got, tt.want)
+		}
+	}
+}
+
 func TestNormalizeLocale(t *testing.T) {
 	tests := []struct {
 		input string
@@ -288,3 +358,237 @@ func TestNormalizeLocale(t *testing.T) {
 		})
 	}
 }
+
+func TestLocaleCandidates(t *testing.T) {
+	cfg := &LocaleConfig{Priority: []string{"ja", "en"}}
+	got := localeCandidates("https://example.com", "/docs/api", "https://example.com/fr/docs/api", cfg.Priority, cfg)
+
+	want := []localeCandidate{
+		{locale: "ja", url: "https://example.com/ja/docs/api"},
+		{locale: "en", url: "https://example.com/en/docs/api"},
+		{locale: "", url: "https://example.com/docs/api"},
+		{locale: "fr", url: "https://example.com/fr/docs/api"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("localeCandidates() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("candidate %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// The original URL is not repeated when it is already in the chain
+	got = localeCandidates("https://example.com", "/docs/api", "https://example.com/en/docs/api", cfg.Priority, cfg)
+	if len(got) != 3 {
+		t.Errorf("expected duplicate original URL to be dropped, got %v", got)
+	}
+}
+
+func TestFetchLocaleFallback(t *testing.T) {
+	// /ja/guide passes the HEAD probe but 404s on GET, /en/guide doesn't exist,
+	// so the fetcher must fall back to the canonical /guide.
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/ja/guide", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		http.NotFound(w, r)
+	})
+	mux.HandleFunc("/guide", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><p>Guide</p></body></html>`))
+	})
+	mux.HandleFunc("/", http.NotFound)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	f := New(t.TempDir())
+	f.delay = 0
+	f.SetLocaleConfig(&LocaleConfig{Priority: []string{"ja", "en"}})
+
+	if err := f.Fetch(server.URL + "/en/guide"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+
+	pages := f.Report().Pages
+	if len(pages) != 1 {
+		t.Fatalf("got %d report entries, want 1: %+v", len(pages), pages)
+	}
+	rec := pages[0]
+	if rec.Outcome != OutcomeSaved {
+		t.Fatalf("outcome = %q, want saved: %+v", rec.Outcome, rec)
+	}
+	if rec.FetchedURL != server.URL+"/guide" {
+		t.Errorf("FetchedURL = %q, want %q", rec.FetchedURL, server.URL+"/guide")
+	}
+	if rec.Locale != "" {
+		t.Errorf("Locale = %q, want empty for the canonical URL", rec.Locale)
+	}
+}
+
+func TestLocaleConfig_CustomLocales(t *testing.T) {
+	cfg := &LocaleConfig{
+		Locales: []string{"sr-latn"},
+		Aliases: map[string]string{"pt-PT": "pt", "en-au": "en"},
+	}
+
+	tests := []struct {
+		urlStr         string
+		wantLocale     string
+		wantCanonical  string
+		wantNormalized string
+	}{
+		{"https://example.com/sr-latn/docs", "sr-latn", "/docs", "sr-latn"},
+		{"https://example.com/pt-pt/docs", "pt-pt", "/docs", "pt"},
+		{"https://example.com/en-AU/docs", "en-au", "/docs", "en"},
+		{"https://example.com/ja-jp/docs", "ja-jp", "/docs", "ja"},
+		{"https://example.com/xx-yy/docs", "", "/xx-yy/docs", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.urlStr, func(t *testing.T) {
+			u, _ := url.Parse(tt.urlStr)
+			locale, canonical := ExtractLocale(u, cfg)
+			if locale != tt.wantLocale || canonical != tt.wantCanonical {
+				t.Errorf("ExtractLocale() = (%q, %q), want (%q, %q)", locale, canonical, tt.wantLocale, tt.wantCanonical)
+			}
+			if got := cfg.NormalizeLocale(locale); locale != "" && got != tt.wantNormalized {
+				t.Errorf("NormalizeLocale(%q) = %q, want %q", locale, got, tt.wantNormalized)
+			}
+		})
+	}
+
+	// Without the config, custom codes are not recognized
+	u, _ := url.Parse("https://example.com/sr-latn/docs")
+	if locale, _ := ExtractLocale(u, &LocaleConfig{}); locale != "" {
+		t.Errorf("ExtractLocale() without custom locales = %q, want empty", locale)
+	}
+}
+
+func TestLocaleCandidates_Aliases(t *testing.T) {
+	cfg := &LocaleConfig{Priority: []string{"pt"}, Aliases: map[string]string{"pt-pt": "pt"}}
+	got := localeCandidates("https://example.com", "/docs", "https://example.com/docs", cfg.Priority, cfg)
+
+	want := []localeCandidate{
+		{locale: "pt", url: "https://example.com/pt/docs"},
+		{locale: "pt", url: "https://example.com/pt-pt/docs"},
+		{locale: "", url: "https://example.com/docs"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("localeCandidates() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("candidate %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestLoadLocaleDefinitions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "locales.yaml")
+	content := "locales: [sr-latn, es-419]\naliases:\n  en-au: en\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write locale file: %v", err)
+	}
+
+	defs, err := LoadLocaleDefinitions(path)
+	if err != nil {
+		t.Fatalf("LoadLocaleDefinitions() returned error: %v", err)
+	}
+
+	cfg := &LocaleConfig{Priority: []string{"en"}}
+	defs.Apply(cfg)
+	for _, code := range []string{"sr-latn", "es-419", "en-au"} {
+		if !cfg.IsKnownLocale(code) {
+			t.Errorf("IsKnownLocale(%q) = false after Apply", code)
+		}
+	}
+	if got := cfg.NormalizeLocale("en-AU"); got != "en" {
+		t.Errorf("NormalizeLocale(en-AU) = %q, want en", got)
+	}
+
+	if _, err := LoadLocaleDefinitions(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("LoadLocaleDefinitions() on a missing file returned no error")
+	}
+}
+
+func TestDetectContentLocale(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		html   string
+		want   string
+	}{
+		{"content-language header", "ja-JP", `<html lang="en"><body></body></html>`, "ja-jp"},
+		{"first of several languages", "de, en", `<html><body></body></html>`, "de"},
+		{"html lang attribute", "", `<html lang="pt-PT"><body></body></html>`, "pt-pt"},
+		{"xml:lang attribute", "", `<html xml:lang="fr"><body></body></html>`, "fr"},
+		{"nothing declared", "", `<html><body></body></html>`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader(tt.html))
+			if err != nil {
+				t.Fatalf("failed to parse HTML: %v", err)
+			}
+			header := http.Header{}
+			if tt.header != "" {
+				header.Set("Content-Language", tt.header)
+			}
+			if got := DetectContentLocale(header, doc); got != tt.want {
+				t.Errorf("DetectContentLocale() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchGroupsOpaqueLocaleVariants(t *testing.T) {
+	// The URLs carry no locale; the pages declare their language and link
+	// each other as hreflang alternates.
+	alternates := `<link rel="alternate" hreflang="en" href="/page-1001">
+<link rel="alternate" hreflang="ja" href="/page-2002">`
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", http.NotFound)
+	mux.HandleFunc("/page-2002", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Language", "ja")
+		w.Write([]byte(`<html><head>` + alternates + `</head><body><a href="/page-1001">English</a></body></html>`))
+	})
+	mux.HandleFunc("/page-1001", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html lang="en"><head>` + alternates + `</head><body><a href="/page-2002">日本語</a></body></html>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	f := New(t.TempDir())
+	f.delay = 0
+	f.SetLocaleConfig(&LocaleConfig{Priority: []string{"en", "ja"}})
+
+	if err := f.Fetch(server.URL + "/page-2002"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+
+	byURL := make(map[string]PageRecord)
+	for _, p := range f.Report().Pages {
+		byURL[p.URL] = p
+	}
+
+	ja := byURL[server.URL+"/page-2002"]
+	if ja.Outcome != OutcomeSkipped || ja.Reason != "preferred_locale_variant" || ja.Locale != "ja" {
+		t.Errorf("ja variant = %+v, want skipped in favour of the en variant", ja)
+	}
+	en := byURL[server.URL+"/page-1001"]
+	if en.Outcome != OutcomeSaved || en.Locale != "en" {
+		t.Errorf("en variant = %+v, want saved with locale en", en)
+	}
+	if n := f.Report().Summary[OutcomeSaved]; n != 1 {
+		t.Errorf("saved %d pages, want 1", n)
+	}
+}
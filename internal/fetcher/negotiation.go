@@ -0,0 +1,102 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements language content negotiation: the Accept-Language
+// header sent in locale priority mode, and the hosts found to vary on it.
+package fetcher
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// AcceptLanguage returns the value of the Accept-Language header asking for
+// the locales of priority, best first: the first without a weight, the
+// others with weights decreasing by 0.1 down to 0.1 ("en, ja;q=0.9,
+// fr;q=0.8"). Returns "" if priority is empty.
+func AcceptLanguage(priority []string) string {
+	var ranges []string
+	for _, locale := range priority {
+		locale = strings.TrimSpace(locale)
+		if locale == "" {
+			continue
+		}
+		if len(ranges) == 0 {
+			ranges = append(ranges, locale)
+			continue
+		}
+		q := max(10-len(ranges), 1)
+		ranges = append(ranges, fmt.Sprintf("%s;q=0.%d", locale, q))
+	}
+	return strings.Join(ranges, ", ")
+}
+
+// acceptLanguage returns the Accept-Language header of the requests of the
+// fetcher: "" outside locale priority mode, or if the extra headers set one
+// (see SetHeaders).
+func (f *Fetcher) acceptLanguage() string {
+	if f.localeConfig == nil || f.headers.Get("Accept-Language") != "" {
+		return ""
+	}
+	priority := f.localeConfig.Priority
+	if len(priority) == 0 {
+		priority = DefaultLocalePriority
+	}
+	return AcceptLanguage(priority)
+}
+
+// variesOnLanguage reports whether a response with header h depends on the
+// Accept-Language header of the request, as its Vary header declares.
+func variesOnLanguage(h http.Header) bool {
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" || strings.EqualFold(name, "Accept-Language") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// noteNegotiation records whether host negotiates the language of its pages,
+// given the header of one of its responses, and returns it. A host is taken
+// to negotiate from its first response varying on Accept-Language on.
+func (f *Fetcher) noteNegotiation(host string, h http.Header) bool {
+	if !variesOnLanguage(h) {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.negotiating == nil {
+		f.negotiating = make(map[string]bool)
+	}
+	f.negotiating[host] = true
+	return true
+}
+
+// negotiates reports whether host was found to negotiate the language of
+// its pages (see noteNegotiation).
+func (f *Fetcher) negotiates(host string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.negotiating[host]
+}
+
+// negotiatedFirst moves the candidates without a locale to the front of
+// candidates, keeping their order otherwise: a host negotiating the language
+// serves them in the preferred locale it has, which saves probing the URL of
+// every locale in turn.
+func negotiatedFirst(candidates []localeCandidate) []localeCandidate {
+	ordered := make([]localeCandidate, 0, len(candidates))
+	for _, cand := range candidates {
+		if cand.locale == "" {
+			ordered = append(ordered, cand)
+		}
+	}
+	for _, cand := range candidates {
+		if cand.locale != "" {
+			ordered = append(ordered, cand)
+		}
+	}
+	return ordered
+}
@@ -0,0 +1,93 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		priority []string
+		want     string
+	}{
+		{nil, ""},
+		{[]string{"ja"}, "ja"},
+		{[]string{"en", " ja ", "", "zh-tw"}, "en, ja;q=0.9, zh-tw;q=0.8"},
+		{strings.Split("a,b,c,d,e,f,g,h,i,j,k,l", ","), "a, b;q=0.9, c;q=0.8, d;q=0.7, e;q=0.6, f;q=0.5, g;q=0.4, h;q=0.3, i;q=0.2, j;q=0.1, k;q=0.1, l;q=0.1"},
+	}
+	for _, tt := range tests {
+		if got := AcceptLanguage(tt.priority); got != tt.want {
+			t.Errorf("AcceptLanguage(%q) = %q, want %q", tt.priority, got, tt.want)
+		}
+	}
+}
+
+func TestVariesOnLanguage(t *testing.T) {
+	tests := []struct {
+		vary []string
+		want bool
+	}{
+		{nil, false},
+		{[]string{"Accept-Encoding"}, false},
+		{[]string{"Accept-Encoding, accept-language"}, true},
+		{[]string{"Cookie", "Accept-Language"}, true},
+		{[]string{"*"}, true},
+	}
+	for _, tt := range tests {
+		h := http.Header{"Vary": tt.vary}
+		if got := variesOnLanguage(h); got != tt.want {
+			t.Errorf("variesOnLanguage(%q) = %v, want %v", tt.vary, got, tt.want)
+		}
+	}
+}
+
+func TestFetchNegotiatedLocale(t *testing.T) {
+	var mu sync.Mutex
+	var requested []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		requested = append(requested, r.Method+" "+r.URL.Path)
+		mu.Unlock()
+		if got := r.Header.Get("Accept-Language"); got != "ja, en;q=0.9" {
+			t.Errorf("%s Accept-Language = %q", r.URL.Path, got)
+		}
+		if r.URL.Path != "/" && r.URL.Path != "/guide" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Vary", "Accept-Language")
+		w.Header().Set("Content-Language", "ja")
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="/guide">ガイド</a></body></html>`))
+	}))
+	defer server.Close()
+
+	f := New(t.TempDir())
+	f.delay = 0
+	f.SetLocaleConfig(&LocaleConfig{Priority: []string{"ja", "en"}})
+	if err := f.Fetch(server.URL + "/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+
+	for _, rec := range f.Report().Pages {
+		if rec.Outcome != OutcomeSaved || !rec.Negotiated || rec.Locale != "ja" {
+			t.Errorf("%s: Outcome, Negotiated, Locale = %q, %v, %q", rec.URL, rec.Outcome, rec.Negotiated, rec.Locale)
+		}
+	}
+	// The locale URLs of /guide aren't probed once the host is known to negotiate
+	for _, r := range requested {
+		if strings.HasSuffix(r, "/guide") && r != "HEAD /guide" && r != "GET /guide" {
+			t.Errorf("requested %s, want only /guide (all: %v)", r, requested)
+		}
+	}
+	if len(f.Report().Pages) != 2 {
+		t.Errorf("got %d pages, want 2: %+v", len(f.Report().Pages), f.Report().Pages)
+	}
+}
@@ -0,0 +1,221 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements URL normalization: the start URL, the links, and the
+// canonical URLs of the pages are rewritten in a canonical form before they
+// are checked against the visited set, so that trivially different URLs, such
+// as "/guide?utm_source=x#intro" and "/guide", are crawled once.
+package fetcher
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DefaultStripParams are the query parameters removed from URLs by default:
+// the tracking parameters of analytics and advertising tools.
+var DefaultStripParams = []string{
+	"utm_*", "gclid", "dclid", "gbraid", "wbraid", "fbclid", "msclkid", "yclid",
+	"mc_cid", "mc_eid", "_ga", "_gl", "_hsenc", "_hsmi", "mkt_tok",
+}
+
+// Trailing slash policies of a URLNormalizer.
+const (
+	// TrailingSlashKeep leaves the trailing slash of paths as it is, since
+	// "/guide" and "/guide/" resolve relative links differently.
+	TrailingSlashKeep = "keep"
+	// TrailingSlashStrip removes the trailing slash of paths: "/guide/"
+	// becomes "/guide".
+	TrailingSlashStrip = "strip"
+	// TrailingSlashAdd appends a slash to the paths whose last segment has no
+	// extension: "/guide" becomes "/guide/", while "/guide.html" is kept.
+	TrailingSlashAdd = "add"
+)
+
+// URLRule is a normalization rule of a URLNormalizer: it rewrites u, a URL
+// already normalized by the built-in rules, in place, e.g., to drop the
+// session parameter of a site or map a mirror host to the main one.
+type URLRule func(u *url.URL)
+
+// URLNormalizer rewrites URLs in a canonical form. The built-in rules
+//
+//   - lowercase the host and drop the default port of the scheme
+//   - drop the fragment
+//   - collapse the "." and ".." segments and the duplicate slashes of the path
+//   - apply the TrailingSlash policy
+//   - remove the query parameters matching StripParams
+//   - sort the query parameters by name
+//
+// are followed by Rules, in order.
+type URLNormalizer struct {
+	// StripParams are the names of the query parameters removed, as
+	// path.Match patterns matched case-insensitively, such as "utm_*".
+	StripParams []string
+	// TrailingSlash is the trailing slash policy: TrailingSlashKeep (the
+	// default when empty), TrailingSlashStrip, or TrailingSlashAdd.
+	TrailingSlash string
+	// Rules are the custom rules applied after the built-in ones.
+	Rules []URLRule
+}
+
+// DefaultURLNormalizer returns the normalizer used by the fetcher unless
+// SetURLNormalizer is called: it strips DefaultStripParams and keeps trailing
+// slashes.
+func DefaultURLNormalizer() *URLNormalizer {
+	return &URLNormalizer{StripParams: DefaultStripParams}
+}
+
+// ParseTrailingSlash returns the trailing slash policy s, in lower case; ""
+// is TrailingSlashKeep.
+//
+// Returns an error if s isn't a trailing slash policy.
+func ParseTrailingSlash(s string) (string, error) {
+	switch policy := strings.ToLower(strings.TrimSpace(s)); policy {
+	case "":
+		return TrailingSlashKeep, nil
+	case TrailingSlashKeep, TrailingSlashStrip, TrailingSlashAdd:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid trailing slash policy %q (expected keep, strip, or add)", s)
+}
+
+// ValidateStripParam checks pattern, a query parameter name or a path.Match
+// pattern such as "utm_*".
+//
+// Returns an error if pattern is empty or malformed.
+func ValidateStripParam(pattern string) error {
+	if strings.TrimSpace(pattern) == "" {
+		return fmt.Errorf("invalid query parameter pattern %q: empty", pattern)
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid query parameter pattern %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// Validate checks the strip-param patterns and the trailing slash policy of n.
+//
+// Returns the first error found.
+func (n *URLNormalizer) Validate() error {
+	for _, p := range n.StripParams {
+		if err := ValidateStripParam(p); err != nil {
+			return err
+		}
+	}
+	_, err := ParseTrailingSlash(n.TrailingSlash)
+	return err
+}
+
+// Normalize returns rawURL in its canonical form, or rawURL itself if it
+// isn't an http or https URL.
+func (n *URLNormalizer) Normalize(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return rawURL
+	}
+
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	u.Fragment, u.RawFragment = "", ""
+
+	// Paths with escaped slashes are left alone: cleaning them would unescape them
+	if u.RawPath == "" {
+		u.Path = cleanPath(u.Path)
+		policy, _ := ParseTrailingSlash(n.TrailingSlash)
+		switch {
+		case policy == TrailingSlashStrip && u.Path != "/":
+			u.Path = strings.TrimSuffix(u.Path, "/")
+		case policy == TrailingSlashAdd && !strings.HasSuffix(u.Path, "/") && path.Ext(u.Path) == "":
+			u.Path += "/"
+		}
+	}
+
+	u.RawQuery = n.normalizeQuery(u.RawQuery)
+	u.ForceQuery = false
+	for _, rule := range n.Rules {
+		rule(u)
+	}
+	return u.String()
+}
+
+// cleanPath returns p with its "." and ".." segments and duplicate slashes
+// collapsed, keeping its trailing slash; the empty path is "/".
+func cleanPath(p string) string {
+	if p == "" {
+		return "/"
+	}
+	cleaned := path.Clean("/" + p)
+	if strings.HasSuffix(p, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// normalizeQuery returns rawQuery without its empty parameters and those
+// matching n.StripParams, sorted by name. Values keep their encoding, and the
+// parameters of the same name their order.
+func (n *URLNormalizer) normalizeQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	var params []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" || n.strips(queryParamName(param)) {
+			continue
+		}
+		params = append(params, param)
+	}
+	sort.SliceStable(params, func(i, j int) bool {
+		return queryParamName(params[i]) < queryParamName(params[j])
+	})
+	return strings.Join(params, "&")
+}
+
+// queryParamName returns the unescaped name of param, a "name=value" pair of
+// a raw query.
+func queryParamName(param string) string {
+	name, _, _ := strings.Cut(param, "=")
+	if unescaped, err := url.QueryUnescape(name); err == nil {
+		return unescaped
+	}
+	return name
+}
+
+// strips reports whether the query parameter name is removed.
+func (n *URLNormalizer) strips(name string) bool {
+	name = strings.ToLower(name)
+	for _, p := range n.StripParams {
+		if ok, _ := path.Match(strings.ToLower(p), name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// SetURLNormalizer sets the normalizer of the URLs crawled, by default
+// DefaultURLNormalizer(): the start URL, the links of the pages, and their
+// canonical URLs are normalized before they are checked against the visited
+// set and requested. A nil n restores the default.
+//
+// Returns an error if n is invalid (see URLNormalizer.Validate).
+func (f *Fetcher) SetURLNormalizer(n *URLNormalizer) error {
+	if n == nil {
+		n = DefaultURLNormalizer()
+	}
+	if err := n.Validate(); err != nil {
+		return err
+	}
+	f.normalizer = n
+	return nil
+}
+
+// normalizeURL returns rawURL normalized by the normalizer of the fetcher.
+func (f *Fetcher) normalizeURL(rawURL string) string {
+	if f.normalizer == nil {
+		return DefaultURLNormalizer().Normalize(rawURL)
+	}
+	return f.normalizer.Normalize(rawURL)
+}
@@ -0,0 +1,147 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestURLNormalizerNormalize(t *testing.T) {
+	tests := []struct {
+		name       string
+		normalizer *URLNormalizer
+		in         string
+		want       string
+	}{
+		{name: "host and default port", in: "https://Docs.Example.COM:443/Guide", want: "https://docs.example.com/Guide"},
+		{name: "other port kept", in: "http://docs.example.com:8080/guide", want: "http://docs.example.com:8080/guide"},
+		{name: "empty path", in: "https://docs.example.com", want: "https://docs.example.com/"},
+		{name: "fragment", in: "https://docs.example.com/guide#install", want: "https://docs.example.com/guide"},
+		{name: "dot segments", in: "https://docs.example.com/a/./b/../c//d/", want: "https://docs.example.com/a/c/d/"},
+		{name: "escaped slash kept", in: "https://docs.example.com/a%2Fb/../c", want: "https://docs.example.com/a%2Fb/../c"},
+		{
+			name: "tracking parameters and order",
+			in:   "https://docs.example.com/search?q=a+b&utm_source=news&UTM_Medium=mail&fbclid=x&lang=ja&q=c",
+			want: "https://docs.example.com/search?lang=ja&q=a+b&q=c",
+		},
+		{name: "empty query", in: "https://docs.example.com/guide?utm_source=x&", want: "https://docs.example.com/guide"},
+		{
+			name:       "custom strip params",
+			normalizer: &URLNormalizer{StripParams: append([]string{"ref", "session_?d"}, DefaultStripParams...)},
+			in:         "https://docs.example.com/guide?ref=nav&session_id=1&v=2",
+			want:       "https://docs.example.com/guide?v=2",
+		},
+		{name: "trailing slash kept", in: "https://docs.example.com/guide/", want: "https://docs.example.com/guide/"},
+		{
+			name:       "trailing slash stripped",
+			normalizer: &URLNormalizer{TrailingSlash: TrailingSlashStrip},
+			in:         "https://docs.example.com/guide/",
+			want:       "https://docs.example.com/guide",
+		},
+		{
+			name:       "root slash kept",
+			normalizer: &URLNormalizer{TrailingSlash: TrailingSlashStrip},
+			in:         "https://docs.example.com/",
+			want:       "https://docs.example.com/",
+		},
+		{
+			name:       "trailing slash added",
+			normalizer: &URLNormalizer{TrailingSlash: TrailingSlashAdd},
+			in:         "https://docs.example.com/guide",
+			want:       "https://docs.example.com/guide/",
+		},
+		{
+			name:       "no trailing slash after an extension",
+			normalizer: &URLNormalizer{TrailingSlash: TrailingSlashAdd},
+			in:         "https://docs.example.com/guide.html",
+			want:       "https://docs.example.com/guide.html",
+		},
+		{
+			name: "custom rule",
+			normalizer: &URLNormalizer{Rules: []URLRule{func(u *url.URL) {
+				u.Host = strings.TrimPrefix(u.Host, "www.")
+			}}},
+			in:   "https://www.example.com/guide",
+			want: "https://example.com/guide",
+		},
+		{name: "not http", in: "mailto:docs@example.com", want: "mailto:docs@example.com"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := tt.normalizer
+			if n == nil {
+				n = DefaultURLNormalizer()
+			}
+			if got := n.Normalize(tt.in); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetURLNormalizer(t *testing.T) {
+	f := New(t.TempDir())
+	for _, n := range []*URLNormalizer{
+		{StripParams: []string{""}},
+		{StripParams: []string{"utm_["}},
+		{TrailingSlash: "sometimes"},
+	} {
+		if err := f.SetURLNormalizer(n); err == nil {
+			t.Errorf("SetURLNormalizer(%+v) error = nil, want an error", n)
+		}
+	}
+	if err := f.SetURLNormalizer(&URLNormalizer{TrailingSlash: "Strip"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.normalizeURL("https://docs.example.com/guide/"); got != "https://docs.example.com/guide" {
+		t.Errorf("normalizeURL() = %q", got)
+	}
+}
+
+func TestFetchNormalizesLinks(t *testing.T) {
+	var guideRequests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<html><body>
+<a href="/guide">Guide</a>
+<a href="/guide?utm_source=home&utm_campaign=docs">Tracked</a>
+<a href="/guide#install">Install</a>
+<a href="/docs/../guide">Dotted</a>
+<a href="/search?b=2&a=1">Search</a>
+<a href="/search?a=1&b=2">Search again</a>
+</body></html>`))
+		case "/guide":
+			guideRequests.Add(1)
+			if r.URL.RawQuery != "" {
+				t.Errorf("guide requested with the query %q", r.URL.RawQuery)
+			}
+			w.Write([]byte(`<html><body><p>Guide</p></body></html>`))
+		case "/search":
+			w.Write([]byte(`<html><body><p>Search</p></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	f := New(t.TempDir())
+	f.delay = 0
+	if err := f.Fetch(server.URL); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+	if n := guideRequests.Load(); n != 1 {
+		t.Errorf("guide requested %d times, want 1", n)
+	}
+	var urls []string
+	for _, rec := range f.Report().Pages {
+		urls = append(urls, strings.TrimPrefix(rec.URL, server.URL))
+	}
+	want := "/ /guide /search?a=1&b=2"
+	if got := strings.Join(urls, " "); got != want {
+		t.Errorf("crawled %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,160 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements Notion mode, which reads a tree of Notion pages
+// through the Notion API instead of crawling notion.so.
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/notion"
+)
+
+// ErrNotionUnavailable is returned by FetchNotion when the pages can't be
+// read through the Notion API.
+var ErrNotionUnavailable = errors.New("notion pages unavailable")
+
+// notionMaxRetries is the number of times a request rate-limited by the
+// Notion API is retried.
+const notionMaxRetries = 3
+
+// SetNotionToken sets the token of the Notion integration authenticating the
+// requests of FetchNotion to the Notion API. The pages read are those shared
+// with the integration. The token is never logged.
+func (f *Fetcher) SetNotionToken(token string) {
+	f.notionToken = token
+}
+
+// FetchNotion reads the Notion page root and the child pages under it,
+// recursively, through the Notion API into the crawl directory, one page per
+// Notion page, built from its blocks (see notion.Tree.HTML) and saved like
+// the page at its URL in the tree (see notion.Tree.URL), e.g.,
+// ".../Engineering-Wiki-0123.../onboarding". The root is saved at its own
+// URL, with depth 0, and every other page at its depth in the tree.
+//
+// The saved records carry the notion.so URL of the page as their FinalURL
+// and the time it was last edited as their LastModified. The URL filters and
+// path patterns apply to the URLs in the tree; the depth limit doesn't, since
+// every page of the tree is read. Requests rate-limited by the API are
+// retried after the delay it asks for.
+//
+// Returns an error wrapping ErrNotionUnavailable if a page or its blocks
+// can't be read.
+func (f *Fetcher) FetchNotion(root *notion.Root) error {
+	return f.FetchNotionContext(context.Background(), root)
+}
+
+// FetchNotionContext is like FetchNotion but stops when ctx is cancelled or
+// its deadline expires, like FetchContext.
+func (f *Fetcher) FetchNotionContext(ctx context.Context, root *notion.Root) error {
+	_, crawlDir, err := f.begin(root.URL)
+	if err != nil {
+		return err
+	}
+	return f.end(ctx, f.crawlNotion(ctx, root, crawlDir))
+}
+
+// crawlNotion reads the tree of root and saves its pages in crawlDir.
+func (f *Fetcher) crawlNotion(ctx context.Context, root *notion.Root, crawlDir string) error {
+	tree, err := notion.Fetch(ctx, root, f.getNotion)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%w: %v", ErrNotionUnavailable, err)
+	}
+	slog.Info("Notion pages", "root", root.ID, "pages", len(tree.Pages))
+
+	f.queue(len(tree.Pages))
+	for _, p := range tree.Pages {
+		f.queue(-1)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		pageURL := tree.URL(p)
+		if !f.shouldCrawlURL(pageURL, p.Depth) {
+			f.recordSkip(pageURL, p.Depth, "url_filter")
+			continue
+		}
+		f.mu.Lock()
+		seen := f.visited[pageURL]
+		f.visited[pageURL] = true
+		f.mu.Unlock()
+		if seen {
+			continue
+		}
+
+		parsedURL, err := url.Parse(pageURL)
+		if err != nil {
+			f.recordSkip(pageURL, p.Depth, "invalid_url")
+			continue
+		}
+		body := []byte(tree.HTML(p))
+		rec := PageRecord{
+			URL:         pageURL,
+			FinalURL:    p.WebURL,
+			Depth:       p.Depth,
+			StatusCode:  http.StatusOK,
+			ContentType: "text/html; charset=utf-8",
+			Bytes:       int64(len(body)),
+			Outcome:     OutcomeFailed,
+		}
+		if !p.Updated.IsZero() {
+			rec.LastModified = p.Updated.Format(time.RFC3339)
+		}
+		if f.save(&rec, parsedURL.String(), f.getFilePath(crawlDir, parsedURL), body) {
+			f.downloadCount++
+		}
+	}
+	return nil
+}
+
+// getNotion returns the body of the successful response to a GET request to
+// apiURL, a URL of the Notion API, retrying it up to notionMaxRetries times
+// when rate-limited.
+func (f *Fetcher) getNotion(ctx context.Context, apiURL string) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		req, err := f.newRequest(ctx, "GET", apiURL)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("Authorization", notion.Authorization(f.notionToken))
+		req.Header.Set("Notion-Version", notion.Version)
+		resp, err := f.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case resp.StatusCode == http.StatusTooManyRequests && attempt < notionMaxRetries:
+			resp.Body.Close()
+			delay := time.Second
+			if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+				delay = time.Duration(seconds) * time.Second
+			}
+			slog.Debug("Rate-limited by the Notion API", "url", apiURL, "retry_after", delay)
+			if err := sleep(ctx, delay); err != nil {
+				return nil, err
+			}
+			continue
+		case resp.StatusCode == http.StatusUnauthorized:
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s returned status %d; check the Notion token", apiURL, resp.StatusCode)
+		case resp.StatusCode == http.StatusNotFound:
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s returned status %d; is the page shared with the integration?", apiURL, resp.StatusCode)
+		case resp.StatusCode != http.StatusOK:
+			resp.Body.Close()
+			return nil, fmt.Errorf("%s returned status %d", apiURL, resp.StatusCode)
+		}
+		data, err := f.readBody(resp.Body, resp.ContentLength)
+		resp.Body.Close()
+		return data, err
+	}
+}
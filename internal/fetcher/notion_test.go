@@ -0,0 +1,88 @@
+package fetcher
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/f4ah6o/site2skill-go/internal/notion"
+)
+
+func TestFetchNotion(t *testing.T) {
+	var limited atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Notion-Version") != notion.Version {
+			t.Errorf("Notion-Version = %q", r.Header.Get("Notion-Version"))
+		}
+		// The first request for the child page is rate-limited
+		if r.URL.Path == "/pages/child" && !limited.Swap(true) {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		switch r.URL.Path {
+		case "/pages/01234567-89ab-cdef-0123-456789abcdef":
+			w.Write([]byte(`{"url":"https://www.notion.so/Wiki-0123","last_edited_time":"2024-05-01T10:00:00.000Z",
+"properties":{"title":{"type":"title","title":[{"plain_text":"Wiki"}]}}}`))
+		case "/blocks/01234567-89ab-cdef-0123-456789abcdef/children":
+			w.Write([]byte(`{"results":[
+{"id":"p1","type":"paragraph","has_children":false,"paragraph":{"rich_text":[{"plain_text":"Welcome."}]}},
+{"id":"child","type":"child_page","has_children":true,"child_page":{"title":"Internal Notes"}}],"has_more":false}`))
+		case "/pages/child":
+			w.Write([]byte(`{"url":"https://www.notion.so/Internal-Notes-child","properties":{"Name":{"type":"title","title":[{"plain_text":"Internal Notes"}]}}}`))
+		case "/blocks/child/children":
+			w.Write([]byte(`{"results":[],"has_more":false}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	root, err := notion.Parse("https://www.notion.so/acme/Wiki-0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatal(err)
+	}
+	root.APIURL = server.URL
+
+	f := New(t.TempDir())
+	f.delay = 0
+	f.SetURLFilters(nil, []string{"internal-notes"})
+	f.SetNotionToken("secret")
+	if err := f.FetchNotion(root); err != nil {
+		t.Fatalf("FetchNotion() returned error: %v", err)
+	}
+	if !limited.Load() {
+		t.Error("the child page wasn't requested")
+	}
+
+	outcomes := make(map[string]string)
+	for _, rec := range f.Report().Pages {
+		outcomes[rec.URL] = string(rec.Outcome) + " " + rec.OutputFile + rec.Reason
+	}
+	want := map[string]string{
+		root.URL:                     "saved crawl/www.notion.so/acme/Wiki-0123456789abcdef0123456789abcdef.html",
+		root.URL + "/internal-notes": "skipped url_filter",
+	}
+	for u, w := range want {
+		if outcomes[u] != w {
+			t.Errorf("%s = %q, want %q (all: %v)", u, outcomes[u], w, outcomes)
+		}
+	}
+	if rec := f.Report().SavedPages()["crawl/www.notion.so/acme/Wiki-0123456789abcdef0123456789abcdef.html"]; rec.FinalURL != "https://www.notion.so/Wiki-0123" || rec.LastModified != "2024-05-01T10:00:00Z" {
+		t.Errorf("FinalURL, LastModified = %q, %q", rec.FinalURL, rec.LastModified)
+	}
+
+	f = New(t.TempDir())
+	f.delay = 0
+	f.SetNotionToken("wrong")
+	if err := f.FetchNotion(root); !errors.Is(err, ErrNotionUnavailable) || !strings.Contains(err.Error(), "token") {
+		t.Errorf("FetchNotion() with a wrong token error = %v, want ErrNotionUnavailable", err)
+	}
+}
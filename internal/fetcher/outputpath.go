@@ -0,0 +1,82 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the translation of URL paths into file names that can
+// be written on any file system, whatever the length and characters of the
+// URL.
+package fetcher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxNameBytes is the longest file name written for a segment of a URL
+	// path, in bytes. File systems take 255 bytes; the rest is left for the
+	// suffixes added to the name later (".md", "-2", ".gz").
+	maxNameBytes = 200
+	// maxExtBytes is the longest extension kept when a name is shortened.
+	maxExtBytes = 16
+	// nameHashLen is the number of hex digits of the hash ending a shortened name.
+	nameHashLen = 12
+)
+
+// pathSegments returns the segments of the path of u, without empty segments,
+// percent-decoded: "/ドキュメント/a%2Fb" has the segments "ドキュメント" and "a/b",
+// whose slash is replaced by safeName. A segment that doesn't decode to valid
+// UTF-8, which some file systems reject in names, is kept encoded.
+func pathSegments(u *url.URL) []string {
+	var segments []string
+	for _, seg := range strings.Split(u.EscapedPath(), "/") {
+		if seg == "" {
+			continue
+		}
+		if decoded, err := url.PathUnescape(seg); err == nil && utf8.ValidString(decoded) {
+			seg = decoded
+		}
+		segments = append(segments, seg)
+	}
+	return segments
+}
+
+// safeName returns seg, a segment of a URL path, as a file name that can be
+// written on Linux, macOS, and Windows alike:
+//   - "." and ".." become "_" and "__", so that no URL writes outside the
+//     crawl directory
+//   - path separators, the characters Windows rejects (<>:"|?*), and control
+//     characters become "_"
+//   - a name over maxNameBytes bytes is cut at a character boundary and ended
+//     with a hash of the whole name before its extension
+//     ("very-long-...-3f2a9c01b7e4.html"), so that names that share a long
+//     prefix stay distinct
+func safeName(seg string) string {
+	if seg == "." || seg == ".." {
+		return strings.Repeat("_", len(seg))
+	}
+	seg = strings.Map(func(r rune) rune {
+		switch {
+		case r < 0x20 || r == 0x7f:
+			return '_'
+		case strings.ContainsRune(`/\<>:"|?*`, r):
+			return '_'
+		}
+		return r
+	}, seg)
+	if len(seg) <= maxNameBytes {
+		return seg
+	}
+	ext := path.Ext(seg)
+	if len(ext) > maxExtBytes {
+		ext = ""
+	}
+	sum := sha256.Sum256([]byte(seg))
+	base := seg[:len(seg)-len(ext)]
+	cut := maxNameBytes - len(ext) - nameHashLen - 1
+	for cut > 0 && !utf8.RuneStart(base[cut]) {
+		cut--
+	}
+	return base[:cut] + "-" + hex.EncodeToString(sum[:])[:nameHashLen] + ext
+}
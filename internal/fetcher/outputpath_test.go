@@ -0,0 +1,102 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestPathSegments(t *testing.T) {
+	tests := []struct {
+		rawURL string
+		want   []string
+	}{
+		{"https://example.com/", nil},
+		{"https://example.com//docs//guide/", []string{"docs", "guide"}},
+		{"https://example.com/%E3%83%89%E3%82%AD%E3%83%A5%E3%83%A1%E3%83%B3%E3%83%88", []string{"ドキュメント"}},
+		{"https://example.com/a%2Fb/c", []string{"a/b", "c"}},
+		{"https://example.com/%FF/c", []string{"%FF", "c"}},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.rawURL)
+		if err != nil {
+			t.Fatalf("failed to parse %s: %v", tt.rawURL, err)
+		}
+		if got := pathSegments(u); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("pathSegments(%s) = %q, want %q", tt.rawURL, got, tt.want)
+		}
+	}
+}
+
+func TestSafeName(t *testing.T) {
+	tests := []struct {
+		seg  string
+		want string
+	}{
+		{"guide.html", "guide.html"},
+		{".", "_"},
+		{"..", "__"},
+		{"...", "..."},
+		{`a/b\c`, "a_b_c"},
+		{`<>:"|?*.html`, "_______.html"},
+		{"tab\there\x7f", "tab_here_"},
+		{"ドキュメント.html", "ドキュメント.html"},
+	}
+	for _, tt := range tests {
+		if got := safeName(tt.seg); got != tt.want {
+			t.Errorf("safeName(%q) = %q, want %q", tt.seg, got, tt.want)
+		}
+	}
+
+	long := strings.Repeat("ド", 100) + ".html"
+	got := safeName(long)
+	if len(got) > maxNameBytes || !utf8.ValidString(got) || !strings.HasSuffix(got, ".html") || !strings.HasPrefix(got, "ドドド") {
+		t.Errorf("safeName() of a %d-byte name = %q (%d bytes)", len(long), got, len(got))
+	}
+	if other := safeName(strings.Repeat("ド", 100) + "x.html"); other == got {
+		t.Errorf("safeName() of two long names sharing a prefix = %q for both", got)
+	}
+	if again := safeName(long); again != got {
+		t.Errorf("safeName() = %q, then %q", got, again)
+	}
+	if noExt := safeName(strings.Repeat("a", 300) + "." + strings.Repeat("b", 40)); len(noExt) > maxNameBytes {
+		t.Errorf("safeName() with a long extension = %q (%d bytes)", noExt, len(noExt))
+	}
+}
+
+func TestFetchLongURL(t *testing.T) {
+	long := "/" + url.PathEscape(strings.Repeat("ドキュメント", 30)) + "?q=" + strings.Repeat("x", 300)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<html><body><a href="` + long + `">long</a></body></html>`))
+		case "/robots.txt":
+			http.NotFound(w, r)
+		default:
+			w.Write([]byte(`<html><body>long</body></html>`))
+		}
+	}))
+	defer server.Close()
+
+	f := New(t.TempDir())
+	f.delay = 0
+	if err := f.Fetch(server.URL + "/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+	saved := 0
+	for _, rec := range f.Report().Pages {
+		if rec.Outcome != OutcomeSaved {
+			t.Errorf("%s: Outcome = %q, Reason = %q", rec.URL, rec.Outcome, rec.Reason)
+			continue
+		}
+		saved++
+	}
+	if saved != 2 {
+		t.Errorf("saved %d pages, want 2", saved)
+	}
+}
@@ -0,0 +1,160 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the page index, which maps the files of a crawl to
+// the URLs they were saved from.
+package fetcher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+)
+
+// PageIndexFile is the name of the page index written in the output
+// directory of the fetcher, next to the crawl directory.
+const PageIndexFile = "pages.json"
+
+// PageIndex maps the files saved by a crawl to the URLs they stand for, with
+// the hash of their content and the details of their responses. The names of
+// the files are derived from the URLs for readability only, and lose what
+// can't be written in a file name (the query of docs?hl=ja is written
+// docs_q_hl_ja.html, with its percent signs dropped): the index is what
+// tells the URL of a file, and it gives the files of different URLs with the
+// same name distinct names (docs_q_hl_ja-2.html), including names that differ
+// only in case, which are the same file on case-insensitive file systems
+// (macOS and Windows by default). It is written as
+// pages.json when the crawl ends, interrupted or not, and read by the builds
+// reusing the crawl (see LoadPageIndex).
+type PageIndex struct {
+	// Pages lists the saved files, sorted by file.
+	Pages []IndexedPage `json:"pages"`
+
+	// byFile holds the pages by File
+	byFile map[string]*IndexedPage
+	// folded holds the files claimed by their lowercase name
+	folded map[string]bool
+}
+
+// IndexedPage is one file of a crawl in the page index.
+type IndexedPage struct {
+	// File is the slash-separated path of the file relative to the output
+	// directory (e.g., "crawl/example.com/docs_q_hl_ja.html"), without the
+	// extension added when the crawl is compressed.
+	File string `json:"file"`
+	// URL is the URL the file stands for: the URL of the page, or the
+	// locale-neutral or canonical URL it was saved under.
+	URL string `json:"url"`
+	// Hash is the SHA-256 hash of the content saved, in hex, before compression.
+	Hash string `json:"hash"`
+	// ContentType, ContentLanguage, and LastModified are those of the page's
+	// record (see PageRecord).
+	ContentType     string `json:"content_type,omitempty"`
+	ContentLanguage string `json:"content_language,omitempty"`
+	LastModified    string `json:"last_modified,omitempty"`
+	// Locale is the locale served for the page in locale priority mode.
+	Locale string `json:"locale,omitempty"`
+	// FetchedAt is when the file was saved.
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// newPageIndex returns an empty page index.
+func newPageIndex() *PageIndex {
+	return &PageIndex{byFile: make(map[string]*IndexedPage), folded: make(map[string]bool)}
+}
+
+// LoadPageIndex reads the page index of the crawl in outputDir, the output
+// directory of the fetcher. Returns an error wrapping os.ErrNotExist if the
+// crawl has none, as crawls made before the index was introduced don't.
+func LoadPageIndex(outputDir string) (*PageIndex, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, PageIndexFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", PageIndexFile, err)
+	}
+	index := newPageIndex()
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", PageIndexFile, err)
+	}
+	for i := range index.Pages {
+		index.byFile[index.Pages[i].File] = &index.Pages[i]
+		index.folded[strings.ToLower(index.Pages[i].File)] = true
+	}
+	return index, nil
+}
+
+// Lookup returns the page saved as file, a slash-separated path relative to
+// the output directory, with or without the extension of compressed pages.
+func (x *PageIndex) Lookup(file string) (IndexedPage, bool) {
+	if x == nil {
+		return IndexedPage{}, false
+	}
+	p, ok := x.byFile[strings.TrimSuffix(file, CompressedExt)]
+	if !ok {
+		return IndexedPage{}, false
+	}
+	return *p, true
+}
+
+// claim returns the name under which the page at pageURL is saved, file if
+// it is free or already holds pageURL, or else file with the first free
+// number appended to its name (docs-2.html), and reserves it for pageURL. A
+// name is free if no file claimed has it, whatever the case of its letters.
+func (x *PageIndex) claim(file, pageURL string) string {
+	ext := path.Ext(file)
+	base := strings.TrimSuffix(file, ext)
+	name := file
+	for n := 2; ; n++ {
+		p, ok := x.byFile[name]
+		if ok && p.URL == pageURL {
+			return name
+		}
+		if !ok && !x.folded[strings.ToLower(name)] {
+			x.byFile[name] = &IndexedPage{File: name, URL: pageURL}
+			x.folded[strings.ToLower(name)] = true
+			return name
+		}
+		name = base + "-" + strconv.Itoa(n) + ext
+	}
+}
+
+// add records the content saved as file, claimed for pageURL, from the page of rec.
+func (x *PageIndex) add(file, pageURL string, rec *PageRecord, body []byte) {
+	sum := sha256.Sum256(body)
+	x.byFile[file] = &IndexedPage{
+		File:            file,
+		URL:             pageURL,
+		Hash:            hex.EncodeToString(sum[:]),
+		ContentType:     rec.ContentType,
+		ContentLanguage: rec.ContentLanguage,
+		LastModified:    rec.LastModified,
+		Locale:          rec.Locale,
+		FetchedAt:       time.Now().UTC(),
+	}
+}
+
+// write saves the pages of the index that were saved (not only claimed) as
+// pages.json in outputDir.
+func (x *PageIndex) write(outputDir string) error {
+	pages := make([]IndexedPage, 0, len(x.byFile))
+	for _, p := range x.byFile {
+		if p.Hash != "" {
+			pages = append(pages, *p)
+		}
+	}
+	x.Pages = pages
+	sort.Slice(x.Pages, func(i, j int) bool { return x.Pages[i].File < x.Pages[j].File })
+	data, err := json.MarshalIndent(x, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = atomicfile.WriteFile(filepath.Join(outputDir, PageIndexFile), append(data, '\n'))
+	return err
+}
@@ -0,0 +1,139 @@
+package fetcher
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPageIndexClaim(t *testing.T) {
+	x := newPageIndex()
+	tests := []struct {
+		file    string
+		pageURL string
+		want    string
+	}{
+		{"crawl/example.com/path_q_key_val2Fue.html", "https://example.com/path?key=val%2Fue", "crawl/example.com/path_q_key_val2Fue.html"},
+		{"crawl/example.com/path_q_key_val2Fue.html", "https://example.com/path?key=val2Fue", "crawl/example.com/path_q_key_val2Fue-2.html"},
+		{"crawl/example.com/path_q_key_val2Fue.html", "https://example.com/path?key=val%2Fue", "crawl/example.com/path_q_key_val2Fue.html"},
+		{"crawl/example.com/path_q_key_val2Fue.html", "https://example.com/path?key=val2F%75e", "crawl/example.com/path_q_key_val2Fue-3.html"},
+		{"crawl/example.com/path_q_key_val2Fue.html", "https://example.com/path?key=val2Fue", "crawl/example.com/path_q_key_val2Fue-2.html"},
+		{"crawl/example.com/docs", "https://example.com/docs", "crawl/example.com/docs"},
+		{"crawl/example.com/docs", "https://example.com/docs?", "crawl/example.com/docs-2"},
+	}
+	for _, tt := range tests {
+		if got := x.claim(tt.file, tt.pageURL); got != tt.want {
+			t.Errorf("claim(%q, %q) = %q, want %q", tt.file, tt.pageURL, got, tt.want)
+		}
+	}
+}
+
+func TestPageIndexWriteLoad(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := LoadPageIndex(dir); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadPageIndex() of a crawl without index returned %v, want os.ErrNotExist", err)
+	}
+
+	x := newPageIndex()
+	file := x.claim("crawl/example.com/docs_q_hl_ja.html", "https://example.com/docs?hl=ja")
+	x.add(file, "https://example.com/docs?hl=ja", &PageRecord{ContentType: "text/html", Locale: "ja"}, []byte("<html></html>"))
+	x.claim("crawl/example.com/failed.html", "https://example.com/failed")
+	if err := x.write(dir); err != nil {
+		t.Fatalf("write() returned error: %v", err)
+	}
+
+	loaded, err := LoadPageIndex(dir)
+	if err != nil {
+		t.Fatalf("LoadPageIndex() returned error: %v", err)
+	}
+	if len(loaded.Pages) != 1 {
+		t.Fatalf("got %d pages, want only the saved one: %+v", len(loaded.Pages), loaded.Pages)
+	}
+	for _, file := range []string{"crawl/example.com/docs_q_hl_ja.html", "crawl/example.com/docs_q_hl_ja.html" + CompressedExt} {
+		page, ok := loaded.Lookup(file)
+		if !ok {
+			t.Errorf("Lookup(%q) found nothing", file)
+			continue
+		}
+		if page.URL != "https://example.com/docs?hl=ja" || page.Locale != "ja" || page.ContentType != "text/html" || len(page.Hash) != 64 || page.FetchedAt.IsZero() {
+			t.Errorf("Lookup(%q) = %+v", file, page)
+		}
+	}
+	if _, ok := loaded.Lookup("crawl/example.com/failed.html"); ok {
+		t.Error("Lookup() found a page that was claimed but not saved")
+	}
+	var none *PageIndex
+	if _, ok := none.Lookup("crawl/example.com/docs_q_hl_ja.html"); ok {
+		t.Error("Lookup() on a nil index found a page")
+	}
+}
+
+func TestFetchPageIndex(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><a href="/path?key=val%2Fue">a</a> <a href="/path?key=val2Fue">b</a></body></html>`))
+		case "/path":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body>` + r.URL.RawQuery + `</body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	f := New(dir)
+	f.delay = 0
+	if err := f.Fetch(server.URL + "/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+
+	index, err := LoadPageIndex(dir)
+	if err != nil {
+		t.Fatalf("LoadPageIndex() returned error: %v", err)
+	}
+	urls := make(map[string]string)
+	for _, page := range index.Pages {
+		urls[page.URL] = page.File
+	}
+	first, second := urls[server.URL+"/path?key=val%2Fue"], urls[server.URL+"/path?key=val2Fue"]
+	if first == "" || second == "" || first == second {
+		t.Fatalf("the two pages were saved as %q and %q, want distinct files (index: %+v)", first, second, index.Pages)
+	}
+	for _, file := range []string{first, second} {
+		body, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		page, _ := index.Lookup(file)
+		if query := page.URL[strings.Index(page.URL, "?")+1:]; !strings.Contains(string(body), query) {
+			t.Errorf("%s holds %q, want the page of %s", file, body, page.URL)
+		}
+	}
+	for _, rec := range f.Report().Pages {
+		if rec.Outcome == OutcomeSaved {
+			if page, ok := index.Lookup(rec.OutputFile); !ok || page.URL != rec.URL {
+				t.Errorf("%s: saved as %q, indexed as %+v", rec.URL, rec.OutputFile, page)
+			}
+		}
+	}
+}
+
+func TestPageIndexClaimCase(t *testing.T) {
+	x := newPageIndex()
+	if got := x.claim("crawl/example.com/docs/Guide.html", "https://example.com/docs/Guide"); got != "crawl/example.com/docs/Guide.html" {
+		t.Errorf("claim() = %q", got)
+	}
+	if got := x.claim("crawl/example.com/docs/guide.html", "https://example.com/docs/guide"); got != "crawl/example.com/docs/guide-2.html" {
+		t.Errorf("claim() of a name differing only in case = %q, want crawl/example.com/docs/guide-2.html", got)
+	}
+	if got := x.claim("crawl/example.com/Docs/guide.html", "https://example.com/Docs/guide"); got != "crawl/example.com/Docs/guide-3.html" {
+		t.Errorf("claim() of a directory differing only in case = %q, want crawl/example.com/Docs/guide-3.html", got)
+	}
+}
@@ -0,0 +1,169 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the traversal of paginated articles and listings,
+// whose pages are linked with rel="next" or numbered with ?page=N.
+package fetcher
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/idn"
+	"golang.org/x/net/html"
+)
+
+const (
+	// maxSeriesPages is the most pages of a paginated series crawled at the
+	// depth of its first page; the pages after them are crawled as ordinary
+	// links, so that endless pagination stops at the depth limit.
+	maxSeriesPages = 100
+	// pageParam is the query parameter of numbered pagination (?page=2).
+	pageParam = "page"
+)
+
+// digitsPattern matches the page numbers of paths.
+var digitsPattern = regexp.MustCompile(`[0-9]+`)
+
+// seriesPage places a page in a paginated series.
+type seriesPage struct {
+	// first is the URL of the first page of the series.
+	first string
+	// number is the number of the page in the series, from 1.
+	number int
+}
+
+// nextPage returns the URL of the page following the page of doc, fetched
+// from baseURL, in a paginated series, or "" if it has none. The next page is
+// the one a <link> or <a> element declares with rel="next", if its URL
+// continues baseURL (see continues); failing that, in numbered pagination,
+// the link of links to the URL of the page with ?page=N+1, where N is the
+// page parameter of baseURL (1 without one).
+func (f *Fetcher) nextPage(doc *html.Node, baseURL string, links []string) string {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return ""
+	}
+	if href := relNext(doc); href != "" {
+		if u, err := url.Parse(href); err == nil {
+			next := f.normalizeURL(idn.ToASCII(base.ResolveReference(u).String()))
+			if u, err := url.Parse(next); err == nil && continues(base, u) {
+				return next
+			}
+		}
+	}
+
+	current := base.Query()
+	n := 1
+	if v := current.Get(pageParam); v != "" {
+		if n, err = strconv.Atoi(v); err != nil || n < 1 {
+			return ""
+		}
+	}
+	current.Del(pageParam)
+	want := strconv.Itoa(n + 1)
+	for _, link := range links {
+		u, err := url.Parse(link)
+		if err != nil || u.Host != base.Host || u.Path != base.Path {
+			continue
+		}
+		q := u.Query()
+		if q.Get(pageParam) != want {
+			continue
+		}
+		q.Del(pageParam)
+		if q.Encode() == current.Encode() {
+			return link
+		}
+	}
+	return ""
+}
+
+// continues reports whether next, the URL a page at base declares next,
+// looks like the following page of the same article or listing rather than
+// the next chapter of a documentation site, which Sphinx and MkDocs also link
+// with rel="next": both are on the same host, and next has the path of base
+// with another query (/article?page=2), the path of base followed by a
+// numbered segment (/blog/page/2), or the path of base with other numbers
+// (/blog/page/3 after /blog/page/2).
+func continues(base, next *url.URL) bool {
+	if next.Host != base.Host {
+		return false
+	}
+	if next.Path == base.Path {
+		return next.RawQuery != base.RawQuery
+	}
+	if rest, ok := strings.CutPrefix(next.Path, strings.TrimSuffix(base.Path, "/")+"/"); ok {
+		return digitsPattern.MatchString(rest)
+	}
+	return digitsPattern.MatchString(next.Path) &&
+		digitsPattern.ReplaceAllString(next.Path, "0") == digitsPattern.ReplaceAllString(base.Path, "0")
+}
+
+// relNext returns the href of the first <link> or <a> element of doc whose
+// rel attribute lists "next", or "".
+func relNext(n *html.Node) string {
+	if n.Type == html.ElementNode && (n.Data == "link" || n.Data == "a") {
+		var rel, href string
+		for _, attr := range n.Attr {
+			switch attr.Key {
+			case "rel":
+				rel = attr.Val
+			case "href":
+				href = strings.TrimSpace(attr.Val)
+			}
+		}
+		if href != "" {
+			for _, token := range strings.Fields(strings.ToLower(rel)) {
+				if token == "next" {
+					return href
+				}
+			}
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if href := relNext(c); href != "" {
+			return href
+		}
+	}
+	return ""
+}
+
+// continueSeries records next as the page following the page at pageURL in
+// its paginated series, and reports whether next is to be crawled as part of
+// the series: it is not if it was already seen, or if the series reached
+// maxSeriesPages.
+func (f *Fetcher) continueSeries(pageURL, next string) bool {
+	if next == pageURL || f.report.has(next) {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.visited[next] {
+		return false
+	}
+	page, ok := f.series[pageURL]
+	if !ok {
+		page = seriesPage{first: pageURL, number: 1}
+	}
+	if page.number >= maxSeriesPages {
+		return false
+	}
+	if f.series == nil {
+		f.series = make(map[string]seriesPage)
+	}
+	f.series[next] = seriesPage{first: page.first, number: page.number + 1}
+	return true
+}
+
+// seriesDetails adds the first page of the paginated series the page at the
+// URL of rec continues, and its number in it, to rec.
+func (f *Fetcher) seriesDetails(rec *PageRecord) {
+	f.mu.Lock()
+	page, ok := f.series[rec.URL]
+	f.mu.Unlock()
+	if ok {
+		rec.SeriesOf = page.first
+		rec.PageNumber = page.number
+	}
+}
@@ -0,0 +1,139 @@
+package fetcher
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestContinues(t *testing.T) {
+	tests := []struct {
+		base, next string
+		want       bool
+	}{
+		{"https://example.com/article", "https://example.com/article?page=2", true},
+		{"https://example.com/blog/", "https://example.com/blog/page/2/", true},
+		{"https://example.com/blog/page/2/", "https://example.com/blog/page/3/", true},
+		{"https://example.com/guide/step-1", "https://example.com/guide/step-2", true},
+		{"https://example.com/docs/install.html", "https://example.com/docs/usage.html", false},
+		{"https://example.com/docs/", "https://example.com/docs/install/", false},
+		{"https://example.com/article", "https://example.com/article", false},
+		{"https://example.com/blog/page/2", "https://other.example.com/blog/page/3", false},
+	}
+	for _, tt := range tests {
+		base, _ := url.Parse(tt.base)
+		next, _ := url.Parse(tt.next)
+		if got := continues(base, next); got != tt.want {
+			t.Errorf("continues(%q, %q) = %t, want %t", tt.base, tt.next, got, tt.want)
+		}
+	}
+}
+
+func TestNextPage(t *testing.T) {
+	tests := []struct {
+		name, baseURL, body string
+		want                string
+	}{
+		{
+			name:    "link rel next",
+			baseURL: "https://example.com/article",
+			body:    `<head><link rel="Next" href="/article?page=2"></head>`,
+			want:    "https://example.com/article?page=2",
+		},
+		{
+			name:    "anchor rel next",
+			baseURL: "https://example.com/blog/",
+			body:    `<a href="/blog/">1</a><a rel="nofollow next" href="page/2/">Older posts</a>`,
+			want:    "https://example.com/blog/page/2/",
+		},
+		{
+			name:    "next chapter",
+			baseURL: "https://example.com/docs/install.html",
+			body:    `<link rel="next" href="usage.html"><a href="usage.html">Usage</a>`,
+		},
+		{
+			name:    "numbered pagination",
+			baseURL: "https://example.com/changelog?lang=en&page=2",
+			body:    `<a href="?lang=en&page=1">1</a><a href="?lang=ja&page=3">ja</a><a href="?page=3&lang=en">3</a>`,
+			want:    "https://example.com/changelog?lang=en&page=3",
+		},
+		{
+			name:    "first numbered page",
+			baseURL: "https://example.com/changelog",
+			body:    `<a href="/changelog?page=2">2</a>`,
+			want:    "https://example.com/changelog?page=2",
+		},
+		{
+			name:    "invalid page number",
+			baseURL: "https://example.com/changelog?page=last",
+			body:    `<a href="/changelog?page=2">2</a>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := html.Parse(strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			f := New(t.TempDir())
+			if got := f.nextPage(doc, tt.baseURL, f.extractLinks(doc, tt.baseURL)); got != tt.want {
+				t.Errorf("nextPage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchPagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		page := r.URL.Query().Get("page")
+		switch {
+		case r.URL.Path == "/":
+			w.Write([]byte(`<html><body><a href="/article">Article</a><a href="/install">Install</a></body></html>`))
+		case r.URL.Path == "/article" && page == "":
+			w.Write([]byte(`<html><head><link rel="next" href="/article?page=2"></head><body>Part 1</body></html>`))
+		case r.URL.Path == "/article" && page == "2":
+			w.Write([]byte(`<html><body>Part 2 <a href="/article">1</a><a href="/article?page=3">3</a></body></html>`))
+		case r.URL.Path == "/article" && page == "3":
+			w.Write([]byte(`<html><body>Part 3</body></html>`))
+		case r.URL.Path == "/install":
+			w.Write([]byte(`<html><head><link rel="next" href="/usage"></head><body>Install</body></html>`))
+		case r.URL.Path == "/usage":
+			w.Write([]byte(`<html><body>Usage</body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	f := New(t.TempDir())
+	f.delay = 0
+	f.maxDepth = 1
+	if err := f.Fetch(server.URL + "/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+
+	article := server.URL + "/article"
+	want := map[string]string{
+		article:                 "saved  0",
+		article + "?page=2":     "saved " + article + " 2",
+		article + "?page=3":     "saved " + article + " 3",
+		server.URL + "/install": "saved  0",
+		// The next chapter of a documentation site isn't a page of the same article
+		server.URL + "/usage": "",
+	}
+	got := make(map[string]string)
+	for _, rec := range f.Report().Pages {
+		got[rec.URL] = fmt.Sprintf("%s %s %d", rec.Outcome, rec.SeriesOf, rec.PageNumber)
+	}
+	for u, w := range want {
+		if got[u] != w {
+			t.Errorf("%s = %q, want %q", u, got[u], w)
+		}
+	}
+}
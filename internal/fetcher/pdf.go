@@ -0,0 +1,91 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the download of the PDF documents linked from the
+// crawled pages, which the convert stage turns into Markdown.
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// SetIncludePDF enables or disables the download of PDF documents. Links to
+// URLs whose path ends with .pdf are skipped as non-HTML resources by
+// default; when enabled, they are fetched like pages, and a response that is
+// a PDF document (of type application/pdf, or of an unspecified or generic
+// type for a .pdf URL, and starting with the PDF header) is saved in the
+// crawl directory with the extension .pdf. The links of PDF documents aren't
+// followed. A .pdf URL that turns out not to be a PDF document is skipped
+// with the reason "not_pdf".
+func (f *Fetcher) SetIncludePDF(include bool) {
+	f.includePDF = include
+}
+
+// isPDFURL reports whether the path of urlStr ends with .pdf.
+func isPDFURL(urlStr string) bool {
+	u, err := url.Parse(urlStr)
+	return err == nil && strings.HasSuffix(strings.ToLower(u.Path), ".pdf")
+}
+
+// skipsResource reports whether urlStr is skipped as a non-HTML resource
+// (see isNonHTMLResource and SetIncludePDF).
+func (f *Fetcher) skipsResource(urlStr string) bool {
+	if f.includePDF && isPDFURL(urlStr) {
+		return false
+	}
+	return isNonHTMLResource(urlStr)
+}
+
+// isPDFResponse reports whether a response of contentType for urlStr should
+// be read as a PDF document.
+func isPDFResponse(contentType, urlStr string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/pdf", "application/x-pdf":
+		return true
+	case "", "application/octet-stream", "binary/octet-stream":
+		return isPDFURL(urlStr)
+	}
+	return false
+}
+
+// savePDF reads the PDF document of the response body and saves it at the
+// path of saveURL in crawlDir, with the extension .pdf. fetchURL is the URL
+// the document was fetched from. Returns an error only when ctx is done.
+func (f *Fetcher) savePDF(ctx context.Context, rec *PageRecord, body io.Reader, contentLength int64, saveURL *url.URL, crawlDir, fetchURL string) error {
+	data, err := f.readBody(body, contentLength)
+	rec.Bytes = int64(len(data))
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if skipped, stop := f.sizeFailure(rec, err); skipped {
+			return stop
+		}
+		f.readFailure(rec, fetchURL, err)
+		return nil
+	}
+	header := data
+	if len(header) > 1024 {
+		header = header[:1024]
+	}
+	if !bytes.Contains(header, []byte("%PDF-")) {
+		rec.Outcome = OutcomeSkipped
+		rec.Reason = "not_pdf"
+		f.record(*rec)
+		return nil
+	}
+
+	filePath := f.getFilePath(crawlDir, saveURL)
+	if ext := filepath.Ext(filePath); strings.EqualFold(ext, ".pdf") {
+		filePath = strings.TrimSuffix(filePath, ext)
+	}
+	if f.save(rec, saveURL.String(), filePath+".pdf", data) {
+		f.downloadCount++
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFetchIncludePDF(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><a href="/docs/manual.pdf">Manual</a> <a href="/docs/guide.PDF?v=2">Guide</a> <a href="/docs/broken.pdf">Broken</a></body></html>`))
+		case "/docs/manual.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte("%PDF-1.4\nmanual"))
+		case "/docs/guide.PDF":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write([]byte("%PDF-1.7\nguide"))
+		case "/docs/broken.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write([]byte("<html>Not found</html>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		name       string
+		includePDF bool
+		locale     bool
+		wantFiles  []string
+	}{
+		{name: "skipped by default"},
+		{name: "included", includePDF: true, wantFiles: []string{"docs/guide.PDF_q_v_2.pdf", "docs/manual.pdf"}},
+		{name: "included in locale priority mode", includePDF: true, locale: true, wantFiles: []string{"docs/guide.PDF_q_v_2.pdf", "docs/manual.pdf"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			f := New(dir)
+			f.delay = 0
+			f.SetIncludePDF(tt.includePDF)
+			if tt.locale {
+				f.SetLocaleConfig(&LocaleConfig{Priority: []string{"en"}})
+			}
+			if err := f.Fetch(server.URL + "/docs/"); err != nil {
+				t.Fatalf("Fetch() returned error: %v", err)
+			}
+
+			var got []string
+			filepath.Walk(filepath.Join(dir, "crawl", host), func(path string, info os.FileInfo, err error) error {
+				if err == nil && strings.HasSuffix(path, ".pdf") {
+					rel, _ := filepath.Rel(filepath.Join(dir, "crawl", host), path)
+					got = append(got, filepath.ToSlash(rel))
+				}
+				return nil
+			})
+			if strings.Join(got, ",") != strings.Join(tt.wantFiles, ",") {
+				t.Errorf("saved PDF files = %v, want %v", got, tt.wantFiles)
+			}
+
+			reasons := make(map[string]string)
+			for _, rec := range f.Report().Pages {
+				reasons[rec.URL] = rec.Reason
+			}
+			wantReason := "non_html_extension"
+			if tt.includePDF {
+				wantReason = "not_pdf"
+			}
+			if got := reasons[server.URL+"/docs/broken.pdf"]; got != wantReason {
+				t.Errorf("broken.pdf reason = %q, want %q", got, wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the per-host politeness settings, which give the hosts
+// of a multi-site crawl their own delay, time-of-day window, and User-Agent
+// suffix: a single global delay is either too slow for one's
+// own staging host or too aggressive for third-party sites.
+package fetcher
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Politeness holds the politeness settings of the hosts of a crawl, as read
+// from a politeness file (see LoadPoliteness):
+//
+//	hosts:
+//	  - host: staging.example.com
+//	    delay: 0s
+//	  - host: "*.example.org"
+//	    delay: 5s
+//	    window: "22:00-06:00"
+//	    user_agent_suffix: "docs-team@example.com"
+//
+// The hosts not matched by any entry get the defaults of the crawl.
+type Politeness struct {
+	// Hosts are the settings of the hosts, the first entry matching a host
+	// applying to it.
+	Hosts []HostPoliteness `yaml:"hosts"`
+}
+
+// HostPoliteness holds the politeness settings of the hosts matching Host.
+type HostPoliteness struct {
+	// Host is a host name or IP address ("docs.example.com"), a wildcard
+	// matching every subdomain of a domain ("*.example.com", which doesn't
+	// match "example.com" itself), or "*" for every host. Hosts are matched
+	// case-insensitively, without their port.
+	Host string `yaml:"host"`
+	// Delay is the politeness delay before each page request to the hosts,
+	// such as 0s for one's own staging host; nil keeps that of the crawl.
+	Delay *time.Duration `yaml:"delay"`
+	// Window is the time of day the hosts may be requested, in local time,
+	// such as "22:00-06:00" for the night; requests outside of it wait for
+	// the window to open. Empty allows any time.
+	Window string `yaml:"window"`
+	// UserAgentSuffix is appended to the User-Agent of the requests to the
+	// hosts, separated by a space, such as a contact the operators of a
+	// third-party site can reach.
+	UserAgentSuffix string `yaml:"user_agent_suffix"`
+
+	// window is Window parsed by Validate
+	window *timeWindow
+}
+
+// LoadPoliteness reads and validates the politeness file at path (see
+// Politeness).
+//
+// Returns an error if the file can't be read, has unknown keys, or holds
+// invalid settings.
+func LoadPoliteness(path string) (*Politeness, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read politeness file: %w", err)
+	}
+	p, err := ParsePoliteness(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return p, nil
+}
+
+// ParsePoliteness parses and validates the YAML politeness settings data
+// (see Politeness).
+func ParsePoliteness(data []byte) (*Politeness, error) {
+	if err := checkRemovedPoliteness(data); err != nil {
+		return nil, err
+	}
+	var p Politeness
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse politeness settings: %w", err)
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// checkRemovedPoliteness returns an error explaining why if data, politeness
+// settings, limits the requests in flight to a host: the Fetcher sends one
+// request at a time, so a limit would change nothing.
+func checkRemovedPoliteness(data []byte) error {
+	var raw struct {
+		Hosts []map[string]any `yaml:"hosts"`
+	}
+	if yaml.Unmarshal(data, &raw) != nil {
+		// The strict decoding reports the error
+		return nil
+	}
+	for i, h := range raw.Hosts {
+		if _, ok := h["max_concurrency"]; ok {
+			return fmt.Errorf("hosts[%d]: max_concurrency is not supported: the crawler sends one request at a time to every host; slow a host down with delay instead", i)
+		}
+	}
+	return nil
+}
+
+// Validate checks the settings of p.
+//
+// Returns an error if a host pattern, delay, window, or User-Agent suffix is
+// invalid.
+func (p *Politeness) Validate() error {
+	for i := range p.Hosts {
+		h := &p.Hosts[i]
+		h.Host = strings.ToLower(strings.TrimSpace(h.Host))
+		if h.Host != "*" {
+			if _, err := ParseAllowList([]string{h.Host}); err != nil || strings.Contains(h.Host, "://") {
+				return fmt.Errorf("hosts[%d]: invalid host %q: want a host name, an IP address, *.domain, or *", i, h.Host)
+			}
+		}
+		if h.Delay != nil && *h.Delay < 0 {
+			return fmt.Errorf("hosts[%d]: delay must not be negative, got %s", i, *h.Delay)
+		}
+		h.window = nil
+		if h.Window != "" {
+			w, err := parseTimeWindow(h.Window)
+			if err != nil {
+				return fmt.Errorf("hosts[%d]: %w", i, err)
+			}
+			h.window = w
+		}
+		if strings.ContainsAny(h.UserAgentSuffix, "\r\n") {
+			return fmt.Errorf("hosts[%d]: user_agent_suffix must be a single line", i)
+		}
+	}
+	return nil
+}
+
+// For returns the settings of host, with or without a port: those of the
+// first entry of p matching it, or nil if none does or p is nil.
+func (p *Politeness) For(host string) *HostPoliteness {
+	if p == nil {
+		return nil
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(strings.Trim(host, "[]"), "."))
+	for i := range p.Hosts {
+		pattern := p.Hosts[i].Host
+		if pattern == "*" || pattern == host || (strings.HasPrefix(pattern, "*.") && strings.HasSuffix(host, pattern[1:])) {
+			return &p.Hosts[i]
+		}
+	}
+	return nil
+}
+
+// timeWindow is a time of day window, in minutes since midnight; end is
+// before start for a window spanning midnight.
+type timeWindow struct {
+	start, end int
+}
+
+// parseTimeWindow parses s, a window "HH:MM-HH:MM" such as "09:00-17:30" or
+// "22:00-06:00".
+func parseTimeWindow(s string) (*timeWindow, error) {
+	from, to, ok := strings.Cut(strings.TrimSpace(s), "-")
+	start, err1 := parseClock(from)
+	end, err2 := parseClock(to)
+	if !ok || err1 != nil || err2 != nil || start == end {
+		return nil, fmt.Errorf("invalid window %q (expected HH:MM-HH:MM, such as 22:00-06:00)", s)
+	}
+	return &timeWindow{start: start, end: end}, nil
+}
+
+// parseClock returns s, a time of day "HH:MM", in minutes since midnight.
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// wait returns the time from now to the opening of w, 0 if now is within it.
+func (w *timeWindow) wait(now time.Time) time.Duration {
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	minute := now.Hour()*60 + now.Minute()
+	if w.start < w.end {
+		if minute >= w.start && minute < w.end {
+			return 0
+		}
+	} else if minute >= w.start || minute < w.end {
+		return 0
+	}
+	open := midnight.Add(time.Duration(w.start) * time.Minute)
+	if !open.After(now) {
+		open = open.AddDate(0, 0, 1)
+	}
+	return open.Sub(now)
+}
+
+// SetPoliteness sets the per-host politeness settings of the crawl: the
+// delay before each page request to a host is that of its settings, if they
+// have one. The windows and User-Agent suffixes are applied by the transport (see PolitenessTransport). A nil p uses the
+// politeness delay of the fetcher for every host (see SetDelay).
+func (f *Fetcher) SetPoliteness(p *Politeness) {
+	f.politeness = p
+}
+
+// politeDelay returns the politeness delay before a page request to host.
+func (f *Fetcher) politeDelay(host string) time.Duration {
+	if h := f.politeness.For(host); h != nil && h.Delay != nil {
+		return *h.Delay
+	}
+	return f.delay
+}
+
+// PolitenessTransport returns a transport sending the requests of base with
+// the settings of p for their host: a request outside the window of its host
+// waits for it to open, and the UserAgentSuffix is appended to the
+// User-Agent. Requests to the other hosts are sent as they are.
+func PolitenessTransport(base http.RoundTripper, p *Politeness) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &politenessTransport{base: base, politeness: p}
+}
+
+// politenessTransport is the transport returned by PolitenessTransport.
+type politenessTransport struct {
+	base       http.RoundTripper
+	politeness *Politeness
+	// now returns the current time; nil uses time.Now
+	now func() time.Time
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *politenessTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	h := t.politeness.For(req.URL.Host)
+	if h == nil {
+		return t.base.RoundTrip(req)
+	}
+	ctx := req.Context()
+	if h.window != nil {
+		now := time.Now()
+		if t.now != nil {
+			now = t.now()
+		}
+		if wait := h.window.wait(now); wait > 0 {
+			slog.Info("Waiting for the crawl window of the host", "host", req.URL.Hostname(), "window", h.Window, "wait", wait.Round(time.Second))
+			if err := sleep(ctx, wait); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if h.UserAgentSuffix != "" {
+		req = req.Clone(ctx)
+		req.Header.Set("User-Agent", strings.TrimSpace(req.Header.Get("User-Agent")+" "+h.UserAgentSuffix))
+	}
+	return t.base.RoundTrip(req)
+}
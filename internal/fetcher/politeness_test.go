@@ -0,0 +1,184 @@
+package fetcher
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePoliteness(t *testing.T) {
+	p, err := ParsePoliteness([]byte(`hosts:
+  - host: Staging.Example.com
+    delay: 0s
+  - host: "*.example.org"
+    delay: 5s
+    window: "22:00-06:00"
+    user_agent_suffix: docs-team@example.com
+  - host: "*"
+    user_agent_suffix: ops@example.com
+`))
+	if err != nil {
+		t.Fatalf("ParsePoliteness() returned error: %v", err)
+	}
+	tests := []struct {
+		host      string
+		want      string
+		wantDelay time.Duration
+	}{
+		{host: "staging.example.com:8443", want: "staging.example.com", wantDelay: 0},
+		{host: "docs.example.org", want: "*.example.org", wantDelay: 5 * time.Second},
+		{host: "example.org", want: "*"},
+		{host: "other.example.com", want: "*"},
+	}
+	for _, tt := range tests {
+		h := p.For(tt.host)
+		if h == nil || h.Host != tt.want {
+			t.Errorf("For(%q) = %+v, want the entry %q", tt.host, h, tt.want)
+			continue
+		}
+		if h.Delay != nil && *h.Delay != tt.wantDelay {
+			t.Errorf("For(%q).Delay = %s, want %s", tt.host, *h.Delay, tt.wantDelay)
+		}
+	}
+	if p.For("other.example.com").Delay != nil {
+		t.Error("an entry without a delay has one")
+	}
+	if (*Politeness)(nil).For("example.com") != nil {
+		t.Error("For() of nil settings returned an entry")
+	}
+
+	for _, invalid := range []string{
+		"hosts:\n  - host: docs.example.com\n    delay: soon\n",
+		"hosts:\n  - host: docs.example.com\n    delay: -1s\n",
+		"hosts:\n  - host: https://docs.example.com/\n",
+		"hosts:\n  - host: docs.example.com\n    window: nights\n",
+		"hosts:\n  - host: docs.example.com\n    window: \"06:00-06:00\"\n",
+		"hosts:\n  - host: docs.example.com\n    rate: 5\n",
+	} {
+		if _, err := ParsePoliteness([]byte(invalid)); err == nil {
+			t.Errorf("ParsePoliteness() accepted %q", invalid)
+		}
+	}
+	if _, err := ParsePoliteness([]byte("hosts:\n  - host: docs.example.com\n    max_concurrency: 1\n")); err == nil || !strings.Contains(err.Error(), "max_concurrency is not supported") {
+		t.Errorf("ParsePoliteness() of a concurrency limit = %v, want an error explaining it isn't supported", err)
+	}
+}
+
+func TestLoadPoliteness(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "politeness.yaml")
+	if err := os.WriteFile(path, []byte("hosts:\n  - host: docs.example.com\n    delay: 2s\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := LoadPoliteness(path)
+	if err != nil || len(p.Hosts) != 1 || *p.Hosts[0].Delay != 2*time.Second {
+		t.Errorf("LoadPoliteness() = %+v, %v", p, err)
+	}
+	if _, err := LoadPoliteness(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("LoadPoliteness() of a missing file returned no error")
+	}
+}
+
+func TestTimeWindowWait(t *testing.T) {
+	day := func(hour, minute int) time.Time { return time.Date(2024, 5, 1, hour, minute, 0, 0, time.UTC) }
+	tests := []struct {
+		window string
+		now    time.Time
+		want   time.Duration
+	}{
+		{window: "09:00-17:00", now: day(12, 0), want: 0},
+		{window: "09:00-17:00", now: day(8, 30), want: 30 * time.Minute},
+		{window: "09:00-17:00", now: day(17, 0), want: 16 * time.Hour},
+		{window: "22:00-06:00", now: day(23, 0), want: 0},
+		{window: "22:00-06:00", now: day(5, 59), want: 0},
+		{window: "22:00-06:00", now: day(6, 0), want: 16 * time.Hour},
+	}
+	for _, tt := range tests {
+		w, err := parseTimeWindow(tt.window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := w.wait(tt.now); got != tt.want {
+			t.Errorf("%s at %s: wait() = %s, want %s", tt.window, tt.now.Format("15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestPolitenessTransport(t *testing.T) {
+	var userAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	p, err := ParsePoliteness([]byte("hosts:\n  - host: 127.0.0.1\n    user_agent_suffix: ops@example.com\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: PolitenessTransport(nil, p)}
+	req, _ := http.NewRequest("GET", server.URL+"/page", nil)
+	req.Header.Set("User-Agent", "test-bot")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if userAgent != "test-bot ops@example.com" {
+		t.Errorf("User-Agent = %q, want the suffix appended", userAgent)
+	}
+}
+
+func TestPolitenessTransportWindow(t *testing.T) {
+	p, err := ParsePoliteness([]byte("hosts:\n  - host: \"*\"\n    window: \"09:00-17:00\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := PolitenessTransport(http.DefaultTransport, p).(*politenessTransport)
+	tr.now = func() time.Time { return time.Date(2024, 5, 1, 8, 0, 0, 0, time.Local) }
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", "http://docs.example.com/", nil)
+	if _, err := tr.RoundTrip(req); err != context.DeadlineExceeded {
+		t.Errorf("RoundTrip() outside the window = %v, want it to wait until the context is done", err)
+	}
+}
+
+func TestFetchPolitenessDelay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="/a">A</a><a href="/b">B</a></body></html>`))
+	}))
+	defer server.Close()
+
+	p, err := ParsePoliteness([]byte("hosts:\n  - host: 127.0.0.1\n    delay: 0s\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := New(t.TempDir())
+	f.delay = time.Hour
+	f.SetPoliteness(p)
+	if f.politeDelay("other.example.com") != time.Hour {
+		t.Error("a host without settings doesn't get the delay of the fetcher")
+	}
+	start := time.Now()
+	if err := f.Fetch(server.URL + "/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Fetch() took %s, want the delay of the host's settings", elapsed)
+	}
+	if saved := f.Report().Summary[OutcomeSaved]; saved != 3 {
+		t.Errorf("%d pages saved, want 3", saved)
+	}
+}
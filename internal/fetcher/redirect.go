@@ -0,0 +1,150 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the handling of redirects: the depth of redirect
+// chains is capped, loops are detected, and a redirected page is known by
+// its final URL, so that the same page isn't fetched under two names.
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+)
+
+// DefaultMaxRedirects is the number of redirects followed per request by default.
+const DefaultMaxRedirects = 10
+
+// ErrRedirectLoop is returned, wrapped, by requests whose redirects lead back
+// to a URL already requested.
+var ErrRedirectLoop = errors.New("redirect loop")
+
+// ErrTooManyRedirects is returned, wrapped, by requests redirected more times
+// than allowed (see SetMaxRedirects).
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// Reasons of the records of redirected pages.
+const (
+	// ReasonRedirectLoop is the Reason of the pages that failed because their
+	// redirects loop.
+	ReasonRedirectLoop = "redirect_loop"
+	// ReasonTooManyRedirects is the Reason of the pages that failed because
+	// they were redirected more times than allowed.
+	ReasonTooManyRedirects = "too_many_redirects"
+	// ReasonRedirectOutOfDomain is the Reason of the pages skipped because
+	// they redirect to another host.
+	ReasonRedirectOutOfDomain = "redirect_out_of_domain"
+	// ReasonDuplicateRedirect is the Reason of the pages skipped because they
+	// redirect to a page already crawled.
+	ReasonDuplicateRedirect = "duplicate_redirect"
+)
+
+// SetMaxRedirects sets the number of redirects followed per request, by
+// default DefaultMaxRedirects. A request redirected more times fails with
+// ErrTooManyRedirects, and the page with the reason ReasonTooManyRedirects.
+// n <= 0 restores the default.
+func (f *Fetcher) SetMaxRedirects(n int) {
+	if n <= 0 {
+		n = DefaultMaxRedirects
+	}
+	f.maxRedirects = n
+}
+
+// checkRedirect is the CheckRedirect function of the HTTP clients of the
+// fetcher: it stops at redirect loops and after maxRedirects redirects, and
+// removes the extra headers of SetHeaders from the requests redirected to
+// another host. Go's client only drops Authorization, WWW-Authenticate, and
+// cookies there; custom credential headers such as X-API-Key would otherwise
+// be sent to whichever host a page redirects to.
+func (f *Fetcher) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > 0 && req.URL.Host != via[0].URL.Host {
+		for name := range f.headers {
+			req.Header.Del(name)
+		}
+	}
+	next := req.URL.String()
+	for _, prev := range via {
+		if prev.URL.String() == next {
+			return fmt.Errorf("%w: %s redirects back to %s", ErrRedirectLoop, via[len(via)-1].URL, next)
+		}
+	}
+	if len(via) > f.maxRedirects {
+		return fmt.Errorf("%w: stopped after %d", ErrTooManyRedirects, f.maxRedirects)
+	}
+	return nil
+}
+
+// redirectFailure records rec, whose request failed with err, with the reason
+// the redirects of the request failed, and reports whether they did.
+func (f *Fetcher) redirectFailure(rec *PageRecord, err error) bool {
+	switch {
+	case errors.Is(err, ErrRedirectLoop):
+		rec.Reason = ReasonRedirectLoop
+	case errors.Is(err, ErrTooManyRedirects):
+		rec.Reason = ReasonTooManyRedirects
+	default:
+		return false
+	}
+	warnlog.Printf("redirect", "Warning: failed to fetch %s: %v", rec.URL, err)
+	rec.Error = err.Error()
+	f.record(*rec)
+	return true
+}
+
+// claimRedirect claims finalURL, the URL targetURL at depth was redirected
+// to, as the identity of the page: the crawl won't fetch it again, nor, in
+// locale priority mode, any locale variant of it. When the start URL
+// redirects to another host (e.g., example.com to www.example.com), the crawl
+// is restricted to that host instead.
+//
+// Returns the reason to skip the page, or "": the page redirects out of the
+// domain (ReasonRedirectOutOfDomain), or to a page already crawled
+// (ReasonDuplicateRedirect).
+func (f *Fetcher) claimRedirect(targetURL, finalURL string, depth int) string {
+	u, err := url.Parse(finalURL)
+	if err != nil {
+		return ""
+	}
+	if u.Host != f.domain && !f.feed {
+		if depth > 0 {
+			return ReasonRedirectOutOfDomain
+		}
+		slog.Info("Start URL redirects to another host; restricting the crawl to it", "url", targetURL, "final_url", finalURL)
+		f.domain = u.Host
+		f.setRobotsBasePath(u)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.localeConfig != nil {
+		_, canonical := ExtractLocale(u, f.localeConfig)
+		if from, err := url.Parse(targetURL); err == nil {
+			if _, fromCanonical := ExtractLocale(from, f.localeConfig); fromCanonical == canonical {
+				// Another locale variant of the same page
+				return ""
+			}
+		}
+		if f.visitedCanonical[canonical] {
+			return ReasonDuplicateRedirect
+		}
+		f.visitedCanonical[canonical] = true
+		return ""
+	}
+	if f.visited[finalURL] {
+		return ReasonDuplicateRedirect
+	}
+	f.visited[finalURL] = true
+	return ""
+}
+
+// redirectLocale returns the locale of finalURL, the URL a locale candidate
+// was redirected to, normalized, or "" if it has none.
+func (f *Fetcher) redirectLocale(finalURL *url.URL) string {
+	locale, _ := ExtractLocale(finalURL, f.localeConfig)
+	if locale == "" {
+		return ""
+	}
+	return f.localeConfig.NormalizeLocale(locale)
+}
@@ -0,0 +1,274 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFetchRedirects(t *testing.T) {
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>Elsewhere</body></html>`))
+	}))
+	defer external.Close()
+
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests[r.URL.Path]++
+		mu.Unlock()
+		switch {
+		case r.URL.Path == "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><a href="/old">Old</a><a href="/new">New</a><a href="/moved">Moved</a>
+<a href="/loop/a">Loop</a><a href="/chain/1">Chain</a><a href="/away">Away</a></body></html>`))
+		case r.URL.Path == "/old", r.URL.Path == "/moved":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		case r.URL.Path == "/new":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><a href="sub">Relative to the final URL</a></body></html>`))
+		case r.URL.Path == "/sub":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body>Sub</body></html>`))
+		case r.URL.Path == "/loop/a":
+			http.Redirect(w, r, "/loop/b", http.StatusFound)
+		case r.URL.Path == "/loop/b":
+			http.Redirect(w, r, "/loop/a", http.StatusFound)
+		case strings.HasPrefix(r.URL.Path, "/chain/"):
+			http.Redirect(w, r, r.URL.Path+"0", http.StatusFound)
+		case r.URL.Path == "/away":
+			http.Redirect(w, r, external.URL+"/page", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	f := New(dir)
+	f.delay = 0
+	f.SetMaxRedirects(3)
+	if err := f.Fetch(server.URL + "/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+
+	outcomes := make(map[string]string)
+	for _, rec := range f.Report().Pages {
+		outcome := string(rec.Outcome)
+		if rec.Reason != "" {
+			outcome += ":" + rec.Reason
+		}
+		outcomes[strings.TrimPrefix(rec.URL, server.URL)] = outcome
+	}
+	want := map[string]string{
+		"/old":     "saved",
+		"/moved":   "skipped:" + ReasonDuplicateRedirect,
+		"/sub":     "saved",
+		"/loop/a":  "failed:" + ReasonRedirectLoop,
+		"/chain/1": "failed:" + ReasonTooManyRedirects,
+		"/away":    "skipped:" + ReasonRedirectOutOfDomain,
+	}
+	for u, w := range want {
+		if outcomes[u] != w {
+			t.Errorf("%s = %q, want %q (all: %v)", u, outcomes[u], w, outcomes)
+		}
+	}
+	if _, ok := outcomes["/new"]; ok {
+		t.Errorf("/new crawled again after /old redirected to it: %v", outcomes)
+	}
+	if requests["/new"] != 2 {
+		t.Errorf("/new requested %d times, want 2 (through /old and /moved)", requests["/new"])
+	}
+	if requests["/chain/10000"] != 0 || requests["/chain/1000"] != 1 {
+		t.Errorf("redirect chain followed past the limit: %v", requests)
+	}
+
+	host := strings.TrimPrefix(server.URL, "http://")
+	if _, err := os.Stat(filepath.Join(dir, "crawl", host, "new.html")); err != nil {
+		t.Errorf("redirected page not saved under its final URL: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "crawl", host, "old.html")); err == nil {
+		t.Error("redirected page saved under the URL it was found at")
+	}
+}
+
+func TestFetchStartURLRedirectsToAnotherHost(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/docs/":
+			w.Write([]byte(`<html><body><a href="/docs/guide">Guide</a></body></html>`))
+		case "/docs/guide":
+			w.Write([]byte(`<html><body>Guide</body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer target.Close()
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, target.URL+r.URL.Path, http.StatusMovedPermanently)
+	}))
+	defer origin.Close()
+
+	f := New(t.TempDir())
+	f.delay = 0
+	if err := f.Fetch(origin.URL + "/docs/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+	saved := make(map[string]bool)
+	for _, rec := range f.Report().Pages {
+		saved[rec.URL] = rec.Outcome == OutcomeSaved
+	}
+	if !saved[origin.URL+"/docs/"] || !saved[target.URL+"/docs/guide"] {
+		t.Errorf("pages of the host the start URL redirects to not saved: %+v", f.Report().Pages)
+	}
+}
+
+func TestFetchLocaleRedirect(t *testing.T) {
+	// The site redirects every page to its Japanese variant, as geo-IP based
+	// sites do: /en/guide isn't the English variant, and the pages without a
+	// locale are the Japanese ones.
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", http.NotFound)
+	for _, path := range []string{"/en/guide", "/guide"} {
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, "/ja/guide", http.StatusFound)
+		})
+	}
+	mux.HandleFunc("/ja/guide", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><p>ガイド</p></body></html>`))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		http.Redirect(w, r, "/ja/", http.StatusFound)
+	})
+	mux.HandleFunc("/ja/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ja/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="/en/guide">Guide</a></body></html>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	f := New(t.TempDir())
+	f.delay = 0
+	f.SetLocaleConfig(&LocaleConfig{Priority: []string{"en", "fr"}})
+	if err := f.Fetch(server.URL + "/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+
+	byURL := make(map[string]PageRecord)
+	for _, rec := range f.Report().Pages {
+		byURL[strings.TrimPrefix(rec.URL, server.URL)] = rec
+	}
+	if root := byURL["/"]; root.Outcome != OutcomeSaved || root.Locale != "ja" {
+		t.Errorf("/ = %s with locale %q, want saved with locale ja", root.Outcome, root.Locale)
+	}
+	guide := byURL["/en/guide"]
+	if guide.Outcome != OutcomeSaved || guide.Locale != "ja" || guide.FinalURL != server.URL+"/ja/guide" {
+		t.Errorf("/en/guide = %+v, want saved from /ja/guide with locale ja", guide)
+	}
+}
+
+func TestFetchLocaleRedirectSavedUnderFinalURL(t *testing.T) {
+	// With locale priority too, a page redirected to another path is saved
+	// under its final URL, so that /guide/index.html isn't saved as a page
+	// named index
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", http.NotFound)
+	mux.HandleFunc("/guide/index.html", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/guide/", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/guide/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><p>Guide</p></body></html>`))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="/guide/index.html">Guide</a></body></html>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	f := New(t.TempDir())
+	f.delay = 0
+	f.SetLocaleConfig(&LocaleConfig{Priority: []string{"en"}})
+	if err := f.Fetch(server.URL + "/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+
+	for _, rec := range f.Report().Pages {
+		if rec.URL != server.URL+"/guide/index.html" {
+			continue
+		}
+		host := strings.TrimPrefix(server.URL, "http://")
+		if rec.Outcome != OutcomeSaved || rec.OutputFile != "crawl/"+host+"/guide.html" {
+			t.Errorf("/guide/index.html = %s as %q, want saved as crawl/%s/guide.html", rec.Outcome, rec.OutputFile, host)
+		}
+		return
+	}
+	t.Errorf("/guide/index.html wasn't crawled")
+}
+
+func TestFetchRedirectDropsHeaders(t *testing.T) {
+	var mu sync.Mutex
+	leaked := make(map[string]string)
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		for _, name := range []string{"X-Api-Key", "Private-Token"} {
+			if v := r.Header.Get(name); v != "" {
+				leaked[name] = v
+			}
+		}
+		mu.Unlock()
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>Elsewhere</body></html>`))
+	}))
+	defer external.Close()
+	var gotKey string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			mu.Lock()
+			gotKey = r.Header.Get("X-Api-Key")
+			mu.Unlock()
+			w.Write([]byte(`<html><body><a href="/away">Away</a></body></html>`))
+		case "/away":
+			http.Redirect(w, r, external.URL+"/page", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	f := New(t.TempDir())
+	f.delay = 0
+	f.SetHeaders(http.Header{"X-Api-Key": {"secret"}, "Private-Token": {"token"}})
+	if err := f.Fetch(server.URL + "/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+	if gotKey != "secret" {
+		t.Errorf("X-Api-Key = %q on the site, want the configured header", gotKey)
+	}
+	if len(leaked) > 0 {
+		t.Errorf("headers sent to the host redirected to: %v", leaked)
+	}
+}
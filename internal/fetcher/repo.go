@@ -0,0 +1,121 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements repository mode, which reads the Markdown sources of a
+// GitHub or GitLab repository instead of crawling its rendered pages.
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/repo"
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+)
+
+// FetchRepo reads the documentation of the repository r into the crawl
+// directory: its README, the Markdown files under docs/, and the pages of its
+// wiki (see repo.Checkout.Files). The repository and its wiki are
+// shallow-cloned into the "git" directory of the output directory, even in
+// dry-run mode, with the headers set with SetHeaders.
+//
+// Each file is recorded under its web URL (e.g.,
+// "https://github.com/acme/widgets/blob/main/docs/install.md", or
+// ".../wiki/Install" for a wiki page) and saved at the path of that URL in
+// the crawl directory, with the extension .md. A README or
+// index file under docs/ is saved as its directory (docs/guide/README.md as
+// docs/guide.md), like a page whose URL ends with a slash. The URL filters
+// and path patterns apply to the web URLs.
+//
+// Returns an error if the repository can't be cloned.
+func (f *Fetcher) FetchRepo(r *repo.Repo) error {
+	return f.FetchRepoContext(context.Background(), r)
+}
+
+// FetchRepoContext is like FetchRepo but stops when ctx is cancelled or its
+// deadline expires, like FetchContext.
+func (f *Fetcher) FetchRepoContext(ctx context.Context, r *repo.Repo) error {
+	_, crawlDir, err := f.begin(r.URL)
+	if err != nil {
+		return err
+	}
+	return f.end(ctx, f.crawlRepo(ctx, r, crawlDir))
+}
+
+// crawlRepo clones r and saves its Markdown files in crawlDir.
+func (f *Fetcher) crawlRepo(ctx context.Context, r *repo.Repo, crawlDir string) error {
+	checkout, err := repo.Clone(ctx, r, filepath.Join(f.outputDir, "git"), f.headers)
+	if err != nil {
+		return err
+	}
+	slog.Info("Cloned repository", "url", r.URL, "ref", checkout.Ref, "commit", checkout.Commit, "wiki", checkout.WikiDir != "")
+	files, err := checkout.Files()
+	if err != nil {
+		return err
+	}
+
+	f.queue(len(files))
+	for _, file := range files {
+		f.queue(-1)
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		depth := 1
+		if file.Kind == repo.KindReadme {
+			depth = 0
+		}
+		if !f.shouldCrawlURL(file.URL, depth) {
+			f.recordSkip(file.URL, depth, "url_filter")
+			continue
+		}
+		f.mu.Lock()
+		seen := f.visited[file.URL]
+		f.visited[file.URL] = true
+		f.mu.Unlock()
+		if seen {
+			continue
+		}
+
+		rec := PageRecord{URL: file.URL, Depth: depth, ContentType: "text/markdown; charset=utf-8", Outcome: OutcomeFailed}
+		body, err := os.ReadFile(file.Local)
+		if err != nil {
+			warnlog.Printf("read_error", "Warning: failed to read %s: %v", file.Local, err)
+			rec.Reason = ReasonReadError
+			rec.Error = err.Error()
+			f.record(rec)
+			continue
+		}
+		rec.Bytes = int64(len(body))
+		filePath, err := repoFilePath(crawlDir, file)
+		if err != nil {
+			f.recordSkip(file.URL, depth, "invalid_url")
+			continue
+		}
+		if f.save(&rec, file.URL, filePath, body) {
+			f.downloadCount++
+		}
+	}
+	return nil
+}
+
+// repoFilePath returns the path in crawlDir of the Markdown file of file (see
+// FetchRepo).
+func repoFilePath(crawlDir string, file repo.File) (string, error) {
+	u, err := url.Parse(file.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %s: %w", file.URL, err)
+	}
+	p := u.Path
+	if name := strings.ToLower(strings.TrimSuffix(path.Base(p), path.Ext(p))); file.Kind == repo.KindDocs && (name == "readme" || name == "index") {
+		p = path.Dir(p)
+	}
+	if ext := strings.ToLower(path.Ext(p)); ext == ".md" || ext == ".markdown" {
+		p = strings.TrimSuffix(p, path.Ext(p))
+	}
+	p += ".md"
+	return filepath.Join(crawlDir, u.Host, filepath.FromSlash(p)), nil
+}
@@ -0,0 +1,79 @@
+package fetcher
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/f4ah6o/site2skill-go/internal/repo"
+)
+
+func TestFetchRepo(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	src := t.TempDir()
+	for name, content := range map[string]string{
+		"README.md":             "# Widgets\n",
+		"docs/install.markdown": "# Install\n",
+		"docs/guide/README.md":  "# Guide\n",
+		"docs/internal.md":      "# Internal\n",
+	} {
+		p := filepath.Join(src, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch", "main"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "docs"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = src
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	dir := t.TempDir()
+	f := New(dir)
+	f.SetProgressReporter(nil)
+	f.SetURLFilters(nil, []string{"internal"})
+	r := &repo.Repo{Host: repo.GitHub, URL: "https://github.com/acme/widgets", CloneURL: "file://" + src}
+	if err := f.FetchRepo(r); err != nil {
+		t.Fatalf("FetchRepo() error = %v", err)
+	}
+
+	blob := "https://github.com/acme/widgets/blob/main/"
+	want := map[string]string{
+		blob + "README.md":             "crawl/github.com/acme/widgets/blob/main/README.md",
+		blob + "docs/install.markdown": "crawl/github.com/acme/widgets/blob/main/docs/install.md",
+		blob + "docs/guide/README.md":  "crawl/github.com/acme/widgets/blob/main/docs/guide.md",
+	}
+	saved := f.Report().SavedPages()
+	if len(saved) != len(want) {
+		t.Errorf("saved %d files, want %d: %v", len(saved), len(want), saved)
+	}
+	for u, file := range want {
+		rec, ok := saved[file]
+		if !ok || rec.URL != u {
+			t.Errorf("%s not saved as %s (saved: %v)", u, file, saved)
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil || !strings.HasPrefix(string(content), "# ") {
+			t.Errorf("%s = %q, %v", file, content, err)
+		}
+	}
+	for _, rec := range f.Report().Pages {
+		if strings.HasSuffix(rec.URL, "internal.md") && rec.Reason != "url_filter" {
+			t.Errorf("excluded file recorded as %s %s", rec.Outcome, rec.Reason)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the machine-readable crawl report.
+
+package fetcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+	"github.com/f4ah6o/site2skill-go/internal/httpcache"
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+)
+
+// Outcome classifies what happened to a URL during a crawl.
+type Outcome string
+
+const (
+	// OutcomeSaved means the page was downloaded and written to the crawl directory.
+	OutcomeSaved Outcome = "saved"
+	// OutcomePlanned means the page was downloaded in dry-run mode and would
+	// have been written to OutputFile (see Fetcher.SetDryRun).
+	OutcomePlanned Outcome = "planned"
+	// OutcomeSkipped means the URL was deliberately not fetched or not saved
+	// (out of scope, filtered, non-HTML, ...). Reason explains why.
+	OutcomeSkipped Outcome = "skipped"
+	// OutcomeBlocked means robots.txt disallowed the URL.
+	OutcomeBlocked Outcome = "blocked"
+	// OutcomeFailed means a network, HTTP, or I/O error prevented saving the page.
+	OutcomeFailed Outcome = "failed"
+)
+
+// PageRecord describes the outcome of a single URL encountered during the crawl.
+type PageRecord struct {
+	// URL is the URL as discovered (after resolving relative links).
+	URL string `json:"url"`
+	// FetchedURL is the URL actually requested when it differs from URL
+	// (e.g., a locale variant chosen in locale priority mode).
+	FetchedURL string `json:"fetched_url,omitempty"`
+	// FinalURL is the URL of the final response after following redirects.
+	FinalURL string `json:"final_url,omitempty"`
+	// RedirectChain lists every URL that answered with a redirect, in order.
+	RedirectChain []string `json:"redirect_chain,omitempty"`
+	// RefreshURL is the URL the page redirects to with a meta refresh, which
+	// is crawled in its place (see Fetcher.SetFollowMetaRefresh).
+	RefreshURL string `json:"refresh_url,omitempty"`
+	// Canonical is the URL the page declares canonical with <link rel="canonical">,
+	// when it differs from the URL fetched. A page with an in-scope canonical URL
+	// is saved under that URL.
+	Canonical string `json:"canonical,omitempty"`
+	// Depth is the link depth from the start URL.
+	Depth int `json:"depth"`
+	// SeriesOf is the URL of the first page of the paginated series the page
+	// continues, found with rel="next" links or ?page=N pagination; empty for
+	// the first page and for other pages.
+	SeriesOf string `json:"series_of,omitempty"`
+	// PageNumber is the number of the page in the series of SeriesOf, from 2.
+	PageNumber int `json:"page_number,omitempty"`
+	// Seed is the start URL the page was reached from when several are
+	// crawled together (see Fetcher.FetchSeeds); empty otherwise.
+	Seed string `json:"seed,omitempty"`
+	// Sitemap is the URL of the sitemap the page was listed in, for pages
+	// discovered from sitemaps rather than links (see Fetcher.SetSitemaps).
+	Sitemap string `json:"sitemap,omitempty"`
+	// StatusCode is the HTTP status of the final response (0 if no response was received).
+	StatusCode int `json:"status_code,omitempty"`
+	// ContentType is the Content-Type header of the final response.
+	ContentType string `json:"content_type,omitempty"`
+	// ContentLanguage is the Content-Language header of the final response.
+	ContentLanguage string `json:"content_language,omitempty"`
+	// LastModified is the Last-Modified header of the final response, as an RFC
+	// 3339 time; empty when the server sent none or an invalid one.
+	LastModified string `json:"last_modified,omitempty"`
+	// Author is the author of the feed entry of the page in feed mode (see
+	// Fetcher.FetchFeed); empty otherwise.
+	Author string `json:"author,omitempty"`
+	// Published is when the feed entry of the page was published in feed
+	// mode, as an RFC 3339 time; empty otherwise or if the feed doesn't say.
+	Published string `json:"published,omitempty"`
+	// Charset is the character encoding the page was converted to UTF-8 from
+	// (e.g., "shift_jis"); empty when it was served in UTF-8.
+	Charset string `json:"charset,omitempty"`
+	// Interstitial is the kind of interstitial the page looks like
+	// (ReasonConsentWall or ReasonJavaScriptRequired), whether it was skipped
+	// or kept (see Fetcher.SetKeepInterstitials); empty for content.
+	Interstitial string `json:"interstitial,omitempty"`
+	// NoIndex and NoFollow are set when the robots directives of the page
+	// (<meta name="robots">, X-Robots-Tag) say noindex or nofollow: a noindex
+	// page is skipped unless kept (see Fetcher.SetKeepNoIndex), and the links
+	// of a nofollow page aren't followed.
+	NoIndex  bool `json:"noindex,omitempty"`
+	NoFollow bool `json:"nofollow,omitempty"`
+	// Bytes is the size of the response body that was read.
+	Bytes int64 `json:"bytes,omitempty"`
+	// Locale is the locale actually served for this page in locale priority mode,
+	// after falling back past unavailable variants. Empty when the no-locale URL was used.
+	Locale string `json:"locale,omitempty"`
+	// LocaleMatch explains how Locale matched the locale priority, e.g.
+	// "en-au (range match for en)" (see LocaleChoice).
+	LocaleMatch string `json:"locale_match,omitempty"`
+	// Negotiated is set when the response varied on the Accept-Language
+	// header of the request, so that the server chose its language.
+	Negotiated bool `json:"negotiated,omitempty"`
+	// Version is the documentation version of the page when version selection is enabled.
+	Version string `json:"version,omitempty"`
+	// OutputFile is the path of the saved HTML file, or of the file a planned page
+	// would be saved to, relative to the fetcher output directory.
+	OutputFile string `json:"output_file,omitempty"`
+	// Cache reports how the HTTP cache served the page ("hit", "revalidated", "miss").
+	Cache string `json:"cache,omitempty"`
+	// Outcome classifies the result.
+	Outcome Outcome `json:"outcome"`
+	// Reason is a short machine-friendly explanation for skipped and blocked
+	// URLs, and classifies the error of failed ones (e.g., ReasonDNS,
+	// ReasonTimeout, ReasonHTTPServerError). Err maps it to a typed error.
+	Reason string `json:"reason,omitempty"`
+	// Error holds the error message for failed URLs.
+	Error string `json:"error,omitempty"`
+}
+
+// CrawlReport is the machine-readable record of a crawl, written as crawl-report.json.
+type CrawlReport struct {
+	// StartURL is the URL the crawl started from.
+	StartURL string `json:"start_url"`
+	// Seeds lists every start URL of a crawl from several (see
+	// Fetcher.FetchSeeds), StartURL first; empty for a single start URL.
+	Seeds []string `json:"seeds,omitempty"`
+	// RunID is the identifier of the run the crawl belongs to (see
+	// Fetcher.SetRunID); empty if it wasn't set.
+	RunID string `json:"run_id,omitempty"`
+	// UserAgent is the User-Agent of the page requests of the crawl.
+	UserAgent string `json:"user_agent,omitempty"`
+	// StartedAt is when the crawl began.
+	StartedAt time.Time `json:"started_at"`
+	// FinishedAt is when the crawl ended.
+	FinishedAt time.Time `json:"finished_at"`
+	// Interrupted reports whether the crawl was cancelled before it was done,
+	// in which case Pages lists only the URLs settled until then.
+	Interrupted bool `json:"interrupted,omitempty"`
+	// SizeBudgetExceeded reports whether the crawl stopped at its total size
+	// budget (see Fetcher.SetSizeLimits), in which case Pages lists only the
+	// URLs settled until then.
+	SizeBudgetExceeded bool `json:"size_budget_exceeded,omitempty"`
+	// Summary counts pages per outcome.
+	Summary map[Outcome]int `json:"summary"`
+	// Pages lists every URL encountered, in the order first seen.
+	Pages []PageRecord `json:"pages"`
+	// Warnings lists every warning logged during the run, including those
+	// collapsed into summaries on the console.
+	Warnings []warnlog.Record `json:"warnings,omitempty"`
+	// Throttles lists every slowdown and recovery of a host by the adaptive
+	// throttle (see Fetcher.SetAdaptiveThrottle), in order.
+	Throttles []ThrottleEvent `json:"throttles,omitempty"`
+
+	mu    sync.Mutex
+	index map[string]int
+}
+
+// newCrawlReport creates an empty report for a crawl starting at startURL.
+func newCrawlReport(startURL string, startedAt time.Time) *CrawlReport {
+	return &CrawlReport{
+		StartURL:  startURL,
+		StartedAt: startedAt,
+		Summary:   make(map[Outcome]int),
+		index:     make(map[string]int),
+	}
+}
+
+// add records rec. Only the first record for a URL is kept, except that a
+// later saved, planned, or failed outcome replaces an earlier skip, since it carries more detail.
+func (r *CrawlReport) add(rec PageRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if i, ok := r.index[rec.URL]; ok {
+		prev := r.Pages[i].Outcome
+		if prev == OutcomeSkipped && rec.Outcome != OutcomeSkipped && rec.Outcome != OutcomeBlocked {
+			r.Summary[prev]--
+			r.Summary[rec.Outcome]++
+			r.Pages[i] = rec
+		}
+		return
+	}
+
+	r.index[rec.URL] = len(r.Pages)
+	r.Pages = append(r.Pages, rec)
+	r.Summary[rec.Outcome]++
+}
+
+// addThrottle records ev.
+func (r *CrawlReport) addThrottle(ev ThrottleEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.Throttles = append(r.Throttles, ev)
+}
+
+// has reports whether the report has a record of rawURL.
+func (r *CrawlReport) has(rawURL string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.index[rawURL]
+	return ok
+}
+
+// counts returns a copy of the summary.
+func (r *CrawlReport) counts() map[Outcome]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return maps.Clone(r.Summary)
+}
+
+// WriteJSON writes the report as indented JSON to path, creating parent directories as needed.
+func (r *CrawlReport) WriteJSON(path string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create report directory: %w", err)
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode crawl report: %w", err)
+	}
+	if _, err := atomicfile.WriteFile(path, append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write crawl report: %w", err)
+	}
+	return nil
+}
+
+// LoadCrawlReport reads a report previously written by WriteJSON.
+func LoadCrawlReport(path string) (*CrawlReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read crawl report: %w", err)
+	}
+	r := newCrawlReport("", time.Time{})
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("failed to decode crawl report: %w", err)
+	}
+	for i, p := range r.Pages {
+		r.index[p.URL] = i
+	}
+	return r, nil
+}
+
+// SavedPages returns the saved records keyed by OutputFile, so later pipeline
+// stages can look up the metadata of a crawled HTML file.
+func (r *CrawlReport) SavedPages() map[string]PageRecord {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pages := make(map[string]PageRecord)
+	for _, p := range r.Pages {
+		if p.Outcome == OutcomeSaved && p.OutputFile != "" {
+			pages[p.OutputFile] = p
+		}
+	}
+	return pages
+}
+
+// Outcomes returns the outcome names present in the summary, sorted, for stable log output.
+func (r *CrawlReport) Outcomes() []Outcome {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	outcomes := make([]Outcome, 0, len(r.Summary))
+	for o, n := range r.Summary {
+		if n > 0 {
+			outcomes = append(outcomes, o)
+		}
+	}
+	sort.Slice(outcomes, func(i, j int) bool { return outcomes[i] < outcomes[j] })
+	return outcomes
+}
+
+// responseDetails fills the response-derived fields of rec from resp.
+func responseDetails(rec *PageRecord, resp *http.Response) {
+	rec.StatusCode = resp.StatusCode
+	rec.ContentType = resp.Header.Get("Content-Type")
+	rec.ContentLanguage = resp.Header.Get("Content-Language")
+	if t, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		rec.LastModified = t.UTC().Format(time.RFC3339)
+	}
+	rec.Cache = resp.Header.Get(httpcache.StatusHeader)
+
+	if resp.Request != nil && resp.Request.URL != nil {
+		if final := resp.Request.URL.String(); final != rec.URL && final != rec.FetchedURL {
+			rec.FinalURL = final
+		}
+		rec.RedirectChain = redirectChain(resp)
+	}
+}
+
+// redirectChain reconstructs the URLs that issued redirects before resp was received.
+func redirectChain(resp *http.Response) []string {
+	var chain []string
+	for req := resp.Request; req != nil && req.Response != nil; req = req.Response.Request {
+		if req.Response.Request == nil {
+			break
+		}
+		chain = append([]string{req.Response.Request.URL.String()}, chain...)
+	}
+	return chain
+}
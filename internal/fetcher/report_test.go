@@ -0,0 +1,155 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestSite serves a tiny site: / links to /docs (redirect to /docs/), /missing, /file.pdf,
+// /private (blocked by robots.txt), and an external host.
+func newTestSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><body>
+<a href="/docs">Docs</a>
+<a href="/missing">Missing</a>
+<a href="/file.pdf">PDF</a>
+<a href="/private">Private</a>
+<a href="https://external.example.com/">External</a>
+</body></html>`))
+	})
+	mux.HandleFunc("/docs", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/docs/", http.StatusMovedPermanently)
+	})
+	mux.HandleFunc("/docs/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("Content-Language", "en")
+		w.Header().Set("Last-Modified", "Sun, 24 Dec 2023 10:00:00 GMT")
+		w.Write([]byte(`<html><body><p>Docs</p></body></html>`))
+	})
+	return httptest.NewServer(mux)
+}
+
+func TestFetchWritesReport(t *testing.T) {
+	server := newTestSite(t)
+	defer server.Close()
+
+	outDir := t.TempDir()
+	f := New(outDir)
+	f.delay = 0
+
+	if err := f.Fetch(server.URL + "/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+
+	report := f.Report()
+	if report == nil {
+		t.Fatal("Report() returned nil after Fetch")
+	}
+
+	byURL := make(map[string]PageRecord)
+	for _, p := range report.Pages {
+		byURL[p.URL] = p
+	}
+
+	tests := []struct {
+		url     string
+		outcome Outcome
+		reason  string
+	}{
+		{server.URL + "/", OutcomeSaved, ""},
+		{server.URL + "/docs", OutcomeSaved, ""},
+		{server.URL + "/missing", OutcomeFailed, ReasonHTTPClientError},
+		{server.URL + "/file.pdf", OutcomeSkipped, "non_html_extension"},
+		{server.URL + "/private", OutcomeBlocked, "robots_txt"},
+		{"https://external.example.com/", OutcomeSkipped, "out_of_domain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			rec, ok := byURL[tt.url]
+			if !ok {
+				t.Fatalf("no report entry for %s", tt.url)
+			}
+			if rec.Outcome != tt.outcome {
+				t.Errorf("outcome = %q, want %q", rec.Outcome, tt.outcome)
+			}
+			if rec.Reason != tt.reason {
+				t.Errorf("reason = %q, want %q", rec.Reason, tt.reason)
+			}
+		})
+	}
+
+	docs := byURL[server.URL+"/docs"]
+	if docs.FinalURL != server.URL+"/docs/" {
+		t.Errorf("FinalURL = %q, want %q", docs.FinalURL, server.URL+"/docs/")
+	}
+	if len(docs.RedirectChain) != 1 || docs.RedirectChain[0] != server.URL+"/docs" {
+		t.Errorf("RedirectChain = %v, want [%s/docs]", docs.RedirectChain, server.URL)
+	}
+	if docs.ContentLanguage != "en" {
+		t.Errorf("ContentLanguage = %q, want %q", docs.ContentLanguage, "en")
+	}
+	if docs.LastModified != "2023-12-24T10:00:00Z" {
+		t.Errorf("LastModified = %q, want %q", docs.LastModified, "2023-12-24T10:00:00Z")
+	}
+	if docs.OutputFile == "" || docs.Bytes == 0 || docs.StatusCode != http.StatusOK {
+		t.Errorf("saved record missing details: %+v", docs)
+	}
+	if missing := byURL[server.URL+"/missing"]; missing.StatusCode != http.StatusNotFound || missing.Error == "" {
+		t.Errorf("failed record missing details: %+v", missing)
+	}
+
+	path := filepath.Join(outDir, "crawl-report.json")
+	if err := report.WriteJSON(path); err != nil {
+		t.Fatalf("WriteJSON() returned error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read report: %v", err)
+	}
+	var decoded struct {
+		StartURL string         `json:"start_url"`
+		Summary  map[string]int `json:"summary"`
+		Pages    []PageRecord   `json:"pages"`
+	}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("report is not valid JSON: %v", err)
+	}
+	if decoded.Summary["saved"] != 2 || len(decoded.Pages) != len(report.Pages) {
+		t.Errorf("decoded report summary = %v with %d pages", decoded.Summary, len(decoded.Pages))
+	}
+}
+
+func TestCrawlReportAdd(t *testing.T) {
+	r := newCrawlReport("https://example.com/", time.Now())
+
+	r.add(PageRecord{URL: "https://example.com/a", Outcome: OutcomeSkipped, Reason: "max_depth"})
+	r.add(PageRecord{URL: "https://example.com/a", Outcome: OutcomeSaved})
+	r.add(PageRecord{URL: "https://example.com/a", Outcome: OutcomeSkipped, Reason: "url_filter"})
+	r.add(PageRecord{URL: "https://example.com/b", Outcome: OutcomeBlocked})
+
+	if len(r.Pages) != 2 {
+		t.Fatalf("got %d pages, want 2", len(r.Pages))
+	}
+	if r.Pages[0].Outcome != OutcomeSaved {
+		t.Errorf("first page outcome = %q, want saved to replace earlier skip", r.Pages[0].Outcome)
+	}
+	if r.Summary[OutcomeSaved] != 1 || r.Summary[OutcomeSkipped] != 0 || r.Summary[OutcomeBlocked] != 1 {
+		t.Errorf("summary = %v", r.Summary)
+	}
+}
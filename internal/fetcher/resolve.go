@@ -0,0 +1,108 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements host resolution overrides for crawling staging servers
+// under the production hostname.
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ParseResolve parses a resolve rule in the syntax of curl's --resolve,
+// "host:port:address", into the "host:port" it overrides and the address to
+// connect to instead. The address may carry its own port ("10.0.0.5:8080");
+// otherwise the port of the rule is used. IPv6 addresses are written in
+// brackets ("docs.example.com:443:[::1]").
+//
+// Returns an error if the rule is malformed.
+func ParseResolve(rule string) (string, string, error) {
+	host, rest, ok := strings.Cut(rule, ":")
+	port, address, ok2 := strings.Cut(rest, ":")
+	if !ok || !ok2 || host == "" || port == "" || address == "" {
+		return "", "", fmt.Errorf("invalid resolve rule %q: want host:port:address", rule)
+	}
+	if _, err := net.LookupPort("tcp", port); err != nil {
+		return "", "", fmt.Errorf("invalid resolve rule %q: bad port %q", rule, port)
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(strings.Trim(address, "[]"), port)
+	}
+	return net.JoinHostPort(strings.ToLower(host), port), address, nil
+}
+
+// HostOverride returns the start URL and resolve rule crawling the server of
+// rawURL as host: rawURL with its host replaced by host (and the port of
+// rawURL dropped unless host gives one), and a rule connecting to the host
+// and port of rawURL instead. Requests then carry host in their Host header,
+// and the output refers to the site by host. For example, rawURL
+// "http://10.0.0.5:8080/docs/" with host "docs.example.com" gives
+// "http://docs.example.com/docs/" and "docs.example.com:80:10.0.0.5:8080".
+//
+// Returns an error if rawURL isn't an absolute http(s) URL.
+func HostOverride(rawURL, host string) (string, string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", "", fmt.Errorf("invalid URL %q: must be an absolute http(s) URL", rawURL)
+	}
+	port := u.Port()
+	if port == "" {
+		port = defaultPort(u.Scheme)
+	}
+	address := net.JoinHostPort(u.Hostname(), port)
+
+	u.Host = host
+	overridden := u.Port()
+	if overridden == "" {
+		overridden = defaultPort(u.Scheme)
+	}
+	return u.String(), u.Hostname() + ":" + overridden + ":" + address, nil
+}
+
+// ResolveTransport returns a transport of NewTransport that connects to the
+// addresses of the resolve rules (see ParseResolve) instead of resolving
+// their hosts. Requests keep their URL, so the Host header and the TLS server
+// name are those of the overridden host. Use it as the base transport of the
+// fetcher, or of the HTTP cache wrapping it, so that page, robots.txt, and
+// HEAD probe requests all reach the overriding server.
+//
+// The other hosts, and the hosts of addresses that aren't IP addresses, are
+// resolved through dns (see DNSCache), or by the system resolver if dns is nil.
+// The hosts of the rules are never looked up in dns.
+//
+// Returns an error if a rule is malformed.
+func ResolveTransport(rules []string, dns *DNSCache) (*http.Transport, error) {
+	overrides := make(map[string]string, len(rules))
+	for _, rule := range rules {
+		hostPort, address, err := ParseResolve(rule)
+		if err != nil {
+			return nil, err
+		}
+		overrides[hostPort] = address
+		if dns != nil {
+			host, _, _ := net.SplitHostPort(hostPort)
+			dns.override(host)
+		}
+	}
+
+	t := NewTransport(dns)
+	dial := t.DialContext
+	t.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if address, ok := overrides[strings.ToLower(addr)]; ok {
+			addr = address
+		}
+		return dial(ctx, network, addr)
+	}
+	return t, nil
+}
+
+// defaultPort returns the default port of an http(s) URL scheme.
+func defaultPort(scheme string) string {
+	if scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
@@ -0,0 +1,129 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseResolve(t *testing.T) {
+	tests := []struct {
+		rule        string
+		wantHost    string
+		wantAddress string
+		wantErr     bool
+	}{
+		{"docs.example.com:443:10.0.0.5", "docs.example.com:443", "10.0.0.5:443", false},
+		{"Docs.Example.com:80:10.0.0.5:8080", "docs.example.com:80", "10.0.0.5:8080", false},
+		{"docs.example.com:443:[::1]", "docs.example.com:443", "[::1]:443", false},
+		{"docs.example.com:443:[::1]:8443", "docs.example.com:443", "[::1]:8443", false},
+		{"docs.example.com:10.0.0.5", "", "", true},
+		{"docs.example.com:https:10.0.0.5", "docs.example.com:https", "10.0.0.5:https", false},
+		{"docs.example.com:notaport:10.0.0.5", "", "", true},
+		{"docs.example.com", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			host, address, err := ParseResolve(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseResolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if host != tt.wantHost || address != tt.wantAddress {
+				t.Errorf("ParseResolve() = %q, %q; want %q, %q", host, address, tt.wantHost, tt.wantAddress)
+			}
+		})
+	}
+}
+
+func TestHostOverride(t *testing.T) {
+	tests := []struct {
+		url      string
+		host     string
+		wantURL  string
+		wantRule string
+	}{
+		{"http://10.0.0.5:8080/docs/", "docs.example.com", "http://docs.example.com/docs/", "docs.example.com:80:10.0.0.5:8080"},
+		{"https://staging.internal/docs/", "docs.example.com", "https://docs.example.com/docs/", "docs.example.com:443:staging.internal:443"},
+		{"https://10.0.0.5/", "docs.example.com:8443", "https://docs.example.com:8443/", "docs.example.com:8443:10.0.0.5:443"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			gotURL, gotRule, err := HostOverride(tt.url, tt.host)
+			if err != nil {
+				t.Fatalf("HostOverride() returned error: %v", err)
+			}
+			if gotURL != tt.wantURL || gotRule != tt.wantRule {
+				t.Errorf("HostOverride() = %q, %q; want %q, %q", gotURL, gotRule, tt.wantURL, tt.wantRule)
+			}
+		})
+	}
+
+	if _, _, err := HostOverride("/docs/", "docs.example.com"); err == nil {
+		t.Error("HostOverride() accepted a relative URL")
+	}
+}
+
+func TestFetchResolve(t *testing.T) {
+	var mu sync.Mutex
+	hosts := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		hosts[r.URL.Path] = r.Host
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /private\n"))
+		case "/docs/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><a href="http://docs.example.test/docs/guide">Guide</a><a href="/private">Private</a></body></html>`))
+		case "/docs/guide":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body>Guide</body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	startURL, rule, err := HostOverride(server.URL+"/docs/", "docs.example.test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport, err := ResolveTransport([]string{rule}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	f := New(dir)
+	f.delay = 0
+	f.SetTransport(transport)
+	if err := f.Fetch(startURL); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+
+	for _, path := range []string{"docs.html", "docs/guide.html"} {
+		if _, err := os.Stat(filepath.Join(dir, "crawl", "docs.example.test", filepath.FromSlash(path))); err != nil {
+			t.Errorf("%s not saved under the overriding host: %v", path, err)
+		}
+	}
+	for path, host := range hosts {
+		if host != "docs.example.test" {
+			t.Errorf("request for %s sent Host %q, want docs.example.test", path, host)
+		}
+	}
+	if _, ok := hosts["/private"]; ok {
+		t.Error("robots.txt of the overriding host was not honored")
+	}
+	for _, p := range f.Report().Pages {
+		if strings.Contains(p.URL, "127.0.0.1") {
+			t.Errorf("report refers to the server address: %s", p.URL)
+		}
+	}
+}
@@ -5,8 +5,11 @@ package fetcher
 
 import (
 	"bufio"
+	"context"
+	"errors"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"strings"
@@ -14,23 +17,66 @@ import (
 	"time"
 )
 
+// Robots policies for RobotsChecker.SetPolicy.
+const (
+	// RobotsDefault follows robots.txt when it can be fetched, and allows
+	// every URL of a host whose robots.txt is missing or can't be fetched.
+	RobotsDefault = "default"
+	// RobotsStrict follows robots.txt, and disallows every URL of a host
+	// whose robots.txt can't be fetched (network error or 5xx status), as RFC
+	// 9309 asks, failing the crawl of a start URL on that host. A missing
+	// robots.txt (4xx status) still allows every URL.
+	RobotsStrict = "strict"
+	// RobotsOff doesn't consult robots.txt, for sites the user owns.
+	RobotsOff = "off"
+)
+
+// ErrRobotsUnavailable is the error of the URLs disallowed in RobotsStrict
+// mode because the robots.txt of their host can't be fetched.
+var ErrRobotsUnavailable = errors.New("robots.txt unavailable")
+
+// ParseRobotsPolicy returns the robots policy s, in lower case; "" is
+// RobotsDefault.
+//
+// Returns an error if s isn't a robots policy.
+func ParseRobotsPolicy(s string) (string, error) {
+	switch policy := strings.ToLower(strings.TrimSpace(s)); policy {
+	case "":
+		return RobotsDefault, nil
+	case RobotsDefault, RobotsStrict, RobotsOff:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid robots policy %q (expected strict, default, or off)", s)
+}
+
 // RobotsChecker checks if URLs are allowed by robots.txt rules.
-// It fetches, parses, and caches robots.txt files for each domain, applying
-// user-agent-specific rules to determine crawl permissions. Thread-safe for
+// It fetches, parses, and caches robots.txt files for each host, keyed by
+// scheme and host, applying user-agent-specific rules to determine crawl
+// permissions, so that crawls spanning several hosts (several start URLs,
+// subdomains, hreflang alternates) consult the rules of each. Thread-safe for
 // concurrent use.
 type RobotsChecker struct {
 	// cache stores parsed robots.txt rules indexed by domain and basePath
 	cache map[string]*robotsRules
 	// mu protects concurrent access to the cache
 	mu sync.RWMutex
-	// userAgent identifies this crawler in robots.txt matching
+	// userAgent is sent with robots.txt requests, and identifies this
+	// crawler in robots.txt matching by its product token
 	userAgent string
+	// token is the product token of userAgent, which User-agent lines are
+	// matched against
+	token string
 	// httpClient is used to fetch robots.txt files
 	httpClient *http.Client
 	// basePath is the base path for subdirectory deployments (e.g., "/site2skill-go")
 	// Used to support GitHub Pages and similar hosting where robots.txt may be
 	// located at a subdirectory rather than the root
 	basePath string
+	// hostBasePaths holds the base paths of hosts set with SetHostBasePath,
+	// by scheme and host ("https://example.com"), overriding basePath
+	hostBasePaths map[string]string
+	// policy is the robots policy: RobotsDefault, RobotsStrict, or RobotsOff
+	policy string
 }
 
 // robotsRules holds parsed robots.txt directives for a specific domain and user agent.
@@ -45,10 +91,18 @@ type robotsRules struct {
 	crawlDelay time.Duration
 	// fetchedAt records when these rules were retrieved
 	fetchedAt time.Time
+	// sitemaps lists the URLs of the Sitemap directives, which apply to
+	// every user agent
+	sitemaps []string
+	// unavailable is the error fetching robots.txt in RobotsStrict mode,
+	// which disallows every URL
+	unavailable error
 }
 
 // NewRobotsChecker creates a new RobotsChecker configured with the specified user agent string.
-// The user agent is used to match User-agent directives in robots.txt files.
+// The user agent is sent with robots.txt requests, and its product token (the first word up to
+// a slash, "MyBot" of "MyBot/1.0 (+https://example.com/bot)") is used to match User-agent
+// directives in robots.txt files.
 //
 // Parameters:
 //   - userAgent: The user agent string to identify this crawler (e.g., "MyBot/1.0")
@@ -57,14 +111,27 @@ type robotsRules struct {
 // and automatically caches robots.txt files to minimize network requests.
 func NewRobotsChecker(userAgent string) *RobotsChecker {
 	return &RobotsChecker{
-		cache:     make(map[string]*robotsRules),
-		userAgent: userAgent,
+		cache:         make(map[string]*robotsRules),
+		hostBasePaths: make(map[string]string),
+		userAgent:     userAgent,
+		token:         strings.ToLower(productToken(userAgent)),
+		policy:        RobotsDefault,
 		httpClient: &http.Client{
 			Timeout: 10 * time.Second,
 		},
 	}
 }
 
+// SetUserAgent replaces the user agent string given to NewRobotsChecker,
+// dropping the cached rules, which were matched against the previous one.
+func (r *RobotsChecker) SetUserAgent(userAgent string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.userAgent = userAgent
+	r.token = strings.ToLower(productToken(userAgent))
+	r.cache = make(map[string]*robotsRules)
+}
+
 // SetBasePath configures the base path for subdirectory deployments like GitHub Pages.
 // This is necessary for sites hosted in subdirectories where robots.txt may be located
 // at a path like /project-name/robots.txt instead of /robots.txt.
@@ -78,28 +145,76 @@ func NewRobotsChecker(userAgent string) *RobotsChecker {
 //   - basePath: The base path (e.g., "/site2skill-go"). Will be normalized to ensure
 //     it starts with "/" and doesn't end with "/". Pass empty string to disable.
 func (r *RobotsChecker) SetBasePath(basePath string) {
-	// Normalize basePath: ensure it starts with / and doesn't end with /
+	r.basePath = normalizeBasePath(basePath)
+}
+
+// SetHostBasePath is like SetBasePath for the host of origin alone, a scheme
+// and host such as "https://example.com", overriding the base path set with
+// SetBasePath. Crawls from several start URLs set the base path of the host
+// of each.
+func (r *RobotsChecker) SetHostBasePath(origin, basePath string) {
+	r.mu.Lock()
+	r.hostBasePaths[origin] = normalizeBasePath(basePath)
+	r.mu.Unlock()
+}
+
+// normalizeBasePath ensures basePath starts with / and doesn't end with /,
+// unless it is empty.
+func normalizeBasePath(basePath string) string {
 	if basePath != "" {
 		if !strings.HasPrefix(basePath, "/") {
 			basePath = "/" + basePath
 		}
 		basePath = strings.TrimSuffix(basePath, "/")
 	}
-	r.basePath = basePath
+	return basePath
+}
+
+// SetPolicy sets the robots policy: RobotsDefault, RobotsStrict, or
+// RobotsOff (see ParseRobotsPolicy).
+func (r *RobotsChecker) SetPolicy(policy string) {
+	r.policy = policy
+}
+
+// SetTransport replaces the HTTP transport used to fetch robots.txt files.
+// Passing nil restores http.DefaultTransport.
+func (r *RobotsChecker) SetTransport(rt http.RoundTripper) {
+	r.httpClient.Transport = rt
 }
 
 // IsAllowed checks if the given URL is allowed by robots.txt.
 // It fetches and caches the robots.txt for the domain if not already cached.
 // When multiple rules match, the longer (more specific) rule takes precedence.
 func (r *RobotsChecker) IsAllowed(targetURL string) bool {
+	return r.IsAllowedContext(context.Background(), targetURL)
+}
+
+// IsAllowedContext is like IsAllowed but aborts fetching robots.txt when ctx is
+// done. The URL is then allowed, and nothing is cached for its domain.
+func (r *RobotsChecker) IsAllowedContext(ctx context.Context, targetURL string) bool {
+	return r.Check(ctx, targetURL) == nil
+}
+
+// Check is like IsAllowedContext but tells why a URL isn't allowed: it
+// returns nil if targetURL is allowed, ErrRobotsBlocked if robots.txt
+// disallows it, or an error wrapping ErrRobotsUnavailable if its robots.txt
+// can't be fetched in RobotsStrict mode. Every URL is allowed in RobotsOff
+// mode, without fetching robots.txt.
+func (r *RobotsChecker) Check(ctx context.Context, targetURL string) error {
+	if r.policy == RobotsOff {
+		return nil
+	}
 	parsedURL, err := url.Parse(targetURL)
 	if err != nil {
-		return true // Allow on parse error
+		return nil // Allow on parse error
 	}
 
-	rules := r.getRules(parsedURL.Scheme, parsedURL.Host)
+	rules := r.getRules(ctx, parsedURL.Scheme, parsedURL.Host)
 	if rules == nil {
-		return true // Allow if no rules found
+		return nil // Allow if no rules found
+	}
+	if rules.unavailable != nil {
+		return rules.unavailable
 	}
 
 	path := parsedURL.Path
@@ -125,20 +240,40 @@ func (r *RobotsChecker) IsAllowed(targetURL string) bool {
 		}
 	}
 
-	return allowed
+	if !allowed {
+		return ErrRobotsBlocked
+	}
+	return nil
+}
+
+// Sitemaps returns the URLs of the sitemaps the robots.txt of the host of
+// targetURL lists with Sitemap directives, fetching it if it isn't cached
+// yet. Returns nil if the host has no robots.txt or it lists no sitemap.
+func (r *RobotsChecker) Sitemaps(ctx context.Context, targetURL string) []string {
+	parsedURL, err := url.Parse(targetURL)
+	if err != nil {
+		return nil
+	}
+	if rules := r.getRules(ctx, parsedURL.Scheme, parsedURL.Host); rules != nil {
+		return rules.sitemaps
+	}
+	return nil
 }
 
 // getRules fetches or retrieves cached robots.txt rules for a domain.
 // It tries the root /robots.txt first, then falls back to basePath/robots.txt
-// for subdirectory deployments like GitHub Pages.
-func (r *RobotsChecker) getRules(scheme, host string) *robotsRules {
-	cacheKey := scheme + "://" + host
-	if r.basePath != "" {
-		cacheKey += r.basePath
-	}
+// for subdirectory deployments like GitHub Pages, with the base path of the
+// host if one was set (see SetHostBasePath).
+func (r *RobotsChecker) getRules(ctx context.Context, scheme, host string) *robotsRules {
+	origin := scheme + "://" + host
 
 	// Check cache first
 	r.mu.RLock()
+	basePath, ok := r.hostBasePaths[origin]
+	if !ok {
+		basePath = r.basePath
+	}
+	cacheKey := origin + basePath
 	rules, exists := r.cache[cacheKey]
 	r.mu.RUnlock()
 
@@ -147,14 +282,23 @@ func (r *RobotsChecker) getRules(scheme, host string) *robotsRules {
 	}
 
 	// Try fetching robots.txt from root first
-	robotsURL := scheme + "://" + host + "/robots.txt"
-	rules = r.fetchRobotsTxt(robotsURL)
+	robotsURL := origin + "/robots.txt"
+	rules, err := r.fetchRobotsTxt(ctx, robotsURL)
+	if err != nil && r.policy == RobotsStrict && ctx.Err() == nil {
+		slog.Warn("robots.txt unavailable; disallowing the host", "url", robotsURL, "error", err)
+		rules = &robotsRules{fetchedAt: time.Now(), unavailable: fmt.Errorf("%w: %s: %v", ErrRobotsUnavailable, robotsURL, err)}
+	}
 
 	// If root robots.txt not found and basePath is set, try basePath/robots.txt
-	if rules == nil && r.basePath != "" {
-		subDirRobotsURL := scheme + "://" + host + r.basePath + "/robots.txt"
-		log.Printf("Root robots.txt not found, trying subdirectory: %s", subDirRobotsURL)
-		rules = r.fetchRobotsTxt(subDirRobotsURL)
+	if rules == nil && err == nil && basePath != "" && ctx.Err() == nil {
+		subDirRobotsURL := origin + basePath + "/robots.txt"
+		slog.Debug("Root robots.txt not found, trying subdirectory", "url", subDirRobotsURL)
+		rules, _ = r.fetchRobotsTxt(ctx, subDirRobotsURL)
+	}
+
+	// An aborted lookup says nothing about the site
+	if ctx.Err() != nil {
+		return rules
 	}
 
 	// Cache the result (even if nil)
@@ -169,24 +313,35 @@ func (r *RobotsChecker) getRules(scheme, host string) *robotsRules {
 // It performs an HTTP GET request and parses the response if successful.
 //
 // Parameters:
+//   - ctx: Cancels the request
 //   - robotsURL: The complete URL to the robots.txt file
 //
 // Returns the parsed robotsRules, or nil if the file doesn't exist (404) or cannot be fetched.
-// A nil return value indicates all URLs should be allowed (permissive behavior).
-func (r *RobotsChecker) fetchRobotsTxt(robotsURL string) *robotsRules {
-	resp, err := r.httpClient.Get(robotsURL)
+// A nil return value indicates all URLs should be allowed (permissive behavior), but for
+// RobotsStrict mode, which disallows them when the error returned isn't nil: the file
+// couldn't be fetched (network error or 5xx status).
+func (r *RobotsChecker) fetchRobotsTxt(ctx context.Context, robotsURL string) (*robotsRules, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", robotsURL, nil)
 	if err != nil {
-		return nil
+		return nil, err
+	}
+	req.Header.Set("User-Agent", r.userAgent)
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= 500 {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
 	// If robots.txt doesn't exist or is inaccessible, allow all
 	if resp.StatusCode != http.StatusOK {
-		return nil
+		return nil, nil
 	}
 
-	log.Printf("Successfully fetched robots.txt from %s", robotsURL)
-	return r.parseRobotsTxt(resp.Body)
+	slog.Info("Fetched robots.txt", "url", robotsURL)
+	return r.parseRobotsTxt(resp.Body), nil
 }
 
 // parseRobotsTxt parses robots.txt content from a reader and extracts rules for the configured user agent.
@@ -194,6 +349,7 @@ func (r *RobotsChecker) fetchRobotsTxt(robotsURL string) *robotsRules {
 //
 // The parser handles both user-agent-specific rules and wildcard (*) rules, preferring
 // specific rules when available and falling back to wildcard rules otherwise.
+// Sitemap directives apply to every user agent, wherever they appear.
 //
 // Parameters:
 //   - reader: An io.Reader providing the robots.txt content
@@ -232,8 +388,7 @@ func (r *RobotsChecker) parseRobotsTxt(reader io.Reader) *robotsRules {
 			// Check if this applies to us
 			if currentUserAgent == "*" {
 				matchesUs = false // Will use as fallback
-			} else if strings.Contains(strings.ToLower(r.userAgent), currentUserAgent) ||
-				currentUserAgent == strings.ToLower(r.userAgent) {
+			} else if strings.Contains(r.token, currentUserAgent) {
 				matchesUs = true
 			} else {
 				matchesUs = false
@@ -256,6 +411,11 @@ func (r *RobotsChecker) parseRobotsTxt(reader io.Reader) *robotsRules {
 				wildcardRules.allowRules = append(wildcardRules.allowRules, value)
 			}
 
+		case "sitemap":
+			if value != "" {
+				rules.sitemaps = append(rules.sitemaps, value)
+			}
+
 		case "crawl-delay":
 			// Parse crawl delay (optional)
 			// Not implemented for now
@@ -1,6 +1,11 @@
 package fetcher
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -187,3 +192,173 @@ func TestRobotsChecker_SetBasePath(t *testing.T) {
 		})
 	}
 }
+
+func TestRobotsChecker_IsAllowedContext(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+	}))
+	defer server.Close()
+
+	r := NewRobotsChecker("test-bot")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if !r.IsAllowedContext(ctx, server.URL+"/private/page") {
+		t.Error("IsAllowedContext with a cancelled context should allow the URL")
+	}
+	if requests != 0 {
+		t.Errorf("cancelled lookup sent %d requests, want 0", requests)
+	}
+
+	// The aborted lookup must not be cached as "no robots.txt"
+	if r.IsAllowedContext(context.Background(), server.URL+"/private/page") {
+		t.Error("/private/page should be disallowed once robots.txt is fetched")
+	}
+	if !r.IsAllowed(server.URL + "/public/page") {
+		t.Error("/public/page should be allowed")
+	}
+	if requests != 1 {
+		t.Errorf("robots.txt fetched %d times, want 1", requests)
+	}
+}
+
+func TestRobotsChecker_PerHost(t *testing.T) {
+	a := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("User-agent: *\nDisallow: /private/\n\nSitemap: https://a.example/sitemap.xml\nsitemap: https://a.example/news.xml\n"))
+	}))
+	defer a.Close()
+	b := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/sub/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("User-agent: *\nDisallow: /sub/secret\n"))
+	}))
+	defer b.Close()
+
+	r := NewRobotsChecker("test-bot")
+	r.SetHostBasePath(b.URL, "sub/")
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{a.URL + "/private/page", false},
+		{a.URL + "/sub/secret", true},
+		{b.URL + "/private/page", true},
+		{b.URL + "/sub/secret", false},
+	}
+	for _, tt := range tests {
+		if got := r.IsAllowed(tt.url); got != tt.want {
+			t.Errorf("IsAllowed(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+
+	if got, want := r.Sitemaps(context.Background(), a.URL+"/docs/"), []string{"https://a.example/sitemap.xml", "https://a.example/news.xml"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Sitemaps(a) = %q, want %q", got, want)
+	}
+	if got := r.Sitemaps(context.Background(), b.URL+"/sub/"); got != nil {
+		t.Errorf("Sitemaps(b) = %q, want none", got)
+	}
+}
+
+func TestParseRobotsPolicy(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", RobotsDefault, false},
+		{"default", RobotsDefault, false},
+		{" Strict ", RobotsStrict, false},
+		{"off", RobotsOff, false},
+		{"ignore", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRobotsPolicy(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseRobotsPolicy(%q) = %q, %v, want %q (error %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRobotsChecker_Policy(t *testing.T) {
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+	missing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer missing.Close()
+	requests := 0
+	disallowAll := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte("User-agent: *\nDisallow: /\n"))
+	}))
+	defer disallowAll.Close()
+
+	tests := []struct {
+		policy string
+		url    string
+		want   error
+	}{
+		{RobotsDefault, unavailable.URL + "/docs/", nil},
+		{RobotsDefault, missing.URL + "/docs/", nil},
+		{RobotsDefault, disallowAll.URL + "/docs/", ErrRobotsBlocked},
+		{RobotsStrict, unavailable.URL + "/docs/", ErrRobotsUnavailable},
+		{RobotsStrict, missing.URL + "/docs/", nil},
+		{RobotsStrict, disallowAll.URL + "/docs/", ErrRobotsBlocked},
+		{RobotsOff, unavailable.URL + "/docs/", nil},
+		{RobotsOff, disallowAll.URL + "/docs/", nil},
+	}
+	for _, tt := range tests {
+		r := NewRobotsChecker("test-bot")
+		r.SetPolicy(tt.policy)
+		before := requests
+		err := r.Check(context.Background(), tt.url)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: Check(%s) = %v, want %v", tt.policy, tt.url, err, tt.want)
+		}
+		if tt.policy == RobotsOff && requests != before {
+			t.Errorf("%s: robots.txt fetched", tt.policy)
+		}
+	}
+}
+
+func TestFetchRobotsPolicy(t *testing.T) {
+	robotsStatus := http.StatusServiceUnavailable
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(robotsStatus)
+			w.Write([]byte("User-agent: *\nDisallow: /private/\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>ok</body></html>`))
+	}))
+	defer server.Close()
+
+	f := New(t.TempDir())
+	f.delay = 0
+	f.SetRobotsPolicy(RobotsStrict)
+	if err := f.Fetch(server.URL + "/docs/"); !errors.Is(err, ErrRobotsUnavailable) {
+		t.Errorf("strict: Fetch() error = %v, want %v", err, ErrRobotsUnavailable)
+	}
+	if pages := f.Report().Pages; len(pages) != 1 || pages[0].Outcome != OutcomeBlocked || pages[0].Reason != ReasonRobotsUnavailable {
+		t.Errorf("strict: report = %+v, want the start URL blocked as %s", pages, ReasonRobotsUnavailable)
+	}
+
+	robotsStatus = http.StatusOK
+	f = New(t.TempDir())
+	f.delay = 0
+	f.SetRobotsPolicy(RobotsOff)
+	if err := f.Fetch(server.URL + "/private/"); err != nil {
+		t.Errorf("off: Fetch() of a disallowed URL returned error: %v", err)
+	}
+}
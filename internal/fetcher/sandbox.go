@@ -0,0 +1,169 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the sandbox of the crawler: an allow-list of the hosts
+// it may connect to, enforced by its transport, so that a crawl run from CI
+// can't be led by the pages it fetches to any other server.
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ErrHostNotAllowed is wrapped by the errors of requests, and of lookups, of
+// hosts outside the allow-list of the sandbox (see SandboxTransport).
+var ErrHostNotAllowed = errors.New("host not allowed by the sandbox")
+
+// AllowList is the set of hosts the sandboxed crawler may connect to: exact
+// host names or IP addresses ("docs.example.com"), and wildcards matching
+// every subdomain of a domain ("*.example.com", which doesn't match
+// "example.com" itself). Hosts are matched case-insensitively, without their
+// port.
+type AllowList struct {
+	exact    map[string]bool
+	suffixes []string
+}
+
+// ParseAllowList parses the host patterns of an allow-list. A pattern may be
+// given as a URL ("https://docs.example.com/guide/"), whose host is used.
+//
+// Returns an error if a pattern is empty or isn't a host name, an IP address,
+// or a wildcard "*.domain".
+func ParseAllowList(patterns []string) (*AllowList, error) {
+	l := &AllowList{exact: make(map[string]bool)}
+	for _, pattern := range patterns {
+		host := strings.ToLower(strings.TrimSpace(pattern))
+		if strings.Contains(host, "://") {
+			u, err := url.Parse(host)
+			if err != nil || u.Hostname() == "" {
+				return nil, fmt.Errorf("invalid allowed host %q: not a URL with a host", pattern)
+			}
+			host = u.Hostname()
+		}
+		host = strings.Trim(host, "[]")
+		domain, wildcard := strings.CutPrefix(host, "*.")
+		if domain == "" || (strings.ContainsAny(domain, "*/:@ ") && net.ParseIP(domain) == nil) {
+			return nil, fmt.Errorf("invalid allowed host %q: want a host name, an IP address, or *.domain", pattern)
+		}
+		if wildcard {
+			l.suffixes = append(l.suffixes, "."+domain)
+		} else {
+			l.exact[domain] = true
+		}
+	}
+	return l, nil
+}
+
+// Allows reports whether host, with or without a port, is in the allow-list.
+func (l *AllowList) Allows(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(strings.Trim(host, "[]"), "."))
+	if l.exact[host] {
+		return true
+	}
+	for _, suffix := range l.suffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Hosts returns the exact hosts of the allow-list, sorted, without its
+// wildcards: the hosts whose addresses can be pinned before the crawl.
+func (l *AllowList) Hosts() []string {
+	hosts := make([]string, 0, len(l.exact))
+	for host := range l.exact {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// String returns the patterns of the allow-list, separated by commas.
+func (l *AllowList) String() string {
+	patterns := l.Hosts()
+	for _, suffix := range l.suffixes {
+		patterns = append(patterns, "*"+suffix)
+	}
+	return strings.Join(patterns, ", ")
+}
+
+// SandboxTransport returns a transport sending the requests of base whose
+// host is in allow, and failing the others with an error wrapping
+// ErrHostNotAllowed without connecting. Each redirect is a request of its
+// own, so redirects to other hosts fail the same way. Place it on top of the
+// other transports of the fetcher, caching included, so that no request
+// bypasses it.
+func SandboxTransport(base http.RoundTripper, allow *AllowList) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &sandboxTransport{base: base, allow: allow}
+}
+
+// sandboxTransport is the transport returned by SandboxTransport.
+type sandboxTransport struct {
+	base  http.RoundTripper
+	allow *AllowList
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *sandboxTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.allow.Allows(req.URL.Host) {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, fmt.Errorf("%w: %s", ErrHostNotAllowed, req.URL.Hostname())
+	}
+	return t.base.RoundTrip(req)
+}
+
+// Pin restricts the cache to the hosts of allow and pins their addresses:
+// the first addresses a host resolves to are used for the rest of the crawl,
+// never looked up again, so a DNS record changed during the crawl can't
+// redirect it to another server. Lookups of other hosts fail with an error
+// wrapping ErrHostNotAllowed, and they are never prefetched. The exact hosts
+// of allow, except those of resolve rules, are resolved at once.
+//
+// Returns the addresses of the hosts resolved, keyed by host, or an error
+// listing the hosts that don't resolve.
+func (c *DNSCache) Pin(ctx context.Context, allow *AllowList) (map[string][]string, error) {
+	c.mu.Lock()
+	c.allow = allow
+	c.mu.Unlock()
+
+	pinned := make(map[string][]string)
+	var errs []error
+	for _, host := range allow.Hosts() {
+		c.mu.Lock()
+		skip := c.overridden[host] || net.ParseIP(host) != nil
+		c.mu.Unlock()
+		if skip {
+			continue
+		}
+		addrs, err := c.LookupHost(ctx, host)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		pinned[host] = addrs
+	}
+	if len(errs) > 0 {
+		return pinned, fmt.Errorf("failed to resolve allowed hosts: %w", errors.Join(errs...))
+	}
+	return pinned, nil
+}
+
+// allowed reports whether the cache may look up host: any host unless it is
+// pinned to an allow-list. The caller holds c.mu.
+func (c *DNSCache) allowed(host string) bool {
+	return c.allow == nil || c.allow.Allows(host)
+}
@@ -0,0 +1,162 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseAllowList(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		allowed  []string
+		denied   []string
+		wantErr  bool
+	}{
+		{
+			name:     "exact hosts",
+			patterns: []string{"docs.example.com", "HTTPS://CDN.example.com/assets/"},
+			allowed:  []string{"docs.example.com", "DOCS.example.com:443", "cdn.example.com", "docs.example.com."},
+			denied:   []string{"example.com", "evil.docs.example.com", "docs.example.com.evil.test"},
+		},
+		{
+			name:     "wildcard",
+			patterns: []string{"*.example.com"},
+			allowed:  []string{"docs.example.com", "a.b.example.com:8080"},
+			denied:   []string{"example.com", "badexample.com"},
+		},
+		{
+			name:     "IP addresses",
+			patterns: []string{"10.0.0.5", "[::1]"},
+			allowed:  []string{"10.0.0.5:8080", "[::1]:443", "::1"},
+			denied:   []string{"10.0.0.6"},
+		},
+		{name: "empty", patterns: []string{""}, wantErr: true},
+		{name: "bare wildcard", patterns: []string{"*"}, wantErr: true},
+		{name: "inner wildcard", patterns: []string{"docs.*.com"}, wantErr: true},
+		{name: "path", patterns: []string{"example.com/docs"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := ParseAllowList(tt.patterns)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAllowList() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			for _, host := range tt.allowed {
+				if !l.Allows(host) {
+					t.Errorf("Allows(%q) = false, want true", host)
+				}
+			}
+			for _, host := range tt.denied {
+				if l.Allows(host) {
+					t.Errorf("Allows(%q) = true, want false", host)
+				}
+			}
+		})
+	}
+}
+
+func TestSandboxTransport(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("request %s reached a host outside the allow-list", r.URL)
+	}))
+	defer other.Close()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/away" {
+			http.Redirect(w, r, strings.Replace(other.URL, "127.0.0.1", "localhost", 1)+"/", http.StatusFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	allow, err := ParseAllowList([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: SandboxTransport(nil, allow)}
+
+	resp, err := client.Get(server.URL + "/")
+	if err != nil {
+		t.Fatalf("request to an allowed host failed: %v", err)
+	}
+	resp.Body.Close()
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{name: "other host", url: strings.Replace(server.URL, "127.0.0.1", "localhost", 1) + "/"},
+		{name: "redirect to other host", url: server.URL + "/away"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.Get(tt.url)
+			if !errors.Is(err, ErrHostNotAllowed) {
+				t.Errorf("Get(%s) error = %v, want ErrHostNotAllowed", tt.url, err)
+			}
+		})
+	}
+}
+
+func TestDNSCachePin(t *testing.T) {
+	fake := &fakeLookup{addrs: map[string][]string{"docs.example.test": {"10.0.0.5"}}, calls: map[string]int{}}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewDNSCache()
+	c.lookup = fake.lookup
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	allow, err := ParseAllowList([]string{"docs.example.test", "*.cdn.example.test", "10.0.0.7"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	pinned, err := c.Pin(ctx, allow)
+	if err != nil {
+		t.Fatalf("Pin() returned error: %v", err)
+	}
+	if got := pinned["docs.example.test"]; len(got) != 1 || got[0] != "10.0.0.5" || len(pinned) != 1 {
+		t.Errorf("Pin() = %v, want docs.example.test pinned to 10.0.0.5", pinned)
+	}
+
+	// Pinned addresses outlive their TTL and aren't refreshed
+	fake.mu.Lock()
+	fake.addrs["docs.example.test"] = []string{"192.0.2.1"}
+	fake.mu.Unlock()
+	now = now.Add(2 * dnsTTL)
+	c.Prefetch("docs.example.test")
+	addrs, err := c.LookupHost(ctx, "docs.example.test")
+	if err != nil || len(addrs) != 1 || addrs[0] != "10.0.0.5" {
+		t.Errorf("LookupHost() = %v, %v; want the pinned [10.0.0.5]", addrs, err)
+	}
+	if n := fake.count("docs.example.test"); n != 1 {
+		t.Errorf("looked up %d times, want 1", n)
+	}
+
+	if _, err := c.LookupHost(ctx, "evil.example.test"); !errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("LookupHost() of a host outside the allow-list error = %v, want ErrHostNotAllowed", err)
+	}
+	c.Prefetch("evil.example.test")
+	if _, err := c.LookupHost(ctx, "img.cdn.example.test"); errors.Is(err, ErrHostNotAllowed) {
+		t.Errorf("LookupHost() of a wildcard host was refused: %v", err)
+	}
+	if fake.count("evil.example.test") != 0 {
+		t.Error("a host outside the allow-list was looked up")
+	}
+
+	// Hosts that don't resolve are reported
+	c = NewDNSCache()
+	c.lookup = fake.lookup
+	allow, _ = ParseAllowList([]string{"missing.example.test"})
+	if _, err := c.Pin(ctx, allow); err == nil {
+		t.Error("Pin() of a host that doesn't resolve returned no error")
+	}
+}
@@ -0,0 +1,59 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements crawls from several start URLs, possibly on different
+// hosts, into one crawl directory.
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// FetchSeeds crawls from each of seeds in turn into the same crawl directory
+// and report, as Fetch crawls from one start URL. Each crawl is restricted to
+// the domain of its seed and starts again at depth 0; a page reached from
+// several seeds is downloaded once, from the first.
+//
+// The records of the report carry the seed each page was reached from
+// (PageRecord.Seed), and the report lists the seeds (CrawlReport.Seeds), so
+// that the pages of each site can be told apart. The crawl stops at the first
+// seed that fails, with an error naming it.
+func (f *Fetcher) FetchSeeds(seeds []string) error {
+	return f.FetchSeedsContext(context.Background(), seeds)
+}
+
+// FetchSeedsContext is like FetchSeeds but stops when ctx is cancelled or
+// its deadline expires, as FetchContext does.
+func (f *Fetcher) FetchSeedsContext(ctx context.Context, seeds []string) error {
+	if len(seeds) == 0 {
+		return errors.New("no start URL to crawl")
+	}
+	first, crawlDir, err := f.begin(seeds[0])
+	if err != nil {
+		return err
+	}
+	for i, seed := range seeds {
+		if i > 0 {
+			if seed, err = f.restrict(seed); err != nil {
+				return f.end(ctx, fmt.Errorf("failed to crawl %s: %w", seeds[i], err))
+			}
+			slog.Info("Crawling next start URL", "url", seed, "domain", f.domain)
+		} else {
+			seed = first
+		}
+		f.report.Seeds = append(f.report.Seeds, seed)
+		f.seed = seed
+		err := f.crawl(ctx, seed, crawlDir, 0)
+		if err == nil {
+			err = f.crawlSitemaps(ctx, seed, crawlDir)
+		}
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, ErrSizeBudget) {
+				return f.end(ctx, err)
+			}
+			return f.end(ctx, fmt.Errorf("failed to crawl %s: %w", seed, err))
+		}
+	}
+	return f.end(ctx, nil)
+}
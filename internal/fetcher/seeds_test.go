@@ -0,0 +1,79 @@
+package fetcher
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFetchSeeds(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/reference/":
+			w.Write([]byte(`<html><body><a href="/reference/auth">Auth</a></body></html>`))
+		case "/reference/auth":
+			w.Write([]byte(`<html><body>Tokens</body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer api.Close()
+	docs := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<html><body><a href="/guide">Guide</a><a href="` + api.URL + `/reference/auth">API</a></body></html>`))
+		case "/guide":
+			w.Write([]byte(`<html><body>Guide</body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer docs.Close()
+
+	f := New(t.TempDir())
+	f.delay = 0
+	if err := f.FetchSeeds([]string{docs.URL + "/", api.URL + "/reference/"}); err != nil {
+		t.Fatalf("FetchSeeds() returned error: %v", err)
+	}
+
+	report := f.Report()
+	if want := []string{docs.URL + "/", api.URL + "/reference/"}; strings.Join(report.Seeds, " ") != strings.Join(want, " ") {
+		t.Errorf("Seeds = %v, want %v", report.Seeds, want)
+	}
+	want := map[string]string{
+		docs.URL + "/":              "saved " + docs.URL + "/",
+		docs.URL + "/guide":         "saved " + docs.URL + "/",
+		api.URL + "/reference/auth": "skipped " + docs.URL + "/",
+		api.URL + "/reference/":     "saved " + api.URL + "/reference/",
+	}
+	got := make(map[string]string)
+	for _, rec := range report.Pages {
+		got[rec.URL] = string(rec.Outcome) + " " + rec.Seed
+	}
+	for u, w := range want {
+		if got[u] != w {
+			t.Errorf("%s = %q, want %q", u, got[u], w)
+		}
+	}
+	// The page linked out of the first site is skipped there and not crawled again
+	if report.Summary[OutcomeSaved] != 3 {
+		t.Errorf("saved %d pages, want 3: %v", report.Summary[OutcomeSaved], got)
+	}
+
+	f = New(t.TempDir())
+	f.delay = 0
+	if err := f.FetchSeeds([]string{docs.URL + "/", "http:///guide"}); err == nil || !strings.Contains(err.Error(), "http:///guide") {
+		t.Errorf("FetchSeeds() with an invalid seed error = %v", err)
+	}
+	if err := f.FetchSeeds(nil); err == nil {
+		t.Error("FetchSeeds() without seeds returned no error")
+	}
+	f.SetURLFilters(nil, []string{"reference"})
+	if err := f.FetchSeeds([]string{docs.URL + "/", api.URL + "/reference/"}); !errors.Is(err, ErrOutOfScope) {
+		t.Errorf("FetchSeeds() with an excluded seed error = %v, want ErrOutOfScope", err)
+	}
+}
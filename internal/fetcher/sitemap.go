@@ -0,0 +1,221 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the discovery of pages from the sitemaps listed in
+// the robots.txt of the hosts crawled.
+package fetcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/idn"
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+)
+
+const (
+	// maxSitemaps is the most sitemap files read for one host, sitemap
+	// indexes included.
+	maxSitemaps = 50
+	// maxSitemapPages is the most page URLs taken from the sitemaps of one
+	// host, the most one sitemap may list.
+	maxSitemapPages = 50000
+)
+
+// SetSitemaps enables or disables the discovery of pages from sitemaps. When
+// enabled, once the pages reachable from a start URL are crawled, the pages
+// listed in the sitemaps named by the Sitemap directives of the robots.txt of
+// its host (sitemap indexes are followed, gzipped sitemaps decompressed) are
+// crawled too, at depth 1, if they are on the host and pass the URL filters:
+// pages no link leads to are found, and pages already crawled aren't fetched
+// again. Their records carry the sitemap they were listed in
+// (PageRecord.Sitemap). Disabled by default.
+func (f *Fetcher) SetSitemaps(enabled bool) {
+	f.sitemaps = enabled
+}
+
+// crawlSitemaps crawls the pages of the sitemaps of the host of seed, a start
+// URL whose links were crawled, into crawlDir if sitemap discovery is enabled
+// (see SetSitemaps).
+func (f *Fetcher) crawlSitemaps(ctx context.Context, seed, crawlDir string) error {
+	if !f.sitemaps || f.feed {
+		return nil
+	}
+	sitemaps := f.robotsChecker.Sitemaps(ctx, seed)
+	if len(sitemaps) == 0 {
+		slog.Debug("No sitemap in robots.txt", "url", seed)
+		return nil
+	}
+	pages, err := f.sitemapPages(ctx, sitemaps)
+	if err != nil {
+		return err
+	}
+
+	var links []string
+	for _, page := range pages {
+		link := f.normalizeURL(idn.ToASCII(page.url))
+		if u, err := url.Parse(link); err != nil || u.Host != f.domain || f.report.has(link) {
+			continue
+		}
+		f.mu.Lock()
+		if f.fromSitemap == nil {
+			f.fromSitemap = make(map[string]string)
+		}
+		if _, ok := f.fromSitemap[link]; ok {
+			f.mu.Unlock()
+			continue
+		}
+		f.fromSitemap[link] = page.sitemap
+		f.mu.Unlock()
+		links = append(links, link)
+	}
+	slog.Info("Sitemaps", "url", seed, "sitemaps", len(sitemaps), "pages", len(pages), "new", len(links))
+	f.queue(len(links))
+	for _, link := range links {
+		f.queue(-1)
+		if err := f.crawl(ctx, link, crawlDir, 1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sitemapPage is a page URL listed in a sitemap.
+type sitemapPage struct {
+	// url is the URL of the page.
+	url string
+	// sitemap is the URL of the sitemap listing it.
+	sitemap string
+}
+
+// sitemapPages returns the pages listed in sitemaps and in the sitemaps they
+// index, up to maxSitemaps files and maxSitemapPages pages. A sitemap that
+// can't be read is skipped with a warning; the error returned is that of ctx
+// or of the size budget.
+func (f *Fetcher) sitemapPages(ctx context.Context, sitemaps []string) ([]sitemapPage, error) {
+	var pages []sitemapPage
+	seen := make(map[string]bool)
+	for len(sitemaps) > 0 && len(seen) < maxSitemaps && len(pages) < maxSitemapPages {
+		sitemapURL := sitemaps[0]
+		sitemaps = sitemaps[1:]
+		if seen[sitemapURL] {
+			continue
+		}
+		seen[sitemapURL] = true
+
+		urls, indexed, err := f.fetchSitemap(ctx, sitemapURL)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, ErrSizeBudget) {
+				return pages, err
+			}
+			warnlog.Printf("sitemap", "Warning: skipping sitemap %s: %v", sitemapURL, err)
+			continue
+		}
+		for _, u := range urls {
+			if len(pages) == maxSitemapPages {
+				warnlog.Printf("sitemap", "Warning: sitemaps list more than %d pages; the rest are left out", maxSitemapPages)
+				break
+			}
+			pages = append(pages, sitemapPage{url: u, sitemap: sitemapURL})
+		}
+		sitemaps = append(sitemaps, indexed...)
+	}
+	if len(sitemaps) > 0 && len(pages) < maxSitemapPages {
+		warnlog.Printf("sitemap", "Warning: more than %d sitemaps; %d left unread", maxSitemaps, len(sitemaps))
+	}
+	return pages, nil
+}
+
+// fetchSitemap downloads the sitemap at sitemapURL, gzipped or not, and
+// returns the page URLs it lists and, for a sitemap index, the sitemaps it
+// lists.
+func (f *Fetcher) fetchSitemap(ctx context.Context, sitemapURL string) ([]string, []string, error) {
+	req, err := f.newRequest(ctx, "GET", sitemapURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+	data, err := f.readBody(resp.Body, resp.ContentLength)
+	if err != nil {
+		return nil, nil, err
+	}
+	slog.Info("Fetched sitemap", "url", sitemapURL)
+	return parseSitemap(data)
+}
+
+// parseSitemap returns the <loc> URLs of the <url> elements of data, a
+// sitemap (https://www.sitemaps.org/protocol.html), and of the <sitemap>
+// elements of a sitemap index, leaving out the locations of the elements of
+// extensions (images, videos). data may be gzipped.
+//
+// Returns an error if data isn't XML.
+func parseSitemap(data []byte) (pages, sitemaps []string, err error) {
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decompress sitemap: %w", err)
+		}
+		if data, err = io.ReadAll(zr); err != nil {
+			return nil, nil, fmt.Errorf("failed to decompress sitemap: %w", err)
+		}
+	}
+
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var parent string
+	var inLoc bool
+	var loc strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return pages, sitemaps, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse sitemap: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			// The <image:loc> and <video:loc> of extensions aren't pages
+			if t.Name.Space != "" && !strings.Contains(t.Name.Space, "sitemaps.org") {
+				continue
+			}
+			switch t.Name.Local {
+			case "url", "sitemap":
+				parent = t.Name.Local
+			case "loc":
+				inLoc = true
+				loc.Reset()
+			}
+		case xml.CharData:
+			if inLoc {
+				loc.Write(t)
+			}
+		case xml.EndElement:
+			if !inLoc || t.Name.Local != "loc" {
+				continue
+			}
+			inLoc = false
+			if u := strings.TrimSpace(loc.String()); u != "" {
+				switch parent {
+				case "url":
+					pages = append(pages, u)
+				case "sitemap":
+					sitemaps = append(sitemaps, u)
+				}
+			}
+		}
+	}
+}
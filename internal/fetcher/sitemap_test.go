@@ -0,0 +1,120 @@
+package fetcher
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseSitemap(t *testing.T) {
+	tests := []struct {
+		name         string
+		data         string
+		wantPages    []string
+		wantSitemaps []string
+		wantErr      bool
+	}{
+		{
+			name: "urlset",
+			data: `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9" xmlns:image="http://www.google.com/schemas/sitemap-image/1.1">
+  <url><loc> https://example.com/docs/ </loc><lastmod>2024-01-01</lastmod></url>
+  <url><loc>https://example.com/docs/a?x=1&amp;y=2</loc>
+    <image:image><image:loc>https://example.com/a.png</image:loc></image:image>
+  </url>
+</urlset>`,
+			wantPages: []string{"https://example.com/docs/", "https://example.com/docs/a?x=1&y=2"},
+		},
+		{
+			name: "sitemap index",
+			data: `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>https://example.com/sitemap-docs.xml</loc></sitemap>
+  <sitemap><loc>https://example.com/sitemap-blog.xml.gz</loc></sitemap>
+</sitemapindex>`,
+			wantSitemaps: []string{"https://example.com/sitemap-docs.xml", "https://example.com/sitemap-blog.xml.gz"},
+		},
+		{
+			name:      "no namespace",
+			data:      `<urlset><url><loc>https://example.com/</loc></url><url><loc></loc></url></urlset>`,
+			wantPages: []string{"https://example.com/"},
+		},
+		{
+			name:    "not XML",
+			data:    `<urlset><url><loc>https://example.com/</url>`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages, sitemaps, err := parseSitemap([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSitemap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(pages, tt.wantPages) || !reflect.DeepEqual(sitemaps, tt.wantSitemaps) {
+				t.Errorf("parseSitemap() = %q, %q, want %q, %q", pages, sitemaps, tt.wantPages, tt.wantSitemaps)
+			}
+		})
+	}
+}
+
+func TestFetchSitemaps(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /private\nSitemap: " + server.URL + "/sitemap_index.xml\n"))
+		case "/sitemap_index.xml":
+			w.Write([]byte(`<sitemapindex><sitemap><loc>` + server.URL + `/sitemap.xml.gz</loc></sitemap><sitemap><loc>` + server.URL + `/missing.xml</loc></sitemap></sitemapindex>`))
+		case "/sitemap.xml.gz":
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			zw.Write([]byte(`<urlset><url><loc>` + server.URL + `/docs/</loc></url><url><loc>` + server.URL + `/docs/orphan</loc></url><url><loc>` + server.URL + `/private</loc></url><url><loc>https://elsewhere.example/</loc></url></urlset>`))
+			zw.Close()
+			w.Write(buf.Bytes())
+		case "/docs/", "/docs/orphan":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><body><p>` + r.URL.Path + `</p></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	for _, enabled := range []bool{false, true} {
+		f := New(t.TempDir())
+		f.delay = 0
+		f.SetSitemaps(enabled)
+		if err := f.Fetch(server.URL + "/docs/"); err != nil {
+			t.Fatalf("Fetch() returned error: %v", err)
+		}
+		byURL := make(map[string]PageRecord)
+		for _, rec := range f.Report().Pages {
+			byURL[rec.URL] = rec
+		}
+		orphan, found := byURL[server.URL+"/docs/orphan"]
+		if found != enabled {
+			t.Errorf("sitemaps %v: orphan page crawled = %v", enabled, found)
+		}
+		if !enabled {
+			continue
+		}
+		if orphan.Outcome != OutcomeSaved || orphan.Depth != 1 || orphan.Sitemap != server.URL+"/sitemap.xml.gz" {
+			t.Errorf("orphan page: Outcome, Depth, Sitemap = %q, %d, %q", orphan.Outcome, orphan.Depth, orphan.Sitemap)
+		}
+		if rec := byURL[server.URL+"/docs/"]; rec.Sitemap != "" || rec.Depth != 0 {
+			t.Errorf("start page: Depth, Sitemap = %d, %q, want the start URL's record unchanged", rec.Depth, rec.Sitemap)
+		}
+		if rec := byURL[server.URL+"/private"]; rec.Outcome != OutcomeBlocked {
+			t.Errorf("page disallowed by robots.txt: Outcome = %q, want %q", rec.Outcome, OutcomeBlocked)
+		}
+		for u := range byURL {
+			if strings.Contains(u, "elsewhere.example") {
+				t.Errorf("page of another host recorded: %s", u)
+			}
+		}
+	}
+}
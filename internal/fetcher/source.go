@@ -0,0 +1,114 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements source mode, which saves the pages of a Source, read
+// in-process by a program embedding the pipeline, instead of crawling a site.
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ErrSourceFailed is returned by FetchSource when a Source fails to read its
+// pages.
+var ErrSourceFailed = errors.New("source failed")
+
+// SourcePage is a page read by a Source.
+type SourcePage struct {
+	// URL is the absolute URL of the page, which its file and document are
+	// named after and which links between the pages resolve against.
+	URL string
+	// Body is the HTML of the page, or its Markdown if Markdown is set.
+	Body []byte
+	// Markdown marks Body as Markdown, converted like the text/markdown
+	// responses of a crawl, instead of an HTML page.
+	Markdown bool
+	// Depth is the depth of the page in the source: 0 for its top-level
+	// page, and more for the pages under it.
+	Depth int
+	// LastModified is when the page was last modified; zero if unknown.
+	LastModified time.Time
+}
+
+// Source reads the pages of a skill from something other than a website,
+// such as an API, a database, or a content repository.
+type Source interface {
+	// Pages returns an iterator over the pages of the source, run as it is
+	// ranged over and stopped when ctx is done. A failure is yielded as the
+	// last error.
+	Pages(ctx context.Context) iter.Seq2[SourcePage, error]
+}
+
+// FetchSource saves the pages of src into the crawl directory, each like the
+// page at its URL, as a crawl started at startURL would. Pages with an
+// invalid URL, or a URL already saved, are skipped; the URL filters and path
+// patterns apply, and the depth limit doesn't, since src lists the pages.
+//
+// Returns an error wrapping ErrSourceFailed if src yields an error.
+func (f *Fetcher) FetchSource(startURL string, src Source) error {
+	return f.FetchSourceContext(context.Background(), startURL, src)
+}
+
+// FetchSourceContext is like FetchSource but stops when ctx is cancelled or
+// its deadline expires, like FetchContext.
+func (f *Fetcher) FetchSourceContext(ctx context.Context, startURL string, src Source) error {
+	_, crawlDir, err := f.begin(startURL)
+	if err != nil {
+		return err
+	}
+	return f.end(ctx, f.crawlSource(ctx, src, crawlDir))
+}
+
+// crawlSource saves the pages of src in crawlDir.
+func (f *Fetcher) crawlSource(ctx context.Context, src Source, crawlDir string) error {
+	for p, err := range src.Pages(ctx) {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrSourceFailed, err)
+		}
+		parsedURL, err := url.Parse(p.URL)
+		if err != nil || !parsedURL.IsAbs() || parsedURL.Host == "" {
+			f.recordSkip(p.URL, p.Depth, "invalid_url")
+			continue
+		}
+		pageURL := parsedURL.String()
+		if !f.shouldCrawlURL(pageURL, p.Depth) {
+			f.recordSkip(pageURL, p.Depth, "url_filter")
+			continue
+		}
+		f.mu.Lock()
+		seen := f.visited[pageURL]
+		f.visited[pageURL] = true
+		f.mu.Unlock()
+		if seen {
+			continue
+		}
+
+		rec := PageRecord{
+			URL:         pageURL,
+			Depth:       p.Depth,
+			StatusCode:  http.StatusOK,
+			ContentType: "text/html; charset=utf-8",
+			Bytes:       int64(len(p.Body)),
+			Outcome:     OutcomeFailed,
+		}
+		filePath := f.getFilePath(crawlDir, parsedURL)
+		if p.Markdown {
+			rec.ContentType = "text/markdown; charset=utf-8"
+			filePath = f.textFilePath(crawlDir, parsedURL)
+		}
+		if !p.LastModified.IsZero() {
+			rec.LastModified = p.LastModified.UTC().Format(time.RFC3339)
+		}
+		if f.save(&rec, pageURL, filePath, p.Body) {
+			f.downloadCount++
+		}
+	}
+	return nil
+}
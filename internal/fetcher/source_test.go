@@ -0,0 +1,75 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"testing"
+	"time"
+)
+
+// sliceSource is a Source of fixed pages, failing with err after them if set.
+type sliceSource struct {
+	pages []SourcePage
+	err   error
+}
+
+func (s sliceSource) Pages(ctx context.Context) iter.Seq2[SourcePage, error] {
+	return func(yield func(SourcePage, error) bool) {
+		for _, p := range s.pages {
+			if !yield(p, nil) {
+				return
+			}
+		}
+		if s.err != nil {
+			yield(SourcePage{}, s.err)
+		}
+	}
+}
+
+func TestFetchSource(t *testing.T) {
+	src := sliceSource{pages: []SourcePage{
+		{URL: "https://kb.example.com/", Body: []byte("<html><body><h1>KB</h1></body></html>"), LastModified: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{URL: "https://kb.example.com/guide/install", Body: []byte("# Install\n"), Markdown: true, Depth: 1},
+		{URL: "https://kb.example.com/internal/notes", Body: []byte("<p>Notes</p>"), Depth: 1},
+		{URL: "/relative", Body: []byte("<p>Relative</p>"), Depth: 1},
+		{URL: "https://kb.example.com/", Body: []byte("<p>Again</p>")},
+	}}
+
+	f := New(t.TempDir())
+	f.SetURLFilters(nil, []string{"internal"})
+	if err := f.FetchSource("https://kb.example.com/", src); err != nil {
+		t.Fatalf("FetchSource() returned error: %v", err)
+	}
+
+	outcomes := make(map[string]string)
+	for _, rec := range f.Report().Pages {
+		outcomes[rec.URL] = string(rec.Outcome) + " " + rec.OutputFile + rec.Reason
+	}
+	want := map[string]string{
+		"https://kb.example.com/":               "saved crawl/kb.example.com/index.html",
+		"https://kb.example.com/guide/install":  "saved crawl/kb.example.com/guide/install.md",
+		"https://kb.example.com/internal/notes": "skipped url_filter",
+		"/relative":                             "skipped invalid_url",
+	}
+	if len(outcomes) != len(want) {
+		t.Errorf("outcomes = %v, want %v", outcomes, want)
+	}
+	for u, w := range want {
+		if outcomes[u] != w {
+			t.Errorf("%s = %q, want %q (all: %v)", u, outcomes[u], w, outcomes)
+		}
+	}
+	saved := f.Report().SavedPages()
+	if rec := saved["crawl/kb.example.com/index.html"]; rec.LastModified != "2024-05-01T10:00:00Z" || rec.ContentType != "text/html; charset=utf-8" {
+		t.Errorf("LastModified, ContentType = %q, %q", rec.LastModified, rec.ContentType)
+	}
+	if rec := saved["crawl/kb.example.com/guide/install.md"]; rec.ContentType != "text/markdown; charset=utf-8" {
+		t.Errorf("ContentType of the Markdown page = %q", rec.ContentType)
+	}
+
+	f = New(t.TempDir())
+	if err := f.FetchSource("https://kb.example.com/", sliceSource{err: errors.New("database unavailable")}); !errors.Is(err, ErrSourceFailed) {
+		t.Errorf("FetchSource() of a failing source error = %v, want ErrSourceFailed", err)
+	}
+}
@@ -0,0 +1,282 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the adaptive throttle, which slows the crawl of a host
+// down when it starts answering 429 Too Many Requests or 503 Service
+// Unavailable, or much more slowly than before, and speeds it up again
+// gradually once it recovers.
+package fetcher
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+)
+
+// Causes of the throttle events of a crawl report.
+const (
+	// ThrottleRateLimited is the Cause of a slowdown after a 429 Too Many
+	// Requests response.
+	ThrottleRateLimited = "rate_limited"
+	// ThrottleUnavailable is the Cause of a slowdown after a 503 Service
+	// Unavailable response.
+	ThrottleUnavailable = "unavailable"
+	// ThrottleSlowResponse is the Cause of a slowdown after a response much
+	// slower than the usual latency of the host.
+	ThrottleSlowResponse = "slow_response"
+	// ThrottleRecovered is the Cause of the event ending the slowdown of a
+	// host, back at the politeness delay.
+	ThrottleRecovered = "recovered"
+)
+
+const (
+	// maxThrottleDelay bounds the delay before the requests to a throttled
+	// host, and the Retry-After wait honored before retrying a request.
+	maxThrottleDelay = time.Minute
+	// throttleRetries is the number of times a request answered 429 or 503 is
+	// retried, after waiting for the host, before the page fails.
+	throttleRetries = 2
+	// latencyWarmup is the number of responses of a host its usual latency is
+	// learned from before a slow one counts as a spike.
+	latencyWarmup = 3
+	// latencySpike is the factor by which a response must be slower than the
+	// usual latency of its host to count as a spike.
+	latencySpike = 4
+	// minLatencySpike is the latency below which no response counts as a
+	// spike, however fast the host usually is.
+	minLatencySpike = 2 * time.Second
+	// recoverAfter is the number of healthy responses in a row after which the
+	// delay of a throttled host is halved.
+	recoverAfter = 5
+)
+
+// ThrottleEvent records a change of the delay of a host by the adaptive
+// throttle (see SetAdaptiveThrottle).
+type ThrottleEvent struct {
+	// Time is when the response causing the change was received.
+	Time time.Time `json:"time"`
+	// Host is the host slowed down or recovered.
+	Host string `json:"host"`
+	// URL is the URL whose response caused the change.
+	URL string `json:"url"`
+	// Cause is ThrottleRateLimited, ThrottleUnavailable, ThrottleSlowResponse,
+	// or ThrottleRecovered.
+	Cause string `json:"cause"`
+	// StatusCode is the HTTP status of the response, for slowdowns caused by
+	// one.
+	StatusCode int `json:"status_code,omitempty"`
+	// LatencyMS is the time to the response headers in milliseconds, for
+	// slowdowns caused by a slow response.
+	LatencyMS int64 `json:"latency_ms,omitempty"`
+	// RetryAfterMS is the wait the response asked for with Retry-After in
+	// milliseconds, capped to a minute; 0 if it asked for none.
+	RetryAfterMS int64 `json:"retry_after_ms,omitempty"`
+	// DelayMS is the delay before each request to the host from then on in
+	// milliseconds.
+	DelayMS int64 `json:"delay_ms"`
+}
+
+// hostThrottle is the adaptive throttle state of a host.
+type hostThrottle struct {
+	// delay is the delay before the requests to the host, longer than the
+	// politeness delay; 0 when the host isn't throttled
+	delay time.Duration
+	// until is the time before which the host asked not to be requested with
+	// Retry-After
+	until time.Time
+	// latency is the moving average of the latencies of the healthy responses
+	// of the host, over samples responses
+	latency time.Duration
+	samples int
+	// healthy counts the healthy responses since the last change of delay
+	healthy int
+}
+
+// SetAdaptiveThrottle turns the adaptive throttle on (the default) or off.
+// When on, a host answering 429 Too Many Requests or 503 Service Unavailable,
+// or much more slowly than its usual latency, is slowed down: the delay before
+// its requests doubles, up to a minute, a Retry-After wait is honored, and the
+// request answered 429 or 503 is retried up to twice before its page fails.
+// After every few healthy responses in a row the delay is halved, until the
+// host is back at the politeness delay. The crawl requests one page at a
+// time, so the delay is what adapts; the connections of the transport are
+// limited with ConfigureConnections.
+//
+// Every slowdown and recovery is logged as a warning, counted in the
+// progress output, and recorded in the Throttles of the crawl report.
+func (f *Fetcher) SetAdaptiveThrottle(enabled bool) {
+	f.noAdaptiveThrottle = !enabled
+}
+
+// do sends req, a request without a body, after the delay of its host, and
+// retries it while the host answers 429 or 503 (see SetAdaptiveThrottle).
+func (f *Fetcher) do(ctx context.Context, req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	for attempt := 0; ; attempt++ {
+		if err := sleep(ctx, f.hostDelay(host, time.Now())); err != nil {
+			return nil, err
+		}
+		start := time.Now()
+		resp, err := f.client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if !f.observe(host, req.URL.String(), resp, start, time.Now()) || attempt == throttleRetries {
+			return resp, nil
+		}
+		resp.Body.Close()
+		slog.Debug("Retrying a throttled request", "url", req.URL.String(), "status", resp.StatusCode, "attempt", attempt+1)
+	}
+}
+
+// hostDelay returns the time to wait at now before requesting host: its
+// politeness delay (see SetPoliteness), or longer if the host is throttled.
+func (f *Fetcher) hostDelay(host string, now time.Time) time.Duration {
+	base := f.politeDelay(host)
+	if f.noAdaptiveThrottle {
+		return base
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	h := f.throttles[host]
+	if h == nil {
+		return base
+	}
+	return max(base, h.delay, h.until.Sub(now))
+}
+
+// observe updates the throttle state of host with resp, the response to the
+// request of rawURL sent at start and received at now. The state is that of
+// the host requested, which was waited for, even if resp comes from another
+// after redirects. Returns whether the request should be retried because the
+// host answered 429 or 503.
+func (f *Fetcher) observe(host, rawURL string, resp *http.Response, start, now time.Time) bool {
+	if f.noAdaptiveThrottle {
+		return false
+	}
+	latency := now.Sub(start)
+	base := f.politeDelay(host)
+
+	f.mu.Lock()
+	if f.throttles == nil {
+		f.throttles = make(map[string]*hostThrottle)
+	}
+	h := f.throttles[host]
+	if h == nil {
+		h = &hostThrottle{}
+		f.throttles[host] = h
+	}
+	ev := ThrottleEvent{Time: now.UTC(), Host: host, URL: rawURL}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		ev.Cause, ev.StatusCode = ThrottleRateLimited, resp.StatusCode
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			ev.Cause = ThrottleUnavailable
+		}
+		if wait := retryAfter(resp.Header.Get("Retry-After"), now); wait > 0 {
+			h.until = now.Add(wait)
+			ev.RetryAfterMS = wait.Milliseconds()
+		}
+		h.slowDown(base)
+	case h.samples >= latencyWarmup && latency >= minLatencySpike && latency >= latencySpike*h.latency:
+		ev.Cause, ev.LatencyMS = ThrottleSlowResponse, latency.Milliseconds()
+		h.slowDown(base)
+	default:
+		h.learn(latency)
+		if !h.recover(base) {
+			f.mu.Unlock()
+			return false
+		}
+		ev.Cause = ThrottleRecovered
+	}
+	ev.DelayMS = max(base, h.delay).Milliseconds()
+	f.mu.Unlock()
+
+	f.recordThrottle(ev)
+	return ev.StatusCode != 0
+}
+
+// slowDown doubles the delay of h, starting from the politeness delay base or
+// a second, whichever is longer, up to maxThrottleDelay.
+func (h *hostThrottle) slowDown(base time.Duration) {
+	h.delay = min(max(h.delay, base, time.Second)*2, maxThrottleDelay)
+	h.healthy = 0
+}
+
+// learn adds latency, that of a healthy response, to the usual latency of h.
+func (h *hostThrottle) learn(latency time.Duration) {
+	if h.samples < latencyWarmup {
+		h.latency = (h.latency*time.Duration(h.samples) + latency) / time.Duration(h.samples+1)
+	} else {
+		h.latency += (latency - h.latency) / 4
+	}
+	h.samples++
+}
+
+// recover counts a healthy response of h, halving its delay every
+// recoverAfter of them. Returns true when that brings a throttled h back to
+// the politeness delay base.
+func (h *hostThrottle) recover(base time.Duration) bool {
+	if h.delay == 0 {
+		return false
+	}
+	h.healthy++
+	if h.healthy < recoverAfter {
+		return false
+	}
+	h.healthy = 0
+	h.delay /= 2
+	if h.delay > max(base, time.Second) {
+		return false
+	}
+	h.delay = 0
+	return true
+}
+
+// retryAfter returns the wait asked for by value, a Retry-After header in
+// seconds or as an HTTP date, at now, capped to maxThrottleDelay; 0 if value
+// is empty or invalid.
+func retryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		wait = t.Sub(now)
+	}
+	return min(max(wait, 0), maxThrottleDelay)
+}
+
+// recordThrottle records ev in the crawl report and logs it.
+func (f *Fetcher) recordThrottle(ev ThrottleEvent) {
+	if f.report != nil {
+		f.report.addThrottle(ev)
+	}
+	delay := time.Duration(ev.DelayMS) * time.Millisecond
+	switch ev.Cause {
+	case ThrottleRecovered:
+		slog.Info("Host recovered from throttling", "host", ev.Host, "delay", delay)
+	case ThrottleSlowResponse:
+		warnlog.Printf("throttle", "Warning: %s answered in %s, slowing down to a request every %s", ev.Host, time.Duration(ev.LatencyMS)*time.Millisecond, delay)
+	default:
+		warnlog.Printf("throttle", "Warning: %s returned status %d, slowing down to a request every %s", ev.Host, ev.StatusCode, delay)
+	}
+}
+
+// throttledHosts returns the number of hosts currently slowed down by the
+// adaptive throttle.
+func (f *Fetcher) throttledHosts() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, h := range f.throttles {
+		if h.delay > 0 {
+			n++
+		}
+	}
+	return n
+}
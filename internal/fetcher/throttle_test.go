@@ -0,0 +1,149 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "", want: 0},
+		{value: "3", want: 3 * time.Second},
+		{value: "-3", want: 0},
+		{value: "3600", want: maxThrottleDelay},
+		{value: now.Add(10 * time.Second).Format(http.TimeFormat), want: 10 * time.Second},
+		{value: now.Add(-time.Hour).Format(http.TimeFormat), want: 0},
+		{value: "soon", want: 0},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.value, now); got != tt.want {
+			t.Errorf("retryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestObserve(t *testing.T) {
+	f := New(t.TempDir())
+	f.delay = 500 * time.Millisecond
+	f.report = newCrawlReport("https://example.com/", time.Now())
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	respond := func(status int, latency time.Duration, header http.Header) bool {
+		t.Helper()
+		now = now.Add(latency)
+		return f.observe("example.com", "https://example.com/page", &http.Response{StatusCode: status, Header: header}, now.Add(-latency), now)
+	}
+
+	// The usual latency is learned from the first responses
+	for range latencyWarmup {
+		if respond(http.StatusOK, 100*time.Millisecond, nil) {
+			t.Fatal("observe() asked to retry a healthy response")
+		}
+	}
+	if got := f.hostDelay("example.com", now); got != f.delay {
+		t.Errorf("hostDelay() of a healthy host = %s, want the politeness delay %s", got, f.delay)
+	}
+
+	// A latency spike slows the host down without a retry
+	if respond(http.StatusOK, 3*time.Second, nil) {
+		t.Error("observe() asked to retry a slow response")
+	}
+	if got := f.hostDelay("example.com", now); got != 2*time.Second {
+		t.Errorf("hostDelay() after a slow response = %s, want 2s", got)
+	}
+
+	// 429 doubles the delay again, honoring Retry-After, and is retried
+	if !respond(http.StatusTooManyRequests, 100*time.Millisecond, http.Header{"Retry-After": {"10"}}) {
+		t.Error("observe() didn't ask to retry a 429 response")
+	}
+	if got := f.hostDelay("example.com", now); got != 10*time.Second {
+		t.Errorf("hostDelay() after Retry-After = %s, want 10s", got)
+	}
+	now = now.Add(10 * time.Second)
+	if got := f.hostDelay("example.com", now); got != 4*time.Second {
+		t.Errorf("hostDelay() after the Retry-After wait = %s, want 4s", got)
+	}
+	if f.throttledHosts() != 1 {
+		t.Errorf("throttledHosts() = %d, want 1", f.throttledHosts())
+	}
+
+	// Healthy responses halve the delay every recoverAfter of them
+	for range 2 * recoverAfter {
+		respond(http.StatusOK, 100*time.Millisecond, nil)
+	}
+	if got := f.hostDelay("example.com", now); got != f.delay {
+		t.Errorf("hostDelay() after recovery = %s, want the politeness delay %s", got, f.delay)
+	}
+	if f.throttledHosts() != 0 {
+		t.Errorf("throttledHosts() after recovery = %d, want 0", f.throttledHosts())
+	}
+
+	var causes []string
+	for _, ev := range f.report.Throttles {
+		causes = append(causes, ev.Cause)
+	}
+	want := []string{ThrottleSlowResponse, ThrottleRateLimited, ThrottleRecovered}
+	if len(causes) != len(want) || causes[0] != want[0] || causes[1] != want[1] || causes[2] != want[2] {
+		t.Fatalf("throttle events = %v, want %v", causes, want)
+	}
+	if ev := f.report.Throttles[1]; ev.StatusCode != 429 || ev.RetryAfterMS != 10000 || ev.DelayMS != 4000 {
+		t.Errorf("429 event = %+v, want status 429, retry after 10s, delay 4s", ev)
+	}
+}
+
+func TestFetchThrottled(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "0")
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body><p>Hello</p></body></html>"))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name      string
+		adaptive  bool
+		want      Outcome
+		requests  int32
+		throttles int
+	}{
+		{name: "adaptive", adaptive: true, want: OutcomeSaved, requests: 2, throttles: 1},
+		{name: "off", adaptive: false, want: OutcomeFailed, requests: 1, throttles: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests.Store(0)
+			f := New(t.TempDir())
+			f.delay = 0
+			f.reporter = nil
+			f.SetAdaptiveThrottle(tt.adaptive)
+			if err := f.Fetch(server.URL + "/"); err != nil && tt.adaptive {
+				t.Fatalf("Fetch() returned error: %v", err)
+			}
+			report := f.Report()
+			if len(report.Pages) == 0 || report.Pages[0].Outcome != tt.want {
+				t.Errorf("pages = %+v, want the start page %s", report.Pages, tt.want)
+			}
+			if requests.Load() != tt.requests {
+				t.Errorf("%d requests, want %d", requests.Load(), tt.requests)
+			}
+			if len(report.Throttles) != tt.throttles {
+				t.Errorf("throttle events = %+v, want %d", report.Throttles, tt.throttles)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the base HTTP transport of the crawls, tuned to reuse
+// its connections to the hosts of a crawl rather than pay for a new
+// connection, TLS handshake, and host lookup per request.
+package fetcher
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultMaxConnsPerHost is the number of connections a transport of
+	// NewTransport opens to a host at most, and keeps idle for reuse.
+	DefaultMaxConnsPerHost = 8
+	// maxIdleConns is the number of idle connections kept across all hosts.
+	maxIdleConns = 100
+	// idleConnTimeout is how long an idle connection is kept for reuse.
+	idleConnTimeout = 90 * time.Second
+	// dialTimeout bounds the connection to a server.
+	dialTimeout = 30 * time.Second
+	// keepAlive is the interval of the TCP keep-alive probes of connections.
+	keepAlive = 30 * time.Second
+	// tlsHandshakeTimeout bounds the TLS handshake with a server.
+	tlsHandshakeTimeout = 10 * time.Second
+)
+
+// NewTransport returns the base transport of the crawls, which
+// ResolveTransport starts from: HTTP/2 is negotiated with the servers
+// supporting it, multiplexing the requests to a host over one connection;
+// up to DefaultMaxConnsPerHost connections are opened to a host, and as many
+// are kept idle between requests instead of the two of http.DefaultTransport,
+// so that the requests of a crawl reuse them; and host names are resolved
+// through dns (see DNSCache), or by the system resolver if dns is nil.
+// Proxies are taken from the environment, as by http.DefaultTransport.
+//
+// Share one transport between the clients of a crawl (pages, robots.txt,
+// HEAD probes, assets) so that they share its connections.
+func NewTransport(dns *DNSCache) *http.Transport {
+	dialer := &net.Dialer{Timeout: dialTimeout, KeepAlive: keepAlive}
+	dial := dialer.DialContext
+	if dns != nil {
+		dial = dns.DialContext(dialer)
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dial,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   DefaultMaxConnsPerHost,
+		MaxConnsPerHost:       DefaultMaxConnsPerHost,
+		IdleConnTimeout:       idleConnTimeout,
+		TLSHandshakeTimeout:   tlsHandshakeTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// ConfigureConnections sets the number of connections t, a transport returned
+// by ResolveTransport, opens to a host at most, and keeps idle for reuse, to
+// maxConnsPerHost (DefaultMaxConnsPerHost if 0), and disables HTTP/2 unless
+// http2, for servers whose HTTP/2 support is broken. Requests beyond the
+// limit wait for a connection to become available.
+func ConfigureConnections(t *http.Transport, maxConnsPerHost int, http2 bool) {
+	if maxConnsPerHost == 0 {
+		maxConnsPerHost = DefaultMaxConnsPerHost
+	}
+	t.MaxConnsPerHost = maxConnsPerHost
+	t.MaxIdleConnsPerHost = maxConnsPerHost
+	if !http2 {
+		t.ForceAttemptHTTP2 = false
+		// A non-nil empty map disables HTTP/2
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+}
@@ -0,0 +1,73 @@
+package fetcher
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestTransportReusesConnections(t *testing.T) {
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, r.Proto)
+	}))
+	server.EnableHTTP2 = true
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	tests := []struct {
+		name  string
+		http2 bool
+		want  string
+	}{
+		{name: "HTTP/2", http2: true, want: "HTTP/2.0"},
+		{name: "HTTP/2 disabled", http2: false, want: "HTTP/1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			atomic.StoreInt32(&conns, 0)
+			tr, err := ResolveTransport(nil, NewDNSCache())
+			if err != nil {
+				t.Fatal(err)
+			}
+			tr.TLSClientConfig = server.Client().Transport.(*http.Transport).TLSClientConfig.Clone()
+			ConfigureConnections(tr, 0, tt.http2)
+			defer tr.CloseIdleConnections()
+			client := &http.Client{Transport: tr}
+
+			for i := 0; i < 5; i++ {
+				resp, err := client.Get(server.URL)
+				if err != nil {
+					t.Fatalf("request %d failed: %v", i, err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if string(body) != tt.want {
+					t.Errorf("request %d protocol = %s, want %s", i, body, tt.want)
+				}
+			}
+			if n := atomic.LoadInt32(&conns); n != 1 {
+				t.Errorf("server accepted %d connections, want 1", n)
+			}
+		})
+	}
+}
+
+func TestConfigureConnections(t *testing.T) {
+	tr := NewTransport(nil)
+	if tr.MaxConnsPerHost != DefaultMaxConnsPerHost || tr.MaxIdleConnsPerHost != DefaultMaxConnsPerHost || !tr.ForceAttemptHTTP2 {
+		t.Errorf("NewTransport() = %d conns, %d idle per host, HTTP/2 %v", tr.MaxConnsPerHost, tr.MaxIdleConnsPerHost, tr.ForceAttemptHTTP2)
+	}
+	ConfigureConnections(tr, 2, true)
+	if tr.MaxConnsPerHost != 2 || tr.MaxIdleConnsPerHost != 2 || tr.TLSNextProto != nil {
+		t.Errorf("ConfigureConnections(2) = %d conns, %d idle per host", tr.MaxConnsPerHost, tr.MaxIdleConnsPerHost)
+	}
+}
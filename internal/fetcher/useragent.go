@@ -0,0 +1,134 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the User-Agent of the crawler, expanded from a template
+// with the version of the tool, a contact site operators can reach, and the
+// identifier of the run, and the identifiers of runs.
+package fetcher
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+const (
+	// ProductToken is the name the crawler goes by in its default User-Agent,
+	// which robots.txt User-agent lines name it by.
+	ProductToken = "site2skillgo"
+	// DefaultContact is the contact of the default User-Agent: the site of the
+	// project.
+	DefaultContact = "https://github.com/f4ah6o/site2skill-go"
+	// DefaultUserAgentTemplate is the template of the default User-Agent (see
+	// FormatUserAgent).
+	DefaultUserAgentTemplate = ProductToken + "/{version} (+{contact})"
+)
+
+var (
+	// placeholderPattern matches the placeholders of User-Agent templates.
+	placeholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+	// runIDPattern matches valid run identifiers.
+	runIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]*$`)
+)
+
+// UserAgent is the user agent string used by the fetcher: the default
+// template with the version of the tool and DefaultContact.
+var UserAgent = expandUserAgent(DefaultUserAgentTemplate, DefaultContact, "")
+
+// FormatUserAgent returns the User-Agent of template, an empty template
+// standing for DefaultUserAgentTemplate, with its placeholders replaced:
+//   - {version}: the version of the tool (see ToolVersion);
+//   - {contact}: contact, an http(s) URL or an email address where site
+//     operators can reach whoever runs the crawl, DefaultContact if empty;
+//   - {run_id}: runID, the identifier of the run (see NewRunID).
+//
+// The product token of the result, its first word up to a slash, is the name
+// robots.txt rules are matched against (see RobotsChecker).
+//
+// Returns an error if template has another placeholder or a control
+// character, if contact is neither a URL nor an email address, or if runID
+// is invalid (see ValidateRunID).
+func FormatUserAgent(template, contact, runID string) (string, error) {
+	if template == "" {
+		template = DefaultUserAgentTemplate
+	}
+	if strings.TrimSpace(template) == "" || strings.ContainsFunc(template, isControl) {
+		return "", fmt.Errorf("invalid user agent %q: must be a single line of text", template)
+	}
+	for _, p := range placeholderPattern.FindAllString(template, -1) {
+		if p != "{version}" && p != "{contact}" && p != "{run_id}" {
+			return "", fmt.Errorf("invalid user agent %q: unknown placeholder %s (expected {version}, {contact}, or {run_id})", template, p)
+		}
+	}
+	if contact == "" {
+		contact = DefaultContact
+	} else if err := ValidateContact(contact); err != nil {
+		return "", err
+	}
+	if err := ValidateRunID(runID); err != nil {
+		return "", err
+	}
+	return expandUserAgent(template, contact, runID), nil
+}
+
+// expandUserAgent replaces the placeholders of template (see FormatUserAgent).
+func expandUserAgent(template, contact, runID string) string {
+	return strings.NewReplacer("{version}", ToolVersion(), "{contact}", contact, "{run_id}", runID).Replace(template)
+}
+
+// ValidateContact returns an error if contact, the contact of a User-Agent,
+// is neither an http(s) URL nor an email address.
+func ValidateContact(contact string) error {
+	if u, err := url.Parse(contact); err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" {
+		return nil
+	}
+	if addr, err := mail.ParseAddress(contact); err == nil && addr.Name == "" && addr.Address == contact {
+		return nil
+	}
+	return fmt.Errorf("invalid contact %q: must be an http(s) URL or an email address", contact)
+}
+
+// ValidateRunID returns an error if runID, the identifier of a run, isn't
+// made of letters, digits, dots, dashes, and underscores.
+func ValidateRunID(runID string) error {
+	if !runIDPattern.MatchString(runID) {
+		return fmt.Errorf("invalid run ID %q: must be made of letters, digits, '.', '-', and '_'", runID)
+	}
+	return nil
+}
+
+// isControl reports whether r is a control character, which header values
+// can't hold.
+func isControl(r rune) bool {
+	return r < 0x20 || r == 0x7f
+}
+
+// productToken returns the product token of userAgent: its first word up to
+// a slash ("site2skillgo" of "site2skillgo/1.0 (+https://...)").
+func productToken(userAgent string) string {
+	token, _, _ := strings.Cut(strings.TrimSpace(userAgent), " ")
+	token, _, _ = strings.Cut(token, "/")
+	return token
+}
+
+// ToolVersion returns the module version of the running binary, without its
+// "v" prefix, or "devel" if it isn't known.
+func ToolVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return strings.TrimPrefix(info.Main.Version, "v")
+	}
+	return "devel"
+}
+
+// NewRunID returns a new identifier of a run: the UTC time it started at and
+// random hexadecimal digits, such as "20240301T120000Z-3f9a2c1b", which sorts
+// runs by time and tells runs started together apart.
+func NewRunID() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return time.Now().UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(b)
+}
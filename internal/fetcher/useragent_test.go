@@ -0,0 +1,102 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestFormatUserAgent(t *testing.T) {
+	version := ToolVersion()
+	tests := []struct {
+		name     string
+		template string
+		contact  string
+		runID    string
+		want     string
+		wantErr  bool
+	}{
+		{name: "default", want: UserAgent},
+		{name: "default with a contact URL", contact: "https://example.com/crawler", want: "site2skillgo/" + version + " (+https://example.com/crawler)"},
+		{name: "contact email", template: "DocsBot/{version} ({contact})", contact: "ops@example.com", want: "DocsBot/" + version + " (ops@example.com)"},
+		{name: "run ID", template: "site2skillgo/{version} (+{contact}; run {run_id})", runID: "20240301T120000Z-3f9a2c1b", want: "site2skillgo/" + version + " (+" + DefaultContact + "; run 20240301T120000Z-3f9a2c1b)"},
+		{name: "no placeholders", template: "DocsBot/2.0", want: "DocsBot/2.0"},
+		{name: "unknown placeholder", template: "site2skillgo/{release}", wantErr: true},
+		{name: "header injection", template: "site2skillgo/1.0\r\nX-Evil: 1", wantErr: true},
+		{name: "blank template", template: "  ", wantErr: true},
+		{name: "invalid contact", contact: "ftp://example.com", wantErr: true},
+		{name: "contact with a name", contact: "Ops <ops@example.com>", wantErr: true},
+		{name: "invalid run ID", runID: "run 1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FormatUserAgent(tt.template, tt.contact, tt.runID)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FormatUserAgent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("FormatUserAgent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRunID(t *testing.T) {
+	a, b := NewRunID(), NewRunID()
+	if !regexp.MustCompile(`^\d{8}T\d{6}Z-[0-9a-f]{8}$`).MatchString(a) {
+		t.Errorf("NewRunID() = %q, want a time and random digits", a)
+	}
+	if a == b {
+		t.Errorf("NewRunID() returned %q twice", a)
+	}
+	if err := ValidateRunID(a); err != nil {
+		t.Errorf("ValidateRunID(%q) returned error: %v", a, err)
+	}
+}
+
+func TestFetchUserAgent(t *testing.T) {
+	const crawler = "DocsBot/2.0 (+https://github.com/example)"
+	agents := make(map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents[r.URL.Path] = r.Header.Get("User-Agent")
+		if r.URL.Path == "/robots.txt" {
+			// The contact URL of the User-Agent names no other crawler
+			w.Write([]byte("User-agent: github\nDisallow: /\n\nUser-agent: docsbot\nDisallow: /docs/private\n"))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body><a href="/docs/private">Private</a></body></html>`))
+	}))
+	defer server.Close()
+
+	f := New(t.TempDir())
+	f.delay = 0
+	f.SetUserAgent(crawler)
+	if err := f.SetDevice(DeviceMobile); err != nil {
+		t.Fatalf("SetDevice() returned error: %v", err)
+	}
+	f.SetRunID("run-1")
+	if err := f.Fetch(server.URL + "/docs/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+
+	mobile := mobileBrowser + " " + crawler
+	if agents["/robots.txt"] != crawler {
+		t.Errorf("robots.txt User-Agent = %q, want %q", agents["/robots.txt"], crawler)
+	}
+	if agents["/docs/"] != mobile {
+		t.Errorf("page User-Agent = %q, want %q", agents["/docs/"], mobile)
+	}
+	if _, ok := agents["/docs/private"]; ok {
+		t.Error("page disallowed for the product token was fetched")
+	}
+	report := f.Report()
+	if report.RunID != "run-1" || report.UserAgent != mobile {
+		t.Errorf("report run ID and User-Agent = %q, %q, want %q, %q", report.RunID, report.UserAgent, "run-1", mobile)
+	}
+	if !strings.HasPrefix(f.UserAgent(), mobileBrowser) {
+		t.Errorf("UserAgent() = %q, want the mobile one", f.UserAgent())
+	}
+}
@@ -0,0 +1,151 @@
+// Package fetcher provides website crawling and downloading functionality.
+// This file implements the selection of one version of versioned
+// documentation sites (/v2/, /3.11/, /latest/ path segments).
+package fetcher
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// versionSegmentPattern matches path segments naming a documentation version:
+// "v2", "v1.4", "3.11", "2.x", or a channel such as "latest" or "stable".
+// Bare numbers ("/2024/", "/issues/123/") are not versions.
+var versionSegmentPattern = regexp.MustCompile(`^(?i:v\d+(\.\d+)*|\d+\.\d+(\.\d+)*|\d+\.x|latest|stable|current|next|nightly)$`)
+
+// VersionConfig configures version selection for documentation sites that
+// publish each version under its own path segment (e.g., /docs/v1/ and /docs/v2/).
+type VersionConfig struct {
+	// Priority lists the preferred versions, most preferred first (e.g.,
+	// ["latest", "stable", "v2"]). Entries are recognized as version segments
+	// even if they don't look like versions (e.g., "main").
+	Priority []string
+}
+
+// SetVersionConfig enables version selection: the first URL crawled under a
+// path prefix followed by a version segment (e.g., /docs/v1/) selects the
+// version of that prefix, the first of cfg.Priority that exists for the URL or
+// else the URL's own version, and pages of other versions under the prefix are
+// skipped as "other_version". The version of each saved page is recorded in
+// PageRecord.Version. If cfg is nil, every version is crawled.
+func (f *Fetcher) SetVersionConfig(cfg *VersionConfig) {
+	f.versionConfig = cfg
+}
+
+// ExtractVersion returns the first path segment of u naming a version (see
+// VersionConfig.Priority), with the path before and after it: for
+// /docs/v2/guide, "/docs/", "v2", and "/guide". Returns an empty version if
+// the path has none.
+func ExtractVersion(u *url.URL, cfg *VersionConfig) (prefix, version, rest string) {
+	if u == nil {
+		return "", "", ""
+	}
+	segments := strings.Split(u.Path, "/")
+	for i, segment := range segments {
+		if segment == "" || !cfg.isVersion(segment) {
+			continue
+		}
+		prefix = strings.Join(segments[:i], "/") + "/"
+		if i+1 < len(segments) {
+			rest = "/" + strings.Join(segments[i+1:], "/")
+		}
+		return prefix, segment, rest
+	}
+	return "", "", u.Path
+}
+
+// isVersion reports whether a path segment names a version.
+func (c *VersionConfig) isVersion(segment string) bool {
+	if versionSegmentPattern.MatchString(segment) {
+		return true
+	}
+	if c == nil {
+		return false
+	}
+	for _, v := range c.Priority {
+		if strings.EqualFold(v, segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// selectVersion applies version selection to targetURL. Returns the URL to
+// crawl instead, which differs from targetURL when targetURL is the first URL
+// of its prefix and a preferred version of it exists, or "" if targetURL
+// belongs to a version other than the one selected for its prefix.
+func (f *Fetcher) selectVersion(ctx context.Context, targetURL string) string {
+	u, err := url.Parse(targetURL)
+	if err != nil {
+		return targetURL
+	}
+	prefix, version, rest := ExtractVersion(u, f.versionConfig)
+	if version == "" {
+		return targetURL
+	}
+	key := u.Host + prefix
+
+	f.mu.Lock()
+	selected, ok := f.versions[key]
+	f.mu.Unlock()
+	if ok {
+		if strings.EqualFold(selected, version) {
+			return targetURL
+		}
+		return ""
+	}
+
+	// The first URL of the prefix selects its version
+	selected = version
+	for _, candidate := range f.versionConfig.Priority {
+		if strings.EqualFold(candidate, version) {
+			break
+		}
+		probe := *u
+		probe.Path = prefix + candidate + rest
+		probe.RawPath = ""
+		if exists, _ := f.checkURLExists(ctx, probe.String()); exists {
+			selected = candidate
+			break
+		}
+	}
+
+	f.mu.Lock()
+	prev, raced := f.versions[key]
+	if !raced {
+		f.versions[key] = selected
+	}
+	f.mu.Unlock()
+	if raced {
+		// Another URL of the prefix selected the version first
+		if strings.EqualFold(prev, version) {
+			return targetURL
+		}
+		return ""
+	}
+	slog.Info("Version selected", "version", selected, "prefix", u.Host+prefix)
+
+	if strings.EqualFold(selected, version) {
+		return targetURL
+	}
+	u.Path = prefix + selected + rest
+	u.RawPath = ""
+	return u.String()
+}
+
+// pageVersion returns the version segment of a page URL when version
+// selection is enabled, or "".
+func (f *Fetcher) pageVersion(pageURL string) string {
+	if f.versionConfig == nil {
+		return ""
+	}
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return ""
+	}
+	_, version, _ := ExtractVersion(u, f.versionConfig)
+	return version
+}
@@ -0,0 +1,95 @@
+package fetcher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExtractVersion(t *testing.T) {
+	cfg := &VersionConfig{Priority: []string{"main"}}
+	tests := []struct {
+		path        string
+		wantPrefix  string
+		wantVersion string
+		wantRest    string
+	}{
+		{"/docs/v2/guide", "/docs/", "v2", "/guide"},
+		{"/docs/v1.4/", "/docs/", "v1.4", "/"},
+		{"/3.11/library/os.html", "/", "3.11", "/library/os.html"},
+		{"/en/latest/intro", "/en/", "latest", "/intro"},
+		{"/docs/2.x", "/docs/", "2.x", ""},
+		{"/docs/main/api", "/docs/", "main", "/api"},
+		{"/blog/2024/post", "", "", "/blog/2024/post"},
+		{"/issues/123", "", "", "/issues/123"},
+		{"/docs/guide", "", "", "/docs/guide"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			prefix, version, rest := ExtractVersion(&url.URL{Path: tt.path}, cfg)
+			if prefix != tt.wantPrefix || version != tt.wantVersion || rest != tt.wantRest {
+				t.Errorf("ExtractVersion() = %q, %q, %q; want %q, %q, %q",
+					prefix, version, rest, tt.wantPrefix, tt.wantVersion, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestFetchVersionPriority(t *testing.T) {
+	pages := map[string]string{
+		"/docs/v1/":      `<a href="/docs/v1/guide">Guide</a><a href="/docs/v2/">v2</a>`,
+		"/docs/v1/guide": `Guide v1`,
+		"/docs/v2/":      `<a href="/docs/v2/guide">Guide</a><a href="/docs/v1/">v1</a>`,
+		"/docs/v2/guide": `Guide v2`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body>" + body + "</body></html>"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	f := New(dir)
+	f.delay = 0
+	f.SetVersionConfig(&VersionConfig{Priority: []string{"v2"}})
+	if err := f.Fetch(server.URL + "/docs/v1/"); err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+
+	host, _ := url.Parse(server.URL)
+	crawlDir := filepath.Join(dir, "crawl", host.Host)
+	for _, path := range []string{"docs/v2.html", "docs/v2/guide.html"} {
+		if _, err := os.Stat(filepath.Join(crawlDir, filepath.FromSlash(path))); err != nil {
+			t.Errorf("%s not saved: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(crawlDir, "docs", "v1")); err == nil {
+		t.Error("pages of version v1 were saved")
+	}
+
+	skipped := 0
+	for _, p := range f.Report().Pages {
+		switch p.Outcome {
+		case OutcomeSaved:
+			if p.Version != "v2" {
+				t.Errorf("saved page %s has version %q, want v2", p.URL, p.Version)
+			}
+		case OutcomeSkipped:
+			if p.Reason == "other_version" {
+				skipped++
+			}
+		}
+	}
+	if skipped == 0 {
+		t.Error("links to version v1 were not reported as other_version")
+	}
+}
@@ -0,0 +1,326 @@
+// Package golden runs golden tests of the pipeline against recorded sites: a
+// fixture is a directory holding the crawl of a site recorded as a WARC
+// archive, the options of the build, and a snapshot of the skill built from
+// it. Verifying a fixture builds the skill again from the archive, without
+// any network access, and compares it with the snapshot, so that a change of
+// the conversion rules shows up as a diff of the documents it changes.
+//
+// Layout of a fixture:
+//
+//	fixture/
+//	  ├── fixture.json     # Start URL, skill name, build options, and timestamp (see Fixture)
+//	  ├── crawl.warc.gz    # Every request and response of the recorded crawl
+//	  └── snapshot/        # The files of the skill built from the crawl
+package golden
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/f4ah6o/site2skill-go/internal/textdiff"
+	"github.com/f4ah6o/site2skill-go/pkg/site2skill"
+)
+
+const (
+	// FixtureFile is the name of the description of a fixture in its directory.
+	FixtureFile = "fixture.json"
+	// ArchiveFile is the name of the WARC archive of the crawl of a fixture.
+	ArchiveFile = "crawl.warc.gz"
+	// SnapshotDir is the name of the directory of the expected skill of a fixture.
+	SnapshotDir = "snapshot"
+)
+
+// Fixture describes a recorded site, as written to fixture.json.
+type Fixture struct {
+	// URL is the start URL of the crawl.
+	URL string `json:"url"`
+	// SkillName is the name of the skill built from the crawl.
+	SkillName string `json:"skill_name"`
+	// Args are the generate options of the build, replayed when the fixture
+	// is verified; empty for the default options.
+	Args []string `json:"args,omitempty"`
+	// Timestamp is the time recorded in the skill (fetched_at, manifest,
+	// ...) instead of the time of the build, so that builds of the fixture
+	// are identical.
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// LoadFixture reads the fixture.json of the fixture directory dir.
+func LoadFixture(dir string) (*Fixture, error) {
+	data, err := os.ReadFile(filepath.Join(dir, FixtureFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+	var fx Fixture
+	if err := json.Unmarshal(data, &fx); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", FixtureFile, err)
+	}
+	if fx.URL == "" || fx.SkillName == "" {
+		return nil, fmt.Errorf("%s has no url or skill_name", FixtureFile)
+	}
+	return &fx, nil
+}
+
+// Save writes fx as the fixture.json of the fixture directory dir.
+func (fx *Fixture) Save(dir string) error {
+	data, err := json.MarshalIndent(fx, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, FixtureFile), append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return nil
+}
+
+// Builder builds the skill of fx in workDir, an empty directory, and returns
+// the directory of the skill. With replay, the crawl is answered by the WARC
+// archive at archive instead of the site; otherwise, it is recorded into it.
+type Builder func(ctx context.Context, fx *Fixture, archive string, replay bool, workDir string) (string, error)
+
+// PipelineBuilder returns a Builder running the pipeline in process with cfg,
+// which holds the build options of the fixtures (conversion, chunking,
+// assets, ...), for golden tests written in Go. The fields that locate the
+// crawl, the skill, and the time of the build are set from the fixture; the
+// skill is built in the format of the first of cfg.Targets, Claude if none.
+func PipelineBuilder(cfg site2skill.Config) Builder {
+	return func(ctx context.Context, fx *Fixture, archive string, replay bool, workDir string) (string, error) {
+		format := site2skill.FormatClaude
+		if len(cfg.Targets) > 0 {
+			format = cfg.Targets[0].Format
+		}
+		c := cfg
+		c.URL = fx.URL
+		c.SkillName = fx.SkillName
+		c.Targets = []site2skill.Target{{Format: format, Dir: filepath.Join(workDir, "out")}}
+		c.TempDir = filepath.Join(workDir, "build")
+		c.NoCache = true
+		c.Timestamp = fx.Timestamp
+		if replay {
+			c.FromWARC, c.Offline = archive, true
+		} else {
+			c.WARC = archive
+		}
+		if _, err := site2skill.Build(ctx, c); err != nil {
+			return "", err
+		}
+		return filepath.Join(workDir, "out", fx.SkillName), nil
+	}
+}
+
+// Record records the crawl of fx into the fixture directory dir with build,
+// and writes fx and the snapshot of the skill built from it, replacing those
+// of an earlier recording. A zero Timestamp of fx is set to the current time.
+func Record(ctx context.Context, dir string, fx *Fixture, build Builder) error {
+	if fx.Timestamp.IsZero() {
+		fx.Timestamp = time.Now().UTC().Truncate(time.Second)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	archive, err := filepath.Abs(filepath.Join(dir, ArchiveFile))
+	if err != nil {
+		return err
+	}
+	// A recording replaces the archive rather than appending to it
+	if err := os.Remove(archive); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove the earlier recording: %w", err)
+	}
+	files, err := buildSnapshot(ctx, fx, archive, false, build)
+	if err != nil {
+		return err
+	}
+	if err := fx.Save(dir); err != nil {
+		return err
+	}
+	return WriteSnapshot(dir, files)
+}
+
+// Result is the outcome of verifying a fixture.
+type Result struct {
+	// Fixture is the directory of the fixture.
+	Fixture string `json:"fixture"`
+	// Differences lists the files of the skill that differ from the
+	// snapshot, by path; empty if the skill matches it.
+	Differences []Difference `json:"differences,omitempty"`
+	// Updated reports whether the snapshot was replaced by the skill.
+	Updated bool `json:"updated,omitempty"`
+}
+
+// Passed reports whether the skill matched the snapshot.
+func (r *Result) Passed() bool {
+	return len(r.Differences) == 0
+}
+
+// Verify builds the skill of the fixture in directory dir again from its
+// archive with build, and compares it with the snapshot. With update, the
+// snapshot is replaced by the skill if they differ, to accept an intended
+// change of the output.
+func Verify(ctx context.Context, dir string, build Builder, update bool) (*Result, error) {
+	fx, err := LoadFixture(dir)
+	if err != nil {
+		return nil, err
+	}
+	archive, err := filepath.Abs(filepath.Join(dir, ArchiveFile))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(archive); err != nil {
+		return nil, fmt.Errorf("failed to read the recorded crawl: %w", err)
+	}
+	want, err := ReadSnapshot(dir)
+	if err != nil {
+		return nil, err
+	}
+	got, err := buildSnapshot(ctx, fx, archive, true, build)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Fixture: dir, Differences: Compare(want, got)}
+	if update && !result.Passed() {
+		if err := WriteSnapshot(dir, got); err != nil {
+			return nil, err
+		}
+		result.Updated = true
+	}
+	return result, nil
+}
+
+// buildSnapshot builds the skill of fx with build in a temporary directory,
+// removed afterwards, and returns its snapshot.
+func buildSnapshot(ctx context.Context, fx *Fixture, archive string, replay bool, build Builder) (map[string][]byte, error) {
+	workDir, err := os.MkdirTemp("", "site2skill-golden-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create build directory: %w", err)
+	}
+	defer os.RemoveAll(workDir)
+	skillDir, err := build(ctx, fx, archive, replay, workDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the skill: %w", err)
+	}
+	return Snapshot(skillDir)
+}
+
+// Snapshot returns the files of the skill directory skillDir by their
+// slash-separated paths, without the hidden directories of the search index
+// and embeddings, which are derived from the documents.
+func Snapshot(skillDir string) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	err := filepath.WalkDir(skillDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != skillDir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(skillDir, path)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read skill: %w", err)
+	}
+	return files, nil
+}
+
+// ReadSnapshot returns the files of the snapshot of the fixture directory dir.
+func ReadSnapshot(dir string) (map[string][]byte, error) {
+	snapshotDir := filepath.Join(dir, SnapshotDir)
+	if _, err := os.Stat(snapshotDir); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	return Snapshot(snapshotDir)
+}
+
+// WriteSnapshot replaces the snapshot of the fixture directory dir with files.
+func WriteSnapshot(dir string, files map[string][]byte) error {
+	snapshotDir := filepath.Join(dir, SnapshotDir)
+	if err := os.RemoveAll(snapshotDir); err != nil {
+		return fmt.Errorf("failed to remove snapshot: %w", err)
+	}
+	for name, data := range files {
+		path := filepath.Join(snapshotDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create snapshot directory: %w", err)
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return fmt.Errorf("failed to write snapshot: %w", err)
+		}
+	}
+	return nil
+}
+
+// Change kinds of a Difference.
+const (
+	// ChangeAdded is a file of the skill missing from the snapshot.
+	ChangeAdded = "added"
+	// ChangeRemoved is a file of the snapshot missing from the skill.
+	ChangeRemoved = "removed"
+	// ChangeModified is a file whose content differs from the snapshot.
+	ChangeModified = "modified"
+)
+
+// Difference is a file of a skill that differs from the snapshot.
+type Difference struct {
+	// Path is the slash-separated path of the file in the skill.
+	Path string `json:"path"`
+	// Change is ChangeAdded, ChangeRemoved, or ChangeModified.
+	Change string `json:"change"`
+	// Diff is the unified diff from the snapshot to the skill, for text
+	// files; empty for binary files.
+	Diff string `json:"diff,omitempty"`
+}
+
+// Compare returns the differences of got, the files of a skill, from want,
+// those of the snapshot, sorted by path.
+func Compare(want, got map[string][]byte) []Difference {
+	var diffs []Difference
+	for name, data := range got {
+		old, ok := want[name]
+		switch {
+		case !ok:
+			diffs = append(diffs, Difference{Path: name, Change: ChangeAdded, Diff: diffText(name, nil, data)})
+		case !bytes.Equal(old, data):
+			diffs = append(diffs, Difference{Path: name, Change: ChangeModified, Diff: diffText(name, old, data)})
+		}
+	}
+	for name, data := range want {
+		if _, ok := got[name]; !ok {
+			diffs = append(diffs, Difference{Path: name, Change: ChangeRemoved, Diff: diffText(name, data, nil)})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].Path < diffs[j].Path })
+	return diffs
+}
+
+// diffText returns the unified diff from old to data, the contents of the
+// file name in the snapshot and the skill, or "" if either isn't text.
+func diffText(name string, old, data []byte) string {
+	if !utf8.Valid(old) || !utf8.Valid(data) {
+		return ""
+	}
+	return textdiff.Unified("snapshot/"+name, "skill/"+name, string(old), string(data), textdiff.DefaultContext)
+}
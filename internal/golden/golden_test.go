@@ -0,0 +1,134 @@
+package golden
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/selftest"
+	"github.com/f4ah6o/site2skill-go/pkg/site2skill"
+)
+
+// update replaces the snapshots of the fixtures of testdata with the skills
+// built from them: go test ./internal/golden -update
+var update = flag.Bool("update", false, "update the snapshots of the golden fixtures")
+
+func TestFixtures(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("testdata", "*", FixtureFile))
+	if err != nil || len(dirs) == 0 {
+		t.Fatalf("no fixtures in testdata: %v", err)
+	}
+	for _, file := range dirs {
+		dir := filepath.Dir(file)
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			result, err := Verify(context.Background(), dir, PipelineBuilder(site2skill.Config{}), *update)
+			if err != nil {
+				t.Fatalf("Verify() returned error: %v", err)
+			}
+			if result.Updated {
+				t.Logf("updated the snapshot of %s", dir)
+				return
+			}
+			for _, d := range result.Differences {
+				t.Errorf("%s %s (run go test ./internal/golden -update to accept it):\n%s", d.Path, d.Change, d.Diff)
+			}
+		})
+	}
+}
+
+func TestRecordAndVerify(t *testing.T) {
+	server, err := selftest.Serve()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	build := PipelineBuilder(site2skill.Config{})
+	fx := &Fixture{URL: server.URL, SkillName: "widget"}
+	if err := Record(ctx, dir, fx, build); err != nil {
+		t.Fatalf("Record() returned error: %v", err)
+	}
+	if fx.Timestamp.IsZero() {
+		t.Error("Record() left the timestamp unset")
+	}
+	for _, name := range []string{FixtureFile, ArchiveFile, filepath.Join(SnapshotDir, "SKILL.md"), filepath.Join(SnapshotDir, "docs", "getting-started.md")} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s not recorded: %v", name, err)
+		}
+	}
+
+	// The site is no longer needed
+	server.Close()
+	result, err := Verify(ctx, dir, build, false)
+	if err != nil {
+		t.Fatalf("Verify() returned error: %v", err)
+	}
+	if !result.Passed() {
+		t.Fatalf("Verify() of the recorded fixture = %+v, want no differences", result.Differences)
+	}
+
+	// A changed snapshot shows up as a diff, until it is updated
+	doc := filepath.Join(dir, SnapshotDir, "docs", "getting-started.md")
+	content, _ := os.ReadFile(doc)
+	if err := os.WriteFile(doc, []byte(strings.Replace(string(content), "frobnicate", "frob", 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(dir, SnapshotDir, "toc.md")); err != nil {
+		t.Fatal(err)
+	}
+	result, err = Verify(ctx, dir, build, false)
+	if err != nil {
+		t.Fatalf("Verify() returned error: %v", err)
+	}
+	if len(result.Differences) != 2 || result.Differences[0].Path != "docs/getting-started.md" || result.Differences[0].Change != ChangeModified ||
+		!strings.Contains(result.Differences[0].Diff, "+widget install --frobnicate") || result.Differences[1].Path != "toc.md" || result.Differences[1].Change != ChangeAdded {
+		t.Errorf("Verify() differences = %+v, want the document modified and toc.md added", result.Differences)
+	}
+	if result, err = Verify(ctx, dir, build, true); err != nil || !result.Updated {
+		t.Fatalf("Verify() with update = %+v, %v; want the snapshot updated", result, err)
+	}
+	if result, err = Verify(ctx, dir, build, false); err != nil || !result.Passed() {
+		t.Errorf("Verify() after the update = %+v, %v; want no differences", result, err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	want := map[string][]byte{"a.md": []byte("one\n"), "b.md": []byte("two\n"), "logo.png": {0x89, 0xff}}
+	got := map[string][]byte{"a.md": []byte("one\n"), "c.md": []byte("three\n"), "logo.png": {0x89, 0xfe}}
+	diffs := Compare(want, got)
+	if len(diffs) != 3 {
+		t.Fatalf("Compare() = %+v, want 3 differences", diffs)
+	}
+	for i, w := range []Difference{{Path: "b.md", Change: ChangeRemoved}, {Path: "c.md", Change: ChangeAdded}, {Path: "logo.png", Change: ChangeModified}} {
+		if diffs[i].Path != w.Path || diffs[i].Change != w.Change {
+			t.Errorf("difference %d = %+v, want %s %s", i, diffs[i], w.Path, w.Change)
+		}
+	}
+	if !strings.Contains(diffs[1].Diff, "+three") || diffs[2].Diff != "" {
+		t.Errorf("diffs = %q, %q; want a text diff and none for the binary file", diffs[1].Diff, diffs[2].Diff)
+	}
+}
+
+func TestLoadFixture(t *testing.T) {
+	dir := t.TempDir()
+	fx := &Fixture{URL: "https://example.com/docs/", SkillName: "example", Args: []string{"--chunk-tokens", "8000"}, Timestamp: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}
+	if err := fx.Save(dir); err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadFixture(dir)
+	if err != nil || got.URL != fx.URL || got.SkillName != fx.SkillName || len(got.Args) != 2 || !got.Timestamp.Equal(fx.Timestamp) {
+		t.Errorf("LoadFixture() = %+v, %v; want %+v", got, err, fx)
+	}
+	if err := os.WriteFile(filepath.Join(dir, FixtureFile), []byte(`{"url": "https://example.com/"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFixture(dir); err == nil {
+		t.Error("LoadFixture() accepted a fixture without a skill name")
+	}
+}
@@ -0,0 +1,410 @@
+// Package httpcache provides an on-disk HTTP cache implemented as an http.RoundTripper.
+// Responses are stored per URL, and per value of the request headers their Vary
+// header names, and revalidated with conditional requests
+// (If-None-Match / If-Modified-Since), so repeated runs only transfer bytes that changed.
+package httpcache
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+)
+
+// StatusHeader is added to every response passing through the cache.
+// Its value is one of the Status* constants and is useful for crawl reports.
+const StatusHeader = "X-Site2skill-Cache"
+
+const (
+	// StatusMiss means the response was fetched from the network and not served from cache.
+	StatusMiss = "miss"
+	// StatusHit means the response was served from cache without contacting the origin.
+	StatusHit = "hit"
+	// StatusRevalidated means the origin answered 304 Not Modified and the cached body was served.
+	StatusRevalidated = "revalidated"
+)
+
+// ErrOffline is wrapped by the errors of the requests an offline Transport
+// can't answer from its entries.
+var ErrOffline = errors.New("not in the HTTP cache, and offline")
+
+// Transport is an http.RoundTripper that caches successful GET responses on disk.
+//
+// Each cached URL is stored as two files in Dir: <key>.meta (JSON metadata) and
+// <key>.body (raw response body), where key is the SHA-256 of the request URL.
+// A response with a Vary header is stored under a key hashing the URL and the
+// values of the request headers it names, such as Accept-Language for a page
+// negotiated from the locale priority, and <key>.meta of the URL points at it,
+// so that a request with other values is neither served nor revalidated with
+// the entry of another variant. Responses with "Vary: *" aren't stored.
+// Entries carrying an ETag or Last-Modified validator are revalidated on every use;
+// entries without validators are served while fresh according to Cache-Control max-age.
+// With FreshFor set, every entry stored or revalidated within that window is
+// served without contacting the origin.
+// Requests with a Range header and non-GET requests bypass the cache, and so
+// do the responses larger than MaxBodyBytes.
+type Transport struct {
+	// Dir is the directory where cache entries are stored.
+	Dir string
+	// Base is the underlying transport used for network requests.
+	// If nil, http.DefaultTransport is used.
+	Base http.RoundTripper
+	// Offline replays the cache without contacting any origin: every entry is
+	// served as is, without revalidation, and requests without one fail with
+	// an error wrapping ErrOffline.
+	Offline bool
+	// FreshFor serves the entries stored or last revalidated less than this
+	// long ago without contacting the origin, whatever their validators and
+	// Cache-Control, so that a recurring crawl re-checks only the pages older
+	// than it. Zero revalidates every entry with validators on every use.
+	FreshFor time.Duration
+	// MaxBodyBytes is the size of the largest body stored. A response
+	// declaring a larger Content-Length, or whose body turns out larger while
+	// it is buffered, is passed through unbuffered and isn't stored, so that
+	// the caller's own size limit aborts its download. Zero stores every body.
+	MaxBodyBytes int64
+	// now returns the current time; overridable in tests
+	now func() time.Time
+}
+
+// entry is the on-disk metadata for a cached response.
+type entry struct {
+	URL        string      `json:"url"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	StoredAt   time.Time   `json:"stored_at"`
+	// Vary lists the request headers the responses of URL vary by, in the
+	// entry stored under the key of the URL, which then has no response
+	// and points at the entries of the variants (see variantKey)
+	Vary []string `json:"vary,omitempty"`
+}
+
+// New creates a caching Transport storing entries in dir and delegating network requests to base.
+// If base is nil, http.DefaultTransport is used.
+func New(dir string, base http.RoundTripper) *Transport {
+	return &Transport{
+		Dir:  dir,
+		Base: base,
+		now:  time.Now,
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		if t.Offline {
+			return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL, ErrOffline)
+		}
+		return t.roundTripNetwork(req, StatusMiss)
+	}
+
+	key, cached := t.lookup(req)
+
+	if t.Offline {
+		if cached == nil {
+			return nil, fmt.Errorf("GET %s: %w", req.URL, ErrOffline)
+		}
+		return t.cachedResponse(req, key, cached, StatusHit)
+	}
+
+	if cached != nil && (t.FreshFor > 0 && t.now().Before(cached.StoredAt.Add(t.FreshFor)) ||
+		!hasValidators(cached.Header) && t.isFresh(cached)) {
+		return t.cachedResponse(req, key, cached, StatusHit)
+	}
+
+	netReq := req
+	if cached != nil && hasValidators(cached.Header) {
+		netReq = req.Clone(req.Context())
+		if etag := cached.Header.Get("ETag"); etag != "" {
+			netReq.Header.Set("If-None-Match", etag)
+		}
+		if lm := cached.Header.Get("Last-Modified"); lm != "" {
+			netReq.Header.Set("If-Modified-Since", lm)
+		}
+	}
+
+	resp, err := t.base().RoundTrip(netReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+		// The revalidation starts the freshness window again
+		if t.FreshFor > 0 {
+			cached.StoredAt = t.now().UTC()
+			_ = t.storeMeta(key, cached)
+		}
+		return t.cachedResponse(req, key, cached, StatusRevalidated)
+	}
+
+	if resp.StatusCode != http.StatusOK || isNoStore(resp.Header) {
+		resp.Header.Set(StatusHeader, StatusMiss)
+		return resp, nil
+	}
+
+	if t.MaxBodyBytes > 0 && resp.ContentLength > t.MaxBodyBytes {
+		resp.Header.Set(StatusHeader, StatusMiss)
+		return resp, nil
+	}
+	body, ok, err := t.readBody(resp)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		resp.Header.Set(StatusHeader, StatusMiss)
+		return resp, nil
+	}
+
+	// Session cookies are credentials and are never written to disk
+	header := resp.Header.Clone()
+	header.Del("Set-Cookie")
+
+	// Failing to persist an entry must never fail the request itself
+	if vary, ok := varyNames(header); ok {
+		key = cacheKey(req.URL.String())
+		if len(vary) > 0 {
+			key = variantKey(req, vary)
+		}
+		err := t.store(key, &entry{
+			URL:        req.URL.String(),
+			StatusCode: resp.StatusCode,
+			Header:     header,
+			StoredAt:   t.now().UTC(),
+		}, body)
+		if err == nil && len(vary) > 0 {
+			_ = t.storeMeta(cacheKey(req.URL.String()), &entry{URL: req.URL.String(), Vary: vary})
+		}
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set(StatusHeader, StatusMiss)
+	return resp, nil
+}
+
+// readBody reads and closes the body of resp to store it. If the body is
+// larger than MaxBodyBytes, it reports false and replaces the body of resp
+// with one yielding the bytes read so far and then the rest of the body.
+func (t *Transport) readBody(resp *http.Response) ([]byte, bool, error) {
+	if t.MaxBodyBytes <= 0 {
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return body, err == nil, err
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, t.MaxBodyBytes+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, false, err
+	}
+	if int64(len(body)) > t.MaxBodyBytes {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return nil, false, nil
+	}
+	resp.Body.Close()
+	return body, true, nil
+}
+
+// Clear removes all cache entries.
+func (t *Transport) Clear() error {
+	return os.RemoveAll(t.Dir)
+}
+
+// base returns the configured underlying transport or http.DefaultTransport.
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// roundTripNetwork forwards req to the underlying transport and tags the response.
+func (t *Transport) roundTripNetwork(req *http.Request, status string) (*http.Response, error) {
+	resp, err := t.base().RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Header.Set(StatusHeader, status)
+	return resp, nil
+}
+
+// cachedResponse builds an *http.Response for req from a cached entry.
+func (t *Transport) cachedResponse(req *http.Request, key string, e *entry, status string) (*http.Response, error) {
+	body, err := os.ReadFile(filepath.Join(t.Dir, key+".body"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached body: %w", err)
+	}
+
+	header := e.Header.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	header.Set(StatusHeader, status)
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// isFresh reports whether an entry without validators may be served without contacting the origin.
+func (t *Transport) isFresh(e *entry) bool {
+	maxAge, ok := parseMaxAge(e.Header.Get("Cache-Control"))
+	if !ok {
+		return false
+	}
+	return t.now().Before(e.StoredAt.Add(maxAge))
+}
+
+// lookup returns the key of the entry of req, and the entry, or nil if there
+// is none or it can't be read: that of the URL, or, if the responses of the
+// URL vary by request headers, that of the variant of req.
+func (t *Transport) lookup(req *http.Request) (string, *entry) {
+	key := cacheKey(req.URL.String())
+	e, err := t.load(key)
+	if err == nil && e != nil && len(e.Vary) > 0 {
+		key = variantKey(req, e.Vary)
+		e, err = t.load(key)
+	}
+	if err != nil {
+		// Unreadable or missing entry: fetch as if uncached
+		return key, nil
+	}
+	return key, e
+}
+
+// load reads the metadata for key. It returns (nil, nil) if no entry exists.
+func (t *Transport) load(key string) (*entry, error) {
+	data, err := os.ReadFile(filepath.Join(t.Dir, key+".meta"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, err
+	}
+	if len(e.Vary) > 0 {
+		// Points at the variants, without a body
+		return &e, nil
+	}
+	if _, err := os.Stat(filepath.Join(t.Dir, key+".body")); err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// store writes the body and metadata for key. The body is written first so a
+// metadata file never points at a missing body.
+func (t *Transport) store(key string, e *entry, body []byte) error {
+	if err := os.MkdirAll(t.Dir, 0755); err != nil {
+		return err
+	}
+	if _, err := atomicfile.WriteFile(filepath.Join(t.Dir, key+".body"), body); err != nil {
+		return err
+	}
+	return t.storeMeta(key, e)
+}
+
+// storeMeta writes the metadata for key.
+func (t *Transport) storeMeta(key string, e *entry) error {
+	meta, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = atomicfile.WriteFile(filepath.Join(t.Dir, key+".meta"), meta)
+	return err
+}
+
+// cacheKey returns the file name stem used for a URL.
+func cacheKey(rawURL string) string {
+	sum := sha256.Sum256([]byte(rawURL))
+	return hex.EncodeToString(sum[:])
+}
+
+// variantKey returns the file name stem used for the response to req varying
+// by the request headers vary: the values of the headers are hashed with the
+// URL.
+func variantKey(req *http.Request, vary []string) string {
+	var b strings.Builder
+	b.WriteString(req.URL.String())
+	for _, name := range vary {
+		fmt.Fprintf(&b, "\n%s: %s", name, strings.Join(req.Header.Values(name), ", "))
+	}
+	return cacheKey(b.String())
+}
+
+// varyNames returns the request headers named by the Vary header of h, in
+// canonical form and sorted, and whether the response may be stored: not if
+// it varies by "*", that is by more than the request headers.
+func varyNames(h http.Header) ([]string, bool) {
+	var names []string
+	for _, v := range h.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return nil, false
+			}
+			if name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names), true
+}
+
+// hasValidators reports whether a response can be revalidated with a conditional request.
+func hasValidators(h http.Header) bool {
+	return h.Get("ETag") != "" || h.Get("Last-Modified") != ""
+}
+
+// isNoStore reports whether the response forbids caching.
+func isNoStore(h http.Header) bool {
+	for _, directive := range strings.Split(h.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseMaxAge extracts the max-age directive from a Cache-Control header value.
+func parseMaxAge(cacheControl string) (time.Duration, bool) {
+	for _, directive := range strings.Split(cacheControl, ",") {
+		directive = strings.TrimSpace(directive)
+		if !strings.HasPrefix(strings.ToLower(directive), "max-age=") {
+			continue
+		}
+		secs, err := strconv.Atoi(directive[len("max-age="):])
+		if err != nil || secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	return 0, false
+}
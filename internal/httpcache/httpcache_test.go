@@ -0,0 +1,390 @@
+package httpcache
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTransportRevalidatesWithETag(t *testing.T) {
+	var fullResponses, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&fullResponses, 1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: New(t.TempDir(), nil)}
+
+	wantStatus := []string{StatusMiss, StatusRevalidated, StatusRevalidated}
+	for i, want := range wantStatus {
+		resp, err := client.Get(server.URL + "/page")
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if string(body) != "hello" {
+			t.Errorf("request %d body = %q, want %q", i, body, "hello")
+		}
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("request %d status = %d, want 200", i, resp.StatusCode)
+		}
+		if got := resp.Header.Get(StatusHeader); got != want {
+			t.Errorf("request %d cache status = %q, want %q", i, got, want)
+		}
+	}
+
+	if fullResponses != 1 || notModified != 2 {
+		t.Errorf("origin served %d full responses and %d 304s, want 1 and 2", fullResponses, notModified)
+	}
+}
+
+func TestTransportServesFreshEntryWithoutNetwork(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("fresh"))
+	}))
+	defer server.Close()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr := New(t.TempDir(), nil)
+	tr.now = func() time.Time { return now }
+	client := &http.Client{Transport: tr}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if hits != 1 {
+		t.Errorf("origin hit %d times while entry fresh, want 1", hits)
+	}
+
+	now = now.Add(2 * time.Minute)
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if hits != 2 {
+		t.Errorf("origin hit %d times after expiry, want 2", hits)
+	}
+}
+
+func TestTransportFreshFor(t *testing.T) {
+	var fullResponses, notModified int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&notModified, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&fullResponses, 1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr := New(t.TempDir(), nil)
+	tr.FreshFor = 7 * 24 * time.Hour
+	tr.now = func() time.Time { return now }
+	client := &http.Client{Transport: tr}
+
+	for i, step := range []struct {
+		after time.Duration
+		want  string
+	}{
+		{0, StatusMiss},
+		{6 * 24 * time.Hour, StatusHit},
+		// Older than the window: revalidated, which starts it again
+		{2 * 24 * time.Hour, StatusRevalidated},
+		{6 * 24 * time.Hour, StatusHit},
+	} {
+		now = now.Add(step.after)
+		resp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		resp.Body.Close()
+		if got := resp.Header.Get(StatusHeader); got != step.want {
+			t.Errorf("request %d cache status = %q, want %q", i, got, step.want)
+		}
+	}
+	if fullResponses != 1 || notModified != 1 {
+		t.Errorf("origin served %d full responses and %d 304s, want 1 and 1", fullResponses, notModified)
+	}
+}
+
+func TestTransportBypassesNonCacheable(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		switch r.URL.Path {
+		case "/missing":
+			http.NotFound(w, r)
+		case "/nostore":
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+			w.Write([]byte("secret"))
+		default:
+			w.Header().Set("Cache-Control", "max-age=60")
+			w.Write([]byte("ok"))
+		}
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: New(t.TempDir(), nil)}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header map[string]string
+	}{
+		{name: "404 responses", method: http.MethodGet, path: "/missing"},
+		{name: "no-store responses", method: http.MethodGet, path: "/nostore"},
+		{name: "HEAD requests", method: http.MethodHead, path: "/head"},
+		{name: "range requests", method: http.MethodGet, path: "/range", header: map[string]string{"Range": "bytes=0-0"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := atomic.LoadInt32(&hits)
+			for i := 0; i < 2; i++ {
+				req, _ := http.NewRequest(tt.method, server.URL+tt.path, nil)
+				for k, v := range tt.header {
+					req.Header.Set(k, v)
+				}
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Fatalf("request failed: %v", err)
+				}
+				resp.Body.Close()
+				if got := resp.Header.Get(StatusHeader); got != StatusMiss {
+					t.Errorf("cache status = %q, want %q", got, StatusMiss)
+				}
+			}
+			if got := atomic.LoadInt32(&hits) - before; got != 2 {
+				t.Errorf("origin hit %d times, want 2", got)
+			}
+		})
+	}
+}
+
+func TestParseMaxAge(t *testing.T) {
+	tests := []struct {
+		input  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"max-age=60", 60 * time.Second, true},
+		{"public, max-age=3600", time.Hour, true},
+		{"no-cache", 0, false},
+		{"max-age=abc", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := parseMaxAge(tt.input)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseMaxAge(%q) = (%v, %v), want (%v, %v)", tt.input, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestTransportOffline(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte("stored"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	resp, err := (&http.Client{Transport: New(dir, nil)}).Get(server.URL + "/page")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	tr := New(dir, nil)
+	tr.Offline = true
+	client := &http.Client{Transport: tr}
+	resp, err = client.Get(server.URL + "/page")
+	if err != nil {
+		t.Fatalf("offline request of a stored entry failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "stored" || resp.Header.Get(StatusHeader) != StatusHit {
+		t.Errorf("offline response = %q (cache %q), want %q (cache %q)", body, resp.Header.Get(StatusHeader), "stored", StatusHit)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{name: "uncached URL", method: http.MethodGet, path: "/other"},
+		{name: "non-GET request", method: http.MethodPost, path: "/page"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(tt.method, server.URL+tt.path, nil)
+			if _, err := client.Do(req); !errors.Is(err, ErrOffline) {
+				t.Errorf("%s %s error = %v, want ErrOffline", tt.method, tt.path, err)
+			}
+		})
+	}
+	if hits != 1 {
+		t.Errorf("origin hit %d times, want 1 (before going offline)", hits)
+	}
+}
+
+func TestTransportVary(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if r.URL.Path == "/any" {
+			w.Header().Set("Vary", "*")
+		} else {
+			w.Header().Set("Vary", "Accept-Encoding, accept-language")
+		}
+		w.Header().Set("Cache-Control", "max-age=60")
+		lang, _, _ := strings.Cut(r.Header.Get("Accept-Language"), ",")
+		w.Write([]byte("page in " + lang))
+	}))
+	defer server.Close()
+
+	tr := New(t.TempDir(), nil)
+	get := func(path, lang string) (string, string) {
+		t.Helper()
+		req, _ := http.NewRequest("GET", server.URL+path, nil)
+		req.Header.Set("Accept-Language", lang)
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body), resp.Header.Get(StatusHeader)
+	}
+
+	tests := []struct {
+		path, lang           string
+		wantBody, wantStatus string
+	}{
+		{"/page", "ja,en;q=0.9", "page in ja", StatusMiss},
+		{"/page", "en,ja;q=0.9", "page in en", StatusMiss},
+		{"/page", "ja,en;q=0.9", "page in ja", StatusHit},
+		{"/page", "en,ja;q=0.9", "page in en", StatusHit},
+		{"/any", "ja", "page in ja", StatusMiss},
+		{"/any", "ja", "page in ja", StatusMiss},
+	}
+	for i, tt := range tests {
+		if body, status := get(tt.path, tt.lang); body != tt.wantBody || status != tt.wantStatus {
+			t.Errorf("request %d of %s in %s = %q (%s), want %q (%s)", i, tt.path, tt.lang, body, status, tt.wantBody, tt.wantStatus)
+		}
+	}
+	if hits != 4 {
+		t.Errorf("origin hit %d times, want 4", hits)
+	}
+}
+
+// endlessBody is an endless response body counting the bytes read from it.
+type endlessBody struct{ read int64 }
+
+func (b *endlessBody) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'x'
+	}
+	b.read += int64(len(p))
+	return len(p), nil
+}
+
+func (b *endlessBody) Close() error { return nil }
+
+// roundTripFunc is an http.RoundTripper calling itself.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestTransportMaxBodyBytes(t *testing.T) {
+	var hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte(strings.Repeat("x", 100)))
+		w.(http.Flusher).Flush()
+		if r.URL.Path == "/big" {
+			w.Write([]byte(strings.Repeat("x", 5000)))
+		}
+	}))
+	defer server.Close()
+
+	tr := New(t.TempDir(), nil)
+	tr.MaxBodyBytes = 1000
+	for i, tt := range []struct {
+		path       string
+		wantLen    int
+		wantStatus string
+	}{
+		{"/small", 100, StatusMiss},
+		{"/small", 100, StatusHit},
+		{"/big", 5100, StatusMiss},
+		{"/big", 5100, StatusMiss},
+	} {
+		req, _ := http.NewRequest("GET", server.URL+tt.path, nil)
+		resp, err := tr.RoundTrip(req)
+		if err != nil {
+			t.Fatalf("request %d failed: %v", i, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if len(body) != tt.wantLen || resp.Header.Get(StatusHeader) != tt.wantStatus {
+			t.Errorf("request %d of %s = %d bytes (%s), want %d (%s)", i, tt.path, len(body), resp.Header.Get(StatusHeader), tt.wantLen, tt.wantStatus)
+		}
+	}
+	if hits != 3 {
+		t.Errorf("origin hit %d times, want 3", hits)
+	}
+
+	// A body larger than the limit is handed over without being read to its end
+	body := &endlessBody{}
+	tr = New(t.TempDir(), roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: body, ContentLength: -1, Request: req}, nil
+	}))
+	tr.MaxBodyBytes = 1000
+	req, _ := http.NewRequest("GET", "http://docs.example.com/huge", nil)
+	resp, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip() returned error: %v", err)
+	}
+	defer resp.Body.Close()
+	if body.read > 64<<10 {
+		t.Errorf("%d bytes read before returning the response, want the body streamed", body.read)
+	}
+	if n, _ := io.CopyN(io.Discard, resp.Body, 1<<20); n != 1<<20 {
+		t.Errorf("read %d bytes of the body, want all of them", n)
+	}
+}
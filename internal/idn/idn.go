@@ -0,0 +1,129 @@
+// Package idn handles internationalized domain names and non-ASCII paths in
+// URLs, such as "https://例え.jp/ドキュメント/", by giving every URL two forms:
+//
+//   - the request form, with the host in punycode and the path, query, and
+//     fragment percent-encoded ("https://xn--r8jz45g.jp/%E3%83%89...").
+//     It is what is requested and compared: crawl frontiers, the crawl report,
+//     the source_url of documents, and the links written into them all use
+//     it, so the same page is never known under two spellings.
+//   - the display form, with the host in Unicode and the path decoded
+//     ("https://例え.jp/ドキュメント/"). It names the files of the crawl and of
+//     the skill so that they read like the site.
+//
+// URLs without non-ASCII characters have the same two forms, except that
+// percent-encodings in the request form use upper-case hex digits.
+package idn
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+// ToASCII returns the request form of the absolute URL rawURL: its host in
+// punycode and lower case, and its path, query, and fragment percent-encoded.
+// Encoded slashes in the path are kept encoded. URLs that can't be parsed, and
+// relative URLs, are returned unchanged.
+func ToASCII(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	u.Host = HostToASCII(u.Host)
+	if u.RawPath != "" && !strings.Contains(strings.ToUpper(u.RawPath), "%2F") {
+		// Re-encode the path from its decoded form, in one spelling
+		u.RawPath = ""
+	}
+	u.RawQuery = encodeNonASCII(u.RawQuery)
+	return u.String()
+}
+
+// ToDisplay returns the display form of the absolute URL rawURL: its host in
+// Unicode and its path decoded. The query is decoded when that yields valid
+// UTF-8. URLs that can't be parsed, and relative URLs, are returned unchanged.
+func ToDisplay(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+	s := u.Scheme + "://" + HostToDisplay(u.Host) + u.Path
+	if u.RawQuery != "" {
+		query := u.RawQuery
+		if q, err := url.QueryUnescape(query); err == nil && utf8.ValidString(q) {
+			query = q
+		}
+		s += "?" + query
+	}
+	if u.Fragment != "" {
+		s += "#" + u.Fragment
+	}
+	return s
+}
+
+// HostToASCII returns host, with an optional port, in punycode and lower
+// case, e.g., "xn--r8jz45g.jp:8443" for "例え.jp:8443". ASCII hosts, and hosts
+// that aren't valid domain names, are only lower-cased.
+func HostToASCII(host string) string {
+	name, port := splitPort(host)
+	if !isASCII(name) {
+		if a, err := idna.Lookup.ToASCII(name); err == nil {
+			name = a
+		}
+	}
+	return strings.ToLower(name) + port
+}
+
+// HostToDisplay returns host, with an optional port, with its punycode
+// labels in Unicode, e.g., "例え.jp:8443" for "xn--r8jz45g.jp:8443". Other
+// hosts are returned unchanged.
+func HostToDisplay(host string) string {
+	name, port := splitPort(host)
+	if !strings.Contains(strings.ToLower(name), "xn--") {
+		return host
+	}
+	u, err := idna.Display.ToUnicode(name)
+	if err != nil {
+		return host
+	}
+	return u + port
+}
+
+// splitPort splits host into its name and its ":port" suffix, if any. IPv6
+// literals keep their brackets.
+func splitPort(host string) (string, string) {
+	i := strings.LastIndexByte(host, ':')
+	if i < 0 || strings.LastIndexByte(host, ']') > i {
+		return host, ""
+	}
+	return host[:i], host[i:]
+}
+
+// encodeNonASCII percent-encodes the bytes of s outside printable ASCII.
+func encodeNonASCII(s string) string {
+	if isASCII(s) && !strings.Contains(s, " ") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// isASCII reports whether s has only ASCII characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
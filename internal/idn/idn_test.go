@@ -0,0 +1,67 @@
+package idn
+
+import "testing"
+
+func TestToASCII(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://例え.jp/ドキュメント/", "https://xn--r8jz45g.jp/%E3%83%89%E3%82%AD%E3%83%A5%E3%83%A1%E3%83%B3%E3%83%88/"},
+		{"https://xn--r8jz45g.jp/%e3%83%89", "https://xn--r8jz45g.jp/%E3%83%89"},
+		{"https://XN--R8JZ45G.jp:8443/%E3%83%89", "https://xn--r8jz45g.jp:8443/%E3%83%89"},
+		{"https://例え.jp/search?q=日本#概要", "https://xn--r8jz45g.jp/search?q=%E6%97%A5%E6%9C%AC#%E6%A6%82%E8%A6%81"},
+		{"https://docs.example.com/a%2Fb/c", "https://docs.example.com/a%2Fb/c"},
+		{"https://docs.example.com/guide?hl=ja", "https://docs.example.com/guide?hl=ja"},
+		{"http://127.0.0.1:8080/docs/", "http://127.0.0.1:8080/docs/"},
+		{"../ドキュメント", "../ドキュメント"},
+		{"https://bad host/%zz", "https://bad host/%zz"},
+	}
+	for _, tt := range tests {
+		if got := ToASCII(tt.in); got != tt.want {
+			t.Errorf("ToASCII(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestToDisplay(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"https://xn--r8jz45g.jp/%E3%83%89%E3%82%AD%E3%83%A5%E3%83%A1%E3%83%B3%E3%83%88/", "https://例え.jp/ドキュメント/"},
+		{"https://xn--r8jz45g.jp:8443/search?q=%E6%97%A5%E6%9C%AC#%E6%A6%82%E8%A6%81", "https://例え.jp:8443/search?q=日本#概要"},
+		{"https://docs.example.com/guide?hl=ja", "https://docs.example.com/guide?hl=ja"},
+		{"https://docs.example.com/?q=%FF", "https://docs.example.com/?q=%FF"},
+		{"/relative", "/relative"},
+	}
+	for _, tt := range tests {
+		if got := ToDisplay(tt.in); got != tt.want {
+			t.Errorf("ToDisplay(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHost(t *testing.T) {
+	tests := []struct {
+		unicode string
+		ascii   string
+	}{
+		{"例え.jp", "xn--r8jz45g.jp"},
+		{"例え.jp:8443", "xn--r8jz45g.jp:8443"},
+		{"ドキュメント.例え.jp", "xn--nckucb1hta9f.xn--r8jz45g.jp"},
+		{"docs.example.com", "docs.example.com"},
+		{"[::1]:8080", "[::1]:8080"},
+	}
+	for _, tt := range tests {
+		if got := HostToASCII(tt.unicode); got != tt.ascii {
+			t.Errorf("HostToASCII(%q) = %q, want %q", tt.unicode, got, tt.ascii)
+		}
+		if got := HostToDisplay(tt.ascii); got != tt.unicode {
+			t.Errorf("HostToDisplay(%q) = %q, want %q", tt.ascii, got, tt.unicode)
+		}
+	}
+	if got := HostToASCII("Docs.Example.COM"); got != "docs.example.com" {
+		t.Errorf("HostToASCII() = %q, want it lower-cased", got)
+	}
+}
@@ -0,0 +1,188 @@
+// Package lang provides the language-specific text processing of search.
+// This file implements the detection of the language of a text, by its
+// script, and for the languages written in the Latin script, by their most
+// frequent words.
+package lang
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	// minLetters is the number of letters a text needs for its language to be
+	// detected, the letters of other scripts than Latin counting scriptWeight
+	// times.
+	minLetters = 100
+	// minScore is the score of frequent words a Latin text needs for its
+	// language to be detected.
+	minScore = 3
+	// scriptWeight is the weight of the letters of the other scripts than
+	// Latin, whose letters each carry about a word, against Latin letters.
+	scriptWeight = 3
+	// minKana is the share of kana among the ideographs of a Japanese text.
+	minKana = 0.05
+)
+
+// scriptLanguages are the languages told apart by their script alone. Han
+// ideographs are Japanese with kana, Chinese without.
+var scriptLanguages = []struct {
+	table    *unicode.RangeTable
+	language string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Greek, "el"},
+	{unicode.Arabic, "ar"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+// frequentWords are the most frequent words of the languages written in the
+// Latin script that Detect tells apart. A word frequent in several languages
+// counts for each in proportion.
+var frequentWords = map[string][]string{
+	"en": {
+		"the", "and", "of", "to", "is", "in", "that", "for", "it", "with", "as", "are", "on",
+		"this", "be", "by", "you", "your", "can", "from", "or", "an", "not", "which", "will",
+		"have", "if", "when", "these", "use",
+	},
+	"fr": {
+		"le", "la", "les", "des", "est", "et", "du", "une", "un", "pour", "dans", "que", "qui",
+		"sur", "pas", "avec", "vous", "sont", "ce", "cette", "au", "aux", "par", "plus", "peut",
+		"ou", "votre", "être", "en", "de",
+	},
+	"de": {
+		"der", "die", "das", "und", "ist", "nicht", "mit", "sie", "ein", "eine", "den", "dem",
+		"zu", "auf", "für", "von", "sich", "auch", "wird", "werden", "oder", "wenn", "kann",
+		"können", "bei", "durch", "einen", "sind", "im", "diese",
+	},
+	"es": {
+		"el", "los", "las", "del", "que", "y", "es", "en", "por", "con", "para", "una", "un",
+		"se", "no", "su", "como", "más", "pero", "al", "lo", "puede", "este", "esta", "sus",
+		"son", "también", "cuando", "si", "de", "la",
+	},
+	"pt": {
+		"o", "os", "as", "da", "do", "das", "dos", "que", "e", "é", "em", "para", "com", "uma",
+		"um", "não", "se", "por", "mais", "como", "ao", "na", "no", "pode", "você", "seu",
+		"sua", "são", "também", "isso", "de", "a",
+	},
+	"it": {
+		"il", "di", "che", "e", "la", "le", "per", "un", "una", "non", "con", "del", "della",
+		"sono", "è", "gli", "si", "da", "nel", "come", "anche", "più", "può", "questo",
+		"questa", "alla", "dei", "delle", "essere", "in", "a",
+	},
+	"nl": {
+		"de", "het", "een", "van", "en", "is", "dat", "op", "te", "in", "voor", "met", "niet",
+		"zijn", "die", "je", "ook", "als", "bij", "aan", "er", "kan", "wordt", "worden", "naar",
+		"door", "dit", "uw", "of", "om",
+	},
+}
+
+// wordWeights maps the frequent words to the languages they are frequent in,
+// with the weight they count for in each.
+var wordWeights = func() map[string]map[string]float64 {
+	languages := make(map[string][]string)
+	for language, words := range frequentWords {
+		for _, w := range words {
+			languages[w] = append(languages[w], language)
+		}
+	}
+	weights := make(map[string]map[string]float64, len(languages))
+	for w, ls := range languages {
+		weights[w] = make(map[string]float64, len(ls))
+		for _, l := range ls {
+			weights[w][l] = 1 / float64(len(ls))
+		}
+	}
+	return weights
+}()
+
+// Detect returns the language of text, prose without markup, by its primary
+// subtag ("en", "ja"), or "" if it can't tell: when text has fewer than
+// minLetters letters, or is written in the Latin script without a clear
+// majority of the frequent words of one language.
+//
+// The language is that of the script of most letters, ideographs and other
+// letters standing for a word each counting scriptWeight times, so that the
+// English terms of a Japanese page don't outweigh it: Japanese for Han
+// ideographs with kana, Chinese for Han without, and one language per other
+// script (see scriptLanguages). Latin texts are scored by the frequent words
+// of English, French, German, Spanish, Portuguese, Italian, and Dutch (see
+// frequentWords).
+func Detect(text string) string {
+	var latin, han, kana int
+	scripts := make(map[string]int)
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		switch {
+		case r < unicode.MaxLatin1 || unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.Is(unicode.Han, r):
+			han++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		default:
+			for _, s := range scriptLanguages {
+				if unicode.Is(s.table, r) {
+					scripts[s.language]++
+					break
+				}
+			}
+		}
+	}
+	others := han + kana
+	for _, n := range scripts {
+		others += n
+	}
+	if latin+scriptWeight*others < minLetters {
+		return ""
+	}
+
+	best, bestCount := "", latin
+	if cjk := scriptWeight * (han + kana); cjk > bestCount {
+		best, bestCount = "zh", cjk
+		if float64(kana) >= minKana*float64(han+kana) {
+			best = "ja"
+		}
+	}
+	for _, s := range scriptLanguages {
+		if n := scriptWeight * scripts[s.language]; n > bestCount {
+			best, bestCount = s.language, n
+		}
+	}
+	if best != "" {
+		return best
+	}
+	return detectLatin(text)
+}
+
+// detectLatin returns the language of text, written in the Latin script, by
+// its frequent words: the language of the highest score, provided it reaches
+// minScore and a quarter more than the next.
+func detectLatin(text string) string {
+	scores := make(map[string]float64)
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		for language, weight := range wordWeights[w] {
+			scores[language] += weight
+		}
+	}
+	best, first, second := "", 0.0, 0.0
+	for language, score := range scores {
+		switch {
+		case score > first || score == first && language < best:
+			best, first, second = language, score, max(first, second)
+		case score > second:
+			second = score
+		}
+	}
+	if first < minScore || first < 1.25*second {
+		return ""
+	}
+	return best
+}
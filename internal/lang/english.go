@@ -0,0 +1,296 @@
+// Package lang provides the language-specific text processing of search.
+// This file implements the English stemmer: the Porter2 algorithm of the
+// Snowball project (https://snowballstem.org/algorithms/english/stemmer.html).
+package lang
+
+import "strings"
+
+// englishExceptions are the words whose stem the algorithm would get wrong.
+var englishExceptions = map[string]string{
+	"skis": "ski", "skies": "sky", "dying": "die", "lying": "lie", "tying": "tie",
+	"idly": "idl", "gently": "gentl", "ugly": "ugli", "early": "earli", "only": "onli",
+	"singly": "singl", "sky": "sky", "news": "news", "howe": "howe", "atlas": "atlas",
+	"cosmos": "cosmos", "bias": "bias", "andes": "andes",
+}
+
+// englishInvariants are left as they are after step 1a.
+var englishInvariants = map[string]bool{
+	"inning": true, "outing": true, "canning": true, "herring": true,
+	"earring": true, "proceed": true, "exceed": true, "succeed": true,
+}
+
+// englishStep2 and the following lists map suffixes to their replacements,
+// longest first.
+var englishStep2 = []suffixRule{
+	{"ization", "ize"}, {"ational", "ate"}, {"fulness", "ful"}, {"ousness", "ous"}, {"iveness", "ive"},
+	{"tional", "tion"}, {"biliti", "ble"}, {"lessli", "less"},
+	{"entli", "ent"}, {"ation", "ate"}, {"alism", "al"}, {"aliti", "al"}, {"ousli", "ous"}, {"iviti", "ive"}, {"fulli", "ful"},
+	{"enci", "ence"}, {"anci", "ance"}, {"abli", "able"}, {"izer", "ize"}, {"ator", "ate"}, {"alli", "al"},
+	{"bli", "ble"}, {"ogi", "og"},
+	{"li", ""},
+}
+
+var englishStep3 = []suffixRule{
+	{"ational", "ate"},
+	{"tional", "tion"},
+	{"alize", "al"}, {"icate", "ic"}, {"iciti", "ic"}, {"ative", ""},
+	{"ical", "ic"}, {"ness", ""},
+	{"ful", ""},
+}
+
+var englishStep4 = []string{
+	"ement",
+	"ance", "ence", "able", "ible", "ment",
+	"ant", "ent", "ism", "ate", "iti", "ous", "ive", "ize", "ion",
+	"al", "er", "ic",
+}
+
+// suffixRule replaces a suffix.
+type suffixRule struct {
+	suffix, replacement string
+}
+
+// English returns the stem of a lowercased English word. Words of other
+// characters than a-z and words of up to 2 letters are returned as they are.
+func English(word string) string {
+	if len(word) <= 2 {
+		return word
+	}
+	for i := 0; i < len(word); i++ {
+		if word[i] < 'a' || word[i] > 'z' {
+			return word
+		}
+	}
+	if stem, ok := englishExceptions[word]; ok {
+		return stem
+	}
+
+	// y is a consonant at the start of the word and after a vowel: mark it Y
+	w := []byte(word)
+	for i := range w {
+		if w[i] == 'y' && (i == 0 || isVowel(w[i-1])) {
+			w[i] = 'Y'
+		}
+	}
+	r1, r2 := regions(w)
+
+	w = englishStep1a(w)
+	if englishInvariants[string(w)] {
+		return strings.ReplaceAll(string(w), "Y", "y")
+	}
+	w = englishStep1b(w, r1)
+	w = englishStep1c(w)
+	w = replaceSuffix(w, englishStep2, r1, func(w []byte, rule suffixRule) bool {
+		switch rule.suffix {
+		case "ogi":
+			return len(w) > 3 && w[len(w)-4] == 'l'
+		case "li":
+			return len(w) > 2 && strings.IndexByte("cdeghkmnrt", w[len(w)-3]) >= 0
+		}
+		return true
+	})
+	w = replaceSuffix(w, englishStep3, r1, func(w []byte, rule suffixRule) bool {
+		return rule.suffix != "ative" || len(w)-len(rule.suffix) >= r2
+	})
+	w = englishStep4Apply(w, r2)
+	w = englishStep5(w, r1, r2)
+	return strings.ReplaceAll(string(w), "Y", "y")
+}
+
+// englishStep1a removes plural endings: sses, ied, ies, and s.
+func englishStep1a(w []byte) []byte {
+	s := string(w)
+	switch {
+	case strings.HasSuffix(s, "sses"):
+		return w[:len(w)-2]
+	case strings.HasSuffix(s, "ied"), strings.HasSuffix(s, "ies"):
+		if len(w) > 4 {
+			return w[:len(w)-2]
+		}
+		return w[:len(w)-1]
+	case strings.HasSuffix(s, "us"), strings.HasSuffix(s, "ss"):
+		return w
+	case strings.HasSuffix(s, "s"):
+		// Delete if a vowel precedes the letter before the s (gaps, not gas)
+		for i := 0; i < len(w)-2; i++ {
+			if isVowel(w[i]) {
+				return w[:len(w)-1]
+			}
+		}
+	}
+	return w
+}
+
+// englishStep1b removes the endings eed, ed, ing, and their -ly forms.
+func englishStep1b(w []byte, r1 int) []byte {
+	s := string(w)
+	for _, suffix := range []string{"eedly", "eed"} {
+		if strings.HasSuffix(s, suffix) {
+			if len(w)-len(suffix) >= r1 {
+				return append(w[:len(w)-len(suffix)], "ee"...)
+			}
+			return w
+		}
+	}
+
+	for _, suffix := range []string{"ingly", "edly", "ing", "ed"} {
+		if !strings.HasSuffix(s, suffix) {
+			continue
+		}
+		stem := w[:len(w)-len(suffix)]
+		if !containsVowel(stem) {
+			return w
+		}
+		switch {
+		case hasSuffix(stem, "at"), hasSuffix(stem, "bl"), hasSuffix(stem, "iz"):
+			return append(stem, 'e')
+		case isDouble(stem):
+			return stem[:len(stem)-1]
+		case isShortWord(stem, r1):
+			return append(stem, 'e')
+		}
+		return stem
+	}
+	return w
+}
+
+// englishStep1c turns a final y into i after a consonant that isn't the
+// first letter (cry, not by).
+func englishStep1c(w []byte) []byte {
+	n := len(w)
+	if n > 2 && (w[n-1] == 'y' || w[n-1] == 'Y') && !isVowel(w[n-2]) {
+		w[n-1] = 'i'
+	}
+	return w
+}
+
+// englishStep4Apply removes the suffixes of englishStep4 in R2; ion only
+// after s or t.
+func englishStep4Apply(w []byte, r2 int) []byte {
+	for _, suffix := range englishStep4 {
+		if !hasSuffix(w, suffix) {
+			continue
+		}
+		start := len(w) - len(suffix)
+		if start < r2 {
+			return w
+		}
+		if suffix == "ion" && (start == 0 || (w[start-1] != 's' && w[start-1] != 't')) {
+			return w
+		}
+		return w[:start]
+	}
+	return w
+}
+
+// englishStep5 removes a final e in R2, or in R1 if not preceded by a short
+// syllable, and the second l of a final ll in R2.
+func englishStep5(w []byte, r1, r2 int) []byte {
+	n := len(w)
+	switch {
+	case n > 0 && w[n-1] == 'e':
+		if n-1 >= r2 || (n-1 >= r1 && !endsShortSyllable(w[:n-1])) {
+			return w[:n-1]
+		}
+	case n > 1 && w[n-1] == 'l' && w[n-2] == 'l':
+		if n-1 >= r2 {
+			return w[:n-1]
+		}
+	}
+	return w
+}
+
+// replaceSuffix applies the rule of the longest suffix of w in rules if the
+// suffix is in the region starting at r and ok accepts it.
+func replaceSuffix(w []byte, rules []suffixRule, r int, ok func([]byte, suffixRule) bool) []byte {
+	for _, rule := range rules {
+		if !hasSuffix(w, rule.suffix) {
+			continue
+		}
+		start := len(w) - len(rule.suffix)
+		if start < r || !ok(w, rule) {
+			return w
+		}
+		return append(w[:start], rule.replacement...)
+	}
+	return w
+}
+
+// regions returns the start of the R1 and R2 regions of w: R1 follows the
+// first consonant after a vowel, and R2 is the R1 of R1. Words beginning with
+// gener, commun, or arsen have their R1 after those prefixes.
+func regions(w []byte) (int, int) {
+	r1 := len(w)
+	for _, prefix := range []string{"gener", "commun", "arsen"} {
+		if hasPrefix(w, prefix) {
+			r1 = len(prefix)
+			break
+		}
+	}
+	if r1 == len(w) {
+		r1 = regionAfter(w, 0)
+	}
+	return r1, regionAfter(w, r1)
+}
+
+// regionAfter returns the position following the first consonant after a
+// vowel in w[start:], or len(w).
+func regionAfter(w []byte, start int) int {
+	for i := start + 1; i < len(w); i++ {
+		if !isVowel(w[i]) && isVowel(w[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+// endsShortSyllable reports whether w ends in a short syllable: a consonant,
+// a vowel, and a consonant other than w, x, or Y, or a vowel and a consonant
+// making up the whole word.
+func endsShortSyllable(w []byte) bool {
+	n := len(w)
+	switch {
+	case n >= 3:
+		return !isVowel(w[n-3]) && isVowel(w[n-2]) && !isVowel(w[n-1]) && w[n-1] != 'w' && w[n-1] != 'x' && w[n-1] != 'Y'
+	case n == 2:
+		return isVowel(w[0]) && !isVowel(w[1])
+	}
+	return false
+}
+
+// isShortWord reports whether w ends in a short syllable and has an empty R1.
+func isShortWord(w []byte, r1 int) bool {
+	return r1 >= len(w) && endsShortSyllable(w)
+}
+
+// isDouble reports whether w ends in one of the doubles bb, dd, ff, gg, mm,
+// nn, pp, rr, and tt.
+func isDouble(w []byte) bool {
+	n := len(w)
+	return n >= 2 && w[n-1] == w[n-2] && strings.IndexByte("bdfgmnprt", w[n-1]) >= 0
+}
+
+// isVowel reports whether c is an English vowel; Y marks a consonant y.
+func isVowel(c byte) bool {
+	return strings.IndexByte("aeiouy", c) >= 0
+}
+
+// containsVowel reports whether w contains a vowel.
+func containsVowel(w []byte) bool {
+	for _, c := range w {
+		if isVowel(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasSuffix reports whether w ends with suffix.
+func hasSuffix(w []byte, suffix string) bool {
+	return strings.HasSuffix(string(w), suffix)
+}
+
+// hasPrefix reports whether w begins with prefix.
+func hasPrefix(w []byte, prefix string) bool {
+	return strings.HasPrefix(string(w), prefix)
+}
@@ -0,0 +1,44 @@
+// Package lang provides the language-specific text processing of search:
+// stemming, which reduces the inflected forms of a word to a common stem
+// ("configuring" and "configuration" to "configur"), and stop words, the
+// frequent words ("the", "of") that are left out of queries, and the
+// detection of the language of a text (see Detect).
+//
+// Languages are identified by their primary subtag: "en" for "en-US". Only
+// English has a stemmer and stop words for now.
+package lang
+
+import "strings"
+
+// Language returns the language of a locale: its primary subtag, lowercased
+// ("en" for "en-US" or "en_GB"). An empty locale gives "".
+func Language(locale string) string {
+	if i := strings.IndexAny(locale, "-_"); i >= 0 {
+		locale = locale[:i]
+	}
+	return strings.ToLower(locale)
+}
+
+// Stemmer returns the stemmer of a language, or nil if the language has none.
+// A stemmer takes a lowercased word and returns its stem.
+func Stemmer(language string) func(string) string {
+	switch language {
+	case "en":
+		return English
+	}
+	return nil
+}
+
+// defaultStopWords are the built-in stop words of each language.
+var defaultStopWords = map[string][]string{
+	"en": {
+		"a", "an", "and", "are", "as", "at", "be", "but", "by", "for", "if", "in",
+		"into", "is", "it", "no", "not", "of", "on", "or", "such", "that", "the",
+		"their", "then", "there", "these", "they", "this", "to", "was", "will", "with",
+	},
+}
+
+// StopWords returns the built-in stop words of a language, or nil if it has none.
+func StopWords(language string) []string {
+	return append([]string(nil), defaultStopWords[language]...)
+}
@@ -0,0 +1,91 @@
+package lang
+
+import "testing"
+
+func TestEnglish(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"configuring", "configur"},
+		{"configuration", "configur"},
+		{"configured", "configur"},
+		{"configure", "configur"},
+		{"authentication", "authent"},
+		{"authenticating", "authent"},
+		{"installation", "instal"},
+		{"installer", "instal"},
+		{"caresses", "caress"},
+		{"ponies", "poni"},
+		{"ties", "tie"},
+		{"gas", "gas"},
+		{"gaps", "gap"},
+		{"hopping", "hop"},
+		{"hoping", "hope"},
+		{"cries", "cri"},
+		{"happily", "happili"},
+		{"relational", "relat"},
+		{"generously", "generous"},
+		{"consistently", "consist"},
+		{"consolingly", "consol"},
+		{"conspiracy", "conspiraci"},
+		{"agreed", "agre"},
+		{"succeeding", "succeed"},
+		{"skies", "sky"},
+		{"yielding", "yield"},
+		{"by", "by"},
+		{"naïve", "naïve"},
+		{"v2", "v2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			if got := English(tt.word); got != tt.want {
+				t.Errorf("English(%q) = %q, want %q", tt.word, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	tests := map[string]string{
+		"en":    "en",
+		"en-US": "en",
+		"pt_BR": "pt",
+		"":      "",
+	}
+	for locale, want := range tests {
+		if got := Language(locale); got != want {
+			t.Errorf("Language(%q) = %q, want %q", locale, got, want)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"English", "To install the command line tool, download the archive for your platform and add it to your path. The tool reads its settings from the configuration file in your home directory, which you can edit with any text editor.", "en"},
+		{"French", "Pour installer l'outil en ligne de commande, téléchargez l'archive de votre plateforme et ajoutez-la à votre chemin. L'outil lit ses paramètres dans le fichier de configuration qui se trouve dans votre répertoire personnel.", "fr"},
+		{"German", "Um das Befehlszeilenwerkzeug zu installieren, laden Sie das Archiv für Ihre Plattform herunter und fügen Sie es Ihrem Pfad hinzu. Das Werkzeug liest die Einstellungen aus der Konfigurationsdatei, die sich in Ihrem Verzeichnis befindet.", "de"},
+		{"Spanish", "Para instalar la herramienta de línea de comandos, descargue el archivo para su plataforma y agréguelo a su ruta. La herramienta lee su configuración del archivo que se encuentra en el directorio principal, y también se puede editar.", "es"},
+		{"Portuguese", "Para instalar a ferramenta de linha de comando, baixe o arquivo da sua plataforma e adicione-o ao seu caminho. A ferramenta lê as configurações do arquivo que fica no seu diretório pessoal, e você pode editá-lo com qualquer editor.", "pt"},
+		{"Italian", "Per installare lo strumento da riga di comando, scarica l'archivio per la tua piattaforma e aggiungilo al percorso. Lo strumento legge le impostazioni dal file di configurazione che si trova nella directory principale, e non è difficile.", "it"},
+		{"Dutch", "Om het opdrachtregelprogramma te installeren, download je het archief voor je platform en voeg je het toe aan je pad. Het programma leest de instellingen uit het configuratiebestand dat in je thuismap staat en dat je met een editor kunt bewerken.", "nl"},
+		{"Japanese with English terms", "コマンドラインツールをインストールするには、お使いのプラットフォーム用のアーカイブをダウンロードし、PATH に追加します。ツールはホームディレクトリにある config.yaml ファイルから設定を読み込みます。", "ja"},
+		{"Chinese", "要安装命令行工具，请下载适用于您平台的归档文件并将其添加到路径中。该工具从主目录中的配置文件读取设置，您可以使用任何文本编辑器编辑该文件。", "zh"},
+		{"Korean", "명령줄 도구를 설치하려면 플랫폼에 맞는 아카이브를 다운로드하고 경로에 추가하세요. 도구는 홈 디렉터리에 있는 구성 파일에서 설정을 읽으며, 이 파일은 아무 텍스트 편집기로나 편집할 수 있습니다.", "ko"},
+		{"Russian", "Чтобы установить инструмент командной строки, загрузите архив для своей платформы и добавьте его в путь. Инструмент читает настройки из файла конфигурации в домашнем каталоге.", "ru"},
+		{"too short", "Install the tool.", ""},
+		{"no frequent words", "Kubernetes Docker Terraform Ansible Prometheus Grafana Elasticsearch Kibana Logstash Fluentd Jenkins GitLab GitHub Bitbucket Jira Confluence", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.text); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+// Package links rewrites the links between the pages of a skill.
+// This file implements the check of the links of a generated skill: every
+// link and image pointing inside the skill must reach a file, and every
+// fragment a heading of the file.
+package links
+
+import (
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/converter"
+	"github.com/f4ah6o/site2skill-go/internal/mdprose"
+)
+
+// Reasons a link is broken (BrokenLink.Reason).
+const (
+	// ReasonMissingFile means the file the link points to doesn't exist.
+	ReasonMissingFile = "missing_file"
+	// ReasonMissingAnchor means the file has no heading with the fragment's ID.
+	ReasonMissingAnchor = "missing_anchor"
+	// ReasonOutsideSkill means the link points outside the skill directory.
+	ReasonOutsideSkill = "outside_skill"
+	// ReasonInvalid means the link target isn't a valid URL.
+	ReasonInvalid = "invalid"
+)
+
+// htmlAnchorPattern matches the id and name attributes of raw HTML, which
+// fragments can point to besides headings.
+var htmlAnchorPattern = regexp.MustCompile(`\b(?:id|name)\s*=\s*["']([^"']+)["']`)
+
+// Report is the result of Check.
+type Report struct {
+	// Documents is the number of Markdown files checked.
+	Documents int `json:"documents"`
+	// Links is the number of links and images pointing inside the skill.
+	Links int `json:"links"`
+	// External is the number of links and images to absolute URLs, which
+	// aren't checked.
+	External int `json:"external"`
+	// Uncrawled is the number of external links marked as pointing to a page
+	// of the site that isn't in the skill (see UncrawledTitle).
+	Uncrawled int `json:"uncrawled"`
+	// Broken lists the broken links, by file and line.
+	Broken []BrokenLink `json:"broken"`
+}
+
+// BrokenLink is a link of a skill whose target doesn't exist.
+type BrokenLink struct {
+	// File is the slash-separated path of the file of the link in the skill
+	// (e.g., "docs/auth.md").
+	File string `json:"file"`
+	// Line is the 1-based line of the link.
+	Line int `json:"line"`
+	// Target is the target of the link as written.
+	Target string `json:"target"`
+	// Reason tells why the link is broken: ReasonMissingFile,
+	// ReasonMissingAnchor, ReasonOutsideSkill, or ReasonInvalid.
+	Reason string `json:"reason"`
+}
+
+// String returns the broken link as "file:line: target (reason)".
+func (b BrokenLink) String() string {
+	return fmt.Sprintf("%s:%d: %s (%s)", b.File, b.Line, b.Target, b.Reason)
+}
+
+// Check checks the links of the Markdown files of the skill in skillDir,
+// SKILL.md and the documents included, outside fenced code blocks, inline
+// code, and the frontmatter: every relative link and image must point to a
+// file of the skill, and every fragment to a heading, or an element with the
+// id or name, of the Markdown file it points to. Links to absolute URLs are
+// counted but not checked. Directories whose name starts with "." are skipped.
+//
+// Returns an error if the skill directory or a file can't be read.
+func Check(skillDir string) (*Report, error) {
+	root, err := filepath.Abs(skillDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve skill directory: %w", err)
+	}
+	var files []string
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && p != root && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), ".md") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list skill files: %w", err)
+	}
+	sort.Strings(files)
+
+	c := &checker{root: root, anchors: make(map[string]map[string]bool), report: &Report{Broken: []BrokenLink{}}}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read file: %w", err)
+		}
+		c.report.Documents++
+		rel, _ := filepath.Rel(root, file)
+		mdprose.Map(string(content), func(line int, prose string) string {
+			for _, m := range linkPattern.FindAllStringSubmatch(prose, -1) {
+				c.check(file, filepath.ToSlash(rel), line, m[4], m[5])
+			}
+			return prose
+		})
+	}
+	return c.report, nil
+}
+
+// Targets returns the targets of the links of the Markdown document content,
+// as written, in order, outside the frontmatter, fenced code blocks, and
+// inline code. Images aren't links and are left out.
+func Targets(content string) []string {
+	var targets []string
+	mdprose.Map(content, func(_ int, prose string) string {
+		for _, m := range linkPattern.FindAllStringSubmatch(prose, -1) {
+			if m[1] == "" {
+				targets = append(targets, m[4])
+			}
+		}
+		return prose
+	})
+	return targets
+}
+
+// checker checks the links of the files of a skill.
+type checker struct {
+	// root is the absolute path of the skill directory
+	root string
+	// anchors caches the IDs fragments can point to, by absolute file path
+	anchors map[string]map[string]bool
+	report  *Report
+}
+
+// check checks the link to target, with the title title, on the line line of
+// file, at rel in the skill.
+func (c *checker) check(file, rel string, line int, target, title string) {
+	broken := func(reason string) {
+		c.report.Broken = append(c.report.Broken, BrokenLink{File: rel, Line: line, Target: target, Reason: reason})
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		c.report.Links++
+		broken(ReasonInvalid)
+		return
+	}
+	if u.Scheme != "" || u.Host != "" {
+		c.report.External++
+		if strings.Contains(title, UncrawledTitle) {
+			c.report.Uncrawled++
+		}
+		return
+	}
+	c.report.Links++
+
+	dest := file
+	if u.Path != "" {
+		dest = filepath.Join(filepath.Dir(file), filepath.FromSlash(u.Path))
+		if r, err := filepath.Rel(c.root, dest); err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+			broken(ReasonOutsideSkill)
+			return
+		}
+		if _, err := os.Stat(dest); err != nil {
+			broken(ReasonMissingFile)
+			return
+		}
+	}
+	if u.Fragment == "" || path.Ext(dest) != ".md" {
+		return
+	}
+	if !c.ids(dest)[u.Fragment] {
+		broken(ReasonMissingAnchor)
+	}
+}
+
+// ids returns the IDs the fragments of links to the Markdown file at path can
+// point to: those of its headings and of the id and name attributes of its
+// raw HTML.
+func (c *checker) ids(path string) map[string]bool {
+	if ids, ok := c.anchors[path]; ok {
+		return ids
+	}
+	ids := make(map[string]bool)
+	if content, err := os.ReadFile(path); err == nil {
+		for _, h := range converter.Headings(string(content)) {
+			ids[h.ID] = true
+		}
+		for _, m := range htmlAnchorPattern.FindAllStringSubmatch(string(content), -1) {
+			ids[m[1]] = true
+		}
+	}
+	c.anchors[path] = ids
+	return ids
+}
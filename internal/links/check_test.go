@@ -0,0 +1,71 @@
+package links
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	files := map[string]string{
+		"SKILL.md": "---\nname: guide\n---\n\n# Guide\n\n- [Install](docs/install.md)\n- [Missing](docs/missing.md)\n",
+		"docs/install.md": "---\ntitle: Install\n---\n\n# Install\n\n## Requirements\n\n" +
+			"[Top](#install) [Nowhere](#nowhere) [Anchor](#legacy)\n" +
+			"[Upgrade](upgrade.md#upgrading) [Gone](upgrade.md#gone)\n" +
+			"![Diagram](../assets/diagram.png) ![Lost](../assets/lost.png)\n" +
+			"[Escape](../../etc/passwd) [Site](https://docs.example.com/) " +
+			"[Pricing](https://docs.example.com/pricing \"not included in this skill\")\n\n" +
+			"<a id=\"legacy\"></a>\n\n```md\n[Code](missing.md)\n```\n\n`[Inline](missing.md)`\n",
+		"docs/upgrade.md":          "# Upgrade\n\n## Upgrading\n",
+		"assets/diagram.png":       "png",
+		"docs/.index/ignored.md":   "[Ignored](missing.md)\n",
+		"docs/reference/nested.md": "[Up](../install.md#requirements)\n",
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Check(dir)
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	want := &Report{
+		Documents: 4,
+		Links:     11,
+		External:  2,
+		Uncrawled: 1,
+		Broken: []BrokenLink{
+			{File: "SKILL.md", Line: 8, Target: "docs/missing.md", Reason: ReasonMissingFile},
+			{File: "docs/install.md", Line: 9, Target: "#nowhere", Reason: ReasonMissingAnchor},
+			{File: "docs/install.md", Line: 10, Target: "upgrade.md#gone", Reason: ReasonMissingAnchor},
+			{File: "docs/install.md", Line: 11, Target: "../assets/lost.png", Reason: ReasonMissingFile},
+			{File: "docs/install.md", Line: 12, Target: "../../etc/passwd", Reason: ReasonOutsideSkill},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Check() = %+v, want %+v", got, want)
+	}
+}
+
+func TestCheckMissingDir(t *testing.T) {
+	if _, err := Check(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Check() error = nil, want an error")
+	}
+}
+
+func TestTargets(t *testing.T) {
+	content := "---\ntitle: \"[Front](front.md)\"\n---\n\n# Install\n\nSee [the upgrade guide](upgrade.md#steps) and [keys](auth.md \"Auth\").\n" +
+		"![Diagram](../assets/diagram.png) `[Inline](inline.md)` [Site](https://example.com/)\n\n```md\n[Code](code.md)\n```\n"
+	want := []string{"upgrade.md#steps", "auth.md", "https://example.com/"}
+	if got := Targets(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("Targets() = %q, want %q", got, want)
+	}
+}
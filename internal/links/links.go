@@ -0,0 +1,308 @@
+// Package links rewrites the links between the pages of a skill, so that the
+// skill can be navigated offline: a link to a crawled page points at the
+// relative path of its converted file, and its fragment at the heading ID of
+// that file, while a link to a page of the site that isn't in the skill keeps
+// its absolute URL and is marked with a title:
+//
+//	[Install](https://docs.example.com/guide/install#requirements) -> [Install](install.md#requirements)
+//	[Pricing](https://docs.example.com/pricing) -> [Pricing](https://docs.example.com/pricing "not included in this skill")
+//
+// Pages are matched by their source, final, and canonical URLs and by the URLs
+// of their duplicates, ignoring the scheme, a trailing slash, index pages, and
+// the .html extension. Links to the files of pages split into parts (see
+// package chunker) are pointed at the part holding the heading they link to.
+// Fenced code blocks, inline code, and the YAML frontmatter are left untouched.
+package links
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/converter"
+	"github.com/f4ah6o/site2skill-go/internal/mdprose"
+	"gopkg.in/yaml.v3"
+)
+
+// UncrawledTitle is the title marking the links to pages of the site that
+// aren't in the skill.
+const UncrawledTitle = "not included in this skill"
+
+var (
+	// linkPattern matches Markdown links and images, whose text may itself
+	// contain a bracketed part: [text](url "title")
+	linkPattern = regexp.MustCompile(`(!?)\[((?:[^\[\]]|\[[^\]]*\])*)\]\(\s*(<?)([^)\s>]+)>?(\s+"[^"]*")?\s*\)`)
+)
+
+// Stats counts the links rewritten by Index.RewriteFile.
+type Stats struct {
+	// Internal is the number of links pointed at the file of a page.
+	Internal int
+	// Uncrawled is the number of links marked as pointing to a page of the
+	// site that isn't in the skill.
+	Uncrawled int
+}
+
+// Add adds the counts of other to s.
+func (s *Stats) Add(other Stats) {
+	s.Internal += other.Internal
+	s.Uncrawled += other.Uncrawled
+}
+
+// document is a file of the skill.
+type document struct {
+	// key identifies the page of the file (see urlKey)
+	key string
+	// part is the number of the file among the parts of the page; 0 if it isn't split
+	part int
+	// anchors maps the fragments of the original page to the heading IDs of the file
+	anchors map[string]string
+	// ids are the heading IDs of the file
+	ids map[string]bool
+}
+
+// Index maps the URLs of the pages of a skill to their files, which must all
+// be in the same directory.
+type Index struct {
+	// docs are the files by name
+	docs map[string]*document
+	// files lists the names of the files of each page key, in part order
+	files map[string][]string
+	// aliases maps the keys of the other URLs of the pages to their keys
+	aliases map[string]string
+	// hosts are the hosts of the pages, those of the site
+	hosts map[string]bool
+}
+
+// NewIndex returns an empty index.
+func NewIndex() *Index {
+	return &Index{
+		docs:    make(map[string]*document),
+		files:   make(map[string][]string),
+		aliases: make(map[string]string),
+		hosts:   make(map[string]bool),
+	}
+}
+
+// AddFile adds the Markdown file at path to the index, with the URLs and
+// anchors of its frontmatter and the IDs of its headings. A file with the
+// name of a file added before replaces it. Files without a source_url are
+// ignored.
+//
+// Returns an error if the file can't be read or its frontmatter parsed.
+func (x *Index) AddFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	frontmatter, body, ok := mdprose.SplitFrontmatter(string(content))
+	if !ok {
+		return nil
+	}
+	var fm struct {
+		SourceURL    string            `yaml:"source_url"`
+		FinalURL     string            `yaml:"final_url"`
+		CanonicalURL string            `yaml:"canonical_url"`
+		Aliases      []string          `yaml:"aliases"`
+		Anchors      map[string]string `yaml:"anchors"`
+		Part         int               `yaml:"part"`
+	}
+	if err := yaml.Unmarshal([]byte(frontmatter), &fm); err != nil {
+		return fmt.Errorf("failed to parse frontmatter: %w", err)
+	}
+	key, host := urlKey(fm.SourceURL)
+	if key == "" {
+		return nil
+	}
+
+	name := filepath.Base(path)
+	x.remove(name)
+	doc := &document{key: key, part: fm.Part, anchors: fm.Anchors, ids: make(map[string]bool)}
+	for _, h := range converter.Headings(body) {
+		doc.ids[h.ID] = true
+	}
+	x.docs[name] = doc
+	x.hosts[host] = true
+
+	files := x.files[key]
+	i := len(files)
+	for i > 0 && x.docs[files[i-1]].part > doc.part {
+		i--
+	}
+	x.files[key] = append(files[:i:i], append([]string{name}, files[i:]...)...)
+	for _, u := range append([]string{fm.FinalURL, fm.CanonicalURL}, fm.Aliases...) {
+		if k, _ := urlKey(u); k != "" && k != key {
+			if _, ok := x.aliases[k]; !ok {
+				x.aliases[k] = key
+			}
+		}
+	}
+	return nil
+}
+
+// remove removes the file name from the index.
+func (x *Index) remove(name string) {
+	doc, ok := x.docs[name]
+	if !ok {
+		return
+	}
+	delete(x.docs, name)
+	files := x.files[doc.key]
+	for i, f := range files {
+		if f == name {
+			files = append(files[:i:i], files[i+1:]...)
+			break
+		}
+	}
+	if len(files) == 0 {
+		delete(x.files, doc.key)
+	} else {
+		x.files[doc.key] = files
+	}
+}
+
+// lookup returns the files of the page with the key k, or of which k is the
+// key of another URL.
+func (x *Index) lookup(k string) []string {
+	if files := x.files[k]; len(files) > 0 {
+		return files
+	}
+	return x.files[x.aliases[k]]
+}
+
+// RewriteFile rewrites the links of the Markdown file at path, one of the
+// files of the index, in place (see Rewrite).
+//
+// Returns what was rewritten, or an error if the file can't be read or written.
+func (x *Index) RewriteFile(path string) (Stats, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to read file: %w", err)
+	}
+	out, stats := x.Rewrite(string(content), filepath.Base(path))
+	if out == string(content) {
+		return stats, nil
+	}
+	if err := os.WriteFile(path, []byte(out), 0644); err != nil {
+		return Stats{}, fmt.Errorf("failed to write file: %w", err)
+	}
+	return stats, nil
+}
+
+// Rewrite rewrites the links of content, the Markdown of the file name: the
+// absolute links to the pages of the index, and the relative links to its
+// files and fragment links whose heading is in another part of the page, are
+// pointed at the file holding the heading; the other absolute links to the
+// hosts of the pages are marked with UncrawledTitle. Rewriting is idempotent.
+func (x *Index) Rewrite(content, name string) (string, Stats) {
+	var stats Stats
+	out := mdprose.Map(content, func(_ int, prose string) string {
+		return linkPattern.ReplaceAllStringFunc(prose, func(link string) string {
+			m := linkPattern.FindStringSubmatch(link)
+			if m[1] == "!" || m[3] == "<" {
+				return link
+			}
+			target, uncrawled := x.resolve(m[4], name)
+			switch {
+			case target != "":
+				if target == m[4] {
+					return link
+				}
+				stats.Internal++
+				return "[" + m[2] + "](" + target + m[5] + ")"
+			case uncrawled && m[5] == "":
+				stats.Uncrawled++
+				return "[" + m[2] + "](" + m[4] + ` "` + UncrawledTitle + `")`
+			}
+			return link
+		})
+	})
+	return out, stats
+}
+
+// resolve returns the relative target of a link to href in the file name if
+// it points to a file of the index, or whether it points to another page of
+// the site.
+func (x *Index) resolve(href, name string) (target string, uncrawled bool) {
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+	fragment := u.Fragment
+	var files []string
+	switch {
+	case u.Scheme == "http" || u.Scheme == "https":
+		key, host := urlKey(href)
+		if files = x.lookup(key); len(files) == 0 {
+			return "", x.hosts[host]
+		}
+	case u.Scheme == "" && u.Host == "" && u.RawQuery == "":
+		file := u.Path
+		if file == "" {
+			file = name
+		}
+		doc, ok := x.docs[file]
+		if !ok || strings.Contains(file, "/") || fragment == "" {
+			return "", false
+		}
+		files = x.files[doc.key]
+		if len(files) < 2 {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+
+	// The part holding the heading, or the first part if none does
+	file, id := files[0], fragment
+	for _, f := range files {
+		doc := x.docs[f]
+		if mapped, ok := doc.anchors[fragment]; ok && doc.ids[mapped] {
+			file, id = f, mapped
+			break
+		}
+		if doc.ids[fragment] {
+			file = f
+			break
+		}
+	}
+	switch {
+	case id == "" && file == name:
+		return name, false
+	case id == "":
+		return file, false
+	case file == name:
+		return "#" + id, false
+	}
+	return file + "#" + id, false
+}
+
+// urlKey returns the key identifying the page at rawURL, its host and path,
+// with the query, but without the scheme, fragment, default port, index page,
+// .html extension, and trailing slash; and its host. Returns "" if rawURL
+// isn't an absolute HTTP URL.
+func urlKey(rawURL string) (string, string) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return "", ""
+	}
+	host := strings.ToLower(u.Hostname())
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	p := u.EscapedPath()
+	for _, suffix := range []string{"/index.html", "/index.htm", "/index", ".html", ".htm", "/"} {
+		if strings.HasSuffix(p, suffix) {
+			p = strings.TrimSuffix(p, suffix)
+			break
+		}
+	}
+	key := host + p
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key, host
+}
@@ -0,0 +1,100 @@
+package links
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRewrite(t *testing.T) {
+	files := map[string]string{
+		"index.md": "---\ntitle: Guide\nsource_url: https://docs.example.com/guide/index\nfinal_url: https://docs.example.com/guide/\n---\n\n# Guide\n\n## Overview\n",
+		"install.md": "---\ntitle: Install (part 1 of 2)\nsource_url: https://docs.example.com/guide/install\n" +
+			"aliases:\n  - https://docs.example.com/old/setup\nanchors:\n  requirements: requirements\npart: 1\nparts: 2\n---\n\n# Install\n\n## Requirements\n",
+		"install-part-2.md": "---\ntitle: Install (part 2 of 2)\nsource_url: https://docs.example.com/guide/install\n" +
+			"anchors:\n  _upgrade: upgrading\npart: 2\nparts: 2\n---\n\n# Install\n\n## Upgrading\n",
+		"notes.md": "# No frontmatter\n",
+	}
+	dir := t.TempDir()
+	x := NewIndex()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := x.AddFile(path); err != nil {
+			t.Fatalf("AddFile(%s) error = %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		want    string
+		stats   Stats
+	}{
+		{
+			name:    "page",
+			file:    "index.md",
+			content: "See [Install](https://docs.example.com/guide/install.html).",
+			want:    "See [Install](install.md).",
+			stats:   Stats{Internal: 1},
+		},
+		{
+			name:    "fragment mapped to the part holding the heading",
+			file:    "index.md",
+			content: "[Upgrade](http://docs.example.com/guide/install#_upgrade) [Requirements](https://docs.example.com/guide/install/#requirements)",
+			want:    "[Upgrade](install-part-2.md#upgrading) [Requirements](install.md#requirements)",
+			stats:   Stats{Internal: 2},
+		},
+		{
+			name:    "alias, final URL, and title",
+			file:    "install.md",
+			content: `[Setup](https://docs.example.com/old/setup) [Home](https://docs.example.com/guide/ "Home")`,
+			want:    `[Setup](install.md) [Home](index.md "Home")`,
+			stats:   Stats{Internal: 2},
+		},
+		{
+			name:    "link to the same page",
+			file:    "index.md",
+			content: "[Overview](https://docs.example.com/guide/#overview)",
+			want:    "[Overview](#overview)",
+			stats:   Stats{Internal: 1},
+		},
+		{
+			name:    "relative links into another part",
+			file:    "install.md",
+			content: "[Upgrading](#upgrading) [Upgrading](install.md#upgrading) [Requirements](#requirements) [Part 2](install-part-2.md)",
+			want:    "[Upgrading](install-part-2.md#upgrading) [Upgrading](install-part-2.md#upgrading) [Requirements](#requirements) [Part 2](install-part-2.md)",
+			stats:   Stats{Internal: 2},
+		},
+		{
+			name:    "uncrawled and external pages",
+			file:    "index.md",
+			content: `[Pricing](https://docs.example.com/pricing) [Blog](https://blog.example.com/) [Jobs](https://docs.example.com/jobs "Jobs")`,
+			want:    `[Pricing](https://docs.example.com/pricing "not included in this skill") [Blog](https://blog.example.com/) [Jobs](https://docs.example.com/jobs "Jobs")`,
+			stats:   Stats{Uncrawled: 1},
+		},
+		{
+			name:    "code, images, and frontmatter untouched",
+			file:    "index.md",
+			content: "---\nsource_url: https://docs.example.com/guide/install\n---\n\n![Diagram](https://docs.example.com/diagram.png)\n\n`[x](https://docs.example.com/pricing)`\n\n```\n[Install](https://docs.example.com/guide/install)\n```\n",
+			want:    "---\nsource_url: https://docs.example.com/guide/install\n---\n\n![Diagram](https://docs.example.com/diagram.png)\n\n`[x](https://docs.example.com/pricing)`\n\n```\n[Install](https://docs.example.com/guide/install)\n```\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stats := x.Rewrite(tt.content, tt.file)
+			if got != tt.want {
+				t.Errorf("Rewrite() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if stats != tt.stats {
+				t.Errorf("Rewrite() stats = %+v, want %+v", stats, tt.stats)
+			}
+			if again, _ := x.Rewrite(got, tt.file); again != got {
+				t.Errorf("Rewrite() isn't idempotent: %s", again)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+// Package logging configures the logs of the commands: their minimum level
+// (--log-level) and their format (--log-format), for people reading a console
+// and for log pipelines ingesting the output of CI jobs.
+//
+// Packages log with log/slog: the crawler logs each event with its URL and
+// other details as attributes, and warnings are logged at slog.LevelWarn (see
+// package warnlog). Setup installs the handler as the default slog handler,
+// which also receives the messages of the log package at slog.LevelInfo, so a
+// level above info silences every progress message.
+//
+// The text format keeps the classic log line, "2006/01/02 15:04:05 message",
+// with the level before the message unless it is info and the attributes
+// after it as key=value pairs. The JSON format writes one object per line
+// with the time, level, message, and attributes, as slog.JSONHandler does.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log formats of Setup.
+const (
+	// FormatText writes classic log lines for people.
+	FormatText = "text"
+	// FormatJSON writes one JSON object per line for log pipelines.
+	FormatJSON = "json"
+)
+
+// Formats lists the log formats of Setup.
+var Formats = []string{FormatText, FormatJSON}
+
+// Levels lists the names of the log levels accepted by ParseLevel, from the
+// most verbose.
+var Levels = []string{"debug", "info", "warn", "error"}
+
+// ParseLevel returns the level named s, one of Levels (case-insensitive).
+//
+// Returns an error if s is not a level name.
+func ParseLevel(s string) (slog.Level, error) {
+	if !slices.Contains(Levels, strings.ToLower(s)) {
+		return 0, fmt.Errorf("unknown log level %q (expected %s)", s, strings.Join(Levels, ", "))
+	}
+	var level slog.Level
+	err := level.UnmarshalText([]byte(s))
+	return level, err
+}
+
+// NewHandler returns a handler writing the records of level or above to w in
+// format (FormatText or FormatJSON).
+//
+// Returns an error if format is unknown.
+func NewHandler(w io.Writer, format string, level slog.Leveler) (slog.Handler, error) {
+	switch format {
+	case FormatText:
+		return &textHandler{mu: &sync.Mutex{}, w: w, level: level}, nil
+	case FormatJSON:
+		return slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level}), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q (expected %s)", format, strings.Join(Formats, ", "))
+	}
+}
+
+// Setup makes the default slog logger, and through it the log package, write
+// the records of the level named level or above to w in format.
+//
+// Returns an error if level or format is unknown.
+func Setup(w io.Writer, level, format string) error {
+	l, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	h, err := NewHandler(w, format, l)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(h))
+	return nil
+}
+
+// textHandler implements FormatText.
+type textHandler struct {
+	// mu serializes the writes of the handler and those derived from it
+	mu    *sync.Mutex
+	w     io.Writer
+	level slog.Leveler
+	// attrs are the attributes added with WithAttrs, formatted
+	attrs string
+	// group is the prefix of the keys of the attributes added next, e.g., "request."
+	group string
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var b strings.Builder
+	if !r.Time.IsZero() {
+		b.WriteString(r.Time.Format("2006/01/02 15:04:05 "))
+	}
+	if r.Level != slog.LevelInfo {
+		b.WriteString(r.Level.String() + " ")
+	}
+	b.WriteString(r.Message)
+	b.WriteString(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		appendAttr(&b, h.group, a)
+		return true
+	})
+	b.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, b.String())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var b strings.Builder
+	for _, a := range attrs {
+		appendAttr(&b, h.group, a)
+	}
+	h2 := *h
+	h2.attrs += b.String()
+	return &h2
+}
+
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.group += name + "."
+	return &h2
+}
+
+// appendAttr writes a as " key=value" to b, with the keys of group prefixed
+// by prefix and their own key. Empty attributes are left out.
+func appendAttr(b *strings.Builder, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			appendAttr(b, prefix, ga)
+		}
+		return
+	}
+	var value string
+	switch a.Value.Kind() {
+	case slog.KindTime:
+		value = a.Value.Time().Format(time.RFC3339)
+	default:
+		value = a.Value.String()
+	}
+	if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+		value = strconv.Quote(value)
+	}
+	fmt.Fprintf(b, " %s%s=%s", prefix, a.Key, value)
+}
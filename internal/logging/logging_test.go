@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"debug", slog.LevelDebug, false},
+		{"info", slog.LevelInfo, false},
+		{"WARN", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"warning", 0, true},
+		{"info+2", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestTextHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := NewHandler(&buf, FormatText, slog.LevelInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(h)
+	logger.Debug("hidden")
+	logger.Info("Fetching site", "url", "https://docs.example.com/", "dir", "build/crawl")
+	logger.Warn("Warning: page failed", "error", "connection refused", "empty", "")
+	logger.With("profile", "stripe").WithGroup("page").Info("Saved", "status", 200, slog.Group("size", "bytes", 10))
+
+	want := []string{
+		`Fetching site url=https://docs.example.com/ dir=build/crawl`,
+		`WARN Warning: page failed error="connection refused" empty=""`,
+		`Saved profile=stripe page.status=200 page.size.bytes=10`,
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("logged %d lines, want %d:\n%s", len(lines), len(want), buf.String())
+	}
+	stamp := regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} `)
+	for i, line := range lines {
+		if !stamp.MatchString(line) {
+			t.Errorf("line %d = %q, want a log timestamp", i, line)
+		}
+		if got := stamp.ReplaceAllString(line, ""); got != want[i] {
+			t.Errorf("line %d = %q, want %q", i, got, want[i])
+		}
+	}
+
+	if _, err := NewHandler(&buf, "xml", slog.LevelInfo); err == nil {
+		t.Error("NewHandler() with an unknown format succeeded, want error")
+	}
+}
+
+func TestSetup(t *testing.T) {
+	// Setup redirects the log package to the new handler
+	defer slog.SetDefault(slog.Default())
+	defer log.SetOutput(log.Writer())
+	defer log.SetFlags(log.Flags())
+
+	var buf bytes.Buffer
+	if err := Setup(&buf, "warn", FormatJSON); err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	log.Printf("Converting pages")
+	slog.Info("Fetching site")
+	slog.Warn("Blocked by robots.txt", "kind", "robots")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("logged %q, want only the warning", lines)
+	}
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatalf("invalid JSON line %s: %v", lines[0], err)
+	}
+	if rec["level"] != "WARN" || rec["msg"] != "Blocked by robots.txt" || rec["kind"] != "robots" {
+		t.Errorf("logged %v", rec)
+	}
+
+	buf.Reset()
+	if err := Setup(&buf, "info", FormatText); err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	log.Printf("Converting %d pages", 3)
+	if !strings.HasSuffix(buf.String(), " Converting 3 pages\n") {
+		t.Errorf("log.Printf wrote %q, want it through the text handler", buf.String())
+	}
+
+	for _, args := range [][2]string{{"verbose", FormatText}, {"info", "xml"}} {
+		if err := Setup(&buf, args[0], args[1]); err == nil {
+			t.Errorf("Setup(%q, %q) succeeded, want error", args[0], args[1])
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Package mcp serves a generated skill to agents over the Model Context
+// Protocol (MCP), so that they can search and read its documents as live
+// tools instead of loading static files.
+//
+// The server speaks JSON-RPC 2.0 and exposes five tools: search_docs (see
+// search.Searcher.Search), get_doc (search.Searcher.Document), related_docs
+// (search.Searcher.Related), list_docs (search.Searcher.Documents), and
+// get_search_capabilities (search.GetCapabilities). It is served over stdio,
+// for agents launching it as a subprocess (see Server.ServeStdio), or over
+// HTTP with Server-Sent Events, for agents connecting to a running server
+// (see Server.Handler).
+//
+// Searches and related documents go through a search.Limiter, so that the
+// agents of a shared server can't run more than
+// search.DefaultMaxConcurrentQueries scans at once, nor one longer, or with
+// more results, than search.DefaultQueryLimits allow.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"runtime/debug"
+
+	"github.com/f4ah6o/site2skill-go/internal/metrics"
+	"github.com/f4ah6o/site2skill-go/internal/search"
+)
+
+// ProtocolVersion is the MCP version the server implements. Clients asking
+// for another version of SupportedVersions are answered in their version.
+const ProtocolVersion = "2025-06-18"
+
+// SupportedVersions lists the MCP versions the server can speak, newest first.
+// The tools it uses are the same in all of them.
+var SupportedVersions = []string{ProtocolVersion, "2025-03-26", "2024-11-05"}
+
+// JSON-RPC 2.0 error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// Server answers the MCP requests of agents about one skill.
+// It is safe for concurrent use.
+type Server struct {
+	searcher *search.Searcher
+	limiter  *search.Limiter
+	name     string
+	access   []string
+	metrics  *metrics.Server
+}
+
+// NewServer returns a server of the skill in skillDir. access lists the
+// access levels the agents are allowed (see search.SearchOptions.Access):
+// documents of other levels are neither found, listed, nor read. Nil allows
+// every document.
+//
+// Returns an error if skillDir has no docs directory.
+func NewServer(skillDir string, access []string) (*Server, error) {
+	searcher, err := search.NewSearcher(skillDir)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		searcher: searcher,
+		limiter:  search.NewLimiter(search.DefaultMaxConcurrentQueries, search.DefaultQueryLimits),
+		name:     filepath.Base(searcher.SkillDir()),
+		access:   access,
+	}, nil
+}
+
+// SetMetrics records the tool calls and searches of the server in m (see
+// metrics.Server), and, over HTTP, its requests; nil records nothing. Call it
+// before serving requests.
+func (s *Server) SetMetrics(m *metrics.Server) {
+	s.metrics = m
+}
+
+// request is a JSON-RPC request, or a notification if it has no ID.
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC response.
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is the error of a JSON-RPC response.
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// handleMessage answers one JSON-RPC message. Returns nil for notifications
+// and responses sent by the client, which are not answered.
+func (s *Server) handleMessage(ctx context.Context, data []byte) *response {
+	var req request
+	if err := json.Unmarshal(data, &req); err != nil {
+		return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{codeParseError, "parse error: " + err.Error()}}
+	}
+	if req.Method == "" {
+		if req.ID == nil {
+			return &response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{codeInvalidRequest, "invalid request: no method"}}
+		}
+		// A response to a request of the server, which sends none
+		return nil
+	}
+
+	result, rpcErr := s.handle(ctx, req.Method, req.Params)
+	if req.ID == nil {
+		return nil
+	}
+	resp := &response{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rpcErr}
+	if rpcErr == nil && result == nil {
+		resp.Result = struct{}{}
+	}
+	return resp
+}
+
+// handle runs the method of a request or notification with its params.
+func (s *Server) handle(ctx context.Context, method string, params json.RawMessage) (any, *rpcError) {
+	switch method {
+	case "initialize":
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		if err := unmarshalParams(params, &p); err != nil {
+			return nil, err
+		}
+		version := ProtocolVersion
+		for _, v := range SupportedVersions {
+			if v == p.ProtocolVersion {
+				version = v
+			}
+		}
+		return map[string]any{
+			"protocolVersion": version,
+			"capabilities":    map[string]any{"tools": map[string]any{}},
+			"serverInfo":      map[string]any{"name": "site2skillgo", "title": s.name + " documentation", "version": buildVersion()},
+			"instructions": fmt.Sprintf("Documentation of %s. Use search_docs to find the documents answering a question, "+
+				"get_doc to read one (or only the lines of a section given by search_docs), related_docs to find the documents "+
+				"similar to one, list_docs to browse them, and get_search_capabilities for the query syntax and limits.", s.name),
+		}, nil
+	case "ping", "notifications/initialized", "notifications/cancelled":
+		return nil, nil
+	case "tools/list":
+		return map[string]any{"tools": tools}, nil
+	case "tools/call":
+		var p struct {
+			Name      string          `json:"name"`
+			Arguments json.RawMessage `json:"arguments"`
+		}
+		if err := unmarshalParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.callTool(ctx, p.Name, p.Arguments)
+	default:
+		return nil, &rpcError{codeMethodNotFound, "method not found: " + method}
+	}
+}
+
+// buildVersion returns the module version of the running binary, or "devel"
+// if it isn't known.
+func buildVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "devel"
+}
+
+// unmarshalParams decodes the params of a request into v; absent params leave
+// v unchanged.
+func unmarshalParams(params json.RawMessage, v any) *rpcError {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return &rpcError{codeInvalidParams, "invalid params: " + err.Error()}
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/f4ah6o/site2skill-go/internal/search"
+)
+
+func writeSkill(t *testing.T) string {
+	t.Helper()
+	skillDir := t.TempDir()
+	docs := map[string]string{
+		"install.md":  "---\ntitle: \"Install\"\nsource_url: \"https://docs.example.com/install\"\n---\n# Install\n\nDownload the binary.\n\n## Docker\n\nRun the image.\n",
+		"internal.md": "---\ntitle: \"Runbook\"\naccess: \"internal\"\n---\n# Runbook\n\nInstall with the internal tool.\n",
+	}
+	if err := os.MkdirAll(filepath.Join(skillDir, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range docs {
+		if err := os.WriteFile(filepath.Join(skillDir, "docs", name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return skillDir
+}
+
+// call sends messages to a stdio server and returns its responses by ID.
+func call(t *testing.T, s *Server, messages ...string) map[string]response {
+	t.Helper()
+	var out strings.Builder
+	if err := s.ServeStdio(context.Background(), strings.NewReader(strings.Join(messages, "\n")+"\n"), &out); err != nil {
+		t.Fatalf("ServeStdio() error = %v", err)
+	}
+	responses := make(map[string]response)
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var resp response
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", line, err)
+		}
+		responses[string(resp.ID)] = resp
+	}
+	return responses
+}
+
+// toolText returns the text of the tool result of resp and whether it is an error.
+func toolText(t *testing.T, resp response) (string, bool) {
+	t.Helper()
+	if resp.Error != nil {
+		t.Fatalf("response error = %+v", resp.Error)
+	}
+	data, _ := json.Marshal(resp.Result)
+	var result toolResult
+	if err := json.Unmarshal(data, &result); err != nil || len(result.Content) != 1 {
+		t.Fatalf("invalid tool result %s", data)
+	}
+	return result.Content[0].Text, result.IsError
+}
+
+func TestServeStdio(t *testing.T) {
+	s, err := NewServer(writeSkill(t), []string{"public"})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+
+	responses := call(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2024-11-05","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"search_docs","arguments":{"query":"install"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"get_doc","arguments":{"path":"docs/install.md","start_line":9,"end_line":11}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"get_doc","arguments":{"path":"internal.md"}}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"get_doc","arguments":{"path":"../../etc/passwd.md"}}}`,
+		`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"list_docs","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":8,"method":"tools/call","params":{"name":"search_docs","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":9,"method":"resources/list"}`,
+		`{"jsonrpc":"2.0","id":10,"method":"tools/call","params":{"name":"related_docs","arguments":{"path":"docs/install.md"}}}`,
+		`{"jsonrpc":"2.0","id":11,"method":"tools/call","params":{"name":"get_search_capabilities","arguments":{}}}`,
+		`not json`,
+	)
+	if len(responses) != 12 {
+		t.Errorf("got %d responses, want 12 (notifications aren't answered)", len(responses))
+	}
+
+	if init, _ := json.Marshal(responses["1"].Result); !strings.Contains(string(init), `"protocolVersion":"2024-11-05"`) {
+		t.Errorf("initialize = %s, want the client's protocol version", init)
+	}
+	if list, _ := json.Marshal(responses["2"].Result); strings.Count(string(list), `"inputSchema"`) != 5 {
+		t.Errorf("tools/list = %s, want 5 tools", list)
+	}
+
+	// The internal runbook is left out of every tool
+	if text, isErr := toolText(t, responses["3"]); isErr || !strings.Contains(text, `"file": "docs/install.md"`) || strings.Contains(text, "internal.md") {
+		t.Errorf("search_docs = %s", text)
+	}
+	if text, isErr := toolText(t, responses["4"]); isErr || text != "## Docker\n\nRun the image.\n" {
+		t.Errorf("get_doc with lines = %q", text)
+	}
+	for _, id := range []string{"5", "6"} {
+		if text, isErr := toolText(t, responses[id]); !isErr || !strings.Contains(text, "document not found") {
+			t.Errorf("get_doc %s = %q, want not found error", id, text)
+		}
+	}
+	if text, isErr := toolText(t, responses["7"]); isErr || !strings.Contains(text, `"title": "Install"`) || strings.Contains(text, "Runbook") {
+		t.Errorf("list_docs = %s", text)
+	}
+
+	// The only other document is internal
+	if text, isErr := toolText(t, responses["10"]); isErr || text != "[]" {
+		t.Errorf("related_docs = %s", text)
+	}
+
+	if text, isErr := toolText(t, responses["11"]); isErr || !strings.Contains(text, `"syntax"`) || !strings.Contains(text, `"MaxResults": 100`) {
+		t.Errorf("get_search_capabilities = %s, want the syntax and the limits of the server", text)
+	}
+
+	for id, code := range map[string]int{"8": codeInvalidParams, "9": codeMethodNotFound, "null": codeParseError} {
+		if resp := responses[id]; resp.Error == nil || resp.Error.Code != code {
+			t.Errorf("response %s error = %+v, want code %d", id, resp.Error, code)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	s, err := NewServer(writeSkill(t), nil)
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	srv := httptest.NewServer(http.StripPrefix("/mcp", s.Handler()))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/mcp/sse", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /sse error = %v", err)
+	}
+	defer resp.Body.Close()
+	events := bufio.NewReader(resp.Body)
+
+	// next returns the event and data of the next event of the stream
+	next := func() (string, string) {
+		t.Helper()
+		var event, data string
+		for {
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatalf("failed to read event: %v", err)
+			}
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			case line == "":
+				return event, data
+			}
+		}
+	}
+
+	event, endpoint := next()
+	if event != "endpoint" {
+		t.Fatalf("first event = %q, want endpoint", event)
+	}
+	base, _ := url.Parse(srv.URL + "/mcp/sse")
+	target, err := base.Parse(endpoint)
+	if err != nil {
+		t.Fatalf("invalid endpoint %q: %v", endpoint, err)
+	}
+
+	post, err := http.Post(target.String(), "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":"a","method":"tools/call","params":{"name":"get_doc","arguments":{"path":"docs/internal.md"}}}`))
+	if err != nil {
+		t.Fatalf("POST error = %v", err)
+	}
+	post.Body.Close()
+	if post.StatusCode != http.StatusAccepted {
+		t.Fatalf("POST status = %d, want 202", post.StatusCode)
+	}
+
+	event, data := next()
+	var msg response
+	if err := json.Unmarshal([]byte(data), &msg); err != nil || event != "message" || string(msg.ID) != `"a"` {
+		t.Fatalf("response event = %q %q", event, data)
+	}
+	if text, isErr := toolText(t, msg); isErr || !strings.Contains(text, "Install with the internal tool.") {
+		t.Errorf("get_doc = %q", text)
+	}
+
+	post, err = http.Post(srv.URL+"/mcp/message?sessionId=unknown", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatalf("POST error = %v", err)
+	}
+	post.Body.Close()
+	if post.StatusCode != http.StatusNotFound {
+		t.Errorf("POST to an unknown session status = %d, want 404", post.StatusCode)
+	}
+}
+
+func TestSearchDocsDiagnosis(t *testing.T) {
+	s, err := NewServer(writeSkill(t), []string{"public"})
+	if err != nil {
+		t.Fatalf("NewServer() error = %v", err)
+	}
+	responses := call(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"search_docs","arguments":{"query":"dowload"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"search_docs","arguments":{"query":"runbok"}}}`,
+	)
+
+	text, isErr := toolText(t, responses["1"])
+	var got struct {
+		Results   []search.SearchResult `json:"results"`
+		Diagnosis *search.Diagnosis     `json:"diagnosis"`
+	}
+	if err := json.Unmarshal([]byte(text), &got); isErr || err != nil || got.Diagnosis == nil || got.Diagnosis.DidYouMean != "download" {
+		t.Errorf("search_docs without results = %s", text)
+	}
+
+	// The words of the documents the agent can't read aren't suggested
+	if text, isErr := toolText(t, responses["2"]); isErr || strings.Contains(text, "runbook") || strings.Contains(text, "did_you_mean") {
+		t.Errorf("search_docs = %s, want no suggestion from the internal runbook", text)
+	}
+}
@@ -0,0 +1,278 @@
+// Package mcp serves a generated skill to agents over the Model Context Protocol.
+// This file implements the tools of the server.
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/search"
+)
+
+// defaultMaxResults is the number of results of search_docs unless the agent
+// asks for another.
+const defaultMaxResults = 10
+
+// tool describes a tool in the response to tools/list.
+type tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	InputSchema map[string]any `json:"inputSchema"`
+}
+
+// filterProperties are the JSON Schema properties of the document filters
+// shared by search_docs and list_docs.
+var filterProperties = map[string]any{
+	"path_prefix": map[string]any{"type": "string", "description": `Only the documents whose source URL path is under this path (e.g., "/api")`},
+	"locale":      map[string]any{"type": "string", "description": `Only the documents of this locale (e.g., "en" also matches "en-US")`},
+	"tag":         map[string]any{"type": "string", "description": "Only the documents with this tag"},
+}
+
+// tools are the tools of the server.
+var tools = []tool{
+	{
+		Name: "search_docs",
+		Description: "Search the documentation for keywords. Returns the matching documents, best first, with the " +
+			"lines around each match and the heading path and line range of its section, to read with get_doc. " +
+			"When nothing matches, it reports how many documents each term matches on its own and suggests " +
+			"words of the documentation close to the terms matching none (did_you_mean).",
+		InputSchema: objectSchema([]string{"query"}, map[string]any{
+			"query":       map[string]any{"type": "string", "description": `Space-separated keywords and "quoted phrases", any of which matches; prefix a term with + to require it or - to exclude it`},
+			"all":         map[string]any{"type": "boolean", "description": "Require every keyword instead of any"},
+			"max_results": maxResultsProperty,
+		}),
+	},
+	{
+		Name: "get_doc",
+		Description: "Read a document of the documentation, given its path as returned by search_docs or list_docs. " +
+			"start_line and end_line read only those lines, e.g., a section found by search_docs.",
+		InputSchema: objectSchema([]string{"path"}, map[string]any{
+			"path":       map[string]any{"type": "string", "description": `Path of the document (e.g., "docs/guide/install.md")`},
+			"start_line": map[string]any{"type": "integer", "minimum": 1, "description": "First line to read, numbered from 1"},
+			"end_line":   map[string]any{"type": "integer", "minimum": 1, "description": "Last line to read"},
+		}),
+	},
+	{
+		Name: "related_docs",
+		Description: "List the documents most similar to a document, given its path as returned by search_docs or " +
+			"list_docs, to widen the context from a relevant page.",
+		InputSchema: objectSchema([]string{"path"}, map[string]any{
+			"path":        map[string]any{"type": "string", "description": `Path of the document (e.g., "docs/guide/install.md")`},
+			"max_results": maxResultsProperty,
+		}),
+	},
+	{
+		Name:        "list_docs",
+		Description: "List the documents of the documentation with their path, title, description, and source URL.",
+		InputSchema: objectSchema(nil, nil),
+	},
+	{
+		Name: "get_search_capabilities",
+		Description: "Describe the query syntax search_docs supports, with an example of each construct, the filters, " +
+			"the limits of each query, and the number of documents, to write valid queries.",
+		InputSchema: map[string]any{"type": "object", "properties": map[string]any{}},
+	},
+}
+
+// maxResultsProperty is the JSON Schema property of max_results, which is
+// capped by the limits of the server's queries.
+var maxResultsProperty = map[string]any{
+	"type":        "integer",
+	"minimum":     1,
+	"maximum":     search.DefaultQueryLimits.MaxResults,
+	"description": fmt.Sprintf("Maximum number of documents returned (default %d, at most %d)", defaultMaxResults, search.DefaultQueryLimits.MaxResults),
+}
+
+// objectSchema returns the JSON Schema of the arguments of a tool: an object
+// with properties, the document filters, and the required properties.
+func objectSchema(required []string, properties map[string]any) map[string]any {
+	props := make(map[string]any)
+	for name, p := range filterProperties {
+		props[name] = p
+	}
+	for name, p := range properties {
+		props[name] = p
+	}
+	schema := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// toolArguments are the arguments of every tool.
+type toolArguments struct {
+	Query      string `json:"query"`
+	All        bool   `json:"all"`
+	MaxResults int    `json:"max_results"`
+	Path       string `json:"path"`
+	StartLine  int    `json:"start_line"`
+	EndLine    int    `json:"end_line"`
+	PathPrefix string `json:"path_prefix"`
+	Locale     string `json:"locale"`
+	Tag        string `json:"tag"`
+}
+
+// toolResult is the result of tools/call: text content, which is an error
+// message if IsError is set.
+type toolResult struct {
+	Content []textContent `json:"content"`
+	IsError bool          `json:"isError,omitempty"`
+}
+
+// textContent is a text content item of a tool result.
+type textContent struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// callTool runs the tool name with the JSON arguments. Failures of the tool
+// are reported in the result, for the agent to see, and unknown tools and
+// invalid arguments as JSON-RPC errors.
+func (s *Server) callTool(ctx context.Context, name string, arguments json.RawMessage) (any, *rpcError) {
+	var args toolArguments
+	if err := unmarshalParams(arguments, &args); err != nil {
+		return nil, err
+	}
+	opts := search.SearchOptions{
+		Access:     s.access,
+		PathPrefix: args.PathPrefix,
+		Locale:     args.Locale,
+		Tag:        args.Tag,
+	}
+
+	var text string
+	var err error
+	start := time.Now()
+	switch name {
+	case "search_docs":
+		if strings.TrimSpace(args.Query) == "" {
+			return nil, &rpcError{codeInvalidParams, "invalid params: query is required"}
+		}
+		opts.Query = args.Query
+		opts.MatchAll = args.All
+		opts.MaxResults = args.MaxResults
+		if opts.MaxResults <= 0 {
+			opts.MaxResults = defaultMaxResults
+		}
+		text, err = s.searchDocs(ctx, opts)
+		s.metrics.ObserveSearch(start, err)
+	case "get_doc":
+		if args.Path == "" {
+			return nil, &rpcError{codeInvalidParams, "invalid params: path is required"}
+		}
+		text, err = s.getDoc(args.Path, args.StartLine, args.EndLine, opts)
+	case "related_docs":
+		if args.Path == "" {
+			return nil, &rpcError{codeInvalidParams, "invalid params: path is required"}
+		}
+		opts.MaxResults = args.MaxResults
+		if opts.MaxResults <= 0 {
+			opts.MaxResults = defaultMaxResults
+		}
+		opts.MaxResults = s.limiter.Limits().ClampResults(opts.MaxResults)
+		text, err = s.relatedDocs(ctx, args.Path, opts)
+	case "list_docs":
+		var docs []search.DocumentInfo
+		if docs, err = s.searcher.Documents(ctx, opts); err == nil {
+			text, err = marshalText(docs)
+		}
+	case "get_search_capabilities":
+		limits := s.limiter.Limits()
+		text, err = marshalText(search.GetCapabilities(s.searcher.SkillDir(), &limits))
+	default:
+		return nil, &rpcError{codeInvalidParams, "unknown tool: " + name}
+	}
+	s.metrics.ObserveTool(name, start, err == nil)
+	if err != nil {
+		return toolResult{Content: []textContent{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+	}
+	return toolResult{Content: []textContent{{Type: "text", Text: text}}}, nil
+}
+
+// searchDocs runs a search through the limiter of the server and returns its
+// results as JSON, or, if there are none, the empty results with the
+// diagnosis of the query (see search.DiagnoseQuery), for the agent to
+// correct it.
+func (s *Server) searchDocs(ctx context.Context, opts search.SearchOptions) (string, error) {
+	opts.SkillDir = s.searcher.SkillDir()
+	results, err := s.limiter.Search(ctx, opts)
+	if err != nil {
+		return "", err
+	}
+	if len(results) > 0 {
+		return marshalText(results)
+	}
+	results = []search.SearchResult{}
+	var diagnosis *search.Diagnosis
+	err = s.limiter.Do(ctx, func(ctx context.Context) error {
+		var err error
+		diagnosis, err = s.searcher.Diagnose(ctx, opts)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return marshalText(struct {
+		Results   []search.SearchResult `json:"results"`
+		Diagnosis *search.Diagnosis     `json:"diagnosis"`
+	}{results, diagnosis})
+}
+
+// getDoc returns the document at path, or its lines from start to end if
+// either is set.
+func (s *Server) getDoc(path string, start, end int, opts search.SearchOptions) (string, error) {
+	content, _, err := s.searcher.Document(path, opts)
+	if err != nil {
+		if errors.Is(err, search.ErrDocumentNotFound) {
+			return "", fmt.Errorf("document not found: %s (see list_docs for the available paths)", path)
+		}
+		return "", err
+	}
+	if start <= 0 && end <= 0 {
+		return content, nil
+	}
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	start = max(start, 1)
+	if end <= 0 || end > len(lines) {
+		end = len(lines)
+	}
+	if start > end {
+		return "", fmt.Errorf("no lines in the requested range: the document has %d lines", len(lines))
+	}
+	return strings.Join(lines[start-1:end], "\n") + "\n", nil
+}
+
+// relatedDocs returns the documents most similar to the document at path as
+// JSON, found through the limiter of the server.
+func (s *Server) relatedDocs(ctx context.Context, path string, opts search.SearchOptions) (string, error) {
+	var related []search.RelatedDocument
+	err := s.limiter.Do(ctx, func(ctx context.Context) error {
+		var err error
+		related, err = s.searcher.Related(ctx, path, opts)
+		return err
+	})
+	if errors.Is(err, search.ErrDocumentNotFound) {
+		return "", fmt.Errorf("document not found: %s (see list_docs for the available paths)", path)
+	}
+	if err != nil {
+		return "", err
+	}
+	if related == nil {
+		related = []search.RelatedDocument{}
+	}
+	return marshalText(related)
+}
+
+// marshalText returns v as indented JSON.
+func marshalText(v any) (string, error) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
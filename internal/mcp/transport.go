@@ -0,0 +1,156 @@
+// Package mcp serves a generated skill to agents over the Model Context Protocol.
+// This file implements the stdio and HTTP with Server-Sent Events transports.
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// maxMessageSize caps the size of a message from a client.
+const maxMessageSize = 4 << 20
+
+// ServeStdio answers the messages read from in, one JSON-RPC message per
+// line, writing the responses to out, one per line, until in is closed or ctx
+// is cancelled. Messages are answered in order. Nothing else is written to
+// out, so logs must go elsewhere (e.g., to stderr).
+//
+// Returns an error if in can't be read or out can't be written.
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	enc := json.NewEncoder(out)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if resp := s.handleMessage(ctx, line); resp != nil {
+			if err := enc.Encode(resp); err != nil {
+				return fmt.Errorf("failed to write response: %w", err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read message: %w", err)
+	}
+	return nil
+}
+
+// Handler returns the HTTP handler of the server's Server-Sent Events
+// transport: a client opens an event stream with GET /sse, whose first
+// "endpoint" event gives the URL to POST its messages to (message?sessionId=...,
+// relative to the stream URL), and receives the responses as "message"
+// events of the stream. The handler can be mounted under a path prefix with
+// http.StripPrefix.
+func (s *Server) Handler() http.Handler {
+	h := &sseHandler{server: s, sessions: make(map[string]*sseSession)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sse", h.stream)
+	mux.HandleFunc("POST /message", h.message)
+	return s.metrics.Handler(mux, "/sse", "/message")
+}
+
+// sseHandler serves the event streams of the connected clients.
+type sseHandler struct {
+	server *Server
+
+	mu       sync.Mutex
+	sessions map[string]*sseSession
+}
+
+// sseSession is the event stream of one client.
+type sseSession struct {
+	// responses are the responses to send on the stream
+	responses chan *response
+	// done is closed when the stream is closed
+	done chan struct{}
+}
+
+// stream serves the event stream of a new session until the client
+// disconnects.
+func (h *sseHandler) stream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	var raw [16]byte
+	if _, err := rand.Read(raw[:]); err != nil {
+		http.Error(w, "failed to create session", http.StatusInternalServerError)
+		return
+	}
+	id := hex.EncodeToString(raw[:])
+	session := &sseSession{responses: make(chan *response, 16), done: make(chan struct{})}
+	h.mu.Lock()
+	h.sessions[id] = session
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.sessions, id)
+		h.mu.Unlock()
+		close(session.done)
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	fmt.Fprintf(w, "event: endpoint\ndata: message?sessionId=%s\n\n", id)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case resp := <-session.responses:
+			data, err := json.Marshal(resp)
+			if err != nil {
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "event: message\ndata: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// message answers a message posted by the client of a session on its event
+// stream.
+func (h *sseHandler) message(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	session := h.sessions[r.URL.Query().Get("sessionId")]
+	h.mu.Unlock()
+	if session == nil {
+		http.Error(w, "unknown session", http.StatusNotFound)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+	if err != nil {
+		http.Error(w, "failed to read message", http.StatusBadRequest)
+		return
+	}
+
+	if resp := h.server.handleMessage(r.Context(), body); resp != nil {
+		select {
+		case session.responses <- resp:
+		case <-session.done:
+			http.Error(w, "session closed", http.StatusGone)
+			return
+		case <-r.Context().Done():
+			return
+		}
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
@@ -0,0 +1,111 @@
+// Package mdprose walks the prose of Markdown documents: the text that is
+// neither YAML frontmatter, a fenced code block, nor inline code. Rewriters of
+// links and references use it so that code samples are left as they are:
+//
+//	out := mdprose.Map(content, func(line int, prose string) string {
+//		return strings.ReplaceAll(prose, "http://", "https://")
+//	})
+//
+// SplitFrontmatter splits the YAML frontmatter from the body of a document,
+// so that every package reading documents agrees on what frontmatter is.
+package mdprose
+
+import (
+	"regexp"
+	"strings"
+)
+
+// frontmatterPattern matches the YAML frontmatter a document starts with,
+// capturing its YAML: the lines between two "---" lines, which may end with
+// spaces and CRLF line endings, the closing one possibly ending the document.
+var frontmatterPattern = regexp.MustCompile(`^---[ \t]*\r?\n((?s).*?)\r?\n---[ \t]*(?:\r?\n|$)`)
+
+// openingPattern matches the opening line of frontmatter.
+var openingPattern = regexp.MustCompile(`^---[ \t]*\r?\n`)
+
+// SplitFrontmatter splits content, a Markdown document, into the YAML of the
+// frontmatter it starts with, without its delimiter lines, and its body, so
+// that content[:len(content)-len(body)] is the whole frontmatter block.
+// Returns content as the body and false if content has no frontmatter, or
+// one that isn't terminated.
+func SplitFrontmatter(content string) (frontmatter, body string, ok bool) {
+	m := frontmatterPattern.FindStringSubmatchIndex(content)
+	if m == nil {
+		return "", content, false
+	}
+	return content[m[2]:m[3]], content[m[1]:], true
+}
+
+// OpensFrontmatter reports whether content starts with the opening line of
+// frontmatter, terminated or not: a document for which it holds but
+// SplitFrontmatter fails has unterminated frontmatter.
+func OpensFrontmatter(content string) bool {
+	return openingPattern.MatchString(content)
+}
+
+// Map applies fn to every span of content that is neither frontmatter, a
+// fenced code block (``` or ~~~), nor inline code, and returns content with
+// the spans replaced by the results of fn. fn receives the 1-based line
+// number of the span in content, frontmatter included.
+func Map(content string, fn func(line int, prose string) string) string {
+	var b strings.Builder
+	lineOffset := 0
+	if _, body, ok := SplitFrontmatter(content); ok {
+		block := content[:len(content)-len(body)]
+		b.WriteString(block)
+		lineOffset = strings.Count(block, "\n")
+		content = body
+	}
+
+	fence := ""
+	for i, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		switch {
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			b.WriteString(line)
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			fence = trimmed[:3]
+			b.WriteString(line)
+		default:
+			lineNo := lineOffset + i + 1
+			b.WriteString(MapOutsideCodeSpans(line, func(prose string) string {
+				return fn(lineNo, prose)
+			}))
+		}
+	}
+	return b.String()
+}
+
+// MapOutsideCodeSpans applies fn to the parts of line that are not inline
+// code, and returns line with them replaced by the results of fn. A code span
+// is delimited by runs of backticks of the same length; unmatched backticks
+// are literal text.
+func MapOutsideCodeSpans(line string, fn func(string) string) string {
+	var b strings.Builder
+	for line != "" {
+		start := strings.Index(line, "`")
+		if start < 0 {
+			b.WriteString(fn(line))
+			break
+		}
+		n := start
+		for n < len(line) && line[n] == '`' {
+			n++
+		}
+		delim := line[start:n]
+		end := strings.Index(line[n:], delim)
+		if end < 0 {
+			// Unmatched backticks are literal text
+			b.WriteString(fn(line[:n]))
+			line = line[n:]
+			continue
+		}
+		b.WriteString(fn(line[:start]))
+		b.WriteString(line[start : n+end+len(delim)])
+		line = line[n+end+len(delim):]
+	}
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package mdprose
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	upper := func(line int, prose string) string { return strings.ToUpper(prose) }
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"prose", "see docs\n", "SEE DOCS\n"},
+		{"frontmatter", "---\ntitle: x\n---\nbody\n", "---\ntitle: x\n---\nBODY\n"},
+		{"fence", "a\n```go\nb\n```\nc\n", "A\n```go\nb\n```\nC\n"},
+		{"tilde fence", "~~~\nb\n~~~\nc", "~~~\nb\n~~~\nC"},
+		{"inline code", "run `cmd x` now\n", "RUN `cmd x` NOW\n"},
+		{"double backticks", "a ``b ` c`` d", "A ``b ` c`` D"},
+		{"unmatched backtick", "a ` b", "A ` B"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Map(tt.content, upper); got != tt.want {
+				t.Errorf("Map(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapLineNumbers(t *testing.T) {
+	var lines []string
+	Map("---\ntitle: x\n---\none\n```\ncode\n```\ntwo `x` end\n", func(line int, prose string) string {
+		lines = append(lines, fmt.Sprintf("%d:%s", line, strings.TrimSpace(prose)))
+		return prose
+	})
+	want := "4:one,8:two,8:end"
+	if got := strings.Join(lines, ","); got != want {
+		t.Errorf("spans = %s, want %s", got, want)
+	}
+}
+
+func TestSplitFrontmatter(t *testing.T) {
+	tests := []struct {
+		name            string
+		content         string
+		wantFrontmatter string
+		wantBody        string
+		wantOK          bool
+	}{
+		{"frontmatter", "---\ntitle: x\n---\nbody\n", "title: x", "body\n", true},
+		{"CRLF", "---\r\ntitle: x\r\nurl: y\r\n---\r\nbody\r\n", "title: x\r\nurl: y", "body\r\n", true},
+		{"trailing spaces", "--- \ntitle: x\n---  \nbody", "title: x", "body", true},
+		{"frontmatter only", "---\ntitle: x\n---", "title: x", "", true},
+		{"rule in body", "---\ntitle: x\n---\na\n---\nb", "title: x", "a\n---\nb", true},
+		{"none", "# Title\n", "", "# Title\n", false},
+		{"unterminated", "---\ntitle: x\n", "", "---\ntitle: x\n", false},
+		{"not a delimiter", "---\ntitle: x\n----\nbody", "", "---\ntitle: x\n----\nbody", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frontmatter, body, ok := SplitFrontmatter(tt.content)
+			if frontmatter != tt.wantFrontmatter || body != tt.wantBody || ok != tt.wantOK {
+				t.Errorf("SplitFrontmatter(%q) = %q, %q, %t, want %q, %q, %t", tt.content, frontmatter, body, ok, tt.wantFrontmatter, tt.wantBody, tt.wantOK)
+			}
+		})
+	}
+	if !OpensFrontmatter("---\r\ntitle: x\n") || OpensFrontmatter("----\n") {
+		t.Error("OpensFrontmatter() doesn't match the opening line of frontmatter only")
+	}
+}
@@ -0,0 +1,145 @@
+// Package metrics exposes the counters of long-running crawls and servers in
+// the Prometheus text exposition format.
+// This file implements the metrics of the crawl, fed by the progress
+// reporter and the progress events of a build.
+package metrics
+
+import (
+	"sync"
+
+	"github.com/f4ah6o/site2skill-go/internal/events"
+	"github.com/f4ah6o/site2skill-go/internal/progress"
+)
+
+// StageBuckets are the upper bounds of the histogram buckets of the durations
+// of pipeline stages, in seconds, from 100 milliseconds to an hour.
+var StageBuckets = []float64{0.1, 0.5, 1, 5, 10, 30, 60, 300, 600, 1800, 3600}
+
+// Error classes of site2skill_page_errors_total for failures without a
+// reason of their own.
+const (
+	// ClassHTTPClient is a page answered with a 4xx status.
+	ClassHTTPClient = "http_4xx"
+	// ClassHTTPServer is a page answered with a 5xx status.
+	ClassHTTPServer = "http_5xx"
+	// ClassNetwork is a page that got no response: refused or reset
+	// connections, timeouts, TLS errors, ...
+	ClassNetwork = "network"
+)
+
+// Crawl holds the metrics of the crawls of a process:
+//
+//	site2skill_pages_total{outcome}              pages fetched, failed, or skipped
+//	site2skill_page_errors_total{class}          failed pages by error class
+//	site2skill_downloaded_bytes_total            bytes of the response bodies downloaded
+//	site2skill_crawl_queue_depth                 links found and not crawled yet
+//	site2skill_stage_duration_seconds{stage}     durations of the pipeline stages
+//
+// Feed it the progress events of a build with Event, and the snapshots of its
+// crawl as a progress.Reporter. It is safe for concurrent use, and a nil
+// *Crawl records nothing.
+type Crawl struct {
+	pages  *Counter
+	errors *Counter
+	bytes  *Counter
+	queue  *Gauge
+	stages *Histogram
+
+	mu sync.Mutex
+	// lastBytes is the Bytes of the last snapshot, which counts from 0 for
+	// each crawl
+	lastBytes int64
+}
+
+// NewCrawl registers the metrics of the crawl in r.
+func NewCrawl(r *Registry) *Crawl {
+	return &Crawl{
+		pages:  r.Counter("site2skill_pages_total", "Pages settled by the crawler, by outcome (fetched, failed, or skipped).", "outcome"),
+		errors: r.Counter("site2skill_page_errors_total", "Pages the crawler failed to fetch or save, by error class: a failure reason such as dns_error, timeout, http_4xx, http_5xx, or network.", "class"),
+		bytes:  r.Counter("site2skill_downloaded_bytes_total", "Bytes of the response bodies downloaded by the crawler."),
+		queue:  r.Gauge("site2skill_crawl_queue_depth", "Links found by the crawler and not crawled yet."),
+		stages: r.Histogram("site2skill_stage_duration_seconds", "Durations of the pipeline stages of the builds, in seconds.", StageBuckets, "stage"),
+	}
+}
+
+// Event records a progress event: the outcome of a page, and the error class
+// of a failed one (see ErrorClass), or the duration of a stage.
+func (c *Crawl) Event(ev events.Event) {
+	if c == nil {
+		return
+	}
+	switch ev.Type {
+	case events.PageFetched:
+		c.pages.Inc("fetched")
+	case events.PageSkipped:
+		c.pages.Inc("skipped")
+	case events.PageFailed:
+		c.pages.Inc("failed")
+		c.errors.Inc(ErrorClass(ev))
+	case events.StageCompleted:
+		c.stages.Observe(float64(ev.DurationMS)/1000, ev.Stage)
+	}
+}
+
+// Report records the bytes downloaded and the queue depth of a snapshot of
+// the crawl. It implements progress.Reporter.
+func (c *Crawl) Report(s progress.Snapshot) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if s.Bytes < c.lastBytes {
+		// A new crawl started
+		c.lastBytes = 0
+	}
+	c.bytes.Add(float64(s.Bytes - c.lastBytes))
+	c.lastBytes = s.Bytes
+	queued := s.Queued
+	if s.Done {
+		queued = 0
+	}
+	c.queue.Set(float64(queued))
+}
+
+// ErrorClass returns the error class of a page_failed event: its reason if it
+// has one (e.g., "dns_error"), else ClassHTTPClient or ClassHTTPServer by its
+// status code, else ClassNetwork.
+func ErrorClass(ev events.Event) string {
+	switch {
+	case ev.Reason != "":
+		return ev.Reason
+	case ev.StatusCode >= 500:
+		return ClassHTTPServer
+	case ev.StatusCode >= 400:
+		return ClassHTTPClient
+	}
+	return ClassNetwork
+}
+
+// Reporters returns a progress.Reporter passing every snapshot to each of
+// reporters, skipping the nil ones, or nil if there are none.
+func Reporters(reporters ...progress.Reporter) progress.Reporter {
+	var all multiReporter
+	for _, r := range reporters {
+		if r != nil {
+			all = append(all, r)
+		}
+	}
+	switch len(all) {
+	case 0:
+		return nil
+	case 1:
+		return all[0]
+	}
+	return all
+}
+
+// multiReporter is the reporter returned by Reporters.
+type multiReporter []progress.Reporter
+
+func (m multiReporter) Report(s progress.Snapshot) {
+	for _, r := range m {
+		r.Report(s)
+	}
+}
@@ -0,0 +1,277 @@
+// Package metrics exposes the counters of long-running crawls and servers in
+// the Prometheus text exposition format, so that they can be scraped and
+// alerted on like any other service.
+//
+// A Registry holds metric families (counters, gauges, and histograms), each
+// with optional labels, and serves them at /metrics as an http.Handler. Crawl
+// and Server register the metrics of the crawler and of the serve and mcp
+// servers. The package implements the small part of the format it needs, so
+// that the binary doesn't depend on the Prometheus client library.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the Content-Type of the text exposition format written by
+// Registry.WriteText.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets are the upper bounds of the histogram buckets of request
+// latencies, in seconds, from 5 milliseconds to 10 seconds.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metric types, as written in the TYPE lines.
+const (
+	typeCounter   = "counter"
+	typeGauge     = "gauge"
+	typeHistogram = "histogram"
+)
+
+// Registry holds metric families and writes them in the text exposition
+// format. It is safe for concurrent use.
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+}
+
+// NewRegistry returns an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// family is a metric and its series, one per combination of label values.
+type family struct {
+	name    string
+	help    string
+	typ     string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// series is the value of a family for one combination of label values.
+type series struct {
+	labelValues []string
+	// value is the value of a counter or gauge, or the sum of the
+	// observations of a histogram
+	value float64
+	// counts are the number of observations of a histogram per bucket, not
+	// cumulated, the last one counting those above every bound
+	counts []uint64
+}
+
+// Counter is a metric that only goes up, such as the number of pages fetched.
+type Counter struct{ f *family }
+
+// Gauge is a metric that goes up and down, such as the length of a queue.
+type Gauge struct{ f *family }
+
+// Histogram counts observations, such as request latencies, in buckets.
+type Histogram struct{ f *family }
+
+// Counter registers a counter named name, with help its description and
+// labels the names of its labels.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	return &Counter{r.register(name, help, typeCounter, labels, nil)}
+}
+
+// Gauge registers a gauge named name, with help its description and labels
+// the names of its labels.
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	return &Gauge{r.register(name, help, typeGauge, labels, nil)}
+}
+
+// Histogram registers a histogram named name, with help its description,
+// buckets the increasing upper bounds of its buckets (DefaultBuckets if nil),
+// and labels the names of its labels.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	return &Histogram{r.register(name, help, typeHistogram, labels, buckets)}
+}
+
+// register adds a family to r. Registering a name twice is a programming
+// error, and panics.
+func (r *Registry) register(name, help, typ string, labels []string, buckets []float64) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, f := range r.families {
+		if f.name == name {
+			panic("metrics: " + name + " registered twice")
+		}
+	}
+	f := &family{name: name, help: help, typ: typ, labels: labels, buckets: buckets, series: make(map[string]*series)}
+	r.families = append(r.families, f)
+	return f
+}
+
+// Inc adds 1 to the counter of the label values.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v, which must not be negative, to the counter of the label values.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic("metrics: counter " + c.f.name + " decreased")
+	}
+	c.f.update(labelValues, func(s *series) { s.value += v })
+}
+
+// Set sets the gauge of the label values to v.
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.f.update(labelValues, func(s *series) { s.value = v })
+}
+
+// Add adds v, which may be negative, to the gauge of the label values.
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.f.update(labelValues, func(s *series) { s.value += v })
+}
+
+// Observe records the observation v in the histogram of the label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.f.update(labelValues, func(s *series) {
+		s.value += v
+		s.counts[sort.SearchFloat64s(h.f.buckets, v)]++
+	})
+}
+
+// update applies fn to the series of the label values, created at zero if
+// needed. Passing as many values as the family has labels is a programming
+// error, and panics otherwise.
+func (f *family) update(labelValues []string, fn func(*series)) {
+	if len(labelValues) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", f.name, len(f.labels), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	s, ok := f.series[key]
+	if !ok {
+		s = &series{labelValues: append([]string(nil), labelValues...)}
+		if f.typ == typeHistogram {
+			s.counts = make([]uint64, len(f.buckets)+1)
+		}
+		f.series[key] = s
+	}
+	fn(s)
+}
+
+// WriteText writes the metrics of r in the text exposition format: the
+// families in the order they were registered, and their series sorted by
+// label values. A family without labels is written at zero until it is first
+// updated; the others have no series until then.
+//
+// Returns the error of w, if any.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	families := append([]*family(nil), r.families...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+	return bw.Flush()
+}
+
+// write writes the HELP and TYPE lines and the series of f to w.
+func (f *family) write(w *bufio.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, escapeHelp(f.help), f.name, f.typ)
+	all := make([]*series, 0, len(f.series))
+	for _, s := range f.series {
+		all = append(all, s)
+	}
+	if len(all) == 0 && len(f.labels) == 0 {
+		all = append(all, &series{counts: make([]uint64, len(f.buckets)+1)})
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return strings.Join(all[i].labelValues, "\xff") < strings.Join(all[j].labelValues, "\xff")
+	})
+
+	for _, s := range all {
+		if f.typ != typeHistogram {
+			fmt.Fprintf(w, "%s%s %s\n", f.name, f.labelPairs(s, "", 0), formatValue(s.value))
+			continue
+		}
+		var cumulative uint64
+		for i, bound := range f.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.labelPairs(s, "le", bound), cumulative)
+		}
+		cumulative += s.counts[len(f.buckets)]
+		fmt.Fprintf(w, "%s_bucket%s %d\n", f.name, f.labelPairs(s, "le", math.Inf(1)), cumulative)
+		fmt.Fprintf(w, "%s_sum%s %s\n", f.name, f.labelPairs(s, "", 0), formatValue(s.value))
+		fmt.Fprintf(w, "%s_count%s %d\n", f.name, f.labelPairs(s, "", 0), cumulative)
+	}
+}
+
+// labelPairs returns the labels of s in braces, followed by the label extra
+// with the value bound if extra isn't empty, or "" if there are none.
+func (f *family) labelPairs(s *series, extra string, bound float64) string {
+	var pairs []string
+	for i, name := range f.labels {
+		pairs = append(pairs, name+`="`+escapeLabel(s.labelValues[i])+`"`)
+	}
+	if extra != "" {
+		pairs = append(pairs, extra+`="`+formatValue(bound)+`"`)
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// ServeHTTP writes the metrics of r in the text exposition format, so that r
+// can be mounted at /metrics.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	if req.Method == http.MethodHead {
+		return
+	}
+	r.WriteText(w)
+}
+
+// formatValue formats a sample value or bucket bound: the shortest decimal
+// representation, or +Inf, -Inf, or NaN.
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escapeHelp escapes the backslashes and newlines of a HELP line.
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// escapeLabel escapes the backslashes, double quotes, and newlines of a label
+// value.
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
@@ -0,0 +1,192 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/events"
+	"github.com/f4ah6o/site2skill-go/internal/progress"
+)
+
+func TestRegistryWriteText(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(r *Registry)
+		want   string
+	}{
+		{
+			name: "unlabeled counter at zero",
+			update: func(r *Registry) {
+				r.Counter("bytes_total", "Bytes.")
+			},
+			want: "# HELP bytes_total Bytes.\n# TYPE bytes_total counter\nbytes_total 0\n",
+		},
+		{
+			name: "labeled series sorted",
+			update: func(r *Registry) {
+				c := r.Counter("pages_total", "Pages.", "outcome")
+				c.Inc("skipped")
+				c.Add(2, "fetched")
+				r.Gauge("queue", "Queue.").Set(3.5)
+			},
+			want: "# HELP pages_total Pages.\n# TYPE pages_total counter\n" +
+				"pages_total{outcome=\"fetched\"} 2\npages_total{outcome=\"skipped\"} 1\n" +
+				"# HELP queue Queue.\n# TYPE queue gauge\nqueue 3.5\n",
+		},
+		{
+			name: "labeled family without series",
+			update: func(r *Registry) {
+				r.Counter("errors_total", "Errors.", "class")
+			},
+			want: "# HELP errors_total Errors.\n# TYPE errors_total counter\n",
+		},
+		{
+			name: "histogram",
+			update: func(r *Registry) {
+				h := r.Histogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+				h.Observe(0.05, "/a")
+				h.Observe(0.5, "/a")
+				h.Observe(2, "/a")
+			},
+			want: "# HELP latency_seconds Latency.\n# TYPE latency_seconds histogram\n" +
+				"latency_seconds_bucket{route=\"/a\",le=\"0.1\"} 1\n" +
+				"latency_seconds_bucket{route=\"/a\",le=\"1\"} 2\n" +
+				"latency_seconds_bucket{route=\"/a\",le=\"+Inf\"} 3\n" +
+				"latency_seconds_sum{route=\"/a\"} 2.55\n" +
+				"latency_seconds_count{route=\"/a\"} 3\n",
+		},
+		{
+			name: "escaping",
+			update: func(r *Registry) {
+				r.Counter("x_total", "A \\ help\nline.", "v").Inc("a\"b\\c\nd")
+			},
+			want: "# HELP x_total A \\\\ help\\nline.\n# TYPE x_total counter\n" +
+				"x_total{v=\"a\\\"b\\\\c\\nd\"} 1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			tt.update(r)
+			var b strings.Builder
+			if err := r.WriteText(&b); err != nil {
+				t.Fatalf("WriteText() error = %v", err)
+			}
+			if b.String() != tt.want {
+				t.Errorf("WriteText() =\n%s\nwant\n%s", b.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistryServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.Counter("up_total", "Up.").Inc()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != ContentType || !strings.Contains(rec.Body.String(), "up_total 1\n") {
+		t.Errorf("GET /metrics = %d %q %q", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest("POST", "/metrics", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /metrics status = %d, want 405", rec.Code)
+	}
+}
+
+func TestCrawl(t *testing.T) {
+	r := NewRegistry()
+	c := NewCrawl(r)
+	c.Event(events.Event{Type: events.PageFetched})
+	c.Event(events.Event{Type: events.PageFetched})
+	c.Event(events.Event{Type: events.PageSkipped, Reason: "robots_txt"})
+	c.Event(events.Event{Type: events.PageFailed, Reason: "dns_error"})
+	c.Event(events.Event{Type: events.PageFailed, StatusCode: 404})
+	c.Event(events.Event{Type: events.PageFailed, StatusCode: 503})
+	c.Event(events.Event{Type: events.PageFailed, Error: "connection reset"})
+	c.Event(events.Event{Type: events.StageCompleted, Stage: "fetch", DurationMS: 2500})
+
+	// The bytes of each crawl count from zero
+	reporter := Reporters(nil, c)
+	reporter.Report(progress.Snapshot{Bytes: 100, Queued: 4})
+	reporter.Report(progress.Snapshot{Bytes: 250, Queued: 2})
+	reporter.Report(progress.Snapshot{Bytes: 50, Queued: 1})
+	reporter.Report(progress.Snapshot{Bytes: 70, Queued: 1, Done: true})
+
+	var b strings.Builder
+	r.WriteText(&b)
+	for _, want := range []string{
+		`site2skill_pages_total{outcome="failed"} 4`,
+		`site2skill_pages_total{outcome="fetched"} 2`,
+		`site2skill_pages_total{outcome="skipped"} 1`,
+		`site2skill_page_errors_total{class="dns_error"} 1`,
+		`site2skill_page_errors_total{class="http_4xx"} 1`,
+		`site2skill_page_errors_total{class="http_5xx"} 1`,
+		`site2skill_page_errors_total{class="network"} 1`,
+		"site2skill_downloaded_bytes_total 320\n",
+		"site2skill_crawl_queue_depth 0\n",
+		`site2skill_stage_duration_seconds_bucket{stage="fetch",le="5"} 1`,
+		`site2skill_stage_duration_seconds_sum{stage="fetch"} 2.5`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, b.String())
+		}
+	}
+
+	// A nil *Crawl records nothing
+	var nilCrawl *Crawl
+	nilCrawl.Event(events.Event{Type: events.PageFetched})
+	nilCrawl.Report(progress.Snapshot{})
+	if Reporters(nil, nil) != nil {
+		t.Error("Reporters() of nil reporters isn't nil")
+	}
+}
+
+func TestServer(t *testing.T) {
+	r := NewRegistry()
+	m := NewServer(r)
+	handler := m.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}), "/docs", "/search")
+
+	for _, path := range []string{"/docs/guide/install.md", "/docs", "/search?q=x", "/missing"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+	m.ObserveSearch(time.Now(), nil)
+	m.ObserveSearch(time.Now(), errors.New("bad query"))
+	m.ObserveTool("get_doc", time.Now(), true)
+
+	var b strings.Builder
+	r.WriteText(&b)
+	for _, want := range []string{
+		`site2skill_http_requests_total{route="/docs",code="200"} 2`,
+		`site2skill_http_requests_total{route="/search",code="200"} 1`,
+		`site2skill_http_requests_total{route="other",code="404"} 1`,
+		`site2skill_http_request_duration_seconds_count{route="/docs"} 2`,
+		`site2skill_search_queries_total{status="error"} 1`,
+		`site2skill_search_queries_total{status="ok"} 1`,
+		"site2skill_search_duration_seconds_count 2\n",
+		`site2skill_mcp_tool_calls_total{tool="get_doc",status="ok"} 1`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, b.String())
+		}
+	}
+
+	// A nil *Server records nothing and leaves handlers as they are
+	var nilServer *Server
+	nilServer.ObserveSearch(time.Now(), nil)
+	nilServer.ObserveTool("get_doc", time.Now(), false)
+	if nilServer.Handler(handler) == nil {
+		t.Error("Handler() of a nil *Server returned nil")
+	}
+}
@@ -0,0 +1,116 @@
+// Package metrics exposes the counters of long-running crawls and servers in
+// the Prometheus text exposition format.
+// This file implements the metrics of the serve and mcp servers.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Server holds the metrics of the servers of a skill:
+//
+//	site2skill_http_requests_total{route,code}           HTTP requests answered
+//	site2skill_http_request_duration_seconds{route}      latencies of the HTTP requests
+//	site2skill_search_queries_total{status}              searches run, ok or error
+//	site2skill_search_duration_seconds                   latencies of the searches
+//	site2skill_mcp_tool_calls_total{tool,status}         MCP tool calls, ok or error
+//	site2skill_mcp_tool_duration_seconds{tool}           latencies of the MCP tool calls
+//
+// It is safe for concurrent use, and a nil *Server records nothing.
+type Server struct {
+	requests        *Counter
+	requestDuration *Histogram
+	searches        *Counter
+	searchDuration  *Histogram
+	tools           *Counter
+	toolDuration    *Histogram
+}
+
+// NewServer registers the metrics of the servers in r.
+func NewServer(r *Registry) *Server {
+	return &Server{
+		requests:        r.Counter("site2skill_http_requests_total", "HTTP requests answered, by route and status code.", "route", "code"),
+		requestDuration: r.Histogram("site2skill_http_request_duration_seconds", "Latencies of the HTTP requests, by route, in seconds.", nil, "route"),
+		searches:        r.Counter("site2skill_search_queries_total", "Searches run through the API or the search_docs tool, by status (ok or error).", "status"),
+		searchDuration:  r.Histogram("site2skill_search_duration_seconds", "Latencies of the searches, in seconds.", nil),
+		tools:           r.Counter("site2skill_mcp_tool_calls_total", "MCP tool calls, by tool and status (ok or error).", "tool", "status"),
+		toolDuration:    r.Histogram("site2skill_mcp_tool_duration_seconds", "Latencies of the MCP tool calls, by tool, in seconds.", nil, "tool"),
+	}
+}
+
+// Handler returns next recording the count and latency of its requests by
+// route, the first segment of their path (e.g., "/docs" for
+// "/docs/guide/install.md"), which keeps the number of series bounded.
+// Routes not in routes are recorded as "other". A nil *Server returns next.
+func (m *Server) Handler(next http.Handler, routes ...string) http.Handler {
+	if m == nil {
+		return next
+	}
+	known := make(map[string]bool, len(routes))
+	for _, route := range routes {
+		known[route] = true
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		route := "/" + strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)[0]
+		if !known[route] {
+			route = "other"
+		}
+		m.requests.Inc(route, strconv.Itoa(rec.status))
+		m.requestDuration.Observe(time.Since(start).Seconds(), route)
+	})
+}
+
+// ObserveSearch records a search started at start, which failed if err isn't
+// nil.
+func (m *Server) ObserveSearch(start time.Time, err error) {
+	if m == nil {
+		return
+	}
+	m.searches.Inc(status(err == nil))
+	m.searchDuration.Observe(time.Since(start).Seconds())
+}
+
+// ObserveTool records a call of the MCP tool name started at start, which
+// failed unless ok.
+func (m *Server) ObserveTool(name string, start time.Time, ok bool) {
+	if m == nil {
+		return
+	}
+	m.tools.Inc(name, status(ok))
+	m.toolDuration.Observe(time.Since(start).Seconds(), name)
+}
+
+// status returns the status label of an operation: "ok" or "error".
+func status(ok bool) string {
+	if ok {
+		return "ok"
+	}
+	return "error"
+}
+
+// statusRecorder records the status code of a response.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.status = code
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap returns the underlying ResponseWriter, for http.ResponseController.
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
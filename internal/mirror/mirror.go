@@ -0,0 +1,230 @@
+// Package mirror serves the pages of an offline mirror of a site, as written
+// by wget (wget -m) or HTTrack, so that a skill can be built from a mirror
+// instead of crawling the site again.
+//
+// Both tools write a directory per host holding the files of its URL paths,
+// e.g., docs.example.com/guide/index.html for https://docs.example.com/guide/;
+// Mirror is an http.RoundTripper answering the requests of a crawl with them,
+// without any network access.
+package mirror
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Layouts of the mirrors, as detected by Open.
+const (
+	// LayoutWget is a mirror written by wget -m (--mirror), the files of
+	// URLs with a query named path?query.
+	LayoutWget = "wget"
+	// LayoutHTTrack is a mirror written by HTTrack, recognized by its
+	// hts-cache directory.
+	LayoutHTTrack = "httrack"
+)
+
+// httrackCacheDir is the directory HTTrack keeps its cache and logs in, next
+// to the host directories.
+const httrackCacheDir = "hts-cache"
+
+// ErrNoHosts is returned by Open for a directory holding no host directory.
+var ErrNoHosts = errors.New("no host directory, as written by wget -m or HTTrack")
+
+// portSuffix matches the port of a host directory name in the spellings of
+// the tools: host:port (wget), host+port (wget --restrict-file-names=windows),
+// and host_port (HTTrack).
+var portSuffix = regexp.MustCompile(`[:+_]([0-9]+)$`)
+
+// Mirror answers the GET and HEAD requests of the URLs of a mirror with their
+// files, the URLs it has no file for with 404 Not Found, and the other
+// requests with 405 Method Not Allowed; the URLs of index.html files are
+// redirected to their directory, without any network access. It is safe
+// for concurrent use.
+type Mirror struct {
+	layout string
+	// hosts maps each mirrored host, lowercased with its port if any, to its
+	// directory
+	hosts map[string]string
+}
+
+// Open reads the host directories of the mirror in dir.
+//
+// Returns an error if dir isn't a directory, or holds no host directory.
+func Open(dir string) (*Mirror, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mirror: %w", err)
+	}
+	m := &Mirror{layout: LayoutWget, hosts: make(map[string]string)}
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if e.Name() == httrackCacheDir {
+			m.layout = LayoutHTTrack
+			continue
+		}
+		if host, ok := hostName(e.Name()); ok {
+			m.hosts[host] = filepath.Join(dir, e.Name())
+		}
+	}
+	if len(m.hosts) == 0 {
+		return nil, fmt.Errorf("invalid mirror %s: %w", dir, ErrNoHosts)
+	}
+	return m, nil
+}
+
+// hostName returns the host of the directory named name, with its port if
+// any, or false if name doesn't look like a host name.
+func hostName(name string) (string, bool) {
+	host := strings.ToLower(name)
+	if loc := portSuffix.FindStringSubmatchIndex(host); loc != nil {
+		host = host[:loc[0]] + ":" + host[loc[2]:loc[3]]
+	}
+	hostname := host
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		if _, err := strconv.Atoi(port); err != nil {
+			return "", false
+		}
+		hostname = h
+	}
+	if hostname != "localhost" && !strings.Contains(hostname, ".") {
+		return "", false
+	}
+	return host, true
+}
+
+// Layout returns the tool that wrote the mirror: LayoutWget or LayoutHTTrack.
+func (m *Mirror) Layout() string {
+	return m.layout
+}
+
+// Hosts returns the mirrored hosts, sorted.
+func (m *Mirror) Hosts() []string {
+	hosts := make([]string, 0, len(m.hosts))
+	for host := range m.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
+// StartURL returns the root URL of the only host of the mirror, or "" if it
+// has several. Since mirrors don't record the scheme of their URLs, it is
+// https.
+func (m *Mirror) StartURL() string {
+	if len(m.hosts) != 1 {
+		return ""
+	}
+	return "https://" + m.Hosts()[0] + "/"
+}
+
+// RoundTrip implements http.RoundTripper.
+func (m *Mirror) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return response(req, http.StatusMethodNotAllowed, nil, nil), nil
+	}
+	name := m.file(req.URL)
+	if name == "" {
+		return response(req, http.StatusNotFound, nil, nil), nil
+	}
+	// The links the tools rewrite to point at the files (wget -k, HTTrack)
+	// name the index.html of directories: redirect them to the directory, so
+	// that its page is crawled once and under the URL of the site
+	if path.Base(req.URL.Path) == "index.html" && req.URL.RawQuery == "" {
+		header := make(http.Header)
+		header.Set("Location", strings.TrimSuffix(req.URL.Path, "index.html"))
+		return response(req, http.StatusMovedPermanently, header, nil), nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the mirrored file of %s: %w", req.URL, err)
+	}
+	header := make(http.Header)
+	contentType := mime.TypeByExtension(filepath.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	header.Set("Content-Type", contentType)
+	if info, err := os.Stat(name); err == nil {
+		header.Set("Last-Modified", info.ModTime().UTC().Format(http.TimeFormat))
+	}
+	header.Set("Content-Length", strconv.Itoa(len(data)))
+	if req.Method == http.MethodHead {
+		resp := response(req, http.StatusOK, header, nil)
+		resp.ContentLength = int64(len(data))
+		return resp, nil
+	}
+	return response(req, http.StatusOK, header, data), nil
+}
+
+// file returns the path of the mirrored file of u, or "" if there is none.
+// The tools name the file of a directory URL index.html, and may append .html
+// to the files of pages (wget -E, HTTrack); wget names the file of a URL with
+// a query path?query, while HTTrack gives it a hashed name, which can't be
+// found.
+func (m *Mirror) file(u *url.URL) string {
+	dir, ok := m.hosts[strings.ToLower(u.Host)]
+	if !ok {
+		dir, ok = m.hosts[strings.ToLower(u.Hostname())]
+	}
+	if !ok {
+		return ""
+	}
+	// Cleaning the rooted path keeps it within the directory of the host
+	p := path.Clean("/" + u.Path)
+	var candidates []string
+	if u.RawQuery != "" {
+		candidates = append(candidates, p+"?"+u.RawQuery, p+"?"+u.RawQuery+".html")
+	} else {
+		if !strings.HasSuffix(u.Path, "/") {
+			candidates = append(candidates, p, p+".html")
+		}
+		candidates = append(candidates, path.Join(p, "index.html"), path.Join(p, "index.htm"))
+	}
+	for _, c := range candidates {
+		name := filepath.Join(dir, filepath.FromSlash(c))
+		if info, err := os.Stat(name); err == nil && info.Mode().IsRegular() {
+			return name
+		}
+	}
+	return ""
+}
+
+// response returns a response of req with status, header, and body.
+func response(req *http.Request, status int, header http.Header, body []byte) *http.Response {
+	if header == nil {
+		header = make(http.Header)
+	}
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          http.NoBody,
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+	if len(body) > 0 {
+		resp.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return resp
+}
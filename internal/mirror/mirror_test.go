@@ -0,0 +1,164 @@
+package mirror
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles writes files, keyed by their slash-separated path, under dir.
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		wantHosts []string
+		wantStart string
+		layout    string
+		wantErr   error
+	}{
+		{
+			name:      "wget",
+			files:     map[string]string{"docs.example.com/index.html": "home", "notes.txt": "not a host"},
+			wantHosts: []string{"docs.example.com"},
+			wantStart: "https://docs.example.com/",
+			layout:    LayoutWget,
+		},
+		{
+			name:      "wget with a port",
+			files:     map[string]string{"localhost:8080/index.html": "home"},
+			wantHosts: []string{"localhost:8080"},
+			wantStart: "https://localhost:8080/",
+			layout:    LayoutWget,
+		},
+		{
+			name: "httrack",
+			files: map[string]string{
+				"hts-cache/new.zip":            "",
+				"index.html":                   "project index",
+				"Docs.Example.com_8080/a.html": "a",
+				"cdn.example.com/app.css":      "css",
+			},
+			wantHosts: []string{"cdn.example.com", "docs.example.com:8080"},
+			layout:    LayoutHTTrack,
+		},
+		{
+			name:    "no host",
+			files:   map[string]string{"assets/app.css": "css"},
+			wantErr: ErrNoHosts,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			m, err := Open(dir)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Open() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			if got := m.Hosts(); !reflect.DeepEqual(got, tt.wantHosts) {
+				t.Errorf("Hosts() = %v, want %v", got, tt.wantHosts)
+			}
+			if got := m.StartURL(); got != tt.wantStart {
+				t.Errorf("StartURL() = %q, want %q", got, tt.wantStart)
+			}
+			if got := m.Layout(); got != tt.layout {
+				t.Errorf("Layout() = %q, want %q", got, tt.layout)
+			}
+		})
+	}
+
+	if _, err := Open(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Open() of a missing directory succeeded")
+	}
+}
+
+func TestMirrorRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"docs.example.com/index.html":            "<html>home</html>",
+		"docs.example.com/guide/index.html":      "<html>guide</html>",
+		"docs.example.com/install.html":          "<html>install</html>",
+		"docs.example.com/search?q=go":           "<html>results</html>",
+		"docs.example.com/assets/app.css":        "body {}",
+		"docs.example.com/LICENSE":               "plain text",
+		"docs.example.com/api/v1/reference.html": "<html>reference</html>",
+	})
+	m, err := Open(dir)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+
+	tests := []struct {
+		method       string
+		url          string
+		wantStatus   int
+		wantBody     string
+		wantType     string
+		wantLocation string
+	}{
+		{method: http.MethodGet, url: "https://docs.example.com/", wantStatus: 200, wantBody: "<html>home</html>", wantType: "text/html"},
+		{method: http.MethodGet, url: "http://DOCS.example.com/guide/", wantStatus: 200, wantBody: "<html>guide</html>", wantType: "text/html"},
+		{method: http.MethodGet, url: "https://docs.example.com/guide", wantStatus: 200, wantBody: "<html>guide</html>"},
+		{method: http.MethodGet, url: "https://docs.example.com/guide/index.html", wantStatus: 301, wantLocation: "/guide/"},
+		{method: http.MethodGet, url: "https://docs.example.com/install", wantStatus: 200, wantBody: "<html>install</html>"},
+		{method: http.MethodGet, url: "https://docs.example.com/search?q=go", wantStatus: 200, wantBody: "<html>results</html>"},
+		{method: http.MethodGet, url: "https://docs.example.com/assets/app.css", wantStatus: 200, wantBody: "body {}", wantType: "text/css"},
+		{method: http.MethodGet, url: "https://docs.example.com/LICENSE", wantStatus: 200, wantBody: "plain text", wantType: "text/plain"},
+		{method: http.MethodGet, url: "https://docs.example.com/api/v1/reference.html", wantStatus: 200, wantBody: "<html>reference</html>"},
+		{method: http.MethodHead, url: "https://docs.example.com/install.html", wantStatus: 200, wantType: "text/html"},
+		{method: http.MethodGet, url: "https://docs.example.com/missing", wantStatus: 404},
+		{method: http.MethodGet, url: "https://docs.example.com/../../etc/passwd", wantStatus: 404},
+		{method: http.MethodGet, url: "https://other.example.com/", wantStatus: 404},
+		{method: http.MethodPost, url: "https://docs.example.com/", wantStatus: 405},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.url, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := m.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip() error = %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+			if got := resp.Header.Get("Content-Type"); !strings.HasPrefix(got, tt.wantType) {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+			if got := resp.Header.Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+		})
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/f4ah6o/site2skill-go/internal/assets"
+	"github.com/f4ah6o/site2skill-go/internal/idn"
 	"gopkg.in/yaml.v3"
 )
 
@@ -32,12 +34,53 @@ func New() *Normalizer {
 type Frontmatter struct {
 	// Title is the document title extracted from the HTML <title> or <h1> tag.
 	Title string `yaml:"title"`
+	// Description is the page's meta description, when it has one.
+	Description string `yaml:"description,omitempty"`
 	// SourceURL is the original URL where the document was fetched from.
 	// Used for citation and to enable absolute link resolution.
 	SourceURL string `yaml:"source_url"`
+	// CanonicalURL is the URL the page declares as canonical, when it declares one.
+	CanonicalURL string `yaml:"canonical_url,omitempty"`
 	// FetchedAt is the ISO 8601 timestamp when the document was fetched.
 	// Format: "2006-01-02T15:04:05Z07:00"
 	FetchedAt string `yaml:"fetched_at"`
+	// ModifiedAt is the RFC 3339 time the server reported the page was last
+	// modified (its Last-Modified header), when it reported one.
+	ModifiedAt string `yaml:"modified_at,omitempty"`
+	// Author is the author named by the feed entry the page was built from.
+	Author string `yaml:"author,omitempty"`
+	// Published is the RFC 3339 time the feed entry of the page was published.
+	Published string `yaml:"published,omitempty"`
+	// HTTPStatus is the HTTP status of the response the page was saved from.
+	HTTPStatus int `yaml:"http_status,omitempty"`
+	// FinalURL is the URL of the response after redirects and locale fallback.
+	FinalURL string `yaml:"final_url,omitempty"`
+	// ContentLanguage is the Content-Language header of the response.
+	ContentLanguage string `yaml:"content_language,omitempty"`
+	// ContentHash is the SHA-256 of the fetched HTML, as "sha256:<hex>".
+	ContentHash string `yaml:"content_hash,omitempty"`
+	// Locale is the locale of the fetched page variant, or the language the page
+	// declares, when known.
+	Locale string `yaml:"locale,omitempty"`
+	// Version is the documentation version of the page (e.g., "v2" or "latest")
+	// when the crawl selected one.
+	Version string `yaml:"version,omitempty"`
+	// WordCount is the number of words in the document outside code blocks.
+	WordCount int `yaml:"word_count,omitempty"`
+	// PageType classifies the page as "docs", "marketing", or "legal".
+	PageType string `yaml:"page_type,omitempty"`
+	// Access is the access level of the page assigned by the access rules.
+	Access string `yaml:"access,omitempty"`
+	// Tags are the page's meta keywords.
+	Tags []string `yaml:"tags,omitempty"`
+	// Outline lists the H1-H3 headings of the document with their "#" markers.
+	Outline []string `yaml:"outline,omitempty"`
+	// Anchors maps the fragments of the original page's headings to the IDs of
+	// the converted headings.
+	Anchors map[string]string `yaml:"anchors,omitempty"`
+	// Aliases are the other URLs serving the same content, whose pages were
+	// removed as duplicates.
+	Aliases []string `yaml:"aliases,omitempty"`
 }
 
 // NormalizeFile normalizes a Markdown documentation file by processing its frontmatter
@@ -57,6 +100,7 @@ type Frontmatter struct {
 //	[home](/) -> [home](https://example.com/)
 //	[section](#header) -> [section](#header) (unchanged)
 //	[external](https://other.com) -> [external](https://other.com) (unchanged)
+//	[例](https://例え.jp/ドキュメント) -> [例](https://xn--r8jz45g.jp/%E3%83%89%E3%82%AD%E3%83%A5%E3%83%A1%E3%83%B3%E3%83%88)
 //
 // Parameters:
 //   - inputPath: Path to the Markdown file to normalize
@@ -131,10 +175,15 @@ func (n *Normalizer) normalizeLinks(content, sourceURL string) string {
 		text := submatches[1]
 		linkURL := submatches[2]
 
-		// Skip if already absolute
-		if strings.HasPrefix(linkURL, "http:") ||
-			strings.HasPrefix(linkURL, "https:") ||
-			strings.HasPrefix(linkURL, "mailto:") {
+		// Absolute URLs are kept, in their request form for internationalized
+		// hosts and paths
+		if strings.HasPrefix(linkURL, "http:") || strings.HasPrefix(linkURL, "https:") {
+			if ascii := idn.ToASCII(linkURL); ascii != linkURL {
+				return fmt.Sprintf("[%s](%s)", text, ascii)
+			}
+			return match
+		}
+		if strings.HasPrefix(linkURL, "mailto:") {
 			return match
 		}
 
@@ -143,6 +192,11 @@ func (n *Normalizer) normalizeLinks(content, sourceURL string) string {
 			return match
 		}
 
+		// Skip files bundled with the skill, such as tables saved as CSV
+		if strings.HasPrefix(linkURL, "../"+assets.DirName+"/") {
+			return match
+		}
+
 		// Resolve absolute URL
 		base, err := url.Parse(sourceURL)
 		if err != nil {
@@ -155,6 +209,6 @@ func (n *Normalizer) normalizeLinks(content, sourceURL string) string {
 		}
 
 		absoluteURL := base.ResolveReference(rel)
-		return fmt.Sprintf("[%s](%s)", text, absoluteURL.String())
+		return fmt.Sprintf("[%s](%s)", text, idn.ToASCII(absoluteURL.String()))
 	})
 }
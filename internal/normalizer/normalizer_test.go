@@ -1,6 +1,9 @@
 package normalizer
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -44,3 +47,60 @@ func TestNormalizeLinks(t *testing.T) {
 		})
 	}
 }
+
+func TestNormalizeLinksKeepsBundledFiles(t *testing.T) {
+	content := "[CSV](../assets/regions-table-1.csv) and [guide](guide.html)"
+	want := "[CSV](../assets/regions-table-1.csv) and [guide](https://example.com/docs/guide.html)"
+	if got := New().normalizeLinks(content, "https://example.com/docs/regions"); got != want {
+		t.Errorf("normalizeLinks() = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeLinksInternationalized(t *testing.T) {
+	content := "[はじめに](はじめに) and [例](https://例え.jp/ドキュメント)"
+	want := "[はじめに](https://xn--r8jz45g.jp/%E3%83%89/%E3%81%AF%E3%81%98%E3%82%81%E3%81%AB) and [例](https://xn--r8jz45g.jp/%E3%83%89%E3%82%AD%E3%83%A5%E3%83%A1%E3%83%B3%E3%83%88)"
+	if got := New().normalizeLinks(content, "https://xn--r8jz45g.jp/%E3%83%89/"); got != want {
+		t.Errorf("normalizeLinks() = %q, want %q", got, want)
+	}
+}
+
+func TestNormalizeFileKeepsFrontmatter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "install.md")
+	doc := `---
+title: "Install"
+description: "How to install the CLI."
+source_url: "https://example.com/docs/install"
+fetched_at: "2024-01-01T00:00:00Z"
+word_count: 3
+page_type: "docs"
+access: "internal"
+tags:
+  - "cli"
+outline:
+  - "# Install"
+---
+
+See [setup](setup.html).
+`
+	if err := os.WriteFile(path, []byte(doc), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := New().NormalizeFile(path, path); err != nil {
+		t.Fatalf("NormalizeFile() returned error: %v", err)
+	}
+	got, _ := os.ReadFile(path)
+	for _, want := range []string{
+		"description: How to install the CLI.",
+		"word_count: 3",
+		"page_type: docs",
+		"access: internal",
+		"tags:\n    - cli",
+		"outline:\n    - '# Install'",
+		"[setup](https://example.com/docs/setup.html)",
+	} {
+		if !strings.Contains(string(got), want) {
+			t.Errorf("normalized file missing %q:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,661 @@
+// Package notion reads a tree of Notion pages through the official API
+// instead of crawling notion.so, whose pages render nothing without
+// JavaScript. The blocks of each page are turned into plain HTML (see
+// Tree.HTML), and the pages keep the hierarchy of the tree in their URLs (see
+// Tree.URL).
+//
+// The API only serves the pages shared with the integration whose token
+// authenticates the requests (see Authorization).
+package notion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/slug"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// APIURL is the base URL of the Notion API.
+const APIURL = "https://api.notion.com/v1"
+
+// Version is the version of the Notion API requested, sent as the
+// Notion-Version header.
+const Version = "2022-06-28"
+
+// TokenEnv is the environment variable the command line reads the token of
+// the integration from.
+const TokenEnv = "SITE2SKILL_NOTION_TOKEN"
+
+// pageSize is the number of blocks requested at a time.
+const pageSize = 100
+
+// ErrNotPage is returned by Parse for a URL that isn't the URL of a Notion
+// page.
+var ErrNotPage = errors.New("not a Notion page URL")
+
+// idPattern matches the ID of a page at the end of its URL, with or without
+// the dashes of a UUID.
+var idPattern = regexp.MustCompile(`(?i)([0-9a-f]{8})-?([0-9a-f]{4})-?([0-9a-f]{4})-?([0-9a-f]{4})-?([0-9a-f]{12})$`)
+
+// Root is the page at the root of a tree of pages.
+type Root struct {
+	// ID is the ID of the page, as a UUID.
+	ID string
+	// URL is the URL of the page, without query or fragment, e.g.,
+	// "https://www.notion.so/acme/Engineering-Wiki-0123456789abcdef0123456789abcdef";
+	// the pages of the tree are recorded under it (see Tree.URL).
+	URL string
+	// APIURL is the base URL of the API the tree is read from; APIURL unless
+	// set otherwise.
+	APIURL string
+}
+
+// Parse returns the root page at rawURL, the URL of a Notion page on
+// notion.so or a notion.site domain, e.g.,
+// "https://www.notion.so/acme/Engineering-Wiki-0123456789abcdef0123456789abcdef"
+// or "https://acme.notion.site/0123456789abcdef0123456789abcdef".
+//
+// Returns an error wrapping ErrNotPage if rawURL isn't such a URL.
+func Parse(rawURL string) (*Root, error) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotPage, err)
+	}
+	host := strings.ToLower(u.Hostname())
+	if u.Scheme != "http" && u.Scheme != "https" ||
+		host != "notion.so" && !strings.HasSuffix(host, ".notion.so") && !strings.HasSuffix(host, ".notion.site") {
+		return nil, fmt.Errorf("%w: %s", ErrNotPage, rawURL)
+	}
+	m := idPattern.FindStringSubmatch(strings.TrimSuffix(u.Path, "/"))
+	if m == nil {
+		return nil, fmt.Errorf("%w: %s (no page ID)", ErrNotPage, rawURL)
+	}
+	u.RawQuery, u.Fragment = "", ""
+	return &Root{
+		ID:     strings.ToLower(strings.Join(m[1:], "-")),
+		URL:    strings.TrimSuffix(u.String(), "/"),
+		APIURL: APIURL,
+	}, nil
+}
+
+// Authorization returns the Authorization header sending token, the token
+// of a Notion integration.
+func Authorization(token string) string {
+	return "Bearer " + token
+}
+
+// Getter returns the body of the successful response to a GET request to
+// apiURL, a URL of the API.
+type Getter func(ctx context.Context, apiURL string) ([]byte, error)
+
+// RichText is a span of the rich text of a block.
+type RichText struct {
+	// PlainText is the text of the span.
+	PlainText string `json:"plain_text"`
+	// Href is the URL the span links to; empty if it links nowhere.
+	Href string `json:"href"`
+	// Type is "text", "mention", or "equation".
+	Type string `json:"type"`
+	// Annotations are the styles of the span.
+	Annotations struct {
+		Bold          bool `json:"bold"`
+		Italic        bool `json:"italic"`
+		Strikethrough bool `json:"strikethrough"`
+		Code          bool `json:"code"`
+	} `json:"annotations"`
+	// Mention is the mention of the span, for Type "mention".
+	Mention struct {
+		Type string `json:"type"`
+		Page struct {
+			ID string `json:"id"`
+		} `json:"page"`
+	} `json:"mention"`
+}
+
+// file is the external or uploaded file of an image, video, or file block.
+type file struct {
+	Type     string `json:"type"`
+	External struct {
+		URL string `json:"url"`
+	} `json:"external"`
+	File struct {
+		URL string `json:"url"`
+	} `json:"file"`
+}
+
+// BlockContent is the content of a block, of any type; each type sets the
+// fields it has.
+type BlockContent struct {
+	RichText        []RichText   `json:"rich_text"`
+	Caption         []RichText   `json:"caption"`
+	Language        string       `json:"language"`
+	Checked         bool         `json:"checked"`
+	Title           string       `json:"title"`
+	URL             string       `json:"url"`
+	Expression      string       `json:"expression"`
+	Cells           [][]RichText `json:"cells"`
+	HasColumnHeader bool         `json:"has_column_header"`
+	PageID          string       `json:"page_id"`
+	Icon            struct {
+		Emoji string `json:"emoji"`
+	} `json:"icon"`
+	file
+}
+
+// Block is a block of a page.
+type Block struct {
+	// ID is the ID of the block, also the ID of the page of a child_page block.
+	ID string
+	// Type is the type of the block, e.g., "paragraph" or "heading_1".
+	Type string
+	// HasChildren reports whether the block has children.
+	HasChildren bool
+	// Content is the content of the block.
+	Content BlockContent
+	// Children are the children of the block; the blocks of a child page are
+	// those of its Page instead.
+	Children []Block
+}
+
+// UnmarshalJSON decodes b, a block object of the API.
+func (b *Block) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	var head struct {
+		ID          string `json:"id"`
+		Type        string `json:"type"`
+		HasChildren bool   `json:"has_children"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return err
+	}
+	b.ID, b.Type, b.HasChildren = head.ID, head.Type, head.HasChildren
+	if content, ok := raw[head.Type]; ok {
+		if err := json.Unmarshal(content, &b.Content); err != nil {
+			return fmt.Errorf("invalid %s block %s: %w", head.Type, head.ID, err)
+		}
+	}
+	return nil
+}
+
+// Page is a page of the tree.
+type Page struct {
+	// ID is the ID of the page.
+	ID string
+	// Title is the title of the page.
+	Title string
+	// WebURL is the URL of the page on notion.so.
+	WebURL string
+	// Updated is when the page was last edited; zero if the API doesn't say.
+	Updated time.Time
+	// Parent is the ID of the parent page in the tree; empty for the root.
+	Parent string
+	// Depth is the depth of the page in the tree, 0 for the root.
+	Depth int
+	// Blocks are the blocks of the page.
+	Blocks []Block
+}
+
+// Tree is a tree of pages read by Fetch.
+type Tree struct {
+	// Root is the root page of the tree.
+	Root *Root
+	// Pages lists the pages of the tree, parents before their children.
+	Pages []Page
+
+	// urls maps the ID of each page to its URL.
+	urls map[string]string
+}
+
+// Fetch reads the page root and the child pages under it, recursively,
+// through the API with get. Child databases aren't read.
+//
+// Returns an error if a page or its blocks can't be read.
+func Fetch(ctx context.Context, root *Root, get Getter) (*Tree, error) {
+	t := &Tree{Root: root, urls: make(map[string]string)}
+	taken := make(map[string]bool)
+	var fetch func(id, parent string, depth int) error
+	fetch = func(id, parent string, depth int) error {
+		if _, seen := t.urls[id]; seen {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		p, err := fetchPage(ctx, root, get, id)
+		if err != nil {
+			return err
+		}
+		p.Parent, p.Depth = parent, depth
+
+		// The URL of a page is that of its parent followed by a slug of its title
+		u := root.URL
+		if parent != "" {
+			u = t.urls[parent] + "/" + slug.Title(p.Title, strings.ReplaceAll(id, "-", ""))
+			if taken[u] {
+				u += "-" + strings.ReplaceAll(id, "-", "")
+			}
+		}
+		taken[u] = true
+		t.urls[id] = u
+
+		if p.Blocks, err = fetchBlocks(ctx, root, get, id); err != nil {
+			return err
+		}
+		t.Pages = append(t.Pages, *p)
+		var children []string
+		walkBlocks(p.Blocks, func(b Block) {
+			if b.Type == "child_page" {
+				children = append(children, b.ID)
+			}
+		})
+		for _, child := range children {
+			if err := fetch(child, id, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := fetch(root.ID, "", 0); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// fetchPage reads the page id, without its blocks.
+func fetchPage(ctx context.Context, root *Root, get Getter, id string) (*Page, error) {
+	data, err := get(ctx, root.APIURL+"/pages/"+url.PathEscape(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read page %s: %w", id, err)
+	}
+	var resp struct {
+		ID             string `json:"id"`
+		URL            string `json:"url"`
+		LastEditedTime string `json:"last_edited_time"`
+		Properties     map[string]struct {
+			Type  string     `json:"type"`
+			Title []RichText `json:"title"`
+		} `json:"properties"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse page %s: %w", id, err)
+	}
+	p := &Page{ID: id, WebURL: resp.URL}
+	for _, prop := range resp.Properties {
+		if prop.Type == "title" {
+			p.Title = plainText(prop.Title)
+		}
+	}
+	if p.Title == "" {
+		p.Title = "Untitled"
+	}
+	if t, err := time.Parse(time.RFC3339, resp.LastEditedTime); err == nil {
+		p.Updated = t.UTC()
+	}
+	return p, nil
+}
+
+// fetchBlocks reads the children of the block or page id, and theirs,
+// recursively, except the blocks of child pages.
+func fetchBlocks(ctx context.Context, root *Root, get Getter, id string) ([]Block, error) {
+	var blocks []Block
+	cursor := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		query := url.Values{"page_size": {fmt.Sprint(pageSize)}}
+		if cursor != "" {
+			query.Set("start_cursor", cursor)
+		}
+		data, err := get(ctx, root.APIURL+"/blocks/"+url.PathEscape(id)+"/children?"+query.Encode())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the blocks of %s: %w", id, err)
+		}
+		var batch struct {
+			Results    []Block `json:"results"`
+			HasMore    bool    `json:"has_more"`
+			NextCursor string  `json:"next_cursor"`
+		}
+		if err := json.Unmarshal(data, &batch); err != nil {
+			return nil, fmt.Errorf("failed to parse the blocks of %s: %w", id, err)
+		}
+		for _, b := range batch.Results {
+			if b.HasChildren && b.Type != "child_page" && b.Type != "child_database" {
+				if b.Children, err = fetchBlocks(ctx, root, get, b.ID); err != nil {
+					return nil, err
+				}
+			}
+			blocks = append(blocks, b)
+		}
+		if !batch.HasMore || batch.NextCursor == "" {
+			return blocks, nil
+		}
+		cursor = batch.NextCursor
+	}
+}
+
+// walkBlocks calls fn for every block of blocks and of their children, in
+// order.
+func walkBlocks(blocks []Block, fn func(Block)) {
+	for _, b := range blocks {
+		fn(b)
+		walkBlocks(b.Children, fn)
+	}
+}
+
+// URL returns the URL p is recorded under: the URL of the root page for the
+// root, and otherwise the URL of its parent followed by a slug of its title
+// (e.g., ".../Engineering-Wiki-0123.../onboarding/local-setup"), so that the
+// hierarchy of the tree becomes the sections of the skill. A slug already
+// taken by a sibling is suffixed with the ID of the page.
+func (t *Tree) URL(p Page) string {
+	return t.urls[p.ID]
+}
+
+// HTML returns p as an HTML page built from its blocks: headings one level
+// below the title, which is the H1, paragraphs, lists, to-do lists as lists
+// of checkboxes, code blocks with their language, quotes, callouts as
+// admonitions, tables, images, and links to bookmarks, files, and embeds.
+// Toggles become their summary followed by their content, and columns and
+// synced blocks their content. Child pages and links to pages link to the
+// URL of the page (see URL); child databases are named but not read.
+func (t *Tree) HTML(p Page) string {
+	main := element("div", "class", "notion-page-content")
+	h1 := element("h1")
+	h1.AppendChild(text(p.Title))
+	main.AppendChild(h1)
+	t.renderBlocks(main, p.Blocks)
+
+	var body bytes.Buffer
+	html.Render(&body, main)
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>%s</title><meta name="generator" content="Notion"></head>
+<body>%s</body></html>
+`, html.EscapeString(p.Title), body.String())
+}
+
+// headings maps the heading block types to the elements they become.
+var headings = map[string]string{"heading_1": "h2", "heading_2": "h3", "heading_3": "h4"}
+
+// listTypes maps the list item block types to the list elements they are
+// grouped in.
+var listTypes = map[string]string{"bulleted_list_item": "ul", "numbered_list_item": "ol", "to_do": "ul"}
+
+// renderBlocks appends the HTML of blocks to parent, grouping consecutive
+// list items into lists.
+func (t *Tree) renderBlocks(parent *html.Node, blocks []Block) {
+	var list *html.Node
+	listType := ""
+	for _, b := range blocks {
+		tag, ok := listTypes[b.Type]
+		if !ok {
+			list, listType = nil, ""
+			for _, n := range t.renderBlock(b) {
+				parent.AppendChild(n)
+			}
+			continue
+		}
+		if list == nil || listType != b.Type {
+			list, listType = element(tag), b.Type
+			parent.AppendChild(list)
+		}
+		li := element("li")
+		if b.Type == "to_do" {
+			box := "[ ] "
+			if b.Content.Checked {
+				box = "[x] "
+			}
+			li.AppendChild(text(box))
+		}
+		t.appendRichText(li, b.Content.RichText)
+		t.renderBlocks(li, b.Children)
+		list.AppendChild(li)
+	}
+}
+
+// renderBlock returns the HTML of b, other than a list item.
+func (t *Tree) renderBlock(b Block) []*html.Node {
+	c := b.Content
+	var n *html.Node
+	switch b.Type {
+	case "paragraph":
+		if len(c.RichText) == 0 && len(b.Children) == 0 {
+			return nil
+		}
+		n = element("p")
+		t.appendRichText(n, c.RichText)
+	case "heading_1", "heading_2", "heading_3":
+		n = element(headings[b.Type])
+		t.appendRichText(n, c.RichText)
+	case "quote":
+		n = element("blockquote")
+		p := element("p")
+		t.appendRichText(p, c.RichText)
+		n.AppendChild(p)
+		t.renderBlocks(n, b.Children)
+		return []*html.Node{n}
+	case "callout":
+		kind := "note"
+		switch c.Icon.Emoji {
+		case "💡":
+			kind = "tip"
+		case "⚠️", "⚠":
+			kind = "warning"
+		case "❗", "🚨", "⛔", "🛑":
+			kind = "danger"
+		}
+		n = element("div", "class", "admonition "+kind)
+		p := element("p")
+		t.appendRichText(p, c.RichText)
+		n.AppendChild(p)
+		t.renderBlocks(n, b.Children)
+		return []*html.Node{n}
+	case "toggle":
+		summary := element("p")
+		strong := element("strong")
+		t.appendRichText(strong, c.RichText)
+		summary.AppendChild(strong)
+		nodes := []*html.Node{summary}
+		container := element("div")
+		t.renderBlocks(container, b.Children)
+		return append(nodes, detach(container)...)
+	case "code":
+		code := element("code")
+		if c.Language != "" && c.Language != "plain text" {
+			code.Attr = append(code.Attr, html.Attribute{Key: "class", Val: "language-" + strings.ReplaceAll(c.Language, " ", "-")})
+		}
+		code.AppendChild(text(plainText(c.RichText)))
+		n = element("pre")
+		n.AppendChild(code)
+		return []*html.Node{n}
+	case "equation":
+		code := element("code", "class", "language-latex")
+		code.AppendChild(text(c.Expression))
+		n = element("pre")
+		n.AppendChild(code)
+		return []*html.Node{n}
+	case "divider":
+		return []*html.Node{element("hr")}
+	case "image":
+		src := fileURL(c.file)
+		if src == "" {
+			return nil
+		}
+		return []*html.Node{element("img", "src", src, "alt", plainText(c.Caption))}
+	case "bookmark", "embed", "link_preview", "video", "pdf", "file", "audio":
+		href := c.URL
+		if href == "" {
+			href = fileURL(c.file)
+		}
+		if href == "" {
+			return nil
+		}
+		label := plainText(c.Caption)
+		if label == "" {
+			label = href
+		}
+		a := element("a", "href", href)
+		a.AppendChild(text(label))
+		n = element("p")
+		n.AppendChild(a)
+	case "child_page":
+		a := element("a", "href", t.urls[b.ID])
+		a.AppendChild(text(c.Title))
+		n = element("p")
+		n.AppendChild(a)
+		return []*html.Node{n}
+	case "child_database":
+		strong := element("strong")
+		strong.AppendChild(text(c.Title))
+		n = element("p")
+		n.AppendChild(strong)
+		return []*html.Node{n}
+	case "link_to_page":
+		href, ok := t.urls[c.PageID]
+		if !ok {
+			return nil
+		}
+		title := href
+		for _, p := range t.Pages {
+			if p.ID == c.PageID {
+				title = p.Title
+			}
+		}
+		a := element("a", "href", href)
+		a.AppendChild(text(title))
+		n = element("p")
+		n.AppendChild(a)
+		return []*html.Node{n}
+	case "table":
+		return []*html.Node{t.table(b)}
+	case "column_list", "column", "synced_block", "template":
+		container := element("div")
+		t.renderBlocks(container, b.Children)
+		return detach(container)
+	default:
+		// Tables of contents, breadcrumbs, and unsupported blocks
+		return nil
+	}
+	nodes := []*html.Node{n}
+	if len(b.Children) > 0 {
+		container := element("div")
+		t.renderBlocks(container, b.Children)
+		nodes = append(nodes, detach(container)...)
+	}
+	return nodes
+}
+
+// table returns the HTML table of the table block b, whose children are its
+// rows.
+func (t *Tree) table(b Block) *html.Node {
+	table := element("table")
+	for i, row := range b.Children {
+		tr := element("tr")
+		for _, cell := range row.Content.Cells {
+			tag := "td"
+			if i == 0 && b.Content.HasColumnHeader {
+				tag = "th"
+			}
+			td := element(tag)
+			t.appendRichText(td, cell)
+			tr.AppendChild(td)
+		}
+		table.AppendChild(tr)
+	}
+	return table
+}
+
+// appendRichText appends the HTML of spans to n.
+func (t *Tree) appendRichText(n *html.Node, spans []RichText) {
+	for _, s := range spans {
+		node := text(s.PlainText)
+		wrap := func(tag string, attrs ...string) {
+			e := element(tag, attrs...)
+			e.AppendChild(node)
+			node = e
+		}
+		if s.Annotations.Code || s.Type == "equation" {
+			wrap("code")
+		}
+		if s.Annotations.Bold {
+			wrap("strong")
+		}
+		if s.Annotations.Italic {
+			wrap("em")
+		}
+		if s.Annotations.Strikethrough {
+			wrap("del")
+		}
+		href := s.Href
+		if s.Type == "mention" && s.Mention.Type == "page" {
+			if u, ok := t.urls[s.Mention.Page.ID]; ok {
+				href = u
+			}
+		}
+		if href != "" {
+			wrap("a", "href", href)
+		}
+		n.AppendChild(node)
+	}
+}
+
+// plainText returns the text of spans.
+func plainText(spans []RichText) string {
+	var b strings.Builder
+	for _, s := range spans {
+		b.WriteString(s.PlainText)
+	}
+	return b.String()
+}
+
+// fileURL returns the URL of f.
+func fileURL(f file) string {
+	if f.Type == "file" {
+		return f.File.URL
+	}
+	return f.External.URL
+}
+
+// element returns an element named tag with the attributes attrs, given as
+// key and value pairs.
+func element(tag string, attrs ...string) *html.Node {
+	n := &html.Node{Type: html.ElementNode, Data: tag, DataAtom: atom.Lookup([]byte(tag))}
+	for i := 0; i+1 < len(attrs); i += 2 {
+		if attrs[i+1] != "" {
+			n.Attr = append(n.Attr, html.Attribute{Key: attrs[i], Val: attrs[i+1]})
+		}
+	}
+	return n
+}
+
+// text returns a text node of s.
+func text(s string) *html.Node {
+	return &html.Node{Type: html.TextNode, Data: s}
+}
+
+// detach removes the children of n and returns them.
+func detach(n *html.Node) []*html.Node {
+	var nodes []*html.Node
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		n.RemoveChild(c)
+		nodes = append(nodes, c)
+		c = next
+	}
+	return nodes
+}
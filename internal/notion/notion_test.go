@@ -0,0 +1,166 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantID  string
+		wantURL string
+		wantErr bool
+	}{
+		{
+			in:      "https://www.notion.so/acme/Engineering-Wiki-0123456789abcdef0123456789ABCDEF?pvs=4#intro",
+			wantID:  "01234567-89ab-cdef-0123-456789abcdef",
+			wantURL: "https://www.notion.so/acme/Engineering-Wiki-0123456789abcdef0123456789ABCDEF",
+		},
+		{
+			in:      "acme.notion.site/01234567-89ab-cdef-0123-456789abcdef/",
+			wantID:  "01234567-89ab-cdef-0123-456789abcdef",
+			wantURL: "https://acme.notion.site/01234567-89ab-cdef-0123-456789abcdef",
+		},
+		{in: "https://www.notion.so/acme/Engineering-Wiki", wantErr: true},
+		{in: "https://docs.example.com/0123456789abcdef0123456789abcdef", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if tt.wantErr {
+				if !errors.Is(err, ErrNotPage) {
+					t.Errorf("Parse() error = %v, want ErrNotPage", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse() returned error: %v", err)
+			}
+			if got.ID != tt.wantID || got.URL != tt.wantURL || got.APIURL != APIURL {
+				t.Errorf("Parse() = %+v, want ID %q and URL %q", *got, tt.wantID, tt.wantURL)
+			}
+		})
+	}
+}
+
+// fakeAPI serves the pages and blocks of a tree, by path, as the Notion API.
+type fakeAPI map[string]string
+
+func (api fakeAPI) get(_ context.Context, apiURL string) ([]byte, error) {
+	path, _, _ := strings.Cut(strings.TrimPrefix(apiURL, "https://api.test"), "?")
+	body, ok := api[path]
+	if !ok {
+		return nil, fmt.Errorf("%s returned status 404", apiURL)
+	}
+	return []byte(body), nil
+}
+
+// page returns the API object of a page titled title.
+func page(id, title string) string {
+	return fmt.Sprintf(`{"id":%q,"url":"https://www.notion.so/%s","last_edited_time":"2024-05-01T10:00:00.000Z",
+"properties":{"Name":{"type":"title","title":[{"plain_text":%q}]}}}`, id, strings.ReplaceAll(id, "-", ""), title)
+}
+
+// children returns the API object of a list of blocks.
+func children(blocks ...string) string {
+	return `{"results":[` + strings.Join(blocks, ",") + `],"has_more":false}`
+}
+
+// block returns the API object of a block of type typ with content.
+func block(id, typ, content string, hasChildren bool) string {
+	return fmt.Sprintf(`{"id":%q,"type":%q,"has_children":%t,%q:%s}`, id, typ, hasChildren, typ, content)
+}
+
+// richText returns the rich text content of a block of text.
+func richText(text string) string {
+	s, _ := json.Marshal(text)
+	return `{"rich_text":[{"type":"text","plain_text":` + string(s) + `}]}`
+}
+
+func TestFetch(t *testing.T) {
+	root := &Root{ID: "root", URL: "https://www.notion.so/acme/Wiki-root", APIURL: "https://api.test"}
+	api := fakeAPI{
+		"/pages/root": page("root", "Wiki"),
+		"/blocks/root/children": children(
+			block("b1", "heading_1", richText("Overview"), false),
+			block("b2", "paragraph", `{"rich_text":[
+{"type":"text","plain_text":"Read "},
+{"type":"mention","plain_text":"Local Setup","mention":{"type":"page","page":{"id":"setup"}}},
+{"type":"text","plain_text":" and ","annotations":{"bold":true}},
+{"type":"text","plain_text":"the site","href":"https://example.com/"}]}`, false),
+			block("b3", "bulleted_list_item", richText("One"), true),
+			block("b4", "bulleted_list_item", richText("Two"), false),
+			block("b5", "to_do", `{"rich_text":[{"plain_text":"Done"}],"checked":true}`, false),
+			block("b6", "code", `{"rich_text":[{"plain_text":"if a > b {}"}],"language":"go"}`, false),
+			block("b7", "callout", `{"rich_text":[{"plain_text":"Back up first."}],"icon":{"emoji":"⚠️"}}`, false),
+			block("b8", "toggle", richText("More"), true),
+			block("b9", "table", `{"table_width":2,"has_column_header":true}`, true),
+			block("b10", "image", `{"type":"external","external":{"url":"https://example.com/a.png"},"caption":[{"plain_text":"Diagram"}]}`, false),
+			block("b11", "table_of_contents", `{}`, false),
+			block("onboarding", "child_page", `{"title":"Onboarding"}`, true),
+		),
+		"/blocks/b3/children": children(block("b31", "bulleted_list_item", richText("Nested"), false)),
+		"/blocks/b8/children": children(block("b81", "paragraph", richText("Hidden text."), false)),
+		"/blocks/b9/children": children(
+			block("r1", "table_row", `{"cells":[[{"plain_text":"Key"}],[{"plain_text":"Value"}]]}`, false),
+			block("r2", "table_row", `{"cells":[[{"plain_text":"a"}],[{"plain_text":"1"}]]}`, false),
+		),
+		"/pages/onboarding":           page("onboarding", "Onboarding"),
+		"/blocks/onboarding/children": children(block("setup", "child_page", `{"title":"Local Setup"}`, true)),
+		"/pages/setup":                page("setup", "Local Setup"),
+		"/blocks/setup/children":      children(block("s1", "paragraph", richText("Install Go."), false)),
+	}
+
+	tree, err := Fetch(context.Background(), root, api.get)
+	if err != nil {
+		t.Fatalf("Fetch() returned error: %v", err)
+	}
+	want := []struct {
+		url   string
+		depth int
+	}{
+		{"https://www.notion.so/acme/Wiki-root", 0},
+		{"https://www.notion.so/acme/Wiki-root/onboarding", 1},
+		{"https://www.notion.so/acme/Wiki-root/onboarding/local-setup", 2},
+	}
+	if len(tree.Pages) != len(want) {
+		t.Fatalf("Fetch() read %d pages, want %d", len(tree.Pages), len(want))
+	}
+	for i, p := range tree.Pages {
+		if got := tree.URL(p); got != want[i].url || p.Depth != want[i].depth {
+			t.Errorf("page %d: URL, Depth = %q, %d, want %q, %d", i, got, p.Depth, want[i].url, want[i].depth)
+		}
+	}
+	if p := tree.Pages[0]; p.Title != "Wiki" || p.WebURL != "https://www.notion.so/root" || p.Updated.IsZero() {
+		t.Errorf("root = %+v", p)
+	}
+
+	got := tree.HTML(tree.Pages[0])
+	for _, want := range []string{
+		`<title>Wiki</title>`,
+		`<meta name="generator" content="Notion">`,
+		`<div class="notion-page-content"><h1>Wiki</h1><h2>Overview</h2>`,
+		`<p>Read <a href="https://www.notion.so/acme/Wiki-root/onboarding/local-setup">Local Setup</a><strong> and </strong><a href="https://example.com/">the site</a></p>`,
+		`<ul><li>One<ul><li>Nested</li></ul></li><li>Two</li></ul><ul><li>[x] Done</li></ul>`,
+		`<pre><code class="language-go">if a &gt; b {}</code></pre>`,
+		`<div class="admonition warning"><p>Back up first.</p></div>`,
+		`<p><strong>More</strong></p><p>Hidden text.</p>`,
+		`<table><tr><th>Key</th><th>Value</th></tr><tr><td>a</td><td>1</td></tr></table>`,
+		`<img src="https://example.com/a.png" alt="Diagram"/>`,
+		`<p><a href="https://www.notion.so/acme/Wiki-root/onboarding">Onboarding</a></p>`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("HTML() doesn't contain %q:\n%s", want, got)
+		}
+	}
+
+	delete(api, "/pages/setup")
+	if _, err := Fetch(context.Background(), root, api.get); err == nil || !strings.Contains(err.Error(), "failed to read page setup") {
+		t.Errorf("Fetch() with a missing page error = %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+// Package openai is a client of OpenAI-compatible APIs: the OpenAI API, or a
+// local model served by Ollama, LM Studio, llama.cpp, or vLLM (e.g.,
+// "http://localhost:11434/v1"). The embeddings, summaries, and translation
+// providers share it.
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultURL is the base URL of the OpenAI API.
+const DefaultURL = "https://api.openai.com/v1"
+
+// Client sends requests to an OpenAI-compatible API.
+type Client struct {
+	// URL is the base URL of the API, without a trailing slash.
+	URL    string
+	apiKey string
+	// HTTP sends the requests.
+	HTTP *http.Client
+}
+
+// New returns a client of the API at baseURL (DefaultURL if empty),
+// authenticating with apiKey as a bearer token unless it is empty, whose
+// requests time out after timeout.
+func New(baseURL, apiKey string, timeout time.Duration) *Client {
+	if baseURL == "" {
+		baseURL = DefaultURL
+	}
+	return &Client{
+		URL:    strings.TrimRight(baseURL, "/"),
+		apiKey: apiKey,
+		HTTP:   &http.Client{Timeout: timeout},
+	}
+}
+
+// Post sends request, encoded as JSON, to the endpoint path of the API (e.g.,
+// "/embeddings"), and decodes its JSON response into response.
+//
+// Returns an error if the request fails, the endpoint answers with an error
+// status, or its response can't be decoded.
+func (c *Client) Post(ctx context.Context, path string, request, response any) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if err := json.Unmarshal(data, response); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// Chat asks model for the answer to the user message following the system
+// message, with a temperature of 0, through the /chat/completions endpoint:
+// a POST of {"model": ..., "messages": [...]} answered by
+// {"choices": [{"message": {"content": ...}}]}.
+//
+// Returns an error if the request fails (see Post), or if its response has
+// no answer.
+func (c *Client) Chat(ctx context.Context, model, system, user string) (string, error) {
+	var parsed struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	err := c.Post(ctx, "/chat/completions", map[string]any{
+		"model": model,
+		"messages": []map[string]string{
+			{"role": "system", "content": system},
+			{"role": "user", "content": user},
+		},
+		"temperature": 0,
+	}, &parsed)
+	if err != nil {
+		return "", err
+	}
+	if len(parsed.Choices) == 0 {
+		return "", fmt.Errorf("no answer in response")
+	}
+	return parsed.Choices[0].Message.Content, nil
+}
@@ -0,0 +1,56 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestChat(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Messages) != 2 ||
+			req.Messages[0].Role != "system" || req.Messages[1].Role != "user" {
+			http.Error(w, "bad body", http.StatusBadRequest)
+			return
+		}
+		if req.Model == "silent" {
+			w.Write([]byte(`{"choices": []}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"choices": []any{map[string]any{"message": map[string]string{"content": req.Model + ": " + req.Messages[1].Content}}}})
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL+"/v1/", "secret", time.Second)
+	if c.URL != srv.URL+"/v1" {
+		t.Errorf("URL = %q, want it without the trailing slash", c.URL)
+	}
+	got, err := c.Chat(context.Background(), "test-model", "Be brief.", "Hello")
+	if err != nil || got != "test-model: Hello" {
+		t.Errorf("Chat() = %q, %v", got, err)
+	}
+	if _, err := c.Chat(context.Background(), "silent", "Be brief.", "Hello"); err == nil || !strings.Contains(err.Error(), "no answer") {
+		t.Errorf("Chat() without an answer: error = %v", err)
+	}
+	if _, err := New(srv.URL+"/v1", "wrong", time.Second).Chat(context.Background(), "test-model", "", "Hello"); err == nil || !strings.Contains(err.Error(), "HTTP 401") {
+		t.Errorf("Chat() with a wrong key: error = %v, want the status", err)
+	}
+	if New("", "", time.Second).URL != DefaultURL {
+		t.Error("New() without a URL doesn't use DefaultURL")
+	}
+}
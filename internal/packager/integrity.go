@@ -0,0 +1,151 @@
+// Package packager provides skill packaging functionality.
+// This file implements the integrity manifest stored in every .skill archive,
+// which lets the receiver of a package check it without a key.
+package packager
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+const (
+	// IntegrityFile is the path of the integrity manifest inside a .skill archive.
+	IntegrityFile = "integrity.json"
+	// IntegrityVersion is the version of the integrity manifest format written by this build.
+	IntegrityVersion = 1
+)
+
+var (
+	// ErrIntegrityMismatch is returned by VerifyIntegrity when the files of an
+	// archive don't match its integrity manifest. The wrapped message names
+	// the offending files.
+	ErrIntegrityMismatch = errors.New("integrity check failed")
+	// ErrNoIntegrity is returned by VerifyIntegrity for an archive without an
+	// integrity manifest, e.g., one packaged by an older site2skillgo.
+	ErrNoIntegrity = errors.New("archive has no integrity manifest")
+)
+
+// Integrity is the integrity manifest of a .skill archive, stored in it as
+// integrity.json: the SHA-256 of every other file of the archive, and where
+// and how the skill was made.
+//
+// Unlike a Signature, the manifest is not authenticated: it detects a
+// corrupted or edited package, not a forged one. Sign the package to prove
+// its origin; the signature covers integrity.json too.
+type Integrity struct {
+	// Version is the integrity manifest format version (see IntegrityVersion).
+	Version int `json:"version"`
+	// GeneratedAt is when the archive was made, or the time set with
+	// Packager.SetModTime.
+	GeneratedAt time.Time `json:"generated_at"`
+	// Tool is the program and version that made the archive, e.g.,
+	// "site2skillgo v1.4.0".
+	Tool string `json:"tool"`
+	// SourceURL is the URL of the site the skill was built from; empty if
+	// unknown.
+	SourceURL string `json:"source_url,omitempty"`
+	// ManifestSHA256 is the manifest digest of Files, computed like the
+	// digest of a Signature.
+	ManifestSHA256 string `json:"manifest_sha256"`
+	// Files lists the digests of the files of the archive, sorted by path,
+	// integrity.json excluded.
+	Files []ManifestEntry `json:"files"`
+}
+
+// writeIntegrity adds the integrity manifest of the files of entries to zw.
+func (p *Packager) writeIntegrity(zw *zip.Writer, entries []ManifestEntry) error {
+	generatedAt := p.modTime
+	if generatedAt.IsZero() {
+		generatedAt = time.Now()
+	}
+	integrity := Integrity{
+		Version:        IntegrityVersion,
+		GeneratedAt:    generatedAt.UTC(),
+		Tool:           "site2skillgo " + toolVersion(),
+		SourceURL:      p.sourceURL,
+		ManifestSHA256: manifestDigest(entries),
+		Files:          entries,
+	}
+	data, err := json.MarshalIndent(integrity, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode integrity manifest: %w", err)
+	}
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: IntegrityFile, Method: zip.Deflate, Modified: generatedAt.UTC()})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// VerifyIntegrity checks the archive at archivePath against its integrity
+// manifest. It fails if any file was added, removed, or modified since the
+// archive was made.
+//
+// Returns the integrity manifest on success. Errors caused by a mismatch wrap
+// ErrIntegrityMismatch, and ErrNoIntegrity if the archive has no manifest.
+func VerifyIntegrity(archivePath string) (*Integrity, error) {
+	integrity, err := readIntegrity(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	if integrity.Version != IntegrityVersion {
+		return nil, fmt.Errorf("unsupported integrity manifest version %d", integrity.Version)
+	}
+	if manifestDigest(integrity.Files) != integrity.ManifestSHA256 {
+		return nil, fmt.Errorf("%w: %s doesn't match its own digest", ErrIntegrityMismatch, IntegrityFile)
+	}
+
+	all, _, err := Manifest(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]ManifestEntry, 0, len(all))
+	for _, e := range all {
+		if e.Path != IntegrityFile {
+			entries = append(entries, e)
+		}
+	}
+	if manifestDigest(entries) != integrity.ManifestSHA256 {
+		return nil, fmt.Errorf("%w: %s", ErrIntegrityMismatch, strings.Join(diffManifest(integrity.Files, entries), ", "))
+	}
+	return integrity, nil
+}
+
+// readIntegrity reads the integrity manifest of the archive at archivePath.
+func readIntegrity(archivePath string) (*Integrity, error) {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer r.Close()
+	f, err := r.Open(IntegrityFile)
+	if err != nil {
+		return nil, ErrNoIntegrity
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", IntegrityFile, err)
+	}
+	var integrity Integrity
+	if err := json.Unmarshal(data, &integrity); err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", IntegrityFile, err)
+	}
+	return &integrity, nil
+}
+
+// toolVersion returns the module version of the running binary, or "devel"
+// if it isn't known.
+func toolVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "devel"
+}
@@ -0,0 +1,127 @@
+package packager
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyIntegrity(t *testing.T) {
+	files := map[string]string{
+		"SKILL.md":     "# My skill\n",
+		"docs/api.md":  "api docs\n",
+		"docs/auth.md": "auth docs\n",
+	}
+
+	tests := []struct {
+		name    string
+		tamper  map[string]string
+		wantErr string
+	}{
+		{name: "valid"},
+		{name: "modified file", tamper: map[string]string{"docs/api.md": "evil docs\n"}, wantErr: "modified docs/api.md"},
+		{name: "added file", tamper: map[string]string{"docs/x.md": "x"}, wantErr: "added docs/x.md"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			skillDir := filepath.Join(dir, "myskill")
+			for name, content := range files {
+				path := filepath.Join(skillDir, filepath.FromSlash(name))
+				os.MkdirAll(filepath.Dir(path), 0755)
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			p := New()
+			p.SetModTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+			p.SetSource("https://docs.example.com/")
+			archive, err := p.Package(skillDir, dir)
+			if err != nil {
+				t.Fatalf("Package() returned error: %v", err)
+			}
+			if tt.tamper != nil {
+				rewriteArchive(t, archive, tt.tamper)
+			}
+
+			integrity, err := VerifyIntegrity(archive)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("VerifyIntegrity() returned error: %v", err)
+				}
+				if len(integrity.Files) != len(files) || integrity.SourceURL != "https://docs.example.com/" ||
+					!integrity.GeneratedAt.Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)) || !strings.HasPrefix(integrity.Tool, "site2skillgo ") {
+					t.Errorf("VerifyIntegrity() = %+v", integrity)
+				}
+				return
+			}
+			if !errors.Is(err, ErrIntegrityMismatch) || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("VerifyIntegrity() error = %v, want ErrIntegrityMismatch mentioning %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestVerifyIntegrityMissing(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "old.skill")
+	out, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(out)
+	w, _ := zw.Create("SKILL.md")
+	w.Write([]byte("# Skill\n"))
+	zw.Close()
+	out.Close()
+
+	if _, err := VerifyIntegrity(archive); !errors.Is(err, ErrNoIntegrity) {
+		t.Errorf("VerifyIntegrity() error = %v, want ErrNoIntegrity", err)
+	}
+}
+
+// rewriteArchive rewrites the archive at path with the files of changes
+// replacing or added to its own, keeping its integrity.json.
+func rewriteArchive(t *testing.T, path string, changes map[string]string) {
+	t.Helper()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents := make(map[string][]byte)
+	var names []string
+	for _, f := range r.File {
+		rc, _ := f.Open()
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[f.Name] = data
+		names = append(names, f.Name)
+	}
+	r.Close()
+	for name, content := range changes {
+		if _, ok := contents[name]; !ok {
+			names = append(names, name)
+		}
+		contents[name] = []byte(content)
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(out)
+	for _, name := range names {
+		w, _ := zw.Create(name)
+		w.Write(contents[name])
+	}
+	zw.Close()
+	out.Close()
+}
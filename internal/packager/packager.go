@@ -5,19 +5,30 @@ package packager
 
 import (
 	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
 )
 
 // Packager creates .skill files (ZIP archives) from skill directories.
 // .skill files are ZIP archives containing the complete skill structure:
 // SKILL.md manifest, documentation files, and optional scripts. These archives
 // can be distributed and installed for use with Claude or Codex.
-type Packager struct{}
+type Packager struct {
+	// modTime replaces the modification times of the archived files; zero keeps them
+	modTime time.Time
+	// sourceURL is the URL of the skill's site recorded in the integrity manifest
+	sourceURL string
+}
 
 // New creates a new Packager instance.
 //
@@ -26,6 +37,19 @@ func New() *Packager {
 	return &Packager{}
 }
 
+// SetModTime records t as the modification time of every file in the
+// archives instead of the time of the file on disk, so that packaging the
+// same skill twice produces identical archives. A zero t keeps the file times.
+func (p *Packager) SetModTime(t time.Time) {
+	p.modTime = t
+}
+
+// SetSource records url as the site the packaged skills were built from in
+// their integrity manifest (see Integrity).
+func (p *Packager) SetSource(url string) {
+	p.sourceURL = url
+}
+
 // Package creates a .skill file (ZIP archive) from a skill directory.
 // The entire skill directory structure is compressed into a single .skill file,
 // which is a ZIP archive with a .skill extension for easy distribution.
@@ -39,7 +63,9 @@ func New() *Packager {
 //	      └── file2.md
 //
 // All files are compressed using DEFLATE compression. Directory entries are included
-// in the archive to preserve empty directories if present.
+// in the archive to preserve empty directories if present. The archive ends with
+// integrity.json, the SHA-256 of every file (see Integrity and VerifyIntegrity); a
+// file of that name in the skill directory is left out.
 //
 // Parameters:
 //   - skillDir: Path to the skill directory to package (e.g., ".claude/skills/myskill")
@@ -50,6 +76,7 @@ func New() *Packager {
 //   - An error if the skill directory doesn't exist, cannot be read, or the archive cannot be created
 //
 // The .skill file is named after the skill directory's basename (e.g., "myskill" -> "myskill.skill").
+// An existing .skill file with the same content as the new archive is left untouched.
 func (p *Packager) Package(skillDir, outputDir string) (string, error) {
 	// Check if skill directory exists
 	if info, err := os.Stat(skillDir); os.IsNotExist(err) || !info.IsDir() {
@@ -61,15 +88,17 @@ func (p *Packager) Package(skillDir, outputDir string) (string, error) {
 
 	log.Printf("Packaging %s to %s...", skillDir, outputFilename)
 
-	// Create zip file
-	zipFile, err := os.Create(outputFilename)
+	// Create zip file. The archive is written to a temporary file renamed into
+	// place once complete, so that a failed or interrupted build never leaves a
+	// truncated package behind
+	zipFile, err := os.CreateTemp(outputDir, "."+skillName+".skill.tmp*")
 	if err != nil {
 		return "", fmt.Errorf("failed to create zip file: %w", err)
 	}
-	defer zipFile.Close()
+	defer os.Remove(zipFile.Name())
 
 	zipWriter := zip.NewWriter(zipFile)
-	defer zipWriter.Close()
+	var entries []ManifestEntry
 
 	// Walk through skill directory and add files to zip
 	err = filepath.Walk(skillDir, func(path string, info os.FileInfo, err error) error {
@@ -83,8 +112,8 @@ func (p *Packager) Package(skillDir, outputDir string) (string, error) {
 			return err
 		}
 
-		// Skip the root directory itself
-		if relPath == "." {
+		// Skip the root directory itself, and a file taking the place of the integrity manifest
+		if relPath == "." || relPath == IntegrityFile {
 			return nil
 		}
 
@@ -96,6 +125,9 @@ func (p *Packager) Package(skillDir, outputDir string) (string, error) {
 
 		// Use forward slashes for zip paths (cross-platform compatibility)
 		header.Name = strings.ReplaceAll(relPath, string(os.PathSeparator), "/")
+		if !p.modTime.IsZero() {
+			header.Modified = p.modTime.UTC()
+		}
 
 		if info.IsDir() {
 			header.Name += "/"
@@ -115,18 +147,41 @@ func (p *Packager) Package(skillDir, outputDir string) (string, error) {
 			}
 			defer file.Close()
 
-			_, err = io.Copy(writer, file)
+			h := sha256.New()
+			_, err = io.Copy(io.MultiWriter(writer, h), file)
 			if err != nil {
 				return err
 			}
+			entries = append(entries, ManifestEntry{Path: header.Name, SHA256: hex.EncodeToString(h.Sum(nil))})
 		}
 
 		return nil
 	})
-
+	if err == nil {
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+		err = p.writeIntegrity(zipWriter, entries)
+	}
+	if err == nil {
+		err = zipWriter.Close()
+	}
+	if closeErr := zipFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(zipFile.Name(), 0644)
+	}
 	if err != nil {
 		return "", fmt.Errorf("failed to package skill: %w", err)
 	}
+	// A package identical to the previous one is left as it is, keeping its
+	// modification time
+	if data, err := os.ReadFile(zipFile.Name()); err == nil && atomicfile.Same(outputFilename, data) {
+		log.Printf("Unchanged: %s", outputFilename)
+		return outputFilename, nil
+	}
+	if err := os.Rename(zipFile.Name(), outputFilename); err != nil {
+		return "", fmt.Errorf("failed to create zip file: %w", err)
+	}
 
 	log.Printf("Successfully created: %s", outputFilename)
 	return outputFilename, nil
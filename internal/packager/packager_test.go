@@ -1,7 +1,12 @@
 package packager
 
 import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestNewPackager(t *testing.T) {
@@ -10,3 +15,112 @@ func TestNewPackager(t *testing.T) {
 		t.Error("New() should return non-nil packager")
 	}
 }
+
+func TestPackageModTime(t *testing.T) {
+	skillDir := filepath.Join(t.TempDir(), "example")
+	if err := os.MkdirAll(filepath.Join(skillDir, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"SKILL.md", "docs/intro.md"} {
+		if err := os.WriteFile(filepath.Join(skillDir, filepath.FromSlash(name)), []byte("# "+name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := New()
+	p.SetModTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	var archives [][]byte
+	for i, mtime := range []time.Time{time.Now(), time.Now().Add(-time.Hour)} {
+		if err := os.Chtimes(filepath.Join(skillDir, "SKILL.md"), mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+		outputDir := filepath.Join(t.TempDir(), fmt.Sprint(i))
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		path, err := p.Package(skillDir, outputDir)
+		if err != nil {
+			t.Fatalf("Package() returned error: %v", err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		archives = append(archives, data)
+	}
+	if !bytes.Equal(archives[0], archives[1]) {
+		t.Error("archives of the same files with a fixed modification time differ")
+	}
+}
+
+func TestPackageUnchanged(t *testing.T) {
+	skillDir := filepath.Join(t.TempDir(), "example")
+	if err := os.MkdirAll(skillDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("# Example\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outputDir := t.TempDir()
+	p := New()
+	p.SetModTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	path, err := p.Package(skillDir, outputDir)
+	if err != nil {
+		t.Fatalf("Package() returned error: %v", err)
+	}
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	// Packaging the same files again leaves the package as it is
+	if _, err := p.Package(skillDir, outputDir); err != nil {
+		t.Fatalf("Package() returned error: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("unchanged package was rewritten (stat error %v)", err)
+	}
+	if entries, _ := os.ReadDir(outputDir); len(entries) != 1 {
+		t.Errorf("output directory holds %d files, want only the package", len(entries))
+	}
+
+	if err := os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("# Example 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Package(skillDir, outputDir); err != nil {
+		t.Fatalf("Package() returned error: %v", err)
+	}
+	if info, err := os.Stat(path); err != nil || info.ModTime().Equal(old) {
+		t.Errorf("changed package wasn't rewritten (stat error %v)", err)
+	}
+}
+
+func TestPackageFailureKeepsPreviousPackage(t *testing.T) {
+	skillDir := filepath.Join(t.TempDir(), "example")
+	if err := os.MkdirAll(skillDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(skillDir, "SKILL.md"), []byte("# Example\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A dangling link can't be read, which fails the archive midway
+	if err := os.Symlink(filepath.Join(skillDir, "missing"), filepath.Join(skillDir, "zz-broken.md")); err != nil {
+		t.Skipf("symlinks unsupported: %v", err)
+	}
+	outputDir := t.TempDir()
+	previous := filepath.Join(outputDir, "example.skill")
+	if err := os.WriteFile(previous, []byte("previous package"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := New().Package(skillDir, outputDir); err == nil {
+		t.Fatal("Package() of an unreadable file succeeded, want error")
+	}
+	if data, err := os.ReadFile(previous); err != nil || string(data) != "previous package" {
+		t.Errorf("previous package = %q, %v; want it unchanged", data, err)
+	}
+	entries, _ := os.ReadDir(outputDir)
+	if len(entries) != 1 {
+		t.Errorf("output directory holds %d files, want only the previous package", len(entries))
+	}
+}
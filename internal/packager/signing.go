@@ -0,0 +1,285 @@
+// Package packager provides skill packaging functionality.
+// This file implements detached Ed25519 signatures over a .skill archive's manifest.
+package packager
+
+import (
+	"archive/zip"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SignatureVersion is the format version written to signature files.
+const SignatureVersion = 1
+
+// SignatureExt is appended to the archive path to form the default signature path.
+const SignatureExt = ".sig"
+
+// ErrSignatureMismatch is returned by VerifyArchive when the archive contents or the
+// signature do not match. The wrapped message names the offending files.
+var ErrSignatureMismatch = errors.New("signature verification failed")
+
+// ManifestEntry is the digest of one file inside a .skill archive.
+type ManifestEntry struct {
+	// Path is the slash-separated path of the file inside the archive.
+	Path string `json:"path"`
+	// SHA256 is the hex-encoded SHA-256 of the uncompressed file contents.
+	SHA256 string `json:"sha256"`
+}
+
+// Signature is a detached signature over a .skill archive, stored as JSON next to it.
+//
+// The signed message is the manifest digest: the SHA-256 of one
+// "<sha256>  <path>\n" line per archived file, sorted by path. Signing the
+// manifest rather than the ZIP bytes keeps the signature valid if the
+// archive is re-compressed without changing its contents.
+type Signature struct {
+	// Version is the signature format version (see SignatureVersion).
+	Version int `json:"version"`
+	// Algorithm is the signature algorithm; always "ed25519".
+	Algorithm string `json:"algorithm"`
+	// KeyID identifies the signing key (see KeyID).
+	KeyID string `json:"key_id"`
+	// SignedAt is when the signature was created.
+	SignedAt time.Time `json:"signed_at"`
+	// ManifestSHA256 is the hex-encoded manifest digest that was signed.
+	ManifestSHA256 string `json:"manifest_sha256"`
+	// Files lists the per-file digests making up the manifest.
+	Files []ManifestEntry `json:"files"`
+	// Signature is the base64-encoded Ed25519 signature of the manifest digest.
+	Signature string `json:"signature"`
+}
+
+// GenerateKey creates a new Ed25519 key pair for signing skills.
+func GenerateKey() (ed25519.PublicKey, ed25519.PrivateKey, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	return pub, priv, nil
+}
+
+// KeyID returns a short, stable identifier for a public key: the first 8 bytes of
+// its SHA-256, hex-encoded.
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// WriteKeyPair writes priv as a PKCS#8 "PRIVATE KEY" PEM file at privPath (mode 0600)
+// and its public key as a PKIX "PUBLIC KEY" PEM file at pubPath.
+func WriteKeyPair(privPath, pubPath string, priv ed25519.PrivateKey) error {
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return fmt.Errorf("failed to encode private key: %w", err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(priv.Public())
+	if err != nil {
+		return fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	if err := os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0600); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0644); err != nil {
+		return fmt.Errorf("failed to write public key: %w", err)
+	}
+	return nil
+}
+
+// LoadPrivateKey reads an Ed25519 private key written by WriteKeyPair.
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %w", err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 private key", path)
+	}
+	return priv, nil
+}
+
+// LoadPublicKey reads an Ed25519 public key written by WriteKeyPair.
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	block, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an Ed25519 public key", path)
+	}
+	return pub, nil
+}
+
+// readPEM reads the first PEM block of the given type from path.
+func readPEM(path, blockType string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s does not contain a %s PEM block", path, blockType)
+	}
+	return block, nil
+}
+
+// Manifest computes the per-file digests of a .skill archive, sorted by path,
+// and the manifest digest derived from them. Directory entries are ignored.
+func Manifest(archivePath string) ([]ManifestEntry, string, error) {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open archive: %w", err)
+	}
+	defer r.Close()
+
+	var entries []ManifestEntry
+	for _, f := range r.File {
+		if strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		h := sha256.New()
+		_, err = io.Copy(h, rc)
+		rc.Close()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		entries = append(entries, ManifestEntry{Path: f.Name, SHA256: hex.EncodeToString(h.Sum(nil))})
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries, manifestDigest(entries), nil
+}
+
+// manifestDigest hashes the canonical text form of entries.
+func manifestDigest(entries []ManifestEntry) string {
+	h := sha256.New()
+	for _, e := range entries {
+		fmt.Fprintf(h, "%s  %s\n", e.SHA256, e.Path)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// SignArchive signs the archive at archivePath with priv and writes the detached
+// signature to archivePath + SignatureExt.
+//
+// Returns the path of the signature file.
+func SignArchive(archivePath string, priv ed25519.PrivateKey) (string, error) {
+	entries, digest, err := Manifest(archivePath)
+	if err != nil {
+		return "", err
+	}
+
+	sig := Signature{
+		Version:        SignatureVersion,
+		Algorithm:      "ed25519",
+		KeyID:          KeyID(priv.Public().(ed25519.PublicKey)),
+		SignedAt:       time.Now().UTC(),
+		ManifestSHA256: digest,
+		Files:          entries,
+		Signature:      base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(digest))),
+	}
+
+	data, err := json.MarshalIndent(sig, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to encode signature: %w", err)
+	}
+	sigPath := archivePath + SignatureExt
+	if err := os.WriteFile(sigPath, append(data, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("failed to write signature: %w", err)
+	}
+	return sigPath, nil
+}
+
+// VerifyArchive checks the archive at archivePath against the detached signature at
+// sigPath using pub. It fails if the signature was made by a different key, if the
+// signature does not match the recorded manifest, or if any file was added, removed,
+// or modified since signing.
+//
+// Returns the decoded signature on success. Errors caused by a mismatch wrap ErrSignatureMismatch.
+func VerifyArchive(archivePath, sigPath string, pub ed25519.PublicKey) (*Signature, error) {
+	data, err := os.ReadFile(sigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signature: %w", err)
+	}
+	var sig Signature
+	if err := json.Unmarshal(data, &sig); err != nil {
+		return nil, fmt.Errorf("failed to decode signature: %w", err)
+	}
+	if sig.Version != SignatureVersion || sig.Algorithm != "ed25519" {
+		return nil, fmt.Errorf("unsupported signature version %d (%s)", sig.Version, sig.Algorithm)
+	}
+
+	if id := KeyID(pub); sig.KeyID != id {
+		return nil, fmt.Errorf("%w: signed by key %s, not %s", ErrSignatureMismatch, sig.KeyID, id)
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signature: %w", err)
+	}
+	if manifestDigest(sig.Files) != sig.ManifestSHA256 || !ed25519.Verify(pub, []byte(sig.ManifestSHA256), raw) {
+		return nil, fmt.Errorf("%w: signature does not match manifest", ErrSignatureMismatch)
+	}
+
+	entries, digest, err := Manifest(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	if digest != sig.ManifestSHA256 {
+		return nil, fmt.Errorf("%w: %s", ErrSignatureMismatch, strings.Join(diffManifest(sig.Files, entries), ", "))
+	}
+
+	return &sig, nil
+}
+
+// diffManifest describes how the archive entries differ from the signed ones.
+func diffManifest(signed, actual []ManifestEntry) []string {
+	want := make(map[string]string, len(signed))
+	for _, e := range signed {
+		want[e.Path] = e.SHA256
+	}
+
+	var diffs []string
+	for _, e := range actual {
+		sum, ok := want[e.Path]
+		switch {
+		case !ok:
+			diffs = append(diffs, "added "+e.Path)
+		case sum != e.SHA256:
+			diffs = append(diffs, "modified "+e.Path)
+		}
+		delete(want, e.Path)
+	}
+	for _, e := range signed {
+		if _, ok := want[e.Path]; ok {
+			diffs = append(diffs, "removed "+e.Path)
+		}
+	}
+	return diffs
+}
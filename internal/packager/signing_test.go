@@ -0,0 +1,162 @@
+package packager
+
+import (
+	"archive/zip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSkillArchive creates a .skill archive containing files and returns its path.
+func writeSkillArchive(t *testing.T, dir string, files map[string]string) string {
+	t.Helper()
+	skillDir := filepath.Join(dir, "myskill")
+	for name, content := range files {
+		path := filepath.Join(skillDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create fixture dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write fixture: %v", err)
+		}
+	}
+	archive, err := New().Package(skillDir, dir)
+	if err != nil {
+		t.Fatalf("Package() returned error: %v", err)
+	}
+	return archive
+}
+
+func TestSignAndVerifyArchive(t *testing.T) {
+	files := map[string]string{
+		"SKILL.md":     "# My skill\n",
+		"docs/api.md":  "api docs\n",
+		"docs/auth.md": "auth docs\n",
+	}
+
+	pub, priv, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() returned error: %v", err)
+	}
+	otherPub, _, _ := GenerateKey()
+
+	tests := []struct {
+		name    string
+		tamper  map[string]string
+		key     []byte
+		wantErr string
+	}{
+		{name: "valid", key: pub},
+		{name: "modified file", tamper: map[string]string{"SKILL.md": "# Evil\n", "docs/api.md": "api docs\n", "docs/auth.md": "auth docs\n"}, key: pub, wantErr: "modified SKILL.md"},
+		{name: "removed file", tamper: map[string]string{"SKILL.md": "# My skill\n", "docs/api.md": "api docs\n"}, key: pub, wantErr: "removed docs/auth.md"},
+		{name: "added file", tamper: map[string]string{"SKILL.md": "# My skill\n", "docs/api.md": "api docs\n", "docs/auth.md": "auth docs\n", "docs/x.md": "x"}, key: pub, wantErr: "added docs/x.md"},
+		{name: "wrong key", key: otherPub, wantErr: "signed by key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			caseDir := t.TempDir()
+			archive := writeSkillArchive(t, caseDir, files)
+			sigPath, err := SignArchive(archive, priv)
+			if err != nil {
+				t.Fatalf("SignArchive() returned error: %v", err)
+			}
+			if sigPath != archive+SignatureExt {
+				t.Errorf("signature path = %q, want %q", sigPath, archive+SignatureExt)
+			}
+
+			if tt.tamper != nil {
+				os.RemoveAll(filepath.Join(caseDir, "myskill"))
+				writeSkillArchive(t, caseDir, tt.tamper)
+			}
+
+			sig, err := VerifyArchive(archive, sigPath, tt.key)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("VerifyArchive() returned error: %v", err)
+				}
+				// The signature also covers integrity.json
+				if len(sig.Files) != len(files)+1 {
+					t.Errorf("signature covers %d files, want %d", len(sig.Files), len(files)+1)
+				}
+				return
+			}
+			if !errors.Is(err, ErrSignatureMismatch) {
+				t.Fatalf("VerifyArchive() error = %v, want ErrSignatureMismatch", err)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("VerifyArchive() error = %q, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestManifestIgnoresCompression(t *testing.T) {
+	dir := t.TempDir()
+	archive := writeSkillArchive(t, dir, map[string]string{"SKILL.md": "# Skill\n"})
+	_, want, err := Manifest(archive)
+	if err != nil {
+		t.Fatalf("Manifest() returned error: %v", err)
+	}
+
+	// Re-pack the same contents without compression
+	stored := filepath.Join(dir, "stored.skill")
+	out, err := os.Create(stored)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	zw := zip.NewWriter(out)
+	for _, f := range r.File {
+		rc, _ := f.Open()
+		w, _ := zw.CreateHeader(&zip.FileHeader{Name: f.Name, Method: zip.Store})
+		io.Copy(w, rc)
+		rc.Close()
+	}
+	r.Close()
+	zw.Close()
+	out.Close()
+
+	_, got, err := Manifest(stored)
+	if err != nil {
+		t.Fatalf("Manifest() returned error: %v", err)
+	}
+	if got != want {
+		t.Errorf("manifest digest changed with compression: %s != %s", got, want)
+	}
+}
+
+func TestKeyPairRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	pub, priv, err := GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey() returned error: %v", err)
+	}
+
+	privPath := filepath.Join(dir, "skill.key")
+	pubPath := filepath.Join(dir, "skill.pub")
+	if err := WriteKeyPair(privPath, pubPath, priv); err != nil {
+		t.Fatalf("WriteKeyPair() returned error: %v", err)
+	}
+
+	loadedPriv, err := LoadPrivateKey(privPath)
+	if err != nil {
+		t.Fatalf("LoadPrivateKey() returned error: %v", err)
+	}
+	loadedPub, err := LoadPublicKey(pubPath)
+	if err != nil {
+		t.Fatalf("LoadPublicKey() returned error: %v", err)
+	}
+	if !loadedPriv.Equal(priv) || !loadedPub.Equal(pub) {
+		t.Error("loaded keys differ from generated keys")
+	}
+	if _, err := LoadPublicKey(privPath); err == nil {
+		t.Error("LoadPublicKey() accepted a private key file")
+	}
+}
@@ -0,0 +1,72 @@
+// Package pathglob matches URL paths against glob patterns selecting sections
+// of a site, such as "/guides/**" for everything under /guides/.
+//
+// A pattern is an absolute slash-separated path whose segments are matched
+// with path.Match ("*" matches any characters of one segment), except "**",
+// which matches any number of segments, none included:
+//
+//	/guides/**           # /guides, /guides/, /guides/intro, /guides/a/b
+//	/api/*/reference     # /api/v1/reference, /api/v2/reference
+//	/blog/2024-*         # /blog/2024-01, /blog/2024-release
+package pathglob
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Validate checks that pattern is an absolute path with well-formed segments.
+func Validate(pattern string) error {
+	if !strings.HasPrefix(pattern, "/") {
+		return fmt.Errorf("invalid path pattern %q: must start with /", pattern)
+	}
+	for _, seg := range strings.Split(pattern[1:], "/") {
+		if _, err := path.Match(seg, ""); err != nil {
+			return fmt.Errorf("invalid path pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Match reports whether the URL path p matches pattern. A malformed pattern
+// matches nothing.
+func Match(pattern, p string) bool {
+	if !strings.HasPrefix(pattern, "/") || !strings.HasPrefix(p, "/") {
+		return false
+	}
+	return matchSegments(strings.Split(pattern[1:], "/"), strings.Split(p[1:], "/"))
+}
+
+// MatchAny reports whether the URL path p matches any of patterns.
+func MatchAny(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		if Match(pattern, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches the segments of a path against those of a pattern.
+func matchSegments(pattern, segs []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Try every number of segments for "**", fewest first
+			for i := 0; i <= len(segs); i++ {
+				if matchSegments(pattern[1:], segs[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segs) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], segs[0]); err != nil || !ok {
+			return false
+		}
+		pattern, segs = pattern[1:], segs[1:]
+	}
+	return len(segs) == 0
+}
@@ -0,0 +1,55 @@
+package pathglob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/guides/**", "/guides", true},
+		{"/guides/**", "/guides/", true},
+		{"/guides/**", "/guides/intro", true},
+		{"/guides/**", "/guides/a/b/index", true},
+		{"/guides/**", "/guidesx/intro", false},
+		{"/guides/**", "/api/guides/intro", false},
+		{"/**/reference", "/api/v1/reference", true},
+		{"/**/reference", "/reference", true},
+		{"/api/*/reference", "/api/v1/reference", true},
+		{"/api/*/reference", "/api/v1/beta/reference", false},
+		{"/blog/2024-*", "/blog/2024-01", true},
+		{"/blog/2024-*", "/blog/2023-12", false},
+		{"/guides/intro", "/guides/intro", true},
+		{"guides/**", "/guides/intro", false},
+		{"/guides/[", "/guides/[", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			if got := Match(tt.pattern, tt.path); got != tt.want {
+				t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		pattern string
+		wantErr bool
+	}{
+		{"/guides/**", false},
+		{"/api/*/v[12]", false},
+		{"guides/**", true},
+		{"/guides/[", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			if err := Validate(tt.pattern); (err != nil) != tt.wantErr {
+				t.Errorf("Validate(%q) error = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,581 @@
+// Package pdf extracts the text of PDF documents.
+// This file implements the fonts and the interpreter of content streams,
+// which places the text drawn on a page and gathers it into lines.
+package pdf
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// font is what the text extraction needs of a font: how to split strings
+// into codes, the text of each code, and the width of each glyph.
+type font struct {
+	// twoByte is set for composite (Type0) fonts, whose codes are two bytes.
+	twoByte bool
+	// toUnicode is the text of codes, from the ToUnicode CMap.
+	toUnicode map[uint32]string
+	// encoding is the text of codes of a simple font, from the Differences
+	// of its encoding.
+	encoding map[uint32]string
+	// widths are the glyph widths by code; defaultWidth is the width of the
+	// others. Both are in thousandths of the font size.
+	widths       map[uint32]float64
+	defaultWidth float64
+	bold, mono   bool
+}
+
+// glyph is a glyph of a string drawn with a font.
+type glyph struct {
+	text  string
+	width float64
+	// space is set for the single-byte code 32, which word spacing applies to.
+	space bool
+}
+
+// decode returns the glyphs of the string s.
+func (ft *font) decode(s string) []glyph {
+	var glyphs []glyph
+	step := 1
+	if ft.twoByte {
+		step = 2
+	}
+	for i := 0; i+step <= len(s); i += step {
+		code := uint32(s[i])
+		if step == 2 {
+			code = code<<8 | uint32(s[i+1])
+		}
+		g := glyph{width: ft.defaultWidth, space: step == 1 && code == 32}
+		if w, ok := ft.widths[code]; ok {
+			g.width = w
+		}
+		if text, ok := ft.toUnicode[code]; ok {
+			g.text = text
+		} else if text, ok := ft.encoding[code]; ok {
+			g.text = text
+		} else if step == 1 {
+			g.text = string(winAnsi(byte(code)))
+		}
+		glyphs = append(glyphs, g)
+	}
+	return glyphs
+}
+
+// winAnsiHigh is the characters of WinAnsiEncoding from 0x80 to 0x9f, where
+// it differs from Latin-1.
+var winAnsiHigh = [32]rune{
+	'€', 0, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0, 'Ž', 0,
+	0, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0, 'ž', 'Ÿ',
+}
+
+// winAnsi returns the character of code c in WinAnsiEncoding, the encoding
+// assumed for simple fonts.
+func winAnsi(c byte) rune {
+	switch {
+	case c >= 0x80 && c <= 0x9f:
+		if r := winAnsiHigh[c-0x80]; r != 0 {
+			return r
+		}
+		return unicode.ReplacementChar
+	case c < 32 && c != '\t' && c != '\n' && c != '\r':
+		return ' '
+	}
+	return rune(c)
+}
+
+// glyphNames is the text of the glyph names that may appear in the
+// Differences of an encoding, other than single letters and digits and the
+// uniXXXX names.
+var glyphNames = map[string]string{
+	"space": " ", "exclam": "!", "quotedbl": "\"", "numbersign": "#", "dollar": "$",
+	"percent": "%", "ampersand": "&", "quotesingle": "'", "quoteright": "’",
+	"quoteleft": "‘", "parenleft": "(", "parenright": ")", "asterisk": "*",
+	"plus": "+", "comma": ",", "hyphen": "-", "minus": "−", "period": ".",
+	"slash": "/", "colon": ":", "semicolon": ";", "less": "<", "equal": "=",
+	"greater": ">", "question": "?", "at": "@", "bracketleft": "[",
+	"backslash": "\\", "bracketright": "]", "asciicircum": "^",
+	"underscore": "_", "grave": "`", "braceleft": "{", "bar": "|",
+	"braceright": "}", "asciitilde": "~", "bullet": "•", "endash": "–",
+	"emdash": "—", "quotedblleft": "“", "quotedblright": "”", "ellipsis": "…",
+	"fi": "fi", "fl": "fl", "ff": "ff", "ffi": "ffi", "ffl": "ffl",
+	"copyright": "©", "registered": "®", "trademark": "™", "degree": "°",
+	"section": "§", "paragraph": "¶", "dagger": "†", "daggerdbl": "‡",
+	"zero": "0", "one": "1", "two": "2", "three": "3", "four": "4",
+	"five": "5", "six": "6", "seven": "7", "eight": "8", "nine": "9",
+}
+
+// glyphText returns the text of the glyph name n, or "" if it is unknown.
+func glyphText(n string) string {
+	if text, ok := glyphNames[n]; ok {
+		return text
+	}
+	if utf8.RuneCountInString(n) == 1 {
+		return n
+	}
+	if hex, ok := strings.CutPrefix(n, "uni"); ok && len(hex) == 4 {
+		if v, err := strconv.ParseUint(hex, 16, 32); err == nil {
+			return string(rune(v))
+		}
+	}
+	return ""
+}
+
+// loadFont returns the font of the font dictionary d.
+func (f *file) loadFont(d dict) *font {
+	ft := &font{defaultWidth: 500}
+	base := strings.ToLower(string(f.name(d["BaseFont"])))
+	descriptor := f.dict(d["FontDescriptor"])
+
+	if f.name(d["Subtype"]) == "Type0" {
+		ft.twoByte = true
+		ft.defaultWidth = 1000
+		if descendants := f.array(d["DescendantFonts"]); len(descendants) > 0 {
+			cid := f.dict(descendants[0])
+			descriptor = f.dict(cid["FontDescriptor"])
+			if dw, ok := f.resolve(cid["DW"]).(int); ok {
+				ft.defaultWidth = float64(dw)
+			}
+			ft.widths = f.cidWidths(f.array(cid["W"]))
+		}
+	} else {
+		ft.widths = make(map[uint32]float64)
+		first := f.int(d["FirstChar"])
+		scale := 1.0
+		if m := f.array(d["FontMatrix"]); len(m) > 0 {
+			// Type3 glyph widths are in glyph space
+			scale = f.number(m[0]) * 1000
+		}
+		for i, w := range f.array(d["Widths"]) {
+			ft.widths[uint32(first+i)] = f.number(w) * scale
+		}
+		if descriptor != nil {
+			if w := f.number(descriptor["MissingWidth"]); w > 0 {
+				ft.defaultWidth = w
+			}
+		}
+		if enc := f.dict(d["Encoding"]); enc != nil {
+			ft.encoding = f.differences(f.array(enc["Differences"]))
+		}
+	}
+	if s, ok := f.resolve(d["ToUnicode"]).(*stream); ok {
+		if data, err := f.decode(s); err == nil {
+			ft.toUnicode = parseCMap(data)
+		}
+	}
+
+	// Few fonts set the FixedPitch and ForceBold flags: the style is mostly
+	// told by the name, without its subset tag ("ABCDEF+")
+	flags := f.int(descriptor["Flags"])
+	if _, after, ok := strings.Cut(base, "+"); ok {
+		base = after
+	}
+	ft.mono = flags&1 != 0 || containsAny(base, monoNames) || strings.HasPrefix(base, "cmtt") || strings.HasPrefix(base, "cmsltt")
+	ft.bold = flags&(1<<18) != 0 || f.number(descriptor["FontWeight"]) >= 600 || containsAny(base, boldNames) || strings.HasPrefix(base, "cmb")
+	return ft
+}
+
+// monoNames and boldNames are the parts of the names of monospaced and bold
+// fonts.
+var (
+	monoNames = []string{"courier", "mono", "nimbusmon", "consol", "menlo", "monaco", "typewriter", "code"}
+	boldNames = []string{"bold", "black", "heavy", "semibold", "demi", "nimbusromno9l-medi", "nimbussanl-medi"}
+)
+
+// containsAny reports whether s contains any of parts.
+func containsAny(s string, parts []string) bool {
+	for _, part := range parts {
+		if strings.Contains(s, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// cidWidths returns the widths of the W array of a CIDFont, whose elements
+// are "c [w1 w2 ...]" or "cfirst clast w".
+func (f *file) cidWidths(w array) map[uint32]float64 {
+	widths := make(map[uint32]float64)
+	for i := 0; i+1 < len(w); {
+		first := f.int(w[i])
+		if list := f.array(w[i+1]); list != nil {
+			for j, v := range list {
+				widths[uint32(first+j)] = f.number(v)
+			}
+			i += 2
+			continue
+		}
+		if i+2 >= len(w) {
+			break
+		}
+		last, width := f.int(w[i+1]), f.number(w[i+2])
+		for c := first; c <= last && c-first < 65536; c++ {
+			widths[uint32(c)] = width
+		}
+		i += 3
+	}
+	return widths
+}
+
+// differences returns the text of the codes of the Differences array of an
+// encoding: a code followed by the glyph names of it and the next codes.
+func (f *file) differences(diffs array) map[uint32]string {
+	encoding := make(map[uint32]string)
+	code := 0
+	for _, v := range diffs {
+		switch v := f.resolve(v).(type) {
+		case int:
+			code = v
+		case name:
+			if text := glyphText(string(v)); text != "" {
+				encoding[uint32(code)] = text
+			}
+			code++
+		}
+	}
+	return encoding
+}
+
+// parseCMap returns the text of the codes of the ToUnicode CMap data, from
+// its bfchar and bfrange mappings.
+func parseCMap(data []byte) map[uint32]string {
+	m := make(map[uint32]string)
+	l := &lexer{data: data}
+	var operands []any
+	for {
+		obj, err := l.object()
+		if err != nil {
+			return m
+		}
+		kw, ok := obj.(keyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+		switch kw {
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(string)
+				dst, ok2 := operands[i+1].(string)
+				if ok1 && ok2 {
+					m[codeOf(src)] = utf16BE(dst)
+				}
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(string)
+				hi, ok2 := operands[i+1].(string)
+				if !ok1 || !ok2 {
+					continue
+				}
+				first, last := codeOf(lo), codeOf(hi)
+				if last < first || last-first > 65535 {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case string:
+					// The last unit of the destination is incremented
+					units := []rune(utf16BE(dst))
+					for c := first; c <= last && len(units) > 0; c++ {
+						m[c] = string(units)
+						units[len(units)-1]++
+					}
+				case array:
+					for j, v := range dst {
+						if s, ok := v.(string); ok && first+uint32(j) <= last {
+							m[first+uint32(j)] = utf16BE(s)
+						}
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// codeOf returns the code of the bytes of a CMap source string.
+func codeOf(s string) uint32 {
+	var code uint32
+	for i := 0; i < len(s); i++ {
+		code = code<<8 | uint32(s[i])
+	}
+	return code
+}
+
+// matrix is a transformation matrix [a b c d e f].
+type matrix [6]float64
+
+// identity is the identity matrix.
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// multiply returns m × n.
+func (m matrix) multiply(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// translate returns the translation matrix by (x, y).
+func translate(x, y float64) matrix {
+	return matrix{1, 0, 0, 1, x, y}
+}
+
+// run is the text of a string drawn on a page: its text, the position of its
+// start and end, and its font size, in page space.
+type run struct {
+	text             string
+	x, y, endX, size float64
+	bold, mono       bool
+}
+
+// graphicsState is the part of the graphics state the text extraction
+// tracks.
+type graphicsState struct {
+	ctm                                              matrix
+	font                                             *font
+	size, charSpace, wordSpace, scale, leading, rise float64
+}
+
+// interpreter runs content streams and collects the runs they draw.
+type interpreter struct {
+	f    *file
+	runs []run
+}
+
+// pageLines returns the lines of text of page p.
+func (f *file) pageLines(p pageObject) []Line {
+	in := &interpreter{f: f}
+	in.run(f.contents(p), p.resources, identity, 0)
+	return lines(in.runs)
+}
+
+// run interprets the content stream data with resources and initial
+// transformation ctm. Form XObjects are run recursively, up to a depth.
+func (in *interpreter) run(data []byte, resources dict, ctm matrix, depth int) {
+	f := in.f
+	fontCache := make(map[string]*font)
+	gs := graphicsState{ctm: ctm, scale: 1}
+	var stack []graphicsState
+	tm, tlm := identity, identity
+	var operands []any
+
+	number := func(i int) float64 {
+		if i < len(operands) {
+			return f.number(operands[i])
+		}
+		return 0
+	}
+	nextLine := func(tx, ty float64) {
+		tlm = translate(tx, ty).multiply(tlm)
+		tm = tlm
+	}
+	show := func(s string) {
+		if gs.font == nil {
+			return
+		}
+		r := run{bold: gs.font.bold, mono: gs.font.mono}
+		var text strings.Builder
+		placed := false
+		for i, g := range gs.font.decode(s) {
+			trm := matrix{gs.size * gs.scale, 0, 0, gs.size, 0, gs.rise}.multiply(tm).multiply(gs.ctm)
+			// The run starts at its first visible glyph, which keeps the
+			// indentation of code drawn with leading spaces
+			if blank := strings.TrimSpace(g.text) == ""; i == 0 || (!placed && !blank) {
+				r.x, r.y = trm[4], trm[5]
+				r.size = math.Hypot(trm[2], trm[3])
+				placed = !blank
+			}
+			text.WriteString(g.text)
+			tx := g.width / 1000 * gs.size
+			tx += gs.charSpace
+			if g.space {
+				tx += gs.wordSpace
+			}
+			tm = translate(tx*gs.scale, 0).multiply(tm)
+		}
+		end := matrix{1, 0, 0, 1, 0, gs.rise}.multiply(tm).multiply(gs.ctm)
+		r.endX = end[4]
+		r.text = text.String()
+		if r.text != "" {
+			in.runs = append(in.runs, r)
+		}
+	}
+
+	l := &lexer{data: data}
+	for {
+		obj, err := l.object()
+		if err != nil {
+			return
+		}
+		op, ok := obj.(keyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+		switch op {
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if len(stack) > 0 {
+				gs = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if len(operands) == 6 {
+				gs.ctm = matrix{number(0), number(1), number(2), number(3), number(4), number(5)}.multiply(gs.ctm)
+			}
+		case "BT":
+			tm, tlm = identity, identity
+		case "Tf":
+			if len(operands) == 2 {
+				key := string(f.name(operands[0]))
+				ft, ok := fontCache[key]
+				if !ok {
+					if d := f.dict(f.dict(resources["Font"])[key]); d != nil {
+						ft = f.loadFont(d)
+					}
+					fontCache[key] = ft
+				}
+				gs.font, gs.size = ft, number(1)
+			}
+		case "Tc":
+			gs.charSpace = number(0)
+		case "Tw":
+			gs.wordSpace = number(0)
+		case "Tz":
+			gs.scale = number(0) / 100
+		case "TL":
+			gs.leading = number(0)
+		case "Ts":
+			gs.rise = number(0)
+		case "Td":
+			nextLine(number(0), number(1))
+		case "TD":
+			gs.leading = -number(1)
+			nextLine(number(0), number(1))
+		case "Tm":
+			if len(operands) == 6 {
+				tlm = matrix{number(0), number(1), number(2), number(3), number(4), number(5)}
+				tm = tlm
+			}
+		case "T*":
+			nextLine(0, -gs.leading)
+		case "Tj":
+			if len(operands) == 1 {
+				s, _ := operands[0].(string)
+				show(s)
+			}
+		case "'":
+			nextLine(0, -gs.leading)
+			if len(operands) == 1 {
+				s, _ := operands[0].(string)
+				show(s)
+			}
+		case "\"":
+			if len(operands) == 3 {
+				gs.wordSpace, gs.charSpace = number(0), number(1)
+				nextLine(0, -gs.leading)
+				s, _ := operands[2].(string)
+				show(s)
+			}
+		case "TJ":
+			if len(operands) == 1 {
+				a, _ := operands[0].(array)
+				for _, v := range a {
+					if s, ok := v.(string); ok {
+						show(s)
+					} else {
+						tx := -f.number(v) / 1000 * gs.size * gs.scale
+						tm = translate(tx, 0).multiply(tm)
+					}
+				}
+			}
+		case "Do":
+			if len(operands) == 1 && depth < 8 {
+				xobject, ok := f.resolve(f.dict(resources["XObject"])[string(f.name(operands[0]))]).(*stream)
+				if ok && f.name(xobject.dict["Subtype"]) == "Form" {
+					if content, err := f.decode(xobject); err == nil {
+						m := identity
+						if a := f.array(xobject.dict["Matrix"]); len(a) == 6 {
+							m = matrix{f.number(a[0]), f.number(a[1]), f.number(a[2]), f.number(a[3]), f.number(a[4]), f.number(a[5])}
+						}
+						res := f.dict(xobject.dict["Resources"])
+						if res == nil {
+							res = resources
+						}
+						in.run(content, res, m.multiply(gs.ctm), depth+1)
+					}
+				}
+			}
+		case "ID":
+			l.skipInlineImage()
+		}
+		operands = operands[:0]
+	}
+}
+
+// lines gathers runs into lines: a run continues the line before it when it
+// is on the same baseline, at or after its end. A gap wider than a fifth of
+// the font size between them is a space between words. A line has the font
+// size of most of its characters, not that of its bullets or footnote
+// markers.
+func lines(runs []run) []Line {
+	var out []Line
+	var cur *run
+	var bold, mono bool
+	chars := make(map[float64]int)
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		if text := strings.Join(strings.Fields(cur.text), " "); text != "" {
+			size := 0.0
+			for s, n := range chars {
+				if n > chars[size] || (n == chars[size] && s > size) {
+					size = s
+				}
+			}
+			out = append(out, Line{Text: text, Size: size, X: cur.x, Y: cur.y, Bold: bold, Mono: mono})
+		}
+		cur = nil
+		clear(chars)
+	}
+	for i := range runs {
+		r := runs[i]
+		if cur != nil {
+			size := math.Max(cur.size, r.size)
+			if math.Abs(r.y-cur.y) <= size*0.5 && r.x >= cur.endX-size*0.5 {
+				if r.x-cur.endX > size*0.2 && !strings.HasSuffix(cur.text, " ") && !strings.HasPrefix(r.text, " ") {
+					cur.text += " "
+				}
+				cur.text += r.text
+				cur.endX = r.endX
+				chars[round(r.size)] += len(strings.TrimSpace(r.text))
+				bold = bold && (r.bold || strings.TrimSpace(r.text) == "")
+				mono = mono && (r.mono || strings.TrimSpace(r.text) == "")
+				continue
+			}
+			flush()
+		}
+		if strings.TrimSpace(r.text) == "" {
+			continue
+		}
+		cur = &r
+		chars[round(r.size)] += len(strings.TrimSpace(r.text))
+		bold, mono = r.bold, r.mono
+	}
+	flush()
+	return out
+}
+
+// round rounds a font size to a tenth of a point.
+func round(size float64) float64 {
+	return math.Round(size*10) / 10
+}
@@ -0,0 +1,351 @@
+// Package pdf extracts the text of PDF documents.
+// This file implements the tokenizer of PDF files and content streams.
+package pdf
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// The objects of a PDF file. Strings are Go strings holding the bytes of the
+// PDF string; numbers are int or float64; booleans are bool; null is nil.
+type (
+	// name is a PDF name, without its slash.
+	name string
+	// dict is a PDF dictionary, by key without its slash.
+	dict map[string]any
+	// array is a PDF array.
+	array []any
+	// ref is an indirect reference, "12 0 R".
+	ref struct{ num, gen int }
+	// keyword is a bare word: an operator of a content stream, a keyword of
+	// the file structure (obj, stream, R), or a delimiter ("<<", "]").
+	keyword string
+	// stream is a PDF stream: its dictionary and its encoded data.
+	stream struct {
+		dict dict
+		raw  []byte
+	}
+)
+
+// errSyntax reports a malformed object.
+var errSyntax = errors.New("malformed PDF object")
+
+// lexer reads the tokens and objects of PDF data from pos.
+type lexer struct {
+	data []byte
+	pos  int
+}
+
+// isSpace reports whether c is PDF white space.
+func isSpace(c byte) bool {
+	return c == 0 || c == '\t' || c == '\n' || c == '\f' || c == '\r' || c == ' '
+}
+
+// isDelimiter reports whether c is a PDF delimiter.
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return false
+}
+
+// skipSpace skips white space and comments.
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// token returns the next token: a number, name, string, or keyword. Returns
+// io.EOF at the end of the data.
+func (l *lexer) token() (any, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, io.EOF
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '(':
+		l.pos++
+		return l.literal(), nil
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return keyword("<<"), nil
+		}
+		l.pos++
+		return l.hex(), nil
+	case c == '>':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return keyword(">>"), nil
+		}
+		l.pos++
+		return nil, errSyntax
+	case c == '[' || c == ']' || c == '{' || c == '}':
+		l.pos++
+		return keyword(c), nil
+	case c == ')':
+		l.pos++
+		return nil, errSyntax
+	case c == '/':
+		l.pos++
+		return l.name(), nil
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isSpace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if isNumber(word) {
+		if n, err := strconv.Atoi(word); err == nil {
+			return n, nil
+		}
+		if f, err := strconv.ParseFloat(word, 64); err == nil {
+			return f, nil
+		}
+	}
+	return keyword(word), nil
+}
+
+// isNumber reports whether word has the syntax of a PDF number.
+func isNumber(word string) bool {
+	digits := false
+	for i := 0; i < len(word); i++ {
+		switch c := word[i]; {
+		case c >= '0' && c <= '9':
+			digits = true
+		case c == '.' || ((c == '+' || c == '-') && i == 0):
+		default:
+			return false
+		}
+	}
+	return digits
+}
+
+// literal reads a literal string, after its opening parenthesis.
+func (l *lexer) literal() string {
+	var b []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return string(b)
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return string(b)
+			}
+			c = l.data[l.pos]
+			l.pos++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// A backslash at the end of a line continues the string
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				n := int(c - '0')
+				for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+					n = n*8 + int(l.data[l.pos]-'0')
+					l.pos++
+				}
+				c = byte(n)
+			}
+		}
+		b = append(b, c)
+	}
+	return string(b)
+}
+
+// hex reads a hexadecimal string, after its opening angle bracket.
+func (l *lexer) hex() string {
+	var b []byte
+	var digit, n int
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		if c == '>' {
+			break
+		}
+		v, ok := hexValue(c)
+		if !ok {
+			continue
+		}
+		digit = digit<<4 | v
+		if n++; n == 2 {
+			b = append(b, byte(digit))
+			digit, n = 0, 0
+		}
+	}
+	if n == 1 {
+		b = append(b, byte(digit<<4))
+	}
+	return string(b)
+}
+
+// hexValue returns the value of the hexadecimal digit c.
+func hexValue(c byte) (int, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), true
+	case c >= 'a' && c <= 'f':
+		return int(c-'a') + 10, true
+	case c >= 'A' && c <= 'F':
+		return int(c-'A') + 10, true
+	}
+	return 0, false
+}
+
+// name reads a name, after its slash.
+func (l *lexer) name() name {
+	var b []byte
+	for l.pos < len(l.data) && !isSpace(l.data[l.pos]) && !isDelimiter(l.data[l.pos]) {
+		c := l.data[l.pos]
+		l.pos++
+		if c == '#' && l.pos+1 < len(l.data) {
+			hi, ok1 := hexValue(l.data[l.pos])
+			lo, ok2 := hexValue(l.data[l.pos+1])
+			if ok1 && ok2 {
+				c = byte(hi<<4 | lo)
+				l.pos += 2
+			}
+		}
+		b = append(b, c)
+	}
+	return name(b)
+}
+
+// object returns the next object: a token, or a whole dictionary, array, or
+// indirect reference. Keywords other than true, false, and null are
+// returned as they are, including the closing delimiters.
+func (l *lexer) object() (any, error) {
+	tok, err := l.token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case keyword:
+		switch t {
+		case "<<":
+			d := make(dict)
+			for {
+				key, err := l.token()
+				if err != nil {
+					return nil, err
+				}
+				if key == keyword(">>") {
+					return d, nil
+				}
+				k, ok := key.(name)
+				if !ok {
+					return nil, errSyntax
+				}
+				value, err := l.object()
+				if err != nil {
+					return nil, err
+				}
+				if value == keyword(">>") {
+					// A key without a value
+					return d, nil
+				}
+				d[string(k)] = value
+			}
+		case "[":
+			a := array{}
+			for {
+				value, err := l.object()
+				if err != nil {
+					return nil, err
+				}
+				if value == keyword("]") {
+					return a, nil
+				}
+				a = append(a, value)
+			}
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		return t, nil
+	case int:
+		// "12 0 R" is a reference
+		save := l.pos
+		if gen, err := l.token(); err == nil {
+			if g, ok := gen.(int); ok {
+				if r, err := l.token(); err == nil && r == keyword("R") {
+					return ref{t, g}, nil
+				}
+			}
+		}
+		l.pos = save
+		return t, nil
+	}
+	return tok, nil
+}
+
+// skipInlineImage skips the data of an inline image, after its ID operator,
+// up to and including its EI operator.
+func (l *lexer) skipInlineImage() {
+	for i := l.pos + 1; i+1 < len(l.data); i++ {
+		if l.data[i] == 'E' && l.data[i+1] == 'I' && isSpace(l.data[i-1]) && (i+2 == len(l.data) || isSpace(l.data[i+2])) {
+			l.pos = i + 2
+			return
+		}
+	}
+	l.pos = len(l.data)
+}
+
+// indexKeyword returns the index of the keyword kw in data from start, not
+// part of a longer word, or -1.
+func indexKeyword(data []byte, kw string, start int) int {
+	for start < len(data) {
+		i := bytes.Index(data[start:], []byte(kw))
+		if i < 0 {
+			return -1
+		}
+		i += start
+		end := i + len(kw)
+		if (i == 0 || isSpace(data[i-1]) || isDelimiter(data[i-1])) && (end == len(data) || isSpace(data[end]) || isDelimiter(data[end])) {
+			return i
+		}
+		start = i + 1
+	}
+	return -1
+}
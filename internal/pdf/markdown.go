@@ -0,0 +1,312 @@
+// Package pdf extracts the text of PDF documents.
+// This file implements the heuristics that recover the structure of a
+// document from its lines: headings, paragraphs, lists, and code blocks.
+package pdf
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// pageNumberPattern matches lines that are only a page number: "12",
+// "Page 12", "12 / 40", "12 of 40", or "- 12 -".
+var pageNumberPattern = regexp.MustCompile(`(?i)^(page\s+)?[-–—]?\s*\d+\s*[-–—]?(\s*(/|of)\s*\d+)?$`)
+
+// contentsPattern matches the entries of a table of contents: a title, dot
+// leaders, and a page number.
+var contentsPattern = regexp.MustCompile(`^(.+?)\s*[.:·…]{4,}\s*[\divxlcIVXLC]+$`)
+
+// bulletPattern matches list items starting with a bullet, and
+// numberedPattern those starting with a number or letter.
+var (
+	bulletPattern   = regexp.MustCompile(`^([•◦▪‣●○■□·*]|[-–—])\s+(.+)$`)
+	numberedPattern = regexp.MustCompile(`^(\d{1,3}|[a-z])[.)]\s+(.+)$`)
+)
+
+// Markdown returns the text of the document as Markdown:
+//
+//   - Running headers and footers, the lines repeated at the top or bottom of
+//     most pages, and page numbers are dropped.
+//   - The body font size is that of most of the text. Short lines in a larger
+//     font are headings, the largest size being level 1, down to level 4;
+//     short standalone bold lines in the body size are headings below those.
+//   - Lines are joined into paragraphs, hyphenated words included, and a
+//     paragraph ends at a wide vertical gap, a change of font size, or a page
+//     break after a sentence. Lines starting with a bullet or number are list
+//     items, as are the entries of a table of contents without their page
+//     numbers, and consecutive monospaced lines are code blocks.
+//
+// The title of the document isn't added; the result may be empty for
+// documents without text.
+func (d *Document) Markdown() string {
+	pages := d.withoutFurniture()
+	body := bodySize(pages)
+	levels := headingLevels(pages, body)
+
+	w := &markdownWriter{}
+	var prev *Line
+	for _, page := range pages {
+		pageBreak := true
+		for i := range page.Lines {
+			line := &page.Lines[i]
+			if m := contentsPattern.FindStringSubmatch(line.Text); m != nil {
+				w.start(blockList, "- "+m[1])
+			} else if level := headingLevel(line, body, levels); level > 0 {
+				if w.kind == blockHeading && w.level == level && prev != nil && !pageBreak && prev.Y > line.Y && prev.Y-line.Y < line.Size*2 {
+					// A heading wrapped over lines
+					w.text += " " + line.Text
+				} else {
+					w.start(blockHeading, line.Text)
+					w.level = level
+				}
+			} else if line.Mono {
+				if w.kind != blockCode {
+					w.start(blockCode, "")
+					w.codeX = line.X
+				}
+				w.code(line)
+			} else if m := bulletPattern.FindStringSubmatch(line.Text); m != nil {
+				w.start(blockList, "- "+m[2])
+			} else if m := numberedPattern.FindStringSubmatch(line.Text); m != nil && unicode.IsUpper(firstRune(m[2])) {
+				w.start(blockList, m[1]+". "+m[2])
+			} else if w.continues(prev, line, pageBreak) {
+				w.join(line.Text)
+			} else {
+				w.start(blockParagraph, line.Text)
+			}
+			w.size = line.Size
+			prev = line
+			pageBreak = false
+		}
+	}
+	w.flush()
+	return strings.Join(w.blocks, "\n\n")
+}
+
+// withoutFurniture returns the pages without their running headers and
+// footers and page numbers.
+func (d *Document) withoutFurniture() []Page {
+	// Digits are masked, as running headers often hold the page number
+	key := func(text string) string {
+		return strings.Map(func(r rune) rune {
+			if unicode.IsDigit(r) {
+				return '#'
+			}
+			return unicode.ToLower(r)
+		}, text)
+	}
+	edge := func(lines []Line, i int) bool {
+		return i < 2 || i >= len(lines)-2
+	}
+
+	repeated := make(map[string]bool)
+	if len(d.Pages) >= 3 {
+		counts := make(map[string]int)
+		for _, page := range d.Pages {
+			seen := make(map[string]bool)
+			for i, line := range page.Lines {
+				if k := key(line.Text); edge(page.Lines, i) && !seen[k] {
+					seen[k] = true
+					counts[k]++
+				}
+			}
+		}
+		for k, n := range counts {
+			if n >= 3 && n*2 >= len(d.Pages) {
+				repeated[k] = true
+			}
+		}
+	}
+
+	pages := make([]Page, len(d.Pages))
+	for p, page := range d.Pages {
+		for i, line := range page.Lines {
+			if edge(page.Lines, i) && (repeated[key(line.Text)] || pageNumberPattern.MatchString(line.Text)) {
+				continue
+			}
+			pages[p].Lines = append(pages[p].Lines, line)
+		}
+	}
+	return pages
+}
+
+// bodySize returns the font size of most of the text of pages.
+func bodySize(pages []Page) float64 {
+	chars := make(map[float64]int)
+	for _, page := range pages {
+		for _, line := range page.Lines {
+			chars[line.Size] += utf8.RuneCountInString(line.Text)
+		}
+	}
+	var body float64
+	for size, n := range chars {
+		if n > chars[body] || (n == chars[body] && size < body) {
+			body = size
+		}
+	}
+	return body
+}
+
+// isHeadingText reports whether text could be a heading: short, with
+// letters, and not ending like a sentence clause.
+func isHeadingText(text string) bool {
+	if utf8.RuneCountInString(text) > 100 || strings.IndexFunc(text, unicode.IsLetter) < 0 {
+		return false
+	}
+	return !strings.HasSuffix(text, ",") && !strings.HasSuffix(text, ";")
+}
+
+// headingLevels returns the heading level of the font sizes larger than the
+// body size by 15% or more that headings are set in, the largest first.
+func headingLevels(pages []Page, body float64) map[float64]int {
+	var sizes []float64
+	seen := make(map[float64]bool)
+	for _, page := range pages {
+		for _, line := range page.Lines {
+			if line.Size >= body*1.15 && !line.Mono && isHeadingText(line.Text) && !seen[line.Size] {
+				seen[line.Size] = true
+				sizes = append(sizes, line.Size)
+			}
+		}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(sizes)))
+	levels := make(map[float64]int)
+	for i, size := range sizes {
+		levels[size] = min(i+1, 4)
+	}
+	return levels
+}
+
+// headingLevel returns the heading level of line, or 0 if it isn't a
+// heading.
+func headingLevel(line *Line, body float64, levels map[float64]int) int {
+	if line.Mono || !isHeadingText(line.Text) {
+		return 0
+	}
+	if level, ok := levels[line.Size]; ok {
+		return level
+	}
+	if line.Bold && math.Abs(line.Size-body) < 0.5 && utf8.RuneCountInString(line.Text) <= 80 &&
+		!strings.HasSuffix(line.Text, ".") && !unicode.IsLower(firstRune(line.Text)) {
+		return min(len(levels)+1, 4)
+	}
+	return 0
+}
+
+// firstRune returns the first character of s.
+func firstRune(s string) rune {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r
+}
+
+// The kinds of Markdown blocks.
+const (
+	blockParagraph = iota + 1
+	blockHeading
+	blockList
+	blockCode
+)
+
+// markdownWriter assembles the Markdown blocks of a document.
+type markdownWriter struct {
+	blocks []string
+	// lastKind is the kind of the last block.
+	lastKind int
+	// kind and text are the current block; level is the level of a heading,
+	// codeX the left margin of a code block, and size the font size of the
+	// last line.
+	kind        int
+	text        string
+	level       int
+	codeX, size float64
+	codeLines   []string
+}
+
+// start ends the current block and starts a block of kind with text.
+func (w *markdownWriter) start(kind int, text string) {
+	w.flush()
+	w.kind, w.text = kind, text
+}
+
+// flush ends the current block.
+func (w *markdownWriter) flush() {
+	switch w.kind {
+	case blockHeading:
+		w.blocks = append(w.blocks, strings.Repeat("#", w.level)+" "+w.text)
+	case blockParagraph:
+		w.blocks = append(w.blocks, escape(w.text))
+	case blockList:
+		if w.lastKind == blockList {
+			// Items of the same list
+			w.blocks[len(w.blocks)-1] += "\n" + w.text
+		} else {
+			w.blocks = append(w.blocks, w.text)
+		}
+	case blockCode:
+		w.blocks = append(w.blocks, fmt.Sprintf("```\n%s\n```", strings.Join(w.codeLines, "\n")))
+		w.codeLines = nil
+	}
+	if w.kind != 0 {
+		w.lastKind = w.kind
+	}
+	w.kind, w.text = 0, ""
+}
+
+// code adds line to the current code block, indented by its distance from
+// the left margin of the block.
+func (w *markdownWriter) code(line *Line) {
+	indent := 0
+	if line.Size > 0 {
+		// Monospaced glyphs are about 0.6 em wide
+		indent = int(math.Round((line.X - w.codeX) / (line.Size * 0.6)))
+	}
+	w.codeLines = append(w.codeLines, strings.Repeat(" ", max(indent, 0))+line.Text)
+}
+
+// continues reports whether line continues the paragraph or list item of
+// the line prev before it.
+func (w *markdownWriter) continues(prev, line *Line, pageBreak bool) bool {
+	if prev == nil || (w.kind != blockParagraph && w.kind != blockList) || math.Abs(line.Size-w.size) >= 0.5 {
+		return false
+	}
+	if pageBreak {
+		// A sentence carried over to the next page
+		return !strings.ContainsAny(w.text[len(w.text)-1:], ".!?:") && !unicode.IsUpper(firstRune(line.Text))
+	}
+	gap := prev.Y - line.Y
+	return gap > 0 && gap < line.Size*1.8
+}
+
+// join adds the text of a line to the current block, joining a word
+// hyphenated at its end.
+func (w *markdownWriter) join(text string) {
+	if before, ok := strings.CutSuffix(w.text, "-"); ok && len(before) > 0 && unicode.IsLetter(lastRune(before)) && unicode.IsLower(firstRune(text)) {
+		w.text = before + text
+		return
+	}
+	w.text += " " + text
+}
+
+// lastRune returns the last character of s.
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+// escape escapes the start of a paragraph that Markdown would read as a
+// heading, quote, list item, or rule.
+func escape(text string) string {
+	switch firstRune(text) {
+	case '#', '>', '+', '-', '*', '=', '|':
+		return "\\" + text
+	}
+	if i := strings.IndexFunc(text, func(r rune) bool { return !unicode.IsDigit(r) }); i > 0 && (text[i] == '.' || text[i] == ')') {
+		return text[:i] + "\\" + text[i:]
+	}
+	return text
+}
@@ -0,0 +1,542 @@
+// Package pdf extracts the text of PDF documents.
+//
+// It has no dependencies and reads the PDF files found on documentation
+// sites: manuals and guides produced by word processors and typesetting
+// tools. Parse recovers the lines of text of each page, with their position,
+// font size, and style, and Document.Markdown turns them into Markdown with
+// heading and page structure heuristics (see Document.Markdown).
+//
+// Only text drawn with fonts is extracted: scanned documents have none, and
+// encrypted documents are rejected with ErrEncrypted.
+package pdf
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/ascii85"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+)
+
+// ErrNotPDF is returned by Parse for data without a PDF header.
+var ErrNotPDF = errors.New("not a PDF document")
+
+// ErrEncrypted is returned by Parse for encrypted documents.
+var ErrEncrypted = errors.New("encrypted PDF document")
+
+// Document is the text of a PDF document.
+type Document struct {
+	// Title is the title of the document information dictionary, if any.
+	Title string
+	// Pages are the pages of the document, in order.
+	Pages []Page
+}
+
+// Page is the text of a page.
+type Page struct {
+	// Lines are the lines of text of the page, in the order they are drawn,
+	// which is the reading order of most documents.
+	Lines []Line
+}
+
+// Line is a line of text.
+type Line struct {
+	// Text is the text of the line, with single spaces between words.
+	Text string
+	// Size is the font size of the line, in points.
+	Size float64
+	// X and Y are the position of the start of the line's baseline, in
+	// points from the bottom left corner of the page.
+	X, Y float64
+	// Bold and Mono report whether the whole line is drawn with bold or
+	// monospaced fonts.
+	Bold, Mono bool
+}
+
+// Parse parses the PDF document data and extracts its text.
+//
+// The objects of the document are found by scanning the file rather than
+// with its cross-reference table, which makes damaged files and incremental
+// updates readable: the last definition of an object wins.
+//
+// Returns ErrNotPDF if data isn't a PDF document, ErrEncrypted if it is
+// encrypted, or an error if it has no pages.
+func Parse(data []byte) (*Document, error) {
+	header := data
+	if len(header) > 1024 {
+		header = header[:1024]
+	}
+	if !bytes.Contains(header, []byte("%PDF-")) {
+		return nil, ErrNotPDF
+	}
+
+	f := scan(data)
+	if f.trailer("Encrypt") != nil {
+		return nil, ErrEncrypted
+	}
+	pages := f.pages()
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("failed to find the pages of the document")
+	}
+
+	doc := &Document{}
+	if info := f.dict(f.trailer("Info")); info != nil {
+		doc.Title = textString(f.str(info["Title"]))
+	}
+	for _, p := range pages {
+		doc.Pages = append(doc.Pages, Page{Lines: f.pageLines(p)})
+	}
+	return doc, nil
+}
+
+// file is the objects of a PDF file.
+type file struct {
+	// objects are the objects by number; generations are ignored.
+	objects map[int]any
+	// trailers are the trailer dictionaries, including those of
+	// cross-reference streams, in file order.
+	trailers []dict
+}
+
+// objectPattern matches the start of an indirect object, "12 0 obj".
+var objectPattern = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// scan reads the objects of data.
+func scan(data []byte) *file {
+	f := &file{objects: make(map[int]any)}
+	for pos := 0; pos < len(data); {
+		loc := objectPattern.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		l := &lexer{data: data, pos: pos + loc[1]}
+		obj, err := l.object()
+		if err != nil {
+			pos += loc[1]
+			continue
+		}
+		if d, ok := obj.(dict); ok {
+			save := l.pos
+			if tok, err := l.token(); err == nil && tok == keyword("stream") {
+				obj = readStream(l, d)
+			} else {
+				l.pos = save
+			}
+		}
+		f.objects[num] = obj
+		pos = l.pos
+	}
+
+	for pos := 0; ; {
+		i := indexKeyword(data, "trailer", pos)
+		if i < 0 {
+			break
+		}
+		l := &lexer{data: data, pos: i + len("trailer")}
+		if d, ok := mustObject(l).(dict); ok {
+			f.trailers = append(f.trailers, d)
+		}
+		pos = i + len("trailer")
+	}
+
+	nums := make([]int, 0, len(f.objects))
+	for num := range f.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		s, ok := f.objects[num].(*stream)
+		if !ok {
+			continue
+		}
+		switch s.dict["Type"] {
+		case name("XRef"):
+			f.trailers = append(f.trailers, s.dict)
+		case name("ObjStm"):
+			f.expandObjectStream(s)
+		}
+	}
+	return f
+}
+
+// mustObject returns the next object of l, or nil.
+func mustObject(l *lexer) any {
+	obj, err := l.object()
+	if err != nil {
+		return nil
+	}
+	return obj
+}
+
+// readStream reads the data of the stream of dictionary d, after its stream
+// keyword, and the endstream keyword.
+func readStream(l *lexer, d dict) *stream {
+	data := l.data
+	start := l.pos
+	if start < len(data) && data[start] == '\r' {
+		start++
+	}
+	if start < len(data) && data[start] == '\n' {
+		start++
+	}
+
+	// A direct length is trusted when endstream follows it; an indirect one
+	// isn't known yet
+	if n, ok := d["Length"].(int); ok && n >= 0 && start+n <= len(data) {
+		if i := indexKeyword(data, "endstream", start+n); i >= 0 && len(bytes.TrimSpace(data[start+n:i])) == 0 {
+			l.pos = i + len("endstream")
+			return &stream{dict: d, raw: data[start : start+n]}
+		}
+	}
+	end := indexKeyword(data, "endstream", start)
+	if end < 0 {
+		l.pos = len(data)
+		return &stream{dict: d, raw: data[start:]}
+	}
+	l.pos = end + len("endstream")
+	raw := data[start:end]
+	raw = bytes.TrimSuffix(raw, []byte("\n"))
+	raw = bytes.TrimSuffix(raw, []byte("\r"))
+	return &stream{dict: d, raw: raw}
+}
+
+// expandObjectStream adds the objects of the object stream s that aren't
+// defined in the file itself.
+func (f *file) expandObjectStream(s *stream) {
+	data, err := f.decode(s)
+	if err != nil {
+		return
+	}
+	n, first := f.int(s.dict["N"]), f.int(s.dict["First"])
+	l := &lexer{data: data}
+	for i := 0; i < n; i++ {
+		num, ok1 := mustObject(l).(int)
+		offset, ok2 := mustObject(l).(int)
+		if !ok1 || !ok2 {
+			return
+		}
+		if _, ok := f.objects[num]; ok || first+offset >= len(data) {
+			continue
+		}
+		obj := &lexer{data: data, pos: first + offset}
+		f.objects[num] = mustObject(obj)
+	}
+}
+
+// resolve returns v, or the object it references.
+func (f *file) resolve(v any) any {
+	for i := 0; i < 16; i++ {
+		r, ok := v.(ref)
+		if !ok {
+			return v
+		}
+		v = f.objects[r.num]
+	}
+	return nil
+}
+
+// trailer returns the value of key in the last trailer that has it.
+func (f *file) trailer(key string) any {
+	for i := len(f.trailers) - 1; i >= 0; i-- {
+		if v, ok := f.trailers[i][key]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// dict returns v as a dictionary (the dictionary of a stream), or nil.
+func (f *file) dict(v any) dict {
+	switch v := f.resolve(v).(type) {
+	case dict:
+		return v
+	case *stream:
+		return v.dict
+	}
+	return nil
+}
+
+// array returns v as an array, or nil.
+func (f *file) array(v any) array {
+	a, _ := f.resolve(v).(array)
+	return a
+}
+
+// str returns v as a string, or "".
+func (f *file) str(v any) string {
+	s, _ := f.resolve(v).(string)
+	return s
+}
+
+// name returns v as a name, or "".
+func (f *file) name(v any) name {
+	n, _ := f.resolve(v).(name)
+	return n
+}
+
+// int returns v as an integer, or 0.
+func (f *file) int(v any) int {
+	switch v := f.resolve(v).(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return 0
+}
+
+// number returns v as a number, or 0.
+func (f *file) number(v any) float64 {
+	switch v := f.resolve(v).(type) {
+	case int:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+// decode returns the decoded data of s.
+func (f *file) decode(s *stream) ([]byte, error) {
+	data := s.raw
+	var filters, params array
+	switch v := f.resolve(s.dict["Filter"]).(type) {
+	case name:
+		filters = array{v}
+		params = array{s.dict["DecodeParms"]}
+	case array:
+		filters = v
+		params = f.array(s.dict["DecodeParms"])
+	}
+
+	for i, filter := range filters {
+		var err error
+		switch f.name(filter) {
+		case "FlateDecode", "Fl":
+			data, err = inflate(data)
+			if err == nil && i < len(params) {
+				data, err = f.unpredict(data, f.dict(params[i]))
+			}
+		case "ASCIIHexDecode", "AHx":
+			l := &lexer{data: data}
+			data = []byte(l.hex())
+		case "ASCII85Decode", "A85":
+			data, err = decodeASCII85(data)
+		default:
+			return nil, fmt.Errorf("unsupported stream filter %v", filter)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// inflate decompresses zlib data, or raw deflate data as some producers
+// write. Truncated data yields what could be decompressed.
+func inflate(data []byte) ([]byte, error) {
+	var r io.Reader
+	if zr, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+		r = zr
+	} else {
+		r = flate.NewReader(bytes.NewReader(data))
+	}
+	out, err := io.ReadAll(r)
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("failed to inflate stream: %w", err)
+	}
+	return out, nil
+}
+
+// unpredict reverses the PNG predictors of the decode parameters params.
+func (f *file) unpredict(data []byte, params dict) ([]byte, error) {
+	if params == nil || f.int(params["Predictor"]) < 10 {
+		return data, nil
+	}
+	columns := f.int(params["Columns"])
+	if columns <= 0 {
+		columns = 1
+	}
+	colors := f.int(params["Colors"])
+	if colors <= 0 {
+		colors = 1
+	}
+	bpc := f.int(params["BitsPerComponent"])
+	if bpc <= 0 {
+		bpc = 8
+	}
+	bpp := (colors*bpc + 7) / 8
+	rowLen := (columns*colors*bpc + 7) / 8
+
+	var out []byte
+	prev := make([]byte, rowLen)
+	for len(data) > rowLen {
+		kind, row := data[0], append([]byte(nil), data[1:rowLen+1]...)
+		data = data[rowLen+1:]
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = row[i-bpp], prev[i-bpp]
+			}
+			up := prev[i]
+			switch kind {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+// paeth is the Paeth predictor of PNG.
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// decodeASCII85 decodes ASCII base-85 data, up to its "~>" end marker.
+func decodeASCII85(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+	out := make([]byte, 4*len(data)+4)
+	n, _, err := ascii85.Decode(out, data, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ASCII85 stream: %w", err)
+	}
+	return out[:n], nil
+}
+
+// textString decodes a PDF text string: UTF-16BE with a byte order mark,
+// UTF-8 with one, or else PDFDocEncoding, read as Latin-1.
+func textString(s string) string {
+	switch {
+	case len(s) >= 2 && s[0] == 0xfe && s[1] == 0xff:
+		return utf16BE(s[2:])
+	case len(s) >= 3 && s[:3] == "\xef\xbb\xbf":
+		return s[3:]
+	}
+	runes := make([]rune, len(s))
+	for i := 0; i < len(s); i++ {
+		runes[i] = rune(s[i])
+	}
+	return string(runes)
+}
+
+// utf16BE decodes UTF-16BE s.
+func utf16BE(s string) string {
+	units := make([]uint16, len(s)/2)
+	for i := range units {
+		units[i] = uint16(s[2*i])<<8 | uint16(s[2*i+1])
+	}
+	return string(utf16.Decode(units))
+}
+
+// pageObject is a page dictionary and its resources, which may be inherited
+// from the page tree.
+type pageObject struct {
+	dict      dict
+	resources dict
+}
+
+// pages returns the pages of the document, in order: those of the page tree
+// of the catalog or, without one, the page objects by object number.
+func (f *file) pages() []pageObject {
+	var pages []pageObject
+	// The depth limit guards against cycles in damaged page trees
+	var walk func(node dict, resources dict, depth int)
+	walk = func(node dict, resources dict, depth int) {
+		if node == nil || depth > 64 || len(pages) > 100000 {
+			return
+		}
+		if r := f.dict(node["Resources"]); r != nil {
+			resources = r
+		}
+		kids := f.array(node["Kids"])
+		if node["Type"] != name("Pages") && kids == nil {
+			pages = append(pages, pageObject{dict: node, resources: resources})
+			return
+		}
+		for _, kid := range kids {
+			walk(f.dict(kid), resources, depth+1)
+		}
+	}
+	if root := f.dict(f.trailer("Root")); root != nil {
+		walk(f.dict(root["Pages"]), nil, 0)
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+
+	nums := make([]int, 0, len(f.objects))
+	for num, obj := range f.objects {
+		if d, ok := obj.(dict); ok && d["Type"] == name("Page") {
+			nums = append(nums, num)
+		}
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		d := f.objects[num].(dict)
+		resources := f.dict(d["Resources"])
+		if resources == nil {
+			resources = f.dict(f.dict(d["Parent"])["Resources"])
+		}
+		pages = append(pages, pageObject{dict: d, resources: resources})
+	}
+	return pages
+}
+
+// contents returns the decoded content streams of page p, concatenated.
+func (f *file) contents(p pageObject) []byte {
+	var streams []any
+	switch v := f.resolve(p.dict["Contents"]).(type) {
+	case *stream:
+		streams = []any{v}
+	case array:
+		streams = v
+	}
+	var out []byte
+	for _, v := range streams {
+		s, ok := f.resolve(v).(*stream)
+		if !ok {
+			continue
+		}
+		data, err := f.decode(s)
+		if err != nil {
+			continue
+		}
+		out = append(out, data...)
+		out = append(out, '\n')
+	}
+	return out
+}
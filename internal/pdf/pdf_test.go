@@ -0,0 +1,189 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testFonts are the font resources of the test documents: Helvetica as F1,
+// Helvetica-Bold as F2, Courier as F3, and a Type0 font with a ToUnicode
+// CMap as F4.
+const testFonts = `<< /F1 10 0 R /F2 11 0 R /F3 12 0 R /F4 13 0 R >>`
+
+// buildPDF returns a PDF document with the content streams of pages,
+// compressed with FlateDecode if compress is set.
+func buildPDF(title string, pages []string, compress bool) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	obj := func(num int, body string) {
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", num, body)
+	}
+	streamObj := func(num int, dict, data string) {
+		if compress {
+			var z bytes.Buffer
+			zw := zlib.NewWriter(&z)
+			zw.Write([]byte(data))
+			zw.Close()
+			fmt.Fprintf(&b, "%d 0 obj\n<< %s /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream\nendobj\n", num, dict, z.Len(), z.Bytes())
+			return
+		}
+		fmt.Fprintf(&b, "%d 0 obj\n<< %s /Length %d >>\nstream\n%s\nendstream\nendobj\n", num, dict, len(data), data)
+	}
+
+	var kids []string
+	for i := range pages {
+		kids = append(kids, fmt.Sprintf("%d 0 R", 100+2*i))
+	}
+	obj(1, "<< /Type /Catalog /Pages 2 0 R >>")
+	obj(2, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /Resources << /Font %s >> >>", strings.Join(kids, " "), len(pages), testFonts))
+	obj(3, fmt.Sprintf("<< /Title (%s) >>", title))
+	obj(10, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+	obj(11, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold >>")
+	obj(12, "<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>")
+	obj(13, "<< /Type /Font /Subtype /Type0 /BaseFont /NotoSans /Encoding /Identity-H /DescendantFonts [14 0 R] /ToUnicode 15 0 R >>")
+	obj(14, "<< /Type /Font /Subtype /CIDFontType2 /BaseFont /NotoSans /DW 600 /W [1 [500 700]] >>")
+	streamObj(15, "", "/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n1 begincodespacerange <0000> <FFFF> endcodespacerange\n"+
+		"2 beginbfchar <0001> <0048> <0002> <0069> endbfchar\n1 beginbfrange <0010> <0012> <3042> endbfrange\nendcmap end end")
+	for i, content := range pages {
+		obj(100+2*i, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents %d 0 R >>", 101+2*i))
+		streamObj(101+2*i, "", content)
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R /Info 3 0 R >>\n%%EOF\n")
+	return b.Bytes()
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name      string
+		content   string
+		compress  bool
+		wantLines []Line
+	}{
+		{
+			name:    "lines and font sizes",
+			content: "BT /F1 24 Tf 72 720 Td (Getting Started) Tj ET\nBT /F1 12 Tf 72 690 Td (Install the tool) Tj 0 -14 Td (\\(quickly\\).) Tj ET",
+			wantLines: []Line{
+				{Text: "Getting Started", Size: 24, X: 72, Y: 720},
+				{Text: "Install the tool", Size: 12, X: 72, Y: 690},
+				{Text: "(quickly).", Size: 12, X: 72, Y: 676},
+			},
+		},
+		{
+			name:     "compressed stream with kerning and word gaps",
+			content:  "BT /F2 12 Tf 1 0 0 1 72 700 Tm [(Hel) -20 (lo) -400 (world)] TJ ET\nBT /F3 10 Tf 72 680 Td (go) Tj ET BT /F3 10 Tf 90 680 Td (build) Tj ET",
+			compress: true,
+			wantLines: []Line{
+				{Text: "Hello world", Size: 12, X: 72, Y: 700, Bold: true},
+				{Text: "go build", Size: 10, X: 72, Y: 680, Mono: true},
+			},
+		},
+		{
+			name:    "composite font with a ToUnicode CMap",
+			content: "q 2 0 0 2 0 0 cm BT /F4 10 Tf 36 300 Td <00010002> Tj <001000110012> Tj ET Q",
+			wantLines: []Line{
+				{Text: "Hiあぃい", Size: 20, X: 72, Y: 600},
+			},
+		},
+		{
+			name:    "leading and quote operators",
+			content: "BT /F1 10 Tf 14 TL 72 700 Td (First) Tj T* (Second) Tj (Third) ' ET",
+			wantLines: []Line{
+				{Text: "First", Size: 10, X: 72, Y: 700},
+				{Text: "Second", Size: 10, X: 72, Y: 686},
+				{Text: "Third", Size: 10, X: 72, Y: 672},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Parse(buildPDF("Manual", []string{tt.content}, tt.compress))
+			if err != nil {
+				t.Fatalf("Parse() returned error: %v", err)
+			}
+			if doc.Title != "Manual" {
+				t.Errorf("Title = %q, want Manual", doc.Title)
+			}
+			if len(doc.Pages) != 1 {
+				t.Fatalf("got %d pages, want 1", len(doc.Pages))
+			}
+			got := doc.Pages[0].Lines
+			if len(got) != len(tt.wantLines) {
+				t.Fatalf("Lines = %+v, want %+v", got, tt.wantLines)
+			}
+			for i, want := range tt.wantLines {
+				g := got[i]
+				if g.Text != want.Text || g.Size != want.Size || g.Bold != want.Bold || g.Mono != want.Mono ||
+					abs(int(g.X-want.X)) > 0 || abs(int(g.Y-want.Y)) > 0 {
+					t.Errorf("line %d = %+v, want %+v", i, g, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	encrypted := bytes.Replace(buildPDF("Secret", []string{"BT ET"}, false), []byte("/Info 3 0 R"), []byte("/Info 3 0 R /Encrypt << /Filter /Standard >>"), 1)
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{name: "not a PDF", data: []byte("<html></html>"), wantErr: ErrNotPDF},
+		{name: "encrypted", data: encrypted, wantErr: ErrEncrypted},
+		{name: "no pages", data: []byte("%PDF-1.4\n1 0 obj\n<< /Type /Catalog >>\nendobj\n")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.data)
+			if err == nil {
+				t.Fatal("Parse() returned no error")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Parse() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	// Each page has a running header and a page number
+	page := func(n int, body string) string {
+		return fmt.Sprintf("BT /F1 8 Tf 72 760 Td (Widget Manual v2) Tj ET\n%s\nBT /F1 8 Tf 300 30 Td (%d) Tj ET", body, n)
+	}
+	pages := []string{
+		page(1, `BT /F1 24 Tf 72 700 Td (Widget Manual) Tj ET
+BT /F1 11 Tf 72 670 Td (Widgets are configured with a file that lists every) Tj 0 -13 Td (option. Options are read at start-) Tj 0 -13 Td (up and can be reloaded.) Tj ET
+BT /F1 18 Tf 72 600 Td (Installation) Tj ET
+BT /F1 11 Tf 72 570 Td (Run the installer:) Tj ET
+BT /F3 11 Tf 72 550 Td (widget install) Tj 0 -13 Td (  --prefix /opt) Tj ET
+BT /F1 11 Tf 72 500 Td (\225 Linux is supported) Tj 0 -13 Td (\225 macOS is supported) Tj ET`),
+		page(2, `BT /F2 11 Tf 72 700 Td (Upgrading) Tj ET
+BT /F1 11 Tf 72 680 Td (Upgrades keep the configuration and) Tj ET`),
+		page(3, `BT /F1 11 Tf 72 700 Td (the data of every widget.) Tj ET
+BT /F1 11 Tf 72 650 Td (# of widgets is unlimited.) Tj ET`),
+	}
+	doc, err := Parse(buildPDF("Widget Manual", pages, false))
+	if err != nil {
+		t.Fatalf("Parse() returned error: %v", err)
+	}
+
+	want := strings.Join([]string{
+		"# Widget Manual",
+		"Widgets are configured with a file that lists every option. Options are read at startup and can be reloaded.",
+		"## Installation",
+		"Run the installer:",
+		"```\nwidget install\n  --prefix /opt\n```",
+		"- Linux is supported\n- macOS is supported",
+		"### Upgrading",
+		"Upgrades keep the configuration and the data of every widget.",
+		"\\# of widgets is unlimited.",
+	}, "\n\n")
+	if got := doc.Markdown(); got != want {
+		t.Errorf("Markdown() =\n%s\nwant\n%s", got, want)
+	}
+}
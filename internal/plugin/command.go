@@ -0,0 +1,165 @@
+// Package plugin runs third-party extensions of site2skill as subprocesses.
+// This file implements converter commands: a command such as pandoc, run once
+// per page, converting the main content of the pages it is configured for.
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/pathglob"
+)
+
+// Command is a converter command, an escape hatch for the pages the built-in
+// conversion handles poorly: the HTML of the main content of each page it
+// matches is written to its standard input, and what it writes to its
+// standard output is taken as the Markdown of the page, instead of the
+// built-in conversion. The environment of the command has SITE2SKILL_URL set
+// to the URL of the page.
+//
+// A command exiting with a non-zero status, or running longer than a minute,
+// fails the page, which the built-in conversion takes over with a warning; an
+// empty output declines the page.
+type Command struct {
+	spec  string
+	args  []string
+	urls  []string
+	paths []string
+	types []string
+}
+
+// ParseCommand parses the specification of a converter command,
+// "MATCH[,MATCH...]=COMMAND", where COMMAND is an executable path optionally
+// followed by arguments separated by spaces, and each MATCH selects pages by
+// one of:
+//
+//   - a URL prefix, such as "https://docs.example.com/api/";
+//   - a path pattern (see package pathglob), such as "/reference/**";
+//   - a media type of the Content-Type of the response, such as "text/html",
+//     or all those of a type, such as "text/*".
+//
+// For example, "/reference/**=pandoc -f html -t gfm" converts the pages under
+// /reference with pandoc.
+//
+// Returns an error if the specification has no command, no match, or a
+// malformed match.
+func ParseCommand(spec string) (*Command, error) {
+	matches, command, ok := strings.Cut(spec, "=")
+	if !ok {
+		return nil, fmt.Errorf("invalid converter command %q: expected MATCH=COMMAND", spec)
+	}
+	c := &Command{spec: spec, args: strings.Fields(command)}
+	if len(c.args) == 0 {
+		return nil, fmt.Errorf("invalid converter command %q: empty command", spec)
+	}
+	for _, match := range strings.Split(matches, ",") {
+		match = strings.TrimSpace(match)
+		switch {
+		case match == "":
+			return nil, fmt.Errorf("invalid converter command %q: empty match", spec)
+		case strings.Contains(match, "://"):
+			c.urls = append(c.urls, match)
+		case strings.HasPrefix(match, "/"):
+			if err := pathglob.Validate(match); err != nil {
+				return nil, fmt.Errorf("invalid converter command %q: %w", spec, err)
+			}
+			c.paths = append(c.paths, match)
+		default:
+			mediaType := strings.ToLower(match)
+			typ, subtype, ok := strings.Cut(mediaType, "/")
+			if !ok || typ == "" || subtype == "" || strings.ContainsAny(mediaType, " ;") {
+				return nil, fmt.Errorf("invalid converter command %q: %q is not a URL prefix, a path pattern, or a media type", spec, match)
+			}
+			c.types = append(c.types, mediaType)
+		}
+	}
+	return c, nil
+}
+
+// Name returns the name of the executable of the command.
+func (c *Command) Name() string {
+	return filepath.Base(c.args[0])
+}
+
+// Fingerprint returns the specification of the command, which identifies its
+// output.
+func (c *Command) Fingerprint() string {
+	return "command " + c.spec
+}
+
+// Handles reports whether one of the matches of the command selects the page
+// at pageURL, served with the Content-Type contentType.
+func (c *Command) Handles(pageURL, contentType string) bool {
+	for _, prefix := range c.urls {
+		if strings.HasPrefix(pageURL, prefix) {
+			return true
+		}
+	}
+	if len(c.paths) > 0 {
+		if u, err := url.Parse(pageURL); err == nil {
+			p := u.Path
+			if p == "" {
+				p = "/"
+			}
+			for _, pattern := range c.paths {
+				if pathglob.Match(pattern, p) {
+					return true
+				}
+			}
+		}
+	}
+	if len(c.types) > 0 && contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return false
+		}
+		for _, t := range c.types {
+			if t == mediaType || strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*")) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Extract declines every page: a converter command only converts.
+func (c *Command) Extract(_, _ string) (string, string, error) {
+	return "", "", nil
+}
+
+// Convert runs the command with html, the main content of the page at
+// pageURL, as its standard input and returns its standard output.
+//
+// Returns an error, with the end of its standard error, if the command can't
+// be run, exits with a non-zero status, or times out.
+func (c *Command) Convert(pageURL, html string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), callTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, c.args[0], c.args[1:]...)
+	cmd.Env = append(os.Environ(), "SITE2SKILL_URL="+pageURL)
+	cmd.Stdin = strings.NewReader(html)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		msg := strings.TrimSpace(stderr.String())
+		if i := strings.LastIndexByte(msg, '\n'); i >= 0 {
+			msg = msg[i+1:]
+		}
+		if msg != "" {
+			return "", fmt.Errorf("failed to run %s: %w: %s", c.args[0], err, msg)
+		}
+		return "", fmt.Errorf("failed to run %s: %w", c.args[0], err)
+	}
+	return stdout.String(), nil
+}
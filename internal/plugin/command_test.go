@@ -0,0 +1,113 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// runCommand is a converter command writing the page URL and its standard
+// input in upper case. In mode "command-fail" it fails with a message, and in
+// mode "command-decline" it writes nothing.
+func runCommand(mode string) {
+	in, _ := io.ReadAll(os.Stdin)
+	switch mode {
+	case "command-fail":
+		fmt.Fprintln(os.Stderr, "pandoc: unknown reader")
+		os.Exit(2)
+	case "command-decline":
+	default:
+		fmt.Printf("# %s\n\n%s\n", os.Getenv("SITE2SKILL_URL"), strings.ToUpper(string(in)))
+	}
+	os.Exit(0)
+}
+
+func TestParseCommand(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantErr bool
+	}{
+		{spec: "/reference/**=pandoc -f html -t gfm"},
+		{spec: "https://docs.example.com/api/, text/*=convert.sh"},
+		{spec: "application/xhtml+xml=convert.sh"},
+		{spec: "pandoc -f html", wantErr: true},
+		{spec: "/reference/**=  ", wantErr: true},
+		{spec: "/a/**,=pandoc", wantErr: true},
+		{spec: "/a/[=pandoc", wantErr: true},
+		{spec: "reference=pandoc", wantErr: true},
+		{spec: "text/html; charset=utf-8=pandoc", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			_, err := ParseCommand(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseCommand(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCommandHandles(t *testing.T) {
+	c, err := ParseCommand("https://docs.example.com/api/,/reference/**,text/*,application/xhtml+xml=pandoc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		url         string
+		contentType string
+		want        bool
+	}{
+		{url: "https://docs.example.com/api/widgets", contentType: "text/html", want: true},
+		{url: "https://docs.example.com/reference", contentType: "text/html", want: true},
+		{url: "https://docs.example.com/reference/a/b", want: true},
+		{url: "https://docs.example.com/guide", contentType: "application/XHTML+xml; charset=utf-8", want: true},
+		{url: "https://docs.example.com/guide", contentType: "text/html; charset=utf-8", want: true},
+		{url: "https://docs.example.com/guide", contentType: "application/json"},
+		{url: "https://docs.example.com/guide"},
+		{url: "https://blog.example.com/api/"},
+	}
+	for _, tt := range tests {
+		if got := c.Handles(tt.url, tt.contentType); got != tt.want {
+			t.Errorf("Handles(%q, %q) = %v, want %v", tt.url, tt.contentType, got, tt.want)
+		}
+	}
+}
+
+func TestCommandConvert(t *testing.T) {
+	tests := []struct {
+		mode    string
+		want    string
+		wantErr string
+	}{
+		{mode: "command", want: "# https://docs.example.com/api\n\n<P>HELLO</P>\n"},
+		{mode: "command-decline", want: ""},
+		{mode: "command-fail", wantErr: "pandoc: unknown reader"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			t.Setenv(pluginModeEnv, tt.mode)
+			c, err := ParseCommand("/api=" + os.Args[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := c.Convert("https://docs.example.com/api", "<p>hello</p>")
+			if tt.wantErr != "" {
+				var exitErr *exec.ExitError
+				if err == nil || !errors.As(err, &exitErr) || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Convert() error = %v, want an exit error with %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Convert() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Convert() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,394 @@
+// Package plugin runs third-party extensions of site2skill as subprocesses,
+// so that site-specific support can be added without changes to site2skill.
+//
+// A plugin is an executable speaking JSON-RPC 2.0 over its standard input and
+// output, one message per line. site2skill starts it, sends requests one at a
+// time, and reads each response before sending the next request; what the
+// plugin writes to its standard error is passed through to the log. The
+// methods are:
+//
+//   - handshake, sent first with {"protocol_version": 1}. The result describes
+//     the plugin: {"name": "acme", "version": "1.2.0", "protocol_version": 1,
+//     "capabilities": ["extract", "convert", "export"], "urls":
+//     ["https://docs.acme.com/"], "formats": [{"name": "acme-portal",
+//     "extension": ".md"}]}. capabilities lists the methods below it
+//     implements; urls restricts extract and convert to the pages whose URL
+//     starts with one of the prefixes (every page if empty); formats lists the
+//     output formats of export.
+//   - extract, with {"url", "html"}: the page URL and its whole HTML. The
+//     result {"html", "title"} is the HTML of the main content of the page,
+//     taken instead of the content selector or Readability, and optionally its
+//     title. An empty html declines, leaving the page to the built-in
+//     extraction.
+//   - convert, with {"url", "html"}: the HTML of the main content. The result
+//     {"markdown"} is its conversion to Markdown, without frontmatter, taken
+//     instead of the built-in conversion. An empty markdown declines.
+//   - export, with {"format", "document"}: a document of the skill, Markdown
+//     with its YAML frontmatter, and one of the formats of the handshake. The
+//     result {"document"} is the document in the format.
+//   - shutdown, sent last. The plugin replies and exits; it is killed if it is
+//     still running shortly after.
+//
+// Errors are JSON-RPC error objects, e.g., {"code": 1, "message": "..."}.
+// Requests carry increasing integer IDs, and site2skill ignores the responses
+// whose ID doesn't match the request in flight. A plugin must not write
+// anything else to its standard output.
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ProtocolVersion is the version of the plugin protocol. A plugin answering
+// the handshake with another version is refused.
+const ProtocolVersion = 1
+
+// Capabilities of a plugin (Info.Capabilities), named after their methods.
+const (
+	// CapabilityExtract means the plugin finds the main content of pages.
+	CapabilityExtract = "extract"
+	// CapabilityConvert means the plugin converts the main content to Markdown.
+	CapabilityConvert = "convert"
+	// CapabilityExport means the plugin renders documents in output formats.
+	CapabilityExport = "export"
+)
+
+const (
+	// callTimeout bounds the wait for the response to a request; the plugin is
+	// stopped when it passes.
+	callTimeout = time.Minute
+	// shutdownTimeout is how long a plugin may take to exit after shutdown.
+	shutdownTimeout = 5 * time.Second
+	// maxMessageSize bounds the size of a response line.
+	maxMessageSize = 64 << 20
+)
+
+// ErrStopped means the plugin exited, or was stopped, before answering.
+var ErrStopped = errors.New("plugin stopped")
+
+// Info is the description of a plugin returned by its handshake.
+type Info struct {
+	// Name identifies the plugin in logs and in the conversion cache.
+	Name string `json:"name"`
+	// Version is the version of the plugin; a new version invalidates the
+	// conversions cached with the previous one.
+	Version string `json:"version"`
+	// ProtocolVersion is the protocol version the plugin speaks.
+	ProtocolVersion int `json:"protocol_version"`
+	// Capabilities lists the methods the plugin implements.
+	Capabilities []string `json:"capabilities"`
+	// URLs restricts extract and convert to the pages whose URL starts with
+	// one of these prefixes; empty means every page.
+	URLs []string `json:"urls,omitempty"`
+	// Formats lists the output formats of export.
+	Formats []FormatInfo `json:"formats,omitempty"`
+}
+
+// FormatInfo describes an output format of a plugin.
+type FormatInfo struct {
+	// Name is the name of the format in exports ("FORMAT=DIR").
+	Name string `json:"name"`
+	// Extension is the file extension of the documents, with its dot.
+	Extension string `json:"extension"`
+}
+
+// Plugin is a running plugin. Its methods are safe for concurrent use; the
+// requests are sent one at a time.
+type Plugin struct {
+	info Info
+	cmd  *exec.Cmd
+
+	// mu serializes the requests
+	mu     sync.Mutex
+	stdin  io.WriteCloser
+	lines  chan []byte
+	nextID int
+	// stopped is closed once the process has exited
+	stopped chan struct{}
+	// waitErr is the exit status of the process, set before stopped is closed
+	waitErr error
+}
+
+// request is a JSON-RPC request.
+type request struct {
+	JSONRPC string `json:"jsonrpc"`
+	ID      int    `json:"id"`
+	Method  string `json:"method"`
+	Params  any    `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC response.
+type response struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// Start runs the plugin command, an executable path optionally followed by
+// arguments separated by spaces, and performs the handshake.
+//
+// Returns an error if the plugin can't be started, fails the handshake, or
+// speaks another protocol version; the process is stopped then.
+func Start(ctx context.Context, command string) (*Plugin, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty plugin command")
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", args[0], err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", args[0], err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", args[0], err)
+	}
+
+	p := &Plugin{cmd: cmd, stdin: stdin, lines: make(chan []byte), stopped: make(chan struct{})}
+	go p.read(stdout)
+	go func() {
+		p.waitErr = cmd.Wait()
+		close(p.stopped)
+	}()
+
+	if err := p.call(ctx, "handshake", map[string]int{"protocol_version": ProtocolVersion}, &p.info); err != nil {
+		p.kill()
+		return nil, fmt.Errorf("plugin %s failed the handshake: %w", args[0], err)
+	}
+	if p.info.ProtocolVersion != ProtocolVersion {
+		p.kill()
+		return nil, fmt.Errorf("plugin %s speaks protocol version %d, want %d", args[0], p.info.ProtocolVersion, ProtocolVersion)
+	}
+	if p.info.Name == "" {
+		p.info.Name = args[0]
+	}
+	return p, nil
+}
+
+// read passes the lines of the standard output of the plugin to call until
+// it is closed.
+func (p *Plugin) read(stdout io.Reader) {
+	defer close(p.lines)
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		line := append([]byte(nil), scanner.Bytes()...)
+		select {
+		case p.lines <- line:
+		case <-p.stopped:
+			return
+		}
+	}
+}
+
+// Info returns the description of the plugin from its handshake.
+func (p *Plugin) Info() Info {
+	return p.info
+}
+
+// Name returns the name of the plugin.
+func (p *Plugin) Name() string {
+	return p.info.Name
+}
+
+// Fingerprint returns the name and version of the plugin, which identify the
+// output of its extract and convert methods.
+func (p *Plugin) Fingerprint() string {
+	return p.info.Name + " " + p.info.Version
+}
+
+// Has reports whether the plugin implements the capability.
+func (p *Plugin) Has(capability string) bool {
+	return slices.Contains(p.info.Capabilities, capability)
+}
+
+// Handles reports whether extract and convert apply to the page at pageURL,
+// whatever its content type.
+func (p *Plugin) Handles(pageURL, _ string) bool {
+	if len(p.info.URLs) == 0 {
+		return true
+	}
+	for _, prefix := range p.info.URLs {
+		if strings.HasPrefix(pageURL, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Extract asks the plugin for the main content of the page at pageURL with
+// the HTML html. It returns the HTML of the content and its title, which may
+// be empty; an empty content means the plugin declined the page, as does a
+// plugin without CapabilityExtract.
+//
+// Returns an error if the plugin fails or stops.
+func (p *Plugin) Extract(pageURL, html string) (string, string, error) {
+	if !p.Has(CapabilityExtract) {
+		return "", "", nil
+	}
+	var result struct {
+		HTML  string `json:"html"`
+		Title string `json:"title"`
+	}
+	err := p.call(context.Background(), "extract", map[string]string{"url": pageURL, "html": html}, &result)
+	return result.HTML, result.Title, err
+}
+
+// Convert asks the plugin for the Markdown of html, the main content of the
+// page at pageURL. An empty result means the plugin declined the page, as
+// does a plugin without CapabilityConvert.
+//
+// Returns an error if the plugin fails or stops.
+func (p *Plugin) Convert(pageURL, html string) (string, error) {
+	if !p.Has(CapabilityConvert) {
+		return "", nil
+	}
+	var result struct {
+		Markdown string `json:"markdown"`
+	}
+	err := p.call(context.Background(), "convert", map[string]string{"url": pageURL, "html": html}, &result)
+	return result.Markdown, err
+}
+
+// Formats returns the output formats of the plugin, which render documents
+// through its export method; none without CapabilityExport.
+func (p *Plugin) Formats() []*Format {
+	if !p.Has(CapabilityExport) {
+		return nil
+	}
+	formats := make([]*Format, 0, len(p.info.Formats))
+	for _, f := range p.info.Formats {
+		formats = append(formats, &Format{plugin: p, info: f})
+	}
+	return formats
+}
+
+// Format is an output format of a plugin. It implements
+// converter.OutputFormat.
+type Format struct {
+	plugin *Plugin
+	info   FormatInfo
+}
+
+// Name returns the name of the format.
+func (f *Format) Name() string {
+	return f.info.Name
+}
+
+// Extension returns the file extension of the documents of the format.
+func (f *Format) Extension() string {
+	return f.info.Extension
+}
+
+// Render asks the plugin for doc, a Markdown document with its frontmatter,
+// in the format.
+//
+// Returns an error if the plugin fails or stops.
+func (f *Format) Render(doc string) (string, error) {
+	var result struct {
+		Document string `json:"document"`
+	}
+	if err := f.plugin.call(context.Background(), "export", map[string]string{"format": f.info.Name, "document": doc}, &result); err != nil {
+		return "", err
+	}
+	return result.Document, nil
+}
+
+// Close sends shutdown to the plugin and waits for it to exit, killing it if
+// it takes longer than shutdownTimeout. Closing a stopped plugin does nothing.
+func (p *Plugin) Close() error {
+	select {
+	case <-p.stopped:
+		return nil
+	default:
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	p.call(ctx, "shutdown", nil, nil)
+	p.mu.Lock()
+	p.stdin.Close()
+	p.mu.Unlock()
+	select {
+	case <-p.stopped:
+	case <-ctx.Done():
+		p.kill()
+	}
+	return nil
+}
+
+// kill stops the process and waits for it to exit.
+func (p *Plugin) kill() {
+	p.cmd.Process.Kill()
+	<-p.stopped
+}
+
+// call sends the request method with params and decodes the result of its
+// response into result, unless result is nil. A plugin that doesn't answer
+// within callTimeout is stopped.
+func (p *Plugin) call(ctx context.Context, method string, params, result any) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.nextID++
+	req := request{JSONRPC: "2.0", ID: p.nextID, Method: method, Params: params}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+	if _, err := p.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrStopped, method, err)
+	}
+
+	timer := time.NewTimer(callTimeout)
+	defer timer.Stop()
+	id := fmt.Sprint(req.ID)
+	for {
+		select {
+		case line, ok := <-p.lines:
+			if !ok {
+				<-p.stopped
+				return fmt.Errorf("%w before answering %s: %v", ErrStopped, method, p.waitErr)
+			}
+			var resp response
+			if err := json.Unmarshal(line, &resp); err != nil {
+				return fmt.Errorf("invalid response to %s: %w", method, err)
+			}
+			if string(resp.ID) != id {
+				continue
+			}
+			if resp.Error != nil {
+				return fmt.Errorf("%s failed: %s", method, resp.Error.Message)
+			}
+			if result == nil {
+				return nil
+			}
+			if err := json.Unmarshal(resp.Result, result); err != nil {
+				return fmt.Errorf("invalid result of %s: %w", method, err)
+			}
+			return nil
+		case <-timer.C:
+			p.cmd.Process.Kill()
+			return fmt.Errorf("%w: no answer to %s within %s", ErrStopped, method, callTimeout)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
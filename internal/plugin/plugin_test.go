@@ -0,0 +1,167 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+// pluginModeEnv makes the test binary run as a plugin in the given mode
+// instead of running the tests; see servePlugin.
+const pluginModeEnv = "SITE2SKILL_TEST_PLUGIN"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(pluginModeEnv); mode != "" {
+		if strings.HasPrefix(mode, "command") {
+			runCommand(mode)
+		}
+		servePlugin(mode)
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// servePlugin is a plugin for docs.example.com extracting <article>, which
+// converts every page to a fixed Markdown and exports documents in upper
+// case. In mode "old" it speaks another protocol version, and in mode "crash"
+// it exits when asked to extract.
+func servePlugin(mode string) {
+	scanner := bufio.NewScanner(os.Stdin)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		var req struct {
+			ID     int               `json:"id"`
+			Method string            `json:"method"`
+			Params map[string]string `json:"params"`
+		}
+		json.Unmarshal(scanner.Bytes(), &req)
+		var result any
+		switch req.Method {
+		case "handshake":
+			version := ProtocolVersion
+			if mode == "old" {
+				version = 0
+			}
+			result = Info{
+				Name:            "example",
+				Version:         "1.0.0",
+				ProtocolVersion: version,
+				Capabilities:    []string{CapabilityExtract, CapabilityConvert, CapabilityExport},
+				URLs:            []string{"https://docs.example.com/"},
+				Formats:         []FormatInfo{{Name: "shout", Extension: ".txt"}},
+			}
+		case "extract":
+			if mode == "crash" {
+				os.Exit(3)
+			}
+			html := req.Params["html"]
+			start, end := strings.Index(html, "<article>"), strings.Index(html, "</article>")
+			if start < 0 || end < start {
+				result = map[string]string{}
+				break
+			}
+			result = map[string]string{"html": html[start+len("<article>") : end], "title": "From the plugin"}
+		case "convert":
+			result = map[string]string{"markdown": "Converted " + req.Params["url"]}
+		case "export":
+			if req.Params["format"] != "shout" {
+				fmt.Printf(`{"jsonrpc":"2.0","id":%d,"error":{"code":1,"message":"unknown format"}}`+"\n", req.ID)
+				continue
+			}
+			result = map[string]string{"document": strings.ToUpper(req.Params["document"])}
+		case "shutdown":
+			fmt.Printf(`{"jsonrpc":"2.0","id":%d,"result":{}}`+"\n", req.ID)
+			return
+		}
+		// A stray response to another request is ignored
+		fmt.Printf(`{"jsonrpc":"2.0","id":%d,"result":{}}`+"\n", req.ID+1000)
+		data, _ := json.Marshal(map[string]any{"jsonrpc": "2.0", "id": req.ID, "result": result})
+		fmt.Printf("%s\n", data)
+	}
+}
+
+// startPlugin starts the test binary as a plugin in mode.
+func startPlugin(t *testing.T, mode string) (*Plugin, error) {
+	t.Helper()
+	t.Setenv(pluginModeEnv, mode)
+	return Start(context.Background(), os.Args[0])
+}
+
+func TestPlugin(t *testing.T) {
+	p, err := startPlugin(t, "ok")
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer p.Close()
+
+	if p.Name() != "example" || p.Fingerprint() != "example 1.0.0" {
+		t.Errorf("Name() = %q, Fingerprint() = %q", p.Name(), p.Fingerprint())
+	}
+	if !p.Has(CapabilityConvert) || p.Has("render") {
+		t.Errorf("capabilities = %v", p.Info().Capabilities)
+	}
+	if !p.Handles("https://docs.example.com/guide", "text/html") || p.Handles("https://blog.example.com/", "text/html") {
+		t.Error("Handles() doesn't follow the URL prefixes of the handshake")
+	}
+
+	content, title, err := p.Extract("https://docs.example.com/guide", "<html><body><nav>Menu</nav><article><p>Body</p></article></body></html>")
+	if err != nil || content != "<p>Body</p>" || title != "From the plugin" {
+		t.Errorf("Extract() = %q, %q, %v", content, title, err)
+	}
+	if content, _, err := p.Extract("https://docs.example.com/guide", "<html><body>No article</body></html>"); err != nil || content != "" {
+		t.Errorf("Extract() of a declined page = %q, %v; want no content", content, err)
+	}
+	if md, err := p.Convert("https://docs.example.com/guide", "<p>Body</p>"); err != nil || md != "Converted https://docs.example.com/guide" {
+		t.Errorf("Convert() = %q, %v", md, err)
+	}
+
+	formats := p.Formats()
+	if len(formats) != 1 || formats[0].Name() != "shout" || formats[0].Extension() != ".txt" {
+		t.Fatalf("Formats() = %+v", formats)
+	}
+	if doc, err := formats[0].Render("# Title\n"); err != nil || doc != "# TITLE\n" {
+		t.Errorf("Render() = %q, %v", doc, err)
+	}
+	unknown := &Format{plugin: p, info: FormatInfo{Name: "whisper"}}
+	if _, err := unknown.Render("# Title\n"); err == nil || !strings.Contains(err.Error(), "unknown format") {
+		t.Errorf("Render() in an unknown format error = %v, want the plugin's error", err)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if _, err := p.Convert("https://docs.example.com/guide", "<p>Body</p>"); !errors.Is(err, ErrStopped) {
+		t.Errorf("Convert() after Close() error = %v, want ErrStopped", err)
+	}
+}
+
+func TestPluginProtocolVersion(t *testing.T) {
+	if _, err := startPlugin(t, "old"); err == nil || !strings.Contains(err.Error(), "protocol version 0") {
+		t.Errorf("Start() error = %v, want a protocol version mismatch", err)
+	}
+}
+
+func TestPluginCrash(t *testing.T) {
+	p, err := startPlugin(t, "crash")
+	if err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	defer p.Close()
+	if _, _, err := p.Extract("https://docs.example.com/", "<html></html>"); !errors.Is(err, ErrStopped) {
+		t.Errorf("Extract() error = %v, want ErrStopped", err)
+	}
+}
+
+func TestStartMissing(t *testing.T) {
+	if _, err := Start(context.Background(), "/nonexistent/site2skill-plugin"); err == nil {
+		t.Error("Start() of a missing executable succeeded")
+	}
+	if _, err := Start(context.Background(), " "); err == nil {
+		t.Error("Start() of an empty command succeeded")
+	}
+}
@@ -0,0 +1,286 @@
+// Package progress reports the progress of a crawl while it runs: the pages
+// fetched, queued, and failed, the bytes downloaded, the current rate, and the
+// estimated time left.
+//
+// The crawler sends a Snapshot of its counters to a Reporter as it settles each
+// URL. Reporters render them for a person (ModeBar redraws one status line,
+// ModePlain prints a status line every few seconds, suited to CI logs) or for a
+// program (ModeJSON prints one JSON object per line). Programs embedding the
+// crawler can implement Reporter themselves.
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Output modes of NewReporter.
+const (
+	// ModeBar redraws a single status line with a bar of the pages fetched
+	// among those known, for interactive terminals.
+	ModeBar = "bar"
+	// ModePlain prints a status line every PlainInterval, and one when the
+	// crawl is done, for logs.
+	ModePlain = "plain"
+	// ModeJSON prints a Snapshot as a JSON object per line (NDJSON) at most
+	// every JSONInterval, and one when the crawl is done.
+	ModeJSON = "json"
+)
+
+// Modes lists the output modes of NewReporter.
+var Modes = []string{ModePlain, ModeBar, ModeJSON}
+
+const (
+	// PlainInterval is the minimum time between two status lines of ModePlain.
+	PlainInterval = 5 * time.Second
+	// JSONInterval is the minimum time between two objects of ModeJSON.
+	JSONInterval = time.Second
+	// barInterval is the minimum time between two redraws of ModeBar.
+	barInterval = 100 * time.Millisecond
+	// barWidth is the number of cells of the bar of ModeBar.
+	barWidth = 20
+)
+
+// Snapshot is the state of a crawl at one point in time.
+type Snapshot struct {
+	// Fetched is the number of pages downloaded and saved.
+	Fetched int `json:"fetched"`
+	// Queued is the number of links found and not crawled yet. Some of them
+	// may turn out to be skipped, so it is an upper bound of the pages left.
+	Queued int `json:"queued"`
+	// Failed is the number of URLs that couldn't be fetched or saved.
+	Failed int `json:"failed"`
+	// Skipped is the number of URLs not fetched or not saved on purpose (out of
+	// scope, filtered, disallowed by robots.txt, ...).
+	Skipped int `json:"skipped"`
+	// Throttled is the number of hosts currently slowed down because they
+	// answered 429 or 503, or much more slowly than usual.
+	Throttled int `json:"throttled,omitempty"`
+	// Bytes is the total size of the response bodies downloaded.
+	Bytes int64 `json:"bytes"`
+	// Elapsed is the time since the crawl started.
+	Elapsed time.Duration `json:"-"`
+	// Rate is the current number of pages requested per second, over the
+	// last few requests.
+	Rate float64 `json:"rate"`
+	// ETA is the estimated time left to request the queued URLs at Rate; 0 when
+	// unknown.
+	ETA time.Duration `json:"-"`
+	// URL is the URL settled last.
+	URL string `json:"url,omitempty"`
+	// Done reports whether the crawl is over; it is set on the last snapshot only.
+	Done bool `json:"done,omitempty"`
+}
+
+// MarshalJSON encodes s with Elapsed and ETA in seconds, as elapsed_seconds
+// and eta_seconds.
+func (s Snapshot) MarshalJSON() ([]byte, error) {
+	type snapshot Snapshot
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		snapshot
+		ElapsedSeconds float64 `json:"elapsed_seconds"`
+		ETASeconds     float64 `json:"eta_seconds,omitempty"`
+	}{"progress", snapshot(s), s.Elapsed.Seconds(), s.ETA.Seconds()})
+}
+
+// Reporter renders the progress of a crawl. Report is called with a snapshot
+// whenever the crawler settles a URL, and a last time with Done set; it may be
+// called from several goroutines, but not concurrently.
+type Reporter interface {
+	Report(s Snapshot)
+}
+
+// NewReporter returns a reporter of mode (ModePlain, ModeBar, or ModeJSON)
+// writing to w.
+//
+// Returns an error if mode is unknown.
+func NewReporter(mode string, w io.Writer) (Reporter, error) {
+	switch mode {
+	case ModeBar:
+		return &barReporter{w: w}, nil
+	case ModePlain:
+		return &plainReporter{w: w}, nil
+	case ModeJSON:
+		return &jsonReporter{enc: json.NewEncoder(w)}, nil
+	default:
+		return nil, fmt.Errorf("unknown progress mode %q (expected %s)", mode, strings.Join(Modes, ", "))
+	}
+}
+
+// throttle lets through one event per interval.
+type throttle struct {
+	last time.Time
+}
+
+// allow reports whether an event at now comes interval or more after the last
+// one allowed, and if so records it.
+func (t *throttle) allow(now time.Time, interval time.Duration) bool {
+	if !t.last.IsZero() && now.Sub(t.last) < interval {
+		return false
+	}
+	t.last = now
+	return true
+}
+
+// barReporter implements ModeBar.
+type barReporter struct {
+	w        io.Writer
+	throttle throttle
+	// width is the length of the last line drawn, cleared by the next one
+	width int
+}
+
+func (r *barReporter) Report(s Snapshot) {
+	if !s.Done && !r.throttle.allow(time.Now(), barInterval) {
+		return
+	}
+	known := s.Fetched + s.Queued
+	filled := barWidth
+	if known > 0 && s.Queued > 0 {
+		filled = barWidth * s.Fetched / known
+	}
+	line := fmt.Sprintf("[%s%s] %s", strings.Repeat("=", filled), strings.Repeat(" ", barWidth-filled), status(s))
+	if url := s.URL; !s.Done && url != "" {
+		if len(url) > 60 {
+			url = "..." + url[len(url)-57:]
+		}
+		line += " " + url
+	}
+	pad := max(0, r.width-len(line))
+	r.width = len(line)
+	fmt.Fprintf(r.w, "\r%s%s", line, strings.Repeat(" ", pad))
+	if s.Done {
+		fmt.Fprintln(r.w)
+		r.width = 0
+	}
+}
+
+// plainReporter implements ModePlain.
+type plainReporter struct {
+	w        io.Writer
+	throttle throttle
+}
+
+func (r *plainReporter) Report(s Snapshot) {
+	if !s.Done && !r.throttle.allow(time.Now(), PlainInterval) {
+		return
+	}
+	if s.Done {
+		fmt.Fprintf(r.w, "Crawl done: %s\n", status(s))
+		return
+	}
+	fmt.Fprintf(r.w, "Crawling: %s\n", status(s))
+}
+
+// jsonReporter implements ModeJSON.
+type jsonReporter struct {
+	mu       sync.Mutex
+	enc      *json.Encoder
+	throttle throttle
+}
+
+func (r *jsonReporter) Report(s Snapshot) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !s.Done && !r.throttle.allow(time.Now(), JSONInterval) {
+		return
+	}
+	r.enc.Encode(s)
+}
+
+// status returns the counters of s on one line, e.g., "12 fetched | 30 queued
+// | 1 failed | 1.2 MB | 1.0 pages/s | ETA 0m30s | 0m12s".
+func status(s Snapshot) string {
+	parts := []string{fmt.Sprintf("%d fetched", s.Fetched)}
+	if !s.Done {
+		parts = append(parts, fmt.Sprintf("%d queued", s.Queued))
+	}
+	parts = append(parts, fmt.Sprintf("%d failed", s.Failed))
+	if !s.Done && s.Throttled > 0 {
+		parts = append(parts, fmt.Sprintf("%d throttled", s.Throttled))
+	}
+	parts = append(parts, FormatBytes(s.Bytes), fmt.Sprintf("%.1f pages/s", s.Rate))
+	if !s.Done && s.ETA > 0 {
+		parts = append(parts, "ETA "+formatDuration(s.ETA))
+	}
+	return strings.Join(append(parts, formatDuration(s.Elapsed)), " | ")
+}
+
+// FormatBytes returns n in bytes, KB, MB, or GB (powers of 1024), e.g., "1.5 MB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, prefix := float64(n)/unit, 0
+	for value >= unit && prefix < 2 {
+		value /= unit
+		prefix++
+	}
+	return fmt.Sprintf("%.1f %cB", value, "KMG"[prefix])
+}
+
+// formatDuration returns d rounded to the second as minutes and seconds, e.g.,
+// "2m05s".
+func formatDuration(d time.Duration) string {
+	secs := int(d.Round(time.Second).Seconds())
+	return fmt.Sprintf("%dm%02ds", secs/60, secs%60)
+}
+
+// rateWindow is the number of recent requests Meter computes the rate over.
+const rateWindow = 20
+
+// Meter measures the current request rate of a crawl and the time left. It is
+// not safe for concurrent use.
+type Meter struct {
+	start time.Time
+	// ticks are the times of the last rateWindow requests
+	ticks []time.Time
+}
+
+// NewMeter returns a meter of a crawl started at start.
+func NewMeter(start time.Time) *Meter {
+	return &Meter{start: start}
+}
+
+// Tick records a request settled at now.
+func (m *Meter) Tick(now time.Time) {
+	if len(m.ticks) == rateWindow {
+		m.ticks = m.ticks[1:]
+	}
+	m.ticks = append(m.ticks, now)
+}
+
+// Rate returns the number of requests per second over the last requests: the
+// time since the start for the first ones, then the time since the oldest of
+// the window. It returns 0 before the first request.
+func (m *Meter) Rate(now time.Time) float64 {
+	if len(m.ticks) == 0 {
+		return 0
+	}
+	since, n := m.start, len(m.ticks)
+	if len(m.ticks) == rateWindow {
+		since, n = m.ticks[0], n-1
+	}
+	elapsed := now.Sub(since).Seconds()
+	if elapsed <= 0 || n == 0 {
+		return 0
+	}
+	return float64(n) / elapsed
+}
+
+// Fill sets the Elapsed, Rate, and ETA of s at now, the ETA being the time to
+// request s.Queued more URLs at the current rate.
+func (m *Meter) Fill(s *Snapshot, now time.Time) {
+	s.Elapsed = now.Sub(m.start)
+	s.Rate = m.Rate(now)
+	s.ETA = 0
+	if s.Rate > 0 && s.Queued > 0 {
+		s.ETA = time.Duration(float64(s.Queued) / s.Rate * float64(time.Second))
+	}
+}
@@ -0,0 +1,127 @@
+package progress
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewReporter(t *testing.T) {
+	snapshot := Snapshot{Fetched: 3, Queued: 1, Failed: 1, Skipped: 2, Throttled: 1, Bytes: 2048, Elapsed: 65 * time.Second, Rate: 0.5, ETA: 2 * time.Second, URL: "https://docs.example.com/guide"}
+	done := snapshot
+	done.Queued, done.ETA, done.Done = 0, 0, true
+
+	tests := []struct {
+		mode string
+		// want are substrings of the output of two snapshots and the last one
+		want    []string
+		notWant []string
+	}{
+		{ModeBar, []string{"\r[===============     ] 3 fetched | 1 queued | 1 failed | 1 throttled | 2.0 KB | 0.5 pages/s | ETA 0m02s | 1m05s https://docs.example.com/guide", "\r[====================] 3 fetched | 1 failed", "\n"}, nil},
+		{ModePlain, []string{"Crawling: 3 fetched | 1 queued | 1 failed | 1 throttled | 2.0 KB | 0.5 pages/s | ETA 0m02s | 1m05s\n", "Crawl done: 3 fetched | 1 failed | 2.0 KB | 0.5 pages/s | 1m05s\n"}, []string{"\r"}},
+		{ModeJSON, []string{`{"type":"progress","fetched":3,"queued":1,"failed":1,"skipped":2,"throttled":1,"bytes":2048,"rate":0.5,"url":"https://docs.example.com/guide","elapsed_seconds":65,"eta_seconds":2}` + "\n", `"done":true`}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			var buf bytes.Buffer
+			r, err := NewReporter(tt.mode, &buf)
+			if err != nil {
+				t.Fatalf("NewReporter() error = %v", err)
+			}
+			r.Report(snapshot)
+			// Throttled: reported too soon after the first
+			r.Report(snapshot)
+			r.Report(done)
+
+			out := buf.String()
+			if tt.mode != ModeBar && strings.Count(out, "\n") != 2 {
+				t.Errorf("output has %d lines, want 2 (the second snapshot throttled):\n%s", strings.Count(out, "\n"), out)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(out, w) {
+					t.Errorf("output missing %q:\n%q", w, out)
+				}
+			}
+			for _, w := range tt.notWant {
+				if strings.Contains(out, w) {
+					t.Errorf("output contains %q:\n%q", w, out)
+				}
+			}
+		})
+	}
+
+	if _, err := NewReporter("fancy", &bytes.Buffer{}); err == nil {
+		t.Error("NewReporter() accepted an unknown mode")
+	}
+}
+
+func TestSnapshotJSON(t *testing.T) {
+	data, err := json.Marshal(Snapshot{Fetched: 1, Elapsed: 1500 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["type"] != "progress" || got["elapsed_seconds"] != 1.5 || got["fetched"] != 1.0 {
+		t.Errorf("Marshal() = %s", data)
+	}
+	for _, key := range []string{"eta_seconds", "done", "url", "Elapsed", "ETA"} {
+		if _, ok := got[key]; ok {
+			t.Errorf("Marshal() = %s, want no %s", data, key)
+		}
+	}
+}
+
+func TestMeter(t *testing.T) {
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	m := NewMeter(start)
+	if rate := m.Rate(start.Add(time.Second)); rate != 0 {
+		t.Errorf("Rate() before any request = %v, want 0", rate)
+	}
+
+	// Two requests per second at first, then one
+	now := start
+	for i := 0; i < 10; i++ {
+		now = now.Add(500 * time.Millisecond)
+		m.Tick(now)
+	}
+	if rate := m.Rate(now); rate != 2 {
+		t.Errorf("Rate() = %v, want 2", rate)
+	}
+	for i := 0; i < rateWindow; i++ {
+		now = now.Add(time.Second)
+		m.Tick(now)
+	}
+	if rate := m.Rate(now); rate != 1 {
+		t.Errorf("Rate() over the last requests = %v, want 1", rate)
+	}
+
+	s := Snapshot{Queued: 30}
+	m.Fill(&s, now)
+	if s.Elapsed != now.Sub(start) || s.Rate != 1 || s.ETA != 30*time.Second {
+		t.Errorf("Fill() = %+v, want a 30s ETA at 1 page/s", s)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1536, "1.5 KB"},
+		{5 << 20, "5.0 MB"},
+		{3 << 30, "3.0 GB"},
+		{4 << 40, "4096.0 GB"},
+	}
+	for _, tt := range tests {
+		if got := FormatBytes(tt.n); got != tt.want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Package publish ships generated skills to where they are consumed.
+// This file implements the Google Cloud Storage bucket of gs:// targets,
+// through the JSON API.
+package publish
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// DefaultGCSEndpoint is the endpoint of the Google Cloud Storage JSON API.
+const DefaultGCSEndpoint = "https://storage.googleapis.com"
+
+// GCSConfig holds the credentials and endpoint of Google Cloud Storage.
+type GCSConfig struct {
+	// Endpoint overrides DefaultGCSEndpoint, e.g., for an emulator.
+	Endpoint string
+	// Token is the OAuth 2.0 access token authenticating the requests; when
+	// empty, one is asked from gcloud, unless Endpoint is an emulator's.
+	Token string
+	// Emulator marks Endpoint as an emulator's, which takes no credentials.
+	Emulator bool
+}
+
+// GCSConfigFromEnv reads the environment: GOOGLE_OAUTH_ACCESS_TOKEN for the
+// access token, and STORAGE_EMULATOR_HOST (e.g., "localhost:4443") for an
+// emulator, like the Google Cloud client libraries.
+func GCSConfigFromEnv() GCSConfig {
+	cfg := GCSConfig{Token: os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")}
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		cfg.Endpoint, cfg.Emulator = host, true
+		if !strings.Contains(host, "://") {
+			cfg.Endpoint = "http://" + host
+		}
+	}
+	return cfg
+}
+
+// gcsBucket reads and writes the objects of a Google Cloud Storage bucket.
+type gcsBucket struct {
+	client *http.Client
+	cfg    GCSConfig
+	bucket string
+}
+
+// newGCSBucket returns the bucket named bucket, accessed with client
+// (http.DefaultClient if nil) and cfg.
+func newGCSBucket(client *http.Client, cfg GCSConfig, bucket string) *gcsBucket {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = DefaultGCSEndpoint
+	}
+	cfg.Endpoint = strings.TrimRight(cfg.Endpoint, "/")
+	return &gcsBucket{client: client, cfg: cfg, bucket: bucket}
+}
+
+// Put uploads body as the object key, served with the Content-Type contentType.
+func (b *gcsBucket) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	rawURL := b.cfg.Endpoint + "/upload/storage/v1/b/" + url.PathEscape(b.bucket) + "/o?" +
+		url.Values{"uploadType": {"media"}, "name": {key}}.Encode()
+	resp, err := b.do(ctx, http.MethodPost, rawURL, body, contentType)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return gcsStatusError("upload "+key, resp)
+	}
+	return nil
+}
+
+// Delete removes the object key; deleting a missing object is not an error.
+func (b *gcsBucket) Delete(ctx context.Context, key string) error {
+	rawURL := b.cfg.Endpoint + "/storage/v1/b/" + url.PathEscape(b.bucket) + "/o/" + url.PathEscape(key)
+	resp, err := b.do(ctx, http.MethodDelete, rawURL, nil, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return gcsStatusError("delete "+key, resp)
+	}
+	return nil
+}
+
+// List returns the names of the objects whose name starts with prefix,
+// reading every page of the listing.
+func (b *gcsBucket) List(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	token := ""
+	for {
+		query := url.Values{"prefix": {prefix}, "fields": {"items(name),nextPageToken"}}
+		if token != "" {
+			query.Set("pageToken", token)
+		}
+		rawURL := b.cfg.Endpoint + "/storage/v1/b/" + url.PathEscape(b.bucket) + "/o?" + query.Encode()
+		resp, err := b.do(ctx, http.MethodGet, rawURL, nil, "")
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if resp.StatusCode != http.StatusOK {
+			err = gcsStatusError("list "+b.bucket, resp)
+		} else if decodeErr := json.NewDecoder(resp.Body).Decode(&page); decodeErr != nil {
+			err = fmt.Errorf("failed to parse listing of %s: %w", b.bucket, decodeErr)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range page.Items {
+			names = append(names, item.Name)
+		}
+		if page.NextPageToken == "" {
+			return names, nil
+		}
+		token = page.NextPageToken
+	}
+}
+
+// do sends a request to the JSON API, authenticated with the access token.
+func (b *gcsBucket) do(ctx context.Context, method, rawURL string, body []byte, contentType string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if !b.cfg.Emulator {
+		token, err := b.token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s %s: %w", method, rawURL, err)
+	}
+	return resp, nil
+}
+
+// token returns GCSConfig.Token, or else the access token of the account
+// gcloud is logged in with, which is then kept for the next requests.
+func (b *gcsBucket) token(ctx context.Context) (string, error) {
+	if b.cfg.Token != "" {
+		return b.cfg.Token, nil
+	}
+	if _, err := exec.LookPath("gcloud"); err != nil {
+		return "", fmt.Errorf("no Google Cloud credentials: set GOOGLE_OAUTH_ACCESS_TOKEN or install gcloud")
+	}
+	out, err := exec.CommandContext(ctx, "gcloud", "auth", "print-access-token").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to get an access token from gcloud: %w", err)
+	}
+	b.cfg.Token = strings.TrimSpace(string(out))
+	return b.cfg.Token, nil
+}
+
+// gcsStatusError describes an unexpected response, including the start of its body.
+func gcsStatusError(action string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if msg := strings.TrimSpace(string(body)); msg != "" {
+		return fmt.Errorf("failed to %s: status %d: %s", action, resp.StatusCode, msg)
+	}
+	return fmt.Errorf("failed to %s: status %d", action, resp.StatusCode)
+}
@@ -0,0 +1,211 @@
+// Package publish ships generated skills to where they are consumed.
+// This file implements the git+ targets, which commit the skill directory to
+// a branch of a repository and push it.
+package publish
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultBranch is the branch of git targets naming none.
+const DefaultBranch = "main"
+
+// maxCommitFiles is the number of changed files a commit message lists.
+const maxCommitFiles = 50
+
+// gitPublisher commits skills to branch of the repository at url, under dir.
+type gitPublisher struct {
+	url    string
+	branch string
+	dir    string
+}
+
+// parseGit parses the target "URL[#BRANCH[:DIR]]" of a git publisher.
+func parseGit(target string) (*gitPublisher, error) {
+	repoURL, fragment, _ := strings.Cut(target, "#")
+	if repoURL == "" {
+		return nil, fmt.Errorf("invalid publish target %q: missing repository URL", "git+"+target)
+	}
+	branch, dir, _ := strings.Cut(fragment, ":")
+	if branch == "" {
+		branch = DefaultBranch
+	}
+	if strings.HasPrefix(branch, "-") || strings.ContainsAny(branch, " ~^:?*[\\") {
+		return nil, fmt.Errorf("invalid publish target %q: invalid branch name %q", "git+"+target, branch)
+	}
+	dir = path.Clean("/" + strings.ReplaceAll(dir, "\\", "/"))[1:]
+	return &gitPublisher{url: repoURL, branch: branch, dir: dir}, nil
+}
+
+// String returns the target URL of the publisher.
+func (p *gitPublisher) String() string {
+	s := "git+" + p.url + "#" + p.branch
+	if p.dir != "" {
+		s += ":" + p.dir
+	}
+	return s
+}
+
+// Publish clones the branch of the repository (or starts it if it doesn't
+// exist), replaces the skill's directory with skill.Dir, and commits and
+// pushes the change with a message summarizing the files added, modified, and
+// removed. Nothing is committed when the skill is unchanged.
+func (p *gitPublisher) Publish(ctx context.Context, skill Skill) (string, error) {
+	work, err := os.MkdirTemp("", "site2skill-publish-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create work directory: %w", err)
+	}
+	defer os.RemoveAll(work)
+
+	if _, err := git(ctx, "", "clone", "--quiet", "--no-checkout", "--depth", "1", p.url, work); err != nil {
+		return "", fmt.Errorf("failed to clone %s: %w", p.url, err)
+	}
+	if _, err := git(ctx, work, "fetch", "--quiet", "--depth", "1", "origin", p.branch); err == nil {
+		if _, err := git(ctx, work, "checkout", "--quiet", "-B", p.branch, "FETCH_HEAD"); err != nil {
+			return "", err
+		}
+	} else {
+		if _, err := git(ctx, work, "checkout", "--quiet", "--orphan", p.branch); err != nil {
+			return "", err
+		}
+		if _, err := git(ctx, work, "rm", "-r", "--cached", "--quiet", "--ignore-unmatch", "."); err != nil {
+			return "", err
+		}
+	}
+
+	dir := p.dir
+	if dir == "" {
+		dir = "." + skill.Format + "/skills"
+	}
+	rel := path.Join(dir, skill.Name)
+	dest := filepath.Join(work, filepath.FromSlash(rel))
+	if err := os.RemoveAll(dest); err != nil {
+		return "", fmt.Errorf("failed to remove previous skill: %w", err)
+	}
+	if err := copyDir(skill.Dir, dest); err != nil {
+		return "", err
+	}
+	if _, err := git(ctx, work, "add", "--all", "--", rel); err != nil {
+		return "", err
+	}
+	status, err := git(ctx, work, "diff", "--cached", "--name-status", "--no-renames", "--", rel)
+	if err != nil {
+		return "", err
+	}
+	if status == "" {
+		return fmt.Sprintf("%s unchanged on %s", rel, p.branch), nil
+	}
+
+	args := []string{"commit", "--quiet", "--file", "-"}
+	if email, _ := git(ctx, work, "config", "user.email"); email == "" && os.Getenv("GIT_AUTHOR_EMAIL") == "" {
+		args = append([]string{"-c", "user.name=site2skillgo", "-c", "user.email=site2skillgo@localhost"}, args...)
+	}
+	if _, err := gitInput(ctx, work, commitMessage(skill.Name, rel, status), args...); err != nil {
+		return "", err
+	}
+	if _, err := git(ctx, work, "push", "--quiet", "origin", "HEAD:refs/heads/"+p.branch); err != nil {
+		return "", fmt.Errorf("failed to push to %s: %w", p.url, err)
+	}
+	commit, err := git(ctx, work, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("committed %s to %s as %s", rel, p.branch, commit), nil
+}
+
+// commitMessage returns the message of the commit of the skill name at rel,
+// from the --name-status output status of its changes: a subject counting the
+// files added, modified, and removed, and a body listing them.
+func commitMessage(name, rel, status string) string {
+	var added, modified, removed int
+	var files []string
+	for _, line := range strings.Split(status, "\n") {
+		code, file, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		switch code {
+		case "A":
+			added++
+		case "D":
+			removed++
+		default:
+			modified++
+		}
+		files = append(files, code+" "+strings.TrimPrefix(file, rel+"/"))
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "Update %s skill: %d added, %d modified, %d removed\n\n", name, added, modified, removed)
+	for i, file := range files {
+		if i == maxCommitFiles {
+			fmt.Fprintf(&b, "... and %d more\n", len(files)-maxCommitFiles)
+			break
+		}
+		b.WriteString(file + "\n")
+	}
+	return b.String()
+}
+
+// copyDir copies the regular files under src into dst.
+func copyDir(src, dst string) error {
+	err := filepath.WalkDir(src, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, name)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0644)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy skill: %w", err)
+	}
+	return nil
+}
+
+// git runs git with args in dir and returns its trimmed output. Terminal
+// prompts are disabled, so that missing credentials fail instead of hanging.
+func git(ctx context.Context, dir string, args ...string) (string, error) {
+	return gitInput(ctx, dir, "", args...)
+}
+
+// gitInput is like git, with input on the standard input of git.
+func gitInput(ctx context.Context, dir, input string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Stdin = strings.NewReader(input)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,202 @@
+// Package publish ships generated skills to where they are consumed: it
+// uploads the skill directory and package to an S3 or Google Cloud Storage
+// bucket, or commits the skill directory to a branch of a git repository.
+//
+// Publishers are described by target URLs (see Parse):
+//
+//	s3://my-bucket/skills
+//	gs://my-bucket/skills
+//	git+https://github.com/acme/skills.git#main
+//	git+ssh://git@github.com/acme/skills.git#nightly:skills
+package publish
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/registry"
+)
+
+// Skill is a generated skill to publish.
+type Skill struct {
+	// Name is the skill name, the base name of Dir.
+	Name string
+	// Format is the skill format, "claude" or "codex".
+	Format string
+	// Dir is the skill directory.
+	Dir string
+	// Package is the path of the .skill file; empty if the skill isn't packaged.
+	Package string
+	// Signature is the path of the package's signature; empty if it isn't signed.
+	Signature string
+}
+
+// Publisher publishes skills to one destination.
+type Publisher interface {
+	// Publish publishes skill, replacing the previous version of the skill
+	// at the destination. Returns a description of what was published, e.g.,
+	// the number of objects uploaded or the commit created.
+	Publish(ctx context.Context, skill Skill) (string, error)
+	// String returns the target URL of the publisher, without credentials.
+	String() string
+}
+
+// Parse returns the publisher of the target URL target:
+//
+//   - "s3://BUCKET[/PREFIX]" uploads to an S3 bucket (see registry.S3ConfigFromEnv
+//     for the credentials and endpoint), and "gs://BUCKET[/PREFIX]" to a Google
+//     Cloud Storage bucket (see GCSConfigFromEnv): each skill's files under
+//     PREFIX/FORMAT/NAME/, and its package as PREFIX/FORMAT/NAME.skill, deleting
+//     the objects of files the skill no longer has.
+//   - "git+URL[#BRANCH[:DIR]]" commits each skill's directory as DIR/NAME to
+//     BRANCH (default "main", created if missing) of the repository at URL
+//     (https, ssh, or file), and pushes it. DIR defaults to the skills
+//     directory of the format: .claude/skills or .codex/skills.
+//
+// Returns an error if the target is malformed or its scheme unsupported.
+func Parse(target string) (Publisher, error) {
+	if rest, ok := strings.CutPrefix(target, "git+"); ok {
+		return parseGit(rest)
+	}
+	scheme, rest, ok := strings.Cut(target, "://")
+	if !ok || (scheme != "s3" && scheme != "gs") {
+		return nil, fmt.Errorf("invalid publish target %q: must start with s3://, gs://, or git+", target)
+	}
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("invalid publish target %q: missing bucket", target)
+	}
+	prefix = strings.Trim(prefix, "/")
+	p := &objectPublisher{target: scheme + "://" + path.Join(bucket, prefix), prefix: prefix}
+	if scheme == "s3" {
+		p.bucket = registry.NewS3Bucket(nil, registry.S3ConfigFromEnv(), bucket)
+	} else {
+		p.bucket = newGCSBucket(nil, GCSConfigFromEnv(), bucket)
+	}
+	return p, nil
+}
+
+// bucket is the object storage a publisher uploads to.
+type bucket interface {
+	Put(ctx context.Context, key string, body []byte, contentType string) error
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// objectPublisher publishes skills to the objects of a bucket under prefix.
+type objectPublisher struct {
+	target string
+	prefix string
+	bucket bucket
+}
+
+// String returns the target URL of the publisher.
+func (p *objectPublisher) String() string {
+	return p.target
+}
+
+// Publish uploads the files of the skill's directory, then its package and
+// signature, and deletes the objects of the skill's previous files that are
+// gone.
+func (p *objectPublisher) Publish(ctx context.Context, skill Skill) (string, error) {
+	base := path.Join(p.prefix, skill.Format, skill.Name)
+	files, err := skillFiles(skill.Dir)
+	if err != nil {
+		return "", err
+	}
+	uploaded := make(map[string]bool, len(files))
+	for _, rel := range files {
+		key := base + "/" + rel
+		if err := p.upload(ctx, key, filepath.Join(skill.Dir, filepath.FromSlash(rel))); err != nil {
+			return "", err
+		}
+		uploaded[key] = true
+	}
+	for _, file := range []struct{ path, key string }{
+		{skill.Package, base + ".skill"},
+		{skill.Signature, base + ".skill.sig"},
+	} {
+		if file.path == "" {
+			if err := p.bucket.Delete(ctx, file.key); err != nil {
+				return "", err
+			}
+			continue
+		}
+		if err := p.upload(ctx, file.key, file.path); err != nil {
+			return "", err
+		}
+	}
+
+	existing, err := p.bucket.List(ctx, base+"/")
+	if err != nil {
+		return "", err
+	}
+	deleted := 0
+	for _, key := range existing {
+		if uploaded[key] {
+			continue
+		}
+		if err := p.bucket.Delete(ctx, key); err != nil {
+			return "", err
+		}
+		deleted++
+	}
+	return fmt.Sprintf("uploaded %d files to %s, deleted %d", len(files), p.target+"/"+path.Join(skill.Format, skill.Name), deleted), nil
+}
+
+// upload uploads the file at name as the object key.
+func (p *objectPublisher) upload(ctx context.Context, key, name string) error {
+	body, err := os.ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	return p.bucket.Put(ctx, key, body, contentType(name))
+}
+
+// contentType returns the media type an object is served with, from the
+// extension of its file name.
+func contentType(name string) string {
+	switch ext := strings.ToLower(filepath.Ext(name)); ext {
+	case ".md":
+		return "text/markdown; charset=utf-8"
+	case ".skill":
+		return "application/zip"
+	case ".sig", ".json":
+		return "application/json"
+	default:
+		if t := mime.TypeByExtension(ext); t != "" {
+			return t
+		}
+		return "application/octet-stream"
+	}
+}
+
+// skillFiles returns the slash-separated paths of the regular files under
+// dir, relative to it, in lexical order.
+func skillFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, name)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list skill files: %w", err)
+	}
+	return files, nil
+}
@@ -0,0 +1,253 @@
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		target  string
+		want    string
+		wantErr bool
+	}{
+		{target: "s3://skills", want: "s3://skills"},
+		{target: "s3://skills/team/", want: "s3://skills/team"},
+		{target: "gs://skills/nightly", want: "gs://skills/nightly"},
+		{target: "git+https://github.com/acme/skills.git", want: "git+https://github.com/acme/skills.git#main"},
+		{target: "git+ssh://git@github.com/acme/skills.git#nightly:agents/skills/", want: "git+ssh://git@github.com/acme/skills.git#nightly:agents/skills"},
+		{target: "git+file:///srv/skills.git#main:../outside", want: "git+file:///srv/skills.git#main:outside"},
+		{target: "s3://", wantErr: true},
+		{target: "https://example.com/skills", wantErr: true},
+		{target: "skills", wantErr: true},
+		{target: "git+", wantErr: true},
+		{target: "git+https://github.com/acme/skills.git#-main", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			p, err := Parse(tt.target)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && p.String() != tt.want {
+				t.Errorf("Parse().String() = %q, want %q", p.String(), tt.want)
+			}
+		})
+	}
+}
+
+// writeSkill writes a skill directory with files under dir.
+func writeSkill(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// fakeGCS serves the objects of one bucket through the JSON API.
+type fakeGCS struct {
+	mu      sync.Mutex
+	objects map[string]string
+	types   map[string]string
+}
+
+func (s *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/upload/storage/v1/b/skills/o":
+		body, _ := io.ReadAll(r.Body)
+		name := r.URL.Query().Get("name")
+		s.objects[name] = string(body)
+		s.types[name] = r.Header.Get("Content-Type")
+		json.NewEncoder(w).Encode(map[string]string{"name": name})
+	case r.Method == http.MethodGet && r.URL.Path == "/storage/v1/b/skills/o":
+		// One object per page, to exercise pagination.
+		var names []string
+		for name := range s.objects {
+			if strings.HasPrefix(name, r.URL.Query().Get("prefix")) && name > r.URL.Query().Get("pageToken") {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		page := map[string]any{"items": []map[string]string{}}
+		if len(names) > 0 {
+			page["items"] = []map[string]string{{"name": names[0]}}
+			if len(names) > 1 {
+				page["nextPageToken"] = names[0]
+			}
+		}
+		json.NewEncoder(w).Encode(page)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/storage/v1/b/skills/o/"):
+		name := strings.TrimPrefix(r.URL.Path, "/storage/v1/b/skills/o/")
+		if _, ok := s.objects[name]; !ok {
+			http.NotFound(w, r)
+			return
+		}
+		delete(s.objects, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+	}
+}
+
+func TestObjectPublisherGCS(t *testing.T) {
+	gcs := &fakeGCS{
+		objects: map[string]string{
+			"nightly/claude/stripe/docs/removed.md": "# Removed\n",
+			"nightly/claude/stripe.skill.sig":       "{}",
+			"nightly/claude/other/SKILL.md":         "# Other\n",
+		},
+		types: map[string]string{},
+	}
+	srv := httptest.NewServer(gcs)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	writeSkill(t, filepath.Join(dir, "stripe"), map[string]string{
+		"SKILL.md":            "# Stripe\n",
+		"docs/api/charges.md": "# Charges\n",
+	})
+	writeSkill(t, dir, map[string]string{"stripe.skill": "zip"})
+
+	p := &objectPublisher{
+		target: "gs://skills/nightly",
+		prefix: "nightly",
+		bucket: newGCSBucket(srv.Client(), GCSConfig{Endpoint: srv.URL, Token: "token"}, "skills"),
+	}
+	got, err := p.Publish(context.Background(), Skill{
+		Name:    "stripe",
+		Format:  "claude",
+		Dir:     filepath.Join(dir, "stripe"),
+		Package: filepath.Join(dir, "stripe.skill"),
+	})
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if want := "uploaded 2 files to gs://skills/nightly/claude/stripe, deleted 1"; got != want {
+		t.Errorf("Publish() = %q, want %q", got, want)
+	}
+	want := map[string]string{
+		"nightly/claude/stripe/SKILL.md":            "# Stripe\n",
+		"nightly/claude/stripe/docs/api/charges.md": "# Charges\n",
+		"nightly/claude/stripe.skill":               "zip",
+		"nightly/claude/other/SKILL.md":             "# Other\n",
+	}
+	if !reflect.DeepEqual(gcs.objects, want) {
+		t.Errorf("objects = %v, want %v", gcs.objects, want)
+	}
+	if ct := gcs.types["nightly/claude/stripe/SKILL.md"]; ct != "text/markdown; charset=utf-8" {
+		t.Errorf("Content-Type of SKILL.md = %q", ct)
+	}
+	if ct := gcs.types["nightly/claude/stripe.skill"]; ct != "application/zip" {
+		t.Errorf("Content-Type of the package = %q", ct)
+	}
+}
+
+func TestGCSBucketAuth(t *testing.T) {
+	var auth []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, "access denied")
+	}))
+	defer srv.Close()
+
+	b := newGCSBucket(srv.Client(), GCSConfig{Endpoint: srv.URL, Token: "secret"}, "skills")
+	err := b.Put(context.Background(), "SKILL.md", []byte("# Skill\n"), "text/markdown")
+	if err == nil || !strings.Contains(err.Error(), "status 403: access denied") {
+		t.Errorf("Put() error = %v, want the status and body", err)
+	}
+	b = newGCSBucket(srv.Client(), GCSConfig{Endpoint: srv.URL, Emulator: true}, "skills")
+	b.Delete(context.Background(), "SKILL.md")
+	if want := []string{"Bearer secret", ""}; !reflect.DeepEqual(auth, want) {
+		t.Errorf("Authorization = %q, want %q", auth, want)
+	}
+}
+
+func TestCommitMessage(t *testing.T) {
+	status := "A\t.claude/skills/stripe/docs/new.md\nM\t.claude/skills/stripe/SKILL.md\nD\t.claude/skills/stripe/docs/old.md"
+	want := "Update stripe skill: 1 added, 1 modified, 1 removed\n\nA docs/new.md\nM SKILL.md\nD docs/old.md\n"
+	if got := commitMessage("stripe", ".claude/skills/stripe", status); got != want {
+		t.Errorf("commitMessage() = %q, want %q", got, want)
+	}
+}
+
+// gitOutput runs git with args in dir and returns its output.
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestGitPublisher(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	remote := filepath.Join(t.TempDir(), "skills.git")
+	gitOutput(t, "", "init", "--quiet", "--bare", "--initial-branch", "main", remote)
+
+	skillDir := filepath.Join(t.TempDir(), "stripe")
+	writeSkill(t, skillDir, map[string]string{
+		"SKILL.md":        "# Stripe\n",
+		"docs/charges.md": "# Charges\n",
+	})
+	p, err := Parse("git+file://" + remote + "#nightly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	skill := Skill{Name: "stripe", Format: "claude", Dir: skillDir}
+	ctx := context.Background()
+
+	// The first publish creates the branch.
+	if got, err := p.Publish(ctx, skill); err != nil || !strings.HasPrefix(got, "committed .claude/skills/stripe to nightly as ") {
+		t.Fatalf("Publish() = %q, %v", got, err)
+	}
+	if got := gitOutput(t, remote, "log", "-1", "--format=%s", "nightly"); got != "Update stripe skill: 2 added, 0 modified, 0 removed" {
+		t.Errorf("first commit subject = %q", got)
+	}
+
+	// Publishing the same skill again commits nothing.
+	if got, err := p.Publish(ctx, skill); err != nil || got != ".claude/skills/stripe unchanged on nightly" {
+		t.Errorf("Publish() of an unchanged skill = %q, %v", got, err)
+	}
+
+	writeSkill(t, skillDir, map[string]string{"SKILL.md": "# Stripe API\n", "docs/refunds.md": "# Refunds\n"})
+	os.Remove(filepath.Join(skillDir, "docs", "charges.md"))
+	if _, err := p.Publish(ctx, skill); err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	want := "Update stripe skill: 1 added, 1 modified, 1 removed\n\nM SKILL.md\nD docs/charges.md\nA docs/refunds.md"
+	if got := gitOutput(t, remote, "log", "-1", "--format=%B", "nightly"); got != want {
+		t.Errorf("commit message = %q, want %q", got, want)
+	}
+	if got := gitOutput(t, remote, "rev-list", "--count", "nightly"); got != "2" {
+		t.Errorf("commits = %s, want 2", got)
+	}
+	files := gitOutput(t, remote, "ls-tree", "-r", "--name-only", "nightly")
+	if want := ".claude/skills/stripe/SKILL.md\n.claude/skills/stripe/docs/refunds.md"; files != want {
+		t.Errorf("files = %q, want %q", files, want)
+	}
+}
@@ -0,0 +1,105 @@
+// Package registry stores and retrieves packed .skill files in remote storage.
+// This file implements the object operations on S3 buckets that publishing
+// whole skill directories needs besides Push and Pull.
+package registry
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// S3Bucket reads and writes the objects of one S3 bucket, signed like the
+// requests of the S3 backend.
+type S3Bucket struct {
+	store  *s3Store
+	bucket string
+}
+
+// NewS3Bucket returns the bucket named bucket, accessed with client
+// (http.DefaultClient if nil) and cfg.
+func NewS3Bucket(client *http.Client, cfg S3Config, bucket string) *S3Bucket {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &S3Bucket{store: newS3Store(client, cfg), bucket: bucket}
+}
+
+// Put uploads body as the object key, served with the Content-Type contentType.
+func (b *S3Bucket) Put(ctx context.Context, key string, body []byte, contentType string) error {
+	return b.store.put(ctx, b.bucket, key, body, http.Header{"Content-Type": {contentType}})
+}
+
+// Delete removes the object key; deleting a missing object is not an error.
+func (b *S3Bucket) Delete(ctx context.Context, key string) error {
+	return b.store.delete(ctx, b.bucket, key)
+}
+
+// List returns the keys of the objects whose key starts with prefix, reading
+// every page of the listing.
+func (b *S3Bucket) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		page, err := b.list(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range page.Contents {
+			keys = append(keys, c.Key)
+		}
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// listResult is the response of ListObjectsV2.
+type listResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// list requests one page of the listing of the bucket with query.
+func (b *S3Bucket) list(ctx context.Context, query url.Values) (*listResult, error) {
+	base := b.store.objectURL(b.bucket, "")
+	if b.store.cfg.Endpoint != "" {
+		// Path-style buckets are listed at their path, without a trailing slash
+		base = strings.TrimSuffix(base, "/")
+	}
+	rawURL := base + "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	b.store.cfg.sign(req, nil, b.store.now())
+	resp, err := b.store.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", b.bucket, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("list "+b.bucket, resp)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read listing of %s: %w", b.bucket, err)
+	}
+	var page listResult
+	if err := xml.Unmarshal(data, &page); err != nil {
+		return nil, fmt.Errorf("failed to parse listing of %s: %w", b.bucket, err)
+	}
+	return &page, nil
+}
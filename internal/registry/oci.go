@@ -0,0 +1,388 @@
+// Package registry stores and retrieves packed .skill files in remote storage.
+// This file implements the OCI distribution API backend.
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Media types used for skill artifacts in OCI registries.
+const (
+	// ArtifactType identifies a site2skill skill artifact.
+	ArtifactType = "application/vnd.site2skill.skill.v1"
+	// SkillMediaType is the layer media type of the .skill archive.
+	SkillMediaType = "application/vnd.site2skill.skill.v1+zip"
+	// SignatureMediaType is the layer media type of the detached signature.
+	SignatureMediaType = "application/vnd.site2skill.skill.signature.v1+json"
+
+	manifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	emptyMediaType    = "application/vnd.oci.empty.v1+json"
+	titleAnnotation   = "org.opencontainers.image.title"
+	createdAnnotation = "org.opencontainers.image.created"
+)
+
+// emptyConfig is the OCI empty descriptor content used as the artifact config.
+var emptyConfig = []byte("{}")
+
+// descriptor is an OCI content descriptor.
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// manifest is an OCI image manifest carrying a skill artifact.
+type manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        descriptor        `json:"config"`
+	Layers        []descriptor      `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// layerBlob is the content of one manifest layer.
+type layerBlob struct {
+	data      []byte
+	mediaType string
+	title     string
+}
+
+// ociStore pushes skills to OCI registries using the distribution API.
+type ociStore struct {
+	client   *http.Client
+	insecure bool
+	username string
+	password string
+	// token is the bearer token obtained from the registry's auth service, if any
+	token string
+	// useBasic is set once the registry asked for basic auth
+	useBasic bool
+}
+
+// newOCIStore creates an OCI store.
+func newOCIStore(client *http.Client, opts Options) *ociStore {
+	return &ociStore{
+		client:   client,
+		insecure: opts.Insecure,
+		username: opts.Username,
+		password: opts.Password,
+	}
+}
+
+// Push uploads the archive (and signature) as blobs, then tags a manifest referencing them.
+func (s *ociStore) Push(ctx context.Context, ref Ref, archivePath string) (string, error) {
+	archive, sig, err := readArtifacts(archivePath)
+	if err != nil {
+		return "", err
+	}
+
+	m := manifest{
+		SchemaVersion: 2,
+		MediaType:     manifestMediaType,
+		ArtifactType:  ArtifactType,
+		Config:        descriptor{MediaType: emptyMediaType, Digest: digestOf(emptyConfig), Size: int64(len(emptyConfig))},
+		Annotations:   map[string]string{createdAnnotation: time.Now().UTC().Format(time.RFC3339)},
+	}
+
+	blobs := []layerBlob{{archive, SkillMediaType, ref.Name() + ".skill"}}
+	if sig != nil {
+		blobs = append(blobs, layerBlob{sig, SignatureMediaType, ref.Name() + ".skill.sig"})
+	}
+
+	if err := s.pushBlob(ctx, ref, emptyConfig); err != nil {
+		return "", err
+	}
+	for _, b := range blobs {
+		if err := s.pushBlob(ctx, ref, b.data); err != nil {
+			return "", err
+		}
+		m.Layers = append(m.Layers, descriptor{
+			MediaType:   b.mediaType,
+			Digest:      digestOf(b.data),
+			Size:        int64(len(b.data)),
+			Annotations: map[string]string{titleAnnotation: b.title},
+		})
+	}
+
+	body, err := json.Marshal(m)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	header := http.Header{"Content-Type": {manifestMediaType}}
+	resp, err := s.do(ctx, ref, http.MethodPut, s.endpoint(ref, "manifests/"+ref.Reference), body, header)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", statusError("push manifest", resp)
+	}
+
+	return digestOf(archive), nil
+}
+
+// pushBlob uploads data unless the registry already has it.
+func (s *ociStore) pushBlob(ctx context.Context, ref Ref, data []byte) error {
+	digest := digestOf(data)
+
+	resp, err := s.do(ctx, ref, http.MethodHead, s.endpoint(ref, "blobs/"+digest), nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	resp, err = s.do(ctx, ref, http.MethodPost, s.endpoint(ref, "blobs/uploads/"), nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return statusError("start blob upload", resp)
+	}
+
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.Header.Get("Location") == "" {
+		return fmt.Errorf("failed to start blob upload: registry returned no upload location")
+	}
+	q := location.Query()
+	q.Set("digest", digest)
+	location.RawQuery = q.Encode()
+
+	header := http.Header{"Content-Type": {"application/octet-stream"}}
+	resp, err = s.do(ctx, ref, http.MethodPut, location.String(), data, header)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return statusError("upload blob", resp)
+	}
+	return nil
+}
+
+// Pull fetches the manifest for ref and downloads its skill and signature layers.
+func (s *ociStore) Pull(ctx context.Context, ref Ref, destDir string) (string, error) {
+	header := http.Header{"Accept": {manifestMediaType}}
+	resp, err := s.do(ctx, ref, http.MethodGet, s.endpoint(ref, "manifests/"+ref.Reference), nil, header)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", statusError("fetch manifest", resp)
+	}
+
+	var m manifest
+	if err := json.NewDecoder(resp.Body).Decode(&m); err != nil {
+		return "", fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	if m.ArtifactType != ArtifactType {
+		return "", fmt.Errorf("%s is not a skill artifact (artifact type %q)", ref, m.ArtifactType)
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	archivePath := filepath.Join(destDir, ref.Name()+".skill")
+	// A stale signature from an earlier pull must not be mistaken for this artifact's
+	os.Remove(archivePath + ".sig")
+
+	found := false
+	for _, layer := range m.Layers {
+		var target string
+		switch layer.MediaType {
+		case SkillMediaType:
+			target, found = archivePath, true
+		case SignatureMediaType:
+			target = archivePath + ".sig"
+		default:
+			continue
+		}
+
+		data, err := s.fetchBlob(ctx, ref, layer.Digest)
+		if err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(target, data, 0644); err != nil {
+			return "", fmt.Errorf("failed to write %s: %w", target, err)
+		}
+	}
+	if !found {
+		return "", fmt.Errorf("%s has no skill layer", ref)
+	}
+
+	return archivePath, nil
+}
+
+// fetchBlob downloads a blob and checks it against its digest.
+func (s *ociStore) fetchBlob(ctx context.Context, ref Ref, digest string) ([]byte, error) {
+	resp, err := s.do(ctx, ref, http.MethodGet, s.endpoint(ref, "blobs/"+digest), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, statusError("fetch blob", resp)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+	if got := digestOf(data); got != digest {
+		return nil, fmt.Errorf("blob digest mismatch: got %s, want %s", got, digest)
+	}
+	return data, nil
+}
+
+// endpoint returns the distribution API URL for a path under the repository.
+func (s *ociStore) endpoint(ref Ref, p string) string {
+	scheme := "https"
+	if s.insecure {
+		scheme = "http"
+	}
+	return scheme + "://" + ref.Host + "/v2/" + ref.Repository + "/" + p
+}
+
+// do sends a request, answering a 401 challenge (basic or bearer token) once.
+func (s *ociStore) do(ctx context.Context, ref Ref, method, rawURL string, body []byte, header http.Header) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		switch {
+		case s.token != "":
+			req.Header.Set("Authorization", "Bearer "+s.token)
+		case s.useBasic:
+			req.SetBasicAuth(s.username, s.password)
+		}
+		resp, err := s.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to %s %s: %w", method, rawURL, err)
+		}
+		return resp, nil
+	}
+
+	resp, err := send()
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	resp.Body.Close()
+
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if s.username == "" {
+			return nil, fmt.Errorf("registry %s requires credentials", ref.Host)
+		}
+		s.useBasic = true
+	case "bearer":
+		if params["scope"] == "" {
+			params["scope"] = "repository:" + ref.Repository + ":pull,push"
+		}
+		if err := s.fetchToken(ctx, params); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("registry %s returned an unsupported auth challenge: %q", ref.Host, challenge)
+	}
+
+	return send()
+}
+
+// fetchToken exchanges credentials for a bearer token at the challenge's realm.
+func (s *ociStore) fetchToken(ctx context.Context, params map[string]string) error {
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return fmt.Errorf("registry returned an invalid token realm: %q", params["realm"])
+	}
+	q := realm.Query()
+	for _, k := range []string{"service", "scope"} {
+		if params[k] != "" {
+			q.Set(k, params[k])
+		}
+	}
+	realm.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create token request: %w", err)
+	}
+	if s.username != "" {
+		req.SetBasicAuth(s.username, s.password)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError("fetch registry token", resp)
+	}
+
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tok); err != nil {
+		return fmt.Errorf("failed to decode registry token: %w", err)
+	}
+	s.token = tok.Token
+	if s.token == "" {
+		s.token = tok.AccessToken
+	}
+	if s.token == "" {
+		return fmt.Errorf("registry token response contained no token")
+	}
+	return nil
+}
+
+// parseChallenge splits a WWW-Authenticate header into its scheme and parameters,
+// e.g. `Bearer realm="https://auth.example.com/token",service="registry"`.
+func parseChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, ", "), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key = strings.TrimSpace(key); key != "" {
+			params[strings.ToLower(key)] = value
+		}
+	}
+	return scheme, params
+}
+
+// statusError describes an unexpected response, including the start of its body.
+func statusError(action string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	if msg := strings.TrimSpace(string(body)); msg != "" {
+		return fmt.Errorf("failed to %s: status %d: %s", action, resp.StatusCode, msg)
+	}
+	return fmt.Errorf("failed to %s: status %d", action, resp.StatusCode)
+}
@@ -0,0 +1,156 @@
+// Package registry stores and retrieves packed .skill files in remote storage,
+// so skills can be versioned and distributed with infrastructure teams already run.
+// Two backends are supported: OCI registries (skills are pushed as OCI artifacts)
+// and S3-compatible object storage.
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// Ref identifies a skill in remote storage.
+//
+// References are written as URLs:
+//
+//	oci://registry.example.com/team/skills/myskill:1.2.0
+//	oci://registry.example.com/team/skills/myskill@sha256:...
+//	s3://my-bucket/skills/myskill:1.2.0
+//
+// The tag defaults to "latest" when omitted.
+type Ref struct {
+	// Scheme is "oci" or "s3".
+	Scheme string
+	// Host is the registry host (oci) or the bucket name (s3).
+	Host string
+	// Repository is the repository path (oci) or the key prefix (s3), without leading slash.
+	Repository string
+	// Reference is the tag, or a "sha256:..." digest for OCI pulls.
+	Reference string
+}
+
+// ParseRef parses a skill reference. See Ref for the accepted forms.
+func ParseRef(s string) (Ref, error) {
+	scheme, rest, ok := strings.Cut(s, "://")
+	if !ok || (scheme != "oci" && scheme != "s3") {
+		return Ref{}, fmt.Errorf("invalid reference %q: must start with oci:// or s3://", s)
+	}
+
+	host, repo, _ := strings.Cut(rest, "/")
+	if host == "" || repo == "" {
+		return Ref{}, fmt.Errorf("invalid reference %q: missing host or repository", s)
+	}
+
+	ref := Ref{Scheme: scheme, Host: host, Reference: "latest"}
+	if i := strings.Index(repo, "@"); i >= 0 {
+		ref.Reference = repo[i+1:]
+		repo = repo[:i]
+	} else if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		ref.Reference = repo[i+1:]
+		repo = repo[:i]
+	}
+	ref.Repository = strings.Trim(repo, "/")
+
+	if ref.Repository == "" || ref.Reference == "" {
+		return Ref{}, fmt.Errorf("invalid reference %q: missing repository or tag", s)
+	}
+	return ref, nil
+}
+
+// Name returns the skill name, i.e. the last element of the repository path.
+func (r Ref) Name() string {
+	return path.Base(r.Repository)
+}
+
+// WithReference returns a copy of r pointing at a different tag or digest.
+func (r Ref) WithReference(reference string) Ref {
+	r.Reference = reference
+	return r
+}
+
+// String formats r in the form accepted by ParseRef.
+func (r Ref) String() string {
+	sep := ":"
+	if strings.HasPrefix(r.Reference, "sha256:") {
+		sep = "@"
+	}
+	return r.Scheme + "://" + r.Host + "/" + r.Repository + sep + r.Reference
+}
+
+// Store pushes and pulls packed skills.
+type Store interface {
+	// Push uploads the .skill file at archivePath, and its detached signature
+	// (archivePath + ".sig") when present, under ref.
+	// Returns the SHA-256 digest of the archive ("sha256:<hex>").
+	Push(ctx context.Context, ref Ref, archivePath string) (string, error)
+	// Pull downloads the skill at ref into destDir as <name>.skill, along with its
+	// signature when one was pushed.
+	// Returns the path of the downloaded .skill file.
+	Pull(ctx context.Context, ref Ref, destDir string) (string, error)
+}
+
+// Options configures access to remote storage.
+type Options struct {
+	// Client is the HTTP client used for all requests; nil means http.DefaultClient.
+	Client *http.Client
+	// Insecure uses plain HTTP instead of HTTPS for OCI registries (e.g., a local registry).
+	Insecure bool
+	// Username and Password authenticate to OCI registries (basic auth or token exchange).
+	Username string
+	Password string
+	// S3 holds credentials and endpoint settings for the S3 backend.
+	S3 S3Config
+}
+
+// OptionsFromEnv returns Options populated from the environment:
+// SITE2SKILL_REGISTRY_USERNAME and SITE2SKILL_REGISTRY_PASSWORD for OCI registries,
+// and the standard AWS_* variables for S3 (see S3ConfigFromEnv).
+func OptionsFromEnv() Options {
+	return Options{
+		Username: os.Getenv("SITE2SKILL_REGISTRY_USERNAME"),
+		Password: os.Getenv("SITE2SKILL_REGISTRY_PASSWORD"),
+		S3:       S3ConfigFromEnv(),
+	}
+}
+
+// New returns the Store that serves ref's scheme.
+func New(ref Ref, opts Options) (Store, error) {
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	switch ref.Scheme {
+	case "oci":
+		return newOCIStore(client, opts), nil
+	case "s3":
+		return newS3Store(client, opts.S3), nil
+	default:
+		return nil, fmt.Errorf("unsupported registry scheme: %s", ref.Scheme)
+	}
+}
+
+// digestOf returns the OCI-style SHA-256 digest of data.
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// readArtifacts reads the archive and, if present, its detached signature.
+func readArtifacts(archivePath string) (archive, sig []byte, err error) {
+	archive, err = os.ReadFile(archivePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read skill file: %w", err)
+	}
+	sig, err = os.ReadFile(archivePath + ".sig")
+	if err != nil && !os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("failed to read signature: %w", err)
+	}
+	return archive, sig, nil
+}
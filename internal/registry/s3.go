@@ -0,0 +1,345 @@
+// Package registry stores and retrieves packed .skill files in remote storage.
+// This file implements the S3 backend with AWS Signature Version 4 request signing.
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// digestMetaHeader stores the archive digest as S3 object metadata so pulls can verify it.
+const digestMetaHeader = "X-Amz-Meta-Sha256"
+
+// S3Config holds credentials and endpoint settings for S3-compatible storage.
+type S3Config struct {
+	// Region is the bucket region used for request signing (e.g., "us-east-1").
+	Region string
+	// AccessKeyID and SecretAccessKey sign requests; when empty, requests are sent unsigned.
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is the token for temporary credentials, if any.
+	SessionToken string
+	// Endpoint overrides the AWS endpoint (e.g., "http://localhost:9000" for MinIO).
+	// Custom endpoints are addressed path-style: <endpoint>/<bucket>/<key>.
+	Endpoint string
+}
+
+// S3ConfigFromEnv reads the standard AWS environment variables: AWS_REGION
+// (or AWS_DEFAULT_REGION), AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+// AWS_SESSION_TOKEN, and AWS_ENDPOINT_URL_S3 (or AWS_ENDPOINT_URL).
+func S3ConfigFromEnv() S3Config {
+	cfg := S3Config{
+		Region:          firstEnv("AWS_REGION", "AWS_DEFAULT_REGION"),
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		Endpoint:        firstEnv("AWS_ENDPOINT_URL_S3", "AWS_ENDPOINT_URL"),
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return cfg
+}
+
+// firstEnv returns the first non-empty environment variable among names.
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if v := os.Getenv(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// s3Store keeps each tag as an object: <prefix>/<tag>.skill, plus <prefix>/<tag>.skill.sig
+// when the skill is signed.
+type s3Store struct {
+	client *http.Client
+	cfg    S3Config
+	now    func() time.Time
+}
+
+// newS3Store creates an S3 store.
+func newS3Store(client *http.Client, cfg S3Config) *s3Store {
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return &s3Store{client: client, cfg: cfg, now: time.Now}
+}
+
+// Push uploads the archive and its signature as objects named after the tag.
+func (s *s3Store) Push(ctx context.Context, ref Ref, archivePath string) (string, error) {
+	if err := checkS3Ref(ref); err != nil {
+		return "", err
+	}
+	archive, sig, err := readArtifacts(archivePath)
+	if err != nil {
+		return "", err
+	}
+
+	digest := digestOf(archive)
+	key := s3Key(ref)
+	header := http.Header{"Content-Type": {"application/zip"}, digestMetaHeader: {digest}}
+	if err := s.put(ctx, ref.Host, key, archive, header); err != nil {
+		return "", err
+	}
+
+	if sig != nil {
+		header := http.Header{"Content-Type": {"application/json"}}
+		if err := s.put(ctx, ref.Host, key+".sig", sig, header); err != nil {
+			return "", err
+		}
+	} else if err := s.delete(ctx, ref.Host, key+".sig"); err != nil {
+		return "", err
+	}
+
+	return digest, nil
+}
+
+// Pull downloads the tag's objects and verifies the archive against its stored digest.
+func (s *s3Store) Pull(ctx context.Context, ref Ref, destDir string) (string, error) {
+	if err := checkS3Ref(ref); err != nil {
+		return "", err
+	}
+	key := s3Key(ref)
+
+	archive, header, err := s.get(ctx, ref.Host, key)
+	if err != nil {
+		return "", err
+	}
+	if archive == nil {
+		return "", fmt.Errorf("%s not found", ref)
+	}
+	if want := header.Get(digestMetaHeader); want != "" && digestOf(archive) != want {
+		return "", fmt.Errorf("object digest mismatch: got %s, want %s", digestOf(archive), want)
+	}
+
+	sig, _, err := s.get(ctx, ref.Host, key+".sig")
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+	archivePath := filepath.Join(destDir, ref.Name()+".skill")
+	if err := os.WriteFile(archivePath, archive, 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", archivePath, err)
+	}
+	os.Remove(archivePath + ".sig")
+	if sig != nil {
+		if err := os.WriteFile(archivePath+".sig", sig, 0644); err != nil {
+			return "", fmt.Errorf("failed to write signature: %w", err)
+		}
+	}
+
+	return archivePath, nil
+}
+
+// checkS3Ref rejects references S3 cannot address.
+func checkS3Ref(ref Ref) error {
+	if strings.HasPrefix(ref.Reference, "sha256:") {
+		return fmt.Errorf("s3 references must use a tag, not a digest: %s", ref)
+	}
+	return nil
+}
+
+// s3Key returns the object key of the archive for ref.
+func s3Key(ref Ref) string {
+	return ref.Repository + "/" + ref.Reference + ".skill"
+}
+
+// put uploads an object.
+func (s *s3Store) put(ctx context.Context, bucket, key string, body []byte, header http.Header) error {
+	resp, err := s.do(ctx, http.MethodPut, bucket, key, body, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return statusError("upload "+key, resp)
+	}
+	return nil
+}
+
+// get downloads an object. A missing object yields nil data and no error.
+func (s *s3Store) get(ctx context.Context, bucket, key string) ([]byte, http.Header, error) {
+	resp, err := s.do(ctx, http.MethodGet, bucket, key, nil, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, resp.Header, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, statusError("download "+key, resp)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return data, resp.Header, nil
+}
+
+// delete removes an object; deleting a missing object is not an error.
+func (s *s3Store) delete(ctx context.Context, bucket, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, bucket, key, nil, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNotFound {
+		return statusError("delete "+key, resp)
+	}
+	return nil
+}
+
+// do builds, signs, and sends a request for an object.
+func (s *s3Store) do(ctx context.Context, method, bucket, key string, body []byte, header http.Header) (*http.Response, error) {
+	rawURL := s.objectURL(bucket, key)
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	s.cfg.sign(req, body, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to %s %s: %w", method, rawURL, err)
+	}
+	return resp, nil
+}
+
+// objectURL returns the URL of an object, virtual-hosted style on AWS and
+// path style on custom endpoints.
+func (s *s3Store) objectURL(bucket, key string) string {
+	escapedKey := s3Escape(key, false)
+	if s.cfg.Endpoint != "" {
+		return strings.TrimRight(s.cfg.Endpoint, "/") + "/" + s3Escape(bucket, true) + "/" + escapedKey
+	}
+	return "https://" + bucket + ".s3." + s.cfg.Region + ".amazonaws.com/" + escapedKey
+}
+
+// sign adds AWS Signature Version 4 headers to req. Requests are left unsigned
+// when no credentials are configured (public buckets).
+func (c S3Config) sign(req *http.Request, body []byte, now time.Time) {
+	if c.AccessKeyID == "" {
+		return
+	}
+
+	payloadHash := sha256.Sum256(body)
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "content-type" || lower == "content-md5" || lower == "range" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope := date + "/" + c.Region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+c.SecretAccessKey), date)
+	key = hmacSHA256(key, c.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// canonicalQuery encodes query parameters sorted by key, as SigV4 requires.
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, k := range keys {
+		vs := append([]string(nil), values[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			parts = append(parts, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// s3Escape percent-encodes s as SigV4 specifies: everything except unreserved
+// characters, and "/" too when encodeSlash is set.
+func s3Escape(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// hmacSHA256 computes HMAC-SHA256(key, data).
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,363 @@
+// Package repo reads the documentation of a GitHub or GitLab repository from
+// its sources instead of its rendered website: the README, the Markdown files
+// under docs/, and the pages of the wiki. The Markdown of a repository is
+// better input than the HTML GitHub and GitLab wrap it in, whose conversion
+// back to Markdown loses detail.
+//
+// Repositories are shallow-cloned with git, which must be installed, so that
+// private repositories work with the credentials git already has, or with an
+// Authorization header (see Clone).
+package repo
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Hosts of a Repo.
+const (
+	// GitHub is github.com or a GitHub Enterprise server.
+	GitHub = "github"
+	// GitLab is gitlab.com or a self-managed GitLab.
+	GitLab = "gitlab"
+)
+
+// ErrNotRepository is returned by Parse for a URL that isn't the URL of a
+// GitHub or GitLab repository.
+var ErrNotRepository = errors.New("not a GitHub or GitLab repository URL")
+
+// Repo is a GitHub or GitLab repository.
+type Repo struct {
+	// Host is GitHub or GitLab.
+	Host string
+	// URL is the web URL of the repository, e.g.,
+	// "https://github.com/acme/widgets".
+	URL string
+	// Ref is the branch or tag to read, from a "/tree/<ref>" URL; empty for
+	// the default branch.
+	Ref string
+	// CloneURL is the URL the repository is cloned from; URL + ".git" unless
+	// set otherwise.
+	CloneURL string
+	// WikiCloneURL is the URL the wiki is cloned from; URL + ".wiki.git"
+	// unless set otherwise, and empty to skip the wiki.
+	WikiCloneURL string
+}
+
+// Parse returns the repository at rawURL: a GitHub repository on a host
+// named github.com or github.<domain>, or a GitLab repository on a host whose
+// name contains "gitlab", e.g., "https://github.com/acme/widgets",
+// "https://gitlab.com/acme/tools/widgets.git", or
+// "https://github.com/acme/widgets/tree/v2". A "/tree/<ref>" suffix selects
+// a branch or tag; a ref containing a slash isn't supported.
+//
+// Returns an error wrapping ErrNotRepository if rawURL isn't such a URL.
+func Parse(rawURL string) (*Repo, error) {
+	if !strings.Contains(rawURL, "://") {
+		rawURL = "https://" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotRepository, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("%w: %s", ErrNotRepository, rawURL)
+	}
+	host := strings.ToLower(u.Hostname())
+	r := &Repo{}
+	switch {
+	case host == "github.com" || strings.HasPrefix(host, "github."):
+		r.Host = GitHub
+	case strings.Contains(host, "gitlab"):
+		r.Host = GitLab
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrNotRepository, rawURL)
+	}
+
+	segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if r.Host == GitHub && len(segments) > 2 {
+		segments, r.Ref = segments[:2], refOf(segments[2:])
+	}
+	// GitLab groups nest, so only "/-/" ends the project path
+	for i, s := range segments {
+		if r.Host == GitLab && s == "-" {
+			segments, r.Ref = segments[:i], refOf(segments[i+1:])
+			break
+		}
+	}
+	if len(segments) < 2 || segments[0] == "" || segments[len(segments)-1] == "" {
+		return nil, fmt.Errorf("%w: %s", ErrNotRepository, rawURL)
+	}
+	segments[len(segments)-1] = strings.TrimSuffix(segments[len(segments)-1], ".git")
+
+	r.URL = u.Scheme + "://" + u.Host + "/" + strings.Join(segments, "/")
+	r.CloneURL = r.URL + ".git"
+	r.WikiCloneURL = r.URL + ".wiki.git"
+	return r, nil
+}
+
+// refOf returns the ref of the path segments following the project path of a
+// repository URL, e.g., "v2" for ["tree", "v2"]; empty if they don't name one.
+func refOf(segments []string) string {
+	if len(segments) >= 2 && segments[0] == "tree" {
+		return segments[1]
+	}
+	return ""
+}
+
+// Kinds of File.
+const (
+	// KindReadme is the README at the root of the repository.
+	KindReadme = "readme"
+	// KindDocs is a Markdown file under docs/.
+	KindDocs = "docs"
+	// KindWiki is a page of the wiki.
+	KindWiki = "wiki"
+)
+
+// File is a Markdown file of a repository or its wiki.
+type File struct {
+	// Kind is KindReadme, KindDocs, or KindWiki.
+	Kind string
+	// Path is the slash-separated path of the file in the repository or the
+	// wiki, e.g., "docs/guide/install.md".
+	Path string
+	// Local is the path of the file in the checkout.
+	Local string
+	// URL is the web URL of the file, e.g.,
+	// "https://github.com/acme/widgets/blob/main/docs/guide/install.md", or
+	// "https://github.com/acme/widgets/wiki/Install" for a wiki page.
+	URL string
+}
+
+// Checkout is a shallow clone of a repository and its wiki.
+type Checkout struct {
+	// Repo is the repository cloned.
+	Repo *Repo
+	// Dir is the directory of the clone of the repository.
+	Dir string
+	// WikiDir is the directory of the clone of the wiki; empty if the
+	// repository has no wiki or it couldn't be cloned.
+	WikiDir string
+	// Ref is the branch or tag checked out.
+	Ref string
+	// Commit is the ID of the commit checked out.
+	Commit string
+}
+
+// Clone shallow-clones r into dir/repo and its wiki into dir/wiki, replacing
+// any earlier clone. header holds extra HTTP headers sent to the server,
+// e.g., an Authorization header for a private repository; they are passed to
+// git through its environment, never on its command line.
+//
+// A wiki that doesn't exist or can't be cloned is skipped, since most
+// repositories have none; WikiDir is empty then.
+//
+// Returns an error if git isn't installed or the repository can't be cloned.
+func Clone(ctx context.Context, r *Repo, dir string, header http.Header) (*Checkout, error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, fmt.Errorf("failed to find git, which reading a repository requires: %w", err)
+	}
+	c := &Checkout{Repo: r, Dir: filepath.Join(dir, "repo")}
+	if err := clone(ctx, r.CloneURL, r.Ref, c.Dir, header); err != nil {
+		return nil, fmt.Errorf("failed to clone %s: %w", r.URL, err)
+	}
+
+	c.Ref = r.Ref
+	if c.Ref == "" {
+		branch, err := git(ctx, c.Dir, nil, "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		c.Ref = branch
+	}
+	commit, err := git(ctx, c.Dir, nil, "rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	c.Commit = commit
+
+	if r.WikiCloneURL != "" {
+		wikiDir := filepath.Join(dir, "wiki")
+		if err := clone(ctx, r.WikiCloneURL, "", wikiDir, header); err == nil {
+			c.WikiDir = wikiDir
+		} else if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+	}
+	return c, nil
+}
+
+// clone shallow-clones the repository at cloneURL, at ref if not empty, into
+// dir.
+func clone(ctx context.Context, cloneURL, ref, dir string, header http.Header) error {
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", dir, err)
+	}
+	args := []string{"clone", "--quiet", "--depth", "1", "--single-branch"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	args = append(args, "--", cloneURL, dir)
+	_, err := git(ctx, "", header, args...)
+	return err
+}
+
+// git runs git with args in dir (the current directory if empty), with the
+// headers of header sent with its HTTP requests, and returns its trimmed
+// output. Terminal prompts are disabled, so that missing credentials fail
+// instead of hanging.
+func git(ctx context.Context, dir string, header http.Header, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	n := 0
+	for name, values := range header {
+		for _, value := range values {
+			cmd.Env = append(cmd.Env,
+				"GIT_CONFIG_KEY_"+strconv.Itoa(n)+"=http.extraHeader",
+				"GIT_CONFIG_VALUE_"+strconv.Itoa(n)+"="+name+": "+value)
+			n++
+		}
+	}
+	if n > 0 {
+		cmd.Env = append(cmd.Env, "GIT_CONFIG_COUNT="+strconv.Itoa(n))
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Files lists the Markdown files of the checkout: the README at the root of
+// the repository, the files under docs/ (recursively, skipping hidden
+// directories), and the pages of the wiki but its sidebar and footer, in that
+// order and sorted by path.
+// Markdown files are those ending in .md or .markdown, in any case.
+func (c *Checkout) Files() ([]File, error) {
+	var files []File
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", c.Dir, err)
+	}
+	for _, e := range entries {
+		if !e.IsDir() && isMarkdown(e.Name()) && strings.EqualFold(strings.TrimSuffix(e.Name(), path.Ext(e.Name())), "readme") {
+			files = append(files, c.file(KindReadme, e.Name()))
+			break
+		}
+	}
+
+	docs, err := markdownFiles(filepath.Join(c.Dir, "docs"))
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range docs {
+		files = append(files, c.file(KindDocs, "docs/"+p))
+	}
+
+	if c.WikiDir != "" {
+		pages, err := markdownFiles(c.WikiDir)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range pages {
+			// _Sidebar.md and _Footer.md frame the pages of GitHub wikis
+			if !strings.HasPrefix(path.Base(p), "_") {
+				files = append(files, c.file(KindWiki, p))
+			}
+		}
+	}
+	return files, nil
+}
+
+// file returns the File of kind at p, a path in the repository or the wiki.
+func (c *Checkout) file(kind, p string) File {
+	f := File{Kind: kind, Path: p, Local: filepath.Join(c.Dir, filepath.FromSlash(p))}
+	if kind == KindWiki {
+		f.Local = filepath.Join(c.WikiDir, filepath.FromSlash(p))
+		page := strings.TrimSuffix(p, path.Ext(p))
+		if c.Repo.Host == GitLab {
+			f.URL = c.Repo.URL + "/-/wikis/" + pathEscape(page)
+		} else {
+			// GitHub wikis are flat: the page name is the file name
+			f.URL = c.Repo.URL + "/wiki/" + pathEscape(path.Base(page))
+		}
+		return f
+	}
+	blob := "/blob/"
+	if c.Repo.Host == GitLab {
+		blob = "/-/blob/"
+	}
+	f.URL = c.Repo.URL + blob + pathEscape(c.Ref) + "/" + pathEscape(p)
+	return f
+}
+
+// pathEscape escapes every segment of the slash-separated path p.
+func pathEscape(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// markdownFiles lists the Markdown files under dir recursively, as sorted
+// slash-separated paths relative to dir, skipping hidden files and
+// directories. A missing dir has none.
+func markdownFiles(dir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) && p == dir {
+				return fs.SkipAll
+			}
+			return err
+		}
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() && isMarkdown(d.Name()) {
+			rel, err := filepath.Rel(dir, p)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the Markdown files of %s: %w", dir, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// isMarkdown reports whether name is the name of a Markdown file.
+func isMarkdown(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".md" || ext == ".markdown"
+}
@@ -0,0 +1,142 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		rawURL string
+		want   *Repo
+	}{
+		{"https://github.com/acme/widgets", &Repo{Host: GitHub, URL: "https://github.com/acme/widgets"}},
+		{"github.com/acme/widgets.git", &Repo{Host: GitHub, URL: "https://github.com/acme/widgets"}},
+		{"https://github.com/acme/widgets/tree/v2", &Repo{Host: GitHub, URL: "https://github.com/acme/widgets", Ref: "v2"}},
+		{"https://github.com/acme/widgets/blob/main/README.md", &Repo{Host: GitHub, URL: "https://github.com/acme/widgets"}},
+		{"https://github.example.com/acme/widgets/", &Repo{Host: GitHub, URL: "https://github.example.com/acme/widgets"}},
+		{"https://gitlab.com/acme/tools/widgets", &Repo{Host: GitLab, URL: "https://gitlab.com/acme/tools/widgets"}},
+		{"https://gitlab.example.com/acme/widgets/-/tree/develop", &Repo{Host: GitLab, URL: "https://gitlab.example.com/acme/widgets", Ref: "develop"}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.rawURL)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.rawURL, err)
+			continue
+		}
+		tt.want.CloneURL = tt.want.URL + ".git"
+		tt.want.WikiCloneURL = tt.want.URL + ".wiki.git"
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.rawURL, got, tt.want)
+		}
+	}
+
+	for _, rawURL := range []string{
+		"https://docs.example.com/guide/",
+		"https://github.com/acme",
+		"ssh://github.com/acme/widgets",
+	} {
+		if _, err := Parse(rawURL); !errors.Is(err, ErrNotRepository) {
+			t.Errorf("Parse(%q) error = %v, want ErrNotRepository", rawURL, err)
+		}
+	}
+}
+
+// initRepo creates a git repository in dir with files, committed on branch main.
+func initRepo(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch", "main"},
+		{"add", "-A"},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "docs"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+}
+
+func TestCloneFiles(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	src := t.TempDir()
+	initRepo(t, filepath.Join(src, "widgets"), map[string]string{
+		"README.md":              "# Widgets\n",
+		"main.go":                "package main\n",
+		"docs/install.md":        "# Install\n",
+		"docs/guide/Usage.MD":    "# Usage\n",
+		"docs/.drafts/wip.md":    "# WIP\n",
+		"docs/images/logo.png":   "png",
+		"notes/changelog.md":     "# Changelog\n",
+		"docs/api/reference.txt": "text",
+	})
+	initRepo(t, filepath.Join(src, "wiki"), map[string]string{
+		"Home.md":     "# Home\n",
+		"FAQ.md":      "# FAQ\n",
+		"_Sidebar.md": "* [Home](Home)\n",
+	})
+
+	r := &Repo{
+		Host:         GitHub,
+		URL:          "https://github.com/acme/widgets",
+		CloneURL:     "file://" + filepath.Join(src, "widgets"),
+		WikiCloneURL: "file://" + filepath.Join(src, "wiki"),
+	}
+	dir := t.TempDir()
+	c, err := Clone(context.Background(), r, dir, nil)
+	if err != nil {
+		t.Fatalf("Clone() error = %v", err)
+	}
+	if c.Ref != "main" || len(c.Commit) != 40 || c.WikiDir == "" {
+		t.Errorf("Clone() = ref %q, commit %q, wiki dir %q", c.Ref, c.Commit, c.WikiDir)
+	}
+
+	files, err := c.Files()
+	if err != nil {
+		t.Fatalf("Files() error = %v", err)
+	}
+	var got [][3]string
+	for _, f := range files {
+		got = append(got, [3]string{f.Kind, f.Path, f.URL})
+		if _, err := os.Stat(f.Local); err != nil {
+			t.Errorf("%s: %v", f.Path, err)
+		}
+	}
+	want := [][3]string{
+		{KindReadme, "README.md", "https://github.com/acme/widgets/blob/main/README.md"},
+		{KindDocs, "docs/guide/Usage.MD", "https://github.com/acme/widgets/blob/main/docs/guide/Usage.MD"},
+		{KindDocs, "docs/install.md", "https://github.com/acme/widgets/blob/main/docs/install.md"},
+		{KindWiki, "FAQ.md", "https://github.com/acme/widgets/wiki/FAQ"},
+		{KindWiki, "Home.md", "https://github.com/acme/widgets/wiki/Home"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Files() = %v, want %v", got, want)
+	}
+
+	// A missing wiki is skipped; a missing repository fails
+	r.WikiCloneURL = "file://" + filepath.Join(src, "missing")
+	if c, err := Clone(context.Background(), r, dir, nil); err != nil || c.WikiDir != "" {
+		t.Errorf("Clone() without a wiki = %+v, %v", c, err)
+	}
+	r.CloneURL = r.WikiCloneURL
+	if _, err := Clone(context.Background(), r, dir, nil); err == nil {
+		t.Error("Clone() of a missing repository succeeded")
+	}
+}
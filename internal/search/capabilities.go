@@ -0,0 +1,259 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file describes the supported query syntax so client agents can construct valid queries.
+package search
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CapabilitiesVersion is incremented whenever the shape of Capabilities changes.
+const CapabilitiesVersion = 2
+
+// Capabilities describes the query language supported by the search engine for a skill.
+// It is printed by search --capabilities, and returned by GET /capabilities of
+// the API server and the get_search_capabilities tool of the MCP server, with
+// the limits of their queries.
+type Capabilities struct {
+	// Version is the schema version of this document (see CapabilitiesVersion).
+	Version int `json:"version"`
+	// DefaultOperator is how multiple bare keywords are combined ("OR" or "AND").
+	DefaultOperator string `json:"default_operator"`
+	// CaseSensitive reports whether matching distinguishes upper and lower case.
+	CaseSensitive bool `json:"case_sensitive"`
+	// Fields lists the document fields a query can match against.
+	Fields []string `json:"fields"`
+	// Syntax lists every supported query construct with an example.
+	Syntax []SyntaxFeature `json:"syntax"`
+	// Filters lists the result filters accepted alongside the query, named after
+	// the fields of SearchOptions: "access", "locale", "path_prefix",
+	// "fetched_after", and "tag".
+	Filters []string `json:"filters"`
+	// Limits are the per-query limits enforced by the serving process, if any.
+	Limits *QueryLimits `json:"limits,omitempty"`
+	// Documents is the number of documents in the skill's docs/ directory.
+	Documents int `json:"documents"`
+	// Embeddings describes the provider and model of the skill's embeddings (e.g.,
+	// "openai text-embedding-3-small"), which enable semantic search; empty when the
+	// skill has none.
+	Embeddings string `json:"embeddings,omitempty"`
+}
+
+// SyntaxFeature documents one construct of the query language.
+type SyntaxFeature struct {
+	// Name is a stable identifier for the feature (e.g., "keyword").
+	Name string `json:"name"`
+	// Syntax shows the general form of the construct.
+	Syntax string `json:"syntax"`
+	// Description explains the matching semantics.
+	Description string `json:"description"`
+	// Example is a query using the construct.
+	Example string `json:"example"`
+
+	// selfTest verifies the advertised behaviour against a synthetic corpus
+	selfTest *syntaxTest
+}
+
+// syntaxTest is a self-test case for a SyntaxFeature: running opts against the
+// self-test corpus must return exactly the files in want.
+type syntaxTest struct {
+	opts SearchOptions
+	want []string
+}
+
+// selfTestCorpus is the synthetic docs/ directory used by SelfTest.
+var selfTestCorpus = map[string]string{
+	"auth.md":    "---\ntitle: \"Auth\"\n---\n# Authentication\n\nUse an API token to authenticate.\n",
+	"limits.md":  "---\ntitle: \"Limits\"\n---\n# Rate limit\n\nThe rate limit is 100 requests per minute.\n",
+	"install.md": "---\ntitle: \"Install\"\n---\n# Installation\n\nRun the installer.\n",
+}
+
+// syntaxFeatures returns the query constructs supported by this build.
+func syntaxFeatures() []SyntaxFeature {
+	return []SyntaxFeature{
+		{
+			Name:        "keyword",
+			Syntax:      "word",
+			Description: "Case-insensitive substring match against the document body.",
+			Example:     "authentication",
+			selfTest: &syntaxTest{
+				opts: SearchOptions{Query: "TOKEN"},
+				want: []string{"auth.md"},
+			},
+		},
+		{
+			Name:        "or",
+			Syntax:      "word1 word2",
+			Description: "Space-separated keywords match documents containing any of them; more matches rank higher.",
+			Example:     "token installer",
+			selfTest: &syntaxTest{
+				opts: SearchOptions{Query: "token installer"},
+				want: []string{"auth.md", "install.md"},
+			},
+		},
+		{
+			Name:        "phrase",
+			Syntax:      "\"word1 word2\"",
+			Description: "Quoted words match as a single substring, with their whitespace collapsed to one space.",
+			Example:     "\"rate limit\"",
+			selfTest: &syntaxTest{
+				opts: SearchOptions{Query: "\"rate  limit\""},
+				want: []string{"limits.md"},
+			},
+		},
+		{
+			Name:        "required",
+			Syntax:      "+word",
+			Description: "A keyword or phrase prefixed with + must be in every result; the other keywords then only rank results.",
+			Example:     "+token authenticate",
+			selfTest: &syntaxTest{
+				opts: SearchOptions{Query: "+token installer"},
+				want: []string{"auth.md"},
+			},
+		},
+		{
+			Name:        "excluded",
+			Syntax:      "-word",
+			Description: "A keyword or phrase prefixed with - must not be in any result.",
+			Example:     "token -deprecated",
+			selfTest: &syntaxTest{
+				opts: SearchOptions{Query: "token installer -api"},
+				want: []string{"install.md"},
+			},
+		},
+		{
+			Name:        "and",
+			Syntax:      "word1 word2 (match all)",
+			Description: "With the match-all option (--all), documents must contain every keyword and phrase instead of any.",
+			Example:     "rate minute",
+			selfTest: &syntaxTest{
+				opts: SearchOptions{Query: "rate minute", MatchAll: true},
+				want: []string{"limits.md"},
+			},
+		},
+		{
+			Name:        "fuzzy",
+			Syntax:      "word (fuzzy mode)",
+			Description: "With the fuzzy option (--fuzzy), keywords of 4 or more characters also match the words within 1 edit (2 from 8 characters), tolerating typos; the words matched are reported in matched_terms.",
+			Example:     "authetication",
+			selfTest: &syntaxTest{
+				opts: SearchOptions{Query: "authetication", Fuzzy: true},
+				want: []string{"auth.md"},
+			},
+		},
+		{
+			Name:        "stem",
+			Syntax:      "word (stemming mode)",
+			Description: "With the stemming option (--stem), keywords also match the words with the same stem in the document's language (English only), and stop words such as \"the\" are ignored; the words matched are reported in matched_terms.",
+			Example:     "configuring",
+			selfTest: &syntaxTest{
+				opts: SearchOptions{Query: "the installing", Stem: true},
+				want: []string{"install.md"},
+			},
+		},
+		{
+			Name:        "regex",
+			Syntax:      "pattern (regex mode)",
+			Description: "With the regex option (--regex), the query is a case-sensitive Go regular expression (RE2; prefix (?i) to ignore case) matched line by line; the matched text is highlighted between « and » in the contexts.",
+			Example:     "OnErrorResume(Next)?",
+			selfTest: &syntaxTest{
+				opts: SearchOptions{Query: `API (token|key)`, Regex: true},
+				want: []string{"auth.md"},
+			},
+		},
+	}
+}
+
+// GetCapabilities describes the query language available for the skill at skillDir.
+// limits may be nil when the caller doesn't enforce per-query limits.
+func GetCapabilities(skillDir string, limits *QueryLimits) Capabilities {
+	caps := Capabilities{
+		Version:         CapabilitiesVersion,
+		DefaultOperator: "OR",
+		CaseSensitive:   false,
+		Fields:          []string{"body"},
+		Syntax:          syntaxFeatures(),
+		Filters:         []string{"access", "locale", "path_prefix", "fetched_after", "tag"},
+		Limits:          limits,
+	}
+
+	docsDir := filepath.Join(skillDir, "docs")
+	filepath.Walk(docsDir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && filepath.Ext(path) == ".md" {
+			caps.Documents++
+		}
+		return nil
+	})
+	if emb, err := LoadEmbeddings(skillDir); err == nil {
+		caps.Embeddings = emb.Spec.String()
+	}
+
+	return caps
+}
+
+// SelfTestResult is the outcome of checking one advertised syntax feature.
+type SelfTestResult struct {
+	// Feature is the SyntaxFeature.Name that was checked.
+	Feature string `json:"feature"`
+	// Passed reports whether the feature behaved as documented.
+	Passed bool `json:"passed"`
+	// Detail explains a failure; empty when Passed is true.
+	Detail string `json:"detail,omitempty"`
+}
+
+// SelfTest runs every advertised syntax feature against a small synthetic corpus and
+// reports whether each one returns the documented results. It guards against the
+// capabilities document drifting away from what the engine actually implements.
+func SelfTest(ctx context.Context) ([]SelfTestResult, error) {
+	skillDir, err := os.MkdirTemp("", "site2skill-selftest-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create self-test corpus: %w", err)
+	}
+	defer os.RemoveAll(skillDir)
+
+	docsDir := filepath.Join(skillDir, "docs")
+	if err := os.MkdirAll(docsDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create self-test corpus: %w", err)
+	}
+	for name, content := range selfTestCorpus {
+		if err := os.WriteFile(filepath.Join(docsDir, name), []byte(content), 0644); err != nil {
+			return nil, fmt.Errorf("failed to write self-test corpus: %w", err)
+		}
+	}
+
+	var results []SelfTestResult
+	for _, feature := range syntaxFeatures() {
+		if feature.selfTest == nil {
+			continue
+		}
+		opts := feature.selfTest.opts
+		opts.SkillDir = skillDir
+
+		res := SelfTestResult{Feature: feature.Name, Passed: true}
+		found, err := SearchDocsContext(ctx, opts)
+		if err != nil {
+			res.Passed = false
+			res.Detail = err.Error()
+		} else if got := resultFiles(found); strings.Join(got, ",") != strings.Join(feature.selfTest.want, ",") {
+			res.Passed = false
+			res.Detail = fmt.Sprintf("query %q matched %v, want %v", opts.Query, got, feature.selfTest.want)
+		}
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+// resultFiles returns the sorted base names of the files in results.
+func resultFiles(results []SearchResult) []string {
+	files := make([]string, 0, len(results))
+	for _, r := range results {
+		files = append(files, filepath.Base(r.File))
+	}
+	sort.Strings(files)
+	return files
+}
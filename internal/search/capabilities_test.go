@@ -0,0 +1,52 @@
+package search
+
+import (
+	"context"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	results, err := SelfTest(context.Background())
+	if err != nil {
+		t.Fatalf("SelfTest() returned error: %v", err)
+	}
+	if len(results) == 0 {
+		t.Fatal("SelfTest() ran no checks")
+	}
+	for _, r := range results {
+		if !r.Passed {
+			t.Errorf("feature %q failed self-test: %s", r.Feature, r.Detail)
+		}
+	}
+}
+
+func TestGetCapabilities(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"a.md":      "alpha",
+		"b.md":      "beta",
+		"notes.txt": "ignored",
+	})
+
+	caps := GetCapabilities(skillDir, &DefaultQueryLimits)
+
+	if caps.Version != CapabilitiesVersion {
+		t.Errorf("Version = %d, want %d", caps.Version, CapabilitiesVersion)
+	}
+	if caps.Documents != 2 {
+		t.Errorf("Documents = %d, want 2", caps.Documents)
+	}
+	if caps.Limits == nil || caps.Limits.MaxResults != DefaultQueryLimits.MaxResults {
+		t.Errorf("Limits = %+v, want DefaultQueryLimits", caps.Limits)
+	}
+
+	seen := make(map[string]bool)
+	for _, f := range caps.Syntax {
+		if f.Name == "" || f.Example == "" {
+			t.Errorf("syntax feature %+v is missing a name or example", f)
+		}
+		if seen[f.Name] {
+			t.Errorf("duplicate syntax feature %q", f.Name)
+		}
+		seen[f.Name] = true
+	}
+}
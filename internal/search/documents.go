@@ -0,0 +1,110 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the listing and reading of the documents of a skill, for
+// the programs serving a skill to agents (see Searcher).
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrDocumentNotFound means a document doesn't exist in the skill or is left
+// out by the filters of the request, e.g., its access level.
+var ErrDocumentNotFound = errors.New("document not found")
+
+// DocumentInfo describes a document of a skill, from its frontmatter.
+type DocumentInfo struct {
+	// Path is the slash-separated path of the document relative to the skill
+	// directory (e.g., "docs/guide/install.md").
+	Path        string   `json:"path"`
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Summary     string   `json:"summary,omitempty"`
+	SourceURL   string   `json:"source_url,omitempty"`
+	FetchedAt   string   `json:"fetched_at,omitempty"`
+	Locale      string   `json:"locale,omitempty"`
+	Access      string   `json:"access,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	WordCount   int      `json:"word_count,omitempty"`
+}
+
+// Documents lists the documents of the skill passing the filters of opts
+// (Access, Locale, PathPrefix, FetchedAfter, and Tag; the rest is ignored), in
+// path order.
+//
+// Returns an error if ctx is cancelled or the documents can't be read.
+func (s *Searcher) Documents(ctx context.Context, opts SearchOptions) ([]DocumentInfo, error) {
+	docs, err := listDocuments(s.skillDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	infos := []DocumentInfo{}
+	for _, d := range docs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(filepath.Join(s.skillDir, filepath.FromSlash(d.Path)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", d.Path, err)
+		}
+		fm, _ := extractFrontmatter(string(content))
+		if !opts.accepts(fm) {
+			continue
+		}
+		infos = append(infos, fm.info(d.Path))
+	}
+	return infos, nil
+}
+
+// info returns the description of the document at path with frontmatter fm.
+func (fm Frontmatter) info(path string) DocumentInfo {
+	return DocumentInfo{
+		Path:        path,
+		Title:       fm.Title,
+		Description: fm.Description,
+		Summary:     fm.Summary,
+		SourceURL:   fm.SourceURL,
+		FetchedAt:   fm.FetchedAt,
+		Locale:      fm.Locale,
+		Access:      fm.Access,
+		Tags:        fm.Tags,
+		WordCount:   fm.WordCount,
+	}
+}
+
+// Document returns the content of the document at path, frontmatter included,
+// and its description. path is relative to the skill directory, like
+// SearchResult.File and DocumentInfo.Path, or to its docs/ directory.
+//
+// Returns an error wrapping ErrDocumentNotFound if path isn't a Markdown file
+// of the docs/ directory, doesn't exist, or doesn't pass the filters of opts
+// (see Documents), so that documents the caller isn't allowed can't be told
+// from missing ones.
+func (s *Searcher) Document(path string, opts SearchOptions) (string, DocumentInfo, error) {
+	docsDir := filepath.Join(s.skillDir, "docs")
+	rel := filepath.Clean(filepath.FromSlash(strings.TrimPrefix(path, "/")))
+	if first, _, _ := strings.Cut(filepath.ToSlash(rel), "/"); first == "docs" {
+		rel, _ = filepath.Rel("docs", rel)
+	}
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) ||
+		filepath.Ext(rel) != ".md" || strings.HasPrefix(filepath.ToSlash(rel), IndexDir+"/") {
+		return "", DocumentInfo{}, fmt.Errorf("%w: %s", ErrDocumentNotFound, path)
+	}
+
+	content, err := os.ReadFile(filepath.Join(docsDir, rel))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", DocumentInfo{}, fmt.Errorf("%w: %s", ErrDocumentNotFound, path)
+	}
+	if err != nil {
+		return "", DocumentInfo{}, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	fm, _ := extractFrontmatter(string(content))
+	if !opts.accepts(fm) {
+		return "", DocumentInfo{}, fmt.Errorf("%w: %s", ErrDocumentNotFound, path)
+	}
+	return string(content), fm.info("docs/" + filepath.ToSlash(rel)), nil
+}
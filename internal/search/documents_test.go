@@ -0,0 +1,53 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearcherDocuments(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"guide.md":   "---\ntitle: \"Guide\"\nsource_url: \"https://docs.example.com/guide\"\n---\nDeploy the service.\n",
+		"runbook.md": "---\ntitle: \"Runbook\"\naccess: \"internal\"\n---\nDeploy with the internal tool.\n",
+	})
+	s, err := NewSearcher(skillDir)
+	if err != nil {
+		t.Fatalf("NewSearcher() error = %v", err)
+	}
+
+	docs, err := s.Documents(context.Background(), SearchOptions{Access: []string{"public"}})
+	if err != nil {
+		t.Fatalf("Documents() error = %v", err)
+	}
+	want := []DocumentInfo{{Path: "docs/guide.md", Title: "Guide", SourceURL: "https://docs.example.com/guide", FetchedAt: "Unknown"}}
+	if !reflect.DeepEqual(docs, want) {
+		t.Errorf("Documents() = %+v, want %+v", docs, want)
+	}
+
+	tests := []struct {
+		path   string
+		access []string
+		found  bool
+	}{
+		{"docs/guide.md", nil, true},
+		{"guide.md", nil, true},
+		{"/docs/runbook.md", nil, true},
+		{"docs/runbook.md", []string{"public"}, false},
+		{"docs/missing.md", nil, false},
+		{"../SKILL.md", nil, false},
+		{"docs/../../outside.md", nil, false},
+		{"docs/.index/index.json", nil, false},
+	}
+	for _, tt := range tests {
+		content, info, err := s.Document(tt.path, SearchOptions{Access: tt.access})
+		if tt.found && (err != nil || info.Title == "" || !strings.HasPrefix(info.Path, "docs/") || content == "") {
+			t.Errorf("Document(%q) = %q, %+v, %v, want the document", tt.path, content, info, err)
+		}
+		if !tt.found && !errors.Is(err, ErrDocumentNotFound) {
+			t.Errorf("Document(%q) error = %v, want ErrDocumentNotFound", tt.path, err)
+		}
+	}
+}
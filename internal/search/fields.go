@@ -0,0 +1,156 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the field-aware ranking of search results: matches in
+// the title, the summary, and the top-level headings of a document weigh more
+// than matches in its body (see FieldWeights).
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The fields of a document that search results are ranked by, as reported in
+// SearchResult.MatchedFields and named in ParseFieldWeights.
+const (
+	// FieldTitle is the title of the document's frontmatter.
+	FieldTitle = "title"
+	// FieldSummary is the summary of the document's frontmatter (see package summarize).
+	FieldSummary = "summary"
+	// FieldHeadings is the H1 and H2 headings of the document's body.
+	FieldHeadings = "headings"
+	// FieldBody is the rest of the document's body.
+	FieldBody = "body"
+)
+
+// FieldWeights are the weights of the matches in each field of a document in
+// the score of a search result: the sum, over the fields, of the number of
+// matches of the query in the field times its weight.
+type FieldWeights struct {
+	// Title weighs the matches in the title of the frontmatter. A document
+	// matching in its title only isn't a result: the title ranks the
+	// documents whose body matches.
+	Title float64
+	// Summary weighs the matches in the summary of the frontmatter, which,
+	// like the title, ranks the documents whose body matches.
+	Summary float64
+	// Headings weighs the matches in the H1 and H2 headings of the body.
+	Headings float64
+	// Body weighs the matches in the rest of the body.
+	Body float64
+}
+
+// DefaultFieldWeights are the field weights of searches that set none: a
+// match in the title counts as 3 in the body, and in the summary or a heading
+// as 2.
+var DefaultFieldWeights = FieldWeights{Title: 3, Summary: 2, Headings: 2, Body: 1}
+
+// ParseFieldWeights parses field weights written as comma-separated
+// FIELD=WEIGHT pairs ("title=5,headings=2"), FIELD being title, summary,
+// headings, or body; the fields left out keep their DefaultFieldWeights. Weights can't be
+// negative, and can't all be 0.
+func ParseFieldWeights(s string) (FieldWeights, error) {
+	w := DefaultFieldWeights
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return FieldWeights{}, fmt.Errorf("invalid field weight %q (expected FIELD=WEIGHT)", pair)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || weight < 0 {
+			return FieldWeights{}, fmt.Errorf("invalid weight %q for %s (expected a non-negative number)", strings.TrimSpace(value), strings.TrimSpace(name))
+		}
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case FieldTitle:
+			w.Title = weight
+		case FieldSummary:
+			w.Summary = weight
+		case FieldHeadings:
+			w.Headings = weight
+		case FieldBody:
+			w.Body = weight
+		default:
+			return FieldWeights{}, fmt.Errorf("unknown field %q (expected %s, %s, %s, or %s)", strings.TrimSpace(name), FieldTitle, FieldSummary, FieldHeadings, FieldBody)
+		}
+	}
+	if w == (FieldWeights{}) {
+		return FieldWeights{}, fmt.Errorf("field weights can't all be 0")
+	}
+	return w, nil
+}
+
+// fieldWeights returns the field weights of opts: FieldWeights, or
+// DefaultFieldWeights if it is zero.
+func (opts SearchOptions) fieldWeights() FieldWeights {
+	if opts.FieldWeights == (FieldWeights{}) {
+		return DefaultFieldWeights
+	}
+	return opts.FieldWeights
+}
+
+// documentFields are the text of the weighted fields of a document: its
+// title, its summary, and the text of its H1 and H2 headings, one per line.
+type documentFields struct {
+	title    string
+	summary  string
+	headings string
+}
+
+// newDocumentFields returns the fields of a document with the given title,
+// summary, and body.
+func newDocumentFields(title, summary, body string) documentFields {
+	return documentFields{title: title, summary: summary, headings: strings.Join(topHeadings(body), "\n")}
+}
+
+// topHeadings returns the text of the H1 and H2 headings of body, outside
+// fenced code blocks.
+func topHeadings(body string) []string {
+	var texts []string
+	for _, h := range outline(strings.Split(body, "\n")) {
+		if h.level <= 2 {
+			texts = append(texts, h.text)
+		}
+	}
+	return texts
+}
+
+// score returns the score of a document matching matches times in its body,
+// headings included, whose fields are f, given count, the number of matches
+// of the query in a text; and the fields it matches in, in the order title,
+// summary, headings, body.
+func (w FieldWeights) score(f documentFields, matches int, count func(text string) int) (float64, []string) {
+	title := count(f.title)
+	summary := count(f.summary)
+	headings := min(count(f.headings), matches)
+	body := matches - headings
+
+	var fields []string
+	for _, field := range []struct {
+		name string
+		n    int
+	}{{FieldTitle, title}, {FieldSummary, summary}, {FieldHeadings, headings}, {FieldBody, body}} {
+		if field.n > 0 {
+			fields = append(fields, field.name)
+		}
+	}
+	return w.Title*float64(title) + w.Summary*float64(summary) + w.Headings*float64(headings) + w.Body*float64(body), fields
+}
+
+// occurrences returns the number of occurrences of the required and optional
+// terms of q, and of words, in text, ignoring case.
+func (q query) occurrences(text string, words []string) int {
+	if text == "" {
+		return 0
+	}
+	lower := strings.ToLower(text)
+	n := 0
+	for _, term := range append(q.terms(), words...) {
+		if term != "" {
+			n += strings.Count(lower, term)
+		}
+	}
+	return n
+}
@@ -0,0 +1,92 @@
+package search
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseFieldWeights(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    FieldWeights
+		wantErr bool
+	}{
+		{"", DefaultFieldWeights, false},
+		{"title=5", FieldWeights{Title: 5, Summary: 2, Headings: 2, Body: 1}, false},
+		{"title=1, headings=1.5 ,BODY=0.5", FieldWeights{Title: 1, Summary: 2, Headings: 1.5, Body: 0.5}, false},
+		{"summary=4", FieldWeights{Title: 3, Summary: 4, Headings: 2, Body: 1}, false},
+		{"title=0,summary=0,headings=0", FieldWeights{Body: 1}, false},
+		{"title=0,summary=0,headings=0,body=0", FieldWeights{}, true},
+		{"title", FieldWeights{}, true},
+		{"title=-1", FieldWeights{}, true},
+		{"title=high", FieldWeights{}, true},
+		{"url=2", FieldWeights{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFieldWeights(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseFieldWeights(%q) = %+v, %v; want %+v (error: %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestTopHeadings(t *testing.T) {
+	body := "# Webhooks\n\nIntro.\n\n## Retries\n\n### Backoff\n\n```\n# not a heading\n```\n\n## Signing ##\n"
+	if got, want := topHeadings(body), []string{"Webhooks", "Retries", "Signing"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("topHeadings() = %q, want %q", got, want)
+	}
+}
+
+func TestSearchDocsFieldWeights(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"guide.md": "---\ntitle: \"Webhooks\"\n---\n# Webhooks\n\nWebhooks notify your server.\n",
+		"faq.md":   "---\ntitle: \"FAQ\"\n---\n# FAQ\n\nDo webhooks retry? Are webhooks signed? Can webhooks be paused?\n",
+		"api.md":   "---\ntitle: \"API\"\nsummary: \"The events sent to webhooks.\"\n---\n# API\n\n## Events and webhooks\n\nSee the list.\n",
+	})
+
+	// guide.md matches once in its title, once in a heading, and once in its
+	// body; faq.md three times in its body; api.md once in its summary and
+	// once in a heading.
+	tests := []struct {
+		name    string
+		weights FieldWeights
+		want    []string
+		scores  []float64
+	}{
+		{"default", FieldWeights{}, []string{"guide.md", "api.md", "faq.md"}, []float64{6, 4, 3}},
+		{"body only", FieldWeights{Body: 1}, []string{"faq.md", "guide.md", "api.md"}, []float64{3, 1, 0}},
+		{"headings", FieldWeights{Headings: 10, Body: 1}, []string{"guide.md", "api.md", "faq.md"}, []float64{11, 10, 3}},
+	}
+	wantFields := map[string][]string{
+		"guide.md": {FieldTitle, FieldHeadings, FieldBody},
+		"faq.md":   {FieldBody},
+		"api.md":   {FieldSummary, FieldHeadings},
+	}
+	for _, index := range []bool{false, true} {
+		if index {
+			if err := BuildIndex(skillDir); err != nil {
+				t.Fatalf("BuildIndex() error = %v", err)
+			}
+		}
+		for _, tt := range tests {
+			results, err := SearchDocs(SearchOptions{SkillDir: skillDir, Query: "webhooks", FieldWeights: tt.weights})
+			if err != nil {
+				t.Fatalf("SearchDocs() error = %v", err)
+			}
+			var got []string
+			var scores []float64
+			for _, r := range results {
+				file := filepath.Base(r.File)
+				got = append(got, file)
+				scores = append(scores, r.Score)
+				if !reflect.DeepEqual(r.MatchedFields, wantFields[file]) {
+					t.Errorf("%s (index %v): MatchedFields of %s = %q, want %q", tt.name, index, file, r.MatchedFields, wantFields[file])
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) || !reflect.DeepEqual(scores, tt.scores) {
+				t.Errorf("%s (index %v): SearchDocs() = %v with scores %v, want %v with scores %v", tt.name, index, got, scores, tt.want, tt.scores)
+			}
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the result filters on the frontmatter of the documents
+// (SearchOptions.Locale, PathPrefix, FetchedAfter, Tag, and Access).
+package search
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/access"
+)
+
+// accepts reports whether a document with frontmatter fm passes the filters of opts.
+func (opts SearchOptions) accepts(fm Frontmatter) bool {
+	if !access.Allowed(opts.Access, fm.Access) {
+		return false
+	}
+	if opts.Locale != "" && !matchLocale(opts.Locale, fm.Locale) {
+		return false
+	}
+	if opts.PathPrefix != "" && !matchPathPrefix(opts.PathPrefix, fm.SourceURL) {
+		return false
+	}
+	if !opts.FetchedAfter.IsZero() {
+		fetched, err := time.Parse(time.RFC3339, fm.FetchedAt)
+		if err != nil || !fetched.After(opts.FetchedAfter) {
+			return false
+		}
+	}
+	if opts.Tag != "" && !containsFold(fm.Tags, opts.Tag) {
+		return false
+	}
+	return true
+}
+
+// matchLocale reports whether locale is filter or one of its regional
+// variants, ignoring case and the separator: "en" matches "en", "en-US", and
+// "en_GB", while "en-US" matches only "en-US".
+func matchLocale(filter, locale string) bool {
+	filter = strings.ReplaceAll(strings.ToLower(filter), "_", "-")
+	locale = strings.ReplaceAll(strings.ToLower(locale), "_", "-")
+	return locale == filter || strings.HasPrefix(locale, filter+"-")
+}
+
+// matchPathPrefix reports whether the path of sourceURL is prefix or below it,
+// by whole segments: "/api" matches "/api" and "/api/auth" but not "/apis". A
+// trailing "/" or "/**" of prefix is ignored, so path patterns like "/api/**"
+// can be given.
+func matchPathPrefix(prefix, sourceURL string) bool {
+	u, err := url.Parse(sourceURL)
+	if err != nil || !u.IsAbs() {
+		return false
+	}
+	prefix = strings.TrimRight(strings.TrimSuffix(prefix, "/**"), "/")
+	if prefix == "" {
+		return true
+	}
+	if !strings.HasPrefix(prefix, "/") {
+		prefix = "/" + prefix
+	}
+	p := u.Path
+	return p == prefix || strings.HasPrefix(p, prefix+"/")
+}
+
+// containsFold reports whether values contains s, ignoring case.
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
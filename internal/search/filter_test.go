@@ -0,0 +1,113 @@
+package search
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestMatchLocale(t *testing.T) {
+	tests := []struct {
+		filter, locale string
+		want           bool
+	}{
+		{"en", "en", true},
+		{"en", "en-US", true},
+		{"en", "en_GB", true},
+		{"EN-us", "en-US", true},
+		{"en-US", "en-GB", false},
+		{"en-US", "en", false},
+		{"en", "eo", false},
+		{"ja", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchLocale(tt.filter, tt.locale); got != tt.want {
+			t.Errorf("matchLocale(%q, %q) = %v, want %v", tt.filter, tt.locale, got, tt.want)
+		}
+	}
+}
+
+func TestMatchPathPrefix(t *testing.T) {
+	tests := []struct {
+		prefix, url string
+		want        bool
+	}{
+		{"/api", "https://example.com/api", true},
+		{"/api", "https://example.com/api/auth", true},
+		{"/api/", "https://example.com/api/auth", true},
+		{"/api/**", "https://example.com/api/v1/auth", true},
+		{"api", "https://example.com/api/auth", true},
+		{"/api", "https://example.com/apis", false},
+		{"/api", "https://example.com/docs/api", false},
+		{"/**", "https://example.com/guide", true},
+		{"/api", "Unknown", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchPathPrefix(tt.prefix, tt.url); got != tt.want {
+			t.Errorf("matchPathPrefix(%q, %q) = %v, want %v", tt.prefix, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestSearchDocsFilters(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"auth.md":  "---\ntitle: \"Auth\"\nsource_url: \"https://example.com/api/auth\"\nfetched_at: \"2024-05-20T00:00:00Z\"\nlocale: \"en-US\"\ntags:\n  - \"Security\"\n---\nTokens expire after an hour.\n",
+		"old.md":   "---\ntitle: \"Old\"\nsource_url: \"https://example.com/api/v1/tokens\"\nfetched_at: \"2024-01-02T00:00:00Z\"\nlocale: \"en\"\n---\nLegacy tokens never expire.\n",
+		"ja.md":    "---\ntitle: \"認証\"\nsource_url: \"https://example.com/ja/api/auth\"\nfetched_at: \"2024-05-20T00:00:00Z\"\nlocale: \"ja\"\ntags: [\"security\"]\n---\nトークン tokens.\n",
+		"guide.md": "---\ntitle: \"Guide\"\nsource_url: \"https://example.com/guide\"\n---\nCreate tokens in the dashboard.\n",
+	})
+
+	tests := []struct {
+		name string
+		opts SearchOptions
+		want []string
+	}{
+		{"no filter", SearchOptions{}, []string{"auth.md", "guide.md", "ja.md", "old.md"}},
+		{"locale", SearchOptions{Locale: "en"}, []string{"auth.md", "old.md"}},
+		{"regional locale", SearchOptions{Locale: "en-US"}, []string{"auth.md"}},
+		{"path prefix", SearchOptions{PathPrefix: "/api/**"}, []string{"auth.md", "old.md"}},
+		{"fetched after", SearchOptions{FetchedAfter: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}, []string{"auth.md", "ja.md"}},
+		{"tag", SearchOptions{Tag: "security"}, []string{"auth.md", "ja.md"}},
+		{"combined", SearchOptions{PathPrefix: "/api", FetchedAfter: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}, []string{"auth.md"}},
+		{"no match", SearchOptions{Tag: "billing"}, nil},
+	}
+
+	search := func(opts SearchOptions) []string {
+		t.Helper()
+		opts.SkillDir = skillDir
+		opts.Query = "tokens"
+		results, err := SearchDocs(opts)
+		if err != nil {
+			t.Fatalf("SearchDocs() returned error: %v", err)
+		}
+		var files []string
+		for _, r := range results {
+			files = append(files, filepath.Base(r.File))
+		}
+		sort.Strings(files)
+		return files
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := search(tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scan: SearchDocs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// The index records the frontmatter of the documents
+	if err := BuildIndex(skillDir); err != nil {
+		t.Fatalf("BuildIndex() returned error: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := search(tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("index: SearchDocs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,312 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the output formats of search results: text, JSON,
+// Markdown, and CSV (see WriteResults).
+package search
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The output formats of search results, as accepted by SearchOptions.Format
+// and the --format option of the search command.
+const (
+	// OutputText is the human-readable text of FormatResults, in color on terminals.
+	OutputText = "text"
+	// OutputJSON is a ResultsEnvelope holding the results.
+	OutputJSON = "json"
+	// OutputMarkdown is a table of the results whose documents link to their
+	// source URLs, to paste into an agent prompt or a document.
+	OutputMarkdown = "markdown"
+	// OutputCSV is one row per result under a header row, for spreadsheets.
+	OutputCSV = "csv"
+)
+
+// OutputFormats lists the output formats of search results.
+var OutputFormats = []string{OutputText, OutputJSON, OutputMarkdown, OutputCSV}
+
+// ResultsSchemaVersion is the version of the JSON output of search results
+// (ResultsEnvelope). Fields may be added within a version; it is increased
+// when fields are removed, renamed, or change meaning.
+const ResultsSchemaVersion = 1
+
+// ResultsEnvelope is the JSON output of a search: the results with the search
+// they answer.
+type ResultsEnvelope struct {
+	// SchemaVersion is ResultsSchemaVersion.
+	SchemaVersion int `json:"schema_version"`
+	// Query is the query searched.
+	Query string `json:"query"`
+	// Total is the number of results.
+	Total int `json:"total"`
+	// ElapsedMS is how long the search took, in milliseconds.
+	ElapsedMS int64 `json:"elapsed_ms"`
+	// Results are the results, best first.
+	Results []SearchResult `json:"results"`
+	// Diagnosis explains a search without results (see DiagnoseQuery); nil
+	// when there are results.
+	Diagnosis *Diagnosis `json:"diagnosis,omitempty"`
+	// Symbols are the code symbols named by the query, where the skill
+	// declares them (see LookupSymbols).
+	Symbols []Symbol `json:"symbols,omitempty"`
+}
+
+// NewResultsEnvelope returns the envelope of results, found for query in
+// elapsed. The results are an empty slice, not nil, when there are none, so
+// that they are encoded as an empty JSON array.
+func NewResultsEnvelope(results []SearchResult, query string, elapsed time.Duration) ResultsEnvelope {
+	if results == nil {
+		results = []SearchResult{}
+	}
+	return ResultsEnvelope{
+		SchemaVersion: ResultsSchemaVersion,
+		Query:         query,
+		Total:         len(results),
+		ElapsedMS:     elapsed.Milliseconds(),
+		Results:       results,
+	}
+}
+
+// ParseOutputFormat validates s, an output format of search results, and
+// returns it lowercased; "" is OutputText. "md" is accepted for
+// OutputMarkdown.
+func ParseOutputFormat(s string) (string, error) {
+	switch format := strings.ToLower(strings.TrimSpace(s)); format {
+	case "":
+		return OutputText, nil
+	case "md":
+		return OutputMarkdown, nil
+	case OutputText, OutputJSON, OutputMarkdown, OutputCSV:
+		return format, nil
+	}
+	return "", fmt.Errorf("invalid output format %q (expected %s)", s, strings.Join(OutputFormats, ", "))
+}
+
+// OutputFormat returns the output format of the results of opts: Format, or
+// OutputJSON if it is empty and JSONOutput is set, or else OutputText.
+func (opts SearchOptions) OutputFormat() string {
+	switch {
+	case opts.Format != "":
+		return opts.Format
+	case opts.JSONOutput:
+		return OutputJSON
+	}
+	return OutputText
+}
+
+// WriteResults writes the results of env in format (see ParseOutputFormat).
+// The text format is printed to stdout in color by FormatSymbols and
+// FormatResults, followed by FormatDiagnosis, whatever w; the others are
+// written to w. The CSV format holds the results only, without their symbols
+// and diagnosis.
+func WriteResults(w io.Writer, format string, env ResultsEnvelope) error {
+	switch format {
+	case "", OutputText:
+		FormatSymbols(env.Symbols)
+		FormatResults(env.Results, env.Query)
+		FormatDiagnosis(env.Diagnosis)
+		return nil
+	case OutputJSON:
+		return WriteJSON(w, env)
+	case OutputMarkdown:
+		return WriteMarkdown(w, env)
+	case OutputCSV:
+		return WriteCSV(w, env.Results)
+	}
+	return fmt.Errorf("invalid output format %q (expected %s)", format, strings.Join(OutputFormats, ", "))
+}
+
+// FormatJSON prints env as JSON to stdout (see WriteJSON).
+//
+// Example:
+//
+//	env := NewResultsEnvelope(results, "authentication", time.Since(start))
+//	if err := FormatJSON(env); err != nil {
+//		log.Fatal(err)
+//	}
+func FormatJSON(env ResultsEnvelope) error {
+	return WriteJSON(os.Stdout, env)
+}
+
+// WriteJSON writes env, the results of a search, to w as indented JSON.
+func WriteJSON(w io.Writer, env ResultsEnvelope) error {
+	if env.Results == nil {
+		env.Results = []SearchResult{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(env)
+}
+
+// WriteMarkdown writes env, the results of a search, to w as a Markdown
+// table: one row per result with its rank, its file linked to its source
+// URL, its number of matches, the section of its first context, and the
+// first matched line of that context, matched terms in bold. The symbols
+// named by the query are listed first, and a search without results is
+// followed by its diagnosis.
+func WriteMarkdown(w io.Writer, env ResultsEnvelope) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Search results for %s\n\n", markdownCell("`"+env.Query+"`"))
+	if len(env.Symbols) > 0 {
+		b.WriteString("Defined in:\n\n")
+		for _, s := range env.Symbols {
+			fmt.Fprintf(&b, "- `%s` (%s) in %s line %d\n", s.qualifiedName(), s.Kind, s.Path, s.Line)
+		}
+		b.WriteString("\n")
+	}
+	if len(env.Results) == 0 {
+		b.WriteString("No matches found.\n")
+		if d := env.Diagnosis; d != nil {
+			if d.DidYouMean != "" {
+				fmt.Fprintf(&b, "\nDid you mean %s?\n", markdownCell("`"+d.DidYouMean+"`"))
+			}
+			if len(d.Terms) > 0 {
+				b.WriteString("\n| Term | Kind | Documents | Suggestions |\n")
+				b.WriteString("|------|------|-----------|-------------|\n")
+				for _, t := range d.Terms {
+					fmt.Fprintf(&b, "| %s | %s | %d | %s |\n", markdownCell(t.Term), t.Kind, t.Documents, markdownCell(suggestionList(t.Suggestions)))
+				}
+			}
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	b.WriteString("| # | Document | Matches | Section | Excerpt |\n")
+	b.WriteString("|---|----------|---------|---------|---------|\n")
+	for i, res := range env.Results {
+		doc := markdownCell(res.File)
+		if res.SourceURL != "" {
+			doc = "[" + doc + "](" + strings.ReplaceAll(res.SourceURL, ")", "%29") + ")"
+		}
+		var section, excerpt string
+		if len(res.Sections) > 0 {
+			s := res.Sections[0]
+			section = markdownCell(s.Breadcrumb())
+			if s.Link != "" {
+				section = "[" + section + "](" + strings.ReplaceAll(s.Link, ")", "%29") + ")"
+			}
+		}
+		if len(res.Contexts) > 0 {
+			excerpt = markdownCell(boldHighlights(matchedLine(res.Contexts[0])))
+		}
+		fmt.Fprintf(&b, "| %d | %s | %d | %s | %s |\n", i+1, doc, res.Matches, section, excerpt)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// FormatDiagnosis prints d, the diagnosis of a search without results, in a
+// human-readable format to stdout: the suggested query, then the number of
+// documents of each term with its suggestions. Nothing is printed if d is nil.
+func FormatDiagnosis(d *Diagnosis) {
+	if d == nil {
+		return
+	}
+	if d.DidYouMean != "" {
+		colorBold.Printf("Did you mean '%s'?\n", d.DidYouMean)
+	}
+	if len(d.Terms) == 0 {
+		return
+	}
+	fmt.Println("Documents matching each term:")
+	for _, t := range d.Terms {
+		line := fmt.Sprintf("  %s (%s): %d", t.Term, t.Kind, t.Documents)
+		if len(t.Suggestions) > 0 {
+			line += " | suggestions: " + suggestionList(t.Suggestions)
+		}
+		fmt.Println(line)
+	}
+}
+
+// FormatSymbols prints symbols, the code symbols named by a query, in a
+// human-readable format to stdout: the kind, language, and location of each
+// declaration. Nothing is printed if there are none.
+func FormatSymbols(symbols []Symbol) {
+	if len(symbols) == 0 {
+		return
+	}
+	colorBold.Println("Defined in:")
+	for _, s := range symbols {
+		line := fmt.Sprintf("  %s (%s", s.qualifiedName(), s.Kind)
+		if s.Language != "" {
+			line += ", " + s.Language
+		}
+		line += fmt.Sprintf("): %s:%d", s.Path, s.Line)
+		if s.SourceURL != "" {
+			line += " | " + s.SourceURL
+		}
+		fmt.Println(line)
+	}
+	fmt.Println()
+}
+
+// suggestionList lists suggestions with their number of documents:
+// "authentication (12), authenticate (3)".
+func suggestionList(suggestions []Suggestion) string {
+	parts := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		parts[i] = fmt.Sprintf("%s (%d)", s.Term, s.Documents)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// WriteCSV writes search results to w as CSV: a header row, then one row per
+// result with its rank, file, source URL, number of matches, score, the
+// section and lines of its first context, fetch and modification times, and
+// the first matched line of its first context, highlight markers removed, and
+// the deep link to the section (see Section.Link).
+func WriteCSV(w io.Writer, results []SearchResult) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"rank", "file", "source_url", "matches", "score", "section", "start_line", "end_line", "fetched_at", "modified_at", "excerpt", "link"})
+	for i, res := range results {
+		var section, start, end, excerpt, score, link string
+		if len(res.Sections) > 0 {
+			s := res.Sections[0]
+			section, start, end, link = s.Breadcrumb(), strconv.Itoa(s.StartLine), strconv.Itoa(s.EndLine), s.Link
+		}
+		if len(res.Contexts) > 0 {
+			excerpt = stripHighlights(matchedLine(res.Contexts[0]))
+		}
+		if res.Score > 0 {
+			score = strconv.FormatFloat(res.Score, 'f', 4, 64)
+		}
+		cw.Write([]string{strconv.Itoa(i + 1), res.File, res.SourceURL, strconv.Itoa(res.Matches), score, section, start, end, res.FetchedAt, res.ModifiedAt, excerpt, link})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// matchedLine returns the first matched line of context (prefixed with "> "),
+// without its prefix, or the first line if none is marked.
+func matchedLine(context string) string {
+	lines := strings.Split(context, "\n")
+	for _, line := range lines {
+		if rest, ok := strings.CutPrefix(line, "> "); ok {
+			return strings.TrimSpace(rest)
+		}
+	}
+	return strings.TrimSpace(lines[0])
+}
+
+// boldHighlights renders the text between HighlightOpen and HighlightClose in
+// bold Markdown instead of the markers.
+func boldHighlights(s string) string {
+	return strings.NewReplacer(HighlightOpen, "**", HighlightClose, "**").Replace(s)
+}
+
+// stripHighlights removes the HighlightOpen and HighlightClose markers of s.
+func stripHighlights(s string) string {
+	return strings.NewReplacer(HighlightOpen, "", HighlightClose, "").Replace(s)
+}
+
+// markdownCell escapes s for a cell of a Markdown table, which can't hold
+// pipes or line breaks.
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\r", " ", "\n", " ").Replace(s)
+}
@@ -0,0 +1,149 @@
+package search
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// formatResults are the results the output format tests write.
+var formatResults = []SearchResult{
+	{
+		File:      "docs/api/auth.md",
+		Matches:   3,
+		Contexts:  []string{"  Tokens are sent as headers.\n> Use an «API key» | or a token\n  More text."},
+		Sections:  []Section{{Headings: []string{"API", "Authentication"}, StartLine: 4, EndLine: 20, Anchor: "authentication", Link: "docs/api/auth.md#authentication"}},
+		SourceURL: "https://example.com/api/auth",
+		FetchedAt: "2024-05-01T00:00:00Z",
+		Score:     0.5,
+	},
+	{
+		File:     "docs/intro.md",
+		Matches:  1,
+		Contexts: []string{"> Intro"},
+	},
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"", OutputText, false},
+		{"text", OutputText, false},
+		{"JSON", OutputJSON, false},
+		{"markdown", OutputMarkdown, false},
+		{"md", OutputMarkdown, false},
+		{" csv ", OutputCSV, false},
+		{"xml", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseOutputFormat(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseOutputFormat(%q) = %q, %v; want %q (error: %v)", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestOutputFormat(t *testing.T) {
+	tests := []struct {
+		opts SearchOptions
+		want string
+	}{
+		{SearchOptions{}, OutputText},
+		{SearchOptions{JSONOutput: true}, OutputJSON},
+		{SearchOptions{Format: OutputCSV, JSONOutput: true}, OutputCSV},
+	}
+	for _, tt := range tests {
+		if got := tt.opts.OutputFormat(); got != tt.want {
+			t.Errorf("OutputFormat() of %+v = %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, OutputJSON, NewResultsEnvelope(formatResults, "api key", 1500*time.Millisecond)); err != nil {
+		t.Fatalf("WriteResults() returned error: %v", err)
+	}
+	var got ResultsEnvelope
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output isn't JSON: %v\n%s", err, buf.String())
+	}
+	if got.SchemaVersion != ResultsSchemaVersion || got.Query != "api key" || got.Total != 2 || got.ElapsedMS != 1500 || len(got.Results) != 2 || got.Results[0].File != "docs/api/auth.md" {
+		t.Errorf("WriteResults() = %+v", got)
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, NewResultsEnvelope(nil, "none", 0)); err != nil {
+		t.Fatalf("WriteJSON() returned error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"results": []`) {
+		t.Errorf("WriteJSON() without results = %s, want an empty results array", buf.String())
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, OutputMarkdown, NewResultsEnvelope(formatResults, "api key", 0)); err != nil {
+		t.Fatalf("WriteResults() returned error: %v", err)
+	}
+	want := "## Search results for `api key`\n\n" +
+		"| # | Document | Matches | Section | Excerpt |\n" +
+		"|---|----------|---------|---------|---------|\n" +
+		"| 1 | [docs/api/auth.md](https://example.com/api/auth) | 3 | [API > Authentication](docs/api/auth.md#authentication) | Use an **API key** \\| or a token |\n" +
+		"| 2 | docs/intro.md | 1 |  | Intro |\n"
+	if buf.String() != want {
+		t.Errorf("WriteResults() =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	env := NewResultsEnvelope(nil, "authetication", 0)
+	env.Diagnosis = &Diagnosis{
+		Terms:      []TermReport{{Term: "authetication", Kind: "optional", Suggestions: []Suggestion{{Term: "authentication", Documents: 12}}}},
+		DidYouMean: "authentication",
+	}
+	if err := WriteMarkdown(&buf, env); err != nil {
+		t.Fatalf("WriteMarkdown() returned error: %v", err)
+	}
+	want = "## Search results for `authetication`\n\n" +
+		"No matches found.\n\n" +
+		"Did you mean `authentication`?\n\n" +
+		"| Term | Kind | Documents | Suggestions |\n" +
+		"|------|------|-----------|-------------|\n" +
+		"| authetication | optional | 0 | authentication (12) |\n"
+	if buf.String() != want {
+		t.Errorf("WriteMarkdown() without results =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, OutputCSV, NewResultsEnvelope(formatResults, "api key", 0)); err != nil {
+		t.Fatalf("WriteResults() returned error: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("output isn't CSV: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want a header and 2 results: %v", len(rows), rows)
+	}
+	want := []string{"1", "docs/api/auth.md", "https://example.com/api/auth", "3", "0.5000", "API > Authentication", "4", "20", "2024-05-01T00:00:00Z", "", "Use an API key | or a token", "docs/api/auth.md#authentication"}
+	if strings.Join(rows[1], "\x00") != strings.Join(want, "\x00") {
+		t.Errorf("row 1 = %q, want %q", rows[1], want)
+	}
+	if rows[0][0] != "rank" || rows[2][1] != "docs/intro.md" || rows[2][10] != "Intro" {
+		t.Errorf("rows = %q", rows)
+	}
+}
+
+func TestWriteResultsInvalidFormat(t *testing.T) {
+	if err := WriteResults(&bytes.Buffer{}, "xml", NewResultsEnvelope(formatResults, "q", 0)); err == nil {
+		t.Error("WriteResults() with an unknown format returned nil error")
+	}
+}
@@ -0,0 +1,68 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the edit distance of the typo-tolerant search mode
+// (SearchOptions.Fuzzy); see wordMatcher.
+package search
+
+import "unicode/utf8"
+
+// maxEdits returns the number of edits a fuzzy keyword tolerates: none for
+// keywords shorter than 4 characters, which would match too many words, 1 up
+// to 7 characters, and 2 beyond.
+func maxEdits(term string) int {
+	switch n := utf8.RuneCountInString(term); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance returns the number of single-character insertions, deletions,
+// substitutions, and transpositions of adjacent characters turning a into b
+// (optimal string alignment distance), or limit+1 if it exceeds limit.
+func editDistance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if abs(len(ra)-len(rb)) > limit {
+		return limit + 1
+	}
+
+	// Three rows of the dynamic programming table suffice for transpositions
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d := min(min(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d = min(d, prev2[j-2]+1)
+			}
+			cur[j] = d
+			rowMin = min(rowMin, d)
+		}
+		// The distance is at least the smallest value of any row
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return min(prev[len(rb)], limit+1)
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
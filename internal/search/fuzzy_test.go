@@ -0,0 +1,69 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"authetication", "authentication", 2, 1},
+		{"recieve", "receive", 1, 1},
+		{"token", "token", 1, 0},
+		{"token", "tokens", 1, 1},
+		{"token", "taken", 1, 1},
+		{"token", "tack", 1, 2},
+		{"webhook", "web", 2, 3},
+		{"日本語", "日本", 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			if got := editDistance(tt.a, tt.b, tt.limit); got != tt.want {
+				t.Errorf("editDistance(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchDocsFuzzy(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"auth.md":  "# Authentication\n\nAuthentication uses tokens.",
+		"hooks.md": "Webhooks retry failed deliveries.",
+		"api.md":   "The API is versioned.",
+	})
+
+	tests := []struct {
+		name        string
+		query       string
+		wantFiles   []string
+		wantMatched []string
+	}{
+		{"missing letter", "authetication", []string{"auth.md"}, []string{"authentication"}},
+		{"transposition", "webhoosk", []string{"hooks.md"}, []string{"webhooks"}},
+		{"exact keywords report nothing", "token", []string{"auth.md"}, nil},
+		{"short keywords match exactly", "apo", nil, nil},
+		{"phrases match exactly", `"authetication uses"`, nil, nil},
+		{"excluded terms match exactly", "authetication -authetication", []string{"auth.md"}, []string{"authentication"}},
+		{"excluded terms exclude exactly", "authetication -tokens", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := SearchDocs(SearchOptions{SkillDir: skillDir, Query: tt.query, Fuzzy: true})
+			if err != nil {
+				t.Fatalf("SearchDocs() returned error: %v", err)
+			}
+			if files := resultFiles(results); !reflect.DeepEqual(files, tt.wantFiles) && len(files)+len(tt.wantFiles) > 0 {
+				t.Fatalf("SearchDocs(%q) matched %v, want %v", tt.query, files, tt.wantFiles)
+			}
+			if len(results) > 0 && !reflect.DeepEqual(results[0].MatchedTerms, tt.wantMatched) {
+				t.Errorf("MatchedTerms = %v, want %v", results[0].MatchedTerms, tt.wantMatched)
+			}
+		})
+	}
+}
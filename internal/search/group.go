@@ -0,0 +1,151 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the grouping of search results by the navigation sections
+// of a skill (see GroupBySection).
+package search
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// GroupBySectionName is the name of the section grouping of search results, as
+// accepted by the --group-by option of the search command.
+const GroupBySectionName = "section"
+
+// manifestFile is the navigation manifest written at the root of a skill, whose
+// documents give the section of each file (see skillgen.Manifest).
+const manifestFile = "manifest.json"
+
+// ResultGroup is the results of a search in one section of a skill.
+type ResultGroup struct {
+	// Section is the URL directory of the documents of the group relative to the
+	// site's common prefix, as in manifest.json (e.g., "api/auth"); "" for the
+	// top-level documents.
+	Section string `json:"section"`
+	// Total is the number of documents of the section matching the search.
+	Total int `json:"total"`
+	// Results are the best results of the section, best first.
+	Results []SearchResult `json:"results"`
+}
+
+// GroupBySection groups results, best first, by the section of their documents
+// in the skill in skillDir, so that the results of a broad query read as a
+// handful of areas of the site instead of a long list. Groups are ordered by
+// their best result and keep their first perGroup results; 0 keeps them all.
+//
+// Sections are read from the skill's manifest.json. Documents it doesn't list,
+// e.g., added by hand, and every document of a skill without a manifest are
+// grouped by their directory under docs/.
+//
+// Returns an error if manifest.json exists but can't be read or parsed.
+func GroupBySection(skillDir string, results []SearchResult, perGroup int) ([]ResultGroup, error) {
+	sections, err := readSections(skillDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []ResultGroup
+	index := make(map[string]int)
+	for _, res := range results {
+		section, ok := sections[res.File]
+		if !ok {
+			section = strings.TrimPrefix(path.Dir(strings.TrimPrefix(res.File, "docs/")), ".")
+		}
+		i, ok := index[section]
+		if !ok {
+			i = len(groups)
+			index[section] = i
+			groups = append(groups, ResultGroup{Section: section})
+		}
+		groups[i].Total++
+		if perGroup <= 0 || len(groups[i].Results) < perGroup {
+			groups[i].Results = append(groups[i].Results, res)
+		}
+	}
+	return groups, nil
+}
+
+// readSections returns the sections of the documents listed in the
+// manifest.json of skillDir by path, or none if it has no manifest.
+func readSections(skillDir string) (map[string]string, error) {
+	data, err := os.ReadFile(filepath.Join(skillDir, manifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", manifestFile, err)
+	}
+	var m struct {
+		Documents []struct {
+			Path    string `json:"path"`
+			Section string `json:"section"`
+		} `json:"documents"`
+	}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", manifestFile, err)
+	}
+	sections := make(map[string]string, len(m.Documents))
+	for _, doc := range m.Documents {
+		sections[doc.Path] = doc.Section
+	}
+	return sections, nil
+}
+
+// FormatGroups prints search results grouped by section (see GroupBySection)
+// in a human-readable format: a heading per section with its number of
+// matching documents, then its best results with their first context.
+func FormatGroups(groups []ResultGroup, query string) {
+	if len(groups) == 0 {
+		fmt.Printf("No matches found for '%s'.\n", query)
+		return
+	}
+
+	total := 0
+	for _, g := range groups {
+		total += g.Total
+	}
+	colorHeader.Printf("\nSearch Results for '%s'\n", query)
+	fmt.Printf("Found matches in %d files in %d sections.\n\n", total, len(groups))
+
+	for _, g := range groups {
+		name := g.Section + "/"
+		if g.Section == "" {
+			name = "(top level)"
+		}
+		files := "files"
+		if g.Total == 1 {
+			files = "file"
+		}
+		colorBold.Printf("%s (%d %s)\n", name, g.Total, files)
+		colorCyan.Println(strings.Repeat("-", 40))
+		for i, res := range g.Results {
+			fmt.Printf("%d. %s\n", i+1, res.File)
+			fmt.Printf("   Matches: %d | Source: %s\n", res.Matches, res.SourceURL)
+			if len(res.Contexts) > 0 {
+				if len(res.Sections) > 0 {
+					colorCyan.Println(sectionLabel(res.Sections[0]))
+				}
+				fmt.Println(colorHighlights(res.Contexts[0]))
+			}
+		}
+		if more := g.Total - len(g.Results); more > 0 {
+			fmt.Printf("   ... and %d more in this section\n", more)
+		}
+		fmt.Println()
+	}
+}
+
+// FormatGroupsJSON prints search results grouped by section as JSON to stdout.
+func FormatGroupsJSON(groups []ResultGroup) error {
+	if groups == nil {
+		groups = []ResultGroup{}
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(groups)
+}
@@ -0,0 +1,84 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGroupBySection(t *testing.T) {
+	skillDir := t.TempDir()
+	manifest := `{"name":"x","documents":[
+		{"path":"docs/index.md","section":""},
+		{"path":"docs/api_auth.md","section":"api"},
+		{"path":"docs/api_users.md","section":"api"},
+		{"path":"docs/api_tokens.md","section":"api"},
+		{"path":"docs/guide_install.md","section":"guide"}]}`
+	if err := os.WriteFile(filepath.Join(skillDir, "manifest.json"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	results := []SearchResult{
+		{File: "docs/api_auth.md"},
+		{File: "docs/guide_install.md"},
+		{File: "docs/api_users.md"},
+		{File: "docs/index.md"},
+		{File: "docs/api_tokens.md"},
+		{File: "docs/extra/notes.md"},
+	}
+
+	// summarize returns the section, total, and files of each group
+	summarize := func(groups []ResultGroup) [][]any {
+		var got [][]any
+		for _, g := range groups {
+			row := []any{g.Section, g.Total}
+			for _, r := range g.Results {
+				row = append(row, r.File)
+			}
+			got = append(got, row)
+		}
+		return got
+	}
+
+	tests := []struct {
+		name     string
+		skillDir string
+		perGroup int
+		want     [][]any
+	}{
+		{"top hits per group", skillDir, 2, [][]any{
+			{"api", 3, "docs/api_auth.md", "docs/api_users.md"},
+			{"guide", 1, "docs/guide_install.md"},
+			{"", 1, "docs/index.md"},
+			{"extra", 1, "docs/extra/notes.md"},
+		}},
+		{"all results", skillDir, 0, [][]any{
+			{"api", 3, "docs/api_auth.md", "docs/api_users.md", "docs/api_tokens.md"},
+			{"guide", 1, "docs/guide_install.md"},
+			{"", 1, "docs/index.md"},
+			{"extra", 1, "docs/extra/notes.md"},
+		}},
+		{"no manifest", t.TempDir(), 1, [][]any{
+			{"", 5, "docs/api_auth.md"},
+			{"extra", 1, "docs/extra/notes.md"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := GroupBySection(tt.skillDir, results, tt.perGroup)
+			if err != nil {
+				t.Fatalf("GroupBySection() error = %v", err)
+			}
+			if got := summarize(groups); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GroupBySection() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := os.WriteFile(filepath.Join(skillDir, "manifest.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GroupBySection(skillDir, results, 0); err == nil {
+		t.Error("GroupBySection() with an invalid manifest succeeded, want error")
+	}
+}
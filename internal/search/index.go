@@ -0,0 +1,493 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the on-disk inverted index built at generation time,
+// which spares searches of large skills from reading every document.
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+)
+
+const (
+	// IndexDir is the directory of the inverted index inside a skill's docs/ directory.
+	IndexDir = ".index"
+	// IndexFile is the file of IndexDir listing the indexed documents and terms.
+	IndexFile = "index.json"
+	// IndexVersion is incremented whenever the index format changes; indexes
+	// of another version are ignored.
+	IndexVersion = 7
+
+	// termsPerShard is the number of terms per postings file, which bounds how
+	// much of the index a query loads.
+	termsPerShard = 2048
+	// maxShards caps the number of postings files.
+	maxShards = 256
+)
+
+// Index is the contents of IndexFile. The postings of each term are stored in
+// the shard file postings-<n>.json picked by hashing the term (see shardOf).
+type Index struct {
+	// Version is the index format version (see IndexVersion).
+	Version int `json:"version"`
+	// Documents lists the indexed documents in path order. Postings refer to
+	// documents by their position in this list.
+	Documents []IndexedDocument `json:"documents"`
+	// Terms is the sorted vocabulary of the documents.
+	Terms []string `json:"terms"`
+	// Shards is the number of postings files.
+	Shards int `json:"shards"`
+	// Checksums are the SHA-256 checksums of the postings files, hex-encoded, by
+	// shard, so that corrupt files are detected and rebuilt (see loadShard).
+	Checksums []string `json:"checksums"`
+}
+
+// IndexedDocument identifies an indexed document, so that an index no longer
+// matching the docs/ directory is detected and ignored.
+type IndexedDocument struct {
+	// Path is the slash-separated path of the document relative to the skill directory.
+	Path string `json:"path"`
+	// Size and ModTime are those of the file when it was indexed.
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	// SourceURL, FetchedAt, Locale, Tags, and Access are those of the document's
+	// frontmatter, so that filtered searches (see SearchOptions.accepts) skip it
+	// without reading it.
+	SourceURL string   `json:"source_url,omitempty"`
+	FetchedAt string   `json:"fetched_at,omitempty"`
+	Locale    string   `json:"locale,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+	Access    string   `json:"access,omitempty"`
+	// Title, Summary, and Headings are the title and summary of the
+	// document's frontmatter and the text of its H1 and H2 headings, so that
+	// results are ranked by the fields they match in (see FieldWeights)
+	// without reading them.
+	Title    string   `json:"title,omitempty"`
+	Summary  string   `json:"summary,omitempty"`
+	Headings []string `json:"headings,omitempty"`
+}
+
+// fields returns the weighted fields recorded for the document.
+func (d IndexedDocument) fields() documentFields {
+	return documentFields{title: d.Title, summary: d.Summary, headings: strings.Join(d.Headings, "\n")}
+}
+
+// frontmatter returns the frontmatter fields recorded for the document.
+func (d IndexedDocument) frontmatter() Frontmatter {
+	return Frontmatter{SourceURL: d.SourceURL, FetchedAt: d.FetchedAt, Locale: d.Locale, Tags: d.Tags, Access: d.Access}
+}
+
+// Posting lists the occurrences of a term in one document.
+type Posting struct {
+	// Doc is the position of the document in Index.Documents.
+	Doc int `json:"doc"`
+	// Positions are the positions of the term among the terms of the document
+	// body, in order. Their count is the term frequency.
+	Positions []int `json:"positions"`
+}
+
+// BuildIndex writes the inverted index of the Markdown files in skillDir's
+// docs/ directory to docs/.index/, replacing any previous index. Terms are
+// the lowercased words of the document bodies (frontmatter excluded), recorded
+// with their positions: runs of letters, digits, and underscores, with text in
+// CJK scripts split into overlapping bigrams (see tokenize).
+//
+// SearchDocs uses the index while it matches the docs/ directory, and scans
+// the files otherwise, so an index left behind by hand edits is never wrong,
+// only unused. The index records the checksums of its files, and searches
+// rebuild the files found corrupt.
+//
+// Returns an error if the documents can't be read or the index can't be written.
+func BuildIndex(skillDir string) error {
+	docsDir := filepath.Join(skillDir, "docs")
+	indexDir := filepath.Join(docsDir, IndexDir)
+	if err := os.RemoveAll(indexDir); err != nil {
+		return fmt.Errorf("failed to remove old index: %w", err)
+	}
+
+	docs, err := listDocuments(skillDir)
+	if err != nil {
+		return fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	postings, err := collectPostings(skillDir, docs)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(indexDir, 0755); err != nil {
+		return fmt.Errorf("failed to create index directory: %w", err)
+	}
+	return writeIndex(indexDir, docs, postings, nil)
+}
+
+// writeIndex writes the index of docs, whose terms have postings, to
+// indexDir, which must exist. Given old, the index currently in indexDir, the
+// postings files whose contents don't change aren't written again, and those
+// beyond the new number of shards are removed.
+func writeIndex(indexDir string, docs []IndexedDocument, postings map[string][]Posting, old *Index) error {
+	idx := &Index{Version: IndexVersion, Documents: docs, Terms: make([]string, 0, len(postings))}
+	for term := range postings {
+		idx.Terms = append(idx.Terms, term)
+	}
+	sort.Strings(idx.Terms)
+	idx.Shards = min(maxShards, len(idx.Terms)/termsPerShard+1)
+	idx.Checksums = make([]string, idx.Shards)
+
+	shards := make([]map[string][]Posting, idx.Shards)
+	for i := range shards {
+		shards[i] = make(map[string][]Posting)
+	}
+	for term, p := range postings {
+		shards[shardOf(term, idx.Shards)][term] = p
+	}
+
+	for i, shard := range shards {
+		if old != nil && old.Shards == idx.Shards {
+			data, err := json.Marshal(shard)
+			if err != nil {
+				return fmt.Errorf("failed to encode %s: %w", shardFile(i), err)
+			}
+			if sum := checksum(data); sum == old.Checksums[i] {
+				idx.Checksums[i] = sum
+				continue
+			}
+		}
+		sum, err := writeShard(indexDir, i, shard)
+		if err != nil {
+			return err
+		}
+		idx.Checksums[i] = sum
+	}
+	if old != nil {
+		for i := idx.Shards; i < old.Shards; i++ {
+			os.Remove(filepath.Join(indexDir, shardFile(i)))
+		}
+	}
+	// The index file is written last: an interrupted build leaves no index
+	_, err := writeJSON(filepath.Join(indexDir, IndexFile), idx)
+	return err
+}
+
+// searchIndex answers opts from the index of the skill, reading only the
+// files of the results for their frontmatter and contexts. Queries whose terms
+// are all single index terms are answered from the postings alone; for the
+// others (phrases, or keywords like "api.key"), the postings narrow down the
+// documents to read and match. Returns false if the skill has no usable index
+// (missing, of another version, out of date, or corrupt and read-only) or the
+// query has a term the index can't look up, in which case the docs must be
+// scanned instead. Corrupt index files are rebuilt (see openIndex and loadShard).
+func searchIndex(ctx context.Context, skillDir string, opts SearchOptions) ([]SearchResult, bool, error) {
+	q := parseQuery(opts.Query, opts.MatchAll)
+	exact := true
+	tokens := make(map[string]bool)
+	for _, term := range append(q.terms(), q.excluded...) {
+		terms := tokenize(term)
+		if len(terms) == 0 {
+			// Terms without letters or digits (e.g., "++") can't be looked up
+			return nil, false, nil
+		}
+		// A single CJK character occurs in two bigrams: its postings overcount it
+		if len(terms) != 1 || terms[0] != term || (utf8.RuneCountInString(term) == 1 && isCJK([]rune(term)[0])) {
+			exact = false
+		}
+		for _, t := range terms {
+			tokens[t] = true
+		}
+	}
+
+	idx, ok := openIndex(skillDir)
+	if !ok {
+		return nil, false, nil
+	}
+	docs, err := listDocuments(skillDir)
+	if err != nil || !sameDocuments(docs, idx.Documents) {
+		return nil, false, nil
+	}
+
+	counts, ok, err := countTokens(ctx, skillDir, idx, tokens)
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+
+	matches := make([]int, len(idx.Documents))
+	scores := make([]float64, len(idx.Documents))
+	fields := make([][]string, len(idx.Documents))
+	weights := opts.fieldWeights()
+	var hits []int
+	for i := range idx.Documents {
+		if !opts.accepts(idx.Documents[i].frontmatter()) {
+			continue
+		}
+		if exact {
+			matches[i] = q.matchCounts(func(term string) int { return counts[term][i] })
+		} else if q.mayMatch(func(term string) bool {
+			for _, t := range tokenize(term) {
+				if counts[t][i] == 0 {
+					return false
+				}
+			}
+			return true
+		}) {
+			if err := ctx.Err(); err != nil {
+				return nil, true, err
+			}
+			content, err := os.ReadFile(filepath.Join(skillDir, filepath.FromSlash(idx.Documents[i].Path)))
+			if err != nil {
+				return nil, false, nil
+			}
+			_, body := extractFrontmatter(string(content))
+			matches[i] = q.match(strings.ToLower(body))
+		}
+		if matches[i] > 0 {
+			scores[i], fields[i] = weights.score(idx.Documents[i].fields(), matches[i], func(text string) int { return q.occurrences(text, nil) })
+			hits = append(hits, i)
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if scores[a] != scores[b] {
+			return scores[a] > scores[b]
+		}
+		return matches[a] > matches[b]
+	})
+	if opts.MaxResults > 0 && len(hits) > opts.MaxResults {
+		hits = hits[:opts.MaxResults]
+	}
+
+	results := make([]SearchResult, 0, len(hits))
+	for _, i := range hits {
+		if err := ctx.Err(); err != nil {
+			return nil, true, err
+		}
+		relPath := filepath.FromSlash(idx.Documents[i].Path)
+		content, err := os.ReadFile(filepath.Join(skillDir, relPath))
+		if err != nil {
+			return nil, false, nil
+		}
+		frontmatter, body := extractFrontmatter(string(content))
+		contexts, sections := getContext(body, q.terms(), opts.contextWindow(), opts.Highlight)
+		results = append(results, SearchResult{
+			File:          relPath,
+			Matches:       matches[i],
+			Contexts:      contexts,
+			Sections:      linkSections(relPath, shiftSections(sections, bodyOffset(string(content), body))),
+			SourceURL:     frontmatter.SourceURL,
+			FetchedAt:     frontmatter.FetchedAt,
+			ModifiedAt:    frontmatter.ModifiedAt,
+			Score:         scores[i],
+			MatchedFields: fields[i],
+		})
+	}
+	return results, true, nil
+}
+
+// countTokens returns, for each of tokens, its number of occurrences in each
+// document of idx, loading only the postings files of the terms containing a
+// token. Returns false if a postings file can't be read or repaired.
+func countTokens(ctx context.Context, skillDir string, idx *Index, tokens map[string]bool) (map[string][]int, bool, error) {
+	// A token matches inside any term containing it, since it can't cross
+	// term boundaries; each term occurrence holds strings.Count(term, token) matches
+	weights := make(map[string]map[string]int)
+	byShard := make(map[int][]string)
+	for _, term := range idx.Terms {
+		for token := range tokens {
+			n := strings.Count(term, token)
+			if n == 0 {
+				continue
+			}
+			if weights[term] == nil {
+				weights[term] = make(map[string]int)
+				shard := shardOf(term, idx.Shards)
+				byShard[shard] = append(byShard[shard], term)
+			}
+			weights[term][token] = n
+		}
+	}
+
+	counts := make(map[string][]int, len(tokens))
+	for token := range tokens {
+		counts[token] = make([]int, len(idx.Documents))
+	}
+	for shard, terms := range byShard {
+		if err := ctx.Err(); err != nil {
+			return nil, true, err
+		}
+		postings, ok := loadShard(skillDir, idx, shard)
+		if !ok {
+			return nil, false, nil
+		}
+		for _, term := range terms {
+			for _, p := range postings[term] {
+				if p.Doc < 0 || p.Doc >= len(idx.Documents) {
+					continue
+				}
+				for token, n := range weights[term] {
+					counts[token][p.Doc] += n * len(p.Positions)
+				}
+			}
+		}
+	}
+	return counts, true, nil
+}
+
+// collectPostings returns the postings of every term of docs, the documents of
+// the index of skillDir, and records the frontmatter fields of the filters in docs.
+func collectPostings(skillDir string, docs []IndexedDocument) (map[string][]Posting, error) {
+	postings := make(map[string][]Posting)
+	for i := range docs {
+		if err := indexDocument(skillDir, docs, i, postings); err != nil {
+			return nil, err
+		}
+	}
+	return postings, nil
+}
+
+// indexDocument reads docs[i], a document of the index of skillDir, adds the
+// postings of its terms to postings, and records the frontmatter fields of the
+// filters and the fields of the ranking in docs[i].
+func indexDocument(skillDir string, docs []IndexedDocument, i int, postings map[string][]Posting) error {
+	doc := &docs[i]
+	content, err := os.ReadFile(filepath.Join(skillDir, filepath.FromSlash(doc.Path)))
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", doc.Path, err)
+	}
+	frontmatter, body := extractFrontmatter(string(content))
+	doc.SourceURL = frontmatter.SourceURL
+	doc.FetchedAt = frontmatter.FetchedAt
+	doc.Locale = frontmatter.Locale
+	doc.Tags = frontmatter.Tags
+	doc.Access = frontmatter.Access
+	doc.Title = frontmatter.Title
+	doc.Summary = frontmatter.Summary
+	doc.Headings = topHeadings(body)
+	positions := make(map[string][]int)
+	for pos, term := range tokenize(strings.ToLower(body)) {
+		positions[term] = append(positions[term], pos)
+	}
+	for term, p := range positions {
+		postings[term] = append(postings[term], Posting{Doc: i, Positions: p})
+	}
+	return nil
+}
+
+// listDocuments returns the Markdown files in the docs/ directory of skillDir
+// and its subdirectories, in path order.
+func listDocuments(skillDir string) ([]IndexedDocument, error) {
+	var docs []IndexedDocument
+	err := filepath.Walk(filepath.Join(skillDir, "docs"), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == IndexDir {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || filepath.Ext(path) != ".md" {
+			return nil
+		}
+		rel, err := filepath.Rel(skillDir, path)
+		if err != nil {
+			return err
+		}
+		docs = append(docs, IndexedDocument{Path: filepath.ToSlash(rel), Size: info.Size(), ModTime: info.ModTime().UTC()})
+		return nil
+	})
+	return docs, err
+}
+
+// sameDocuments reports whether two document lists are identical.
+func sameDocuments(a, b []IndexedDocument) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Path != b[i].Path || a[i].Size != b[i].Size || !a[i].ModTime.Equal(b[i].ModTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// tokenize splits lowercased text into terms: runs of letters, digits, and
+// underscores. Chinese, Japanese, and Korean text isn't separated by spaces, so
+// the runs of CJK characters are split into their overlapping bigrams instead
+// ("認証方式" gives "認証", "証方", and "方式"), and a lone CJK character is a
+// term of its own. Any CJK string of two or more characters then occurs in a
+// document only where all of its bigrams do, in sequence.
+func tokenize(text string) []string {
+	var terms []string
+	for _, run := range strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	}) {
+		runes := []rune(run)
+		start := 0
+		for start < len(runes) {
+			cjk := isCJK(runes[start])
+			end := start + 1
+			for end < len(runes) && isCJK(runes[end]) == cjk {
+				end++
+			}
+			switch {
+			case !cjk || end-start == 1:
+				terms = append(terms, string(runes[start:end]))
+			default:
+				for i := start; i+1 < end; i++ {
+					terms = append(terms, string(runes[i:i+2]))
+				}
+			}
+			start = end
+		}
+	}
+	return terms
+}
+
+// isCJK reports whether r is a character of the Chinese, Japanese, or Korean
+// scripts, including the Japanese prolonged sound and iteration marks.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) || r == 'ー' || r == '々'
+}
+
+// shardOf returns the postings file of a term among n.
+func shardOf(term string, n int) int {
+	h := fnv.New32a()
+	h.Write([]byte(term))
+	return int(h.Sum32() % uint32(n))
+}
+
+// shardFile returns the name of the postings file of shard i.
+func shardFile(i int) string {
+	return fmt.Sprintf("postings-%d.json", i)
+}
+
+// writeJSON writes v as JSON to path and returns the data written. The file
+// is replaced atomically, so concurrent searches never read it half-written,
+// and left as it is if its content didn't change.
+func writeJSON(path string, v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", filepath.Base(path), err)
+	}
+	if _, err := atomicfile.WriteFile(path, data); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	return data, nil
+}
+
+// readJSON reads the JSON file at path into v.
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
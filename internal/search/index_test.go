@@ -0,0 +1,115 @@
+package search
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"set the api.key option", []string{"set", "the", "api", "key", "option"}},
+		{"snake_case v2", []string{"snake_case", "v2"}},
+		{"認証方式", []string{"認証", "証方", "方式"}},
+		{"apiキーで認証します", []string{"api", "キー", "ーで", "で認", "認証", "証し", "しま", "ます"}},
+		{"日 本", []string{"日", "本"}},
+		{"한국어 문서", []string{"한국", "국어", "문서"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := tokenize(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tokenize(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchIndex(t *testing.T) {
+	skillDir := t.TempDir()
+	docs := map[string]string{
+		"install.md":   "---\ntitle: Install\nsource_url: https://example.com/install\n---\n\n# Install\n\nRun `go install example.com/cli@latest`.\nInstallation needs Go.\n",
+		"auth.md":      "---\ntitle: Auth\nsource_url: https://example.com/auth\n---\n\n# Authentication\n\nSet the api.key option.\nTokens authenticate requests.\n",
+		"guide/faq.md": "# FAQ\n\nHow do I install the CLI? See the install guide.\n",
+		"ja/auth.md":   "# 認証\n\nAPIキーで認証します。トークン認証も使えます。\n",
+	}
+	for name, content := range docs {
+		path := filepath.Join(skillDir, "docs", filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	queries := []SearchOptions{
+		{Query: "install"},
+		{Query: "Auth go"},
+		{Query: "install", MaxResults: 1},
+		{Query: "nothing"},
+		{Query: "install -cli"},
+		{Query: "+install go"},
+		{Query: "install go", MatchAll: true},
+		{Query: "api.key"},
+		{Query: `"the install guide" tokens`},
+		{Query: `"set the" -"api.key"`},
+		{Query: "認証"},
+		{Query: "認"},
+		{Query: "トークン認証 install"},
+		{Query: "apiキー"},
+		{Query: "認証 -トークン"},
+	}
+	scanned := make([][]SearchResult, len(queries))
+	for i, opts := range queries {
+		opts.SkillDir = skillDir
+		results, err := SearchDocs(opts)
+		if err != nil {
+			t.Fatalf("SearchDocs(%q) returned error: %v", opts.Query, err)
+		}
+		scanned[i] = results
+	}
+
+	if err := BuildIndex(skillDir); err != nil {
+		t.Fatalf("BuildIndex() returned error: %v", err)
+	}
+	for i, opts := range queries {
+		opts.SkillDir = skillDir
+		results, ok, err := searchIndex(context.Background(), skillDir, opts)
+		if !ok || err != nil {
+			t.Fatalf("searchIndex(%q) = %v, %v; want the index to answer", opts.Query, ok, err)
+		}
+		if len(results) == 0 && len(scanned[i]) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(results, scanned[i]) {
+			t.Errorf("searchIndex(%q) = %+v, scanning found %+v", opts.Query, results, scanned[i])
+		}
+	}
+
+	// Terms without letters or digits are left to scanning
+	if _, ok, _ := searchIndex(context.Background(), skillDir, SearchOptions{SkillDir: skillDir, Query: "install `"}); ok {
+		t.Error("searchIndex() answered a term without letters or digits")
+	}
+	results, err := SearchDocs(SearchOptions{SkillDir: skillDir, Query: "install `"})
+	if err != nil || len(results) != 2 {
+		t.Errorf("SearchDocs(install `) = %+v, %v; want install.md and faq.md", results, err)
+	}
+
+	// An index no longer matching the docs is ignored
+	if err := os.WriteFile(filepath.Join(skillDir, "docs", "new.md"), []byte("Reinstall everything.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := searchIndex(context.Background(), skillDir, SearchOptions{SkillDir: skillDir, Query: "install"}); ok {
+		t.Error("searchIndex() used an out-of-date index")
+	}
+	results, err = SearchDocs(SearchOptions{SkillDir: skillDir, Query: "reinstall"})
+	if err != nil || len(results) != 1 {
+		t.Errorf("SearchDocs(reinstall) = %+v, %v; want new.md", results, err)
+	}
+}
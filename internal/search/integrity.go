@@ -0,0 +1,129 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the detection and repair of corrupt index files, so a damaged
+// index is rebuilt from docs/ instead of failing or misleading searches.
+package search
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// repairMu serializes the repairs of indexes by concurrent searches.
+var repairMu sync.Mutex
+
+// openIndex reads the index of the skill at skillDir. An index file that isn't
+// valid JSON, or that doesn't list the checksums of its postings files, is
+// corrupt: the whole index is then rebuilt from docs/. Returns false if the
+// skill has no index, has one of another version, or the corrupt index can't
+// be rebuilt (e.g., in a read-only skill directory).
+func openIndex(skillDir string) (*Index, bool) {
+	path := filepath.Join(skillDir, "docs", IndexDir, IndexFile)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var idx Index
+	if err := json.Unmarshal(data, &idx); err == nil && idx.Version != IndexVersion {
+		return nil, false
+	} else if err == nil && idx.Shards >= 1 && len(idx.Checksums) == idx.Shards {
+		return &idx, true
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: search index %s is corrupt; rebuilding it\n", path)
+	repairMu.Lock()
+	defer repairMu.Unlock()
+	if err := BuildIndex(skillDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to rebuild search index: %v\n", err)
+		return nil, false
+	}
+	idx = Index{}
+	if err := readJSON(path, &idx); err != nil {
+		return nil, false
+	}
+	return &idx, true
+}
+
+// loadShard reads the postings file of shard, checking it against its
+// checksum. A corrupt file is rebuilt from the documents of idx, which must
+// match docs/ (see sameDocuments), and idx is updated with its new checksum.
+// Returns false if the file is missing or can't be repaired.
+func loadShard(skillDir string, idx *Index, shard int) (map[string][]Posting, bool) {
+	path := filepath.Join(skillDir, "docs", IndexDir, shardFile(shard))
+	if _, err := os.Stat(path); err != nil {
+		return nil, false
+	}
+	if postings, ok := readShard(skillDir, idx, shard); ok {
+		return postings, true
+	}
+
+	fmt.Fprintf(os.Stderr, "Warning: search index file %s is corrupt; rebuilding it\n", path)
+	postings, err := repairShard(skillDir, idx, shard)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to rebuild search index file %s: %v\n", path, err)
+		return nil, false
+	}
+	return postings, true
+}
+
+// readShard reads the postings file of shard, checking it against its
+// checksum. Returns false if the file is missing or corrupt.
+func readShard(skillDir string, idx *Index, shard int) (map[string][]Posting, bool) {
+	data, err := os.ReadFile(filepath.Join(skillDir, "docs", IndexDir, shardFile(shard)))
+	if err != nil {
+		return nil, false
+	}
+	var postings map[string][]Posting
+	if checksum(data) != idx.Checksums[shard] || json.Unmarshal(data, &postings) != nil {
+		return nil, false
+	}
+	return postings, true
+}
+
+// repairShard rebuilds the postings file of shard from the documents of idx,
+// writes it and the index file with its new checksum, and returns its postings.
+func repairShard(skillDir string, idx *Index, shard int) (map[string][]Posting, error) {
+	repairMu.Lock()
+	defer repairMu.Unlock()
+
+	all, err := collectPostings(skillDir, idx.Documents)
+	if err != nil {
+		return nil, err
+	}
+	postings := make(map[string][]Posting)
+	for term, p := range all {
+		if shardOf(term, idx.Shards) == shard {
+			postings[term] = p
+		}
+	}
+
+	indexDir := filepath.Join(skillDir, "docs", IndexDir)
+	sum, err := writeShard(indexDir, shard, postings)
+	if err != nil {
+		return nil, err
+	}
+	idx.Checksums[shard] = sum
+	if _, err := writeJSON(filepath.Join(indexDir, IndexFile), idx); err != nil {
+		return nil, err
+	}
+	return postings, nil
+}
+
+// writeShard writes the postings file of shard to indexDir and returns its checksum.
+func writeShard(indexDir string, shard int, postings map[string][]Posting) (string, error) {
+	data, err := writeJSON(filepath.Join(indexDir, shardFile(shard)), postings)
+	if err != nil {
+		return "", err
+	}
+	return checksum(data), nil
+}
+
+// checksum returns the hex-encoded SHA-256 checksum of data.
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
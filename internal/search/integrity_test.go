@@ -0,0 +1,61 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSearchIndexRepairsCorruptFiles(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"install.md": "Run the installer.",
+		"auth.md":    "Use an API token to authenticate.",
+	})
+	opts := SearchOptions{SkillDir: skillDir, Query: "token installer"}
+	scanned, err := SearchDocs(opts)
+	if err != nil {
+		t.Fatalf("SearchDocs() returned error: %v", err)
+	}
+	indexDir := filepath.Join(skillDir, "docs", IndexDir)
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{"postings file with wrong contents", shardFile(0), "{}"},
+		{"postings file that isn't JSON", shardFile(0), "{\"tok"},
+		{"index file that isn't JSON", IndexFile, fmt.Sprintf("{\"version\": %d, \"docu", IndexVersion)},
+		{"index file without checksums", IndexFile, fmt.Sprintf("{\"version\": %d, \"shards\": 1}", IndexVersion)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := BuildIndex(skillDir); err != nil {
+				t.Fatalf("BuildIndex() returned error: %v", err)
+			}
+			good, err := os.ReadFile(filepath.Join(indexDir, tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(indexDir, tt.file), []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			results, ok, err := searchIndex(context.Background(), skillDir, opts)
+			if !ok || err != nil {
+				t.Fatalf("searchIndex() = %v, %v; want the repaired index to answer", ok, err)
+			}
+			if !reflect.DeepEqual(results, scanned) {
+				t.Errorf("searchIndex() = %+v, scanning found %+v", results, scanned)
+			}
+			repaired, err := os.ReadFile(filepath.Join(indexDir, tt.file))
+			if err != nil || string(repaired) != string(good) {
+				t.Errorf("%s = %q, %v; want it rebuilt as %q", tt.file, repaired, err, good)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements admission control and per-query limits for concurrent search callers.
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ErrQueryTooLarge is returned when a query exceeds QueryLimits.MaxQueryTerms.
+var ErrQueryTooLarge = errors.New("query too large")
+
+// QueryLimits bounds the resources a single search query may consume.
+// A zero value for any field means that dimension is unlimited.
+type QueryLimits struct {
+	// Timeout is the maximum wall-clock time a single query may run.
+	Timeout time.Duration
+	// MaxResults caps the number of results returned, regardless of SearchOptions.MaxResults.
+	MaxResults int
+	// MaxQueryTerms caps the number of whitespace-separated keywords in a query.
+	MaxQueryTerms int
+	// MaxContextsPerResult caps the number of context snippets returned for each file.
+	MaxContextsPerResult int
+}
+
+// DefaultQueryLimits are the limits applied by server-style callers that don't configure their own.
+var DefaultQueryLimits = QueryLimits{
+	Timeout:              10 * time.Second,
+	MaxResults:           100,
+	MaxQueryTerms:        32,
+	MaxContextsPerResult: 10,
+}
+
+// DefaultMaxConcurrentQueries is the number of queries the servers let scan
+// the skill at the same time: one per CPU, since a scan is CPU- and disk-bound.
+var DefaultMaxConcurrentQueries = runtime.GOMAXPROCS(0)
+
+// ClampResults returns the number of results to ask for a query wanting n,
+// which is 0 for all: n, or MaxResults if it is set and n is 0 or above it.
+func (q QueryLimits) ClampResults(n int) int {
+	if q.MaxResults > 0 && (n <= 0 || n > q.MaxResults) {
+		return q.MaxResults
+	}
+	return n
+}
+
+// Limiter runs searches on behalf of many concurrent clients.
+// It bounds the number of queries scanning the skill at the same time and applies
+// QueryLimits to each of them, so one pathological query can't starve the others.
+// A Limiter is safe for concurrent use.
+type Limiter struct {
+	// slots is a counting semaphore; each running query holds one element
+	slots chan struct{}
+	// limits are applied to every query run through this limiter
+	limits QueryLimits
+}
+
+// NewLimiter creates a Limiter that allows at most maxConcurrent queries to run at once.
+// If maxConcurrent is less than 1, a single query is allowed at a time.
+func NewLimiter(maxConcurrent int, limits QueryLimits) *Limiter {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	return &Limiter{
+		slots:  make(chan struct{}, maxConcurrent),
+		limits: limits,
+	}
+}
+
+// Limits returns the per-query limits enforced by this limiter.
+func (l *Limiter) Limits() QueryLimits {
+	return l.limits
+}
+
+// Do waits for a free slot and runs fn with ctx bounded by QueryLimits.Timeout,
+// for the work of a server scanning the skill besides Search, such as finding
+// related documents or diagnosing a query. The other limits are up to fn.
+//
+// Waiting for a slot doesn't count against the timeout; it only ends early if
+// ctx is cancelled, in which case fn isn't run and the context error is returned.
+func (l *Limiter) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-l.slots }()
+
+	if l.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, l.limits.Timeout)
+		defer cancel()
+	}
+	return fn(ctx)
+}
+
+// Search waits for a free slot and runs the query with the limiter's QueryLimits applied.
+//
+// Waiting for a slot counts against neither the query timeout nor the result limits;
+// it only ends early if ctx is cancelled. Queries with more terms than
+// QueryLimits.MaxQueryTerms are rejected with ErrQueryTooLarge before taking a slot.
+func (l *Limiter) Search(ctx context.Context, opts SearchOptions) ([]SearchResult, error) {
+	// A regular expression is a single term
+	if l.limits.MaxQueryTerms > 0 && !opts.Regex {
+		if n := len(strings.Fields(opts.Query)); n > l.limits.MaxQueryTerms {
+			return nil, fmt.Errorf("%w: %d terms (limit %d)", ErrQueryTooLarge, n, l.limits.MaxQueryTerms)
+		}
+	}
+	opts.MaxResults = l.limits.ClampResults(opts.MaxResults)
+
+	var results []SearchResult
+	err := l.Do(ctx, func(ctx context.Context) error {
+		var err error
+		results, err = SearchDocsContext(ctx, opts)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if l.limits.MaxContextsPerResult > 0 {
+		for i := range results {
+			if len(results[i].Contexts) > l.limits.MaxContextsPerResult {
+				results[i].Contexts = results[i].Contexts[:l.limits.MaxContextsPerResult]
+			}
+			if len(results[i].Sections) > l.limits.MaxContextsPerResult {
+				results[i].Sections = results[i].Sections[:l.limits.MaxContextsPerResult]
+			}
+		}
+	}
+
+	return results, nil
+}
@@ -0,0 +1,204 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeSkillDocs creates a skill directory with the given docs/ files and returns its path.
+func writeSkillDocs(t *testing.T, docs map[string]string) string {
+	t.Helper()
+	skillDir := t.TempDir()
+	docsDir := filepath.Join(skillDir, "docs")
+	if err := os.MkdirAll(docsDir, 0755); err != nil {
+		t.Fatalf("failed to create docs dir: %v", err)
+	}
+	for name, content := range docs {
+		if err := os.WriteFile(filepath.Join(docsDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+	return skillDir
+}
+
+func TestLimiterSearch(t *testing.T) {
+	docs := make(map[string]string)
+	for i := 0; i < 5; i++ {
+		docs[fmt.Sprintf("doc%d.md", i)] = "---\ntitle: \"Doc\"\n---\n" + strings.Repeat("token line\n\n\n\n\n\n", i+1)
+	}
+	skillDir := writeSkillDocs(t, docs)
+
+	tests := []struct {
+		name        string
+		limits      QueryLimits
+		query       string
+		maxResults  int
+		wantResults int
+		wantErr     error
+	}{
+		{
+			name:        "no limits",
+			query:       "token",
+			wantResults: 5,
+		},
+		{
+			name:        "result cap overrides unlimited request",
+			limits:      QueryLimits{MaxResults: 2},
+			query:       "token",
+			wantResults: 2,
+		},
+		{
+			name:        "smaller request is kept",
+			limits:      QueryLimits{MaxResults: 4},
+			query:       "token",
+			maxResults:  1,
+			wantResults: 1,
+		},
+		{
+			name:    "too many terms",
+			limits:  QueryLimits{MaxQueryTerms: 2},
+			query:   "a b c",
+			wantErr: ErrQueryTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewLimiter(2, tt.limits)
+			results, err := l.Search(context.Background(), SearchOptions{
+				SkillDir:   skillDir,
+				Query:      tt.query,
+				MaxResults: tt.maxResults,
+			})
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Search() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Search() returned error: %v", err)
+			}
+			if len(results) != tt.wantResults {
+				t.Errorf("Search() returned %d results, want %d", len(results), tt.wantResults)
+			}
+		})
+	}
+}
+
+func TestLimiterMaxContextsPerResult(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"many.md": strings.Repeat("match\n\n\n\n\n\n\n\n", 6),
+	})
+
+	l := NewLimiter(1, QueryLimits{MaxContextsPerResult: 2})
+	results, err := l.Search(context.Background(), SearchOptions{SkillDir: skillDir, Query: "match"})
+	if err != nil {
+		t.Fatalf("Search() returned error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results))
+	}
+	if got := len(results[0].Contexts); got != 2 {
+		t.Errorf("got %d contexts, want 2", got)
+	}
+}
+
+func TestLimiterCancelledWhileWaiting(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{"a.md": "hello"})
+	l := NewLimiter(1, QueryLimits{})
+
+	// Occupy the only slot so the next query has to wait.
+	l.slots <- struct{}{}
+	defer func() { <-l.slots }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := l.Search(ctx, SearchOptions{SkillDir: skillDir, Query: "hello"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Search() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestLimiterConcurrentSearches(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"a.md": "alpha beta",
+		"b.md": "beta gamma",
+	})
+	l := NewLimiter(3, DefaultQueryLimits)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results, err := l.Search(context.Background(), SearchOptions{SkillDir: skillDir, Query: "beta"})
+			if err != nil {
+				errs <- err
+				return
+			}
+			if len(results) != 2 {
+				errs <- fmt.Errorf("got %d results, want 2", len(results))
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestSearchDocsContextCancelled(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{"a.md": "hello"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := SearchDocsContext(ctx, SearchOptions{SkillDir: skillDir, Query: "hello"}); !errors.Is(err, context.Canceled) {
+		t.Errorf("SearchDocsContext() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestLimiterDo(t *testing.T) {
+	l := NewLimiter(1, QueryLimits{Timeout: 10 * time.Millisecond})
+	err := l.Do(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do() error = %v, want context.DeadlineExceeded", err)
+	}
+
+	// The slot is released once fn returns
+	ran := false
+	if err := l.Do(context.Background(), func(context.Context) error { ran = true; return nil }); err != nil || !ran {
+		t.Errorf("Do() = %v, ran %v; want fn run", err, ran)
+	}
+}
+
+func TestClampResults(t *testing.T) {
+	tests := []struct {
+		limit, n, want int
+	}{
+		{limit: 0, n: 0, want: 0},
+		{limit: 0, n: 500, want: 500},
+		{limit: 100, n: 0, want: 100},
+		{limit: 100, n: 10, want: 10},
+		{limit: 100, n: 500, want: 100},
+	}
+	for _, tt := range tests {
+		if got := (QueryLimits{MaxResults: tt.limit}).ClampResults(tt.n); got != tt.want {
+			t.Errorf("ClampResults(%d) with limit %d = %d, want %d", tt.n, tt.limit, got, tt.want)
+		}
+	}
+}
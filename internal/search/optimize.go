@@ -0,0 +1,84 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the maintenance of the inverted index of an existing skill.
+package search
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// OptimizeResult reports what OptimizeIndex changed.
+type OptimizeResult struct {
+	// Documents is the number of documents in the new index.
+	Documents int
+	// Added and Removed count the documents of docs/ missing from the old
+	// index and those of the old index no longer in docs/.
+	Added, Removed int
+	// Stale reports whether the old index was missing, of another version, or
+	// out of date, and thus unused by searches.
+	Stale bool
+	// SizeBefore and SizeAfter are the total sizes in bytes of the index files.
+	SizeBefore, SizeAfter int64
+}
+
+// OptimizeIndex rebuilds the inverted index of the skill at skillDir from its
+// docs/ directory (see BuildIndex) and reports the difference with the old
+// one. The index is always written whole, so there are no segments to merge:
+// rebuilding drops the terms of the documents removed or edited by hand since
+// the index was built, and makes searches use the index again when those edits
+// left it out of date.
+//
+// Returns an error if the skill has no docs/ directory or the index can't be built.
+func OptimizeIndex(skillDir string) (*OptimizeResult, error) {
+	docsDir := filepath.Join(skillDir, "docs")
+	if _, err := os.Stat(docsDir); err != nil {
+		return nil, fmt.Errorf("docs directory not found: %s", docsDir)
+	}
+	indexDir := filepath.Join(docsDir, IndexDir)
+
+	res := &OptimizeResult{SizeBefore: dirSize(indexDir)}
+	docs, err := listDocuments(skillDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	var old Index
+	if err := readJSON(filepath.Join(indexDir, IndexFile), &old); err != nil || old.Version != IndexVersion {
+		old = Index{}
+		res.Stale = true
+	} else if !sameDocuments(docs, old.Documents) {
+		res.Stale = true
+	}
+	oldPaths := make(map[string]bool, len(old.Documents))
+	for _, doc := range old.Documents {
+		oldPaths[doc.Path] = true
+	}
+	for _, doc := range docs {
+		if oldPaths[doc.Path] {
+			delete(oldPaths, doc.Path)
+		} else {
+			res.Added++
+		}
+	}
+	res.Removed = len(oldPaths)
+
+	if err := BuildIndex(skillDir); err != nil {
+		return nil, err
+	}
+	res.Documents = len(docs)
+	res.SizeAfter = dirSize(indexDir)
+	return res, nil
+}
+
+// dirSize returns the total size of the files in dir, or 0 if it doesn't exist.
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
@@ -0,0 +1,50 @@
+package search
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOptimizeIndex(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"install.md": "Run the installer.",
+		"auth.md":    "Use an API token.",
+	})
+	if err := BuildIndex(skillDir); err != nil {
+		t.Fatalf("BuildIndex() returned error: %v", err)
+	}
+
+	// Hand edits leave the index out of date
+	docsDir := filepath.Join(skillDir, "docs")
+	if err := os.Remove(filepath.Join(docsDir, "auth.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(docsDir, "limits.md"), []byte("The rate limit is 100 requests per minute."), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := OptimizeIndex(skillDir)
+	if err != nil {
+		t.Fatalf("OptimizeIndex() returned error: %v", err)
+	}
+	if res.Documents != 2 || res.Added != 1 || res.Removed != 1 || !res.Stale || res.SizeBefore == 0 || res.SizeAfter == 0 {
+		t.Errorf("OptimizeIndex() = %+v, want 2 documents, 1 added, 1 removed, stale", res)
+	}
+	if _, ok, err := searchIndex(context.Background(), skillDir, SearchOptions{SkillDir: skillDir, Query: "limit"}); !ok || err != nil {
+		t.Errorf("searchIndex() = %v, %v; want the optimized index to answer", ok, err)
+	}
+
+	res, err = OptimizeIndex(skillDir)
+	if err != nil {
+		t.Fatalf("OptimizeIndex() returned error: %v", err)
+	}
+	if res.Added != 0 || res.Removed != 0 || res.Stale || res.SizeBefore != res.SizeAfter {
+		t.Errorf("OptimizeIndex() of a current index = %+v, want no changes", res)
+	}
+
+	if _, err := OptimizeIndex(t.TempDir()); err == nil {
+		t.Error("OptimizeIndex() accepted a directory without docs/")
+	}
+}
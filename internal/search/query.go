@@ -0,0 +1,125 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the query syntax: keywords, quoted phrases, and
+// required (+) and excluded (-) terms.
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// query is a parsed search query. Terms are lowercased; the whitespace inside
+// phrases is collapsed to single spaces.
+type query struct {
+	// optional terms rank the documents containing them; without required
+	// terms, a document must contain at least one of them
+	optional []string
+	// required terms must all be in a document
+	required []string
+	// excluded terms must not be in a document
+	excluded []string
+}
+
+// parseQuery parses a query string: whitespace-separated terms, each a
+// keyword or a "quoted phrase", optionally prefixed with + (required) or -
+// (excluded). With matchAll, terms without a prefix are required too.
+// A lone + or - is a keyword, and an unterminated quote runs to the end.
+func parseQuery(s string, matchAll bool) query {
+	var q query
+	s = strings.ToLower(s)
+	for {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		if s == "" {
+			return q
+		}
+
+		var op byte
+		if (s[0] == '+' || s[0] == '-') && len(s) > 1 && !unicode.IsSpace(rune(s[1])) {
+			op, s = s[0], s[1:]
+		}
+
+		var term string
+		if s[0] == '"' {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				term, s = s[1:], ""
+			} else {
+				term, s = s[1:end+1], s[end+2:]
+			}
+			term = strings.Join(strings.Fields(term), " ")
+		} else {
+			end := strings.IndexFunc(s, unicode.IsSpace)
+			if end < 0 {
+				end = len(s)
+			}
+			term, s = s[:end], s[end:]
+		}
+		if term == "" {
+			continue
+		}
+
+		switch {
+		case op == '-':
+			q.excluded = append(q.excluded, term)
+		case op == '+' || matchAll:
+			q.required = append(q.required, term)
+		default:
+			q.optional = append(q.optional, term)
+		}
+	}
+}
+
+// terms returns the required and optional terms: those counted as matches
+// and shown in contexts.
+func (q query) terms() []string {
+	return append(append([]string(nil), q.required...), q.optional...)
+}
+
+// match returns the number of occurrences of the terms of q in bodyLower, a
+// lowercased document body, or 0 if the document doesn't satisfy q.
+func (q query) match(bodyLower string) int {
+	return q.matchCounts(func(term string) int {
+		return strings.Count(bodyLower, term)
+	})
+}
+
+// matchCounts is match for a document in which term occurs count(term) times.
+func (q query) matchCounts(count func(term string) int) int {
+	for _, term := range q.excluded {
+		if count(term) > 0 {
+			return 0
+		}
+	}
+	total := 0
+	for _, term := range q.required {
+		n := count(term)
+		if n == 0 {
+			return 0
+		}
+		total += n
+	}
+	for _, term := range q.optional {
+		total += count(term)
+	}
+	return total
+}
+
+// mayMatch reports whether a document may satisfy q, given whether each term
+// may occur in it (may(term) is false only if the term certainly doesn't).
+// Excluded terms are ignored: they can only be checked against the document.
+func (q query) mayMatch(may func(term string) bool) bool {
+	if len(q.required) > 0 {
+		for _, term := range q.required {
+			if !may(term) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, term := range q.optional {
+		if may(term) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		matchAll bool
+		want     query
+	}{
+		{"Rate LIMIT", false, query{optional: []string{"rate", "limit"}}},
+		{"rate limit", true, query{required: []string{"rate", "limit"}}},
+		{`"Rate   limit" token`, false, query{optional: []string{"rate limit", "token"}}},
+		{`+token -deprecated +"api key" install`, false, query{optional: []string{"install"}, required: []string{"token", "api key"}, excluded: []string{"deprecated"}}},
+		{`-deprecated token`, true, query{required: []string{"token"}, excluded: []string{"deprecated"}}},
+		{`a - b +`, false, query{optional: []string{"a", "-", "b", "+"}}},
+		{`"unterminated phrase`, false, query{optional: []string{"unterminated phrase"}}},
+		{`"" +"" token`, false, query{optional: []string{"token"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := parseQuery(tt.query, tt.matchAll); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseQuery(%q, %v) = %+v, want %+v", tt.query, tt.matchAll, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryMatch(t *testing.T) {
+	body := "the rate limit is 100 requests per minute. rate limits apply per token."
+	tests := []struct {
+		query    string
+		matchAll bool
+		want     int
+	}{
+		{"rate install", false, 2},
+		{`"rate limit"`, false, 2},
+		{`"limit is" token`, false, 2},
+		{"+token install", false, 1},
+		{"+install token", false, 0},
+		{"rate -deprecated", false, 2},
+		{"rate -token", false, 0},
+		{"-deprecated", false, 0},
+		{"rate minute", true, 3},
+		{"rate install", true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := parseQuery(tt.query, tt.matchAll).match(body); got != tt.want {
+				t.Errorf("match(%q, %v) = %d, want %d", tt.query, tt.matchAll, got, tt.want)
+			}
+		})
+	}
+}
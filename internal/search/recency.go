@@ -0,0 +1,78 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the recency boost of SearchOptions.RecencyHalfLife.
+package search
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+)
+
+// searchRecent answers a search with SearchOptions.RecencyHalfLife: it ranks
+// every result of the search without it by its score boosted by recency (see
+// boostRecent), then applies MaxResults.
+func searchRecent(ctx context.Context, opts SearchOptions) ([]SearchResult, error) {
+	inner := opts
+	inner.RecencyHalfLife = 0
+	inner.MaxResults = 0
+	results, err := SearchDocsContext(ctx, inner)
+	if err != nil {
+		return nil, err
+	}
+	boostRecent(results, opts.RecencyHalfLife)
+	if opts.MaxResults > 0 && len(results) > opts.MaxResults {
+		results = results[:opts.MaxResults]
+	}
+	return results, nil
+}
+
+// boostRecent sets the Score of results to their score, their rank fusion
+// score in a semantic search and their field-weighted score otherwise,
+// halved for every halfLife their document is older than the most recent one
+// (see resultDate), and sorts them by it, keeping the order of equal scores.
+// Results without a date count as old as the oldest one.
+func boostRecent(results []SearchResult, halfLife time.Duration) {
+	dates := make([]time.Time, len(results))
+	var newest, oldest time.Time
+	for i, r := range results {
+		date, ok := resultDate(r)
+		if !ok {
+			continue
+		}
+		dates[i] = date
+		if newest.IsZero() || date.After(newest) {
+			newest = date
+		}
+		if oldest.IsZero() || date.Before(oldest) {
+			oldest = date
+		}
+	}
+
+	for i := range results {
+		date := dates[i]
+		if date.IsZero() {
+			date = oldest
+		}
+		score := results[i].Score
+		if !newest.IsZero() {
+			score *= math.Exp2(-float64(newest.Sub(date)) / float64(halfLife))
+		}
+		results[i].Score = score
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+}
+
+// resultDate returns the date of the document of r: the time its server
+// reported it was last modified, else the time it was fetched. Returns false
+// if it has neither.
+func resultDate(r SearchResult) (time.Time, bool) {
+	for _, s := range []string{r.ModifiedAt, r.FetchedAt} {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,64 @@
+package search
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSearchDocsRecency(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"archive.md": "---\ntitle: \"Upgrading to v1\"\nfetched_at: \"2024-06-01T00:00:00Z\"\nmodified_at: \"2022-01-01T00:00:00Z\"\n---\n# Upgrade\n\nUpgrade the agent, then upgrade the server. Upgrade guide for v1.\n",
+		"current.md": "---\ntitle: \"Upgrading to v3\"\nfetched_at: \"2024-06-01T00:00:00Z\"\nmodified_at: \"2024-05-01T00:00:00Z\"\n---\n# Upgrade\n\nUpgrade guide for v3.\n",
+		"recent.md":  "---\ntitle: \"Release notes\"\nfetched_at: \"2024-05-01T00:00:00Z\"\n---\n# Release notes\n\nSee the upgrade guide.\n",
+		"undated.md": "# Notes\n\nUpgrade when ready.\n",
+	})
+
+	files := func(results []SearchResult) []string {
+		var got []string
+		for _, r := range results {
+			got = append(got, filepath.Base(r.File))
+		}
+		return got
+	}
+
+	tests := []struct {
+		name       string
+		halfLife   time.Duration
+		maxResults int
+		want       []string
+	}{
+		{"by matches", 0, 1, []string{"archive.md"}},
+		{"boosted", 30 * 24 * time.Hour, 0, []string{"current.md", "recent.md", "archive.md", "undated.md"}},
+		{"boosted max results", 30 * 24 * time.Hour, 2, []string{"current.md", "recent.md"}},
+		// Over ten years, two years make little difference
+		{"long half-life", 10 * 365 * 24 * time.Hour, 0, []string{"archive.md", "current.md", "recent.md", "undated.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := SearchDocs(SearchOptions{SkillDir: skillDir, Query: "upgrade", RecencyHalfLife: tt.halfLife, MaxResults: tt.maxResults})
+			if err != nil {
+				t.Fatalf("SearchDocs() error = %v", err)
+			}
+			if got := files(results); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SearchDocs() = %v, want %v", got, tt.want)
+			}
+			for _, r := range results {
+				// current.md is the most recent, so its score (a match in
+				// an H1 heading and one in the body) isn't lowered
+				if tt.halfLife > 0 && filepath.Base(r.File) == "current.md" && r.Score != 3 {
+					t.Errorf("Score of the most recent result = %v, want 3", r.Score)
+				}
+			}
+		})
+	}
+
+	results, err := SearchDocs(SearchOptions{SkillDir: skillDir, Query: "guide", RecencyHalfLife: time.Hour})
+	if err != nil {
+		t.Fatalf("SearchDocs() error = %v", err)
+	}
+	if len(results) == 0 || results[0].ModifiedAt != "2024-05-01T00:00:00Z" {
+		t.Errorf("SearchDocs() = %+v, want current.md first with its modified_at", results)
+	}
+}
@@ -0,0 +1,61 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the regular expression search mode (SearchOptions.Regex).
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// HighlightOpen and HighlightClose surround the text matched by a regular
+	// expression, or the terms matched with SearchOptions.Highlight, in the
+	// context snippets of SearchResult.
+	HighlightOpen  = "«"
+	HighlightClose = "»"
+)
+
+// compileRegex compiles the query of a regular expression search.
+func compileRegex(query string) (*regexp.Regexp, error) {
+	re, err := regexp.Compile(query)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	return re, nil
+}
+
+// matchRegex evaluates re against each line of body and returns the number of
+// non-empty matches and the context snippets of the matched lines, with the
+// matched text between HighlightOpen and HighlightClose and window lines
+// around them, and their sections (see getContext).
+func matchRegex(body string, re *regexp.Regexp, window int) (int, []string, []Section) {
+	original := strings.Split(body, "\n")
+	lines := append([]string(nil), original...)
+	count := 0
+	var matchIndices []int
+	for i, line := range lines {
+		var b strings.Builder
+		last := 0
+		for _, loc := range re.FindAllStringIndex(line, -1) {
+			// Empty matches (e.g., of "x*") would match every line
+			if loc[0] == loc[1] {
+				continue
+			}
+			b.WriteString(line[last:loc[0]])
+			b.WriteString(HighlightOpen + line[loc[0]:loc[1]] + HighlightClose)
+			last = loc[1]
+			count++
+		}
+		if last > 0 {
+			b.WriteString(line[last:])
+			lines[i] = b.String()
+			matchIndices = append(matchIndices, i)
+		}
+	}
+	if count == 0 {
+		return 0, nil, nil
+	}
+	groups := groupMatches(matchIndices, window)
+	return count, snippets(lines, groups, window), sectionsOf(original, groups)
+}
@@ -0,0 +1,66 @@
+package search
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchRegex(t *testing.T) {
+	body := "# Errors\n\nCall OnErrorResume or OnErrorResumeNext.\n\nSee onerrorresume.\nx\ny"
+	tests := []struct {
+		name      string
+		query     string
+		wantCount int
+		wantCtx   []string
+	}{
+		{
+			name:      "optional group, case-sensitive",
+			query:     `OnErrorResume(Next)?`,
+			wantCount: 2,
+			wantCtx:   []string{"  # Errors\n  \n> Call «OnErrorResume» or «OnErrorResumeNext».\n  \n  See onerrorresume."},
+		},
+		{
+			name:      "case-insensitive",
+			query:     `(?i)^see \w+`,
+			wantCount: 1,
+			wantCtx:   []string{"  Call OnErrorResume or OnErrorResumeNext.\n  \n> «See onerrorresume».\n  x\n  y"},
+		},
+		{
+			name:      "empty matches are ignored",
+			query:     `z*`,
+			wantCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			re, err := compileRegex(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			count, contexts, _ := matchRegex(body, re, defaultContextLines)
+			if count != tt.wantCount || !reflect.DeepEqual(contexts, tt.wantCtx) {
+				t.Errorf("matchRegex(%q) = %d, %q; want %d, %q", tt.query, count, contexts, tt.wantCount, tt.wantCtx)
+			}
+		})
+	}
+}
+
+func TestSearchDocsRegex(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"a.md": "Use OnErrorResumeNext here.",
+		"b.md": "Nothing to see.",
+	})
+
+	results, err := SearchDocs(SearchOptions{SkillDir: skillDir, Query: `OnError\w+`, Regex: true})
+	if err != nil {
+		t.Fatalf("SearchDocs() returned error: %v", err)
+	}
+	if files := resultFiles(results); !reflect.DeepEqual(files, []string{"a.md"}) {
+		t.Errorf("SearchDocs() matched %v, want [a.md]", files)
+	}
+
+	if _, err := SearchDocs(SearchOptions{SkillDir: skillDir, Query: `OnError(`, Regex: true}); err == nil {
+		t.Error("SearchDocs() accepted an invalid regular expression")
+	}
+}
@@ -0,0 +1,236 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the search of the documents related to another (see
+// Searcher.Related), for "see also" lists and for agents widening their context
+// from a seed page.
+package search
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/f4ah6o/site2skill-go/internal/embeddings"
+)
+
+// RelatedDocument is a document similar to the seed of Searcher.Related.
+type RelatedDocument struct {
+	DocumentInfo
+	// Similarity is the cosine similarity of the document to the seed, from 0
+	// (nothing in common) to 1.
+	Similarity float64 `json:"similarity"`
+}
+
+// Related returns the documents most similar to the document at path (see
+// Document for the accepted paths), most similar first, leaving out those with
+// nothing in common with it.
+//
+// Documents are compared by their term vectors: the words of their bodies
+// without the stop words of their language, weighted by TF-IDF across the
+// documents compared. With opts.Semantic, they are compared by the mean of the
+// embeddings of their sections instead (see BuildEmbeddings), which finds
+// documents about the same subject in other words; no query is embedded, so
+// no provider is called.
+//
+// The candidates are the documents passing the filters of opts (see
+// Documents), up to opts.MaxResults of them (0 means unlimited); the seed only
+// needs to pass its access levels. The other options are ignored.
+//
+// Returns an error wrapping ErrDocumentNotFound if the seed can't be read (see
+// Document), or an error if ctx is cancelled, the documents can't be read, or
+// opts.Semantic is set and the skill has no embeddings.
+func (s *Searcher) Related(ctx context.Context, path string, opts SearchOptions) ([]RelatedDocument, error) {
+	_, seed, err := s.Document(path, SearchOptions{Access: opts.Access})
+	if err != nil {
+		return nil, err
+	}
+
+	var related []RelatedDocument
+	if opts.Semantic {
+		related, err = s.relatedByEmbeddings(ctx, seed.Path, opts)
+	} else {
+		related, err = s.relatedByTerms(ctx, seed.Path, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(related, func(i, j int) bool {
+		if related[i].Similarity != related[j].Similarity {
+			return related[i].Similarity > related[j].Similarity
+		}
+		return related[i].Path < related[j].Path
+	})
+	if opts.MaxResults > 0 && len(related) > opts.MaxResults {
+		related = related[:opts.MaxResults]
+	}
+	return related, nil
+}
+
+// relatedByTerms returns the candidates of opts with a term in common with the
+// document at seedPath, with the cosine similarity of their TF-IDF vectors.
+func (s *Searcher) relatedByTerms(ctx context.Context, seedPath string, opts SearchOptions) ([]RelatedDocument, error) {
+	docs, err := listDocuments(s.skillDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	// The term frequencies of every document count toward the document
+	// frequencies, even those left out of the candidates
+	type candidate struct {
+		info  DocumentInfo
+		terms map[string]int
+	}
+	var candidates []candidate
+	var seed map[string]int
+	df := make(map[string]int)
+	for _, d := range docs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(filepath.Join(s.skillDir, filepath.FromSlash(d.Path)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", d.Path, err)
+		}
+		fm, body := extractFrontmatter(string(content))
+		terms := termFrequencies(body, stopWordSet(opts.StopWords, documentLanguage(fm)))
+		for term := range terms {
+			df[term]++
+		}
+		switch {
+		case d.Path == seedPath:
+			seed = terms
+		case opts.accepts(fm):
+			candidates = append(candidates, candidate{info: fm.info(d.Path), terms: terms})
+		}
+	}
+
+	n := float64(len(docs))
+	weigh := func(terms map[string]int) map[string]float64 {
+		v := make(map[string]float64, len(terms))
+		for term, tf := range terms {
+			// Terms of every document tell nothing apart
+			if idf := math.Log(n / float64(df[term])); idf > 0 {
+				v[term] = (1 + math.Log(float64(tf))) * idf
+			}
+		}
+		return v
+	}
+	seedVector := weigh(seed)
+	var related []RelatedDocument
+	for _, c := range candidates {
+		if sim := sparseCosine(seedVector, weigh(c.terms)); sim > 0 {
+			related = append(related, RelatedDocument{DocumentInfo: c.info, Similarity: sim})
+		}
+	}
+	return related, nil
+}
+
+// termFrequencies returns the number of occurrences of the terms of text (see
+// tokenize), leaving out the stop words and the numbers.
+func termFrequencies(text string, stop map[string]bool) map[string]int {
+	terms := make(map[string]int)
+	for _, term := range tokenize(strings.ToLower(text)) {
+		if stop[term] || strings.IndexFunc(term, func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+			continue
+		}
+		terms[term]++
+	}
+	return terms
+}
+
+// sparseCosine returns the cosine similarity of two sparse vectors.
+func sparseCosine(a, b map[string]float64) float64 {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	var dot, na, nb float64
+	for term, x := range a {
+		dot += x * b[term]
+		na += x * x
+	}
+	for _, x := range b {
+		nb += x * x
+	}
+	if dot == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// relatedByEmbeddings returns the candidates of opts with the cosine similarity
+// of the mean embedding of their sections to that of the document at seedPath.
+func (s *Searcher) relatedByEmbeddings(ctx context.Context, seedPath string, opts SearchOptions) ([]RelatedDocument, error) {
+	emb, err := LoadEmbeddings(s.skillDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("skill has no embeddings for semantic similarity: generate it with embeddings or run 'site2skillgo index embed'")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// The chunks of a document are contiguous, in path order
+	means := make(map[string][]float32)
+	var paths []string
+	for start := 0; start < len(emb.Chunks); {
+		end := start + 1
+		for end < len(emb.Chunks) && emb.Chunks[end].Path == emb.Chunks[start].Path {
+			end++
+		}
+		paths = append(paths, emb.Chunks[start].Path)
+		means[emb.Chunks[start].Path] = meanVector(emb.Chunks[start:end])
+		start = end
+	}
+	seed, ok := means[seedPath]
+	if !ok {
+		return nil, fmt.Errorf("%s has no embeddings: run 'site2skillgo index embed' to embed the documents added since", seedPath)
+	}
+
+	var related []RelatedDocument
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if path == seedPath {
+			continue
+		}
+		sim := embeddings.Cosine(seed, means[path])
+		if sim <= 0 {
+			continue
+		}
+		// Documents removed since the skill was embedded are skipped
+		content, err := os.ReadFile(filepath.Join(s.skillDir, filepath.FromSlash(path)))
+		if err != nil {
+			continue
+		}
+		fm, _ := extractFrontmatter(string(content))
+		if opts.accepts(fm) {
+			related = append(related, RelatedDocument{DocumentInfo: fm.info(path), Similarity: sim})
+		}
+	}
+	return related, nil
+}
+
+// meanVector returns the mean of the normalized vectors of chunks, so that
+// every section weighs the same whatever the norm of its embedding.
+func meanVector(chunks []EmbeddedChunk) []float32 {
+	mean := make([]float32, len(chunks[0].Vector))
+	for _, c := range chunks {
+		var norm float64
+		for _, x := range c.Vector {
+			norm += float64(x) * float64(x)
+		}
+		if norm == 0 || len(c.Vector) != len(mean) {
+			continue
+		}
+		norm = math.Sqrt(norm)
+		for i, x := range c.Vector {
+			mean[i] += float32(float64(x) / norm)
+		}
+	}
+	return mean
+}
@@ -0,0 +1,82 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/f4ah6o/site2skill-go/internal/embeddings"
+)
+
+func TestRelated(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"auth.md":     "---\ntitle: \"Auth\"\n---\n# Authentication\n\nCreate an API token and send the token in the Authorization header. Tokens expire.\n",
+		"tokens.md":   "---\ntitle: \"Tokens\"\n---\n# Rotating tokens\n\nRotate the API token before it expires: create a new token, then revoke the old token.\n",
+		"internal.md": "---\ntitle: \"Runbook\"\naccess: \"internal\"\n---\n# Token runbook\n\nRevoke a leaked token and rotate the token of the service.\n",
+		"header.md":   "---\ntitle: \"Headers\"\n---\n# Headers\n\nThe Authorization header carries credentials.\n",
+		"install.md":  "---\ntitle: \"Install\"\n---\n# Install\n\nDownload the binary for your platform and unpack the archive.\n",
+	})
+	s, err := NewSearcher(skillDir)
+	if err != nil {
+		t.Fatalf("NewSearcher() error = %v", err)
+	}
+
+	paths := func(related []RelatedDocument) []string {
+		var got []string
+		for _, r := range related {
+			got = append(got, r.Path)
+			if r.Similarity <= 0 || r.Similarity > 1.0001 {
+				t.Errorf("%s similarity = %v, want in (0, 1]", r.Path, r.Similarity)
+			}
+		}
+		return got
+	}
+
+	tests := []struct {
+		name string
+		path string
+		opts SearchOptions
+		want []string
+	}{
+		{"term vectors", "docs/auth.md", SearchOptions{}, []string{"docs/tokens.md", "docs/header.md", "docs/internal.md"}},
+		{"access", "auth.md", SearchOptions{Access: []string{"public"}}, []string{"docs/tokens.md", "docs/header.md"}},
+		{"max results", "/docs/auth.md", SearchOptions{MaxResults: 1}, []string{"docs/tokens.md"}},
+		{"nothing in common", "docs/install.md", SearchOptions{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			related, err := s.Related(context.Background(), tt.path, tt.opts)
+			if err != nil {
+				t.Fatalf("Related() error = %v", err)
+			}
+			if got := paths(related); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Related(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+
+	if _, err := s.Related(context.Background(), "docs/internal.md", SearchOptions{Access: []string{"public"}}); !errors.Is(err, ErrDocumentNotFound) {
+		t.Errorf("Related() of a document not allowed error = %v, want ErrDocumentNotFound", err)
+	}
+	if _, err := s.Related(context.Background(), "docs/auth.md", SearchOptions{Semantic: true}); err == nil {
+		t.Error("Related() with Semantic and no embeddings succeeded, want error")
+	}
+
+	if _, err := BuildEmbeddings(context.Background(), skillDir, embeddings.NewHash(256), nil); err != nil {
+		t.Fatalf("BuildEmbeddings() error = %v", err)
+	}
+	related, err := s.Related(context.Background(), "docs/auth.md", SearchOptions{Semantic: true, Access: []string{"public"}})
+	if err != nil {
+		t.Fatalf("Related() with Semantic error = %v", err)
+	}
+	got := paths(related)
+	if len(got) == 0 || got[0] != "docs/tokens.md" {
+		t.Errorf("Related() with Semantic = %v, want docs/tokens.md first", got)
+	}
+	for _, p := range got {
+		if p == "docs/auth.md" || p == "docs/internal.md" {
+			t.Errorf("Related() with Semantic = %v, want neither the seed nor internal documents", got)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the scan of the documents of a skill when its index
+// isn't used: the documents are read and matched concurrently by a bounded
+// pool of workers (see SearchOptions.Workers).
+package search
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/f4ah6o/site2skill-go/internal/lang"
+)
+
+// minScanWorkers is the least number of workers of a scan when
+// SearchOptions.Workers is 0: scanning waits on reads more than on the CPU,
+// notably on network filesystems, so even a single CPU keeps several reads in
+// flight.
+const minScanWorkers = 4
+
+// workers returns the number of documents opts scans concurrently: Workers,
+// or the number of CPUs, at least minScanWorkers, if it is 0 or less.
+func (opts SearchOptions) workers() int {
+	if opts.Workers > 0 {
+		return opts.Workers
+	}
+	return max(minScanWorkers, runtime.GOMAXPROCS(0))
+}
+
+// scanJob is a Markdown file of the docs directory to scan, with its rank in
+// the walk of the directory.
+type scanJob struct {
+	seq  int
+	path string
+}
+
+// scanned is a document scanned by a worker of scanDocs: its rank in the walk
+// of the docs directory, and its result, nil when it doesn't match.
+type scanned struct {
+	seq    int
+	result *SearchResult
+}
+
+// scanDocs searches the Markdown files of docsDir, in the skill absSkillDir,
+// for q, or re if it isn't nil, and passes the results to found in order
+// until it returns false.
+//
+// The files are walked in the order of their paths and scanned by
+// opts.workers() goroutines. The results are ranked by score, then by number
+// of matches, then by path, and limited to opts.MaxResults once every file is
+// scanned, since any file could outrank the results found so far. With
+// opts.Stream, the results are passed in the order of their paths as soon as
+// the files before them are scanned, and the scan stops once found returns
+// false or MaxResults results are passed.
+func scanDocs(ctx context.Context, absSkillDir, docsDir string, opts SearchOptions, q query, re *regexp.Regexp, found func(SearchResult) bool) error {
+	scanCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan scanJob)
+	walkErrc := make(chan error, 1)
+	go func() {
+		defer close(jobs)
+		seq := 0
+		walkErrc <- filepath.Walk(docsDir, func(path string, info os.FileInfo, walkFuncErr error) error {
+			if walkFuncErr != nil {
+				return walkFuncErr
+			}
+			if info.IsDir() || filepath.Ext(path) != ".md" {
+				return nil
+			}
+			select {
+			case jobs <- scanJob{seq: seq, path: path}:
+				seq++
+				return nil
+			case <-scanCtx.Done():
+				return scanCtx.Err()
+			}
+		})
+	}()
+
+	out := make(chan scanned)
+	var wg sync.WaitGroup
+	for range opts.workers() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				if scanCtx.Err() != nil {
+					return
+				}
+				s := scanned{seq: job.seq}
+				if result, ok := scanDocument(absSkillDir, job.path, opts, q, re); ok {
+					s.result = &result
+				}
+				select {
+				case out <- s:
+				case <-scanCtx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	var matched []scanned
+	// The streamed results wait in pending until the files walked before
+	// them are scanned, next being the rank of the first file not passed yet
+	pending := make(map[int]*SearchResult)
+	next, streamed, stopped := 0, 0, false
+	for s := range out {
+		if !opts.Stream {
+			if s.result != nil {
+				matched = append(matched, s)
+			}
+			continue
+		}
+		if stopped {
+			continue
+		}
+		pending[s.seq] = s.result
+		for {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if result == nil {
+				continue
+			}
+			streamed++
+			if !found(*result) || (opts.MaxResults > 0 && streamed >= opts.MaxResults) {
+				stopped = true
+				cancel()
+				break
+			}
+		}
+	}
+
+	walkErr := <-walkErrc
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if walkErr != nil && !stopped {
+		return walkErr
+	}
+	if opts.Stream {
+		return nil
+	}
+
+	// Sort by score, then by matches count (descending), then in the order
+	// of the walk
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i].result, matched[j].result
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Matches != b.Matches {
+			return a.Matches > b.Matches
+		}
+		return matched[i].seq < matched[j].seq
+	})
+
+	// Limit results
+	if opts.MaxResults > 0 && len(matched) > opts.MaxResults {
+		matched = matched[:opts.MaxResults]
+	}
+
+	for _, s := range matched {
+		if !found(*s.result) {
+			break
+		}
+	}
+	return nil
+}
+
+// scanDocument searches the Markdown file at path, in the skill absSkillDir,
+// for q, or re if it isn't nil, and returns its result, or false if it
+// doesn't match or isn't accepted by the filters of opts. A file that can't
+// be read is reported on stderr and doesn't match.
+func scanDocument(absSkillDir, path string, opts SearchOptions, q query, re *regexp.Regexp) (SearchResult, bool) {
+	// Read file content
+	content, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", path, err)
+		return SearchResult{}, false
+	}
+
+	// Extract frontmatter and body
+	frontmatter, body := extractFrontmatter(string(content))
+	if !opts.accepts(frontmatter) {
+		return SearchResult{}, false
+	}
+
+	// Count matches
+	var matchesCount int
+	var contexts, matchedTerms []string
+	var sections []Section
+	if re != nil {
+		matchesCount, contexts, sections = matchRegex(body, re, opts.contextWindow())
+	} else if opts.Fuzzy || opts.Stem {
+		docQuery := q
+		var stem func(string) string
+		if opts.Stem {
+			language := documentLanguage(frontmatter)
+			stem = lang.Stemmer(language)
+			docQuery = q.withoutStopWords(stopWordSet(opts.StopWords, language))
+		}
+		m := newWordMatcher(docQuery, strings.ToLower(body), opts.Fuzzy, stem)
+		if matchesCount = m.match(); matchesCount > 0 {
+			matchedTerms = m.matchedWords()
+			contexts, sections = getContext(body, append(docQuery.terms(), matchedTerms...), opts.contextWindow(), opts.Highlight)
+		}
+	} else if matchesCount = q.match(strings.ToLower(body)); matchesCount > 0 {
+		contexts, sections = getContext(body, q.terms(), opts.contextWindow(), opts.Highlight)
+	}
+
+	if matchesCount == 0 {
+		return SearchResult{}, false
+	}
+	relPath, _ := filepath.Rel(absSkillDir, path)
+	result := SearchResult{
+		File:         relPath,
+		Matches:      matchesCount,
+		MatchedTerms: matchedTerms,
+		Contexts:     contexts,
+		Sections:     linkSections(relPath, shiftSections(sections, bodyOffset(string(content), body))),
+		SourceURL:    frontmatter.SourceURL,
+		FetchedAt:    frontmatter.FetchedAt,
+		ModifiedAt:   frontmatter.ModifiedAt,
+	}
+	count := func(text string) int { return q.occurrences(text, matchedTerms) }
+	if re != nil {
+		count = func(text string) int { return len(re.FindAllStringIndex(text, -1)) }
+	}
+	result.Score, result.MatchedFields = opts.fieldWeights().score(newDocumentFields(frontmatter.Title, frontmatter.Summary, body), matchesCount, count)
+	return result, true
+}
@@ -0,0 +1,65 @@
+package search
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScanWorkers(t *testing.T) {
+	// Documents matching the same number of times tie: they keep the order
+	// of their paths whatever the order they are scanned in
+	docs := make(map[string]string)
+	for i := range 40 {
+		docs[fmt.Sprintf("page%02d.md", i)] = fmt.Sprintf("---\ntitle: Page %d\n---\n%s\n", i, strings.Repeat("deploy ", 1+i%3))
+	}
+	docs["other.md"] = "---\ntitle: Other\n---\nNothing to see.\n"
+	skillDir := writeSkillDocs(t, docs)
+
+	files := func(results []SearchResult) []string {
+		var names []string
+		for _, r := range results {
+			names = append(names, r.File)
+		}
+		return names
+	}
+	search := func(opts SearchOptions) []string {
+		t.Helper()
+		opts.SkillDir, opts.Query, opts.Fuzzy = skillDir, "deploy", true
+		results, err := SearchDocs(opts)
+		if err != nil {
+			t.Fatalf("SearchDocs(%+v) error = %v", opts, err)
+		}
+		return files(results)
+	}
+
+	tests := []struct {
+		name string
+		opts SearchOptions
+	}{
+		{"ranked", SearchOptions{}},
+		{"ranked max results", SearchOptions{MaxResults: 7}},
+		{"streamed", SearchOptions{Stream: true}},
+		{"streamed max results", SearchOptions{Stream: true, MaxResults: 5}},
+	}
+	for _, tt := range tests {
+		opts := tt.opts
+		opts.Workers = 1
+		want := search(opts)
+		if len(want) == 0 {
+			t.Fatalf("%s: no results", tt.name)
+		}
+		for _, workers := range []int{0, 3, 16} {
+			opts.Workers = workers
+			if got := search(opts); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s with %d workers = %v, want %v", tt.name, workers, got, want)
+			}
+		}
+	}
+
+	// Streamed results come in the order of their paths
+	if got := search(SearchOptions{Stream: true, MaxResults: 3, Workers: 8}); !reflect.DeepEqual(got, []string{"docs/page00.md", "docs/page01.md", "docs/page02.md"}) {
+		t.Errorf("streamed results = %v, want the first 3 pages", got)
+	}
+}
@@ -1,7 +1,8 @@
 // Package search provides full-text search functionality for documentation files in skill packages.
 //
 // It enables searching through generated skill documentation files and returning relevant
-// results with surrounding context. Results can be formatted as human-readable output or JSON.
+// results with surrounding context. Results can be formatted as human-readable output, JSON,
+// Markdown, or CSV (see WriteResults).
 //
 // Example:
 //
@@ -18,19 +19,22 @@
 package search
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
-	"sort"
 	"strings"
 
+	"github.com/f4ah6o/site2skill-go/internal/mdprose"
 	"github.com/fatih/color"
+	"gopkg.in/yaml.v3"
 )
 
 const (
-	contextLines = 2
+	// defaultContextLines is the number of lines shown before and after the
+	// matched lines of a context when SearchOptions.ContextLines is 0.
+	defaultContextLines = 2
 )
 
 var (
@@ -49,7 +53,9 @@ var (
 // ---
 // Content here...
 //
-// It extracts title, source_url, and fetched_at fields if present.
+// It extracts the fields of Frontmatter that are present. Frontmatter that isn't
+// valid YAML (e.g., hand-edited files) is read as "key: value" lines, which only
+// yields title, source_url, and fetched_at.
 // Returns the parsed Frontmatter struct and the remaining body content.
 func extractFrontmatter(content string) (Frontmatter, string) {
 	fm := Frontmatter{
@@ -57,16 +63,15 @@ func extractFrontmatter(content string) (Frontmatter, string) {
 		FetchedAt: "Unknown",
 	}
 
-	// Match frontmatter: ---\n...\n---\n
-	re := regexp.MustCompile(`(?s)^---\s*\n(.*?)\n---\s*\n(.*)`)
-	matches := re.FindStringSubmatch(content)
-
-	if len(matches) < 3 {
+	frontmatterStr, body, ok := mdprose.SplitFrontmatter(content)
+	if !ok {
 		return fm, content
 	}
 
-	frontmatterStr := matches[1]
-	body := matches[2]
+	parsed := fm
+	if err := yaml.Unmarshal([]byte(frontmatterStr), &parsed); err == nil {
+		return parsed, body
+	}
 
 	// Parse simple YAML key-value pairs
 	lines := strings.Split(frontmatterStr, "\n")
@@ -92,38 +97,101 @@ func extractFrontmatter(content string) (Frontmatter, string) {
 	return fm, body
 }
 
-// getContext finds keyword matches in text and extracts surrounding context lines.
+// getContext finds matches of the lowercased terms (keywords or phrases) in text and
+// extracts surrounding context lines.
 //
-// It searches for all keywords in the query (space-separated) and groups nearby matches.
-// For each group, it returns contextLines lines before and after the match. Matched lines
-// are prefixed with "> " and context lines with "  " for easy identification.
-func getContext(text, query string) []string {
-	lines := strings.Split(text, "\n")
-	keywords := strings.Fields(strings.ToLower(query))
-	var contexts []string
+// It groups nearby matches. For each group, it returns window lines before and after
+// the match. Matched lines are prefixed with "> " and context lines with "  " for easy
+// identification; with highlight, the occurrences of the terms in the matched lines are
+// also surrounded with HighlightOpen and HighlightClose. It also returns the section of
+// text containing each group, with lines numbered from the first line of text.
+func getContext(text string, keywords []string, window int, highlight bool) ([]string, []Section) {
+	original := strings.Split(text, "\n")
+	lines := original
+	if highlight {
+		lines = append([]string(nil), original...)
+	}
 
 	// Find all matching line indices
 	var matchIndices []int
-	for i, line := range lines {
+	for i, line := range original {
 		lineLower := strings.ToLower(line)
 		for _, kw := range keywords {
 			if strings.Contains(lineLower, kw) {
 				matchIndices = append(matchIndices, i)
+				if highlight {
+					lines[i] = highlightTerms(line, lineLower, keywords)
+				}
+				break
+			}
+		}
+	}
+	groups := groupMatches(matchIndices, window)
+	return snippets(lines, groups, window), sectionsOf(original, groups)
+}
+
+// highlightTerms surrounds the occurrences of the lowercased terms in line, whose
+// lowercase is lineLower, with HighlightOpen and HighlightClose, merging those
+// that overlap. The line is returned as is if lowercasing changed its length, as
+// the offsets of the occurrences wouldn't match it.
+func highlightTerms(line, lineLower string, terms []string) string {
+	if len(line) != len(lineLower) {
+		return line
+	}
+	// covered[i] reports whether byte i of line is part of an occurrence
+	covered := make([]bool, len(line))
+	for _, term := range terms {
+		if term == "" {
+			continue
+		}
+		for from := 0; ; {
+			i := strings.Index(lineLower[from:], term)
+			if i < 0 {
 				break
 			}
+			for j := from + i; j < from+i+len(term); j++ {
+				covered[j] = true
+			}
+			from += i + len(term)
 		}
 	}
 
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		if covered[i] && (i == 0 || !covered[i-1]) {
+			b.WriteString(HighlightOpen)
+		}
+		b.WriteByte(line[i])
+		if covered[i] && (i == len(line)-1 || !covered[i+1]) {
+			b.WriteString(HighlightClose)
+		}
+	}
+	return b.String()
+}
+
+// contextWindow returns the number of context lines of opts (see SearchOptions.ContextLines).
+func (opts SearchOptions) contextWindow() int {
+	switch {
+	case opts.ContextLines == 0:
+		return defaultContextLines
+	case opts.ContextLines < 0:
+		return 0
+	}
+	return opts.ContextLines
+}
+
+// groupMatches groups nearby matched lines, given by their ascending indices:
+// lines whose contexts of window lines would overlap or touch.
+func groupMatches(matchIndices []int, window int) [][]int {
 	if len(matchIndices) == 0 {
-		return contexts
+		return nil
 	}
 
-	// Group nearby matches
 	var groups [][]int
 	currentGroup := []int{matchIndices[0]}
 
 	for i := 1; i < len(matchIndices); i++ {
-		if matchIndices[i]-matchIndices[i-1] <= (contextLines*2 + 1) {
+		if matchIndices[i]-matchIndices[i-1] <= (window*2 + 1) {
 			currentGroup = append(currentGroup, matchIndices[i])
 		} else {
 			groups = append(groups, currentGroup)
@@ -131,11 +199,18 @@ func getContext(text, query string) []string {
 		}
 	}
 	groups = append(groups, currentGroup)
+	return groups
+}
+
+// snippets returns a snippet of each group of matched lines of lines with
+// window lines before and after it (see getContext).
+func snippets(lines []string, groups [][]int, window int) []string {
+	var contexts []string
 
 	// Extract context for each group
 	for _, group := range groups {
-		startIdx := max(0, group[0]-contextLines)
-		endIdx := min(len(lines), group[len(group)-1]+contextLines+1)
+		startIdx := max(0, group[0]-window)
+		endIdx := min(len(lines), group[len(group)-1]+window+1)
 
 		var snippetLines []string
 		for i := startIdx; i < endIdx; i++ {
@@ -156,10 +231,65 @@ func getContext(text, query string) []string {
 	return contexts
 }
 
-// SearchDocs searches documentation files in the skill directory for keywords matching the query.
+// SearchDocs searches documentation files in the skill directory for documents matching the query.
+//
+// The query is made of whitespace-separated keywords and "quoted phrases", matched
+// case-insensitively as substrings of the document body. Terms prefixed with + are required
+// and terms prefixed with - excluded; the other terms are optional, and a document must
+// contain one of them unless the query has required terms. With SearchOptions.MatchAll,
+// every term without a prefix is required. Matches count the occurrences of the required
+// and optional terms. Substring matching suits text in scripts without spaces between
+// words, such as Japanese: "認証" matches inside "APIキーで認証します".
+//
+// With SearchOptions.Regex, the query is instead a Go regular expression (RE2 syntax,
+// case-sensitive unless it starts with (?i)) evaluated on each line of the document
+// bodies. Matches count its non-empty matches, and the matched text is highlighted
+// in the contexts between HighlightOpen and HighlightClose. An invalid expression is
+// an error.
+//
+// With SearchOptions.Fuzzy, each keyword of at least 4 characters that is a single word
+// also matches the words of the documents within 1 edit of it (2 from 8 characters), an
+// edit being the insertion, deletion, or substitution of a character or the transposition
+// of two adjacent ones, so "authetication" finds "authentication". Phrases and excluded
+// terms match exactly. The words matched in place of a keyword are reported in
+// SearchResult.MatchedTerms.
+//
+// With SearchOptions.Stem, each keyword that is a single word also matches the words of the
+// documents with the same stem in the document's language, taken from the locale of its
+// frontmatter (English when it has none): "configuring" finds "configuration" and
+// "configured". The stop words of the language ("the", "of") are left out of the query,
+// unless it has no other terms; SearchOptions.StopWords replaces the built-in lists. Only
+// English has a stemmer and stop words; in other languages, keywords match as usual. Stem
+// can be combined with Fuzzy.
+//
+// With SearchOptions.Access, only the documents of the access levels it lists are searched,
+// the others being left out as if they didn't exist; documents without an access
+// frontmatter field are public. A skill tagged by access rules at generation time can
+// then serve audiences with different clearance. Locale, PathPrefix, FetchedAfter, and Tag
+// likewise restrict the search to the documents whose frontmatter matches them, e.g.,
+// the /api/ pages fetched in the last month.
+//
+// With SearchOptions.RecencyHalfLife, the recent documents are boosted, so that queries
+// about fast-moving topics (release notes, migration guides) prefer the current pages to
+// archived versions captured in the same skill. The date of a document is its modified_at
+// frontmatter field, the Last-Modified time its server reported, else its fetched_at; the
+// score of each result, its match count or its rank fusion score with Semantic, is
+// halved for every RecencyHalfLife its document is older than the most recent result.
+// Documents without a date count as old as the oldest result. Results then carry their
+// boosted Score.
+//
+// With SearchOptions.Semantic, documents are also found by meaning: the query is embedded
+// like the sections of the documents were at generation time (see BuildEmbeddings), and the
+// documents whose sections are the most similar to it are ranked together with the keyword
+// matches by reciprocal rank fusion, so a document both matching the keywords and similar
+// to the query ranks first, and a document about the query but not using its words is
+// still found. Results then carry their Score and Similarity. Required and excluded terms
+// and the filters still apply to the documents found by similarity. The skill must have
+// embeddings.
 //
 // It walks through all Markdown files in the docs subdirectory of the skill and searches for
-// matches. Results are sorted by match count (descending) and limited by MaxResults if specified.
+// matches, SearchOptions.Workers files at a time, or looks the terms up in the skill's
+// inverted index (see BuildIndex) when the index is up to date. Results are sorted by match count (descending) and limited by MaxResults if specified.
 // Each result includes context lines surrounding the matches.
 //
 // Args:
@@ -179,83 +309,94 @@ func getContext(text, query string) []string {
 //		MaxResults: 10,
 //	})
 func SearchDocs(opts SearchOptions) ([]SearchResult, error) {
+	return SearchDocsContext(context.Background(), opts)
+}
+
+// SearchDocsContext is like SearchDocs but stops scanning when ctx is cancelled or its
+// deadline expires. In that case the context error is returned and no partial results
+// are reported, so a slow query never looks like a query with few matches.
+//
+// It is safe to call SearchDocsContext from multiple goroutines concurrently.
+func SearchDocsContext(ctx context.Context, opts SearchOptions) ([]SearchResult, error) {
+	var results []SearchResult
+	err := searchDocs(ctx, opts, func(r SearchResult) bool {
+		results = append(results, r)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// searchDocs runs the search of SearchDocsContext, passing the results to found
+// in order until it returns false. With SearchOptions.Stream, the documents
+// scanned are passed as soon as they match instead of once all are ranked.
+func searchDocs(ctx context.Context, opts SearchOptions, found func(SearchResult) bool) error {
 	// Convert to absolute path
 	absSkillDir, err := filepath.Abs(opts.SkillDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+		return fmt.Errorf("failed to get absolute path: %w", err)
 	}
 
 	docsDir := filepath.Join(absSkillDir, "docs")
 	if _, statErr := os.Stat(docsDir); os.IsNotExist(statErr) {
-		return nil, fmt.Errorf("docs directory not found: %s (skill dir: %s)", docsDir, absSkillDir)
+		return fmt.Errorf("docs directory not found: %s (skill dir: %s)", docsDir, absSkillDir)
 	}
 
-	keywords := strings.Fields(strings.ToLower(opts.Query))
-	var results []SearchResult
-
-	// Walk through all .md files in docs directory
-	walkErr := filepath.Walk(docsDir, func(path string, info os.FileInfo, walkFuncErr error) error {
-		if walkFuncErr != nil {
-			return walkFuncErr
-		}
-		if info.IsDir() || filepath.Ext(path) != ".md" {
-			return nil
+	if opts.RecencyHalfLife > 0 && !opts.Stream {
+		results, err := searchRecent(ctx, opts)
+		if err != nil {
+			return err
 		}
+		passAll(results, found)
+		return nil
+	}
 
-		// Read file content
-		content, err := os.ReadFile(path)
+	if opts.Semantic {
+		results, err := searchSemantic(ctx, absSkillDir, opts)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", path, err)
-			return nil // Continue with other files
+			return err
 		}
+		passAll(results, found)
+		return nil
+	}
 
-		// Extract frontmatter and body
-		frontmatter, body := extractFrontmatter(string(content))
-		bodyLower := strings.ToLower(body)
-
-		// Count matches
-		matchesCount := 0
-		for _, kw := range keywords {
-			matchesCount += strings.Count(bodyLower, kw)
+	// The index built at generation time spares reading every file, except
+	// for regular expressions, which are evaluated against every line, and
+	// fuzzy and stemmed queries, which are compared with every word
+	var re *regexp.Regexp
+	if opts.Regex {
+		if re, err = compileRegex(opts.Query); err != nil {
+			return err
 		}
-
-		if matchesCount > 0 {
-			contexts := getContext(body, opts.Query)
-
-			relPath, _ := filepath.Rel(absSkillDir, path)
-			results = append(results, SearchResult{
-				File:      relPath,
-				Matches:   matchesCount,
-				Contexts:  contexts,
-				SourceURL: frontmatter.SourceURL,
-				FetchedAt: frontmatter.FetchedAt,
-			})
+	} else if opts.Fuzzy || opts.Stem {
+		// Scan below
+	} else if results, ok, err := searchIndex(ctx, absSkillDir, opts); ok {
+		if err != nil {
+			return err
 		}
-
+		passAll(results, found)
 		return nil
-	})
-
-	if walkErr != nil {
-		return nil, walkErr
 	}
 
-	// Sort by matches count (descending)
-	sort.Slice(results, func(i, j int) bool {
-		return results[i].Matches > results[j].Matches
-	})
+	return scanDocs(ctx, absSkillDir, docsDir, opts, parseQuery(opts.Query, opts.MatchAll), re, found)
+}
 
-	// Limit results
-	if opts.MaxResults > 0 && len(results) > opts.MaxResults {
-		results = results[:opts.MaxResults]
+// passAll passes results to found in order until it returns false.
+func passAll(results []SearchResult, found func(SearchResult) bool) {
+	for _, r := range results {
+		if !found(r) {
+			return
+		}
 	}
-
-	return results, nil
 }
 
 // FormatResults formats and prints search results in a human-readable format to stdout.
 //
 // It displays a formatted table of results, each showing the file path, match count, source URL,
-// fetch timestamp, and context snippets (up to 3). Matched lines are prefixed with "> " for
+// fetch timestamp (and modification time, when known), the score of a semantic or recency-boosted search, the words matched by a fuzzy or stemmed search, if any, and context snippets (up to 3),
+// each headed by the heading path and line range of its section. Matched lines are prefixed with "> " for
 // easy identification, and context lines are prefixed with "  ". Output includes colored
 // headers and separators for better readability in terminals.
 //
@@ -279,13 +420,31 @@ func FormatResults(results []SearchResult, query string) {
 	for i, res := range results {
 		colorBold.Printf("%d. %s\n", i+1, res.File)
 		fmt.Printf("   Matches: %d | Source: %s\n", res.Matches, res.SourceURL)
-		fmt.Printf("   Fetched: %s\n", res.FetchedAt)
+		if len(res.MatchedFields) > 0 {
+			fmt.Printf("   Matched in: %s\n", strings.Join(res.MatchedFields, ", "))
+		}
+		if res.Similarity > 0 {
+			fmt.Printf("   Score: %.4f | Similarity: %.2f\n", res.Score, res.Similarity)
+		} else if res.Score > 0 {
+			fmt.Printf("   Score: %.4f\n", res.Score)
+		}
+		if res.ModifiedAt != "" {
+			fmt.Printf("   Fetched: %s | Modified: %s\n", res.FetchedAt, res.ModifiedAt)
+		} else {
+			fmt.Printf("   Fetched: %s\n", res.FetchedAt)
+		}
+		if len(res.MatchedTerms) > 0 {
+			fmt.Printf("   Matched: %s\n", strings.Join(res.MatchedTerms, ", "))
+		}
 		colorCyan.Println(strings.Repeat("-", 40))
 
 		// Show up to 3 contexts
 		maxContexts := min(3, len(res.Contexts))
 		for j := 0; j < maxContexts; j++ {
-			fmt.Println(res.Contexts[j])
+			if j < len(res.Sections) {
+				colorCyan.Println(sectionLabel(res.Sections[j]))
+			}
+			fmt.Println(colorHighlights(res.Contexts[j]))
 			if j < maxContexts-1 || len(res.Contexts) > 3 {
 				fmt.Println("   ...")
 			}
@@ -294,28 +453,37 @@ func FormatResults(results []SearchResult, query string) {
 	}
 }
 
-// FormatJSON formats and prints search results as JSON to stdout.
-//
-// Results are printed with indentation for readability. Each SearchResult object includes
-// file path, match count, context snippets, source URL, and fetch timestamp.
-//
-// Args:
-//
-//	results: A slice of SearchResult to format as JSON.
-//
-// Returns:
-//
-//	An error if JSON encoding fails, otherwise nil.
-//
-// Example:
-//
-//	if err := FormatJSON(results); err != nil {
-//		log.Fatal(err)
-//	}
-func FormatJSON(results []SearchResult) error {
-	encoder := json.NewEncoder(os.Stdout)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(results)
+// sectionLabel describes the location of a context: its heading path, line
+// range, and deep link.
+func sectionLabel(s Section) string {
+	if len(s.Headings) == 0 {
+		return fmt.Sprintf("   [lines %d-%d]", s.StartLine, s.EndLine)
+	}
+	if s.Link != "" {
+		return fmt.Sprintf("   [%s, lines %d-%d] %s", s.Breadcrumb(), s.StartLine, s.EndLine, s.Link)
+	}
+	return fmt.Sprintf("   [%s, lines %d-%d]", s.Breadcrumb(), s.StartLine, s.EndLine)
+}
+
+// colorHighlights renders the text between HighlightOpen and HighlightClose
+// in color instead of the markers.
+func colorHighlights(s string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, HighlightOpen)
+		if start < 0 {
+			break
+		}
+		end := strings.Index(s[start:], HighlightClose)
+		if end < 0 {
+			break
+		}
+		b.WriteString(s[:start])
+		b.WriteString(colorWarning.Sprint(s[start+len(HighlightOpen) : start+end]))
+		s = s[start+end+len(HighlightClose):]
+	}
+	b.WriteString(s)
+	return b.String()
 }
 
 // min returns the minimum of two integers.
@@ -1,6 +1,10 @@
 package search
 
 import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 )
 
@@ -27,3 +31,197 @@ func TestSearchDocs(t *testing.T) {
 		})
 	}
 }
+
+func TestSearchDocsAccess(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"guide.md":    "---\ntitle: \"Guide\"\n---\nDeploy the service.\n",
+		"blog.md":     "---\ntitle: \"Blog\"\naccess: \"public\"\n---\nWe deploy weekly.\n",
+		"runbook.md":  "---\ntitle: \"Runbook\"\naccess: \"internal\"\n---\nDeploy with the internal tool.\n",
+		"incident.md": "---\ntitle: \"Incident\"\naccess: \"confidential\"\n---\nThe deploy leaked keys.\n",
+	})
+
+	tests := []struct {
+		name   string
+		access []string
+		want   []string
+	}{
+		{"no restriction", nil, []string{"blog.md", "guide.md", "incident.md", "runbook.md"}},
+		{"public", []string{"public"}, []string{"blog.md", "guide.md"}},
+		{"internal", []string{"public", "internal"}, []string{"blog.md", "guide.md", "runbook.md"}},
+		{"confidential only", []string{"confidential"}, []string{"incident.md"}},
+		{"nothing", []string{}, nil},
+	}
+
+	search := func(access []string) []string {
+		t.Helper()
+		results, err := SearchDocs(SearchOptions{SkillDir: skillDir, Query: "deploy", Access: access})
+		if err != nil {
+			t.Fatalf("SearchDocs() returned error: %v", err)
+		}
+		var files []string
+		for _, r := range results {
+			files = append(files, filepath.Base(r.File))
+		}
+		sort.Strings(files)
+		return files
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := search(tt.access); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scan: SearchDocs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// The index records the levels of the documents
+	if err := BuildIndex(skillDir); err != nil {
+		t.Fatalf("BuildIndex() returned error: %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := search(tt.access); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("index: SearchDocs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetContext(t *testing.T) {
+	body := "one\ntwo\nDeploy the app.\nfour\nfive\nsix\nseven\neight\nRedeploy it.\nten"
+
+	tests := []struct {
+		name      string
+		window    int
+		highlight bool
+		want      []string
+	}{
+		{"default window", 2, false, []string{
+			"  one\n  two\n> Deploy the app.\n  four\n  five",
+			"  seven\n  eight\n> Redeploy it.\n  ten",
+		}},
+		{"matched lines only", 0, false, []string{"> Deploy the app.", "> Redeploy it."}},
+		{"merged groups", 3, false, []string{
+			"  one\n  two\n> Deploy the app.\n  four\n  five\n  six\n  seven\n  eight\n> Redeploy it.\n  ten",
+		}},
+		{"highlight", 0, true, []string{"> «Deploy» the «app».", "> Re«deploy» it."}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, sections := getContext(body, []string{"deploy", "app"}, tt.window, tt.highlight)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getContext() = %q, want %q", got, tt.want)
+			}
+			if len(sections) != len(tt.want) {
+				t.Errorf("getContext() returned %d sections, want %d", len(sections), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestHighlightTerms(t *testing.T) {
+	tests := []struct {
+		line  string
+		terms []string
+		want  string
+	}{
+		{"Deploy the app", []string{"deploy"}, "«Deploy» the app"},
+		{"deploy, deploy", []string{"deploy"}, "«deploy», «deploy»"},
+		{"Deployment", []string{"deploy", "deployment"}, "«Deployment»"},
+		{"redeploy", []string{"rede", "deploy"}, "«redeploy»"},
+		{"no match", []string{"deploy"}, "no match"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := highlightTerms(tt.line, strings.ToLower(tt.line), tt.terms); got != tt.want {
+				t.Errorf("highlightTerms(%q, %q) = %q, want %q", tt.line, tt.terms, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchDocsContextOptions(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"guide.md": "---\ntitle: \"Guide\"\n---\nIntro.\nDeploy the service.\nOutro.\n",
+	})
+	opts := SearchOptions{SkillDir: skillDir, Query: "deploy", ContextLines: -1, Highlight: true}
+	want := []string{"> «Deploy» the service."}
+
+	for _, mode := range []string{"scan", "index"} {
+		if mode == "index" {
+			if err := BuildIndex(skillDir); err != nil {
+				t.Fatalf("BuildIndex() returned error: %v", err)
+			}
+		}
+		results, err := SearchDocs(opts)
+		if err != nil {
+			t.Fatalf("%s: SearchDocs() returned error: %v", mode, err)
+		}
+		if len(results) != 1 || !reflect.DeepEqual(results[0].Contexts, want) {
+			t.Errorf("%s: SearchDocs() = %+v, want contexts %q", mode, results, want)
+		}
+	}
+}
+
+func TestExtractFrontmatter(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    Frontmatter
+	}{
+		{
+			name: "enriched",
+			content: `---
+title: Install
+description: How to install the CLI.
+source_url: https://example.com/docs/install
+canonical_url: https://example.com/docs/install
+fetched_at: "2024-01-01T00:00:00Z"
+locale: en-US
+word_count: 14
+tags:
+    - install
+    - cli
+outline:
+    - '# Install'
+    - '## Requirements'
+---
+
+Body`,
+			want: Frontmatter{
+				Title:        "Install",
+				Description:  "How to install the CLI.",
+				SourceURL:    "https://example.com/docs/install",
+				CanonicalURL: "https://example.com/docs/install",
+				FetchedAt:    "2024-01-01T00:00:00Z",
+				Locale:       "en-US",
+				WordCount:    14,
+				Tags:         []string{"install", "cli"},
+				Outline:      []string{"# Install", "## Requirements"},
+			},
+		},
+		{
+			name:    "invalid YAML falls back to key-value lines",
+			content: "---\ntitle: \"C:\\path\\x\"\nsource_url: https://example.com/\n---\n\nBody",
+			want:    Frontmatter{Title: `C:\path\x`, SourceURL: "https://example.com/", FetchedAt: "Unknown"},
+		},
+		{
+			name:    "no frontmatter",
+			content: "Body",
+			want:    Frontmatter{SourceURL: "Unknown", FetchedAt: "Unknown"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, body := extractFrontmatter(tt.content)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractFrontmatter() = %+v, want %+v", got, tt.want)
+			}
+			if body != "\nBody" && body != "Body" {
+				t.Errorf("body = %q, want Body", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements Searcher, the entry point of programs embedding the search of a skill.
+package search
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"os"
+	"path/filepath"
+)
+
+// Searcher searches the documents of one skill directory. It is the stable API
+// for Go programs embedding skill search instead of running the search command:
+//
+//	s, err := search.NewSearcher("/path/to/skill")
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	for result, err := range s.Search(ctx, search.SearchOptions{Query: "authentication"}) {
+//		if err != nil {
+//			log.Fatal(err)
+//		}
+//		fmt.Println(result.File, result.Matches)
+//	}
+//
+// A Searcher holds no open files and is safe for concurrent use.
+type Searcher struct {
+	skillDir string
+}
+
+// NewSearcher returns a searcher of the skill in skillDir.
+//
+// Returns an error if skillDir has no docs directory.
+func NewSearcher(skillDir string) (*Searcher, error) {
+	absSkillDir, err := filepath.Abs(skillDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	docsDir := filepath.Join(absSkillDir, "docs")
+	if info, err := os.Stat(docsDir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("docs directory not found: %s (skill dir: %s)", docsDir, absSkillDir)
+	}
+	return &Searcher{skillDir: absSkillDir}, nil
+}
+
+// SkillDir returns the absolute path of the skill directory searched.
+func (s *Searcher) SkillDir() string {
+	return s.skillDir
+}
+
+// Search returns an iterator over the results of the search described by opts
+// (see SearchDocs; opts.SkillDir is ignored). The search runs as the iterator
+// is ranged over, and stops when the loop breaks or ctx is cancelled.
+//
+// Results are yielded ranked, once every document is searched, unless
+// opts.Stream is set: the documents scanned are then yielded as soon as they
+// match, so that the first results of a slow search (e.g., a regular
+// expression over a large skill) can be used before it completes.
+//
+// If the search fails, or ctx is cancelled, the error is yielded last with a
+// zero SearchResult.
+func (s *Searcher) Search(ctx context.Context, opts SearchOptions) iter.Seq2[SearchResult, error] {
+	opts.SkillDir = s.skillDir
+	return func(yield func(SearchResult, error) bool) {
+		stopped := false
+		err := searchDocs(ctx, opts, func(r SearchResult) bool {
+			if ctx.Err() != nil {
+				return false
+			}
+			if !yield(r, nil) {
+				stopped = true
+				return false
+			}
+			return true
+		})
+		if stopped {
+			return
+		}
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			yield(SearchResult{}, err)
+		}
+	}
+}
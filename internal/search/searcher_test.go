@@ -0,0 +1,75 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSearcher(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"a.md": "---\ntitle: \"A\"\n---\nDeploy once.\n",
+		"b.md": "---\ntitle: \"B\"\n---\nDeploy, deploy, deploy.\n",
+		"c.md": "---\ntitle: \"C\"\n---\nDeploy twice: deploy.\n",
+		"d.md": "---\ntitle: \"D\"\n---\nNothing here.\n",
+	})
+	s, err := NewSearcher(skillDir)
+	if err != nil {
+		t.Fatalf("NewSearcher() error = %v", err)
+	}
+
+	collect := func(ctx context.Context, opts SearchOptions, limit int) ([]string, error) {
+		t.Helper()
+		var files []string
+		for r, err := range s.Search(ctx, opts) {
+			if err != nil {
+				return files, err
+			}
+			files = append(files, filepath.Base(r.File))
+			if len(files) == limit {
+				break
+			}
+		}
+		return files, nil
+	}
+
+	tests := []struct {
+		name  string
+		opts  SearchOptions
+		limit int
+		want  []string
+	}{
+		{"ranked", SearchOptions{Query: "deploy"}, 0, []string{"b.md", "c.md", "a.md"}},
+		{"streamed", SearchOptions{Query: "deploy", Stream: true}, 0, []string{"a.md", "b.md", "c.md"}},
+		{"streamed max results", SearchOptions{Query: "deploy", Stream: true, MaxResults: 2}, 0, []string{"a.md", "b.md"}},
+		{"break", SearchOptions{Query: "deploy", Stream: true}, 1, []string{"a.md"}},
+		// The skill directory of the searcher is searched
+		{"skill dir ignored", SearchOptions{SkillDir: t.TempDir(), Query: "nothing"}, 0, []string{"d.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := collect(context.Background(), tt.opts, tt.limit)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if got, err := collect(ctx, SearchOptions{Query: "deploy", Stream: true}, 0); !errors.Is(err, context.Canceled) || len(got) != 0 {
+		t.Errorf("Search() with a cancelled context = %v, %v, want context.Canceled", got, err)
+	}
+	if _, err := collect(context.Background(), SearchOptions{Query: "[", Regex: true}, 0); err == nil {
+		t.Error("Search() with an invalid regular expression succeeded, want error")
+	}
+
+	if _, err := NewSearcher(t.TempDir()); err == nil {
+		t.Error("NewSearcher() without a docs directory succeeded, want error")
+	}
+}
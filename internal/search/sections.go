@@ -0,0 +1,139 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the location of matches in the heading structure of a document
+// (SearchResult.Sections).
+package search
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/converter"
+)
+
+// heading is an ATX heading ("## Install") of a document.
+type heading struct {
+	level int
+	text  string
+	// id is the anchor of the heading (see converter.Headings)
+	id string
+	// line is the 0-based index of the heading line
+	line int
+}
+
+// outline returns the ATX headings of lines, outside fenced code blocks, with
+// the anchors of converter.Headings.
+func outline(lines []string) []heading {
+	var headings []heading
+	var ids converter.Slugger
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+
+		level := 0
+		for level < len(line) && line[level] == '#' {
+			level++
+		}
+		if level == 0 || level > 6 || (level < len(line) && line[level] != ' ' && line[level] != '\t') {
+			continue
+		}
+		text := strings.TrimSpace(line[level:])
+		// A closing sequence of #s is only one after a space ("## C#" is not)
+		if closed := strings.TrimRight(text, "#"); closed == "" || strings.HasSuffix(closed, " ") || strings.HasSuffix(closed, "\t") {
+			text = strings.TrimSpace(closed)
+		}
+		if text != "" {
+			headings = append(headings, heading{level: level, text: text, id: ids.Slug(converter.HeadingText(text)), line: i})
+		}
+	}
+	return headings
+}
+
+// sectionAt returns the section of a document of n lines with the given
+// headings that contains the line at index match. Its line range runs from
+// its heading to the line before the next heading of the same or a higher
+// level; before the first heading, it runs from the first line. Lines are
+// numbered from 1.
+func sectionAt(headings []heading, n, match int) Section {
+	var path []heading
+	next := -1
+	for i, h := range headings {
+		if h.line > match {
+			next = i
+			break
+		}
+		for len(path) > 0 && path[len(path)-1].level >= h.level {
+			path = path[:len(path)-1]
+		}
+		path = append(path, h)
+	}
+
+	section := Section{Headings: []string{}, StartLine: 1, EndLine: n}
+	level := 0
+	if len(path) > 0 {
+		innermost := path[len(path)-1]
+		section.StartLine = innermost.line + 1
+		section.Anchor = innermost.id
+		level = innermost.level
+	}
+	for _, h := range path {
+		section.Headings = append(section.Headings, h.text)
+	}
+	if next >= 0 {
+		for _, h := range headings[next:] {
+			if level == 0 || h.level <= level {
+				section.EndLine = h.line
+				break
+			}
+		}
+	}
+	return section
+}
+
+// sectionsOf returns the section containing the first line of each group of
+// matched line indices of lines.
+func sectionsOf(lines []string, groups [][]int) []Section {
+	headings := outline(lines)
+	sections := make([]Section, 0, len(groups))
+	for _, group := range groups {
+		sections = append(sections, sectionAt(headings, len(lines), group[0]))
+	}
+	return sections
+}
+
+// shiftSections adds offset to the line ranges of sections, turning lines of
+// a document body into lines of its file when offset is the number of lines
+// of its frontmatter (see bodyOffset).
+func shiftSections(sections []Section, offset int) []Section {
+	for i := range sections {
+		sections[i].StartLine += offset
+		sections[i].EndLine += offset
+	}
+	return sections
+}
+
+// linkSections sets the Link of sections, the sections of file, to their
+// deep links.
+func linkSections(file string, sections []Section) []Section {
+	for i := range sections {
+		if sections[i].Anchor != "" {
+			sections[i].Link = filepath.ToSlash(file) + "#" + sections[i].Anchor
+		}
+	}
+	return sections
+}
+
+// bodyOffset returns the number of lines of content before body, its suffix
+// returned by extractFrontmatter.
+func bodyOffset(content, body string) int {
+	return strings.Count(content[:len(content)-len(body)], "\n")
+}
@@ -0,0 +1,76 @@
+package search
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSectionAt(t *testing.T) {
+	lines := strings.Split(strings.Join([]string{
+		"Intro text.",            // 1
+		"# Getting Started",      // 2
+		"Overview.",              // 3
+		"## Installation",        // 4
+		"### Docker",             // 5
+		"Run the docker image.",  // 6
+		"```sh",                  // 7
+		"# not a heading",        // 8
+		"```",                    // 9
+		"### Binary",             // 10
+		"Download the binary.",   // 11
+		"## Configuration ##",    // 12
+		"Edit the config file.",  // 13
+		"# Reference",            // 14
+		"See the API reference.", // 15
+		"## C#",                  // 16
+		"## Docker",              // 17
+		"Docker again.",          // 18
+	}, "\n"), "\n")
+	headings := outline(lines)
+
+	tests := []struct {
+		line int
+		want Section
+	}{
+		{1, Section{Headings: []string{}, StartLine: 1, EndLine: 1}},
+		{3, Section{Headings: []string{"Getting Started"}, StartLine: 2, EndLine: 13, Anchor: "getting-started"}},
+		{6, Section{Headings: []string{"Getting Started", "Installation", "Docker"}, StartLine: 5, EndLine: 9, Anchor: "docker"}},
+		{8, Section{Headings: []string{"Getting Started", "Installation", "Docker"}, StartLine: 5, EndLine: 9, Anchor: "docker"}},
+		{11, Section{Headings: []string{"Getting Started", "Installation", "Binary"}, StartLine: 10, EndLine: 11, Anchor: "binary"}},
+		{13, Section{Headings: []string{"Getting Started", "Configuration"}, StartLine: 12, EndLine: 13, Anchor: "configuration"}},
+		{15, Section{Headings: []string{"Reference"}, StartLine: 14, EndLine: 18, Anchor: "reference"}},
+		{16, Section{Headings: []string{"Reference", "C#"}, StartLine: 16, EndLine: 16, Anchor: "c"}},
+		{18, Section{Headings: []string{"Reference", "Docker"}, StartLine: 17, EndLine: 18, Anchor: "docker-1"}},
+	}
+
+	for _, tt := range tests {
+		if got := sectionAt(headings, len(lines), tt.line-1); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sectionAt(line %d) = %+v, want %+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestSearchDocsSections(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"guide.md": "---\ntitle: \"Guide\"\n---\n# Getting Started\n\n## Installation\n\n### Docker\n\nRun the docker image.\n",
+	})
+
+	for _, opts := range []SearchOptions{
+		{Query: "docker image"},
+		{Query: `docker\s+image`, Regex: true},
+	} {
+		opts.SkillDir = skillDir
+		results, err := SearchDocs(opts)
+		if err != nil || len(results) != 1 {
+			t.Fatalf("SearchDocs(%q) = %+v, %v; want guide.md", opts.Query, results, err)
+		}
+		want := []Section{{Headings: []string{"Getting Started", "Installation", "Docker"}, StartLine: 8, EndLine: 11, Anchor: "docker", Link: "docs/guide.md#docker"}}
+		if !reflect.DeepEqual(results[0].Sections, want) {
+			t.Errorf("SearchDocs(%q) sections = %+v, want %+v", opts.Query, results[0].Sections, want)
+		}
+		if got := results[0].Sections[0].Breadcrumb(); got != "Getting Started > Installation > Docker" {
+			t.Errorf("Breadcrumb() = %q", got)
+		}
+	}
+}
@@ -0,0 +1,438 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the embeddings of the document sections and the
+// semantic search mode (SearchOptions.Semantic), which ranks the documents by
+// both keyword matches and similarity of meaning to the query.
+package search
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/embeddings"
+)
+
+const (
+	// EmbeddingsDir is the directory of the embeddings inside a skill's docs/ directory.
+	EmbeddingsDir = ".embeddings"
+	// EmbeddingsFile is the file of EmbeddingsDir holding the embeddings.
+	EmbeddingsFile = "embeddings.json"
+	// EmbeddingsVersion is incremented whenever the format of EmbeddingsFile
+	// or the chunking of the documents changes; embeddings of another version
+	// are ignored.
+	EmbeddingsVersion = 1
+
+	// maxChunkChars caps the size of the embedded chunks: longer sections are
+	// split between lines, as embedding models truncate long inputs.
+	maxChunkChars = 4000
+	// semanticCandidates is the minimum number of documents most similar to
+	// the query that semantic searches consider besides the keyword matches.
+	semanticCandidates = 20
+	// rrfK damps the weight of the top ranks in reciprocal rank fusion.
+	rrfK = 60
+)
+
+// Embeddings is the contents of EmbeddingsFile: the embeddings of the sections
+// of the documents of a skill.
+type Embeddings struct {
+	// Version is the format version (see EmbeddingsVersion).
+	Version int `json:"version"`
+	// Spec identifies the provider and model of the vectors; queries must be
+	// embedded with the same.
+	Spec embeddings.Spec `json:"spec"`
+	// Chunks lists the embedded sections of the documents, in path order.
+	Chunks []EmbeddedChunk `json:"chunks"`
+}
+
+// EmbeddedChunk is the embedding of a section of a document, or of part of a
+// section longer than the chunk size.
+type EmbeddedChunk struct {
+	// Path is the slash-separated path of the document relative to the skill directory.
+	Path string `json:"path"`
+	// Section locates the chunk: its heading path and line range in the file.
+	Section Section `json:"section"`
+	// Hash is the hex-encoded SHA-256 checksum of the embedded text, so that
+	// the vectors of unchanged chunks are reused when the skill is rebuilt.
+	Hash string `json:"hash"`
+	// Vector is the embedding of the chunk.
+	Vector Vector `json:"vector"`
+}
+
+// Vector is an embedding, encoded in JSON as the base64 of its components as
+// little-endian float32 values.
+type Vector []float32
+
+// MarshalJSON encodes v as a base64 string.
+func (v Vector) MarshalJSON() ([]byte, error) {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return json.Marshal(base64.StdEncoding.EncodeToString(buf))
+}
+
+// UnmarshalJSON decodes a base64 string encoded by MarshalJSON.
+func (v *Vector) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	buf, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(buf)%4 != 0 {
+		return fmt.Errorf("invalid vector encoding")
+	}
+	*v = make(Vector, len(buf)/4)
+	for i := range *v {
+		(*v)[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return nil
+}
+
+// EmbeddingStats describes embeddings built by BuildEmbeddings.
+type EmbeddingStats struct {
+	// Documents and Chunks are the numbers of documents and chunks embedded.
+	Documents int
+	Chunks    int
+	// Reused is the number of chunks whose vector was taken from the previous embeddings.
+	Reused int
+}
+
+// textChunk is a section of a document to embed.
+type textChunk struct {
+	section Section
+	text    string
+}
+
+// BuildEmbeddings writes the embeddings of the Markdown files in skillDir's
+// docs/ directory, computed by p, to docs/.embeddings/, replacing any previous
+// embeddings. Each document is split into chunks at its headings, and sections
+// longer than 4000 characters between lines; each chunk is embedded with the
+// document title and its heading path, so that it carries its context.
+//
+// The vectors of prev, typically the embeddings of the skill before it was
+// rebuilt (see LoadEmbeddings), are reused for the chunks whose text is
+// unchanged when they were computed with the same spec, so that rebuilding a
+// skill only embeds the sections that changed. prev may be nil.
+//
+// Returns an error if the documents can't be read, p fails, or the embeddings
+// can't be written.
+func BuildEmbeddings(ctx context.Context, skillDir string, p embeddings.Provider, prev *Embeddings) (EmbeddingStats, error) {
+	var stats EmbeddingStats
+	docs, err := listDocuments(skillDir)
+	if err != nil {
+		return stats, fmt.Errorf("failed to list documents: %w", err)
+	}
+
+	spec := p.Spec()
+	reuse := make(map[string]Vector)
+	if prev != nil && prev.Spec == spec {
+		for _, c := range prev.Chunks {
+			reuse[c.Hash] = c.Vector
+		}
+	}
+
+	emb := &Embeddings{Version: EmbeddingsVersion, Spec: spec, Chunks: []EmbeddedChunk{}}
+	var texts []string
+	var pending []int
+	for _, doc := range docs {
+		content, err := os.ReadFile(filepath.Join(skillDir, filepath.FromSlash(doc.Path)))
+		if err != nil {
+			return stats, fmt.Errorf("failed to read %s: %w", doc.Path, err)
+		}
+		frontmatter, body := extractFrontmatter(string(content))
+		chunks := chunkDocument(frontmatter.Title, body)
+		if len(chunks) > 0 {
+			stats.Documents++
+		}
+		for _, c := range chunks {
+			sum := sha256.Sum256([]byte(c.text))
+			chunk := EmbeddedChunk{
+				Path:    doc.Path,
+				Section: shiftSections([]Section{c.section}, bodyOffset(string(content), body))[0],
+				Hash:    hex.EncodeToString(sum[:]),
+			}
+			if v, ok := reuse[chunk.Hash]; ok {
+				chunk.Vector = v
+				stats.Reused++
+			} else {
+				texts = append(texts, c.text)
+				pending = append(pending, len(emb.Chunks))
+			}
+			emb.Chunks = append(emb.Chunks, chunk)
+		}
+	}
+
+	if len(texts) > 0 {
+		vectors, err := p.Embed(ctx, texts)
+		if err != nil {
+			return stats, fmt.Errorf("failed to embed documents: %w", err)
+		}
+		if len(vectors) != len(texts) {
+			return stats, fmt.Errorf("failed to embed documents: got %d embeddings for %d chunks", len(vectors), len(texts))
+		}
+		for i, v := range vectors {
+			emb.Chunks[pending[i]].Vector = v
+		}
+	}
+	stats.Chunks = len(emb.Chunks)
+
+	dir := filepath.Join(skillDir, "docs", EmbeddingsDir)
+	if err := os.RemoveAll(dir); err != nil {
+		return stats, fmt.Errorf("failed to remove old embeddings: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return stats, fmt.Errorf("failed to create embeddings directory: %w", err)
+	}
+	if _, err := writeJSON(filepath.Join(dir, EmbeddingsFile), emb); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// LoadEmbeddings reads the embeddings of the skill in skillDir.
+//
+// Returns an error wrapping os.ErrNotExist if the skill has none, and an
+// error if they can't be read or are of another version.
+func LoadEmbeddings(skillDir string) (*Embeddings, error) {
+	data, err := os.ReadFile(filepath.Join(skillDir, "docs", EmbeddingsDir, EmbeddingsFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embeddings: %w", err)
+	}
+	var emb Embeddings
+	if err := json.Unmarshal(data, &emb); err != nil {
+		return nil, fmt.Errorf("failed to parse embeddings: %w", err)
+	}
+	if emb.Version != EmbeddingsVersion {
+		return nil, fmt.Errorf("embeddings are of version %d, expected %d: rebuild them", emb.Version, EmbeddingsVersion)
+	}
+	return &emb, nil
+}
+
+// chunkDocument splits a document body into the chunks to embed: one per
+// section, starting at each heading, with the sections longer than
+// maxChunkChars split between lines, with their line ranges ending at their
+// last line that isn't blank. The text of each chunk is led by the
+// title of the document and the heading path of the section. Chunks without
+// text are left out.
+func chunkDocument(title, body string) []textChunk {
+	lines := strings.Split(body, "\n")
+	headings := outline(lines)
+	starts := []int{0}
+	for _, h := range headings {
+		if h.line > 0 {
+			starts = append(starts, h.line)
+		}
+	}
+
+	var chunks []textChunk
+	for i, start := range starts {
+		end := len(lines)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		for from := start; from < end; {
+			to, size := from, 0
+			for to < end && (to == from || size+len(lines[to]) <= maxChunkChars) {
+				size += len(lines[to]) + 1
+				to++
+			}
+			text := strings.TrimSpace(strings.Join(lines[from:to], "\n"))
+			if text != "" {
+				last := to
+				for strings.TrimSpace(lines[last-1]) == "" {
+					last--
+				}
+				section := sectionAt(headings, len(lines), from)
+				section.StartLine, section.EndLine = from+1, last
+				context := section.Headings
+				if title != "" {
+					context = append([]string{title}, context...)
+				}
+				if len(context) > 0 {
+					text = strings.Join(context, " > ") + "\n\n" + text
+				}
+				chunks = append(chunks, textChunk{section: section, text: text})
+			}
+			from = to
+		}
+	}
+	return chunks
+}
+
+// semanticHit is a document similar to the query of a semantic search.
+type semanticHit struct {
+	path       string
+	similarity float64
+	// chunk is the most similar chunk of the document
+	chunk EmbeddedChunk
+}
+
+// searchSemantic answers a semantic search (see SearchDocs): it ranks the
+// keyword results of opts together with the documents whose embeddings are the
+// most similar to the query's, by reciprocal rank fusion.
+func searchSemantic(ctx context.Context, skillDir string, opts SearchOptions) ([]SearchResult, error) {
+	emb, err := LoadEmbeddings(skillDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("skill has no embeddings for semantic search: generate it with embeddings or run 'site2skillgo index embed'")
+	}
+	if err != nil {
+		return nil, err
+	}
+	provider := opts.Embedder
+	if provider == nil {
+		if provider, err = embeddings.New(emb.Spec, embeddings.APIKey()); err != nil {
+			return nil, err
+		}
+	}
+	if spec := provider.Spec(); spec != emb.Spec {
+		return nil, fmt.Errorf("skill was embedded with %s, which can't be compared with %s", emb.Spec, spec)
+	}
+
+	// The query is embedded without its syntax; excluded terms would only
+	// draw it closer to the documents they exclude
+	q := parseQuery(opts.Query, opts.MatchAll)
+	queryText := strings.Join(q.terms(), " ")
+	if opts.Regex || queryText == "" {
+		queryText = opts.Query
+	}
+	vectors, err := provider.Embed(ctx, []string{queryText})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("failed to embed query: got %d embeddings", len(vectors))
+	}
+
+	keywordOpts := opts
+	keywordOpts.Semantic = false
+	keywordOpts.MaxResults = 0
+	keyword, err := SearchDocsContext(ctx, keywordOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	hits := rankChunks(emb.Chunks, vectors[0])
+	limit := max(semanticCandidates, opts.MaxResults)
+	scores := make(map[string]float64)
+	results := make(map[string]*SearchResult)
+	for i := range keyword {
+		res := &keyword[i]
+		path := filepath.ToSlash(res.File)
+		scores[path] = 1 / float64(rrfK+i+1)
+		results[path] = res
+	}
+	rank := 0
+	for _, hit := range hits {
+		if rank == limit {
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		res, ok := results[hit.path]
+		if !ok {
+			if res = semanticResult(skillDir, hit, opts, q); res == nil {
+				continue
+			}
+			results[hit.path] = res
+		}
+		res.Similarity = hit.similarity
+		rank++
+		scores[hit.path] += 1 / float64(rrfK+rank)
+	}
+
+	ranked := make([]SearchResult, 0, len(results))
+	for path, res := range results {
+		res.Score = scores[path]
+		ranked = append(ranked, *res)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].File < ranked[j].File
+	})
+	if opts.MaxResults > 0 && len(ranked) > opts.MaxResults {
+		ranked = ranked[:opts.MaxResults]
+	}
+	return ranked, nil
+}
+
+// rankChunks returns the documents of chunks by decreasing similarity of
+// their most similar chunk to the query vector, leaving out those not similar at all.
+func rankChunks(chunks []EmbeddedChunk, queryVector []float32) []semanticHit {
+	best := make(map[string]int)
+	var hits []semanticHit
+	for _, c := range chunks {
+		sim := embeddings.Cosine(c.Vector, queryVector)
+		if sim <= 0 {
+			continue
+		}
+		if i, ok := best[c.Path]; !ok {
+			best[c.Path] = len(hits)
+			hits = append(hits, semanticHit{path: c.Path, similarity: sim, chunk: c})
+		} else if sim > hits[i].similarity {
+			hits[i].similarity, hits[i].chunk = sim, c
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		return hits[i].similarity > hits[j].similarity
+	})
+	return hits
+}
+
+// semanticResult returns the result for a document found by similarity only,
+// with the beginning of its most similar chunk as context, or nil if it can't
+// be read, is filtered out by opts, or contains an excluded term of q or lacks
+// one of its required terms.
+func semanticResult(skillDir string, hit semanticHit, opts SearchOptions, q query) *SearchResult {
+	relPath := filepath.FromSlash(hit.path)
+	content, err := os.ReadFile(filepath.Join(skillDir, relPath))
+	if err != nil {
+		return nil
+	}
+	frontmatter, body := extractFrontmatter(string(content))
+	if !opts.accepts(frontmatter) {
+		return nil
+	}
+	if !opts.Regex {
+		bodyLower := strings.ToLower(body)
+		for _, term := range q.excluded {
+			if strings.Contains(bodyLower, term) {
+				return nil
+			}
+		}
+		for _, term := range q.required {
+			if !strings.Contains(bodyLower, term) {
+				return nil
+			}
+		}
+	}
+
+	lines := strings.Split(string(content), "\n")
+	section := hit.chunk.Section
+	start := min(max(section.StartLine-1, 0), len(lines))
+	end := max(start, min(min(section.EndLine, len(lines)), start+2*opts.contextWindow()+1))
+	var snippet []string
+	for _, line := range lines[start:end] {
+		snippet = append(snippet, "  "+line)
+	}
+	return &SearchResult{
+		File:       relPath,
+		Contexts:   []string{strings.Join(snippet, "\n")},
+		Sections:   linkSections(relPath, []Section{section}),
+		SourceURL:  frontmatter.SourceURL,
+		FetchedAt:  frontmatter.FetchedAt,
+		ModifiedAt: frontmatter.ModifiedAt,
+	}
+}
@@ -0,0 +1,150 @@
+package search
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/f4ah6o/site2skill-go/internal/embeddings"
+)
+
+// countingProvider counts the texts embedded by a provider.
+type countingProvider struct {
+	embeddings.Provider
+	texts int
+}
+
+func (p *countingProvider) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	p.texts += len(texts)
+	return p.Provider.Embed(ctx, texts)
+}
+
+func TestChunkDocument(t *testing.T) {
+	body := "Intro text.\n# Setup\n\nInstall it.\n## Docker\nRun the image.\n# Empty\n"
+
+	chunks := chunkDocument("Guide", body)
+	want := []textChunk{
+		{Section{Headings: []string{}, StartLine: 1, EndLine: 1}, "Guide\n\nIntro text."},
+		{Section{Headings: []string{"Setup"}, StartLine: 2, EndLine: 4, Anchor: "setup"}, "Guide > Setup\n\n# Setup\n\nInstall it."},
+		{Section{Headings: []string{"Setup", "Docker"}, StartLine: 5, EndLine: 6, Anchor: "docker"}, "Guide > Setup > Docker\n\n## Docker\nRun the image."},
+		{Section{Headings: []string{"Empty"}, StartLine: 7, EndLine: 7, Anchor: "empty"}, "Guide > Empty\n\n# Empty"},
+	}
+	if !reflect.DeepEqual(chunks, want) {
+		t.Errorf("chunkDocument() = %+v, want %+v", chunks, want)
+	}
+}
+
+func TestVectorJSON(t *testing.T) {
+	v := Vector{0.5, -1, 0}
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var got Vector
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, v) {
+		t.Errorf("round trip = %v, want %v", got, v)
+	}
+	if err := json.Unmarshal([]byte(`"AAA="`), &got); err == nil {
+		t.Error("Unmarshal() of a truncated vector succeeded, want error")
+	}
+}
+
+func TestBuildEmbeddingsReuse(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"a.md": "---\ntitle: \"A\"\n---\n# One\nFirst.\n# Two\nSecond.\n",
+		"b.md": "---\ntitle: \"B\"\n---\nOnly section.\n",
+	})
+	p := &countingProvider{Provider: embeddings.NewHash(64)}
+
+	stats, err := BuildEmbeddings(context.Background(), skillDir, p, nil)
+	if err != nil {
+		t.Fatalf("BuildEmbeddings() error = %v", err)
+	}
+	if stats != (EmbeddingStats{Documents: 2, Chunks: 3}) || p.texts != 3 {
+		t.Errorf("BuildEmbeddings() = %+v with %d texts embedded, want 2 documents and 3 chunks embedded", stats, p.texts)
+	}
+	emb, err := LoadEmbeddings(skillDir)
+	if err != nil {
+		t.Fatalf("LoadEmbeddings() error = %v", err)
+	}
+	if c := emb.Chunks[0]; c.Path != "docs/a.md" || c.Section.StartLine != 4 || len(c.Vector) != 64 {
+		t.Errorf("first chunk = %s lines %d-%d with %d dimensions", c.Path, c.Section.StartLine, c.Section.EndLine, len(c.Vector))
+	}
+
+	// Rebuilding embeds only the changed chunks
+	skillDir2 := writeSkillDocs(t, map[string]string{
+		"a.md": "---\ntitle: \"A\"\n---\n# One\nFirst.\n# Two\nSecond, edited.\n",
+		"b.md": "---\ntitle: \"B\"\n---\nOnly section.\n",
+	})
+	p.texts = 0
+	if stats, err = BuildEmbeddings(context.Background(), skillDir2, p, emb); err != nil {
+		t.Fatalf("BuildEmbeddings() error = %v", err)
+	}
+	if stats.Reused != 2 || p.texts != 1 {
+		t.Errorf("rebuild reused %d chunks and embedded %d, want 2 and 1", stats.Reused, p.texts)
+	}
+}
+
+func TestSearchDocsSemantic(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"proxy.md":   "---\ntitle: \"Proxy\"\n---\n# Proxy configuration\n\nSet HTTPS_PROXY to route requests.\n",
+		"network.md": "---\ntitle: \"Networking\"\n---\n# Outbound traffic\n\nConfiguring the firewall.\n",
+		"billing.md": "---\ntitle: \"Billing\"\n---\n# Invoices\n\nInvoices are sent monthly.\n",
+	})
+
+	search := func(opts SearchOptions) []string {
+		t.Helper()
+		opts.SkillDir = skillDir
+		opts.Semantic = true
+		results, err := SearchDocs(opts)
+		if err != nil {
+			t.Fatalf("SearchDocs() error = %v", err)
+		}
+		var files []string
+		for _, r := range results {
+			files = append(files, filepath.Base(r.File))
+			if r.Score <= 0 {
+				t.Errorf("%s: Score = %v, want > 0", r.File, r.Score)
+			}
+		}
+		return files
+	}
+
+	if _, err := SearchDocs(SearchOptions{SkillDir: skillDir, Query: "proxy", Semantic: true}); err == nil {
+		t.Fatal("SearchDocs() without embeddings succeeded, want error")
+	}
+	if _, err := BuildEmbeddings(context.Background(), skillDir, embeddings.NewHash(0), nil); err != nil {
+		t.Fatalf("BuildEmbeddings() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts SearchOptions
+		want []string
+	}{
+		// No document contains "configured": both are found by meaning, the
+		// shorter section being the more similar
+		{"similar only", SearchOptions{Query: "configured"}, []string{"proxy.md", "network.md"}},
+		// The keyword match ranks first
+		{"hybrid", SearchOptions{Query: "proxy configured"}, []string{"proxy.md", "network.md"}},
+		{"excluded term", SearchOptions{Query: "configured -firewall"}, []string{"proxy.md"}},
+		{"filter", SearchOptions{Query: "configured", Tag: "none"}, nil},
+		{"max results", SearchOptions{Query: "proxy configured", MaxResults: 1}, []string{"proxy.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := search(tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SearchDocs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := SearchDocs(SearchOptions{SkillDir: skillDir, Query: "proxy", Semantic: true, Embedder: embeddings.NewHash(8)}); err == nil {
+		t.Error("SearchDocs() with another embedder succeeded, want error")
+	}
+}
@@ -0,0 +1,308 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the diagnosis of searches without results: the number
+// of documents each term of the query matches, and the terms of the skill
+// close to those matching none ("did you mean").
+package search
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// maxSuggestions is the most terms suggested in place of a term of a query.
+	maxSuggestions = 3
+	// minSuggestedLen is the length, in characters, below which terms get no
+	// suggestions, as too many words are close to them.
+	minSuggestedLen = 3
+)
+
+// Diagnosis explains what a query matches in a skill, for a search that
+// found nothing: how many documents each of its terms matches on its own,
+// and the terms of the skill close to those matching none.
+type Diagnosis struct {
+	// Terms reports each term of the query: the required terms, then the
+	// optional ones, then the excluded ones.
+	Terms []TermReport `json:"terms"`
+	// DidYouMean is the query with each term matching no document replaced by
+	// its first suggestion; empty if no such term has a suggestion.
+	DidYouMean string `json:"did_you_mean,omitempty"`
+}
+
+// TermReport is a term of a query in a Diagnosis.
+type TermReport struct {
+	// Term is the keyword or phrase, lowercased.
+	Term string `json:"term"`
+	// Kind is how the query uses the term: "required", "optional", or "excluded".
+	Kind string `json:"kind"`
+	// Documents is the number of documents the term matches on its own,
+	// within the filters of the search (access, locale, path prefix, fetch
+	// time, and tag), with its fuzzy or stemmed matching if any.
+	Documents int `json:"documents"`
+	// Suggestions are the words of the documents close to a keyword matching
+	// no document, best first: those within the edit distance of fuzzy search
+	// (1 edit, 2 from 8 characters; 1 for 3-character keywords) and those
+	// the keyword extends ("configuration" for "configurations"), by
+	// distance and then by number of documents.
+	Suggestions []Suggestion `json:"suggestions,omitempty"`
+}
+
+// Suggestion is a word suggested in place of a term of a query.
+type Suggestion struct {
+	// Term is the word, lowercased.
+	Term string `json:"term"`
+	// Documents is the number of documents containing the word, within the
+	// filters of the search.
+	Documents int `json:"documents"`
+}
+
+// DiagnoseQuery reports the number of documents each term of the query of
+// opts matches, and suggests words of the skill for the keywords matching
+// none (see Diagnosis): a search without results is mistyped, uses words the
+// documentation doesn't, or combines terms no document has together. The
+// words are looked up in the skill's index when it is up to date, and the
+// documents scanned otherwise.
+//
+// Returns nil for regular expression searches, whose terms can't be told apart.
+func DiagnoseQuery(ctx context.Context, opts SearchOptions) (*Diagnosis, error) {
+	if opts.Regex {
+		return nil, nil
+	}
+	absSkillDir, err := filepath.Abs(opts.SkillDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+
+	// Each term is searched alone, as a phrase, with the filters and matching of opts
+	base := opts
+	base.SkillDir = absSkillDir
+	base.MatchAll, base.Semantic, base.Stream, base.Highlight = false, false, false, false
+	base.RecencyHalfLife, base.MaxResults, base.ContextLines = 0, 0, -1
+
+	q := parseQuery(opts.Query, opts.MatchAll)
+	d := &Diagnosis{}
+	for _, group := range []struct {
+		kind  string
+		terms []string
+	}{{"required", q.required}, {"optional", q.optional}, {"excluded", q.excluded}} {
+		for _, term := range group.terms {
+			termOpts := base
+			termOpts.Query = quoteTerm(term)
+			n := 0
+			if err := searchDocs(ctx, termOpts, func(SearchResult) bool { n++; return true }); err != nil {
+				return nil, err
+			}
+			d.Terms = append(d.Terms, TermReport{Term: term, Kind: group.kind, Documents: n})
+		}
+	}
+
+	// Words close to the keywords matching nothing
+	var missing []int
+	for i, t := range d.Terms {
+		if t.Documents == 0 && t.Kind != "excluded" && suggestible(t.Term) {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 {
+		return d, nil
+	}
+	frequencies, err := wordFrequencies(ctx, absSkillDir, opts, func(word string) bool {
+		for _, i := range missing {
+			if _, ok := wordDistance(d.Terms[i].Term, word); ok {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return nil, err
+	}
+	replaced := false
+	for _, i := range missing {
+		d.Terms[i].Suggestions = suggestions(d.Terms[i].Term, frequencies)
+		replaced = replaced || len(d.Terms[i].Suggestions) > 0
+	}
+	if replaced {
+		d.DidYouMean = d.query(opts.MatchAll)
+	}
+	return d, nil
+}
+
+// Diagnose is DiagnoseQuery on the skill of s (opts.SkillDir is ignored).
+func (s *Searcher) Diagnose(ctx context.Context, opts SearchOptions) (*Diagnosis, error) {
+	opts.SkillDir = s.skillDir
+	return DiagnoseQuery(ctx, opts)
+}
+
+// query returns the query of d with each term matching no document replaced
+// by its first suggestion, required terms prefixed with + unless matchAll
+// makes every unprefixed term required, and excluded terms with -.
+func (d *Diagnosis) query(matchAll bool) string {
+	parts := make([]string, 0, len(d.Terms))
+	for _, t := range d.Terms {
+		term := t.Term
+		if t.Documents == 0 && len(t.Suggestions) > 0 {
+			term = t.Suggestions[0].Term
+		}
+		if strings.Contains(term, " ") {
+			term = `"` + term + `"`
+		}
+		switch {
+		case t.Kind == "excluded":
+			term = "-" + term
+		case t.Kind == "required" && !matchAll:
+			term = "+" + term
+		}
+		parts = append(parts, term)
+	}
+	return strings.Join(parts, " ")
+}
+
+// quoteTerm returns term as a query matching it alone: a quoted phrase,
+// unless the term has a quote, which a phrase can't hold.
+func quoteTerm(term string) string {
+	if strings.Contains(term, `"`) {
+		return term
+	}
+	return `"` + term + `"`
+}
+
+// suggestible reports whether term is a keyword that gets suggestions: a
+// single indexed word of at least minSuggestedLen characters.
+func suggestible(term string) bool {
+	words := tokenize(term)
+	return len(words) == 1 && words[0] == term && utf8.RuneCountInString(term) >= minSuggestedLen
+}
+
+// wordDistance returns the edit distance between term and word, if word is
+// close enough to term to be suggested in its place: within max(1,
+// maxEdits(term)) edits, or a prefix of term of at least 4 characters and
+// half its length (the keyword adds a suffix to a word of the skill).
+func wordDistance(term, word string) (int, bool) {
+	if word == term {
+		return 0, false
+	}
+	limit := max(1, maxEdits(term))
+	if d := editDistance(term, word, limit); d <= limit {
+		return d, true
+	}
+	n := utf8.RuneCountInString(word)
+	if strings.HasPrefix(term, word) && n >= 4 && 2*n >= utf8.RuneCountInString(term) {
+		return utf8.RuneCountInString(term) - n, true
+	}
+	return 0, false
+}
+
+// suggestions returns the words of frequencies, which maps words to their
+// number of documents, to suggest in place of term, best first.
+func suggestions(term string, frequencies map[string]int) []Suggestion {
+	type candidate struct {
+		Suggestion
+		distance int
+	}
+	var candidates []candidate
+	for word, n := range frequencies {
+		if d, ok := wordDistance(term, word); ok && n > 0 {
+			candidates = append(candidates, candidate{Suggestion{Term: word, Documents: n}, d})
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		if a.Documents != b.Documents {
+			return a.Documents > b.Documents
+		}
+		return a.Term < b.Term
+	})
+	var out []Suggestion
+	for _, c := range candidates[:min(maxSuggestions, len(candidates))] {
+		out = append(out, c.Suggestion)
+	}
+	return out
+}
+
+// wordFrequencies returns the number of documents of skillDir accepted by
+// the filters of opts that contain each word (see tokenize) for which wanted
+// returns true. The words are read from the skill's index when it is up to
+// date, and from the documents otherwise.
+func wordFrequencies(ctx context.Context, skillDir string, opts SearchOptions, wanted func(word string) bool) (map[string]int, error) {
+	if frequencies, ok, err := indexFrequencies(ctx, skillDir, opts, wanted); ok || err != nil {
+		return frequencies, err
+	}
+
+	docs, err := listDocuments(skillDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	frequencies := make(map[string]int)
+	for _, doc := range docs {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		content, err := os.ReadFile(filepath.Join(skillDir, filepath.FromSlash(doc.Path)))
+		if err != nil {
+			continue
+		}
+		frontmatter, body := extractFrontmatter(string(content))
+		if !opts.accepts(frontmatter) {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, word := range tokenize(strings.ToLower(body)) {
+			if !seen[word] {
+				seen[word] = true
+				if wanted(word) {
+					frequencies[word]++
+				}
+			}
+		}
+	}
+	return frequencies, nil
+}
+
+// indexFrequencies is wordFrequencies from the index of skillDir, loading
+// only the postings files of the words wanted. Returns false if the skill has
+// no usable index.
+func indexFrequencies(ctx context.Context, skillDir string, opts SearchOptions, wanted func(word string) bool) (map[string]int, bool, error) {
+	idx, ok := openIndex(skillDir)
+	if !ok {
+		return nil, false, nil
+	}
+	docs, err := listDocuments(skillDir)
+	if err != nil || !sameDocuments(docs, idx.Documents) {
+		return nil, false, nil
+	}
+
+	byShard := make(map[int][]string)
+	for _, term := range idx.Terms {
+		if wanted(term) {
+			shard := shardOf(term, idx.Shards)
+			byShard[shard] = append(byShard[shard], term)
+		}
+	}
+	frequencies := make(map[string]int)
+	for shard, terms := range byShard {
+		if err := ctx.Err(); err != nil {
+			return nil, true, err
+		}
+		postings, ok := loadShard(skillDir, idx, shard)
+		if !ok {
+			return nil, false, nil
+		}
+		for _, term := range terms {
+			for _, p := range postings[term] {
+				if p.Doc >= 0 && p.Doc < len(idx.Documents) && opts.accepts(idx.Documents[p.Doc].frontmatter()) {
+					frequencies[term]++
+				}
+			}
+		}
+	}
+	return frequencies, true, nil
+}
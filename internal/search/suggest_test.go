@@ -0,0 +1,137 @@
+package search
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestWordDistance(t *testing.T) {
+	tests := []struct {
+		term, word string
+		want       int
+		wantOK     bool
+	}{
+		{"authetication", "authentication", 1, true},
+		{"confg", "config", 1, true},
+		{"tken", "token", 1, true},
+		{"api", "apk", 1, true},
+		{"configurations", "configuration", 1, true},
+		{"webhooksettings", "webhooks", 7, true},
+		{"deployments", "deploy", 5, true},
+		{"install", "install", 0, false},
+		{"install", "uninstall", 0, false},
+		{"tokens", "tok", 0, false},
+		{"timeout", "token", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := wordDistance(tt.term, tt.word)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("wordDistance(%q, %q) = %d, %v; want %d, %v", tt.term, tt.word, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestDiagnoseQuery(t *testing.T) {
+	docs := map[string]string{
+		"auth.md":     "---\ntitle: Auth\n---\n# Authentication\n\nAuthentication uses tokens. Rotate the token monthly.\n",
+		"tokens.md":   "---\ntitle: Tokens\n---\n# Tokens\n\nEach token has an authentication scope.\n",
+		"install.md":  "---\ntitle: Install\n---\n# Install\n\nInstall the CLI, then configure the webhook.\n",
+		"internal.md": "---\ntitle: Runbook\naccess: internal\n---\n# Runbook\n\nAuthenticaton secrets live in the vault.\n",
+	}
+	skillDir := writeSkillDocs(t, docs)
+
+	tests := []struct {
+		name  string
+		opts  SearchOptions
+		index bool
+		want  *Diagnosis
+	}{
+		{
+			name: "typo",
+			opts: SearchOptions{Query: "authetication"},
+			want: &Diagnosis{
+				Terms: []TermReport{{Term: "authetication", Kind: "optional", Suggestions: []Suggestion{
+					{Term: "authentication", Documents: 2},
+					{Term: "authenticaton", Documents: 1},
+				}}},
+				DidYouMean: "authentication",
+			},
+		},
+		{
+			name:  "typo from the index",
+			opts:  SearchOptions{Query: "authetication"},
+			index: true,
+			want: &Diagnosis{
+				Terms: []TermReport{{Term: "authetication", Kind: "optional", Suggestions: []Suggestion{
+					{Term: "authentication", Documents: 2},
+					{Term: "authenticaton", Documents: 1},
+				}}},
+				DidYouMean: "authentication",
+			},
+		},
+		{
+			name: "filtered out",
+			opts: SearchOptions{Query: "authetication", Access: []string{"public"}},
+			want: &Diagnosis{
+				Terms: []TermReport{{Term: "authetication", Kind: "optional", Suggestions: []Suggestion{
+					{Term: "authentication", Documents: 2},
+				}}},
+				DidYouMean: "authentication",
+			},
+		},
+		{
+			name: "terms not found together",
+			opts: SearchOptions{Query: "+webhook +rotate -cli"},
+			want: &Diagnosis{Terms: []TermReport{
+				{Term: "webhook", Kind: "required", Documents: 1},
+				{Term: "rotate", Kind: "required", Documents: 1},
+				{Term: "cli", Kind: "excluded", Documents: 1},
+			}},
+		},
+		{
+			name: "suffix",
+			opts: SearchOptions{Query: "webhooks token", MatchAll: true},
+			want: &Diagnosis{
+				Terms: []TermReport{
+					{Term: "webhooks", Kind: "required", Suggestions: []Suggestion{{Term: "webhook", Documents: 1}}},
+					{Term: "token", Kind: "required", Documents: 2},
+				},
+				DidYouMean: "webhook token",
+			},
+		},
+		{
+			name: "phrase",
+			opts: SearchOptions{Query: `"rotate tokens" +confgure`},
+			want: &Diagnosis{
+				Terms: []TermReport{
+					{Term: "confgure", Kind: "required", Suggestions: []Suggestion{{Term: "configure", Documents: 1}}},
+					{Term: "rotate tokens", Kind: "optional"},
+				},
+				DidYouMean: `+configure "rotate tokens"`,
+			},
+		},
+		{
+			name: "regex",
+			opts: SearchOptions{Query: "auth.*", Regex: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.SkillDir = skillDir
+			if tt.index {
+				if err := BuildIndex(skillDir); err != nil {
+					t.Fatalf("BuildIndex() returned error: %v", err)
+				}
+			}
+			got, err := DiagnoseQuery(context.Background(), opts)
+			if err != nil {
+				t.Fatalf("DiagnoseQuery() returned error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiagnoseQuery() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements symbols.json, the code symbols (functions, classes,
+// types) declared in the code blocks of a skill's documents, which exact-symbol
+// lookups consult instead of a full-text search.
+package search
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/access"
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+)
+
+// SymbolsFile is the name of the symbol table written at the root of a skill.
+const SymbolsFile = "symbols.json"
+
+// SymbolsVersion is the version of the format of SymbolsFile.
+const SymbolsVersion = 1
+
+// The kinds of symbols, as recorded in Symbol.Kind.
+const (
+	SymbolFunction  = "function"
+	SymbolMethod    = "method"
+	SymbolClass     = "class"
+	SymbolInterface = "interface"
+	SymbolStruct    = "struct"
+	SymbolEnum      = "enum"
+	SymbolTrait     = "trait"
+	SymbolType      = "type"
+)
+
+var (
+	// goFuncPattern matches a Go function or method declaration, capturing
+	// the receiver type and the name.
+	goFuncPattern = regexp.MustCompile(`^func\s+(?:\(\s*(?:\w+\s+)?\*?([A-Za-z_]\w*)(?:\[[^\]]*\])?\s*\)\s*)?([A-Za-z_]\w*)\s*[\[(]`)
+	// goTypePattern matches a Go type declaration, capturing the name and its
+	// underlying struct or interface, if any.
+	goTypePattern = regexp.MustCompile(`^type\s+([A-Za-z_]\w*)(?:\[[^\]]*\])?\s+(struct|interface)?`)
+	// functionPattern matches the function declarations of Python, Ruby,
+	// JavaScript, TypeScript, PHP, shell, Rust, Kotlin, and Swift, capturing
+	// the name.
+	functionPattern = regexp.MustCompile(`^(?:(?:export|default|public|private|protected|internal|static|final|override|open|pub(?:\([^)]*\))?|unsafe|extern|async|suspend)\s+)*(?:def|function\*?|fn|fun|func)\s+(?:self\.)?([A-Za-z_$][\w$]*[?!]?)\s*[(<]?`)
+	// typePattern matches the class, interface, struct, enum, and trait
+	// declarations of most languages, capturing the keyword and the name.
+	typePattern = regexp.MustCompile(`^(?:(?:export|default|public|private|protected|internal|static|final|abstract|sealed|partial|open|data|pub(?:\([^)]*\))?)\s+)*(class|interface|struct|enum|trait|protocol)\s+([A-Za-z_$][\w$]*)`)
+	// symbolQueryPattern matches the queries looked up as symbols: an
+	// identifier, optionally qualified by a type ("Client.Do", "Client::new").
+	symbolQueryPattern = regexp.MustCompile(`^(?:([A-Za-z_$][\w$]*)(?:\.|::|#))?([A-Za-z_$][\w$]*[?!]?)$`)
+)
+
+// Symbol is a code symbol declared in a code block of a document.
+type Symbol struct {
+	// Name is the name of the symbol, e.g., "NewClient".
+	Name string `json:"name"`
+	// Kind is the kind of symbol: SymbolFunction, SymbolMethod, SymbolClass,
+	// SymbolInterface, SymbolStruct, SymbolEnum, SymbolTrait, or SymbolType.
+	Kind string `json:"kind"`
+	// Receiver is the type of a Go method, e.g., "Client" for Client.Do.
+	Receiver string `json:"receiver,omitempty"`
+	// Language is the language of the code block, as its fence declares it.
+	Language string `json:"language,omitempty"`
+	// Path is the slash-separated path of the document relative to the skill
+	// directory, and Line the line of the declaration in it, from 1.
+	Path string `json:"path"`
+	Line int    `json:"line"`
+	// SourceURL is the source URL of the document.
+	SourceURL string `json:"source_url,omitempty"`
+	// Access is the access level of the document; empty when it is public.
+	Access string `json:"access,omitempty"`
+}
+
+// qualifiedName returns the name of s qualified by its receiver, if any:
+// "Client.Do".
+func (s Symbol) qualifiedName() string {
+	if s.Receiver != "" {
+		return s.Receiver + "." + s.Name
+	}
+	return s.Name
+}
+
+// symbolTable is the contents of SymbolsFile.
+type symbolTable struct {
+	// Version is SymbolsVersion.
+	Version int `json:"version"`
+	// Symbols are sorted by name, then by path and line.
+	Symbols []Symbol `json:"symbols"`
+}
+
+// BuildSymbols writes the symbols declared in the code blocks of the documents
+// of the skill at skillDir to SymbolsFile at its root, and returns their
+// number. A symbol declared several times in a document is recorded at its
+// first declaration. Without symbols, no file is written and an old one is
+// removed.
+//
+// Declarations are recognized by their keywords, whatever the language of the
+// code block: Go functions, methods, and types; def, function, fn, fun, and
+// func declarations (Python, Ruby, JavaScript, TypeScript, PHP, Rust, Kotlin,
+// Swift); and class, interface, struct, enum, trait, and protocol
+// declarations. Calls and usages aren't symbols.
+//
+// Returns an error if the documents can't be read or the file can't be written.
+func BuildSymbols(skillDir string) (int, error) {
+	docs, err := listDocuments(skillDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list documents: %w", err)
+	}
+	var symbols []Symbol
+	for _, doc := range docs {
+		content, err := os.ReadFile(filepath.Join(skillDir, filepath.FromSlash(doc.Path)))
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s: %w", doc.Path, err)
+		}
+		frontmatter, body := extractFrontmatter(string(content))
+		offset := bodyOffset(string(content), body)
+		for _, s := range extractSymbols(body) {
+			s.Path, s.Line = doc.Path, s.Line+offset
+			s.SourceURL = frontmatter.SourceURL
+			if frontmatter.Access != access.Public {
+				s.Access = frontmatter.Access
+			}
+			symbols = append(symbols, s)
+		}
+	}
+
+	path := filepath.Join(skillDir, SymbolsFile)
+	if len(symbols) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return 0, fmt.Errorf("failed to remove %s: %w", SymbolsFile, err)
+		}
+		return 0, nil
+	}
+	sort.SliceStable(symbols, func(i, j int) bool {
+		if symbols[i].Name != symbols[j].Name {
+			return symbols[i].Name < symbols[j].Name
+		}
+		if symbols[i].Path != symbols[j].Path {
+			return symbols[i].Path < symbols[j].Path
+		}
+		return symbols[i].Line < symbols[j].Line
+	})
+	data, err := json.MarshalIndent(symbolTable{Version: SymbolsVersion, Symbols: symbols}, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode %s: %w", SymbolsFile, err)
+	}
+	if _, err := atomicfile.WriteFile(path, append(data, '\n')); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", SymbolsFile, err)
+	}
+	return len(symbols), nil
+}
+
+// extractSymbols returns the symbols declared in the fenced code blocks of
+// body, in order, each at most once, with the line of its declaration in body,
+// from 1.
+func extractSymbols(body string) []Symbol {
+	var symbols []Symbol
+	seen := make(map[string]bool)
+	fence, language := "", ""
+	for i, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence == "" {
+			if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+				fence = trimmed[:3]
+				language = ""
+				if fields := strings.Fields(strings.TrimLeft(trimmed, fence[:1])); len(fields) > 0 {
+					language = strings.ToLower(fields[0])
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, fence) {
+			fence = ""
+			continue
+		}
+		s, ok := declaredSymbol(trimmed)
+		if !ok {
+			continue
+		}
+		key := s.Kind + " " + s.Receiver + "." + s.Name
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		s.Language, s.Line = language, i+1
+		symbols = append(symbols, s)
+	}
+	return symbols
+}
+
+// declaredSymbol returns the symbol declared by line, a line of code without
+// its indentation, or false if it declares none.
+func declaredSymbol(line string) (Symbol, bool) {
+	if m := goFuncPattern.FindStringSubmatch(line); m != nil {
+		if m[1] != "" {
+			return Symbol{Name: m[2], Kind: SymbolMethod, Receiver: m[1]}, true
+		}
+		return Symbol{Name: m[2], Kind: SymbolFunction}, true
+	}
+	if m := goTypePattern.FindStringSubmatch(line); m != nil {
+		kind := SymbolType
+		if m[2] != "" {
+			kind = m[2]
+		}
+		return Symbol{Name: m[1], Kind: kind}, true
+	}
+	if m := typePattern.FindStringSubmatch(line); m != nil {
+		kind := m[1]
+		if kind == "protocol" {
+			kind = SymbolInterface
+		}
+		return Symbol{Name: m[2], Kind: kind}, true
+	}
+	if m := functionPattern.FindStringSubmatch(line); m != nil {
+		return Symbol{Name: m[1], Kind: SymbolFunction}, true
+	}
+	return Symbol{}, false
+}
+
+// LookupSymbols returns the symbols of the skill at opts.SkillDir named
+// opts.Query, as recorded in its SymbolsFile (see BuildSymbols): an identifier
+// such as "NewClient", or a method qualified by its type such as "Client.Do".
+// Names are compared exactly, or ignoring case if no symbol has the exact
+// name. Only the symbols of the documents of the access levels of opts.Access
+// are returned.
+//
+// Returns nil if the query isn't an identifier, the skill has no symbol table,
+// or no symbol matches; an error if the symbol table can't be read.
+func LookupSymbols(opts SearchOptions) ([]Symbol, error) {
+	m := symbolQueryPattern.FindStringSubmatch(strings.TrimSpace(opts.Query))
+	if m == nil || opts.Regex {
+		return nil, nil
+	}
+	qualifier, name := m[1], m[2]
+
+	var table symbolTable
+	if err := readJSON(filepath.Join(opts.SkillDir, SymbolsFile), &table); err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", SymbolsFile, err)
+	}
+	if table.Version != SymbolsVersion {
+		return nil, nil
+	}
+
+	var exact, folded []Symbol
+	for _, s := range table.Symbols {
+		if !access.Allowed(opts.Access, s.Access) {
+			continue
+		}
+		if qualifier != "" && !strings.EqualFold(qualifier, s.Receiver) {
+			continue
+		}
+		switch {
+		case s.Name == name && (qualifier == "" || qualifier == s.Receiver):
+			exact = append(exact, s)
+		case strings.EqualFold(s.Name, name):
+			folded = append(folded, s)
+		}
+	}
+	if len(exact) > 0 {
+		return exact, nil
+	}
+	return folded, nil
+}
+
+// Symbols is LookupSymbols on the skill of s (opts.SkillDir is ignored).
+func (s *Searcher) Symbols(opts SearchOptions) ([]Symbol, error) {
+	opts.SkillDir = s.skillDir
+	return LookupSymbols(opts)
+}
@@ -0,0 +1,101 @@
+package search
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDeclaredSymbol(t *testing.T) {
+	tests := []struct {
+		line string
+		want Symbol
+		ok   bool
+	}{
+		{line: "func NewClient(opts Options) *Client {", want: Symbol{Name: "NewClient", Kind: SymbolFunction}, ok: true},
+		{line: "func (c *Client) Do(req *Request) error", want: Symbol{Name: "Do", Kind: SymbolMethod, Receiver: "Client"}, ok: true},
+		{line: "func (s Set[T]) Add(v T)", want: Symbol{Name: "Add", Kind: SymbolMethod, Receiver: "Set"}, ok: true},
+		{line: "type Options struct {", want: Symbol{Name: "Options", Kind: SymbolStruct}, ok: true},
+		{line: "type Doer interface {", want: Symbol{Name: "Doer", Kind: SymbolInterface}, ok: true},
+		{line: "type ID string", want: Symbol{Name: "ID", Kind: SymbolType}, ok: true},
+		{line: "def fetch_page(url):", want: Symbol{Name: "fetch_page", Kind: SymbolFunction}, ok: true},
+		{line: "export async function createClient(config) {", want: Symbol{Name: "createClient", Kind: SymbolFunction}, ok: true},
+		{line: "pub fn new() -> Self {", want: Symbol{Name: "new", Kind: SymbolFunction}, ok: true},
+		{line: "export default class ApiClient extends Base {", want: Symbol{Name: "ApiClient", Kind: SymbolClass}, ok: true},
+		{line: "public enum Color {", want: Symbol{Name: "Color", Kind: SymbolEnum}, ok: true},
+		{line: "protocol Fetching {", want: Symbol{Name: "Fetching", Kind: SymbolInterface}, ok: true},
+		{line: "client := NewClient(opts)", ok: false},
+		{line: "# define the client", ok: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, ok := declaredSymbol(tt.line)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("declaredSymbol(%q) = %+v, %v, want %+v, %v", tt.line, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestLookupSymbols(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"client.md": "---\ntitle: Client\nsource_url: https://example.com/client\n---\n\n# Client\n\nNewClient is not declared here.\n\n```go\nfunc NewClient() *Client\n\nfunc (c *Client) Do(req *Request) error\nfunc newclient() {}\n```\n",
+		"admin.md":  "---\ntitle: Admin\naccess: internal\n---\n\n```python\ndef Do():\n    pass\n```\n",
+	})
+	n, err := BuildSymbols(skillDir)
+	if err != nil {
+		t.Fatalf("BuildSymbols() returned error: %v", err)
+	}
+	if n != 4 {
+		t.Errorf("BuildSymbols() = %d, want 4", n)
+	}
+
+	tests := []struct {
+		name   string
+		query  string
+		access []string
+		want   []string
+	}{
+		{name: "exact name", query: "NewClient", want: []string{"NewClient docs/client.md:11"}},
+		{name: "case-insensitive fallback", query: "NEWCLIENT", want: []string{"NewClient docs/client.md:11", "newclient docs/client.md:14"}},
+		{name: "qualified method", query: "Client.Do", want: []string{"Client.Do docs/client.md:13"}},
+		{name: "every access level", query: "Do", want: []string{"Do docs/admin.md:7", "Client.Do docs/client.md:13"}},
+		{name: "access filter", query: "Do", access: []string{"public"}, want: []string{"Client.Do docs/client.md:13"}},
+		{name: "not an identifier", query: "new client", want: nil},
+		{name: "unknown symbol", query: "Close", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			symbols, err := LookupSymbols(SearchOptions{SkillDir: skillDir, Query: tt.query, Access: tt.access})
+			if err != nil {
+				t.Fatalf("LookupSymbols() returned error: %v", err)
+			}
+			var got []string
+			for _, s := range symbols {
+				got = append(got, fmt.Sprintf("%s %s:%d", s.qualifiedName(), s.Path, s.Line))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LookupSymbols(%q) = %q, want %q", tt.query, got, tt.want)
+			}
+		})
+	}
+
+	// Without symbols, the table is removed and lookups find nothing
+	if err := os.Remove(filepath.Join(skillDir, "docs", "client.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(skillDir, "docs", "admin.md")); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := BuildSymbols(skillDir); err != nil || n != 0 {
+		t.Fatalf("BuildSymbols() = %d, %v, want 0, nil", n, err)
+	}
+	if _, err := os.Stat(filepath.Join(skillDir, SymbolsFile)); !os.IsNotExist(err) {
+		t.Errorf("%s not removed: %v", SymbolsFile, err)
+	}
+	if symbols, err := LookupSymbols(SearchOptions{SkillDir: skillDir, Query: "NewClient"}); err != nil || symbols != nil {
+		t.Errorf("LookupSymbols() without a table = %v, %v, want nil, nil", symbols, err)
+	}
+}
@@ -1,6 +1,13 @@
 // Package search provides types for documentation search operations.
 package search
 
+import (
+	"strings"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/embeddings"
+)
+
 // SearchResult represents a single search result from a documentation file.
 // It includes metadata about where the match was found, how many times it occurred,
 // surrounding context lines, and the source information.
@@ -9,24 +16,97 @@ type SearchResult struct {
 	File string `json:"file"`
 	// Matches is the total number of keyword matches found in this file.
 	Matches int `json:"matches"`
+	// MatchedTerms lists the words of the file matched in place of the keywords of a
+	// fuzzy or stemmed search (e.g., "authentication" for "authetication"), sorted; empty otherwise.
+	MatchedTerms []string `json:"matched_terms,omitempty"`
 	// Contexts is a slice of context snippets, each showing matches with surrounding lines.
-	// Each line is prefixed with "> " for matched lines and "  " for context lines.
+	// Each line is prefixed with "> " for matched lines and "  " for context lines. In
+	// regular expression searches, the matched text is between HighlightOpen and HighlightClose.
 	Contexts []string `json:"contexts"`
+	// Sections locates the contexts in the document: Sections[i] is the section
+	// containing the first matched line of Contexts[i].
+	Sections []Section `json:"sections,omitempty"`
 	// SourceURL is the original URL where the documentation was fetched from.
 	SourceURL string `json:"source_url"`
 	// FetchedAt is the ISO 3339 timestamp when the documentation was fetched.
 	FetchedAt string `json:"fetched_at"`
+	// ModifiedAt is the RFC 3339 time the server reported the documentation was last
+	// modified; empty when it reported none.
+	ModifiedAt string `json:"modified_at,omitempty"`
+	// Score is the score by which the results are sorted: the matches weighted by
+	// the field they are in (see FieldWeights), the rank fusion score in a semantic
+	// search, or either boosted by recency with SearchOptions.RecencyHalfLife (see
+	// SearchDocs).
+	Score float64 `json:"score,omitempty"`
+	// MatchedFields lists the fields of the document the query matches in, among
+	// FieldTitle, FieldSummary, FieldHeadings, and FieldBody, in that order; empty for the
+	// documents found by meaning only in a semantic search.
+	MatchedFields []string `json:"matched_fields,omitempty"`
+	// Similarity is the cosine similarity of the file's section most similar to the
+	// query in a semantic search; 0 if the file isn't among the most similar.
+	Similarity float64 `json:"similarity,omitempty"`
+}
+
+// Section is a section of a document delimited by its Markdown headings.
+type Section struct {
+	// Headings is the heading path of the section, outermost first (e.g.,
+	// ["Getting Started", "Installation", "Docker"]); empty before the first heading.
+	Headings []string `json:"headings"`
+	// StartLine and EndLine are the first and last lines of the section in the
+	// file, numbered from 1: its heading, and the line before the next heading
+	// of the same or a higher level.
+	StartLine int `json:"start_line"`
+	EndLine   int `json:"end_line"`
+	// Anchor is the anchor of the heading of the section, the GitHub-style
+	// slug of its text (e.g., "docker"), with "-1", "-2", and so on appended
+	// to repeats within the document; empty before the first heading.
+	Anchor string `json:"anchor,omitempty"`
+	// Link is the deep link to the section, its file and Anchor (e.g.,
+	// "docs/guide.md#docker"); empty when it has no anchor.
+	Link string `json:"link,omitempty"`
+}
+
+// Breadcrumb returns the heading path of the section separated by " > ".
+func (s Section) Breadcrumb() string {
+	return strings.Join(s.Headings, " > ")
 }
 
 // Frontmatter represents YAML frontmatter metadata extracted from a Markdown file.
 // It contains document metadata added during the conversion process.
 type Frontmatter struct {
 	// Title is the document title from the frontmatter.
-	Title string
+	Title string `yaml:"title"`
+	// Description is the page's meta description.
+	Description string `yaml:"description"`
+	// Summary is the abstract of the document written by a summarizer (see
+	// package summarize).
+	Summary string `yaml:"summary"`
 	// SourceURL is the original URL where the document was fetched from.
-	SourceURL string
+	SourceURL string `yaml:"source_url"`
+	// CanonicalURL is the URL the page declares as canonical.
+	CanonicalURL string `yaml:"canonical_url"`
 	// FetchedAt is the ISO 3339 timestamp when the document was fetched.
-	FetchedAt string
+	FetchedAt string `yaml:"fetched_at"`
+	// ModifiedAt is the RFC 3339 time the server reported the document was last
+	// modified, when it reported one.
+	ModifiedAt string `yaml:"modified_at"`
+	// Locale is the locale of the page.
+	Locale string `yaml:"locale"`
+	// WordCount is the number of words in the document outside code blocks.
+	WordCount int `yaml:"word_count"`
+	// PageType classifies the page as "docs", "marketing", or "legal".
+	PageType string `yaml:"page_type"`
+	// Access is the access level of the page ("public", "internal", or
+	// "confidential"); empty means public. See package access.
+	Access string `yaml:"access"`
+	// Part and Parts number the files of a page split by the chunk budget (e.g., part 2 of 3).
+	Part  int `yaml:"part"`
+	Parts int `yaml:"parts"`
+	// Tags are the page's meta keywords.
+	Tags []string `yaml:"tags"`
+	// Outline lists the H1-H3 headings of the document with their "#" markers
+	// (e.g., "## Install").
+	Outline []string `yaml:"outline"`
 }
 
 // SearchOptions contains configuration parameters for search operations.
@@ -34,10 +114,82 @@ type Frontmatter struct {
 type SearchOptions struct {
 	// SkillDir is the path to the skill directory containing the docs/ subdirectory to search.
 	SkillDir string
-	// Query is the search query string (space-separated keywords with OR logic).
+	// Query is the search query string: space-separated keywords and "quoted phrases"
+	// (OR logic), +required and -excluded terms (see SearchDocs).
 	Query string
+	// MatchAll requires every term of the query without a prefix (AND logic), as if
+	// each was prefixed with +.
+	MatchAll bool
+	// Regex treats Query as a Go regular expression evaluated line by line, with the
+	// matches highlighted in the contexts (see SearchDocs). Query syntax and MatchAll
+	// don't apply.
+	Regex bool
+	// Fuzzy also matches the keywords of at least 4 characters with the words within 1 or
+	// 2 edits of them, tolerating typos (see SearchDocs). It doesn't apply with Regex.
+	Fuzzy bool
+	// Stem also matches the keywords with the words sharing their stem in the language of
+	// each document, and leaves the stop words of the language out of the query (see SearchDocs).
+	// It doesn't apply with Regex.
+	Stem bool
+	// StopWords replaces the built-in stop words of the languages it lists (e.g., "en")
+	// with Stem; an empty list disables them. See LoadStopWords.
+	StopWords map[string][]string
+	// Semantic ranks the documents by both keyword matches and similarity of meaning to
+	// the query, using the embeddings of the skill (see SearchDocs and BuildEmbeddings).
+	Semantic bool
+	// Embedder embeds the query of a semantic search; nil uses the provider the skill
+	// was embedded with, authenticated with the API key of the environment (see
+	// embeddings.APIKey).
+	Embedder embeddings.Provider
+	// Access lists the access levels the caller is allowed (see package access): documents
+	// whose access frontmatter field is another level are left out of the results, and
+	// documents without one are public. Nil searches every document.
+	Access []string
+	// Locale restricts the results to the documents of this locale, given by the locale
+	// frontmatter field; a language ("en") also matches its regional variants ("en-US").
+	Locale string
+	// PathPrefix restricts the results to the documents whose source URL path is this
+	// path or below it (e.g., "/api" or "/api/**").
+	PathPrefix string
+	// FetchedAfter restricts the results to the documents fetched after this time, given
+	// by the fetched_at frontmatter field; the zero time doesn't restrict them.
+	FetchedAfter time.Time
+	// Tag restricts the results to the documents with this tag (case-insensitive).
+	Tag string
+	// RecencyHalfLife boosts the recent documents in the ranking: the score of each
+	// result (its match count, or rank fusion score with Semantic) is halved for
+	// every RecencyHalfLife its document is older than the most recent result, by
+	// its modified_at frontmatter field, else its fetched_at (see SearchDocs). 0
+	// disables it. It doesn't apply with Stream.
+	RecencyHalfLife time.Duration
 	// MaxResults limits the number of results returned (0 means unlimited).
 	MaxResults int
+	// Stream returns the documents scanned as soon as they match, in the order of their
+	// paths, instead of ranked once every document is searched, and stops scanning after
+	// MaxResults of them (see Searcher.Search). Results looked up in the index or ranked
+	// by similarity are always ranked.
+	Stream bool
+	// Workers is the number of documents read and matched concurrently when the
+	// index isn't used (see SearchDocs): 0 means the number of CPUs, at least 4.
+	Workers int
+	// ContextLines is the number of lines shown before and after the matched lines in
+	// the contexts of each result: 0 means the default of 2, and a negative number shows
+	// the matched lines only.
+	ContextLines int
+	// Highlight surrounds the matched terms in the contexts with HighlightOpen and
+	// HighlightClose, which FormatResults renders in color. The matches of Regex are
+	// always highlighted.
+	Highlight bool
+	// FieldWeights weighs the matches in the title, headings, and body of the
+	// documents in the score of the results (see FieldWeights); zero means
+	// DefaultFieldWeights.
+	FieldWeights FieldWeights
+	// Format is the output format of the results (see WriteResults): OutputText
+	// (the default when empty), OutputJSON, OutputMarkdown, or OutputCSV.
+	Format string
 	// JSONOutput specifies whether to format results as JSON instead of human-readable text.
+	//
+	// Deprecated: set Format to OutputJSON instead, which JSONOutput stands for
+	// when Format is empty.
 	JSONOutput bool
 }
@@ -0,0 +1,185 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the incremental update of the inverted index, and the
+// watching of a skill's docs/ directory that keeps it up to date.
+package search
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/watch"
+)
+
+// IndexUpdate reports what UpdateIndex changed.
+type IndexUpdate struct {
+	// Documents is the number of documents in the updated index.
+	Documents int
+	// Added, Updated, and Removed count the documents of docs/ missing from
+	// the old index, those changed since they were indexed, and those of the
+	// old index no longer in docs/.
+	Added, Updated, Removed int
+	// Rebuilt reports whether the index was built from scratch because the
+	// old one was missing, of another version, or corrupt. Added, Updated, and
+	// Removed are then 0.
+	Rebuilt bool
+}
+
+// Changed reports whether the update changed the index.
+func (u *IndexUpdate) Changed() bool {
+	return u.Rebuilt || u.Added+u.Updated+u.Removed > 0
+}
+
+// UpdateIndex brings the inverted index of the skill at skillDir up to date
+// with its docs/ directory, reading only the documents added or changed since
+// they were indexed (by size and modification time): the postings of the
+// other documents are kept from the old index, and only the postings files
+// whose contents change are written again. The result is the index BuildIndex
+// would write. Without a usable old index, the index is built from scratch.
+//
+// Returns an error if the skill has no docs/ directory, or the documents can't
+// be read or the index can't be written.
+func UpdateIndex(skillDir string) (*IndexUpdate, error) {
+	docsDir := filepath.Join(skillDir, "docs")
+	if _, err := os.Stat(docsDir); err != nil {
+		return nil, fmt.Errorf("docs directory not found: %s", docsDir)
+	}
+	indexDir := filepath.Join(docsDir, IndexDir)
+
+	// Searches of this process repairing the index would race with the update
+	repairMu.Lock()
+	defer repairMu.Unlock()
+
+	docs, err := listDocuments(skillDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	old, oldPostings, ok := readIndex(skillDir)
+	if !ok {
+		if err := BuildIndex(skillDir); err != nil {
+			return nil, err
+		}
+		return &IndexUpdate{Documents: len(docs), Rebuilt: true}, nil
+	}
+
+	res := &IndexUpdate{Documents: len(docs)}
+	oldPositions := make(map[string]int, len(old.Documents))
+	for i, doc := range old.Documents {
+		oldPositions[doc.Path] = i
+	}
+	// moved maps the position of each unchanged document in the old index to
+	// its position in the new one, -1 for the others
+	moved := make([]int, len(old.Documents))
+	for i := range moved {
+		moved[i] = -1
+	}
+	var changed []int
+	for i, doc := range docs {
+		j, ok := oldPositions[doc.Path]
+		if !ok {
+			res.Added++
+			changed = append(changed, i)
+			continue
+		}
+		delete(oldPositions, doc.Path)
+		if !sameDocuments(docs[i:i+1], old.Documents[j:j+1]) {
+			res.Updated++
+			changed = append(changed, i)
+			continue
+		}
+		docs[i] = old.Documents[j]
+		moved[j] = i
+	}
+	res.Removed = len(oldPositions)
+	if !res.Changed() {
+		return res, nil
+	}
+
+	postings := make(map[string][]Posting, len(oldPostings))
+	for term, list := range oldPostings {
+		for _, p := range list {
+			if p.Doc >= 0 && p.Doc < len(moved) && moved[p.Doc] >= 0 {
+				postings[term] = append(postings[term], Posting{Doc: moved[p.Doc], Positions: p.Positions})
+			}
+		}
+	}
+	for _, i := range changed {
+		if err := indexDocument(skillDir, docs, i, postings); err != nil {
+			return nil, err
+		}
+	}
+	// The postings of each term are in document order, as BuildIndex writes them
+	for _, list := range postings {
+		if !sort.SliceIsSorted(list, func(a, b int) bool { return list[a].Doc < list[b].Doc }) {
+			sort.Slice(list, func(a, b int) bool { return list[a].Doc < list[b].Doc })
+		}
+	}
+
+	if err := writeIndex(indexDir, docs, postings, old); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// readIndex reads the index of the skill at skillDir and the postings of all
+// its terms, without repairing it (see openIndex). Returns false if the skill
+// has no index, has one of another version, or any of its files is corrupt.
+func readIndex(skillDir string) (*Index, map[string][]Posting, bool) {
+	var idx Index
+	if err := readJSON(filepath.Join(skillDir, "docs", IndexDir, IndexFile), &idx); err != nil {
+		return nil, nil, false
+	}
+	if idx.Version != IndexVersion || idx.Shards < 1 || len(idx.Checksums) != idx.Shards {
+		return nil, nil, false
+	}
+	postings := make(map[string][]Posting, len(idx.Terms))
+	for shard := range idx.Shards {
+		shardPostings, ok := readShard(skillDir, &idx, shard)
+		if !ok {
+			return nil, nil, false
+		}
+		for term, list := range shardPostings {
+			postings[term] = list
+		}
+	}
+	return &idx, postings, true
+}
+
+// WatchIndex keeps the inverted index of the skill at skillDir up to date
+// until ctx is cancelled: it updates the index (see UpdateIndex), then polls
+// the documents of docs/ every interval and updates the index again whenever
+// they change, once they stay unchanged for an interval (see watch.Wait). The
+// searches of the skill, and thus the serve and mcp servers answering them,
+// then use the index for the documents as regenerated or edited instead of
+// scanning every document until the index is rebuilt.
+//
+// Each update is passed to report, with its error if it failed; a failed
+// update is tried again on the next change. report may be nil.
+//
+// Returns ctx's error once it is cancelled, or an error if docs/ can't be walked.
+func WatchIndex(ctx context.Context, skillDir string, interval time.Duration, report func(*IndexUpdate, error)) error {
+	if report == nil {
+		report = func(*IndexUpdate, error) {}
+	}
+	paths := []string{filepath.Join(skillDir, "docs")}
+	// The index directory is hidden, so its files are never watched
+	snapshot, err := watch.Take(paths, nil)
+	if err != nil {
+		return fmt.Errorf("failed to watch documents: %w", err)
+	}
+	report(UpdateIndex(skillDir))
+	for {
+		next, _, err := watch.Wait(ctx, paths, nil, snapshot, interval)
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return fmt.Errorf("failed to watch documents: %w", err)
+		}
+		snapshot = next
+		report(UpdateIndex(skillDir))
+	}
+}
@@ -0,0 +1,121 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUpdateIndex(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"install.md": "---\ntitle: Install\n---\n# Install\n\nRun the installer.\n",
+		"auth.md":    "---\ntitle: Auth\n---\n# Auth\n\nUse an API token.\n",
+		"limits.md":  "---\ntitle: Limits\n---\n# Limits\n\nThe rate limit is 100 requests per minute.\n",
+	})
+	docsDir := filepath.Join(skillDir, "docs")
+
+	res, err := UpdateIndex(skillDir)
+	if err != nil {
+		t.Fatalf("UpdateIndex() without an index returned error: %v", err)
+	}
+	if want := (IndexUpdate{Documents: 3, Rebuilt: true}); *res != want {
+		t.Errorf("UpdateIndex() without an index = %+v, want %+v", *res, want)
+	}
+	if res, err = UpdateIndex(skillDir); err != nil || res.Changed() {
+		t.Errorf("UpdateIndex() of an up-to-date index = %+v, %v; want no change", res, err)
+	}
+
+	// Regenerated, added, and removed documents
+	if err := os.WriteFile(filepath.Join(docsDir, "auth.md"), []byte("---\ntitle: Authentication\n---\n# Authentication\n\nUse an API token or OAuth.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(docsDir, "api"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(docsDir, "api", "webhooks.md"), []byte("---\ntitle: Webhooks\n---\n# Webhooks\n\nWebhooks retry with a token.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(docsDir, "limits.md")); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = UpdateIndex(skillDir)
+	if err != nil {
+		t.Fatalf("UpdateIndex() returned error: %v", err)
+	}
+	if want := (IndexUpdate{Documents: 3, Added: 1, Updated: 1, Removed: 1}); *res != want {
+		t.Errorf("UpdateIndex() = %+v, want %+v", *res, want)
+	}
+	updated, updatedPostings, ok := readIndex(skillDir)
+	if !ok {
+		t.Fatal("readIndex() of the updated index failed")
+	}
+	results, ok, err := searchIndex(context.Background(), skillDir, SearchOptions{SkillDir: skillDir, Query: "token"})
+	if !ok || err != nil || len(results) != 2 {
+		t.Errorf("searchIndex() = %d results, %v, %v; want the updated index to find 2", len(results), ok, err)
+	}
+
+	// The update writes the index a full build would
+	if err := BuildIndex(skillDir); err != nil {
+		t.Fatalf("BuildIndex() returned error: %v", err)
+	}
+	built, builtPostings, ok := readIndex(skillDir)
+	if !ok {
+		t.Fatal("readIndex() of the built index failed")
+	}
+	if !reflect.DeepEqual(updated, built) || !reflect.DeepEqual(updatedPostings, builtPostings) {
+		t.Errorf("updated index = %+v, want the built index %+v", updated, built)
+	}
+}
+
+func TestWatchIndex(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"install.md": "Run the installer.",
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates := make(chan IndexUpdate, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- WatchIndex(ctx, skillDir, 10*time.Millisecond, func(u *IndexUpdate, err error) {
+			if err != nil {
+				t.Errorf("update failed: %v", err)
+				return
+			}
+			updates <- *u
+		})
+	}()
+
+	next := func() IndexUpdate {
+		t.Helper()
+		select {
+		case u := <-updates:
+			return u
+		case <-time.After(5 * time.Second):
+			t.Fatal("no index update")
+			return IndexUpdate{}
+		}
+	}
+	if u := next(); !u.Rebuilt {
+		t.Errorf("first update = %+v, want the index built", u)
+	}
+	if err := os.WriteFile(filepath.Join(skillDir, "docs", "limits.md"), []byte("The rate limit is 100 requests per minute."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if u := next(); u.Added != 1 || u.Documents != 2 {
+		t.Errorf("update after adding a document = %+v, want 1 added", u)
+	}
+	if _, ok, err := searchIndex(ctx, skillDir, SearchOptions{SkillDir: skillDir, Query: "limit"}); !ok || err != nil {
+		t.Errorf("searchIndex() = %v, %v; want the watched index to answer", ok, err)
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("WatchIndex() = %v, want context.Canceled", err)
+	}
+}
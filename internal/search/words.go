@@ -0,0 +1,184 @@
+// Package search provides full-text search functionality for documentation files in skill packages.
+// This file implements the matching of keywords with other words of a document, in the fuzzy
+// (SearchOptions.Fuzzy) and stemming (SearchOptions.Stem) modes.
+package search
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/lang"
+	"gopkg.in/yaml.v3"
+)
+
+// wordMatcher evaluates a query on a document in the fuzzy and stemming modes:
+// each keyword that is a single word also matches the words of the document
+// within maxEdits of it (fuzzy) or with the same stem (stemming). Phrases and
+// excluded terms match exactly.
+type wordMatcher struct {
+	q query
+	// bodyLower is the lowercased document body
+	bodyLower string
+	// words counts the occurrences of each word of bodyLower
+	words map[string]int
+	// fuzzy enables the fuzzy mode
+	fuzzy bool
+	// stem returns the stem of a word in the language of the document; nil
+	// disables the stemming mode
+	stem func(string) string
+	// stems caches the stems of words
+	stems map[string]string
+	// matched collects the words matched in place of a keyword
+	matched map[string]bool
+}
+
+// newWordMatcher returns a wordMatcher of q for the document body bodyLower.
+func newWordMatcher(q query, bodyLower string, fuzzy bool, stem func(string) string) *wordMatcher {
+	m := &wordMatcher{
+		q:         q,
+		bodyLower: bodyLower,
+		words:     make(map[string]int),
+		fuzzy:     fuzzy,
+		stem:      stem,
+		stems:     make(map[string]string),
+		matched:   make(map[string]bool),
+	}
+	for _, word := range tokenize(bodyLower) {
+		m.words[word]++
+	}
+	return m
+}
+
+// match returns the number of occurrences of the terms of the query in the
+// document, counting those of the words matched in their place, or 0 if the
+// document doesn't satisfy the query.
+func (m *wordMatcher) match() int {
+	for _, term := range m.q.excluded {
+		if strings.Contains(m.bodyLower, term) {
+			return 0
+		}
+	}
+	return query{optional: m.q.optional, required: m.q.required}.matchCounts(m.count)
+}
+
+// count returns the number of occurrences of a required or optional term in
+// the document.
+func (m *wordMatcher) count(term string) int {
+	n := strings.Count(m.bodyLower, term)
+	if words := tokenize(term); len(words) != 1 || words[0] != term {
+		return n
+	}
+	edits := 0
+	if m.fuzzy {
+		edits = maxEdits(term)
+	}
+	var termStem string
+	if m.stem != nil {
+		termStem = m.stemOf(term)
+	}
+	if edits == 0 && m.stem == nil {
+		return n
+	}
+
+	for word, c := range m.words {
+		// Words containing the term are already counted as substrings
+		if strings.Contains(word, term) {
+			continue
+		}
+		if (edits > 0 && editDistance(term, word, edits) <= edits) || (m.stem != nil && m.stemOf(word) == termStem) {
+			n += c
+			m.matched[word] = true
+		}
+	}
+	return n
+}
+
+// stemOf returns the stem of word.
+func (m *wordMatcher) stemOf(word string) string {
+	s, ok := m.stems[word]
+	if !ok {
+		s = m.stem(word)
+		m.stems[word] = s
+	}
+	return s
+}
+
+// matchedWords returns the words matched in place of a keyword, sorted, or
+// nil if there are none.
+func (m *wordMatcher) matchedWords() []string {
+	var words []string
+	for word := range m.matched {
+		words = append(words, word)
+	}
+	sort.Strings(words)
+	return words
+}
+
+// withoutStopWords returns q without its required and optional terms in stop,
+// unless they all are: a query of stop words alone still matches them.
+func (q query) withoutStopWords(stop map[string]bool) query {
+	var kept query
+	kept.excluded = q.excluded
+	for _, term := range q.optional {
+		if !stop[term] {
+			kept.optional = append(kept.optional, term)
+		}
+	}
+	for _, term := range q.required {
+		if !stop[term] {
+			kept.required = append(kept.required, term)
+		}
+	}
+	if len(kept.optional)+len(kept.required) == 0 {
+		return q
+	}
+	return kept
+}
+
+// documentLanguage returns the language of a document from the locale of its
+// frontmatter, English if it has none.
+func documentLanguage(fm Frontmatter) string {
+	if language := lang.Language(fm.Locale); language != "" {
+		return language
+	}
+	return "en"
+}
+
+// stopWordSet returns the stop words of a language: those of custom if it
+// lists the language, else the built-in ones.
+func stopWordSet(custom map[string][]string, language string) map[string]bool {
+	words, ok := custom[language]
+	if !ok {
+		words = lang.StopWords(language)
+	}
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		set[strings.ToLower(w)] = true
+	}
+	return set
+}
+
+// LoadStopWords reads stop word lists from a YAML or JSON file mapping
+// languages to their stop words, for SearchOptions.StopWords:
+//
+//	en: [a, an, the, how, what]
+//	de: [der, die, das]
+//
+// Returns an error if the file can't be read or parsed.
+func LoadStopWords(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stop words file: %w", err)
+	}
+	var lists map[string][]string
+	if err := yaml.Unmarshal(data, &lists); err != nil {
+		return nil, fmt.Errorf("failed to parse stop words file %s: %w", path, err)
+	}
+	stopWords := make(map[string][]string, len(lists))
+	for language, words := range lists {
+		stopWords[lang.Language(language)] = words
+	}
+	return stopWords, nil
+}
@@ -0,0 +1,69 @@
+package search
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSearchDocsStem(t *testing.T) {
+	skillDir := writeSkillDocs(t, map[string]string{
+		"proxy.md": "---\nlocale: en-US\n---\nThe proxy configuration is read at startup.",
+		"cache.md": "Caches are configured per host.",
+		"de.md":    "---\nlocale: de\n---\nDie Konfiguration des Proxys.",
+		"the.md":   "The end.",
+	})
+
+	tests := []struct {
+		name        string
+		opts        SearchOptions
+		wantFiles   []string
+		wantMatched []string
+	}{
+		{"shared stem", SearchOptions{Query: "configuring"}, []string{"cache.md", "proxy.md"}, nil},
+		{"stop words are ignored", SearchOptions{Query: "the configuring", MatchAll: true}, []string{"cache.md", "proxy.md"}, nil},
+		{"stop words alone still match", SearchOptions{Query: "the"}, []string{"proxy.md", "the.md"}, nil},
+		{"custom stop words", SearchOptions{Query: "the configuring", MatchAll: true, StopWords: map[string][]string{"en": {}}}, []string{"proxy.md"}, []string{"configuration"}},
+		{"languages without a stemmer match as usual", SearchOptions{Query: "proxies"}, []string{"proxy.md"}, []string{"proxy"}},
+		{"with fuzzy", SearchOptions{Query: "confgured", Fuzzy: true}, []string{"cache.md"}, []string{"configured"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			opts.SkillDir = skillDir
+			opts.Stem = true
+			results, err := SearchDocs(opts)
+			if err != nil {
+				t.Fatalf("SearchDocs() returned error: %v", err)
+			}
+			if files := resultFiles(results); !reflect.DeepEqual(files, tt.wantFiles) {
+				t.Fatalf("SearchDocs(%q) matched %v, want %v", opts.Query, files, tt.wantFiles)
+			}
+			if tt.wantMatched != nil && !reflect.DeepEqual(results[0].MatchedTerms, tt.wantMatched) {
+				t.Errorf("MatchedTerms = %v, want %v", results[0].MatchedTerms, tt.wantMatched)
+			}
+		})
+	}
+}
+
+func TestLoadStopWords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stopwords.yaml")
+	if err := os.WriteFile(path, []byte("en-US: [how, what]\nde: [der, die]\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := LoadStopWords(path)
+	if err != nil {
+		t.Fatalf("LoadStopWords() returned error: %v", err)
+	}
+	want := map[string][]string{"en": {"how", "what"}, "de": {"der", "die"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadStopWords() = %v, want %v", got, want)
+	}
+
+	if _, err := LoadStopWords(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("LoadStopWords() accepted a missing file")
+	}
+}
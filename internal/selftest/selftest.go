@@ -0,0 +1,267 @@
+// Package selftest checks a site2skillgo installation end to end: it serves a
+// miniature documentation site bundled in the binary, builds a skill from it
+// with the full pipeline, and checks the output, so that a regression or a
+// broken environment (plugins, embeddings, file permissions) shows up before a
+// real build does.
+package selftest
+
+import (
+	"archive/zip"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/pkg/site2skill"
+)
+
+// SkillName is the name of the skill built from the bundled site.
+const SkillName = "selftest"
+
+//go:embed site
+var siteFiles embed.FS
+
+// Result is the outcome of one check of a self-test.
+type Result struct {
+	// Check names what was checked (e.g., "build" or "documents (claude)").
+	Check string `json:"check"`
+	// Passed reports whether the output was as expected.
+	Passed bool `json:"passed"`
+	// Detail explains a failure; empty when Passed is true.
+	Detail string `json:"detail,omitempty"`
+}
+
+// expectedDoc is a document the skill built from the bundled site must have.
+type expectedDoc struct {
+	// file is the name of the document in docs/
+	file string
+	// title is the title of its frontmatter
+	title string
+	// contains lists text the document must contain
+	contains []string
+	// links lists the URL paths of the pages the document links to
+	links []string
+}
+
+// expectedDocs lists the documents of the bundled site and what they hold:
+// a code block keeps its language, a table stays a GFM table, and links
+// between pages point to the document of the page, or keep its URL with
+// absolute links.
+var expectedDocs = []expectedDoc{
+	{file: "index.md", title: "Widget Docs", contains: []string{
+		"assembles widgets from parts",
+	}, links: []string{"/getting-started/"}},
+	{file: "getting-started.md", title: "Getting started - Widget Docs", contains: []string{
+		"```bash\nwidget install --frobnicate\n```",
+		"```python\nimport widget",
+	}, links: []string{"/reference/api/"}},
+	{file: "api.md", title: "API reference - Widget Docs", contains: []string{
+		"| Function | Parameters | Returns |",
+		"| assemble | parts | a widget |",
+	}},
+}
+
+// searchTerm is a word found in one document of the bundled site only, and
+// searchDoc is that document.
+const (
+	searchTerm = "frobnicate"
+	searchDoc  = "getting-started.md"
+)
+
+// Server serves the bundled site on a local port.
+type Server struct {
+	// URL is the URL of the site's home page, e.g., "http://127.0.0.1:41234/".
+	URL string
+
+	server *http.Server
+}
+
+// Serve starts serving the bundled site on a free port of the loopback
+// interface. Call Close to stop it.
+func Serve() (*Server, error) {
+	root, err := fs.Sub(siteFiles, "site")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the bundled site: %w", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
+	}
+	s := &Server{
+		URL:    "http://" + listener.Addr().String() + "/",
+		server: &http.Server{Handler: http.FileServer(http.FS(root))},
+	}
+	go s.server.Serve(listener)
+	return s, nil
+}
+
+// Close stops the server.
+func (s *Server) Close() error {
+	return s.server.Close()
+}
+
+// Run builds a skill from the bundled site with cfg and checks it, working in
+// dir. The URL, skill name, and temporary directory of cfg are replaced, and
+// the directory of each of its targets (a Claude skill if it has none) is
+// moved into dir, so the self-test writes nothing outside of dir; the other
+// settings apply as given, so that the self-test exercises them.
+//
+// A failing build or check is reported as a Result that didn't pass. Returns
+// an error only if the self-test itself can't run, e.g., if the site can't be
+// served.
+func Run(ctx context.Context, cfg site2skill.Config, dir string) ([]Result, error) {
+	server, err := Serve()
+	if err != nil {
+		return nil, err
+	}
+	defer server.Close()
+
+	cfg.URL = server.URL
+	cfg.SkillName = SkillName
+	cfg.TempDir = filepath.Join(dir, "build")
+	targets := cfg.Targets
+	if len(targets) == 0 {
+		targets = []site2skill.Target{{Format: site2skill.FormatClaude}}
+	}
+	cfg.Targets = nil
+	for _, t := range targets {
+		cfg.Targets = append(cfg.Targets, site2skill.Target{Format: t.Format, Dir: filepath.Join(dir, t.Format)})
+	}
+
+	res, err := site2skill.Build(ctx, cfg)
+	if err != nil {
+		return []Result{{Check: "build", Detail: err.Error()}}, nil
+	}
+	results := []Result{{Check: "build", Passed: true}}
+	results = append(results, check("crawl", checkCrawl(res)))
+	for _, skill := range res.Skills {
+		suffix := ""
+		if len(res.Skills) > 1 {
+			suffix = " (" + skill.Format + ")"
+		}
+		results = append(results,
+			check("documents"+suffix, checkDocuments(skill.Dir, cfg.AbsoluteLinks)),
+			check("validation"+suffix, checkValid(skill)),
+			check("package"+suffix, checkPackage(skill.Package)),
+			check("search"+suffix, checkSearch(ctx, skill.Dir)),
+		)
+	}
+	return results, nil
+}
+
+// Failed returns the number of results that didn't pass.
+func Failed(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if !r.Passed {
+			n++
+		}
+	}
+	return n
+}
+
+// check returns the result of the check named name that failed with err, or
+// passed if err is nil.
+func check(name string, err error) Result {
+	if err != nil {
+		return Result{Check: name, Detail: err.Error()}
+	}
+	return Result{Check: name, Passed: true}
+}
+
+// checkCrawl checks that every page of the bundled site was saved.
+func checkCrawl(res *site2skill.BuildResult) error {
+	if res.Pages["saved"] != len(expectedDocs) || res.Pages["failed"] != 0 {
+		return fmt.Errorf("%d pages saved and %d failed, want %d saved", res.Pages["saved"], res.Pages["failed"], len(expectedDocs))
+	}
+	return nil
+}
+
+// checkDocuments checks the documents of the skill in skillDir against
+// expectedDocs; absolute is whether links between pages are absolute URLs.
+func checkDocuments(skillDir string, absolute bool) error {
+	var problems []string
+	for _, want := range expectedDocs {
+		content, err := os.ReadFile(filepath.Join(skillDir, "docs", want.file))
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s is missing", want.file))
+			continue
+		}
+		doc := string(content)
+		if !strings.Contains(doc, "\ntitle: "+want.title+"\n") && !strings.Contains(doc, "\ntitle: \""+want.title+"\"\n") {
+			problems = append(problems, fmt.Sprintf("%s doesn't have the title %q", want.file, want.title))
+		}
+		contains := append([]string(nil), want.contains...)
+		for _, link := range want.links {
+			if absolute {
+				contains = append(contains, link+")")
+			} else {
+				contains = append(contains, "]("+path.Base(link)+".md)")
+			}
+		}
+		for _, text := range contains {
+			if !strings.Contains(doc, text) {
+				problems = append(problems, fmt.Sprintf("%s doesn't contain %q", want.file, text))
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkValid checks that skill passed validation.
+func checkValid(skill site2skill.Skill) error {
+	if !skill.Valid {
+		return errors.New("the skill failed validation (see the log)")
+	}
+	return nil
+}
+
+// checkPackage checks that the .skill file at path is a ZIP archive with the
+// skill's SKILL.md and documents.
+func checkPackage(path string) error {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer r.Close()
+	files := make(map[string]bool)
+	for _, f := range r.File {
+		files[f.Name] = true
+	}
+	for _, name := range []string{"SKILL.md", "docs/" + expectedDocs[0].file} {
+		if !files[name] {
+			return fmt.Errorf("%s is missing from %s", name, filepath.Base(path))
+		}
+	}
+	return nil
+}
+
+// checkSearch checks that searching the skill in skillDir for searchTerm
+// finds searchDoc.
+func checkSearch(ctx context.Context, skillDir string) error {
+	searcher, err := site2skill.NewSearcher(skillDir)
+	if err != nil {
+		return fmt.Errorf("failed to open the search index: %w", err)
+	}
+	var found []string
+	for r, err := range searcher.Search(ctx, site2skill.SearchOptions{Query: searchTerm}) {
+		if err != nil {
+			return fmt.Errorf("failed to search: %w", err)
+		}
+		found = append(found, filepath.Base(r.File))
+	}
+	if len(found) != 1 || found[0] != searchDoc {
+		return fmt.Errorf("searching %q found %v, want %s", searchTerm, found, searchDoc)
+	}
+	return nil
+}
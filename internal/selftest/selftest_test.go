@@ -0,0 +1,75 @@
+package selftest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/f4ah6o/site2skill-go/pkg/site2skill"
+)
+
+func TestServe(t *testing.T) {
+	server, err := Serve()
+	if err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	defer server.Close()
+
+	tests := []struct {
+		path   string
+		status int
+		want   string
+	}{
+		{"", http.StatusOK, "<title>Widget Docs</title>"},
+		{"getting-started/", http.StatusOK, "widget install --frobnicate"},
+		{"robots.txt", http.StatusOK, "Allow: /"},
+		{"missing/", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		resp, err := http.Get(server.URL + tt.path)
+		if err != nil {
+			t.Fatalf("GET /%s error = %v", tt.path, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != tt.status || !strings.Contains(string(body), tt.want) {
+			t.Errorf("GET /%s = %d %q, want %d with %q", tt.path, resp.StatusCode, body, tt.status, tt.want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	cfg := site2skill.Config{
+		Targets: []site2skill.Target{{Format: site2skill.FormatClaude}, {Format: site2skill.FormatCodex}},
+		NoCache: true,
+	}
+	results, err := Run(context.Background(), cfg, t.TempDir())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(results) != 10 {
+		t.Errorf("got %d results, want 10: %+v", len(results), results)
+	}
+	for _, r := range results {
+		if !r.Passed {
+			t.Errorf("check %s failed: %s", r.Check, r.Detail)
+		}
+	}
+	if n := Failed(results); n != 0 {
+		t.Errorf("Failed() = %d, want 0", n)
+	}
+}
+
+func TestRunBuildFailure(t *testing.T) {
+	// An unknown plugin fails the build, which is reported as a failed check
+	cfg := site2skill.Config{Plugins: []string{"/nonexistent/site2skill-plugin"}}
+	results, err := Run(context.Background(), cfg, t.TempDir())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Check != "build" || results[0].Passed || Failed(results) != 1 {
+		t.Errorf("Run() = %+v, want a failed build", results)
+	}
+}
@@ -0,0 +1,48 @@
+// Package skillgen provides skill structure generation functionality.
+// This file implements the anchors.json map from links into the original site
+// to headings of the skill's files.
+package skillgen
+
+import (
+	"encoding/json"
+	"path/filepath"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+)
+
+// AnchorsFile is the name of the heading anchor map written at the root of a skill.
+const AnchorsFile = "anchors.json"
+
+// Anchor locates the heading that a link into the original site points to.
+// anchors.json maps every "<source URL>#<fragment>" of a heading the converter
+// recognized to its Anchor, so that tools resolving citations to the live site
+// find the heading in the skill, whose ID was regenerated from the heading text.
+type Anchor struct {
+	// Path is the slash-separated path of the file inside the skill (e.g., "docs/auth.md").
+	Path string `json:"path"`
+	// ID is the ID of the heading in the file, as GitHub renders it (e.g., "api-keys").
+	ID string `json:"id"`
+}
+
+// writeAnchors saves the anchors of the manifest's documents as anchors.json
+// in skillDir. A page split into parts maps each fragment to the part holding
+// the heading; the aliases of a page map their fragments to the page.
+func writeAnchors(skillDir string, m *Manifest) error {
+	anchors := make(map[string]Anchor)
+	for _, doc := range m.Documents {
+		if doc.SourceURL == "" {
+			continue
+		}
+		for _, u := range append([]string{doc.SourceURL}, doc.Aliases...) {
+			for fragment, id := range doc.anchors {
+				anchors[u+"#"+fragment] = Anchor{Path: doc.Path, ID: id}
+			}
+		}
+	}
+	data, err := json.MarshalIndent(anchors, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = atomicfile.WriteFile(filepath.Join(skillDir, AnchorsFile), append(data, '\n'))
+	return err
+}
@@ -0,0 +1,84 @@
+// Package skillgen provides skill structure generation functionality.
+// This file implements single-file bundles of a skill's documents, for
+// embedding pipelines and fine-tuning jobs that take one file.
+package skillgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// BundleMarkdown bundles the documents into one Markdown file.
+	BundleMarkdown = "markdown"
+	// BundleJSONL bundles the documents into a JSON Lines file, one record per document.
+	BundleJSONL = "jsonl"
+)
+
+// BundleFormats lists the formats of WriteBundle.
+var BundleFormats = []string{BundleMarkdown, BundleJSONL}
+
+// WriteBundle writes the documents of the skill in skillDir to w as a single
+// file in format, in the order of the manifest (by section, then sidebar
+// position and URL):
+//
+//   - BundleMarkdown writes the body of every document under an H1 with its
+//     title and a "Source:" line with its URL, like llms-full.txt. Links
+//     between documents point to their source URLs.
+//   - BundleJSONL writes one JSON object per line and document, with the
+//     fields of its frontmatter (e.g., "title" and "source_url"), its "path"
+//     in the skill, its "section", and its Markdown body as "content".
+//
+// Every document is included whatever its access level; the "access" field
+// of a JSONL record tells which ones are internal or confidential. Returns
+// the number of documents written.
+//
+// Returns an error if format is unknown, the skill has no manifest, or a
+// document can't be read or parsed.
+func WriteBundle(skillDir string, w io.Writer, format string) (int, error) {
+	if format != BundleMarkdown && format != BundleJSONL {
+		return 0, fmt.Errorf("unknown bundle format %q (want %s)", format, strings.Join(BundleFormats, " or "))
+	}
+	m, err := ReadManifest(skillDir)
+	if err != nil {
+		return 0, err
+	}
+
+	if format == BundleMarkdown {
+		var b strings.Builder
+		if err := writeConcatenated(&b, skillDir, m.Documents); err != nil {
+			return 0, err
+		}
+		if _, err := io.WriteString(w, b.String()); err != nil {
+			return 0, fmt.Errorf("failed to write bundle: %w", err)
+		}
+		return len(m.Documents), nil
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(false)
+	for _, doc := range m.Documents {
+		body, frontmatter, err := readDocBody(skillDir, doc)
+		if err != nil {
+			return 0, err
+		}
+		record := make(map[string]any)
+		if err := yaml.Unmarshal(frontmatter, &record); err != nil {
+			return 0, fmt.Errorf("failed to parse frontmatter of %s: %w", doc.Path, err)
+		}
+		if record == nil {
+			record = make(map[string]any)
+		}
+		record["path"] = doc.Path
+		record["section"] = doc.Section
+		record["content"] = strings.TrimRight(body, "\n") + "\n"
+		if err := encoder.Encode(record); err != nil {
+			return 0, fmt.Errorf("failed to write bundle: %w", err)
+		}
+	}
+	return len(m.Documents), nil
+}
@@ -0,0 +1,244 @@
+// Package skillgen provides skill structure generation functionality.
+// This file implements changelog.md, the releases described by the changelog
+// and release notes pages of a skill, newest first, so that agents answer
+// "what changed in X" and "since when" questions from one file.
+package skillgen
+
+import (
+	"cmp"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+)
+
+const (
+	// ChangelogFile is the name of the releases written at the root of a skill.
+	ChangelogFile = "changelog.md"
+	// KindChangelog is the kind frontmatter field of changelog pages (see
+	// converter.KindChangelog).
+	KindChangelog = "changelog"
+	// releaseLevel is the level of the release headings of changelog.md.
+	releaseLevel = 2
+	// maxDateLineWords is the longest line, in words, read as the date of a
+	// release when it follows its heading.
+	maxDateLineWords = 6
+)
+
+var (
+	// versionPattern matches the version a release heading names, such as
+	// "v1.2.0", "[1.2.0] - 2024-01-02", or "Version 2.1 (March 2024)".
+	versionPattern = regexp.MustCompile(`(?i)(?:^|[\s\[(])v?(\d+\.\d+(?:\.\d+)?(?:-[0-9a-z.]+)?)(?:$|[\s\]),:])`)
+	// datePattern matches the dates of release headings: ISO dates, and month
+	// names with or before the day ("March 1, 2024", "1 March 2024").
+	datePattern = regexp.MustCompile(`(?i)(?:^|[^a-z0-9])(\d{4}[-/.]\d{2}[-/.]\d{2}|(?:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.? \d{1,2}(?:st|nd|rd|th)?,? \d{4}|\d{1,2}(?:st|nd|rd|th)? (?:jan|feb|mar|apr|may|jun|jul|aug|sep|oct|nov|dec)[a-z]*\.? \d{4})(?:$|[^a-z0-9])`)
+	// ordinalSuffix matches the suffix of an ordinal day, removed before
+	// dates are parsed.
+	ordinalSuffix = regexp.MustCompile(`(?i)(\d)(?:st|nd|rd|th)\b`)
+)
+
+// dateLayouts are the layouts dates matched by datePattern are parsed with.
+var dateLayouts = []string{
+	"2006-01-02", "2006/01/02", "2006.01.02",
+	"January 2, 2006", "January 2 2006", "Jan 2, 2006", "Jan 2 2006", "Jan. 2, 2006",
+	"2 January 2006", "2 Jan 2006", "2 Jan. 2006",
+}
+
+// release is a release described by a changelog page of a skill.
+type release struct {
+	// version is the version of the release, without a "v" prefix.
+	version string
+	// date is when the release was published, as YYYY-MM-DD when the page
+	// dates it in a layout of dateLayouts, as written otherwise; empty if the
+	// page doesn't date it.
+	date string
+	// body is the description of the release, as Markdown whose headings are
+	// nested under a heading of releaseLevel.
+	body string
+	// doc is the document describing the release.
+	doc Document
+}
+
+// writeChangelog writes changelog.md in skillDir with the releases of the
+// manifest's changelog documents, those of KindChangelog (see
+// extractReleases), newest first by semantic version, and returns their
+// number. A release described by several documents is listed once, from the
+// first in the order of the manifest. Without releases, no file is written
+// and an old one is removed.
+func writeChangelog(skillDir string, m *Manifest) (int, error) {
+	var releases []release
+	seen := make(map[string]bool)
+	for _, doc := range m.Documents {
+		if doc.Kind != KindChangelog {
+			continue
+		}
+		body, _, err := readDocBody(skillDir, doc)
+		if err != nil {
+			return 0, err
+		}
+		for _, r := range extractReleases(body) {
+			if !seen[r.version] {
+				seen[r.version] = true
+				r.doc = doc
+				releases = append(releases, r)
+			}
+		}
+	}
+
+	path := filepath.Join(skillDir, ChangelogFile)
+	if len(releases) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+		return 0, nil
+	}
+	slices.SortStableFunc(releases, func(a, b release) int {
+		return compareVersions(b.version, a.version)
+	})
+
+	var b strings.Builder
+	b.WriteString("# Changelog\n\n")
+	fmt.Fprintf(&b, "Releases described by the changelog pages of the %s documentation, newest first, with the page describing each.\n", m.Name)
+	for _, r := range releases {
+		fmt.Fprintf(&b, "\n%s %s", strings.Repeat("#", releaseLevel), r.version)
+		if r.date != "" {
+			fmt.Fprintf(&b, " (%s)", r.date)
+		}
+		b.WriteString("\n\n")
+		if r.body != "" {
+			b.WriteString(r.body + "\n\n")
+		}
+		fmt.Fprintf(&b, "_From [%s](%s)", r.doc.Title, r.doc.Path)
+		if r.doc.Access != "" && r.doc.Access != "public" {
+			fmt.Fprintf(&b, " (%s)", r.doc.Access)
+		}
+		b.WriteString("_\n")
+	}
+	_, err := atomicfile.WriteFile(path, []byte(b.String()))
+	return len(releases), err
+}
+
+// extractReleases returns the releases described in body, a Markdown
+// document body, in order: the sections of the headings naming a version
+// (see versionPattern) at the highest level such headings are, outside
+// fenced code blocks, up to the next heading of the same or a higher level.
+// The date of a release is the date in its heading, or else in a short line
+// following it, which is then left out of its body.
+func extractReleases(body string) []release {
+	lines := strings.Split(body, "\n")
+	prose := proseLines(lines)
+	level := 7
+	for i, line := range lines {
+		if m := markdownHeadingPattern.FindStringSubmatch(strings.TrimSpace(line)); prose[i] && m != nil && versionPattern.MatchString(m[2]) {
+			level = min(level, len(m[1]))
+		}
+	}
+
+	var releases []release
+	for i := 0; i < len(lines); i++ {
+		m := markdownHeadingPattern.FindStringSubmatch(strings.TrimSpace(lines[i]))
+		if !prose[i] || m == nil || len(m[1]) != level {
+			continue
+		}
+		v := versionPattern.FindStringSubmatch(m[2])
+		if v == nil {
+			continue
+		}
+		end := len(lines)
+		for j := i + 1; j < len(lines); j++ {
+			if n := markdownHeadingPattern.FindStringSubmatch(strings.TrimSpace(lines[j])); prose[j] && n != nil && len(n[1]) <= level {
+				end = j
+				break
+			}
+		}
+		r := release{version: v[1], date: findDate(m[2])}
+		start := i + 1
+		for start < end && strings.TrimSpace(lines[start]) == "" {
+			start++
+		}
+		if r.date == "" && start < end && prose[start] && len(strings.Fields(lines[start])) <= maxDateLineWords {
+			if r.date = findDate(lines[start]); r.date != "" {
+				start++
+			}
+		}
+		r.body = shiftHeadings(lines[start:end], prose[start:end], level, releaseLevel)
+		releases = append(releases, r)
+		i = end - 1
+	}
+	return releases
+}
+
+// findDate returns the date in text (see datePattern), as YYYY-MM-DD if it
+// parses, or "".
+func findDate(text string) string {
+	m := datePattern.FindStringSubmatch(text)
+	if m == nil {
+		return ""
+	}
+	date := m[1]
+	normalized := ordinalSuffix.ReplaceAllString(date, "$1")
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, normalized); err == nil {
+			return t.Format("2006-01-02")
+		}
+	}
+	return date
+}
+
+// compareVersions compares the semantic versions a and b, as
+// "1.2", "1.2.0", or "1.2.0-beta.1": by their numbers, then a pre-release
+// before its release, then by the identifiers of the pre-releases, numeric
+// identifiers numerically and before the others.
+func compareVersions(a, b string) int {
+	aCore, aPre, _ := strings.Cut(a, "-")
+	bCore, bPre, _ := strings.Cut(b, "-")
+	aParts, bParts := strings.Split(aCore, "."), strings.Split(bCore, ".")
+	for i := range max(len(aParts), len(bParts)) {
+		if c := cmp.Compare(versionNumber(aParts, i), versionNumber(bParts, i)); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case aPre == bPre:
+		return 0
+	case aPre == "":
+		return 1
+	case bPre == "":
+		return -1
+	}
+	aIDs, bIDs := strings.Split(aPre, "."), strings.Split(bPre, ".")
+	for i := range min(len(aIDs), len(bIDs)) {
+		aNum, aErr := strconv.Atoi(aIDs[i])
+		bNum, bErr := strconv.Atoi(bIDs[i])
+		var c int
+		switch {
+		case aErr == nil && bErr == nil:
+			c = cmp.Compare(aNum, bNum)
+		case aErr == nil:
+			c = -1
+		case bErr == nil:
+			c = 1
+		default:
+			c = strings.Compare(aIDs[i], bIDs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return cmp.Compare(len(aIDs), len(bIDs))
+}
+
+// versionNumber returns the i-th number of a version, 0 if it has none.
+func versionNumber(parts []string, i int) int {
+	if i >= len(parts) {
+		return 0
+	}
+	n, _ := strconv.Atoi(parts[i])
+	return n
+}
@@ -0,0 +1,216 @@
+// Package skillgen provides skill structure generation functionality.
+// This file implements the comparison of two generated versions of a skill,
+// so that a scheduled regeneration can be reviewed before it is published.
+package skillgen
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/textdiff"
+)
+
+// SkillDiff lists the differences between two versions of a skill.
+type SkillDiff struct {
+	// Sources lists the sources added, moved, or removed between the
+	// lockfiles of the versions (see Lock.Diff); empty if the new version has
+	// no lockfile or no source moved.
+	Sources []SourceChange `json:"sources,omitempty"`
+	// Added lists the documents only the new version has.
+	Added []DocumentDiff `json:"added"`
+	// Modified lists the documents whose content changed.
+	Modified []DocumentDiff `json:"modified"`
+	// Removed lists the documents only the old version has.
+	Removed []DocumentDiff `json:"removed"`
+	// Unchanged is the number of documents with the same content in both versions.
+	Unchanged int `json:"unchanged"`
+}
+
+// DocumentDiff is one document added, modified, or removed between two
+// versions of a skill.
+type DocumentDiff struct {
+	// Path is the slash-separated path of the document inside the skill (e.g., "docs/auth.md").
+	Path string `json:"path"`
+	// Title is the title of the document, in the new version unless it was removed.
+	Title string `json:"title"`
+	// URL is the source URL of the document; empty if unknown.
+	URL string `json:"url,omitempty"`
+	// OldHash and NewHash are the content hashes of the document in each
+	// version (see HashDocuments); empty in the version without it.
+	OldHash string `json:"old_hash,omitempty"`
+	NewHash string `json:"new_hash,omitempty"`
+	// Patch is the unified diff of the document, without its fetched_at
+	// field; empty unless patches were requested.
+	Patch string `json:"patch,omitempty"`
+}
+
+// docInfo is the title and source URL of a document of a skill.
+type docInfo struct {
+	title string
+	url   string
+}
+
+// DiffSkills compares the skill in oldDir with the skill in newDir, e.g., the
+// published version and a regenerated one. Documents are matched by path and
+// compared by their content hashes (see HashDocuments), so pages fetched again
+// without change aren't reported; their titles and source URLs come from the
+// manifests. A document renamed between the versions, e.g., a page split into
+// parts, is reported as removed and added. With patches, each document added,
+// modified, or removed gets the unified diff of its file.
+//
+// Returns an error if either skill has no docs directory, or its files can't
+// be read.
+func DiffSkills(oldDir, newDir string, patches bool) (*SkillDiff, error) {
+	oldHashes, err := HashDocuments(oldDir)
+	if err != nil {
+		return nil, err
+	}
+	newHashes, err := HashDocuments(newDir)
+	if err != nil {
+		return nil, err
+	}
+	oldInfo, err := readDocInfo(oldDir)
+	if err != nil {
+		return nil, err
+	}
+	newInfo, err := readDocInfo(newDir)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &SkillDiff{Added: []DocumentDiff{}, Modified: []DocumentDiff{}, Removed: []DocumentDiff{}}
+	if d.Sources, err = diffLocks(oldDir, newDir); err != nil {
+		return nil, err
+	}
+
+	oldByPath := make(map[string]string, len(oldHashes.Documents))
+	for _, doc := range oldHashes.Documents {
+		oldByPath[doc.Path] = doc.Hash
+	}
+	newByPath := make(map[string]string, len(newHashes.Documents))
+	for _, doc := range newHashes.Documents {
+		newByPath[doc.Path] = doc.Hash
+	}
+	paths := make([]string, 0, len(oldByPath)+len(newByPath))
+	for path := range oldByPath {
+		paths = append(paths, path)
+	}
+	for path := range newByPath {
+		if _, ok := oldByPath[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		oldHash, inOld := oldByPath[path]
+		newHash, inNew := newByPath[path]
+		if oldHash == newHash {
+			d.Unchanged++
+			continue
+		}
+		info, ok := newInfo[path]
+		if !inNew || !ok {
+			info = oldInfo[path]
+		}
+		if info.title == "" {
+			info.title = strings.TrimSuffix(filepath.Base(path), ".md")
+		}
+		doc := DocumentDiff{Path: path, Title: info.title, URL: info.url, OldHash: oldHash, NewHash: newHash}
+		if patches {
+			if doc.Patch, err = patchDocument(oldDir, newDir, path, inOld, inNew); err != nil {
+				return nil, err
+			}
+		}
+		switch {
+		case !inOld:
+			d.Added = append(d.Added, doc)
+		case !inNew:
+			d.Removed = append(d.Removed, doc)
+		default:
+			d.Modified = append(d.Modified, doc)
+		}
+	}
+	return d, nil
+}
+
+// Changed reports whether the versions differ.
+func (d *SkillDiff) Changed() bool {
+	return len(d.Added)+len(d.Modified)+len(d.Removed)+len(d.Sources) > 0
+}
+
+// Summary returns a one-line count of the differences.
+func (d *SkillDiff) Summary() string {
+	return fmt.Sprintf("%d added, %d modified, %d removed, %d unchanged",
+		len(d.Added), len(d.Modified), len(d.Removed), d.Unchanged)
+}
+
+// readDocInfo returns the titles and source URLs of the documents listed in
+// the manifest.json of skillDir, keyed by path; none if it has no manifest.
+func readDocInfo(skillDir string) (map[string]docInfo, error) {
+	m, err := ReadManifest(skillDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]docInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	info := make(map[string]docInfo, len(m.Documents))
+	for _, doc := range m.Documents {
+		info[doc.Path] = docInfo{title: doc.Title, url: doc.SourceURL}
+	}
+	return info, nil
+}
+
+// diffLocks returns the sources that moved between the skill.lock.json of
+// oldDir and that of newDir, or none if newDir has no lockfile.
+func diffLocks(oldDir, newDir string) ([]SourceChange, error) {
+	newLock, err := ReadLock(newDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	oldLock, err := ReadLock(oldDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return oldLock.Diff(newLock), nil
+}
+
+// patchDocument returns the unified diff of the document at path between the
+// skill in oldDir and the skill in newDir, against /dev/null for the version
+// without it.
+func patchDocument(oldDir, newDir, path string, inOld, inNew bool) (string, error) {
+	read := func(dir string, exists bool) (string, error) {
+		if !exists {
+			return "", nil
+		}
+		content, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(path)))
+		if err != nil {
+			return "", fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		return string(withoutFetchedAt(content)), nil
+	}
+	oldText, err := read(oldDir, inOld)
+	if err != nil {
+		return "", err
+	}
+	newText, err := read(newDir, inNew)
+	if err != nil {
+		return "", err
+	}
+	oldName, newName := "a/"+path, "b/"+path
+	if !inOld {
+		oldName = "/dev/null"
+	}
+	if !inNew {
+		newName = "/dev/null"
+	}
+	return textdiff.Unified(oldName, newName, oldText, newText, textdiff.DefaultContext), nil
+}
@@ -0,0 +1,217 @@
+// Package skillgen provides skill structure generation functionality.
+// This file implements faq.md, the questions answered by the FAQ pages of a
+// skill with their answers, so that agents find the answer to a common
+// question without reading every FAQ page.
+package skillgen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+)
+
+const (
+	// FAQFile is the name of the questions and answers written at the root of a skill.
+	FAQFile = "faq.md"
+	// KindFAQ is the kind frontmatter field of FAQ pages (see
+	// converter.KindFAQ).
+	KindFAQ = "faq"
+	// faqQuestionLevel is the level of the question headings of faq.md.
+	faqQuestionLevel = 3
+)
+
+var (
+	// markdownHeadingPattern matches an ATX heading line, capturing its
+	// markers and text.
+	markdownHeadingPattern = regexp.MustCompile(`^(#{1,6})\s+(.+?)(?:\s+#+)?\s*$`)
+	// boldQuestionPattern matches a paragraph of a bold question, as FAQ
+	// pages without headings write their questions.
+	boldQuestionPattern = regexp.MustCompile(`^\*\*(.+[?？])\*\*$`)
+)
+
+// faqEntry is a question answered by a FAQ page of a skill.
+type faqEntry struct {
+	// question is the question, and answer its answer, as Markdown whose
+	// headings are nested under a heading of faqQuestionLevel.
+	question, answer string
+}
+
+// writeFAQ writes faq.md in skillDir with the questions of the manifest's
+// FAQ documents, those of KindFAQ (see extractQuestions), under the document
+// answering them, and returns their number. A question answered by several
+// documents is listed once, under the first in the order of the manifest.
+// Without questions, no file is written and an old one is removed.
+func writeFAQ(skillDir string, m *Manifest) (int, error) {
+	var b strings.Builder
+	count := 0
+	seen := make(map[string]bool)
+	for _, doc := range m.Documents {
+		if doc.Kind != KindFAQ {
+			continue
+		}
+		body, _, err := readDocBody(skillDir, doc)
+		if err != nil {
+			return 0, err
+		}
+		var entries []faqEntry
+		for _, e := range extractQuestions(body) {
+			key := strings.ToLower(oneLine(e.question))
+			if !seen[key] {
+				seen[key] = true
+				entries = append(entries, e)
+			}
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## [%s](%s)", doc.Title, doc.Path)
+		if doc.Access != "" && doc.Access != "public" {
+			fmt.Fprintf(&b, " _(%s)_", doc.Access)
+		}
+		b.WriteString("\n")
+		for _, e := range entries {
+			fmt.Fprintf(&b, "\n%s %s\n", strings.Repeat("#", faqQuestionLevel), e.question)
+			if e.answer != "" {
+				b.WriteString("\n" + e.answer + "\n")
+			}
+		}
+		count += len(entries)
+	}
+
+	path := filepath.Join(skillDir, FAQFile)
+	if count == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+		return 0, nil
+	}
+	content := "# Frequently Asked Questions\n\n" +
+		fmt.Sprintf("Questions answered by the FAQ pages of the %s documentation, under the page answering each.\n", m.Name) +
+		b.String()
+	_, err := atomicfile.WriteFile(path, []byte(content))
+	return count, err
+}
+
+// extractQuestions returns the questions asked in body, a Markdown document
+// body, in order, outside fenced code blocks, with their answers:
+//   - headings ending with a question mark, answered by the content up to the
+//     next heading of the same or a higher level, or the next question;
+//   - bold paragraphs ending with a question mark, answered by the content up
+//     to the next heading or question of another form;
+//   - the terms of definition lists ending with a question mark, answered by
+//     their definition (see converter.convertDefinitionList).
+func extractQuestions(body string) []faqEntry {
+	lines := strings.Split(body, "\n")
+	prose := proseLines(lines)
+	var entries []faqEntry
+	for i := 0; i < len(lines); i++ {
+		if !prose[i] {
+			continue
+		}
+		line := strings.TrimSpace(lines[i])
+		end, level := len(lines), 0
+		var question string
+		if m := markdownHeadingPattern.FindStringSubmatch(line); m != nil {
+			if !isQuestion(m[2]) {
+				continue
+			}
+			question, level = m[2], len(m[1])
+			for j := i + 1; j < len(lines); j++ {
+				if n := markdownHeadingPattern.FindStringSubmatch(strings.TrimSpace(lines[j])); prose[j] && n != nil && (len(n[1]) <= level || isQuestion(n[2])) {
+					end = j
+					break
+				}
+			}
+		} else if m := boldQuestionPattern.FindStringSubmatch(line); m != nil {
+			question = m[1]
+			for j := i + 1; j < len(lines); j++ {
+				next := strings.TrimSpace(lines[j])
+				if prose[j] && (markdownHeadingPattern.MatchString(next) || boldQuestionPattern.MatchString(next) || isDefinedQuestion(lines, j)) {
+					end = j
+					break
+				}
+			}
+		} else if isDefinedQuestion(lines, i) {
+			question = line
+			var answer []string
+		definition:
+			for end = i + 1; end < len(lines); end++ {
+				next := lines[end]
+				switch {
+				case strings.HasPrefix(next, ": "), strings.HasPrefix(next, "    "):
+					answer = append(answer, strings.TrimPrefix(strings.TrimPrefix(next, ": "), "    "))
+				case strings.TrimSpace(next) == "" && end+1 < len(lines) && strings.HasPrefix(lines[end+1], "    "):
+					// A blank line between the paragraphs of the definition
+					answer = append(answer, "")
+				default:
+					break definition
+				}
+			}
+			entries = append(entries, faqEntry{question: question, answer: strings.TrimSpace(strings.Join(answer, "\n"))})
+			i = end - 1
+			continue
+		} else {
+			continue
+		}
+		answer := shiftHeadings(lines[i+1:end], prose[i+1:end], level, faqQuestionLevel)
+		entries = append(entries, faqEntry{question: question, answer: answer})
+		i = end - 1
+	}
+	return entries
+}
+
+// isQuestion reports whether text asks a question.
+func isQuestion(text string) bool {
+	text = strings.TrimSpace(text)
+	return strings.HasSuffix(text, "?") || strings.HasSuffix(text, "？")
+}
+
+// isDefinedQuestion reports whether lines[i] is a question defined as the
+// term of a definition list.
+func isDefinedQuestion(lines []string, i int) bool {
+	return isQuestion(lines[i]) && i+1 < len(lines) && strings.HasPrefix(lines[i+1], ": ")
+}
+
+// proseLines reports, for every line of a Markdown document, whether it is
+// outside fenced code blocks.
+func proseLines(lines []string) []bool {
+	prose := make([]bool, len(lines))
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+			continue
+		}
+		prose[i] = true
+	}
+	return prose
+}
+
+// shiftHeadings returns lines, the content of a section under a heading of
+// level from (0 for none), trimmed, with its headings outside code blocks
+// (prose, see proseLines) moved to sit under a heading of level to, down to
+// level 6.
+func shiftHeadings(lines []string, prose []bool, from, to int) string {
+	shifted := make([]string, len(lines))
+	for i, line := range lines {
+		shifted[i] = line
+		m := markdownHeadingPattern.FindStringSubmatch(strings.TrimSpace(line))
+		if !prose[i] || m == nil {
+			continue
+		}
+		level := min(max(len(m[1])+to-from, to+1), 6)
+		shifted[i] = strings.Repeat("#", level) + " " + m[2]
+	}
+	return strings.TrimSpace(strings.Join(shifted, "\n"))
+}
@@ -0,0 +1,102 @@
+// Package skillgen provides skill structure generation functionality.
+// This file implements the freshness summary of a skill's documents.
+package skillgen
+
+import (
+	"fmt"
+	"time"
+)
+
+// FreshnessWindow is the age below which a page counts as recently fetched.
+const FreshnessWindow = 30 * 24 * time.Hour
+
+// Freshness badges, from the share of pages fetched within FreshnessWindow.
+const (
+	// BadgeFresh means at least 90% of the pages were fetched recently.
+	BadgeFresh = "fresh"
+	// BadgeAging means at least half of the pages were fetched recently.
+	BadgeAging = "aging"
+	// BadgeStale means fewer than half of the pages were fetched recently.
+	BadgeStale = "stale"
+)
+
+// Freshness summarizes when the pages of a skill were fetched, so that
+// consumers can tell at a glance whether the skill needs an update.
+type Freshness struct {
+	// ComputedAt is when the summary was computed (RFC 3339); Recent is relative to it.
+	ComputedAt string `json:"computed_at"`
+	// Pages is the number of pages with a fetch time.
+	Pages int `json:"pages"`
+	// Newest and Oldest are the latest and earliest fetch times of the pages (RFC 3339).
+	Newest string `json:"newest_fetched_at"`
+	Oldest string `json:"oldest_fetched_at"`
+	// WindowDays is FreshnessWindow in days.
+	WindowDays int `json:"window_days"`
+	// Recent is the share of pages, from 0 to 1, fetched within WindowDays of ComputedAt.
+	Recent float64 `json:"recent_share"`
+	// Badge is BadgeFresh, BadgeAging, or BadgeStale.
+	Badge string `json:"badge"`
+}
+
+// computeFreshness summarizes the fetch times of the documents as of now.
+// The parts of a split page count as one page; documents without a valid
+// fetched_at are left out. Documents fetched after now (a clock skewed
+// between machines, or a fixed build timestamp older than the pages kept by
+// an update) count as recent. Returns nil if no document has one.
+func computeFreshness(docs []Document, now time.Time) *Freshness {
+	fetched := make(map[string]time.Time)
+	for _, doc := range docs {
+		t, err := time.Parse(time.RFC3339, doc.FetchedAt)
+		if err != nil {
+			continue
+		}
+		key := doc.SourceURL
+		if key == "" {
+			key = doc.Path
+		}
+		fetched[key] = t
+	}
+	if len(fetched) == 0 {
+		return nil
+	}
+
+	var newest, oldest time.Time
+	recent := 0
+	for _, t := range fetched {
+		if newest.IsZero() || t.After(newest) {
+			newest = t
+		}
+		if oldest.IsZero() || t.Before(oldest) {
+			oldest = t
+		}
+		if now.Sub(t) <= FreshnessWindow {
+			recent++
+		}
+	}
+	f := &Freshness{
+		ComputedAt: now.UTC().Format(time.RFC3339),
+		Pages:      len(fetched),
+		Newest:     newest.UTC().Format(time.RFC3339),
+		Oldest:     oldest.UTC().Format(time.RFC3339),
+		WindowDays: int(FreshnessWindow / (24 * time.Hour)),
+		Recent:     float64(recent) / float64(len(fetched)),
+	}
+	switch {
+	case f.Recent >= 0.9:
+		f.Badge = BadgeFresh
+	case f.Recent >= 0.5:
+		f.Badge = BadgeAging
+	default:
+		f.Badge = BadgeStale
+	}
+	return f
+}
+
+// summary returns the SKILL.md line reporting the freshness, or "" for nil.
+func (f *Freshness) summary() string {
+	if f == nil {
+		return ""
+	}
+	return fmt.Sprintf("Freshness: **%s** - %.0f%% of %d pages fetched within %d days (newest %s, oldest %s)\n\n",
+		f.Badge, f.Recent*100, f.Pages, f.WindowDays, f.Newest[:10], f.Oldest[:10])
+}
@@ -0,0 +1,193 @@
+// Package skillgen provides skill structure generation functionality.
+// This file implements glossary.md, the terms defined in the documents of a
+// skill, so that agents answer "what is X" questions without a full-text search.
+package skillgen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+)
+
+const (
+	// GlossaryFile is the name of the glossary written at the root of a skill.
+	GlossaryFile = "glossary.md"
+	// maxTermLength is the longest term recognized, in characters; longer
+	// bold or code spans are emphasis, not terms.
+	maxTermLength = 60
+	// maxTermWords is the largest number of words of a term.
+	maxTermWords = 6
+	// definitionLength is the longest definition kept in the glossary.
+	definitionLength = 300
+)
+
+var (
+	// definingSentencePattern matches a paragraph opening with a bold or code
+	// term followed by a defining verb, as "A **widget** is ...", which the
+	// converter writes for dfn elements, or "`max_retries` sets ...".
+	definingSentencePattern = regexp.MustCompile("^(?:(?:A|An|The)\\s+)?(?:\\*\\*([^*]+)\\*\\*|`([^`]+)`)\\s+(?:is|are|refers to|means|represents|denotes|describes|stands for|sets|controls)\\s+\\S")
+	// sentenceEnd matches the end of the first sentence of a paragraph.
+	sentenceEnd = regexp.MustCompile(`[.!?](?:\s|$)`)
+	// inlineMarkup matches bold and code markers, removed from definitions.
+	inlineMarkup = regexp.MustCompile("\\*\\*|`")
+)
+
+// glossaryEntry is a term defined in a document of a skill.
+type glossaryEntry struct {
+	// term is the term, as written where it is defined.
+	term string
+	// definition is the definition of the term: the definition of a
+	// definition list, or the sentence defining the term.
+	definition string
+	// path is the slash-separated path of the defining document inside the
+	// skill (e.g., "docs/auth.md"), and title its title.
+	path, title string
+	// access is the access level of the defining document; empty when it is public.
+	access string
+}
+
+// writeGlossary writes glossary.md in skillDir with the terms defined in the
+// manifest's documents (see extractTerms), and returns their number. A term
+// defined by several documents is listed once, with the first definition in
+// the order of the manifest. Without terms, no file is written and an old one
+// is removed.
+func writeGlossary(skillDir string, m *Manifest) (int, error) {
+	var terms []glossaryEntry
+	seen := make(map[string]bool)
+	for _, doc := range m.Documents {
+		body, _, err := readDocBody(skillDir, doc)
+		if err != nil {
+			return 0, err
+		}
+		for _, t := range extractTerms(body) {
+			key := strings.ToLower(t.term)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			t.path, t.title = doc.Path, doc.Title
+			if doc.Access != "public" {
+				t.access = doc.Access
+			}
+			terms = append(terms, t)
+		}
+	}
+
+	path := filepath.Join(skillDir, GlossaryFile)
+	if len(terms) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return 0, err
+		}
+		return 0, nil
+	}
+	sort.SliceStable(terms, func(i, j int) bool {
+		return strings.ToLower(terms[i].term) < strings.ToLower(terms[j].term)
+	})
+
+	var b strings.Builder
+	b.WriteString("# Glossary\n\n")
+	fmt.Fprintf(&b, "Terms defined in the %s documentation, with the document defining each.\n\n", m.Name)
+	for _, t := range terms {
+		fmt.Fprintf(&b, "- **%s**: %s ([%s](%s))", t.term, t.definition, t.title, t.path)
+		if t.access != "" {
+			fmt.Fprintf(&b, " _(%s)_", t.access)
+		}
+		b.WriteString("\n")
+	}
+	_, err := atomicfile.WriteFile(path, []byte(b.String()))
+	return len(terms), err
+}
+
+// extractTerms returns the terms defined in body, a Markdown document body,
+// in order, outside fenced code blocks:
+//   - the terms of definition lists, each line followed by a line starting
+//     with ": " (see converter.convertDefinitionList), defined by that line;
+//   - the bold or code term opening a paragraph and followed by a defining
+//     verb ("A **widget** is a ..."), defined by the first sentence of the
+//     paragraph.
+func extractTerms(body string) []glossaryEntry {
+	var terms []glossaryEntry
+	lines := strings.Split(body, "\n")
+	fence := ""
+	// group are the terms of the last definition of a definition list, which
+	// the definitions following it also define
+	var group []string
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence, group = trimmed[:3], nil
+			continue
+		}
+
+		if definition, ok := strings.CutPrefix(line, ": "); ok {
+			if i > 0 && !strings.HasPrefix(lines[i-1], ": ") && !strings.HasPrefix(lines[i-1], "    ") {
+				group = definedTerms(lines[:i])
+			}
+			for _, term := range group {
+				terms = appendTerm(terms, term, definition)
+			}
+			continue
+		}
+
+		// A defining sentence opens a paragraph
+		if i > 0 && strings.TrimSpace(lines[i-1]) != "" {
+			continue
+		}
+		m := definingSentencePattern.FindStringSubmatch(trimmed)
+		if m == nil {
+			continue
+		}
+		paragraph := trimmed
+		for j := i + 1; j < len(lines) && strings.TrimSpace(lines[j]) != ""; j++ {
+			paragraph += " " + strings.TrimSpace(lines[j])
+		}
+		if loc := sentenceEnd.FindStringIndex(paragraph); loc != nil {
+			paragraph = paragraph[:loc[0]+1]
+		}
+		terms = appendTerm(terms, m[1]+m[2], paragraph)
+	}
+	return terms
+}
+
+// definedTerms returns the terms of the definition following lines: the lines
+// since the last blank line, in order, unless one of them is markup (a
+// heading, quote, table row, list item, or HTML) rather than a term.
+func definedTerms(lines []string) []string {
+	start := len(lines)
+	for start > 0 && strings.TrimSpace(lines[start-1]) != "" {
+		start--
+	}
+	var group []string
+	for _, line := range lines[start:] {
+		trimmed := strings.TrimSpace(line)
+		if strings.ContainsAny(trimmed[:1], "#>|-*+<!") {
+			return nil
+		}
+		group = append(group, trimmed)
+	}
+	return group
+}
+
+// appendTerm appends term, defined by definition, to terms, removing the bold
+// and code markers of both. Terms too long to be terms, and empty
+// definitions, are left out.
+func appendTerm(terms []glossaryEntry, term, definition string) []glossaryEntry {
+	term = strings.TrimSpace(inlineMarkup.ReplaceAllString(term, ""))
+	definition = strings.Join(strings.Fields(inlineMarkup.ReplaceAllString(definition, "")), " ")
+	if term == "" || definition == "" || utf8.RuneCountInString(term) > maxTermLength || len(strings.Fields(term)) > maxTermWords {
+		return terms
+	}
+	return append(terms, glossaryEntry{term: term, definition: truncate(definition, definitionLength)})
+}
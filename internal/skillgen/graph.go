@@ -0,0 +1,152 @@
+// Package skillgen provides skill structure generation functionality.
+// This file implements graph.json, the links between the documents of a
+// skill, which tell agents what else a document refers to and tell humans
+// which pages nothing links to.
+package skillgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+	"github.com/f4ah6o/site2skill-go/internal/links"
+)
+
+const (
+	// GraphFile is the name of the link graph written at the root of a skill.
+	GraphFile = "graph.json"
+	// GraphVersion is the version of the format of GraphFile.
+	GraphVersion = 1
+)
+
+// Graph is the link graph of the documents of a skill, written as graph.json.
+type Graph struct {
+	// Version is GraphVersion.
+	Version int `json:"version"`
+	// Nodes lists the documents, in the order of the manifest.
+	Nodes []GraphNode `json:"nodes"`
+	// Edges lists the links between documents, ordered by the documents they
+	// link from, then to, in the order of the manifest.
+	Edges []GraphEdge `json:"edges"`
+	// Orphans lists the paths of the pages no other page links to, except the
+	// top-level page of the site: the first file of each, for a page split
+	// into parts.
+	Orphans []string `json:"orphans"`
+}
+
+// GraphNode is a document of a link graph.
+type GraphNode struct {
+	// Path is the slash-separated path of the file inside the skill (e.g., "docs/auth.md").
+	Path string `json:"path"`
+	// Title is the page title.
+	Title string `json:"title"`
+	// SourceURL is the URL the page was fetched from.
+	SourceURL string `json:"source_url,omitempty"`
+	// Section is the section of the document (see Document.Section).
+	Section string `json:"section"`
+	// Links is the number of documents the document links to, and Backlinks
+	// the number of documents linking to it.
+	Links     int `json:"links"`
+	Backlinks int `json:"backlinks"`
+}
+
+// GraphEdge is a document linking to another in a link graph.
+type GraphEdge struct {
+	// From and To are the paths of the linking and linked documents.
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Count is the number of links of From to To.
+	Count int `json:"count"`
+}
+
+// buildGraph returns the link graph of the manifest's documents in skillDir:
+// the relative links of each document (see links.Targets) to another
+// document, whatever their fragment. The links between the parts of a page
+// split into parts are navigation, not references, and are left out.
+func buildGraph(skillDir string, m *Manifest) (*Graph, error) {
+	g := &Graph{Version: GraphVersion, Nodes: []GraphNode{}, Edges: []GraphEdge{}, Orphans: []string{}}
+	positions := make(map[string]int, len(m.Documents))
+	for i, doc := range m.Documents {
+		positions[doc.Path] = i
+		g.Nodes = append(g.Nodes, GraphNode{Path: doc.Path, Title: doc.Title, SourceURL: doc.SourceURL, Section: doc.Section})
+	}
+
+	counts := make(map[[2]int]int)
+	for i, doc := range m.Documents {
+		content, err := os.ReadFile(filepath.Join(skillDir, filepath.FromSlash(doc.Path)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", doc.Path, err)
+		}
+		for _, target := range links.Targets(string(content)) {
+			u, err := url.Parse(target)
+			if err != nil || u.Scheme != "" || u.Host != "" || u.Path == "" {
+				continue
+			}
+			j, ok := positions[path.Join(path.Dir(doc.Path), u.Path)]
+			if !ok || j == i || samePage(doc, m.Documents[j]) {
+				continue
+			}
+			counts[[2]int{i, j}]++
+		}
+	}
+
+	pairs := make([][2]int, 0, len(counts))
+	for pair := range counts {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(a, b int) bool {
+		if pairs[a][0] != pairs[b][0] {
+			return pairs[a][0] < pairs[b][0]
+		}
+		return pairs[a][1] < pairs[b][1]
+	})
+	// A page is linked if any of its parts is
+	linked := make(map[string]bool)
+	for _, pair := range pairs {
+		from, to := m.Documents[pair[0]], m.Documents[pair[1]]
+		g.Edges = append(g.Edges, GraphEdge{From: from.Path, To: to.Path, Count: counts[pair]})
+		g.Nodes[pair[0]].Links++
+		g.Nodes[pair[1]].Backlinks++
+		linked[pageKey(to)] = true
+	}
+	for _, doc := range m.Documents {
+		if doc.Part <= 1 && !linked[pageKey(doc)] && pageKey(doc) != m.home {
+			g.Orphans = append(g.Orphans, doc.Path)
+		}
+	}
+	return g, nil
+}
+
+// writeGraph writes the link graph of the manifest's documents (see
+// buildGraph) as graph.json in skillDir, and returns it.
+func writeGraph(skillDir string, m *Manifest) (*Graph, error) {
+	g, err := buildGraph(skillDir, m)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	_, err = atomicfile.WriteFile(filepath.Join(skillDir, GraphFile), append(data, '\n'))
+	return g, err
+}
+
+// pageKey identifies the page of doc, which the files of a page split into
+// parts share: its source URL, or its path if it has none.
+func pageKey(doc Document) string {
+	if doc.SourceURL != "" {
+		return doc.SourceURL
+	}
+	return doc.Path
+}
+
+// samePage reports whether a and b are parts of the same page.
+func samePage(a, b Document) bool {
+	return pageKey(a) == pageKey(b)
+}
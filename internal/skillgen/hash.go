@@ -0,0 +1,94 @@
+// Package skillgen provides skill structure generation functionality.
+// This file implements the content hashes of the documents of a skill, which
+// tell external schedulers whether a skill changed without rebuilding it.
+package skillgen
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/mdprose"
+)
+
+// fetchedAtPattern matches the fetched_at line of a frontmatter.
+var fetchedAtPattern = regexp.MustCompile(`(?m)^fetched_at:.*\n`)
+
+// Hashes are the content hashes of the documents of a skill.
+type Hashes struct {
+	// Corpus is the hash of every document path and hash, which changes
+	// whenever a document is added, removed, renamed, or modified.
+	Corpus string `json:"corpus"`
+	// Documents lists the hash of every document, sorted by path.
+	Documents []DocumentHash `json:"documents"`
+}
+
+// DocumentHash is the content hash of one document of a skill.
+type DocumentHash struct {
+	// Path is the slash-separated path of the document inside the skill (e.g., "docs/auth.md").
+	Path string `json:"path"`
+	// Hash is the SHA-256 of the document, as "sha256:<hex>".
+	Hash string `json:"hash"`
+}
+
+// HashDocuments returns the content hashes of the Markdown documents in the
+// docs directory of the skill in skillDir, reading them as they are: nothing
+// is crawled or rebuilt.
+//
+// The fetched_at field of the frontmatter is left out of the hash of a
+// document, so an update fetching a page again without change (or a full
+// generation of the same content) keeps its hash; any other change to the
+// file, in the body or the frontmatter, changes it. The hashes are therefore
+// stable across builds of the same content, unlike the files' modification
+// times or the .skill package.
+//
+// Returns an error if the skill has no docs directory or a document can't be
+// read.
+func HashDocuments(skillDir string) (*Hashes, error) {
+	docsDir := filepath.Join(skillDir, "docs")
+	if _, err := os.Stat(docsDir); err != nil {
+		return nil, fmt.Errorf("failed to read skill: %w", err)
+	}
+	files, err := filepath.Glob(filepath.Join(docsDir, "*.md"))
+	if err != nil {
+		return nil, err
+	}
+	h := &Hashes{Documents: []DocumentHash{}}
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		h.Documents = append(h.Documents, DocumentHash{Path: "docs/" + filepath.Base(file), Hash: sha256Hex(withoutFetchedAt(content))})
+	}
+	sort.Slice(h.Documents, func(i, j int) bool { return h.Documents[i].Path < h.Documents[j].Path })
+
+	lines := make([]string, len(h.Documents))
+	for i, d := range h.Documents {
+		lines[i] = d.Hash + "  " + d.Path + "\n"
+	}
+	h.Corpus = sha256Hex([]byte(strings.Join(lines, "")))
+	return h, nil
+}
+
+// withoutFetchedAt returns the content of a document without the fetched_at
+// field of its frontmatter, which changes whenever the page is fetched again.
+func withoutFetchedAt(content []byte) []byte {
+	frontmatter, body, ok := mdprose.SplitFrontmatter(string(content))
+	if !ok {
+		return content
+	}
+	stripped := fetchedAtPattern.ReplaceAll([]byte(frontmatter+"\n"), nil)
+	return append(append(append([]byte("---\n"), stripped...), "---\n"...), body...)
+}
+
+// sha256Hex returns the SHA-256 of data as "sha256:<hex>".
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,169 @@
+// Package skillgen provides skill structure generation functionality.
+// This file implements the llms.txt and llms-full.txt files of the crawled
+// site, following the llms.txt convention (https://llmstxt.org/).
+package skillgen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+	"github.com/f4ah6o/site2skill-go/internal/mdprose"
+)
+
+const (
+	// LLMsTxtFile is the index of the site: its title, summary, and a link
+	// to every page with its description, by section.
+	LLMsTxtFile = "llms.txt"
+	// LLMsFullTxtFile is the content of every page of the site in one file.
+	LLMsFullTxtFile = "llms-full.txt"
+)
+
+// docLinkPattern matches a relative link to another document of the skill
+var docLinkPattern = regexp.MustCompile(`\]\(([^()\s:#/]+\.md)((?:#[^()\s]*)?)\)`)
+
+// leadingHeadingPattern matches the heading a document body starts with
+var leadingHeadingPattern = regexp.MustCompile(`^#\s+[^\n]*\n+`)
+
+// WriteLLMsTxt writes llms.txt and llms-full.txt for the skill in skillDir
+// into dir, from its manifest and documents:
+//
+//   - llms.txt starts with the site title (the skill name if the site has
+//     none) as its H1 and the site description as a blockquote, then lists
+//     the pages under an H2 per section, each as a link to its source URL
+//     with its summary, or else its description.
+//   - llms-full.txt holds the body of every document, in the same order,
+//     under an H1 with its title and a "Source:" line with its URL. Links
+//     between documents point to their source URLs.
+//
+// Only public documents are included, since the files are meant to be
+// published: those tagged internal or confidential (see package access) are
+// left out. A page split into several parts is listed once in llms.txt.
+// Returns the number of documents included.
+//
+// Returns an error if the skill has no manifest, or if a document can't be
+// read or a file written.
+func WriteLLMsTxt(skillDir, dir string) (int, error) {
+	m, err := ReadManifest(skillDir)
+	if err != nil {
+		return 0, err
+	}
+	var docs []Document
+	for _, doc := range m.Documents {
+		if doc.Access != "" && doc.Access != "public" {
+			continue
+		}
+		docs = append(docs, doc)
+	}
+
+	title := m.Title
+	if title == "" {
+		title = m.Name
+	}
+	var index strings.Builder
+	fmt.Fprintf(&index, "# %s\n", oneLine(title))
+	if m.Description != "" {
+		fmt.Fprintf(&index, "\n> %s\n", oneLine(m.Description))
+	}
+	if m.URL != "" {
+		fmt.Fprintf(&index, "\nThe documentation of %s, converted to Markdown.\n", m.URL)
+	}
+	for i, doc := range docs {
+		if i == 0 || doc.Section != docs[i-1].Section {
+			fmt.Fprintf(&index, "\n## %s\n\n", llmsSectionName(doc.Section))
+		}
+		if doc.Part <= 1 {
+			link := doc.SourceURL
+			if link == "" {
+				link = doc.Path
+			}
+			fmt.Fprintf(&index, "- [%s](%s)", oneLine(doc.Title), link)
+			if abstract := doc.abstract(); abstract != "" {
+				index.WriteString(": " + oneLine(abstract))
+			}
+			index.WriteString("\n")
+		}
+	}
+	var full strings.Builder
+	if err := writeConcatenated(&full, skillDir, docs); err != nil {
+		return 0, err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	if _, err := atomicfile.WriteFile(filepath.Join(dir, LLMsTxtFile), []byte(index.String())); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", LLMsTxtFile, err)
+	}
+	if _, err := atomicfile.WriteFile(filepath.Join(dir, LLMsFullTxtFile), []byte(full.String())); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", LLMsFullTxtFile, err)
+	}
+	return len(docs), nil
+}
+
+// writeConcatenated writes the bodies of docs, documents of the skill in
+// skillDir, to b one after another, each under an H1 with its title and a
+// "Source:" line with its URL. Links between the documents point to their
+// source URLs.
+func writeConcatenated(b *strings.Builder, skillDir string, docs []Document) error {
+	sources := make(map[string]string)
+	for _, doc := range docs {
+		if doc.SourceURL != "" {
+			sources[filepath.Base(doc.Path)] = doc.SourceURL
+		}
+	}
+	for i, doc := range docs {
+		body, _, err := readDocBody(skillDir, doc)
+		if err != nil {
+			return err
+		}
+		body = leadingHeadingPattern.ReplaceAllString(body, "")
+		body = docLinkPattern.ReplaceAllStringFunc(body, func(link string) string {
+			match := docLinkPattern.FindStringSubmatch(link)
+			if source, ok := sources[match[1]]; ok {
+				return "](" + source + match[2] + ")"
+			}
+			return link
+		})
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(b, "# %s\n", oneLine(doc.Title))
+		if doc.SourceURL != "" {
+			fmt.Fprintf(b, "Source: %s\n", doc.SourceURL)
+		}
+		b.WriteString("\n" + strings.TrimSpace(body) + "\n")
+	}
+	return nil
+}
+
+// readDocBody reads the document doc of the skill in skillDir and returns its
+// body, without leading blank lines, and its raw YAML frontmatter (nil if it
+// has none).
+func readDocBody(skillDir string, doc Document) (body string, frontmatter []byte, err error) {
+	content, err := os.ReadFile(filepath.Join(skillDir, filepath.FromSlash(doc.Path)))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read %s: %w", doc.Path, err)
+	}
+	yamlText, body, ok := mdprose.SplitFrontmatter(string(content))
+	if ok {
+		frontmatter = []byte(yamlText)
+	}
+	return strings.TrimLeft(body, "\n"), frontmatter, nil
+}
+
+// llmsSectionName returns the llms.txt heading of a section.
+func llmsSectionName(section string) string {
+	if section == "" {
+		return "Docs"
+	}
+	return section
+}
+
+// oneLine joins the lines of s with spaces.
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,162 @@
+// Package skillgen provides skill structure generation functionality.
+// This file implements skills holding one documentation tree per locale,
+// tied together by locales.json, which maps every page across the locales.
+package skillgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+)
+
+// LocalesFile is the name of the locale index written at the root of a skill
+// with one documentation tree per locale.
+const LocalesFile = "locales.json"
+
+var (
+	// partPattern matches the suffix of the files of the parts of a page after
+	// the first (see chunker.Chunker.ChunkFile).
+	partPattern = regexp.MustCompile(`-part-[0-9]+\.md$`)
+	// partTitlePattern matches the suffix of the titles of the parts of a page.
+	partTitlePattern = regexp.MustCompile(` \(part [0-9]+ of [0-9]+\)$`)
+)
+
+// LocaleIndex maps the pages of a skill with one documentation tree per
+// locale (e.g., en/docs/ and ja/docs/) to their files in every locale. It is
+// written as locales.json.
+type LocaleIndex struct {
+	// Name is the skill name.
+	Name string `json:"name"`
+	// URL is the start URL the site was crawled from.
+	URL string `json:"url,omitempty"`
+	// Locales lists the locales of the skill, most preferred first; each has
+	// its tree in the directory of the same name.
+	Locales []string `json:"locales"`
+	// Pages lists the pages of every locale, sorted by path.
+	Pages []LocalePage `json:"pages"`
+}
+
+// LocalePage is one page of a skill with one documentation tree per locale.
+type LocalePage struct {
+	// Path is the slash-separated path of the file of the page inside the
+	// tree of every locale (e.g., "docs/install.md"), the first if the page is
+	// split into parts: the language variants of a page are saved under its
+	// locale-neutral URL, and so under the same name in every locale.
+	Path string `json:"path"`
+	// Title is the title of the page in the first locale that has it.
+	Title string `json:"title"`
+	// Files lists the slash-separated paths of the files of the page inside
+	// the skill, by locale (e.g., "ja": ["ja/docs/install.md"]); several for a
+	// page split into parts. Locales lacking the page are left out.
+	Files map[string][]string `json:"files"`
+}
+
+// GenerateLocales completes a skill whose locales were generated, each as a
+// skill of its own, into the directories of outputBase/skillName named after
+// them (e.g., with Generate("en", sourceDir, outputBase/skillName)). It writes
+// locales.json, mapping the pages across the locales by file name, and
+// a SKILL.md introducing the locales at the root of the skill, whose trees
+// keep their own SKILL.md, manifest, and search index.
+//
+// Returns the locale index, or an error if the manifest of a locale can't be
+// read or the files can't be written.
+func (g *Generator) GenerateLocales(skillName string, locales []string, outputBase string) (*LocaleIndex, error) {
+	if len(locales) == 0 {
+		return nil, fmt.Errorf("no locale to index")
+	}
+	skillDir := filepath.Join(outputBase, skillName)
+	index := &LocaleIndex{Name: skillName, URL: g.sourceURL, Locales: locales}
+	pages := make(map[string]*LocalePage)
+	for _, locale := range locales {
+		m, err := ReadManifest(filepath.Join(skillDir, locale))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the manifest of locale %s: %w", locale, err)
+		}
+		for _, doc := range m.Documents {
+			key := doc.Path
+			if doc.Part > 1 {
+				key = partPattern.ReplaceAllString(key, ".md")
+			}
+			page, ok := pages[key]
+			if !ok {
+				page = &LocalePage{Path: key, Title: partTitlePattern.ReplaceAllString(doc.Title, ""), Files: make(map[string][]string)}
+				pages[key] = page
+			}
+			page.Files[locale] = append(page.Files[locale], locale+"/"+doc.Path)
+		}
+	}
+	keys := make([]string, 0, len(pages))
+	for key := range pages {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	index.Pages = make([]LocalePage, 0, len(keys))
+	counts := make([]int, len(locales))
+	for _, key := range keys {
+		page := pages[key]
+		for _, files := range page.Files {
+			sort.Strings(files)
+		}
+		for i, locale := range locales {
+			if len(page.Files[locale]) > 0 {
+				counts[i]++
+			}
+		}
+		index.Pages = append(index.Pages, *page)
+	}
+
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := atomicfile.WriteFile(filepath.Join(skillDir, LocalesFile), append(data, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", LocalesFile, err)
+	}
+
+	skillMDPath := filepath.Join(skillDir, "SKILL.md")
+	if _, err := atomicfile.WriteFile(skillMDPath, []byte(g.localesSkillContent(index, counts))); err != nil {
+		return nil, fmt.Errorf("failed to create SKILL.md: %w", err)
+	}
+	log.Printf("Created %s for locales %s", skillMDPath, strings.Join(locales, ", "))
+	return index, nil
+}
+
+// localesSkillContent returns the SKILL.md of a skill with one documentation
+// tree per locale: counts are the numbers of pages of index.Locales.
+func (g *Generator) localesSkillContent(index *LocaleIndex, counts []int) string {
+	name := strings.ToUpper(index.Name)
+	var b strings.Builder
+	if g.format == FormatCodex {
+		fmt.Fprintf(&b, "# %s Documentation Skill\n\n", name)
+	} else {
+		fmt.Fprintf(&b, "---\nname: %s\ndescription: %s documentation assistant (%s)\n---\n\n# %s Skill\n\n",
+			index.Name, name, strings.Join(index.Locales, ", "), name)
+	}
+	fmt.Fprintf(&b, "This skill provides access to %s documentation in %d languages. ", name, len(index.Locales))
+	b.WriteString("Each language has its own directory, with its documentation files in `docs/`, its own `SKILL.md` table of contents, and its own search index.\n\n")
+
+	b.WriteString("## Languages\n\n")
+	for i, locale := range index.Locales {
+		unit := "pages"
+		if counts[i] == 1 {
+			unit = "page"
+		}
+		fmt.Fprintf(&b, "- `%s/`: %d %s (see `%s/SKILL.md`)\n", locale, counts[i], unit, locale)
+	}
+	b.WriteString("\n`" + LocalesFile + "` lists every page of the site with its files in each language, so that a page read in one language can be found in another.\n\n")
+
+	fmt.Fprintf(&b, "## Search Tool\n\nSearch the documentation of one language:\n\n```bash\nsite2skillgo search \"<query>\" --skill-dir %s\n```\n\n", index.Locales[0])
+
+	b.WriteString("## Usage\n\n")
+	b.WriteString("1. Read the documentation in the language of the user, or in the first language listed if the skill doesn't have it\n")
+	b.WriteString("2. The same page has the same file name in every language; each file has frontmatter with `source_url` and `fetched_at`\n")
+	b.WriteString("3. Always cite the source URL in responses\n")
+	b.WriteString("4. Note the fetch date - documentation may have changed\n")
+	return b.String()
+}
@@ -0,0 +1,195 @@
+// Package skillgen provides skill structure generation functionality.
+// This file implements skill.lock.json, the lockfile pinning the revision of
+// every source a skill was built from, which tells an update which source
+// moved.
+package skillgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+)
+
+const (
+	// LockFile is the name of the lockfile written at the root of a skill.
+	LockFile = "skill.lock.json"
+	// LockVersion is the version of the lockfile format written by this build.
+	LockVersion = 1
+	// SourceWeb is the kind of a source crawled from a website.
+	SourceWeb = "web"
+)
+
+// Source change statuses of SourceChange.
+const (
+	// SourceAdded marks a source the previous lockfile didn't pin.
+	SourceAdded = "added"
+	// SourceMoved marks a source whose revision changed.
+	SourceMoved = "moved"
+	// SourceRemoved marks a source no longer in the skill.
+	SourceRemoved = "removed"
+)
+
+// Lock pins the revision of every source of a skill. It is written as
+// skill.lock.json, and compared with the previous one when the skill is
+// updated (see Changes.Sources).
+type Lock struct {
+	// Version is the lockfile format version.
+	Version int `json:"version"`
+	// Sources lists the sources of the skill, sorted by kind and URL.
+	Sources []LockedSource `json:"sources"`
+}
+
+// LockedSource is the revision of one source of a skill.
+type LockedSource struct {
+	// Kind is the kind of source; SourceWeb is the only one so far.
+	Kind string `json:"kind"`
+	// URL locates the source; the start URL of the crawl for SourceWeb.
+	URL string `json:"url"`
+	// Revision identifies the content of the source: for SourceWeb, a hash of
+	// the source URL and content hash of every page, which changes when a
+	// page is added, removed, or modified, but not when the site is merely
+	// fetched again.
+	Revision string `json:"revision"`
+	// Pages is the number of pages of the source.
+	Pages int `json:"pages"`
+	// LastModified is the newest modified_at of the pages of the source
+	// (RFC 3339), as reported by the site; empty if none reports it.
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// SourceChange is one source added, moved, or removed since the previous
+// lockfile of a skill.
+type SourceChange struct {
+	// Kind is the kind of source.
+	Kind string `json:"kind"`
+	// URL locates the source.
+	URL string `json:"url"`
+	// Status is SourceAdded, SourceMoved, or SourceRemoved.
+	Status string `json:"status"`
+	// From is the previous revision; empty for added sources.
+	From string `json:"from,omitempty"`
+	// To is the new revision; empty for removed sources.
+	To string `json:"to,omitempty"`
+}
+
+// String describes the change on one line, e.g., "web https://docs.example.com/
+// moved (sha256:1a2b3c4d5e6f -> sha256:6f5e4d3c2b1a)".
+func (c SourceChange) String() string {
+	s := fmt.Sprintf("%s %s %s", c.Kind, c.URL, c.Status)
+	switch c.Status {
+	case SourceMoved:
+		s += fmt.Sprintf(" (%s -> %s)", shortRevision(c.From), shortRevision(c.To))
+	case SourceAdded:
+		s += fmt.Sprintf(" (%s)", shortRevision(c.To))
+	}
+	return s
+}
+
+// shortRevision returns the revision with its hash cut to 12 digits.
+func shortRevision(rev string) string {
+	algo, hash, ok := strings.Cut(rev, ":")
+	if !ok || len(hash) <= 12 {
+		return rev
+	}
+	return algo + ":" + hash[:12]
+}
+
+// buildLock returns the lockfile of a skill with manifest m. The documents of
+// a web skill all come from the crawl of m.URL; documents without a source URL
+// count by path.
+func buildLock(m *Manifest) *Lock {
+	lines := make([]string, 0, len(m.Documents))
+	pages := make(map[string]bool)
+	var lastModified string
+	for _, doc := range m.Documents {
+		key := doc.SourceURL
+		if key == "" {
+			key = doc.Path
+		}
+		// The parts of a split page share its content hash
+		if !pages[key] {
+			pages[key] = true
+			lines = append(lines, key+" "+doc.ContentHash)
+		}
+		if doc.ModifiedAt > lastModified {
+			lastModified = doc.ModifiedAt
+		}
+	}
+	sort.Strings(lines)
+	return &Lock{
+		Version: LockVersion,
+		Sources: []LockedSource{{
+			Kind:         SourceWeb,
+			URL:          m.URL,
+			Revision:     sha256Hex([]byte(strings.Join(lines, "\n"))),
+			Pages:        len(pages),
+			LastModified: lastModified,
+		}},
+	}
+}
+
+// ReadLock reads the skill.lock.json of the skill in skillDir.
+//
+// Returns an error wrapping os.ErrNotExist if the skill has no lockfile, or an
+// error if it can't be read or parsed.
+func ReadLock(skillDir string) (*Lock, error) {
+	data, err := os.ReadFile(filepath.Join(skillDir, LockFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", LockFile, err)
+	}
+	var l Lock
+	if err := json.Unmarshal(data, &l); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", LockFile, err)
+	}
+	return &l, nil
+}
+
+// write saves the lockfile as skill.lock.json in skillDir.
+func (l *Lock) write(skillDir string) error {
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = atomicfile.WriteFile(filepath.Join(skillDir, LockFile), append(data, '\n'))
+	return err
+}
+
+// Diff returns the sources added, moved, and removed in next since l, sorted by
+// kind and URL. Sources are matched by kind and URL; a nil l (a skill without
+// lockfile) has no sources.
+func (l *Lock) Diff(next *Lock) []SourceChange {
+	type key struct{ kind, url string }
+	prev := make(map[key]LockedSource)
+	if l != nil {
+		for _, s := range l.Sources {
+			prev[key{s.Kind, s.URL}] = s
+		}
+	}
+	var changes []SourceChange
+	for _, s := range next.Sources {
+		k := key{s.Kind, s.URL}
+		old, ok := prev[k]
+		delete(prev, k)
+		switch {
+		case !ok:
+			changes = append(changes, SourceChange{Kind: s.Kind, URL: s.URL, Status: SourceAdded, To: s.Revision})
+		case old.Revision != s.Revision:
+			changes = append(changes, SourceChange{Kind: s.Kind, URL: s.URL, Status: SourceMoved, From: old.Revision, To: s.Revision})
+		}
+	}
+	for _, s := range prev {
+		changes = append(changes, SourceChange{Kind: s.Kind, URL: s.URL, Status: SourceRemoved, From: s.Revision})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return changes[i].Kind < changes[j].Kind
+		}
+		return changes[i].URL < changes[j].URL
+	})
+	return changes
+}
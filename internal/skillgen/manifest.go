@@ -0,0 +1,453 @@
+// Package skillgen provides skill structure generation functionality.
+// This file implements the document inventory behind the SKILL.md table of
+// contents and the manifest.json navigation file.
+package skillgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+	"github.com/f4ah6o/site2skill-go/internal/converter"
+	"github.com/f4ah6o/site2skill-go/internal/mdprose"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ManifestFile is the name of the navigation manifest written at the root of a skill.
+	ManifestFile = "manifest.json"
+	// tocLimit is the largest number of documents listed one by one in SKILL.md;
+	// larger skills list their sections only and leave the documents to the manifest.
+	tocLimit = 200
+	// summaryLength is the longest description shown next to a document in SKILL.md.
+	summaryLength = 120
+)
+
+// Manifest describes a generated skill: the documented site and every document,
+// grouped by the URL hierarchy of the site. It is written as manifest.json.
+type Manifest struct {
+	// Name is the skill name.
+	Name string `json:"name"`
+	// URL is the start URL the site was crawled from, used to update the skill.
+	URL string `json:"url,omitempty"`
+	// Title is the title of the site's top-level page.
+	Title string `json:"title,omitempty"`
+	// Description is the description of the site's top-level page.
+	Description string `json:"description,omitempty"`
+	// Freshness summarizes when the documents were fetched; nil if none records it.
+	Freshness *Freshness `json:"freshness,omitempty"`
+	// Documents lists the documents in table of contents order.
+	Documents []Document `json:"documents"`
+
+	// home is the page key (see pageKey) of the site's top-level page, which
+	// provides Title and Description; empty if no document has a source URL
+	home string
+}
+
+// Document is one documentation file of a skill.
+type Document struct {
+	// Path is the slash-separated path of the file inside the skill (e.g., "docs/auth.md").
+	Path string `json:"path"`
+	// Title is the page title.
+	Title string `json:"title"`
+	// SourceURL is the URL the page was fetched from.
+	SourceURL string `json:"source_url,omitempty"`
+	// FetchedAt is when the page was fetched (RFC 3339).
+	FetchedAt string `json:"fetched_at,omitempty"`
+	// ModifiedAt is when the site reports the page was last modified (RFC 3339).
+	ModifiedAt string `json:"modified_at,omitempty"`
+	// Author is the author of the feed entry the page was built from.
+	Author string `json:"author,omitempty"`
+	// Published is when the feed entry of the page was published (RFC 3339).
+	Published string `json:"published,omitempty"`
+	// Source names the site the page comes from in a skill built from
+	// several (e.g., "api.example.com"); empty otherwise.
+	Source string `json:"source,omitempty"`
+	// Section is the URL directory of the page relative to the site's common
+	// prefix (e.g., "api/auth"); empty for top-level pages. In a skill built
+	// from several sites, sections are under the source of the page (e.g.,
+	// "api.example.com/auth").
+	Section string `json:"section"`
+	// Description is the page's meta description.
+	Description string `json:"description,omitempty"`
+	// Summary is the abstract of the document written by a summarizer (see
+	// package summarize).
+	Summary string `json:"summary,omitempty"`
+	// WordCount is the number of words in the page.
+	WordCount int `json:"word_count,omitempty"`
+	// Outline lists the page's H1-H3 headings.
+	Outline []string `json:"outline,omitempty"`
+	// Headings lists the page's H1-H3 headings with their deep links, to
+	// reference an exact section of the file.
+	Headings []HeadingLink `json:"headings,omitempty"`
+	// Breadcrumbs is the breadcrumb trail of the page on the site, from the
+	// top of the site to the page.
+	Breadcrumbs []string `json:"breadcrumbs,omitempty"`
+	// Nav is the trail of the entries of the site's sidebar navigation
+	// leading to the page, from the top of the sidebar to the page.
+	Nav []string `json:"nav,omitempty"`
+	// NavPosition is the position of the page's entry in the sidebar, which
+	// orders the documents of a section as the site does (see
+	// comparePositions).
+	NavPosition []int `json:"nav_position,omitempty"`
+	// Kind is the kind of the page, KindFAQ or KindChangelog, when the
+	// converter recognized it as one.
+	Kind string `json:"kind,omitempty"`
+	// Aliases are the other URLs serving the page, whose copies were removed as duplicates.
+	Aliases []string `json:"aliases,omitempty"`
+	// Part numbers the files of a page split into several parts, from 1; 0 for whole pages.
+	Part int `json:"part,omitempty"`
+	// ContentHash is the hash of the HTML the page was converted from, which
+	// tells whether the page changed when the skill is updated.
+	ContentHash string `json:"content_hash,omitempty"`
+	// Access is the access level the page was tagged with ("public", "internal",
+	// or "confidential"); empty for untagged pages, which are public.
+	Access string `json:"access,omitempty"`
+
+	// anchors maps the fragments of the original page's headings to heading IDs
+	// of the file; they are written to anchors.json
+	anchors map[string]string
+}
+
+// HeadingLink is a heading of a document and the deep link to its section.
+type HeadingLink struct {
+	// Level is the heading level, 1 to 3.
+	Level int `json:"level"`
+	// Title is the heading text, without Markdown syntax.
+	Title string `json:"title"`
+	// Anchor is the ID of the heading, its GitHub-style slug (see
+	// converter.Headings).
+	Anchor string `json:"anchor"`
+	// Link is the path of the document and Anchor (e.g., "docs/auth.md#api-keys").
+	Link string `json:"link"`
+}
+
+// headingLinks returns the H1-H3 headings of body, the content of the
+// document at docPath, with their deep links.
+func headingLinks(docPath string, body []byte) []HeadingLink {
+	var headings []HeadingLink
+	for _, h := range converter.Headings(string(body)) {
+		if h.Level <= 3 && h.ID != "" {
+			headings = append(headings, HeadingLink{Level: h.Level, Title: h.Text, Anchor: h.ID, Link: docPath + "#" + h.ID})
+		}
+	}
+	return headings
+}
+
+// docFrontmatter holds the frontmatter fields read into a Document.
+type docFrontmatter struct {
+	Title       string            `yaml:"title"`
+	Source      string            `yaml:"source"`
+	SourceURL   string            `yaml:"source_url"`
+	FetchedAt   string            `yaml:"fetched_at"`
+	ModifiedAt  string            `yaml:"modified_at"`
+	Author      string            `yaml:"author"`
+	Published   string            `yaml:"published"`
+	Description string            `yaml:"description"`
+	Summary     string            `yaml:"summary"`
+	WordCount   int               `yaml:"word_count"`
+	Outline     []string          `yaml:"outline"`
+	Breadcrumbs []string          `yaml:"breadcrumbs"`
+	Nav         []string          `yaml:"nav"`
+	NavPosition []int             `yaml:"nav_position"`
+	Kind        string            `yaml:"kind"`
+	Part        int               `yaml:"part"`
+	Anchors     map[string]string `yaml:"anchors"`
+	Aliases     []string          `yaml:"aliases"`
+	ContentHash string            `yaml:"content_hash"`
+	Access      string            `yaml:"access"`
+}
+
+// buildManifest reads the frontmatter of every Markdown file in the skill's docs
+// directory. Documents are assigned to sections by URL directory, and sorted by
+// section, then in the order of the site's sidebar navigation, the pages
+// missing from it last, then by source URL; the page with the shortest URL path
+// provides the site title and description.
+func buildManifest(skillName, skillDir string) (*Manifest, error) {
+	docsDir := filepath.Join(skillDir, "docs")
+	files, err := filepath.Glob(filepath.Join(docsDir, "*.md"))
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{Name: skillName, Documents: []Document{}}
+	var dirs [][]string
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		var fm docFrontmatter
+		body := content
+		if frontmatter, rest, ok := mdprose.SplitFrontmatter(string(content)); ok {
+			// Files with broken frontmatter are still listed, by file name
+			yaml.Unmarshal([]byte(frontmatter), &fm)
+			body = content[len(content)-len(rest):]
+		}
+		name := filepath.Base(file)
+		if fm.Title == "" {
+			fm.Title = strings.TrimSuffix(name, ".md")
+		}
+		docPath := "docs/" + name
+		m.Documents = append(m.Documents, Document{
+			Path:        docPath,
+			Title:       fm.Title,
+			Source:      fm.Source,
+			SourceURL:   fm.SourceURL,
+			FetchedAt:   fm.FetchedAt,
+			ModifiedAt:  fm.ModifiedAt,
+			Author:      fm.Author,
+			Published:   fm.Published,
+			Description: fm.Description,
+			Summary:     fm.Summary,
+			WordCount:   fm.WordCount,
+			Outline:     fm.Outline,
+			Headings:    headingLinks(docPath, body),
+			Breadcrumbs: fm.Breadcrumbs,
+			Nav:         fm.Nav,
+			NavPosition: fm.NavPosition,
+			Kind:        fm.Kind,
+			Aliases:     fm.Aliases,
+			Part:        fm.Part,
+			ContentHash: fm.ContentHash,
+			Access:      fm.Access,
+			anchors:     fm.Anchors,
+		})
+		dirs = append(dirs, urlDir(fm.SourceURL))
+	}
+
+	// Sections are relative to the directory all pages of a source share
+	// (e.g., /docs/), under the name of the source if any; documents without
+	// a source URL are listed at the top level
+	located := make(map[string][][]string)
+	for i, doc := range m.Documents {
+		if doc.SourceURL != "" {
+			located[doc.Source] = append(located[doc.Source], dirs[i])
+		}
+	}
+	prefixes := make(map[string][]string, len(located))
+	for source, sourceDirs := range located {
+		prefixes[source] = commonPrefix(sourceDirs)
+	}
+	home := -1
+	for i, doc := range m.Documents {
+		if doc.SourceURL == "" {
+			continue
+		}
+		section := dirs[i][len(prefixes[doc.Source]):]
+		if doc.Source != "" {
+			section = append([]string{doc.Source}, section...)
+		}
+		m.Documents[i].Section = strings.Join(section, "/")
+		// The first part of a split page introduces it
+		if depth := urlDepth(doc.SourceURL); home < 0 || depth < urlDepth(m.Documents[home].SourceURL) ||
+			depth == urlDepth(m.Documents[home].SourceURL) && doc.Part < m.Documents[home].Part {
+			home = i
+		}
+	}
+	if home >= 0 {
+		m.home = pageKey(m.Documents[home])
+		m.Title = m.Documents[home].Title
+		m.Description = m.Documents[home].Description
+	}
+
+	sort.SliceStable(m.Documents, func(i, j int) bool {
+		a, b := m.Documents[i], m.Documents[j]
+		if a.Section != b.Section {
+			return a.Section < b.Section
+		}
+		if c := comparePositions(a.NavPosition, b.NavPosition); c != 0 {
+			return c < 0
+		}
+		if a.SourceURL != b.SourceURL {
+			return a.SourceURL < b.SourceURL
+		}
+		if a.Part != b.Part {
+			return a.Part < b.Part
+		}
+		return a.Path < b.Path
+	})
+	return m, nil
+}
+
+// comparePositions compares the sidebar positions of two documents (see
+// Document.NavPosition) in the order of the sidebar: -1 if a comes first, 1
+// if b does, and 0 if they are the same entry. Positions are compared index
+// by index, an entry coming before its subentries, and the documents missing
+// from the sidebar after those in it.
+func comparePositions(a, b []int) int {
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return 1
+	case len(b) == 0:
+		return -1
+	}
+	return slices.Compare(a, b)
+}
+
+// urlDir returns the directory segments of a URL's path, or nil if it can't be parsed.
+// A trailing slash marks a directory index page, which belongs to that directory.
+func urlDir(rawURL string) []string {
+	u, err := url.Parse(rawURL)
+	if err != nil || rawURL == "" {
+		return nil
+	}
+	dir := path.Dir(u.Path)
+	var segments []string
+	for _, s := range strings.Split(dir, "/") {
+		if s != "" && s != "." {
+			segments = append(segments, s)
+		}
+	}
+	return segments
+}
+
+// urlDepth returns the number of path segments of a URL.
+func urlDepth(rawURL string) int {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return 0
+	}
+	return len(strings.FieldsFunc(u.Path, func(r rune) bool { return r == '/' }))
+}
+
+// commonPrefix returns the leading segments shared by every directory.
+func commonPrefix(dirs [][]string) []string {
+	if len(dirs) == 0 {
+		return nil
+	}
+	prefix := dirs[0]
+	for _, dir := range dirs[1:] {
+		n := 0
+		for n < len(prefix) && n < len(dir) && prefix[n] == dir[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return prefix
+}
+
+// ReadManifest reads the manifest.json of the skill in skillDir.
+//
+// Returns an error wrapping os.ErrNotExist if the skill has no manifest, or an
+// error if it can't be read or parsed.
+func ReadManifest(skillDir string) (*Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(skillDir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ManifestFile, err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", ManifestFile, err)
+	}
+	return &m, nil
+}
+
+// write saves the manifest as manifest.json in skillDir.
+func (m *Manifest) write(skillDir string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = atomicfile.WriteFile(filepath.Join(skillDir, ManifestFile), append(data, '\n'))
+	return err
+}
+
+// overview returns the SKILL.md lines introducing the documented site and
+// how fresh its documents are, or "".
+func (m *Manifest) overview() string {
+	if m.Title == "" {
+		return m.Freshness.summary()
+	}
+	text := "Documentation site: **" + m.Title + "**"
+	if m.Description != "" {
+		text += " - " + m.Description
+	}
+	return text + "\n\n" + m.Freshness.summary()
+}
+
+// tableOfContents renders the SKILL.md section listing the documents by section.
+// Skills with more than tocLimit documents list their sections with document counts.
+func (m *Manifest) tableOfContents() string {
+	if len(m.Documents) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("\n## Contents\n\n")
+	if len(m.Documents) > tocLimit {
+		fmt.Fprintf(&b, "%d documents; `%s` lists each with its URL, description, and outline, and `%s` nests them by place in the site.\n\n", len(m.Documents), ManifestFile, TOCFile)
+		counts := make(map[string]int)
+		var sections []string
+		for _, doc := range m.Documents {
+			if counts[doc.Section] == 0 {
+				sections = append(sections, doc.Section)
+			}
+			counts[doc.Section]++
+		}
+		for _, section := range sections {
+			fmt.Fprintf(&b, "- %s: %d documents\n", sectionName(section), counts[section])
+		}
+		return b.String()
+	}
+
+	fmt.Fprintf(&b, "`%s` lists every document with its URL, description, and outline.\n", ManifestFile)
+	for i, doc := range m.Documents {
+		switch {
+		case i > 0 && doc.Section == m.Documents[i-1].Section:
+		case doc.Section == "":
+			// Top-level documents come first, without a heading
+			b.WriteString("\n")
+		default:
+			fmt.Fprintf(&b, "\n### %s\n\n", sectionName(doc.Section))
+		}
+		fmt.Fprintf(&b, "- [%s](%s)", doc.Title, doc.Path)
+		if summary := truncate(doc.abstract(), summaryLength); summary != "" {
+			b.WriteString(": " + summary)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// abstract returns what the document is about: its summary, or else its
+// description.
+func (d Document) abstract() string {
+	if d.Summary != "" {
+		return d.Summary
+	}
+	return d.Description
+}
+
+// sectionName returns the display name of a section.
+func sectionName(section string) string {
+	if section == "" {
+		return "(top level)"
+	}
+	return section + "/"
+}
+
+// truncate shortens s to at most n bytes, cutting at a word boundary and adding an ellipsis.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	cut := strings.LastIndex(s[:n], " ")
+	if cut <= 0 {
+		cut = n
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+	}
+	return strings.TrimRight(s[:cut], " ,;:.") + "..."
+}
@@ -6,9 +6,17 @@ package skillgen
 import (
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/assets"
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+	"github.com/f4ah6o/site2skill-go/internal/pathglob"
+	"github.com/f4ah6o/site2skill-go/internal/search"
+	"github.com/f4ah6o/site2skill-go/internal/snippets"
 )
 
 const (
@@ -26,6 +34,12 @@ const (
 type Generator struct {
 	// format specifies the target AI platform ("claude", "codex", or "both")
 	format string
+	// sourceURL is the start URL of the crawl, recorded in the manifest
+	sourceURL string
+	// only lists the URL path patterns of the sections an update replaces; empty for all
+	only []string
+	// timestamp replaces the current time in the generated files; zero uses the clock
+	timestamp time.Time
 }
 
 // New creates a new Generator configured for the specified output format.
@@ -41,6 +55,50 @@ func New(format string) *Generator {
 	}
 }
 
+// SetSourceURL records the URL the skill's site was crawled from in its
+// manifest, so that the skill can be updated later by crawling it again.
+func (g *Generator) SetSourceURL(url string) {
+	g.sourceURL = url
+}
+
+// SetTimestamp records t instead of the current time in the generated files
+// (the freshness of manifest.json and the date of changes.md) and as the
+// modification time of the documents, so that the same pages generate an
+// identical skill. A zero t uses the current time.
+func (g *Generator) SetTimestamp(t time.Time) {
+	g.timestamp = t
+}
+
+// now returns the time recorded in the generated files.
+func (g *Generator) now() time.Time {
+	if !g.timestamp.IsZero() {
+		return g.timestamp
+	}
+	return time.Now()
+}
+
+// SetOnly restricts Update to the sections of the site whose URL paths match
+// one of patterns (see the pathglob package), e.g. "/guides/**": the other
+// pages of the skill are kept as they are whether crawled or not, and the
+// assets and snippets of the update are added to those of the skill instead
+// of replacing them.
+func (g *Generator) SetOnly(patterns []string) {
+	g.only = patterns
+}
+
+// inSection reports whether the page identified by key (its source URL, or
+// its path for a page without one) belongs to the sections set with SetOnly.
+func (g *Generator) inSection(key string) bool {
+	if len(g.only) == 0 {
+		return true
+	}
+	if strings.HasPrefix(key, "docs/") {
+		return false
+	}
+	u, err := url.Parse(key)
+	return err == nil && pathglob.MatchAny(g.only, u.Path)
+}
+
 // Generate creates a complete skill directory structure for the specified skill package.
 // It sets up the directory layout, generates platform-specific manifest files, and
 // copies documentation files into the proper structure.
@@ -48,11 +106,22 @@ func New(format string) *Generator {
 // The generated structure:
 //
 //	skillName/
-//	  ├── SKILL.md          # Platform-specific manifest and usage instructions
-//	  └── docs/             # Markdown documentation files with YAML frontmatter
-//	      ├── file1.md
-//	      ├── file2.md
-//	      └── ...
+//	  ├── SKILL.md          # Platform-specific manifest, usage instructions, and table of contents
+//	  ├── manifest.json     # Site title, description, and document inventory (see Manifest)
+//	  ├── anchors.json      # Heading anchors of the original pages mapped to files (see Anchor)
+//	  ├── graph.json        # Links between the documents (see Graph)
+//	  ├── toc.md            # Every document nested by its place in the site
+//	  ├── glossary.md       # Terms defined by the documents
+//	  ├── faq.md            # Questions answered by the FAQ pages
+//	  ├── changelog.md      # Releases described by the changelog pages
+//	  ├── symbols.json      # Code symbols declared in code blocks (see search.Symbol)
+//	  ├── docs/             # Markdown documentation files with YAML frontmatter
+//	  │   ├── .index/       # Inverted index of the files for the search command
+//	  │   ├── file1.md
+//	  │   ├── file2.md
+//	  │   └── ...
+//	  ├── assets/           # Downloaded media and CSV tables (only if sourceDir has assets/)
+//	  └── snippets/         # Code samples shared by several pages (only if sourceDir has snippets/)
 //
 // Parameters:
 //   - skillName: Name of the skill (used as the directory name)
@@ -76,19 +145,188 @@ func (g *Generator) Generate(skillName, sourceDir, outputBase string) error {
 		return fmt.Errorf("failed to create docs directory: %w", err)
 	}
 
-	// Create SKILL.md based on format
-	if err := g.createSkillMD(skillDir, skillName); err != nil {
-		return fmt.Errorf("failed to create SKILL.md: %w", err)
-	}
-
 	// Copy markdown files
 	if err := g.copyMarkdownFiles(sourceDir, docsDir); err != nil {
 		return fmt.Errorf("failed to copy markdown files: %w", err)
 	}
 
+	// A changelog of an earlier update doesn't describe a full generation
+	if err := os.Remove(filepath.Join(skillDir, ChangesFile)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", ChangesFile, err)
+	}
+
+	_, _, err := g.index(skillName, sourceDir, skillDir)
+	return err
+}
+
+// index completes a skill whose docs/ directory is up to date: it copies the
+// assets and snippets of sourceDir, and writes manifest.json, skill.lock.json,
+// anchors.json, graph.json, toc.md, glossary.md, faq.md, changelog.md, the
+// search index, symbols.json, and SKILL.md.
+// Returns the manifest and the lockfile.
+func (g *Generator) index(skillName, sourceDir, skillDir string) (*Manifest, *Lock, error) {
+	// Copy downloaded assets and shared code samples; the other pages of a
+	// partial update still reference those of earlier runs
+	replace := len(g.only) == 0
+	if !replace {
+		if err := mergeAssetMap(filepath.Join(sourceDir, assets.DirName), filepath.Join(skillDir, assets.DirName)); err != nil {
+			return nil, nil, fmt.Errorf("failed to merge the asset map: %w", err)
+		}
+	}
+	if err := g.copyAssets(filepath.Join(sourceDir, assets.DirName), filepath.Join(skillDir, assets.DirName), replace); err != nil {
+		return nil, nil, fmt.Errorf("failed to copy assets: %w", err)
+	}
+	if err := g.copyAssets(filepath.Join(sourceDir, snippets.DirName), filepath.Join(skillDir, snippets.DirName), replace); err != nil {
+		return nil, nil, fmt.Errorf("failed to copy snippets: %w", err)
+	}
+
+	// Index the documents for navigation
+	manifest, err := buildManifest(skillName, skillDir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build manifest: %w", err)
+	}
+	manifest.URL = g.sourceURL
+	manifest.Freshness = computeFreshness(manifest.Documents, g.now())
+	if err := manifest.write(skillDir); err != nil {
+		return nil, nil, fmt.Errorf("failed to write %s: %w", ManifestFile, err)
+	}
+
+	// Pin the revision of the sources, which tells the next update which moved
+	lock := buildLock(manifest)
+	if err := lock.write(skillDir); err != nil {
+		return nil, nil, fmt.Errorf("failed to write %s: %w", LockFile, err)
+	}
+
+	// Map the headings of the original pages to their files
+	if err := writeAnchors(skillDir, manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to write %s: %w", AnchorsFile, err)
+	}
+
+	// Map the links between the documents, and nest them by place in the site
+	graph, err := writeGraph(skillDir, manifest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to write %s: %w", GraphFile, err)
+	}
+	if err := writeTOC(skillDir, manifest, graph); err != nil {
+		return nil, nil, fmt.Errorf("failed to write %s: %w", TOCFile, err)
+	}
+
+	// Collect the terms the documents define, for "what is X" questions
+	if _, err := writeGlossary(skillDir, manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to write %s: %w", GlossaryFile, err)
+	}
+	// And the questions and releases of the FAQ and changelog pages
+	if _, err := writeFAQ(skillDir, manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to write %s: %w", FAQFile, err)
+	}
+	if _, err := writeChangelog(skillDir, manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to write %s: %w", ChangelogFile, err)
+	}
+
+	// Index the document bodies for the search command; the index records the
+	// modification times of the documents
+	if !g.timestamp.IsZero() {
+		if err := setModTimes(filepath.Join(skillDir, "docs"), g.timestamp); err != nil {
+			return nil, nil, fmt.Errorf("failed to set document times: %w", err)
+		}
+	}
+	if err := search.BuildIndex(skillDir); err != nil {
+		return nil, nil, fmt.Errorf("failed to build search index: %w", err)
+	}
+	// Record the symbols declared in code blocks for exact-symbol lookups
+	if _, err := search.BuildSymbols(skillDir); err != nil {
+		return nil, nil, err
+	}
+
+	// Create SKILL.md based on format
+	if err := g.createSkillMD(skillDir, manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to create SKILL.md: %w", err)
+	}
+
+	return manifest, lock, nil
+}
+
+// setModTimes sets the modification time of the files in dir and its
+// subdirectories to t.
+func setModTimes(dir string, t time.Time) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		return os.Chtimes(path, t, t)
+	})
+}
+
+// copyAssets copies the files in a source directory of files referenced by the
+// pages (assets/ or snippets/) into the skill's directory of the same name,
+// removing the files left from a previous generation if replace is true. Files
+// whose content didn't change aren't written again.
+// Markdown files reference them as ../assets/<file> or ../snippets/<file>.
+// Does nothing if assetsDir doesn't exist.
+func (g *Generator) copyAssets(assetsDir, dstDir string, replace bool) error {
+	entries, err := os.ReadDir(assetsDir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return err
+	}
+
+	// Files already up to date are left as they are, keeping their modification times
+	fileCount, written := 0, 0
+	keep := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		if !e.Type().IsRegular() {
+			continue
+		}
+		keep[e.Name()] = true
+		w, err := atomicfile.CopyFile(filepath.Join(assetsDir, e.Name()), filepath.Join(dstDir, e.Name()))
+		if err != nil {
+			return err
+		}
+		if w {
+			written++
+		}
+		fileCount++
+	}
+	if replace {
+		if err := atomicfile.RemoveStale(dstDir, keep); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("Copied %d files to %s/ (%d unchanged)", fileCount, filepath.Base(dstDir), fileCount-written)
 	return nil
 }
 
+// mergeAssetMap adds the assets of the asset map of dstDir, the assets of a
+// skill being partially updated, to that of srcDir, the assets downloaded by
+// the update, which replaces it: the URLs of the pages left alone keep their
+// files. Nothing is done if srcDir has no map.
+func mergeAssetMap(srcDir, dstDir string) error {
+	if _, err := os.Stat(filepath.Join(srcDir, assets.MapFile)); os.IsNotExist(err) {
+		return nil
+	}
+	src, err := assets.ReadMap(srcDir)
+	if err != nil {
+		return err
+	}
+	dst, err := assets.ReadMap(dstDir)
+	if err != nil {
+		return err
+	}
+	for target, name := range dst {
+		if _, ok := src[target]; !ok {
+			src[target] = name
+		}
+	}
+	return assets.WriteMap(srcDir, src)
+}
+
 // createSkillMD generates the SKILL.md manifest file for the skill package.
 // The manifest provides instructions for AI assistants on how to use the skill,
 // including search commands, file locations, and response formatting guidelines.
@@ -97,13 +335,16 @@ func (g *Generator) Generate(skillName, sourceDir, outputBase string) error {
 //   - Claude format: Includes YAML frontmatter with name and description
 //   - Codex format: Uses standard Markdown headings without frontmatter
 //
+// Both end with a table of contents of the documents, grouped by URL hierarchy.
+//
 // Parameters:
 //   - skillDir: The skill's root directory where SKILL.md will be created
-//   - skillName: The skill name (used in the manifest content and metadata)
+//   - manifest: The skill's documents; its Name is used in the manifest content and metadata
 //
 // Returns an error if the file cannot be written.
-func (g *Generator) createSkillMD(skillDir, skillName string) error {
+func (g *Generator) createSkillMD(skillDir string, manifest *Manifest) error {
 	skillMDPath := filepath.Join(skillDir, "SKILL.md")
+	skillName := manifest.Name
 
 	var content string
 	if g.format == FormatCodex {
@@ -114,8 +355,18 @@ func (g *Generator) createSkillMD(skillDir, skillName string) error {
 	} else {
 		content = g.getClaudeSkillContent(skillName)
 	}
+	content = insertOverview(content, manifest.overview()) + manifest.tableOfContents()
+	if _, err := os.Stat(filepath.Join(skillDir, GlossaryFile)); err == nil {
+		content += "\n## Glossary\n\nThe terms the documentation defines are listed in `" + GlossaryFile + "`, with the document defining each.\n"
+	}
+	if _, err := os.Stat(filepath.Join(skillDir, FAQFile)); err == nil {
+		content += "\n## FAQ\n\nThe questions the FAQ pages answer are collected in `" + FAQFile + "`, with their answers, under the page answering each.\n"
+	}
+	if _, err := os.Stat(filepath.Join(skillDir, ChangelogFile)); err == nil {
+		content += "\n## Changelog\n\nThe releases the changelog pages describe are collected in `" + ChangelogFile + "`, newest first by version, with their dates.\n"
+	}
 
-	if err := os.WriteFile(skillMDPath, []byte(content), 0644); err != nil {
+	if _, err := atomicfile.WriteFile(skillMDPath, []byte(content)); err != nil {
 		return err
 	}
 
@@ -161,7 +412,7 @@ Options:
 ## Usage
 
 1. Search or read files in `+"`docs/`"+` for relevant information
-2. Each file has frontmatter with `+"`source_url`"+` and `+"`fetched_at`"+`
+2. Each file has frontmatter with `+"`source_url`"+` and `+"`fetched_at`"+`; most also have a `+"`description`"+` and an `+"`outline`"+` of their headings, which tell whether a file is relevant without reading it
 3. Always cite the source URL in responses
 4. Note the fetch date - documentation may have changed
 
@@ -209,7 +460,7 @@ Options:
 ## Documentation Files
 
 Each file in `+"`docs/`"+` contains:
-- **Frontmatter**: YAML metadata with `+"`title`"+`, `+"`source_url`"+`, and `+"`fetched_at`"+`
+- **Frontmatter**: YAML metadata with `+"`title`"+`, `+"`source_url`"+`, and `+"`fetched_at`"+`, plus `+"`description`"+`, `+"`canonical_url`"+`, `+"`locale`"+`, `+"`word_count`"+`, `+"`tags`"+`, and an `+"`outline`"+` of the H1-H3 headings when the page provides them
 - **Content**: Markdown-formatted documentation
 
 ## Best Practices
@@ -234,6 +485,8 @@ site2skillgo search "payment methods" --json --max-results 5 --skill-dir .
 // copyMarkdownFiles copies all Markdown files from the source directory to the skill's docs directory.
 // It recursively walks the source directory and copies only .md files, flattening the structure
 // (all files go directly into docs/ regardless of source subdirectories).
+// Files whose content didn't change are left as they are, keeping their
+// modification times, and the others are replaced atomically.
 //
 // Security: Performs path validation to prevent directory traversal attacks by checking that
 // destination paths remain within the docs directory.
@@ -253,7 +506,7 @@ func (g *Generator) copyMarkdownFiles(sourceDir, docsDir string) error {
 		return fmt.Errorf("source directory does not exist: %s", sourceDir)
 	}
 
-	fileCount := 0
+	fileCount, written := 0, 0
 	err := filepath.Walk(sourceDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
@@ -279,16 +532,14 @@ func (g *Generator) copyMarkdownFiles(sourceDir, docsDir string) error {
 				return nil
 			}
 
-			// Copy file
-			content, err := os.ReadFile(path)
+			// Copy the file, unless it is already up to date
+			w, err := atomicfile.CopyFile(path, dstPath)
 			if err != nil {
-				return fmt.Errorf("failed to read %s: %w", path, err)
+				return err
 			}
-
-			if err := os.WriteFile(dstPath, content, 0644); err != nil {
-				return fmt.Errorf("failed to write %s: %w", dstPath, err)
+			if w {
+				written++
 			}
-
 			fileCount++
 		}
 
@@ -299,6 +550,16 @@ func (g *Generator) copyMarkdownFiles(sourceDir, docsDir string) error {
 		return err
 	}
 
-	log.Printf("Copied %d files to docs/", fileCount)
+	log.Printf("Copied %d files to docs/ (%d unchanged)", fileCount, fileCount-written)
 	return nil
 }
+
+// insertOverview inserts the overview of the documented site into SKILL.md
+// content, after the introduction and before the first section.
+func insertOverview(content, overview string) string {
+	i := strings.Index(content, "\n## ")
+	if overview == "" || i < 0 {
+		return content
+	}
+	return content[:i+1] + overview + content[i+1:]
+}
@@ -1,7 +1,18 @@
 package skillgen
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/assets"
 )
 
 func TestNewGenerator(t *testing.T) {
@@ -32,3 +43,1244 @@ func TestNewGenerator(t *testing.T) {
 		})
 	}
 }
+
+func TestGenerateManifest(t *testing.T) {
+	src := t.TempDir()
+	docs := map[string]string{
+		"index.md":   "---\ntitle: Example Docs\ndescription: Everything about Example.\nsource_url: https://example.com/docs/\n---\n\nWelcome",
+		"auth.md":    "---\ntitle: Authentication\ndescription: Sign requests with API keys.\nsource_url: https://example.com/docs/api/auth\noutline:\n  - '# Authentication'\nanchors:\n  api_keys: api-keys\naliases:\n  - https://example.com/docs/api/auth.html\n---\n\nKeys",
+		"install.md": "---\ntitle: Install\nsource_url: https://example.com/docs/install\n---\n\nSteps",
+		"notes.md":   "No frontmatter",
+	}
+	for name, content := range docs {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := t.TempDir()
+	if err := New(FormatClaude).Generate("example", src, out); err != nil {
+		t.Fatalf("Generate() returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(out, "example", ManifestFile))
+	if err != nil {
+		t.Fatalf("manifest missing: %v", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("invalid manifest: %v", err)
+	}
+	if m.Title != "Example Docs" || m.Description != "Everything about Example." {
+		t.Errorf("site = %q, %q; want the index page's title and description", m.Title, m.Description)
+	}
+	var got []string
+	for _, doc := range m.Documents {
+		got = append(got, doc.Section+":"+doc.Path)
+	}
+	want := ":docs/notes.md :docs/index.md :docs/install.md api:docs/auth.md"
+	if strings.Join(got, " ") != want {
+		t.Errorf("documents = %v, want %s", got, want)
+	}
+
+	data, err = os.ReadFile(filepath.Join(out, "example", AnchorsFile))
+	if err != nil {
+		t.Fatalf("anchors missing: %v", err)
+	}
+	var anchors map[string]Anchor
+	if err := json.Unmarshal(data, &anchors); err != nil {
+		t.Fatalf("invalid anchors: %v", err)
+	}
+	wantAnchors := map[string]Anchor{
+		"https://example.com/docs/api/auth#api_keys":      {Path: "docs/auth.md", ID: "api-keys"},
+		"https://example.com/docs/api/auth.html#api_keys": {Path: "docs/auth.md", ID: "api-keys"},
+	}
+	if !reflect.DeepEqual(anchors, wantAnchors) {
+		t.Errorf("anchors = %v, want %v", anchors, wantAnchors)
+	}
+
+	skillMD, err := os.ReadFile(filepath.Join(out, "example", "SKILL.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"Documentation site: **Example Docs** - Everything about Example.\n\n## Documentation",
+		"## Contents\n",
+		"- [Install](docs/install.md)\n",
+		"### api/\n\n- [Authentication](docs/auth.md): Sign requests with API keys.\n",
+	} {
+		if !strings.Contains(string(skillMD), want) {
+			t.Errorf("SKILL.md missing %q:\n%s", want, skillMD)
+		}
+	}
+}
+
+func TestBuildManifestSources(t *testing.T) {
+	skill := t.TempDir()
+	src := filepath.Join(skill, "docs")
+	if err := os.Mkdir(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	docs := map[string]string{
+		"index.md": "---\ntitle: Docs\nsource: docs.example.com/docs\nsource_url: https://docs.example.com/docs/\n---\n\nWelcome",
+		"setup.md": "---\ntitle: Setup\nsource: docs.example.com/docs\nsource_url: https://docs.example.com/docs/guide/setup\n---\n\nSteps",
+		"auth.md":  "---\ntitle: Auth\nsource: api.example.com\nsource_url: https://api.example.com/reference/auth\n---\n\nKeys",
+	}
+	for name, content := range docs {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m, err := buildManifest("example", skill)
+	if err != nil {
+		t.Fatalf("buildManifest() returned error: %v", err)
+	}
+	var got []string
+	for _, doc := range m.Documents {
+		got = append(got, doc.Section+":"+doc.Path+":"+doc.Source)
+	}
+	want := "api.example.com:docs/auth.md:api.example.com docs.example.com/docs:docs/index.md:docs.example.com/docs " +
+		"docs.example.com/docs/guide:docs/setup.md:docs.example.com/docs"
+	if strings.Join(got, " ") != want {
+		t.Errorf("documents = %v, want %s", got, want)
+	}
+}
+
+func TestTableOfContentsLimit(t *testing.T) {
+	m := &Manifest{Name: "big"}
+	for i := 0; i <= tocLimit; i++ {
+		section := "api"
+		if i%2 == 0 {
+			section = "guides"
+		}
+		m.Documents = append(m.Documents, Document{Path: fmt.Sprintf("docs/p%d.md", i), Title: "P", Section: section})
+	}
+
+	toc := m.tableOfContents()
+	if strings.Contains(toc, "docs/p0.md") {
+		t.Errorf("table of contents over the limit lists documents:\n%s", toc)
+	}
+	if !strings.Contains(toc, "- guides/: 101 documents") {
+		t.Errorf("table of contents missing section counts:\n%s", toc)
+	}
+}
+
+func TestWriteStats(t *testing.T) {
+	skillDir := filepath.Join(t.TempDir(), "demo")
+	files := map[string]string{
+		"SKILL.md":          "# Demo\n",
+		"docs/small.md":     "",
+		"docs/large.md":     strings.Repeat("word ", 200),
+		"assets/logo.png":   "\x89PNG\x00\x00",
+		"snippets/setup.sh": "echo hello\n",
+	}
+	for _, i := range []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10} {
+		files[fmt.Sprintf("docs/page%02d.md", i)] = strings.Repeat("word ", i)
+	}
+	for name, content := range files {
+		path := filepath.Join(skillDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats, err := WriteStats(skillDir, nil)
+	if err != nil {
+		t.Fatalf("WriteStats() returned error: %v", err)
+	}
+	if len(stats.Files) != len(files) {
+		t.Errorf("Files has %d entries, want %d", len(stats.Files), len(files))
+	}
+	if len(stats.Largest) != largestDocuments || stats.Largest[0].Path != "docs/large.md" {
+		t.Errorf("Largest = %+v, want %d documents starting with docs/large.md", stats.Largest, largestDocuments)
+	}
+	for _, f := range stats.Largest {
+		if f.Path == "docs/small.md" {
+			t.Errorf("Largest lists the smallest document: %+v", stats.Largest)
+		}
+	}
+	for _, f := range stats.Files {
+		if f.Path == "assets/logo.png" && f.Tokens != 0 {
+			t.Errorf("binary file counted %d tokens", f.Tokens)
+		}
+	}
+	if stats.DocTokens >= stats.Tokens || stats.DocBytes >= stats.Bytes {
+		t.Errorf("document totals %d tokens, %d bytes not below skill totals %d, %d",
+			stats.DocTokens, stats.DocBytes, stats.Tokens, stats.Bytes)
+	}
+
+	data, err := os.ReadFile(filepath.Join(skillDir, StatsFile))
+	if err != nil {
+		t.Fatalf("stats.json not written: %v", err)
+	}
+	var got Stats
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("stats.json is invalid: %v", err)
+	}
+	if got.Tokens != stats.Tokens || got.Skill != "demo" {
+		t.Errorf("stats.json = %+v, want %+v", got, stats)
+	}
+
+	// Measuring again must not count stats.json itself
+	again, err := ComputeStats(skillDir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Bytes != stats.Bytes {
+		t.Errorf("second measurement has %d bytes, want %d", again.Bytes, stats.Bytes)
+	}
+	if summary := stats.Summary(); !strings.Contains(summary, " 1. ~") || !strings.Contains(summary, "docs/large.md") {
+		t.Errorf("Summary() missing largest documents:\n%s", summary)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	page := func(title, url, hash, fetchedAt string) string {
+		return fmt.Sprintf("---\ntitle: %s\nsource_url: %s\nfetched_at: %q\ncontent_hash: %s\n---\n\n%s body\n", title, url, fetchedAt, hash, title)
+	}
+	writeDocs := func(dir string, docs map[string]string) {
+		t.Helper()
+		os.RemoveAll(dir)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range docs {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	src, out := t.TempDir(), t.TempDir()
+	writeDocs(src, map[string]string{
+		"index.md":   page("Home", "https://example.com/docs/", "sha256:a", "2024-01-01T00:00:00Z"),
+		"install.md": page("Install", "https://example.com/docs/install", "sha256:b", "2024-01-01T00:00:00Z"),
+		"old.md":     page("Old", "https://example.com/docs/old", "sha256:c", "2024-01-01T00:00:00Z"),
+	})
+	g := New(FormatClaude)
+	g.SetSourceURL("https://example.com/docs/")
+	if err := g.Generate("example", src, out); err != nil {
+		t.Fatalf("Generate() returned error: %v", err)
+	}
+
+	writeDocs(src, map[string]string{
+		"index.md":   page("Home", "https://example.com/docs/", "sha256:a", "2024-02-01T00:00:00Z"),
+		"install.md": page("Install", "https://example.com/docs/install", "sha256:changed", "2024-02-01T00:00:00Z"),
+		"new.md":     page("New", "https://example.com/docs/new", "sha256:d", "2024-02-01T00:00:00Z"),
+	})
+	changes, err := New(FormatClaude).Update("example", src, out)
+	if err != nil {
+		t.Fatalf("Update() returned error: %v", err)
+	}
+	if got := changes.Summary(); got != "1 added, 1 modified, 1 removed, 1 unchanged" {
+		t.Errorf("Summary() = %q", got)
+	}
+	if len(changes.Removed) != 1 || changes.Removed[0].URL != "https://example.com/docs/old" {
+		t.Errorf("Removed = %+v", changes.Removed)
+	}
+	if len(changes.Sources) != 1 || changes.Sources[0].Status != SourceMoved || changes.Sources[0].URL != "https://example.com/docs/" {
+		t.Errorf("Sources = %+v, want the web source moved", changes.Sources)
+	}
+
+	skillDir := filepath.Join(out, "example")
+	tests := []struct {
+		path string
+		want string
+	}{
+		// Unchanged pages keep the files of the earlier generation
+		{"docs/index.md", "2024-01-01"},
+		{"docs/install.md", "2024-02-01"},
+		{"docs/new.md", "2024-02-01"},
+		{ChangesFile, "## Added\n\n- [New](docs/new.md) - https://example.com/docs/new\n"},
+		{ChangesFile, "## Removed\n\n- Old - https://example.com/docs/old\n"},
+		{ChangesFile, "## Sources\n\n- web https://example.com/docs/ moved (sha256:"},
+		{ManifestFile, `"url": "https://example.com/docs/"`},
+		{LockFile, `"kind": "web"`},
+		{LockFile, `"pages": 3`},
+		{"SKILL.md", "[New](docs/new.md)"},
+		{ManifestFile, `"badge": "stale"`},
+		{"SKILL.md", "Freshness: **stale** - 0% of 3 pages fetched within 30 days (newest 2024-02-01, oldest 2024-01-01)"},
+	}
+	for _, tt := range tests {
+		data, err := os.ReadFile(filepath.Join(skillDir, tt.path))
+		if err != nil {
+			t.Errorf("%s: %v", tt.path, err)
+			continue
+		}
+		if !strings.Contains(string(data), tt.want) {
+			t.Errorf("%s does not contain %q:\n%s", tt.path, tt.want, data)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(skillDir, "docs", "old.md")); !os.IsNotExist(err) {
+		t.Errorf("removed page still in docs/: %v", err)
+	}
+
+	// Fetching the same pages again doesn't move the source
+	writeDocs(src, map[string]string{
+		"index.md":   page("Home", "https://example.com/docs/", "sha256:a", "2024-03-01T00:00:00Z"),
+		"install.md": page("Install", "https://example.com/docs/install", "sha256:changed", "2024-03-01T00:00:00Z"),
+		"new.md":     page("New", "https://example.com/docs/new", "sha256:d", "2024-03-01T00:00:00Z"),
+	})
+	if changes, err = New(FormatClaude).Update("example", src, out); err != nil {
+		t.Fatalf("Update() returned error: %v", err)
+	}
+	if len(changes.Sources) != 0 {
+		t.Errorf("Sources = %+v after an update without changes, want none", changes.Sources)
+	}
+
+	// A full generation drops the changelog of the update
+	if err := New(FormatClaude).Generate("example", src, out); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(skillDir, ChangesFile)); !os.IsNotExist(err) {
+		t.Errorf("%s kept after Generate: %v", ChangesFile, err)
+	}
+}
+
+func TestMergeAssetMap(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	if err := mergeAssetMap(src, dst); err != nil {
+		t.Fatalf("mergeAssetMap() without maps returned error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(src, assets.MapFile)); !os.IsNotExist(err) {
+		t.Errorf("%s written without assets: %v", assets.MapFile, err)
+	}
+
+	// The update downloaded a new logo; the diagram of the other pages is kept
+	if err := assets.WriteMap(dst, map[string]string{"https://example.com/logo.png": "old.png", "https://example.com/diagram.svg": "diagram.svg"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := assets.WriteMap(src, map[string]string{"https://example.com/logo.png": "new.png"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := mergeAssetMap(src, dst); err != nil {
+		t.Fatalf("mergeAssetMap() returned error: %v", err)
+	}
+	got, err := assets.ReadMap(src)
+	want := map[string]string{"https://example.com/logo.png": "new.png", "https://example.com/diagram.svg": "diagram.svg"}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("merged map = %v, %v; want %v", got, err, want)
+	}
+}
+
+func TestLockDiff(t *testing.T) {
+	lock := func(sources ...LockedSource) *Lock {
+		return &Lock{Version: LockVersion, Sources: sources}
+	}
+	a := LockedSource{Kind: SourceWeb, URL: "https://a.example.com/", Revision: "sha256:1"}
+	a2 := LockedSource{Kind: SourceWeb, URL: "https://a.example.com/", Revision: "sha256:2"}
+	b := LockedSource{Kind: SourceWeb, URL: "https://b.example.com/", Revision: "sha256:3"}
+
+	tests := []struct {
+		name string
+		prev *Lock
+		next *Lock
+		want []string
+	}{
+		{"no previous lockfile", nil, lock(a), []string{"web https://a.example.com/ added (sha256:1)"}},
+		{"unchanged", lock(a, b), lock(a, b), nil},
+		{"moved", lock(a, b), lock(a2, b), []string{"web https://a.example.com/ moved (sha256:1 -> sha256:2)"}},
+		{"added and removed", lock(b), lock(a), []string{"web https://a.example.com/ added (sha256:1)", "web https://b.example.com/ removed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, c := range tt.prev.Diff(tt.next) {
+				got = append(got, c.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	long := "sha256:" + strings.Repeat("ab", 32)
+	if got := (SourceChange{Kind: SourceWeb, URL: "u", Status: SourceAdded, To: long}).String(); got != "web u added (sha256:abababababab)" {
+		t.Errorf("String() = %q, want the revision cut to 12 digits", got)
+	}
+}
+
+func TestHashDocuments(t *testing.T) {
+	writeSkill := func(docs map[string]string) string {
+		t.Helper()
+		skillDir := t.TempDir()
+		if err := os.MkdirAll(filepath.Join(skillDir, "docs"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		for name, content := range docs {
+			if err := os.WriteFile(filepath.Join(skillDir, "docs", name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return skillDir
+	}
+	hash := func(docs map[string]string) *Hashes {
+		t.Helper()
+		h, err := HashDocuments(writeSkill(docs))
+		if err != nil {
+			t.Fatalf("HashDocuments() returned error: %v", err)
+		}
+		return h
+	}
+
+	base := hash(map[string]string{
+		"install.md": "---\ntitle: Install\nfetched_at: \"2024-01-01T00:00:00Z\"\ncontent_hash: sha256:a\n---\n\nInstall body\n",
+		"auth.md":    "# Auth\n",
+	})
+	if len(base.Documents) != 2 || base.Documents[0].Path != "docs/auth.md" || !strings.HasPrefix(base.Documents[1].Hash, "sha256:") {
+		t.Fatalf("Documents = %+v, want both documents sorted by path", base.Documents)
+	}
+
+	tests := []struct {
+		name        string
+		docs        map[string]string
+		wantChanged []string
+	}{
+		{"fetched again", map[string]string{
+			"install.md": "---\ntitle: Install\nfetched_at: \"2024-02-01T00:00:00Z\"\ncontent_hash: sha256:a\n---\n\nInstall body\n",
+			"auth.md":    "# Auth\n",
+		}, nil},
+		{"body modified", map[string]string{
+			"install.md": "---\ntitle: Install\nfetched_at: \"2024-01-01T00:00:00Z\"\ncontent_hash: sha256:a\n---\n\nNew install body\n",
+			"auth.md":    "# Auth\n",
+		}, []string{"docs/install.md"}},
+		{"frontmatter modified", map[string]string{
+			"install.md": "---\ntitle: Install\nfetched_at: \"2024-01-01T00:00:00Z\"\ncontent_hash: sha256:b\n---\n\nInstall body\n",
+			"auth.md":    "# Auth\n",
+		}, []string{"docs/install.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := hash(tt.docs)
+			var changed []string
+			for i, d := range h.Documents {
+				if d.Hash != base.Documents[i].Hash {
+					changed = append(changed, d.Path)
+				}
+			}
+			if !reflect.DeepEqual(changed, tt.wantChanged) {
+				t.Errorf("changed documents = %v, want %v", changed, tt.wantChanged)
+			}
+			if (h.Corpus != base.Corpus) != (len(tt.wantChanged) > 0) {
+				t.Errorf("Corpus = %s, base %s, want a change only if a document changed", h.Corpus, base.Corpus)
+			}
+		})
+	}
+
+	renamed := hash(map[string]string{
+		"install.md": "---\ntitle: Install\nfetched_at: \"2024-01-01T00:00:00Z\"\ncontent_hash: sha256:a\n---\n\nInstall body\n",
+		"authn.md":   "# Auth\n",
+	})
+	if renamed.Corpus == base.Corpus {
+		t.Error("Corpus unchanged after a document was renamed")
+	}
+	if _, err := HashDocuments(t.TempDir()); err == nil {
+		t.Error("HashDocuments() of a directory without docs succeeded, want error")
+	}
+}
+
+func TestGenerateTimestamp(t *testing.T) {
+	src := t.TempDir()
+	page := "---\ntitle: Install\nsource_url: https://example.com/docs/install\nfetched_at: \"2024-01-01T00:00:00Z\"\n---\n\nInstall body\n"
+	if err := os.WriteFile(filepath.Join(src, "install.md"), []byte(page), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var outputs [][]string
+	for range 2 {
+		out := t.TempDir()
+		g := New(FormatClaude)
+		g.SetTimestamp(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+		if err := g.Generate("example", src, out); err != nil {
+			t.Fatalf("Generate() returned error: %v", err)
+		}
+		var files []string
+		for _, name := range []string{ManifestFile, "docs/.index/index.json"} {
+			data, err := os.ReadFile(filepath.Join(out, "example", filepath.FromSlash(name)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			files = append(files, string(data))
+		}
+		outputs = append(outputs, files)
+	}
+	if !reflect.DeepEqual(outputs[0], outputs[1]) {
+		t.Errorf("generations with a fixed timestamp differ:\n%v\n%v", outputs[0], outputs[1])
+	}
+	if !strings.Contains(outputs[0][0], `"computed_at": "2024-01-02T00:00:00Z"`) {
+		t.Errorf("manifest doesn't record the timestamp:\n%s", outputs[0][0])
+	}
+}
+
+func TestComputeFreshness(t *testing.T) {
+	now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	doc := func(url, fetchedAt string) Document {
+		return Document{Path: "docs/" + url + ".md", SourceURL: url, FetchedAt: fetchedAt}
+	}
+	tests := []struct {
+		name   string
+		docs   []Document
+		badge  string
+		recent float64
+		oldest string
+	}{
+		{
+			name:   "all recent",
+			docs:   []Document{doc("a", "2024-02-20T00:00:00Z"), doc("b", "2024-02-28T00:00:00Z")},
+			badge:  BadgeFresh,
+			recent: 1,
+			oldest: "2024-02-20T00:00:00Z",
+		},
+		{
+			name: "parts count once",
+			docs: []Document{
+				doc("a", "2024-02-20T00:00:00Z"), doc("a", "2024-02-20T00:00:00Z"), doc("a", "2024-02-20T00:00:00Z"),
+				doc("b", "2023-12-01T00:00:00Z"),
+			},
+			badge:  BadgeAging,
+			recent: 0.5,
+			oldest: "2023-12-01T00:00:00Z",
+		},
+		{
+			name:   "mostly old, unparsable ignored",
+			docs:   []Document{doc("a", "2024-02-20T00:00:00Z"), doc("b", "2023-01-01T00:00:00Z"), doc("c", "2023-06-01T00:00:00Z"), doc("d", "yesterday")},
+			badge:  BadgeStale,
+			recent: 1.0 / 3,
+			oldest: "2023-01-01T00:00:00Z",
+		},
+		{
+			name:   "fetched after now",
+			docs:   []Document{doc("a", "2024-03-01T00:05:00Z"), doc("b", "2024-02-28T00:00:00Z")},
+			badge:  BadgeFresh,
+			recent: 1,
+			oldest: "2024-02-28T00:00:00Z",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := computeFreshness(tt.docs, now)
+			if f == nil {
+				t.Fatal("computeFreshness() returned nil")
+			}
+			if f.Badge != tt.badge || f.Recent != tt.recent || f.Oldest != tt.oldest {
+				t.Errorf("got badge %s, recent %v, oldest %s; want %s, %v, %s", f.Badge, f.Recent, f.Oldest, tt.badge, tt.recent, tt.oldest)
+			}
+		})
+	}
+
+	if f := computeFreshness([]Document{doc("a", "")}, now); f != nil {
+		t.Errorf("computeFreshness() without fetch times = %+v, want nil", f)
+	}
+}
+
+func TestWriteLLMsTxt(t *testing.T) {
+	src := t.TempDir()
+	docs := map[string]string{
+		"index.md":   "---\ntitle: Example Docs\ndescription: Everything about Example.\nsource_url: https://example.com/docs/\n---\n\n# Example Docs\n\nWelcome. Start with [the install guide](install.md#steps).\n",
+		"install.md": "---\ntitle: Install\ndescription: Install the CLI.\nsource_url: https://example.com/docs/install\n---\n\n# Install\n\n## Steps\n\nRun it.\n",
+		"auth.md":    "---\ntitle: Authentication\nsource_url: https://example.com/docs/api/auth\n---\n\nSign requests with [keys](missing.md).\n",
+		"admin.md":   "---\ntitle: Admin\nsource_url: https://example.com/docs/api/admin\naccess: internal\n---\n\nInternal only.\n",
+	}
+	for name, content := range docs {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := t.TempDir()
+	gen := New(FormatClaude)
+	gen.SetSourceURL("https://example.com/docs/")
+	if err := gen.Generate("example", src, out); err != nil {
+		t.Fatalf("Generate() returned error: %v", err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "site")
+	n, err := WriteLLMsTxt(filepath.Join(out, "example"), dir)
+	if err != nil {
+		t.Fatalf("WriteLLMsTxt() returned error: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("WriteLLMsTxt() included %d documents, want 3", n)
+	}
+
+	index, err := os.ReadFile(filepath.Join(dir, LLMsTxtFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantIndex := `# Example Docs
+
+> Everything about Example.
+
+The documentation of https://example.com/docs/, converted to Markdown.
+
+## Docs
+
+- [Example Docs](https://example.com/docs/): Everything about Example.
+- [Install](https://example.com/docs/install): Install the CLI.
+
+## api
+
+- [Authentication](https://example.com/docs/api/auth)
+`
+	if string(index) != wantIndex {
+		t.Errorf("llms.txt =\n%s\nwant\n%s", index, wantIndex)
+	}
+
+	full, err := os.ReadFile(filepath.Join(dir, LLMsFullTxtFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFull := `# Example Docs
+Source: https://example.com/docs/
+
+Welcome. Start with [the install guide](https://example.com/docs/install#steps).
+
+# Install
+Source: https://example.com/docs/install
+
+## Steps
+
+Run it.
+
+# Authentication
+Source: https://example.com/docs/api/auth
+
+Sign requests with [keys](missing.md).
+`
+	if string(full) != wantFull {
+		t.Errorf("llms-full.txt =\n%s\nwant\n%s", full, wantFull)
+	}
+}
+
+func TestWriteBundle(t *testing.T) {
+	src := t.TempDir()
+	docs := map[string]string{
+		"index.md": "---\ntitle: Example Docs\nsource_url: https://example.com/docs/\nword_count: 4\n---\n\n# Example Docs\n\nSee [auth](auth.md) & more.\n",
+		"auth.md":  "---\ntitle: Authentication\nsource_url: https://example.com/docs/api/auth\naccess: internal\n---\n\nUse <keys>.\n",
+	}
+	for name, content := range docs {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := t.TempDir()
+	gen := New(FormatClaude)
+	gen.SetSourceURL("https://example.com/docs/")
+	if err := gen.Generate("example", src, out); err != nil {
+		t.Fatalf("Generate() returned error: %v", err)
+	}
+	skillDir := filepath.Join(out, "example")
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{BundleMarkdown, `# Example Docs
+Source: https://example.com/docs/
+
+See [auth](https://example.com/docs/api/auth) & more.
+
+# Authentication
+Source: https://example.com/docs/api/auth
+
+Use <keys>.
+`},
+		{BundleJSONL, `{"content":"# Example Docs\n\nSee [auth](auth.md) & more.\n","path":"docs/index.md","section":"","source_url":"https://example.com/docs/","title":"Example Docs","word_count":4}
+{"access":"internal","content":"Use <keys>.\n","path":"docs/auth.md","section":"api","source_url":"https://example.com/docs/api/auth","title":"Authentication"}
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var b strings.Builder
+			n, err := WriteBundle(skillDir, &b, tt.format)
+			if err != nil {
+				t.Fatalf("WriteBundle() returned error: %v", err)
+			}
+			if n != 2 || b.String() != tt.want {
+				t.Errorf("WriteBundle() = %d documents\n%s\nwant 2 documents\n%s", n, b.String(), tt.want)
+			}
+		})
+	}
+
+	if _, err := WriteBundle(skillDir, io.Discard, "csv"); err == nil {
+		t.Error("WriteBundle() accepted an unknown format")
+	}
+}
+
+func TestGenerateLocales(t *testing.T) {
+	outputBase := t.TempDir()
+	skillDir := filepath.Join(outputBase, "example")
+	manifests := map[string]*Manifest{
+		"en": {Name: "en", Documents: []Document{
+			{Path: "docs/guide.md", Title: "Guide (part 1 of 2)", Part: 1},
+			{Path: "docs/guide-part-2.md", Title: "Guide (part 2 of 2)", Part: 2},
+			{Path: "docs/index.md", Title: "Home"},
+		}},
+		"ja": {Name: "ja", Documents: []Document{
+			{Path: "docs/index.md", Title: "ホーム"},
+		}},
+	}
+	for locale, m := range manifests {
+		if err := os.MkdirAll(filepath.Join(skillDir, locale), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := m.write(filepath.Join(skillDir, locale)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gen := New(FormatClaude)
+	index, err := gen.GenerateLocales("example", []string{"ja", "en"}, outputBase)
+	if err != nil {
+		t.Fatalf("GenerateLocales() returned error: %v", err)
+	}
+	want := []LocalePage{
+		{Path: "docs/guide.md", Title: "Guide", Files: map[string][]string{"en": {"en/docs/guide-part-2.md", "en/docs/guide.md"}}},
+		{Path: "docs/index.md", Title: "ホーム", Files: map[string][]string{"ja": {"ja/docs/index.md"}, "en": {"en/docs/index.md"}}},
+	}
+	if !reflect.DeepEqual(index.Pages, want) {
+		t.Errorf("Pages = %+v, want %+v", index.Pages, want)
+	}
+	if _, err := os.Stat(filepath.Join(skillDir, LocalesFile)); err != nil {
+		t.Errorf("%s missing: %v", LocalesFile, err)
+	}
+	md, err := os.ReadFile(filepath.Join(skillDir, "SKILL.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"name: example\n", "- `ja/`: 1 page (", "- `en/`: 2 pages", "--skill-dir ja"} {
+		if !strings.Contains(string(md), want) {
+			t.Errorf("SKILL.md doesn't contain %q:\n%s", want, md)
+		}
+	}
+
+	if _, err := gen.GenerateLocales("example", []string{"fr"}, outputBase); err == nil {
+		t.Error("GenerateLocales() with a locale without a manifest returned no error")
+	}
+}
+
+func TestExtractTerms(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "definition list",
+			body: "# Terms\n\nToken\n: A credential sent with requests.\n\n    Tokens expire.\n\nScope\n: What a token grants.\n",
+			want: []string{"Token: A credential sent with requests.", "Scope: What a token grants."},
+		},
+		{
+			name: "terms sharing a definition",
+			body: "Region\nZone\n: Where resources run.\n",
+			want: []string{"Region: Where resources run.", "Zone: Where resources run."},
+		},
+		{
+			name: "defining sentences",
+			body: "A **widget** is a reusable\ncomponent. It renders.\n\n`max_retries` sets the number of retries.\n\nSee **widget** is not a definition here.\n",
+			want: []string{"widget: A widget is a reusable component.", "max_retries: max_retries sets the number of retries."},
+		},
+		{
+			name: "code blocks are skipped",
+			body: "```yaml\nkey\n: value\n```\n\n**Note** this isn't defining.\n",
+			want: nil,
+		},
+		{
+			name: "long terms are skipped",
+			body: "**this bold span has far too many words to be a term** is emphasized.\n",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, term := range extractTerms(tt.body) {
+				got = append(got, term.term+": "+term.definition)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractTerms() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateGlossary(t *testing.T) {
+	src := t.TempDir()
+	docs := map[string]string{
+		"widgets.md": "---\ntitle: Widgets\nsource_url: https://example.com/docs/widgets\n---\n\nA **Widget** is a reusable component.\n\n```go\nfunc NewWidget() *Widget\n```\n",
+		"terms.md":   "---\ntitle: Terms\nsource_url: https://example.com/docs/terms\naccess: internal\n---\n\nGadget\n: A widget with state.\n\nwidget\n: Defined again.\n",
+	}
+	for name, content := range docs {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := t.TempDir()
+	if err := New(FormatClaude).Generate("example", src, out); err != nil {
+		t.Fatalf("Generate() returned error: %v", err)
+	}
+	skillDir := filepath.Join(out, "example")
+
+	glossary, err := os.ReadFile(filepath.Join(skillDir, GlossaryFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `# Glossary
+
+Terms defined in the example documentation, with the document defining each.
+
+- **Gadget**: A widget with state. ([Terms](docs/terms.md)) _(internal)_
+- **widget**: Defined again. ([Terms](docs/terms.md)) _(internal)_
+`
+	if string(glossary) != want {
+		t.Errorf("%s =\n%s\nwant\n%s", GlossaryFile, glossary, want)
+	}
+
+	skillMD, err := os.ReadFile(filepath.Join(skillDir, "SKILL.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(skillMD), GlossaryFile) {
+		t.Errorf("SKILL.md doesn't mention %s:\n%s", GlossaryFile, skillMD)
+	}
+	if _, err := os.Stat(filepath.Join(skillDir, "symbols.json")); err != nil {
+		t.Errorf("symbols.json not written: %v", err)
+	}
+}
+
+func TestExtractQuestions(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "question headings",
+			body: "# FAQ\n\n## Billing\n\n### Is it free?\n\nYes.\n\n#### Limits\n\nTen projects.\n\n### Can I cancel?\n\nAt any time.\n\n## Accounts\n",
+			want: []string{"Is it free? => Yes.\n\n#### Limits\n\nTen projects.", "Can I cancel? => At any time."},
+		},
+		{
+			name: "nested questions",
+			body: "## Why Go?\n\nSpeed.\n\n### Why not Rust?\n\nTaste.\n",
+			want: []string{"Why Go? => Speed.", "Why not Rust? => Taste."},
+		},
+		{
+			name: "bold questions and definition lists",
+			body: "**Where are my keys?**\n\nIn the settings.\n\n**Who can see them?**\n\nAdmins.\n\nDo keys expire?\n: After a year.\n\n    Rotate them sooner.\n\nToken\n: Not a question.\n",
+			want: []string{"Where are my keys? => In the settings.", "Who can see them? => Admins.", "Do keys expire? => After a year.\n\nRotate them sooner."},
+		},
+		{
+			name: "code blocks are skipped",
+			body: "```md\n## Is this a question?\n```\n",
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, e := range extractQuestions(tt.body) {
+				got = append(got, e.question+" => "+e.answer)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractQuestions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractReleases(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{
+			name: "keep a changelog",
+			body: "# Changelog\n\n## [Unreleased]\n\n## [1.2.0] - 2024-03-01\n\n### Added\n\n- Retries.\n\n## [1.1.0] - 2024-01-15\n\n- Fixes.\n",
+			want: []string{"1.2.0 2024-03-01 ### Added\n\n- Retries.", "1.1.0 2024-01-15 - Fixes."},
+		},
+		{
+			name: "dates under the headings",
+			body: "### v2.0.0-beta.1\n\n_March 3rd, 2024_\n\nBreaking changes.\n\n#### Upgrading from 1.9\n\nRun the migration.\n\n### v1.9\n\nReleased sometime.\n",
+			want: []string{"2.0.0-beta.1 2024-03-03 Breaking changes.\n\n### Upgrading from 1.9\n\nRun the migration.", "1.9  Released sometime."},
+		},
+		{
+			name: "releases nested under years",
+			body: "## 2024\n\n### 3.1.0 (1 February 2024)\n\nFaster.\n\n## 2023\n\n### 3.0.0 (Dec 5, 2023)\n\nNew API.\n",
+			want: []string{"3.1.0 2024-02-01 Faster.", "3.0.0 2023-12-05 New API."},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, r := range extractReleases(tt.body) {
+				got = append(got, r.version+" "+r.date+" "+r.body)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("extractReleases() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	versions := []string{"1.0.0", "1.0.0-alpha", "1.10.0", "1.0.0-beta.11", "1.0.0-beta.2", "1.2", "1.0.0-alpha.1", "1.0.0-rc.1", "1.0.0-beta"}
+	slices.SortFunc(versions, compareVersions)
+	want := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.2", "1.10.0"}
+	if !reflect.DeepEqual(versions, want) {
+		t.Errorf("sorted versions = %q, want %q", versions, want)
+	}
+}
+
+func TestGenerateFAQAndChangelog(t *testing.T) {
+	src := t.TempDir()
+	docs := map[string]string{
+		"faq.md":       "---\ntitle: FAQ\nsource_url: https://example.com/docs/faq\nkind: faq\n---\n\n# FAQ\n\n## Is it free?\n\nYes.\n",
+		"support.md":   "---\ntitle: Support\nsource_url: https://example.com/docs/support\nkind: faq\naccess: internal\n---\n\n## Is it free?\n\nAsk sales.\n\n## Who do I call?\n\nThe on-call engineer.\n",
+		"guide.md":     "---\ntitle: Guide\nsource_url: https://example.com/docs/guide\n---\n\n## Why Go?\n\nNot a FAQ page.\n\n## 9.9.9\n\nNot a changelog.\n",
+		"changelog.md": "---\ntitle: Changelog\nsource_url: https://example.com/docs/changelog\nkind: changelog\n---\n\n## v1.10.0 - 2024-05-01\n\nNew flags.\n\n## v1.9.0 - 2024-04-01\n\nFixes.\n",
+		"releases.md":  "---\ntitle: Releases\nsource_url: https://example.com/docs/releases\nkind: changelog\n---\n\n## 2.0.0-rc.1\n\nCandidate.\n\n## 1.9.0\n\nDuplicate.\n",
+	}
+	for name, content := range docs {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := t.TempDir()
+	if err := New(FormatClaude).Generate("example", src, out); err != nil {
+		t.Fatalf("Generate() returned error: %v", err)
+	}
+	skillDir := filepath.Join(out, "example")
+
+	faq, err := os.ReadFile(filepath.Join(skillDir, FAQFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantFAQ := `# Frequently Asked Questions
+
+Questions answered by the FAQ pages of the example documentation, under the page answering each.
+
+## [FAQ](docs/faq.md)
+
+### Is it free?
+
+Yes.
+
+## [Support](docs/support.md) _(internal)_
+
+### Who do I call?
+
+The on-call engineer.
+`
+	if string(faq) != wantFAQ {
+		t.Errorf("%s =\n%s\nwant\n%s", FAQFile, faq, wantFAQ)
+	}
+
+	changelog, err := os.ReadFile(filepath.Join(skillDir, ChangelogFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantChangelog := `# Changelog
+
+Releases described by the changelog pages of the example documentation, newest first, with the page describing each.
+
+## 2.0.0-rc.1
+
+Candidate.
+
+_From [Releases](docs/releases.md)_
+
+## 1.10.0 (2024-05-01)
+
+New flags.
+
+_From [Changelog](docs/changelog.md)_
+
+## 1.9.0 (2024-04-01)
+
+Fixes.
+
+_From [Changelog](docs/changelog.md)_
+`
+	if string(changelog) != wantChangelog {
+		t.Errorf("%s =\n%s\nwant\n%s", ChangelogFile, changelog, wantChangelog)
+	}
+
+	skillMD, err := os.ReadFile(filepath.Join(skillDir, "SKILL.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{FAQFile, ChangelogFile} {
+		if !strings.Contains(string(skillMD), "`"+file+"`") {
+			t.Errorf("SKILL.md doesn't mention %s:\n%s", file, skillMD)
+		}
+	}
+
+	// Without FAQ and changelog pages, the files of an earlier generation are removed
+	for _, name := range []string{"faq.md", "support.md", "changelog.md", "releases.md"} {
+		for _, dir := range []string{src, filepath.Join(skillDir, "docs")} {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := New(FormatClaude).Generate("example", src, out); err != nil {
+		t.Fatalf("Generate() returned error: %v", err)
+	}
+	for _, file := range []string{FAQFile, ChangelogFile} {
+		if _, err := os.Stat(filepath.Join(skillDir, file)); !os.IsNotExist(err) {
+			t.Errorf("%s left behind: %v", file, err)
+		}
+	}
+}
+
+func TestGenerateGraphAndTOC(t *testing.T) {
+	src := t.TempDir()
+	docs := map[string]string{
+		"index.md":   "---\ntitle: Example Docs\nsource_url: https://example.com/docs/\n---\n\n# Example Docs\n\nStart with [the install guide](install.md#steps) or [the API](api.md).\n",
+		"install.md": "---\ntitle: Install\nsource_url: https://example.com/docs/install\n---\n\n# Install\n\n## Steps\n\nThen [sign requests](auth.md) and [sign again](auth.md).\n\n```md\n[Code](admin.md)\n```\n",
+		"api.md":     "---\ntitle: API\nsource_url: https://example.com/docs/api/\nbreadcrumbs:\n  - Home\n  - API\n---\n\n# API\n\nSee [Authentication](auth.md).\n",
+		"auth.md":    "---\ntitle: Authentication | Example\nsource_url: https://example.com/docs/api/auth\nbreadcrumbs:\n  - Home\n  - API\n  - Authentication\npart: 1\n---\n\n# Authentication\n\nNext: [part 2](auth-part-2.md).\n",
+		"auth-part-2.md": "---\ntitle: Authentication | Example\nsource_url: https://example.com/docs/api/auth\nbreadcrumbs:\n  - Home\n  - API\n  - Authentication\npart: 2\n---\n\n" +
+			"Previous: [part 1](auth.md).\n",
+		"admin.md": "---\ntitle: Admin\nsource_url: https://example.com/docs/ops/admin\n---\n\n# Admin\n\nBack to [install](install.md).\n",
+	}
+	for name, content := range docs {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := t.TempDir()
+	if err := New(FormatClaude).Generate("example", src, out); err != nil {
+		t.Fatalf("Generate() returned error: %v", err)
+	}
+	skillDir := filepath.Join(out, "example")
+
+	data, err := os.ReadFile(filepath.Join(skillDir, GraphFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var graph Graph
+	if err := json.Unmarshal(data, &graph); err != nil {
+		t.Fatalf("failed to parse %s: %v", GraphFile, err)
+	}
+	wantEdges := []GraphEdge{
+		{From: "docs/index.md", To: "docs/install.md", Count: 1},
+		{From: "docs/index.md", To: "docs/api.md", Count: 1},
+		{From: "docs/install.md", To: "docs/auth.md", Count: 2},
+		{From: "docs/api.md", To: "docs/auth.md", Count: 1},
+		{From: "docs/admin.md", To: "docs/install.md", Count: 1},
+	}
+	if !reflect.DeepEqual(graph.Edges, wantEdges) {
+		t.Errorf("edges = %+v, want %+v", graph.Edges, wantEdges)
+	}
+	if want := []string{"docs/admin.md"}; !reflect.DeepEqual(graph.Orphans, want) {
+		t.Errorf("orphans = %q, want %q", graph.Orphans, want)
+	}
+	for _, node := range graph.Nodes {
+		if node.Path == "docs/auth.md" && (node.Links != 0 || node.Backlinks != 2) {
+			t.Errorf("node %s has %d links and %d backlinks, want 0 and 2", node.Path, node.Links, node.Backlinks)
+		}
+	}
+
+	m, err := ReadManifest(skillDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, doc := range m.Documents {
+		if doc.Path != "docs/install.md" {
+			continue
+		}
+		wantHeadings := []HeadingLink{
+			{Level: 1, Title: "Install", Anchor: "install", Link: "docs/install.md#install"},
+			{Level: 2, Title: "Steps", Anchor: "steps", Link: "docs/install.md#steps"},
+		}
+		if !reflect.DeepEqual(doc.Headings, wantHeadings) {
+			t.Errorf("headings of %s = %+v, want %+v", doc.Path, doc.Headings, wantHeadings)
+		}
+	}
+
+	toc, err := os.ReadFile(filepath.Join(skillDir, TOCFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Table of Contents\n\n" +
+		"The documents of the example documentation, by their place in the site. `graph.json` records the links between them.\n\n" +
+		"- [Example Docs](docs/index.md)\n" +
+		"- [Install](docs/install.md)\n" +
+		"  - [Steps](docs/install.md#steps)\n" +
+		"- [API](docs/api.md)\n" +
+		"  - [Authentication | Example](docs/auth.md)\n" +
+		"  - [Authentication | Example](docs/auth-part-2.md)\n" +
+		"- ops\n" +
+		"  - [Admin](docs/admin.md)\n" +
+		"\n## Unlinked Pages\n\n" +
+		"No other page links to these pages, which only this table of contents and searches lead to.\n\n" +
+		"- [Admin](docs/admin.md)\n"
+	if string(toc) != want {
+		t.Errorf("%s =\n%s\nwant\n%s", TOCFile, toc, want)
+	}
+}
+
+func TestNavigationOrder(t *testing.T) {
+	// The sidebar lists Quickstart before Authentication and Errors, unlike
+	// the URLs; the FAQ isn't in the sidebar
+	src := t.TempDir()
+	docs := map[string]string{
+		"index.md":      "---\ntitle: Overview\nsource_url: https://example.com/docs/\nnav:\n  - Overview\nnav_position: [1, 1, 1]\n---\n\n# Overview\n",
+		"quickstart.md": "---\ntitle: Quickstart\nsource_url: https://example.com/docs/quickstart\nnav:\n  - Quickstart\nnav_position: [1, 2, 1]\n---\n\n# Quickstart\n",
+		"errors.md":     "---\ntitle: Errors\nsource_url: https://example.com/docs/errors\nnav:\n  - API\n  - Errors\nnav_position: [1, 3, 2, 2, 1]\n---\n\n# Errors\n",
+		"auth.md":       "---\ntitle: Authentication\nsource_url: https://example.com/docs/auth\nnav:\n  - API\n  - Authentication\nnav_position: [1, 3, 2, 1, 1]\n---\n\n# Authentication\n",
+		"faq.md":        "---\ntitle: FAQ\nsource_url: https://example.com/docs/faq\n---\n\n# FAQ\n",
+	}
+	for name, content := range docs {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := t.TempDir()
+	if err := New(FormatClaude).Generate("example", src, out); err != nil {
+		t.Fatalf("Generate() returned error: %v", err)
+	}
+	skillDir := filepath.Join(out, "example")
+
+	m, err := ReadManifest(skillDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, doc := range m.Documents {
+		paths = append(paths, doc.Path)
+	}
+	if want := []string{"docs/index.md", "docs/quickstart.md", "docs/auth.md", "docs/errors.md", "docs/faq.md"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("manifest documents = %q, want %q", paths, want)
+	}
+
+	toc, err := os.ReadFile(filepath.Join(skillDir, TOCFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "- [Overview](docs/index.md)\n" +
+		"- [Quickstart](docs/quickstart.md)\n" +
+		"- [FAQ](docs/faq.md)\n" +
+		"- API\n" +
+		"  - [Authentication](docs/auth.md)\n" +
+		"  - [Errors](docs/errors.md)\n"
+	if !strings.Contains(string(toc), want) {
+		t.Errorf("%s =\n%s\nwant the documents nested as\n%s", TOCFile, toc, want)
+	}
+}
+
+func TestDiffSkills(t *testing.T) {
+	generate := func(docs map[string]string) string {
+		t.Helper()
+		src := t.TempDir()
+		for name, content := range docs {
+			if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		out := t.TempDir()
+		if err := New(FormatClaude).Generate("example", src, out); err != nil {
+			t.Fatalf("Generate() returned error: %v", err)
+		}
+		return filepath.Join(out, "example")
+	}
+	oldDir := generate(map[string]string{
+		"index.md":   "---\ntitle: Home\nsource_url: https://example.com/docs/\nfetched_at: \"2024-01-01T00:00:00Z\"\ncontent_hash: sha256:a\n---\n\nWelcome\n",
+		"install.md": "---\ntitle: Install\nsource_url: https://example.com/docs/install\ncontent_hash: sha256:b\n---\n\nRun make.\n",
+		"legacy.md":  "---\ntitle: Legacy\nsource_url: https://example.com/docs/legacy\ncontent_hash: sha256:c\n---\n\nOld\n",
+	})
+	newDir := generate(map[string]string{
+		"index.md":   "---\ntitle: Home\nsource_url: https://example.com/docs/\nfetched_at: \"2024-02-01T00:00:00Z\"\ncontent_hash: sha256:a\n---\n\nWelcome\n",
+		"install.md": "---\ntitle: Installation\nsource_url: https://example.com/docs/install\ncontent_hash: sha256:d\n---\n\nRun make install.\n",
+		"api.md":     "---\ntitle: API\nsource_url: https://example.com/docs/api\ncontent_hash: sha256:e\n---\n\nEndpoints\n",
+	})
+
+	d, err := DiffSkills(oldDir, newDir, true)
+	if err != nil {
+		t.Fatalf("DiffSkills() returned error: %v", err)
+	}
+	if got := d.Summary(); got != "1 added, 1 modified, 1 removed, 1 unchanged" {
+		t.Fatalf("Summary() = %s, want the home page unchanged despite its fetched_at", got)
+	}
+	if a := d.Added[0]; a.Path != "docs/api.md" || a.Title != "API" || a.URL != "https://example.com/docs/api" || a.OldHash != "" ||
+		!strings.HasPrefix(a.Patch, "--- /dev/null\n+++ b/docs/api.md\n") {
+		t.Errorf("Added = %+v", a)
+	}
+	if r := d.Removed[0]; r.Path != "docs/legacy.md" || r.Title != "Legacy" || r.NewHash != "" ||
+		!strings.HasPrefix(r.Patch, "--- a/docs/legacy.md\n+++ /dev/null\n") {
+		t.Errorf("Removed = %+v", r)
+	}
+	m := d.Modified[0]
+	if m.Path != "docs/install.md" || m.Title != "Installation" || m.OldHash == m.NewHash {
+		t.Errorf("Modified = %+v", m)
+	}
+	for _, want := range []string{"-title: Install\n+title: Installation\n", "-Run make.\n+Run make install.\n"} {
+		if !strings.Contains(m.Patch, want) {
+			t.Errorf("patch of install.md doesn't contain %q:\n%s", want, m.Patch)
+		}
+	}
+	if len(d.Sources) != 1 || d.Sources[0].Status != SourceMoved {
+		t.Errorf("Sources = %+v, want the site moved", d.Sources)
+	}
+
+	same, err := DiffSkills(oldDir, oldDir, false)
+	if err != nil {
+		t.Fatalf("DiffSkills() returned error: %v", err)
+	}
+	if same.Changed() || same.Unchanged != 3 {
+		t.Errorf("DiffSkills() of a skill with itself = %+v, want no change", same)
+	}
+
+	if _, err := DiffSkills(oldDir, filepath.Join(t.TempDir(), "missing"), false); err == nil {
+		t.Error("DiffSkills() of a missing skill returned no error")
+	}
+}
+
+func TestGenerateKeepsUnchangedFiles(t *testing.T) {
+	src := t.TempDir()
+	docs := map[string]string{
+		"index.md":   "---\ntitle: Home\nsource_url: https://example.com/docs/\n---\n\nWelcome. See [Install](install.md).\n",
+		"install.md": "---\ntitle: Install\nsource_url: https://example.com/docs/install\n---\n\nRun make.\n",
+	}
+	for name, content := range docs {
+		if err := os.WriteFile(filepath.Join(src, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out := t.TempDir()
+	if err := New(FormatClaude).Generate("example", src, out); err != nil {
+		t.Fatalf("Generate() returned error: %v", err)
+	}
+
+	skillDir := filepath.Join(out, "example")
+	old := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	files := []string{"docs/index.md", "docs/install.md", GraphFile, TOCFile, AnchorsFile}
+	for _, f := range files {
+		if err := os.Chtimes(filepath.Join(skillDir, f), old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Generating the skill again from a changed page rewrites only that page
+	if err := os.WriteFile(filepath.Join(src, "install.md"), []byte("---\ntitle: Install\nsource_url: https://example.com/docs/install\n---\n\nRun make install.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := New(FormatClaude).Generate("example", src, out); err != nil {
+		t.Fatalf("Generate() returned error: %v", err)
+	}
+	for _, f := range files {
+		info, err := os.Stat(filepath.Join(skillDir, f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kept, want := info.ModTime().Equal(old), f != "docs/install.md"; kept != want {
+			t.Errorf("%s modification time kept = %v, want %v", f, kept, want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Package skillgen provides skill structure generation functionality.
+// This file implements the token and size statistics of a generated skill.
+package skillgen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+	"github.com/f4ah6o/site2skill-go/internal/chunker"
+	"github.com/f4ah6o/site2skill-go/internal/search"
+)
+
+const (
+	// StatsFile is the name of the statistics report written at the root of a skill.
+	StatsFile = "stats.json"
+	// largestDocuments is the number of documents listed in Stats.Largest.
+	largestDocuments = 10
+)
+
+// Stats reports the estimated tokens and bytes of every file in a skill, to
+// check that the skill fits an agent's context budget. It is written as stats.json.
+type Stats struct {
+	// Skill is the skill name.
+	Skill string `json:"skill"`
+	// Tokens is the total estimated tokens of all text files.
+	Tokens int `json:"tokens"`
+	// Bytes is the total size of all files.
+	Bytes int64 `json:"bytes"`
+	// DocTokens is the total estimated tokens of the documents in docs/.
+	DocTokens int `json:"doc_tokens"`
+	// DocBytes is the total size of the documents in docs/.
+	DocBytes int64 `json:"doc_bytes"`
+	// Largest lists the documents with the most tokens, largest first.
+	Largest []FileStats `json:"largest"`
+	// Files lists every file of the skill, sorted by path.
+	Files []FileStats `json:"files"`
+}
+
+// FileStats is the size of one file of a skill.
+type FileStats struct {
+	// Path is the file path relative to the skill directory, with forward slashes.
+	Path string `json:"path"`
+	// Tokens is the estimated number of tokens, 0 for binary files such as images.
+	Tokens int `json:"tokens"`
+	// Bytes is the file size.
+	Bytes int64 `json:"bytes"`
+}
+
+// ComputeStats measures every file in skillDir except stats.json itself and
+// the search index and embeddings, which agents never read, counting tokens with t (chunker.Estimator if nil). Files that are not valid
+// UTF-8 text, such as images, count towards bytes only.
+//
+// Returns an error if the directory can't be walked or a file can't be read.
+func ComputeStats(skillDir string, t chunker.Tokenizer) (*Stats, error) {
+	if t == nil {
+		t = chunker.Estimator{}
+	}
+	stats := &Stats{Skill: filepath.Base(skillDir), Largest: []FileStats{}, Files: []FileStats{}}
+	err := filepath.WalkDir(skillDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && (d.Name() == search.IndexDir || d.Name() == search.EmbeddingsDir) {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(skillDir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == StatsFile {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", rel, err)
+		}
+
+		file := FileStats{Path: rel, Bytes: int64(len(content))}
+		if utf8.Valid(content) && !strings.ContainsRune(string(content), 0) {
+			file.Tokens = t.CountTokens(string(content))
+		}
+		stats.Files = append(stats.Files, file)
+		stats.Tokens += file.Tokens
+		stats.Bytes += file.Bytes
+		if strings.HasPrefix(rel, "docs/") && strings.HasSuffix(rel, ".md") {
+			stats.DocTokens += file.Tokens
+			stats.DocBytes += file.Bytes
+			stats.Largest = append(stats.Largest, file)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(stats.Largest, func(i, j int) bool {
+		return stats.Largest[i].Tokens > stats.Largest[j].Tokens
+	})
+	if len(stats.Largest) > largestDocuments {
+		stats.Largest = stats.Largest[:largestDocuments]
+	}
+	return stats, nil
+}
+
+// WriteStats measures skillDir (see ComputeStats) and saves the result as
+// stats.json in it.
+func WriteStats(skillDir string, t chunker.Tokenizer) (*Stats, error) {
+	stats, err := ComputeStats(skillDir, t)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := atomicfile.WriteFile(filepath.Join(skillDir, StatsFile), append(data, '\n')); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", StatsFile, err)
+	}
+	return stats, nil
+}
+
+// Summary returns a human-readable report of the totals and largest documents.
+func (s *Stats) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Skill %s: %d files, %s, ~%d tokens\n", s.Skill, len(s.Files), formatBytes(s.Bytes), s.Tokens)
+	fmt.Fprintf(&b, "Documents: %s, ~%d tokens\n", formatBytes(s.DocBytes), s.DocTokens)
+	if len(s.Largest) > 0 {
+		fmt.Fprintf(&b, "Largest documents:\n")
+		for i, f := range s.Largest {
+			fmt.Fprintf(&b, "  %2d. ~%d tokens, %s - %s\n", i+1, f.Tokens, formatBytes(f.Bytes), f.Path)
+		}
+	}
+	return b.String()
+}
+
+// formatBytes formats a size in bytes, KB, or MB.
+func formatBytes(n int64) string {
+	switch {
+	case n >= 1024*1024:
+		return fmt.Sprintf("%.2f MB", float64(n)/(1024*1024))
+	case n >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(n)/1024)
+	}
+	return fmt.Sprintf("%d B", n)
+}
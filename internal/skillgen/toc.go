@@ -0,0 +1,163 @@
+// Package skillgen provides skill structure generation functionality.
+// This file implements toc.md, the table of contents of every document of a
+// skill nested by its place in the site, with the pages nothing links to.
+package skillgen
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+)
+
+// TOCFile is the name of the table of contents written at the root of a skill.
+const TOCFile = "toc.md"
+
+// tocNode is an entry of the hierarchy of toc.md: a breadcrumb or URL
+// directory, with the documents placed right under it and its subentries.
+type tocNode struct {
+	label    string
+	docs     []Document
+	children []*tocNode
+}
+
+// child returns the subentry of n labeled label, adding it if n has none.
+func (n *tocNode) child(label string) *tocNode {
+	for _, c := range n.children {
+		if strings.EqualFold(c.label, label) {
+			return c
+		}
+	}
+	c := &tocNode{label: label}
+	n.children = append(n.children, c)
+	return c
+}
+
+// writeTOC writes toc.md in skillDir: every document of the manifest, as a
+// link with its title followed by the deep links to its H2 sections, nested by its place in the site (see tocTrails) in the
+// order of the site's sidebar navigation, the pages missing from it in the
+// order of the manifest, followed by the pages no other page links to, the
+// orphans of g.
+func writeTOC(skillDir string, m *Manifest, g *Graph) error {
+	docs := slices.Clone(m.Documents)
+	sort.SliceStable(docs, func(i, j int) bool {
+		return comparePositions(docs[i].NavPosition, docs[j].NavPosition) < 0
+	})
+	root := &tocNode{}
+	trails := tocTrails(docs)
+	for i, doc := range docs {
+		n := root
+		for _, label := range trails[i] {
+			n = n.child(label)
+		}
+		n.docs = append(n.docs, doc)
+	}
+
+	var b strings.Builder
+	b.WriteString("# Table of Contents\n\n")
+	fmt.Fprintf(&b, "The documents of the %s documentation, by their place in the site. `%s` records the links between them.\n\n", m.Name, GraphFile)
+	writeTOCNode(&b, root, 0)
+
+	if len(g.Orphans) > 0 {
+		titles := make(map[string]string, len(m.Documents))
+		for _, doc := range m.Documents {
+			titles[doc.Path] = doc.Title
+		}
+		b.WriteString("\n## Unlinked Pages\n\n")
+		b.WriteString("No other page links to these pages, which only this table of contents and searches lead to.\n\n")
+		for _, p := range g.Orphans {
+			fmt.Fprintf(&b, "- [%s](%s)\n", oneLine(titles[p]), p)
+		}
+	}
+	_, err := atomicfile.WriteFile(filepath.Join(skillDir, TOCFile), []byte(b.String()))
+	return err
+}
+
+// writeTOCNode writes the documents and subentries of n to b as a nested
+// list, at the indentation of depth. A subentry labeled with the title of a
+// document of n is written as the link to that document, with the subentry's
+// documents nested under it.
+func writeTOCNode(b *strings.Builder, n *tocNode, depth int) {
+	indent := strings.Repeat("  ", depth)
+	heads := make(map[*tocNode]Document)
+	for _, doc := range n.docs {
+		if c := n.headed(doc); c != nil {
+			if _, ok := heads[c]; !ok {
+				heads[c] = doc
+				continue
+			}
+		}
+		writeTOCDoc(b, doc, indent)
+	}
+	for _, c := range n.children {
+		if doc, ok := heads[c]; ok {
+			writeTOCDoc(b, doc, indent)
+		} else {
+			fmt.Fprintf(b, "%s- %s\n", indent, oneLine(c.label))
+		}
+		writeTOCNode(b, c, depth+1)
+	}
+}
+
+// writeTOCDoc writes doc to b as a list item at indent: the link to the
+// document, with the deep links to its H2 sections nested under it.
+func writeTOCDoc(b *strings.Builder, doc Document, indent string) {
+	fmt.Fprintf(b, "%s- [%s](%s)\n", indent, oneLine(doc.Title), doc.Path)
+	for _, h := range doc.Headings {
+		if h.Level == 2 {
+			fmt.Fprintf(b, "%s  - [%s](%s)\n", indent, oneLine(h.Title), h.Link)
+		}
+	}
+}
+
+// headed returns the subentry of n labeled with the title of doc, a whole
+// page or its first part, or nil.
+func (n *tocNode) headed(doc Document) *tocNode {
+	if doc.Part > 1 {
+		return nil
+	}
+	for _, c := range n.children {
+		if strings.EqualFold(c.label, doc.Title) {
+			return c
+		}
+	}
+	return nil
+}
+
+// tocTrails returns the place of each of docs in the site, as the labels of
+// the entries of toc.md it is nested under:
+//   - for a page with breadcrumbs, its breadcrumb trail without the page
+//     itself (the last crumb, when the page title starts with it), and
+//     without the crumbs every trail starts with (e.g., "Home");
+//   - for a page in the sidebar navigation of the site, the entries of the
+//     sidebar leading to its own;
+//   - for the other pages, the directories of their section.
+func tocTrails(docs []Document) [][]string {
+	trails := make([][]string, len(docs))
+	var crumbed [][]string
+	for i, doc := range docs {
+		trail := doc.Breadcrumbs
+		if n := len(trail); n > 0 && strings.HasPrefix(strings.ToLower(doc.Title), strings.ToLower(trail[n-1])) {
+			trail = trail[:n-1]
+		}
+		if doc.Breadcrumbs != nil {
+			trails[i] = trail
+			crumbed = append(crumbed, trail)
+		} else if len(doc.Nav) > 0 {
+			trails[i] = doc.Nav[:len(doc.Nav)-1]
+		} else if doc.Section != "" {
+			trails[i] = strings.Split(doc.Section, "/")
+		}
+	}
+	if shared := len(commonPrefix(crumbed)); shared > 0 {
+		for i, doc := range docs {
+			if doc.Breadcrumbs != nil {
+				trails[i] = trails[i][shared:]
+			}
+		}
+	}
+	return trails
+}
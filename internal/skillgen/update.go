@@ -0,0 +1,322 @@
+// Package skillgen provides skill structure generation functionality.
+// This file implements the incremental update of an existing skill and the
+// changes.md changelog it writes.
+package skillgen
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+	"github.com/f4ah6o/site2skill-go/internal/mdprose"
+	"gopkg.in/yaml.v3"
+)
+
+// ChangesFile is the name of the changelog written at the root of an updated skill.
+const ChangesFile = "changes.md"
+
+// Changes lists the pages an update added, modified, and removed, and the
+// sources that moved.
+type Changes struct {
+	// Sources lists the sources added, moved, or removed since the previous
+	// lockfile of the skill (see Lock); empty if none moved.
+	Sources []SourceChange
+	// Added lists the pages that are new since the skill was generated.
+	Added []PageChange
+	// Modified lists the pages whose content hash changed.
+	Modified []PageChange
+	// Removed lists the pages no longer on the site.
+	Removed []PageChange
+	// Unchanged is the number of pages whose files were kept as they were.
+	Unchanged int
+	// Outside is the number of pages of the skill left out of a partial update
+	// (see Generator.SetOnly), kept as they were.
+	Outside int
+}
+
+// PageChange is one page added, modified, or removed by an update.
+type PageChange struct {
+	// URL is the source URL of the page; empty for files without frontmatter.
+	URL string
+	// Title is the page title.
+	Title string
+	// Paths lists the files of the page inside the skill (several for a page
+	// split into parts), sorted.
+	Paths []string
+}
+
+// page is a page of a skill: all its files share its source URL and content hash.
+type page struct {
+	title string
+	hash  string
+	paths []string
+}
+
+// Update brings an existing skill up to date with the Markdown files of a new
+// crawl in sourceDir. Pages are matched by source URL and compared by the
+// content_hash of their frontmatter against the manifest of the skill: the
+// files of pages whose hash is unchanged are kept as they were (including
+// their fetched_at), those of modified and added pages are copied, and those
+// of pages no longer crawled are deleted. Pages without a content hash count
+// as modified. The assets, snippets, manifest.json, anchors.json, graph.json,
+// toc.md, glossary.md, faq.md, changelog.md, symbols.json, and SKILL.md are then regenerated, and changes.md lists the changes. The new
+// skill.lock.json is compared with the previous one to tell which sources
+// moved.
+//
+// A skill without manifest.json is generated as if every page were added, and
+// one without skill.lock.json as if every source were added.
+// The manifest keeps the skill's source URL unless SetSourceURL was called.
+// With SetOnly, only the pages of the selected sections are compared, and
+// a crawled page is skipped if its files would replace those of a page
+// outside them.
+//
+// Returns the changes, or an error if the skill or the new files can't be
+// read or written.
+func (g *Generator) Update(skillName, sourceDir, outputBase string) (*Changes, error) {
+	skillDir := filepath.Join(outputBase, skillName)
+	docsDir := filepath.Join(skillDir, "docs")
+	if err := os.MkdirAll(docsDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create docs directory: %w", err)
+	}
+
+	old, oldURL, err := readManifestPages(skillDir)
+	if err != nil {
+		return nil, err
+	}
+	oldLock, err := ReadLock(skillDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if g.sourceURL == "" {
+		g.sourceURL = oldURL
+	}
+	crawled, err := readSourcePages(sourceDir)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := &Changes{}
+	if len(g.only) > 0 {
+		outside := make(map[string]bool)
+		for key, p := range old {
+			if !g.inSection(key) {
+				for _, path := range p.paths {
+					outside[path] = true
+				}
+				delete(old, key)
+				changes.Outside++
+			}
+		}
+		for key, p := range crawled {
+			if !g.inSection(key) {
+				delete(crawled, key)
+				continue
+			}
+			for _, path := range p.paths {
+				if outside[path] {
+					log.Printf("Warning: skipping %s: %s belongs to a page outside the updated sections", key, path)
+					delete(crawled, key)
+					break
+				}
+			}
+		}
+	}
+	for _, key := range sortedKeys(crawled) {
+		p := crawled[key]
+		prev, ok := old[key]
+		switch {
+		case !ok:
+			changes.Added = append(changes.Added, p.change(key))
+		case p.hash == "" || p.hash != prev.hash || strings.Join(p.paths, " ") != strings.Join(prev.paths, " "):
+			if err := removeFiles(skillDir, prev.paths); err != nil {
+				return nil, err
+			}
+			changes.Modified = append(changes.Modified, p.change(key))
+		default:
+			changes.Unchanged++
+			continue
+		}
+		for _, path := range p.paths {
+			if _, err := atomicfile.CopyFile(filepath.Join(sourceDir, filepath.Base(path)), filepath.Join(skillDir, filepath.FromSlash(path))); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, key := range sortedKeys(old) {
+		if _, ok := crawled[key]; !ok {
+			if err := removeFiles(skillDir, old[key].paths); err != nil {
+				return nil, err
+			}
+			changes.Removed = append(changes.Removed, old[key].change(key))
+		}
+	}
+	log.Printf("Updated docs/: %s", changes.Summary())
+
+	_, lock, err := g.index(skillName, sourceDir, skillDir)
+	if err != nil {
+		return nil, err
+	}
+	changes.Sources = oldLock.Diff(lock)
+	for _, c := range changes.Sources {
+		log.Printf("Source %s", c)
+	}
+	if _, err := atomicfile.WriteFile(filepath.Join(skillDir, ChangesFile), []byte(changes.Markdown(g.now().UTC()))); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", ChangesFile, err)
+	}
+	return changes, nil
+}
+
+// Empty reports whether the update changed nothing: no source moved, and no
+// page was added, modified, or removed.
+func (c *Changes) Empty() bool {
+	return len(c.Sources) == 0 && len(c.Added) == 0 && len(c.Modified) == 0 && len(c.Removed) == 0
+}
+
+// Summary returns a one-line count of the changes.
+func (c *Changes) Summary() string {
+	s := fmt.Sprintf("%d added, %d modified, %d removed, %d unchanged",
+		len(c.Added), len(c.Modified), len(c.Removed), c.Unchanged)
+	if c.Outside > 0 {
+		s += fmt.Sprintf(", %d outside the updated sections", c.Outside)
+	}
+	return s
+}
+
+// Markdown renders the changelog written as changes.md, dated at.
+func (c *Changes) Markdown(at time.Time) string {
+	var b strings.Builder
+	b.WriteString("# Changes\n\n")
+	fmt.Fprintf(&b, "Updated %s: %s.\n", at.Format(time.RFC3339), c.Summary())
+	if len(c.Sources) > 0 {
+		b.WriteString("\n## Sources\n\n")
+		for _, s := range c.Sources {
+			fmt.Fprintf(&b, "- %s\n", s)
+		}
+	}
+	for _, section := range []struct {
+		name  string
+		pages []PageChange
+		link  bool
+	}{
+		{"Added", c.Added, true},
+		{"Modified", c.Modified, true},
+		{"Removed", c.Removed, false},
+	} {
+		if len(section.pages) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n\n", section.name)
+		for _, p := range section.pages {
+			// Removed pages have no file left to link to
+			if section.link && len(p.Paths) > 0 {
+				fmt.Fprintf(&b, "- [%s](%s)", p.Title, p.Paths[0])
+			} else {
+				fmt.Fprintf(&b, "- %s", p.Title)
+			}
+			if p.URL != "" {
+				b.WriteString(" - " + p.URL)
+			}
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+// change describes the page p identified by key as a PageChange.
+func (p *page) change(key string) PageChange {
+	c := PageChange{Title: p.title, Paths: p.paths}
+	if !strings.HasPrefix(key, "docs/") {
+		c.URL = key
+	}
+	return c
+}
+
+// readManifestPages returns the pages listed in the manifest.json of skillDir,
+// keyed by source URL (by path for documents without one), and the source URL
+// of the skill. Returns no pages if the skill has no manifest.
+func readManifestPages(skillDir string) (map[string]*page, string, error) {
+	m, err := ReadManifest(skillDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]*page{}, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	pages := make(map[string]*page)
+	for _, doc := range m.Documents {
+		addPage(pages, doc.SourceURL, doc.Path, doc.Title, doc.ContentHash, doc.Part)
+	}
+	return pages, m.URL, nil
+}
+
+// readSourcePages returns the pages of the Markdown files in sourceDir, keyed
+// like readManifestPages, with the paths their files get in the skill.
+func readSourcePages(sourceDir string) (map[string]*page, error) {
+	files, err := filepath.Glob(filepath.Join(sourceDir, "*.md"))
+	if err != nil {
+		return nil, err
+	}
+	pages := make(map[string]*page)
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		var fm docFrontmatter
+		if frontmatter, _, ok := mdprose.SplitFrontmatter(string(content)); ok {
+			yaml.Unmarshal([]byte(frontmatter), &fm)
+		}
+		name := filepath.Base(file)
+		if fm.Title == "" {
+			fm.Title = strings.TrimSuffix(name, ".md")
+		}
+		addPage(pages, fm.SourceURL, "docs/"+name, fm.Title, fm.ContentHash, fm.Part)
+	}
+	return pages, nil
+}
+
+// addPage adds a file to its page in pages. The title of a split page is that
+// of its first part.
+func addPage(pages map[string]*page, sourceURL, path, title, hash string, part int) {
+	key := sourceURL
+	if key == "" {
+		key = path
+	}
+	p, ok := pages[key]
+	if !ok {
+		p = &page{title: title, hash: hash}
+		pages[key] = p
+	}
+	if part <= 1 {
+		p.title = title
+	}
+	p.paths = append(p.paths, path)
+	sort.Strings(p.paths)
+}
+
+// sortedKeys returns the keys of pages in order.
+func sortedKeys(pages map[string]*page) []string {
+	keys := make([]string, 0, len(pages))
+	for key := range pages {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// removeFiles deletes the files at the slash-separated paths inside skillDir,
+// ignoring those already gone.
+func removeFiles(skillDir string, paths []string) error {
+	for _, path := range paths {
+		if err := os.Remove(filepath.Join(skillDir, filepath.FromSlash(path))); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+// Package slug turns the titles of pages imported from a workspace, such as
+// Notion or Confluence, into the path segments of the URLs they are recorded
+// under ("Local Setup (macOS)" becomes "local-setup-macos").
+package slug
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Title returns the lower-case letters and digits of title with hyphens
+// between their runs, or "page-<id>" if it has none.
+func Title(title, id string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	if b.Len() == 0 {
+		return "page-" + id
+	}
+	return b.String()
+}
@@ -0,0 +1,21 @@
+package slug
+
+import "testing"
+
+func TestTitle(t *testing.T) {
+	tests := []struct {
+		title, id string
+		want      string
+	}{
+		{"Local Setup (macOS)", "1", "local-setup-macos"},
+		{"  API -- Reference  ", "1", "api-reference"},
+		{"はじめに 2", "1", "はじめに-2"},
+		{"???", "0123abcd", "page-0123abcd"},
+		{"", "42", "page-42"},
+	}
+	for _, tt := range tests {
+		if got := Title(tt.title, tt.id); got != tt.want {
+			t.Errorf("Title(%q, %q) = %q, want %q", tt.title, tt.id, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Package snippets replaces code samples repeated across converted pages with
+// references to shared files. SDK documentation often copies the same setup
+// code onto every page; keeping one copy shrinks the skill and the context an
+// agent loads, without losing the code.
+package snippets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// DirName is the name of the shared snippets folder at the root of a skill.
+	DirName = "snippets"
+	// DefaultMinLines is the shortest code block, in lines, that is shared by default.
+	DefaultMinLines = 10
+)
+
+// extensions maps code block languages to snippet file extensions. Markdown
+// snippets are saved as .txt, since .md files are taken for documentation.
+var extensions = map[string]string{
+	"go": ".go", "python": ".py", "py": ".py", "javascript": ".js", "js": ".js",
+	"typescript": ".ts", "ts": ".ts", "tsx": ".tsx", "jsx": ".jsx", "java": ".java",
+	"kotlin": ".kt", "swift": ".swift", "ruby": ".rb", "rb": ".rb", "php": ".php",
+	"rust": ".rs", "c": ".c", "cpp": ".cpp", "csharp": ".cs", "cs": ".cs",
+	"sh": ".sh", "bash": ".sh", "shell": ".sh", "zsh": ".sh", "console": ".sh",
+	"powershell": ".ps1", "json": ".json", "yaml": ".yaml", "yml": ".yaml",
+	"toml": ".toml", "xml": ".xml", "html": ".html", "css": ".css", "sql": ".sql",
+	"dockerfile": ".dockerfile", "graphql": ".graphql", "proto": ".proto",
+}
+
+// Stats counts the work of a Deduplicate call.
+type Stats struct {
+	// Snippets is the number of shared files written.
+	Snippets int
+	// Replaced is the number of code blocks replaced by a reference.
+	Replaced int
+}
+
+// Deduplicator replaces repeated code blocks with references to shared files.
+type Deduplicator struct {
+	// dir is where shared snippet files are written
+	dir string
+	// minLines is the shortest code block considered
+	minLines int
+}
+
+// block is a fenced code block found in a Markdown file.
+type block struct {
+	// start and end are the line indexes of the opening and closing fences
+	start, end int
+	// indent is the indentation of the fences, for blocks inside lists
+	indent string
+	// lang is the language of the info string, if any
+	lang string
+	// code is the content of the block, without the indentation of the fences
+	code string
+}
+
+// location identifies a code block by file and opening fence line.
+type location struct {
+	path  string
+	start int
+}
+
+// New creates a Deduplicator writing shared snippets into dir, which Markdown
+// files reference as ../snippets/<file>.
+func New(dir string) *Deduplicator {
+	return &Deduplicator{dir: dir, minLines: DefaultMinLines}
+}
+
+// SetMinLines sets the shortest code block, in lines, that is shared.
+func (d *Deduplicator) SetMinLines(n int) {
+	d.minLines = max(n, 1)
+}
+
+// Deduplicate finds the fenced code blocks of at least the minimum length that
+// occur more than once across the Markdown files at paths. Each such block is
+// saved once as snippet-<hash>.<ext> in the snippets directory. Its first
+// occurrence, in path order, stays inline; every later one is replaced with a
+// line linking the shared file. Blocks compare equal when their code matches
+// exactly, ignoring the indentation of blocks inside lists and the language.
+//
+// Returns an error if a file can't be read or written.
+func (d *Deduplicator) Deduplicate(paths []string) (Stats, error) {
+	var stats Stats
+	paths = append([]string(nil), paths...)
+	sort.Strings(paths)
+
+	files := make(map[string][]string, len(paths))
+	blocks := make(map[string][]block, len(paths))
+	counts := make(map[string]int)
+	first := make(map[string]location)
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return stats, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		lines := strings.Split(string(content), "\n")
+		files[path] = lines
+		for _, b := range findBlocks(lines) {
+			if b.end-b.start-1 >= d.minLines {
+				blocks[path] = append(blocks[path], b)
+				if counts[b.code] == 0 {
+					first[b.code] = location{path, b.start}
+				}
+				counts[b.code]++
+			}
+		}
+	}
+
+	names := make(map[string]string)
+	for _, path := range paths {
+		lines := files[path]
+		changed := false
+		// Replace from the end so earlier line indexes stay valid
+		found := blocks[path]
+		for i := len(found) - 1; i >= 0; i-- {
+			b := found[i]
+			if counts[b.code] < 2 || first[b.code] == (location{path, b.start}) {
+				continue
+			}
+			name, err := d.save(names, b)
+			if err != nil {
+				return stats, err
+			}
+			ref := fmt.Sprintf("%s*This code sample (%d lines) is shared by several pages: [%s/%s](../%s/%s).*",
+				b.indent, b.end-b.start-1, DirName, name, DirName, name)
+			lines = append(lines[:b.start], append([]string{ref}, lines[b.end+1:]...)...)
+			changed = true
+			stats.Replaced++
+		}
+		if changed {
+			if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+				return stats, fmt.Errorf("failed to write %s: %w", path, err)
+			}
+		}
+	}
+	stats.Snippets = len(names)
+	return stats, nil
+}
+
+// save writes the snippet file of b, once per code, and returns its name.
+func (d *Deduplicator) save(names map[string]string, b block) (string, error) {
+	if name, ok := names[b.code]; ok {
+		return name, nil
+	}
+	ext, ok := extensions[strings.ToLower(b.lang)]
+	if !ok {
+		ext = ".txt"
+	}
+	sum := sha256.Sum256([]byte(b.code))
+	name := "snippet-" + hex.EncodeToString(sum[:])[:12] + ext
+
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create snippets directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(d.dir, name), []byte(b.code+"\n"), 0644); err != nil {
+		return "", fmt.Errorf("failed to write snippet: %w", err)
+	}
+	names[b.code] = name
+	return name, nil
+}
+
+// findBlocks returns the fenced code blocks of a Markdown file. Unclosed
+// fences are ignored.
+func findBlocks(lines []string) []block {
+	var blocks []block
+	var current *block
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if current != nil {
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				code := make([]string, 0, i-current.start-1)
+				for _, l := range lines[current.start+1 : i] {
+					code = append(code, strings.TrimPrefix(l, current.indent))
+				}
+				current.end = i
+				current.code = strings.Join(code, "\n")
+				blocks = append(blocks, *current)
+				current = nil
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			marker := trimmed[:1]
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, marker))]
+			lang, _, _ := strings.Cut(strings.TrimSpace(trimmed[len(fence):]), " ")
+			current = &block{
+				start:  i,
+				indent: line[:len(line)-len(strings.TrimLeft(line, " \t"))],
+				lang:   lang,
+			}
+		}
+	}
+	return blocks
+}
@@ -0,0 +1,77 @@
+package snippets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDeduplicate(t *testing.T) {
+	setup := "```go\n" + strings.Repeat("client.Configure()\n", 10) + "```"
+	listed := "1. Configure the client:\n\n   ```go\n" + strings.Repeat("   client.Configure()\n", 10) + "   ```\n2. Run it."
+	short := "```sh\nmake\n```"
+
+	dir := t.TempDir()
+	docs := map[string]string{
+		"a.md": "# A\n\n" + setup + "\n\n" + short + "\n",
+		"b.md": "# B\n\n" + listed + "\n\n" + short + "\n",
+		"c.md": "# C\n\n" + setup + "\n\nEnd.\n",
+		"d.md": "# D\n\n```go\n" + strings.Repeat("other()\n", 10) + "```\n",
+	}
+	var paths []string
+	for name, content := range docs {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	snippetsDir := filepath.Join(dir, DirName)
+	stats, err := New(snippetsDir).Deduplicate(paths)
+	if err != nil {
+		t.Fatalf("Deduplicate() returned error: %v", err)
+	}
+	if stats.Snippets != 1 || stats.Replaced != 2 {
+		t.Errorf("stats = %+v, want 1 snippet and 2 replaced blocks", stats)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(snippetsDir, "snippet-*.go"))
+	if len(files) != 1 {
+		t.Fatalf("snippet files = %v, want one .go file", files)
+	}
+	name := filepath.Base(files[0])
+	code, _ := os.ReadFile(files[0])
+	if string(code) != strings.Repeat("client.Configure()\n", 10) {
+		t.Errorf("snippet content = %q", code)
+	}
+
+	ref := "*This code sample (10 lines) is shared by several pages: [snippets/" + name + "](../snippets/" + name + ").*"
+	want := map[string]string{
+		"a.md": docs["a.md"],
+		"b.md": "# B\n\n1. Configure the client:\n\n   " + ref + "\n2. Run it.\n\n" + short + "\n",
+		"c.md": "# C\n\n" + ref + "\n\nEnd.\n",
+		"d.md": docs["d.md"],
+	}
+	for file, content := range want {
+		got, _ := os.ReadFile(filepath.Join(dir, file))
+		if string(got) != content {
+			t.Errorf("%s =\n%s\nwant:\n%s", file, got, content)
+		}
+	}
+}
+
+func TestDeduplicateNothingShared(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "a.md")
+	os.WriteFile(path, []byte("```\nunclosed\n"), 0644)
+
+	stats, err := New(filepath.Join(dir, DirName)).Deduplicate([]string{path})
+	if err != nil || stats != (Stats{}) {
+		t.Errorf("Deduplicate() = %+v, %v; want no work", stats, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, DirName)); !os.IsNotExist(err) {
+		t.Errorf("snippets directory created without snippets")
+	}
+}
@@ -0,0 +1,107 @@
+// Package summarize writes abstracts of the documents of a skill.
+// This file implements the local summarizer taking the first sentences of
+// each document.
+package summarize
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var (
+	// linkPattern matches Markdown links and images, capturing their text.
+	linkPattern = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	// markupPattern matches the emphasis and code markers of Markdown.
+	markupPattern = regexp.MustCompile("\\*\\*|__|`|\\*")
+	// listItemPattern matches the start of a list item.
+	listItemPattern = regexp.MustCompile(`^(?:[-*+]|\d+[.)])\s`)
+	// thematicBreakPattern matches a thematic break.
+	thematicBreakPattern = regexp.MustCompile(`^(?:[-*_]\s*){3,}$`)
+	// sentencePattern matches a sentence, up to its final punctuation and the
+	// spaces after it.
+	sentencePattern = regexp.MustCompile(`.+?(?:[.!?]+["')\]]*(?:\s+|$)|[。！？]+)`)
+)
+
+// Lead summarizes a document by its lead: the first MaxSentences sentences of
+// its paragraphs, skipping headings, code blocks, tables, lists, quotes, and
+// HTML, up to maxLength bytes. Documents without prose have no summary.
+type Lead struct{}
+
+// NewLead returns the summarizer taking the first sentences of documents.
+func NewLead() *Lead {
+	return &Lead{}
+}
+
+// Spec returns the spec of the summarizer.
+func (*Lead) Spec() Spec {
+	return Spec{Provider: ProviderLead}
+}
+
+// Summarize returns the lead of page. It never fails.
+func (*Lead) Summarize(_ context.Context, page Page) (string, error) {
+	var sentences []string
+	length := 0
+	for _, paragraph := range paragraphs(page.Text) {
+		text := linkPattern.ReplaceAllString(paragraph, "$1")
+		text = markupPattern.ReplaceAllString(text, "")
+		for _, sentence := range sentencePattern.FindAllString(text, -1) {
+			sentence = strings.TrimSpace(sentence)
+			if len(strings.Fields(sentence)) < 3 && !strings.ContainsFunc(sentence, isCJK) {
+				continue
+			}
+			if length+len(sentence) > maxLength && len(sentences) > 0 {
+				return strings.Join(sentences, " "), nil
+			}
+			sentences = append(sentences, sentence)
+			length += len(sentence) + 1
+			if len(sentences) == MaxSentences {
+				return strings.Join(sentences, " "), nil
+			}
+		}
+	}
+	return strings.Join(sentences, " "), nil
+}
+
+// paragraphs returns the paragraphs of prose of text, a Markdown body, each
+// on one line: the runs of lines that aren't blank, headings, code blocks,
+// tables, list items, quotes, definitions, HTML, or thematic breaks.
+func paragraphs(text string) []string {
+	var paragraphs []string
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			paragraphs = append(paragraphs, strings.Join(current, " "))
+			current = nil
+		}
+	}
+	fence := ""
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		switch {
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			flush()
+			fence = trimmed[:3]
+		case trimmed == "" || strings.ContainsAny(trimmed[:1], "#|><:") ||
+			listItemPattern.MatchString(trimmed) || thematicBreakPattern.MatchString(trimmed):
+			flush()
+		default:
+			current = append(current, trimmed)
+		}
+	}
+	flush()
+	return paragraphs
+}
+
+// isCJK reports whether r is a letter of a script written without spaces
+// between words, whose sentences have a single field.
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,69 @@
+// Package summarize writes abstracts of the documents of a skill.
+// This file implements the summarizer of OpenAI-compatible chat endpoints.
+package summarize
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/openai"
+)
+
+// maxInputLength is the longest part of a document sent to the model, in
+// bytes; the beginning of a document tells what it is about.
+const maxInputLength = 24000
+
+// systemPrompt instructs the model of OpenAI.
+const systemPrompt = "You summarize pages of technical documentation for an index read by AI agents. " +
+	"Reply with a summary of the page of one to three sentences, in the language of the page, " +
+	"saying what the page covers and when to read it. Reply with the summary only, as plain text without Markdown."
+
+// OpenAI summarizes documents with an OpenAI-compatible chat endpoint (see
+// openai.Client.Chat).
+type OpenAI struct {
+	spec   Spec
+	client *openai.Client
+}
+
+// NewOpenAI returns a summarizer calling the chat endpoint of the API at
+// baseURL (openai.DefaultURL if empty) with model (DefaultOpenAIModel if
+// empty), authenticating with apiKey as a bearer token unless it is empty.
+// Its requests time out after 120 seconds.
+func NewOpenAI(baseURL, model, apiKey string) *OpenAI {
+	if model == "" {
+		model = DefaultOpenAIModel
+	}
+	client := openai.New(baseURL, apiKey, 120*time.Second)
+	return &OpenAI{spec: Spec{Provider: ProviderOpenAI, URL: client.URL, Model: model}, client: client}
+}
+
+// Spec returns the spec of the summarizer.
+func (p *OpenAI) Spec() Spec {
+	return p.spec
+}
+
+// Summarize asks the model for a summary of page, of which the first
+// maxInputLength bytes of the text are sent.
+//
+// Returns an error if the request fails, the endpoint answers with an error
+// status, or its response has no answer.
+func (p *OpenAI) Summarize(ctx context.Context, page Page) (string, error) {
+	text := page.Text
+	if len(text) > maxInputLength {
+		text = strings.ToValidUTF8(text[:maxInputLength], "")
+	}
+	var prompt strings.Builder
+	fmt.Fprintf(&prompt, "Title: %s\n", page.Title)
+	if page.URL != "" {
+		fmt.Fprintf(&prompt, "URL: %s\n", page.URL)
+	}
+	prompt.WriteString("\n" + text)
+
+	summary, err := p.client.Chat(ctx, p.spec.Model, systemPrompt, prompt.String())
+	if err != nil {
+		return "", fmt.Errorf("failed to request summary: %w", err)
+	}
+	return summary, nil
+}
@@ -0,0 +1,279 @@
+// Package summarize writes abstracts of the documents of a skill: a summary of
+// one to three sentences in the frontmatter of each Markdown file, which the
+// manifest, llms.txt, and the ranking of searches use.
+//
+// Summaries come from a Summarizer:
+//
+//   - ProviderOpenAI asks a model of an OpenAI-compatible /chat/completions
+//     endpoint: the OpenAI API, or a local model served by Ollama, LM Studio,
+//     llama.cpp, or vLLM (e.g., "http://localhost:11434/v1").
+//   - ProviderLead takes the first sentences of each document, without a
+//     model or network access. It suits air-gapped builds and tests.
+//
+// Any other implementation of Summarizer, e.g., a client of another LLM API,
+// can be set in site2skill.Config.Summarizer.
+package summarize
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/mdprose"
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+	"gopkg.in/yaml.v3"
+)
+
+// The providers of New.
+const (
+	// ProviderOpenAI asks a model of an OpenAI-compatible chat endpoint (see OpenAI).
+	ProviderOpenAI = "openai"
+	// ProviderLead takes the first sentences of the documents (see Lead).
+	ProviderLead = "lead"
+)
+
+// Providers lists the providers of New.
+var Providers = []string{ProviderOpenAI, ProviderLead}
+
+const (
+	// DefaultOpenAIModel is the chat model used with ProviderOpenAI when none is given.
+	DefaultOpenAIModel = "gpt-4o-mini"
+
+	// APIKeyEnv is the environment variable holding the API key of
+	// ProviderOpenAI; OPENAI_API_KEY is used when it isn't set.
+	APIKeyEnv = "SITE2SKILL_SUMMARIES_API_KEY"
+
+	// Field is the frontmatter field of the summaries.
+	Field = "summary"
+	// MaxSentences is the largest number of sentences of a summary.
+	MaxSentences = 3
+	// maxLength is the longest summary kept, in bytes; longer answers are
+	// cut at a word boundary.
+	maxLength = 600
+)
+
+// Page is a document to summarize.
+type Page struct {
+	// Title is the title of the document.
+	Title string
+	// URL is the source URL of the document; empty if unknown.
+	URL string
+	// Text is the Markdown body of the document, without its frontmatter.
+	Text string
+}
+
+// Summarizer writes the summaries of documents.
+type Summarizer interface {
+	// Summarize returns a summary of page of one to MaxSentences sentences,
+	// as plain text.
+	Summarize(ctx context.Context, page Page) (string, error)
+	// Spec identifies the provider and model writing the summaries.
+	Spec() Spec
+}
+
+// Spec identifies the provider and model of a Summarizer.
+type Spec struct {
+	// Provider is ProviderOpenAI, ProviderLead, or the name of another summarizer.
+	Provider string `json:"provider"`
+	// URL is the base URL of the endpoint of ProviderOpenAI (e.g., openai.DefaultURL).
+	URL string `json:"url,omitempty"`
+	// Model is the chat model of ProviderOpenAI (e.g., DefaultOpenAIModel).
+	Model string `json:"model,omitempty"`
+}
+
+// String describes the spec (e.g., "openai gpt-4o-mini").
+func (s Spec) String() string {
+	if s.Model != "" {
+		return s.Provider + " " + s.Model
+	}
+	return s.Provider
+}
+
+// New returns the summarizer of spec, filling in its defaults:
+// openai.DefaultURL and DefaultOpenAIModel for ProviderOpenAI, which
+// authenticates with apiKey (none if empty).
+//
+// Returns an error if the provider is unknown.
+func New(spec Spec, apiKey string) (Summarizer, error) {
+	switch spec.Provider {
+	case ProviderOpenAI:
+		return NewOpenAI(spec.URL, spec.Model, apiKey), nil
+	case ProviderLead:
+		return NewLead(), nil
+	}
+	return nil, fmt.Errorf("unknown summaries provider %q (expected %s)", spec.Provider, strings.Join(Providers, " or "))
+}
+
+// APIKey returns the API key of ProviderOpenAI from the environment: APIKeyEnv,
+// or else OPENAI_API_KEY.
+func APIKey() string {
+	if key := os.Getenv(APIKeyEnv); key != "" {
+		return key
+	}
+	return os.Getenv("OPENAI_API_KEY")
+}
+
+// Key returns the key of the summary of a document in the summaries passed to
+// Dir: its content hash (the content_hash of its frontmatter) and its part, for
+// a page split into parts. Returns "" for a document without a content hash,
+// whose summary can't be reused.
+func Key(contentHash string, part int) string {
+	if contentHash == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s#%d", contentHash, part)
+}
+
+// Stats reports what Dir did.
+type Stats struct {
+	// Documents is the number of Markdown files of the directory.
+	Documents int
+	// Summarized is the number of documents summarized by the summarizer.
+	Summarized int
+	// Reused is the number of documents given their previous summary, and
+	// Kept those already having one.
+	Reused, Kept int
+	// Failed is the number of documents the summarizer failed to summarize,
+	// which are left without a summary.
+	Failed int
+}
+
+// Dir writes a summary into the frontmatter of each Markdown file of dir, as
+// its summary field (see Field): the summary in previous under the Key of the
+// file, if any, which spares summarizing the pages unchanged since the last
+// build, or else the summary s writes. The files that already have a summary,
+// or have no frontmatter, are left as they are.
+//
+// A document s fails to summarize is reported as a warning and left without a
+// summary. Returns ctx's error once it is cancelled, or an error if the files
+// can't be read or written.
+func Dir(ctx context.Context, dir string, s Summarizer, previous map[string]string) (*Stats, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.md"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find markdown files: %w", err)
+	}
+	stats := &Stats{}
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		d, err := readDoc(file)
+		if err != nil {
+			return nil, err
+		}
+		if d == nil {
+			continue
+		}
+		stats.Documents++
+		if d.fields.Summary != "" {
+			stats.Kept++
+			continue
+		}
+
+		summary, ok := previous[Key(d.fields.ContentHash, d.fields.Part)]
+		if ok && summary != "" {
+			stats.Reused++
+		} else {
+			summary, err = s.Summarize(ctx, Page{Title: d.fields.Title, URL: d.fields.SourceURL, Text: d.body})
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			if summary = Clean(summary); err != nil || summary == "" {
+				if err == nil {
+					err = fmt.Errorf("empty summary")
+				}
+				warnlog.Printf("summary", "Warning: failed to summarize %s: %v", file, err)
+				stats.Failed++
+				continue
+			}
+			stats.Summarized++
+		}
+		if err := d.writeSummary(summary); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+// Clean returns summary on one line, without the quotes or the "Summary:"
+// label models wrap their answers in, and cut to at most maxLength bytes at a
+// word boundary.
+func Clean(summary string) string {
+	summary = strings.Join(strings.Fields(summary), " ")
+	if label, rest, ok := strings.Cut(summary, ":"); ok && strings.EqualFold(label, "summary") {
+		summary = strings.TrimSpace(rest)
+	}
+	summary = strings.Trim(summary, `"'“”`)
+	if len(summary) <= maxLength {
+		return summary
+	}
+	cut := strings.LastIndex(summary[:maxLength], " ")
+	if cut <= 0 {
+		cut = maxLength
+	}
+	return strings.TrimRight(summary[:cut], " ,;:") + "..."
+}
+
+// doc is a Markdown file with YAML frontmatter.
+type doc struct {
+	path string
+	// meta is the mapping node of the frontmatter, rewritten with the summary
+	meta *yaml.Node
+	// fields are the frontmatter fields read by Dir
+	fields struct {
+		Title       string `yaml:"title"`
+		SourceURL   string `yaml:"source_url"`
+		ContentHash string `yaml:"content_hash"`
+		Part        int    `yaml:"part"`
+		Summary     string `yaml:"summary"`
+	}
+	body string
+}
+
+// readDoc reads the Markdown file at path, or returns nil if it has no
+// frontmatter, or one that isn't a YAML mapping.
+func readDoc(path string) (*doc, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	frontmatter, body, ok := mdprose.SplitFrontmatter(string(content))
+	if !ok {
+		return nil, nil
+	}
+	var meta yaml.Node
+	d := &doc{path: path, body: body}
+	if yaml.Unmarshal([]byte(frontmatter), &meta) != nil || len(meta.Content) != 1 ||
+		meta.Content[0].Kind != yaml.MappingNode || meta.Decode(&d.fields) != nil {
+		return nil, nil
+	}
+	d.meta = meta.Content[0]
+	return d, nil
+}
+
+// writeSummary rewrites the file of d with summary as the summary field of its
+// frontmatter, after its description if it has one, or else at its end.
+func (d *doc) writeSummary(summary string) error {
+	key := &yaml.Node{Kind: yaml.ScalarNode, Value: Field}
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: summary, Style: yaml.DoubleQuotedStyle}
+	at := len(d.meta.Content)
+	for i := 0; i+1 < len(d.meta.Content); i += 2 {
+		if d.meta.Content[i].Value == "description" {
+			at = i + 2
+		}
+	}
+	d.meta.Content = append(d.meta.Content[:at], append([]*yaml.Node{key, value}, d.meta.Content[at:]...)...)
+
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(d.meta); err != nil {
+		return fmt.Errorf("failed to write frontmatter of %s: %w", d.path, err)
+	}
+	if err := os.WriteFile(d.path, []byte("---\n"+b.String()+"---\n"+d.body), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", d.path, err)
+	}
+	return nil
+}
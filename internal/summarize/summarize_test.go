@@ -0,0 +1,176 @@
+package summarize
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLead(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{
+			name: "first sentences",
+			text: "# Install\n\nInstall the CLI with [Homebrew](https://brew.sh) or\nthe **installer**. It needs Go 1.22. Run `site2skillgo version` to check.\nThen sign in.\n",
+			want: "Install the CLI with Homebrew or the installer. It needs Go 1.22. Run site2skillgo version to check.",
+		},
+		{
+			name: "markup skipped",
+			text: "```bash\necho This is code.\n```\n\n- A list item is skipped.\n1. So is this one.\n\n| A table. | Too. |\n\n> A quote as well.\n\n---\n\nWebhooks notify your server of events.\n",
+			want: "Webhooks notify your server of events.",
+		},
+		{
+			name: "short fragments skipped",
+			text: "Note. Keys expire after 90 days.\n",
+			want: "Keys expire after 90 days.",
+		},
+		{
+			name: "no prose",
+			text: "# Reference\n\n| Name | Type |\n|---|---|\n",
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NewLead().Summarize(context.Background(), Page{Text: tt.text})
+			if err != nil || got != tt.want {
+				t.Errorf("Summarize() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestOpenAI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Model != "test-model" || len(req.Messages) != 2 {
+			http.Error(w, "bad body", http.StatusBadRequest)
+			return
+		}
+		if !strings.HasPrefix(req.Messages[1].Content, "Title: Install\nURL: https://example.com/install\n\nRun it.") {
+			http.Error(w, "bad prompt", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"choices": []any{map[string]any{"message": map[string]string{"content": "Summary: How to install the CLI."}}}})
+	}))
+	defer srv.Close()
+
+	p := NewOpenAI(srv.URL+"/v1/", "test-model", "secret")
+	if got := p.Spec(); got != (Spec{Provider: ProviderOpenAI, URL: srv.URL + "/v1", Model: "test-model"}) {
+		t.Errorf("Spec() = %+v", got)
+	}
+	got, err := p.Summarize(context.Background(), Page{Title: "Install", URL: "https://example.com/install", Text: "Run it."})
+	if err != nil {
+		t.Fatalf("Summarize() error = %v", err)
+	}
+	if got != "Summary: How to install the CLI." {
+		t.Errorf("Summarize() = %q", got)
+	}
+
+	if _, err := NewOpenAI(srv.URL+"/v1", "test-model", "wrong").Summarize(context.Background(), Page{Title: "Install"}); err == nil {
+		t.Error("Summarize() with a wrong key: error = nil, want an error")
+	}
+}
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Summary: How to install\n the CLI.", "How to install the CLI."},
+		{`"Quoted answer."`, "Quoted answer."},
+		{strings.Repeat("word ", 200), strings.TrimSpace(strings.Repeat("word ", 120)) + "..."},
+	}
+	for _, tt := range tests {
+		if got := Clean(tt.in); got != tt.want {
+			t.Errorf("Clean(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// fakeSummarizer summarizes pages by their title, failing on titles starting with "Broken".
+type fakeSummarizer struct {
+	calls int
+}
+
+func (f *fakeSummarizer) Summarize(_ context.Context, page Page) (string, error) {
+	f.calls++
+	if strings.HasPrefix(page.Title, "Broken") {
+		return "", errors.New("model unavailable")
+	}
+	return "All about " + page.Title + ".", nil
+}
+
+func (f *fakeSummarizer) Spec() Spec {
+	return Spec{Provider: "fake"}
+}
+
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"install.md":   "---\ntitle: \"Install\"\ndescription: \"Install the CLI.\"\nsource_url: \"https://example.com/install\"\ncontent_hash: \"sha256:aaa\"\ntags:\n  - \"cli\"\n---\n\n# Install\n",
+		"auth.md":      "---\ntitle: \"Auth\"\ncontent_hash: \"sha256:bbb\"\npart: 2\n---\n\n# Auth\n",
+		"written.md":   "---\ntitle: \"Written\"\nsummary: \"By hand.\"\n---\n\n# Written\n",
+		"broken.md":    "---\ntitle: \"Broken page\"\n---\n\n# Broken\n",
+		"plain.md":     "# No frontmatter\n",
+		"not-text.txt": "ignored",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := &fakeSummarizer{}
+	stats, err := Dir(context.Background(), dir, s, map[string]string{Key("sha256:bbb", 2): "Reused summary.", Key("sha256:bbb", 1): "Other part."})
+	if err != nil {
+		t.Fatalf("Dir() error = %v", err)
+	}
+	if want := (Stats{Documents: 4, Summarized: 1, Reused: 1, Kept: 1, Failed: 1}); *stats != want {
+		t.Errorf("Dir() = %+v, want %+v", *stats, want)
+	}
+	if s.calls != 2 {
+		t.Errorf("summarizer called %d times, want 2", s.calls)
+	}
+
+	want := map[string]string{
+		"install.md": "---\ntitle: \"Install\"\ndescription: \"Install the CLI.\"\nsummary: \"All about Install.\"\nsource_url: \"https://example.com/install\"\ncontent_hash: \"sha256:aaa\"\ntags:\n  - \"cli\"\n---\n\n# Install\n",
+		"auth.md":    "---\ntitle: \"Auth\"\ncontent_hash: \"sha256:bbb\"\npart: 2\nsummary: \"Reused summary.\"\n---\n\n# Auth\n",
+		"written.md": files["written.md"],
+		"broken.md":  files["broken.md"],
+		"plain.md":   files["plain.md"],
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("%s =\n%s\nwant\n%s", name, got, content)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Dir(ctx, dir, s, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Dir() with a cancelled context: error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,220 @@
+// Package textdiff compares texts line by line and renders their differences
+// as unified diffs, the format of diff -u and git diff.
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultContext is the number of unchanged lines shown around each change.
+const DefaultContext = 3
+
+// maxEdits bounds the search for the shortest edit script, whose memory grows
+// with the square of the number of edits: texts differing by more lines are
+// diffed as the deletion of every differing line followed by the insertion
+// of the new ones.
+const maxEdits = 4000
+
+// edit is one line of the shortest edit script between two texts: kept
+// (' '), deleted from the old text ('-'), or inserted from the new one ('+').
+type edit struct {
+	kind byte
+	line string
+}
+
+// Unified returns the unified diff turning oldText into newText, with the
+// headers "--- oldName" and "+++ newName" and context unchanged lines around
+// each change, or "" if the texts are equal. A text whose last line has no
+// newline gets the "\ No newline at end of file" marker.
+func Unified(oldName, newName, oldText, newText string, context int) string {
+	if oldText == newText {
+		return ""
+	}
+	edits := diffLines(splitLines(oldText), splitLines(newText))
+
+	// oldLine[i] and newLine[i] are the number of lines of each text before edits[i]
+	oldLine := make([]int, len(edits)+1)
+	newLine := make([]int, len(edits)+1)
+	for i, e := range edits {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if e.kind != '+' {
+			oldLine[i+1]++
+		}
+		if e.kind != '-' {
+			newLine[i+1]++
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- %s\n+++ %s\n", oldName, newName)
+	for i := 0; i < len(edits); {
+		for i < len(edits) && edits[i].kind == ' ' {
+			i++
+		}
+		if i == len(edits) {
+			break
+		}
+		// A hunk spans the changes separated by at most 2*context unchanged lines
+		start := max(i-context, 0)
+		end := i
+		for {
+			for end < len(edits) && edits[end].kind != ' ' {
+				end++
+			}
+			next := end
+			for next < len(edits) && edits[next].kind == ' ' && next-end < 2*context {
+				next++
+			}
+			if next == len(edits) || edits[next].kind == ' ' {
+				break
+			}
+			end = next
+		}
+		stop := min(end+context, len(edits))
+
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n",
+			hunkRange(oldLine[start], oldLine[stop]-oldLine[start]),
+			hunkRange(newLine[start], newLine[stop]-newLine[start]))
+		for _, e := range edits[start:stop] {
+			b.WriteByte(e.kind)
+			b.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = stop
+	}
+	return b.String()
+}
+
+// hunkRange formats the range of a hunk header for count lines after the
+// first lines of a text: "start,count" with a 1-based start, or "start" for a
+// single line. An empty range starts at the line before it, as in diff -u.
+func hunkRange(first, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", first)
+	case 1:
+		return fmt.Sprintf("%d", first+1)
+	}
+	return fmt.Sprintf("%d,%d", first+1, count)
+}
+
+// splitLines splits text into lines, each with its newline except a last line
+// without one.
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns the shortest edit script turning the lines a into the
+// lines b, found with the O(ND) algorithm of Myers after setting aside the
+// lines the texts start and end with.
+func diffLines(a, b []string) []edit {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]edit, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		edits = append(edits, edit{' ', line})
+	}
+	edits = append(edits, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, edit{' ', line})
+	}
+	return edits
+}
+
+// myers returns the shortest edit script turning a into b, or the
+// replacement of a by b if it takes more than maxEdits edits. v[offset+k]
+// holds the furthest x reached on diagonal k = x - y; trace[d] keeps the
+// diagonals -d..d of v as they were before d edits, to walk the path back
+// from the end.
+func myers(a, b []string) []edit {
+	n, m := len(a), len(b)
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+search:
+	for d := 0; ; d++ {
+		if d > maxEdits {
+			return replace(a, b)
+		}
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	var edits []edit
+	x, y := n, m
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && v[d+k-1] < v[d+k+1]) {
+			prevK = k + 1
+		}
+		prevX := v[d+prevK]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			edits = append(edits, edit{' ', a[x]})
+		}
+		if x == prevX {
+			y--
+			edits = append(edits, edit{'+', b[y]})
+		} else {
+			x--
+			edits = append(edits, edit{'-', a[x]})
+		}
+		x, y = prevX, prevY
+	}
+	// The path starts with the lines both texts begin with
+	for x > 0 {
+		x--
+		edits = append(edits, edit{' ', a[x]})
+	}
+	for i, j := 0, len(edits)-1; i < j; i, j = i+1, j-1 {
+		edits[i], edits[j] = edits[j], edits[i]
+	}
+	return edits
+}
+
+// replace returns the edit script deleting the lines a and inserting the
+// lines b.
+func replace(a, b []string) []edit {
+	edits := make([]edit, 0, len(a)+len(b))
+	for _, line := range a {
+		edits = append(edits, edit{'-', line})
+	}
+	for _, line := range b {
+		edits = append(edits, edit{'+', line})
+	}
+	return edits
+}
@@ -0,0 +1,118 @@
+package textdiff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numbered returns the lines "1\n" to "n\n".
+func numbered(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("%d\n", i+1)
+	}
+	return lines
+}
+
+func TestUnified(t *testing.T) {
+	twenty := numbered(20)
+	edited := append([]string(nil), twenty...)
+	edited[1] = "two\n"
+	edited[17] = "eighteen\n"
+	near := append([]string(nil), twenty...)
+	near[1] = "two\n"
+	near[7] = "eight\n"
+
+	tests := []struct {
+		name     string
+		old, new string
+		want     string
+	}{
+		{
+			name: "equal",
+			old:  "a\nb\n",
+			new:  "a\nb\n",
+			want: "",
+		},
+		{
+			name: "changed line",
+			old:  "a\nb\nc\n",
+			new:  "a\nB\nc\n",
+			want: "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name: "added file",
+			old:  "",
+			new:  "a\nb\n",
+			want: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "removed file",
+			old:  "a\n",
+			new:  "",
+			want: "--- old\n+++ new\n@@ -1 +0,0 @@\n-a\n",
+		},
+		{
+			name: "no newline at end",
+			old:  "a\nb",
+			new:  "a\nb\nc\n",
+			want: "--- old\n+++ new\n@@ -1,2 +1,3 @@\n a\n-b\n\\ No newline at end of file\n+b\n+c\n",
+		},
+		{
+			name: "insertion and deletion",
+			old:  "a\nb\nc\nd\n",
+			new:  "a\nx\nb\nd\n",
+			want: "--- old\n+++ new\n@@ -1,4 +1,4 @@\n a\n+x\n b\n-c\n d\n",
+		},
+		{
+			name: "separate hunks",
+			old:  strings.Join(twenty, ""),
+			new:  strings.Join(edited, ""),
+			want: "--- old\n+++ new\n" +
+				"@@ -1,5 +1,5 @@\n 1\n-2\n+two\n 3\n 4\n 5\n" +
+				"@@ -15,6 +15,6 @@\n 15\n 16\n 17\n-18\n+eighteen\n 19\n 20\n",
+		},
+		{
+			name: "merged hunks",
+			old:  strings.Join(twenty, ""),
+			new:  strings.Join(near, ""),
+			want: "--- old\n+++ new\n" +
+				"@@ -1,11 +1,11 @@\n 1\n-2\n+two\n 3\n 4\n 5\n 6\n 7\n-8\n+eight\n 9\n 10\n 11\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("old", "new", tt.old, tt.new, DefaultContext); got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesShortest(t *testing.T) {
+	a := strings.Split("a b c a b b a", " ")
+	b := strings.Split("c b a b a c", " ")
+	edits := diffLines(a, b)
+
+	var gotA, gotB []string
+	changes := 0
+	for _, e := range edits {
+		if e.kind != '+' {
+			gotA = append(gotA, e.line)
+		}
+		if e.kind != '-' {
+			gotB = append(gotB, e.line)
+		}
+		if e.kind != ' ' {
+			changes++
+		}
+	}
+	if strings.Join(gotA, " ") != strings.Join(a, " ") || strings.Join(gotB, " ") != strings.Join(b, " ") {
+		t.Fatalf("edits %v don't turn %v into %v", edits, a, b)
+	}
+	// The example of Myers' paper has a shortest edit script of 5 edits
+	if changes != 5 {
+		t.Errorf("got %d edits, want 5", changes)
+	}
+}
@@ -0,0 +1,159 @@
+// Package translate translates the documents of a skill.
+// This file implements the translator of the DeepL API.
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultDeepLURL is the base URL of the DeepL API Pro.
+	DefaultDeepLURL = "https://api.deepl.com"
+	// DefaultDeepLFreeURL is the base URL of the DeepL API Free, whose keys
+	// end with ":fx".
+	DefaultDeepLFreeURL = "https://api-free.deepl.com"
+)
+
+var (
+	// protectedPattern matches the parts of Markdown sent to DeepL as
+	// placeholders, not to be translated: inline code spans, the targets of
+	// links and images, and URLs.
+	protectedPattern = regexp.MustCompile("`[^`\n]*`|\\]\\([^)\n]*\\)|https?://[^\\s)>\\]]+")
+	// placeholderPattern matches the placeholders of protected parts in the
+	// translations of DeepL.
+	placeholderPattern = regexp.MustCompile(`<x i="(\d+)"\s*/>`)
+	// xmlEscaper and xmlUnescaper escape the text sent to DeepL as XML.
+	xmlEscaper   = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	xmlUnescaper = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&amp;", "&")
+)
+
+// DeepL translates documents with the DeepL API: a POST of {"text": [...],
+// "source_lang": ..., "target_lang": ...} to <URL>/v2/translate answered by
+// {"translations": [{"text": ...}]}. The text is sent as XML, its inline code,
+// link targets, and URLs replaced by <x/> tags DeepL leaves in place.
+type DeepL struct {
+	spec   Spec
+	apiKey string
+	// Client sends the requests; its default times out after 120 seconds.
+	Client *http.Client
+}
+
+// NewDeepL returns a translator calling the DeepL API at baseURL, or if it is
+// empty, DefaultDeepLFreeURL for a key of the DeepL API Free and
+// DefaultDeepLURL for others, authenticating with apiKey unless it is empty.
+func NewDeepL(baseURL, apiKey string) *DeepL {
+	if baseURL == "" {
+		baseURL = DefaultDeepLURL
+		if strings.HasSuffix(apiKey, ":fx") {
+			baseURL = DefaultDeepLFreeURL
+		}
+	}
+	return &DeepL{
+		spec:   Spec{Provider: ProviderDeepL, URL: strings.TrimRight(baseURL, "/")},
+		apiKey: apiKey,
+		Client: &http.Client{Timeout: 120 * time.Second},
+	}
+}
+
+// Spec returns the spec of the translator.
+func (p *DeepL) Spec() Spec {
+	return p.spec
+}
+
+// Translate asks DeepL for the translation of text from the language from to
+// the language to, passed as their primary subtag for the source ("EN") and
+// in full for the target ("PT-BR").
+//
+// Returns an error if the request fails, the endpoint answers with an error
+// status, or its response has no translation.
+func (p *DeepL) Translate(ctx context.Context, text, from, to string) (string, error) {
+	escaped, protected := protect(text)
+	source, _, _ := strings.Cut(strings.ReplaceAll(from, "_", "-"), "-")
+	body, err := json.Marshal(map[string]any{
+		"text":                []string{escaped},
+		"source_lang":         strings.ToUpper(source),
+		"target_lang":         strings.ToUpper(strings.ReplaceAll(to, "_", "-")),
+		"tag_handling":        "xml",
+		"preserve_formatting": true,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode translation request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.spec.URL+"/v2/translate", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create translation request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "DeepL-Auth-Key "+p.apiKey)
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to request translation: %w", err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read translation response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("failed to request translation: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	var parsed struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return "", fmt.Errorf("failed to parse translation response: %w", err)
+	}
+	if len(parsed.Translations) == 0 {
+		return "", fmt.Errorf("failed to request translation: no translation in response")
+	}
+	return restore(parsed.Translations[0].Text, protected), nil
+}
+
+// protect returns text escaped as XML, its protected parts (see
+// protectedPattern) replaced by <x i="N"/> tags, and the protected parts.
+func protect(text string) (string, []string) {
+	var (
+		b         strings.Builder
+		protected []string
+		last      int
+	)
+	for _, m := range protectedPattern.FindAllStringIndex(text, -1) {
+		b.WriteString(xmlEscaper.Replace(text[last:m[0]]))
+		fmt.Fprintf(&b, `<x i="%d"/>`, len(protected))
+		protected = append(protected, text[m[0]:m[1]])
+		last = m[1]
+	}
+	b.WriteString(xmlEscaper.Replace(text[last:]))
+	return b.String(), protected
+}
+
+// restore returns translated, the translation of text protected by protect,
+// unescaped and with its protected parts back in place of their tags.
+func restore(translated string, protected []string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range placeholderPattern.FindAllStringSubmatchIndex(translated, -1) {
+		b.WriteString(xmlUnescaper.Replace(translated[last:m[0]]))
+		if i, err := strconv.Atoi(translated[m[2]:m[3]]); err == nil && i < len(protected) {
+			b.WriteString(protected[i])
+		}
+		last = m[1]
+	}
+	b.WriteString(xmlUnescaper.Replace(translated[last:]))
+	return b.String()
+}
@@ -0,0 +1,71 @@
+// Package translate translates the documents of a skill.
+// This file implements the translator of OpenAI-compatible chat endpoints.
+package translate
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/openai"
+)
+
+// systemPrompt instructs the model of OpenAI; its %s are the languages
+// translated from and to.
+const systemPrompt = "You translate pages of technical documentation written in Markdown. " +
+	"Translate the Markdown the user sends from the language %s to the language %s. " +
+	"Keep the Markdown syntax, inline code, URLs, link targets, and HTML tags unchanged, and don't translate identifiers. " +
+	"Reply with the translated Markdown only, without a code fence around it or any comment."
+
+// OpenAI translates documents with an OpenAI-compatible chat endpoint (see
+// openai.Client.Chat).
+type OpenAI struct {
+	spec   Spec
+	client *openai.Client
+}
+
+// NewOpenAI returns a translator calling the chat endpoint of the API at
+// baseURL (openai.DefaultURL if empty) with model (DefaultOpenAIModel if
+// empty), authenticating with apiKey as a bearer token unless it is empty.
+// Its requests time out after 300 seconds.
+func NewOpenAI(baseURL, model, apiKey string) *OpenAI {
+	if model == "" {
+		model = DefaultOpenAIModel
+	}
+	client := openai.New(baseURL, apiKey, 300*time.Second)
+	return &OpenAI{spec: Spec{Provider: ProviderOpenAI, URL: client.URL, Model: model}, client: client}
+}
+
+// Spec returns the spec of the translator.
+func (p *OpenAI) Spec() Spec {
+	return p.spec
+}
+
+// Translate asks the model for the translation of text from the language from
+// to the language to, without the code fence models may wrap it in.
+//
+// Returns an error if the request fails, the endpoint answers with an error
+// status, or its response has no answer.
+func (p *OpenAI) Translate(ctx context.Context, text, from, to string) (string, error) {
+	translation, err := p.client.Chat(ctx, p.spec.Model, fmt.Sprintf(systemPrompt, from, to), text)
+	if err != nil {
+		return "", fmt.Errorf("failed to request translation: %w", err)
+	}
+	return unfence(translation), nil
+}
+
+// unfence returns answer without the ```markdown fence models sometimes wrap
+// their whole answer in.
+func unfence(answer string) string {
+	trimmed := strings.TrimSpace(answer)
+	first, rest, ok := strings.Cut(trimmed, "\n")
+	if !ok || !strings.HasPrefix(first, "```") || !slices.Contains([]string{"", "md", "markdown"}, strings.TrimSpace(first[3:])) {
+		return answer
+	}
+	if inner, ok := strings.CutSuffix(rest, "```"); ok && !strings.Contains(inner, "\n```") {
+		return strings.Trim(inner, "\n")
+	}
+	return answer
+}
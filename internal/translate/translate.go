@@ -0,0 +1,428 @@
+// Package translate translates the documents of a skill that are in another
+// language than the skill's, such as the pages a site only publishes in
+// English within its Japanese docs, so that readers of one language can use
+// the whole skill. Translated documents record the language they were
+// translated from in the translated_from field of their frontmatter.
+//
+// Translations come from a Translator:
+//
+//   - ProviderOpenAI asks a model of an OpenAI-compatible /chat/completions
+//     endpoint: the OpenAI API, or a local model served by Ollama, LM Studio,
+//     llama.cpp, or vLLM (e.g., "http://localhost:11434/v1").
+//   - ProviderDeepL calls the DeepL API, Free or Pro depending on the key.
+//
+// Any other implementation of Translator, e.g., a client of another machine
+// translation API, can be set in site2skill.Config.Translator.
+//
+// Fenced code blocks are never sent to the translator, and documents are sent
+// in pieces of at most MaxPieceLength bytes, split between paragraphs.
+package translate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/lang"
+	"github.com/f4ah6o/site2skill-go/internal/mdprose"
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+	"gopkg.in/yaml.v3"
+)
+
+// The providers of New.
+const (
+	// ProviderOpenAI asks a model of an OpenAI-compatible chat endpoint (see OpenAI).
+	ProviderOpenAI = "openai"
+	// ProviderDeepL calls the DeepL API (see DeepL).
+	ProviderDeepL = "deepl"
+)
+
+// Providers lists the providers of New.
+var Providers = []string{ProviderOpenAI, ProviderDeepL}
+
+const (
+	// DefaultOpenAIModel is the chat model used with ProviderOpenAI when none is given.
+	DefaultOpenAIModel = "gpt-4o-mini"
+
+	// APIKeyEnv is the environment variable holding the API key of the
+	// providers; OPENAI_API_KEY (ProviderOpenAI) or DEEPL_AUTH_KEY
+	// (ProviderDeepL) is used when it isn't set.
+	APIKeyEnv = "SITE2SKILL_TRANSLATION_API_KEY"
+
+	// Field is the frontmatter field recording the language a document was
+	// translated from.
+	Field = "translated_from"
+	// MaxPieceLength is the longest piece of a document sent to the
+	// translator at once, in bytes; a longer paragraph is sent whole.
+	MaxPieceLength = 8000
+)
+
+// languagePattern matches language tags: a primary subtag of two or three
+// letters and optional subtags ("ja", "pt-BR", "zh_Hant").
+var languagePattern = regexp.MustCompile(`^[A-Za-z]{2,3}([-_][A-Za-z0-9]{1,8})*$`)
+
+// ValidateLanguage returns an error if language, the language documents are
+// translated into, isn't a language tag.
+func ValidateLanguage(language string) error {
+	if !languagePattern.MatchString(language) {
+		return fmt.Errorf("invalid translation language %q: must be a language tag such as \"ja\" or \"pt-BR\"", language)
+	}
+	return nil
+}
+
+// Translator translates documents.
+type Translator interface {
+	// Translate returns text, Markdown without fenced code blocks, translated
+	// from the language from to the language to, both language tags ("en",
+	// "ja", "pt-BR"). The Markdown syntax, inline code, and link targets of
+	// text are kept.
+	Translate(ctx context.Context, text, from, to string) (string, error)
+	// Spec identifies the provider and model of the translations.
+	Spec() Spec
+}
+
+// Spec identifies the provider and model of a Translator.
+type Spec struct {
+	// Provider is ProviderOpenAI, ProviderDeepL, or the name of another translator.
+	Provider string `json:"provider"`
+	// URL is the base URL of the endpoint (e.g., openai.DefaultURL).
+	URL string `json:"url,omitempty"`
+	// Model is the chat model of ProviderOpenAI (e.g., DefaultOpenAIModel).
+	Model string `json:"model,omitempty"`
+}
+
+// String describes the spec (e.g., "openai gpt-4o-mini").
+func (s Spec) String() string {
+	if s.Model != "" {
+		return s.Provider + " " + s.Model
+	}
+	return s.Provider
+}
+
+// New returns the translator of spec, filling in its defaults:
+// openai.DefaultURL and DefaultOpenAIModel for ProviderOpenAI, and the DeepL
+// API Free or Pro endpoint of apiKey for ProviderDeepL. Both authenticate with
+// apiKey (none if empty).
+//
+// Returns an error if the provider is unknown, or if spec has a model for
+// ProviderDeepL, which has none.
+func New(spec Spec, apiKey string) (Translator, error) {
+	switch spec.Provider {
+	case ProviderOpenAI:
+		return NewOpenAI(spec.URL, spec.Model, apiKey), nil
+	case ProviderDeepL:
+		if spec.Model != "" {
+			return nil, fmt.Errorf("the %s translation provider takes no model", ProviderDeepL)
+		}
+		return NewDeepL(spec.URL, apiKey), nil
+	}
+	return nil, fmt.Errorf("unknown translation provider %q (expected %s)", spec.Provider, strings.Join(Providers, " or "))
+}
+
+// APIKey returns the API key of provider from the environment: APIKeyEnv, or
+// else OPENAI_API_KEY for ProviderOpenAI and DEEPL_AUTH_KEY for ProviderDeepL.
+func APIKey(provider string) string {
+	if key := os.Getenv(APIKeyEnv); key != "" {
+		return key
+	}
+	switch provider {
+	case ProviderOpenAI:
+		return os.Getenv("OPENAI_API_KEY")
+	case ProviderDeepL:
+		return os.Getenv("DEEPL_AUTH_KEY")
+	}
+	return ""
+}
+
+// Key returns the key of the translation of a document in the previous
+// translations passed to Dir: its content hash (the content_hash of its
+// frontmatter) and its part, for a page split into parts. Returns "" for a
+// document without a content hash, whose translation can't be reused.
+func Key(contentHash string, part int) string {
+	if contentHash == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s#%d", contentHash, part)
+}
+
+// Stats reports what Dir did.
+type Stats struct {
+	// Documents is the number of Markdown files of the directory in another
+	// language than the target language.
+	Documents int
+	// Translated is the number of documents translated by the translator.
+	Translated int
+	// Reused is the number of documents given their previous translation.
+	Reused int
+	// Failed is the number of documents the translator failed to translate,
+	// which are left in their language.
+	Failed int
+}
+
+// Dir translates the Markdown files of dir in another language than to into
+// to. The language of a document is its detected_language, or else the
+// language of its locale; documents in neither, or already translated, are
+// left as they are.
+//
+// The translation is the file in previous under the Key of the document, if
+// it was translated into to, which spares translating the pages unchanged
+// since the last build, or else the one t writes. The title of a translated
+// document is its translated H1 heading when its body starts with one. Its
+// frontmatter records the original language as translated_from (see Field)
+// and to as its locale, and loses its detected_language.
+//
+// A document t fails to translate is reported as a warning and left in its
+// language. Returns ctx's error once it is cancelled, or an error if the files
+// can't be read or written.
+func Dir(ctx context.Context, dir string, t Translator, to string, previous map[string]string) (*Stats, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.md"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find markdown files: %w", err)
+	}
+	target := lang.Language(to)
+	stats := &Stats{}
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		d, err := readDoc(file)
+		if err != nil {
+			return nil, err
+		}
+		if d == nil || d.fields.TranslatedFrom != "" {
+			continue
+		}
+		from := d.fields.DetectedLanguage
+		if from == "" {
+			from = d.fields.Locale
+		}
+		if from == "" || lang.Language(from) == target {
+			continue
+		}
+		stats.Documents++
+
+		title, body, ok := previousTranslation(previous[Key(d.fields.ContentHash, d.fields.Part)], target)
+		if ok {
+			stats.Reused++
+		} else {
+			body, err = translateBody(ctx, t, d.body, from, to)
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return nil, ctxErr
+			}
+			if err != nil {
+				warnlog.Printf("translation", "Warning: failed to translate %s: %v", file, err)
+				stats.Failed++
+				continue
+			}
+			title = d.fields.Title
+			if leadingHeading(d.body) != "" && leadingHeading(body) != "" {
+				title = leadingHeading(body)
+			}
+			stats.Translated++
+		}
+		if err := d.writeTranslation(title, body, from, to); err != nil {
+			return nil, err
+		}
+	}
+	return stats, nil
+}
+
+// previousTranslation returns the title and body of the document at path if
+// it was translated into the language target; ok is false otherwise, or if
+// path is empty or can't be read.
+func previousTranslation(path, target string) (title, body string, ok bool) {
+	if path == "" {
+		return "", "", false
+	}
+	d, err := readDoc(path)
+	if err != nil || d == nil || d.fields.TranslatedFrom == "" || lang.Language(d.fields.Locale) != target {
+		return "", "", false
+	}
+	return d.fields.Title, d.body, true
+}
+
+// translateBody translates body, Markdown, piece by piece (see pieces),
+// keeping its fenced code blocks and the blank lines it starts with as they
+// are.
+func translateBody(ctx context.Context, t Translator, body, from, to string) (string, error) {
+	var b strings.Builder
+	b.WriteString(body[:len(body)-len(strings.TrimLeft(body, "\n"))])
+	for i, p := range pieces(body, MaxPieceLength) {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		if p.code {
+			b.WriteString(p.text)
+			continue
+		}
+		translated, err := t.Translate(ctx, p.text, from, to)
+		if err != nil {
+			return "", err
+		}
+		if translated = strings.Trim(translated, "\n"); strings.TrimSpace(translated) == "" {
+			return "", fmt.Errorf("empty translation")
+		}
+		b.WriteString(translated)
+	}
+	return b.String() + "\n", nil
+}
+
+// piece is a part of a document sent to the translator, or a fenced code
+// block kept as it is.
+type piece struct {
+	text string
+	code bool
+}
+
+// pieces splits markdown into its fenced code blocks and the runs of
+// paragraphs between them, of at most max bytes unless a single paragraph is
+// longer. Joined by blank lines, the pieces make up markdown again, save for
+// the blank lines around them.
+func pieces(markdown string, max int) []piece {
+	var (
+		result []piece
+		prose  []string
+		size   int
+		block  []string
+		fence  string
+	)
+	flushProse := func() {
+		if len(prose) > 0 {
+			result = append(result, piece{text: strings.Join(prose, "\n\n")})
+			prose, size = nil, 0
+		}
+	}
+	addParagraph := func() {
+		if len(block) == 0 {
+			return
+		}
+		paragraph := strings.Join(block, "\n")
+		block = nil
+		if size > 0 && size+2+len(paragraph) > max {
+			flushProse()
+		}
+		prose = append(prose, paragraph)
+		size += len(paragraph) + 2
+	}
+	for _, line := range strings.Split(strings.Trim(markdown, "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			block = append(block, line)
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				result = append(result, piece{text: strings.Join(block, "\n"), code: true})
+				block, fence = nil, ""
+			}
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			addParagraph()
+			flushProse()
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+			block = []string{line}
+		case trimmed == "":
+			addParagraph()
+		default:
+			block = append(block, line)
+		}
+	}
+	if fence != "" {
+		result = append(result, piece{text: strings.Join(block, "\n"), code: true})
+		block = nil
+	}
+	addParagraph()
+	flushProse()
+	return result
+}
+
+// leadingHeading returns the text of the H1 heading markdown starts with, or
+// "" if its first line isn't one.
+func leadingHeading(markdown string) string {
+	line, _, _ := strings.Cut(strings.TrimLeft(markdown, "\n"), "\n")
+	if heading, ok := strings.CutPrefix(line, "# "); ok {
+		return strings.TrimSpace(heading)
+	}
+	return ""
+}
+
+// doc is a Markdown file with YAML frontmatter.
+type doc struct {
+	path string
+	// meta is the mapping node of the frontmatter, rewritten with the translation
+	meta *yaml.Node
+	// fields are the frontmatter fields read by Dir
+	fields struct {
+		Title            string `yaml:"title"`
+		Locale           string `yaml:"locale"`
+		DetectedLanguage string `yaml:"detected_language"`
+		TranslatedFrom   string `yaml:"translated_from"`
+		ContentHash      string `yaml:"content_hash"`
+		Part             int    `yaml:"part"`
+	}
+	body string
+}
+
+// readDoc reads the Markdown file at path, or returns nil if it has no
+// frontmatter, or one that isn't a YAML mapping.
+func readDoc(path string) (*doc, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	frontmatter, body, ok := mdprose.SplitFrontmatter(string(content))
+	if !ok {
+		return nil, nil
+	}
+	var meta yaml.Node
+	d := &doc{path: path, body: body}
+	if yaml.Unmarshal([]byte(frontmatter), &meta) != nil || len(meta.Content) != 1 ||
+		meta.Content[0].Kind != yaml.MappingNode || meta.Decode(&d.fields) != nil {
+		return nil, nil
+	}
+	d.meta = meta.Content[0]
+	return d, nil
+}
+
+// writeTranslation rewrites the file of d with body, translated from the
+// language from into to, and title: to becomes the locale of its frontmatter,
+// followed by from as its translated_from field, and its detected_language is
+// removed.
+func (d *doc) writeTranslation(title, body, from, to string) error {
+	str := func(value string) *yaml.Node {
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value, Style: yaml.DoubleQuotedStyle}
+	}
+	var content []*yaml.Node
+	hasLocale := false
+	for i := 0; i+1 < len(d.meta.Content); i += 2 {
+		key, value := d.meta.Content[i], d.meta.Content[i+1]
+		switch key.Value {
+		case "detected_language":
+			continue
+		case "title":
+			value = str(title)
+		case "locale":
+			value, hasLocale = str(to), true
+			content = append(content, key, value, &yaml.Node{Kind: yaml.ScalarNode, Value: Field}, str(from))
+			continue
+		}
+		content = append(content, key, value)
+	}
+	if !hasLocale {
+		content = append(content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: "locale"}, str(to),
+			&yaml.Node{Kind: yaml.ScalarNode, Value: Field}, str(from))
+	}
+	d.meta.Content = content
+
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(d.meta); err != nil {
+		return fmt.Errorf("failed to write frontmatter of %s: %w", d.path, err)
+	}
+	if err := os.WriteFile(d.path, []byte("---\n"+b.String()+"---\n"+body), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", d.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOpenAI(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Model != "test-model" || len(req.Messages) != 2 {
+			http.Error(w, "bad body", http.StatusBadRequest)
+			return
+		}
+		if !strings.Contains(req.Messages[0].Content, "from the language en to the language ja") || req.Messages[1].Content != "# Install\n\nRun `make`." {
+			http.Error(w, "bad prompt", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"choices": []any{map[string]any{"message": map[string]string{"content": "```markdown\n# インストール\n\n`make` を実行します。\n```"}}}})
+	}))
+	defer srv.Close()
+
+	p := NewOpenAI(srv.URL+"/v1/", "test-model", "secret")
+	if got := p.Spec(); got != (Spec{Provider: ProviderOpenAI, URL: srv.URL + "/v1", Model: "test-model"}) {
+		t.Errorf("Spec() = %+v", got)
+	}
+	got, err := p.Translate(context.Background(), "# Install\n\nRun `make`.", "en", "ja")
+	if err != nil {
+		t.Fatalf("Translate() error = %v", err)
+	}
+	if want := "# インストール\n\n`make` を実行します。"; got != want {
+		t.Errorf("Translate() = %q, want %q", got, want)
+	}
+
+	if _, err := NewOpenAI(srv.URL+"/v1", "test-model", "wrong").Translate(context.Background(), "Hello.", "en", "ja"); err == nil {
+		t.Error("Translate() with a wrong key: error = nil, want an error")
+	}
+}
+
+func TestDeepL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/translate" || r.Header.Get("Authorization") != "DeepL-Auth-Key secret:fx" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			Text        []string `json:"text"`
+			SourceLang  string   `json:"source_lang"`
+			TargetLang  string   `json:"target_lang"`
+			TagHandling string   `json:"tag_handling"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.SourceLang != "EN" || req.TargetLang != "PT-BR" || req.TagHandling != "xml" {
+			http.Error(w, "bad body", http.StatusBadRequest)
+			return
+		}
+		if want := `Run <x i="0"/> if a &lt; b, see [the guide<x i="1"/>.`; len(req.Text) != 1 || req.Text[0] != want {
+			http.Error(w, "bad text", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"translations": []any{map[string]string{"text": `Execute <x i="0"/> se a &lt; b, veja [o guia<x i="1"/>.`}}})
+	}))
+	defer srv.Close()
+
+	if got := NewDeepL("", "key:fx").Spec().URL; got != DefaultDeepLFreeURL {
+		t.Errorf("Spec().URL of a free key = %q, want %q", got, DefaultDeepLFreeURL)
+	}
+	if got := NewDeepL("", "key").Spec().URL; got != DefaultDeepLURL {
+		t.Errorf("Spec().URL of a pro key = %q, want %q", got, DefaultDeepLURL)
+	}
+
+	p := NewDeepL(srv.URL, "secret:fx")
+	got, err := p.Translate(context.Background(), "Run `make` if a < b, see [the guide](https://example.com/guide).", "en-US", "pt-BR")
+	if err != nil {
+		t.Fatalf("Translate() error = %v", err)
+	}
+	if want := "Execute `make` se a < b, veja [o guia](https://example.com/guide)."; got != want {
+		t.Errorf("Translate() = %q, want %q", got, want)
+	}
+
+	if _, err := NewDeepL(srv.URL, "wrong").Translate(context.Background(), "Hello.", "en", "ja"); err == nil {
+		t.Error("Translate() with a wrong key: error = nil, want an error")
+	}
+	if _, err := New(Spec{Provider: ProviderDeepL, Model: "any"}, ""); err == nil {
+		t.Error("New() of deepl with a model: error = nil, want an error")
+	}
+}
+
+func TestPieces(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		max      int
+		want     []piece
+	}{
+		{
+			name:     "code blocks kept apart",
+			markdown: "\n# Title\n\nIntro.\n\n```go\nfunc main() {\n\n}\n```\n\nOutro.\n",
+			max:      100,
+			want:     []piece{{text: "# Title\n\nIntro."}, {text: "```go\nfunc main() {\n\n}\n```", code: true}, {text: "Outro."}},
+		},
+		{
+			name:     "split between paragraphs",
+			markdown: "One one.\n\nTwo two.\nStill two.\n\nThree.",
+			max:      20,
+			want:     []piece{{text: "One one."}, {text: "Two two.\nStill two."}, {text: "Three."}},
+		},
+		{
+			name:     "longer fence",
+			markdown: "````md\n```\nnot closed\n```\n````\nAfter.",
+			max:      100,
+			want:     []piece{{text: "````md\n```\nnot closed\n```\n````", code: true}, {text: "After."}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := pieces(tt.markdown, tt.max)
+			if len(got) != len(tt.want) {
+				t.Fatalf("pieces() = %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("pieces()[%d] = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// fakeTranslator "translates" text by upper-casing it, failing on text containing "broken".
+type fakeTranslator struct {
+	calls []string
+}
+
+func (f *fakeTranslator) Translate(_ context.Context, text, from, to string) (string, error) {
+	f.calls = append(f.calls, from+">"+to+": "+text)
+	if strings.Contains(text, "broken") {
+		return "", errors.New("service unavailable")
+	}
+	return strings.ToUpper(text), nil
+}
+
+func (f *fakeTranslator) Spec() Spec {
+	return Spec{Provider: "fake"}
+}
+
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+	previous := filepath.Join(t.TempDir(), "auth.md")
+	files := map[string]string{
+		"install.md":  "---\ntitle: \"Install\"\nlocale: \"en\"\ncontent_hash: \"sha256:aaa\"\n---\n\n# Install\n\nRun it.\n\n```sh\nmake install\n```\n",
+		"detected.md": "---\ntitle: \"Guide\"\nlocale: \"ja\"\ndetected_language: \"fr\"\n---\n\nBonjour.\n",
+		"auth.md":     "---\ntitle: \"Auth\"\nlocale: \"en-US\"\ncontent_hash: \"sha256:bbb\"\npart: 2\n---\n\n# Auth\n",
+		"native.md":   "---\ntitle: \"ようこそ\"\nlocale: \"ja-JP\"\n---\n\n# ようこそ\n",
+		"unknown.md":  "---\ntitle: \"Unknown\"\n---\n\n# Unknown\n",
+		"broken.md":   "---\ntitle: \"Broken\"\nlocale: \"en\"\n---\n\nThis is broken.\n",
+		"done.md":     "---\ntitle: \"Done\"\nlocale: \"ja\"\ntranslated_from: \"en\"\n---\n\n# Done\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(previous, []byte("---\ntitle: \"認証\"\nlocale: \"ja\"\ntranslated_from: \"en-US\"\n---\n\n# 認証\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tr := &fakeTranslator{}
+	stats, err := Dir(context.Background(), dir, tr, "ja", map[string]string{Key("sha256:bbb", 2): previous})
+	if err != nil {
+		t.Fatalf("Dir() error = %v", err)
+	}
+	if want := (Stats{Documents: 4, Translated: 2, Reused: 1, Failed: 1}); *stats != want {
+		t.Errorf("Dir() = %+v, want %+v", *stats, want)
+	}
+	if want := []string{"en>ja: This is broken.", "fr>ja: Bonjour.", "en>ja: # Install\n\nRun it."}; strings.Join(tr.calls, "|") != strings.Join(want, "|") {
+		t.Errorf("translator calls = %q, want %q", tr.calls, want)
+	}
+
+	want := map[string]string{
+		"install.md":  "---\ntitle: \"INSTALL\"\nlocale: \"ja\"\ntranslated_from: \"en\"\ncontent_hash: \"sha256:aaa\"\n---\n\n# INSTALL\n\nRUN IT.\n\n```sh\nmake install\n```\n",
+		"detected.md": "---\ntitle: \"Guide\"\nlocale: \"ja\"\ntranslated_from: \"fr\"\n---\n\nBONJOUR.\n",
+		"auth.md":     "---\ntitle: \"認証\"\nlocale: \"ja\"\ntranslated_from: \"en-US\"\ncontent_hash: \"sha256:bbb\"\npart: 2\n---\n\n# 認証\n",
+		"native.md":   files["native.md"],
+		"unknown.md":  files["unknown.md"],
+		"broken.md":   files["broken.md"],
+		"done.md":     files["done.md"],
+	}
+	for name, content := range want {
+		got, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != content {
+			t.Errorf("%s =\n%s\nwant\n%s", name, got, content)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := Dir(ctx, dir, tr, "ja", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Dir() with a cancelled context: error = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,181 @@
+// Package urlrewrite rewrites the URLs of converted Markdown files with
+// configurable rules, so that skills built from a pre-release staging site
+// present production URLs: in the frontmatter (source_url, canonical_url,
+// final_url, aliases) and in the links of the document.
+//
+// A rule is written "FROM=TO". FROM is either a host, matching the URLs of
+// that host on any port, or a URL prefix:
+//
+//	staging.docs.internal=docs.example.com            # host only; scheme and path are kept
+//	staging.docs.internal=https://docs.example.com    # host and scheme
+//	https://staging.example.com/v2/=https://docs.example.com/   # URL prefix
+package urlrewrite
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/f4ah6o/site2skill-go/internal/idn"
+)
+
+// urlPattern matches absolute http(s) URLs in Markdown, YAML, and HTML text.
+var urlPattern = regexp.MustCompile(`(?i)https?://[^\s<>()\[\]"'` + "`" + `]+`)
+
+// Rule rewrites the URLs matching From.
+type Rule struct {
+	// From is a host (with an optional port) or an absolute URL prefix.
+	From string
+	// To replaces the matched host, or the scheme and host if it has a
+	// scheme, for a host rule; it replaces the matched prefix for a URL prefix rule.
+	To string
+}
+
+// ParseRule parses a rule written "FROM=TO".
+//
+// Internationalized hosts and paths may be written in Unicode: they are
+// converted to the punycode and percent-encoded form of the URLs in documents
+// (see idn.ToASCII).
+//
+// Returns an error if either side is empty, if a URL prefix FROM is rewritten
+// to a host, or if a host contains a path.
+func ParseRule(s string) (Rule, error) {
+	from, to, ok := strings.Cut(s, "=")
+	r := Rule{From: requestForm(strings.TrimSpace(from)), To: requestForm(strings.TrimSpace(to))}
+	if !ok || r.From == "" || r.To == "" {
+		return Rule{}, fmt.Errorf("invalid rewrite rule %q: want FROM=TO", s)
+	}
+	if r.isPrefix() {
+		if !strings.Contains(r.To, "://") {
+			return Rule{}, fmt.Errorf("invalid rewrite rule %q: a URL prefix must be rewritten to a URL", s)
+		}
+		return r, nil
+	}
+	if strings.ContainsAny(r.From, "/?#") {
+		return Rule{}, fmt.Errorf("invalid rewrite rule %q: %q is neither a host nor a URL", s, r.From)
+	}
+	if !strings.Contains(r.To, "://") && strings.ContainsAny(r.To, "/?#") {
+		return Rule{}, fmt.Errorf("invalid rewrite rule %q: %q is neither a host nor a URL", s, r.To)
+	}
+	return r, nil
+}
+
+// ParseRules parses rules written "FROM=TO" (see ParseRule).
+func ParseRules(rules []string) ([]Rule, error) {
+	parsed := make([]Rule, 0, len(rules))
+	for _, s := range rules {
+		r, err := ParseRule(s)
+		if err != nil {
+			return nil, err
+		}
+		parsed = append(parsed, r)
+	}
+	return parsed, nil
+}
+
+// requestForm returns the host or URL s in its request form if it has
+// non-ASCII characters, or s unchanged.
+func requestForm(s string) string {
+	if !strings.ContainsFunc(s, func(r rune) bool { return r >= utf8.RuneSelf }) {
+		return s
+	}
+	if strings.Contains(s, "://") {
+		return idn.ToASCII(s)
+	}
+	return idn.HostToASCII(s)
+}
+
+// isPrefix reports whether the rule matches a URL prefix rather than a host.
+func (r Rule) isPrefix() bool {
+	return strings.Contains(r.From, "://")
+}
+
+// apply returns u rewritten by the rule, and whether the rule matched.
+func (r Rule) apply(u string) (string, bool) {
+	if r.isPrefix() {
+		if len(u) >= len(r.From) && strings.EqualFold(u[:len(r.From)], r.From) {
+			return r.To + u[len(r.From):], true
+		}
+		return u, false
+	}
+
+	scheme, rest, ok := strings.Cut(u, "://")
+	if !ok {
+		return u, false
+	}
+	end := strings.IndexAny(rest, "/?#")
+	if end < 0 {
+		end = len(rest)
+	}
+	host, path := rest[:end], rest[end:]
+	hostname := host
+	if !strings.Contains(r.From, ":") {
+		// A host without port matches it on any port
+		if i := strings.LastIndex(host, ":"); i >= 0 && !strings.HasSuffix(host, "]") {
+			hostname = host[:i]
+		}
+	}
+	if !strings.EqualFold(hostname, r.From) {
+		return u, false
+	}
+	if strings.Contains(r.To, "://") {
+		return strings.TrimSuffix(r.To, "/") + path, true
+	}
+	return scheme + "://" + r.To + path, true
+}
+
+// Rewriter applies rewrite rules to URLs.
+type Rewriter struct {
+	rules []Rule
+}
+
+// New returns a Rewriter applying rules. A URL is rewritten by the first rule
+// matching it.
+func New(rules []Rule) *Rewriter {
+	return &Rewriter{rules: rules}
+}
+
+// URL returns u rewritten by the first matching rule, or u unchanged.
+func (r *Rewriter) URL(u string) string {
+	for _, rule := range r.rules {
+		if rewritten, ok := rule.apply(u); ok {
+			return rewritten
+		}
+	}
+	return u
+}
+
+// Rewrite rewrites every absolute http(s) URL in content, including those in
+// frontmatter and code, and returns the result and the number of URLs rewritten.
+func (r *Rewriter) Rewrite(content string) (string, int) {
+	count := 0
+	rewritten := urlPattern.ReplaceAllStringFunc(content, func(u string) string {
+		// Punctuation ending a sentence isn't part of the URL
+		trimmed := strings.TrimRight(u, ".,;:!?*_")
+		if out := r.URL(trimmed); out != trimmed {
+			count++
+			return out + u[len(trimmed):]
+		}
+		return u
+	})
+	return rewritten, count
+}
+
+// RewriteFile rewrites the URLs of the file at path in place (see Rewrite)
+// and returns the number of URLs rewritten.
+func (r *Rewriter) RewriteFile(path string) (int, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	rewritten, count := r.Rewrite(string(content))
+	if count == 0 {
+		return 0, nil
+	}
+	if err := os.WriteFile(path, []byte(rewritten), 0644); err != nil {
+		return 0, fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return count, nil
+}
@@ -0,0 +1,92 @@
+package urlrewrite
+
+import "testing"
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		rule    string
+		want    Rule
+		wantErr bool
+	}{
+		{"staging.docs.internal=docs.example.com", Rule{"staging.docs.internal", "docs.example.com"}, false},
+		{" staging.docs.internal = https://docs.example.com ", Rule{"staging.docs.internal", "https://docs.example.com"}, false},
+		{"https://staging.example.com/v2/=https://docs.example.com/", Rule{"https://staging.example.com/v2/", "https://docs.example.com/"}, false},
+		{"https://staging.example.com/=docs.example.com", Rule{}, true},
+		{"staging.example.com/docs=docs.example.com", Rule{}, true},
+		{"staging.example.com=docs.example.com/docs", Rule{}, true},
+		{"staging.example.com=", Rule{}, true},
+		{"staging.example.com", Rule{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			got, err := ParseRule(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseRule() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	rules, err := ParseRules([]string{
+		"https://staging.example.com/v2/=https://docs.example.com/",
+		"staging.docs.internal=docs.example.com",
+		"preview.internal:8080=https://example.com",
+		"ステージング.例え.jp=例え.jp",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := New(rules)
+
+	tests := []struct {
+		name      string
+		content   string
+		want      string
+		wantCount int
+	}{
+		{
+			name:      "frontmatter",
+			content:   "source_url: \"http://staging.docs.internal:8443/guide\"\n",
+			want:      "source_url: \"http://docs.example.com/guide\"\n",
+			wantCount: 1,
+		},
+		{
+			name:      "link and sentence punctuation",
+			content:   "See [the API](https://STAGING.docs.internal/api#auth). Or https://staging.example.com/v2/intro.",
+			want:      "See [the API](https://docs.example.com/api#auth). Or https://docs.example.com/intro.",
+			wantCount: 2,
+		},
+		{
+			name:      "host with port and scheme",
+			content:   "<http://preview.internal:8080/docs?x=1>",
+			want:      "<https://example.com/docs?x=1>",
+			wantCount: 1,
+		},
+		{
+			name:      "internationalized host",
+			content:   "[はじめに](https://xn--qckpc6c8myb.xn--r8jz45g.jp/%E3%81%AF%E3%81%98%E3%82%81%E3%81%AB)",
+			want:      "[はじめに](https://xn--r8jz45g.jp/%E3%81%AF%E3%81%98%E3%82%81%E3%81%AB)",
+			wantCount: 1,
+		},
+		{
+			name:      "other hosts and ports",
+			content:   "https://docs.example.com/ http://preview.internal:9090/ https://staging.docs.internal.example.org/",
+			want:      "https://docs.example.com/ http://preview.internal:9090/ https://staging.docs.internal.example.org/",
+			wantCount: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, count := r.Rewrite(tt.content)
+			if got != tt.want || count != tt.wantCount {
+				t.Errorf("Rewrite() = %q, %d; want %q, %d", got, count, tt.want, tt.wantCount)
+			}
+		})
+	}
+}
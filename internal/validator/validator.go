@@ -4,137 +4,414 @@
 package validator
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
-	"regexp"
 	"sort"
 	"strings"
+	"unicode/utf8"
+
+	"github.com/f4ah6o/site2skill-go/internal/links"
+	"github.com/f4ah6o/site2skill-go/internal/mdprose"
+	"gopkg.in/yaml.v3"
+)
+
+// Issue severities. Errors fail validation; warnings are reported only,
+// unless validation is strict.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
 )
 
+// claudeSizeLimit is the largest uncompressed size of a skill Claude loads.
+const claudeSizeLimit = 8 * 1024 * 1024
+
+// Schema describes what a valid skill holds beyond its structure (SKILL.md,
+// manifest.json, and docs/): the frontmatter fields of its documents and the
+// limits of its size.
+type Schema struct {
+	// RequiredFields are the frontmatter fields every document must have,
+	// with a value.
+	RequiredFields []string
+	// MaxTotalBytes is the largest total size of the files of the skill; 0
+	// for no limit.
+	MaxTotalBytes int64
+	// MaxFileBytes is the largest size of a document; 0 for no limit.
+	MaxFileBytes int64
+	// MaxDocuments is the largest number of documents; 0 for no limit.
+	MaxDocuments int
+}
+
+// DefaultSchema returns the schema of the skills site2skill generates: every
+// document has a title, source_url, and fetched_at, and no size limit is
+// enforced beyond the warning for Claude's 8MB limit.
+func DefaultSchema() Schema {
+	return Schema{RequiredFields: []string{"title", "source_url", "fetched_at"}}
+}
+
+// Issue is a problem found in a skill.
+type Issue struct {
+	// Severity is SeverityError or SeverityWarning.
+	Severity string `json:"severity"`
+	// File is the slash-separated path of the file the issue is about,
+	// relative to the skill directory; empty for the skill as a whole.
+	File string `json:"file,omitempty"`
+	// Message describes the issue.
+	Message string `json:"message"`
+}
+
+// String returns the issue as "file: severity: message".
+func (i Issue) String() string {
+	if i.File == "" {
+		return i.Severity + ": " + i.Message
+	}
+	return i.File + ": " + i.Severity + ": " + i.Message
+}
+
+// Report is the result of checking a skill.
+type Report struct {
+	// Documents is the number of documents in docs/.
+	Documents int `json:"documents"`
+	// Bytes is the total size of the files of the skill.
+	Bytes int64 `json:"bytes"`
+	// Issues lists the problems found, errors and warnings, in the order
+	// they were found.
+	Issues []Issue `json:"issues"`
+}
+
+// Errors returns the number of errors of the report.
+func (r *Report) Errors() int {
+	return r.count(SeverityError)
+}
+
+// Warnings returns the number of warnings of the report.
+func (r *Report) Warnings() int {
+	return r.count(SeverityWarning)
+}
+
+// count returns the number of issues of the severity.
+func (r *Report) count(severity string) int {
+	n := 0
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			n++
+		}
+	}
+	return n
+}
+
+// add records an issue of the severity about the file.
+func (r *Report) add(severity, file, format string, args ...any) {
+	r.Issues = append(r.Issues, Issue{Severity: severity, File: file, Message: fmt.Sprintf(format, args...)})
+}
+
 // Validator validates skill directory structures and content to ensure they meet
 // platform requirements and are properly formatted for use with Claude or Codex.
 // It checks for required files, valid frontmatter, and size constraints.
-type Validator struct{}
+type Validator struct {
+	// schema is what the skills checked must hold
+	schema Schema
+}
 
 // New creates a new Validator instance.
 //
-// Returns a Validator ready to validate skill directories.
+// Returns a Validator ready to validate skill directories against DefaultSchema.
 func New() *Validator {
-	return &Validator{}
+	return &Validator{schema: DefaultSchema()}
 }
 
-// Validate performs comprehensive validation of a skill directory structure and content.
-//
-// Validation checks:
-//  1. Directory existence
-//  2. SKILL.md file presence and frontmatter (name, description fields)
-//  3. docs/ directory with at least one .md file
-//  4. Optional scripts/ directory detection
-//  5. Size analysis (warns if > 8MB uncompressed for Claude compatibility)
+// SetSchema makes the validator check skills against schema instead of
+// DefaultSchema.
+func (v *Validator) SetSchema(schema Schema) {
+	v.schema = schema
+}
+
+// Validate performs comprehensive validation of a skill directory structure and
+// content (see Check), logging every issue and a size analysis of the skill
+// (warning if it is over 8MB uncompressed, for Claude compatibility).
 //
 // Parameters:
 //   - skillDir: Path to the skill directory root to validate
 //
 // Returns:
-//   - true if all required checks pass (warnings are logged but don't fail validation)
-//   - false if any critical checks fail (missing required files or directories)
-//
-// The validator logs detailed information about each check, including warnings for
-// non-critical issues like missing frontmatter fields or size concerns.
+//   - true if no check found an error (warnings are logged but don't fail validation)
+//   - false if any did, or the skill can't be read
 func (v *Validator) Validate(skillDir string) bool {
 	log.Printf("Validating skill in: %s", skillDir)
 
-	var errors []string
-	var warnings []string
-
-	// 1. Check directory existence
-	if info, err := os.Stat(skillDir); os.IsNotExist(err) || !info.IsDir() {
-		log.Printf("Error: Directory not found: %s", skillDir)
+	report, err := v.Check(skillDir)
+	if err != nil {
+		log.Printf("Error: %v", err)
 		return false
 	}
+	log.Printf("  %d documents, %.2f MB", report.Documents, float64(report.Bytes)/(1024*1024))
 
-	// 2. Check SKILL.md
-	skillMDPath := filepath.Join(skillDir, "SKILL.md")
-	if _, err := os.Stat(skillMDPath); os.IsNotExist(err) {
-		errors = append(errors, "SKILL.md not found.")
-	} else {
-		log.Printf("Found SKILL.md")
-		// Validate frontmatter
-		content, err := os.ReadFile(skillMDPath)
-		if err == nil {
-			if strings.HasPrefix(string(content), "---\n") {
-				re := regexp.MustCompile(`(?s)^---\n(.*?)\n---`)
-				matches := re.FindStringSubmatch(string(content))
-				if len(matches) > 1 {
-					frontmatter := matches[1]
-					requiredFields := []string{"name", "description"}
-					for _, field := range requiredFields {
-						if !strings.Contains(frontmatter, field+":") {
-							warnings = append(warnings, fmt.Sprintf("SKILL.md frontmatter missing '%s' field", field))
-						}
-					}
-					log.Printf("  YAML frontmatter present")
-				} else {
-					warnings = append(warnings, "SKILL.md has incomplete frontmatter")
-				}
-			} else {
-				warnings = append(warnings, "SKILL.md missing YAML frontmatter")
+	v.checkSkillSize(skillDir)
+
+	if report.Errors() > 0 {
+		log.Printf("VALIDATION FAILED:")
+		for _, issue := range report.Issues {
+			if issue.Severity == SeverityError {
+				log.Printf("  - %s", issue)
 			}
 		}
+		return false
+	}
+
+	if report.Warnings() > 0 {
+		log.Printf("Warnings:")
+		for _, issue := range report.Issues {
+			log.Printf("  - %s", issue)
+		}
+	}
+
+	log.Printf("Validation passed!")
+	return true
+}
+
+// Check checks the skill in skillDir against the schema of the validator:
+//  1. SKILL.md exists, with a frontmatter naming and describing the skill
+//     (a warning otherwise, since Codex skills have none);
+//  2. manifest.json exists, is well-formed, names the skill, and lists
+//     documents that exist; documents missing from it are warnings;
+//  3. docs/ exists, and each of its documents is non-empty, not truncated
+//     (an unterminated frontmatter or code block, or invalid UTF-8), and has
+//     a well-formed frontmatter with the required fields;
+//  4. the links and images of the Markdown files pointing inside the skill
+//     reach existing files (see links.Check); missing heading anchors are
+//     warnings;
+//  5. the number of documents and the sizes of the documents and of the
+//     whole skill are within the limits of the schema.
+//
+// Returns an error if skillDir isn't a directory or can't be read.
+func (v *Validator) Check(skillDir string) (*Report, error) {
+	if info, err := os.Stat(skillDir); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("skill directory not found: %s", skillDir)
 	}
+	report := &Report{Issues: []Issue{}}
+
+	// 1. SKILL.md
+	checkSkillMD(skillDir, report)
+
+	// 2. manifest.json
+	listed := checkManifest(skillDir, report)
 
-	// 3. Check docs/ directory
+	// 3. docs/
 	docsDir := filepath.Join(skillDir, "docs")
-	if info, err := os.Stat(docsDir); os.IsNotExist(err) || !info.IsDir() {
-		errors = append(errors, "docs/ directory not found.")
-	} else {
-		log.Printf("Found docs/")
+	if info, err := os.Stat(docsDir); err != nil || !info.IsDir() {
+		report.add(SeverityError, "docs", "directory not found")
+	} else if err := v.checkDocuments(skillDir, docsDir, listed, report); err != nil {
+		return nil, err
+	}
 
-		// Count markdown files
-		mdFiles := []string{}
-		filepath.Walk(docsDir, func(path string, info os.FileInfo, err error) error {
-			if err == nil && !info.IsDir() && filepath.Ext(path) == ".md" {
-				mdFiles = append(mdFiles, path)
-			}
-			return nil
-		})
+	// 4. Links and images
+	linkReport, err := links.Check(skillDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range linkReport.Broken {
+		severity := SeverityError
+		if b.Reason == links.ReasonMissingAnchor {
+			severity = SeverityWarning
+		}
+		report.add(severity, b.File, "line %d: broken link to %s (%s)", b.Line, b.Target, b.Reason)
+	}
 
-		if len(mdFiles) == 0 {
-			warnings = append(warnings, "docs/ directory is empty (no .md files)")
-		} else {
-			log.Printf("  %d markdown files", len(mdFiles))
+	// 5. Limits
+	err = filepath.WalkDir(skillDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
 		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		report.Bytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to measure skill: %w", err)
+	}
+	if v.schema.MaxTotalBytes > 0 && report.Bytes > v.schema.MaxTotalBytes {
+		report.add(SeverityError, "", "skill size %d bytes exceeds the limit of %d bytes", report.Bytes, v.schema.MaxTotalBytes)
 	}
+	if v.schema.MaxDocuments > 0 && report.Documents > v.schema.MaxDocuments {
+		report.add(SeverityError, "", "%d documents exceed the limit of %d", report.Documents, v.schema.MaxDocuments)
+	}
+	return report, nil
+}
 
-	// 4. Check optional directories
-	scriptsDir := filepath.Join(skillDir, "scripts")
-	if info, err := os.Stat(scriptsDir); err == nil && info.IsDir() {
-		log.Printf("Found scripts/ (optional)")
+// checkSkillMD checks that the skill has a SKILL.md with a frontmatter
+// naming and describing it.
+func checkSkillMD(skillDir string, report *Report) {
+	content, err := os.ReadFile(filepath.Join(skillDir, "SKILL.md"))
+	if err != nil {
+		report.add(SeverityError, "SKILL.md", "not found")
+		return
 	}
+	if !mdprose.OpensFrontmatter(string(content)) {
+		report.add(SeverityWarning, "SKILL.md", "missing YAML frontmatter")
+		return
+	}
+	frontmatter, _, ok := mdprose.SplitFrontmatter(string(content))
+	if !ok {
+		report.add(SeverityWarning, "SKILL.md", "incomplete frontmatter")
+		return
+	}
+	for _, field := range []string{"name", "description"} {
+		if !strings.Contains(frontmatter, field+":") {
+			report.add(SeverityWarning, "SKILL.md", "frontmatter missing '%s' field", field)
+		}
+	}
+}
 
-	// 5. Check skill size
-	v.checkSkillSize(skillDir)
+// checkManifest checks that the skill has a well-formed manifest.json whose
+// documents exist, and returns the set of their paths; nil if there is no
+// valid manifest.
+func checkManifest(skillDir string, report *Report) map[string]bool {
+	content, err := os.ReadFile(filepath.Join(skillDir, "manifest.json"))
+	if err != nil {
+		report.add(SeverityError, "manifest.json", "not found")
+		return nil
+	}
+	var manifest struct {
+		Name      string `json:"name"`
+		Documents []struct {
+			Path  string `json:"path"`
+			Title string `json:"title"`
+		} `json:"documents"`
+	}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		report.add(SeverityError, "manifest.json", "malformed: %v", err)
+		return nil
+	}
+	if manifest.Name == "" {
+		report.add(SeverityError, "manifest.json", "missing the skill name")
+	}
+	listed := make(map[string]bool, len(manifest.Documents))
+	for i, doc := range manifest.Documents {
+		switch {
+		case doc.Path == "":
+			report.add(SeverityError, "manifest.json", "document %d has no path", i+1)
+			continue
+		case doc.Title == "":
+			report.add(SeverityWarning, "manifest.json", "document %s has no title", doc.Path)
+		}
+		listed[doc.Path] = true
+		if _, err := os.Stat(filepath.Join(skillDir, filepath.FromSlash(doc.Path))); err != nil {
+			report.add(SeverityError, "manifest.json", "lists %s, which doesn't exist", doc.Path)
+		}
+	}
+	return listed
+}
 
-	// 6. Report results
-	if len(errors) > 0 {
-		log.Printf("VALIDATION FAILED:")
-		for _, err := range errors {
-			log.Printf("  - %s", err)
+// checkDocuments checks the Markdown documents of docsDir, the docs directory
+// of the skill in skillDir (see checkDocument), and counts them; documents
+// missing from listed, the paths of the manifest's documents, are warnings
+// unless listed is nil. Directories whose name starts with "." (the search
+// index) are skipped.
+func (v *Validator) checkDocuments(skillDir, docsDir string, listed map[string]bool, report *Report) error {
+	var files []string
+	err := filepath.WalkDir(docsDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
 		}
-		return false
+		if d.IsDir() && path != docsDir && strings.HasPrefix(d.Name(), ".") {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && filepath.Ext(path) == ".md" {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list documents: %w", err)
+	}
+	sort.Strings(files)
+	if len(files) == 0 {
+		report.add(SeverityWarning, "docs", "directory is empty (no .md files)")
 	}
 
-	if len(warnings) > 0 {
-		log.Printf("Warnings:")
-		for _, warn := range warnings {
-			log.Printf("  - %s", warn)
+	for _, file := range files {
+		rel, _ := filepath.Rel(skillDir, file)
+		rel = filepath.ToSlash(rel)
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", rel, err)
 		}
+		report.Documents++
+		if listed != nil && !listed[rel] {
+			report.add(SeverityWarning, rel, "not listed in manifest.json")
+		}
+		v.checkDocument(rel, content, report)
 	}
+	return nil
+}
 
-	log.Printf("Validation passed!")
-	return true
+// checkDocument checks the document rel with the content: it must be
+// non-empty and within the size limit of the schema, be valid UTF-8, have a
+// terminated frontmatter of well-formed YAML with the required fields and a
+// body, and close its code blocks.
+func (v *Validator) checkDocument(rel string, content []byte, report *Report) {
+	if len(content) == 0 {
+		report.add(SeverityError, rel, "empty file")
+		return
+	}
+	if v.schema.MaxFileBytes > 0 && int64(len(content)) > v.schema.MaxFileBytes {
+		report.add(SeverityError, rel, "size %d bytes exceeds the limit of %d bytes", len(content), v.schema.MaxFileBytes)
+	}
+	if !utf8.Valid(content) {
+		report.add(SeverityError, rel, "invalid UTF-8 (truncated file?)")
+	}
+
+	text := string(content)
+	frontmatter, body, ok := mdprose.SplitFrontmatter(text)
+	if !mdprose.OpensFrontmatter(text) {
+		report.add(SeverityError, rel, "missing frontmatter")
+	} else if !ok {
+		report.add(SeverityError, rel, "unterminated frontmatter (truncated file?)")
+		body = ""
+	} else {
+		var fields map[string]any
+		if err := yaml.Unmarshal([]byte(frontmatter), &fields); err != nil {
+			report.add(SeverityError, rel, "malformed frontmatter: %v", err)
+		} else {
+			for _, field := range v.schema.RequiredFields {
+				if value, ok := fields[field]; !ok || value == nil || value == "" {
+					report.add(SeverityError, rel, "frontmatter missing required field %q", field)
+				}
+			}
+		}
+	}
+	if strings.TrimSpace(body) == "" {
+		report.add(SeverityError, rel, "empty document body")
+		return
+	}
+	if fence := openFence(body); fence != "" {
+		report.add(SeverityError, rel, "unclosed %s code block (truncated file?)", fence)
+	}
+}
+
+// openFence returns the marker of the fenced code block left open at the end
+// of the Markdown body, or "" if every code block is closed.
+func openFence(body string) string {
+	fence := ""
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+		}
+	}
+	return fence
 }
 
 // fileSize represents metadata about a file's size and location for analysis purposes.
@@ -187,7 +464,7 @@ func (v *Validator) checkSkillSize(skillDir string) {
 	log.Printf("\n--- Skill Size Analysis ---")
 	log.Printf("Total Uncompressed Size: %.2f MB", totalSizeMB)
 
-	if totalSize > 8*1024*1024 {
+	if totalSize > claudeSizeLimit {
 		log.Printf("Warning: Skill uncompressed size exceeds Claude's 8MB limit.")
 		log.Printf("Warning: The skill may fail to load in Claude.")
 	} else {
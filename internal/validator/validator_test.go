@@ -1,6 +1,11 @@
 package validator
 
 import (
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -32,3 +37,142 @@ func TestValidateSkill(t *testing.T) {
 		})
 	}
 }
+
+// writeSkill writes a valid skill with two documents to a temporary
+// directory, with the files of files overriding or adding to those of the
+// skill and the files of remove removed, and returns the directory.
+func writeSkill(t *testing.T, files map[string]string, remove []string) string {
+	t.Helper()
+	skill := map[string]string{
+		"SKILL.md":      "---\nname: example\ndescription: Example docs\n---\n\n# Example\n",
+		"manifest.json": `{"name": "example", "documents": [{"path": "docs/install.md", "title": "Install"}, {"path": "docs/auth.md", "title": "Auth"}]}`,
+		"docs/install.md": "---\ntitle: \"Install\"\nsource_url: \"https://example.com/install\"\nfetched_at: \"2024-05-01T00:00:00Z\"\n---\n\n" +
+			"# Install\n\n![Diagram](../assets/diagram.png)\n\n```sh\nmake install\n```\n\nThen [sign in](auth.md#tokens).\n",
+		"docs/auth.md":       "---\ntitle: \"Auth\"\nsource_url: \"https://example.com/auth\"\nfetched_at: \"2024-05-01T00:00:00Z\"\n---\n\n# Auth\n\n## Tokens\n\nUse a token.\n",
+		"assets/diagram.png": "png",
+	}
+	maps.Copy(skill, files)
+	for _, name := range remove {
+		delete(skill, name)
+	}
+	dir := t.TempDir()
+	for name, content := range skill {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string
+		remove []string
+		schema *Schema
+		want   []string
+	}{
+		{
+			name: "valid skill",
+		},
+		{
+			name:   "missing manifest and SKILL.md",
+			remove: []string{"manifest.json", "SKILL.md"},
+			want:   []string{"SKILL.md: error: not found", "manifest.json: error: not found"},
+		},
+		{
+			name:  "malformed manifest",
+			files: map[string]string{"manifest.json": `{"name": "example", "documents": [`},
+			want:  []string{"manifest.json: error: malformed: unexpected end of JSON input"},
+		},
+		{
+			name:   "manifest out of date",
+			files:  map[string]string{"docs/extra.md": "---\ntitle: Extra\nsource_url: https://example.com/extra\nfetched_at: 2024-05-01T00:00:00Z\n---\n\nExtra.\n"},
+			remove: []string{"docs/auth.md"},
+			want: []string{
+				"manifest.json: error: lists docs/auth.md, which doesn't exist",
+				"docs/extra.md: warning: not listed in manifest.json",
+				"docs/install.md: error: line 15: broken link to auth.md#tokens (missing_file)",
+			},
+		},
+		{
+			name: "codex SKILL.md",
+			files: map[string]string{
+				"SKILL.md": "# Example\n",
+			},
+			want: []string{"SKILL.md: warning: missing YAML frontmatter"},
+		},
+		{
+			name: "empty and truncated documents",
+			files: map[string]string{
+				"docs/auth.md":    "",
+				"docs/install.md": "---\ntitle: Install\nsource_url: https://example.com/install\n",
+			},
+			want: []string{
+				"docs/auth.md: error: empty file",
+				"docs/install.md: error: unterminated frontmatter (truncated file?)",
+				"docs/install.md: error: empty document body",
+			},
+		},
+		{
+			name: "unclosed code block and missing fields",
+			files: map[string]string{
+				"docs/auth.md": "---\ntitle: \"\"\nsource_url: https://example.com/auth\n---\n\n# Auth\n\n## Tokens\n\n```go\nfunc main() {\n",
+			},
+			want: []string{
+				`docs/auth.md: error: frontmatter missing required field "title"`,
+				`docs/auth.md: error: frontmatter missing required field "fetched_at"`,
+				"docs/auth.md: error: unclosed ``` code block (truncated file?)",
+			},
+		},
+		{
+			name:   "missing asset and anchor",
+			files:  map[string]string{"docs/auth.md": "---\ntitle: Auth\nsource_url: https://example.com/auth\nfetched_at: 2024-05-01T00:00:00Z\n---\n\n# Auth\n"},
+			remove: []string{"assets/diagram.png"},
+			want: []string{
+				"docs/install.md: error: line 9: broken link to ../assets/diagram.png (missing_file)",
+				"docs/install.md: warning: line 15: broken link to auth.md#tokens (missing_anchor)",
+			},
+		},
+		{
+			name:   "limits",
+			schema: &Schema{MaxTotalBytes: 500, MaxFileBytes: 200, MaxDocuments: 1},
+			want: []string{
+				"docs/install.md: error: size 205 bytes exceeds the limit of 200 bytes",
+				"error: skill size 522 bytes exceeds the limit of 500 bytes",
+				"error: 2 documents exceed the limit of 1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeSkill(t, tt.files, tt.remove)
+			v := New()
+			if tt.schema != nil {
+				v.SetSchema(*tt.schema)
+			}
+			report, err := v.Check(dir)
+			if err != nil {
+				t.Fatalf("Check() returned error: %v", err)
+			}
+			var got []string
+			for _, issue := range report.Issues {
+				got = append(got, issue.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("issues =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+			if report.Errors() > 0 == v.Validate(dir) {
+				t.Errorf("Validate() = %v with %d errors", !(report.Errors() > 0), report.Errors())
+			}
+		})
+	}
+
+	if _, err := New().Check(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Check() of a missing directory succeeded")
+	}
+}
@@ -0,0 +1,284 @@
+// Package warc reads and writes WARC archives.
+// This file implements the recording of the exchanges of an http.Client into
+// an archive, and their replay from it.
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNotArchived is wrapped by the errors of the requests an Archive has no
+// response for.
+var ErrNotArchived = errors.New("not in the WARC archive")
+
+// redactedHeaders are the headers left out of the records, as credentials
+// that must not be written to disk.
+var redactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// RecordingTransport returns a transport sending the requests of base and
+// recording each exchange in w as a request record and a response record,
+// once the body of the response is closed. A body not read to its end is
+// recorded as far as it was read, and marked truncated. The credentials of
+// the requests and responses (Authorization, Cookie, and Set-Cookie headers)
+// aren't recorded, and requests that fail without a response aren't either.
+func RecordingTransport(base http.RoundTripper, w *Writer) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &recordingTransport{base: base, w: w}
+}
+
+// recordingTransport is the transport returned by RecordingTransport.
+type recordingTransport struct {
+	base http.RoundTripper
+	w    *Writer
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &recordingBody{ReadCloser: resp.Body, w: t.w, req: req, resp: resp}
+	return resp, nil
+}
+
+// recordingBody is the body of a recorded response, which it records when closed.
+type recordingBody struct {
+	io.ReadCloser
+	w    *Writer
+	req  *http.Request
+	resp *http.Response
+	buf  bytes.Buffer
+	eof  bool
+	once sync.Once
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if errors.Is(err, io.EOF) {
+		b.eof = true
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() {
+		// A failure to record must never fail the request itself
+		_ = b.w.WriteExchange(b.req, b.resp, b.buf.Bytes(), !b.eof && b.req.Method != http.MethodHead)
+	})
+	return err
+}
+
+// WriteExchange writes a request record of req and a response record of resp
+// with body, the body as read, marked truncated unless complete. The
+// credentials of their headers are left out.
+//
+// Returns the error of the underlying writer, if any.
+func (w *Writer) WriteExchange(req *http.Request, resp *http.Response, body []byte, truncated bool) error {
+	var reqBlock bytes.Buffer
+	fmt.Fprintf(&reqBlock, "%s %s HTTP/1.1\r\nHost: %s\r\n", req.Method, req.URL.RequestURI(), req.URL.Host)
+	header := req.Header.Clone()
+	for _, name := range redactedHeaders {
+		header.Del(name)
+	}
+	header.Write(&reqBlock)
+	reqBlock.WriteString("\r\n")
+
+	var respBlock bytes.Buffer
+	status := resp.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	fmt.Fprintf(&respBlock, "HTTP/1.1 %s\r\n", status)
+	header = resp.Header.Clone()
+	for _, name := range redactedHeaders {
+		header.Del(name)
+	}
+	// The body is recorded as read: decompressed, and not chunked
+	header.Del("Transfer-Encoding")
+	if req.Method != http.MethodHead {
+		header.Set("Content-Length", strconv.Itoa(len(body)))
+	}
+	header.Write(&respBlock)
+	respBlock.WriteString("\r\n")
+	respBlock.Write(body)
+
+	target := req.URL.String()
+	requestID := NewRecordID()
+	responseHeader := Header{
+		{Name: "WARC-Type", Value: TypeResponse},
+		{Name: "WARC-Target-URI", Value: target},
+		{Name: "WARC-Concurrent-To", Value: requestID},
+		{Name: "Content-Type", Value: ContentTypeHTTPResponse},
+	}
+	if truncated {
+		responseHeader.Set("WARC-Truncated", "unspecified")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.write(&Record{
+		Header: Header{
+			{Name: "WARC-Record-ID", Value: requestID},
+			{Name: "WARC-Type", Value: TypeRequest},
+			{Name: "WARC-Target-URI", Value: target},
+			{Name: "Content-Type", Value: ContentTypeHTTPRequest},
+		},
+		Block: reqBlock.Bytes(),
+	})
+	if err != nil {
+		return err
+	}
+	return w.write(&Record{Header: responseHeader, Block: respBlock.Bytes()})
+}
+
+// Archive replays the responses of an archive: it is an http.RoundTripper
+// answering the GET and HEAD requests of the URLs it has a response for with
+// the last one recorded, whatever their other headers, without any network
+// access. It is safe for concurrent use.
+type Archive struct {
+	mu       sync.Mutex
+	f        *os.File
+	compress bool
+	// offsets are those of the last response of each URL
+	offsets map[string]int64
+	// info holds the fields of the warcinfo records
+	info Header
+	// firstPage is the URL of the first HTML page recorded
+	firstPage string
+}
+
+// Open indexes the responses of the archive at path for replay. Close the
+// archive when done.
+//
+// Returns an error if the file can't be read or isn't a WARC archive.
+func Open(path string) (*Archive, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WARC archive: %w", err)
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	a := &Archive{f: f, compress: r.compress, offsets: make(map[string]int64)}
+	for {
+		rec, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to index %s: %w", path, err)
+		}
+		switch rec.Type() {
+		case TypeResponse:
+			a.offsets[rec.TargetURI()] = rec.Offset
+			if a.firstPage == "" && isPage(rec) {
+				a.firstPage = rec.TargetURI()
+			}
+		case TypeWarcinfo:
+			a.info = append(a.info, parseFields(rec.Block)...)
+		}
+	}
+	return a, nil
+}
+
+// Close closes the file of the archive.
+func (a *Archive) Close() error {
+	return a.f.Close()
+}
+
+// Len returns the number of URLs the archive has a response for.
+func (a *Archive) Len() int {
+	return len(a.offsets)
+}
+
+// Info returns the value of the warcinfo field name, such as "software", or
+// "" if the archive has none.
+func (a *Archive) Info(name string) string {
+	return a.info.Get(name)
+}
+
+// FirstPage returns the URL of the first HTML page the archive has a
+// successful response for, the start URL of most crawls, or "" if there is
+// none. Unlike the start-url field of the archives written by site2skill (see
+// Info), it is available in the archives of any tool, such as wget --warc-file.
+func (a *Archive) FirstPage() string {
+	return a.firstPage
+}
+
+// RoundTrip implements http.RoundTripper.
+func (a *Archive) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	offset, ok := a.offsets[req.URL.String()]
+	if !ok || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL, ErrNotArchived)
+	}
+	rec, err := a.recordAt(offset)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(rec.Block)), req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the archived response of %s: %w", req.URL, err)
+	}
+	if req.Method == http.MethodHead {
+		resp.Body.Close()
+		resp.Body = http.NoBody
+	}
+	return resp, nil
+}
+
+// isPage reports whether rec is a successful response with an HTML page.
+func isPage(rec *Record) bool {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(rec.Block)), nil)
+	if err != nil {
+		return false
+	}
+	resp.Body.Close()
+	return resp.StatusCode == http.StatusOK && strings.Contains(resp.Header.Get("Content-Type"), "html")
+}
+
+// recordAt reads the record at offset in the file of the archive.
+func (a *Archive) recordAt(offset int64) (*Record, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.f.Seek(offset, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to read WARC archive: %w", err)
+	}
+	r, err := NewReader(a.f)
+	if err != nil {
+		return nil, err
+	}
+	r.compress = a.compress
+	return r.Next()
+}
+
+// parseFields parses the "name: value" lines of a warcinfo block.
+func parseFields(block []byte) Header {
+	var fields Header
+	for _, line := range bytes.Split(block, []byte("\n")) {
+		name, value, ok := bytes.Cut(bytes.TrimRight(line, "\r"), []byte(":"))
+		if ok {
+			fields = append(fields, Field{Name: string(bytes.TrimSpace(name)), Value: string(bytes.TrimSpace(value))})
+		}
+	}
+	return fields
+}
@@ -0,0 +1,141 @@
+package warc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordingTransportArchive(t *testing.T) {
+	for _, name := range []string{"crawl.warc", "crawl.warc.gz"} {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/old":
+					http.Redirect(w, r, "/docs/", http.StatusMovedPermanently)
+				case "/docs/":
+					w.Header().Set("Content-Type", "text/html")
+					w.Header().Set("Last-Modified", "Wed, 01 Jan 2025 00:00:00 GMT")
+					http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret"})
+					w.Write([]byte("<html>docs</html>"))
+				case "/big":
+					w.Write([]byte(strings.Repeat("x", 1000)))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer server.Close()
+
+			path := filepath.Join(t.TempDir(), name)
+			f, err := os.Create(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := NewWriter(f, strings.HasSuffix(name, ".gz"))
+			w.WriteInfo(Header{{Name: "start-url", Value: server.URL + "/docs/"}})
+			client := &http.Client{Transport: RecordingTransport(nil, w)}
+
+			req, _ := http.NewRequest("GET", server.URL+"/old", nil)
+			req.Header.Set("Authorization", "Bearer secret")
+			resp, err := client.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			io.ReadAll(resp.Body)
+			resp.Body.Close()
+			// A body read in part is recorded as read
+			resp, err = client.Get(server.URL + "/big")
+			if err != nil {
+				t.Fatal(err)
+			}
+			io.ReadFull(resp.Body, make([]byte, 10))
+			resp.Body.Close()
+			f.Close()
+
+			data, _ := os.ReadFile(path)
+			if strings.Contains(string(data), "secret") {
+				t.Error("the archive holds credentials")
+			}
+
+			a, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer a.Close()
+			if a.Len() != 3 || a.Info("start-url") != server.URL+"/docs/" {
+				t.Errorf("Len() = %d, Info(start-url) = %q; want 3 and %s", a.Len(), a.Info("start-url"), server.URL+"/docs/")
+			}
+			if a.FirstPage() != server.URL+"/docs/" {
+				t.Errorf("FirstPage() = %q, want %s", a.FirstPage(), server.URL+"/docs/")
+			}
+
+			server.Close()
+			replay := &http.Client{Transport: a}
+			resp, err = replay.Get(server.URL + "/old")
+			if err != nil {
+				t.Fatalf("replayed Get() error = %v", err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if string(body) != "<html>docs</html>" || resp.Request.URL.Path != "/docs/" || resp.Header.Get("Last-Modified") == "" {
+				t.Errorf("replayed response = %q at %s with headers %v", body, resp.Request.URL, resp.Header)
+			}
+			resp, err = replay.Get(server.URL + "/big")
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ = io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if len(body) != 10 {
+				t.Errorf("replayed truncated body has %d bytes, want 10", len(body))
+			}
+			if _, err := replay.Get(server.URL + "/missing"); !errors.Is(err, ErrNotArchived) {
+				t.Errorf("replayed Get() of a URL not archived error = %v, want ErrNotArchived", err)
+			}
+		})
+	}
+}
+
+func TestArchiveOtherTools(t *testing.T) {
+	// Archives of other tools, such as wget --warc-file, hold their target
+	// URIs in angle brackets and the responses as sent, chunked
+	var buf bytes.Buffer
+	w := NewWriter(&buf, false)
+	for _, rec := range []struct{ uri, block string }{
+		{"<https://docs.example.com/robots.txt>", "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\nContent-Length: 0\r\n\r\n"},
+		{"<https://docs.example.com/>", "HTTP/1.1 200 OK\r\nContent-Type: text/html; charset=utf-8\r\nTransfer-Encoding: chunked\r\n\r\n6\r\n<html>\r\n0\r\n\r\n"},
+	} {
+		w.Write(&Record{
+			Header: Header{{Name: "WARC-Type", Value: TypeResponse}, {Name: "WARC-Target-URI", Value: rec.uri}},
+			Block:  []byte(rec.block),
+		})
+	}
+	path := filepath.Join(t.TempDir(), "wget.warc")
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer a.Close()
+	if a.FirstPage() != "https://docs.example.com/" || a.Info("start-url") != "" {
+		t.Errorf("FirstPage() = %q, Info(start-url) = %q; want https://docs.example.com/ and none", a.FirstPage(), a.Info("start-url"))
+	}
+	resp, err := (&http.Client{Transport: a}).Get("https://docs.example.com/")
+	if err != nil {
+		t.Fatalf("replayed Get() error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "<html>" {
+		t.Errorf("replayed body = %q, want <html>", body)
+	}
+}
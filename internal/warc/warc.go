@@ -0,0 +1,324 @@
+// Package warc reads and writes WARC archives (ISO 28500, WARC/1.1), the
+// standard format of web archives, so that a crawl can be recorded with the
+// HTTP requests and responses it made, headers included, and be read by the
+// existing web-archiving tools (warcio, pywb, OpenWayback, ...) or replayed.
+//
+// An archive is a sequence of records, each a version line, named header
+// fields, and a block of Content-Length bytes. Writer writes them, each in a
+// gzip member of its own when the archive is compressed (.warc.gz), so that a
+// record can be read without decompressing those before it; Reader reads them
+// back, compressed or not. RecordingTransport records the exchanges of an
+// http.Client, and Archive replays them.
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version is the version line of the records written.
+const Version = "WARC/1.1"
+
+// Record types.
+const (
+	// TypeWarcinfo describes the archive: the software that wrote it and
+	// the crawl it records.
+	TypeWarcinfo = "warcinfo"
+	// TypeRequest is an HTTP request, its block the request as sent.
+	TypeRequest = "request"
+	// TypeResponse is an HTTP response, its block the status line, headers,
+	// and body as received.
+	TypeResponse = "response"
+)
+
+// Content types of the blocks.
+const (
+	// ContentTypeHTTPRequest is the content type of request records.
+	ContentTypeHTTPRequest = "application/http; msgtype=request"
+	// ContentTypeHTTPResponse is the content type of response records.
+	ContentTypeHTTPResponse = "application/http; msgtype=response"
+	// ContentTypeFields is the content type of warcinfo records, whose block
+	// holds "name: value" lines.
+	ContentTypeFields = "application/warc-fields"
+)
+
+// Field is a named header field of a record, such as WARC-Type.
+type Field struct {
+	Name  string
+	Value string
+}
+
+// Header holds the header fields of a record, in order.
+type Header []Field
+
+// Get returns the value of the first field named name, case-insensitively,
+// or "" if there is none.
+func (h Header) Get(name string) string {
+	for _, f := range h {
+		if strings.EqualFold(f.Name, name) {
+			return f.Value
+		}
+	}
+	return ""
+}
+
+// Set replaces the value of the field named name, case-insensitively, or
+// appends the field if there is none.
+func (h *Header) Set(name, value string) {
+	for i, f := range *h {
+		if strings.EqualFold(f.Name, name) {
+			(*h)[i].Value = value
+			return
+		}
+	}
+	*h = append(*h, Field{Name: name, Value: value})
+}
+
+// Record is a WARC record.
+type Record struct {
+	// Header holds the named fields of the record, e.g., WARC-Type and
+	// WARC-Target-URI. Content-Length is that of Block.
+	Header Header
+	// Block is the content of the record, e.g., an HTTP response.
+	Block []byte
+	// Offset is the position of the record in the archive, counted in
+	// compressed bytes for a compressed archive; set by Reader.
+	Offset int64
+}
+
+// Type returns the WARC-Type of the record.
+func (r *Record) Type() string {
+	return r.Header.Get("WARC-Type")
+}
+
+// TargetURI returns the WARC-Target-URI of the record, the URL of the
+// request or response it holds.
+func (r *Record) TargetURI() string {
+	return strings.Trim(r.Header.Get("WARC-Target-URI"), "<>")
+}
+
+// Writer writes records to an archive. It is safe for concurrent use.
+type Writer struct {
+	mu       sync.Mutex
+	w        io.Writer
+	compress bool
+	// now returns the current time; overridable in tests
+	now func() time.Time
+}
+
+// NewWriter returns a writer writing records to w, each in a gzip member of
+// its own if compress is set (for .warc.gz archives).
+func NewWriter(w io.Writer, compress bool) *Writer {
+	return &Writer{w: w, compress: compress, now: time.Now}
+}
+
+// Write writes rec, after giving it a WARC-Record-ID and a WARC-Date if it
+// has none, and the Content-Length of its block.
+//
+// Returns the error of the underlying writer, if any.
+func (w *Writer) Write(rec *Record) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.write(rec)
+}
+
+// write writes rec; the caller holds w.mu.
+func (w *Writer) write(rec *Record) error {
+	if rec.Header.Get("WARC-Record-ID") == "" {
+		rec.Header = append(Header{{Name: "WARC-Record-ID", Value: NewRecordID()}}, rec.Header...)
+	}
+	if rec.Header.Get("WARC-Date") == "" {
+		rec.Header.Set("WARC-Date", w.now().UTC().Format(time.RFC3339))
+	}
+	rec.Header.Set("Content-Length", strconv.Itoa(len(rec.Block)))
+
+	var buf bytes.Buffer
+	buf.WriteString(Version + "\r\n")
+	for _, f := range rec.Header {
+		buf.WriteString(f.Name + ": " + f.Value + "\r\n")
+	}
+	buf.WriteString("\r\n")
+	buf.Write(rec.Block)
+	buf.WriteString("\r\n\r\n")
+
+	if !w.compress {
+		_, err := w.w.Write(buf.Bytes())
+		return err
+	}
+	zw := gzip.NewWriter(w.w)
+	if _, err := zw.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// WriteInfo writes a warcinfo record holding fields, such as the software
+// writing the archive, as "name: value" lines.
+func (w *Writer) WriteInfo(fields Header) error {
+	var block bytes.Buffer
+	for _, f := range fields {
+		block.WriteString(f.Name + ": " + f.Value + "\r\n")
+	}
+	return w.Write(&Record{
+		Header: Header{
+			{Name: "WARC-Type", Value: TypeWarcinfo},
+			{Name: "Content-Type", Value: ContentTypeFields},
+		},
+		Block: block.Bytes(),
+	})
+}
+
+// NewRecordID returns a new record ID, a random UUID URN in angle brackets.
+func NewRecordID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// Reader reads the records of an archive.
+type Reader struct {
+	r        *countingReader
+	compress bool
+}
+
+// NewReader returns a reader of the records of the archive read from r,
+// compressed per record with gzip or not, which it detects.
+//
+// Returns an error if r can't be read.
+func NewReader(r io.Reader) (*Reader, error) {
+	cr := &countingReader{r: bufio.NewReader(r)}
+	magic, err := cr.r.Peek(2)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read WARC archive: %w", err)
+	}
+	return &Reader{r: cr, compress: len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b}, nil
+}
+
+// Next returns the next record of the archive, or io.EOF after the last one.
+//
+// Returns an error if the record is malformed or can't be read.
+func (r *Reader) Next() (*Record, error) {
+	offset := r.r.n
+	if _, err := r.r.r.Peek(1); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("failed to read WARC record: %w", err)
+	}
+	if !r.compress {
+		rec, err := readRecord(r.r)
+		if err != nil {
+			return nil, err
+		}
+		rec.Offset = offset
+		return rec, nil
+	}
+	// Each member holds one record; the gzip reader reads no further than
+	// its member since r.r is an io.ByteReader
+	zr, err := gzip.NewReader(r.r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WARC record at offset %d: %w", offset, err)
+	}
+	zr.Multistream(false)
+	rec, err := readRecord(bufio.NewReader(zr))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(io.Discard, zr); err != nil {
+		return nil, fmt.Errorf("failed to read WARC record at offset %d: %w", offset, err)
+	}
+	rec.Offset = offset
+	return rec, nil
+}
+
+// lineReader is a reader of lines and bytes, such as a *bufio.Reader.
+type lineReader interface {
+	io.Reader
+	ReadString(delim byte) (string, error)
+}
+
+// readRecord reads one record from br: its version line, its header fields,
+// its block, and the two line breaks ending it.
+func readRecord(br lineReader) (*Record, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WARC record: %w", err)
+	}
+	if !strings.HasPrefix(line, "WARC/") {
+		return nil, fmt.Errorf("invalid WARC record: version line %q", strings.TrimSpace(line))
+	}
+	rec := &Record{}
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read WARC record header: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid WARC record header line %q", line)
+		}
+		rec.Header = append(rec.Header, Field{Name: name, Value: strings.TrimSpace(value)})
+	}
+	length, err := strconv.ParseInt(rec.Header.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid WARC record Content-Length %q", rec.Header.Get("Content-Length"))
+	}
+	rec.Block = make([]byte, length)
+	if _, err := io.ReadFull(br, rec.Block); err != nil {
+		return nil, fmt.Errorf("failed to read WARC record block: %w", err)
+	}
+	// The block is followed by two line breaks, which some writers omit at
+	// the end of the archive
+	for range 2 {
+		line, err := br.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("failed to read WARC record: %w", err)
+		}
+		if strings.TrimRight(line, "\r\n") != "" {
+			return nil, fmt.Errorf("invalid WARC record: %q after the block", line)
+		}
+	}
+	return rec, nil
+}
+
+// countingReader counts the bytes read from r, so that Reader can tell the
+// offset of each record.
+type countingReader struct {
+	r *bufio.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadString(delim byte) (string, error) {
+	s, err := c.r.ReadString(delim)
+	c.n += int64(len(s))
+	return s, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		c.n++
+	}
+	return b, err
+}
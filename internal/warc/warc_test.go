@@ -0,0 +1,92 @@
+package warc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriterReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		compress bool
+	}{
+		{name: "plain", compress: false},
+		{name: "gzip per record", compress: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf, tt.compress)
+			w.now = func() time.Time { return time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC) }
+			if err := w.WriteInfo(Header{{Name: "software", Value: "site2skillgo"}, {Name: "start-url", Value: "https://docs.example.com/"}}); err != nil {
+				t.Fatal(err)
+			}
+			blocks := []string{"HTTP/1.1 200 OK\r\n\r\nfirst", "", "HTTP/1.1 404 Not Found\r\n\r\nsecond\r\n\r\nWARC/1.1"}
+			for _, block := range blocks {
+				err := w.Write(&Record{Header: Header{{Name: "WARC-Type", Value: TypeResponse}, {Name: "WARC-Target-URI", Value: "https://docs.example.com/"}}, Block: []byte(block)})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !tt.compress && !strings.Contains(buf.String(), "WARC-Date: 2025-01-02T03:04:05Z\r\n") {
+				t.Errorf("archive has no WARC-Date:\n%s", buf.String())
+			}
+
+			r, err := NewReader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := r.Next()
+			if err != nil || info.Type() != TypeWarcinfo || !strings.Contains(string(info.Block), "start-url: https://docs.example.com/\r\n") {
+				t.Fatalf("Next() = %+v, %v; want the warcinfo record", info, err)
+			}
+			var offsets []int64
+			for i, want := range blocks {
+				rec, err := r.Next()
+				if err != nil {
+					t.Fatalf("Next() of record %d error = %v", i, err)
+				}
+				if string(rec.Block) != want || rec.TargetURI() != "https://docs.example.com/" || !strings.HasPrefix(rec.Header.Get("warc-record-id"), "<urn:uuid:") {
+					t.Errorf("record %d = %q %v, want %q", i, rec.Block, rec.Header, want)
+				}
+				offsets = append(offsets, rec.Offset)
+			}
+			if _, err := r.Next(); !errors.Is(err, io.EOF) {
+				t.Errorf("Next() after the last record error = %v, want io.EOF", err)
+			}
+
+			// Records can be read from their offset
+			r, _ = NewReader(bytes.NewReader(buf.Bytes()[offsets[2]:]))
+			if rec, err := r.Next(); err != nil || string(rec.Block) != blocks[2] {
+				t.Errorf("Next() at offset %d = %v, %v; want the last record", offsets[2], rec, err)
+			}
+		})
+	}
+}
+
+func TestReaderInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive string
+	}{
+		{name: "not a WARC", archive: "<html></html>\n"},
+		{name: "bad length", archive: "WARC/1.1\r\nContent-Length: x\r\n\r\n"},
+		{name: "short block", archive: "WARC/1.1\r\nContent-Length: 10\r\n\r\nabc"},
+		{name: "bad header", archive: "WARC/1.1\r\nno colon\r\n\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewReader(strings.NewReader(tt.archive))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := r.Next(); err == nil || errors.Is(err, io.EOF) {
+				t.Errorf("Next() error = %v, want a parse error", err)
+			}
+		})
+	}
+}
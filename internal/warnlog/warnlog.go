@@ -0,0 +1,174 @@
+// Package warnlog keeps the console output of large runs readable by collapsing
+// repeated warnings. The first few warnings of each kind (e.g., "robots" for URLs
+// disallowed by robots.txt) are logged in full; the rest are only counted and
+// reported as one summary line per kind when the pipeline step ends. Every
+// warning is kept, so the full list can be written to a report file.
+//
+// Warnings are logged with log/slog at slog.LevelWarn, with their kind and
+// step as attributes, so log pipelines can filter and count them.
+package warnlog
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// DefaultLimit is the number of warnings of each kind logged in full by default.
+const DefaultLimit = 5
+
+// Record is one warning, as kept for the report.
+type Record struct {
+	// Step is the pipeline step during which the warning occurred (e.g., "fetch").
+	Step string `json:"step,omitempty"`
+	// Kind groups warnings that are collapsed together (e.g., "http_status").
+	Kind string `json:"kind"`
+	// Message is the full warning text.
+	Message string `json:"message"`
+}
+
+// Throttle logs the first warnings of each kind and counts the rest.
+// It is safe for concurrent use.
+type Throttle struct {
+	mu sync.Mutex
+	// limit is the number of warnings of each kind logged in full; 0 means no limit
+	limit int
+	// shown and suppressed count the warnings of each kind since the last Flush
+	shown      map[string]int
+	suppressed map[string]int
+	// kinds lists the kinds with suppressed warnings in first-seen order, for stable summaries
+	kinds []string
+	// step tags new records; see SetStep
+	step string
+	// records holds every warning since the Throttle was created
+	records []Record
+	// warn logs a warning of a kind during a step; logWarning unless replaced in tests
+	warn func(kind, step, msg string)
+}
+
+// logWarning logs msg at slog.LevelWarn with its kind and step.
+func logWarning(kind, step, msg string) {
+	attrs := []any{"kind", kind}
+	if step != "" {
+		attrs = append(attrs, "step", step)
+	}
+	slog.Warn(msg, attrs...)
+}
+
+// New creates a Throttle logging up to DefaultLimit warnings of each kind in full.
+func New() *Throttle {
+	return &Throttle{
+		limit:      DefaultLimit,
+		shown:      make(map[string]int),
+		suppressed: make(map[string]int),
+		warn:       logWarning,
+	}
+}
+
+// SetLimit sets how many warnings of each kind are logged in full before the
+// rest are only counted. A limit of 0 logs every warning.
+func (t *Throttle) SetLimit(limit int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.limit = limit
+}
+
+// SetStep sets the pipeline step recorded with subsequent warnings.
+func (t *Throttle) SetStep(step string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.step = step
+}
+
+// Printf records a warning of the given kind and logs it unless the limit for
+// that kind has been reached. When it is first reached, a note says that
+// further warnings of the kind are being counted.
+func (t *Throttle) Printf(kind, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.records = append(t.records, Record{Step: t.step, Kind: kind, Message: msg})
+	if t.limit == 0 || t.shown[kind] < t.limit {
+		t.shown[kind]++
+		t.warn(kind, t.step, msg)
+		return
+	}
+	if t.suppressed[kind] == 0 {
+		t.kinds = append(t.kinds, kind)
+		t.warn(kind, t.step, fmt.Sprintf("Further %q warnings are counted and summarized at the end of this step", kind))
+	}
+	t.suppressed[kind]++
+}
+
+// Flush logs one summary line for each kind whose warnings were suppressed since
+// the last Flush, and starts counting afresh. Call it at the end of each step.
+// Returns the number of warnings that were not shown.
+func (t *Throttle) Flush() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	total := 0
+	for _, kind := range t.kinds {
+		n := t.suppressed[kind]
+		t.warn(kind, t.step, fmt.Sprintf("Warning: %d more %q warnings not shown (%d in total)", n, kind, n+t.shown[kind]))
+		total += n
+	}
+	t.shown = make(map[string]int)
+	t.suppressed = make(map[string]int)
+	t.kinds = nil
+	return total
+}
+
+// Records returns every warning recorded so far, in order.
+func (t *Throttle) Records() []Record {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Record(nil), t.records...)
+}
+
+// Reset discards the recorded warnings and counts, e.g. before a new build in
+// the same process. The limit is kept.
+func (t *Throttle) Reset() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.shown = make(map[string]int)
+	t.suppressed = make(map[string]int)
+	t.kinds = nil
+	t.step = ""
+	t.records = nil
+}
+
+// std is the Throttle used by the package-level functions.
+var std = New()
+
+// Printf records a warning with the standard Throttle; see Throttle.Printf.
+func Printf(kind, format string, args ...interface{}) {
+	std.Printf(kind, format, args...)
+}
+
+// SetLimit sets the limit of the standard Throttle; see Throttle.SetLimit.
+func SetLimit(limit int) {
+	std.SetLimit(limit)
+}
+
+// SetStep sets the step of the standard Throttle; see Throttle.SetStep.
+func SetStep(step string) {
+	std.SetStep(step)
+}
+
+// Flush logs the summaries of the standard Throttle; see Throttle.Flush.
+func Flush() int {
+	return std.Flush()
+}
+
+// Reset resets the standard Throttle; see Throttle.Reset.
+func Reset() {
+	std.Reset()
+}
+
+// Records returns the warnings recorded by the standard Throttle.
+func Records() []Record {
+	return std.Records()
+}
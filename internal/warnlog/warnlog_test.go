@@ -0,0 +1,120 @@
+package warnlog
+
+import (
+	"bytes"
+	"log"
+	"log/slog"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestThrottle(t *testing.T) {
+	var lines []string
+	th := New()
+	th.warn = func(_, _, msg string) {
+		lines = append(lines, msg)
+	}
+	th.SetLimit(2)
+	th.SetStep("fetch")
+
+	for i := 1; i <= 5; i++ {
+		th.Printf("robots", "Blocked by robots.txt: /private/%d", i)
+	}
+	th.Printf("http_status", "Warning: /missing returned status 404")
+
+	if hidden := th.Flush(); hidden != 3 {
+		t.Errorf("Flush() = %d, want 3", hidden)
+	}
+	want := []string{
+		"Blocked by robots.txt: /private/1",
+		"Blocked by robots.txt: /private/2",
+		`Further "robots" warnings are counted and summarized at the end of this step`,
+		"Warning: /missing returned status 404",
+		`Warning: 3 more "robots" warnings not shown (5 in total)`,
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("logged:\n%q\nwant:\n%q", lines, want)
+	}
+
+	// Counting starts afresh after a flush, but every warning is kept
+	lines = nil
+	th.SetStep("convert")
+	th.Printf("robots", "Blocked by robots.txt: /private/6")
+	if th.Flush() != 0 || len(lines) != 1 {
+		t.Errorf("after Flush, logged %q, want the warning in full", lines)
+	}
+	records := th.Records()
+	if len(records) != 7 {
+		t.Fatalf("Records() returned %d warnings, want 7", len(records))
+	}
+	if last := records[6]; last != (Record{Step: "convert", Kind: "robots", Message: "Blocked by robots.txt: /private/6"}) {
+		t.Errorf("last record = %+v", last)
+	}
+}
+
+func TestThrottleNoLimit(t *testing.T) {
+	shown := 0
+	th := New()
+	th.warn = func(string, string, string) { shown++ }
+	th.SetLimit(0)
+	for i := 0; i < 20; i++ {
+		th.Printf("readability", "Warning: page %d", i)
+	}
+	if shown != 20 || th.Flush() != 0 {
+		t.Errorf("shown %d warnings, want all 20", shown)
+	}
+}
+
+func TestThrottleReset(t *testing.T) {
+	th := New()
+	th.warn = func(string, string, string) {}
+	th.SetLimit(1)
+	th.SetStep("fetch")
+	th.Printf("robots", "first")
+	th.Printf("robots", "second")
+
+	th.Reset()
+	if got := th.Records(); len(got) != 0 {
+		t.Errorf("Records() after Reset = %v, want none", got)
+	}
+	if hidden := th.Flush(); hidden != 0 {
+		t.Errorf("Flush() after Reset = %d, want 0", hidden)
+	}
+	th.Printf("robots", "third")
+	if got := th.Records(); len(got) != 1 || got[0].Step != "" {
+		t.Errorf("Records() = %v, want one record without a step", got)
+	}
+}
+
+func TestLogWarning(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	defer log.SetOutput(log.Writer())
+	defer log.SetFlags(log.Flags())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	New().Printf("robots", "Blocked by robots.txt: /private")
+	th := New()
+	th.SetStep("fetch")
+	th.Printf("http_status", "Warning: /missing returned status 404")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := [][]string{
+		{`"level":"WARN"`, `"msg":"Blocked by robots.txt: /private"`, `"kind":"robots"`},
+		{`"kind":"http_status"`, `"step":"fetch"`},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("logged %d lines, want %d:\n%s", len(lines), len(want), buf.String())
+	}
+	for i, line := range lines {
+		for _, w := range want[i] {
+			if !strings.Contains(line, w) {
+				t.Errorf("line %d = %s, missing %s", i, line, w)
+			}
+		}
+	}
+	if strings.Contains(lines[0], `"step"`) {
+		t.Errorf("line 0 = %s, want no step outside a step", lines[0])
+	}
+}
@@ -0,0 +1,134 @@
+// Package watch detects changes to files by polling them, for the dev command
+// rebuilding a skill whenever the local copy of its site changes.
+//
+// Polling the size and modification time of every file is enough for the
+// source trees of documentation sites and works the same on every platform
+// and file system, without a dependency on OS notification APIs.
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// fileState is what a snapshot records of a file to tell whether it changed.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// Snapshot records the state of the files under a set of paths, by path.
+type Snapshot map[string]fileState
+
+// Take returns a snapshot of the files under paths, each a file or a
+// directory walked recursively, by absolute path. Hidden files and directories (".git",
+// ".claude") and the paths under exclude are skipped, so that a build writing
+// its output next to its sources doesn't trigger itself. Missing paths are
+// recorded as absent rather than failing, so that they are picked up once
+// created.
+//
+// Returns an error if a path can't be walked.
+func Take(paths, exclude []string) (Snapshot, error) {
+	excluded := make(map[string]bool, len(exclude))
+	for _, p := range exclude {
+		if abs, err := filepath.Abs(p); err == nil {
+			excluded[abs] = true
+		}
+	}
+	s := make(Snapshot)
+	for _, root := range paths {
+		root, err := filepath.Abs(root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+		}
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if excluded[path] || (path != root && strings.HasPrefix(d.Name(), ".")) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			s[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+		}
+	}
+	return s, nil
+}
+
+// Diff returns the paths added, removed, or modified in next since s, sorted.
+func (s Snapshot) Diff(next Snapshot) []string {
+	var changed []string
+	for path, state := range next {
+		if prev, ok := s[path]; !ok || prev != state {
+			changed = append(changed, path)
+		}
+	}
+	for path := range s {
+		if _, ok := next[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// Wait polls the files under paths every interval (see Take) until they differ
+// from prev, then until they stay unchanged for one more interval, so that
+// the files of an editor save or a copy are reported together. It returns the
+// snapshot of the settled files and the paths changed since prev.
+//
+// Returns ctx's error if ctx is cancelled first, or an error if the paths
+// can't be walked.
+func Wait(ctx context.Context, paths, exclude []string, prev Snapshot, interval time.Duration) (Snapshot, []string, error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var pending Snapshot
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-ticker.C:
+		}
+		next, err := Take(paths, exclude)
+		if err != nil {
+			return nil, nil, err
+		}
+		if pending != nil && len(pending.Diff(next)) == 0 {
+			if changed := prev.Diff(next); len(changed) > 0 {
+				return next, changed, nil
+			}
+			// The files were changed back
+			pending = nil
+			continue
+		}
+		if pending != nil || len(prev.Diff(next)) > 0 {
+			pending = next
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTakeAndDiff(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "index.html"), "<h1>Home</h1>")
+	writeFile(t, filepath.Join(dir, "guide", "install.html"), "<h1>Install</h1>")
+	writeFile(t, filepath.Join(dir, ".git", "HEAD"), "ref")
+	writeFile(t, filepath.Join(dir, "build", "out.md"), "out")
+	rules := filepath.Join(t.TempDir(), "site2skill.yaml")
+	writeFile(t, rules, "version: 1")
+
+	paths := []string{dir, rules, filepath.Join(dir, "missing")}
+	exclude := []string{filepath.Join(dir, "build")}
+	before, err := Take(paths, exclude)
+	if err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+	if len(before) != 3 {
+		t.Errorf("Take() recorded %d files, want 3 (hidden and excluded skipped): %v", len(before), before)
+	}
+
+	// Modification times may not change within the clock resolution; sizes do
+	writeFile(t, filepath.Join(dir, "guide", "install.html"), "<h1>Install it</h1>")
+	writeFile(t, filepath.Join(dir, "guide", "new.html"), "<h1>New</h1>")
+	os.Remove(filepath.Join(dir, "index.html"))
+	writeFile(t, filepath.Join(dir, ".git", "HEAD"), "other ref")
+	writeFile(t, filepath.Join(dir, "build", "out.md"), "other out")
+
+	after, err := Take(paths, exclude)
+	if err != nil {
+		t.Fatalf("Take() error = %v", err)
+	}
+	want := []string{
+		filepath.Join(dir, "guide", "install.html"),
+		filepath.Join(dir, "guide", "new.html"),
+		filepath.Join(dir, "index.html"),
+	}
+	if got := before.Diff(after); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+	if got := after.Diff(after); len(got) != 0 {
+		t.Errorf("Diff() of the same snapshot = %v, want none", got)
+	}
+}
+
+func TestWait(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "index.html")
+	writeFile(t, page, "<h1>Home</h1>")
+	prev, err := Take([]string{dir}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go func() {
+		time.Sleep(30 * time.Millisecond)
+		writeFile(t, page, "<h1>Home page</h1>")
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	next, changed, err := Wait(ctx, []string{dir}, nil, prev, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if !reflect.DeepEqual(changed, []string{page}) || next[page].size != int64(len("<h1>Home page</h1>")) {
+		t.Errorf("Wait() = %v, %v, want the changed page", next, changed)
+	}
+
+	// Without changes, Wait returns when ctx is done
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, _, err := Wait(ctx, []string{dir}, nil, next, 10*time.Millisecond); err != context.DeadlineExceeded {
+		t.Errorf("Wait() without changes error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
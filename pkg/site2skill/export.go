@@ -0,0 +1,125 @@
+// Package site2skill runs the whole site2skillgo build pipeline in-process.
+//
+// This file implements the export stages, which render the documents of the
+// skill in other output formats (MDX, AsciiDoc, or those of plugins) for
+// documentation sites, and write the llms.txt files of the site.
+package site2skill
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/assets"
+	"github.com/f4ah6o/site2skill-go/internal/atomicfile"
+	"github.com/f4ah6o/site2skill-go/internal/converter"
+	"github.com/f4ah6o/site2skill-go/internal/skillgen"
+	"github.com/f4ah6o/site2skill-go/internal/snippets"
+)
+
+// docLinkPattern matches a relative link to another document of the skill
+var docLinkPattern = regexp.MustCompile(`\]\(([^()\s:#]+)\.md((?:#[^()\s]*)?)\)`)
+
+// export writes the documents of the first skill in the format of each of
+// Config.Exports into DIR/docs, with the assets and snippets they link to,
+// replacing an earlier export.
+func (b *builder) export() error {
+	if len(b.cfg.Exports) == 0 || len(b.result.Skills) == 0 {
+		return nil
+	}
+	log.Printf("=== Step 10: Exporting Documents ===")
+	start := time.Now()
+	skillDir := b.result.Skills[0].Dir
+	dirs := make([]string, 0, len(b.cfg.Exports))
+	documents := 0
+	for _, spec := range b.cfg.Exports {
+		format, dir, err := converter.ParseExport(spec, b.pluginFormats()...)
+		if err != nil {
+			return err
+		}
+		n, err := exportDocs(skillDir, dir, format)
+		if err != nil {
+			return fmt.Errorf("failed to export %s documents: %w", format.Name(), err)
+		}
+		log.Printf("Exported %d %s documents to %s", n, format.Name(), dir)
+		b.result.Exports = append(b.result.Exports, dir)
+		dirs = append(dirs, dir)
+		documents += n
+	}
+	b.stageCompleted("export", start, map[string]int{"exports": len(dirs), "documents": documents}, dirs...)
+	return nil
+}
+
+// llmsTxt writes llms.txt and llms-full.txt of the first skill into
+// Config.LLMsTxt.
+func (b *builder) llmsTxt() error {
+	if b.cfg.LLMsTxt == "" || len(b.result.Skills) == 0 {
+		return nil
+	}
+	log.Printf("=== Step 11: Writing llms.txt ===")
+	start := time.Now()
+	n, err := skillgen.WriteLLMsTxt(b.result.Skills[0].Dir, b.cfg.LLMsTxt)
+	if err != nil {
+		return fmt.Errorf("failed to write llms.txt: %w", err)
+	}
+	files := []string{filepath.Join(b.cfg.LLMsTxt, skillgen.LLMsTxtFile), filepath.Join(b.cfg.LLMsTxt, skillgen.LLMsFullTxtFile)}
+	log.Printf("Wrote %s and %s with %d documents", files[0], files[1], n)
+	b.stageCompleted("llms_txt", start, map[string]int{"documents": n}, files...)
+	return nil
+}
+
+// exportDocs renders the documents of skillDir/docs in format into dir/docs,
+// links between them included, and copies the assets/ and snippets/ folders of
+// the skill next to it so the relative links of the documents resolve. Files
+// left from an earlier export are removed, and those whose content didn't
+// change aren't written again. Returns the number of documents exported.
+func exportDocs(skillDir, dir string, format converter.OutputFormat) (int, error) {
+	docsDir := filepath.Join(dir, "docs")
+	if err := os.MkdirAll(docsDir, 0755); err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", docsDir, err)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(skillDir, "docs"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read documents: %w", err)
+	}
+	n := 0
+	keep := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".md") {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(skillDir, "docs", e.Name()))
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s: %w", e.Name(), err)
+		}
+		doc := string(content)
+		if format.Extension() != ".md" {
+			doc = docLinkPattern.ReplaceAllString(doc, "]($1"+format.Extension()+"$2)")
+		}
+		name := strings.TrimSuffix(e.Name(), ".md") + format.Extension()
+		rendered, err := format.Render(doc)
+		if err != nil {
+			return 0, fmt.Errorf("failed to render %s: %w", e.Name(), err)
+		}
+		if _, err := atomicfile.WriteFile(filepath.Join(docsDir, name), []byte(rendered)); err != nil {
+			return 0, fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		keep[name] = true
+		n++
+	}
+	if err := atomicfile.RemoveStale(docsDir, keep); err != nil {
+		return 0, err
+	}
+
+	for _, sub := range []string{assets.DirName, snippets.DirName} {
+		if _, err := atomicfile.Sync(filepath.Join(skillDir, sub), filepath.Join(dir, sub)); err != nil {
+			return 0, fmt.Errorf("failed to copy %s: %w", sub, err)
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,156 @@
+// Package site2skill runs the whole site2skillgo build pipeline in-process.
+//
+// This file implements the inspection of the conversion of a single page.
+package site2skill
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/access"
+	"github.com/f4ah6o/site2skill-go/internal/converter"
+	"github.com/f4ah6o/site2skill-go/internal/fetcher"
+	"github.com/f4ah6o/site2skill-go/internal/httpcache"
+)
+
+// Inspection describes how one page is fetched and converted: see Inspect.
+type Inspection struct {
+	// URL is the inspected page; FinalURL is the URL of the response after redirects.
+	URL      string `json:"url"`
+	FinalURL string `json:"final_url"`
+	// StatusCode is the HTTP status of the response.
+	StatusCode int `json:"status_code"`
+	// Cache is the status of the response in the HTTP cache ("hit",
+	// "revalidated", or "miss"); empty when the cache is disabled.
+	Cache string `json:"cache,omitempty"`
+	// Inspection describes the conversion: the detected generator, the
+	// extraction method, the elements matched by the selector rules and
+	// stripped as boilerplate, the metadata, and the Markdown.
+	converter.Inspection
+}
+
+// Inspect fetches the page at pageURL, through the HTTP cache unless
+// cfg.NoCache is set, and converts it with the conversion options of cfg,
+// reporting how its content was extracted (see converter.Converter.Inspect).
+// It is the quickest way to debug a page converted badly: the selector rules
+// can be tuned and the page inspected again without crawling the site.
+//
+// Of cfg, only the conversion options (ContentSelector, StripSelectors,
+// Platform, TableFallback, TableCSVRows, Admonitions, PageTypes, AccessRules,
+// Plugins, Converters, Transformers, MarkdownTransformers), the request
+// options (Headers, ConsentCookies, Device, UserAgent, Contact, RunID,
+// Resolve, HostHeader, Proxy, CACert, Insecure), the cache options (CacheDir, NoCache, TempDir), and
+// Timestamp are used; URL is replaced by pageURL.
+// robots.txt isn't checked, and nothing is written besides the HTTP cache.
+//
+// Returns an error if the options are invalid, the page can't be fetched, or
+// its response isn't successful.
+func Inspect(ctx context.Context, cfg Config, pageURL string) (*Inspection, error) {
+	page, err := inspectPage(ctx, cfg, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	return page.inspection, nil
+}
+
+// inspectedPage is a page fetched and converted by inspectPage.
+type inspectedPage struct {
+	inspection *Inspection
+	// body is the HTML of the page
+	body []byte
+	// transport is the transport the page was fetched with, without the HTTP
+	// cache, and userAgent its User-Agent
+	transport http.RoundTripper
+	userAgent string
+}
+
+// inspectPage is Inspect, also returning the HTML of the page and how it was
+// requested.
+func inspectPage(ctx context.Context, cfg Config, pageURL string) (*inspectedPage, error) {
+	cfg.URL = pageURL
+	conv, err := cfg.newConverter()
+	if err != nil {
+		return nil, err
+	}
+	userAgent, err := cfg.userAgent()
+	if err != nil {
+		return nil, err
+	}
+	accessRules, err := access.ParseRules(cfg.AccessRules)
+	if err != nil {
+		return nil, err
+	}
+
+	b := &builder{ctx: ctx, cfg: cfg}
+	if err := b.resolveHosts(); err != nil {
+		return nil, err
+	}
+	if err := b.startPlugins(); err != nil {
+		return nil, err
+	}
+	defer b.stopPlugins()
+	b.addExtensions(conv)
+	transport := b.transport
+	if !cfg.NoCache && (cfg.CacheDir != "" || cfg.TempDir != "") {
+		transport = httpcache.New(cfg.httpCacheDir(), b.transport)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.startURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range cfg.Headers {
+		req.Header[name] = values
+	}
+	for _, s := range cfg.ConsentCookies {
+		c, err := fetcher.ParseCookie(s)
+		if err != nil {
+			return nil, err
+		}
+		req.AddCookie(c)
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := (&http.Client{Transport: transport}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", pageURL, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", pageURL, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("failed to fetch %s: HTTP %d", pageURL, resp.StatusCode)
+	}
+
+	now := cfg.Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+	in := &Inspection{
+		URL:        pageURL,
+		FinalURL:   resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+		Cache:      resp.Header.Get(httpcache.StatusHeader),
+	}
+	meta := converter.PageMeta{
+		SourceURL:       pageURL,
+		FetchedAt:       now.UTC().Format(time.RFC3339),
+		StatusCode:      resp.StatusCode,
+		ContentLanguage: resp.Header.Get("Content-Language"),
+		ContentType:     resp.Header.Get("Content-Type"),
+		Access:          access.LevelOf(accessRules, pageURL),
+	}
+	if in.FinalURL != b.startURL {
+		meta.FinalURL = in.FinalURL
+	}
+	conversion, err := conv.Inspect(body, meta)
+	if err != nil {
+		return nil, err
+	}
+	in.Inspection = *conversion
+	return &inspectedPage{inspection: in, body: body, transport: b.transport, userAgent: userAgent}, nil
+}
@@ -0,0 +1,160 @@
+// Package site2skill runs the whole site2skillgo build pipeline in-process.
+//
+// This file implements builds with one documentation tree per locale (see
+// Config.EachLocale).
+package site2skill
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/skillgen"
+)
+
+// buildLocales runs the pipeline once for each of b.cfg.Locales, into a skill
+// named after the locale inside the skill of every target (see
+// Config.EachLocale), then indexes the pages across the locales and packages
+// the whole skill.
+func (b *builder) buildLocales() error {
+	var plan []PlannedPage
+	skills := make([]Skill, len(b.cfg.Targets))
+	for i, target := range b.cfg.Targets {
+		skills[i] = Skill{Format: target.Format, Dir: filepath.Join(target.Dir, b.cfg.SkillName), Valid: true}
+	}
+	// Crawling the site, the locales share the transport, whose connections
+	// are reused from one locale to the next, and the DNS cache
+	if !b.cfg.Offline {
+		if err := b.resolveHosts(); err != nil {
+			return err
+		}
+	}
+	for _, locale := range b.cfg.Locales {
+		if err := b.ctx.Err(); err != nil {
+			return err
+		}
+		log.Printf("=== Locale %s ===", locale)
+		sub := &builder{ctx: b.ctx, cfg: b.localeConfig(locale), accessRules: b.accessRules, nested: true, transport: b.transport, dns: b.dns}
+		sub.result = &BuildResult{ReportPath: sub.cfg.crawlReportPath()}
+		if err := sub.run(); err != nil {
+			return fmt.Errorf("failed to build locale %s: %w", locale, err)
+		}
+		if b.result.ReportPath == "" {
+			b.result.ReportPath = sub.result.ReportPath
+		}
+		if sub.result.Pages != nil && b.result.Pages == nil {
+			b.result.Pages = make(map[string]int)
+		}
+		for outcome, n := range sub.result.Pages {
+			b.result.Pages[outcome] += n
+		}
+		for _, p := range sub.result.Plan {
+			p.File = locale + "/" + p.File
+			plan = append(plan, p)
+		}
+		for i, s := range sub.result.Skills {
+			skills[i].Tokens += s.Tokens
+			skills[i].Bytes += s.Bytes
+			skills[i].Valid = skills[i].Valid && s.Valid
+			if s.Changes != nil {
+				skills[i].Changes = mergeChanges(skills[i].Changes, s.Changes, locale)
+			}
+		}
+	}
+	if b.cfg.DryRun {
+		b.result.Plan = plan
+		if plan == nil {
+			b.result.Plan = []PlannedPage{}
+		}
+		return nil
+	}
+
+	start := time.Now()
+	for i, target := range b.cfg.Targets {
+		gen := skillgen.New(target.Format)
+		gen.SetSourceURL(b.cfg.URL)
+		index, err := gen.GenerateLocales(b.cfg.SkillName, b.cfg.Locales, target.Dir)
+		if err != nil {
+			return fmt.Errorf("failed to index locales: %w", err)
+		}
+		log.Printf("Indexed %d pages across locales %s", len(index.Pages), strings.Join(index.Locales, ", "))
+		if !skills[i].Valid {
+			log.Printf("Warning: Validation failed for a locale of %s. Please check errors.", skills[i].Dir)
+		}
+	}
+	b.result.Skills = skills
+	dirs := make([]string, len(skills))
+	for i, s := range skills {
+		dirs[i] = s.Dir
+	}
+	b.stageCompleted("generate", start, changeStats(map[string]int{"skills": len(dirs), "locales": len(b.cfg.Locales)}, skills), dirs...)
+
+	if err := b.pack(); err != nil {
+		return err
+	}
+	if b.cfg.Clean {
+		if err := os.RemoveAll(b.cfg.TempDir); err != nil {
+			log.Printf("Warning: could not remove temp dir: %v", err)
+		}
+		log.Printf("Temporary files removed from %s", b.cfg.TempDir)
+	} else {
+		log.Printf("Temporary files kept in %s", b.cfg.TempDir)
+	}
+	return nil
+}
+
+// localeConfig returns the configuration of the build of locale in a build
+// with one tree per locale: locale priority mode with locale alone, whose
+// pages fall back to the locale-neutral URL, into the skill named after
+// locale inside the skill of every target, with the intermediate files in
+// TempDir/locale. The crawl report and HTTP cache are per locale too: the
+// cache doesn't key responses on the Accept-Language header they vary on.
+func (b *builder) localeConfig(locale string) Config {
+	cfg := b.cfg
+	cfg.EachLocale = false
+	cfg.Locales = []string{locale}
+	cfg.SkillName = locale
+	cfg.TempDir = filepath.Join(b.cfg.TempDir, locale)
+	cfg.Clean = false
+	cfg.Targets = make([]Target, len(b.cfg.Targets))
+	for i, t := range b.cfg.Targets {
+		cfg.Targets[i] = Target{Format: t.Format, Dir: filepath.Join(t.Dir, b.cfg.SkillName)}
+	}
+	if b.cfg.ReportPath != "" {
+		ext := filepath.Ext(b.cfg.ReportPath)
+		cfg.ReportPath = strings.TrimSuffix(b.cfg.ReportPath, ext) + "." + locale + ext
+	}
+	if b.cfg.CacheDir != "" {
+		cfg.CacheDir = filepath.Join(b.cfg.CacheDir, locale)
+	}
+	return cfg
+}
+
+// mergeChanges adds more, the changes of the tree of locale, to changes, which
+// may be nil, with the paths of the pages made relative to the whole skill.
+func mergeChanges(changes, more *skillgen.Changes, locale string) *skillgen.Changes {
+	if changes == nil {
+		changes = &skillgen.Changes{}
+	}
+	prefix := func(pages []skillgen.PageChange) []skillgen.PageChange {
+		out := make([]skillgen.PageChange, len(pages))
+		for i, p := range pages {
+			p.Paths = append([]string(nil), p.Paths...)
+			for j := range p.Paths {
+				p.Paths[j] = locale + "/" + p.Paths[j]
+			}
+			out[i] = p
+		}
+		return out
+	}
+	changes.Sources = append(changes.Sources, more.Sources...)
+	changes.Added = append(changes.Added, prefix(more.Added)...)
+	changes.Modified = append(changes.Modified, prefix(more.Modified)...)
+	changes.Removed = append(changes.Removed, prefix(more.Removed)...)
+	changes.Unchanged += more.Unchanged
+	changes.Outside += more.Outside
+	return changes
+}
@@ -0,0 +1,1428 @@
+// Package site2skill runs the whole site2skillgo build pipeline in-process.
+//
+// This file implements the pipeline stages: fetch, convert, normalize, chunk,
+// generate, validate, and package. The export stages are in export.go.
+package site2skill
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/f4ah6o/site2skill-go/internal/access"
+	"github.com/f4ah6o/site2skill-go/internal/airgap"
+	"github.com/f4ah6o/site2skill-go/internal/assets"
+	"github.com/f4ah6o/site2skill-go/internal/chunker"
+	"github.com/f4ah6o/site2skill-go/internal/confluence"
+	"github.com/f4ah6o/site2skill-go/internal/converter"
+	"github.com/f4ah6o/site2skill-go/internal/dedupe"
+	"github.com/f4ah6o/site2skill-go/internal/fetcher"
+	"github.com/f4ah6o/site2skill-go/internal/httpcache"
+	"github.com/f4ah6o/site2skill-go/internal/idn"
+	"github.com/f4ah6o/site2skill-go/internal/links"
+	"github.com/f4ah6o/site2skill-go/internal/mirror"
+	"github.com/f4ah6o/site2skill-go/internal/normalizer"
+	"github.com/f4ah6o/site2skill-go/internal/notion"
+	"github.com/f4ah6o/site2skill-go/internal/packager"
+	"github.com/f4ah6o/site2skill-go/internal/pathglob"
+	"github.com/f4ah6o/site2skill-go/internal/plugin"
+	"github.com/f4ah6o/site2skill-go/internal/progress"
+	"github.com/f4ah6o/site2skill-go/internal/repo"
+	"github.com/f4ah6o/site2skill-go/internal/search"
+	"github.com/f4ah6o/site2skill-go/internal/skillgen"
+	"github.com/f4ah6o/site2skill-go/internal/snippets"
+	"github.com/f4ah6o/site2skill-go/internal/urlrewrite"
+	"github.com/f4ah6o/site2skill-go/internal/validator"
+	"github.com/f4ah6o/site2skill-go/internal/warc"
+	"github.com/f4ah6o/site2skill-go/internal/warnlog"
+)
+
+// builder carries the state of one Build through the pipeline stages.
+type builder struct {
+	ctx    context.Context
+	cfg    Config
+	result *BuildResult
+	// downloadDir holds the crawl; markdownDir the converted pages
+	downloadDir string
+	markdownDir string
+	// fetchedAt is recorded in the frontmatter of every page
+	fetchedAt string
+	// startURL is the URL the crawl starts from: cfg.URL, or with the host of cfg.HostHeader
+	startURL string
+	// transport connects to the servers of the crawl, those of the resolve
+	// rules instead of their hosts, resolving host names through dns
+	transport http.RoundTripper
+	// dns caches the host lookups of the transport; the fetcher prefetches into it
+	dns *fetcher.DNSCache
+	// rewriter applies Config.RewriteURLs to the converted pages; nil without rules
+	rewriter *urlrewrite.Rewriter
+	// accessRules are Config.AccessRules, parsed
+	accessRules []access.Rule
+	// plugins are the running plugins of Config.Plugins
+	plugins []*plugin.Plugin
+	// commands are the converter commands of Config.Converters
+	commands []*plugin.Command
+	// warc records the exchanges of the crawl into Config.WARC; nil without it
+	warc *warc.Writer
+	// archive replays the crawl of Config.FromWARC; nil without it
+	archive *warc.Archive
+	// replay is set when the crawl is answered by Config.FromWARC or
+	// Config.FromMirror rather than a site
+	replay bool
+	// hidden counts warnings not shown on the console since the crawl
+	hidden int
+	// report is the crawl report of a dry run, which isn't written
+	report *fetcher.CrawlReport
+	// nested builds one locale of a build with one tree per locale (see
+	// Config.EachLocale): it stops once its skills are validated, leaving
+	// their packaging and the temporary files to the whole build
+	nested bool
+}
+
+// run executes every stage in order, stopping at the first error.
+func (b *builder) run() error {
+	b.downloadDir = filepath.Join(b.cfg.TempDir, "download")
+	b.markdownDir = filepath.Join(b.cfg.TempDir, "markdown")
+	now := b.cfg.Timestamp
+	if now.IsZero() {
+		now = time.Now()
+	}
+	b.fetchedAt = now.UTC().Format(time.RFC3339)
+	warnlog.Reset()
+	if !b.nested {
+		log.Printf("Run ID: %s", b.cfg.RunID)
+	}
+	if err := b.resolveHosts(); err != nil {
+		return err
+	}
+	if b.archive != nil {
+		defer b.archive.Close()
+	}
+	if b.cfg.WARC != "" {
+		closeWARC, err := b.openWARC()
+		if err != nil {
+			return err
+		}
+		defer closeWARC()
+	}
+	if len(b.cfg.RewriteURLs) > 0 {
+		rules, err := urlrewrite.ParseRules(b.cfg.RewriteURLs)
+		if err != nil {
+			return fmt.Errorf("failed to parse URL rewrite rules: %w", err)
+		}
+		b.rewriter = urlrewrite.New(rules)
+	}
+
+	if b.cfg.DryRun {
+		if err := b.fetch(); err != nil {
+			return err
+		}
+		b.plan()
+		return nil
+	}
+	if err := b.startPlugins(); err != nil {
+		return err
+	}
+	defer b.stopPlugins()
+
+	if !b.cfg.SkipFetch {
+		// Remove previous outputs but keep the HTTP cache so it can be reused across runs
+		for _, dir := range []string{b.downloadDir, b.markdownDir} {
+			if err := os.RemoveAll(dir); err != nil && !os.IsNotExist(err) {
+				log.Printf("Warning: could not remove temp dir: %v", err)
+			}
+		}
+		if err := os.MkdirAll(b.downloadDir, 0755); err != nil {
+			return fmt.Errorf("failed to create temp download dir: %w", err)
+		}
+	}
+	if err := os.MkdirAll(b.markdownDir, 0755); err != nil {
+		return fmt.Errorf("failed to create temp markdown dir: %w", err)
+	}
+
+	stages := []func() error{b.fetch, b.convert, b.normalize, b.chunk, b.translate, b.summarize, b.generate, b.validate, b.pack, b.export, b.llmsTxt}
+	if b.nested {
+		stages = stages[:8]
+	}
+	for _, stage := range stages {
+		if err := b.ctx.Err(); err != nil {
+			return err
+		}
+		if err := stage(); err != nil {
+			return err
+		}
+	}
+	if b.nested {
+		return nil
+	}
+
+	if b.cfg.Clean {
+		if err := os.RemoveAll(b.cfg.TempDir); err != nil {
+			log.Printf("Warning: could not remove temp dir: %v", err)
+		}
+		log.Printf("Temporary files removed from %s", b.cfg.TempDir)
+	} else {
+		log.Printf("Temporary files kept in %s", b.cfg.TempDir)
+	}
+	return nil
+}
+
+// resolveHosts sets the start URL and the transport connecting to the servers
+// of the crawl: those of Config.Resolve and Config.HostHeader when hosts are
+// overridden, and the others through a DNS cache, or through Config.Proxy,
+// with the TLS options of the configuration. An offline build connects to no
+// server: its transport replays the HTTP cache.
+func (b *builder) resolveHosts() error {
+	b.startURL = b.cfg.URL
+	rules := append([]string(nil), b.cfg.Resolve...)
+	if b.cfg.HostHeader != "" {
+		startURL, rule, err := fetcher.HostOverride(b.cfg.URL, b.cfg.HostHeader)
+		if err != nil {
+			return fmt.Errorf("failed to override host: %w", err)
+		}
+		b.startURL = startURL
+		rules = append([]string{rule}, rules...)
+	}
+	if b.nested && b.transport != nil {
+		// The locales of a build share its connections and host lookups
+		return nil
+	}
+	if b.cfg.Offline && b.cfg.FromWARC != "" {
+		archive, err := warc.Open(b.cfg.FromWARC)
+		if err != nil {
+			return err
+		}
+		b.archive = archive
+		b.replay = true
+		// The archives of other tools may hold compressed responses
+		b.transport = fetcher.CompressionTransport(archive)
+		log.Printf("Offline: replaying the crawl recorded in %s (%d URLs)", b.cfg.FromWARC, archive.Len())
+		return nil
+	}
+	if b.cfg.Offline && b.cfg.FromMirror != "" {
+		m, err := mirror.Open(b.cfg.FromMirror)
+		if err != nil {
+			return err
+		}
+		b.replay = true
+		b.transport = m
+		log.Printf("Offline: crawling the %s mirror in %s (hosts: %s)", m.Layout(), b.cfg.FromMirror, strings.Join(m.Hosts(), ", "))
+		return nil
+	}
+	if b.cfg.Offline {
+		b.transport = &httpcache.Transport{Dir: b.cfg.httpCacheDir(), Offline: true}
+		log.Printf("Offline: requests are answered from the HTTP cache in %s", b.cfg.httpCacheDir())
+		return nil
+	}
+	// Through a proxy, hosts are resolved by the proxy
+	if b.cfg.Proxy == "" {
+		b.dns = fetcher.NewDNSCache()
+	}
+	t, err := fetcher.ResolveTransport(rules, b.dns)
+	if err != nil {
+		return fmt.Errorf("failed to override host: %w", err)
+	}
+	fetcher.ConfigureConnections(t, b.cfg.MaxConnsPerHost, !b.cfg.NoHTTP2)
+	if b.cfg.NoHTTP2 {
+		log.Printf("HTTP/2 is disabled")
+	}
+	if b.cfg.Proxy != "" {
+		if err := fetcher.ConfigureProxy(t, b.cfg.Proxy); err != nil {
+			return err
+		}
+		if u, err := fetcher.ParseProxy(b.cfg.Proxy); err == nil {
+			log.Printf("Proxy: %s", u.Redacted())
+		}
+	}
+	if b.cfg.CACert != "" || b.cfg.Insecure {
+		if err := fetcher.ConfigureTLS(t, b.cfg.CACert, b.cfg.Insecure); err != nil {
+			return err
+		}
+		if b.cfg.CACert != "" {
+			log.Printf("CA bundle: %s", b.cfg.CACert)
+		}
+		if b.cfg.Insecure {
+			log.Printf("Warning: TLS certificate verification is disabled")
+		}
+	}
+	b.transport = fetcher.CompressionTransport(t)
+	if b.cfg.Politeness != nil {
+		b.transport = fetcher.PolitenessTransport(b.transport, b.cfg.Politeness)
+		log.Printf("Politeness settings for %d host patterns", len(b.cfg.Politeness.Hosts))
+	}
+	if b.cfg.Sandbox {
+		return b.sandbox()
+	}
+	return nil
+}
+
+// sandbox restricts the transport and the DNS cache of the crawl to the hosts
+// of the crawl and Config.AllowHosts, pinning the addresses of the hosts it
+// resolves, unless a proxy resolves them (see Config.Sandbox).
+//
+// Returns an error if an allowed host doesn't resolve.
+func (b *builder) sandbox() error {
+	var patterns []string
+	for _, u := range append([]string{b.startURL}, b.cfg.Seeds...) {
+		if !strings.Contains(u, "://") {
+			u = "https://" + u
+		}
+		patterns = append(patterns, u)
+	}
+	for _, rule := range b.cfg.Resolve {
+		// The rules were parsed by ResolveTransport
+		hostPort, _, _ := fetcher.ParseResolve(rule)
+		host, _, _ := net.SplitHostPort(hostPort)
+		patterns = append(patterns, host)
+	}
+	if b.cfg.Notion {
+		patterns = append(patterns, notion.APIURL)
+	}
+	allow, err := fetcher.ParseAllowList(append(patterns, b.cfg.AllowHosts...))
+	if err != nil {
+		return fmt.Errorf("failed to build the sandbox allow-list: %w", err)
+	}
+	log.Printf("Sandbox: allowed hosts %s", allow)
+	if b.dns == nil {
+		log.Printf("Warning: the addresses of the allowed hosts aren't pinned, since the proxy resolves them")
+	} else {
+		pinned, err := b.dns.Pin(b.ctx, allow)
+		if err != nil {
+			return fmt.Errorf("failed to pin the addresses of the allowed hosts: %w", err)
+		}
+		for _, host := range allow.Hosts() {
+			if addrs, ok := pinned[host]; ok {
+				log.Printf("Pinned %s to %s", host, strings.Join(addrs, ", "))
+			}
+		}
+	}
+	b.transport = fetcher.SandboxTransport(b.transport, allow)
+	return nil
+}
+
+// openWARC creates the archive of Config.WARC, compressed per record if its
+// name ends in .gz, and starts it with a warcinfo record describing the crawl.
+// Call the returned function to close it once the requests are done.
+//
+// Returns an error if the file can't be created.
+func (b *builder) openWARC() (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(b.cfg.WARC), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create WARC archive directory: %w", err)
+	}
+	f, err := os.Create(b.cfg.WARC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create WARC archive: %w", err)
+	}
+	b.warc = warc.NewWriter(f, strings.HasSuffix(b.cfg.WARC, ".gz"))
+	err = b.warc.WriteInfo(warc.Header{
+		{Name: "software", Value: "site2skill-go"},
+		{Name: "format", Value: "WARC File Format 1.1"},
+		{Name: "conformsTo", Value: "http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/"},
+		{Name: WARCStartURLField, Value: b.cfg.URL},
+	})
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to write WARC archive: %w", err)
+	}
+	log.Printf("Recording the crawl into %s", b.cfg.WARC)
+	return func() {
+		if err := f.Close(); err != nil {
+			log.Printf("Warning: failed to close WARC archive %s: %v", b.cfg.WARC, err)
+		}
+	}, nil
+}
+
+// recorded returns t recording its exchanges into the archive of Config.WARC,
+// or t itself without one.
+func (b *builder) recorded(t http.RoundTripper) http.RoundTripper {
+	if b.warc == nil {
+		return t
+	}
+	return warc.RecordingTransport(t, b.warc)
+}
+
+// emit passes ev to the Progress callback, if any.
+func (b *builder) emit(ev Event) {
+	if b.cfg.Progress == nil {
+		return
+	}
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+	b.cfg.Progress(ev)
+}
+
+// stageCompleted emits a stage_completed event for a stage that began at start.
+func (b *builder) stageCompleted(stage string, start time.Time, stats map[string]int, files ...string) {
+	b.emit(Event{
+		Type:       StageCompleted,
+		Stage:      stage,
+		DurationMS: time.Since(start).Milliseconds(),
+		Stats:      stats,
+		Files:      files,
+	})
+}
+
+// fetch crawls the site into downloadDir and writes the crawl report.
+func (b *builder) fetch() error {
+	if b.cfg.SkipFetch {
+		if b.cfg.Offline {
+			log.Printf("=== Step 1: Skipped Fetching (Offline, Replaying %s) ===", b.downloadDir)
+			return nil
+		}
+		log.Printf("=== Step 1: Skipped Fetching (Using %s) ===", b.downloadDir)
+		return nil
+	}
+
+	log.Printf("=== Step 1: Fetching %s ===", b.startURL)
+	warnlog.SetStep("fetch")
+	start := time.Now()
+	f := fetcher.New(b.downloadDir)
+	f.SetPageHook(func(rec fetcher.PageRecord) { b.emit(pageEvent(rec)) })
+	f.SetProgressReporter(b.cfg.ProgressReporter)
+	userAgent, err := fetcher.FormatUserAgent(b.cfg.UserAgent, b.cfg.Contact, b.cfg.RunID)
+	if err != nil {
+		return err
+	}
+	f.SetUserAgent(userAgent)
+	f.SetRunID(b.cfg.RunID)
+	if b.cfg.Device != "" {
+		if err := f.SetDevice(b.cfg.Device); err != nil {
+			return err
+		}
+		log.Printf("Device: %s", b.cfg.Device)
+	}
+	log.Printf("User-Agent: %s", f.UserAgent())
+
+	f.SetTransport(b.recorded(b.transport))
+	f.SetDNSCache(b.dns)
+	if b.replay {
+		f.SetDelay(0)
+	} else {
+		f.SetPoliteness(b.cfg.Politeness)
+	}
+	if b.cfg.HostHeader != "" {
+		log.Printf("Crawling %s as host %s", b.cfg.URL, b.cfg.HostHeader)
+	}
+	if len(b.cfg.Resolve) > 0 {
+		log.Printf("Resolve overrides: %v", b.cfg.Resolve)
+	}
+	// A replayed crawl needs no cache
+	if !b.cfg.NoCache && !b.replay {
+		cacheDir := b.cfg.httpCacheDir()
+		cache := httpcache.New(cacheDir, b.transport)
+		cache.FreshFor = b.cfg.RecheckAfter
+		// The cache mustn't buffer the pages the size limits skip
+		cache.MaxBodyBytes = b.cfg.MaxPageBytes
+		if b.cfg.MaxTotalBytes > 0 && (cache.MaxBodyBytes == 0 || b.cfg.MaxTotalBytes < cache.MaxBodyBytes) {
+			cache.MaxBodyBytes = b.cfg.MaxTotalBytes
+		}
+		f.SetTransport(b.recorded(cache))
+		log.Printf("HTTP cache: %s", cacheDir)
+		if b.cfg.RecheckAfter > 0 {
+			log.Printf("Re-checking only the pages fetched more than %s ago", b.cfg.RecheckAfter)
+		}
+	}
+
+	// Configure locale priority if enabled
+	if len(b.cfg.Locales) > 0 {
+		cfg := &fetcher.LocaleConfig{
+			Priority:  b.cfg.Locales,
+			ParamName: b.cfg.LocaleParam,
+		}
+		if aliases := converter.PlatformLocaleAliases(b.cfg.Platform); len(aliases) > 0 {
+			(&fetcher.LocaleDefinitions{Aliases: aliases}).Apply(cfg)
+		}
+		if b.cfg.LocaleFile != "" {
+			defs, err := fetcher.LoadLocaleDefinitions(b.cfg.LocaleFile)
+			if err != nil {
+				return fmt.Errorf("failed to load locale definitions: %w", err)
+			}
+			defs.Apply(cfg)
+			log.Printf("Custom locales: %v, aliases: %v", defs.Locales, defs.Aliases)
+		}
+		if len(b.cfg.LocaleCodes) > 0 || len(b.cfg.LocaleAliases) > 0 {
+			defs := &fetcher.LocaleDefinitions{Locales: b.cfg.LocaleCodes, Aliases: b.cfg.LocaleAliases}
+			defs.Apply(cfg)
+			log.Printf("Custom locales: %v, aliases: %v", defs.Locales, defs.Aliases)
+		}
+		f.SetLocaleConfig(cfg)
+		log.Printf("Locale priority mode enabled: %v", b.cfg.Locales)
+		if b.cfg.LocaleParam != "" {
+			log.Printf("Using query parameter: ?%s=<locale>", b.cfg.LocaleParam)
+		}
+	}
+
+	// Configure version selection if enabled
+	if len(b.cfg.Versions) > 0 {
+		f.SetVersionConfig(&fetcher.VersionConfig{Priority: b.cfg.Versions})
+		log.Printf("Version priority: %v", b.cfg.Versions)
+	}
+
+	if len(b.cfg.Headers) > 0 {
+		f.SetHeaders(b.cfg.Headers)
+		names := make([]string, 0, len(b.cfg.Headers))
+		for name := range b.cfg.Headers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		log.Printf("Sending credentials: %s", strings.Join(names, ", "))
+	}
+
+	if len(b.cfg.Include) > 0 || len(b.cfg.Exclude) > 0 {
+		f.SetURLFilters(b.cfg.Include, b.cfg.Exclude)
+		if len(b.cfg.Include) > 0 {
+			log.Printf("Include filters: %v", b.cfg.Include)
+		}
+		if len(b.cfg.Exclude) > 0 {
+			log.Printf("Exclude filters: %v", b.cfg.Exclude)
+		}
+	}
+
+	if len(b.cfg.Only) > 0 {
+		f.SetPathPatterns(b.cfg.Only)
+		log.Printf("Sections: %v", b.cfg.Only)
+	}
+
+	f.SetDryRun(b.cfg.DryRun)
+	f.SetIgnoreCanonical(b.cfg.IgnoreCanonical)
+	f.SetSkipExternalCanonical(b.cfg.SkipExternalCanonical)
+	f.SetIncludePDF(b.cfg.IncludePDF)
+	f.SetSitemaps(b.cfg.Sitemaps)
+	if policy, _ := fetcher.ParseRobotsPolicy(b.cfg.Robots); policy != fetcher.RobotsDefault {
+		f.SetRobotsPolicy(policy)
+		log.Printf("Robots policy: %s", policy)
+	}
+	f.SetMaxRedirects(b.cfg.MaxRedirects)
+	f.SetAdaptiveThrottle(!b.cfg.NoAdaptiveThrottle)
+	if b.cfg.NoAdaptiveThrottle {
+		log.Printf("Adaptive throttling is disabled")
+	}
+	f.SetSizeLimits(b.cfg.MaxPageBytes, b.cfg.MaxTotalBytes)
+	f.SetCompressCrawl(b.cfg.CompressCrawl)
+	if err := f.SetContentTypes(b.cfg.ContentTypes); err != nil {
+		return err
+	}
+	if len(b.cfg.ContentTypes) > 0 {
+		log.Printf("Content types: %v", b.cfg.ContentTypes)
+	}
+	if err := f.SetLinkAttributes(b.cfg.LinkAttributes); err != nil {
+		return err
+	}
+	if len(b.cfg.LinkAttributes) > 0 {
+		log.Printf("Link attributes: %v", b.cfg.LinkAttributes)
+	}
+	if err := f.SetURLNormalizer(b.cfg.urlNormalizer()); err != nil {
+		return err
+	}
+	if len(b.cfg.StripParams) > 0 {
+		log.Printf("Stripped query parameters: %v", b.cfg.StripParams)
+	}
+	if b.cfg.TrailingSlash != "" && b.cfg.TrailingSlash != TrailingSlashKeep {
+		log.Printf("Trailing slashes: %s", b.cfg.TrailingSlash)
+	}
+	if err := f.SetConsentCookies(b.cfg.ConsentCookies); err != nil {
+		return err
+	}
+	if len(b.cfg.ConsentCookies) > 0 {
+		log.Printf("Consent cookies: %s", strings.Join(b.cfg.ConsentCookies, "; "))
+	}
+	f.SetFollowMetaRefresh(!b.cfg.NoMetaRefresh)
+	f.SetKeepInterstitials(b.cfg.KeepInterstitials)
+	f.SetKeepNoIndex(b.cfg.KeepNoIndex)
+	if b.cfg.MaxPageBytes > 0 {
+		log.Printf("Page size limit: %s", progress.FormatBytes(b.cfg.MaxPageBytes))
+	}
+	if b.cfg.MaxTotalBytes > 0 {
+		log.Printf("Download size budget: %s", progress.FormatBytes(b.cfg.MaxTotalBytes))
+	}
+
+	fetch := f.FetchContext
+	if len(b.cfg.Seeds) > 0 {
+		fetch = func(ctx context.Context, startURL string) error {
+			return f.FetchSeedsContext(ctx, append([]string{startURL}, b.cfg.Seeds...))
+		}
+		log.Printf("More start URLs: %v", b.cfg.Seeds)
+	}
+	if b.cfg.Feed {
+		fetch = func(ctx context.Context, feedURL string) error {
+			return f.FetchFeedContext(ctx, feedURL, b.cfg.FeedArticles)
+		}
+		log.Printf("Feed mode: %s (articles: %t)", b.startURL, b.cfg.FeedArticles)
+	}
+	if b.cfg.Repo {
+		r, err := repo.Parse(b.startURL)
+		if err != nil {
+			return err
+		}
+		fetch = func(ctx context.Context, _ string) error {
+			return f.FetchRepoContext(ctx, r)
+		}
+		log.Printf("Repository mode: %s", r.URL)
+	}
+	if b.cfg.Confluence {
+		s, err := confluence.Parse(b.startURL)
+		if err != nil {
+			return err
+		}
+		f.SetConfluenceToken(b.cfg.ConfluenceToken)
+		fetch = func(ctx context.Context, _ string) error {
+			return f.FetchConfluenceContext(ctx, s)
+		}
+		log.Printf("Confluence mode: space %s at %s (token: %t)", s.Key, s.BaseURL, b.cfg.ConfluenceToken != "")
+	}
+	if b.cfg.Notion {
+		root, err := notion.Parse(b.startURL)
+		if err != nil {
+			return err
+		}
+		f.SetNotionToken(b.cfg.NotionToken)
+		fetch = func(ctx context.Context, _ string) error {
+			return f.FetchNotionContext(ctx, root)
+		}
+		log.Printf("Notion mode: page %s", root.ID)
+	}
+	if b.cfg.Source != nil {
+		fetch = func(ctx context.Context, startURL string) error {
+			return f.FetchSourceContext(ctx, startURL, b.cfg.Source)
+		}
+		log.Printf("Source mode: %T", b.cfg.Source)
+	}
+	if err := fetch(b.ctx, b.startURL); err != nil {
+		// The report of an interrupted crawl records the pages saved until then
+		if report := f.Report(); report != nil && report.Interrupted && !b.cfg.DryRun {
+			b.hidden = warnlog.Flush()
+			b.writeReport(report)
+		}
+		return fmt.Errorf("failed to fetch site: %w", err)
+	}
+	b.hidden = warnlog.Flush()
+
+	report := f.Report()
+	if report == nil {
+		return nil
+	}
+	b.result.Pages = make(map[string]int, len(report.Summary))
+	for outcome, n := range report.Summary {
+		b.result.Pages[string(outcome)] = n
+	}
+	b.stageCompleted("fetch", start, b.result.Pages)
+
+	if b.cfg.DryRun {
+		// The report of the previous crawl is kept for --skip-fetch
+		b.report = report
+		return nil
+	}
+	b.writeReport(report)
+	return nil
+}
+
+// urlNormalizer returns the URL normalizer of the crawl options of c: the
+// default stripped parameters and c.StripParams, c.TrailingSlash, and
+// c.URLRules.
+func (c Config) urlNormalizer() *fetcher.URLNormalizer {
+	return &fetcher.URLNormalizer{
+		StripParams:   append(append([]string(nil), DefaultStripParams...), c.StripParams...),
+		TrailingSlash: c.TrailingSlash,
+		Rules:         c.URLRules,
+	}
+}
+
+// writeReport writes the crawl report with the warnings of the run to
+// BuildResult.ReportPath, logging a warning if it can't be written.
+func (b *builder) writeReport(report *fetcher.CrawlReport) {
+	report.Warnings = warnlog.Records()
+	if err := report.WriteJSON(b.result.ReportPath); err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+	log.Printf("Crawl report written to %s", b.result.ReportPath)
+	if b.hidden > 0 {
+		log.Printf("All warnings are listed in %s", b.result.ReportPath)
+	}
+}
+
+// plan lists the pages of the dry-run crawl in BuildResult.Plan, with the
+// file the convert stage would write each to.
+func (b *builder) plan() {
+	b.result.Plan = []PlannedPage{}
+	for _, rec := range b.report.Pages {
+		if rec.Outcome != fetcher.OutcomePlanned {
+			continue
+		}
+		if len(b.cfg.Only) > 0 && !inSections(b.cfg.Only, rec.URL) {
+			continue
+		}
+		name := strings.TrimSuffix(path.Base(rec.OutputFile), fetcher.CompressedExt)
+		b.result.Plan = append(b.result.Plan, PlannedPage{
+			URL:    rec.URL,
+			Locale: rec.Locale,
+			File:   "docs/" + datedName(rec.Published, sanitizeFilename(strings.TrimSuffix(name, path.Ext(name)))) + ".md",
+		})
+	}
+	log.Printf("Dry run: %d pages would be saved", len(b.result.Plan))
+}
+
+// convert converts every crawled HTML page into a Markdown file in markdownDir.
+func (b *builder) convert() error {
+	log.Printf("=== Step 2: Converting HTML to Markdown ===")
+	warnlog.SetStep("convert")
+	start := time.Now()
+	crawlDir := filepath.Join(b.downloadDir, "crawl")
+
+	var htmlFiles []string
+	filepath.Walk(crawlDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		// Repository mode saves Markdown files (see fetcher.FetchRepo), PDF
+		// documents are saved with IncludePDF, and pages are compressed with
+		// CompressCrawl
+		if ext := filepath.Ext(path); !info.IsDir() && (ext == ".html" || ext == ".md" || ext == ".pdf" || strings.HasSuffix(path, ".html"+fetcher.CompressedExt)) {
+			htmlFiles = append(htmlFiles, path)
+		}
+		return nil
+	})
+
+	log.Printf("Found %d HTML and Markdown files.", len(htmlFiles))
+
+	// Per-page metadata (the locale actually served, HTTP response details) comes from the crawl report
+	var savedPages map[string]fetcher.PageRecord
+	if report, err := fetcher.LoadCrawlReport(b.result.ReportPath); err == nil {
+		savedPages = report.SavedPages()
+		if report.Interrupted && b.cfg.SkipFetch {
+			log.Printf("Warning: the crawl was interrupted; the skill covers only the %d pages saved before it stopped", len(savedPages))
+		}
+	}
+	// The URL of each file comes from the page index, as its name loses part
+	// of it; crawls made before the index fall back to the name
+	index, err := fetcher.LoadPageIndex(b.downloadDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Warning: %v", err)
+	}
+
+	conv, err := b.cfg.newConverter()
+	if err != nil {
+		return err
+	}
+	b.addExtensions(conv)
+	if b.cfg.ContentSelector != "" {
+		log.Printf("Content selector: %s", b.cfg.ContentSelector)
+	}
+	if len(b.cfg.StripSelectors) > 0 {
+		log.Printf("Strip selectors: %v", b.cfg.StripSelectors)
+	}
+	if b.cfg.Platform != "" {
+		log.Printf("Platform: %s", b.cfg.Platform)
+	}
+	if len(b.cfg.PageTypes) > 0 {
+		log.Printf("Page types: %v", b.cfg.PageTypes)
+	}
+	if b.cfg.LanguageFilter != "" && b.cfg.LanguageFilter != converter.LanguageFilterOff {
+		log.Printf("Language filter: %s", b.cfg.LanguageFilter)
+	}
+	if !b.cfg.NoCache {
+		if err := conv.SetCache(filepath.Join(b.cfg.TempDir, "convert-cache")); err != nil {
+			log.Printf("Warning: conversion cache disabled: %v", err)
+		}
+	}
+
+	absCrawlDir, err := filepath.Abs(crawlDir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path for crawl dir: %w", err)
+	}
+	var series map[string][]string
+	stitched := make(map[string]bool)
+	if b.cfg.StitchPages {
+		series = pageSeries(savedPages, b.downloadDir)
+		for _, following := range series {
+			for _, file := range following {
+				stitched[file] = true
+			}
+		}
+		if len(series) > 0 {
+			log.Printf("Stitching %d pages into %d paginated documents", len(stitched), len(series))
+		}
+	}
+	failed, outside, skipped := 0, 0, 0
+	// firstErr is the error of the first page that failed, which the error of
+	// a stage where every page failed wraps
+	var firstErr error
+	for _, htmlFile := range htmlFiles {
+		if err := b.ctx.Err(); err != nil {
+			return err
+		}
+		if stitched[htmlFile] {
+			// Converted with the first page of its series
+			skipped++
+			continue
+		}
+
+		// Security check
+		absHTMLFile, err := filepath.Abs(htmlFile)
+		if err != nil {
+			log.Printf("Warning: could not get absolute path for %s: %v", htmlFile, err)
+			continue
+		}
+		relPath, err := filepath.Rel(absCrawlDir, absHTMLFile)
+		if err != nil || len(relPath) > 0 && relPath[0] == '.' {
+			log.Printf("Warning: skipping potential path traversal file: %s", htmlFile)
+			continue
+		}
+
+		// Construct source URL, with the scheme of the start URL the page was reached from
+		file := filepath.ToSlash(filepath.Join("crawl", relPath))
+		rec, saved := savedPages[file]
+		baseURL := b.cfg.URL
+		if rec.Seed != "" {
+			baseURL = rec.Seed
+		}
+		sourceURL := reconstructURL(baseURL, relPath)
+		if page, ok := index.Lookup(file); ok {
+			sourceURL = page.URL
+		}
+		if len(b.cfg.Only) > 0 && !inSections(b.cfg.Only, sourceURL) {
+			// The pages leading to the sections are crawled but not converted
+			outside++
+			continue
+		}
+
+		// Determine output filename; the documents of feed entries are dated
+		baseName := strings.TrimSuffix(filepath.Base(htmlFile), fetcher.CompressedExt)
+		nameWithoutExt := baseName[:len(baseName)-len(filepath.Ext(baseName))]
+		mdFilename := datedName(rec.Published, sanitizeFilename(nameWithoutExt)) + ".md"
+		mdPath := filepath.Join(b.markdownDir, mdFilename)
+
+		if _, err := os.Stat(mdPath); err == nil {
+			log.Printf("Warning: name collision for %s. Overwriting.", mdFilename)
+		}
+
+		meta := converter.PageMeta{SourceURL: sourceURL, FetchedAt: b.fetchedAt, Access: access.LevelOf(b.accessRules, sourceURL)}
+		if saved {
+			meta.Locale = rec.Locale
+			meta.Version = rec.Version
+			meta.StatusCode = rec.StatusCode
+			meta.ContentLanguage = rec.ContentLanguage
+			meta.ContentType = rec.ContentType
+			meta.ModifiedAt = rec.LastModified
+			meta.Author = rec.Author
+			meta.Published = rec.Published
+			meta.Source = sourceName(rec.Seed)
+			meta.FinalURL = rec.URL
+			if rec.FinalURL != "" {
+				meta.FinalURL = rec.FinalURL
+			} else if rec.FetchedURL != "" {
+				meta.FinalURL = rec.FetchedURL
+			}
+		}
+
+		convert := conv.ConvertPage
+		switch filepath.Ext(htmlFile) {
+		case ".md":
+			convert = conv.ConvertMarkdown
+		case ".pdf":
+			convert = conv.ConvertPDF
+		default:
+			if following := series[htmlFile]; len(following) > 0 {
+				convert = func(htmlFile, mdPath string, meta converter.PageMeta) error {
+					return conv.ConvertSeries(append([]string{htmlFile}, following...), mdPath, meta)
+				}
+			}
+		}
+		if err := convert(htmlFile, mdPath, meta); err != nil {
+			log.Printf("Error converting %s: %v", htmlFile, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			failed++
+		}
+	}
+
+	if outside > 0 {
+		log.Printf("Sections: skipped %d pages outside them", outside)
+	}
+	hits, misses := conv.CacheStats()
+	if hits > 0 {
+		log.Printf("Conversion cache: %d reused, %d converted", hits, misses)
+	}
+	b.hidden += warnlog.Flush()
+	if failed > 0 && failed == len(htmlFiles)-outside-skipped {
+		return fmt.Errorf("%w for all %d pages, first: %w", converter.ErrConversionFailed, failed, firstErr)
+	}
+	b.stageCompleted("convert", start, map[string]int{
+		"pages":      len(htmlFiles),
+		"failed":     failed,
+		"cache_hits": hits,
+	})
+	return nil
+}
+
+// newConverter returns a converter configured with the conversion options of c.
+func (c Config) newConverter() (*converter.Converter, error) {
+	conv := converter.New()
+	if c.ContentSelector != "" {
+		if err := conv.SetContentSelector(c.ContentSelector); err != nil {
+			return nil, fmt.Errorf("failed to configure converter: %w", err)
+		}
+	}
+	if len(c.StripSelectors) > 0 {
+		if err := conv.SetStripSelectors(c.StripSelectors); err != nil {
+			return nil, fmt.Errorf("failed to configure converter: %w", err)
+		}
+	}
+	if err := conv.SetPlatform(c.Platform); err != nil {
+		return nil, fmt.Errorf("failed to configure converter: %w", err)
+	}
+	if c.TableFallback != "" {
+		if err := conv.SetTableFallback(c.TableFallback); err != nil {
+			return nil, fmt.Errorf("failed to configure converter: %w", err)
+		}
+	}
+	if c.Admonitions != "" {
+		if err := conv.SetAdmonitionStyle(c.Admonitions); err != nil {
+			return nil, fmt.Errorf("failed to configure converter: %w", err)
+		}
+	}
+	conv.SetTableCSVRows(c.TableCSVRows)
+	if len(c.PageTypes) > 0 {
+		if err := conv.SetPageTypes(c.PageTypes); err != nil {
+			return nil, fmt.Errorf("failed to configure converter: %w", err)
+		}
+	}
+	if err := conv.SetLanguageFilter(c.LanguageFilter, c.Locales); err != nil {
+		return nil, fmt.Errorf("failed to configure converter: %w", err)
+	}
+	return conv, nil
+}
+
+// normalize removes duplicate pages, cleans up the Markdown files, and applies
+// the asset, URL, air-gap, and snippet rewrites.
+func (b *builder) normalize() error {
+	log.Printf("=== Step 3: Normalizing Markdown ===")
+	warnlog.SetStep("normalize")
+	start := time.Now()
+	mdFiles, err := filepath.Glob(filepath.Join(b.markdownDir, "*.md"))
+	if err != nil {
+		return fmt.Errorf("failed to find markdown files: %w", err)
+	}
+
+	duplicates := 0
+	if !b.cfg.KeepDuplicates {
+		stats, err := dedupe.New().Deduplicate(mdFiles)
+		if err != nil {
+			return fmt.Errorf("failed to remove duplicate pages: %w", err)
+		}
+		if duplicates = stats.Duplicates + stats.NearDuplicates; duplicates > 0 {
+			log.Printf("Duplicates: removed %d identical and %d nearly identical pages, recorded as aliases",
+				stats.Duplicates, stats.NearDuplicates)
+			if mdFiles, err = filepath.Glob(filepath.Join(b.markdownDir, "*.md")); err != nil {
+				return fmt.Errorf("failed to find markdown files: %w", err)
+			}
+		}
+	}
+
+	norm := normalizer.New()
+	for _, mdFile := range mdFiles {
+		if err := b.ctx.Err(); err != nil {
+			return err
+		}
+		if err := norm.NormalizeFile(mdFile, mdFile); err != nil {
+			log.Printf("Error normalizing %s: %v", mdFile, err)
+		}
+	}
+	if !b.cfg.AbsoluteLinks {
+		if err := b.rewriteLinks(mdFiles); err != nil {
+			return err
+		}
+	}
+
+	if b.cfg.DownloadAssets {
+		downloader := assets.New(filepath.Join(b.markdownDir, assets.DirName))
+		userAgent, err := b.cfg.userAgent()
+		if err != nil {
+			return err
+		}
+		downloader.SetUserAgent(userAgent)
+		if !b.cfg.NoCache && !b.cfg.Offline {
+			downloader.SetTransport(b.recorded(httpcache.New(b.cfg.httpCacheDir(), b.transport)))
+		} else {
+			downloader.SetTransport(b.recorded(b.transport))
+		}
+		var total assets.Stats
+		for _, mdFile := range mdFiles {
+			if err := b.ctx.Err(); err != nil {
+				return err
+			}
+			stats, err := downloader.LocalizeFileContext(b.ctx, mdFile)
+			if err := b.ctx.Err(); err != nil {
+				return err
+			}
+			if err != nil {
+				log.Printf("Error downloading assets for %s: %v", mdFile, err)
+				continue
+			}
+			total.Downloaded += stats.Downloaded
+			total.Failed += stats.Failed
+			total.Shared += stats.Shared
+		}
+		if err := downloader.WriteMap(); err != nil {
+			return fmt.Errorf("failed to write the asset map: %w", err)
+		}
+		log.Printf("Assets: linked %d references to local files, %d could not be downloaded, %d duplicates stored once", total.Downloaded, total.Failed, total.Shared)
+	}
+
+	if b.rewriter != nil {
+		rewritten := 0
+		for _, mdFile := range mdFiles {
+			n, err := b.rewriter.RewriteFile(mdFile)
+			if err != nil {
+				return fmt.Errorf("failed to rewrite URLs: %w", err)
+			}
+			rewritten += n
+		}
+		log.Printf("URL rewrites: rewrote %d URLs", rewritten)
+	}
+
+	if b.cfg.AirGapped {
+		var total airgap.Stats
+		for _, mdFile := range mdFiles {
+			stats, err := airgap.RewriteFile(mdFile)
+			if err != nil {
+				return fmt.Errorf("failed to remove external references from %s: %w", mdFile, err)
+			}
+			total.Links += stats.Links
+			total.Images += stats.Images
+			total.Embeds += stats.Embeds
+			total.BareURLs += stats.BareURLs
+		}
+		log.Printf("Air-gapped: neutralized %d external links, %d remote images, %d embeds, %d bare URLs",
+			total.Links, total.Images, total.Embeds, total.BareURLs)
+	}
+	if b.cfg.DedupeSnippets {
+		stats, err := snippets.New(filepath.Join(b.markdownDir, snippets.DirName)).Deduplicate(mdFiles)
+		if err != nil {
+			return fmt.Errorf("failed to share repeated code samples: %w", err)
+		}
+		log.Printf("Snippets: replaced %d repeated code samples with links to %d shared files", stats.Replaced, stats.Snippets)
+	}
+	b.hidden += warnlog.Flush()
+	recordWarnings(b.result.ReportPath, b.cfg.SkipFetch, b.hidden)
+	b.stageCompleted("normalize", start, map[string]int{"files": len(mdFiles), "duplicates": duplicates})
+	return nil
+}
+
+// rewriteLinks points the links of mdFiles to the pages of the skill at their
+// files and marks those to the other pages of the site (see package links).
+// When only some sections are rebuilt, or the skill is updated, the documents
+// of the existing skill are link targets too.
+func (b *builder) rewriteLinks(mdFiles []string) error {
+	index := links.NewIndex()
+	if (b.cfg.Update || len(b.cfg.Only) > 0) && len(b.cfg.Targets) > 0 {
+		existing, _ := filepath.Glob(filepath.Join(b.cfg.Targets[0].Dir, b.cfg.SkillName, "docs", "*.md"))
+		for _, mdFile := range existing {
+			if err := index.AddFile(mdFile); err != nil {
+				log.Printf("Warning: could not index links of %s: %v", mdFile, err)
+			}
+		}
+	}
+	for _, mdFile := range mdFiles {
+		if err := index.AddFile(mdFile); err != nil {
+			log.Printf("Warning: could not index links of %s: %v", mdFile, err)
+		}
+	}
+	var total links.Stats
+	for _, mdFile := range mdFiles {
+		if err := b.ctx.Err(); err != nil {
+			return err
+		}
+		stats, err := index.RewriteFile(mdFile)
+		if err != nil {
+			return fmt.Errorf("failed to rewrite links of %s: %w", mdFile, err)
+		}
+		total.Add(stats)
+	}
+	log.Printf("Links: pointed %d links at the files of their pages, marked %d links to pages not in the skill", total.Internal, total.Uncrawled)
+	return nil
+}
+
+// chunk splits the Markdown files longer than the token budget into several files.
+func (b *builder) chunk() error {
+	if b.cfg.ChunkTokens <= 0 {
+		log.Printf("=== Step 4: Skipped Chunking (No Token Budget) ===")
+		return nil
+	}
+
+	log.Printf("=== Step 4: Chunking Pages Over %d Tokens ===", b.cfg.ChunkTokens)
+	start := time.Now()
+	mdFiles, err := filepath.Glob(filepath.Join(b.markdownDir, "*.md"))
+	if err != nil {
+		return fmt.Errorf("failed to find markdown files: %w", err)
+	}
+
+	ch := chunker.New(b.cfg.ChunkTokens)
+	if b.cfg.Tokenizer != nil {
+		ch.SetTokenizer(b.cfg.Tokenizer)
+	}
+	split, parts := 0, 0
+	for _, mdFile := range mdFiles {
+		if err := b.ctx.Err(); err != nil {
+			return err
+		}
+		paths, err := ch.ChunkFile(mdFile)
+		if err != nil {
+			log.Printf("Error chunking %s: %v", mdFile, err)
+			continue
+		}
+		if len(paths) > 1 {
+			log.Printf("Chunked: %s -> %d files", mdFile, len(paths))
+			split++
+			parts += len(paths)
+		}
+	}
+	if split > 0 && !b.cfg.AbsoluteLinks {
+		// Links into the split pages now point at the part holding their heading
+		if mdFiles, err = filepath.Glob(filepath.Join(b.markdownDir, "*.md")); err != nil {
+			return fmt.Errorf("failed to find markdown files: %w", err)
+		}
+		if err := b.rewriteLinks(mdFiles); err != nil {
+			return err
+		}
+	}
+	log.Printf("Chunking: split %d pages into %d files", split, parts)
+	b.stageCompleted("chunk", start, map[string]int{"files": len(mdFiles), "split": split, "parts": parts})
+	return nil
+}
+
+// generate creates the skill structure for every target.
+func (b *builder) generate() error {
+	start := time.Now()
+	dirs := make([]string, 0, len(b.cfg.Targets))
+	for _, target := range b.cfg.Targets {
+		gen := skillgen.New(target.Format)
+		gen.SetSourceURL(b.cfg.URL)
+		gen.SetOnly(b.cfg.Only)
+		gen.SetTimestamp(b.cfg.Timestamp)
+		dir := filepath.Join(target.Dir, b.cfg.SkillName)
+		// The embeddings of the previous skill, if any, spare embedding the unchanged sections again
+		var prevEmbeddings *search.Embeddings
+		if b.cfg.Embedder != nil {
+			prevEmbeddings, _ = search.LoadEmbeddings(dir)
+		}
+		var changes *skillgen.Changes
+		if b.cfg.Update || len(b.cfg.Only) > 0 {
+			log.Printf("=== Step 7: Updating Skill Structure (%s format) ===", target.Format)
+			var err error
+			if changes, err = gen.Update(b.cfg.SkillName, b.markdownDir, target.Dir); err != nil {
+				return fmt.Errorf("failed to update skill structure: %w", err)
+			}
+		} else {
+			log.Printf("=== Step 7: Generating Skill Structure (%s format) ===", target.Format)
+			if err := gen.Generate(b.cfg.SkillName, b.markdownDir, target.Dir); err != nil {
+				return fmt.Errorf("failed to generate skill structure: %w", err)
+			}
+		}
+		if b.cfg.Embedder != nil {
+			log.Printf("Embedding documents with %s...", b.cfg.Embedder.Spec())
+			embedded, err := search.BuildEmbeddings(b.ctx, dir, b.cfg.Embedder, prevEmbeddings)
+			if err != nil {
+				return fmt.Errorf("failed to build embeddings: %w", err)
+			}
+			log.Printf("Embedded %d sections of %d documents (%d unchanged)", embedded.Chunks, embedded.Documents, embedded.Reused)
+		}
+		stats, err := skillgen.WriteStats(dir, b.cfg.Tokenizer)
+		if err != nil {
+			return fmt.Errorf("failed to write skill statistics: %w", err)
+		}
+		log.Printf("%s", stats.Summary())
+		b.result.Skills = append(b.result.Skills, Skill{
+			Format:  target.Format,
+			Dir:     dir,
+			Tokens:  stats.Tokens,
+			Bytes:   stats.Bytes,
+			Changes: changes,
+		})
+		dirs = append(dirs, dir)
+	}
+	b.stageCompleted("generate", start, changeStats(map[string]int{"skills": len(dirs)}, b.result.Skills), dirs...)
+	return nil
+}
+
+// changeStats adds the numbers of pages added, modified, and removed by the
+// updates of skills to stats, the statistics of the generate stage, when the
+// build is an update.
+func changeStats(stats map[string]int, skills []Skill) map[string]int {
+	for _, s := range skills {
+		if s.Changes == nil {
+			continue
+		}
+		stats["added"] += len(s.Changes.Added)
+		stats["modified"] += len(s.Changes.Modified)
+		stats["removed"] += len(s.Changes.Removed)
+	}
+	return stats
+}
+
+// validate checks every generated skill, and in air-gapped builds fails if any
+// external reference remains.
+func (b *builder) validate() error {
+	log.Printf("=== Step 8: Validating Skill ===")
+	start := time.Now()
+	val := validator.New()
+	invalid := 0
+	for i := range b.result.Skills {
+		skill := &b.result.Skills[i]
+		skill.Valid = val.Validate(skill.Dir)
+		if !skill.Valid {
+			log.Printf("Warning: Validation failed for %s. Please check errors.", skill.Dir)
+			invalid++
+		}
+
+		if b.cfg.AirGapped {
+			violations, err := airgap.Check(skill.Dir)
+			if err != nil {
+				return fmt.Errorf("failed to check %s for external references: %w", skill.Dir, err)
+			}
+			if len(violations) > 0 {
+				for _, v := range violations {
+					log.Printf("  - %s", v)
+				}
+				return fmt.Errorf("air-gapped build failed: %s still references %d external resources", skill.Dir, len(violations))
+			}
+			log.Printf("Air-gapped check passed: no external references")
+		}
+	}
+	b.stageCompleted("validate", start, map[string]int{"valid": len(b.result.Skills) - invalid, "invalid": invalid})
+	return nil
+}
+
+// pack packages every skill as a .skill file with the integrity manifest of its
+// files, signing it if a key is configured.
+func (b *builder) pack() error {
+	log.Printf("=== Step 9: Packaging Skill ===")
+	start := time.Now()
+	pkg := packager.New()
+	pkg.SetModTime(b.cfg.Timestamp)
+	files := make([]string, 0, len(b.result.Skills))
+	for i, target := range b.cfg.Targets {
+		skill := &b.result.Skills[i]
+		if m, err := skillgen.ReadManifest(skill.Dir); err == nil {
+			pkg.SetSource(m.URL)
+		}
+		skillFile, err := pkg.Package(skill.Dir, target.Dir)
+		if err != nil {
+			return fmt.Errorf("failed to package skill: %w", err)
+		}
+		skill.Package = skillFile
+		files = append(files, skillFile)
+
+		if b.cfg.SignKey != nil {
+			sigPath, err := packager.SignArchive(skillFile, b.cfg.SignKey)
+			if err != nil {
+				return fmt.Errorf("failed to sign skill: %w", err)
+			}
+			skill.Signature = sigPath
+			log.Printf("Signed %s (key %s): %s", skillFile, packager.KeyID(b.cfg.SignKey.Public().(ed25519.PublicKey)), sigPath)
+		}
+	}
+	b.stageCompleted("package", start, map[string]int{"packages": len(files)}, files...)
+	return b.publish()
+}
+
+// publish publishes the packaged skills with the publishers of
+// Config.Publishers.
+func (b *builder) publish() error {
+	if len(b.cfg.Publishers) == 0 {
+		return nil
+	}
+	start := time.Now()
+	var skills []Skill
+	for _, skill := range b.result.Skills {
+		if skill.Changes != nil && skill.Changes.Empty() {
+			log.Printf("Not publishing %s: unchanged since the last update", skill.Package)
+			continue
+		}
+		skills = append(skills, skill)
+	}
+	for _, p := range b.cfg.Publishers {
+		for _, skill := range skills {
+			if err := p.Publish(b.ctx, skill); err != nil {
+				return fmt.Errorf("failed to publish skill %s: %w", skill.Package, err)
+			}
+			log.Printf("Published %s (%s)", skill.Package, publisherName(p))
+		}
+	}
+	b.stageCompleted("publish", start, map[string]int{
+		"publishers": len(b.cfg.Publishers),
+		"skills":     len(skills),
+		"unchanged":  len(b.result.Skills) - len(skills),
+	})
+	return nil
+}
+
+// publisherName returns the name of p for the log: its String method if it
+// has one, e.g., the target of NewPublisher, or else its type.
+func publisherName(p Publisher) string {
+	if s, ok := p.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", p)
+}
+
+// recordWarnings stores the warnings of this run in the crawl report at
+// reportPath, replacing those of an earlier run. When fetching was skipped, the
+// warnings of the crawl that produced the report are kept. hidden is the number
+// of warnings not shown on the console since the crawl.
+func recordWarnings(reportPath string, skipFetch bool, hidden int) {
+	report, err := fetcher.LoadCrawlReport(reportPath)
+	if err != nil {
+		return
+	}
+
+	var warnings []warnlog.Record
+	if skipFetch {
+		for _, w := range report.Warnings {
+			if w.Step == "fetch" {
+				warnings = append(warnings, w)
+			}
+		}
+	}
+	report.Warnings = append(warnings, warnlog.Records()...)
+	if err := report.WriteJSON(reportPath); err != nil {
+		log.Printf("Warning: %v", err)
+		return
+	}
+	if hidden > 0 {
+		log.Printf("All warnings are listed in %s", reportPath)
+	}
+}
+
+// inSections reports whether the path of sourceURL matches one of patterns.
+func inSections(patterns []string, sourceURL string) bool {
+	u, err := url.Parse(sourceURL)
+	return err == nil && pathglob.MatchAny(patterns, u.Path)
+}
+
+// reconstructURL reconstructs the original website URL from a crawled file's relative path.
+// It removes the .html extension and prepends the appropriate scheme (http or https).
+//
+// Parameters:
+//   - baseURL: The base URL of the crawled site (used to determine scheme)
+//   - relPath: The relative file path from the crawl directory
+//
+// Returns the reconstructed URL as a string with the format "scheme://path",
+// in its request form: the Unicode host and path of an internationalized site
+// (see idn.ToDisplay) are written in punycode and percent-encoded.
+//
+// Example:
+//
+//	baseURL: "https://example.com"
+//	relPath: "docs/api/index.html"
+//	returns: "https://docs/api/index"
+func reconstructURL(baseURL, relPath string) string {
+	// Remove .html extension if present, of compressed pages too
+	relPath = strings.TrimSuffix(relPath, fetcher.CompressedExt)
+	if len(relPath) > 5 && relPath[len(relPath)-5:] == ".html" {
+		relPath = relPath[:len(relPath)-5]
+	}
+
+	// Parse base URL to get scheme
+	scheme := "https"
+	if len(baseURL) > 7 && baseURL[:7] == "http://" {
+		scheme = "http"
+	}
+
+	return idn.ToASCII(fmt.Sprintf("%s://%s", scheme, filepath.ToSlash(relPath)))
+}
+
+// pageSeries returns the paginated series of the crawl whose pages are
+// stitched into one document (see Config.StitchPages): the files of the
+// pages after the first, in order, by the file of the first page, as paths in
+// downloadDir. savedPages are the saved pages of the crawl report by output
+// file; the pages of a series whose first page wasn't saved are left alone.
+func pageSeries(savedPages map[string]fetcher.PageRecord, downloadDir string) map[string][]string {
+	files := make(map[string]string, len(savedPages))
+	for file, rec := range savedPages {
+		files[rec.URL] = file
+		if rec.FinalURL != "" {
+			files[rec.FinalURL] = file
+		}
+	}
+	following := make(map[string][]fetcher.PageRecord)
+	for _, rec := range savedPages {
+		if first, ok := files[rec.SeriesOf]; ok && rec.SeriesOf != "" {
+			following[first] = append(following[first], rec)
+		}
+	}
+	series := make(map[string][]string, len(following))
+	for first, recs := range following {
+		sort.Slice(recs, func(i, j int) bool { return recs[i].PageNumber < recs[j].PageNumber })
+		firstFile := filepath.Join(downloadDir, filepath.FromSlash(first))
+		for _, rec := range recs {
+			series[firstFile] = append(series[firstFile], filepath.Join(downloadDir, filepath.FromSlash(rec.OutputFile)))
+		}
+	}
+	return series
+}
+
+// sourceName returns the name of the site crawled from seed, a start URL of
+// a crawl from several: its host and path without the trailing slash (e.g.,
+// "docs.example.com" or "github.com/acme/app/wiki"). Returns "" for "".
+func sourceName(seed string) string {
+	u, err := url.Parse(seed)
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	return strings.TrimSuffix(u.Host+u.Path, "/")
+}
+
+// datedName returns name prefixed with the date of published, an RFC 3339
+// time, as "2006-01-02-name", so that the documents of feed entries sort by
+// date; name itself if published is empty or invalid.
+func datedName(published, name string) string {
+	t, err := time.Parse(time.RFC3339, published)
+	if err != nil {
+		return name
+	}
+	return t.Format("2006-01-02") + "-" + name
+}
+
+// sanitizeFilename removes invalid characters from a filename to ensure cross-platform compatibility.
+// It replaces any character that is not a letter, digit, dot, underscore, or hyphen with an underscore.
+// Letters and digits of any script are kept, so the pages of Japanese and other
+// non-English sites keep readable, distinct names.
+//
+// Parameters:
+//   - name: The filename to sanitize
+//
+// Returns a sanitized filename safe for use on all major operating systems.
+//
+// Example:
+//
+//	"hello world!.txt" -> "hello_world_.txt"
+//	"file/path\\name" -> "file_path_name"
+//	"はじめに" -> "はじめに"
+func sanitizeFilename(name string) string {
+	// Replace characters other than letters, digits, and ._- with _
+	result := ""
+	for _, ch := range name {
+		if unicode.IsLetter(ch) || unicode.IsDigit(ch) || unicode.IsMark(ch) ||
+			ch == '.' || ch == '_' || ch == '-' {
+			result += string(ch)
+		} else {
+			result += "_"
+		}
+	}
+	return result
+}
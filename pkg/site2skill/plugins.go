@@ -0,0 +1,90 @@
+// Package site2skill runs the whole site2skillgo build pipeline in-process.
+//
+// This file implements the plugins of a build: third-party extractors,
+// converters, and exporters run as subprocesses, and converter commands run
+// once per page (see package plugin).
+package site2skill
+
+import (
+	"log"
+	"strings"
+
+	"github.com/f4ah6o/site2skill-go/internal/converter"
+	"github.com/f4ah6o/site2skill-go/internal/plugin"
+)
+
+// startPlugins parses the converter commands of Config.Converters, starts
+// the plugins of Config.Plugins, which stay running until stopPlugins, and
+// checks Config.Exports against the output formats they add.
+func (b *builder) startPlugins() error {
+	b.commands = nil
+	for _, spec := range b.cfg.Converters {
+		c, err := plugin.ParseCommand(spec)
+		if err != nil {
+			return err
+		}
+		log.Printf("Converter command: %s", spec)
+		b.commands = append(b.commands, c)
+	}
+	for _, command := range b.cfg.Plugins {
+		p, err := plugin.Start(b.ctx, command)
+		if err != nil {
+			b.stopPlugins()
+			return err
+		}
+		info := p.Info()
+		log.Printf("Plugin: %s %s (%s)", info.Name, info.Version, strings.Join(info.Capabilities, ", "))
+		b.plugins = append(b.plugins, p)
+	}
+	for _, spec := range b.cfg.Exports {
+		if _, _, err := converter.ParseExport(spec, b.pluginFormats()...); err != nil {
+			b.stopPlugins()
+			return err
+		}
+	}
+	return nil
+}
+
+// stopPlugins shuts down the running plugins.
+func (b *builder) stopPlugins() {
+	for _, p := range b.plugins {
+		p.Close()
+	}
+	b.plugins = nil
+}
+
+// pluginFormats returns the output formats of the running plugins.
+func (b *builder) pluginFormats() []converter.OutputFormat {
+	var formats []converter.OutputFormat
+	for _, p := range b.plugins {
+		for _, f := range p.Formats() {
+			formats = append(formats, f)
+		}
+	}
+	return formats
+}
+
+// addExtensions makes conv offer the pages to the converters of
+// Config.Extensions, then to the converter commands, in the order of
+// Config.Converters, then to the running plugins that extract or convert
+// them, in the order of Config.Plugins, and run the transformers of
+// Config.Transformers and Config.MarkdownTransformers.
+func (b *builder) addExtensions(conv *converter.Converter) {
+	for _, t := range b.cfg.Transformers {
+		conv.AddTransformer(t)
+	}
+	for _, t := range b.cfg.MarkdownTransformers {
+		conv.AddMarkdownTransformer(t)
+	}
+	for _, ext := range b.cfg.Extensions {
+		conv.AddExtension(ext)
+	}
+	for _, c := range b.commands {
+		conv.AddExtension(c)
+	}
+	for _, p := range b.plugins {
+		if p.Has(plugin.CapabilityExtract) || p.Has(plugin.CapabilityConvert) {
+			conv.AddExtension(p)
+		}
+	}
+}
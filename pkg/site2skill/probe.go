@@ -0,0 +1,87 @@
+// Package site2skill runs the whole site2skillgo build pipeline in-process.
+//
+// This file implements the probe of a documentation site that the init
+// command suggests the settings of a config file profile from.
+package site2skill
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/f4ah6o/site2skill-go/internal/fetcher"
+	"golang.org/x/net/html"
+)
+
+// SiteProbe describes a documentation site from its start page and its
+// robots.txt: see Probe.
+type SiteProbe struct {
+	// URL is the probed start URL; FinalURL is the URL of the response after redirects.
+	URL      string `json:"url"`
+	FinalURL string `json:"final_url"`
+	// Title is the title of the start page.
+	Title string `json:"title"`
+	// Generator is the content of the generator meta tag of the start page
+	// (e.g., "Docusaurus v3.1.0"); empty when it has none.
+	Generator string `json:"generator,omitempty"`
+	// Platform is the name of the platform profile detected for the start
+	// page (e.g., "docusaurus"); empty when none was.
+	Platform string `json:"platform,omitempty"`
+	// Lang is the language the start page declares (e.g., "en").
+	Lang string `json:"lang,omitempty"`
+	// Locales are the locales of the variants of the start page its hreflang
+	// links list (e.g., ["en", "ja"]), sorted, without x-default.
+	Locales []string `json:"locales,omitempty"`
+	// RobotsAllowed reports whether the robots.txt of the site lets the
+	// crawler fetch the start URL.
+	RobotsAllowed bool `json:"robots_allowed"`
+	// Sitemaps are the sitemaps the robots.txt of the site lists, whose pages
+	// Config.Sitemaps crawls.
+	Sitemaps []string `json:"sitemaps,omitempty"`
+}
+
+// Probe fetches the start page of the site at siteURL, as Inspect does, and
+// its robots.txt, and reports what the settings of a build of the site
+// depend on: the platform of the site, detected whatever cfg.Platform, the
+// locales of its hreflang links, whether robots.txt allows the crawl, and the
+// sitemaps it lists.
+//
+// Of cfg, the options used by Inspect are used.
+//
+// Returns an error if the options are invalid, the page can't be fetched, or
+// its response isn't successful.
+func Probe(ctx context.Context, cfg Config, siteURL string) (*SiteProbe, error) {
+	cfg.Platform = "auto"
+	page, err := inspectPage(ctx, cfg, siteURL)
+	if err != nil {
+		return nil, err
+	}
+	in := page.inspection
+	probe := &SiteProbe{
+		URL:       in.URL,
+		FinalURL:  in.FinalURL,
+		Title:     in.Title,
+		Generator: in.Generator,
+		Platform:  in.Platform,
+		Lang:      in.Lang,
+	}
+	if doc, err := html.Parse(bytes.NewReader(page.body)); err == nil {
+		for locale := range fetcher.ExtractHreflang(doc) {
+			if locale != "x-default" {
+				probe.Locales = append(probe.Locales, locale)
+			}
+		}
+		sort.Strings(probe.Locales)
+	}
+
+	robots := fetcher.NewRobotsChecker(page.userAgent)
+	robots.SetTransport(page.transport)
+	err = robots.Check(ctx, in.FinalURL)
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	probe.RobotsAllowed = !errors.Is(err, fetcher.ErrRobotsBlocked)
+	probe.Sitemaps = robots.Sitemaps(ctx, in.FinalURL)
+	return probe, nil
+}
@@ -0,0 +1,50 @@
+// Package site2skill runs the whole site2skillgo build pipeline in-process.
+//
+// This file implements the search of generated skills.
+package site2skill
+
+import (
+	"github.com/f4ah6o/site2skill-go/internal/search"
+)
+
+// Searcher searches the documents of a generated skill, like the search
+// command: see search.Searcher and NewSearcher.
+type Searcher = search.Searcher
+
+// SearchOptions describes a search: the query, its syntax and matching
+// (MatchAll, Regex, Fuzzy, Stem, Semantic), the filters (Access, Locale,
+// PathPrefix, FetchedAfter, Tag), and the results (MaxResults, ContextLines,
+// Highlight, Stream). SkillDir is ignored by a Searcher.
+type SearchOptions = search.SearchOptions
+
+// SearchResult is a document matching a search, with the contexts of its
+// matches and the sections they are in.
+type SearchResult = search.SearchResult
+
+// Symbol is a code symbol declared in a code block of a skill's documents, as
+// recorded in its symbols.json: see Searcher.Symbols.
+type Symbol = search.Symbol
+
+// RelatedDocument is a document similar to another, with its similarity: see
+// Searcher.Related.
+type RelatedDocument = search.RelatedDocument
+
+// NewSearcher returns a searcher of the skill in skillDir (the directory
+// containing SKILL.md and docs/), so that other Go programs can search a
+// skill without running the binary:
+//
+//	s, err := site2skill.NewSearcher(".claude/skills/example")
+//	if err != nil {
+//		return err
+//	}
+//	for res, err := range s.Search(ctx, site2skill.SearchOptions{Query: "auth", Stream: true}) {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(res.File)
+//	}
+//
+// Returns an error if skillDir has no docs directory.
+func NewSearcher(skillDir string) (*Searcher, error) {
+	return search.NewSearcher(skillDir)
+}
@@ -0,0 +1,1374 @@
+// Package site2skill runs the whole site2skillgo build pipeline in-process, so
+// other Go programs (e.g., a service that turns a customer's docs URL into a
+// skill) can embed it instead of shelling out to the binary.
+//
+// A build fetches a documentation site, converts its pages to Markdown,
+// normalizes them, generates one skill directory per target format, validates
+// it, and packages it as a .skill file:
+//
+//	res, err := site2skill.Build(ctx, site2skill.Config{
+//		URL:       "https://docs.example.com/",
+//		SkillName: "example",
+//		Targets:   []site2skill.Target{{Format: site2skill.FormatClaude, Dir: "out"}},
+//		TempDir:   "build",
+//		Progress: func(ev site2skill.Event) {
+//			log.Printf("%s %s%s", ev.Type, ev.URL, ev.Stage)
+//		},
+//	})
+//
+// Progress is reported through Config.Progress with the same events the
+// generate command streams with --events.
+package site2skill
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/user"
+	"path/filepath"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/access"
+	"github.com/f4ah6o/site2skill-go/internal/chunker"
+	"github.com/f4ah6o/site2skill-go/internal/confluence"
+	"github.com/f4ah6o/site2skill-go/internal/converter"
+	"github.com/f4ah6o/site2skill-go/internal/embeddings"
+	"github.com/f4ah6o/site2skill-go/internal/events"
+	"github.com/f4ah6o/site2skill-go/internal/fetcher"
+	"github.com/f4ah6o/site2skill-go/internal/httpcache"
+	"github.com/f4ah6o/site2skill-go/internal/notion"
+	"github.com/f4ah6o/site2skill-go/internal/pathglob"
+	"github.com/f4ah6o/site2skill-go/internal/progress"
+	"github.com/f4ah6o/site2skill-go/internal/publish"
+	"github.com/f4ah6o/site2skill-go/internal/repo"
+	"github.com/f4ah6o/site2skill-go/internal/skillgen"
+	"github.com/f4ah6o/site2skill-go/internal/summarize"
+	"github.com/f4ah6o/site2skill-go/internal/translate"
+	"github.com/f4ah6o/site2skill-go/internal/warc"
+)
+
+const (
+	// FormatClaude specifies output format for Claude AI skill packages.
+	FormatClaude = "claude"
+	// FormatCodex specifies output format for OpenAI Codex skill packages.
+	FormatCodex = "codex"
+	// FormatBoth specifies output format for both Claude and Codex skill packages.
+	FormatBoth = "both"
+)
+
+// Event is a progress event: a page settled by the crawler or a completed
+// pipeline stage. See the event type constants.
+type Event = events.Event
+
+// Event types.
+const (
+	// PageFetched means a page was downloaded and saved.
+	PageFetched = events.PageFetched
+	// PageFailed means a network, HTTP, or I/O error prevented saving a page.
+	PageFailed = events.PageFailed
+	// PageSkipped means a URL was deliberately not fetched or saved; Event.Reason explains why.
+	PageSkipped = events.PageSkipped
+	// StageCompleted means a pipeline stage (fetch, convert, normalize, chunk,
+	// translate, summarize, generate, validate, package, publish, export,
+	// llms_txt) finished.
+	StageCompleted = events.StageCompleted
+)
+
+// Tokenizer counts the tokens of a text for Config.ChunkTokens.
+type Tokenizer = chunker.Tokenizer
+
+// Embedder computes the embeddings of the documents for Config.Embedder; see
+// NewEmbedder.
+type Embedder = embeddings.Provider
+
+// NewEmbedder returns the embeddings provider provider ("openai" or "hash"):
+// for "openai", the embeddings endpoint of the OpenAI-compatible API at baseURL
+// (the OpenAI API if empty) with model (text-embedding-3-small if empty),
+// authenticated with the API key of the environment (see
+// embeddings.APIKey); for "hash", a local provider needing neither model
+// nor network, which matches shared vocabulary rather than meaning.
+//
+// Returns an error if the provider is unknown.
+func NewEmbedder(provider, baseURL, model string) (Embedder, error) {
+	return embeddings.New(embeddings.Spec{Provider: provider, URL: baseURL, Model: model}, embeddings.APIKey())
+}
+
+// Summarizer writes the summaries of the documents for Config.Summarizer; see
+// NewSummarizer. Implement it to summarize with another LLM API.
+type Summarizer = summarize.Summarizer
+
+// SummaryPage is a document passed to a Summarizer: its title, source URL,
+// and Markdown body.
+type SummaryPage = summarize.Page
+
+// SummarizerSpec identifies the provider and model of a Summarizer, e.g., for
+// the log of the summarize stage.
+type SummarizerSpec = summarize.Spec
+
+// NewSummarizer returns the summarizer provider ("openai" or "lead"): for
+// "openai", the chat endpoint of the OpenAI-compatible API at baseURL (the
+// OpenAI API if empty) with model (gpt-4o-mini if empty), authenticated with
+// the API key of the environment (see summarize.APIKey); for "lead", a local
+// summarizer taking the first sentences of each document.
+//
+// Returns an error if the provider is unknown.
+func NewSummarizer(provider, baseURL, model string) (Summarizer, error) {
+	return summarize.New(summarize.Spec{Provider: provider, URL: baseURL, Model: model}, summarize.APIKey())
+}
+
+// Translator translates the documents for Config.Translator; see
+// NewTranslator. Implement it to translate with another API.
+type Translator = translate.Translator
+
+// TranslatorSpec identifies the provider and model of a Translator, e.g., for
+// the log of the translate stage.
+type TranslatorSpec = translate.Spec
+
+// NewTranslator returns the translator provider ("openai" or "deepl"): for
+// "openai", the chat endpoint of the OpenAI-compatible API at baseURL (the
+// OpenAI API if empty) with model (gpt-4o-mini if empty); for "deepl", the
+// DeepL API at baseURL (the DeepL API Free or Pro, depending on the key, if
+// empty), which takes no model. Both authenticate with the API key of the
+// environment (see translate.APIKey).
+//
+// Returns an error if the provider is unknown, or given a model for "deepl".
+func NewTranslator(provider, baseURL, model string) (Translator, error) {
+	return translate.New(translate.Spec{Provider: provider, URL: baseURL, Model: model}, translate.APIKey(provider))
+}
+
+// PageMeta is the metadata of a page passed to the transformers: its source
+// URL, fetch time, locale, HTTP response, and access level.
+type PageMeta = converter.PageMeta
+
+// Transformer rewrites the DOM of every HTML page for Config.Transformers,
+// e.g., to map internal links, before its main content is extracted.
+type Transformer = converter.Transformer
+
+// TransformerFunc adapts a function to a Transformer.
+type TransformerFunc = converter.TransformerFunc
+
+// MarkdownTransformer rewrites the Markdown of every page for
+// Config.MarkdownTransformers, e.g., to normalize terminology, before it is
+// written.
+type MarkdownTransformer = converter.MarkdownTransformer
+
+// MarkdownTransformerFunc adapts a function to a MarkdownTransformer.
+type MarkdownTransformerFunc = converter.MarkdownTransformerFunc
+
+// SourceAdapter reads the pages of a skill in-process for Config.Source,
+// instead of a crawl of Config.URL: e.g., from an API, a database, or a
+// content repository. Implement it to build skills from content that isn't
+// published as a website.
+type SourceAdapter = fetcher.Source
+
+// SourcePage is a page yielded by a SourceAdapter: its URL, its HTML or
+// Markdown, its depth in the source, and when it was last modified.
+type SourcePage = fetcher.SourcePage
+
+// Converter extracts the main content of the pages it handles, or converts
+// it into Markdown, in-process for Config.Extensions, instead of the built-in
+// rules. Its Fingerprint is part of the key of the conversion cache, so it
+// must change whenever the output does.
+type Converter = converter.Extension
+
+// Publisher publishes the skills of a build for Config.Publishers once they
+// are packaged, e.g., by uploading their .skill file to an internal registry.
+type Publisher interface {
+	// Publish publishes skill, whose Package (and Signature, if signed) is
+	// written. An error fails the build.
+	Publish(ctx context.Context, skill Skill) error
+}
+
+// PublisherFunc adapts a function to a Publisher.
+type PublisherFunc func(ctx context.Context, skill Skill) error
+
+// Publish calls f(ctx, skill).
+func (f PublisherFunc) Publish(ctx context.Context, skill Skill) error {
+	return f(ctx, skill)
+}
+
+// NewPublisher returns the built-in publisher of the target URL target (see
+// publish.Parse): "s3://BUCKET[/PREFIX]" and "gs://BUCKET[/PREFIX]" upload the
+// skill directory and package to an S3 or Google Cloud Storage bucket, and
+// "git+URL[#BRANCH[:DIR]]" commits the skill directory to a branch of a git
+// repository and pushes it, with a commit message summarizing the changes.
+//
+// Returns an error if the target is malformed or its scheme unsupported.
+func NewPublisher(target string) (Publisher, error) {
+	p, err := publish.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	return targetPublisher{p}, nil
+}
+
+// targetPublisher adapts a publish.Publisher to a Publisher.
+type targetPublisher struct {
+	p publish.Publisher
+}
+
+// Publish publishes skill with the target's publisher and logs what was published.
+func (t targetPublisher) Publish(ctx context.Context, skill Skill) error {
+	msg, err := t.p.Publish(ctx, publish.Skill{
+		Name:      filepath.Base(skill.Dir),
+		Format:    skill.Format,
+		Dir:       skill.Dir,
+		Package:   skill.Package,
+		Signature: skill.Signature,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", t.p, err)
+	}
+	log.Printf("%s: %s", t.p, msg)
+	return nil
+}
+
+// String returns the target URL of the publisher.
+func (t targetPublisher) String() string {
+	return t.p.String()
+}
+
+// Politeness holds per-host politeness settings for Config.Politeness, as
+// read from a politeness file by LoadPoliteness.
+type Politeness = fetcher.Politeness
+
+// HostPoliteness holds the politeness settings of the hosts matching a
+// pattern in Politeness.
+type HostPoliteness = fetcher.HostPoliteness
+
+// LoadPoliteness reads and validates the YAML politeness file at path.
+func LoadPoliteness(path string) (*Politeness, error) {
+	return fetcher.LoadPoliteness(path)
+}
+
+// URLRule rewrites a URL, already normalized by the built-in rules, in place
+// for Config.URLRules.
+type URLRule = fetcher.URLRule
+
+// Trailing slash policies for Config.TrailingSlash.
+const (
+	// TrailingSlashKeep leaves the trailing slash of paths as it is.
+	TrailingSlashKeep = fetcher.TrailingSlashKeep
+	// TrailingSlashStrip removes the trailing slash of paths.
+	TrailingSlashStrip = fetcher.TrailingSlashStrip
+	// TrailingSlashAdd appends a slash to the paths without extension.
+	TrailingSlashAdd = fetcher.TrailingSlashAdd
+)
+
+// Robots policies for Config.Robots.
+const (
+	// RobotsDefault follows robots.txt, allowing every page of a host whose
+	// robots.txt is missing or can't be fetched.
+	RobotsDefault = fetcher.RobotsDefault
+	// RobotsStrict follows robots.txt, disallowing every page of a host whose
+	// robots.txt can't be fetched.
+	RobotsStrict = fetcher.RobotsStrict
+	// RobotsOff doesn't consult robots.txt.
+	RobotsOff = fetcher.RobotsOff
+)
+
+// Devices for Config.Device.
+const (
+	// DeviceDesktop crawls as the site2skillgo crawler.
+	DeviceDesktop = fetcher.DeviceDesktop
+	// DeviceMobile crawls with the User-Agent of a mobile browser.
+	DeviceMobile = fetcher.DeviceMobile
+)
+
+// Changes lists the pages added, modified, and removed by an update, and the
+// sources that moved; see Config.Update.
+type Changes = skillgen.Changes
+
+// ProgressReporter renders the progress of the crawl for
+// Config.ProgressReporter; see NewProgressReporter.
+type ProgressReporter = progress.Reporter
+
+// ProgressSnapshot is the state of a crawl passed to a ProgressReporter: the
+// pages fetched, queued, failed, and skipped, the bytes downloaded, the
+// current rate, and the estimated time left.
+type ProgressSnapshot = progress.Snapshot
+
+// Output modes of NewProgressReporter.
+const (
+	// ProgressBar redraws a single status line with a bar, for terminals.
+	ProgressBar = progress.ModeBar
+	// ProgressPlain prints a status line every few seconds, for logs.
+	ProgressPlain = progress.ModePlain
+	// ProgressJSON prints a ProgressSnapshot as a JSON object per line.
+	ProgressJSON = progress.ModeJSON
+)
+
+// NewProgressReporter returns a reporter of mode (ProgressBar, ProgressPlain,
+// or ProgressJSON) writing to w.
+//
+// Returns an error if mode is unknown.
+func NewProgressReporter(mode string, w io.Writer) (ProgressReporter, error) {
+	return progress.NewReporter(mode, w)
+}
+
+// DefaultChunkTokens is the token budget per file used by the command-line tool.
+const DefaultChunkTokens = chunker.DefaultBudget
+
+// DefaultMaxRedirects is the Config.MaxRedirects used when it is 0.
+const DefaultMaxRedirects = fetcher.DefaultMaxRedirects
+
+// DefaultMaxConnsPerHost is the Config.MaxConnsPerHost used when it is 0.
+const DefaultMaxConnsPerHost = fetcher.DefaultMaxConnsPerHost
+
+// DefaultContentTypes are the Config.ContentTypes used when it is nil.
+var DefaultContentTypes = fetcher.DefaultContentTypes
+
+// PlatformNames returns the names of the built-in platform profiles for
+// Config.Platform, sorted.
+func PlatformNames() []string {
+	return converter.PlatformNames()
+}
+
+// DefaultLinkAttributes are the Config.LinkAttributes used when it is nil.
+var DefaultLinkAttributes = fetcher.DefaultLinkAttributes
+
+// DefaultStripParams are the query parameters always removed from the URLs
+// crawled; see Config.StripParams.
+var DefaultStripParams = fetcher.DefaultStripParams
+
+// DefaultTableCSVRows is the Config.TableCSVRows used by the command-line tool.
+const DefaultTableCSVRows = converter.DefaultTableCSVRows
+
+// Errors wrapped by the errors Build returns, to be checked with errors.Is.
+var (
+	// ErrRobotsBlocked means robots.txt disallows the start URL.
+	ErrRobotsBlocked = fetcher.ErrRobotsBlocked
+	// ErrRobotsUnavailable means the robots.txt of the start URL's host
+	// can't be fetched with Config.Robots RobotsStrict.
+	ErrRobotsUnavailable = fetcher.ErrRobotsUnavailable
+	// ErrOutOfScope means the start URL is excluded by Config.Exclude or by its
+	// file extension.
+	ErrOutOfScope = fetcher.ErrOutOfScope
+	// ErrConversionFailed means none of the fetched pages could be converted.
+	// The error also wraps the error of the first page, which may wrap
+	// ErrParseFailed or ErrTransformFailed.
+	ErrConversionFailed = converter.ErrConversionFailed
+	// ErrParseFailed means a page couldn't be parsed as HTML or PDF.
+	ErrParseFailed = converter.ErrParseFailed
+	// ErrTransformFailed means a Markdown transformer (see
+	// Config.MarkdownTransformers) returned an error.
+	ErrTransformFailed = converter.ErrTransformFailed
+	// ErrHostNotAllowed means a request went to a host outside the
+	// allow-list of Config.Sandbox.
+	ErrHostNotAllowed = fetcher.ErrHostNotAllowed
+	// ErrMaxRuntime means the build ran longer than Config.MaxRuntime.
+	ErrMaxRuntime = errors.New("build exceeded the maximum runtime")
+	// ErrOffline means a request of a Config.Offline build has no response
+	// in the HTTP cache.
+	ErrOffline = httpcache.ErrOffline
+	// ErrNotArchived means a request of a Config.FromWARC build has no
+	// response in the archive.
+	ErrNotArchived = warc.ErrNotArchived
+)
+
+// Errors of the pages of a crawl, wrapped by the Event.Err of the page
+// events, to be checked with errors.Is. The page events of blocked and out of
+// scope pages also wrap ErrRobotsBlocked, ErrRobotsUnavailable, or
+// ErrOutOfScope, and those of pages whose requests left the sandbox wrap
+// ErrHostNotAllowed.
+var (
+	// ErrUnsupportedContentType means a page was skipped for its content
+	// type (see Config.ContentTypes).
+	ErrUnsupportedContentType = fetcher.ErrUnsupportedContentType
+	// ErrPageTooLarge means a page was skipped for exceeding
+	// Config.MaxPageBytes.
+	ErrPageTooLarge = fetcher.ErrPageTooLarge
+	// ErrSizeBudget means the crawl stopped at Config.MaxTotalBytes.
+	ErrSizeBudget = fetcher.ErrSizeBudget
+	// ErrHTTPStatus means a page was answered with another status than 200.
+	// The errors wrapping it are *StatusError, which holds the status.
+	ErrHTTPStatus = fetcher.ErrHTTPStatus
+	// ErrRedirectLoop means the redirects of a page loop.
+	ErrRedirectLoop = fetcher.ErrRedirectLoop
+	// ErrTooManyRedirects means a page redirected too many times.
+	ErrTooManyRedirects = fetcher.ErrTooManyRedirects
+	// ErrDNS means the host of a page couldn't be resolved.
+	ErrDNS = fetcher.ErrDNS
+	// ErrTimeout means the request of a page timed out.
+	ErrTimeout = fetcher.ErrTimeout
+	// ErrNetwork means the request of a page got no response: refused or
+	// reset connections, TLS errors, ...
+	ErrNetwork = fetcher.ErrNetwork
+	// ErrReadFailed means the response body of a page couldn't be read.
+	ErrReadFailed = fetcher.ErrReadFailed
+	// ErrWriteFailed means a page couldn't be written to the temp directory.
+	ErrWriteFailed = fetcher.ErrWriteFailed
+)
+
+// PageError is the Event.Err of a page event: the URL and reason of the page,
+// wrapping the error the reason maps to.
+type PageError = fetcher.PageError
+
+// StatusError is the error of a page answered with another status than 200,
+// wrapped by its PageError; errors.As gives its StatusCode.
+type StatusError = fetcher.StatusError
+
+// WARCStartURLField is the field of the warcinfo record of the archives of
+// Config.WARC holding the URL of the crawl, which reconverting from the
+// archive starts from.
+const WARCStartURLField = "start-url"
+
+// Target is one skill format to generate and the directory it is written to.
+// The skill structure is created in Dir/<SkillName> and the .skill file in Dir.
+type Target struct {
+	// Format is FormatClaude or FormatCodex.
+	Format string
+	// Dir is the skills directory (e.g., ".claude/skills").
+	Dir string
+}
+
+// Config holds the settings of one build. URL, SkillName, Targets, and TempDir
+// are required; the zero value of every other field gives the default behavior
+// of the generate command without flags, except that locale priority mode is off
+// unless Locales is set.
+type Config struct {
+	// URL is the documentation site to fetch.
+	URL string
+	// Seeds are more start URLs, possibly on other hosts, crawled after URL
+	// into the same skill (e.g., "https://api.example.com/" and the wiki of a
+	// product whose documentation is split across sites). Each crawl is
+	// restricted to the host of its start URL. The frontmatter of every page
+	// records the site it comes from as source (its start URL's host and
+	// path, e.g., "api.example.com"), and the manifest groups the documents
+	// into sections by source. Not supported in feed, repository, Confluence,
+	// and Notion modes.
+	Seeds []string
+	// SkillName is the name of the generated skill.
+	SkillName string
+	// Targets lists the formats to generate; see DefaultTargets.
+	Targets []Target
+	// TempDir holds intermediate files (crawled HTML, Markdown, caches, crawl report).
+	TempDir string
+	// SkipFetch reuses the pages crawled into TempDir by an earlier build.
+	SkipFetch bool
+	// Offline rebuilds the skill from the raw HTML crawled into TempDir by an
+	// earlier build without any network access, to iterate on the conversion
+	// options (selectors, chunking, frontmatter, ...) against a finished
+	// crawl. It implies SkipFetch, and the other requests of the build, such
+	// as asset downloads, are answered from the HTTP cache without
+	// revalidation or fail with an error wrapping ErrOffline (see
+	// httpcache.Transport.Offline); with FromWARC, the crawl runs again and
+	// the archive answers every request instead. Embedders and summarizers
+	// calling a network API are refused; plugins and converter commands run
+	// as usual.
+	Offline bool
+	// WARC is the path of a WARC archive (WARC/1.1) the requests of the build
+	// are recorded into, as request and response records with their headers
+	// and bodies, for web-archiving tools and for later builds (see
+	// FromWARC); the archive is compressed per record if the path ends in
+	// ".gz". The crawl's credentials (Authorization and cookies) aren't
+	// recorded. Outside TempDir, the archive is kept by Clean, as the record
+	// of a crawl whose pages are removed. Empty records nothing.
+	WARC string
+	// FromWARC rebuilds the skill from the crawl recorded in this WARC
+	// archive (see WARC) instead of the site: the crawl runs again with the
+	// archive answering its requests, and fails those it has no response for
+	// with an error wrapping ErrNotArchived. It implies Offline, without
+	// needing the pages of an earlier build in TempDir. The archives of other
+	// tools, such as wget --warc-file, are replayed the same way.
+	FromWARC string
+	// FromMirror builds the skill from the offline mirror of the site in
+	// this directory, as written by wget -m or HTTrack (see package mirror),
+	// instead of the site: the crawl runs with the files of the mirror
+	// answering its requests, and the URLs missing from it fail as not
+	// found. It implies Offline, like FromWARC.
+	FromMirror string
+	// DryRun crawls the site without writing anything: no page, crawl report,
+	// or skill is written, and Build returns once the crawl is done with the
+	// pages it would save in BuildResult.Plan. The HTTP cache is still filled,
+	// unless NoCache is set.
+	DryRun bool
+	// Clean removes TempDir when the build succeeds.
+	Clean bool
+	// Update updates the existing skill of each target instead of regenerating
+	// it: the files of pages whose content hash is unchanged are kept, and the
+	// skill's changes.md lists the pages added, modified, and removed (see
+	// Skill.Changes). A target without a skill is generated.
+	Update bool
+	// Timestamp, if set, replaces the current time in the output: the
+	// fetched_at of the pages, the freshness of manifest.json, the date of
+	// changes.md, and the modification times of the documents and of the files
+	// in the packages. Builds of the same pages with the same Timestamp (e.g.,
+	// from SOURCE_DATE_EPOCH in CI) produce identical skills and packages. The
+	// crawl report and progress events keep real times.
+	Timestamp time.Time
+	// Only restricts the build to the sections of the site whose URL paths
+	// match one of these patterns (e.g., "/guides/**"; see package pathglob)
+	// and implies Update: only the pages of these sections are crawled from
+	// URL and replaced in the existing skill, whose other pages are kept.
+	// The manifest, SKILL.md, and search index still cover the whole skill.
+	Only []string
+	// Feed treats URL as an RSS or Atom feed: instead of crawling the site,
+	// the entries of the feed become the documents of the skill, named after
+	// their publication date (e.g., "2024-05-01-release-notes.md") with their
+	// author and publication time in their frontmatter. An entry is built
+	// from the content the feed carries, or from its article page if it
+	// carries none. Links are not followed.
+	Feed bool
+	// FeedArticles, with Feed, downloads the article page of every entry
+	// instead of building it from the feed, for feeds carrying summaries only.
+	FeedArticles bool
+	// Repo treats URL as a GitHub or GitLab repository (e.g.,
+	// "https://github.com/acme/widgets", or ".../tree/v2" for a branch or
+	// tag): instead of crawling its rendered pages, the repository and its
+	// wiki are shallow-cloned with git, and its README, the Markdown files
+	// under docs/, and the pages of its wiki become the documents of the
+	// skill, their Markdown kept as written. Headers are sent to the git
+	// server, e.g., to read a private repository.
+	Repo bool
+	// Confluence treats URL as a Confluence space (e.g.,
+	// "https://acme.atlassian.net/wiki/spaces/ENG", or
+	// "https://wiki.acme.com/display/ENG" on Confluence Data Center): instead
+	// of crawling its web interface, its pages are read through the REST API
+	// in their storage format, with code, admonition, and task list macros
+	// kept, and recorded under URLs following the page hierarchy of the space
+	// (e.g., ".../spaces/ENG/onboarding/local-setup"), which become the
+	// sections of the skill. The web URL of each page is kept as its
+	// final_url.
+	Confluence bool
+	// ConfluenceToken, with Confluence, authenticates the requests to the
+	// REST API: a Confluence Cloud API token given as "EMAIL:API_TOKEN", or a
+	// Confluence Data Center personal access token. Without it only public
+	// spaces can be read. It is never logged.
+	ConfluenceToken string
+	// Notion treats URL as a Notion page (e.g.,
+	// "https://www.notion.so/acme/Engineering-Wiki-0123456789abcdef0123456789abcdef"):
+	// instead of crawling notion.so, whose pages render nothing without
+	// JavaScript, the page and its child pages, recursively, are read through
+	// the Notion API, their blocks converted like HTML, and recorded under
+	// URLs following the page tree (e.g., ".../Engineering-Wiki-0123.../onboarding"),
+	// which become the sections of the skill. The notion.so URL of each page
+	// is kept as its final_url.
+	Notion bool
+	// NotionToken, required with Notion, is the token of the Notion
+	// integration the pages are shared with. It is never logged.
+	NotionToken string
+	// Source, if set, reads the pages of the skill instead of a crawl of URL
+	// (see SourceAdapter), which still names the site in the manifest and
+	// resolves relative URLs. The pages are saved and converted like crawled
+	// pages; the URL filters and path patterns of Include, Exclude, and Only
+	// apply. Not supported with Seeds, EachLocale, the feed, repository,
+	// Confluence, and Notion modes, WARC archives, and mirrors, nor in
+	// Sandbox mode, since the source makes its own requests.
+	Source SourceAdapter
+	// IncludePDF downloads the linked PDF documents under the crawl scope,
+	// which are skipped otherwise, and converts their text into Markdown
+	// documents, recovering headings, paragraphs, lists, and code blocks from
+	// the fonts and layout of their pages and dropping their running headers,
+	// footers, and page numbers. Scanned documents have no text and are
+	// skipped.
+	IncludePDF bool
+	// Sitemaps crawls the pages listed in the sitemaps named by the Sitemap
+	// directives of the robots.txt of the start URLs' hosts, once the pages
+	// linked from the start URLs are crawled, so that pages no link leads to
+	// are found. They are crawled at depth 1, within the host and the URL
+	// filters, and the crawl report records the sitemap of each. Ignored in
+	// the feed, repository, Confluence, and Notion modes.
+	Sitemaps bool
+
+	// Locales enables locale priority mode with these locale codes, most preferred first.
+	Locales []string
+	// LocaleParam is the query parameter carrying the locale (e.g., "hl" for ?hl=ja).
+	LocaleParam string
+	// LocaleFile is a YAML/JSON file with extra locale codes and aliases.
+	LocaleFile string
+	// LocaleCodes and LocaleAliases are extra locale definitions given inline.
+	LocaleCodes   []string
+	LocaleAliases map[string]string
+	// EachLocale builds one documentation tree per locale of Locales instead
+	// of picking the first available: the pipeline runs once per locale, in
+	// locale priority mode with that locale alone, into a directory of the
+	// skill named after it (e.g., SKILL/en/docs and SKILL/ja/docs, each with
+	// its own SKILL.md, manifest, and search index). A page missing in a
+	// locale falls back to its locale-neutral URL. The skill's SKILL.md
+	// introduces the locales, and its locales.json maps every page to its
+	// files in each locale (see skillgen.LocaleIndex). The intermediate files
+	// and crawl report of each locale are kept in TempDir/LOCALE (the crawl
+	// report in ReportPath with the locale inserted before its extension, if
+	// set). Not supported with Exports and LLMsTxt, nor in feed, repository,
+	// Confluence, and Notion modes.
+	EachLocale bool
+	// Versions enables version selection with these versions, most preferred
+	// first (e.g., "latest", "v2"): of a documentation site publishing each
+	// version under its own path segment (/v2/, /3.11/, /latest/), only the
+	// first listed version that exists is crawled, or else the version of the
+	// first URL crawled. Each page records its version in its frontmatter.
+	Versions []string
+
+	// Include restricts the crawl to URLs containing one of these strings.
+	Include []string
+	// Exclude skips URLs containing any of these strings.
+	Exclude []string
+	// IgnoreCanonical saves every page under its own URL. By default, a page
+	// declaring an in-scope canonical URL with <link rel="canonical"> is saved
+	// under that URL, and further pages with the same canonical URL (such as
+	// query-parameter variants) are skipped.
+	IgnoreCanonical bool
+	// SkipExternalCanonical skips pages whose canonical URL is outside the crawl
+	// scope: another host, excluded by Include or Exclude, or not a page.
+	SkipExternalCanonical bool
+	// MaxRedirects is the number of redirects followed per request;
+	// 0 uses DefaultMaxRedirects. Pages redirected more times, or whose
+	// redirects loop, fail. A redirected page is saved under its final URL,
+	// which the crawl doesn't fetch again; pages redirecting to another host
+	// are skipped, unless the start URL does, which moves the crawl to that
+	// host.
+	MaxRedirects int
+	// MaxPageBytes is the size in bytes of the largest page or PDF document
+	// downloaded; larger ones are skipped with the reason page_too_large,
+	// without reading past the limit. 0 means no limit.
+	MaxPageBytes int64
+	// MaxTotalBytes is the number of bytes the crawl downloads at most: it
+	// stops at the page that would exceed it, recorded with the reason
+	// size_budget, and the skill is built from the pages saved until then,
+	// with the crawl report marked size_budget_exceeded. 0 means no limit.
+	MaxTotalBytes int64
+	// ContentTypes are the media types of the responses saved, such as
+	// "text/html", or all those of a type, such as "text/*"; nil saves
+	// DefaultContentTypes. Other responses are skipped as
+	// non_html_content_type. HTML pages are crawled for links; the other
+	// documents, such as Markdown and plain text, are taken as Markdown and
+	// aren't. Every page is converted to UTF-8 from the encoding its response
+	// declares or, failing that, the legacy encoding of its language that it
+	// is valid in (Shift_JIS and EUC-JP for Japanese, EUC-KR for Korean, GBK
+	// and Big5 for Chinese), else windows-1252.
+	ContentTypes []string
+	// LinkAttributes are the attributes of any element whose values are
+	// crawled as links, such as the data-href of the elements of client-side
+	// routers; nil uses DefaultLinkAttributes. Besides them and the href of
+	// <a> elements, the crawl follows the href of <area> elements, the src of
+	// <iframe> and <frame> elements, and the URL of a meta refresh too late
+	// to be followed as a redirect.
+	LinkAttributes []string
+	// CompressCrawl stores the crawled HTML pages gzip-compressed in the temp
+	// directory (page.html.gz), which the conversion decompresses as it reads
+	// them, for large sites whose crawl would take gigabytes. Responses are
+	// always requested gzip or brotli compressed.
+	CompressCrawl bool
+	// Proxy is the URL of the proxy every request goes through: an HTTP or
+	// HTTPS proxy ("http://proxy.example.com:8080"), or a SOCKS5 proxy
+	// ("socks5://127.0.0.1:1080"), with optional credentials, which are never
+	// logged. Empty uses the proxy of the environment (HTTP_PROXY,
+	// HTTPS_PROXY, and NO_PROXY), if any. Through a proxy, host names are
+	// resolved by the proxy, so it can't be combined with Resolve and
+	// HostHeader.
+	Proxy string
+	// CACert is the path of a PEM file of certificate authorities trusted
+	// besides those of the system, such as the CA of a staging server or of
+	// a TLS-intercepting corporate proxy.
+	CACert string
+	// Insecure skips the verification of TLS certificates, for staging
+	// servers with self-signed certificates. Prefer CACert.
+	Insecure bool
+	// Politeness gives the hosts of the crawl their own politeness delay,
+	// number of requests in flight, time-of-day window, and User-Agent
+	// suffix, for multi-site crawls whose hosts need different treatment;
+	// nil treats every host alike. See LoadPoliteness.
+	Politeness *Politeness
+	// MaxConnsPerHost is the number of connections opened to a host at most,
+	// and kept idle between requests for reuse by the crawl, robots.txt, and
+	// asset requests; 0 uses DefaultMaxConnsPerHost. The builds of
+	// EachLocale share their connections and host lookups.
+	MaxConnsPerHost int
+	// NoHTTP2 disables HTTP/2, which is otherwise negotiated with the servers
+	// supporting it, for servers whose HTTP/2 support is broken.
+	NoHTTP2 bool
+	// NoAdaptiveThrottle keeps requesting the hosts answering 429 Too Many
+	// Requests or 503 Service Unavailable, or much more slowly than usual, at
+	// the politeness delay, without retrying those responses. By default, the
+	// delay of such a host is doubled, up to a minute, honoring Retry-After,
+	// and halved again after every few healthy responses; the slowdowns are
+	// recorded in the crawl report (see fetcher.Fetcher.SetAdaptiveThrottle).
+	NoAdaptiveThrottle bool
+	// StripParams are the query parameters removed from the URLs crawled
+	// besides DefaultStripParams (the utm_* parameters and the click
+	// identifiers of advertising tools), as names or path.Match patterns such
+	// as "ref" or "session_*", matched case-insensitively. The URLs are also
+	// normalized otherwise before they are checked against the pages already
+	// crawled: lowercase host without default port, no fragment, collapsed
+	// "." and ".." segments, and query parameters sorted by name.
+	StripParams []string
+	// TrailingSlash is the trailing slash policy of the URLs crawled:
+	// TrailingSlashKeep (the default when empty), TrailingSlashStrip, or
+	// TrailingSlashAdd, for sites serving "/guide" and "/guide/" alike.
+	TrailingSlash string
+	// Robots is how robots.txt is followed: RobotsDefault (when empty), which
+	// allows the pages of hosts whose robots.txt can't be fetched;
+	// RobotsStrict, which disallows them, recording them as blocked with the
+	// reason "robots_unavailable" and failing the build with an error
+	// wrapping ErrRobotsUnavailable for a start URL; or RobotsOff, which
+	// doesn't consult robots.txt, for sites the user owns.
+	Robots string
+	// URLRules are custom URL normalization rules, applied in order after
+	// the built-in ones, e.g., to map a mirror host to the main one.
+	URLRules []URLRule
+	// ConsentCookies are cookies sent with every request, as "name=value",
+	// such as the cookie a consent banner sets once accepted
+	// ("CookieConsent=true"), so that sites gating their pages behind a
+	// consent wall serve them. Unlike the credentials of Headers, they may be
+	// logged.
+	ConsentCookies []string
+	// NoMetaRefresh saves the pages redirecting with a <meta
+	// http-equiv="refresh"> as they are. By default, such pages are skipped
+	// as meta_refresh and the URL they redirect to is crawled instead.
+	NoMetaRefresh bool
+	// KeepInterstitials saves the pages that look like interstitials rather
+	// than content: consent walls, and pages that render nothing without
+	// JavaScript or only ask to enable it. They are flagged in the crawl
+	// report and logged either way; by default, they are skipped as
+	// consent_wall or javascript_required and their links aren't followed.
+	KeepInterstitials bool
+	// KeepNoIndex saves the pages whose robots directives (<meta
+	// name="robots">, X-Robots-Tag) say noindex. By default, they are
+	// skipped as noindex, their links still followed; the links of nofollow
+	// pages are never followed. The directives are ignored with RobotsOff.
+	KeepNoIndex bool
+	// Headers are sent with every page request (e.g., credentials). Their values are never logged.
+	Headers http.Header
+	// Device is the kind of client the crawler presents itself as, by its
+	// User-Agent: DeviceDesktop (the default) or DeviceMobile, for sites
+	// serving mobile clients a different, sometimes cleaner, page structure.
+	// Mobile responses are cached apart from desktop ones.
+	Device string
+	// UserAgent is the template of the User-Agent identifying the crawler to
+	// the sites it crawls, robots.txt and asset requests included, whose
+	// placeholders {version}, {contact}, and {run_id} stand for the version
+	// of the tool, Contact, and RunID (see fetcher.FormatUserAgent). Empty
+	// uses fetcher.DefaultUserAgentTemplate. robots.txt rules are matched
+	// against its first word up to a slash.
+	UserAgent string
+	// Contact is an http(s) URL or an email address where site operators
+	// can reach whoever runs the crawl, written in place of the {contact}
+	// placeholder of UserAgent; empty uses the site of the project.
+	Contact string
+	// RunID identifies the build in its logs, its crawl report, and the
+	// {run_id} placeholder of UserAgent, so that the requests of a run can
+	// be found in server logs; empty generates one (see fetcher.NewRunID).
+	RunID string
+	// Resolve lists host overrides in the syntax of curl's --resolve,
+	// "host:port:address": connections to host:port go to address instead, while
+	// URLs, Host headers, and robots.txt stay those of host. Use it to crawl a
+	// staging server under the production hostname.
+	Resolve []string
+	// HostHeader crawls the server of URL as this host: requests carry it in
+	// their Host header and the output uses it in URLs, as if URL had this host
+	// (without URL's port unless HostHeader gives one). Use it for staging
+	// servers reachable only by IP or through an internal load balancer.
+	HostHeader string
+	// Sandbox restricts the crawl to an allow-list of hosts, for crawls run
+	// unattended, e.g., from CI: the hosts of URL, Seeds, HostHeader, and
+	// Resolve, the Notion API in Notion mode, and AllowHosts. Requests to
+	// other hosts, redirects and assets included, fail without connecting
+	// (see fetcher.SandboxTransport), and the addresses of the allowed hosts
+	// are resolved once, before the crawl, and pinned for the rest of it,
+	// except through a Proxy, which resolves them itself: the allow-list
+	// still applies, to the host names of the requests. It isn't supported in repository mode, whose clones don't go through the
+	// crawler, and doesn't cover plugins, converter commands, or the
+	// embeddings and summaries providers.
+	Sandbox bool
+	// AllowHosts are the hosts the sandbox allows besides those of the
+	// crawl, as host names, IP addresses, or wildcards ("*.example.com"
+	// allows every subdomain of example.com), such as the CDN serving the
+	// assets of the site. Ignored without Sandbox.
+	AllowHosts []string
+	// MaxRuntime bounds the duration of the build: once it has passed, the
+	// crawl stops as if interrupted and Build returns an error wrapping
+	// ErrMaxRuntime. Zero means no limit.
+	MaxRuntime time.Duration
+	// RewriteURLs lists URL rewrite rules, "FROM=TO", applied to the URLs of
+	// the converted pages (frontmatter and links) so that a skill built from a
+	// staging site presents production URLs. FROM is a host
+	// ("staging.docs.internal=docs.example.com") or a URL prefix
+	// ("https://staging.example.com/v2/=https://docs.example.com/"); see
+	// package urlrewrite.
+	RewriteURLs []string
+	// AccessRules lists access rules, "PATTERN=LEVEL", tagging the converted
+	// pages whose URL paths match the path glob PATTERN with the access level
+	// LEVEL ("public", "internal", or "confidential") in their access
+	// frontmatter field; the first matching rule wins, and pages matching none
+	// are public. Searches can then be restricted to the levels a caller is
+	// allowed (see search.SearchOptions.Access and package access).
+	AccessRules []string
+	// CacheDir is the HTTP cache directory; empty means TempDir/http-cache.
+	CacheDir string
+	// NoCache disables the on-disk HTTP and conversion caches.
+	NoCache bool
+	// RecheckAfter is the freshness window of the pages: the pages fetched
+	// (or found unchanged by a conditional request) less than this long ago
+	// are taken from the HTTP cache without contacting the site, so that a
+	// recurring update re-checks only the pages older than it. Zero re-checks
+	// every page; it requires the HTTP cache.
+	RecheckAfter time.Duration
+	// ReportPath is where the JSON crawl report is written; empty means TempDir/crawl-report.json.
+	ReportPath string
+
+	// ContentSelector selects the main content of each page instead of Readability.
+	ContentSelector string
+	// StripSelectors match elements removed from every page before conversion.
+	StripSelectors []string
+	// Platform is the extraction profile of a documentation platform applied
+	// to the pages: "auto" (the default when empty) detects the platform of
+	// every page from its markup, one of PlatformNames applies its profile to
+	// every page, and "none" applies none. A profile selects the main content
+	// unless ContentSelector is set, strips the furniture of the platform
+	// (breadcrumbs, "edit this page" links, and previous and next page
+	// links) besides StripSelectors, and, in locale priority mode, adds the
+	// locale codes the platform uses in its URLs (e.g., Docusaurus's
+	// "zh-Hans") to the locale aliases.
+	Platform string
+	// TableFallback renders tables that can't be GFM tables: "html" (default) or "list".
+	TableFallback string
+	// Admonitions renders note/warning boxes: "github" (default), "blockquote", or "none".
+	Admonitions string
+	// TableCSVRows also saves tables with more than this many body rows as CSV
+	// files in the skill's assets/ folder, linked above each table; 0 disables it.
+	TableCSVRows int
+	// PageTypes keeps only pages classified as one of these types: "docs",
+	// "marketing", or "legal". Empty keeps every page.
+	PageTypes []string
+	// LanguageFilter detects the language of the text of every page and
+	// handles the pages in another language than their locale (else than
+	// the language they declare, else than those of Locales), such as the
+	// untranslated pages of a partially translated site: "flag" records the
+	// detected language in the detected_language frontmatter field and warns
+	// about them, "exclude" also leaves them out, and "off" (or "") doesn't
+	// detect languages (see converter.SetLanguageFilter).
+	LanguageFilter string
+	// DedupeSnippets replaces code samples of 10 or more lines repeated across
+	// pages, after their first occurrence, with links to a shared file in the
+	// skill's snippets/ folder.
+	DedupeSnippets bool
+	// KeepDuplicates keeps every page. By default, pages whose content is
+	// identical or nearly identical to another page's (the same page served at
+	// several URLs) are removed, and their URLs listed in the aliases
+	// frontmatter field of the page kept.
+	KeepDuplicates bool
+	// StitchPages converts each paginated article or listing into one
+	// document. The crawl follows the rel="next" links and ?page=N pagination
+	// of pages whatever the depth limit, recording the pages after the first
+	// with the page they continue; with StitchPages, their content is appended
+	// to the document of the first page, in order, instead of becoming
+	// documents of their own.
+	StitchPages bool
+	// ChunkTokens splits pages longer than this many tokens into several files
+	// along heading boundaries; 0 keeps every page whole.
+	ChunkTokens int
+	// Tokenizer counts tokens for ChunkTokens and the skill statistics; nil uses
+	// an estimate of the cl100k_base encoding.
+	Tokenizer Tokenizer
+	// DownloadAssets saves referenced images and media into the skill's assets/ folder.
+	DownloadAssets bool
+	// AirGapped neutralizes external references and fails the build if any remain.
+	AirGapped bool
+	// AbsoluteLinks keeps the links between pages as absolute URLs. By
+	// default, the links to the crawled pages point at the relative paths of
+	// their files, so that the skill can be navigated offline, and those to the
+	// other pages of the site are marked as not included (see package links).
+	AbsoluteLinks bool
+	// Embedder, if set, embeds the sections of the documents of each skill for
+	// semantic search (see search.BuildEmbeddings). The vectors of the sections
+	// unchanged since the skill was last built with the same embedder are reused.
+	Embedder Embedder
+	// Summarizer, if set, writes a summary of one to three sentences into the
+	// frontmatter of each document before the skills are generated (see
+	// summarize.Dir). The manifest, SKILL.md, and llms.txt list the documents
+	// with their summary, and searches rank matches in it above those in the
+	// body. The summaries of the pages unchanged since the skill was last
+	// built are reused.
+	Summarizer Summarizer
+	// Translator, if set, translates the documents in another language than
+	// TranslateTo into it before they are summarized (see translate.Dir),
+	// such as the pages a site only publishes in English within its Japanese
+	// docs. The language of a document is its detected language (see
+	// LanguageFilter), or else that of its locale. Translated documents record
+	// their original language in the translated_from frontmatter field. The
+	// translations of the pages unchanged since the skill was last built are
+	// reused.
+	Translator Translator
+	// TranslateTo is the language tag the documents are translated into with
+	// Translator (e.g., "ja" or "pt-BR"); empty stands for the first of
+	// Locales, or with EachLocale, the locale of each tree.
+	TranslateTo string
+	// SignKey, if set, signs each .skill file.
+	SignKey ed25519.PrivateKey
+	// Exports lists exports, "FORMAT=DIR", of the documents of the skill (of
+	// the first target) rendered in another output format for a documentation
+	// site: "mdx" writes DIR/docs/*.mdx, escaped for the MDX compiler, and
+	// "asciidoc" DIR/docs/*.adoc; "markdown" copies them as they are. Links
+	// between the documents use the extension of the format, and the assets/
+	// and snippets/ folders are copied next to docs/ so the other relative
+	// links resolve. Each export replaces those folders of DIR.
+	Exports []string
+	// LLMsTxt, if set, is the directory where llms.txt and llms-full.txt of
+	// the site are written, following the llms.txt convention: an index of
+	// the pages with their titles and descriptions, and the content of every
+	// page in one file (see skillgen.WriteLLMsTxt). Pages tagged internal or
+	// confidential by AccessRules are left out.
+	LLMsTxt string
+	// Plugins lists the commands of plugins (executable paths, optionally
+	// followed by arguments separated by spaces) extending the build: they
+	// extract the main content of the pages they handle, convert it to
+	// Markdown, or add output formats for Exports, speaking the protocol of
+	// package plugin over their standard input and output. They run for the
+	// duration of the build; the first one extracting or converting a page wins,
+	// and the built-in rules take over for the pages they decline.
+	Plugins []string
+	// Converters lists the converter commands, "MATCH[,MATCH...]=COMMAND" (see
+	// plugin.ParseCommand): commands such as pandoc converting the main
+	// content of the pages matching a URL prefix, a path pattern, or a
+	// Content-Type from HTML on their standard input to Markdown on their
+	// standard output, instead of the built-in conversion. The first command
+	// matching a page converts it, before the plugins.
+	Converters []string
+	// Transformers rewrite the DOM of every HTML page before its main content
+	// is extracted, and MarkdownTransformers the Markdown of every page before
+	// it is written, e.g., to map internal links or normalize terminology. They
+	// run in order, and an error fails the conversion of the page. The
+	// conversion cache is bypassed while Transformers is set, as their output
+	// can't be fingerprinted.
+	Transformers         []Transformer
+	MarkdownTransformers []MarkdownTransformer
+	// Extensions extract the main content of the pages they handle or
+	// convert it into Markdown, in-process (see Converter), before the
+	// converter commands and plugins: the first one extracting or converting
+	// a page wins, and the built-in rules take over for the pages they all
+	// decline, and with a warning, when one fails.
+	Extensions []Converter
+	// Publishers publish every packaged skill, in order, once the skills of
+	// the build are packaged (see Publisher). The first error fails the
+	// build; the skills are left packaged. With Update, the skills the update
+	// left unchanged (see Changes.Empty) aren't published again.
+	Publishers []Publisher
+
+	// Progress, if set, is called with every progress event. It may be called
+	// from several goroutines during the fetch stage and must not block for long.
+	Progress func(Event)
+	// ProgressReporter, if set, renders the counters of the crawl while it
+	// runs (see NewProgressReporter); nil reports nothing.
+	ProgressReporter ProgressReporter
+}
+
+// Skill describes one generated skill.
+type Skill struct {
+	// Format is the skill format (FormatClaude or FormatCodex).
+	Format string
+	// Dir is the generated skill directory.
+	Dir string
+	// Tokens is the estimated number of tokens of the skill's text files,
+	// counted with Config.Tokenizer. Per-file counts are in its stats.json.
+	Tokens int
+	// Bytes is the total size of the skill's files.
+	Bytes int64
+	// Changes lists the changes made to the skill when Config.Update was set; nil otherwise.
+	Changes *Changes
+	// Valid reports whether the skill passed validation; failures are logged
+	// but do not stop the build.
+	Valid bool
+	// Package is the path of the .skill file.
+	Package string
+	// Signature is the path of the package's signature, if SignKey was set.
+	Signature string
+}
+
+// BuildResult describes the output of a successful build.
+type BuildResult struct {
+	// Skills lists the generated skills, one per target.
+	Skills []Skill
+	// Pages counts the crawled URLs per outcome ("saved", "skipped", "blocked",
+	// "failed"); nil when SkipFetch was set.
+	Pages map[string]int
+	// ReportPath is the path of the JSON crawl report; that of the first
+	// locale with Config.EachLocale.
+	ReportPath string
+	// RunID is the identifier of the build: Config.RunID, or the one
+	// generated without it.
+	RunID string
+	// Exports lists the directories of Config.Exports written.
+	Exports []string
+	// Plan lists the pages the crawl would save, in the order crawled, when
+	// Config.DryRun was set; nil otherwise.
+	Plan []PlannedPage
+}
+
+// PlannedPage is a page a dry-run crawl would save (see Config.DryRun).
+type PlannedPage struct {
+	// URL is the URL of the page.
+	URL string `json:"url"`
+	// Locale is the locale chosen for the page in locale priority mode; empty
+	// when the page has no locale variant.
+	Locale string `json:"locale,omitempty"`
+	// File is the slash-separated path of the page's file in the skill (e.g.,
+	// "docs/install.md"), before chunking splits it or deduplication merges
+	// it into another page.
+	File string `json:"file"`
+}
+
+// Build runs the pipeline described by cfg and returns the generated skills.
+//
+// Cancelling ctx, or reaching its deadline, aborts the request in flight and
+// stops the build after the current page of the fetch, convert, and normalize
+// stages or before the next stage. The returned error then wraps ctx.Err().
+//
+// Warnings are collapsed and counted process-wide (see the --warning-limit
+// flag), so builds that should get separate warning summaries must not run
+// concurrently in one process.
+func Build(ctx context.Context, cfg Config) (*BuildResult, error) {
+	if cfg.URL == "" || cfg.SkillName == "" {
+		return nil, fmt.Errorf("URL and skill name are required")
+	}
+	if cfg.TempDir == "" {
+		return nil, fmt.Errorf("temp directory is required")
+	}
+	if cfg.DryRun && cfg.SkipFetch {
+		return nil, fmt.Errorf("dry run and skip fetch are mutually exclusive")
+	}
+	if len(cfg.Targets) == 0 {
+		return nil, fmt.Errorf("at least one target is required")
+	}
+	if cfg.Repo {
+		if cfg.Feed {
+			return nil, fmt.Errorf("feed and repository modes are mutually exclusive")
+		}
+		if _, err := repo.Parse(cfg.URL); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Confluence {
+		if cfg.Feed || cfg.Repo {
+			return nil, fmt.Errorf("Confluence mode is mutually exclusive with feed and repository modes")
+		}
+		if _, err := confluence.Parse(cfg.URL); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Notion {
+		if cfg.Feed || cfg.Repo || cfg.Confluence {
+			return nil, fmt.Errorf("Notion mode is mutually exclusive with feed, repository, and Confluence modes")
+		}
+		if _, err := notion.Parse(cfg.URL); err != nil {
+			return nil, err
+		}
+		if cfg.NotionToken == "" {
+			return nil, fmt.Errorf("Notion mode requires the token of an integration the pages are shared with")
+		}
+	}
+	if cfg.Source != nil {
+		if cfg.Feed || cfg.Repo || cfg.Confluence || cfg.Notion {
+			return nil, fmt.Errorf("a source is mutually exclusive with feed, repository, Confluence, and Notion modes")
+		}
+		if cfg.Sandbox {
+			return nil, fmt.Errorf("sandbox mode is not supported with a source: it reads its pages outside the crawler")
+		}
+		if cfg.WARC != "" || cfg.FromWARC != "" || cfg.FromMirror != "" {
+			return nil, fmt.Errorf("a source can't be recorded in or built from a WARC archive or mirror")
+		}
+	}
+	if len(cfg.Seeds) > 0 && (cfg.Feed || cfg.Repo || cfg.Confluence || cfg.Notion || cfg.Source != nil) {
+		return nil, fmt.Errorf("several start URLs are not supported with a source or in feed, repository, Confluence, and Notion modes")
+	}
+	if cfg.EachLocale {
+		if len(cfg.Locales) == 0 {
+			return nil, fmt.Errorf("one tree per locale requires locale priority mode")
+		}
+		if cfg.Feed || cfg.Repo || cfg.Confluence || cfg.Notion || cfg.Source != nil {
+			return nil, fmt.Errorf("one tree per locale is not supported with a source or in feed, repository, Confluence, and Notion modes")
+		}
+		if len(cfg.Exports) > 0 || cfg.LLMsTxt != "" {
+			return nil, fmt.Errorf("one tree per locale can't be exported or written as llms.txt")
+		}
+	}
+	if cfg.Translator != nil {
+		if cfg.TranslateTo == "" && len(cfg.Locales) == 0 {
+			return nil, fmt.Errorf("translating documents requires the language to translate them into, or locale priority mode")
+		}
+		if cfg.TranslateTo != "" {
+			if err := translate.ValidateLanguage(cfg.TranslateTo); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, t := range cfg.Targets {
+		if t.Format != FormatClaude && t.Format != FormatCodex {
+			return nil, fmt.Errorf("invalid target format %q: must be %q or %q", t.Format, FormatClaude, FormatCodex)
+		}
+	}
+	for _, pattern := range cfg.Only {
+		if err := pathglob.Validate(pattern); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.RunID == "" {
+		cfg.RunID = fetcher.NewRunID()
+	}
+	if _, err := cfg.userAgent(); err != nil {
+		return nil, err
+	}
+	if cfg.MaxRedirects < 0 {
+		return nil, fmt.Errorf("invalid redirect limit %d: must be positive", cfg.MaxRedirects)
+	}
+	if cfg.MaxConnsPerHost < 0 {
+		return nil, fmt.Errorf("invalid connection limit %d: must be positive", cfg.MaxConnsPerHost)
+	}
+	if cfg.Proxy != "" {
+		if _, err := fetcher.ParseProxy(cfg.Proxy); err != nil {
+			return nil, err
+		}
+		if len(cfg.Resolve) > 0 || cfg.HostHeader != "" {
+			return nil, fmt.Errorf("a proxy can't be combined with resolve rules or a host header: the proxy resolves host names")
+		}
+	}
+	if cfg.Politeness != nil {
+		if err := cfg.Politeness.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid politeness settings: %w", err)
+		}
+	}
+	for _, t := range cfg.ContentTypes {
+		if _, err := fetcher.ParseContentType(t); err != nil {
+			return nil, err
+		}
+	}
+	for _, a := range cfg.LinkAttributes {
+		if _, err := fetcher.ParseLinkAttribute(a); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.urlNormalizer().Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := fetcher.ParseRobotsPolicy(cfg.Robots); err != nil {
+		return nil, err
+	}
+	// The platform also sets locale aliases of the crawl
+	if _, err := converter.ParsePlatform(cfg.Platform); err != nil {
+		return nil, err
+	}
+	for _, c := range cfg.ConsentCookies {
+		if _, err := fetcher.ParseCookie(c); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Sandbox {
+		if cfg.Repo {
+			return nil, fmt.Errorf("sandbox mode is not supported in repository mode: repositories are cloned outside the crawler")
+		}
+		if _, err := fetcher.ParseAllowList(cfg.AllowHosts); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.WARC != "" || cfg.FromWARC != "" {
+		if cfg.Repo {
+			return nil, fmt.Errorf("WARC archives are not supported in repository mode: repositories are cloned outside the crawler")
+		}
+		if cfg.EachLocale {
+			return nil, fmt.Errorf("one tree per locale can't be recorded in or rebuilt from a WARC archive")
+		}
+	}
+	if cfg.FromMirror != "" {
+		if cfg.FromWARC != "" {
+			return nil, fmt.Errorf("building from a WARC archive and from a mirror are mutually exclusive")
+		}
+		if cfg.Repo || cfg.EachLocale {
+			return nil, fmt.Errorf("mirrors can't be built from in repository mode or one tree per locale")
+		}
+	}
+	if cfg.FromWARC != "" || cfg.FromMirror != "" {
+		if cfg.SkipFetch {
+			return nil, fmt.Errorf("building from a WARC archive or mirror and skip fetch are mutually exclusive")
+		}
+		cfg.Offline = true
+	}
+	if cfg.Offline {
+		if cfg.DryRun || cfg.Sandbox {
+			return nil, fmt.Errorf("offline builds can't be dry runs or sandboxed: they make no requests")
+		}
+		if cfg.WARC != "" {
+			return nil, fmt.Errorf("offline builds make no requests to record in a WARC archive")
+		}
+		if cfg.Embedder != nil && cfg.Embedder.Spec().Provider != embeddings.ProviderHash {
+			return nil, fmt.Errorf("offline builds can't embed documents with %s: use the %s provider", cfg.Embedder.Spec(), embeddings.ProviderHash)
+		}
+		if cfg.Summarizer != nil && cfg.Summarizer.Spec().Provider != summarize.ProviderLead {
+			return nil, fmt.Errorf("offline builds can't summarize documents with %s: use the %s provider", cfg.Summarizer.Spec(), summarize.ProviderLead)
+		}
+		if cfg.Translator != nil {
+			return nil, fmt.Errorf("offline builds can't translate documents with %s", cfg.Translator.Spec())
+		}
+		if cfg.FromWARC == "" && cfg.FromMirror == "" {
+			if _, err := os.Stat(filepath.Join(cfg.TempDir, "download")); err != nil {
+				return nil, fmt.Errorf("no crawl to rebuild from in %s: %w", cfg.TempDir, err)
+			}
+			cfg.SkipFetch = true
+		}
+	}
+	if cfg.MaxRuntime < 0 {
+		return nil, fmt.Errorf("invalid maximum runtime %s: must not be negative", cfg.MaxRuntime)
+	}
+	if cfg.RecheckAfter < 0 {
+		return nil, fmt.Errorf("invalid recheck window %s: must not be negative", cfg.RecheckAfter)
+	}
+	if cfg.RecheckAfter > 0 && cfg.NoCache {
+		return nil, fmt.Errorf("a recheck window requires the HTTP cache: NoCache is set")
+	}
+	if cfg.MaxPageBytes < 0 || cfg.MaxTotalBytes < 0 {
+		return nil, fmt.Errorf("invalid size limits %d and %d: must not be negative", cfg.MaxPageBytes, cfg.MaxTotalBytes)
+	}
+	if len(cfg.Plugins) == 0 {
+		// The formats of plugins are known once they are started
+		for _, spec := range cfg.Exports {
+			if _, _, err := converter.ParseExport(spec); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	accessRules, err := access.ParseRules(cfg.AccessRules)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.MaxRuntime > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, cfg.MaxRuntime, ErrMaxRuntime)
+		defer cancel()
+	}
+
+	b := &builder{ctx: ctx, cfg: cfg, result: &BuildResult{ReportPath: cfg.crawlReportPath(), RunID: cfg.RunID}, accessRules: accessRules}
+	run := b.run
+	if cfg.EachLocale {
+		b.result.ReportPath = ""
+		run = b.buildLocales
+	}
+	if err := run(); err != nil {
+		if errors.Is(context.Cause(ctx), ErrMaxRuntime) {
+			return nil, fmt.Errorf("%w of %s: %w", ErrMaxRuntime, cfg.MaxRuntime, err)
+		}
+		return nil, err
+	}
+	return b.result, nil
+}
+
+// DefaultTargets returns the targets the generate command uses for a format
+// ("claude", "codex", or "both").
+//
+// For Claude format:
+//   - Global: ~/.claude/skills
+//   - Local: .claude/skills (current directory)
+//
+// For Codex format:
+//   - Global: $CODEX_HOME/skills or ~/.codex/skills
+//   - Local: .codex/skills (current directory)
+func DefaultTargets(format string, global bool) ([]Target, error) {
+	var formats []string
+	switch format {
+	case FormatClaude, FormatCodex:
+		formats = []string{format}
+	case FormatBoth:
+		formats = []string{FormatClaude, FormatCodex}
+	default:
+		return nil, fmt.Errorf("invalid format: %s. Must be 'claude', 'codex', or 'both'", format)
+	}
+
+	targets := make([]Target, 0, len(formats))
+	for _, f := range formats {
+		dir := filepath.Join("."+f, "skills")
+		if global {
+			home, err := skillsHome(f)
+			if err != nil {
+				return nil, err
+			}
+			dir = filepath.Join(home, "skills")
+		}
+		targets = append(targets, Target{Format: f, Dir: dir})
+	}
+	return targets, nil
+}
+
+// skillsHome returns the user's home directory for a format: ~/.claude for
+// Claude, and $CODEX_HOME or ~/.codex for Codex.
+func skillsHome(format string) (string, error) {
+	if format == FormatCodex {
+		return CodexHome()
+	}
+	usr, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current user: %w", err)
+	}
+	return filepath.Join(usr.HomeDir, ".claude"), nil
+}
+
+// CodexHome returns the Codex home directory.
+// It checks the CODEX_HOME environment variable first, then falls back to ~/.codex
+func CodexHome() (string, error) {
+	if codexHome := os.Getenv("CODEX_HOME"); codexHome != "" {
+		return codexHome, nil
+	}
+
+	usr, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("failed to get current user: %w", err)
+	}
+
+	return filepath.Join(usr.HomeDir, ".codex"), nil
+}
+
+// httpCacheDir returns the HTTP cache directory for this configuration.
+func (c Config) httpCacheDir() string {
+	dir := c.CacheDir
+	if dir == "" {
+		dir = filepath.Join(c.TempDir, "http-cache")
+	}
+	// Responses vary with the User-Agent, which the cache doesn't key on
+	if c.Device == DeviceMobile {
+		dir = filepath.Join(dir, DeviceMobile)
+	}
+	return dir
+}
+
+// userAgent returns the User-Agent of the page requests of this
+// configuration: that of UserAgent, Contact, and RunID as Device.
+func (c Config) userAgent() (string, error) {
+	ua, err := fetcher.FormatUserAgent(c.UserAgent, c.Contact, c.RunID)
+	if err != nil {
+		return "", err
+	}
+	return fetcher.DeviceUserAgent(c.Device, ua)
+}
+
+// crawlReportPath returns the path of the JSON crawl report for this configuration.
+func (c Config) crawlReportPath() string {
+	if c.ReportPath != "" {
+		return c.ReportPath
+	}
+	return filepath.Join(c.TempDir, "crawl-report.json")
+}
+
+// pageEvent converts a crawl report record into a page event. Blocked URLs are
+// reported as skipped, with the "robots_txt" reason.
+func pageEvent(rec fetcher.PageRecord) Event {
+	ev := Event{URL: rec.URL, StatusCode: rec.StatusCode}
+	switch rec.Outcome {
+	case fetcher.OutcomeSaved, fetcher.OutcomePlanned:
+		ev.Type = PageFetched
+		ev.OutputFile = rec.OutputFile
+	case fetcher.OutcomeFailed:
+		ev.Type = PageFailed
+		ev.Error = rec.Error
+		ev.Reason = rec.Reason
+		ev.Err = rec.Err()
+	default:
+		ev.Type = PageSkipped
+		ev.Reason = rec.Reason
+		ev.Err = rec.Err()
+	}
+	return ev
+}
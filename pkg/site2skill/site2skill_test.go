@@ -0,0 +1,1135 @@
+package site2skill
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/assets"
+	"github.com/f4ah6o/site2skill-go/internal/fetcher"
+)
+
+func newDocsServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Home</title></head><body><main><h1>Home</h1>
+<p>Welcome to the example documentation. It explains how the example works.</p>
+<a href="/docs/guide">Guide</a><a href="/docs/missing">Missing</a></main></body></html>`))
+		case "/docs/guide":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Guide</title></head><body><main><h1>Guide</h1>
+<p>Install the example and run it. This page walks through every step.</p></main></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func hashEmbedder(t *testing.T) Embedder {
+	t.Helper()
+	e, err := NewEmbedder("hash", "", "")
+	if err != nil {
+		t.Fatalf("NewEmbedder() error = %v", err)
+	}
+	return e
+}
+
+// countingSummarizer takes the first sentences of the documents, counting
+// the documents it summarizes.
+type countingSummarizer struct {
+	mu    sync.Mutex
+	count int
+}
+
+func (s *countingSummarizer) Summarize(ctx context.Context, page SummaryPage) (string, error) {
+	s.mu.Lock()
+	s.count++
+	s.mu.Unlock()
+	lead, err := NewSummarizer("lead", "", "")
+	if err != nil {
+		return "", err
+	}
+	return lead.Summarize(ctx, page)
+}
+
+func (s *countingSummarizer) Spec() SummarizerSpec {
+	return SummarizerSpec{Provider: "counting"}
+}
+
+// markingTranslator "translates" documents by tagging every piece with the
+// language it translates into.
+type markingTranslator struct{}
+
+func (markingTranslator) Translate(_ context.Context, text, from, to string) (string, error) {
+	return text + " [" + to + "]", nil
+}
+
+func (markingTranslator) Spec() TranslatorSpec {
+	return TranslatorSpec{Provider: "marking"}
+}
+
+func TestBuild(t *testing.T) {
+	server := newDocsServer(t)
+	dir := t.TempDir()
+
+	var mu sync.Mutex
+	var types []string
+	var stages []string
+	var failure error
+	cfg := Config{
+		URL:       server.URL + "/docs/",
+		SkillName: "example",
+		Targets: []Target{
+			{Format: FormatClaude, Dir: filepath.Join(dir, "claude")},
+			{Format: FormatCodex, Dir: filepath.Join(dir, "codex")},
+		},
+		TempDir:         filepath.Join(dir, "build"),
+		ContentSelector: "main",
+		ChunkTokens:     DefaultChunkTokens,
+		AccessRules:     []string{"/docs/guide=internal"},
+		Exports:         []string{"asciidoc=" + filepath.Join(dir, "site")},
+		LLMsTxt:         filepath.Join(dir, "site"),
+		Embedder:        hashEmbedder(t),
+		Summarizer:      &countingSummarizer{},
+		Progress: func(ev Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			types = append(types, ev.Type)
+			switch ev.Type {
+			case StageCompleted:
+				stages = append(stages, ev.Stage)
+			case PageFailed:
+				failure = ev.Err
+			}
+		},
+	}
+
+	res, err := Build(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Build() returned error: %v", err)
+	}
+
+	if len(res.Skills) != 2 {
+		t.Fatalf("got %d skills, want 2", len(res.Skills))
+	}
+	for _, skill := range res.Skills {
+		if _, err := os.Stat(skill.Package); err != nil {
+			t.Errorf("%s package missing: %v", skill.Format, err)
+		}
+		if guide, err := os.ReadFile(filepath.Join(skill.Dir, "docs", "guide.md")); err != nil {
+			t.Errorf("%s skill is missing docs/guide.md: %v", skill.Format, err)
+		} else if !strings.Contains(string(guide), "\naccess: internal\n") {
+			t.Errorf("%s docs/guide.md isn't tagged internal:\n%s", skill.Format, guide)
+		} else if !strings.Contains(string(guide), "\nsummary: \"Install the example and run it. This page walks through every step.\"\n") {
+			t.Errorf("%s docs/guide.md has no summary:\n%s", skill.Format, guide)
+		}
+		if _, err := os.Stat(filepath.Join(skill.Dir, "docs", ".embeddings", "embeddings.json")); err != nil {
+			t.Errorf("%s skill has no embeddings: %v", skill.Format, err)
+		}
+		if _, err := os.Stat(filepath.Join(skill.Dir, "stats.json")); err != nil || skill.Tokens == 0 {
+			t.Errorf("%s skill statistics missing (%d tokens): %v", skill.Format, skill.Tokens, err)
+		}
+	}
+	if res.Pages["saved"] != 2 || res.Pages["failed"] != 1 {
+		t.Errorf("Pages = %v, want 2 saved and 1 failed", res.Pages)
+	}
+
+	searcher, err := NewSearcher(res.Skills[0].Dir)
+	if err != nil {
+		t.Fatalf("NewSearcher() returned error: %v", err)
+	}
+	var found []string
+	for r, err := range searcher.Search(context.Background(), SearchOptions{Query: "install"}) {
+		if err != nil {
+			t.Fatalf("Search() returned error: %v", err)
+		}
+		found = append(found, filepath.Base(r.File))
+	}
+	if strings.Join(found, ",") != "guide.md" {
+		t.Errorf("Search() found %v, want guide.md", found)
+	}
+	if _, err := os.Stat(res.ReportPath); err != nil {
+		t.Errorf("crawl report missing: %v", err)
+	}
+
+	if guide, err := os.ReadFile(filepath.Join(dir, "site", "docs", "guide.adoc")); err != nil {
+		t.Errorf("AsciiDoc export is missing docs/guide.adoc: %v", err)
+	} else if !strings.HasPrefix(string(guide), "= Guide\n") {
+		t.Errorf("docs/guide.adoc has no document title:\n%s", guide)
+	}
+
+	if index, err := os.ReadFile(filepath.Join(dir, "site", "llms.txt")); err != nil {
+		t.Errorf("llms.txt missing: %v", err)
+	} else if !strings.Contains(string(index), "- [Home](") || strings.Contains(string(index), "Guide") {
+		t.Errorf("llms.txt doesn't list the home page only, the guide being internal:\n%s", index)
+	}
+
+	wantStages := "fetch,convert,normalize,chunk,summarize,generate,validate,package,export,llms_txt"
+	if got := strings.Join(stages, ","); got != wantStages {
+		t.Errorf("stages = %s, want %s", got, wantStages)
+	}
+	counts := make(map[string]int)
+	for _, typ := range types {
+		counts[typ]++
+	}
+	if counts[PageFetched] != 2 || counts[PageFailed] != 1 {
+		t.Errorf("page events = %v, want 2 fetched and 1 failed", counts)
+	}
+	var statusErr *StatusError
+	var pageErr *PageError
+	if !errors.Is(failure, ErrHTTPStatus) || !errors.As(failure, &statusErr) || statusErr.StatusCode != http.StatusNotFound ||
+		!errors.As(failure, &pageErr) || pageErr.URL != server.URL+"/docs/missing" {
+		t.Errorf("Err of the failed page = %v, want a 404 of /docs/missing", failure)
+	}
+}
+
+func TestBuildDryRun(t *testing.T) {
+	server := newDocsServer(t)
+	dir := t.TempDir()
+	cfg := Config{
+		URL:       server.URL + "/docs/",
+		SkillName: "example",
+		Targets:   []Target{{Format: FormatClaude, Dir: filepath.Join(dir, "claude")}},
+		TempDir:   filepath.Join(dir, "build"),
+		NoCache:   true,
+		DryRun:    true,
+	}
+
+	res, err := Build(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Build() returned error: %v", err)
+	}
+	want := []PlannedPage{
+		{URL: server.URL + "/docs/", File: "docs/docs.md"},
+		{URL: server.URL + "/docs/guide", File: "docs/guide.md"},
+	}
+	if !reflect.DeepEqual(res.Plan, want) {
+		t.Errorf("Plan = %+v, want %+v", res.Plan, want)
+	}
+	if res.Pages["planned"] != 2 || res.Pages["failed"] != 1 || len(res.Skills) != 0 {
+		t.Errorf("Pages = %v with %d skills, want 2 planned, 1 failed, and no skill", res.Pages, len(res.Skills))
+	}
+	for _, path := range []string{cfg.TempDir, cfg.Targets[0].Dir} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("dry run wrote %s (stat error %v)", path, err)
+		}
+	}
+}
+
+func TestBuildUpdate(t *testing.T) {
+	server := newDocsServer(t)
+	dir := t.TempDir()
+	cfg := Config{
+		URL:             server.URL + "/docs/",
+		SkillName:       "example",
+		Targets:         []Target{{Format: FormatClaude, Dir: filepath.Join(dir, "skills")}},
+		TempDir:         filepath.Join(dir, "build"),
+		ContentSelector: "main",
+		Summarizer:      &countingSummarizer{},
+	}
+	if _, err := Build(context.Background(), cfg); err != nil {
+		t.Fatalf("Build() returned error: %v", err)
+	}
+
+	// The summaries of the unchanged pages are reused, and the unchanged
+	// skill isn't published again
+	summarizer := &countingSummarizer{}
+	published := 0
+	cfg.Update = true
+	cfg.Summarizer = summarizer
+	cfg.RecheckAfter = time.Hour
+	cfg.Publishers = []Publisher{PublisherFunc(func(ctx context.Context, skill Skill) error {
+		published++
+		return nil
+	})}
+	res, err := Build(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Build() with Update returned error: %v", err)
+	}
+	if summarizer.count != 0 {
+		t.Errorf("update summarized %d unchanged documents, want 0", summarizer.count)
+	}
+	if published != 0 {
+		t.Errorf("unchanged skill published %d times, want 0", published)
+	}
+	cfg.Publishers = nil
+	changes := res.Skills[0].Changes
+	if changes == nil {
+		t.Fatal("Changes is nil after an update")
+	}
+	if got := changes.Summary(); got != "0 added, 0 modified, 0 removed, 2 unchanged" {
+		t.Errorf("Changes = %s, want every page unchanged", got)
+	}
+	if _, err := os.Stat(filepath.Join(res.Skills[0].Dir, "changes.md")); err != nil {
+		t.Errorf("changes.md missing: %v", err)
+	}
+
+	// A partial build replaces the guide only and keeps the home page
+	cfg.Update = false
+	cfg.Only = []string{"/docs/guide/**"}
+	if res, err = Build(context.Background(), cfg); err != nil {
+		t.Fatalf("Build() with Only returned error: %v", err)
+	}
+	if got := res.Skills[0].Changes.Summary(); got != "0 added, 0 modified, 0 removed, 1 unchanged, 1 outside the updated sections" {
+		t.Errorf("Changes = %s, want the guide unchanged and the home page left out", got)
+	}
+	if _, err := os.Stat(filepath.Join(res.Skills[0].Dir, "docs", "docs.md")); err != nil {
+		t.Errorf("page outside the section removed: %v", err)
+	}
+}
+
+func TestBuildEachLocale(t *testing.T) {
+	pages := map[string]string{
+		"/en/docs/":      `<h1>Home</h1><p>Welcome to the example documentation.</p><a href="/en/docs/guide">Guide</a>`,
+		"/ja/docs/":      `<h1>ホーム</h1><p>サンプルのドキュメントへようこそ。</p><a href="/ja/docs/guide">ガイド</a>`,
+		"/en/docs/guide": `<h1>Guide</h1><p>Install the example and run it.</p>`,
+		// The guide isn't translated: the Japanese tree falls back to this one
+		"/docs/guide": `<h1>Guide</h1><p>Install the example and run it.</p>`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		lang := "en"
+		if strings.HasPrefix(r.URL.Path, "/ja/") {
+			lang = "ja"
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html lang="` + lang + `"><body><main>` + body + "</main></body></html>"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	cfg := Config{
+		URL:             server.URL + "/en/docs/",
+		SkillName:       "example",
+		Targets:         []Target{{Format: FormatClaude, Dir: filepath.Join(dir, "skills")}},
+		TempDir:         filepath.Join(dir, "build"),
+		ContentSelector: "main",
+		Locales:         []string{"en", "ja"},
+		EachLocale:      true,
+		Translator:      markingTranslator{},
+	}
+	res, err := Build(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("Build() returned error: %v", err)
+	}
+	skill := res.Skills[0]
+	if skill.Dir != filepath.Join(dir, "skills", "example") || !skill.Valid || skill.Tokens == 0 {
+		t.Errorf("skill = %+v", skill)
+	}
+	if _, err := os.Stat(skill.Package); err != nil {
+		t.Errorf("package missing: %v", err)
+	}
+	if res.Pages["saved"] != 4 {
+		t.Errorf("Pages = %v, want 4 saved", res.Pages)
+	}
+	for _, file := range []string{"en/docs/docs.md", "en/docs/guide.md", "ja/docs/docs.md", "ja/docs/guide.md", "en/SKILL.md", "ja/manifest.json"} {
+		if _, err := os.Stat(filepath.Join(skill.Dir, file)); err != nil {
+			t.Errorf("%s missing: %v", file, err)
+		}
+	}
+	if home, err := os.ReadFile(filepath.Join(skill.Dir, "ja", "docs", "docs.md")); err != nil || !strings.Contains(string(home), "ホーム") || strings.Contains(string(home), "translated_from") {
+		t.Errorf("ja/docs/docs.md isn't the Japanese page (error %v):\n%s", err, home)
+	}
+	// The English fallback of the guide is translated into Japanese, and the English guide left as it is
+	if guide, err := os.ReadFile(filepath.Join(skill.Dir, "ja", "docs", "guide.md")); err != nil ||
+		!strings.Contains(string(guide), "locale: \"ja\"\ntranslated_from: \"en\"\n") || !strings.Contains(string(guide), "run it. [ja]") {
+		t.Errorf("ja/docs/guide.md isn't translated (error %v):\n%s", err, guide)
+	}
+	if guide, err := os.ReadFile(filepath.Join(skill.Dir, "en", "docs", "guide.md")); err != nil || strings.Contains(string(guide), "[ja]") {
+		t.Errorf("en/docs/guide.md is translated (error %v):\n%s", err, guide)
+	}
+
+	index, err := os.ReadFile(filepath.Join(skill.Dir, "locales.json"))
+	if err != nil {
+		t.Fatalf("locales.json missing: %v", err)
+	}
+	if !strings.Contains(string(index), `"path": "docs/guide.md",
+      "title": "Guide",
+      "files": {
+        "en": [
+          "en/docs/guide.md"
+        ],
+        "ja": [
+          "ja/docs/guide.md"
+        ]`) {
+		t.Errorf("locales.json doesn't map the guide across locales:\n%s", index)
+	}
+	if md, err := os.ReadFile(filepath.Join(skill.Dir, "SKILL.md")); err != nil || !strings.Contains(string(md), "- `ja/`: 2 pages") {
+		t.Errorf("SKILL.md doesn't list the locales (error %v):\n%s", err, md)
+	}
+}
+
+func TestBuildInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr string
+	}{
+		{
+			name:    "missing URL",
+			cfg:     Config{SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}},
+			wantErr: "URL and skill name are required",
+		},
+		{
+			name:    "missing temp dir",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", Targets: []Target{{Format: FormatClaude, Dir: "out"}}},
+			wantErr: "temp directory is required",
+		},
+		{
+			name:    "no targets",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: "build"},
+			wantErr: "at least one target",
+		},
+		{
+			name:    "both is not a target format",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatBoth, Dir: "out"}}},
+			wantErr: "invalid target format",
+		},
+		{
+			name:    "unknown robots policy",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Robots: "ignore"},
+			wantErr: "invalid robots policy",
+		},
+		{
+			name:    "negative connection limit",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, MaxConnsPerHost: -1},
+			wantErr: "invalid connection limit",
+		},
+		{
+			name:    "one tree per locale without locales",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, EachLocale: true},
+			wantErr: "requires locale priority mode",
+		},
+		{
+			name:    "translation without a language",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Translator: markingTranslator{}},
+			wantErr: "requires the language to translate them into",
+		},
+		{
+			name:    "source in feed mode",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Source: pagesSource{}, Feed: true},
+			wantErr: "source is mutually exclusive",
+		},
+		{
+			name:    "relative section pattern",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Only: []string{"guides/**"}},
+			wantErr: "invalid path pattern",
+		},
+		{
+			name:    "unknown access level",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, AccessRules: []string{"/internal/**=secret"}},
+			wantErr: "unknown access level",
+		},
+		{
+			name:    "unknown export format",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Exports: []string{"rst=site"}},
+			wantErr: "unknown output format",
+		},
+		{
+			name:    "missing plugin",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: t.TempDir(), Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Plugins: []string{"/nonexistent/site2skill-plugin"}},
+			wantErr: "failed to start plugin",
+		},
+		{
+			name:    "invalid converter command",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: t.TempDir(), Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Converters: []string{"reference=pandoc"}},
+			wantErr: "invalid converter command",
+		},
+		{
+			name:    "dry run without fetch",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, DryRun: true, SkipFetch: true},
+			wantErr: "mutually exclusive",
+		},
+		{
+			name:    "unknown device",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Device: "tablet"},
+			wantErr: "unknown device",
+		},
+		{
+			name:    "repository mode on a site",
+			cfg:     Config{URL: "https://example.com/docs/", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Repo: true},
+			wantErr: "not a GitHub or GitLab repository URL",
+		},
+		{
+			name:    "repository and feed modes",
+			cfg:     Config{URL: "https://github.com/acme/widgets", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Repo: true, Feed: true},
+			wantErr: "mutually exclusive",
+		},
+		{
+			name:    "Confluence mode on a site",
+			cfg:     Config{URL: "https://example.com/docs/", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Confluence: true},
+			wantErr: "not a Confluence space URL",
+		},
+		{
+			name:    "Confluence and feed modes",
+			cfg:     Config{URL: "https://acme.atlassian.net/wiki/spaces/ENG", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Confluence: true, Feed: true},
+			wantErr: "mutually exclusive",
+		},
+		{
+			name:    "Notion mode on a site",
+			cfg:     Config{URL: "https://example.com/docs/", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Notion: true, NotionToken: "secret"},
+			wantErr: "not a Notion page URL",
+		},
+		{
+			name:    "Notion mode without a token",
+			cfg:     Config{URL: "https://www.notion.so/acme/Wiki-0123456789abcdef0123456789abcdef", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Notion: true},
+			wantErr: "requires the token",
+		},
+		{
+			name:    "several start URLs in feed mode",
+			cfg:     Config{URL: "https://example.com/feed.xml", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Feed: true, Seeds: []string{"https://api.example.com/"}},
+			wantErr: "several start URLs",
+		},
+		{
+			name:    "sandbox in repository mode",
+			cfg:     Config{URL: "https://github.com/acme/widgets", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Repo: true, Sandbox: true},
+			wantErr: "not supported in repository mode",
+		},
+		{
+			name:    "invalid allowed host",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Sandbox: true, AllowHosts: []string{"cdn.*.com"}},
+			wantErr: "invalid allowed host",
+		},
+		{
+			name:    "negative maximum runtime",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, MaxRuntime: -time.Minute},
+			wantErr: "invalid maximum runtime",
+		},
+		{
+			name:    "recheck window without cache",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, RecheckAfter: 24 * time.Hour, NoCache: true},
+			wantErr: "requires the HTTP cache",
+		},
+		{
+			name:    "offline without a crawl",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: filepath.Join(t.TempDir(), "build"), Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Offline: true},
+			wantErr: "no crawl to rebuild from",
+		},
+		{
+			name:    "offline sandbox",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Offline: true, Sandbox: true},
+			wantErr: "offline builds can't be dry runs or sandboxed",
+		},
+		{
+			name:    "recording an offline build",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, FromWARC: "crawl.warc", WARC: "again.warc"},
+			wantErr: "no requests to record",
+		},
+		{
+			name:    "WARC in repository mode",
+			cfg:     Config{URL: "https://github.com/acme/widgets", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, Repo: true, WARC: "crawl.warc"},
+			wantErr: "not supported in repository mode",
+		},
+		{
+			name:    "WARC archive and mirror",
+			cfg:     Config{URL: "https://example.com", SkillName: "x", TempDir: "build", Targets: []Target{{Format: FormatClaude, Dir: "out"}}, FromWARC: "crawl.warc", FromMirror: "mirror"},
+			wantErr: "mutually exclusive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Build(context.Background(), tt.cfg)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Build() error = %v, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestBuildCancelled(t *testing.T) {
+	server := newDocsServer(t)
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cfg := Config{
+		URL:       server.URL + "/docs/",
+		SkillName: "example",
+		Targets:   []Target{{Format: FormatClaude, Dir: filepath.Join(dir, "out")}},
+		TempDir:   filepath.Join(dir, "build"),
+		Progress: func(ev Event) {
+			if ev.Type == PageFetched {
+				cancel()
+			}
+		},
+	}
+
+	_, err := Build(ctx, cfg)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Build() error = %v, want context.Canceled", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "out", "example")); !os.IsNotExist(err) {
+		t.Errorf("skill was generated after cancellation")
+	}
+
+	// The report of the interrupted crawl records the pages saved until then
+	report, err := fetcher.LoadCrawlReport(cfg.crawlReportPath())
+	if err != nil {
+		t.Fatalf("crawl report of the interrupted crawl: %v", err)
+	}
+	if !report.Interrupted || report.Summary[fetcher.OutcomeSaved] != 1 {
+		t.Errorf("report interrupted = %v with summary %v, want interrupted after 1 saved page", report.Interrupted, report.Summary)
+	}
+	filepath.WalkDir(cfg.TempDir, func(path string, d os.DirEntry, err error) error {
+		if err == nil && strings.Contains(d.Name(), ".tmp") {
+			t.Errorf("temporary file left behind: %s", path)
+		}
+		return nil
+	})
+}
+
+func TestBuildSandbox(t *testing.T) {
+	var mu sync.Mutex
+	var outside []string
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		outside = append(outside, r.URL.Path)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG\r\n\x1a\ncdn"))
+	}))
+	defer cdn.Close()
+	cdnURL := strings.Replace(cdn.URL, "127.0.0.1", "localhost", 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Home</title></head><body><main><h1>Home</h1>
+<p>Welcome to the example documentation. It explains how the example works.</p>
+<img src="/docs/local.png" alt="Local"><img src="` + cdnURL + `/remote.png" alt="Remote"></main></body></html>`))
+		case "/docs/local.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG\r\n\x1a\nlocal"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		allowHosts  []string
+		wantOutside int
+	}{
+		{name: "crawl hosts only", allowHosts: nil, wantOutside: 0},
+		{name: "allowed CDN", allowHosts: []string{"localhost"}, wantOutside: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mu.Lock()
+			outside = nil
+			mu.Unlock()
+			dir := t.TempDir()
+			_, err := Build(context.Background(), Config{
+				URL:            server.URL + "/docs/",
+				SkillName:      "example",
+				Targets:        []Target{{Format: FormatClaude, Dir: filepath.Join(dir, "out")}},
+				TempDir:        filepath.Join(dir, "build"),
+				NoCache:        true,
+				DownloadAssets: true,
+				Sandbox:        true,
+				AllowHosts:     tt.allowHosts,
+			})
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(outside) != tt.wantOutside {
+				t.Errorf("the CDN received requests %v, want %d", outside, tt.wantOutside)
+			}
+			// The asset map lists the downloaded assets next to them
+			assetsDir := filepath.Join(dir, "out", "example", "assets")
+			files, _ := os.ReadDir(assetsDir)
+			if len(files) != 2+tt.wantOutside {
+				t.Errorf("%d asset files, want %d assets and the asset map", len(files), 1+tt.wantOutside)
+			}
+			if m, err := assets.ReadMap(assetsDir); err != nil || len(m) != 1+tt.wantOutside || m[server.URL+"/docs/local.png"] == "" {
+				t.Errorf("asset map = %v, %v; want %d assets including local.png", m, err, 1+tt.wantOutside)
+			}
+		})
+	}
+}
+
+func TestBuildOffline(t *testing.T) {
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests = append(requests, r.URL.Path)
+		mu.Unlock()
+		switch r.URL.Path {
+		case "/docs/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Home</title></head><body><main><h1>Home</h1>
+<p>Welcome to the example documentation. It explains how the example works.</p>
+<div class="extra"><p>Extra notes on the example documentation, removed on the second build.</p></div>
+<img src="/docs/local.png" alt="Local"></main></body></html>`))
+		case "/docs/local.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte("\x89PNG\r\n\x1a\nlocal"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	cfg := Config{
+		URL:            server.URL + "/docs/",
+		SkillName:      "example",
+		Targets:        []Target{{Format: FormatClaude, Dir: filepath.Join(dir, "out")}},
+		TempDir:        filepath.Join(dir, "build"),
+		DownloadAssets: true,
+	}
+	if _, err := Build(context.Background(), cfg); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	mu.Lock()
+	requests = nil
+	mu.Unlock()
+	cfg.Offline = true
+	cfg.StripSelectors = []string{".extra"}
+	if _, err := Build(context.Background(), cfg); err != nil {
+		t.Fatalf("offline Build() error = %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 0 {
+		t.Errorf("offline build sent requests %v, want none", requests)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "out", "example", "docs", "*.md"))
+	var docs string
+	for _, file := range files {
+		data, _ := os.ReadFile(file)
+		docs += string(data)
+	}
+	if strings.Contains(docs, "Extra notes") || !strings.Contains(docs, "Welcome to the example") {
+		t.Errorf("offline build didn't apply the new conversion options:\n%s", docs)
+	}
+	if assets, _ := os.ReadDir(filepath.Join(dir, "out", "example", "assets")); len(assets) != 2 {
+		t.Errorf("%d assets replayed from the HTTP cache, want 1", len(assets)-1)
+	}
+}
+
+func TestBuildWARC(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Home</title></head><body><main><h1>Home</h1>
+<p>Welcome to the example documentation. It explains how the example works.</p>
+<p><a href="/docs/guide">Guide</a></p><img src="/docs/local.png" alt="Local"></main></body></html>`))
+		case "/docs/guide":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Guide</title></head><body><main><h1>Guide</h1>
+<p>Install the example and run it. This page walks through every step.</p>
+<div class="extra"><p>Extra notes on the guide, removed when rebuilding.</p></div></main></body></html>`))
+		case "/docs/local.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte("\x89PNG\r\n\x1a\nlocal"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "crawl.warc.gz")
+	_, err := Build(context.Background(), Config{
+		URL:            server.URL + "/docs/",
+		SkillName:      "example",
+		Targets:        []Target{{Format: FormatClaude, Dir: filepath.Join(dir, "out")}},
+		TempDir:        filepath.Join(dir, "build"),
+		NoCache:        true,
+		DownloadAssets: true,
+		Clean:          true,
+		WARC:           archive,
+	})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	server.Close()
+
+	res, err := Build(context.Background(), Config{
+		URL:            server.URL + "/docs/",
+		SkillName:      "example",
+		Targets:        []Target{{Format: FormatClaude, Dir: filepath.Join(dir, "replayed")}},
+		TempDir:        filepath.Join(dir, "build"),
+		DownloadAssets: true,
+		StripSelectors: []string{".extra"},
+		FromWARC:       archive,
+	})
+	if err != nil {
+		t.Fatalf("Build() from the WARC archive error = %v", err)
+	}
+	if res.Pages["saved"] != 2 {
+		t.Errorf("Pages = %v, want 2 saved", res.Pages)
+	}
+	guide, err := os.ReadFile(filepath.Join(dir, "replayed", "example", "docs", "guide.md"))
+	if err != nil || !strings.Contains(string(guide), "Install the example") || strings.Contains(string(guide), "Extra notes") {
+		t.Errorf("replayed docs/guide.md = %q, %v; want the guide without the extra notes", guide, err)
+	}
+	if assets, _ := os.ReadDir(filepath.Join(dir, "replayed", "example", "assets")); len(assets) != 2 {
+		t.Errorf("%d assets replayed from the WARC archive, want 1", len(assets)-1)
+	}
+}
+
+func TestBuildFromMirror(t *testing.T) {
+	// A mirror as written by wget -m -k -E: links rewritten to the files
+	dir := t.TempDir()
+	files := map[string]string{
+		"docs.example.com/docs/index.html": `<html><head><title>Home</title></head><body><main><h1>Home</h1>
+<p>Welcome to the example documentation. It explains how the example works.</p>
+<p><a href="guide.html">Guide</a> <a href="api/index.html">API</a> <a href="missing.html">Missing</a></p></main></body></html>`,
+		"docs.example.com/docs/guide.html": `<html><head><title>Guide</title></head><body><main><h1>Guide</h1>
+<p>Install the example and run it. This page walks through every step.</p></main></body></html>`,
+		"docs.example.com/docs/api/index.html": `<html><head><title>API</title></head><body><main><h1>API</h1>
+<p>The API reference of the example, with every function it exports.</p></main></body></html>`,
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, "mirror", filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0755)
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := Build(context.Background(), Config{
+		URL:        "https://docs.example.com/docs/",
+		SkillName:  "example",
+		Targets:    []Target{{Format: FormatClaude, Dir: filepath.Join(dir, "out")}},
+		TempDir:    filepath.Join(dir, "build"),
+		FromMirror: filepath.Join(dir, "mirror"),
+	})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if res.Pages["saved"] != 3 {
+		t.Errorf("Pages = %v, want 3 saved", res.Pages)
+	}
+	for _, name := range []string{"docs.md", "guide.md", "api.md"} {
+		if _, err := os.Stat(filepath.Join(dir, "out", "example", "docs", name)); err != nil {
+			t.Errorf("docs/%s not written: %v", name, err)
+		}
+	}
+}
+
+// pagesSource is a SourceAdapter of fixed pages.
+type pagesSource []SourcePage
+
+func (s pagesSource) Pages(ctx context.Context) iter.Seq2[SourcePage, error] {
+	return func(yield func(SourcePage, error) bool) {
+		for _, p := range s {
+			if !yield(p, nil) {
+				return
+			}
+		}
+	}
+}
+
+// apiConverter converts the pages under /api/ into a fixed Markdown.
+type apiConverter struct{}
+
+func (apiConverter) Name() string        { return "api" }
+func (apiConverter) Fingerprint() string { return "api/1" }
+func (apiConverter) Handles(pageURL, _ string) bool {
+	return strings.Contains(pageURL, "/api/")
+}
+func (apiConverter) Extract(_, _ string) (string, string, error) { return "", "", nil }
+func (apiConverter) Convert(_, _ string) (string, error) {
+	return "# API\n\nConverted by the api converter.\n", nil
+}
+
+func TestBuildSource(t *testing.T) {
+	dir := t.TempDir()
+	src := pagesSource{
+		{URL: "https://kb.example.com/", Body: []byte(`<html><head><title>KB</title></head><body><main><h1>KB</h1>
+<p>Welcome to the knowledge base. <a href="/guide">Guide</a> <a href="/api/">API</a></p></main></body></html>`)},
+		{URL: "https://kb.example.com/guide", Body: []byte("# Guide\n\nInstall the example and run it.\n"), Markdown: true, Depth: 1},
+		{URL: "https://kb.example.com/api/", Body: []byte("<html><body><main><h1>API</h1><p>Original.</p></main></body></html>"), Depth: 1},
+	}
+	var published []string
+	res, err := Build(context.Background(), Config{
+		URL:        "https://kb.example.com/",
+		SkillName:  "kb",
+		Targets:    []Target{{Format: FormatClaude, Dir: filepath.Join(dir, "out")}},
+		TempDir:    filepath.Join(dir, "build"),
+		Source:     src,
+		Extensions: []Converter{apiConverter{}},
+		Publishers: []Publisher{PublisherFunc(func(_ context.Context, skill Skill) error {
+			published = append(published, filepath.Base(skill.Package))
+			return nil
+		})},
+	})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	if res.Pages["saved"] != 3 {
+		t.Errorf("Pages = %v, want 3 saved", res.Pages)
+	}
+	guide, err := os.ReadFile(filepath.Join(dir, "out", "kb", "docs", "guide.md"))
+	if err != nil || !strings.Contains(string(guide), "Install the example") {
+		t.Errorf("docs/guide.md = %q, %v", guide, err)
+	}
+	api, err := os.ReadFile(filepath.Join(dir, "out", "kb", "docs", "api.md"))
+	if err != nil || !strings.Contains(string(api), "Converted by the api converter.") {
+		t.Errorf("docs/api.md = %q, %v", api, err)
+	}
+	if want := []string{"kb.skill"}; strings.Join(published, ",") != strings.Join(want, ",") {
+		t.Errorf("published = %v, want %v", published, want)
+	}
+
+	_, err = Build(context.Background(), Config{
+		URL:        "https://kb.example.com/",
+		SkillName:  "kb",
+		Targets:    []Target{{Format: FormatClaude, Dir: filepath.Join(dir, "out")}},
+		TempDir:    filepath.Join(dir, "build"),
+		Source:     src,
+		Publishers: []Publisher{PublisherFunc(func(context.Context, Skill) error { return errors.New("registry unavailable") })},
+	})
+	if err == nil || !strings.Contains(err.Error(), "failed to publish skill") {
+		t.Errorf("Build() with a failing publisher error = %v, want a publish error", err)
+	}
+}
+
+func TestBuildMaxRuntime(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+
+	_, err := Build(context.Background(), Config{
+		URL:        server.URL + "/docs/",
+		SkillName:  "example",
+		Targets:    []Target{{Format: FormatClaude, Dir: filepath.Join(dir, "out")}},
+		TempDir:    filepath.Join(dir, "build"),
+		MaxRuntime: 50 * time.Millisecond,
+	})
+	if !errors.Is(err, ErrMaxRuntime) {
+		t.Fatalf("Build() error = %v, want ErrMaxRuntime", err)
+	}
+}
+
+func TestBuildRobotsBlocked(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\nDisallow: /\n"))
+	}))
+	defer server.Close()
+	dir := t.TempDir()
+
+	_, err := Build(context.Background(), Config{
+		URL:       server.URL + "/docs/",
+		SkillName: "example",
+		Targets:   []Target{{Format: FormatClaude, Dir: filepath.Join(dir, "out")}},
+		TempDir:   filepath.Join(dir, "build"),
+	})
+	if !errors.Is(err, ErrRobotsBlocked) {
+		t.Fatalf("Build() error = %v, want ErrRobotsBlocked", err)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	server := newDocsServer(t)
+	cfg := Config{TempDir: t.TempDir(), ContentSelector: "main", AccessRules: []string{"/docs/guide=internal"}}
+
+	in, err := Inspect(context.Background(), cfg, server.URL+"/docs/guide")
+	if err != nil {
+		t.Fatalf("Inspect() returned error: %v", err)
+	}
+	if in.StatusCode != 200 || in.Cache != "miss" || in.Title != "Guide" || in.Extraction != "content_selector" {
+		t.Errorf("Inspect() = status %d, cache %q, title %q, extraction %q", in.StatusCode, in.Cache, in.Title, in.Extraction)
+	}
+	if !strings.Contains(in.Markdown, "access: \"internal\"") || !strings.Contains(in.Markdown, "Install the example") {
+		t.Errorf("Markdown = %q", in.Markdown)
+	}
+
+	if _, err := Inspect(context.Background(), cfg, server.URL+"/docs/missing"); err == nil || !strings.Contains(err.Error(), "HTTP 404") {
+		t.Errorf("Inspect() of a missing page: error = %v, want HTTP 404", err)
+	}
+}
+
+func TestProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			w.Write([]byte("User-agent: *\nDisallow: /private/\nSitemap: https://docs.example.com/sitemap.xml\n"))
+		case "/docs/", "/private/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html lang="en"><head><title>Example Docs</title>
+<meta name="generator" content="Docusaurus v3.1.0">
+<link rel="alternate" hreflang="ja" href="/ja/docs/"><link rel="alternate" hreflang="en" href="/docs/">
+<link rel="alternate" hreflang="x-default" href="/docs/"></head>
+<body><main><h1>Example Docs</h1><p>Welcome to the example documentation.</p></main></body></html>`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	probe, err := Probe(context.Background(), Config{NoCache: true}, server.URL+"/docs/")
+	if err != nil {
+		t.Fatalf("Probe() returned error: %v", err)
+	}
+	want := SiteProbe{
+		URL:           server.URL + "/docs/",
+		FinalURL:      server.URL + "/docs/",
+		Title:         "Example Docs",
+		Generator:     "Docusaurus v3.1.0",
+		Platform:      "docusaurus",
+		Lang:          "en",
+		Locales:       []string{"en", "ja"},
+		RobotsAllowed: true,
+		Sitemaps:      []string{"https://docs.example.com/sitemap.xml"},
+	}
+	if !reflect.DeepEqual(*probe, want) {
+		t.Errorf("Probe() = %+v, want %+v", *probe, want)
+	}
+
+	if probe, err = Probe(context.Background(), Config{NoCache: true}, server.URL+"/private/"); err != nil || probe.RobotsAllowed {
+		t.Errorf("Probe() of a disallowed page = %+v, %v, want RobotsAllowed false", probe, err)
+	}
+}
+
+func TestReconstructURL(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		relPath string
+		want    string
+	}{
+		{"https://docs.example.com/", "docs.example.com/guide/intro.html", "https://docs.example.com/guide/intro"},
+		{"https://docs.example.com/", "docs.example.com/guide/intro.html.gz", "https://docs.example.com/guide/intro"},
+		{"http://127.0.0.1:8080/", "127.0.0.1:8080/index.html", "http://127.0.0.1:8080/index"},
+		{"https://例え.jp/", filepath.Join("例え.jp", "ドキュメント", "はじめに.html"), "https://xn--r8jz45g.jp/%E3%83%89%E3%82%AD%E3%83%A5%E3%83%A1%E3%83%B3%E3%83%88/%E3%81%AF%E3%81%98%E3%82%81%E3%81%AB"},
+	}
+	for _, tt := range tests {
+		if got := reconstructURL(tt.baseURL, tt.relPath); got != tt.want {
+			t.Errorf("reconstructURL(%q, %q) = %q, want %q", tt.baseURL, tt.relPath, got, tt.want)
+		}
+	}
+
+	for name, want := range map[string]string{
+		"hello world!": "hello_world_",
+		"はじめに":         "はじめに",
+		"api_q_hl_ja":  "api_q_hl_ja",
+		"café/menu":    "café_menu",
+	} {
+		if got := sanitizeFilename(name); got != want {
+			t.Errorf("sanitizeFilename(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestDatedName(t *testing.T) {
+	tests := []struct {
+		published string
+		want      string
+	}{
+		{"2024-05-01T10:00:00Z", "2024-05-01-release-notes"},
+		{"", "release-notes"},
+		{"May 1, 2024", "release-notes"},
+	}
+	for _, tt := range tests {
+		if got := datedName(tt.published, "release-notes"); got != tt.want {
+			t.Errorf("datedName(%q) = %q, want %q", tt.published, got, tt.want)
+		}
+	}
+}
+
+func TestPageSeries(t *testing.T) {
+	saved := map[string]fetcher.PageRecord{
+		"crawl/example.com/guide.html":          {URL: "https://example.com/guide", OutputFile: "crawl/example.com/guide.html"},
+		"crawl/example.com/guide_q_page_3.html": {URL: "https://example.com/guide?page=3", SeriesOf: "https://example.com/guide", PageNumber: 3, OutputFile: "crawl/example.com/guide_q_page_3.html"},
+		"crawl/example.com/guide_q_page_2.html": {URL: "https://example.com/guide?page=2", SeriesOf: "https://example.com/guide", PageNumber: 2, OutputFile: "crawl/example.com/guide_q_page_2.html"},
+		"crawl/example.com/blog/page/2.html":    {URL: "https://example.com/blog/page/2", SeriesOf: "https://example.com/blog/", PageNumber: 2, OutputFile: "crawl/example.com/blog/page/2.html"},
+		"crawl/example.com/install.html":        {URL: "https://example.com/install", OutputFile: "crawl/example.com/install.html"},
+	}
+	got := pageSeries(saved, "build")
+	want := map[string][]string{
+		filepath.Join("build", "crawl", "example.com", "guide.html"): {
+			filepath.Join("build", "crawl", "example.com", "guide_q_page_2.html"),
+			filepath.Join("build", "crawl", "example.com", "guide_q_page_3.html"),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pageSeries() = %v, want %v", got, want)
+	}
+}
+
+func TestSourceName(t *testing.T) {
+	tests := []struct {
+		seed string
+		want string
+	}{
+		{"https://docs.example.com/", "docs.example.com"},
+		{"https://github.com/acme/app/wiki", "github.com/acme/app/wiki"},
+		{"http://localhost:8080/docs/", "localhost:8080/docs"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := sourceName(tt.seed); got != tt.want {
+			t.Errorf("sourceName(%q) = %q, want %q", tt.seed, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultTargets(t *testing.T) {
+	tests := []struct {
+		format string
+		want   []Target
+	}{
+		{FormatClaude, []Target{{FormatClaude, filepath.Join(".claude", "skills")}}},
+		{FormatCodex, []Target{{FormatCodex, filepath.Join(".codex", "skills")}}},
+		{FormatBoth, []Target{
+			{FormatClaude, filepath.Join(".claude", "skills")},
+			{FormatCodex, filepath.Join(".codex", "skills")},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			got, err := DefaultTargets(tt.format, false)
+			if err != nil {
+				t.Fatalf("DefaultTargets() returned error: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("target %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	t.Setenv("CODEX_HOME", "/opt/codex")
+	got, err := DefaultTargets(FormatCodex, true)
+	if err != nil || got[0].Dir != filepath.Join("/opt/codex", "skills") {
+		t.Errorf("global codex target = %v, %v; want /opt/codex/skills", got, err)
+	}
+
+	if _, err := DefaultTargets("gemini", false); err == nil {
+		t.Error("DefaultTargets(\"gemini\") should return an error")
+	}
+}
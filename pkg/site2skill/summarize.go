@@ -0,0 +1,51 @@
+// Package site2skill runs the whole site2skillgo build pipeline in-process.
+//
+// This file implements the summarize stage, which writes a summary into the
+// frontmatter of each converted document with Config.Summarizer.
+package site2skill
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/skillgen"
+	"github.com/f4ah6o/site2skill-go/internal/summarize"
+)
+
+// summarize writes the summaries of the Markdown files of markdownDir, reusing
+// those the manifests of the previous skills record for unchanged pages.
+func (b *builder) summarize() error {
+	if b.cfg.Summarizer == nil {
+		return nil
+	}
+	log.Printf("=== Step 6: Summarizing Documents with %s ===", b.cfg.Summarizer.Spec())
+	start := time.Now()
+	previous := make(map[string]string)
+	for _, target := range b.cfg.Targets {
+		m, err := skillgen.ReadManifest(filepath.Join(target.Dir, b.cfg.SkillName))
+		if err != nil {
+			continue
+		}
+		for _, doc := range m.Documents {
+			if key := summarize.Key(doc.ContentHash, doc.Part); key != "" && doc.Summary != "" {
+				previous[key] = doc.Summary
+			}
+		}
+	}
+	stats, err := summarize.Dir(b.ctx, b.markdownDir, b.cfg.Summarizer, previous)
+	if err != nil {
+		return fmt.Errorf("failed to summarize documents: %w", err)
+	}
+	log.Printf("Summaries: wrote %d, reused %d unchanged, kept %d written before, failed %d of %d documents",
+		stats.Summarized, stats.Reused, stats.Kept, stats.Failed, stats.Documents)
+	b.stageCompleted("summarize", start, map[string]int{
+		"documents":  stats.Documents,
+		"summarized": stats.Summarized,
+		"reused":     stats.Reused,
+		"kept":       stats.Kept,
+		"failed":     stats.Failed,
+	})
+	return nil
+}
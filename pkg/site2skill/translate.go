@@ -0,0 +1,56 @@
+// Package site2skill runs the whole site2skillgo build pipeline in-process.
+//
+// This file implements the translate stage, which translates the converted
+// documents in another language than the skill's with Config.Translator.
+package site2skill
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"github.com/f4ah6o/site2skill-go/internal/skillgen"
+	"github.com/f4ah6o/site2skill-go/internal/translate"
+)
+
+// translate translates the Markdown files of markdownDir into
+// Config.TranslateTo, or else the first locale of the locale priority, reusing
+// the documents of the previous skills translated from unchanged pages.
+func (b *builder) translate() error {
+	if b.cfg.Translator == nil {
+		return nil
+	}
+	to := b.cfg.TranslateTo
+	if to == "" {
+		to = b.cfg.Locales[0]
+	}
+	log.Printf("=== Step 5: Translating Documents into %s with %s ===", to, b.cfg.Translator.Spec())
+	start := time.Now()
+	previous := make(map[string]string)
+	for _, target := range b.cfg.Targets {
+		dir := filepath.Join(target.Dir, b.cfg.SkillName)
+		m, err := skillgen.ReadManifest(dir)
+		if err != nil {
+			continue
+		}
+		for _, doc := range m.Documents {
+			if key := translate.Key(doc.ContentHash, doc.Part); key != "" {
+				previous[key] = filepath.Join(dir, filepath.FromSlash(doc.Path))
+			}
+		}
+	}
+	stats, err := translate.Dir(b.ctx, b.markdownDir, b.cfg.Translator, to, previous)
+	if err != nil {
+		return fmt.Errorf("failed to translate documents: %w", err)
+	}
+	log.Printf("Translations: translated %d, reused %d unchanged, failed %d of %d documents in other languages",
+		stats.Translated, stats.Reused, stats.Failed, stats.Documents)
+	b.stageCompleted("translate", start, map[string]int{
+		"documents":  stats.Documents,
+		"translated": stats.Translated,
+		"reused":     stats.Reused,
+		"failed":     stats.Failed,
+	})
+	return nil
+}